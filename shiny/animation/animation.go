@@ -0,0 +1,215 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package animation provides animations that are paced by a window's frames.
+//
+// A Clock is ticked with the time of each frame, such as by widget.RunWindow
+// on each screen.FrameEvent, and advances the Animators that are started on
+// it. A Float is an animated property of a widget, that marks the widget as
+// needing paint, or layout, whenever its value changes, so that only the
+// nodes that animate are painted again, and only once per frame.
+//
+// Animations are started, ticked and read on the one goroutine, such as that
+// of a widget.RunWindow event loop, and the widget event handlers that it
+// calls.
+package animation // import "golang.org/x/exp/shiny/animation"
+
+import (
+	"time"
+
+	"golang.org/x/exp/shiny/widget/node"
+)
+
+// Animator is an animation that a Clock advances, once per frame.
+type Animator interface {
+	// Animate advances the animation to the frame at now. It returns whether
+	// the animation continues, and so needs another frame.
+	Animate(now time.Time) bool
+}
+
+// AnimatorFunc is an Animator that is a function.
+type AnimatorFunc func(now time.Time) bool
+
+// Animate implements the Animator interface.
+func (f AnimatorFunc) Animate(now time.Time) bool { return f(now) }
+
+// Clock advances Animators with the times of a window's frames.
+//
+// The zero value is a Clock with no Animators.
+type Clock struct {
+	anims []Animator
+	now   time.Time
+}
+
+// Start starts the Animator a, if it is not already running, so that it is
+// advanced by the next call to Tick, and the ones after, for as long as it
+// continues. An Animator is compared with the == operator, so it should be
+// a pointer, not a func.
+func (c *Clock) Start(a Animator) {
+	for _, b := range c.anims {
+		if b == a {
+			return
+		}
+	}
+	c.anims = append(c.anims, a)
+}
+
+// Stop stops the Animator a. It does nothing if a is not running.
+func (c *Clock) Stop(a Animator) {
+	for i, b := range c.anims {
+		if b == a {
+			c.anims = append(c.anims[:i], c.anims[i+1:]...)
+			return
+		}
+	}
+}
+
+// Running returns whether any Animator is running, and so whether Tick
+// should be called on the next frame.
+func (c *Clock) Running() bool {
+	return len(c.anims) != 0
+}
+
+// Now returns the time of the most recent call to Tick, or the zero time if
+// there has been none.
+func (c *Clock) Now() time.Time {
+	return c.now
+}
+
+// Tick advances every running Animator to the frame at now, stopping those
+// that do not continue. Animators started during the Tick are first advanced
+// by the next one.
+func (c *Clock) Tick(now time.Time) {
+	c.now = now
+	anims := c.anims
+	c.anims = nil
+	for _, a := range anims {
+		if a.Animate(now) {
+			c.Start(a)
+		}
+	}
+}
+
+// Float is an animated float64 property of a node, such as its opacity or
+// the offset of a sliding panel.
+//
+// The zero value is a property whose value is 0, and that marks no node.
+type Float struct {
+	// Node, if non-nil, is marked with Marks whenever the value changes.
+	Node node.Node
+
+	// Marks are the marks given to Node. The zero value means
+	// node.MarkNeedsPaint. A property that changes how the node is laid out,
+	// or is painted in the base pass, needs node.MarkNeedsMeasureLayout or
+	// node.MarkNeedsPaintBase instead, or as well.
+	Marks node.Marks
+
+	value float64
+	clock *Clock
+
+	// The animation in progress is either tween or, if springing, a spring
+	// towards target, moving at velocity, whose last step was at last.
+	tween     Tween
+	start     time.Time
+	springing bool
+	spring    Spring
+	target    float64
+	velocity  float64
+	last      time.Time
+}
+
+// Value returns the property's current value.
+func (f *Float) Value() float64 {
+	return f.value
+}
+
+// Set sets the property's value, stopping any animation of it.
+func (f *Float) Set(v float64) {
+	f.stop()
+	f.set(v)
+}
+
+// TweenTo animates the property from its current value to the value to, over
+// the duration d, along the curve, on the Clock c. A nil curve means Linear.
+// The animation starts at c's next tick.
+func (f *Float) TweenTo(c *Clock, to float64, d time.Duration, curve Curve) {
+	f.stop()
+	f.clock = c
+	f.tween = Tween{From: f.value, To: to, Duration: d, Curve: curve}
+	f.start = time.Time{}
+	c.Start(f)
+}
+
+// SpringTo animates the property from its current value towards the value to,
+// as if pulled by the spring s, on the Clock c. If the property is already
+// springing, it keeps its velocity, so that re-targeting it is smooth.
+func (f *Float) SpringTo(c *Clock, to float64, s Spring) {
+	if !f.springing || f.clock != c {
+		f.stop()
+		f.clock = c
+		f.springing = true
+		f.velocity = 0
+		f.last = time.Time{}
+	}
+	f.spring = s
+	f.target = to
+	c.Start(f)
+}
+
+// Animating returns whether the property is being animated.
+func (f *Float) Animating() bool {
+	return f.clock != nil
+}
+
+// Animate implements the Animator interface.
+func (f *Float) Animate(now time.Time) bool {
+	if f.clock == nil {
+		return false
+	}
+	if f.springing {
+		if f.last.IsZero() {
+			f.last = now
+		}
+		v, done := f.spring.step(f.value, &f.velocity, f.target, now.Sub(f.last))
+		f.last = now
+		f.set(v)
+		if done {
+			f.clock, f.springing = nil, false
+		}
+		return !done
+	}
+
+	if f.start.IsZero() {
+		f.start = now
+	}
+	elapsed := now.Sub(f.start)
+	f.set(f.tween.At(elapsed))
+	if elapsed >= f.tween.Duration {
+		f.clock = nil
+		return false
+	}
+	return true
+}
+
+func (f *Float) stop() {
+	if f.clock != nil {
+		f.clock.Stop(f)
+		f.clock = nil
+	}
+	f.springing = false
+}
+
+func (f *Float) set(v float64) {
+	if f.value == v {
+		return
+	}
+	f.value = v
+	if f.Node != nil {
+		m := f.Marks
+		if m == 0 {
+			m = node.MarkNeedsPaint
+		}
+		f.Node.Mark(m)
+	}
+}
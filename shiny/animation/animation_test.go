@@ -0,0 +1,181 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package animation
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/shiny/widget/node"
+)
+
+// leaf is a node that counts how often it is marked.
+type leaf struct {
+	node.LeafEmbed
+	marked int
+}
+
+func newLeaf() *leaf {
+	w := &leaf{}
+	w.Wrapper = w
+	return w
+}
+
+func (w *leaf) Mark(m node.Marks) {
+	w.marked++
+	w.LeafEmbed.Mark(m)
+}
+
+func TestCurves(t *testing.T) {
+	testCases := []struct {
+		name  string
+		c     Curve
+		t     float64
+		want  float64
+		delta float64
+	}{
+		{"Linear", Linear, 0.25, 0.25, 0},
+		{"EaseIn", EaseIn, 0, 0, 0},
+		{"EaseIn", EaseIn, 1, 1, 0},
+		{"EaseIn", EaseIn, 0.5, 0.315, 1e-3},
+		{"EaseOut", EaseOut, 0.5, 0.685, 1e-3},
+		{"EaseInOut", EaseInOut, 0.5, 0.5, 1e-6},
+		{"CubicBezier", CubicBezier(1.0/3, 1.0/3, 2.0/3, 2.0/3), 0.7, 0.7, 1e-6},
+	}
+	for _, tc := range testCases {
+		if got := tc.c(tc.t); math.Abs(got-tc.want) > tc.delta {
+			t.Errorf("%s(%v): got %v, want %v", tc.name, tc.t, got, tc.want)
+		}
+	}
+}
+
+func TestTween(t *testing.T) {
+	tw := Tween{From: 10, To: 20, Duration: time.Second}
+	testCases := []struct {
+		elapsed time.Duration
+		want    float64
+	}{
+		{-time.Second, 10},
+		{0, 10},
+		{250 * time.Millisecond, 12.5},
+		{time.Second, 20},
+		{2 * time.Second, 20},
+	}
+	for _, tc := range testCases {
+		if got := tw.At(tc.elapsed); got != tc.want {
+			t.Errorf("At(%v): got %v, want %v", tc.elapsed, got, tc.want)
+		}
+	}
+}
+
+func TestSpring(t *testing.T) {
+	for _, s := range []Spring{{}, {Stiffness: 200, Damping: 5}} {
+		x, v := 0.0, 0.0
+		max, done := 0.0, false
+		for i := 0; i < 600 && !done; i++ {
+			x, done = s.step(x, &v, 1, time.Second/60)
+			max = math.Max(max, x)
+		}
+		if !done || x != 1 || v != 0 {
+			t.Errorf("%+v: got x=%v, v=%v, done=%t, want at rest at 1", s, x, v, done)
+		}
+		// Only an under-damped spring overshoots.
+		if overshot := max > 1.01; overshot != (s.Damping == 5) {
+			t.Errorf("%+v: overshot: got %t (max %v)", s, overshot, max)
+		}
+	}
+}
+
+func TestFloatTween(t *testing.T) {
+	var c Clock
+	w := newLeaf()
+	f := &Float{Node: w}
+	f.TweenTo(&c, 100, 100*time.Millisecond, nil)
+	if !c.Running() || !f.Animating() {
+		t.Fatalf("after TweenTo: got not running")
+	}
+
+	// The animation starts at the first tick.
+	t0 := time.Unix(1000, 0)
+	c.Tick(t0)
+	if got := f.Value(); got != 0 {
+		t.Errorf("first tick: got %v, want 0", got)
+	}
+	if w.marked != 0 {
+		t.Errorf("first tick: got %d marks, want 0", w.marked)
+	}
+	c.Tick(t0.Add(25 * time.Millisecond))
+	if got := f.Value(); got != 25 {
+		t.Errorf("second tick: got %v, want 25", got)
+	}
+	if w.marked != 1 || !w.Marks.NeedsPaint() {
+		t.Errorf("second tick: got %d marks, needs paint %t, want 1, true", w.marked, w.Marks.NeedsPaint())
+	}
+	c.Tick(t0.Add(time.Second))
+	if got := f.Value(); got != 100 {
+		t.Errorf("last tick: got %v, want 100", got)
+	}
+	if c.Running() || f.Animating() {
+		t.Errorf("last tick: got running")
+	}
+
+	// Set stops an animation.
+	f.TweenTo(&c, 0, time.Second, EaseInOut)
+	f.Set(50)
+	if c.Running() || f.Animating() || f.Value() != 50 {
+		t.Errorf("Set: got running %t, value %v, want false, 50", c.Running(), f.Value())
+	}
+}
+
+func TestFloatSpring(t *testing.T) {
+	var c Clock
+	f := &Float{}
+	f.SpringTo(&c, 1, Spring{})
+	now := time.Unix(1000, 0)
+	c.Tick(now)
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second / 60)
+		c.Tick(now)
+	}
+	v := f.velocity
+	if v <= 0 {
+		t.Fatalf("got velocity %v, want positive", v)
+	}
+	// Re-targeting keeps the velocity.
+	f.SpringTo(&c, 2, Spring{})
+	if f.velocity != v {
+		t.Errorf("re-targeted: got velocity %v, want %v", f.velocity, v)
+	}
+	for i := 0; i < 600 && c.Running(); i++ {
+		now = now.Add(time.Second / 60)
+		c.Tick(now)
+	}
+	if c.Running() || f.Value() != 2 {
+		t.Errorf("got running %t, value %v, want false, 2", c.Running(), f.Value())
+	}
+}
+
+func TestClockStart(t *testing.T) {
+	var c Clock
+	n := 0
+	a := AnimatorFunc(func(now time.Time) bool {
+		n++
+		return n < 3
+	})
+	var b struct{ Animator }
+	b.Animator = a
+	c.Start(&b)
+	c.Start(&b)
+	for i := 0; i < 5; i++ {
+		c.Tick(time.Unix(int64(i), 0))
+	}
+	if n != 3 {
+		t.Errorf("got %d calls, want 3", n)
+	}
+	if got, want := c.Now(), time.Unix(4, 0); !got.Equal(want) {
+		t.Errorf("Now: got %v, want %v", got, want)
+	}
+}
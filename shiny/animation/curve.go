@@ -0,0 +1,146 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package animation
+
+import (
+	"math"
+	"time"
+)
+
+// Curve is an easing curve. It maps the fraction t, from 0 to 1, of an
+// animation's duration to the fraction of the animation's change that has
+// happened by then. It should map 0 to 0 and 1 to 1, but may overshoot in
+// between.
+type Curve func(t float64) float64
+
+// These Curves are those of the CSS timing functions of the same names.
+var (
+	Linear    Curve = func(t float64) float64 { return t }
+	Ease            = CubicBezier(0.25, 0.1, 0.25, 1)
+	EaseIn          = CubicBezier(0.42, 0, 1, 1)
+	EaseOut         = CubicBezier(0, 0, 0.58, 1)
+	EaseInOut       = CubicBezier(0.42, 0, 0.58, 1)
+)
+
+// CubicBezier returns the Curve that is the cubic Bézier curve from (0, 0) to
+// (1, 1) with the control points (x1, y1) and (x2, y2), as for the CSS
+// cubic-bezier timing function. x1 and x2 should be in the range [0, 1].
+func CubicBezier(x1, y1, x2, y2 float64) Curve {
+	// bezier returns the co-ordinate, at the parameter s, of the curve
+	// whose control points' co-ordinates are 0, p1, p2 and 1.
+	bezier := func(p1, p2, s float64) float64 {
+		u := 1 - s
+		return 3*u*u*s*p1 + 3*u*s*s*p2 + s*s*s
+	}
+	return func(t float64) float64 {
+		if t <= 0 {
+			return 0
+		}
+		if t >= 1 {
+			return 1
+		}
+		// x is monotonic in s, for x1 and x2 in [0, 1], so bisect for the s
+		// whose x is t.
+		lo, hi := 0.0, 1.0
+		for i := 0; i < 32; i++ {
+			s := (lo + hi) / 2
+			if bezier(x1, x2, s) < t {
+				lo = s
+			} else {
+				hi = s
+			}
+		}
+		return bezier(y1, y2, (lo+hi)/2)
+	}
+}
+
+// Tween interpolates between two values over a duration.
+type Tween struct {
+	From, To float64
+	Duration time.Duration
+
+	// Curve is the easing curve. A nil Curve means Linear.
+	Curve Curve
+}
+
+// At returns the value at the elapsed time since the start. It is From before
+// the start and To after the end.
+func (t *Tween) At(elapsed time.Duration) float64 {
+	if elapsed >= t.Duration {
+		return t.To
+	}
+	if elapsed <= 0 {
+		return t.From
+	}
+	frac := float64(elapsed) / float64(t.Duration)
+	if t.Curve != nil {
+		frac = t.Curve(frac)
+	}
+	return Lerp(t.From, t.To, frac)
+}
+
+// Lerp returns the linear interpolation from a to b at t: a when t is 0 and b
+// when t is 1.
+func Lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// Spring is a damped spring, pulling a value towards a target.
+//
+// The zero value is a spring whose Stiffness is 170 and Damping is 26, which
+// is close to critically damped, so that it settles quickly without
+// overshooting.
+type Spring struct {
+	// Stiffness is the force per unit of distance from the target.
+	Stiffness float64
+
+	// Damping is the force per unit of velocity, opposing the motion. A
+	// spring whose Damping is less than 2*sqrt(Stiffness*Mass) overshoots
+	// and oscillates about the target.
+	Damping float64
+
+	// Mass is the mass of the value. The zero value means 1.
+	Mass float64
+
+	// Precision is how close to the target, and how slow, the value must be
+	// for the spring to come to rest there. The zero value means 1e-3.
+	Precision float64
+}
+
+// springStep is the step of the simulation of a Spring. Simulating in small,
+// equal steps keeps it stable and independent of the frame rate.
+const springStep = time.Millisecond
+
+// step advances a value x, moving at *v towards target, by dt. It returns the
+// new value, and whether the spring came to rest at the target.
+func (s *Spring) step(x float64, v *float64, target float64, dt time.Duration) (float64, bool) {
+	k, c, m, eps := s.Stiffness, s.Damping, s.Mass, s.Precision
+	if k == 0 && c == 0 {
+		k, c = 170, 26
+	}
+	if m == 0 {
+		m = 1
+	}
+	if eps == 0 {
+		eps = 1e-3
+	}
+	// Cap dt, so that a long pause, such as while the window was hidden,
+	// does not take long to simulate.
+	if dt > time.Second {
+		dt = time.Second
+	}
+	h := springStep.Seconds()
+	for ; dt > 0; dt -= springStep {
+		// Semi-implicit Euler integration.
+		a := (-k*(x-target) - c**v) / m
+		*v += a * h
+		x += *v * h
+	}
+	if math.Abs(x-target) < eps && math.Abs(*v) < eps {
+		*v = 0
+		return target, true
+	}
+	return x, false
+}
@@ -0,0 +1,401 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package atlas packs many small images, such as glyphs, icons and sprites,
+// into a few large screen.Textures.
+//
+// Drawing many sub-rectangles of one Texture is much cheaper, for GPU-backed
+// drivers, than drawing as many separate Textures, and creating one Texture
+// is much cheaper than creating many.
+package atlas // import "golang.org/x/exp/shiny/atlas"
+
+import (
+	"container/list"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/image/math/f64"
+)
+
+var (
+	// ErrTooLarge is returned by Alloc for a size that does not fit in a
+	// page, even an empty one.
+	ErrTooLarge = errors.New("atlas: region is larger than a page")
+
+	errReleased = errors.New("atlas: atlas is released")
+)
+
+// Options are optional arguments to New.
+type Options struct {
+	// PageSize is the size of each page's Texture. The zero value means
+	// 1024x1024.
+	PageSize image.Point
+
+	// MaxPages is the maximum number of pages. Once they are all full,
+	// allocating a Region evicts the least recently used ones. The zero
+	// value means 4.
+	MaxPages int
+
+	// Padding is the number of transparent pixels to the right of and below
+	// each Region, which stops its neighbors from bleeding into it when it
+	// is drawn scaled with linear filtering.
+	Padding int
+
+	// Texture are the options of each page's Texture.
+	Texture screen.NewTextureOptions
+}
+
+// Atlas allocates Regions of a set of pages, each of which is a Texture.
+//
+// Its methods, and those of its Regions, are safe for concurrent use.
+type Atlas struct {
+	s        screen.Screen
+	pageSize image.Point
+	maxPages int
+	padding  int
+	texOpts  screen.NewTextureOptions
+
+	mu       sync.Mutex
+	pages    []*page
+	lru      list.List // Of *Region, most recently used first.
+	released bool
+}
+
+// New returns a new Atlas whose pages are allocated by s. A nil opts is valid
+// and means to use the default option values.
+func New(s screen.Screen, opts *Options) *Atlas {
+	a := &Atlas{
+		s:        s,
+		pageSize: image.Point{1024, 1024},
+		maxPages: 4,
+	}
+	if opts != nil {
+		if opts.PageSize.X > 0 && opts.PageSize.Y > 0 {
+			a.pageSize = opts.PageSize
+		}
+		if opts.MaxPages > 0 {
+			a.maxPages = opts.MaxPages
+		}
+		a.padding = opts.Padding
+		a.texOpts = opts.Texture
+	}
+	return a
+}
+
+// page is one of an Atlas's Textures. It is divided into shelves, rows of
+// Regions, from the top down.
+type page struct {
+	tex     screen.Texture
+	shelves []*shelf
+	// bottom is the y of the first row below every shelf.
+	bottom int
+}
+
+// shelf is a row of slots, from the left.
+type shelf struct {
+	y, h  int
+	slots []slot
+	// right is the x of the first column right of every slot.
+	right int
+}
+
+// slot is a column of a shelf, which holds a Region or is free.
+type slot struct {
+	x, w int
+	r    *Region
+}
+
+// Region is a sub-rectangle of one of an Atlas's Textures.
+//
+// A Region becomes invalid when it is released, or when its Atlas evicts it
+// to make room for another Region. Drawing an invalid Region does nothing,
+// and uploading to it is ignored.
+type Region struct {
+	a    *Atlas
+	p    *page
+	s    *shelf
+	rect image.Rectangle
+	elem *list.Element // In a.lru, or nil if invalid.
+}
+
+// Alloc returns a new Region of the given size. Its pixels are transparent.
+//
+// If no page has room for it, and there are already as many pages as
+// Options.MaxPages, the least recently drawn Regions are evicted until there
+// is room.
+func (a *Atlas) Alloc(size image.Point) (*Region, error) {
+	if size.X <= 0 || size.Y <= 0 {
+		return nil, errors.New("atlas: region size must be positive")
+	}
+	sz := size.Add(image.Point{a.padding, a.padding})
+	if sz.X > a.pageSize.X || sz.Y > a.pageSize.Y {
+		return nil, ErrTooLarge
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.released {
+		return nil, errReleased
+	}
+	r := &Region{a: a}
+	for !a.place(r, sz) {
+		if len(a.pages) < a.maxPages {
+			tex, err := a.s.NewTextureWithOptions(a.pageSize, &a.texOpts)
+			if err != nil {
+				return nil, err
+			}
+			a.pages = append(a.pages, &page{tex: tex})
+			continue
+		}
+		e := a.lru.Back()
+		if e == nil {
+			// Unreachable, as an empty page has room for sz.
+			return nil, ErrTooLarge
+		}
+		a.evict(e.Value.(*Region))
+	}
+	r.elem = a.lru.PushFront(r)
+	// Clear whatever an evicted Region left, including the padding.
+	r.p.tex.Fill(image.Rectangle{r.rect.Min, r.rect.Min.Add(sz)}, color.Transparent, draw.Src)
+	r.rect.Max = r.rect.Min.Add(size)
+	return r, nil
+}
+
+// place places r, whose size including padding is sz, in the first page with
+// room for it, returning whether there was room. On each page, it prefers
+// the shortest shelf that is tall enough and has a free slot or free space
+// for r, and otherwise starts a new shelf below the others. It must only be
+// called while holding a.mu.
+func (a *Atlas) place(r *Region, sz image.Point) bool {
+	for _, p := range a.pages {
+		var best *shelf
+		for _, s := range p.shelves {
+			if s.h < sz.Y || (best != nil && s.h >= best.h) {
+				continue
+			}
+			if s.right+sz.X <= a.pageSize.X || s.freeSlot(sz.X) >= 0 {
+				best = s
+			}
+		}
+		if best == nil && p.bottom+sz.Y <= a.pageSize.Y {
+			best = &shelf{y: p.bottom, h: sz.Y}
+			p.shelves = append(p.shelves, best)
+			p.bottom += sz.Y
+		}
+		if best != nil {
+			x := best.insert(r, sz.X)
+			r.p, r.s = p, best
+			r.rect = image.Rectangle{Min: image.Point{x, best.y}, Max: image.Point{x, best.y}.Add(sz)}
+			return true
+		}
+	}
+	return false
+}
+
+// freeSlot returns the index of the narrowest free slot at least w wide, or
+// -1 if there is none.
+func (s *shelf) freeSlot(w int) int {
+	j := -1
+	for i, t := range s.slots {
+		if t.r == nil && t.w >= w && (j < 0 || t.w < s.slots[j].w) {
+			j = i
+		}
+	}
+	return j
+}
+
+// insert inserts r, w wide, in a free slot or to the right of every slot,
+// returning its x.
+func (s *shelf) insert(r *Region, w int) int {
+	i := s.freeSlot(w)
+	if i < 0 {
+		x := s.right
+		s.slots = append(s.slots, slot{x, w, r})
+		s.right += w
+		return x
+	}
+	t := s.slots[i]
+	s.slots[i] = slot{t.x, w, r}
+	if t.w > w {
+		// Split off the rest of the free slot.
+		s.slots = append(s.slots, slot{})
+		copy(s.slots[i+2:], s.slots[i+1:])
+		s.slots[i+1] = slot{t.x + w, t.w - w, nil}
+	}
+	return t.x
+}
+
+// remove frees r's slot, merging it with any free neighbors.
+func (s *shelf) remove(r *Region) {
+	i := 0
+	for s.slots[i].r != r {
+		i++
+	}
+	s.slots[i].r = nil
+	if i+1 < len(s.slots) && s.slots[i+1].r == nil {
+		s.slots[i].w += s.slots[i+1].w
+		s.slots = append(s.slots[:i+1], s.slots[i+2:]...)
+	}
+	if i > 0 && s.slots[i-1].r == nil {
+		s.slots[i-1].w += s.slots[i].w
+		s.slots = append(s.slots[:i], s.slots[i+1:]...)
+		i--
+	}
+	if i == len(s.slots)-1 {
+		// The rightmost slot is free, so it is free space.
+		s.right = s.slots[i].x
+		s.slots = s.slots[:i]
+	}
+}
+
+// evict invalidates r and frees its space. It must only be called while
+// holding a.mu.
+func (a *Atlas) evict(r *Region) {
+	a.lru.Remove(r.elem)
+	r.elem = nil
+	r.s.remove(r)
+
+	// Empty shelves at the bottom of the page become free space again, so
+	// that they can be re-used at a different height.
+	p := r.p
+	for n := len(p.shelves); n > 0 && len(p.shelves[n-1].slots) == 0; n-- {
+		p.bottom = p.shelves[n-1].y
+		p.shelves = p.shelves[:n-1]
+	}
+}
+
+// Release releases the Atlas's pages, invalidating all of its Regions.
+func (a *Atlas) Release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.released {
+		return
+	}
+	a.released = true
+	for e := a.lru.Front(); e != nil; e = e.Next() {
+		e.Value.(*Region).elem = nil
+	}
+	a.lru.Init()
+	for _, p := range a.pages {
+		p.tex.Release()
+	}
+	a.pages = nil
+}
+
+// Valid returns whether r has been neither released nor evicted.
+func (r *Region) Valid() bool {
+	r.a.mu.Lock()
+	defer r.a.mu.Unlock()
+	return r.elem != nil
+}
+
+// Texture returns the Texture that holds r, within r.Rect().
+func (r *Region) Texture() screen.Texture {
+	return r.p.tex
+}
+
+// Rect returns r's sub-rectangle of its Texture.
+func (r *Region) Rect() image.Rectangle {
+	return r.rect
+}
+
+// Size returns the size of r.
+func (r *Region) Size() image.Point {
+	return r.rect.Size()
+}
+
+// Bounds returns the bounds of r, in its own co-ordinate space, which is
+// image.Rectangle{Max: r.Size()}.
+func (r *Region) Bounds() image.Rectangle {
+	return image.Rectangle{Max: r.rect.Size()}
+}
+
+// Upload uploads the sub-Buffer defined by src and sr to r, such that sr.Min
+// in src-space aligns with dp in r-space. The upload is clipped to r's
+// bounds, so that it never overwrites other Regions.
+func (r *Region) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	src2dst := dp.Sub(sr.Min)
+	dr := sr.Intersect(src.Bounds()).Add(src2dst).Intersect(r.Bounds())
+	if dr.Empty() {
+		return
+	}
+
+	r.a.mu.Lock()
+	defer r.a.mu.Unlock()
+
+	if r.elem == nil {
+		return
+	}
+	r.p.tex.Upload(dr.Min.Add(r.rect.Min), src, dr.Sub(src2dst))
+}
+
+// Release releases r, so that its space can be re-used. It is valid to
+// release an invalid Region.
+func (r *Region) Release() {
+	r.a.mu.Lock()
+	defer r.a.mu.Unlock()
+
+	if r.elem != nil {
+		r.a.evict(r)
+	}
+}
+
+// use marks r as the most recently used Region of its Atlas, returning r's
+// Texture, or nil if r is invalid.
+func (r *Region) use() screen.Texture {
+	r.a.mu.Lock()
+	defer r.a.mu.Unlock()
+
+	if r.elem == nil {
+		return nil
+	}
+	r.a.lru.MoveToFront(r.elem)
+	return r.p.tex
+}
+
+// Draw draws all of r to dst, transformed by src2dst, which maps from r-space
+// to dst-space, as for the Draw method of the screen.Drawer interface.
+func (r *Region) Draw(dst screen.Drawer, src2dst f64.Aff3, op draw.Op, opts *screen.DrawOptions) {
+	if tex, q, ok := r.Quad(src2dst); ok {
+		dst.Draw(q.Src2Dst, tex, q.SR, op, opts)
+	}
+}
+
+// Quad returns r's Texture and a screen.Quad that draws all of r, transformed
+// by src2dst, which maps from r-space to dst-space. Like drawing r, it marks
+// r as recently used. Passing many Regions' Quads to screen.DrawBatch draws
+// those on the same page at once. ok is false if r is invalid.
+func (r *Region) Quad(src2dst f64.Aff3) (tex screen.Texture, q screen.Quad, ok bool) {
+	tex = r.use()
+	if tex == nil {
+		return nil, screen.Quad{}, false
+	}
+	// Pre-multiply by the translation from texture-space to r-space.
+	x, y := float64(r.rect.Min.X), float64(r.rect.Min.Y)
+	src2dst[2] -= src2dst[0]*x + src2dst[1]*y
+	src2dst[5] -= src2dst[3]*x + src2dst[4]*y
+	return tex, screen.Quad{Src2Dst: src2dst, SR: r.rect}, true
+}
+
+// Copy draws all of r to dst such that r's top-left corner aligns with dp in
+// dst-space, as for the Copy method of the screen.Drawer interface.
+func (r *Region) Copy(dst screen.Drawer, dp image.Point, op draw.Op, opts *screen.DrawOptions) {
+	if tex := r.use(); tex != nil {
+		dst.Copy(dp, tex, r.rect, op, opts)
+	}
+}
+
+// Scale draws all of r to dst, scaled to fit dr, as for the Scale method of
+// the screen.Drawer interface.
+func (r *Region) Scale(dst screen.Drawer, dr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	if tex := r.use(); tex != nil {
+		dst.Scale(dr, tex, r.rect, op, opts)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package atlas
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"golang.org/x/exp/shiny/driver/headlessdriver"
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestAlloc(t *testing.T) {
+	a := New(headlessdriver.NewScreen(), &Options{
+		PageSize: image.Point{16, 16},
+		MaxPages: 1,
+	})
+	defer a.Release()
+
+	if _, err := a.Alloc(image.Point{17, 1}); err != ErrTooLarge {
+		t.Fatalf("Alloc 17x1: got %v, want ErrTooLarge", err)
+	}
+
+	// Four 8x8 Regions fill the page, without overlapping.
+	var rs []*Region
+	for i := 0; i < 4; i++ {
+		r, err := a.Alloc(image.Point{8, 8})
+		if err != nil {
+			t.Fatalf("Alloc #%d: %v", i, err)
+		}
+		for j, q := range rs {
+			if r.Rect().Overlaps(q.Rect()) {
+				t.Fatalf("Region #%d %v overlaps Region #%d %v", i, r.Rect(), j, q.Rect())
+			}
+		}
+		if i > 0 && r.Texture() != rs[0].Texture() {
+			t.Fatalf("Region #%d: not on the only page", i)
+		}
+		rs = append(rs, r)
+	}
+
+	// Drawing Region #0 makes #1 the least recently used, so the next
+	// allocation evicts it and takes its place.
+	w, err := headlessdriver.NewScreen().NewWindow(&screen.NewWindowOptions{Width: 8, Height: 8})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+	rs[0].Copy(w, image.Point{}, draw.Src, nil)
+
+	r, err := a.Alloc(image.Point{4, 8})
+	if err != nil {
+		t.Fatalf("Alloc after full: %v", err)
+	}
+	if rs[1].Valid() {
+		t.Errorf("Region #1: still valid after eviction")
+	}
+	for i, q := range rs {
+		if i != 1 && !q.Valid() {
+			t.Errorf("Region #%d: evicted, want valid", i)
+		}
+	}
+	if !r.Rect().In(rs[1].Rect()) {
+		t.Errorf("Region after eviction: got %v, want within %v", r.Rect(), rs[1].Rect())
+	}
+
+	// The other half of Region #1's slot is still free.
+	if q, err := a.Alloc(image.Point{4, 8}); err != nil {
+		t.Errorf("Alloc into split slot: %v", err)
+	} else if !rs[0].Valid() || !rs[2].Valid() || !rs[3].Valid() {
+		t.Errorf("Alloc into split slot: evicted a Region")
+	} else if !q.Rect().In(rs[1].Rect()) {
+		t.Errorf("Alloc into split slot: got %v, want within %v", q.Rect(), rs[1].Rect())
+	}
+
+	rs[2].Release()
+	if rs[2].Valid() {
+		t.Errorf("Region #2: still valid after Release")
+	}
+	a.Release()
+	if rs[0].Valid() {
+		t.Errorf("Region #0: still valid after Atlas.Release")
+	}
+}
+
+func TestUploadAndDraw(t *testing.T) {
+	s := headlessdriver.NewScreen()
+	a := New(s, &Options{
+		PageSize: image.Point{8, 8},
+		Padding:  1,
+	})
+	defer a.Release()
+
+	red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+	blue := color.RGBA{0x00, 0x00, 0xff, 0xff}
+	r0, err := a.Alloc(image.Point{2, 2})
+	if err != nil {
+		t.Fatalf("Alloc: %v", err)
+	}
+	r1, err := a.Alloc(image.Point{2, 2})
+	if err != nil {
+		t.Fatalf("Alloc: %v", err)
+	}
+	if got, want := r1.Rect().Min.X-r0.Rect().Max.X, 1; got != want {
+		t.Errorf("gap between Regions: got %d, want %d", got, want)
+	}
+
+	b, err := s.NewBuffer(image.Point{4, 4})
+	if err != nil {
+		t.Fatalf("NewBuffer: %v", err)
+	}
+	defer b.Release()
+	draw.Draw(b.RGBA(), b.Bounds(), image.NewUniform(red), image.Point{}, draw.Src)
+	// The upload is clipped to r0, so it does not overwrite r1.
+	r0.Upload(image.Point{}, b, b.Bounds())
+	draw.Draw(b.RGBA(), b.Bounds(), image.NewUniform(blue), image.Point{}, draw.Src)
+	r1.Upload(image.Point{1, 0}, b, b.Bounds())
+
+	// Draw the whole page, so that r1's leftmost column, which was not
+	// uploaded to, and the padding between the Regions are visible.
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 8, Height: 2})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+	w.Fill(image.Rect(0, 0, 8, 2), color.White, draw.Src)
+	w.Copy(image.Point{}, r0.Texture(), r0.Texture().Bounds(), draw.Over, nil)
+	w.Publish()
+
+	m := headlessdriver.Frame(w)
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	for x, want := range []color.RGBA{red, red, white, white, blue, white, white, white} {
+		if got := m.RGBAAt(x, 1); got != want {
+			t.Errorf("pixel (%d, 1): got %v, want %v", x, got, want)
+		}
+	}
+}
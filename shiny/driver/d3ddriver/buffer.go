@@ -0,0 +1,30 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package d3ddriver
+
+import (
+	"image"
+)
+
+// bufferImpl is in Go memory. Uploads copy it to the destination texture,
+// with UpdateSubresource.
+type bufferImpl struct {
+	s    *screenImpl
+	rgba image.RGBA
+	size image.Point
+}
+
+func (b *bufferImpl) Size() image.Point       { return b.size }
+func (b *bufferImpl) Bounds() image.Rectangle { return image.Rectangle{Max: b.size} }
+func (b *bufferImpl) RGBA() *image.RGBA       { return &b.rgba }
+
+func (b *bufferImpl) Release() {
+	// Uploads copy the pixels before returning, so they can be recycled
+	// immediately.
+	b.s.pixPool.Put(b.rgba.Pix)
+	b.rgba.Pix = nil
+}
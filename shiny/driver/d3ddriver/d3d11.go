@@ -0,0 +1,385 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package d3ddriver
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// This file holds the parts of the Direct3D 11, DXGI and D3DCompiler APIs
+// that the driver uses. COM objects are called through their vtables,
+// whose method indexes are those of the Windows SDK's headers.
+
+var (
+	d3d11       = syscall.NewLazyDLL("d3d11.dll")
+	d3dcompiler = syscall.NewLazyDLL("d3dcompiler_47.dll")
+
+	procD3D11CreateDevice = d3d11.NewProc("D3D11CreateDevice")
+	procD3DCompile        = d3dcompiler.NewProc("D3DCompile")
+)
+
+// comObject is a COM object, whose first word points to its vtable.
+type comObject struct {
+	vtbl *[256]uintptr
+}
+
+// call calls the method at index i of o's vtable, with o as the first
+// argument, returning the result, typically an HRESULT.
+//
+//go:uintptrescapes
+func (o *comObject) call(i int, args ...uintptr) uintptr {
+	var a [15]uintptr
+	a[0] = uintptr(unsafe.Pointer(o))
+	n := 1 + copy(a[1:], args)
+	fn := o.vtbl[i]
+	var r uintptr
+	switch {
+	case n <= 3:
+		r, _, _ = syscall.Syscall(fn, uintptr(n), a[0], a[1], a[2])
+	case n <= 6:
+		r, _, _ = syscall.Syscall6(fn, uintptr(n), a[0], a[1], a[2], a[3], a[4], a[5])
+	case n <= 9:
+		r, _, _ = syscall.Syscall9(fn, uintptr(n), a[0], a[1], a[2], a[3], a[4], a[5], a[6], a[7], a[8])
+	case n <= 12:
+		r, _, _ = syscall.Syscall12(fn, uintptr(n), a[0], a[1], a[2], a[3], a[4], a[5], a[6], a[7], a[8], a[9], a[10], a[11])
+	default:
+		r, _, _ = syscall.Syscall15(fn, uintptr(n), a[0], a[1], a[2], a[3], a[4], a[5], a[6], a[7], a[8], a[9], a[10], a[11], a[12], a[13], a[14])
+	}
+	return r
+}
+
+// release calls IUnknown::Release, if o is non-nil.
+func (o *comObject) release() {
+	if o != nil {
+		o.call(2)
+	}
+}
+
+// queryInterface calls IUnknown::QueryInterface.
+func (o *comObject) queryInterface(iid *_GUID) (*comObject, error) {
+	var p *comObject
+	if hr := o.call(0, uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(&p))); failed(hr) {
+		return nil, hrError("QueryInterface", hr)
+	}
+	return p, nil
+}
+
+func failed(hr uintptr) bool { return int32(hr) < 0 }
+
+func hrError(what string, hr uintptr) error {
+	return fmt.Errorf("d3ddriver: %s failed: HRESULT %#08x", what, uint32(hr))
+}
+
+type _GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+var (
+	_IID_IDXGIDevice     = _GUID{0x54ec77fa, 0x1377, 0x44e6, [8]byte{0x8c, 0x32, 0x88, 0xfd, 0x5f, 0x44, 0xc8, 0x4c}}
+	_IID_IDXGIFactory2   = _GUID{0x50c83a1c, 0xe072, 0x4c48, [8]byte{0x87, 0xb0, 0x36, 0x30, 0xfa, 0x36, 0xa6, 0xd0}}
+	_IID_ID3D11Texture2D = _GUID{0x6f15aaf2, 0xd208, 0x4e89, [8]byte{0x9a, 0xb4, 0x48, 0x95, 0x35, 0xd3, 0x4f, 0x9c}}
+)
+
+// Vtable indexes.
+const (
+	// ID3D11Device.
+	_ID3D11Device_CreateBuffer             = 3
+	_ID3D11Device_CreateTexture2D          = 5
+	_ID3D11Device_CreateShaderResourceView = 7
+	_ID3D11Device_CreateRenderTargetView   = 9
+	_ID3D11Device_CreateVertexShader       = 12
+	_ID3D11Device_CreatePixelShader        = 15
+	_ID3D11Device_CreateBlendState         = 20
+	_ID3D11Device_CreateRasterizerState    = 22
+	_ID3D11Device_CreateSamplerState       = 23
+	_ID3D11Device_CreateQuery              = 24
+
+	// ID3D11DeviceContext.
+	_ID3D11DeviceContext_VSSetConstantBuffers   = 7
+	_ID3D11DeviceContext_PSSetShaderResources   = 8
+	_ID3D11DeviceContext_PSSetShader            = 9
+	_ID3D11DeviceContext_PSSetSamplers          = 10
+	_ID3D11DeviceContext_VSSetShader            = 11
+	_ID3D11DeviceContext_Draw                   = 13
+	_ID3D11DeviceContext_Map                    = 14
+	_ID3D11DeviceContext_Unmap                  = 15
+	_ID3D11DeviceContext_PSSetConstantBuffers   = 16
+	_ID3D11DeviceContext_IASetPrimitiveTopology = 24
+	_ID3D11DeviceContext_End                    = 28
+	_ID3D11DeviceContext_GetData                = 29
+	_ID3D11DeviceContext_OMSetRenderTargets     = 33
+	_ID3D11DeviceContext_OMSetBlendState        = 35
+	_ID3D11DeviceContext_RSSetState             = 43
+	_ID3D11DeviceContext_RSSetViewports         = 44
+	_ID3D11DeviceContext_CopySubresourceRegion  = 46
+	_ID3D11DeviceContext_UpdateSubresource      = 48
+	_ID3D11DeviceContext_ClearRenderTargetView  = 50
+	_ID3D11DeviceContext_GenerateMips           = 54
+	_ID3D11DeviceContext_Flush                  = 111
+
+	// IDXGIObject.
+	_IDXGIObject_GetParent = 6
+
+	// IDXGIDevice.
+	_IDXGIDevice_GetAdapter = 7
+
+	// IDXGIFactory and IDXGIFactory2.
+	_IDXGIFactory_MakeWindowAssociation   = 8
+	_IDXGIFactory2_CreateSwapChainForHwnd = 15
+
+	// IDXGISwapChain.
+	_IDXGISwapChain_Present       = 8
+	_IDXGISwapChain_GetBuffer     = 9
+	_IDXGISwapChain_ResizeBuffers = 13
+
+	// ID3DBlob.
+	_ID3DBlob_GetBufferPointer = 3
+	_ID3DBlob_GetBufferSize    = 4
+)
+
+const (
+	_D3D_DRIVER_TYPE_HARDWARE = 1
+	_D3D_DRIVER_TYPE_WARP     = 5
+
+	_D3D_FEATURE_LEVEL_10_0 = 0xa000
+	_D3D_FEATURE_LEVEL_10_1 = 0xa100
+	_D3D_FEATURE_LEVEL_11_0 = 0xb000
+
+	_D3D11_CREATE_DEVICE_BGRA_SUPPORT = 0x20
+	_D3D11_SDK_VERSION                = 7
+
+	_DXGI_FORMAT_R8G8B8A8_UNORM = 28
+	_DXGI_FORMAT_BC1_UNORM      = 71
+	_DXGI_FORMAT_BC3_UNORM      = 77
+
+	_D3D11_USAGE_DEFAULT = 0
+	_D3D11_USAGE_STAGING = 3
+
+	_D3D11_BIND_CONSTANT_BUFFER = 0x4
+	_D3D11_BIND_SHADER_RESOURCE = 0x8
+	_D3D11_BIND_RENDER_TARGET   = 0x20
+
+	_D3D11_CPU_ACCESS_READ = 0x20000
+
+	_D3D11_RESOURCE_MISC_GENERATE_MIPS = 0x1
+
+	_D3D11_MAP_READ = 1
+
+	_D3D11_QUERY_EVENT = 0
+
+	_D3D11_PRIMITIVE_TOPOLOGY_TRIANGLESTRIP = 5
+
+	_D3D11_FILL_SOLID = 3
+	_D3D11_CULL_NONE  = 1
+
+	_D3D11_FILTER_MIN_MAG_MIP_POINT  = 0
+	_D3D11_FILTER_MIN_MAG_MIP_LINEAR = 0x15
+
+	_D3D11_TEXTURE_ADDRESS_WRAP   = 1
+	_D3D11_TEXTURE_ADDRESS_MIRROR = 2
+	_D3D11_TEXTURE_ADDRESS_CLAMP  = 3
+
+	_D3D11_COMPARISON_NEVER = 1
+	_D3D11_FLOAT32_MAX      = 3.402823466e+38
+
+	_D3D11_BLEND_ZERO           = 1
+	_D3D11_BLEND_ONE            = 2
+	_D3D11_BLEND_SRC_COLOR      = 3
+	_D3D11_BLEND_INV_SRC_COLOR  = 4
+	_D3D11_BLEND_SRC_ALPHA      = 5
+	_D3D11_BLEND_INV_SRC_ALPHA  = 6
+	_D3D11_BLEND_DEST_ALPHA     = 7
+	_D3D11_BLEND_INV_DEST_ALPHA = 8
+	_D3D11_BLEND_DEST_COLOR     = 9
+
+	_D3D11_BLEND_OP_ADD = 1
+
+	_D3D11_COLOR_WRITE_ENABLE_ALL = 0xf
+
+	_D3DCOMPILE_OPTIMIZATION_LEVEL3 = 1 << 15
+
+	_DXGI_USAGE_RENDER_TARGET_OUTPUT = 0x20
+
+	_DXGI_SCALING_NONE = 1
+
+	_DXGI_SWAP_EFFECT_FLIP_SEQUENTIAL = 3
+	_DXGI_SWAP_EFFECT_FLIP_DISCARD    = 4
+
+	_DXGI_ALPHA_MODE_IGNORE = 3
+
+	_DXGI_MWA_NO_ALT_ENTER = 0x2
+
+	_DXGI_ERROR_DEVICE_REMOVED = 0x887a0005
+	_DXGI_ERROR_DEVICE_RESET   = 0x887a0007
+)
+
+type _DXGI_SAMPLE_DESC struct {
+	Count   uint32
+	Quality uint32
+}
+
+type _D3D11_TEXTURE2D_DESC struct {
+	Width          uint32
+	Height         uint32
+	MipLevels      uint32
+	ArraySize      uint32
+	Format         uint32
+	SampleDesc     _DXGI_SAMPLE_DESC
+	Usage          uint32
+	BindFlags      uint32
+	CPUAccessFlags uint32
+	MiscFlags      uint32
+}
+
+type _D3D11_BUFFER_DESC struct {
+	ByteWidth           uint32
+	Usage               uint32
+	BindFlags           uint32
+	CPUAccessFlags      uint32
+	MiscFlags           uint32
+	StructureByteStride uint32
+}
+
+type _D3D11_BOX struct {
+	Left   uint32
+	Top    uint32
+	Front  uint32
+	Right  uint32
+	Bottom uint32
+	Back   uint32
+}
+
+type _D3D11_MAPPED_SUBRESOURCE struct {
+	PData      unsafe.Pointer
+	RowPitch   uint32
+	DepthPitch uint32
+}
+
+type _D3D11_QUERY_DESC struct {
+	Query     uint32
+	MiscFlags uint32
+}
+
+type _D3D11_VIEWPORT struct {
+	TopLeftX float32
+	TopLeftY float32
+	Width    float32
+	Height   float32
+	MinDepth float32
+	MaxDepth float32
+}
+
+type _D3D11_RASTERIZER_DESC struct {
+	FillMode              uint32
+	CullMode              uint32
+	FrontCounterClockwise int32
+	DepthBias             int32
+	DepthBiasClamp        float32
+	SlopeScaledDepthBias  float32
+	DepthClipEnable       int32
+	ScissorEnable         int32
+	MultisampleEnable     int32
+	AntialiasedLineEnable int32
+}
+
+type _D3D11_SAMPLER_DESC struct {
+	Filter         uint32
+	AddressU       uint32
+	AddressV       uint32
+	AddressW       uint32
+	MipLODBias     float32
+	MaxAnisotropy  uint32
+	ComparisonFunc uint32
+	BorderColor    [4]float32
+	MinLOD         float32
+	MaxLOD         float32
+}
+
+type _D3D11_RENDER_TARGET_BLEND_DESC struct {
+	BlendEnable           int32
+	SrcBlend              uint32
+	DestBlend             uint32
+	BlendOp               uint32
+	SrcBlendAlpha         uint32
+	DestBlendAlpha        uint32
+	BlendOpAlpha          uint32
+	RenderTargetWriteMask uint8
+}
+
+type _D3D11_BLEND_DESC struct {
+	AlphaToCoverageEnable  int32
+	IndependentBlendEnable int32
+	RenderTarget           [8]_D3D11_RENDER_TARGET_BLEND_DESC
+}
+
+type _DXGI_SWAP_CHAIN_DESC1 struct {
+	Width       uint32
+	Height      uint32
+	Format      uint32
+	Stereo      int32
+	SampleDesc  _DXGI_SAMPLE_DESC
+	BufferUsage uint32
+	BufferCount uint32
+	Scaling     uint32
+	SwapEffect  uint32
+	AlphaMode   uint32
+	Flags       uint32
+}
+
+// createDevice calls D3D11CreateDevice, for a device of feature level 10.0
+// or later, returning the device and its immediate context.
+func createDevice(driverType uintptr) (device, context *comObject, err error) {
+	if err := procD3D11CreateDevice.Find(); err != nil {
+		return nil, nil, err
+	}
+	levels := [...]uint32{
+		_D3D_FEATURE_LEVEL_11_0,
+		_D3D_FEATURE_LEVEL_10_1,
+		_D3D_FEATURE_LEVEL_10_0,
+	}
+	hr, _, _ := procD3D11CreateDevice.Call(
+		0, driverType, 0, _D3D11_CREATE_DEVICE_BGRA_SUPPORT,
+		uintptr(unsafe.Pointer(&levels[0])), uintptr(len(levels)),
+		_D3D11_SDK_VERSION,
+		uintptr(unsafe.Pointer(&device)), 0, uintptr(unsafe.Pointer(&context)))
+	if failed(hr) {
+		return nil, nil, hrError("D3D11CreateDevice", hr)
+	}
+	return device, context, nil
+}
+
+// compile calls D3DCompile, returning a blob of the compiled shader.
+func compile(src, entryPoint, target string) (*comObject, error) {
+	if err := procD3DCompile.Find(); err != nil {
+		return nil, err
+	}
+	s := []byte(src)
+	e, err := syscall.BytePtrFromString(entryPoint)
+	if err != nil {
+		return nil, err
+	}
+	t, err := syscall.BytePtrFromString(target)
+	if err != nil {
+		return nil, err
+	}
+	var code, errs *comObject
+	hr, _, _ := procD3DCompile.Call(
+		uintptr(unsafe.Pointer(&s[0])), uintptr(len(s)), 0, 0, 0,
+		uintptr(unsafe.Pointer(e)), uintptr(unsafe.Pointer(t)),
+		_D3DCOMPILE_OPTIMIZATION_LEVEL3, 0,
+		uintptr(unsafe.Pointer(&code)), uintptr(unsafe.Pointer(&errs)))
+	errs.release()
+	if failed(hr) {
+		return nil, hrError("D3DCompile of "+entryPoint, hr)
+	}
+	return code, nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package d3ddriver provides a Direct3D 11 driver for accessing a screen on
+// Windows.
+//
+// Windows are managed as the windriver's are. Textures, and each window's
+// back buffer, are ID3D11Texture2Ds, drawn to by the GPU. Publish copies the
+// back buffer to a DXGI flip model swap chain, and presents it at the
+// display's next vertical blank. Unlike the gldriver, it does not depend on
+// the system having working OpenGL drivers, and it falls back to the WARP
+// software rasterizer if no Direct3D 11 hardware is available.
+//
+// It needs Windows 8 or later, for the flip model, and the D3DCompiler_47 DLL,
+// which ships with Windows 8.1 and later, to compile its shaders.
+//
+// Depth testing is not supported, and NewWindowOptions.DepthBits is ignored,
+// as is NewWindowOptions.Transparent.
+package d3ddriver // import "golang.org/x/exp/shiny/driver/d3ddriver"
+
+import (
+	"golang.org/x/exp/shiny/driver/internal/errscreen"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Main is called by the program's main function to run the graphical
+// application.
+//
+// It calls f on the Screen, possibly in a separate goroutine, as some OS-
+// specific libraries require being on 'the main thread'. It returns when f
+// returns.
+func Main(f func(screen.Screen)) {
+	if err := main(f); err != nil {
+		f(errscreen.Stub(err))
+	}
+}
+
+// Available returns whether the system can create a Direct3D 11 device, and
+// so whether Main can succeed.
+func Available() bool {
+	return available()
+}
@@ -0,0 +1,582 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package d3ddriver
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// The functions in this file may be called from any goroutine. The device is
+// free-threaded, but its immediate context is not, so every call on the
+// context is made while holding contextMu. If you need to hold a window's or
+// a texture's mu as well, the lock ordering is to lock contextMu last.
+
+var (
+	initOnce sync.Once
+	initErr  error
+
+	device  *comObject // An ID3D11Device.
+	factory *comObject // The device's IDXGIFactory2.
+
+	contextMu sync.Mutex
+	context   *comObject // The device's ID3D11DeviceContext.
+
+	// quadBuffer is the constant buffer of the quad being drawn, as
+	// returned by quad.Vertices, followed by the color that it is
+	// multiplied by.
+	quadBuffer *comObject
+
+	// pixelShaders are indexed by whether a texture is sampled, rather
+	// than a uniform color drawn.
+	pixelShaders [2]*comObject
+
+	// blendStates are indexed by the screen.BlendMode. BlendSrc's state does
+	// not enable blending.
+	blendStates [screen.NumBlendModes]*comObject
+
+	// samplers are indexed by whether a texture is sampled with nearest
+	// neighbor, rather than linear, filtering, and then by the
+	// screen.TextureWrap.
+	samplers [2][numWraps]*comObject
+
+	// finished is an event query, ended and waited for by finish.
+	finished *comObject
+)
+
+// numWraps is the number of screen.TextureWrap values.
+const numWraps = 3
+
+// addressModes are the address modes of each screen.TextureWrap, in the same
+// order.
+var addressModes = [numWraps]uint32{
+	_D3D11_TEXTURE_ADDRESS_CLAMP,  // WrapClamp
+	_D3D11_TEXTURE_ADDRESS_WRAP,   // WrapRepeat
+	_D3D11_TEXTURE_ADDRESS_MIRROR, // WrapMirror
+}
+
+// blendFactors are the source and destination blend factors of each
+// screen.BlendMode, in the same order, as documented there. The first,
+// BlendDefault, is never drawn with.
+var blendFactors = [screen.NumBlendModes][2]uint32{
+	{_D3D11_BLEND_ONE, _D3D11_BLEND_INV_SRC_ALPHA},            // BlendDefault
+	{_D3D11_BLEND_ZERO, _D3D11_BLEND_ZERO},                    // BlendClear
+	{_D3D11_BLEND_ONE, _D3D11_BLEND_ZERO},                     // BlendSrc
+	{_D3D11_BLEND_ZERO, _D3D11_BLEND_ONE},                     // BlendDst
+	{_D3D11_BLEND_ONE, _D3D11_BLEND_INV_SRC_ALPHA},            // BlendSrcOver
+	{_D3D11_BLEND_INV_DEST_ALPHA, _D3D11_BLEND_ONE},           // BlendDstOver
+	{_D3D11_BLEND_DEST_ALPHA, _D3D11_BLEND_ZERO},              // BlendSrcIn
+	{_D3D11_BLEND_ZERO, _D3D11_BLEND_SRC_ALPHA},               // BlendDstIn
+	{_D3D11_BLEND_INV_DEST_ALPHA, _D3D11_BLEND_ZERO},          // BlendSrcOut
+	{_D3D11_BLEND_ZERO, _D3D11_BLEND_INV_SRC_ALPHA},           // BlendDstOut
+	{_D3D11_BLEND_DEST_ALPHA, _D3D11_BLEND_INV_SRC_ALPHA},     // BlendSrcAtop
+	{_D3D11_BLEND_INV_DEST_ALPHA, _D3D11_BLEND_SRC_ALPHA},     // BlendDstAtop
+	{_D3D11_BLEND_INV_DEST_ALPHA, _D3D11_BLEND_INV_SRC_ALPHA}, // BlendXor
+	{_D3D11_BLEND_ONE, _D3D11_BLEND_ONE},                      // BlendAdd
+	{_D3D11_BLEND_DEST_COLOR, _D3D11_BLEND_INV_SRC_ALPHA},     // BlendMultiply
+	{_D3D11_BLEND_ONE, _D3D11_BLEND_INV_SRC_COLOR},            // BlendScreen
+}
+
+// alphaFactor returns the blend factor for the alpha channel that is
+// equivalent to f. Direct3D does not allow color factors there.
+func alphaFactor(f uint32) uint32 {
+	switch f {
+	case _D3D11_BLEND_SRC_COLOR:
+		return _D3D11_BLEND_SRC_ALPHA
+	case _D3D11_BLEND_INV_SRC_COLOR:
+		return _D3D11_BLEND_INV_SRC_ALPHA
+	case _D3D11_BLEND_DEST_COLOR:
+		return _D3D11_BLEND_DEST_ALPHA
+	}
+	return f
+}
+
+// shaderSource is compiled when the driver starts. The quad, four vertices
+// that are each a position, in normalized device co-ordinates, and a texture
+// co-ordinate, packed into a float4, is drawn as a triangle strip without
+// any vertex buffer.
+const shaderSource = `
+cbuffer Quad : register(b0) {
+	float4 vertices[4];
+	float4 color;
+};
+
+Texture2D tex : register(t0);
+SamplerState samp : register(s0);
+
+struct Vertex {
+	float4 position : SV_Position;
+	float2 texCoord : TEXCOORD0;
+};
+
+Vertex vertexShader(uint vid : SV_VertexID) {
+	Vertex v;
+	v.position = float4(vertices[vid].xy, 0, 1);
+	v.texCoord = vertices[vid].zw;
+	return v;
+}
+
+float4 fillShader(Vertex v) : SV_Target {
+	return color;
+}
+
+float4 textureShader(Vertex v) : SV_Target {
+	return tex.Sample(samp, v.texCoord) * color;
+}
+`
+
+func available() bool {
+	return d3dInit() == nil
+}
+
+// d3dInit creates the device, its shaders, and its pipeline states, the
+// first time that it is called. A hardware device is preferred, but WARP, a
+// software rasterizer, is used if the system has no Direct3D 11 capable GPU,
+// as in many virtual machines.
+func d3dInit() error {
+	initOnce.Do(func() { initErr = initDevice() })
+	return initErr
+}
+
+func initDevice() (err error) {
+	device, context, err = createDevice(_D3D_DRIVER_TYPE_HARDWARE)
+	if err != nil {
+		if device, context, err = createDevice(_D3D_DRIVER_TYPE_WARP); err != nil {
+			return err
+		}
+	}
+
+	dxgiDevice, err := device.queryInterface(&_IID_IDXGIDevice)
+	if err != nil {
+		return err
+	}
+	defer dxgiDevice.release()
+	var adapter *comObject
+	if hr := dxgiDevice.call(_IDXGIDevice_GetAdapter, uintptr(unsafe.Pointer(&adapter))); failed(hr) {
+		return hrError("IDXGIDevice::GetAdapter", hr)
+	}
+	defer adapter.release()
+	if hr := adapter.call(_IDXGIObject_GetParent, uintptr(unsafe.Pointer(&_IID_IDXGIFactory2)), uintptr(unsafe.Pointer(&factory))); failed(hr) {
+		// DXGI 1.2, and so the flip model, needs Windows 8 or later.
+		return hrError("IDXGIAdapter::GetParent", hr)
+	}
+
+	vs, err := compile(shaderSource, "vertexShader", "vs_4_0")
+	if err != nil {
+		return err
+	}
+	defer vs.release()
+	var vertexShader *comObject
+	if hr := device.call(_ID3D11Device_CreateVertexShader,
+		vs.call(_ID3DBlob_GetBufferPointer), vs.call(_ID3DBlob_GetBufferSize), 0,
+		uintptr(unsafe.Pointer(&vertexShader))); failed(hr) {
+		return hrError("CreateVertexShader", hr)
+	}
+	for i, entryPoint := range [2]string{"fillShader", "textureShader"} {
+		ps, err := compile(shaderSource, entryPoint, "ps_4_0")
+		if err != nil {
+			return err
+		}
+		hr := device.call(_ID3D11Device_CreatePixelShader,
+			ps.call(_ID3DBlob_GetBufferPointer), ps.call(_ID3DBlob_GetBufferSize), 0,
+			uintptr(unsafe.Pointer(&pixelShaders[i])))
+		ps.release()
+		if failed(hr) {
+			return hrError("CreatePixelShader", hr)
+		}
+	}
+
+	bd := _D3D11_BUFFER_DESC{
+		ByteWidth: 20 * 4,
+		Usage:     _D3D11_USAGE_DEFAULT,
+		BindFlags: _D3D11_BIND_CONSTANT_BUFFER,
+	}
+	if hr := device.call(_ID3D11Device_CreateBuffer, uintptr(unsafe.Pointer(&bd)), 0, uintptr(unsafe.Pointer(&quadBuffer))); failed(hr) {
+		return hrError("CreateBuffer", hr)
+	}
+
+	for mode := 1; mode < screen.NumBlendModes; mode++ {
+		var desc _D3D11_BLEND_DESC
+		rt := &desc.RenderTarget[0]
+		rt.RenderTargetWriteMask = _D3D11_COLOR_WRITE_ENABLE_ALL
+		if screen.BlendMode(mode) != screen.BlendSrc {
+			// Colors and textures are alpha-premultiplied.
+			f := blendFactors[mode]
+			rt.BlendEnable = 1
+			rt.SrcBlend, rt.DestBlend, rt.BlendOp = f[0], f[1], _D3D11_BLEND_OP_ADD
+			rt.SrcBlendAlpha, rt.DestBlendAlpha, rt.BlendOpAlpha = alphaFactor(f[0]), alphaFactor(f[1]), _D3D11_BLEND_OP_ADD
+		}
+		if hr := device.call(_ID3D11Device_CreateBlendState, uintptr(unsafe.Pointer(&desc)), uintptr(unsafe.Pointer(&blendStates[mode]))); failed(hr) {
+			return hrError("CreateBlendState", hr)
+		}
+	}
+
+	for n := 0; n < 2; n++ {
+		sd := _D3D11_SAMPLER_DESC{
+			// Textures without mipmaps have a single level, which the mip
+			// filter always picks.
+			Filter:         _D3D11_FILTER_MIN_MAG_MIP_LINEAR,
+			ComparisonFunc: _D3D11_COMPARISON_NEVER,
+			MaxLOD:         _D3D11_FLOAT32_MAX,
+		}
+		if n == 1 {
+			sd.Filter = _D3D11_FILTER_MIN_MAG_MIP_POINT
+		}
+		for w := 0; w < numWraps; w++ {
+			sd.AddressU, sd.AddressV, sd.AddressW = addressModes[w], addressModes[w], addressModes[w]
+			if hr := device.call(_ID3D11Device_CreateSamplerState, uintptr(unsafe.Pointer(&sd)), uintptr(unsafe.Pointer(&samplers[n][w]))); failed(hr) {
+				return hrError("CreateSamplerState", hr)
+			}
+		}
+	}
+
+	// The default rasterizer state culls back faces, but a quad's winding
+	// depends on its transform.
+	rd := _D3D11_RASTERIZER_DESC{
+		FillMode:        _D3D11_FILL_SOLID,
+		CullMode:        _D3D11_CULL_NONE,
+		DepthClipEnable: 1,
+	}
+	var rasterizerState *comObject
+	if hr := device.call(_ID3D11Device_CreateRasterizerState, uintptr(unsafe.Pointer(&rd)), uintptr(unsafe.Pointer(&rasterizerState))); failed(hr) {
+		return hrError("CreateRasterizerState", hr)
+	}
+
+	qd := _D3D11_QUERY_DESC{Query: _D3D11_QUERY_EVENT}
+	if hr := device.call(_ID3D11Device_CreateQuery, uintptr(unsafe.Pointer(&qd)), uintptr(unsafe.Pointer(&finished))); failed(hr) {
+		return hrError("CreateQuery", hr)
+	}
+
+	// This state is the same for every draw, and nothing resets it.
+	context.call(_ID3D11DeviceContext_IASetPrimitiveTopology, _D3D11_PRIMITIVE_TOPOLOGY_TRIANGLESTRIP)
+	context.call(_ID3D11DeviceContext_VSSetShader, uintptr(unsafe.Pointer(vertexShader)), 0, 0)
+	context.call(_ID3D11DeviceContext_VSSetConstantBuffers, 0, 1, uintptr(unsafe.Pointer(&quadBuffer)))
+	context.call(_ID3D11DeviceContext_PSSetConstantBuffers, 0, 1, uintptr(unsafe.Pointer(&quadBuffer)))
+	context.call(_ID3D11DeviceContext_RSSetState, uintptr(unsafe.Pointer(rasterizerState)))
+	return nil
+}
+
+// texture is an ID3D11Texture2D, of RGBA pixels, with views of it as a
+// shader resource and as a render target.
+type texture struct {
+	tex *comObject
+	srv *comObject
+	rtv *comObject
+}
+
+// newTexture returns a new texture, whose contents are transparent. If
+// mipmapped, it has a full chain of mipmap levels, which generateMipmaps
+// fills in.
+func newTexture(sz image.Point, mipmapped bool) (*texture, error) {
+	if sz.X <= 0 || sz.Y <= 0 {
+		return nil, errors.New("d3ddriver: texture size must be positive")
+	}
+	desc := _D3D11_TEXTURE2D_DESC{
+		Width:      uint32(sz.X),
+		Height:     uint32(sz.Y),
+		MipLevels:  1,
+		ArraySize:  1,
+		Format:     _DXGI_FORMAT_R8G8B8A8_UNORM,
+		SampleDesc: _DXGI_SAMPLE_DESC{Count: 1},
+		Usage:      _D3D11_USAGE_DEFAULT,
+		BindFlags:  _D3D11_BIND_SHADER_RESOURCE | _D3D11_BIND_RENDER_TARGET,
+	}
+	if mipmapped {
+		desc.MipLevels = 0
+		desc.MiscFlags = _D3D11_RESOURCE_MISC_GENERATE_MIPS
+	}
+	t := &texture{}
+	if hr := device.call(_ID3D11Device_CreateTexture2D, uintptr(unsafe.Pointer(&desc)), 0, uintptr(unsafe.Pointer(&t.tex))); failed(hr) {
+		return nil, hrError("CreateTexture2D", hr)
+	}
+	if hr := device.call(_ID3D11Device_CreateShaderResourceView, uintptr(unsafe.Pointer(t.tex)), 0, uintptr(unsafe.Pointer(&t.srv))); failed(hr) {
+		t.release()
+		return nil, hrError("CreateShaderResourceView", hr)
+	}
+	if hr := device.call(_ID3D11Device_CreateRenderTargetView, uintptr(unsafe.Pointer(t.tex)), 0, uintptr(unsafe.Pointer(&t.rtv))); failed(hr) {
+		t.release()
+		return nil, hrError("CreateRenderTargetView", hr)
+	}
+
+	// A new texture's contents are undefined. Clear them.
+	var transparent [4]float32
+	contextMu.Lock()
+	context.call(_ID3D11DeviceContext_ClearRenderTargetView, uintptr(unsafe.Pointer(t.rtv)), uintptr(unsafe.Pointer(&transparent)))
+	contextMu.Unlock()
+	return t, nil
+}
+
+// newCompressedTexture returns a new texture whose pixels are compressed in
+// the given DXGI format, with undefined contents. It cannot be rendered to,
+// and so has no render target view, and its sides must be multiples of 4.
+func newCompressedTexture(sz image.Point, format uint32) (*texture, error) {
+	if sz.X <= 0 || sz.Y <= 0 {
+		return nil, errors.New("d3ddriver: texture size must be positive")
+	}
+	if sz.X%4 != 0 || sz.Y%4 != 0 {
+		return nil, errors.New("d3ddriver: compressed texture size must be a multiple of 4")
+	}
+	desc := _D3D11_TEXTURE2D_DESC{
+		Width:      uint32(sz.X),
+		Height:     uint32(sz.Y),
+		MipLevels:  1,
+		ArraySize:  1,
+		Format:     format,
+		SampleDesc: _DXGI_SAMPLE_DESC{Count: 1},
+		Usage:      _D3D11_USAGE_DEFAULT,
+		BindFlags:  _D3D11_BIND_SHADER_RESOURCE,
+	}
+	t := &texture{}
+	if hr := device.call(_ID3D11Device_CreateTexture2D, uintptr(unsafe.Pointer(&desc)), 0, uintptr(unsafe.Pointer(&t.tex))); failed(hr) {
+		return nil, hrError("CreateTexture2D", hr)
+	}
+	if hr := device.call(_ID3D11Device_CreateShaderResourceView, uintptr(unsafe.Pointer(t.tex)), 0, uintptr(unsafe.Pointer(&t.srv))); failed(hr) {
+		t.release()
+		return nil, hrError("CreateShaderResourceView", hr)
+	}
+	return t, nil
+}
+
+func (t *texture) release() {
+	t.rtv.release()
+	t.srv.release()
+	t.tex.release()
+}
+
+// box returns the first level of a texture's region r.
+func box(r image.Rectangle) _D3D11_BOX {
+	return _D3D11_BOX{
+		Left:   uint32(r.Min.X),
+		Top:    uint32(r.Min.Y),
+		Front:  0,
+		Right:  uint32(r.Max.X),
+		Bottom: uint32(r.Max.Y),
+		Back:   1,
+	}
+}
+
+// uploadTexture copies pix, RGBA pixels with the given stride, to the
+// rectangle r of the texture t. For a compressed texture, pix is blocks, and
+// stride is the bytes per row of blocks. The pixels are copied before it returns, so
+// pix can be re-used straight away.
+func uploadTexture(t *texture, r image.Rectangle, pix []byte, stride int) {
+	b := box(r)
+	contextMu.Lock()
+	defer contextMu.Unlock()
+	context.call(_ID3D11DeviceContext_UpdateSubresource, uintptr(unsafe.Pointer(t.tex)), 0,
+		uintptr(unsafe.Pointer(&b)), uintptr(unsafe.Pointer(&pix[0])), uintptr(stride), 0)
+}
+
+// generateMipmaps regenerates the mipmap levels of the texture t from its
+// first level.
+func generateMipmaps(t *texture) {
+	contextMu.Lock()
+	defer contextMu.Unlock()
+	context.call(_ID3D11DeviceContext_GenerateMips, uintptr(unsafe.Pointer(t.srv)))
+}
+
+// copyTexture copies the top left sz pixels of src to dst.
+func copyTexture(dst, src *texture, sz image.Point) {
+	contextMu.Lock()
+	defer contextMu.Unlock()
+	copyRegion(dst.tex, src.tex, image.Rectangle{Max: sz})
+}
+
+// copyRegion copies the region r of the ID3D11Texture2D src to the top left
+// of dst. It must only be called while holding contextMu.
+func copyRegion(dst, src *comObject, r image.Rectangle) {
+	b := box(r)
+	context.call(_ID3D11DeviceContext_CopySubresourceRegion,
+		uintptr(unsafe.Pointer(dst)), 0, 0, 0, 0,
+		uintptr(unsafe.Pointer(src)), 0, uintptr(unsafe.Pointer(&b)))
+}
+
+// downloadTexture copies the rectangle r of the texture t to pix, as RGBA
+// pixels with the given stride, waiting for any pending drawing to finish.
+func downloadTexture(t *texture, r image.Rectangle, pix []byte, stride int) error {
+	desc := _D3D11_TEXTURE2D_DESC{
+		Width:          uint32(r.Dx()),
+		Height:         uint32(r.Dy()),
+		MipLevels:      1,
+		ArraySize:      1,
+		Format:         _DXGI_FORMAT_R8G8B8A8_UNORM,
+		SampleDesc:     _DXGI_SAMPLE_DESC{Count: 1},
+		Usage:          _D3D11_USAGE_STAGING,
+		CPUAccessFlags: _D3D11_CPU_ACCESS_READ,
+	}
+	var staging *comObject
+	if hr := device.call(_ID3D11Device_CreateTexture2D, uintptr(unsafe.Pointer(&desc)), 0, uintptr(unsafe.Pointer(&staging))); failed(hr) {
+		return hrError("CreateTexture2D", hr)
+	}
+	defer staging.release()
+
+	contextMu.Lock()
+	defer contextMu.Unlock()
+	copyRegion(staging, t.tex, r)
+	// Map waits for the copy, and so for any drawing before it.
+	var m _D3D11_MAPPED_SUBRESOURCE
+	if hr := context.call(_ID3D11DeviceContext_Map, uintptr(unsafe.Pointer(staging)), 0, _D3D11_MAP_READ, 0, uintptr(unsafe.Pointer(&m))); failed(hr) {
+		return hrError("Map", hr)
+	}
+	defer context.call(_ID3D11DeviceContext_Unmap, uintptr(unsafe.Pointer(staging)), 0)
+
+	const maxBytes = 1 << 30
+	rowBytes := 4 * r.Dx()
+	src := (*[maxBytes]byte)(m.PData)[: int(m.RowPitch)*(r.Dy()-1)+rowBytes : int(m.RowPitch)*(r.Dy()-1)+rowBytes]
+	for y := 0; y < r.Dy(); y++ {
+		copy(pix[y*stride:y*stride+rowBytes], src[y*int(m.RowPitch):])
+	}
+	return nil
+}
+
+// drawQuad draws the triangle strip v, as returned by quad.Vertices, onto dst,
+// a texture of size dstSize, blending as opts and op ask. If src is non-nil,
+// its texture is sampled, as its filter and wrap options ask. Otherwise, the
+// quad is filled with the color c.
+func drawQuad(dst *texture, dstSize image.Point, src *textureImpl, v [16]float32, c color.Color, op draw.Op, opts *screen.DrawOptions) {
+	// The constant buffer is the quad's vertices, followed by the color
+	// that the texture shader multiplies by, for transparency.
+	var cb [20]float32
+	copy(cb[:16], v[:])
+	k := float32(opts.GetAlpha()) / 0xffff
+	cb[16], cb[17], cb[18], cb[19] = k, k, k, k
+	if src == nil {
+		// The color is alpha-premultiplied, as the blend functions expect.
+		r, g, b, a := c.RGBA()
+		cb[16] = k * float32(r) / 0xffff
+		cb[17] = k * float32(g) / 0xffff
+		cb[18] = k * float32(b) / 0xffff
+		cb[19] = k * float32(a) / 0xffff
+	}
+	vp := _D3D11_VIEWPORT{
+		Width:    float32(dstSize.X),
+		Height:   float32(dstSize.Y),
+		MaxDepth: 1,
+	}
+
+	contextMu.Lock()
+	defer contextMu.Unlock()
+	context.call(_ID3D11DeviceContext_UpdateSubresource, uintptr(unsafe.Pointer(quadBuffer)), 0, 0,
+		uintptr(unsafe.Pointer(&cb[0])), 0, 0)
+	context.call(_ID3D11DeviceContext_OMSetRenderTargets, 1, uintptr(unsafe.Pointer(&dst.rtv)), 0)
+	context.call(_ID3D11DeviceContext_RSSetViewports, 1, uintptr(unsafe.Pointer(&vp)))
+	context.call(_ID3D11DeviceContext_OMSetBlendState, uintptr(unsafe.Pointer(blendStates[opts.GetBlend(op)])), 0, 0xffffffff)
+	if src != nil {
+		nearest := 0
+		if src.filter == screen.FilterNearest {
+			nearest = 1
+		}
+		context.call(_ID3D11DeviceContext_PSSetShader, uintptr(unsafe.Pointer(pixelShaders[1])), 0, 0)
+		context.call(_ID3D11DeviceContext_PSSetShaderResources, 0, 1, uintptr(unsafe.Pointer(&src.tex.srv)))
+		context.call(_ID3D11DeviceContext_PSSetSamplers, 0, 1, uintptr(unsafe.Pointer(&samplers[nearest][src.wrap])))
+	} else {
+		context.call(_ID3D11DeviceContext_PSSetShader, uintptr(unsafe.Pointer(pixelShaders[0])), 0, 0)
+	}
+	context.call(_ID3D11DeviceContext_Draw, 4, 0)
+
+	// Unbind dst and src, so that either can be bound the other way around
+	// by the next draw.
+	var none *comObject
+	context.call(_ID3D11DeviceContext_PSSetShaderResources, 0, 1, uintptr(unsafe.Pointer(&none)))
+	context.call(_ID3D11DeviceContext_OMSetRenderTargets, 0, 0, 0)
+}
+
+// newSwapChain returns a new flip model IDXGISwapChain1 for the window hwnd,
+// whose buffers are of size sz. It prefers DXGI_SWAP_EFFECT_FLIP_DISCARD,
+// which needs Windows 10, to DXGI_SWAP_EFFECT_FLIP_SEQUENTIAL.
+func newSwapChain(hwnd syscall.Handle, sz image.Point) (*comObject, error) {
+	desc := _DXGI_SWAP_CHAIN_DESC1{
+		Width:       uint32(sz.X),
+		Height:      uint32(sz.Y),
+		Format:      _DXGI_FORMAT_R8G8B8A8_UNORM,
+		SampleDesc:  _DXGI_SAMPLE_DESC{Count: 1},
+		BufferUsage: _DXGI_USAGE_RENDER_TARGET_OUTPUT,
+		BufferCount: 2,
+		// While the window is being resized, the last frame is shown
+		// unscaled, at the top left, until the next is presented, rather than
+		// stretched to fit.
+		Scaling:    _DXGI_SCALING_NONE,
+		SwapEffect: _DXGI_SWAP_EFFECT_FLIP_DISCARD,
+		AlphaMode:  _DXGI_ALPHA_MODE_IGNORE,
+	}
+	var sc *comObject
+	createSwapChain := func() uintptr {
+		return factory.call(_IDXGIFactory2_CreateSwapChainForHwnd, uintptr(unsafe.Pointer(device)), uintptr(hwnd),
+			uintptr(unsafe.Pointer(&desc)), 0, 0, uintptr(unsafe.Pointer(&sc)))
+	}
+	contextMu.Lock()
+	defer contextMu.Unlock()
+	hr := createSwapChain()
+	if failed(hr) {
+		desc.SwapEffect = _DXGI_SWAP_EFFECT_FLIP_SEQUENTIAL
+		hr = createSwapChain()
+	}
+	if failed(hr) {
+		return nil, hrError("CreateSwapChainForHwnd", hr)
+	}
+	// Alt+Enter would otherwise switch to exclusive fullscreen, which
+	// bypasses the window's fullscreen modes.
+	factory.call(_IDXGIFactory_MakeWindowAssociation, uintptr(hwnd), _DXGI_MWA_NO_ALT_ENTER)
+	return sc, nil
+}
+
+// resizeSwapChain resizes the buffers of the swap chain sc to sz.
+func resizeSwapChain(sc *comObject, sz image.Point) error {
+	contextMu.Lock()
+	defer contextMu.Unlock()
+	if hr := sc.call(_IDXGISwapChain_ResizeBuffers, 0, uintptr(sz.X), uintptr(sz.Y), 0, 0); failed(hr) {
+		return hrError("IDXGISwapChain::ResizeBuffers", hr)
+	}
+	return nil
+}
+
+// errDeviceLost is returned by present if the GPU was removed or reset, such
+// as by a driver update.
+var errDeviceLost = errors.New("d3ddriver: device lost")
+
+// present copies the top left sz pixels of the back buffer to the next
+// buffer of the swap chain sc, and presents it at the next vertical blank.
+// It blocks while the swap chain's queue of presented buffers is full, which
+// throttles Publish to the display's refresh rate.
+func present(sc *comObject, back *texture, sz image.Point) error {
+	contextMu.Lock()
+	defer contextMu.Unlock()
+
+	var buf *comObject
+	if hr := sc.call(_IDXGISwapChain_GetBuffer, 0, uintptr(unsafe.Pointer(&_IID_ID3D11Texture2D)), uintptr(unsafe.Pointer(&buf))); failed(hr) {
+		return hrError("IDXGISwapChain::GetBuffer", hr)
+	}
+	copyRegion(buf, back.tex, image.Rectangle{Max: sz})
+	buf.release()
+	switch hr := sc.call(_IDXGISwapChain_Present, 1, 0); {
+	case uint32(hr) == _DXGI_ERROR_DEVICE_REMOVED || uint32(hr) == _DXGI_ERROR_DEVICE_RESET:
+		return errDeviceLost
+	case failed(hr):
+		return hrError("IDXGISwapChain::Present", hr)
+	}
+	return nil
+}
+
+// finish waits for the GPU to finish executing every command issued so far.
+func finish() {
+	contextMu.Lock()
+	defer contextMu.Unlock()
+	context.call(_ID3D11DeviceContext_End, uintptr(unsafe.Pointer(finished)))
+	context.call(_ID3D11DeviceContext_Flush)
+	// GetData returns S_FALSE, 1, until the query's event happens.
+	for uint32(context.call(_ID3D11DeviceContext_GetData, uintptr(unsafe.Pointer(finished)), 0, 0, 0)) == 1 {
+		runtime.Gosched()
+	}
+}
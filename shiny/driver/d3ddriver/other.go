@@ -0,0 +1,20 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package d3ddriver
+
+import (
+	"fmt"
+	"runtime"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+func available() bool { return false }
+
+func main(f func(screen.Screen)) error {
+	return fmt.Errorf("d3ddriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,196 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package d3ddriver
+
+import (
+	"image"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/exp/shiny/driver/internal/pixpool"
+	"golang.org/x/exp/shiny/driver/internal/win32"
+	"golang.org/x/exp/shiny/screen"
+)
+
+var theScreen = &screenImpl{
+	windows: make(map[syscall.Handle]*windowImpl),
+}
+
+type screenImpl struct {
+	textureBytes atomic.Int64
+
+	// pixPool recycles the pixels of released Buffers.
+	pixPool pixpool.Pool
+
+	mu                   sync.Mutex
+	windows              map[syscall.Handle]*windowImpl
+	defaultWindowOptions *screen.NewWindowOptions
+}
+
+func main(f func(screen.Screen)) error {
+	if err := d3dInit(); err != nil {
+		return err
+	}
+	setEventHandlers()
+	return win32.Main(func() { f(theScreen) })
+}
+
+func (s *screenImpl) NewBuffer(size image.Point) (screen.Buffer, error) {
+	return &bufferImpl{
+		s:    s,
+		rgba: s.pixPool.NewRGBA(size),
+		size: size,
+	}, nil
+}
+
+func (s *screenImpl) NewTexture(size image.Point) (screen.Texture, error) {
+	return s.NewTextureWithOptions(size, nil)
+}
+
+// NewTextureWithOptions implements screen.Screen. The ID3D11Texture2D is
+// RGBA whatever the format, unless it is compressed.
+func (s *screenImpl) NewTextureWithOptions(size image.Point, opts *screen.NewTextureOptions) (screen.Texture, error) {
+	t := &textureImpl{
+		s:      s,
+		size:   size,
+		format: opts.GetFormat(),
+	}
+	if t.format == screen.PixelFormatBGRA8 {
+		t.format = screen.PixelFormatRGBA8
+	}
+	if opts != nil {
+		t.filter, t.mipmap, t.wrap = opts.Filter, opts.Mipmap, opts.Wrap
+		t.compressed = opts.Compressed
+	}
+	var (
+		tex *texture
+		err error
+	)
+	if t.compressed != screen.CompressedNone {
+		if int(t.compressed) >= len(dxgiCompressedFormats) || dxgiCompressedFormats[t.compressed] == 0 {
+			return nil, screen.ErrUnsupportedFormat
+		}
+		t.format, t.mipmap = screen.PixelFormatRGBA8, false
+		tex, err = newCompressedTexture(size, dxgiCompressedFormats[t.compressed])
+	} else {
+		tex, err = newTexture(size, t.mipmap)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.tex = tex
+	s.textureBytes.Add(t.bytes())
+	return t, nil
+}
+
+// dxgiCompressedFormats are the DXGI formats of each screen.CompressedFormat
+// that Direct3D supports. Every Direct3D 10 or later GPU supports the BC
+// formats, and none the ETC2 or ASTC ones.
+var dxgiCompressedFormats = [...]uint32{
+	screen.CompressedBC1: _DXGI_FORMAT_BC1_UNORM,
+	screen.CompressedBC3: _DXGI_FORMAT_BC3_UNORM,
+}
+
+// CompressedFormats implements screen.CompressedScreen.
+func (s *screenImpl) CompressedFormats() []screen.CompressedFormat {
+	return []screen.CompressedFormat{screen.CompressedBC1, screen.CompressedBC3}
+}
+
+func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) {
+	s.mu.Lock()
+	opts = opts.WithDefaults(s.defaultWindowOptions)
+	s.mu.Unlock()
+
+	w := &windowImpl{
+		s:      s,
+		hidden: opts != nil && opts.Hidden,
+	}
+	if opts != nil {
+		w.Priority = opts.EventPriority
+		w.KeepAllMoves = opts.KeepAllMoves
+		w.KeyEvents = opts.KeyEvents
+		w.Queues = opts.EventQueues
+		w.occlusion.Policy = opts.OccludedPublish
+
+		if opts.Transparent {
+			// A flip model swap chain cannot present to a layered window,
+			// whose contents are only set by UpdateLayeredWindow.
+			o := *opts
+			o.Transparent = false
+			opts = &o
+		}
+	}
+
+	var owner syscall.Handle
+	if opts != nil {
+		if o, ok := opts.Owner.(*windowImpl); ok {
+			owner = o.hwnd
+		}
+	}
+	var err error
+	w.hwnd, err = win32.NewWindow(opts, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.windows[w.hwnd] = w
+	s.mu.Unlock()
+
+	if err := win32.ResizeClientRect(w.hwnd, opts); err != nil {
+		w.Release()
+		return nil, err
+	}
+	if opts != nil && len(opts.Shape) > 0 {
+		if err := win32.SetShape(w.hwnd, opts.Shape); err != nil {
+			w.Release()
+			return nil, err
+		}
+	}
+
+	// Showing the window sends its first size.Event, even for a hidden
+	// window, upon which its back buffer and swap chain are created.
+	win32.Show(w.hwnd, opts)
+	if err := w.initErr(); err != nil {
+		w.Release()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *screenImpl) SetDefaultWindowOptions(opts *screen.NewWindowOptions) {
+	opts = opts.WithDefaults(nil)
+
+	s.mu.Lock()
+	s.defaultWindowOptions = opts
+	s.mu.Unlock()
+}
+
+func (s *screenImpl) TextureMemoryEstimate() int64 {
+	return s.textureBytes.Load()
+}
+
+func (*screenImpl) AccessibilityPrefs() screen.AccessibilityPrefs {
+	return win32.AccessibilityPrefs()
+}
+
+func (*screenImpl) ColorScheme() screen.ColorScheme {
+	return win32.ColorScheme()
+}
+
+func (*screenImpl) Clipboard() screen.Clipboard {
+	return win32.Clipboard{}
+}
+
+func (*screenImpl) Displays() []screen.Display {
+	return win32.Displays()
+}
+
+func (*screenImpl) NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return win32.NewTrayIcon(opts)
+}
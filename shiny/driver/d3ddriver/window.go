@@ -0,0 +1,711 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package d3ddriver
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/capture"
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/quad"
+	"golang.org/x/exp/shiny/driver/internal/win32"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/lifecycle"
+	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/paint"
+	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/event/touch"
+)
+
+type windowImpl struct {
+	s    *screenImpl
+	hwnd syscall.Handle
+
+	event.Deque
+
+	// hidden is whether the window is never shown, in which case its back
+	// buffer is never presented.
+	hidden bool
+
+	// sz and lifecycleStage are only accessed on the Windows message pump
+	// thread.
+	sz             size.Event
+	lifecycleStage lifecycle.Stage
+
+	imagePool drawer.ImagePool
+	layers    drawer.Layers
+	captures  capture.Hooks
+	timing    frame.Timing
+	occlusion frame.Occlusion
+
+	// mu protects released, which is whether Release has been called. It is
+	// held for reading while calling the win32 package, so that a concurrent
+	// Release waits for any in-flight call to finish before destroying the
+	// window.
+	mu       sync.RWMutex
+	released bool
+
+	// backMu guards the fields below. Unlike mu, it is locked on the Windows
+	// message pump thread, by sizeEvent, so it is never held while sending
+	// the window a message. If you need to hold both a windowImpl's backMu
+	// and a textureImpl's mu, the lock ordering is to lock the windowImpl's
+	// first (and unlock it last).
+	backMu sync.Mutex
+	// back is the back buffer, that the Drawer methods draw to. Publish
+	// copies it to the swap chain's next buffer. Both are created by the
+	// window's first size event, and swapChainErr is why that failed, if it
+	// did.
+	back          *texture
+	backSize      image.Point
+	swapChain     *comObject // An IDXGISwapChain1.
+	swapChainSize image.Point
+	swapChainErr  error
+	// closed is whether Release has released back and swapChain.
+	closed bool
+}
+
+func (w *windowImpl) Release() {
+	w.mu.Lock()
+	released := w.released
+	w.released = true
+	w.mu.Unlock()
+	if released {
+		return
+	}
+
+	w.imagePool.Release()
+	w.layers.Release()
+	w.StopTimers()
+
+	w.backMu.Lock()
+	w.closed = true
+	if w.back != nil {
+		w.back.release()
+		w.back = nil
+	}
+	w.swapChain.release()
+	w.swapChain = nil
+	w.backMu.Unlock()
+
+	s := w.s
+	s.mu.Lock()
+	delete(s.windows, w.hwnd)
+	s.mu.Unlock()
+
+	win32.Release(w.hwnd)
+}
+
+// initErr returns why the window's back buffer and swap chain could not be
+// created, if they could not.
+func (w *windowImpl) initErr() error {
+	w.backMu.Lock()
+	defer w.backMu.Unlock()
+	if w.swapChainErr == nil && w.swapChain == nil {
+		return errors.New("d3ddriver: window has no size")
+	}
+	return w.swapChainErr
+}
+
+func (w *windowImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	w.backMu.Lock()
+	defer w.backMu.Unlock()
+
+	if w.back == nil {
+		return
+	}
+	upload(w.back, w.backSize, dp, src, sr)
+}
+
+func (w *windowImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	w.backMu.Lock()
+	defer w.backMu.Unlock()
+
+	if w.back == nil {
+		return
+	}
+	fill(w.back, w.backSize, dr, src, op)
+}
+
+// Draw and DrawUniform sample the source texture as its filter and wrap
+// options ask, bilinearly and clamping to its edges by default. Depth testing
+// is not supported, but opts' blend mode and transparency are.
+
+func (w *windowImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	t := src.(*textureImpl)
+
+	w.backMu.Lock()
+	defer w.backMu.Unlock()
+
+	if w.back == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return
+	}
+	if t.wrap == screen.WrapClamp {
+		sr = sr.Intersect(t.Bounds())
+	}
+	drawQuad(w.back, w.backSize, t, quad.Vertices(src2dst, sr, t.size, w.backSize), nil, op, opts)
+}
+
+func (w *windowImpl) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	w.backMu.Lock()
+	defer w.backMu.Unlock()
+
+	if w.back == nil {
+		return
+	}
+	drawQuad(w.back, w.backSize, nil, quad.Vertices(src2dst, sr, image.Point{}, w.backSize), src, op, opts)
+}
+
+func (w *windowImpl) Copy(dp image.Point, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Copy(w, dp, src, sr, op, opts)
+}
+
+func (w *windowImpl) Scale(dr image.Rectangle, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Scale(w, dr, src, sr, op, opts)
+}
+
+func (w *windowImpl) DrawImage(dp image.Point, src image.Image, op draw.Op, opts *screen.DrawOptions) {
+	w.imagePool.DrawImage(w.s, w, dp, src, op, opts)
+}
+
+func (w *windowImpl) NewLayer(z int, size image.Point) (screen.Layer, error) {
+	return w.layers.NewLayer(w.s, z, size)
+}
+
+func (w *windowImpl) Publish() screen.PublishResult {
+	if !w.occlusion.Wait() {
+		return screen.PublishResult{Dropped: true}
+	}
+	composited := w.layers.Composite(w)
+
+	w.backMu.Lock()
+	if w.back == nil {
+		w.backMu.Unlock()
+		return screen.PublishResult{Dropped: true}
+	}
+	// The back buffer is copied, not flipped, to the front, so its contents
+	// are preserved. Compositing any layers overwrites the contents, though.
+	res := screen.PublishResult{BackBufferPreserved: !composited, Dropped: true}
+	if !w.hidden {
+		// The swap chain's buffers are resized on the Windows message pump
+		// thread, so they may not match the back buffer yet.
+		sz := image.Point{
+			X: min(w.backSize.X, w.swapChainSize.X),
+			Y: min(w.backSize.Y, w.swapChainSize.Y),
+		}
+		if err := present(w.swapChain, w.back, sz); err != nil {
+			// TODO: re-create the device, and every texture, when it is
+			// lost, and send a screen.DeviceLostEvent, as gldriver does.
+			log.Print(err)
+		} else {
+			// present throttles Publish to the display's refresh rate,
+			// so the frame is shown at about the next refresh.
+			res.Dropped = false
+			w.timing.Presented(&res, time.Now())
+		}
+	}
+	var captured *image.RGBA
+	if w.captures.Active() && w.backSize.X > 0 && w.backSize.Y > 0 {
+		captured = image.NewRGBA(image.Rectangle{Max: w.backSize})
+		// Textures are RGBA, so the pixels need no conversion.
+		if err := downloadTexture(w.back, captured.Rect, captured.Pix, captured.Stride); err != nil {
+			log.Print(err)
+			captured = nil
+		}
+	}
+	w.backMu.Unlock()
+
+	w.captures.Send(captured)
+	return res
+}
+
+func (w *windowImpl) AddCaptureHook(fn func(f screen.CapturedFrame)) (remove func()) {
+	return w.captures.Add(fn)
+}
+
+func (w *windowImpl) RenderFrame(fn func(d screen.Drawer)) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	fn(w)
+	finish()
+	return nil
+}
+
+// NextFrame sends a screen.FrameEvent when the Desktop Window Manager next
+// composes the window.
+func (w *windowImpl) NextFrame() {
+	if !w.isReleased() {
+		win32.NextFrame(w)
+	}
+}
+
+var errReleased = errors.New("d3ddriver: window is released")
+
+func (w *windowImpl) Begin() *screen.Context { return screen.NewContext(w) }
+
+func (w *windowImpl) GLInfo() screen.GLInfo { return screen.GLInfo{} }
+
+func (w *windowImpl) isReleased() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.released
+}
+
+func (w *windowImpl) SetTitle(title string) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetTitle(w.hwnd, title)
+	}
+}
+
+func (w *windowImpl) SetIcon(m image.Image) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetIcon(w.hwnd, m)
+	}
+}
+
+func (w *windowImpl) SetBadge(label string) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetBadge(w.hwnd, label)
+	}
+}
+
+func (w *windowImpl) SetProgress(progress float64) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetProgress(w.hwnd, progress)
+	}
+}
+
+func (w *windowImpl) SetSize(width, height int) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released && width > 0 && height > 0 {
+		win32.SetSize(w.hwnd, width, height)
+	}
+}
+
+func (w *windowImpl) SetPosition(p image.Point) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetPosition(w.hwnd, p)
+	}
+}
+
+func (w *windowImpl) GetGeometry() (image.Point, image.Point) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return image.Point{}, image.Point{}
+	}
+	pos, sz, _ := win32.Geometry(w.hwnd)
+	return pos, sz
+}
+
+func (w *windowImpl) SetSizeConstraints(min, max image.Point) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetSizeConstraints(w.hwnd, min, max)
+	}
+}
+
+func (w *windowImpl) SetAspectRatio(aspect image.Point) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetAspectRatio(w.hwnd, aspect)
+	}
+}
+
+func (w *windowImpl) SetTextInputRect(r image.Rectangle) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetTextInputRect(w.hwnd, r)
+	}
+}
+
+func (w *windowImpl) SetCursor(c screen.Cursor) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetCursor(w.hwnd, c)
+	}
+}
+
+func (w *windowImpl) SetCursorVisible(visible bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetCursorVisible(w.hwnd, visible)
+	}
+}
+
+func (w *windowImpl) SetPointerCapture(capture bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetPointerCapture(w.hwnd, capture)
+	}
+}
+
+func (w *windowImpl) StartDrag(data screen.DragData) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.StartDrag(w.hwnd, data)
+}
+
+func (w *windowImpl) StartMove() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	win32.StartMove(w.hwnd)
+	return nil
+}
+
+func (w *windowImpl) StartResize(edge screen.WindowEdge) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	win32.StartResize(w.hwnd, edge)
+	return nil
+}
+
+func (w *windowImpl) ShowFileDialog(opts *screen.FileDialogOptions) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.ShowFileDialog(w.hwnd, opts)
+}
+
+func (w *windowImpl) SetMenuBar(bar *screen.Menu) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.SetMenuBar(w.hwnd, bar)
+}
+
+func (w *windowImpl) ShowContextMenu(m *screen.Menu, p image.Point) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.ShowContextMenu(w.hwnd, m, p)
+}
+
+func (w *windowImpl) SetAccessTree(root *screen.AccessNode) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.SetAccessTree(w.hwnd, root)
+}
+
+func (w *windowImpl) RegisterHotKey(k key.Code, mods key.Modifiers) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.RegisterHotKey(w.hwnd, k, mods)
+}
+
+func (w *windowImpl) UnregisterHotKey(k key.Code, mods key.Modifiers) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.UnregisterHotKey(w.hwnd, k, mods)
+	}
+}
+
+func (w *windowImpl) Notify(n *screen.Notification) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.Notify(w.hwnd, n)
+}
+
+func (w *windowImpl) CloseNotification(id int) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.CloseNotification(w.hwnd, id)
+	}
+}
+
+func (w *windowImpl) Minimize() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.Minimize(w.hwnd)
+	}
+}
+
+func (w *windowImpl) Maximize() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.Maximize(w.hwnd)
+	}
+}
+
+func (w *windowImpl) Restore() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.Restore(w.hwnd)
+	}
+}
+
+func (w *windowImpl) SetFullscreen(mode screen.FullscreenMode) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetFullscreen(w.hwnd, mode)
+	}
+}
+
+func (w *windowImpl) SetAlwaysOnTop(onTop bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetAlwaysOnTop(w.hwnd, onTop)
+	}
+}
+
+func (w *windowImpl) SetOpacity(opacity float32) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetOpacity(w.hwnd, win32.Opacity(opacity))
+	}
+}
+
+func (w *windowImpl) Raise() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.Raise(w.hwnd)
+	}
+}
+
+func (w *windowImpl) Lower() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.Lower(w.hwnd)
+	}
+}
+
+func (w *windowImpl) ColorSpace() screen.ColorSpace { return screen.ColorSpaceSRGB }
+
+// SetColorSpace only accepts screen.ColorSpaceSRGB.
+//
+// TODO: support other color spaces, with IDXGISwapChain3.SetColorSpace1.
+func (w *windowImpl) SetColorSpace(cs screen.ColorSpace) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	if cs != screen.ColorSpaceSRGB {
+		return errors.New("d3ddriver: color spaces other than sRGB are not supported")
+	}
+	return nil
+}
+
+// resize creates the back buffer and swap chain, for the window's first size
+// event, and otherwise replaces the back buffer with one of the new size,
+// keeping as much of the old contents as fit, and resizes the swap chain's
+// buffers. It is called on the Windows message pump thread.
+func (w *windowImpl) resize(sz size.Event) {
+	newSize := image.Point{sz.WidthPx, sz.HeightPx}
+
+	w.backMu.Lock()
+	defer w.backMu.Unlock()
+
+	if w.closed {
+		return
+	}
+	if newSize.X < 1 || newSize.Y < 1 {
+		// Textures cannot be empty, but a minimized window is. Keep its
+		// back buffer, for when it is restored.
+		if w.back != nil {
+			return
+		}
+		newSize = image.Point{1, 1}
+	}
+	if w.back == nil {
+		back, err := newTexture(newSize, false)
+		if err != nil {
+			w.swapChainErr = err
+			return
+		}
+		sc, err := newSwapChain(w.hwnd, newSize)
+		if err != nil {
+			back.release()
+			w.swapChainErr = err
+			return
+		}
+		w.back, w.backSize = back, newSize
+		w.swapChain, w.swapChainSize = sc, newSize
+		return
+	}
+
+	if newSize != w.backSize {
+		if back, err := newTexture(newSize, false); err != nil {
+			// Keep drawing to the old back buffer, which Publish clips to
+			// the swap chain's size.
+			log.Print(err)
+		} else {
+			copyTexture(back, w.back, image.Point{
+				X: min(newSize.X, w.backSize.X),
+				Y: min(newSize.Y, w.backSize.Y),
+			})
+			w.back.release()
+			w.back, w.backSize = back, newSize
+		}
+	}
+	if newSize != w.swapChainSize {
+		if err := resizeSwapChain(w.swapChain, newSize); err != nil {
+			log.Print(err)
+		} else {
+			w.swapChainSize = newSize
+		}
+	}
+}
+
+// setEventHandlers routes the win32 package's events to the windows. It is
+// called by Main, rather than by an init function, so that it replaces
+// those that the windriver's init function sets, if that driver is also
+// linked in.
+func setEventHandlers() {
+	sendAt := func(hwnd syscall.Handle, e interface{}, t time.Time) {
+		if w := lookup(hwnd); w != nil {
+			w.SendAt(e, t)
+		}
+	}
+	send := func(hwnd syscall.Handle, e interface{}) { sendAt(hwnd, e, time.Now()) }
+	// sendInput sends an input event at the time of its message.
+	sendInput := func(hwnd syscall.Handle, e interface{}) { sendAt(hwnd, e, win32.MessageTime()) }
+	win32.MouseEvent = func(hwnd syscall.Handle, e mouse.Event) { sendInput(hwnd, e) }
+	win32.PaintEvent = func(hwnd syscall.Handle, e paint.Event) { send(hwnd, e) }
+	win32.KeyEvent = func(hwnd syscall.Handle, e screen.KeyEvent) {
+		sendInput(hwnd, e)
+	}
+	win32.LifecycleEvent = lifecycleEvent
+	win32.SizeEvent = sizeEvent
+	win32.AccessibilityEvent = func(hwnd syscall.Handle, e screen.AccessibilityEvent) { send(hwnd, e) }
+	win32.ColorSchemeEvent = func(hwnd syscall.Handle, e screen.ColorSchemeEvent) { send(hwnd, e) }
+	win32.DisplayEvent = func(hwnd syscall.Handle, e screen.DisplayEvent) { send(hwnd, e) }
+	win32.ScaleEvent = func(hwnd syscall.Handle, e screen.ScaleEvent) { send(hwnd, e) }
+	win32.TextEvent = func(hwnd syscall.Handle, e screen.TextEvent) { sendInput(hwnd, e) }
+	win32.RelativeMouseEvent = func(hwnd syscall.Handle, e screen.RelativeMouseEvent) { sendInput(hwnd, e) }
+	win32.DragEvent = func(hwnd syscall.Handle, e screen.DragEvent) { send(hwnd, e) }
+	win32.WindowStateEvent = func(hwnd syscall.Handle, e screen.WindowStateEvent) { send(hwnd, e) }
+	win32.VisibilityEvent = func(hwnd syscall.Handle, e screen.VisibilityEvent) {
+		if w := lookup(hwnd); w != nil {
+			w.occlusion.Set(e.Visibility)
+			w.Send(e)
+		}
+	}
+	win32.CloseRequestEvent = func(hwnd syscall.Handle, e screen.CloseRequestEvent) { send(hwnd, e) }
+	win32.FileDialogEvent = func(hwnd syscall.Handle, e screen.FileDialogEvent) { send(hwnd, e) }
+	win32.MenuEvent = func(hwnd syscall.Handle, e screen.MenuEvent) { send(hwnd, e) }
+	win32.AccessActionEvent = func(hwnd syscall.Handle, e screen.AccessActionEvent) { send(hwnd, e) }
+	win32.ScrollEvent = func(hwnd syscall.Handle, e screen.ScrollEvent) { sendInput(hwnd, e) }
+	win32.TouchEvent = func(hwnd syscall.Handle, e touch.Event) { sendInput(hwnd, e) }
+	win32.PenEvent = func(hwnd syscall.Handle, e screen.PenEvent) { sendInput(hwnd, e) }
+	win32.CrossingEvent = func(hwnd syscall.Handle, e screen.CrossingEvent) { sendInput(hwnd, e) }
+	win32.FocusEvent = func(hwnd syscall.Handle, e screen.FocusEvent) { send(hwnd, e) }
+	win32.HotKeyEvent = func(hwnd syscall.Handle, e screen.HotKeyEvent) { send(hwnd, e) }
+	win32.KeyboardLayoutEvent = func(hwnd syscall.Handle, e screen.KeyboardLayoutEvent) { send(hwnd, e) }
+	win32.NotificationEvent = func(hwnd syscall.Handle, e screen.NotificationEvent) { send(hwnd, e) }
+}
+
+// lookup returns the window of hwnd, or nil if it was released, such as
+// while a menu or dialog was open.
+func lookup(hwnd syscall.Handle) *windowImpl {
+	theScreen.mu.Lock()
+	defer theScreen.mu.Unlock()
+	return theScreen.windows[hwnd]
+}
+
+func lifecycleEvent(hwnd syscall.Handle, to lifecycle.Stage) {
+	w := lookup(hwnd)
+	if w == nil || w.lifecycleStage == to {
+		return
+	}
+	w.Send(lifecycle.Event{
+		From: w.lifecycleStage,
+		To:   to,
+	})
+	w.lifecycleStage = to
+}
+
+// sizeEvent resizes the window's back buffer and swap chain, and then sends
+// e, followed by a paint.Event if the size changed, as the window's new
+// contents are undefined until it is next published.
+func sizeEvent(hwnd syscall.Handle, e size.Event) {
+	w := lookup(hwnd)
+	if w == nil {
+		return
+	}
+	w.resize(e)
+	w.Send(e)
+
+	if e != w.sz {
+		w.sz = e
+		w.Send(paint.Event{External: true})
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
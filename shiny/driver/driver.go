@@ -3,6 +3,15 @@
 // license that can be found in the LICENSE file.
 
 // Package driver provides the default driver for accessing a screen.
+//
+// The drivers that a program can use on its system are listed by Drivers.
+// MainWith runs a program with the first available driver of a given list,
+// and Main with the first of those named by the SHINY_DRIVER environment
+// variable, a comma-separated list of driver names such as "wayland,x11", or
+// with the system's default driver if that variable is empty.
+//
+// The headlessdriver and the vncdriver can only be named by programs built
+// with the headless build tag, so that other programs do not link them.
 package driver // import "golang.org/x/exp/shiny/driver"
 
 // TODO: figure out what to say about the responsibility for users of this
@@ -11,15 +20,116 @@ package driver // import "golang.org/x/exp/shiny/driver"
 // or OpenGL library.
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/exp/shiny/driver/internal/errscreen"
 	"golang.org/x/exp/shiny/screen"
 )
 
+// Capabilities are the features that a driver supports.
+type Capabilities struct {
+	// GPUTextures is whether Textures are held and drawn by the GPU, rather
+	// than in software.
+	GPUTextures bool
+
+	// VSync is whether FrameEvents are paced by the display's vertical
+	// blank, rather than by a timer.
+	VSync bool
+
+	// Transparency is whether the driver honors NewWindowOptions.Transparent.
+	Transparency bool
+
+	// Multitouch is whether the driver sends touch.Events, for each of
+	// several simultaneous touches.
+	Multitouch bool
+}
+
+// Driver describes a driver for accessing a screen.
+type Driver struct {
+	// Name is the driver's name, as given to MainWith and in the SHINY_DRIVER
+	// environment variable, such as "x11" for the x11driver.
+	Name string
+
+	// Capabilities are the features that the driver supports on this
+	// system.
+	Capabilities Capabilities
+
+	available func() bool
+	main      func(f func(screen.Screen))
+}
+
+// Available returns whether the driver can be used, such as whether there is
+// a display server for it to connect to. It is a quick check, so a driver
+// that is available can still fail, once it is run, to connect to that
+// server.
+func (d Driver) Available() bool {
+	return d.available == nil || d.available()
+}
+
+// errNoDriver is the error of the Screen that Main uses if the system has no
+// default driver.
+var errNoDriver = errors.New("no driver for accessing a screen")
+
+// Drivers returns the drivers that a program can use on this system, whether
+// or not they are available, in order of preference, ending with the
+// headlessdriver and the vncdriver if the program was built with the headless
+// build tag.
+func Drivers() []Driver {
+	return append(append([]Driver(nil), drivers...), headless...)
+}
+
 // Main is called by the program's main function to run the graphical
 // application.
 //
 // It calls f on the Screen, possibly in a separate goroutine, as some OS-
 // specific libraries require being on 'the main thread'. It returns when f
 // returns.
+//
+// If the SHINY_DRIVER environment variable is not empty, Main is like
+// MainWith, given the driver names that the variable lists. Otherwise, it uses
+// the first available of the system's default drivers.
 func Main(f func(screen.Screen)) {
-	main(f)
+	if v := os.Getenv("SHINY_DRIVER"); v != "" {
+		MainWith(f, strings.Split(v, ",")...)
+		return
+	}
+	run(f, drivers, errNoDriver)
+}
+
+// MainWith is like Main, but uses the first available driver of those with
+// the given names, in order, such as "x11" and then "headless". Unknown names
+// are ignored. If none of the named drivers is available, it uses the last of
+// them anyway, which calls f on a Screen whose methods return why it failed.
+func MainWith(f func(screen.Screen), names ...string) {
+	var ds []Driver
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		for _, d := range Drivers() {
+			if d.Name == name {
+				ds = append(ds, d)
+				break
+			}
+		}
+	}
+	run(f, ds, fmt.Errorf("driver: no driver named %q", names))
+}
+
+// run runs f with the first available driver of ds, or with the last of them
+// if none is available. If ds is empty, it calls f on a Screen whose methods
+// return err.
+func run(f func(screen.Screen), ds []Driver, err error) {
+	if len(ds) == 0 {
+		f(errscreen.Stub(err))
+		return
+	}
+	for _, d := range ds {
+		if d.Available() {
+			d.main(f)
+			return
+		}
+	}
+	ds[len(ds)-1].main(f)
 }
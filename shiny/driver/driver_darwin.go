@@ -2,15 +2,31 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build darwin
+// +build darwin,!ios
 
 package driver
 
 import (
 	"golang.org/x/exp/shiny/driver/gldriver"
-	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/driver/mtldriver"
 )
 
-func main(f func(screen.Screen)) {
-	gldriver.Main(f)
-}
+// OpenGL is deprecated on macOS. Prefer Metal, if the system has it.
+var drivers = []Driver{{
+	Name: "metal",
+	Capabilities: Capabilities{
+		GPUTextures:  true,
+		VSync:        true,
+		Transparency: true,
+	},
+	available: mtldriver.Available,
+	main:      mtldriver.Main,
+}, {
+	Name: "gl",
+	Capabilities: Capabilities{
+		GPUTextures:  true,
+		VSync:        true,
+		Transparency: true,
+	},
+	main: gldriver.Main,
+}}
@@ -3,20 +3,14 @@
 // license that can be found in the LICENSE file.
 
 // +build !darwin
-// +build !linux android
+// +build !linux
 // +build !windows
 // +build !dragonfly
 // +build !openbsd
+// +build !js !wasm
 
 package driver
 
-import (
-	"errors"
-
-	"golang.org/x/exp/shiny/driver/internal/errscreen"
-	"golang.org/x/exp/shiny/screen"
-)
-
-func main(f func(screen.Screen)) {
-	f(errscreen.Stub(errors.New("no driver for accessing a screen")))
-}
+// drivers is empty, as there is no driver for accessing a screen on this
+// system, other than the headlessdriver and the vncdriver.
+var drivers []Driver
@@ -0,0 +1,23 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build headless
+
+package driver
+
+import (
+	"golang.org/x/exp/shiny/driver/headlessdriver"
+	"golang.org/x/exp/shiny/driver/vncdriver"
+)
+
+// headless holds the drivers that need no display: the headlessdriver, and
+// the vncdriver, which is built on it. They are never the default, but can be
+// named, such as to run a program in a system without a display.
+var headless = []Driver{{
+	Name: "headless",
+	main: headlessdriver.Main,
+}, {
+	Name: "vnc",
+	main: vncdriver.Main,
+}}
@@ -0,0 +1,21 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build js,wasm
+
+package driver
+
+import (
+	"golang.org/x/exp/shiny/driver/wasmdriver"
+)
+
+var drivers = []Driver{{
+	Name: "wasm",
+	Capabilities: Capabilities{
+		VSync:        true,
+		Transparency: true,
+		Multitouch:   true,
+	},
+	main: wasmdriver.Main,
+}}
@@ -0,0 +1,38 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package driver
+
+import (
+	"os"
+
+	"golang.org/x/exp/shiny/driver/waylanddriver"
+	"golang.org/x/exp/shiny/driver/x11driver"
+)
+
+// drivers prefers the Wayland driver if there is a Wayland compositor, as
+// named by the WAYLAND_DISPLAY environment variable, and the X11 driver
+// otherwise.
+//
+// The gldriver is not listed, as it needs cgo on Linux. Programs that insist
+// on OpenGL should call gldriver.Main directly.
+var drivers = []Driver{{
+	Name: "wayland",
+	Capabilities: Capabilities{
+		VSync:        true,
+		Transparency: true,
+		Multitouch:   true,
+	},
+	available: func() bool { return os.Getenv("WAYLAND_DISPLAY") != "" },
+	main:      waylanddriver.Main,
+}, {
+	Name: "x11",
+	Capabilities: Capabilities{
+		Transparency: true,
+	},
+	available: func() bool { return os.Getenv("DISPLAY") != "" },
+	main:      x11driver.Main,
+}}
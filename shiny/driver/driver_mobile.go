@@ -0,0 +1,20 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build android ios
+
+package driver
+
+import (
+	"golang.org/x/exp/shiny/driver/mobiledriver"
+)
+
+var drivers = []Driver{{
+	Name: "mobile",
+	Capabilities: Capabilities{
+		VSync:      true,
+		Multitouch: true,
+	},
+	main: mobiledriver.Main,
+}}
@@ -0,0 +1,12 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !headless
+
+package driver
+
+// headless is empty, so that programs do not link the headlessdriver, nor the
+// vncdriver that is built on it, unless they are built with the headless
+// build tag.
+var headless []Driver
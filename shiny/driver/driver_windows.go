@@ -5,10 +5,27 @@
 package driver
 
 import (
+	"golang.org/x/exp/shiny/driver/d3ddriver"
 	"golang.org/x/exp/shiny/driver/windriver"
-	"golang.org/x/exp/shiny/screen"
 )
 
-func main(f func(screen.Screen)) {
-	windriver.Main(f)
-}
+// drivers prefers the windriver, which only needs GDI, to the newer
+// d3ddriver, which needs Windows 8 or later.
+var drivers = []Driver{{
+	Name: "windows",
+	Capabilities: Capabilities{
+		VSync:        true,
+		Transparency: true,
+		Multitouch:   true,
+	},
+	main: windriver.Main,
+}, {
+	Name: "d3d",
+	Capabilities: Capabilities{
+		GPUTextures: true,
+		VSync:       true,
+		Multitouch:  true,
+	},
+	available: d3ddriver.Available,
+	main:      d3ddriver.Main,
+}}
@@ -2,15 +2,21 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build linux,!android dragonfly openbsd
+// +build dragonfly openbsd
 
 package driver
 
 import (
+	"os"
+
 	"golang.org/x/exp/shiny/driver/x11driver"
-	"golang.org/x/exp/shiny/screen"
 )
 
-func main(f func(screen.Screen)) {
-	x11driver.Main(f)
-}
+var drivers = []Driver{{
+	Name: "x11",
+	Capabilities: Capabilities{
+		Transparency: true,
+	},
+	available: func() bool { return os.Getenv("DISPLAY") != "" },
+	main:      x11driver.Main,
+}}
@@ -0,0 +1,124 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gldriver
+
+import (
+	"encoding/binary"
+	"image/draw"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/gl"
+)
+
+// DrawBatch implements screen.BatchDrawer.
+//
+// Unlike Draw, which sets a quad's transformation and texture coordinates as
+// uniforms, it transforms every quad's corners on the CPU, uploads them all
+// as one vertex buffer, of two triangles per quad, and draws them with a
+// single draw call.
+func (w *windowImpl) DrawBatch(src screen.Texture, quads []screen.Quad, op draw.Op, opts *screen.DrawOptions) {
+	if t, ok := src.(*tiledTexture); ok {
+		t.drawBatchOn(w, quads, op, opts)
+		return
+	}
+	t := src.(*textureImpl)
+	// The texture belongs to the share context, and is guarded by its mutex.
+	w.s.shareMu.Lock()
+	id := t.id
+	w.s.shareMu.Unlock()
+	if id == (gl.Texture{}) {
+		return // Released.
+	}
+
+	// Each vertex is its x and y in pixel space, then its u and v in
+	// texture space.
+	verts := make([]float32, 0, 24*len(quads))
+	tw, th := float64(t.size.X), float64(t.size.Y)
+	for _, q := range quads {
+		sr := q.SR
+		if t.wrap == screen.WrapClamp {
+			sr = sr.Intersect(t.Bounds())
+		}
+		if sr.Empty() {
+			continue
+		}
+		m := &q.Src2Dst
+		vertex := func(x, y int) {
+			sx, sy := float64(x), float64(y)
+			verts = append(verts,
+				float32(m[0]*sx+m[1]*sy+m[2]),
+				float32(m[3]*sx+m[4]*sy+m[5]),
+				float32(sx/tw),
+				float32(sy/th),
+			)
+		}
+		vertex(sr.Min.X, sr.Min.Y)
+		vertex(sr.Max.X, sr.Min.Y)
+		vertex(sr.Min.X, sr.Max.Y)
+		vertex(sr.Min.X, sr.Max.Y)
+		vertex(sr.Max.X, sr.Min.Y)
+		vertex(sr.Max.X, sr.Max.Y)
+	}
+	if len(verts) == 0 {
+		return
+	}
+
+	w.glctxMu.Lock()
+	defer w.glctxMu.Unlock()
+
+	if w.released {
+		return
+	}
+	if !w.backBufferBound {
+		w.bindBackBuffer()
+	}
+
+	z := w.useDepth(opts)
+	if !w.useClip() {
+		return
+	}
+	w.szMu.Lock()
+	sz := w.sz
+	w.szMu.Unlock()
+
+	glctx := w.glctx
+	useBlend(glctx, op, opts)
+	w.progs.useBatch(glctx)
+	glctx.Uniform2f(w.progs.batch.size, float32(sz.WidthPx), float32(sz.HeightPx))
+	glctx.Uniform1f(w.progs.batch.depth, z)
+	glctx.Uniform1f(w.progs.batch.alpha, float32(opts.GetAlpha())/0xffff)
+
+	glctx.ActiveTexture(gl.TEXTURE0)
+	glctx.BindTexture(gl.TEXTURE_2D, id)
+	glctx.Uniform1i(w.progs.batch.sample, 0)
+
+	glctx.BindBuffer(gl.ARRAY_BUFFER, w.progs.batch.verts)
+	glctx.BufferData(gl.ARRAY_BUFFER, f32Bytes(binary.LittleEndian, verts...), gl.STREAM_DRAW)
+	glctx.EnableVertexAttribArray(w.progs.batch.pos)
+	glctx.VertexAttribPointer(w.progs.batch.pos, 2, gl.FLOAT, false, 16, 0)
+	glctx.EnableVertexAttribArray(w.progs.batch.inUV)
+	glctx.VertexAttribPointer(w.progs.batch.inUV, 2, gl.FLOAT, false, 16, 8)
+
+	glctx.DrawArrays(gl.TRIANGLES, 0, len(verts)/4)
+
+	glctx.DisableVertexAttribArray(w.progs.batch.pos)
+	glctx.DisableVertexAttribArray(w.progs.batch.inUV)
+}
+
+// The batch vertex shader maps from pixel space, where the window ranges from
+// (0, 0) to size and the Y-axis points downwards, to clip space. Its texture
+// coordinates are already normalized.
+const batchVertexSrc = `#version 100
+uniform vec2 size;
+uniform float depth;
+attribute vec2 pos;
+attribute vec2 inUV;
+varying vec2 uv;
+void main() {
+	vec2 p = pos / size;
+	gl_Position = vec4(2.0*p.x - 1.0, 1.0 - 2.0*p.y, depth, 1);
+	uv = inUV;
+}
+`
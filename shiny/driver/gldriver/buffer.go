@@ -4,22 +4,70 @@
 
 package gldriver
 
-import "image"
+import (
+	"image"
+	"sync"
+)
 
 type bufferImpl struct {
+	s *screenImpl
+
 	// buf should always be equal to (i.e. the same ptr, len, cap as) rgba.Pix.
 	// It is a separate, redundant field in order to detect modifications to
 	// the rgba field that are invalid as per the screen.Buffer documentation.
 	buf  []byte
 	rgba image.RGBA
 	size image.Point
+
+	// uploads counts the asynchronous uploads of the buffer that are not yet
+	// done, and released is whether Release was called while there were
+	// any. mu guards them.
+	mu       sync.Mutex
+	uploads  int
+	released bool
 }
 
-func (b *bufferImpl) Release()                {}
 func (b *bufferImpl) Size() image.Point       { return b.size }
 func (b *bufferImpl) Bounds() image.Rectangle { return image.Rectangle{Max: b.size} }
 func (b *bufferImpl) RGBA() *image.RGBA       { return &b.rgba }
 
+func (b *bufferImpl) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.uploads > 0 {
+		// The last asynchronous upload recycles the pixels.
+		b.released = true
+		return
+	}
+	// Other uploads are synchronous, so the pixels can be recycled
+	// immediately.
+	b.recycle()
+}
+
+func (b *bufferImpl) recycle() {
+	b.s.pixPool.Put(b.buf)
+	b.buf, b.rgba.Pix = nil, nil
+}
+
+// startUpload and endUpload are called before and after an asynchronous
+// upload of the buffer.
+
+func (b *bufferImpl) startUpload() {
+	b.mu.Lock()
+	b.uploads++
+	b.mu.Unlock()
+}
+
+func (b *bufferImpl) endUpload() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.uploads--; b.uploads == 0 && b.released {
+		b.recycle()
+	}
+}
+
 func (b *bufferImpl) preUpload() {
 	// Check that the program hasn't tried to modify the rgba field via the
 	// pointer returned by the bufferImpl.RGBA method. This check doesn't catch
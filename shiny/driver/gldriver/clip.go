@@ -0,0 +1,219 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gldriver
+
+import (
+	"encoding/binary"
+	"image"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/gl"
+)
+
+// maxClipDepth is the maximum number of nested path clips that are applied
+// exactly. The high 4 bits of each back buffer stencil value hold the number
+// of path clips that contain that pixel, and the low 4 bits count winding
+// numbers while a path clip is pushed. Deeper path clips, like path clips on
+// a back buffer without a stencil buffer, clip to the path's bounds.
+const maxClipDepth = 15
+
+// clipRegion is an entry of a windowImpl's clip stack.
+type clipRegion struct {
+	// r is the intersection of the rectangles and path bounds pushed so far,
+	// in window pixels. Draws are scissored to r.
+	r image.Rectangle
+	// depth is the number of path clips, so far, that are applied via the
+	// stencil buffer. Draws only touch pixels whose stencil value's high 4
+	// bits equal depth.
+	depth int
+}
+
+// topClip returns the top of the clip stack or, if it is empty, a clipRegion
+// covering the whole window. It must only be called while holding w.glctxMu.
+func (w *windowImpl) topClip() clipRegion {
+	if n := len(w.clips); n > 0 {
+		return w.clips[n-1]
+	}
+	w.szMu.Lock()
+	sz := w.sz
+	w.szMu.Unlock()
+	return clipRegion{r: image.Rect(0, 0, sz.WidthPx, sz.HeightPx)}
+}
+
+// PushClip implements screen.Clipper.
+func (w *windowImpl) PushClip(r image.Rectangle) {
+	w.glctxMu.Lock()
+	defer w.glctxMu.Unlock()
+
+	c := w.topClip()
+	c.r = c.r.Intersect(r)
+	w.clips = append(w.clips, c)
+}
+
+// PushClipPath implements screen.Clipper.
+//
+// Like FillPath, it draws the path's triangle fans into the stencil buffer to
+// count winding numbers, but into the back buffer's, sampled once per pixel.
+// A quad over the path's bounds then increments the clip depth, held in the
+// stencil's high bits, of the pixels with a non-zero winding number and
+// resets the winding counts.
+func (w *windowImpl) PushClipPath(src2dst f64.Aff3, p *screen.Path) {
+	verts, bounds := flattenPath(src2dst, p)
+
+	w.glctxMu.Lock()
+	defer w.glctxMu.Unlock()
+
+	prev := w.topClip()
+	c := clipRegion{
+		r:     prev.r.Intersect(bounds),
+		depth: prev.depth,
+	}
+	w.clips = append(w.clips, c)
+	if w.released || c.r.Empty() || c.depth == maxClipDepth {
+		return
+	}
+
+	glctx := w.glctx
+	if !w.backBufferBound {
+		w.bindBackBuffer()
+	}
+	if w.stencilBits < 0 {
+		w.stencilBits = glctx.GetInteger(gl.STENCIL_BITS)
+	}
+	if w.stencilBits < 8 {
+		return
+	}
+	w.clips[len(w.clips)-1].depth++
+
+	w.szMu.Lock()
+	sz := w.sz
+	w.szMu.Unlock()
+
+	nTriVerts := len(verts) / 2
+	verts = appendQuad(verts, c.r)
+
+	if c.depth == 0 {
+		// This is the outermost path clip, so the stencil buffer holds
+		// whatever the previous frame left, or nothing after a Publish.
+		glctx.Disable(gl.SCISSOR_TEST)
+		glctx.StencilMask(0xff)
+		glctx.ClearStencil(0)
+		glctx.Clear(gl.STENCIL_BUFFER_BIT)
+	}
+	// OpenGL's window coordinates have the Y-axis pointing upwards.
+	glctx.Enable(gl.SCISSOR_TEST)
+	glctx.Scissor(int32(c.r.Min.X), int32(sz.HeightPx-c.r.Max.Y), int32(c.r.Dx()), int32(c.r.Dy()))
+	glctx.Disable(gl.DEPTH_TEST)
+	glctx.Enable(gl.STENCIL_TEST)
+	glctx.ColorMask(false, false, false, false)
+
+	w.progs.usePath(glctx)
+	glctx.Uniform2f(w.progs.path.size, float32(sz.WidthPx), float32(sz.HeightPx))
+	glctx.Uniform2f(w.progs.path.offset, 0, 0)
+	glctx.BindBuffer(gl.ARRAY_BUFFER, w.progs.path.verts)
+	glctx.BufferData(gl.ARRAY_BUFFER, f32Bytes(binary.LittleEndian, verts...), gl.DYNAMIC_DRAW)
+	glctx.EnableVertexAttribArray(w.progs.path.pos)
+	glctx.VertexAttribPointer(w.progs.path.pos, 2, gl.FLOAT, false, 0, 0)
+
+	// Count winding numbers, in the low bits, of the pixels inside every
+	// enclosing path clip.
+	glctx.StencilMask(0x0f)
+	glctx.StencilFunc(gl.EQUAL, c.depth<<4, 0xf0)
+	glctx.StencilOpSeparate(gl.FRONT, gl.KEEP, gl.KEEP, gl.INCR_WRAP)
+	glctx.StencilOpSeparate(gl.BACK, gl.KEEP, gl.KEEP, gl.DECR_WRAP)
+	glctx.DrawArrays(gl.TRIANGLES, 0, nTriVerts)
+
+	// Replace every non-zero winding number by the incremented depth.
+	glctx.StencilMask(0xff)
+	glctx.StencilFunc(gl.NOTEQUAL, (c.depth+1)<<4, 0x0f)
+	glctx.StencilOp(gl.KEEP, gl.KEEP, gl.REPLACE)
+	glctx.DrawArrays(gl.TRIANGLES, nTriVerts, 6)
+
+	glctx.DisableVertexAttribArray(w.progs.path.pos)
+	glctx.ColorMask(true, true, true, true)
+}
+
+// PopClip implements screen.Clipper.
+func (w *windowImpl) PopClip() {
+	w.glctxMu.Lock()
+	defer w.glctxMu.Unlock()
+
+	n := len(w.clips)
+	if n == 0 {
+		return
+	}
+	c := w.clips[n-1]
+	w.clips = w.clips[:n-1]
+	if w.released || c.depth == w.topClip().depth {
+		return
+	}
+
+	// Decrement the depth of the pixels inside c. Their low bits are zero, so
+	// decrementing the whole value and masking the write to the high bits
+	// takes one from the high bits alone.
+	glctx := w.glctx
+	if !w.backBufferBound {
+		w.bindBackBuffer()
+	}
+	w.szMu.Lock()
+	sz := w.sz
+	w.szMu.Unlock()
+
+	glctx.Enable(gl.SCISSOR_TEST)
+	glctx.Scissor(int32(c.r.Min.X), int32(sz.HeightPx-c.r.Max.Y), int32(c.r.Dx()), int32(c.r.Dy()))
+	glctx.Disable(gl.DEPTH_TEST)
+	glctx.Enable(gl.STENCIL_TEST)
+	glctx.ColorMask(false, false, false, false)
+	glctx.StencilMask(0xf0)
+	glctx.StencilFunc(gl.EQUAL, c.depth<<4, 0xf0)
+	glctx.StencilOp(gl.KEEP, gl.KEEP, gl.DECR)
+
+	w.progs.usePath(glctx)
+	glctx.Uniform2f(w.progs.path.size, float32(sz.WidthPx), float32(sz.HeightPx))
+	glctx.Uniform2f(w.progs.path.offset, 0, 0)
+	glctx.BindBuffer(gl.ARRAY_BUFFER, w.progs.path.verts)
+	glctx.BufferData(gl.ARRAY_BUFFER, f32Bytes(binary.LittleEndian, appendQuad(nil, c.r)...), gl.DYNAMIC_DRAW)
+	glctx.EnableVertexAttribArray(w.progs.path.pos)
+	glctx.VertexAttribPointer(w.progs.path.pos, 2, gl.FLOAT, false, 0, 0)
+	glctx.DrawArrays(gl.TRIANGLES, 0, 6)
+	glctx.DisableVertexAttribArray(w.progs.path.pos)
+
+	glctx.ColorMask(true, true, true, true)
+	glctx.StencilMask(0xff)
+}
+
+// useClip sets the scissor and stencil tests for the next draw onto the back
+// buffer, which must be bound, according to the top of the clip stack. It
+// returns false if that clip is empty, in which case nothing should be drawn.
+// It must only be called while holding w.glctxMu.
+func (w *windowImpl) useClip() bool {
+	n := len(w.clips)
+	if n == 0 {
+		w.glctx.Disable(gl.SCISSOR_TEST)
+		w.glctx.Disable(gl.STENCIL_TEST)
+		return true
+	}
+	c := w.clips[n-1]
+	if c.r.Empty() {
+		return false
+	}
+
+	w.szMu.Lock()
+	sz := w.sz
+	w.szMu.Unlock()
+
+	w.glctx.Enable(gl.SCISSOR_TEST)
+	w.glctx.Scissor(int32(c.r.Min.X), int32(sz.HeightPx-c.r.Max.Y), int32(c.r.Dx()), int32(c.r.Dy()))
+	if c.depth == 0 {
+		w.glctx.Disable(gl.STENCIL_TEST)
+		return true
+	}
+	w.glctx.Enable(gl.STENCIL_TEST)
+	w.glctx.StencilMask(0)
+	w.glctx.StencilFunc(gl.EQUAL, c.depth<<4, 0xf0)
+	w.glctx.StencilOp(gl.KEEP, gl.KEEP, gl.KEEP)
+	return true
+}
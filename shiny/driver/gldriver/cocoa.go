@@ -12,7 +12,6 @@ package gldriver
 #cgo CFLAGS: -x objective-c
 #cgo LDFLAGS: -framework Cocoa -framework OpenGL
 #include <OpenGL/gl3.h>
-#import <Carbon/Carbon.h> // for HIToolbox/Events.h
 #import <Cocoa/Cocoa.h>
 #include <pthread.h>
 #include <stdint.h>
@@ -22,9 +21,27 @@ void startDriver();
 void stopDriver();
 void makeCurrentContext(uintptr_t ctx);
 void flushContext(uintptr_t ctx);
-uintptr_t doNewWindow(int width, int height, char* title);
-void doShowWindow(uintptr_t id);
+uintptr_t doNewWindow(int width, int height, int x, int y, int hasPosition, int fixedSize, char* title, int highPerformance, int interceptClose, int transparent, int undecorated, int samples);
+void doShowWindow(uintptr_t id, int hidden);
 void doCloseWindow(uintptr_t id);
+void doSetTitle(uintptr_t id, char* title);
+void doSetSize(uintptr_t id, int width, int height);
+void doSetPosition(uintptr_t id, int x, int y);
+void doGetGeometry(uintptr_t id, int* x, int* y, int* width, int* height);
+void doSetTextInputRect(uintptr_t id, int x, int y, int width, int height);
+void doSetCursor(uintptr_t id, uintptr_t cursor);
+void doSetCursorVisible(uintptr_t id, int visible);
+void doSetPointerCapture(uintptr_t id, int capture);
+void doMinimize(uintptr_t id);
+void doMaximize(uintptr_t id);
+void doRestore(uintptr_t id);
+void doSetFullscreen(uintptr_t id, int fullscreen);
+void doSetColorSpace(uintptr_t id, int p3);
+uintptr_t shareContextCreate();
+void getAccessibilityPrefs(int* reduceMotion, int* increaseContrast, int* reduceTransparency);
+int isDarkMode();
+char* clipboardReadText();
+int clipboardWriteText(char* text, int len);
 uint64_t threadID();
 */
 import "C"
@@ -32,10 +49,14 @@ import "C"
 import (
 	"errors"
 	"fmt"
+	"image"
 	"log"
 	"runtime"
 	"unsafe"
 
+	"golang.org/x/exp/shiny/driver/internal/cocoadisplay"
+	"golang.org/x/exp/shiny/driver/internal/cocoakey"
+	"golang.org/x/exp/shiny/driver/internal/hotkey"
 	"golang.org/x/exp/shiny/driver/internal/lifecycler"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/mobile/event/key"
@@ -65,21 +86,72 @@ func init() {
 	initThreadID = C.threadID()
 }
 
-func newWindow(opts *screen.NewWindowOptions) (uintptr, error) {
+func newWindow(opts *screen.NewWindowOptions, owner uintptr) (uintptr, error) {
 	width, height := optsSize(opts)
 
 	title := C.CString(opts.GetTitle())
 	defer C.free(unsafe.Pointer(title))
 
-	return uintptr(C.doNewWindow(C.int(width), C.int(height), title)), nil
+	x, y, hasPosition, fixedSize, highPerformance, interceptClose, transparent, undecorated, samples := 0, 0, 0, 0, 0, 0, 0, 0, 0
+	if opts != nil {
+		if opts.Position != nil {
+			x, y, hasPosition = opts.Position.X, opts.Position.Y, 1
+		}
+		if opts.FixedSize {
+			fixedSize = 1
+		}
+		if opts.PreferredGPU == screen.GPUHighPerformance {
+			highPerformance = 1
+		}
+		if opts.InterceptClose {
+			interceptClose = 1
+		}
+		if opts.Transparent {
+			transparent = 1
+		}
+		if opts.Undecorated {
+			undecorated = 1
+		}
+		samples = opts.Samples
+	}
+
+	id := uintptr(C.doNewWindow(C.int(width), C.int(height), C.int(x), C.int(y),
+		C.int(hasPosition), C.int(fixedSize), title, C.int(highPerformance), C.int(interceptClose), C.int(transparent), C.int(undecorated), C.int(samples)))
+	cocoadisplay.RegisterDragTypes(id)
+	return id, nil
 }
 
 func initWindow(w *windowImpl) {
 	w.glctx, w.worker = gl.NewContext()
+	w.glctx = traceContext(w.glctx, "window")
 }
 
-func showWindow(w *windowImpl) {
-	C.doShowWindow(C.uintptr_t(w.id))
+// objectLabel does nothing, and returns false, as macOS's OpenGL lacks the
+// KHR_debug extension.
+func objectLabel(identifier, name uint32, label string) bool { return false }
+
+// pboSupported returns false, so that uploads are not staged through pixel
+// buffer objects, and the pboStage's other native calls are never made.
+//
+// TODO: stage them, with the fence sync functions of OpenGL 3.2.
+func pboSupported() bool                         { return false }
+func texSubImageUnpack(x, y, width, height int)  {}
+func fenceSync() uintptr                         { return 0 }
+func syncSignaled(sync uintptr, flush bool) bool { return true }
+func deleteSync(sync uintptr)                    {}
+
+func showWindow(w *windowImpl, opts *screen.NewWindowOptions) {
+	hidden := 0
+	if opts != nil && opts.Hidden {
+		hidden = 1
+	}
+	if opts != nil && hidden == 0 {
+		if owner, ok := opts.Owner.(*windowImpl); ok {
+			cocoadisplay.ShowOwned(w.id, owner.id, opts.Modal)
+			return
+		}
+	}
+	C.doShowWindow(C.uintptr_t(w.id), C.int(hidden))
 }
 
 //export preparedOpenGL
@@ -93,9 +165,164 @@ func preparedOpenGL(id, ctx, vba uintptr) {
 }
 
 func closeWindow(id uintptr) {
+	cocoadisplay.ReleaseOwned(id)
 	C.doCloseWindow(C.uintptr_t(id))
+	cocoadisplay.ReleaseMenus(id)
+	cocoadisplay.ReleaseAccess(id)
+}
+
+func setTitle(w *windowImpl, title string) {
+	ctitle := C.CString(title)
+	defer C.free(unsafe.Pointer(ctitle))
+	C.doSetTitle(C.uintptr_t(w.id), ctitle)
+}
+
+func setIcon(w *windowImpl, m image.Image) { cocoadisplay.SetDockIcon(m) }
+
+func setBadge(w *windowImpl, label string) { cocoadisplay.SetDockBadge(label) }
+
+func setProgress(w *windowImpl, progress float64) { cocoadisplay.SetDockProgress(progress) }
+
+func setSize(w *windowImpl, width, height int) {
+	C.doSetSize(C.uintptr_t(w.id), C.int(width), C.int(height))
+}
+
+func setPosition(w *windowImpl, p image.Point) {
+	C.doSetPosition(C.uintptr_t(w.id), C.int(p.X), C.int(p.Y))
+}
+
+func geometry(w *windowImpl) (image.Point, image.Point) {
+	var x, y, width, height C.int
+	C.doGetGeometry(C.uintptr_t(w.id), &x, &y, &width, &height)
+	return image.Point{int(x), int(y)}, image.Point{int(width), int(height)}
+}
+
+func setTextInputRect(w *windowImpl, r image.Rectangle) {
+	C.doSetTextInputRect(C.uintptr_t(w.id), C.int(r.Min.X), C.int(r.Min.Y), C.int(r.Dx()), C.int(r.Dy()))
+}
+
+// setCursor passes ownership of the new NSCursor to the view.
+func setCursor(w *windowImpl, c screen.Cursor) {
+	C.doSetCursor(C.uintptr_t(w.id), C.uintptr_t(cocoadisplay.NewCursor(w.id, c)))
+}
+
+func setCursorVisible(w *windowImpl, visible bool) {
+	v := 0
+	if visible {
+		v = 1
+	}
+	C.doSetCursorVisible(C.uintptr_t(w.id), C.int(v))
+}
+
+func setPointerCapture(w *windowImpl, capture bool) {
+	v := 0
+	if capture {
+		v = 1
+	}
+	C.doSetPointerCapture(C.uintptr_t(w.id), C.int(v))
+}
+
+func startDrag(w *windowImpl, data screen.DragData) error {
+	return cocoadisplay.StartDrag(w.id, data)
+}
+
+func startMove(w *windowImpl) error {
+	return cocoadisplay.StartMove(w.id)
+}
+
+func startResize(w *windowImpl, edge screen.WindowEdge) error {
+	return cocoadisplay.StartResize(w.id, edge)
+}
+
+func showFileDialog(w *windowImpl, opts *screen.FileDialogOptions) error {
+	cocoadisplay.ShowFileDialog(w.id, w, opts)
+	return nil
+}
+
+func setMenuBar(w *windowImpl, bar *screen.Menu) error {
+	cocoadisplay.SetMenuBar(w.id, w, bar)
+	return nil
+}
+
+func showContextMenu(w *windowImpl, m *screen.Menu, p image.Point) error {
+	cocoadisplay.ShowContextMenu(w.id, w, m, p)
+	return nil
 }
 
+func setAccessTree(w *windowImpl, root *screen.AccessNode) error {
+	cocoadisplay.SetAccessTree(w.id, w, root)
+	return nil
+}
+
+func minimize(w *windowImpl) { C.doMinimize(C.uintptr_t(w.id)) }
+
+func maximize(w *windowImpl) { C.doMaximize(C.uintptr_t(w.id)) }
+
+func restore(w *windowImpl) { C.doRestore(C.uintptr_t(w.id)) }
+
+// setFullscreen uses macOS's fullscreen, which gives the window a space of
+// its own. Windows cannot bypass the compositor, so FullscreenExclusive is
+// the same as FullscreenBorderless.
+func setFullscreen(w *windowImpl, mode screen.FullscreenMode) {
+	v := 0
+	if mode != screen.FullscreenNone {
+		v = 1
+	}
+	C.doSetFullscreen(C.uintptr_t(w.id), C.int(v))
+}
+
+func setSizeConstraints(w *windowImpl, min, max image.Point) {
+	cocoadisplay.SetSizeConstraints(w.id, min, max)
+}
+
+func setAspectRatio(w *windowImpl, aspect image.Point) { cocoadisplay.SetAspectRatio(w.id, aspect) }
+
+func setAlwaysOnTop(w *windowImpl, onTop bool) { cocoadisplay.SetAlwaysOnTop(w.id, onTop) }
+
+func setOpacity(w *windowImpl, opacity float32) { cocoadisplay.SetOpacity(w.id, opacity) }
+
+func registerHotKey(w *windowImpl, k hotkey.Key) error {
+	return cocoadisplay.RegisterHotKey(w, k.Code, k.Modifiers)
+}
+
+func unregisterHotKey(w *windowImpl, k hotkey.Key) {
+	cocoadisplay.UnregisterHotKey(w, k.Code, k.Modifiers)
+}
+
+func releaseHotKeys(w *windowImpl) { cocoadisplay.ReleaseHotKeys(w) }
+
+func postNotification(w *windowImpl, n *screen.Notification) error {
+	return cocoadisplay.PostNotification(w, n)
+}
+
+func closeNotification(w *windowImpl, id int) { cocoadisplay.CloseNotification(w, id) }
+func releaseNotifications(w *windowImpl)      { cocoadisplay.ReleaseNotifications(w) }
+
+func raise(w *windowImpl) { cocoadisplay.Raise(w.id) }
+
+func lower(w *windowImpl) { cocoadisplay.Lower(w.id) }
+
+// setColorSpace sets the color space of the window's NSWindow, which macOS
+// converts the window's pixels from.
+func setColorSpace(w *windowImpl, cs screen.ColorSpace) error {
+	switch cs {
+	case screen.ColorSpaceSRGB:
+		C.doSetColorSpace(C.uintptr_t(w.id), 0)
+	case screen.ColorSpaceDisplayP3:
+		C.doSetColorSpace(C.uintptr_t(w.id), 1)
+	default:
+		return errors.New("gldriver: unsupported color space")
+	}
+	return nil
+}
+
+// srgbSurfaces returns true, as the default framebuffer of an
+// NSOpenGLContext is sRGB-capable, once drawLoop enables
+// GL_FRAMEBUFFER_SRGB.
+func srgbSurfaces() bool { return true }
+
+func nextFrame(w *windowImpl) { cocoadisplay.NextFrame(w) }
+
 var mainCallback func(screen.Screen)
 
 func main(f func(screen.Screen)) error {
@@ -154,14 +381,20 @@ func drawLoop(w *windowImpl, vba uintptr) {
 	if errno := C.glGetError(); errno != 0 {
 		panic(fmt.Sprintf("gldriver: glBindVertexArray failed: %d", errno))
 	}
+	if w.progs.linear {
+		C.glEnable(C.GL_FRAMEBUFFER_SRGB)
+	}
 
 	workAvailable := w.worker.WorkAvailable()
+	run := traceRun(w.glctx)
 
 	// TODO(crawshaw): exit this goroutine on Release.
 	for {
 		select {
 		case <-workAvailable:
 			w.worker.DoWork()
+		case f := <-run:
+			f()
 		case <-w.publish:
 		loop:
 			for {
@@ -179,7 +412,7 @@ func drawLoop(w *windowImpl, vba uintptr) {
 }
 
 //export setGeom
-func setGeom(id uintptr, ppp float32, widthPx, heightPx int) {
+func setGeom(id uintptr, ppp float32, scale float64, widthPx, heightPx int) {
 	theScreen.mu.Lock()
 	w := theScreen.windows[id]
 	theScreen.mu.Unlock()
@@ -188,6 +421,7 @@ func setGeom(id uintptr, ppp float32, widthPx, heightPx int) {
 		return // closing window
 	}
 
+	w.sendScale(ppp, scale)
 	sz := size.Event{
 		WidthPx:     widthPx,
 		HeightPx:    heightPx,
@@ -205,11 +439,46 @@ func setGeom(id uintptr, ppp float32, widthPx, heightPx int) {
 	w.Send(sz)
 }
 
+//export windowStateChanged
+func windowStateChanged(id uintptr, state int32) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[id]
+	theScreen.mu.Unlock()
+
+	if w == nil {
+		return // closing window
+	}
+	w.sendState(screen.WindowState(state))
+}
+
+//export visibilityChanged
+func visibilityChanged(id uintptr, visible bool) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[id]
+	theScreen.mu.Unlock()
+
+	if w == nil {
+		return // closing window
+	}
+	// A window's occlusion state does not tell whether it is partially
+	// visible.
+	v := screen.VisibilityOccluded
+	if visible {
+		v = screen.VisibilityFull
+	}
+	w.sendVisibility(v)
+}
+
 //export windowClosing
 func windowClosing(id uintptr) {
 	sendLifecycle(id, (*lifecycler.State).SetDead, true)
 }
 
+//export windowCloseRequested
+func windowCloseRequested(id uintptr) {
+	sendWindowEvent(id, screen.CloseRequestEvent{})
+}
+
 func sendWindowEvent(id uintptr, e interface{}) {
 	theScreen.mu.Lock()
 	w := theScreen.windows[id]
@@ -221,30 +490,17 @@ func sendWindowEvent(id uintptr, e interface{}) {
 	w.Send(e)
 }
 
-var mods = [...]struct {
-	flags uint32
-	code  uint16
-	mod   key.Modifiers
-}{
-	// Left and right variants of modifier keys have their own masks,
-	// but they are not documented. These were determined empirically.
-	{1<<17 | 0x102, C.kVK_Shift, key.ModShift},
-	{1<<17 | 0x104, C.kVK_RightShift, key.ModShift},
-	{1<<18 | 0x101, C.kVK_Control, key.ModControl},
-	// TODO key.ControlRight
-	{1<<19 | 0x120, C.kVK_Option, key.ModAlt},
-	{1<<19 | 0x140, C.kVK_RightOption, key.ModAlt},
-	{1<<20 | 0x108, C.kVK_Command, key.ModMeta},
-	{1<<20 | 0x110, C.kVK_Command, key.ModMeta}, // TODO: missing kVK_RightCommand
-}
-
-func cocoaMods(flags uint32) (m key.Modifiers) {
-	for _, mod := range mods {
-		if flags&mod.flags == mod.flags {
-			m |= mod.mod
-		}
+// sendInputEvent is like sendWindowEvent, but for an input event, which is
+// sent at the time of the NSEvent that it came from.
+func sendInputEvent(id uintptr, e interface{}) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[id]
+	theScreen.mu.Unlock()
+
+	if w == nil {
+		return // closing window
 	}
-	return m
+	w.SendAt(e, cocoadisplay.EventTime())
 }
 
 func cocoaMouseDir(ty int32) mouse.Direction {
@@ -266,83 +522,141 @@ func cocoaMouseButton(button int32) mouse.Button {
 		return mouse.ButtonRight
 	case 2:
 		return mouse.ButtonMiddle
+	case 3:
+		return screen.MouseButtonBack
+	case 4:
+		return screen.MouseButtonForward
 	default:
-		return mouse.ButtonNone
+		if button < 0 {
+			return mouse.ButtonNone
+		}
+		// Map any further buttons, from 5 upwards, to 10 upwards.
+		return mouse.Button(button + 5)
+	}
+}
+
+//export relativeMouseEvent
+func relativeMouseEvent(id uintptr, dx, dy float32) {
+	sendInputEvent(id, screen.RelativeMouseEvent{DeltaX: dx, DeltaY: dy})
+}
+
+//export dragEvent
+func dragEvent(id uintptr, ty int32, x, y float32, pb uintptr) {
+	e := screen.DragEvent{
+		Type:  screen.DragType(ty),
+		X:     x,
+		Y:     y,
+		Types: cocoadisplay.DragTypes(pb),
+	}
+	switch e.Type {
+	case screen.DragLeave:
+		e.X, e.Y = 0, 0
+	case screen.Drop:
+		e.Data = cocoadisplay.DragData(pb)
+	}
+	sendWindowEvent(id, e)
+}
+
+//export penEvent
+func penEvent(id uintptr, x, y float32, ty int32, pressure, tiltX, tiltY float32, eraser, barrel int32, flags uint32) {
+	e := screen.PenEvent{
+		X:         x,
+		Y:         y,
+		Type:      screen.PenType(ty),
+		TiltX:     tiltX,
+		TiltY:     tiltY,
+		Eraser:    eraser != 0,
+		Barrel:    barrel != 0,
+		Modifiers: cocoakey.Modifiers(flags),
+	}
+	if e.Type == screen.PenDown || e.Type == screen.PenMove {
+		e.Pressure = pressure
+	}
+	sendInputEvent(id, e)
+}
+
+// scroller converts the scrolls of scrollerID, the window most recently
+// scrolled. Like the window's other events, they are only sent on the main
+// thread.
+var (
+	scroller   cocoadisplay.Scroller
+	scrollerID uintptr
+)
+
+//export scrollEvent
+func scrollEvent(id uintptr, x, y, dx, dy float32, precise int32, phase, momentumPhase uint32, flags uint32) {
+	if id != scrollerID {
+		scroller, scrollerID = cocoadisplay.Scroller{}, id
+	}
+	e, wheel, ok := scroller.Scroll(x, y, dx, dy, precise != 0, phase, momentumPhase, cocoakey.Modifiers(flags))
+	if !ok {
+		return
+	}
+	sendInputEvent(id, e)
+	for _, e := range wheel {
+		sendInputEvent(id, e)
 	}
 }
 
 //export mouseEvent
-func mouseEvent(id uintptr, x, y, dx, dy float32, ty, button int32, flags uint32) {
+func mouseEvent(id uintptr, x, y float32, ty, button int32, flags uint32) {
 	cmButton := mouse.ButtonNone
 	switch ty {
 	default:
 		cmButton = cocoaMouseButton(button)
 	case C.NSMouseMoved, C.NSLeftMouseDragged, C.NSRightMouseDragged, C.NSOtherMouseDragged:
 		// No-op.
-	case C.NSScrollWheel:
-		// Note that the direction of scrolling is inverted by default
-		// on OS X by the "natural scrolling" setting. At the Cocoa
-		// level this inversion is applied to trackpads and mice behind
-		// the scenes, and the value of dy goes in the direction the OS
-		// wants scrolling to go.
-		//
-		// This means the same trackpad/mouse motion on OS X and Linux
-		// can produce wheel events in opposite directions, but the
-		// direction matches what other programs on the OS do.
-		//
-		// If we wanted to expose the phsyical device motion in the
-		// event we could use [NSEvent isDirectionInvertedFromDevice]
-		// to know if "natural scrolling" is enabled.
-		//
-		// TODO: On a trackpad, a scroll can be a drawn-out affair with a
-		// distinct beginning and end. Should the intermediate events be
-		// DirNone?
-		//
-		// TODO: handle horizontal scrolling
-		button := mouse.ButtonWheelUp
-		if dy < 0 {
-			dy = -dy
-			button = mouse.ButtonWheelDown
-		}
-		e := mouse.Event{
-			X:         x,
-			Y:         y,
-			Button:    button,
-			Direction: mouse.DirStep,
-			Modifiers: cocoaMods(flags),
-		}
-		for delta := int(dy); delta != 0; delta-- {
-			sendWindowEvent(id, e)
-		}
-		return
 	}
-	sendWindowEvent(id, mouse.Event{
+	sendInputEvent(id, mouse.Event{
 		X:         x,
 		Y:         y,
 		Button:    cmButton,
 		Direction: cocoaMouseDir(ty),
-		Modifiers: cocoaMods(flags),
+		Modifiers: cocoakey.Modifiers(flags),
 	})
 }
 
+//export crossingEvent
+func crossingEvent(id uintptr, entered bool, x, y float32) {
+	sendInputEvent(id, screen.CrossingEvent{Entered: entered, X: x, Y: y})
+}
+
 //export keyEvent
-func keyEvent(id uintptr, runeVal rune, dir uint8, code uint16, flags uint32) {
-	sendWindowEvent(id, key.Event{
-		Rune:      cocoaRune(runeVal),
-		Direction: key.Direction(dir),
-		Code:      cocoaKeyCode(code),
-		Modifiers: cocoaMods(flags),
+func keyEvent(id uintptr, runeVal, unmodified rune, dir uint8, code uint16, flags uint32) {
+	// Cocoa's virtual key codes are those of the keys' positions on an ANSI
+	// keyboard, whatever the layout, so the Code is that of the physical key.
+	e := screen.KeyEvent{
+		Event: key.Event{
+			Rune:      cocoakey.Rune(runeVal),
+			Direction: key.Direction(dir),
+			Code:      cocoakey.Code(code),
+			Modifiers: cocoakey.Modifiers(flags),
+		},
+		Physical:   cocoakey.Code(code),
+		Scancode:   uint32(code),
+		Unmodified: cocoakey.Rune(unmodified),
+		Repeat:     key.Direction(dir) == key.DirNone,
+	}
+	sendInputEvent(id, e)
+}
+
+//export textEvent
+func textEvent(id uintptr, preedit *C.char, preeditCursor C.int, commit *C.char) {
+	sendInputEvent(id, screen.TextEvent{
+		Preedit:       C.GoString(preedit),
+		PreeditCursor: int(preeditCursor),
+		Commit:        C.GoString(commit),
 	})
 }
 
 //export flagEvent
 func flagEvent(id uintptr, flags uint32) {
-	for _, mod := range mods {
-		if flags&mod.flags == mod.flags && lastFlags&mod.flags != mod.flags {
-			keyEvent(id, -1, C.NSKeyDown, mod.code, flags)
+	for _, mod := range cocoakey.Mods {
+		if flags&mod.Flags == mod.Flags && lastFlags&mod.Flags != mod.Flags {
+			keyEvent(id, -1, -1, C.NSKeyDown, mod.Code, flags)
 		}
-		if lastFlags&mod.flags == mod.flags && flags&mod.flags != mod.flags {
-			keyEvent(id, -1, C.NSKeyUp, mod.code, flags)
+		if lastFlags&mod.Flags == mod.Flags && flags&mod.Flags != mod.Flags {
+			keyEvent(id, -1, -1, C.NSKeyUp, mod.Code, flags)
 		}
 	}
 	lastFlags = flags
@@ -381,6 +695,111 @@ func sendLifecycleAll(dead bool) {
 	}
 }
 
+func accessibilityPrefs() (p screen.AccessibilityPrefs) {
+	var reduceMotion, increaseContrast, reduceTransparency C.int
+	C.getAccessibilityPrefs(&reduceMotion, &increaseContrast, &reduceTransparency)
+	if reduceMotion != 0 {
+		p |= screen.ReduceMotion
+	}
+	if increaseContrast != 0 {
+		p |= screen.HighContrast
+	}
+	if reduceTransparency != 0 {
+		p |= screen.ReduceTransparency
+	}
+	return p
+}
+
+func clipboard() screen.Clipboard { return clipboardImpl{} }
+
+func displays() []screen.Display { return cocoadisplay.Displays() }
+
+func newTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return cocoadisplay.NewTrayIcon(opts)
+}
+
+// clipboardImpl is the general pasteboard.
+type clipboardImpl struct{}
+
+func (clipboardImpl) ReadText() (string, error) {
+	text := C.clipboardReadText()
+	if text == nil {
+		return "", nil
+	}
+	defer C.free(unsafe.Pointer(text))
+	return C.GoString(text), nil
+}
+
+func (clipboardImpl) WriteText(text string) error {
+	b := C.CString(text)
+	defer C.free(unsafe.Pointer(b))
+	if C.clipboardWriteText(b, C.int(len(text))) == 0 {
+		return errors.New("gldriver: writing to the pasteboard failed")
+	}
+	return nil
+}
+
+func colorScheme() screen.ColorScheme {
+	if C.isDarkMode() != 0 {
+		return screen.DarkColorScheme
+	}
+	return screen.LightColorScheme
+}
+
+// lastLayout is the keyboard input source that the windows were last told
+// of. It is only accessed on the main thread.
+var lastLayout string
+
+//export keyboardLayoutChanged
+func keyboardLayoutChanged(source *C.char) {
+	layout := C.GoString(source)
+	if layout == lastLayout {
+		// Each window's input context posts the notification.
+		return
+	}
+	lastLayout = layout
+	e := screen.KeyboardLayoutEvent{Layout: layout}
+
+	theScreen.mu.Lock()
+	for _, w := range theScreen.windows {
+		w.Send(e)
+	}
+	theScreen.mu.Unlock()
+}
+
+//export colorSchemeChanged
+func colorSchemeChanged() {
+	e := screen.ColorSchemeEvent{Scheme: colorScheme()}
+
+	theScreen.mu.Lock()
+	for _, w := range theScreen.windows {
+		w.Send(e)
+	}
+	theScreen.mu.Unlock()
+}
+
+//export accessibilityChanged
+func accessibilityChanged() {
+	e := screen.AccessibilityEvent{Prefs: accessibilityPrefs()}
+
+	theScreen.mu.Lock()
+	for _, w := range theScreen.windows {
+		w.Send(e)
+	}
+	theScreen.mu.Unlock()
+}
+
+//export displaysChanged
+func displaysChanged() {
+	displays := cocoadisplay.Displays()
+
+	theScreen.mu.Lock()
+	for _, w := range theScreen.windows {
+		w.Send(screen.DisplayEvent{Displays: append([]screen.Display(nil), displays...)})
+	}
+	theScreen.mu.Unlock()
+}
+
 //export lifecycleDeadAll
 func lifecycleDeadAll() { sendLifecycleAll(true) }
 
@@ -397,273 +816,15 @@ func lifecycleFocused(id uintptr, val bool) {
 	sendLifecycle(id, (*lifecycler.State).SetFocused, val)
 }
 
-// cocoaRune marks the Carbon/Cocoa private-range unicode rune representing
-// a non-unicode key event to -1, used for Rune in the key package.
-//
-// http://www.unicode.org/Public/MAPPINGS/VENDORS/APPLE/CORPCHAR.TXT
-func cocoaRune(r rune) rune {
-	if '\uE000' <= r && r <= '\uF8FF' {
-		return -1
+func shareContextCreate() error {
+	if C.shareContextCreate() == 0 {
+		return errors.New("gldriver: share context creation failed")
 	}
-	return r
+	return nil
 }
 
-// cocoaKeyCode converts a Carbon/Cocoa virtual key code number
-// into the standard keycodes used by the key package.
-//
-// To get a sense of the key map, see the diagram on
-//	http://boredzo.org/blog/archives/2007-05-22/virtual-key-codes
-func cocoaKeyCode(vkcode uint16) key.Code {
-	switch vkcode {
-	case C.kVK_ANSI_A:
-		return key.CodeA
-	case C.kVK_ANSI_B:
-		return key.CodeB
-	case C.kVK_ANSI_C:
-		return key.CodeC
-	case C.kVK_ANSI_D:
-		return key.CodeD
-	case C.kVK_ANSI_E:
-		return key.CodeE
-	case C.kVK_ANSI_F:
-		return key.CodeF
-	case C.kVK_ANSI_G:
-		return key.CodeG
-	case C.kVK_ANSI_H:
-		return key.CodeH
-	case C.kVK_ANSI_I:
-		return key.CodeI
-	case C.kVK_ANSI_J:
-		return key.CodeJ
-	case C.kVK_ANSI_K:
-		return key.CodeK
-	case C.kVK_ANSI_L:
-		return key.CodeL
-	case C.kVK_ANSI_M:
-		return key.CodeM
-	case C.kVK_ANSI_N:
-		return key.CodeN
-	case C.kVK_ANSI_O:
-		return key.CodeO
-	case C.kVK_ANSI_P:
-		return key.CodeP
-	case C.kVK_ANSI_Q:
-		return key.CodeQ
-	case C.kVK_ANSI_R:
-		return key.CodeR
-	case C.kVK_ANSI_S:
-		return key.CodeS
-	case C.kVK_ANSI_T:
-		return key.CodeT
-	case C.kVK_ANSI_U:
-		return key.CodeU
-	case C.kVK_ANSI_V:
-		return key.CodeV
-	case C.kVK_ANSI_W:
-		return key.CodeW
-	case C.kVK_ANSI_X:
-		return key.CodeX
-	case C.kVK_ANSI_Y:
-		return key.CodeY
-	case C.kVK_ANSI_Z:
-		return key.CodeZ
-	case C.kVK_ANSI_1:
-		return key.Code1
-	case C.kVK_ANSI_2:
-		return key.Code2
-	case C.kVK_ANSI_3:
-		return key.Code3
-	case C.kVK_ANSI_4:
-		return key.Code4
-	case C.kVK_ANSI_5:
-		return key.Code5
-	case C.kVK_ANSI_6:
-		return key.Code6
-	case C.kVK_ANSI_7:
-		return key.Code7
-	case C.kVK_ANSI_8:
-		return key.Code8
-	case C.kVK_ANSI_9:
-		return key.Code9
-	case C.kVK_ANSI_0:
-		return key.Code0
-	// TODO: move the rest of these codes to constants in key.go
-	// if we are happy with them.
-	case C.kVK_Return:
-		return key.CodeReturnEnter
-	case C.kVK_Escape:
-		return key.CodeEscape
-	case C.kVK_Delete:
-		return key.CodeDeleteBackspace
-	case C.kVK_Tab:
-		return key.CodeTab
-	case C.kVK_Space:
-		return key.CodeSpacebar
-	case C.kVK_ANSI_Minus:
-		return key.CodeHyphenMinus
-	case C.kVK_ANSI_Equal:
-		return key.CodeEqualSign
-	case C.kVK_ANSI_LeftBracket:
-		return key.CodeLeftSquareBracket
-	case C.kVK_ANSI_RightBracket:
-		return key.CodeRightSquareBracket
-	case C.kVK_ANSI_Backslash:
-		return key.CodeBackslash
-	// 50: Keyboard Non-US "#" and ~
-	case C.kVK_ANSI_Semicolon:
-		return key.CodeSemicolon
-	case C.kVK_ANSI_Quote:
-		return key.CodeApostrophe
-	case C.kVK_ANSI_Grave:
-		return key.CodeGraveAccent
-	case C.kVK_ANSI_Comma:
-		return key.CodeComma
-	case C.kVK_ANSI_Period:
-		return key.CodeFullStop
-	case C.kVK_ANSI_Slash:
-		return key.CodeSlash
-	case C.kVK_CapsLock:
-		return key.CodeCapsLock
-	case C.kVK_F1:
-		return key.CodeF1
-	case C.kVK_F2:
-		return key.CodeF2
-	case C.kVK_F3:
-		return key.CodeF3
-	case C.kVK_F4:
-		return key.CodeF4
-	case C.kVK_F5:
-		return key.CodeF5
-	case C.kVK_F6:
-		return key.CodeF6
-	case C.kVK_F7:
-		return key.CodeF7
-	case C.kVK_F8:
-		return key.CodeF8
-	case C.kVK_F9:
-		return key.CodeF9
-	case C.kVK_F10:
-		return key.CodeF10
-	case C.kVK_F11:
-		return key.CodeF11
-	case C.kVK_F12:
-		return key.CodeF12
-	// 70: PrintScreen
-	// 71: Scroll Lock
-	// 72: Pause
-	// 73: Insert
-	case C.kVK_Home:
-		return key.CodeHome
-	case C.kVK_PageUp:
-		return key.CodePageUp
-	case C.kVK_ForwardDelete:
-		return key.CodeDeleteForward
-	case C.kVK_End:
-		return key.CodeEnd
-	case C.kVK_PageDown:
-		return key.CodePageDown
-	case C.kVK_RightArrow:
-		return key.CodeRightArrow
-	case C.kVK_LeftArrow:
-		return key.CodeLeftArrow
-	case C.kVK_DownArrow:
-		return key.CodeDownArrow
-	case C.kVK_UpArrow:
-		return key.CodeUpArrow
-	case C.kVK_ANSI_KeypadClear:
-		return key.CodeKeypadNumLock
-	case C.kVK_ANSI_KeypadDivide:
-		return key.CodeKeypadSlash
-	case C.kVK_ANSI_KeypadMultiply:
-		return key.CodeKeypadAsterisk
-	case C.kVK_ANSI_KeypadMinus:
-		return key.CodeKeypadHyphenMinus
-	case C.kVK_ANSI_KeypadPlus:
-		return key.CodeKeypadPlusSign
-	case C.kVK_ANSI_KeypadEnter:
-		return key.CodeKeypadEnter
-	case C.kVK_ANSI_Keypad1:
-		return key.CodeKeypad1
-	case C.kVK_ANSI_Keypad2:
-		return key.CodeKeypad2
-	case C.kVK_ANSI_Keypad3:
-		return key.CodeKeypad3
-	case C.kVK_ANSI_Keypad4:
-		return key.CodeKeypad4
-	case C.kVK_ANSI_Keypad5:
-		return key.CodeKeypad5
-	case C.kVK_ANSI_Keypad6:
-		return key.CodeKeypad6
-	case C.kVK_ANSI_Keypad7:
-		return key.CodeKeypad7
-	case C.kVK_ANSI_Keypad8:
-		return key.CodeKeypad8
-	case C.kVK_ANSI_Keypad9:
-		return key.CodeKeypad9
-	case C.kVK_ANSI_Keypad0:
-		return key.CodeKeypad0
-	case C.kVK_ANSI_KeypadDecimal:
-		return key.CodeKeypadFullStop
-	case C.kVK_ANSI_KeypadEquals:
-		return key.CodeKeypadEqualSign
-	case C.kVK_F13:
-		return key.CodeF13
-	case C.kVK_F14:
-		return key.CodeF14
-	case C.kVK_F15:
-		return key.CodeF15
-	case C.kVK_F16:
-		return key.CodeF16
-	case C.kVK_F17:
-		return key.CodeF17
-	case C.kVK_F18:
-		return key.CodeF18
-	case C.kVK_F19:
-		return key.CodeF19
-	case C.kVK_F20:
-		return key.CodeF20
-	// 116: Keyboard Execute
-	case C.kVK_Help:
-		return key.CodeHelp
-	// 118: Keyboard Menu
-	// 119: Keyboard Select
-	// 120: Keyboard Stop
-	// 121: Keyboard Again
-	// 122: Keyboard Undo
-	// 123: Keyboard Cut
-	// 124: Keyboard Copy
-	// 125: Keyboard Paste
-	// 126: Keyboard Find
-	case C.kVK_Mute:
-		return key.CodeMute
-	case C.kVK_VolumeUp:
-		return key.CodeVolumeUp
-	case C.kVK_VolumeDown:
-		return key.CodeVolumeDown
-	// 130: Keyboard Locking Caps Lock
-	// 131: Keyboard Locking Num Lock
-	// 132: Keyboard Locking Scroll Lock
-	// 133: Keyboard Comma
-	// 134: Keyboard Equal Sign
-	// ...: Bunch of stuff
-	case C.kVK_Control:
-		return key.CodeLeftControl
-	case C.kVK_Shift:
-		return key.CodeLeftShift
-	case C.kVK_Option:
-		return key.CodeLeftAlt
-	case C.kVK_Command:
-		return key.CodeLeftGUI
-	case C.kVK_RightControl:
-		return key.CodeRightControl
-	case C.kVK_RightShift:
-		return key.CodeRightShift
-	case C.kVK_RightOption:
-		return key.CodeRightAlt
-	// TODO key.CodeRightGUI
-	default:
-		return key.CodeUnknown
-	}
+func shareContextRecreate() error {
+	return errors.New("gldriver: share context re-creation not implemented on darwin")
 }
 
 func surfaceCreate() error {
@@ -9,6 +9,7 @@
 #include "_cgo_export.h"
 #include <pthread.h>
 #include <stdio.h>
+#include <string.h>
 
 #import <Cocoa/Cocoa.h>
 #import <Foundation/Foundation.h>
@@ -20,17 +21,51 @@
 enum
 {
     NSEventTypeScrollWheel = NSScrollWheel,
-    NSEventTypeKeyDown = NSKeyDown
+    NSEventTypeKeyDown = NSKeyDown,
+    NSEventModifierFlagCommand = NSCommandKeyMask
 };
 enum
 {
     NSWindowStyleMaskTitled = NSTitledWindowMask,
     NSWindowStyleMaskResizable = NSResizableWindowMask,
     NSWindowStyleMaskMiniaturizable = NSMiniaturizableWindowMask,
-    NSWindowStyleMaskClosable = NSClosableWindowMask
+    NSWindowStyleMaskClosable = NSClosableWindowMask,
+    NSWindowStyleMaskFullScreen = NSFullScreenWindowMask
 };
 #endif
 
+// shareContext is the share context. Every window's context shares its
+// objects, such as textures.
+static NSOpenGLContext* shareContext;
+
+uintptr_t shareContextCreate() {
+	NSOpenGLPixelFormatAttribute attr[] = {
+		NSOpenGLPFAOpenGLProfile, NSOpenGLProfileVersion3_2Core,
+		NSOpenGLPFAColorSize,     24,
+		NSOpenGLPFAAlphaSize,     8,
+		NSOpenGLPFADepthSize,     16,
+		NSOpenGLPFAStencilSize,   8,
+		NSOpenGLPFADoubleBuffer,
+		NSOpenGLPFAAllowOfflineRenderers,
+		0
+	};
+	id pixFormat = [[NSOpenGLPixelFormat alloc] initWithAttributes:attr];
+	if (!pixFormat) {
+		return 0;
+	}
+	shareContext = [[NSOpenGLContext alloc] initWithFormat:pixFormat shareContext:nil];
+	if (!shareContext) {
+		return 0;
+	}
+	[shareContext makeCurrentContext];
+
+	// As for prepareOpenGL, bind a default VBA.
+	GLuint vba;
+	glGenVertexArrays(1, &vba);
+	glBindVertexArray(vba);
+	return 1;
+}
+
 void makeCurrentContext(uintptr_t context) {
 	NSOpenGLContext* ctx = (NSOpenGLContext*)context;
 	[ctx makeCurrentContext];
@@ -49,11 +84,78 @@ uint64 threadID() {
 	return id;
 }
 
-@interface ScreenGLView : NSOpenGLView<NSWindowDelegate>
+@interface ScreenGLView : NSOpenGLView<NSWindowDelegate, NSTextInputClient>
 {
+	// markedText is the text that the input method is composing, or nil.
+	NSString* markedText;
+	// textInputRect is the text cursor, in pixels with a top left origin,
+	// as set by doSetTextInputRect.
+	NSRect textInputRect;
+	// keyEvent is the key press being interpreted by the input method, or
+	// nil. keyConsumed is whether the input method used it to compose text.
+	NSEvent* keyEvent;
+	BOOL keyConsumed;
+	// cursor is the cursor shown over the view, or nil for the arrow, as set
+	// by doSetCursor. cursorHidden is whether it is hidden instead.
+	NSCursor* cursor;
+	BOOL cursorHidden;
+	// pointerCaptured is whether the window has captured the pointer, as
+	// set by doSetPointerCapture.
+	BOOL pointerCaptured;
+	// penEraser is whether the pen on a drawing tablet, if any, near the
+	// tablet is an eraser, from the most recent proximity event.
+	BOOL penEraser;
+	// interceptClose is whether the user's requests to close the window are
+	// sent to Go instead of closing it, as set by doNewWindow. closing is
+	// whether Go is closing the window, by doCloseWindow.
+	BOOL interceptClose;
+	BOOL closing;
 }
+- (void)setTextInputRect:(NSRect)r;
+- (void)setScreenCursor:(NSCursor*)c;
+- (void)setCursorHidden:(BOOL)hidden;
+- (void)setPointerCaptured:(BOOL)captured;
+- (void)setInterceptClose:(BOOL)intercept;
+- (void)setClosing;
 @end
 
+// blankCursor returns a transparent cursor, for hiding the cursor over a
+// single view, unlike [NSCursor hide], which hides it everywhere.
+static NSCursor* blankCursor() {
+	static NSCursor* c = nil;
+	if (c == nil) {
+		NSImage* img = [[NSImage alloc] initWithSize:NSMakeSize(1, 1)];
+		c = [[NSCursor alloc] initWithImage:img hotSpot:NSZeroPoint];
+		[img release];
+	}
+	return c;
+}
+
+// unmodifiedRune returns what the key of the key event types with no
+// modifier keys held, or -1 if it types no character. Unlike
+// charactersByApplyingModifiers, charactersIgnoringModifiers, on older
+// systems, still applies the Shift key.
+static int32_t unmodifiedRune(NSEvent *theEvent) {
+	NSString* s = theEvent.charactersIgnoringModifiers;
+	if ([theEvent respondsToSelector:@selector(charactersByApplyingModifiers:)]) {
+		s = [theEvent charactersByApplyingModifiers:0];
+	}
+	if (s.length == 0) {
+		return -1;
+	}
+	uint8_t buf[4] = {0, 0, 0, 0};
+	if (![s getBytes:buf
+			maxLength:4
+			usedLength:nil
+			encoding:NSUTF32LittleEndianStringEncoding
+			options:NSStringEncodingConversionAllowLossy
+			range:[s rangeOfComposedCharacterSequenceAtIndex:0]
+			remainingRange:nil]) {
+		return -1;
+	}
+	return (int32_t)((uint32_t)buf[0]<<0 | (uint32_t)buf[1]<<8 | (uint32_t)buf[2]<<16 | (uint32_t)buf[3]<<24);
+}
+
 @implementation ScreenGLView
 - (void)prepareOpenGL {
 	[self setWantsBestResolutionOpenGLSurface:YES];
@@ -82,6 +184,9 @@ uint64 threadID() {
 	// 27" iMac,               2560x1440, 109ppi, backingScaleFactor=1, scale=1.51
 	// 27" Retina iMac,        5120x2880, 218ppi, backingScaleFactor=2, scale=3.03
 	NSScreen *screen = self.window.screen;
+	if (screen == nil) {
+		screen = [NSScreen mainScreen]; // The window is off screen.
+	}
 	double screenPixW = [screen frame].size.width * [screen backingScaleFactor];
 
 	CGDirectDisplayID display = (CGDirectDisplayID)[[[screen deviceDescription] valueForKey:@"NSScreenNumber"] intValue];
@@ -98,12 +203,29 @@ uint64 threadID() {
 	int w = r.size.width * [screen backingScaleFactor];
 	int h = r.size.height * [screen backingScaleFactor];
 
-	setGeom((GoUintptr)self, pixelsPerPt, w, h);
+	setGeom((GoUintptr)self, pixelsPerPt, [screen backingScaleFactor], w, h);
+}
+
+// callSetState reports the window's state, numbered as for a
+// screen.WindowState. Zooming, Cocoa's maximizing, has no notification of
+// its own, so callSetState is also called when the view is resized.
+- (void)callSetState {
+	NSWindow* window = self.window;
+	int state = 0;
+	if (window.miniaturized) {
+		state = 1;
+	} else if (window.styleMask & NSWindowStyleMaskFullScreen) {
+		state = 3;
+	} else if (window.zoomed) {
+		state = 2;
+	}
+	windowStateChanged((GoUintptr)self, state);
 }
 
 - (void)reshape {
 	[super reshape];
 	[self callSetGeom];
+	[self callSetState];
 }
 
 - (void)drawRect:(NSRect)theRect {
@@ -123,15 +245,64 @@ uint64 threadID() {
 	double x = p.x * scale;
 	double y = (h - p.y) * scale - 1; // flip origin from bottom-left to top-left.
 
-	double dx, dy;
+	if (pointerCaptured && (theEvent.type == NSEventTypeMouseMoved ||
+		theEvent.type == NSEventTypeLeftMouseDragged ||
+		theEvent.type == NSEventTypeRightMouseDragged ||
+		theEvent.type == NSEventTypeOtherMouseDragged)) {
+		// The event's deltas, unlike its location, are positive downwards.
+		relativeMouseEvent((GoUintptr)self, theEvent.deltaX * scale, theEvent.deltaY * scale);
+		return;
+	}
+
+	// Pens on drawing tablets send mouse events, with the tablet point
+	// subtype, that are sent as screen.PenEvents instead. The pen's types
+	// are those of screen.PenType.
+	//
+	// Trackpads send NSTouches, but they are not sent as touch.Events, as
+	// they do not touch the window.
+	int penType = -1;
+	switch (theEvent.type) {
+	case NSEventTypeMouseMoved:
+		penType = 0;
+		break;
+	case NSEventTypeLeftMouseDown:
+		penType = 1;
+		break;
+	case NSEventTypeLeftMouseDragged:
+		penType = 2;
+		break;
+	case NSEventTypeLeftMouseUp:
+		penType = 3;
+		break;
+	}
+	if (penType >= 0 && theEvent.subtype == NSEventSubtypeTabletPoint) {
+		// The tilts are from -1 to 1, and, unlike screen.PenEvent's, the
+		// Y tilt is positive upwards.
+		NSPoint tilt = theEvent.tilt;
+		int barrel = (theEvent.buttonMask & (NSEventButtonMaskPenLowerSide | NSEventButtonMaskPenUpperSide)) != 0;
+		penEvent((GoUintptr)self, x, y, penType, theEvent.pressure, tilt.x * 90, -tilt.y * 90,
+			penEraser, barrel, theEvent.modifierFlags);
+		return;
+	}
+
 	if (theEvent.type == NSEventTypeScrollWheel) {
-		dx = theEvent.scrollingDeltaX;
-		dy = theEvent.scrollingDeltaY;
+		scrollEvent((GoUintptr)self, x, y, theEvent.scrollingDeltaX, theEvent.scrollingDeltaY,
+			theEvent.hasPreciseScrollingDeltas, theEvent.phase, theEvent.momentumPhase, theEvent.modifierFlags);
+		return;
 	}
 
-	mouseEvent((GoUintptr)self, x, y, dx, dy, theEvent.type, theEvent.buttonNumber, theEvent.modifierFlags);
+	mouseEvent((GoUintptr)self, x, y, theEvent.type, theEvent.buttonNumber, theEvent.modifierFlags);
+}
+
+- (void)crossingEventNS:(NSEvent *)theEvent entered:(int)entered {
+	NSPoint p = [theEvent locationInWindow];
+	double h = self.frame.size.height;
+	double scale = [self.window.screen backingScaleFactor];
+	crossingEvent((GoUintptr)self, entered, p.x * scale, (h - p.y) * scale - 1);
 }
 
+- (void)mouseEntered:(NSEvent *)theEvent      { [self crossingEventNS:theEvent entered:1]; }
+- (void)mouseExited:(NSEvent *)theEvent       { [self crossingEventNS:theEvent entered:0]; }
 - (void)mouseMoved:(NSEvent *)theEvent        { [self mouseEventNS:theEvent]; }
 - (void)mouseDown:(NSEvent *)theEvent         { [self mouseEventNS:theEvent]; }
 - (void)mouseUp:(NSEvent *)theEvent           { [self mouseEventNS:theEvent]; }
@@ -144,6 +315,10 @@ uint64 threadID() {
 - (void)otherMouseDragged:(NSEvent *)theEvent { [self mouseEventNS:theEvent]; }
 - (void)scrollWheel:(NSEvent *)theEvent       { [self mouseEventNS:theEvent]; }
 
+- (void)tabletProximity:(NSEvent *)theEvent {
+	penEraser = theEvent.isEnteringProximity && theEvent.pointingDeviceType == NSPointingDeviceTypeEraser;
+}
+
 // raw modifier key presses
 - (void)flagsChanged:(NSEvent *)theEvent {
 	flagEvent((GoUintptr)self, theEvent.modifierFlags);
@@ -151,12 +326,28 @@ uint64 threadID() {
 
 // overrides special handling of escape and tab
 - (BOOL)performKeyEquivalent:(NSEvent *)theEvent {
-	[self key:theEvent];
+	if ((theEvent.modifierFlags & NSEventModifierFlagCommand) != 0) {
+		[self key:theEvent];
+	} else {
+		[self keyDown:theEvent];
+	}
 	return YES;
 }
 
-- (void)keyDown:(NSEvent *)theEvent { [self key:theEvent]; }
-- (void)keyUp:(NSEvent *)theEvent   { [self key:theEvent]; }
+// keyDown offers the key press to the input method, which calls back into
+// the NSTextInputClient methods below. It is sent as a key event unless the
+// input method used it to compose text.
+- (void)keyDown:(NSEvent *)theEvent {
+	keyEvent = theEvent;
+	keyConsumed = NO;
+	[self interpretKeyEvents:[NSArray arrayWithObject:theEvent]];
+	keyEvent = nil;
+	if (!keyConsumed) {
+		[self key:theEvent];
+	}
+}
+
+- (void)keyUp:(NSEvent *)theEvent { [self key:theEvent]; }
 
 - (void)key:(NSEvent *)theEvent {
 	NSRange range = [theEvent.characters rangeOfComposedCharacterSequenceAtIndex:0];
@@ -183,30 +374,227 @@ uint64 threadID() {
 	} else {
 		direction = 2;
 	}
-	keyEvent((GoUintptr)self, (int32_t)rune, direction, theEvent.keyCode, theEvent.modifierFlags);
+	keyEvent((GoUintptr)self, (int32_t)rune, unmodifiedRune(theEvent), direction, theEvent.keyCode, theEvent.modifierFlags);
+}
+
+// NSTextInputClient methods.
+
+- (void)insertText:(id)string replacementRange:(NSRange)replacementRange {
+	NSString* s = [string isKindOfClass:[NSAttributedString class]] ? [string string] : string;
+	BOOL composing = [self hasMarkedText];
+	[markedText release];
+	markedText = nil;
+	if (!composing && keyEvent != nil && [s isEqualToString:keyEvent.characters]) {
+		// Plain typing, which is sent as a key event.
+		return;
+	}
+	keyConsumed = YES;
+	textEvent((GoUintptr)self, "", 0, (char*)[s UTF8String]);
+}
+
+- (void)setMarkedText:(id)string selectedRange:(NSRange)selectedRange replacementRange:(NSRange)replacementRange {
+	NSString* s = [string isKindOfClass:[NSAttributedString class]] ? [string string] : string;
+	[markedText release];
+	markedText = [s copy];
+	keyConsumed = YES;
+
+	// Convert the cursor from UTF-16 code units to UTF-8 bytes.
+	NSUInteger i = MIN(selectedRange.location, [s length]);
+	int cursor = (int)strlen([[s substringToIndex:i] UTF8String]);
+	textEvent((GoUintptr)self, (char*)[s UTF8String], cursor, "");
+}
+
+- (void)unmarkText {
+	if (![self hasMarkedText]) {
+		return;
+	}
+	// Commit the text being composed.
+	NSString* s = markedText;
+	markedText = nil;
+	textEvent((GoUintptr)self, "", 0, (char*)[s UTF8String]);
+	[s release];
+}
+
+- (BOOL)hasMarkedText {
+	return markedText != nil && [markedText length] > 0;
+}
+
+- (NSRange)markedRange {
+	if (![self hasMarkedText]) {
+		return NSMakeRange(NSNotFound, 0);
+	}
+	return NSMakeRange(0, [markedText length]);
+}
+
+- (NSRange)selectedRange {
+	return NSMakeRange(NSNotFound, 0);
+}
+
+- (NSArray*)validAttributesForMarkedText {
+	return [NSArray array];
+}
+
+- (NSAttributedString*)attributedSubstringForProposedRange:(NSRange)range actualRange:(NSRangePointer)actualRange {
+	return nil;
+}
+
+- (NSUInteger)characterIndexForPoint:(NSPoint)point {
+	return NSNotFound;
+}
+
+- (NSRect)firstRectForCharacterRange:(NSRange)range actualRange:(NSRangePointer)actualRange {
+	// Convert textInputRect from pixels with a top left origin to screen
+	// co-ordinates, for placing the candidate window.
+	double scale = [self.window backingScaleFactor];
+	NSRect r = NSMakeRect(
+		textInputRect.origin.x / scale,
+		self.bounds.size.height - (textInputRect.origin.y + textInputRect.size.height) / scale,
+		textInputRect.size.width / scale,
+		textInputRect.size.height / scale);
+	r = [self convertRect:r toView:nil];
+	return [self.window convertRectToScreen:r];
+}
+
+- (void)doCommandBySelector:(SEL)selector {
+	// Commands, such as those bound to the arrow keys, are sent as key
+	// events by keyDown. Not calling super avoids a system beep.
+}
+
+- (void)setTextInputRect:(NSRect)r {
+	textInputRect = r;
+	[[self inputContext] invalidateCharacterCoordinates];
+}
+
+- (void)setScreenCursor:(NSCursor*)c {
+	[cursor release];
+	cursor = c;
+	[self.window invalidateCursorRectsForView:self];
+}
+
+- (void)setCursorHidden:(BOOL)hidden {
+	cursorHidden = hidden;
+	[self.window invalidateCursorRectsForView:self];
+}
+
+- (void)setPointerCaptured:(BOOL)captured {
+	if (captured == pointerCaptured) {
+		return;
+	}
+	pointerCaptured = captured;
+	// Dissociating the mouse from the cursor holds the cursor in place,
+	// while the mouse's motion is still reported in events' deltas.
+	CGAssociateMouseAndMouseCursorPosition(!captured);
+	if (captured) {
+		[NSCursor hide];
+	} else {
+		[NSCursor unhide];
+	}
+}
+
+- (void)setInterceptClose:(BOOL)intercept {
+	interceptClose = intercept;
+}
+
+- (void)setClosing {
+	closing = YES;
+}
+
+- (void)resetCursorRects {
+	NSCursor* c = cursorHidden ? blankCursor() : cursor;
+	if (c != nil) {
+		[self addCursorRect:[self bounds] cursor:c];
+	}
+}
+
+// sendDragEvent sends a screen.DragEvent of the given type, and accepts the
+// drag as a copy.
+- (NSDragOperation)sendDragEvent:(id<NSDraggingInfo>)sender type:(int)type {
+	NSPoint p = [sender draggingLocation];
+	double h = self.frame.size.height;
+	double scale = [self.window.screen backingScaleFactor];
+	dragEvent((GoUintptr)self, type, p.x * scale, (h - p.y) * scale, (GoUintptr)[sender draggingPasteboard]);
+	return NSDragOperationCopy;
+}
+
+- (NSDragOperation)draggingEntered:(id<NSDraggingInfo>)sender {
+	return [self sendDragEvent:sender type:0];
+}
+
+- (NSDragOperation)draggingUpdated:(id<NSDraggingInfo>)sender {
+	return [self sendDragEvent:sender type:1];
+}
+
+- (void)draggingExited:(id<NSDraggingInfo>)sender {
+	[self sendDragEvent:sender type:2];
+}
+
+- (BOOL)performDragOperation:(id<NSDraggingInfo>)sender {
+	[self sendDragEvent:sender type:3];
+	return YES;
 }
 
 - (void)windowDidChangeScreenProfile:(NSNotification *)notification {
 	[self callSetGeom];
 }
 
-// TODO: catch windowDidMiniaturize?
+// windowDidChangeScreen and windowDidChangeBackingProperties are called when
+// the window moves to a screen with a different resolution or scale factor,
+// or the screen's scale factor changes.
+- (void)windowDidChangeScreen:(NSNotification *)notification {
+	[self callSetGeom];
+}
+
+- (void)windowDidChangeBackingProperties:(NSNotification *)notification {
+	[self callSetGeom];
+}
+
+- (void)windowDidMiniaturize:(NSNotification *)notification {
+	[self callSetState];
+}
+
+- (void)windowDidDeminiaturize:(NSNotification *)notification {
+	[self callSetState];
+}
+
+- (void)windowDidEnterFullScreen:(NSNotification *)notification {
+	[self callSetState];
+}
+
+- (void)windowDidExitFullScreen:(NSNotification *)notification {
+	[self callSetState];
+}
 
 - (void)windowDidExpose:(NSNotification *)notification {
 	lifecycleVisible((GoUintptr)self, true);
 }
 
+- (void)windowDidChangeOcclusionState:(NSNotification *)notification {
+	visibilityChanged((GoUintptr)self, (self.window.occlusionState & NSWindowOcclusionStateVisible) != 0);
+}
+
 - (void)windowDidBecomeKey:(NSNotification *)notification {
 	lifecycleFocused((GoUintptr)self, true);
 }
 
 - (void)windowDidResignKey:(NSNotification *)notification {
+	// The pointer capture ends when the window loses the focus, as the
+	// pointer would otherwise stay held for an application that is not
+	// being used.
+	[self setPointerCaptured:NO];
 	lifecycleFocused((GoUintptr)self, false);
 	if ([NSApp isHidden]) {
 		lifecycleVisible((GoUintptr)self, false);
 	}
 }
 
+- (BOOL)windowShouldClose:(NSWindow *)sender {
+	if (!interceptClose || closing) {
+		return YES;
+	}
+	windowCloseRequested((GoUintptr)self);
+	return NO;
+}
+
 - (void)windowWillClose:(NSNotification *)notification {
 	// TODO: is this right? Closing a window via the top-left red button
 	// seems to return early without ever calling windowClosing.
@@ -214,9 +602,12 @@ uint64 threadID() {
 		return; // already called close
 	}
 
+	[self setPointerCaptured:NO];
 	windowClosing((GoUintptr)self);
 	[self.window.nextResponder release];
 	self.window.nextResponder = NULL;
+	[cursor release];
+	cursor = nil;
 }
 @end
 
@@ -227,6 +618,24 @@ uint64 threadID() {
 
 @implementation AppDelegate
 - (void)applicationDidFinishLaunching:(NSNotification *)aNotification {
+	[[[NSWorkspace sharedWorkspace] notificationCenter] addObserver:self
+		selector:@selector(accessibilityDisplayOptionsDidChange:)
+		name:NSWorkspaceAccessibilityDisplayOptionsDidChangeNotification
+		object:nil];
+	[[NSNotificationCenter defaultCenter] addObserver:self
+		selector:@selector(screenParametersDidChange:)
+		name:NSApplicationDidChangeScreenParametersNotification
+		object:nil];
+	// There is no public notification for changes to the system appearance,
+	// but System Preferences posts this one.
+	[[NSDistributedNotificationCenter defaultCenter] addObserver:self
+		selector:@selector(interfaceThemeDidChange:)
+		name:@"AppleInterfaceThemeChangedNotification"
+		object:nil];
+	[[NSNotificationCenter defaultCenter] addObserver:self
+		selector:@selector(keyboardSelectionDidChange:)
+		name:NSTextInputContextKeyboardSelectionDidChangeNotification
+		object:nil];
 	driverStarted();
 	[[NSRunningApplication currentApplication] activateWithOptions:(NSApplicationActivateAllWindows | NSApplicationActivateIgnoringOtherApps)];
 }
@@ -238,12 +647,78 @@ uint64 threadID() {
 - (void)applicationWillHide:(NSNotification *)aNotification {
 	lifecycleHideAll();
 }
+
+- (void)accessibilityDisplayOptionsDidChange:(NSNotification *)aNotification {
+	accessibilityChanged();
+}
+
+- (void)interfaceThemeDidChange:(NSNotification *)aNotification {
+	colorSchemeChanged();
+}
+
+- (void)keyboardSelectionDidChange:(NSNotification *)aNotification {
+	NSTextInputContext* ctx = aNotification.object;
+	NSString* source = ctx.selectedKeyboardInputSource;
+	if (source != nil) {
+		keyboardLayoutChanged((char*)[source UTF8String]);
+	}
+}
+
+- (void)screenParametersDidChange:(NSNotification *)aNotification {
+	displaysChanged();
+}
 @end
 
-uintptr_t doNewWindow(int width, int height, char* title) {
+void getAccessibilityPrefs(int* reduceMotion, int* increaseContrast, int* reduceTransparency) {
+	NSWorkspace* ws = [NSWorkspace sharedWorkspace];
+	*reduceMotion = 0;
+	if ([ws respondsToSelector:@selector(accessibilityDisplayShouldReduceMotion)]) {
+		*reduceMotion = ws.accessibilityDisplayShouldReduceMotion;
+	}
+	*increaseContrast = ws.accessibilityDisplayShouldIncreaseContrast;
+	*reduceTransparency = ws.accessibilityDisplayShouldReduceTransparency;
+}
+
+// isDarkMode reports whether the user has chosen the dark appearance. Unlike
+// NSApp.effectiveAppearance, the user defaults are safe to read from any
+// thread.
+int isDarkMode() {
+	NSString* style = [[NSUserDefaults standardUserDefaults] stringForKey:@"AppleInterfaceStyle"];
+	return [style isEqualToString:@"Dark"];
+}
+
+char* clipboardReadText() {
+	__block char* text = NULL;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		NSString* s = [[NSPasteboard generalPasteboard] stringForType:NSPasteboardTypeString];
+		if (s != nil) {
+			text = strdup([s UTF8String]);
+		}
+	});
+	return text;
+}
+
+int clipboardWriteText(char* text, int len) {
+	__block BOOL ok = NO;
+	NSString* s = [[NSString alloc] initWithBytes:text length:len encoding:NSUTF8StringEncoding];
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		NSPasteboard* pb = [NSPasteboard generalPasteboard];
+		[pb clearContents];
+		ok = [pb setString:s forType:NSPasteboardTypeString];
+	});
+	[s release];
+	return ok;
+}
+
+uintptr_t doNewWindow(int width, int height, int x, int y, int hasPosition, int fixedSize, char* title, int highPerformance, int interceptClose, int transparent, int undecorated, int samples) {
 	NSScreen *screen = [NSScreen mainScreen];
 	double w = (double)width / [screen backingScaleFactor];
 	double h = (double)height / [screen backingScaleFactor];
+	// Cocoa's screen co-ordinates have their origin at the bottom left of
+	// the main screen, and are in points, not pixels.
+	NSPoint topLeft = NSMakePoint(
+		(double)x / [screen backingScaleFactor],
+		screen.frame.size.height - (double)y / [screen backingScaleFactor]);
 	__block ScreenGLView* view = NULL;
 
 	dispatch_sync(dispatch_get_main_queue(), ^{
@@ -270,26 +745,87 @@ uintptr_t doNewWindow(int width, int height, char* title) {
 				styleMask:NSWindowStyleMaskTitled
 				backing:NSBackingStoreBuffered
 				defer:NO];
-		window.styleMask |= NSWindowStyleMaskResizable;
+		if (!fixedSize) {
+			window.styleMask |= NSWindowStyleMaskResizable;
+		}
 		window.styleMask |= NSWindowStyleMaskMiniaturizable;
 		window.styleMask |= NSWindowStyleMaskClosable;
+		window.collectionBehavior |= NSWindowCollectionBehaviorFullScreenPrimary;
 		window.title = name;
 		window.displaysWhenScreenProfileChanges = YES;
-		[window cascadeTopLeftFromPoint:NSMakePoint(20,20)];
+		if (undecorated) {
+			// The content covers the title bar, which is transparent and
+			// has neither a title nor buttons, but the window is still
+			// titled, so that it can become key and be minimized.
+			window.styleMask |= NSWindowStyleMaskFullSizeContentView;
+			window.titlebarAppearsTransparent = YES;
+			window.titleVisibility = NSWindowTitleHidden;
+			[window standardWindowButton:NSWindowCloseButton].hidden = YES;
+			[window standardWindowButton:NSWindowMiniaturizeButton].hidden = YES;
+			[window standardWindowButton:NSWindowZoomButton].hidden = YES;
+		}
+		if (hasPosition) {
+			[window setFrameTopLeftPoint:topLeft];
+		} else {
+			[window cascadeTopLeftFromPoint:NSMakePoint(20,20)];
+		}
 		[window setAcceptsMouseMovedEvents:YES];
 
-		NSOpenGLPixelFormatAttribute attr[] = {
+		// Allowing offline renderers lets the system keep using the
+		// integrated GPU on a machine with automatic graphics switching.
+		// Disallowing them forces a switch to the discrete GPU.
+		NSOpenGLPixelFormatAttribute attr[20] = {
 			NSOpenGLPFAOpenGLProfile, NSOpenGLProfileVersion3_2Core,
 			NSOpenGLPFAColorSize,     24,
 			NSOpenGLPFAAlphaSize,     8,
 			NSOpenGLPFADepthSize,     16,
+			NSOpenGLPFAStencilSize,   8,
 			NSOpenGLPFADoubleBuffer,
-			NSOpenGLPFAAllowOfflineRenderers,
-			0
 		};
-		id pixFormat = [[NSOpenGLPixelFormat alloc] initWithAttributes:attr];
+		int n = 11;
+		if (!highPerformance) {
+			attr[n++] = NSOpenGLPFAAllowOfflineRenderers;
+		}
+		id pixFormat = nil;
+		if (samples > 1) {
+			// Multisampling is only a request, and falls back to a single
+			// sample if no renderer supports it.
+			attr[n+0] = NSOpenGLPFAMultisample;
+			attr[n+1] = NSOpenGLPFASampleBuffers;
+			attr[n+2] = 1;
+			attr[n+3] = NSOpenGLPFASamples;
+			attr[n+4] = samples;
+			attr[n+5] = 0;
+			pixFormat = [[NSOpenGLPixelFormat alloc] initWithAttributes:attr];
+		}
+		if (!pixFormat) {
+			attr[n] = 0;
+			pixFormat = [[NSOpenGLPixelFormat alloc] initWithAttributes:attr];
+		}
 		view = [[ScreenGLView alloc] initWithFrame:rect pixelFormat:pixFormat];
+		NSOpenGLContext* ctx = [[NSOpenGLContext alloc] initWithFormat:pixFormat shareContext:shareContext];
+		if (ctx) {
+			[view setOpenGLContext:ctx];
+		} else {
+			// This can happen if the pixel formats have incompatible
+			// renderers, such as for highPerformance.
+			NSLog(@"gldriver: cannot share GL objects with the window's context; textures will not be drawn");
+		}
+		if (transparent) {
+			// The window, and its OpenGL surface, are composited with
+			// per-pixel alpha over whatever is behind them.
+			window.opaque = NO;
+			window.backgroundColor = [NSColor clearColor];
+			GLint opacity = 0;
+			[[view openGLContext] setValues:&opacity forParameter:NSOpenGLContextParameterSurfaceOpacity];
+		}
+		[view setInterceptClose:interceptClose];
 		[window setContentView:view];
+		// The tracking area follows the view's size, so that the view is
+		// told whenever the pointer enters or leaves the window's content.
+		[view addTrackingArea:[[NSTrackingArea alloc] initWithRect:NSZeroRect
+			options:NSTrackingMouseEnteredAndExited | NSTrackingActiveAlways | NSTrackingInVisibleRect
+			owner:view userInfo:nil]];
 		[window setDelegate:view];
 		[window makeFirstResponder:view];
 	});
@@ -297,16 +833,177 @@ uintptr_t doNewWindow(int width, int height, char* title) {
 	return (uintptr_t)view;
 }
 
-void doShowWindow(uintptr_t viewID) {
+void doShowWindow(uintptr_t viewID, int hidden) {
 	ScreenGLView* view = (ScreenGLView*)viewID;
 	dispatch_async(dispatch_get_main_queue(), ^{
+		if (hidden) {
+			// A window that is never ordered front is never drawn, so
+			// AppKit never calls prepareOpenGL. Call it ourselves, so
+			// that the window can still be rendered to.
+			[view prepareOpenGL];
+			return;
+		}
 		[view.window makeKeyAndOrderFront:view.window];
 	});
 }
 
+void doSetTitle(uintptr_t viewID, char* title) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	NSString* name = [[NSString alloc] initWithUTF8String:title];
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		if (view.window != nil) {
+			view.window.title = name;
+		}
+	});
+	[name release];
+}
+
+void doSetSize(uintptr_t viewID, int width, int height) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		if (window == nil) {
+			return; // The window has been closed.
+		}
+		double scale = [window backingScaleFactor];
+		NSRect old = window.frame;
+		NSRect frame = [window frameRectForContentRect:NSMakeRect(0, 0, width / scale, height / scale)];
+		// Keep the top left corner where it is. Cocoa's origin is the
+		// bottom left corner.
+		frame.origin.x = old.origin.x;
+		frame.origin.y = old.origin.y + old.size.height - frame.size.height;
+		[window setFrame:frame display:YES];
+	});
+}
+
+void doSetPosition(uintptr_t viewID, int x, int y) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		// As for doNewWindow, convert from pixels with a top left origin.
+		NSScreen *screen = [NSScreen mainScreen];
+		NSPoint topLeft = NSMakePoint(
+			(double)x / [screen backingScaleFactor],
+			screen.frame.size.height - (double)y / [screen backingScaleFactor]);
+		[view.window setFrameTopLeftPoint:topLeft]; // A no-op if closed.
+	});
+}
+
+void doMinimize(uintptr_t viewID) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[view.window miniaturize:nil]; // A no-op if closed.
+	});
+}
+
+// doMaximize and doRestore leave fullscreen, if the window is fullscreen,
+// before zooming or unzooming it.
+//
+// TODO: wait for the animation out of fullscreen to finish before zooming,
+// which may otherwise be ignored.
+void doMaximize(uintptr_t viewID) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		if (window.styleMask & NSWindowStyleMaskFullScreen) {
+			[window toggleFullScreen:nil];
+		}
+		[window deminiaturize:nil];
+		if (!window.zoomed) {
+			[window zoom:nil];
+		}
+	});
+}
+
+void doRestore(uintptr_t viewID) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		if (window.styleMask & NSWindowStyleMaskFullScreen) {
+			[window toggleFullScreen:nil];
+		}
+		[window deminiaturize:nil];
+		if (window.zoomed) {
+			[window zoom:nil];
+		}
+	});
+}
+
+void doSetColorSpace(uintptr_t viewID, int p3) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		view.window.colorSpace = p3 ? [NSColorSpace displayP3ColorSpace] : [NSColorSpace sRGBColorSpace];
+	});
+}
+
+void doSetFullscreen(uintptr_t viewID, int fullscreen) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		BOOL isFullscreen = (window.styleMask & NSWindowStyleMaskFullScreen) != 0;
+		if (isFullscreen != (fullscreen != 0)) {
+			[window toggleFullScreen:nil];
+		}
+	});
+}
+
+void doSetTextInputRect(uintptr_t viewID, int x, int y, int width, int height) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[view setTextInputRect:NSMakeRect(x, y, width, height)];
+	});
+}
+
+void doSetCursor(uintptr_t viewID, uintptr_t cursorID) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	NSCursor* cursor = (NSCursor*)cursorID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (view.window == nil) {
+			[cursor release]; // The window has been closed.
+			return;
+		}
+		[view setScreenCursor:cursor];
+	});
+}
+
+void doSetPointerCapture(uintptr_t viewID, int capture) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (view.window == nil || !view.window.isKeyWindow) {
+			capture = 0; // Only the key window can capture the pointer.
+		}
+		[view setPointerCaptured:capture];
+	});
+}
+
+void doSetCursorVisible(uintptr_t viewID, int visible) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[view setCursorHidden:!visible];
+	});
+}
+
+void doGetGeometry(uintptr_t viewID, int* x, int* y, int* width, int* height) {
+	ScreenGLView* view = (ScreenGLView*)viewID;
+	*x = *y = *width = *height = 0;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		if (view.window == nil) {
+			return; // The window has been closed.
+		}
+		NSScreen *screen = [NSScreen mainScreen];
+		double scale = [screen backingScaleFactor];
+		NSRect frame = view.window.frame;
+		*x = frame.origin.x * scale;
+		*y = (screen.frame.size.height - (frame.origin.y + frame.size.height)) * scale;
+		NSRect r = [view convertRectToBacking:[view bounds]];
+		*width = r.size.width;
+		*height = r.size.height;
+	});
+}
+
 void doCloseWindow(uintptr_t viewID) {
 	ScreenGLView* view = (ScreenGLView*)viewID;
 	dispatch_sync(dispatch_get_main_queue(), ^{
+		[view setClosing];
 		[view.window performClose:view];
 	});
 }
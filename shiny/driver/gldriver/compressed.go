@@ -0,0 +1,116 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gldriver
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/gl"
+)
+
+// glCompressedFormats are the OpenGL internal formats of each
+// screen.CompressedFormat.
+var glCompressedFormats = [...]gl.Enum{
+	screen.CompressedBC1:       0x83F1, // GL_COMPRESSED_RGBA_S3TC_DXT1_EXT
+	screen.CompressedBC3:       0x83F3, // GL_COMPRESSED_RGBA_S3TC_DXT5_EXT
+	screen.CompressedETC2RGB8:  0x9274, // GL_COMPRESSED_RGB8_ETC2
+	screen.CompressedETC2RGBA8: 0x9278, // GL_COMPRESSED_RGBA8_ETC2_EAC
+	screen.CompressedASTC4x4:   0x93B0, // GL_COMPRESSED_RGBA_ASTC_4x4_KHR
+}
+
+// CompressedFormats implements screen.CompressedScreen.
+func (s *screenImpl) CompressedFormats() []screen.CompressedFormat {
+	s.shareMu.Lock()
+	defer s.shareMu.Unlock()
+
+	if err := s.startShare(); err != nil {
+		return nil
+	}
+	return s.compressedFormats
+}
+
+// compressedFormats returns the compressed formats that glctx supports.
+//
+// A desktop OpenGL 3.0 or later context, such as the core profile contexts
+// on macOS, cannot list its extensions with glGetString, only with
+// glGetStringi, which the gl package lacks. Every desktop GPU supports the
+// BC formats, though, and they support ETC2 from OpenGL 4.3.
+func compressedFormats(glctx gl.Context) []screen.CompressedFormat {
+	es, major, minor := parseGLVersion(glctx.GetString(gl.VERSION))
+	ext := ""
+	if es || major < 3 {
+		ext = " " + glctx.GetString(gl.EXTENSIONS) + " "
+	}
+	has := func(names ...string) bool {
+		for _, name := range names {
+			if strings.Contains(ext, " "+name+" ") {
+				return true
+			}
+		}
+		return false
+	}
+
+	var f []screen.CompressedFormat
+	if !es || has("GL_EXT_texture_compression_s3tc") {
+		f = append(f, screen.CompressedBC1, screen.CompressedBC3)
+	} else if has("GL_EXT_texture_compression_dxt1") && has("GL_ANGLE_texture_compression_dxt5") {
+		// ANGLE exposes each of the S3TC formats as its own extension.
+		f = append(f, screen.CompressedBC1, screen.CompressedBC3)
+	}
+	if (es && major >= 3) || (!es && (major > 4 || major == 4 && minor >= 3)) || has("GL_ARB_ES3_compatibility") {
+		f = append(f, screen.CompressedETC2RGB8, screen.CompressedETC2RGBA8)
+	}
+	if has("GL_KHR_texture_compression_astc_ldr") {
+		f = append(f, screen.CompressedASTC4x4)
+	}
+	return f
+}
+
+// parseGLVersion parses a GL_VERSION string, such as "4.1 ATI-4.6.21" or
+// "OpenGL ES 3.0 (ANGLE 2.1.0)".
+func parseGLVersion(v string) (es bool, major, minor int) {
+	if strings.HasPrefix(v, "OpenGL ES ") {
+		es, v = true, v[len("OpenGL ES "):]
+	}
+	fmt.Sscanf(v, "%d.%d", &major, &minor)
+	return es, major, minor
+}
+
+// supportsCompressed returns whether s.share supports f. It must only be
+// called while holding s.shareMu, after startShare.
+func (s *screenImpl) supportsCompressed(f screen.CompressedFormat) bool {
+	for _, g := range s.compressedFormats {
+		if g == f {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadCompressed implements screen.CompressedUploader.
+func (t *textureImpl) UploadCompressed(dp image.Point, src *screen.CompressedImage, sr image.Rectangle) {
+	if t.compressed == screen.CompressedNone {
+		return
+	}
+	dr, pix := drawer.ClipCompressed(t.size, t.compressed, dp, src, sr)
+	if pix == nil {
+		return
+	}
+
+	t.s.shareMu.Lock()
+	defer t.s.shareMu.Unlock()
+
+	if t.id == (gl.Texture{}) {
+		return // Released.
+	}
+	glctx := t.s.share
+	glctx.BindTexture(gl.TEXTURE_2D, t.id)
+	glctx.CompressedTexSubImage2D(gl.TEXTURE_2D, 0, dr.Min.X, dr.Min.Y, dr.Dx(), dr.Dy(), glCompressedFormats[t.compressed], pix)
+	glctx.Flush()
+}
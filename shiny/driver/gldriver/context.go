@@ -12,24 +12,48 @@ import (
 
 // NewContext creates an OpenGL ES context with a dedicated processing thread.
 func NewContext() (gl.Context, error) {
+	glctx, _, err := startContext(surfaceCreate, "")
+	return glctx, err
+}
+
+// startContext starts a dedicated processing thread for a new gl.Context.
+// The create function is called on that thread, and should create a native
+// GL context and make it current. The context is traced, as a context of the
+// given kind, unless kind is empty.
+//
+// It also returns a channel that the thread receives functions from, and
+// calls, for the native GL calls that the gl package has no methods for.
+func startContext(create func() error, kind string) (gl.Context, chan func(), error) {
 	glctx, worker := gl.NewContext()
+	if kind != "" {
+		glctx = traceContext(glctx, kind)
+	}
+	run := traceRun(glctx)
+	if run == nil {
+		run = make(chan func())
+	}
 
 	errCh := make(chan error)
 	workAvailable := worker.WorkAvailable()
 	go func() {
 		runtime.LockOSThread()
-		err := surfaceCreate()
+		err := create()
 		errCh <- err
 		if err != nil {
 			return
 		}
 
-		for range workAvailable {
-			worker.DoWork()
+		for {
+			select {
+			case <-workAvailable:
+				worker.DoWork()
+			case f := <-run:
+				f()
+			}
 		}
 	}()
 	if err := <-errCh; err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return glctx, nil
+	return glctx, run, nil
 }
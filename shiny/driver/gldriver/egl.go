@@ -9,11 +9,13 @@ package gldriver
 const (
 	_EGL_DONT_CARE = -1
 
-	_EGL_NO_SURFACE = 0
-	_EGL_NO_CONTEXT = 0
-	_EGL_NO_DISPLAY = 0
+	_EGL_NO_SURFACE      = 0
+	_EGL_NO_CONTEXT      = 0
+	_EGL_NO_DISPLAY      = 0
+	_EGL_DEFAULT_DISPLAY = 0
 
 	_EGL_OPENGL_ES2_BIT = 0x04 // EGL_RENDERABLE_TYPE mask
+	_EGL_PBUFFER_BIT    = 0x01 // EGL_SURFACE_TYPE mask
 	_EGL_WINDOW_BIT     = 0x04 // EGL_SURFACE_TYPE mask
 
 	_EGL_OPENGL_ES_API   = 0x30A0
@@ -26,11 +28,20 @@ const (
 	_EGL_RED_SIZE        = 0x3024
 	_EGL_DEPTH_SIZE      = 0x3025
 	_EGL_STENCIL_SIZE    = 0x3026
+	_EGL_SAMPLES         = 0x3031
 	_EGL_SAMPLE_BUFFERS  = 0x3032
 	_EGL_CONFIG_CAVEAT   = 0x3027
 	_EGL_NONE            = 0x3038
+	_EGL_HEIGHT          = 0x3056
+	_EGL_WIDTH           = 0x3057
 
 	_EGL_CONTEXT_CLIENT_VERSION = 0x3098
+
+	_EGL_EXTENSIONS = 0x3055
+
+	// EGL_KHR_gl_colorspace.
+	_EGL_GL_COLORSPACE_KHR      = 0x309D
+	_EGL_GL_COLORSPACE_SRGB_KHR = 0x3089
 )
 
 // ANGLE specific options found in eglext.h
@@ -49,6 +60,11 @@ const (
 
 	_EGL_PLATFORM_ANGLE_TYPE_OPENGL_ANGLE   = 0x320D
 	_EGL_PLATFORM_ANGLE_TYPE_OPENGLES_ANGLE = 0x320E
+
+	// EGL_ANGLE_power_preference.
+	_EGL_POWER_PREFERENCE_ANGLE = 0x3482
+	_EGL_LOW_POWER_ANGLE        = 0x0001
+	_EGL_HIGH_POWER_ANGLE       = 0x0002
 )
 
 const (
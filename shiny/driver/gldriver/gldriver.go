@@ -3,12 +3,32 @@
 // license that can be found in the LICENSE file.
 
 // Package gldriver provides an OpenGL driver for accessing a screen.
+//
+// Rendering problems can be debugged by setting the SHINY_GL_TRACE
+// environment variable to a comma-separated list of:
+//
+//	calls   to log every GL call, with its arguments and result.
+//	errors  to check for GL errors after every GL call, and log the calls
+//	        that fail. Like calls, it makes every GL call much slower.
+//	labels  to label textures, framebuffers and programs with the
+//	        KHR_debug extension, where the GL implementation has it, for
+//	        graphics debuggers such as RenderDoc.
+//	frames  to log each window's GL calls as one trace per frame, when the
+//	        window is published, instead of as they are made.
+//	all     for all of the above.
+//
+// Logged calls name their objects by those labels, whether or not the GL
+// implementation has the extension.
 package gldriver // import "golang.org/x/exp/shiny/driver/gldriver"
 
 import (
 	"encoding/binary"
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
 	"math"
+	"strings"
 
 	"golang.org/x/exp/shiny/driver/internal/errscreen"
 	"golang.org/x/exp/shiny/screen"
@@ -40,7 +60,8 @@ func mul(a, b f64.Aff3) f64.Aff3 {
 	}
 }
 
-// writeAff3 must only be called while holding windowImpl.glctxMu.
+// writeAff3 must only be called while holding the mutex for glctx:
+// windowImpl.glctxMu or screenImpl.shareMu.
 func writeAff3(glctx gl.Context, u gl.Uniform, a f64.Aff3) {
 	var m [9]float32
 	m[0*3+0] = float32(a[0*3+0])
@@ -85,7 +106,299 @@ func f32Bytes(byteOrder binary.ByteOrder, values ...float32) []byte {
 	return b
 }
 
-// compileProgram must only be called while holding windowImpl.glctxMu.
+// programs are the GL programs, and their vertex buffers, for drawing textures
+// and uniform colors. Program objects are shared by all of the GL contexts in
+// a share group, but so are the values of their uniforms, such as mvp, so
+// each context compiles its own, lest concurrent draws to different windows
+// overwrite each other's uniforms.
+type programs struct {
+	// linear is whether the programs draw on an sRGB-encoded back buffer, as
+	// for a window with NewWindowOptions.LinearBlending, in which case the
+	// programs that draw colors output them in linear light. It is immutable.
+	linear bool
+
+	texture struct {
+		program gl.Program
+		pos     gl.Attrib
+		mvp     gl.Uniform
+		uvp     gl.Uniform
+		inUV    gl.Attrib
+		sample  gl.Uniform
+		depth   gl.Uniform
+		alpha   gl.Uniform
+		quad    gl.Buffer
+	}
+	fill struct {
+		program gl.Program
+		pos     gl.Attrib
+		mvp     gl.Uniform
+		color   gl.Uniform
+		depth   gl.Uniform
+		quad    gl.Buffer
+	}
+	path struct {
+		program gl.Program
+		pos     gl.Attrib
+		size    gl.Uniform
+		offset  gl.Uniform
+		color   gl.Uniform
+		verts   gl.Buffer
+	}
+	pathCover struct {
+		program gl.Program
+		pos     gl.Attrib
+		size    gl.Uniform
+		color   gl.Uniform
+		sample  gl.Uniform
+	}
+	batch struct {
+		program gl.Program
+		pos     gl.Attrib
+		inUV    gl.Attrib
+		size    gl.Uniform
+		depth   gl.Uniform
+		alpha   gl.Uniform
+		sample  gl.Uniform
+		verts   gl.Buffer
+	}
+	yuv struct {
+		program gl.Program
+		pos     gl.Attrib
+		mvp     gl.Uniform
+		chroma  gl.Uniform
+		y, u, v gl.Uniform
+		nv12    gl.Uniform
+		yOffset gl.Uniform
+		yScale  gl.Uniform
+		uCoef   gl.Uniform
+		vCoef   gl.Uniform
+		quad    gl.Buffer
+		// planes are the textures that UploadYUV uploads a YUVImage's Y, U
+		// and V planes to, and sizes are their sizes.
+		planes [3]gl.Texture
+		sizes  [3]image.Point
+	}
+}
+
+// compile compiles those of p's programs that are not already compiled, and
+// creates their buffers, so that the first frame to use each does not stall
+// while the GL implementation compiles it. It must only be called while
+// holding the mutex for glctx.
+func (p *programs) compile(glctx gl.Context) error {
+	for _, c := range [...]struct {
+		program *gl.Program
+		name    string
+		compile func(gl.Context) error
+	}{
+		{&p.texture.program, "texture", p.compileTexture},
+		{&p.fill.program, "fill", p.compileFill},
+		{&p.path.program, "path", p.compilePath},
+		{&p.pathCover.program, "pathCover", p.compilePathCover},
+		{&p.batch.program, "batch", p.compileBatch},
+		{&p.yuv.program, "yuv", p.compileYUV},
+	} {
+		if c.program.Value != 0 {
+			continue
+		}
+		if err := c.compile(glctx); err != nil {
+			return err
+		}
+		labelObject(glctx, _GL_PROGRAM_KHR, c.program.Value, c.name+" program")
+	}
+	return nil
+}
+
+// warmUp draws nothing, with the texture and fill programs and with and
+// without blending, as many GL implementations only finish building the
+// pipeline state for a program, such as its blending, on its first draw. It
+// must only be called after compile, while holding the mutex for glctx.
+func (p *programs) warmUp(glctx gl.Context) {
+	// A zero matrix collapses the quad to a point, so that nothing is drawn.
+	var mvp f64.Aff3
+	for _, op := range [...]draw.Op{draw.Src, draw.Over} {
+		doFill(p, glctx, mvp, color.Transparent, op, nil, 0)
+		doDraw(p, glctx, mvp, gl.Texture{}, image.Point{1, 1}, image.Rectangle{}, op, nil, 0)
+	}
+	glctx.Flush()
+}
+
+// useTexture compiles, if need be, and uses p's texture program. It must only
+// be called while holding the mutex for glctx.
+func (p *programs) useTexture(glctx gl.Context) {
+	if !glctx.IsProgram(p.texture.program) {
+		if err := p.compileTexture(glctx); err != nil {
+			panic(err.Error())
+		}
+	}
+	glctx.UseProgram(p.texture.program)
+}
+
+// compileTexture compiles p's texture program. It must only be called while
+// holding the mutex for glctx.
+func (p *programs) compileTexture(glctx gl.Context) error {
+	prog, err := compileProgram(glctx, textureVertexSrc, p.fragmentSrc(textureFragmentSrc))
+	if err != nil {
+		return err
+	}
+	p.texture.program = prog
+	p.texture.pos = glctx.GetAttribLocation(prog, "pos")
+	p.texture.mvp = glctx.GetUniformLocation(prog, "mvp")
+	p.texture.uvp = glctx.GetUniformLocation(prog, "uvp")
+	p.texture.inUV = glctx.GetAttribLocation(prog, "inUV")
+	p.texture.sample = glctx.GetUniformLocation(prog, "sample")
+	p.texture.depth = glctx.GetUniformLocation(prog, "depth")
+	p.texture.alpha = glctx.GetUniformLocation(prog, "alpha")
+	p.texture.quad = glctx.CreateBuffer()
+
+	glctx.BindBuffer(gl.ARRAY_BUFFER, p.texture.quad)
+	glctx.BufferData(gl.ARRAY_BUFFER, quadCoords, gl.STATIC_DRAW)
+	return nil
+}
+
+// useFill compiles, if need be, and uses p's fill program. It must only be
+// called while holding the mutex for glctx.
+func (p *programs) useFill(glctx gl.Context) {
+	if !glctx.IsProgram(p.fill.program) {
+		if err := p.compileFill(glctx); err != nil {
+			panic(err.Error())
+		}
+	}
+	glctx.UseProgram(p.fill.program)
+}
+
+// compileFill compiles p's fill program. It must only be called while holding
+// the mutex for glctx.
+func (p *programs) compileFill(glctx gl.Context) error {
+	prog, err := compileProgram(glctx, fillVertexSrc, p.fragmentSrc(fillFragmentSrc))
+	if err != nil {
+		return err
+	}
+	p.fill.program = prog
+	p.fill.pos = glctx.GetAttribLocation(prog, "pos")
+	p.fill.mvp = glctx.GetUniformLocation(prog, "mvp")
+	p.fill.color = glctx.GetUniformLocation(prog, "color")
+	p.fill.depth = glctx.GetUniformLocation(prog, "depth")
+	p.fill.quad = glctx.CreateBuffer()
+
+	glctx.BindBuffer(gl.ARRAY_BUFFER, p.fill.quad)
+	glctx.BufferData(gl.ARRAY_BUFFER, quadCoords, gl.STATIC_DRAW)
+	return nil
+}
+
+// usePath compiles, if need be, and uses p's path program, which draws a
+// path's triangles in pixel space. It must only be called while holding the
+// mutex for glctx.
+func (p *programs) usePath(glctx gl.Context) {
+	if !glctx.IsProgram(p.path.program) {
+		if err := p.compilePath(glctx); err != nil {
+			panic(err.Error())
+		}
+	}
+	glctx.UseProgram(p.path.program)
+}
+
+// compilePath compiles p's path program. It must only be called while holding
+// the mutex for glctx.
+func (p *programs) compilePath(glctx gl.Context) error {
+	prog, err := compileProgram(glctx, pathVertexSrc, pathFragmentSrc)
+	if err != nil {
+		return err
+	}
+	p.path.program = prog
+	p.path.pos = glctx.GetAttribLocation(prog, "pos")
+	p.path.size = glctx.GetUniformLocation(prog, "size")
+	p.path.offset = glctx.GetUniformLocation(prog, "offset")
+	p.path.color = glctx.GetUniformLocation(prog, "color")
+	p.path.verts = glctx.CreateBuffer()
+	return nil
+}
+
+// usePathCover compiles, if need be, and uses p's path cover program, which
+// composites a path's accumulated coverage. It must only be called while
+// holding the mutex for glctx.
+func (p *programs) usePathCover(glctx gl.Context) {
+	if !glctx.IsProgram(p.pathCover.program) {
+		if err := p.compilePathCover(glctx); err != nil {
+			panic(err.Error())
+		}
+	}
+	glctx.UseProgram(p.pathCover.program)
+}
+
+// compilePathCover compiles p's path cover program. It must only be called while
+// holding the mutex for glctx.
+func (p *programs) compilePathCover(glctx gl.Context) error {
+	prog, err := compileProgram(glctx, pathCoverVertexSrc, p.fragmentSrc(pathCoverFragmentSrc))
+	if err != nil {
+		return err
+	}
+	p.pathCover.program = prog
+	p.pathCover.pos = glctx.GetAttribLocation(prog, "pos")
+	p.pathCover.size = glctx.GetUniformLocation(prog, "size")
+	p.pathCover.color = glctx.GetUniformLocation(prog, "color")
+	p.pathCover.sample = glctx.GetUniformLocation(prog, "sample")
+	return nil
+}
+
+// useBatch compiles, if need be, and uses p's batch program, which draws many
+// texture quads given in pixel space. It must only be called while holding
+// the mutex for glctx.
+func (p *programs) useBatch(glctx gl.Context) {
+	if !glctx.IsProgram(p.batch.program) {
+		if err := p.compileBatch(glctx); err != nil {
+			panic(err.Error())
+		}
+	}
+	glctx.UseProgram(p.batch.program)
+}
+
+// compileBatch compiles p's batch program. It must only be called while holding
+// the mutex for glctx.
+func (p *programs) compileBatch(glctx gl.Context) error {
+	prog, err := compileProgram(glctx, batchVertexSrc, p.fragmentSrc(textureFragmentSrc))
+	if err != nil {
+		return err
+	}
+	p.batch.program = prog
+	p.batch.pos = glctx.GetAttribLocation(prog, "pos")
+	p.batch.inUV = glctx.GetAttribLocation(prog, "inUV")
+	p.batch.size = glctx.GetUniformLocation(prog, "size")
+	p.batch.depth = glctx.GetUniformLocation(prog, "depth")
+	p.batch.alpha = glctx.GetUniformLocation(prog, "alpha")
+	p.batch.sample = glctx.GetUniformLocation(prog, "sample")
+	p.batch.verts = glctx.CreateBuffer()
+	return nil
+}
+
+// fragmentSrc returns the source of a fragment shader, fSrc, that outputs an
+// alpha-premultiplied, sRGB-encoded color. If p.linear is set, that color is
+// decoded to linear light, which the back buffer blends with the linear light
+// of its decoded pixels before encoding the result.
+func (p *programs) fragmentSrc(fSrc string) string {
+	if !p.linear {
+		return fSrc
+	}
+	return strings.Replace(fSrc, "void main() {", "void srgbMain() {", 1) + linearMainSrc
+}
+
+// linearMainSrc is the main function of fragmentSrc's fragment shaders, which
+// converts the color output by srgbMain, un-premultiplied, to linear light.
+const linearMainSrc = `
+void main() {
+	srgbMain();
+	vec4 c = gl_FragColor;
+	if (c.a > 0.0) {
+		vec3 s = c.rgb / c.a;
+		vec3 lo = s / 12.92;
+		vec3 hi = pow((s + 0.055) / 1.055, vec3(2.4));
+		c.rgb = mix(lo, hi, step(0.04045, s)) * c.a;
+	}
+	gl_FragColor = c;
+}
+`
+
+// compileProgram must only be called while holding the mutex for glctx:
+// windowImpl.glctxMu or screenImpl.shareMu.
 func compileProgram(glctx gl.Context, vSrc, fSrc string) (gl.Program, error) {
 	program := glctx.CreateProgram()
 	if program.Value == 0 {
@@ -117,7 +430,8 @@ func compileProgram(glctx gl.Context, vSrc, fSrc string) (gl.Program, error) {
 	return program, nil
 }
 
-// compileShader must only be called while holding windowImpl.glctxMu.
+// compileShader must only be called while holding the mutex for glctx:
+// windowImpl.glctxMu or screenImpl.shareMu.
 func compileShader(glctx gl.Context, shaderType gl.Enum, src string) (gl.Shader, error) {
 	shader := glctx.CreateShader(shaderType)
 	if shader.Value == 0 {
@@ -131,3 +445,53 @@ func compileShader(glctx gl.Context, shaderType gl.Enum, src string) (gl.Shader,
 	}
 	return shader, nil
 }
+
+// errClipboard is a screen.Clipboard whose methods all return err, for
+// platforms where the clipboard is not implemented.
+type errClipboard struct {
+	err error
+}
+
+func (c errClipboard) ReadText() (string, error)   { return "", c.err }
+func (c errClipboard) WriteText(text string) error { return c.err }
+
+// useYUV compiles, if need be, and uses p's YUV program, which converts YUV
+// planes to RGBA. It must only be called while holding the mutex for glctx.
+func (p *programs) useYUV(glctx gl.Context) {
+	if !glctx.IsProgram(p.yuv.program) {
+		if err := p.compileYUV(glctx); err != nil {
+			panic(err.Error())
+		}
+	}
+	glctx.UseProgram(p.yuv.program)
+}
+
+// compileYUV compiles p's YUV program. It must only be called while holding
+// the mutex for glctx.
+func (p *programs) compileYUV(glctx gl.Context) error {
+	prog, err := compileProgram(glctx, yuvVertexSrc, yuvFragmentSrc)
+	if err != nil {
+		return err
+	}
+	p.yuv.program = prog
+	p.yuv.pos = glctx.GetAttribLocation(prog, "pos")
+	p.yuv.mvp = glctx.GetUniformLocation(prog, "mvp")
+	p.yuv.chroma = glctx.GetUniformLocation(prog, "chroma")
+	p.yuv.y = glctx.GetUniformLocation(prog, "y")
+	p.yuv.u = glctx.GetUniformLocation(prog, "u")
+	p.yuv.v = glctx.GetUniformLocation(prog, "v")
+	p.yuv.nv12 = glctx.GetUniformLocation(prog, "nv12")
+	p.yuv.yOffset = glctx.GetUniformLocation(prog, "yOffset")
+	p.yuv.yScale = glctx.GetUniformLocation(prog, "yScale")
+	p.yuv.uCoef = glctx.GetUniformLocation(prog, "uCoef")
+	p.yuv.vCoef = glctx.GetUniformLocation(prog, "vCoef")
+	p.yuv.quad = glctx.CreateBuffer()
+	for i := range p.yuv.planes {
+		p.yuv.planes[i] = glctx.CreateTexture()
+		p.yuv.sizes[i] = image.Point{}
+	}
+
+	glctx.BindBuffer(gl.ARRAY_BUFFER, p.yuv.quad)
+	glctx.BufferData(gl.ARRAY_BUFFER, quadCoords, gl.STATIC_DRAW)
+	return nil
+}
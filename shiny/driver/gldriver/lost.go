@@ -0,0 +1,92 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gldriver
+
+import (
+	"fmt"
+	"log"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/gl"
+)
+
+// recoverShare replaces the share context, if it is still that of generation
+// gen, after it was lost, and re-creates every unreleased texture in the
+// replacement. Their contents are undefined until the app uploads or draws to
+// them again.
+//
+// TODO: stop the lost share context's processing thread. The gl package has
+// no way to stop a Worker, so that thread, blocked on WorkAvailable, leaks.
+func (s *screenImpl) recoverShare(gen int) error {
+	s.shareMu.Lock()
+	defer s.shareMu.Unlock()
+
+	if gen != s.shareGen {
+		// Another window has already replaced it.
+		return nil
+	}
+	glctx, run, err := startContext(shareContextRecreate, "share")
+	if err != nil {
+		return fmt.Errorf("gldriver: share context re-creation failed: %v", err)
+	}
+	// The lost context's pixel buffer objects and fences went with it.
+	s.share, s.shareRun, s.shareProgs, s.pbo = glctx, run, programs{}, pboStage{}
+	if err := s.shareProgs.compile(glctx); err != nil {
+		log.Print(err)
+	}
+	for t := range s.textures {
+		t.fb = gl.Framebuffer{}
+		t.create(glctx)
+	}
+	glctx.Flush()
+	s.shareGen++
+	return nil
+}
+
+// checkContext is called by the OS-specific draw loop after swapping w's
+// buffers, with lost being whether the swap reported that w's native context
+// was lost. That context is re-created, by calling recreate, if it was lost or
+// if the share context was replaced since the native context was created, as
+// then it no longer shares any textures. The share context is replaced first,
+// as a lost context usually means every context in the share group was lost.
+//
+// It returns whether recreate was called, and so whether Publish should call
+// resetContext. It must only be called on the draw loop's thread.
+func (w *windowImpl) checkContext(lost bool, recreate func() error) bool {
+	if lost {
+		if err := w.s.recoverShare(w.shareGen); err != nil {
+			log.Print(err)
+			return false
+		}
+	}
+	w.s.shareMu.Lock()
+	gen := w.s.shareGen
+	w.s.shareMu.Unlock()
+	if gen == w.shareGen {
+		return false
+	}
+
+	if err := recreate(); err != nil {
+		log.Printf("gldriver: context re-creation failed: %v", err)
+		return false
+	}
+	w.shareGen = gen
+	return true
+}
+
+// resetContext forgets the GL objects of w's lost context, none of which exist
+// in its replacement, and tells the app that the contents of its Textures and
+// Layers are undefined. It must only be called while holding w.glctxMu.
+func (w *windowImpl) resetContext() {
+	w.progs = programs{linear: w.progs.linear}
+	if err := w.progs.compile(w.glctx); err != nil {
+		log.Print(err)
+	}
+	w.coverage = coverage{}
+	w.backBufferBound = false
+	w.stencilBits = -1
+	w.clearDepth = w.depth
+	w.Send(screen.DeviceLostEvent{})
+}
@@ -11,18 +11,121 @@ package gldriver
 
 import (
 	"fmt"
+	"image"
 	"runtime"
 
+	"golang.org/x/exp/shiny/driver/internal/hotkey"
 	"golang.org/x/exp/shiny/screen"
 )
 
-func newWindow(opts *screen.NewWindowOptions) (uintptr, error) { return 0, nil }
+func newWindow(opts *screen.NewWindowOptions, owner uintptr) (uintptr, error) { return 0, nil }
 
 func initWindow(id *windowImpl) {}
-func showWindow(id *windowImpl) {}
 func closeWindow(id uintptr)    {}
 func drawLoop(w *windowImpl)    {}
 
+func showWindow(w *windowImpl, opts *screen.NewWindowOptions) {}
+
+func objectLabel(identifier, name uint32, label string) bool { return false }
+func pboSupported() bool                                     { return false }
+func texSubImageUnpack(x, y, width, height int)              {}
+func fenceSync() uintptr                                     { return 0 }
+func syncSignaled(sync uintptr, flush bool) bool             { return true }
+func deleteSync(sync uintptr)                                {}
+
+func setTitle(w *windowImpl, title string)              {}
+func setIcon(w *windowImpl, m image.Image)              {}
+func setBadge(w *windowImpl, label string)              {}
+func setProgress(w *windowImpl, progress float64)       {}
+func setSize(w *windowImpl, width, height int)          {}
+func setPosition(w *windowImpl, p image.Point)          {}
+func geometry(w *windowImpl) (image.Point, image.Point) { return image.Point{}, image.Point{} }
+func setTextInputRect(w *windowImpl, r image.Rectangle) {}
+func setCursor(w *windowImpl, c screen.Cursor)          {}
+func setCursorVisible(w *windowImpl, visible bool)      {}
+func setPointerCapture(w *windowImpl, capture bool)     {}
+func minimize(w *windowImpl)                            {}
+func maximize(w *windowImpl)                            {}
+func restore(w *windowImpl)                             {}
+func nextFrame(w *windowImpl)                           {}
+
+func setFullscreen(w *windowImpl, mode screen.FullscreenMode) {}
+func setSizeConstraints(w *windowImpl, min, max image.Point)  {}
+func setAspectRatio(w *windowImpl, aspect image.Point)        {}
+func setAlwaysOnTop(w *windowImpl, onTop bool)                {}
+func setOpacity(w *windowImpl, opacity float32)               {}
+func raise(w *windowImpl)                                     {}
+func lower(w *windowImpl)                                     {}
+
+func setColorSpace(w *windowImpl, cs screen.ColorSpace) error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func registerHotKey(w *windowImpl, k hotkey.Key) error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func unregisterHotKey(w *windowImpl, k hotkey.Key) {}
+func releaseHotKeys(w *windowImpl)                 {}
+
+func postNotification(w *windowImpl, n *screen.Notification) error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func closeNotification(w *windowImpl, id int) {}
+func releaseNotifications(w *windowImpl)      {}
+
+func srgbSurfaces() bool { return false }
+
+func accessibilityPrefs() screen.AccessibilityPrefs { return 0 }
+func colorScheme() screen.ColorScheme               { return screen.LightColorScheme }
+
+func startDrag(w *windowImpl, data screen.DragData) error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func startMove(w *windowImpl) error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func startResize(w *windowImpl, edge screen.WindowEdge) error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func showFileDialog(w *windowImpl, opts *screen.FileDialogOptions) error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func setMenuBar(w *windowImpl, bar *screen.Menu) error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func showContextMenu(w *windowImpl, m *screen.Menu, p image.Point) error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func setAccessTree(w *windowImpl, root *screen.AccessNode) error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func clipboard() screen.Clipboard {
+	return errClipboard{fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)}
+}
+
+func displays() []screen.Display { return nil }
+
+func newTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return nil, fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func shareContextCreate() error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func shareContextRecreate() error {
+	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
 func main(f func(screen.Screen)) error {
 	return fmt.Errorf("gldriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
 }
@@ -0,0 +1,258 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gldriver
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/gl"
+)
+
+// pathSamples are the sub-pixel sample positions, relative to a pixel's
+// center, at which FillPath evaluates a path's coverage. They are the
+// standard 8x multisampling pattern.
+var pathSamples = [...][2]float32{
+	{+1.0 / 16, -3.0 / 16},
+	{-1.0 / 16, +3.0 / 16},
+	{+5.0 / 16, +1.0 / 16},
+	{-3.0 / 16, -5.0 / 16},
+	{-5.0 / 16, +5.0 / 16},
+	{-7.0 / 16, -1.0 / 16},
+	{+3.0 / 16, +7.0 / 16},
+	{+7.0 / 16, -7.0 / 16},
+}
+
+// coverage is a window-sized Framebuffer with a color texture, in which
+// FillPath accumulates coverage, and a stencil buffer, in which it counts
+// winding numbers.
+type coverage struct {
+	fb      gl.Framebuffer
+	tex     gl.Texture
+	stencil gl.Renderbuffer
+	size    image.Point
+}
+
+// bind binds c's Framebuffer, creating or resizing it as necessary. It must
+// only be called while holding the mutex for glctx.
+func (c *coverage) bind(glctx gl.Context, size image.Point) {
+	if c.fb.Value == 0 {
+		c.fb = glctx.CreateFramebuffer()
+		c.tex = glctx.CreateTexture()
+		c.stencil = glctx.CreateRenderbuffer()
+	}
+	glctx.BindFramebuffer(gl.FRAMEBUFFER, c.fb)
+	if c.size == size {
+		return
+	}
+	c.size = size
+
+	glctx.BindTexture(gl.TEXTURE_2D, c.tex)
+	glctx.TexImage2D(gl.TEXTURE_2D, 0, size.X, size.Y, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	glctx.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, c.tex, 0)
+
+	glctx.BindRenderbuffer(gl.RENDERBUFFER, c.stencil)
+	glctx.RenderbufferStorage(gl.RENDERBUFFER, gl.STENCIL_INDEX8, size.X, size.Y)
+	glctx.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.STENCIL_ATTACHMENT, gl.RENDERBUFFER, c.stencil)
+	labelObject(glctx, _GL_FRAMEBUFFER, c.fb.Value, "path coverage")
+	labelObject(glctx, _GL_TEXTURE, c.tex.Value, "path coverage")
+}
+
+// FillPath implements screen.PathDrawer.
+//
+// It renders the path with the stencil-then-cover technique. For each of the
+// pathSamples, the path's triangle fans are drawn into the stencil buffer,
+// incrementing or decrementing it by their orientation, so that the stencil
+// holds each pixel's winding number. A quad over the path's bounds then adds
+// 1/len(pathSamples) to the coverage wherever the winding number is non-zero,
+// and resets the stencil. Finally, the accumulated coverage modulates src as
+// it is composited onto the window.
+func (w *windowImpl) FillPath(src2dst f64.Aff3, p *screen.Path, src color.Color) {
+	verts, bounds := flattenPath(src2dst, p)
+	if len(verts) == 0 {
+		return
+	}
+	nTriVerts := len(verts) / 2
+
+	w.glctxMu.Lock()
+	defer w.glctxMu.Unlock()
+
+	if w.released {
+		return
+	}
+
+	w.szMu.Lock()
+	sz := w.sz
+	w.szMu.Unlock()
+
+	// The sample offsets move the geometry by less than a pixel, so
+	// outsetting the bounds by one pixel is enough.
+	dr := bounds.Inset(-1).Intersect(image.Rect(0, 0, sz.WidthPx, sz.HeightPx))
+	if len(w.clips) > 0 {
+		dr = dr.Intersect(w.clips[len(w.clips)-1].r)
+	}
+	if dr.Empty() {
+		return
+	}
+	// The cover quad, as two triangles, follows the triangle fans.
+	verts = appendQuad(verts, dr)
+
+	glctx := w.glctx
+	w.backBufferBound = false
+	w.coverage.bind(glctx, image.Point{sz.WidthPx, sz.HeightPx})
+	glctx.Viewport(0, 0, sz.WidthPx, sz.HeightPx)
+	glctx.Disable(gl.DEPTH_TEST)
+	// OpenGL's window coordinates have the Y-axis pointing upwards.
+	glctx.Enable(gl.SCISSOR_TEST)
+	glctx.Scissor(int32(dr.Min.X), int32(sz.HeightPx-dr.Max.Y), int32(dr.Dx()), int32(dr.Dy()))
+	glctx.StencilMask(0xff)
+	glctx.ClearColor(0, 0, 0, 0)
+	glctx.ClearStencil(0)
+	glctx.Clear(gl.COLOR_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
+
+	w.progs.usePath(glctx)
+	glctx.Uniform2f(w.progs.path.size, float32(sz.WidthPx), float32(sz.HeightPx))
+	k := 1 / float32(len(pathSamples))
+	glctx.Uniform4f(w.progs.path.color, k, k, k, k)
+
+	glctx.BindBuffer(gl.ARRAY_BUFFER, w.progs.path.verts)
+	glctx.BufferData(gl.ARRAY_BUFFER, f32Bytes(binary.LittleEndian, verts...), gl.DYNAMIC_DRAW)
+	glctx.EnableVertexAttribArray(w.progs.path.pos)
+	glctx.VertexAttribPointer(w.progs.path.pos, 2, gl.FLOAT, false, 0, 0)
+
+	glctx.Enable(gl.STENCIL_TEST)
+	glctx.Enable(gl.BLEND)
+	glctx.BlendFunc(gl.ONE, gl.ONE)
+	for _, s := range pathSamples {
+		// Moving the geometry by -s samples it at s from each pixel center.
+		glctx.ColorMask(false, false, false, false)
+		glctx.StencilFunc(gl.ALWAYS, 0, 0xff)
+		glctx.StencilOpSeparate(gl.FRONT, gl.KEEP, gl.KEEP, gl.INCR_WRAP)
+		glctx.StencilOpSeparate(gl.BACK, gl.KEEP, gl.KEEP, gl.DECR_WRAP)
+		glctx.Uniform2f(w.progs.path.offset, -s[0], -s[1])
+		glctx.DrawArrays(gl.TRIANGLES, 0, nTriVerts)
+
+		glctx.ColorMask(true, true, true, true)
+		glctx.StencilFunc(gl.NOTEQUAL, 0, 0xff)
+		glctx.StencilOp(gl.ZERO, gl.ZERO, gl.ZERO)
+		glctx.Uniform2f(w.progs.path.offset, 0, 0)
+		glctx.DrawArrays(gl.TRIANGLES, nTriVerts, 6)
+	}
+	glctx.Disable(gl.STENCIL_TEST)
+	glctx.DisableVertexAttribArray(w.progs.path.pos)
+
+	w.bindBackBuffer()
+	if !w.useClip() {
+		return
+	}
+	useBlend(glctx, draw.Over, nil)
+	w.progs.usePathCover(glctx)
+	glctx.Uniform2f(w.progs.pathCover.size, float32(sz.WidthPx), float32(sz.HeightPx))
+	r, g, b, a := src.RGBA()
+	glctx.Uniform4f(
+		w.progs.pathCover.color,
+		float32(r)/65535,
+		float32(g)/65535,
+		float32(b)/65535,
+		float32(a)/65535,
+	)
+	glctx.ActiveTexture(gl.TEXTURE0)
+	glctx.BindTexture(gl.TEXTURE_2D, w.coverage.tex)
+	glctx.Uniform1i(w.progs.pathCover.sample, 0)
+
+	glctx.EnableVertexAttribArray(w.progs.pathCover.pos)
+	glctx.VertexAttribPointer(w.progs.pathCover.pos, 2, gl.FLOAT, false, 0, 0)
+	glctx.DrawArrays(gl.TRIANGLES, nTriVerts, 6)
+	glctx.DisableVertexAttribArray(w.progs.pathCover.pos)
+}
+
+// flattenPath returns the vertices, in pixel space, of the triangle fans of p
+// transformed by src2dst, and their bounds.
+func flattenPath(src2dst f64.Aff3, p *screen.Path) (verts []float32, bounds image.Rectangle) {
+	minX, minY := math.Inf(+1), math.Inf(+1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	p.Flatten(src2dst, 0.25, func(c []f64.Vec2) {
+		for i := 1; i+1 < len(c); i++ {
+			verts = append(verts,
+				float32(c[0][0]), float32(c[0][1]),
+				float32(c[i][0]), float32(c[i][1]),
+				float32(c[i+1][0]), float32(c[i+1][1]),
+			)
+		}
+		for _, v := range c {
+			minX, minY = math.Min(minX, v[0]), math.Min(minY, v[1])
+			maxX, maxY = math.Max(maxX, v[0]), math.Max(maxY, v[1])
+		}
+	})
+	if len(verts) == 0 {
+		return nil, image.Rectangle{}
+	}
+	return verts, image.Rect(
+		int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX)), int(math.Ceil(maxY)),
+	)
+}
+
+// appendQuad appends the vertices of r, in pixel space, as two triangles.
+func appendQuad(verts []float32, r image.Rectangle) []float32 {
+	x0, y0 := float32(r.Min.X), float32(r.Min.Y)
+	x1, y1 := float32(r.Max.X), float32(r.Max.Y)
+	return append(verts,
+		x0, y0, x1, y0, x0, y1,
+		x0, y1, x1, y0, x1, y1,
+	)
+}
+
+// The path vertex shader maps from pixel space, where the window ranges from
+// (0, 0) to size and the Y-axis points downwards, to clip space.
+const pathVertexSrc = `#version 100
+uniform vec2 size;
+uniform vec2 offset;
+attribute vec2 pos;
+void main() {
+	vec2 p = (pos + offset) / size;
+	gl_Position = vec4(2.0*p.x - 1.0, 1.0 - 2.0*p.y, 0, 1);
+}
+`
+
+const pathFragmentSrc = `#version 100
+precision mediump float;
+uniform vec4 color;
+void main() {
+	gl_FragColor = color;
+}
+`
+
+// The path cover vertex shader is like the path vertex shader, but also
+// computes where to sample the coverage texture, whose rows are bottom-up.
+const pathCoverVertexSrc = `#version 100
+uniform vec2 size;
+attribute vec2 pos;
+varying vec2 uv;
+void main() {
+	vec2 p = pos / size;
+	gl_Position = vec4(2.0*p.x - 1.0, 1.0 - 2.0*p.y, 0, 1);
+	uv = vec2(p.x, 1.0 - p.y);
+}
+`
+
+const pathCoverFragmentSrc = `#version 100
+precision mediump float;
+uniform sampler2D sample;
+uniform vec4 color;
+varying vec2 uv;
+void main() {
+	gl_FragColor = color * texture2D(sample, uv).a;
+}
+`
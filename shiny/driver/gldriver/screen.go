@@ -8,104 +8,173 @@ import (
 	"fmt"
 	"image"
 	"sync"
+	"sync/atomic"
 
+	"golang.org/x/exp/shiny/driver/internal/pixpool"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/mobile/gl"
 )
 
 var theScreen = &screenImpl{
-	windows: make(map[uintptr]*windowImpl),
+	windows:  make(map[uintptr]*windowImpl),
+	textures: make(map[*textureImpl]struct{}),
 }
 
 type screenImpl struct {
-	texture struct {
-		program gl.Program
-		pos     gl.Attrib
-		mvp     gl.Uniform
-		uvp     gl.Uniform
-		inUV    gl.Attrib
-		sample  gl.Uniform
-		quad    gl.Buffer
-	}
-	fill struct {
-		program gl.Program
-		pos     gl.Attrib
-		mvp     gl.Uniform
-		color   gl.Uniform
-		quad    gl.Buffer
-	}
+	textureBytes atomic.Int64
+
+	// pixPool recycles the pixels of released Buffers.
+	pixPool pixpool.Pool
+
+	// share is the share context: the GL context that owns every texture.
+	// Each window has its own GL context, in the same share group, so that a
+	// texture can be drawn to any window, and remains valid regardless of
+	// which windows are created or released. The share context is created
+	// lazily, by the first NewTexture or NewWindow call, and is only
+	// replaced if it is lost, by recoverShare.
+	//
+	// textures are the unreleased textures, which recoverShare re-creates in
+	// a replacement share context. shareGen counts the share contexts that
+	// have been replaced.
+	//
+	// compressedFormats are the compressed texture formats that the share
+	// context supports, and maxTextureSize is its GL_MAX_TEXTURE_SIZE.
+	//
+	// shareRun is received from by the share context's processing thread, as
+	// per startContext, and pbo stages large uploads to its textures.
+	//
+	// shareMu guards share, shareRun, shareProgs, textures, shareGen,
+	// compressedFormats, maxTextureSize, pbo and the GL objects owned by the
+	// share context, in the same way that windowImpl.glctxMu guards a
+	// window's GL context. If you need to hold both a glctxMu and shareMu,
+	// the lock ordering is to lock glctxMu first (and unlock it last).
+	shareMu    sync.Mutex
+	share      gl.Context
+	shareRun   chan func()
+	shareProgs programs
+	textures   map[*textureImpl]struct{}
+	shareGen   int
+	pbo        pboStage
 
-	mu      sync.Mutex
-	windows map[uintptr]*windowImpl
+	compressedFormats []screen.CompressedFormat
+	maxTextureSize    int
+
+	// uploads are the asynchronous uploads, started by UploadAsync, that
+	// the upload goroutine has yet to do. uploadsMu guards uploads and
+	// uploading, which is whether that goroutine is running.
+	uploadsMu sync.Mutex
+	uploads   []asyncUpload
+	uploading bool
+
+	mu                   sync.Mutex
+	windows              map[uintptr]*windowImpl
+	defaultWindowOptions *screen.NewWindowOptions
 }
 
 func (s *screenImpl) NewBuffer(size image.Point) (retBuf screen.Buffer, retErr error) {
-	m := image.NewRGBA(image.Rectangle{Max: size})
+	m := s.pixPool.NewRGBA(size)
 	return &bufferImpl{
+		s:    s,
 		buf:  m.Pix,
-		rgba: *m,
+		rgba: m,
 		size: size,
 	}, nil
 }
 
 func (s *screenImpl) NewTexture(size image.Point) (screen.Texture, error) {
-	// TODO: can we compile these programs eagerly instead of lazily?
-
-	// Find a GL context for this texture.
-	// TODO: this might be correct. Some GL objects can be shared
-	// across contexts. But this needs a review of the spec to make
-	// sure it's correct, and some testing would be nice.
-	var w *windowImpl
+	return s.NewTextureWithOptions(size, nil)
+}
 
-	s.mu.Lock()
-	for _, window := range s.windows {
-		w = window
-		break
+func (s *screenImpl) NewTextureWithOptions(size image.Point, opts *screen.NewTextureOptions) (screen.Texture, error) {
+	format := opts.GetFormat()
+	if format == screen.PixelFormatBGRA8 {
+		format = screen.PixelFormatRGBA8
 	}
-	s.mu.Unlock()
-
-	if w == nil {
-		return nil, fmt.Errorf("gldriver: no window available")
+	var o screen.NewTextureOptions
+	if opts != nil {
+		o = *opts
 	}
 
-	w.glctxMu.Lock()
-	defer w.glctxMu.Unlock()
-	glctx := w.glctx
-	if glctx == nil {
-		return nil, fmt.Errorf("gldriver: no GL context available")
+	s.shareMu.Lock()
+	defer s.shareMu.Unlock()
+
+	if err := s.startShare(); err != nil {
+		return nil, err
 	}
 
-	if !glctx.IsProgram(s.texture.program) {
-		p, err := compileProgram(glctx, textureVertexSrc, textureFragmentSrc)
-		if err != nil {
-			return nil, err
+	if o.Compressed != screen.CompressedNone {
+		if !s.supportsCompressed(o.Compressed) {
+			return nil, screen.ErrUnsupportedFormat
 		}
-		s.texture.program = p
-		s.texture.pos = glctx.GetAttribLocation(p, "pos")
-		s.texture.mvp = glctx.GetUniformLocation(p, "mvp")
-		s.texture.uvp = glctx.GetUniformLocation(p, "uvp")
-		s.texture.inUV = glctx.GetAttribLocation(p, "inUV")
-		s.texture.sample = glctx.GetUniformLocation(p, "sample")
-		s.texture.quad = glctx.CreateBuffer()
-
-		glctx.BindBuffer(gl.ARRAY_BUFFER, s.texture.quad)
-		glctx.BufferData(gl.ARRAY_BUFFER, quadCoords, gl.STATIC_DRAW)
+		// Mipmaps cannot be generated from compressed pixels.
+		format, o.Mipmap = screen.PixelFormatRGBA8, false
 	}
+	if max := s.maxTextureSize; max > 0 && (size.X > max || size.Y > max) {
+		if o.Compressed != screen.CompressedNone {
+			return nil, screen.ErrTextureTooLarge
+		}
+		return s.newTiledTexture(size, format, o), nil
+	}
+	return s.newTexture(size, format, o), nil
+}
 
+// newTexture creates a texture whose sides are at most s.maxTextureSize. It
+// must only be called while holding s.shareMu, after startShare.
+func (s *screenImpl) newTexture(size image.Point, format screen.PixelFormat, o screen.NewTextureOptions) *textureImpl {
+	glctx := s.share
+	if !isContext3(glctx) && !(isPowerOf2(size.X) && isPowerOf2(size.Y)) {
+		// OpenGL ES 2 textures whose sides are not powers of two are
+		// incomplete, and sample as black, unless they are clamped and
+		// without mipmaps.
+		o.Mipmap, o.Wrap = false, screen.WrapClamp
+	}
 	t := &textureImpl{
-		w:    w,
-		id:   glctx.CreateTexture(),
-		size: size,
+		s:          s,
+		size:       size,
+		format:     format,
+		compressed: o.Compressed,
+		filter:     o.Filter,
+		mipmap:     o.Mipmap,
+		wrap:       o.Wrap,
 	}
+	t.create(glctx)
+	// Flush, so that the texture is complete before any window's context
+	// uses it.
+	glctx.Flush()
+
+	s.textures[t] = struct{}{}
+	s.textureBytes.Add(t.bytes())
+	return t
+}
 
-	glctx.BindTexture(gl.TEXTURE_2D, t.id)
-	glctx.TexImage2D(gl.TEXTURE_2D, 0, size.X, size.Y, gl.RGBA, gl.UNSIGNED_BYTE, nil)
-	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
-	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+// wrapModes are the OpenGL wrap modes of each screen.TextureWrap.
+var wrapModes = [...]int{
+	screen.WrapClamp:  gl.CLAMP_TO_EDGE,
+	screen.WrapRepeat: gl.REPEAT,
+	screen.WrapMirror: gl.MIRRORED_REPEAT,
+}
 
-	return t, nil
+func isPowerOf2(x int) bool {
+	return x > 0 && x&(x-1) == 0
+}
+
+// startShare starts the share context, if it has not already been started,
+// and compiles its programs. It must only be called while holding s.shareMu.
+func (s *screenImpl) startShare() error {
+	if s.share != nil {
+		return nil
+	}
+	glctx, run, err := startContext(shareContextCreate, "share")
+	if err != nil {
+		return fmt.Errorf("gldriver: share context creation failed: %v", err)
+	}
+	if err := s.shareProgs.compile(glctx); err != nil {
+		return err
+	}
+	s.share, s.shareRun = glctx, run
+	s.compressedFormats = compressedFormats(glctx)
+	s.maxTextureSize = glctx.GetInteger(gl.MAX_TEXTURE_SIZE)
+	return nil
 }
 
 func optsSize(opts *screen.NewWindowOptions) (width, height int) {
@@ -122,7 +191,29 @@ func optsSize(opts *screen.NewWindowOptions) (width, height int) {
 }
 
 func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) {
-	id, err := newWindow(opts)
+	s.mu.Lock()
+	opts = opts.WithDefaults(s.defaultWindowOptions)
+	s.mu.Unlock()
+
+	// Every window's GL context is created in the share context's share
+	// group, so the share context has to exist first.
+	s.shareMu.Lock()
+	err := s.startShare()
+	shareGen := s.shareGen
+	s.shareMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var owner *windowImpl
+	if opts != nil {
+		owner, _ = opts.Owner.(*windowImpl)
+	}
+	var ownerID uintptr
+	if owner != nil {
+		ownerID = owner.id
+	}
+	id, err := newWindow(opts, ownerID)
 	if err != nil {
 		return nil, err
 	}
@@ -132,6 +223,27 @@ func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, er
 		publish:     make(chan struct{}),
 		publishDone: make(chan screen.PublishResult),
 		drawDone:    make(chan struct{}),
+		depth:       opts != nil && opts.DepthBits > 0,
+		clearDepth:  true,
+		stencilBits: -1,
+		shareGen:    shareGen,
+	}
+	if opts != nil {
+		w.Priority = opts.EventPriority
+		w.KeepAllMoves = opts.KeepAllMoves
+		w.KeyEvents = opts.KeyEvents
+		w.Queues = opts.EventQueues
+		w.glErrorPolicy = opts.GLErrorPolicy
+		w.gpu = opts.PreferredGPU
+		w.samples = opts.Samples
+		w.interceptClose = opts.InterceptClose
+		w.fixedSize = opts.FixedSize
+		w.occlusion.Policy = opts.OccludedPublish
+		w.progs.linear = opts.LinearBlending && srgbSurfaces()
+	}
+	if owner != nil && opts.Modal {
+		w.modalOwner = owner
+		atomic.AddInt32(&owner.modalChildren, 1)
 	}
 	initWindow(w)
 
@@ -143,7 +255,43 @@ func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, er
 		w.lifecycler.SendEvent(w, nil)
 	}
 
-	showWindow(w)
+	showWindow(w, opts)
+	go w.prepare(opts != nil && opts.WarmUp)
 
 	return w, nil
 }
+
+func (s *screenImpl) SetDefaultWindowOptions(opts *screen.NewWindowOptions) {
+	opts = opts.WithDefaults(nil)
+
+	s.mu.Lock()
+	s.defaultWindowOptions = opts
+	s.mu.Unlock()
+}
+
+// TODO: also report the driver's view of the available video memory, via the
+// GL_NVX_gpu_memory_info or GL_ATI_meminfo extensions where available?
+
+func (s *screenImpl) TextureMemoryEstimate() int64 {
+	return s.textureBytes.Load()
+}
+
+func (s *screenImpl) AccessibilityPrefs() screen.AccessibilityPrefs {
+	return accessibilityPrefs()
+}
+
+func (s *screenImpl) ColorScheme() screen.ColorScheme {
+	return colorScheme()
+}
+
+func (s *screenImpl) Clipboard() screen.Clipboard {
+	return clipboard()
+}
+
+func (s *screenImpl) Displays() []screen.Display {
+	return displays()
+}
+
+func (s *screenImpl) NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return newTrayIcon(opts)
+}
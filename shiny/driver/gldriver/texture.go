@@ -6,39 +6,129 @@ package gldriver
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 
+	"golang.org/x/exp/shiny/driver/internal/drawer"
 	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/image/math/f64"
 	"golang.org/x/mobile/gl"
 )
 
+var (
+	errTextureReleased   = errors.New("gldriver: texture is released")
+	errTextureCompressed = errors.New("gldriver: texture is compressed")
+)
+
+// textureImpl is a texture owned by the share context. Its id and fb fields
+// are guarded by s.shareMu.
+//
+// Every texture's storage is RGBA, whatever its format, as OpenGL ES 2 can
+// only render to RGBA textures, and textures are rendered to by Fill and by
+// the methods that let them back a screen.Layer. Uploads convert pixels to
+// the texture's format, and then to RGBA.
+//
+// The exception is compressed textures, whose storage is compressed, and
+// which cannot be rendered to, and so ignore every method that would change
+// their pixels but UploadCompressed.
+//
+// TODO: store Alpha8 and Gray8 textures as ALPHA and LUMINANCE, with their
+// own framebuffers, where the context supports rendering to them.
 type textureImpl struct {
-	w    *windowImpl
-	id   gl.Texture
-	fb   gl.Framebuffer
-	size image.Point
+	s          *screenImpl
+	id         gl.Texture
+	fb         gl.Framebuffer
+	size       image.Point
+	format     screen.PixelFormat
+	compressed screen.CompressedFormat
+	filter     screen.TextureFilter
+	mipmap     bool
+	wrap       screen.TextureWrap
 }
 
-func (t *textureImpl) Size() image.Point       { return t.size }
-func (t *textureImpl) Bounds() image.Rectangle { return image.Rectangle{Max: t.size} }
+func (t *textureImpl) Size() image.Point          { return t.size }
+func (t *textureImpl) Bounds() image.Rectangle    { return image.Rectangle{Max: t.size} }
+func (t *textureImpl) Format() screen.PixelFormat { return t.format }
+
+// bytes returns the estimated memory used by the texture. Mipmaps add a
+// third.
+func (t *textureImpl) bytes() int64 {
+	if t.compressed != screen.CompressedNone {
+		return drawer.CompressedBytes(t.compressed, t.size)
+	}
+	n := 4 * int64(t.size.X) * int64(t.size.Y)
+	if t.mipmap {
+		n += n / 3
+	}
+	return n
+}
+
+// create creates t's GL texture in glctx, with t's size and options and
+// undefined contents. It must only be called while holding t.s.shareMu.
+func (t *textureImpl) create(glctx gl.Context) {
+	magFilter, minFilter := gl.LINEAR, gl.LINEAR
+	if t.filter == screen.FilterNearest {
+		magFilter, minFilter = gl.NEAREST, gl.NEAREST
+	}
+	if t.mipmap {
+		minFilter = gl.LINEAR_MIPMAP_LINEAR
+		if t.filter == screen.FilterNearest {
+			minFilter = gl.NEAREST_MIPMAP_NEAREST
+		}
+	}
+	t.id = glctx.CreateTexture()
+	glctx.BindTexture(gl.TEXTURE_2D, t.id)
+	if t.compressed != screen.CompressedNone {
+		// Unlike glTexImage2D, glCompressedTexImage2D needs the pixels'
+		// size to match the data's, even if there is no data.
+		glctx.CompressedTexImage2D(gl.TEXTURE_2D, 0, glCompressedFormats[t.compressed], t.size.X, t.size.Y, 0,
+			make([]byte, drawer.CompressedBytes(t.compressed, t.size)))
+	} else {
+		glctx.TexImage2D(gl.TEXTURE_2D, 0, t.size.X, t.size.Y, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	}
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, magFilter)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, minFilter)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, wrapModes[t.wrap])
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, wrapModes[t.wrap])
+	if t.mipmap {
+		glctx.GenerateMipmap(gl.TEXTURE_2D)
+	}
+	labelObject(glctx, _GL_TEXTURE, t.id.Value, fmt.Sprintf("Texture %dx%d", t.size.X, t.size.Y))
+}
 
 func (t *textureImpl) Release() {
-	t.w.glctxMu.Lock()
-	defer t.w.glctxMu.Unlock()
+	t.s.shareMu.Lock()
+	defer t.s.shareMu.Unlock()
 
+	if t.id == (gl.Texture{}) {
+		return // Already released.
+	}
+	delete(t.s.textures, t)
+	t.s.textureBytes.Add(-t.bytes())
 	if t.fb.Value != 0 {
-		t.w.glctx.DeleteFramebuffer(t.fb)
+		t.s.share.DeleteFramebuffer(t.fb)
 		t.fb = gl.Framebuffer{}
 	}
-	t.w.glctx.DeleteTexture(t.id)
+	// The texture's storage is freed once no window's context has it bound.
+	t.s.share.DeleteTexture(t.id)
 	t.id = gl.Texture{}
 }
 
 func (t *textureImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
 	buf := src.(*bufferImpl)
 	buf.preUpload()
+	if t.compressed != screen.CompressedNone {
+		return
+	}
+	if t.format != screen.PixelFormatRGBA8 {
+		// The Buffer's pixels must first be converted to t's format.
+		p, _ := screen.PixelsOf(&buf.rgba)
+		t.UploadPixels(dp, p, sr)
+		return
+	}
 
 	// src2dst is added to convert from the src coordinate space to the dst
 	// coordinate space. It is subtracted to convert the other way.
@@ -55,23 +145,59 @@ func (t *textureImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectang
 	}
 
 	// Bring dr.Min in dst-space back to src-space to get the pixel buffer offset.
-	pix := buf.rgba.Pix[buf.rgba.PixOffset(dr.Min.X-src2dst.X, dr.Min.Y-src2dst.Y):]
+	t.upload(dr, buf.rgba.Pix[buf.rgba.PixOffset(dr.Min.X-src2dst.X, dr.Min.Y-src2dst.Y):], buf.rgba.Stride)
+}
 
-	t.w.glctxMu.Lock()
-	defer t.w.glctxMu.Unlock()
+// UploadPixels implements screen.PixelUploader.
+func (t *textureImpl) UploadPixels(dp image.Point, src *screen.Pixels, sr image.Rectangle) {
+	if t.compressed != screen.CompressedNone {
+		return
+	}
+	src2dst := dp.Sub(sr.Min)
+	sr = sr.Intersect(src.Rect)
+	dr := sr.Add(src2dst).Intersect(t.Bounds())
+	if dr.Empty() {
+		return
+	}
+	m := drawer.ConvertPixels(t.format, src, dr.Sub(src2dst))
+	t.upload(dr, m.Pix, m.Stride)
+}
 
-	t.w.glctx.BindTexture(gl.TEXTURE_2D, t.id)
+// upload re-specifies the dr sub-rectangle of the texture as the RGBA pixels
+// pix, whose rows start every stride bytes.
+func (t *textureImpl) upload(dr image.Rectangle, pix []byte, stride int) {
+	t.s.shareMu.Lock()
+	defer t.s.shareMu.Unlock()
+
+	if t.id == (gl.Texture{}) {
+		return // Released.
+	}
+	glctx := t.s.share
+	glctx.BindTexture(gl.TEXTURE_2D, t.id)
+	defer t.changed()
 
+	if t.s.uploadPBO(dr, pix, stride) {
+		return
+	}
+	// Only the dr sub-rectangle of the texture is re-specified, so that
+	// updating a small region of a large texture is cheap.
 	width := dr.Dx()
-	if width*4 == buf.rgba.Stride {
-		t.w.glctx.TexSubImage2D(gl.TEXTURE_2D, 0, dr.Min.X, dr.Min.Y, width, dr.Dy(), gl.RGBA, gl.UNSIGNED_BYTE, pix)
+	if width*4 == stride {
+		glctx.TexSubImage2D(gl.TEXTURE_2D, 0, dr.Min.X, dr.Min.Y, width, dr.Dy(), gl.RGBA, gl.UNSIGNED_BYTE, pix)
+		return
+	}
+	if isContext3(glctx) {
+		// GL_UNPACK_ROW_LENGTH, new in ES 3.0, is measured in pixels, not
+		// bytes. The stride of an *image.RGBA is always a multiple of 4.
+		glctx.PixelStorei(gl.UNPACK_ROW_LENGTH, int32(stride/4))
+		glctx.TexSubImage2D(gl.TEXTURE_2D, 0, dr.Min.X, dr.Min.Y, width, dr.Dy(), gl.RGBA, gl.UNSIGNED_BYTE, pix)
+		glctx.PixelStorei(gl.UNPACK_ROW_LENGTH, 0)
 		return
 	}
-	// TODO: can we use GL_UNPACK_ROW_LENGTH with glPixelStorei for stride in
-	// ES 3.0, instead of uploading the pixels row-by-row?
+	// ES 2.0 has no GL_UNPACK_ROW_LENGTH, so upload the pixels row-by-row.
 	for y, p := dr.Min.Y, 0; y < dr.Max.Y; y++ {
-		t.w.glctx.TexSubImage2D(gl.TEXTURE_2D, 0, dr.Min.X, y, width, 1, gl.RGBA, gl.UNSIGNED_BYTE, pix[p:])
-		p += buf.rgba.Stride
+		glctx.TexSubImage2D(gl.TEXTURE_2D, 0, dr.Min.X, y, width, 1, gl.RGBA, gl.UNSIGNED_BYTE, pix[p:])
+		p += stride
 	}
 }
 
@@ -80,18 +206,144 @@ func (t *textureImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
 	minY := float64(dr.Min.Y)
 	maxX := float64(dr.Max.X)
 	maxY := float64(dr.Max.Y)
-	mvp := calcMVP(
-		t.size.X, t.size.Y,
+	t.fill(t.mvp(
 		minX, minY,
 		maxX, minY,
 		minX, maxY,
+	), src, op, nil)
+}
+
+// Draw, DrawUniform, Copy and Scale draw on the texture, via its framebuffer,
+// so that it can back a screen.Layer. Drawing a texture on itself is
+// undefined.
+
+func (t *textureImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	if u, ok := src.(*tiledTexture); ok {
+		u.drawOn(t, src2dst, sr, op, opts)
+		return
+	}
+	u := src.(*textureImpl)
+	if u.wrap == screen.WrapClamp {
+		sr = sr.Intersect(u.Bounds())
+	}
+	if sr.Empty() || u == t || t.compressed != screen.CompressedNone {
+		return
+	}
+	srcL := float64(sr.Min.X)
+	srcT := float64(sr.Min.Y)
+	srcR := float64(sr.Max.X)
+	srcB := float64(sr.Max.Y)
+	mvp := t.mvp(
+		src2dst[0]*srcL+src2dst[1]*srcT+src2dst[2],
+		src2dst[3]*srcL+src2dst[4]*srcT+src2dst[5],
+		src2dst[0]*srcR+src2dst[1]*srcT+src2dst[2],
+		src2dst[3]*srcR+src2dst[4]*srcT+src2dst[5],
+		src2dst[0]*srcL+src2dst[1]*srcB+src2dst[2],
+		src2dst[3]*srcL+src2dst[4]*srcB+src2dst[5],
 	)
 
-	glctx := t.w.glctx
+	t.s.shareMu.Lock()
+	defer t.s.shareMu.Unlock()
+
+	if t.id == (gl.Texture{}) || u.id == (gl.Texture{}) {
+		return // Released.
+	}
+	glctx := t.s.share
+	t.bindFramebuffer()
+
+	glctx.Viewport(0, 0, t.size.X, t.size.Y)
+	doDraw(&t.s.shareProgs, glctx, mvp, u.id, u.size, sr, op, opts, 0)
+	t.changed()
+}
+
+func (t *textureImpl) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	minX := float64(sr.Min.X)
+	minY := float64(sr.Min.Y)
+	maxX := float64(sr.Max.X)
+	maxY := float64(sr.Max.Y)
+	t.fill(t.mvp(
+		src2dst[0]*minX+src2dst[1]*minY+src2dst[2],
+		src2dst[3]*minX+src2dst[4]*minY+src2dst[5],
+		src2dst[0]*maxX+src2dst[1]*minY+src2dst[2],
+		src2dst[3]*maxX+src2dst[4]*minY+src2dst[5],
+		src2dst[0]*minX+src2dst[1]*maxY+src2dst[2],
+		src2dst[3]*minX+src2dst[4]*maxY+src2dst[5],
+	), src, op, opts)
+}
 
-	t.w.glctxMu.Lock()
-	defer t.w.glctxMu.Unlock()
+func (t *textureImpl) Copy(dp image.Point, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Copy(t, dp, src, sr, op, opts)
+}
+
+func (t *textureImpl) Scale(dr image.Rectangle, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Scale(t, dr, src, sr, op, opts)
+}
 
+func (t *textureImpl) fill(mvp f64.Aff3, src color.Color, op draw.Op, opts *screen.DrawOptions) {
+	t.s.shareMu.Lock()
+	defer t.s.shareMu.Unlock()
+
+	if t.id == (gl.Texture{}) || t.compressed != screen.CompressedNone {
+		return // Released, or cannot be rendered to.
+	}
+	glctx := t.s.share
+	t.bindFramebuffer()
+
+	glctx.Viewport(0, 0, t.size.X, t.size.Y)
+	doFill(&t.s.shareProgs, glctx, mvp, src, op, opts, 0)
+	t.changed()
+}
+
+// changed is called, while holding t.s.shareMu, after t's pixels change. It
+// regenerates t's mipmaps, if it has them, and flushes the share context.
+// The share context has no window, and so no back buffer to restore, but
+// other contexts in the share group only see the new pixels after a flush.
+func (t *textureImpl) changed() {
+	glctx := t.s.share
+	if t.mipmap {
+		glctx.BindTexture(gl.TEXTURE_2D, t.id)
+		glctx.GenerateMipmap(gl.TEXTURE_2D)
+	}
+	glctx.Flush()
+}
+
+// mvp is like the windowImpl method of the same name, but for drawing on the
+// texture's framebuffer. Texel row 0 is at framebuffer y == 0, the bottom
+// row in OpenGL's window coordinates, as for Upload and Download, so unlike
+// a window's pixel space, the Y-axis is flipped.
+func (t *textureImpl) mvp(tlx, tly, trx, try, blx, bly float64) f64.Aff3 {
+	h := float64(t.size.Y)
+	return calcMVP(t.size.X, t.size.Y, tlx, h-tly, trx, h-try, blx, h-bly)
+}
+
+func (t *textureImpl) Download(dp image.Point, sr image.Rectangle) (*image.RGBA, error) {
+	r := sr.Intersect(t.Bounds())
+	m := image.NewRGBA(r.Add(dp.Sub(sr.Min)))
+
+	t.s.shareMu.Lock()
+	defer t.s.shareMu.Unlock()
+
+	if t.id == (gl.Texture{}) {
+		return nil, errTextureReleased
+	}
+	if t.compressed != screen.CompressedNone {
+		return nil, errTextureCompressed
+	}
+	if r.Empty() {
+		return m, nil
+	}
+	t.bindFramebuffer()
+	// Texel row 0, the top row of the Texture, is at framebuffer y == 0, as
+	// for Upload, and so the rows need no flipping.
+	t.s.share.ReadPixels(m.Pix, r.Min.X, r.Min.Y, r.Dx(), r.Dy(), gl.RGBA, gl.UNSIGNED_BYTE)
+	return m, nil
+}
+
+// bindFramebuffer binds the share context to a framebuffer whose color
+// attachment is the texture, creating that framebuffer if necessary. It must
+// be called with s.shareMu held.
+func (t *textureImpl) bindFramebuffer() {
+	glctx := t.s.share
 	create := t.fb.Value == 0
 	if create {
 		t.fb = glctx.CreateFramebuffer()
@@ -99,16 +351,8 @@ func (t *textureImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
 	glctx.BindFramebuffer(gl.FRAMEBUFFER, t.fb)
 	if create {
 		glctx.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, t.id, 0)
+		labelObject(glctx, _GL_FRAMEBUFFER, t.fb.Value, fmt.Sprintf("Texture %dx%d", t.size.X, t.size.Y))
 	}
-
-	glctx.Viewport(0, 0, t.size.X, t.size.Y)
-	doFill(t.w.s, t.w.glctx, mvp, src, op)
-
-	// We can't restore the GL state (i.e. bind the back buffer, also known as
-	// gl.Framebuffer{Value: 0}) right away, since we don't necessarily know
-	// the right viewport size yet. It is valid to call textureImpl.Fill before
-	// we've gotten our first size.Event. We bind it lazily instead.
-	t.w.backBufferBound = false
 }
 
 var quadCoords = f32Bytes(binary.LittleEndian,
@@ -121,13 +365,14 @@ var quadCoords = f32Bytes(binary.LittleEndian,
 const textureVertexSrc = `#version 100
 uniform mat3 mvp;
 uniform mat3 uvp;
+uniform float depth;
 attribute vec3 pos;
 attribute vec2 inUV;
 varying vec2 uv;
 void main() {
 	vec3 p = pos;
 	p.z = 1.0;
-	gl_Position = vec4(mvp * p, 1);
+	gl_Position = vec4((mvp * p).xy, depth, 1);
 	uv = (uvp * vec3(inUV, 1)).xy;
 }
 `
@@ -136,18 +381,20 @@ const textureFragmentSrc = `#version 100
 precision mediump float;
 varying vec2 uv;
 uniform sampler2D sample;
+uniform float alpha;
 void main() {
-	gl_FragColor = texture2D(sample, uv);
+	gl_FragColor = texture2D(sample, uv) * alpha;
 }
 `
 
 const fillVertexSrc = `#version 100
 uniform mat3 mvp;
+uniform float depth;
 attribute vec3 pos;
 void main() {
 	vec3 p = pos;
 	p.z = 1.0;
-	gl_Position = vec4(mvp * p, 1);
+	gl_Position = vec4((mvp * p).xy, depth, 1);
 }
 `
 
@@ -0,0 +1,261 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gldriver
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/image/math/f64"
+)
+
+// MaxTextureSize implements screen.MaxTextureSizer.
+func (s *screenImpl) MaxTextureSize() int {
+	s.shareMu.Lock()
+	defer s.shareMu.Unlock()
+
+	if err := s.startShare(); err != nil {
+		return 0
+	}
+	return s.maxTextureSize
+}
+
+// tiledTexture is a texture larger than the share context's maximum texture
+// size. Its pixels are split across tiles: textureImpls whose sides are at
+// most that size, in rows of cols tiles. Every tile is side by side pixels,
+// but for those at the right and bottom edges, which are smaller.
+//
+// Its methods split their work across the tiles, and drawing it draws each
+// tile's part of the source rectangle. As each tile is sampled on its own,
+// with its edges clamped, drawing it scaled or transformed can show seams
+// where the tiles meet, and it cannot repeat.
+type tiledTexture struct {
+	size   image.Point
+	format screen.PixelFormat
+	side   int
+	cols   int
+	tiles  []*textureImpl
+}
+
+// newTiledTexture must only be called while holding s.shareMu, after
+// startShare.
+func (s *screenImpl) newTiledTexture(size image.Point, format screen.PixelFormat, o screen.NewTextureOptions) *tiledTexture {
+	side := s.maxTextureSize
+	t := &tiledTexture{
+		size:   size,
+		format: format,
+		side:   side,
+		cols:   (size.X + side - 1) / side,
+	}
+	o.Wrap = screen.WrapClamp
+	for y := 0; y < size.Y; y += side {
+		for x := 0; x < size.X; x += side {
+			r := image.Rect(x, y, x+side, y+side).Intersect(t.Bounds())
+			t.tiles = append(t.tiles, s.newTexture(r.Size(), format, o))
+		}
+	}
+	return t
+}
+
+func (t *tiledTexture) Size() image.Point          { return t.size }
+func (t *tiledTexture) Bounds() image.Rectangle    { return image.Rectangle{Max: t.size} }
+func (t *tiledTexture) Format() screen.PixelFormat { return t.format }
+
+// tileBounds returns the bounds of t's i'th tile, in t's space.
+func (t *tiledTexture) tileBounds(i int) image.Rectangle {
+	x, y := i%t.cols*t.side, i/t.cols*t.side
+	return image.Rect(x, y, x+t.side, y+t.side).Intersect(t.Bounds())
+}
+
+func (t *tiledTexture) Release() {
+	for _, u := range t.tiles {
+		u.Release()
+	}
+}
+
+func (t *tiledTexture) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	src2dst := dp.Sub(sr.Min)
+	dr := sr.Add(src2dst)
+	for i, u := range t.tiles {
+		r := t.tileBounds(i)
+		if ur := dr.Intersect(r); !ur.Empty() {
+			u.Upload(ur.Min.Sub(r.Min), src, ur.Sub(src2dst))
+		}
+	}
+}
+
+// UploadPixels implements screen.PixelUploader.
+func (t *tiledTexture) UploadPixels(dp image.Point, src *screen.Pixels, sr image.Rectangle) {
+	src2dst := dp.Sub(sr.Min)
+	dr := sr.Add(src2dst)
+	for i, u := range t.tiles {
+		r := t.tileBounds(i)
+		if ur := dr.Intersect(r); !ur.Empty() {
+			u.UploadPixels(ur.Min.Sub(r.Min), src, ur.Sub(src2dst))
+		}
+	}
+}
+
+func (t *tiledTexture) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	for i, u := range t.tiles {
+		r := t.tileBounds(i)
+		if ur := dr.Intersect(r); !ur.Empty() {
+			u.Fill(ur.Sub(r.Min), src, op)
+		}
+	}
+}
+
+func (t *tiledTexture) Download(dp image.Point, sr image.Rectangle) (*image.RGBA, error) {
+	r := sr.Intersect(t.Bounds())
+	src2dst := dp.Sub(sr.Min)
+	if r.Empty() {
+		// The first tile reports whether t is released.
+		if _, err := t.tiles[0].Download(image.Point{}, image.Rectangle{}); err != nil {
+			return nil, err
+		}
+	}
+	m := image.NewRGBA(r.Add(src2dst))
+	for i, u := range t.tiles {
+		tr := t.tileBounds(i)
+		ur := r.Intersect(tr)
+		if ur.Empty() {
+			continue
+		}
+		um, err := u.Download(ur.Min.Add(src2dst), ur.Sub(tr.Min))
+		if err != nil {
+			return nil, err
+		}
+		draw.Draw(m, um.Rect, um, um.Rect.Min, draw.Src)
+	}
+	return m, nil
+}
+
+// Draw, DrawUniform, Copy and Scale draw on each tile that the transformed
+// source rectangle overlaps.
+
+func (t *tiledTexture) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	if u, ok := src.(*tiledTexture); ok {
+		u.drawOn(t, src2dst, sr, op, opts)
+		return
+	}
+	t.eachTile(src2dst, sr, func(u *textureImpl, m f64.Aff3) {
+		u.Draw(m, src, sr, op, opts)
+	})
+}
+
+func (t *tiledTexture) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	t.eachTile(src2dst, sr, func(u *textureImpl, m f64.Aff3) {
+		u.DrawUniform(m, src, sr, op, opts)
+	})
+}
+
+func (t *tiledTexture) Copy(dp image.Point, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Copy(t, dp, src, sr, op, opts)
+}
+
+func (t *tiledTexture) Scale(dr image.Rectangle, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Scale(t, dr, src, sr, op, opts)
+}
+
+// eachTile calls f with each tile that sr, transformed by src2dst, overlaps,
+// and with src2dst translated to that tile's space.
+func (t *tiledTexture) eachTile(src2dst f64.Aff3, sr image.Rectangle, f func(u *textureImpl, m f64.Aff3)) {
+	if sr.Empty() {
+		return
+	}
+	dr := transformedBounds(&src2dst, sr)
+	for i, u := range t.tiles {
+		r := t.tileBounds(i)
+		if !dr.Overlaps(r) {
+			continue
+		}
+		m := src2dst
+		m[2] -= float64(r.Min.X)
+		m[5] -= float64(r.Min.Y)
+		f(u, m)
+	}
+}
+
+// drawOn draws the sr sub-rectangle of t on dst, one tile at a time, each
+// with src2dst translated from that tile's space to t's.
+func (t *tiledTexture) drawOn(dst screen.Drawer, src2dst f64.Aff3, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	sr = sr.Intersect(t.Bounds())
+	for i, u := range t.tiles {
+		r := t.tileBounds(i)
+		ur := sr.Intersect(r)
+		if ur.Empty() {
+			continue
+		}
+		dst.Draw(tileToTexture(src2dst, r.Min), u, ur.Sub(r.Min), op, opts)
+	}
+}
+
+// drawBatchOn draws quads of t on w, as their parts on each tile. Runs of
+// consecutive parts on the same tile are drawn as one batch, so that the
+// parts are drawn in the quads' order.
+func (t *tiledTexture) drawBatchOn(w *windowImpl, quads []screen.Quad, op draw.Op, opts *screen.DrawOptions) {
+	var (
+		run     []screen.Quad
+		runTile = -1
+	)
+	for _, q := range quads {
+		sr := q.SR.Intersect(t.Bounds())
+		for i := range t.tiles {
+			r := t.tileBounds(i)
+			ur := sr.Intersect(r)
+			if ur.Empty() {
+				continue
+			}
+			if i != runTile && len(run) != 0 {
+				w.DrawBatch(t.tiles[runTile], run, op, opts)
+				run = run[:0]
+			}
+			runTile = i
+			run = append(run, screen.Quad{
+				Src2Dst: tileToTexture(q.Src2Dst, r.Min),
+				SR:      ur.Sub(r.Min),
+			})
+		}
+	}
+	if len(run) != 0 {
+		w.DrawBatch(t.tiles[runTile], run, op, opts)
+	}
+}
+
+// tileToTexture returns src2dst, which transforms from a tiledTexture's
+// space, preceded by the translation from the space of its tile whose
+// top-left pixel is at p.
+func tileToTexture(src2dst f64.Aff3, p image.Point) f64.Aff3 {
+	x, y := float64(p.X), float64(p.Y)
+	src2dst[2] += src2dst[0]*x + src2dst[1]*y
+	src2dst[5] += src2dst[3]*x + src2dst[4]*y
+	return src2dst
+}
+
+// transformedBounds returns the smallest rectangle of whole pixels that
+// contains r transformed by m.
+func transformedBounds(m *f64.Aff3, r image.Rectangle) image.Rectangle {
+	minX, minY := math.Inf(+1), math.Inf(+1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range [4]image.Point{
+		r.Min,
+		{r.Max.X, r.Min.Y},
+		{r.Min.X, r.Max.Y},
+		r.Max,
+	} {
+		x := m[0]*float64(p.X) + m[1]*float64(p.Y) + m[2]
+		y := m[3]*float64(p.X) + m[4]*float64(p.Y) + m[5]
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	return image.Rect(
+		int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX)), int(math.Ceil(maxY)),
+	)
+}
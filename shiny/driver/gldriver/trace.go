@@ -0,0 +1,659 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gldriver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/mobile/gl"
+)
+
+// traceFlags are what the SHINY_GL_TRACE environment variable asks for, as
+// documented in the package comment.
+type traceFlags uint8
+
+const (
+	traceCalls traceFlags = 1 << iota
+	traceErrors
+	traceLabels
+	traceFrames
+)
+
+var theTraceFlags = parseTraceFlags(os.Getenv("SHINY_GL_TRACE"))
+
+func parseTraceFlags(s string) (f traceFlags) {
+	for _, name := range strings.Split(s, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "calls":
+			f |= traceCalls
+		case "errors":
+			f |= traceErrors
+		case "labels":
+			f |= traceLabels
+		case "frames":
+			f |= traceFrames
+		case "all":
+			f |= traceCalls | traceErrors | traceLabels | traceFrames
+		default:
+			log.Printf("gldriver: unknown SHINY_GL_TRACE flag %q", name)
+		}
+	}
+	return f
+}
+
+const (
+	// maxTraceErrors bounds how many GL errors are read after each call, and
+	// how many are kept for the GetError calls of checkGLError. Like that
+	// limit, it guards against a lost context reporting errors forever.
+	maxTraceErrors = 16
+	// maxFrameCalls bounds how many calls a frame's trace holds, in case
+	// the window is never published.
+	maxFrameCalls = 1 << 14
+)
+
+// These constants match the values found in the KHR_debug extension's
+// headers, for the objects that gldriver labels.
+const (
+	_GL_BUFFER_KHR  = 0x82e0
+	_GL_SHADER_KHR  = 0x82e1
+	_GL_PROGRAM_KHR = 0x82e2
+	_GL_TEXTURE     = 0x1702
+	_GL_FRAMEBUFFER = 0x8d40
+)
+
+type objectKey struct {
+	identifier gl.Enum
+	name       uint32
+}
+
+// tracedContext is a gl.Context that logs the calls to the methods that
+// gldriver uses, according to its flags. Like any gl.Context, it must only
+// be used while holding its mutex: windowImpl.glctxMu or
+// screenImpl.shareMu.
+type tracedContext struct {
+	gl.Context
+
+	name  string
+	flags traceFlags
+
+	// run is received from by the context's processing thread, which calls
+	// the functions it is sent. labelObject sends it the native KHR_debug
+	// calls, which the gl package has no methods for, and the share
+	// context's is also screenImpl.shareRun.
+	run chan func()
+
+	labels   map[objectKey]string
+	noLabels bool
+
+	// pending are the GL errors that traced has read, and that GetError
+	// returns before any others.
+	pending []gl.Enum
+
+	frame      []string
+	frameCount int
+	dropped    int
+}
+
+var (
+	traceMu    sync.Mutex
+	traceCount = map[string]int{}
+)
+
+// traceContext returns glctx, wrapped in a tracedContext if SHINY_GL_TRACE
+// asks for any tracing. The kind is "share" or "window", and only windows'
+// contexts buffer their calls into frames.
+func traceContext(glctx gl.Context, kind string) gl.Context {
+	if theTraceFlags == 0 {
+		return glctx
+	}
+	traceMu.Lock()
+	traceCount[kind]++
+	name := fmt.Sprintf("%s %d", kind, traceCount[kind])
+	traceMu.Unlock()
+
+	flags := theTraceFlags
+	if kind != "window" {
+		flags &^= traceFrames
+	}
+	return &tracedContext{
+		Context: glctx,
+		name:    name,
+		flags:   flags,
+		run:     make(chan func()),
+		labels:  map[objectKey]string{},
+	}
+}
+
+// traceRun returns the channel that glctx's processing thread must receive
+// functions to call from. It is nil, and so never ready, if glctx is not
+// traced.
+func traceRun(glctx gl.Context) chan func() {
+	if c, ok := glctx.(*tracedContext); ok {
+		return c.run
+	}
+	return nil
+}
+
+// isContext3 returns whether glctx, or the context it traces, is an OpenGL
+// ES 3 context.
+func isContext3(glctx gl.Context) bool {
+	if c, ok := glctx.(*tracedContext); ok {
+		glctx = c.Context
+	}
+	_, ok := glctx.(gl.Context3)
+	return ok
+}
+
+// labelObject labels the GL object with the given KHR_debug identifier and
+// name, for the traced calls and, if SHINY_GL_TRACE asks for labels and the
+// GL implementation has GL_KHR_debug, for graphics debuggers such as
+// RenderDoc. It does nothing if glctx is not traced.
+func labelObject(glctx gl.Context, identifier gl.Enum, name uint32, label string) {
+	c, ok := glctx.(*tracedContext)
+	if !ok || name == 0 {
+		return
+	}
+	c.labels[objectKey{identifier, name}] = label
+	if c.flags&traceLabels == 0 || c.noLabels {
+		return
+	}
+	// The object may not exist until the calls still queued for the
+	// processing thread, such as its first BindTexture, are done.
+	c.Context.Finish()
+	done := make(chan bool)
+	c.run <- func() {
+		done <- objectLabel(uint32(identifier), name, label)
+	}
+	if !<-done {
+		c.noLabels = true
+	}
+}
+
+// traceFrame logs the calls buffered since the previous frame of glctx, if
+// it is a traced window context. It must be called by Publish, while holding
+// windowImpl.glctxMu.
+func traceFrame(glctx gl.Context) {
+	c, ok := glctx.(*tracedContext)
+	if !ok || c.flags&traceFrames == 0 {
+		return
+	}
+	c.frameCount++
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "gldriver: %s: frame %d: %d calls", c.name, c.frameCount, len(c.frame)+c.dropped)
+	for _, call := range c.frame {
+		b.WriteString("\n\t")
+		b.WriteString(call)
+	}
+	if c.dropped != 0 {
+		fmt.Fprintf(b, "\n\t... and %d more", c.dropped)
+	}
+	log.Print(b.String())
+	c.frame, c.dropped = c.frame[:0], 0
+}
+
+// traced records a call to the named method, according to c.flags. Its
+// result is ret, or nil for methods that return nothing.
+func (c *tracedContext) traced(method string, ret interface{}, args ...interface{}) {
+	if c.flags&traceErrors != 0 {
+		for i := 0; i < maxTraceErrors; i++ {
+			e := c.Context.GetError()
+			if e == gl.NO_ERROR {
+				break
+			}
+			if len(c.pending) < maxTraceErrors {
+				c.pending = append(c.pending, e)
+			}
+			log.Printf("gldriver: %s: GL error %#x from %s", c.name, uint32(e), c.format(method, ret, args))
+		}
+	}
+	if c.flags&(traceCalls|traceFrames) == 0 {
+		return
+	}
+	call := c.format(method, ret, args)
+	if c.flags&traceFrames == 0 {
+		log.Printf("gldriver: %s: %s", c.name, call)
+	} else if len(c.frame) < maxFrameCalls {
+		c.frame = append(c.frame, call)
+	} else {
+		c.dropped++
+	}
+}
+
+func (c *tracedContext) format(method string, ret interface{}, args []interface{}) string {
+	b := new(strings.Builder)
+	b.WriteString(method)
+	b.WriteByte('(')
+	for i, a := range args {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(c.formatArg(a))
+	}
+	b.WriteByte(')')
+	if ret != nil {
+		b.WriteString(" = ")
+		b.WriteString(c.formatArg(ret))
+	}
+	return b.String()
+}
+
+func (c *tracedContext) formatArg(a interface{}) string {
+	switch a := a.(type) {
+	case gl.Enum:
+		return fmt.Sprintf("%#x", uint32(a))
+	case gl.Buffer:
+		return c.formatObject("buffer", _GL_BUFFER_KHR, a.Value)
+	case gl.Framebuffer:
+		return c.formatObject("framebuffer", _GL_FRAMEBUFFER, a.Value)
+	case gl.Program:
+		return c.formatObject("program", _GL_PROGRAM_KHR, a.Value)
+	case gl.Shader:
+		return c.formatObject("shader", _GL_SHADER_KHR, a.Value)
+	case gl.Texture:
+		return c.formatObject("texture", _GL_TEXTURE, a.Value)
+	case gl.Renderbuffer:
+		return fmt.Sprintf("renderbuffer %d", a.Value)
+	case gl.Attrib:
+		return fmt.Sprintf("attrib %d", a.Value)
+	case gl.Uniform:
+		return fmt.Sprintf("uniform %d", a.Value)
+	case []byte:
+		if a == nil {
+			return "nil"
+		}
+		return fmt.Sprintf("[%d bytes]", len(a))
+	case string:
+		// Shader sources and info logs are too long, and have too many
+		// lines, to log in full.
+		if len(a) > 64 || strings.Contains(a, "\n") {
+			return fmt.Sprintf("[%d-byte string]", len(a))
+		}
+		return fmt.Sprintf("%q", a)
+	}
+	return fmt.Sprint(a)
+}
+
+func (c *tracedContext) formatObject(kind string, identifier gl.Enum, name uint32) string {
+	if label, ok := c.labels[objectKey{identifier, name}]; ok {
+		return fmt.Sprintf("%s %d %q", kind, name, label)
+	}
+	return fmt.Sprintf("%s %d", kind, name)
+}
+
+// GetError returns the errors that traced has read before any that are
+// still set.
+func (c *tracedContext) GetError() gl.Enum {
+	if len(c.pending) != 0 {
+		e := c.pending[0]
+		c.pending = c.pending[1:]
+		return e
+	}
+	return c.Context.GetError()
+}
+
+// The methods below are the gl.Context methods that gldriver uses.
+
+func (c *tracedContext) ActiveTexture(texture gl.Enum) {
+	c.Context.ActiveTexture(texture)
+	c.traced("ActiveTexture", nil, texture)
+}
+
+func (c *tracedContext) AttachShader(p gl.Program, s gl.Shader) {
+	c.Context.AttachShader(p, s)
+	c.traced("AttachShader", nil, p, s)
+}
+
+func (c *tracedContext) BindBuffer(target gl.Enum, b gl.Buffer) {
+	c.Context.BindBuffer(target, b)
+	c.traced("BindBuffer", nil, target, b)
+}
+
+func (c *tracedContext) BindFramebuffer(target gl.Enum, fb gl.Framebuffer) {
+	c.Context.BindFramebuffer(target, fb)
+	c.traced("BindFramebuffer", nil, target, fb)
+}
+
+func (c *tracedContext) BindRenderbuffer(target gl.Enum, rb gl.Renderbuffer) {
+	c.Context.BindRenderbuffer(target, rb)
+	c.traced("BindRenderbuffer", nil, target, rb)
+}
+
+func (c *tracedContext) BindTexture(target gl.Enum, t gl.Texture) {
+	c.Context.BindTexture(target, t)
+	c.traced("BindTexture", nil, target, t)
+}
+
+func (c *tracedContext) BlendFunc(sfactor gl.Enum, dfactor gl.Enum) {
+	c.Context.BlendFunc(sfactor, dfactor)
+	c.traced("BlendFunc", nil, sfactor, dfactor)
+}
+
+func (c *tracedContext) BufferData(target gl.Enum, src []byte, usage gl.Enum) {
+	c.Context.BufferData(target, src, usage)
+	c.traced("BufferData", nil, target, src, usage)
+}
+
+func (c *tracedContext) Clear(mask gl.Enum) {
+	c.Context.Clear(mask)
+	c.traced("Clear", nil, mask)
+}
+
+func (c *tracedContext) ClearColor(red float32, green float32, blue float32, alpha float32) {
+	c.Context.ClearColor(red, green, blue, alpha)
+	c.traced("ClearColor", nil, red, green, blue, alpha)
+}
+
+func (c *tracedContext) ClearDepthf(d float32) {
+	c.Context.ClearDepthf(d)
+	c.traced("ClearDepthf", nil, d)
+}
+
+func (c *tracedContext) ClearStencil(s int) {
+	c.Context.ClearStencil(s)
+	c.traced("ClearStencil", nil, s)
+}
+
+func (c *tracedContext) ColorMask(red bool, green bool, blue bool, alpha bool) {
+	c.Context.ColorMask(red, green, blue, alpha)
+	c.traced("ColorMask", nil, red, green, blue, alpha)
+}
+
+func (c *tracedContext) CompileShader(s gl.Shader) {
+	c.Context.CompileShader(s)
+	c.traced("CompileShader", nil, s)
+}
+
+func (c *tracedContext) CompressedTexImage2D(target gl.Enum, level int, internalformat gl.Enum, width int, height int, border int, data []byte) {
+	c.Context.CompressedTexImage2D(target, level, internalformat, width, height, border, data)
+	c.traced("CompressedTexImage2D", nil, target, level, internalformat, width, height, border, data)
+}
+
+func (c *tracedContext) CompressedTexSubImage2D(target gl.Enum, level int, xoffset int, yoffset int, width int, height int, format gl.Enum, data []byte) {
+	c.Context.CompressedTexSubImage2D(target, level, xoffset, yoffset, width, height, format, data)
+	c.traced("CompressedTexSubImage2D", nil, target, level, xoffset, yoffset, width, height, format, data)
+}
+
+func (c *tracedContext) CreateBuffer() gl.Buffer {
+	r := c.Context.CreateBuffer()
+	c.traced("CreateBuffer", r)
+	return r
+}
+
+func (c *tracedContext) CreateFramebuffer() gl.Framebuffer {
+	r := c.Context.CreateFramebuffer()
+	c.traced("CreateFramebuffer", r)
+	return r
+}
+
+func (c *tracedContext) CreateProgram() gl.Program {
+	r := c.Context.CreateProgram()
+	c.traced("CreateProgram", r)
+	return r
+}
+
+func (c *tracedContext) CreateRenderbuffer() gl.Renderbuffer {
+	r := c.Context.CreateRenderbuffer()
+	c.traced("CreateRenderbuffer", r)
+	return r
+}
+
+func (c *tracedContext) CreateShader(ty gl.Enum) gl.Shader {
+	r := c.Context.CreateShader(ty)
+	c.traced("CreateShader", r, ty)
+	return r
+}
+
+func (c *tracedContext) CreateTexture() gl.Texture {
+	r := c.Context.CreateTexture()
+	c.traced("CreateTexture", r)
+	return r
+}
+
+func (c *tracedContext) DeleteFramebuffer(v gl.Framebuffer) {
+	c.Context.DeleteFramebuffer(v)
+	c.traced("DeleteFramebuffer", nil, v)
+	delete(c.labels, objectKey{_GL_FRAMEBUFFER, v.Value})
+}
+
+func (c *tracedContext) DeleteProgram(p gl.Program) {
+	c.Context.DeleteProgram(p)
+	c.traced("DeleteProgram", nil, p)
+	delete(c.labels, objectKey{_GL_PROGRAM_KHR, p.Value})
+}
+
+func (c *tracedContext) DeleteShader(s gl.Shader) {
+	c.Context.DeleteShader(s)
+	c.traced("DeleteShader", nil, s)
+}
+
+func (c *tracedContext) DeleteTexture(v gl.Texture) {
+	c.Context.DeleteTexture(v)
+	c.traced("DeleteTexture", nil, v)
+	delete(c.labels, objectKey{_GL_TEXTURE, v.Value})
+}
+
+func (c *tracedContext) DepthFunc(fn gl.Enum) {
+	c.Context.DepthFunc(fn)
+	c.traced("DepthFunc", nil, fn)
+}
+
+func (c *tracedContext) Disable(cap_ gl.Enum) {
+	c.Context.Disable(cap_)
+	c.traced("Disable", nil, cap_)
+}
+
+func (c *tracedContext) DisableVertexAttribArray(a gl.Attrib) {
+	c.Context.DisableVertexAttribArray(a)
+	c.traced("DisableVertexAttribArray", nil, a)
+}
+
+func (c *tracedContext) DrawArrays(mode gl.Enum, first int, count int) {
+	c.Context.DrawArrays(mode, first, count)
+	c.traced("DrawArrays", nil, mode, first, count)
+}
+
+func (c *tracedContext) Enable(cap_ gl.Enum) {
+	c.Context.Enable(cap_)
+	c.traced("Enable", nil, cap_)
+}
+
+func (c *tracedContext) EnableVertexAttribArray(a gl.Attrib) {
+	c.Context.EnableVertexAttribArray(a)
+	c.traced("EnableVertexAttribArray", nil, a)
+}
+
+func (c *tracedContext) Finish() {
+	c.Context.Finish()
+	c.traced("Finish", nil)
+}
+
+func (c *tracedContext) Flush() {
+	c.Context.Flush()
+	c.traced("Flush", nil)
+}
+
+func (c *tracedContext) FramebufferRenderbuffer(target gl.Enum, attachment gl.Enum, rbTarget gl.Enum, rb gl.Renderbuffer) {
+	c.Context.FramebufferRenderbuffer(target, attachment, rbTarget, rb)
+	c.traced("FramebufferRenderbuffer", nil, target, attachment, rbTarget, rb)
+}
+
+func (c *tracedContext) FramebufferTexture2D(target gl.Enum, attachment gl.Enum, texTarget gl.Enum, t gl.Texture, level int) {
+	c.Context.FramebufferTexture2D(target, attachment, texTarget, t, level)
+	c.traced("FramebufferTexture2D", nil, target, attachment, texTarget, t, level)
+}
+
+func (c *tracedContext) GenerateMipmap(target gl.Enum) {
+	c.Context.GenerateMipmap(target)
+	c.traced("GenerateMipmap", nil, target)
+}
+
+func (c *tracedContext) GetAttribLocation(p gl.Program, name string) gl.Attrib {
+	r := c.Context.GetAttribLocation(p, name)
+	c.traced("GetAttribLocation", r, p, name)
+	return r
+}
+
+func (c *tracedContext) GetInteger(pname gl.Enum) int {
+	r := c.Context.GetInteger(pname)
+	c.traced("GetInteger", r, pname)
+	return r
+}
+
+func (c *tracedContext) GetProgramInfoLog(p gl.Program) string {
+	r := c.Context.GetProgramInfoLog(p)
+	c.traced("GetProgramInfoLog", r, p)
+	return r
+}
+
+func (c *tracedContext) GetProgrami(p gl.Program, pname gl.Enum) int {
+	r := c.Context.GetProgrami(p, pname)
+	c.traced("GetProgrami", r, p, pname)
+	return r
+}
+
+func (c *tracedContext) GetShaderInfoLog(s gl.Shader) string {
+	r := c.Context.GetShaderInfoLog(s)
+	c.traced("GetShaderInfoLog", r, s)
+	return r
+}
+
+func (c *tracedContext) GetShaderi(s gl.Shader, pname gl.Enum) int {
+	r := c.Context.GetShaderi(s, pname)
+	c.traced("GetShaderi", r, s, pname)
+	return r
+}
+
+func (c *tracedContext) GetString(pname gl.Enum) string {
+	r := c.Context.GetString(pname)
+	c.traced("GetString", r, pname)
+	return r
+}
+
+func (c *tracedContext) GetUniformLocation(p gl.Program, name string) gl.Uniform {
+	r := c.Context.GetUniformLocation(p, name)
+	c.traced("GetUniformLocation", r, p, name)
+	return r
+}
+
+func (c *tracedContext) IsProgram(p gl.Program) bool {
+	r := c.Context.IsProgram(p)
+	c.traced("IsProgram", r, p)
+	return r
+}
+
+func (c *tracedContext) LinkProgram(p gl.Program) {
+	c.Context.LinkProgram(p)
+	c.traced("LinkProgram", nil, p)
+}
+
+func (c *tracedContext) PixelStorei(pname gl.Enum, param int32) {
+	c.Context.PixelStorei(pname, param)
+	c.traced("PixelStorei", nil, pname, param)
+}
+
+func (c *tracedContext) ReadPixels(dst []byte, x int, y int, width int, height int, format gl.Enum, ty gl.Enum) {
+	c.Context.ReadPixels(dst, x, y, width, height, format, ty)
+	c.traced("ReadPixels", nil, dst, x, y, width, height, format, ty)
+}
+
+func (c *tracedContext) RenderbufferStorage(target gl.Enum, internalFormat gl.Enum, width int, height int) {
+	c.Context.RenderbufferStorage(target, internalFormat, width, height)
+	c.traced("RenderbufferStorage", nil, target, internalFormat, width, height)
+}
+
+func (c *tracedContext) Scissor(x int32, y int32, width int32, height int32) {
+	c.Context.Scissor(x, y, width, height)
+	c.traced("Scissor", nil, x, y, width, height)
+}
+
+func (c *tracedContext) ShaderSource(s gl.Shader, src string) {
+	c.Context.ShaderSource(s, src)
+	c.traced("ShaderSource", nil, s, src)
+}
+
+func (c *tracedContext) StencilFunc(fn gl.Enum, ref int, mask uint32) {
+	c.Context.StencilFunc(fn, ref, mask)
+	c.traced("StencilFunc", nil, fn, ref, mask)
+}
+
+func (c *tracedContext) StencilMask(mask uint32) {
+	c.Context.StencilMask(mask)
+	c.traced("StencilMask", nil, mask)
+}
+
+func (c *tracedContext) StencilOp(fail gl.Enum, zfail gl.Enum, zpass gl.Enum) {
+	c.Context.StencilOp(fail, zfail, zpass)
+	c.traced("StencilOp", nil, fail, zfail, zpass)
+}
+
+func (c *tracedContext) StencilOpSeparate(face gl.Enum, sfail gl.Enum, dpfail gl.Enum, dppass gl.Enum) {
+	c.Context.StencilOpSeparate(face, sfail, dpfail, dppass)
+	c.traced("StencilOpSeparate", nil, face, sfail, dpfail, dppass)
+}
+
+func (c *tracedContext) TexImage2D(target gl.Enum, level int, width int, height int, format gl.Enum, ty gl.Enum, data []byte) {
+	c.Context.TexImage2D(target, level, width, height, format, ty, data)
+	c.traced("TexImage2D", nil, target, level, width, height, format, ty, data)
+}
+
+func (c *tracedContext) TexParameteri(target gl.Enum, pname gl.Enum, param int) {
+	c.Context.TexParameteri(target, pname, param)
+	c.traced("TexParameteri", nil, target, pname, param)
+}
+
+func (c *tracedContext) TexSubImage2D(target gl.Enum, level int, x int, y int, width int, height int, format gl.Enum, ty gl.Enum, data []byte) {
+	c.Context.TexSubImage2D(target, level, x, y, width, height, format, ty, data)
+	c.traced("TexSubImage2D", nil, target, level, x, y, width, height, format, ty, data)
+}
+
+func (c *tracedContext) Uniform1f(dst gl.Uniform, v float32) {
+	c.Context.Uniform1f(dst, v)
+	c.traced("Uniform1f", nil, dst, v)
+}
+
+func (c *tracedContext) Uniform1i(dst gl.Uniform, v int) {
+	c.Context.Uniform1i(dst, v)
+	c.traced("Uniform1i", nil, dst, v)
+}
+
+func (c *tracedContext) Uniform2f(dst gl.Uniform, v0 float32, v1 float32) {
+	c.Context.Uniform2f(dst, v0, v1)
+	c.traced("Uniform2f", nil, dst, v0, v1)
+}
+
+func (c *tracedContext) Uniform3f(dst gl.Uniform, v0 float32, v1 float32, v2 float32) {
+	c.Context.Uniform3f(dst, v0, v1, v2)
+	c.traced("Uniform3f", nil, dst, v0, v1, v2)
+}
+
+func (c *tracedContext) Uniform4f(dst gl.Uniform, v0 float32, v1 float32, v2 float32, v3 float32) {
+	c.Context.Uniform4f(dst, v0, v1, v2, v3)
+	c.traced("Uniform4f", nil, dst, v0, v1, v2, v3)
+}
+
+func (c *tracedContext) UniformMatrix3fv(dst gl.Uniform, src []float32) {
+	c.Context.UniformMatrix3fv(dst, src)
+	c.traced("UniformMatrix3fv", nil, dst, src)
+}
+
+func (c *tracedContext) UseProgram(p gl.Program) {
+	c.Context.UseProgram(p)
+	c.traced("UseProgram", nil, p)
+}
+
+func (c *tracedContext) VertexAttribPointer(dst gl.Attrib, size int, ty gl.Enum, normalized bool, stride int, offset int) {
+	c.Context.VertexAttribPointer(dst, size, ty, normalized, stride, offset)
+	c.traced("VertexAttribPointer", nil, dst, size, ty, normalized, stride, offset)
+}
+
+func (c *tracedContext) Viewport(x int, y int, width int, height int) {
+	c.Context.Viewport(x, y, width, height)
+	c.traced("Viewport", nil, x, y, width, height)
+}
@@ -0,0 +1,158 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gldriver
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/gl"
+)
+
+// pboMinBytes is the size of the smallest upload that is staged through a
+// pixel buffer object. Staging a smaller one costs more than it saves.
+const pboMinBytes = 64 << 10
+
+// pboStage stages large uploads to the share context's textures through two
+// pixel buffer objects, in turn, on OpenGL ES 3. Copying the pixels into a
+// buffer object is all that the caller waits for, and the GPU then copies
+// them into the texture while the next upload is copied into the other. Each
+// buffer object's fence is signaled once the GPU is done with the upload
+// from it, and is waited for before the buffer object is used again.
+type pboStage struct {
+	checked, supported bool
+
+	bufs   [2]gl.Buffer
+	fences [2]uintptr // GLsync objects, or zero.
+	next   int
+}
+
+// asyncUpload is an upload started by UploadAsync.
+type asyncUpload struct {
+	dst screen.Uploader
+	dp  image.Point
+	src *bufferImpl
+	sr  image.Rectangle
+	q   screen.EventDeque
+	id  int
+}
+
+// UploadAsync implements screen.AsyncUploader.
+func (t *textureImpl) UploadAsync(dp image.Point, src screen.Buffer, sr image.Rectangle, q screen.EventDeque, id int) {
+	t.s.uploadAsync(asyncUpload{t, dp, src.(*bufferImpl), sr, q, id})
+}
+
+// UploadAsync implements screen.AsyncUploader.
+func (t *tiledTexture) UploadAsync(dp image.Point, src screen.Buffer, sr image.Rectangle, q screen.EventDeque, id int) {
+	t.tiles[0].s.uploadAsync(asyncUpload{t, dp, src.(*bufferImpl), sr, q, id})
+}
+
+// uploadAsync queues u for the upload goroutine, starting it if it is not
+// running.
+func (s *screenImpl) uploadAsync(u asyncUpload) {
+	u.src.preUpload()
+	u.src.startUpload()
+
+	s.uploadsMu.Lock()
+	defer s.uploadsMu.Unlock()
+
+	s.uploads = append(s.uploads, u)
+	if !s.uploading {
+		s.uploading = true
+		go s.doUploads()
+	}
+}
+
+// doUploads does the queued asynchronous uploads, in order, until there are
+// none left.
+func (s *screenImpl) doUploads() {
+	for {
+		s.uploadsMu.Lock()
+		if len(s.uploads) == 0 {
+			s.uploading = false
+			s.uploadsMu.Unlock()
+			return
+		}
+		u := s.uploads[0]
+		s.uploads[0] = asyncUpload{}
+		s.uploads = s.uploads[1:]
+		s.uploadsMu.Unlock()
+
+		u.dst.Upload(u.dp, u.src, u.sr)
+		u.src.endUpload()
+		if u.q != nil {
+			u.q.Send(screen.UploadEvent{ID: u.id, Buffer: u.src})
+		}
+	}
+}
+
+// runShare calls f on the share context's processing thread, and waits for
+// it to return. It must only be called while holding s.shareMu, and after
+// any queued gl.Context calls that f depends on are done.
+func (s *screenImpl) runShare(f func()) {
+	done := make(chan struct{})
+	s.shareRun <- func() {
+		f()
+		close(done)
+	}
+	<-done
+}
+
+// uploadPBO re-specifies the dr sub-rectangle of the texture bound to the
+// share context as the RGBA pixels pix, whose rows start every stride bytes,
+// staging them through s.pbo. It returns false, doing nothing, if they are
+// too few to be worth staging or the share context cannot stage them. It
+// must only be called while holding s.shareMu.
+func (s *screenImpl) uploadPBO(dr image.Rectangle, pix []byte, stride int) bool {
+	glctx, p := s.share, &s.pbo
+	n := (dr.Dy()-1)*stride + dr.Dx()*4
+	if n < pboMinBytes || !isContext3(glctx) {
+		return false
+	}
+	if !p.checked {
+		p.checked = true
+		s.runShare(func() { p.supported = pboSupported() })
+	}
+	if !p.supported {
+		return false
+	}
+
+	i := p.next
+	p.next = 1 - i
+	if f := p.fences[i]; f != 0 {
+		s.runShare(func() { waitSync(f) })
+		p.fences[i] = 0
+	}
+	created := p.bufs[i] == (gl.Buffer{})
+	if created {
+		p.bufs[i] = glctx.CreateBuffer()
+	}
+	glctx.BindBuffer(gl.PIXEL_UNPACK_BUFFER, p.bufs[i])
+	if created {
+		labelObject(glctx, _GL_BUFFER_KHR, p.bufs[i].Value, fmt.Sprintf("upload stage %d", i))
+	}
+	glctx.PixelStorei(gl.UNPACK_ROW_LENGTH, int32(stride/4))
+	// BufferData blocks until it and the calls queued before it are done,
+	// so that they are before the native calls.
+	glctx.BufferData(gl.PIXEL_UNPACK_BUFFER, pix[:n], gl.STREAM_DRAW)
+	s.runShare(func() {
+		texSubImageUnpack(dr.Min.X, dr.Min.Y, dr.Dx(), dr.Dy())
+		p.fences[i] = fenceSync()
+	})
+	glctx.BindBuffer(gl.PIXEL_UNPACK_BUFFER, gl.Buffer{})
+	glctx.PixelStorei(gl.UNPACK_ROW_LENGTH, 0)
+	return true
+}
+
+// waitSync waits for the fence sync to be signaled, and deletes it. It must
+// be called on the processing thread of the context that created it.
+func waitSync(sync uintptr) {
+	for flush := true; !syncSignaled(sync, flush); flush = false {
+		time.Sleep(100 * time.Microsecond)
+	}
+	deleteSync(sync)
+}
@@ -9,18 +9,22 @@ package gldriver
 import (
 	"errors"
 	"fmt"
+	"image"
 	"runtime"
+	"strings"
 	"syscall"
 	"unsafe"
 
+	"golang.org/x/exp/shiny/driver/internal/hotkey"
 	"golang.org/x/exp/shiny/driver/internal/win32"
 	"golang.org/x/exp/shiny/screen"
-	"golang.org/x/mobile/event/key"
 	"golang.org/x/mobile/event/lifecycle"
 	"golang.org/x/mobile/event/mouse"
 	"golang.org/x/mobile/event/paint"
 	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/event/touch"
 	"golang.org/x/mobile/gl"
+	"golang.org/x/sys/windows"
 )
 
 // TODO: change this to true, after manual testing on Win32.
@@ -40,20 +44,33 @@ var (
 	eglGetError              = gl.LibEGL.NewProc("eglGetError")
 	eglBindAPI               = gl.LibEGL.NewProc("eglBindAPI")
 	eglCreateWindowSurface   = gl.LibEGL.NewProc("eglCreateWindowSurface")
+	eglCreatePbufferSurface  = gl.LibEGL.NewProc("eglCreatePbufferSurface")
 	eglCreateContext         = gl.LibEGL.NewProc("eglCreateContext")
+	eglDestroyContext        = gl.LibEGL.NewProc("eglDestroyContext")
 	eglMakeCurrent           = gl.LibEGL.NewProc("eglMakeCurrent")
 	eglSwapInterval          = gl.LibEGL.NewProc("eglSwapInterval")
 	eglDestroySurface        = gl.LibEGL.NewProc("eglDestroySurface")
 	eglSwapBuffers           = gl.LibEGL.NewProc("eglSwapBuffers")
+	eglQueryString           = gl.LibEGL.NewProc("eglQueryString")
+	eglGetProcAddress        = gl.LibEGL.NewProc("eglGetProcAddress")
+
+	glGetString = gl.LibGLESv2.NewProc("glGetString")
+
+	glTexSubImage2D  = gl.LibGLESv2.NewProc("glTexSubImage2D")
+	glFenceSync      = gl.LibGLESv2.NewProc("glFenceSync")
+	glClientWaitSync = gl.LibGLESv2.NewProc("glClientWaitSync")
+	glDeleteSync     = gl.LibGLESv2.NewProc("glDeleteSync")
 )
 
 type eglConfig uintptr // void*
 
 type eglInt int32
 
+// rgb888 is the config for every context. The share context has a pbuffer
+// surface, and windows' contexts have window surfaces.
 var rgb888 = [...]eglInt{
 	_EGL_RENDERABLE_TYPE, _EGL_OPENGL_ES2_BIT,
-	_EGL_SURFACE_TYPE, _EGL_WINDOW_BIT,
+	_EGL_SURFACE_TYPE, _EGL_WINDOW_BIT | _EGL_PBUFFER_BIT,
 	_EGL_BLUE_SIZE, 8,
 	_EGL_GREEN_SIZE, 8,
 	_EGL_RED_SIZE, 8,
@@ -66,27 +83,250 @@ type ctxWin32 struct {
 	ctx     uintptr
 	display uintptr // EGLDisplay
 	surface uintptr // EGLSurface
+	config  eglConfig
 }
 
-func newWindow(opts *screen.NewWindowOptions) (uintptr, error) {
-	w, err := win32.NewWindow(opts)
+func newWindow(opts *screen.NewWindowOptions, owner uintptr) (uintptr, error) {
+	if opts != nil && opts.Transparent {
+		// ANGLE cannot present to a layered window, whose contents are only
+		// set by UpdateLayeredWindow.
+		o := *opts
+		o.Transparent = false
+		opts = &o
+	}
+	w, err := win32.NewWindow(opts, syscall.Handle(owner))
 	if err != nil {
 		return 0, err
 	}
+	if err := win32.ResizeClientRect(w, opts); err != nil {
+		return 0, err
+	}
+	if opts != nil && len(opts.Shape) > 0 {
+		if err := win32.SetShape(w, opts.Shape); err != nil {
+			return 0, err
+		}
+	}
 	return uintptr(w), nil
 }
 
 func initWindow(w *windowImpl) {
 	w.glctx, w.worker = gl.NewContext()
+	w.glctx = traceContext(w.glctx, "window")
+}
+
+// objectLabel labels a GL object of the context current on the calling
+// thread, with the KHR_debug extension's glObjectLabelKHR. It returns false
+// if the context lacks that extension.
+func objectLabel(identifier, name uint32, label string) bool {
+	p, _, _ := glGetString.Call(gl.EXTENSIONS)
+	if p == 0 {
+		return false
+	}
+	if !strings.Contains(windows.BytePtrToString((*byte)(win32.Pointer(p))), "GL_KHR_debug") {
+		return false
+	}
+	// eglGetProcAddress may return a non-zero address for functions that do
+	// not exist, so it is only called once the extension is known.
+	fn, err := syscall.BytePtrFromString("glObjectLabelKHR")
+	if err != nil {
+		return false
+	}
+	f, _, _ := eglGetProcAddress.Call(uintptr(unsafe.Pointer(fn)))
+	if f == 0 {
+		return false
+	}
+	l := []byte(label + "\x00")
+	syscall.Syscall6(f, 4, uintptr(identifier), uintptr(name), uintptr(len(label)), uintptr(unsafe.Pointer(&l[0])), 0, 0)
+	return true
+}
+
+// The OpenGL ES 3 constants of the fence sync functions.
+const (
+	_GL_SYNC_FLUSH_COMMANDS_BIT    = 0x0001
+	_GL_SYNC_GPU_COMMANDS_COMPLETE = 0x9117
+	_GL_TIMEOUT_EXPIRED            = 0x911B
+)
+
+// pboSupported, texSubImageUnpack, fenceSync, syncSignaled and deleteSync
+// make the native calls of pboStage, on the share context's processing
+// thread. pboSupported returns whether libGLESv2 has the fence sync
+// functions, and must be called before the others.
+
+func pboSupported() bool {
+	return glFenceSync.Find() == nil && glClientWaitSync.Find() == nil && glDeleteSync.Find() == nil
+}
+
+func texSubImageUnpack(x, y, width, height int) {
+	glTexSubImage2D.Call(gl.TEXTURE_2D, 0, uintptr(x), uintptr(y), uintptr(width), uintptr(height), gl.RGBA, gl.UNSIGNED_BYTE, 0)
+}
+
+func fenceSync() uintptr {
+	sync, _, _ := glFenceSync.Call(_GL_SYNC_GPU_COMMANDS_COMPLETE, 0)
+	return sync
+}
+
+// syncSignaled returns whether sync is signaled, without waiting. A failed
+// wait counts as signaled, so that it is not waited for forever.
+func syncSignaled(sync uintptr, flush bool) bool {
+	f := uintptr(0)
+	if flush {
+		f = _GL_SYNC_FLUSH_COMMANDS_BIT
+	}
+	// The zero timeout is a GLuint64, which is two arguments on 386.
+	r, _, _ := glClientWaitSync.Call(sync, f, 0, 0)
+	return r != _GL_TIMEOUT_EXPIRED
 }
 
-func showWindow(w *windowImpl) {
+func deleteSync(sync uintptr) { glDeleteSync.Call(sync) }
+
+func showWindow(w *windowImpl, opts *screen.NewWindowOptions) {
 	// Show makes an initial call to sizeEvent (via win32.SizeEvent), where
-	// we setup the EGL surface and GL context.
-	win32.Show(syscall.Handle(w.id))
+	// we setup the EGL surface and GL context, even if the window is hidden.
+	win32.Show(syscall.Handle(w.id), opts)
+}
+
+// TODO: destroy the window.
+func closeWindow(id uintptr) { win32.EnableOwner(syscall.Handle(id)) }
+
+func setTitle(w *windowImpl, title string) { win32.SetTitle(syscall.Handle(w.id), title) }
+
+func setIcon(w *windowImpl, m image.Image) { win32.SetIcon(syscall.Handle(w.id), m) }
+
+func setBadge(w *windowImpl, label string) { win32.SetBadge(syscall.Handle(w.id), label) }
+
+func setProgress(w *windowImpl, progress float64) {
+	win32.SetProgress(syscall.Handle(w.id), progress)
+}
+
+func setSize(w *windowImpl, width, height int) { win32.SetSize(syscall.Handle(w.id), width, height) }
+
+func setPosition(w *windowImpl, p image.Point) { win32.SetPosition(syscall.Handle(w.id), p) }
+
+func geometry(w *windowImpl) (image.Point, image.Point) {
+	pos, sz, _ := win32.Geometry(syscall.Handle(w.id))
+	return pos, sz
+}
+
+func setTextInputRect(w *windowImpl, r image.Rectangle) {
+	win32.SetTextInputRect(syscall.Handle(w.id), r)
+}
+
+func setCursor(w *windowImpl, c screen.Cursor) { win32.SetCursor(syscall.Handle(w.id), c) }
+
+func setCursorVisible(w *windowImpl, visible bool) {
+	win32.SetCursorVisible(syscall.Handle(w.id), visible)
+}
+
+func setPointerCapture(w *windowImpl, capture bool) {
+	win32.SetPointerCapture(syscall.Handle(w.id), capture)
+}
+
+func startDrag(w *windowImpl, data screen.DragData) error {
+	return win32.StartDrag(syscall.Handle(w.id), data)
+}
+
+func startMove(w *windowImpl) error {
+	win32.StartMove(syscall.Handle(w.id))
+	return nil
+}
+
+func startResize(w *windowImpl, edge screen.WindowEdge) error {
+	win32.StartResize(syscall.Handle(w.id), edge)
+	return nil
+}
+
+func showFileDialog(w *windowImpl, opts *screen.FileDialogOptions) error {
+	return win32.ShowFileDialog(syscall.Handle(w.id), opts)
+}
+
+func setMenuBar(w *windowImpl, bar *screen.Menu) error {
+	return win32.SetMenuBar(syscall.Handle(w.id), bar)
+}
+
+func showContextMenu(w *windowImpl, m *screen.Menu, p image.Point) error {
+	return win32.ShowContextMenu(syscall.Handle(w.id), m, p)
+}
+
+func setAccessTree(w *windowImpl, root *screen.AccessNode) error {
+	return win32.SetAccessTree(syscall.Handle(w.id), root)
+}
+
+func minimize(w *windowImpl) { win32.Minimize(syscall.Handle(w.id)) }
+
+func maximize(w *windowImpl) { win32.Maximize(syscall.Handle(w.id)) }
+
+func restore(w *windowImpl) { win32.Restore(syscall.Handle(w.id)) }
+
+func setFullscreen(w *windowImpl, mode screen.FullscreenMode) {
+	win32.SetFullscreen(syscall.Handle(w.id), mode)
+}
+
+func setSizeConstraints(w *windowImpl, min, max image.Point) {
+	win32.SetSizeConstraints(syscall.Handle(w.id), min, max)
+}
+
+func setAspectRatio(w *windowImpl, aspect image.Point) {
+	win32.SetAspectRatio(syscall.Handle(w.id), aspect)
+}
+
+func registerHotKey(w *windowImpl, k hotkey.Key) error {
+	return win32.RegisterHotKey(syscall.Handle(w.id), k.Code, k.Modifiers)
+}
+
+func unregisterHotKey(w *windowImpl, k hotkey.Key) {
+	win32.UnregisterHotKey(syscall.Handle(w.id), k.Code, k.Modifiers)
+}
+
+func releaseHotKeys(w *windowImpl) { win32.ReleaseHotKeys(syscall.Handle(w.id)) }
+
+func postNotification(w *windowImpl, n *screen.Notification) error {
+	return win32.Notify(syscall.Handle(w.id), n)
+}
+
+func closeNotification(w *windowImpl, id int) {
+	win32.CloseNotification(syscall.Handle(w.id), id)
+}
+
+// releaseNotifications does nothing, as activating a released window's
+// notification sends no event.
+func releaseNotifications(w *windowImpl) {}
+
+func setAlwaysOnTop(w *windowImpl, onTop bool) { win32.SetAlwaysOnTop(syscall.Handle(w.id), onTop) }
+
+func setOpacity(w *windowImpl, opacity float32) {
+	win32.SetOpacity(syscall.Handle(w.id), win32.Opacity(opacity))
+}
+
+func raise(w *windowImpl) { win32.Raise(syscall.Handle(w.id)) }
+
+func lower(w *windowImpl) { win32.Lower(syscall.Handle(w.id)) }
+
+// setColorSpace only accepts sRGB, as ANGLE's surfaces cannot be tagged with
+// another color space.
+func setColorSpace(w *windowImpl, cs screen.ColorSpace) error {
+	if cs != screen.ColorSpaceSRGB {
+		return errors.New("gldriver: color spaces other than sRGB are not supported on windows")
+	}
+	return nil
+}
+
+// srgbSurfaces returns whether the EGL display has the EGL_KHR_gl_colorspace
+// extension, with which createEGLSurface can create sRGB-encoded surfaces. It
+// must be called after the share context is started.
+func srgbSurfaces() bool {
+	p, _, _ := eglQueryString.Call(eglDisplay, _EGL_EXTENSIONS)
+	if p == 0 {
+		return false
+	}
+	for _, ext := range strings.Fields(windows.BytePtrToString((*byte)(win32.Pointer(p)))) {
+		if ext == "EGL_KHR_gl_colorspace" {
+			return true
+		}
+	}
+	return false
 }
 
-func closeWindow(id uintptr) {} // TODO
+func nextFrame(w *windowImpl) { win32.NextFrame(w) }
 
 func drawLoop(w *windowImpl) {
 	runtime.LockOSThread()
@@ -94,6 +334,7 @@ func drawLoop(w *windowImpl) {
 	display := w.ctx.(ctxWin32).display
 	surface := w.ctx.(ctxWin32).surface
 	ctx := w.ctx.(ctxWin32).ctx
+	config := w.ctx.(ctxWin32).config
 
 	if ret, _, _ := eglMakeCurrent.Call(display, surface, surface, ctx); ret == 0 {
 		panic(fmt.Sprintf("eglMakeCurrent failed: %v", eglErr()))
@@ -101,10 +342,13 @@ func drawLoop(w *windowImpl) {
 
 	// TODO(crawshaw): exit this goroutine on Release.
 	workAvailable := w.worker.WorkAvailable()
+	run := traceRun(w.glctx)
 	for {
 		select {
 		case <-workAvailable:
 			w.worker.DoWork()
+		case f := <-run:
+			f()
 		case <-w.publish:
 		loop:
 			for {
@@ -115,9 +359,34 @@ func drawLoop(w *windowImpl) {
 					break loop
 				}
 			}
+			lost := false
 			if ret, _, _ := eglSwapBuffers.Call(display, surface); ret == 0 {
-				panic(fmt.Sprintf("eglSwapBuffers failed: %v", eglErr()))
+				code, _, _ := eglGetError.Call()
+				if code != _EGL_CONTEXT_LOST {
+					panic(fmt.Sprintf("eglSwapBuffers failed: %v", eglErrString(code)))
+				}
+				lost = true
 			}
+			w.contextLost = w.checkContext(lost, func() error {
+				eglMakeCurrent.Call(display, _EGL_NO_SURFACE, _EGL_NO_SURFACE, _EGL_NO_CONTEXT)
+				eglDestroyContext.Call(display, ctx)
+				c, err := createEGLContext(w.gpu, config)
+				if err != nil {
+					return err
+				}
+				ctx = c
+				w.ctx = ctxWin32{
+					ctx:     ctx,
+					display: display,
+					surface: surface,
+					config:  config,
+				}
+				if ret, _, _ := eglMakeCurrent.Call(display, surface, surface, ctx); ret == 0 {
+					return fmt.Errorf("eglMakeCurrent failed: %v", eglErr())
+				}
+				eglSwapInterval.Call(display, 1)
+				return nil
+			})
 			w.publishDone <- screen.PublishResult{}
 		}
 	}
@@ -129,6 +398,27 @@ func init() {
 	win32.MouseEvent = mouseEvent
 	win32.KeyEvent = keyEvent
 	win32.LifecycleEvent = lifecycleEvent
+	win32.AccessibilityEvent = accessibilityEvent
+	win32.ColorSchemeEvent = colorSchemeEvent
+	win32.DisplayEvent = displayEvent
+	win32.ScaleEvent = scaleEvent
+	win32.TextEvent = textEvent
+	win32.RelativeMouseEvent = relativeMouseEvent
+	win32.DragEvent = dragEvent
+	win32.WindowStateEvent = windowStateEvent
+	win32.VisibilityEvent = visibilityEvent
+	win32.CloseRequestEvent = closeRequestEvent
+	win32.FileDialogEvent = fileDialogEvent
+	win32.MenuEvent = menuEvent
+	win32.AccessActionEvent = accessActionEvent
+	win32.ScrollEvent = scrollEvent
+	win32.TouchEvent = touchEvent
+	win32.PenEvent = penEvent
+	win32.CrossingEvent = crossingEvent
+	win32.FocusEvent = focusEvent
+	win32.HotKeyEvent = hotKeyEvent
+	win32.KeyboardLayoutEvent = keyboardLayoutEvent
+	win32.NotificationEvent = notificationEvent
 }
 
 func lifecycleEvent(hwnd syscall.Handle, to lifecycle.Stage) {
@@ -152,14 +442,195 @@ func mouseEvent(hwnd syscall.Handle, e mouse.Event) {
 	w := theScreen.windows[uintptr(hwnd)]
 	theScreen.mu.Unlock()
 
+	w.SendAt(e, win32.MessageTime())
+}
+
+func scrollEvent(hwnd syscall.Handle, e screen.ScrollEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.SendAt(e, win32.MessageTime())
+}
+
+func touchEvent(hwnd syscall.Handle, e touch.Event) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.SendAt(e, win32.MessageTime())
+}
+
+func penEvent(hwnd syscall.Handle, e screen.PenEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.SendAt(e, win32.MessageTime())
+}
+
+func crossingEvent(hwnd syscall.Handle, e screen.CrossingEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.SendAt(e, win32.MessageTime())
+}
+
+func focusEvent(hwnd syscall.Handle, e screen.FocusEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.Send(e)
+}
+
+func hotKeyEvent(hwnd syscall.Handle, e screen.HotKeyEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.Send(e)
+}
+
+func keyboardLayoutEvent(hwnd syscall.Handle, e screen.KeyboardLayoutEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.Send(e)
+}
+
+func notificationEvent(hwnd syscall.Handle, e screen.NotificationEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	if w != nil {
+		w.Send(e)
+	}
+}
+
+func keyEvent(hwnd syscall.Handle, e screen.KeyEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	t := win32.MessageTime()
+	w.SendAt(e, t)
+}
+
+func accessibilityEvent(hwnd syscall.Handle, e screen.AccessibilityEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.Send(e)
+}
+
+func colorSchemeEvent(hwnd syscall.Handle, e screen.ColorSchemeEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.Send(e)
+}
+
+func displayEvent(hwnd syscall.Handle, e screen.DisplayEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.Send(e)
+}
+
+func scaleEvent(hwnd syscall.Handle, e screen.ScaleEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.Send(e)
+}
+
+func textEvent(hwnd syscall.Handle, e screen.TextEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.SendAt(e, win32.MessageTime())
+}
+
+func relativeMouseEvent(hwnd syscall.Handle, e screen.RelativeMouseEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.SendAt(e, win32.MessageTime())
+}
+
+func dragEvent(hwnd syscall.Handle, e screen.DragEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.Send(e)
+}
+
+func windowStateEvent(hwnd syscall.Handle, e screen.WindowStateEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.Send(e)
+}
+
+func visibilityEvent(hwnd syscall.Handle, e screen.VisibilityEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.occlusion.Set(e.Visibility)
+	w.Send(e)
+}
+
+func closeRequestEvent(hwnd syscall.Handle, e screen.CloseRequestEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	w.Send(e)
+}
+
+func fileDialogEvent(hwnd syscall.Handle, e screen.FileDialogEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	if w == nil {
+		return // The window was released while the dialog was open.
+	}
+	w.Send(e)
+}
+
+func menuEvent(hwnd syscall.Handle, e screen.MenuEvent) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[uintptr(hwnd)]
+	theScreen.mu.Unlock()
+
+	if w == nil {
+		return // The window was released while a context menu was open.
+	}
 	w.Send(e)
 }
 
-func keyEvent(hwnd syscall.Handle, e key.Event) {
+func accessActionEvent(hwnd syscall.Handle, e screen.AccessActionEvent) {
 	theScreen.mu.Lock()
 	w := theScreen.windows[uintptr(hwnd)]
 	theScreen.mu.Unlock()
 
+	if w == nil {
+		return
+	}
 	w.Send(e)
 }
 
@@ -225,6 +696,22 @@ func sizeEvent(hwnd syscall.Handle, e size.Event) {
 	}()
 }
 
+func accessibilityPrefs() screen.AccessibilityPrefs {
+	return win32.AccessibilityPrefs()
+}
+
+func colorScheme() screen.ColorScheme {
+	return win32.ColorScheme()
+}
+
+func clipboard() screen.Clipboard { return win32.Clipboard{} }
+
+func displays() []screen.Display { return win32.Displays() }
+
+func newTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return win32.NewTrayIcon(opts)
+}
+
 func eglErr() error {
 	if ret, _, _ := eglGetError.Call(); ret != _EGL_SUCCESS {
 		return errors.New(eglErrString(ret))
@@ -232,7 +719,17 @@ func eglErr() error {
 	return nil
 }
 
-func createEGLSurface(hwnd syscall.Handle, w *windowImpl) error {
+// The EGL display and config are created once, and the share context once
+// per share context generation, on the share context's thread, by
+// shareContextCreate. Every window's context
+// shares the share context's objects, so they are all on the same display.
+var (
+	eglDisplay   uintptr = _EGL_NO_DISPLAY
+	eglCfg       eglConfig
+	shareContext uintptr = _EGL_NO_CONTEXT
+)
+
+func initEGLDisplay() error {
 	var displayAttribPlatforms = [][]eglInt{
 		// Default
 		[]eglInt{
@@ -271,18 +768,13 @@ func createEGLSurface(hwnd syscall.Handle, w *windowImpl) error {
 		},
 	}
 
-	dc, err := win32.GetDC(hwnd)
-	if err != nil {
-		return fmt.Errorf("win32.GetDC failed: %v", err)
-	}
-
 	var display uintptr = _EGL_NO_DISPLAY
 	for i, displayAttrib := range displayAttribPlatforms {
 		lastTry := i == len(displayAttribPlatforms)-1
 
 		display, _, _ = eglGetPlatformDisplayEXT.Call(
 			_EGL_PLATFORM_ANGLE_ANGLE,
-			uintptr(dc),
+			_EGL_DEFAULT_DISPLAY,
 			uintptr(unsafe.Pointer(&displayAttrib[0])),
 		)
 
@@ -322,18 +814,40 @@ func createEGLSurface(hwnd syscall.Handle, w *windowImpl) error {
 		return errors.New("eglChooseConfig found no valid config")
 	}
 
-	surface, _, _ := eglCreateWindowSurface.Call(display, uintptr(config), uintptr(hwnd), 0, 0)
+	eglDisplay, eglCfg = display, config
+	return nil
+}
+
+func shareContextCreate() error {
+	if eglDisplay == _EGL_NO_DISPLAY {
+		if err := initEGLDisplay(); err != nil {
+			return err
+		}
+	}
+
+	// The share context is never used to draw, but making a context current
+	// needs a surface.
+	surfaceAttribs := []eglInt{
+		_EGL_WIDTH, 1,
+		_EGL_HEIGHT, 1,
+		_EGL_NONE,
+	}
+	surface, _, _ := eglCreatePbufferSurface.Call(
+		eglDisplay,
+		uintptr(eglCfg),
+		uintptr(unsafe.Pointer(&surfaceAttribs[0])),
+	)
 	if surface == _EGL_NO_SURFACE {
-		return fmt.Errorf("eglCreateWindowSurface failed: %v", eglErr())
+		return fmt.Errorf("eglCreatePbufferSurface failed: %v", eglErr())
 	}
 
-	contextAttribs := [...]eglInt{
+	contextAttribs := []eglInt{
 		_EGL_CONTEXT_CLIENT_VERSION, 2,
 		_EGL_NONE,
 	}
 	context, _, _ := eglCreateContext.Call(
-		display,
-		uintptr(config),
+		eglDisplay,
+		uintptr(eglCfg),
 		_EGL_NO_CONTEXT,
 		uintptr(unsafe.Pointer(&contextAttribs[0])),
 	)
@@ -341,17 +855,126 @@ func createEGLSurface(hwnd syscall.Handle, w *windowImpl) error {
 		return fmt.Errorf("eglCreateContext failed: %v", eglErr())
 	}
 
+	if ret, _, _ := eglMakeCurrent.Call(eglDisplay, surface, surface, context); ret == 0 {
+		return fmt.Errorf("eglMakeCurrent failed: %v", eglErr())
+	}
+	shareContext = context
+	return nil
+}
+
+// shareContextRecreate replaces the share context, after it was lost, on the
+// replacement's thread. The lost context is destroyed, and its objects with it.
+func shareContextRecreate() error {
+	lost := shareContext
+	if err := shareContextCreate(); err != nil {
+		return err
+	}
+	eglDestroyContext.Call(eglDisplay, lost)
+	return nil
+}
+
+func createEGLSurface(hwnd syscall.Handle, w *windowImpl) error {
+	display, config := eglDisplay, chooseEGLConfig(w.samples)
+
+	surfaceAttribs := []eglInt{_EGL_NONE}
+	if w.progs.linear {
+		surfaceAttribs = []eglInt{
+			_EGL_GL_COLORSPACE_KHR, _EGL_GL_COLORSPACE_SRGB_KHR,
+			_EGL_NONE,
+		}
+	}
+	surface, _, _ := eglCreateWindowSurface.Call(
+		display,
+		uintptr(config),
+		uintptr(hwnd),
+		uintptr(unsafe.Pointer(&surfaceAttribs[0])),
+	)
+	if surface == _EGL_NO_SURFACE {
+		return fmt.Errorf("eglCreateWindowSurface failed: %v", eglErr())
+	}
+
+	context, err := createEGLContext(w.gpu, config)
+	if err != nil {
+		return err
+	}
+
 	eglSwapInterval.Call(display, 1)
 
 	w.ctx = ctxWin32{
 		ctx:     context,
 		display: display,
 		surface: surface,
+		config:  config,
 	}
 
 	return nil
 }
 
+// chooseEGLConfig returns the config for a window with the given
+// NewWindowOptions.Samples: a multisampled variant of rgb888 with at least
+// that many samples, if there is one, or else eglCfg.
+func chooseEGLConfig(samples int) eglConfig {
+	if samples <= 1 {
+		return eglCfg
+	}
+	attribs := append(rgb888[:len(rgb888)-1:len(rgb888)-1],
+		_EGL_SAMPLE_BUFFERS, 1,
+		_EGL_SAMPLES, eglInt(samples),
+		_EGL_NONE,
+	)
+	var numConfigs eglInt
+	var config eglConfig
+	ret, _, _ := eglChooseConfig.Call(
+		eglDisplay,
+		uintptr(unsafe.Pointer(&attribs[0])),
+		uintptr(unsafe.Pointer(&config)),
+		1,
+		uintptr(unsafe.Pointer(&numConfigs)),
+	)
+	if ret == 0 || numConfigs <= 0 {
+		return eglCfg
+	}
+	return config
+}
+
+// createEGLContext creates a window's context, with the given config, in the
+// share context's share group.
+func createEGLContext(gpu screen.GPUPreference, config eglConfig) (uintptr, error) {
+	display := eglDisplay
+
+	contextAttribs := []eglInt{
+		_EGL_CONTEXT_CLIENT_VERSION, 2,
+	}
+	switch gpu {
+	case screen.GPUHighPerformance:
+		contextAttribs = append(contextAttribs, _EGL_POWER_PREFERENCE_ANGLE, _EGL_HIGH_POWER_ANGLE)
+	case screen.GPULowPower:
+		contextAttribs = append(contextAttribs, _EGL_POWER_PREFERENCE_ANGLE, _EGL_LOW_POWER_ANGLE)
+	}
+	contextAttribs = append(contextAttribs, _EGL_NONE)
+	context, _, _ := eglCreateContext.Call(
+		display,
+		uintptr(config),
+		shareContext,
+		uintptr(unsafe.Pointer(&contextAttribs[0])),
+	)
+	if context == _EGL_NO_CONTEXT && len(contextAttribs) > 3 {
+		// The EGL_ANGLE_power_preference extension is unavailable. The GPU
+		// preference is only a hint, so try again without it.
+		contextAttribs = append(contextAttribs[:2], _EGL_NONE)
+		context, _, _ = eglCreateContext.Call(
+			display,
+			uintptr(config),
+			shareContext,
+			uintptr(unsafe.Pointer(&contextAttribs[0])),
+		)
+	}
+	if context == _EGL_NO_CONTEXT {
+		return 0, fmt.Errorf("eglCreateContext failed: %v", eglErr())
+	}
+	return context, nil
+}
+
 func surfaceCreate() error {
 	return errors.New("gldriver: surface creation not implemented on windows")
 }
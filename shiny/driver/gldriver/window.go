@@ -5,16 +5,25 @@
 package gldriver
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
+	"log"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"golang.org/x/exp/shiny/driver/internal/capture"
 	"golang.org/x/exp/shiny/driver/internal/drawer"
 	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/hotkey"
 	"golang.org/x/exp/shiny/driver/internal/lifecycler"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/event/key"
 	"golang.org/x/mobile/event/lifecycle"
 	"golang.org/x/mobile/event/size"
 	"golang.org/x/mobile/gl"
@@ -29,9 +38,10 @@ type windowImpl struct {
 	//	- Windows: win32.HWND
 	id uintptr
 
-	// ctx is a C data structure for the GL context.
+	// ctx is a C data structure for the GL context. Each window has its own
+	// GL context, in the same share group as the screenImpl's share context.
 	//	- Cocoa:   uintptr holding a NSOpenGLContext*.
-	//	- X11:     uintptr holding an EGLSurface.
+	//	- X11:     ctxX11
 	//	- Windows: ctxWin32
 	ctx interface{}
 
@@ -53,32 +63,164 @@ type windowImpl struct {
 	glctxMu sync.Mutex
 	glctx   gl.Context
 	worker  gl.Worker
+	// progs are the programs compiled in glctx.
+	progs programs
 	// backBufferBound is whether the default Framebuffer, with ID 0, also
 	// known as the back buffer or the window's Framebuffer, is bound and its
 	// viewport is known to equal the window size. It can become false when we
 	// bind to a texture's Framebuffer or when the window size changes.
 	backBufferBound bool
+	// coverage is the offscreen Framebuffer in which FillPath accumulates a
+	// path's anti-aliased coverage. It is lazily created, and resized to
+	// match the window.
+	coverage coverage
+	// clips is the stack of clips pushed by PushClip and PushClipPath.
+	// stencilBits is the back buffer's number of stencil bits, or -1 if not
+	// yet queried.
+	clips       []clipRegion
+	stencilBits int
+	// depth is whether the window was created with a non-zero DepthBits, in
+	// which case draws with non-nil DrawOptions are depth tested. It is
+	// immutable. clearDepth is whether the depth buffer needs clearing before
+	// the next depth tested draw, as it does after every Publish.
+	depth      bool
+	clearDepth bool
+	// glErrorPolicy, gpu and samples are immutable.
+	glErrorPolicy screen.GLErrorPolicy
+	gpu           screen.GPUPreference
+	samples       int
+	// interceptClose is whether a WM_DELETE_WINDOW message sends a
+	// CloseRequestEvent, instead of killing the window. It is immutable,
+	// and only used by the X11 code. The Cocoa and Windows code keep track
+	// of it themselves.
+	interceptClose bool
+	// fixedSize is whether the window was created with
+	// NewWindowOptions.FixedSize, in which case its size constraints are
+	// ignored. It is immutable.
+	fixedSize bool
+	// modalOwner is the owner whose input the window, created with
+	// NewWindowOptions.Modal, blocks, or nil. It is immutable.
+	// modalChildren is how many such windows block the window's input, and
+	// is accessed atomically. Only the X11 code checks it, as Cocoa and
+	// Windows block the owner's input themselves.
+	modalOwner    *windowImpl
+	modalChildren int32
+	// released is whether Release has been called. Once it is set, drawing
+	// to the window and publishing it are no-ops.
+	released bool
+	// colorSpace is the color space set by SetColorSpace.
+	colorSpace screen.ColorSpace
+	// shareGen is the generation, as counted by screenImpl.shareGen, of the
+	// share context whose share group glctx's native context is in. It is
+	// only accessed by the OS-specific draw loop. contextLost is whether
+	// that loop re-created the native context while swapping its buffers,
+	// and is only accessed by Publish and that loop, in between the send
+	// on publish and the receive on publishDone.
+	shareGen    int
+	contextLost bool
 
 	// szMu protects only sz. If you need to hold both glctxMu and szMu, the
 	// lock ordering is to lock glctxMu first (and unlock it last).
 	szMu sync.Mutex
 	sz   size.Event
+
+	// pixelsPerPt and scale are the window's scale as of its last size
+	// event. They are only accessed by the code, specific to each OS, that
+	// sends size events, on a single thread. See sendScale.
+	pixelsPerPt float32
+	scale       float64
+	// state is the window's state, as of its last screen.WindowStateEvent.
+	// Like pixelsPerPt, it is only accessed on a single thread, by the Cocoa
+	// and X11 code. See sendState.
+	state screen.WindowState
+	// visibility is how much of the window the platform last said was
+	// visible, apart from whether it is minimized. Like state, it is only
+	// accessed by the Cocoa and X11 code. See sendVisibility.
+	visibility screen.Visibility
+
+	imagePool drawer.ImagePool
+	layers    drawer.Layers
+	captures  capture.Hooks
+	timing    frame.Timing
+	occlusion frame.Occlusion
 }
 
-// NextEvent implements the screen.EventDeque interface.
+// NextEvent implements the screen.EventDeque interface. The window sees every
+// size event, even those that its filters consume.
 func (w *windowImpl) NextEvent() interface{} {
-	e := w.Deque.NextEvent()
-	if handleSizeEventsAtChannelReceive {
-		if sz, ok := e.(size.Event); ok {
-			w.glctxMu.Lock()
-			w.backBufferBound = false
-			w.szMu.Lock()
-			w.sz = sz
-			w.szMu.Unlock()
-			w.glctxMu.Unlock()
+	for {
+		e := w.Deque.NextUnfilteredEvent()
+		if handleSizeEventsAtChannelReceive {
+			if sz, ok := e.(size.Event); ok {
+				w.glctxMu.Lock()
+				w.backBufferBound = false
+				w.szMu.Lock()
+				w.sz = sz
+				w.szMu.Unlock()
+				w.glctxMu.Unlock()
+			}
 		}
+		if e = w.Deque.Filter(e); e != nil {
+			return e
+		}
+	}
+}
+
+// sendScale sends a screen.ScaleEvent if ppp or scale differs from that of
+// the window's previous size event, other than for its first size event. It
+// is called before sending each size event, which has the same ppp.
+func (w *windowImpl) sendScale(ppp float32, scale float64) {
+	if ppp == w.pixelsPerPt && scale == w.scale {
+		return
+	}
+	initial := w.pixelsPerPt == 0
+	w.pixelsPerPt, w.scale = ppp, scale
+	if !initial {
+		w.Send(screen.ScaleEvent{PixelsPerPt: ppp, Scale: scale})
+	}
+}
+
+// sendState sends a screen.WindowStateEvent if state differs from the
+// window's previous state.
+func (w *windowImpl) sendState(state screen.WindowState) {
+	if state == w.state {
+		return
+	}
+	w.state = state
+	w.Send(screen.WindowStateEvent{State: state})
+	w.sendVisibility(w.visibility)
+}
+
+// sendVisibility sends a screen.VisibilityEvent if the window's visibility,
+// from its state and from v, how much of it the platform says is visible,
+// differs from its previous visibility.
+func (w *windowImpl) sendVisibility(v screen.Visibility) {
+	w.visibility = v
+	if w.state == screen.WindowMinimized {
+		v = screen.VisibilityMinimized
+	}
+	if w.occlusion.Set(v) {
+		w.Send(screen.VisibilityEvent{Visibility: v})
+	}
+}
+
+// prepare compiles the programs of w's GL context, and, if warmUp is set,
+// warms up its pipeline state, so that w's first frame does not stall. It
+// runs on its own goroutine, as the GL calls wait until the draw loop is
+// processing them, and any drawing meanwhile waits for it to finish.
+func (w *windowImpl) prepare(warmUp bool) {
+	w.glctxMu.Lock()
+	defer w.glctxMu.Unlock()
+	if w.released {
+		return
+	}
+	if err := w.progs.compile(w.glctx); err != nil {
+		log.Print(err)
+		return
+	}
+	if warmUp {
+		w.progs.warmUp(w.glctx)
 	}
-	return e
 }
 
 func (w *windowImpl) Release() {
@@ -95,6 +237,9 @@ func (w *windowImpl) Release() {
 	// This should send a lifecycle event (To: StageDead) to the Go app's event
 	// loop, which should respond by calling Window.Release (this method).
 	// Window.Release is where system resources are actually cleaned up.
+	// For a window created with NewWindowOptions.InterceptClose, it sends a
+	// screen.CloseRequestEvent instead, and Cocoa's windowShouldClose keeps
+	// the window open, until the Go app calls Window.Release.
 	//
 	// When Window.Release is called, the closeWindow call below:
 	//	- Cocoa:   calls Obj-C's performClose, which emulates the red button
@@ -107,12 +252,41 @@ func (w *windowImpl) Release() {
 	// race is won by performClose (which is called serially on the main
 	// thread). Even if that isn't true, the windowWillClose handler is
 	// idempotent.
+	//
+	// Release itself is also idempotent, and may race with other methods,
+	// such as Draw or Publish, called from other goroutines. Setting the
+	// released flag while holding glctxMu means that any such in-flight
+	// call completes before the window is closed, and any later call does
+	// nothing.
+
+	w.glctxMu.Lock()
+	released := w.released
+	w.released = true
+	w.glctxMu.Unlock()
+	if released {
+		return
+	}
+
+	w.imagePool.Release()
+	w.layers.Release()
+	w.StopTimers()
 
 	theScreen.mu.Lock()
 	delete(theScreen.windows, w.id)
 	theScreen.mu.Unlock()
 
+	releaseHotKeys(w)
+	releaseNotifications(w)
 	closeWindow(w.id)
+	if w.modalOwner != nil {
+		atomic.AddInt32(&w.modalOwner.modalChildren, -1)
+	}
+}
+
+// inputBlocked returns whether the window is not sent key and mouse events,
+// because a modal window that it owns is open.
+func (w *windowImpl) inputBlocked() bool {
+	return atomic.LoadInt32(&w.modalChildren) > 0
 }
 
 func (w *windowImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
@@ -122,6 +296,14 @@ func (w *windowImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangl
 		return
 	}
 	dp = dp.Add(sr.Min.Sub(originalSRMin))
+
+	w.glctxMu.Lock()
+	released := w.released
+	w.glctxMu.Unlock()
+	if released {
+		return
+	}
+
 	// TODO: keep a texture around for this purpose?
 	t, err := w.s.NewTexture(sr.Size())
 	if err != nil {
@@ -135,13 +317,93 @@ func (w *windowImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangl
 	t.Release()
 }
 
-func useOp(glctx gl.Context, op draw.Op) {
-	if op == draw.Over {
-		glctx.Enable(gl.BLEND)
-		glctx.BlendFunc(gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
-	} else {
+// blendFuncs are the source and destination blend factors of each
+// screen.BlendMode, as documented there, for alpha-premultiplied colors.
+var blendFuncs = [screen.NumBlendModes][2]gl.Enum{
+	screen.BlendClear:    {gl.ZERO, gl.ZERO},
+	screen.BlendSrc:      {gl.ONE, gl.ZERO},
+	screen.BlendDst:      {gl.ZERO, gl.ONE},
+	screen.BlendSrcOver:  {gl.ONE, gl.ONE_MINUS_SRC_ALPHA},
+	screen.BlendDstOver:  {gl.ONE_MINUS_DST_ALPHA, gl.ONE},
+	screen.BlendSrcIn:    {gl.DST_ALPHA, gl.ZERO},
+	screen.BlendDstIn:    {gl.ZERO, gl.SRC_ALPHA},
+	screen.BlendSrcOut:   {gl.ONE_MINUS_DST_ALPHA, gl.ZERO},
+	screen.BlendDstOut:   {gl.ZERO, gl.ONE_MINUS_SRC_ALPHA},
+	screen.BlendSrcAtop:  {gl.DST_ALPHA, gl.ONE_MINUS_SRC_ALPHA},
+	screen.BlendDstAtop:  {gl.ONE_MINUS_DST_ALPHA, gl.SRC_ALPHA},
+	screen.BlendXor:      {gl.ONE_MINUS_DST_ALPHA, gl.ONE_MINUS_SRC_ALPHA},
+	screen.BlendAdd:      {gl.ONE, gl.ONE},
+	screen.BlendMultiply: {gl.DST_COLOR, gl.ONE_MINUS_SRC_ALPHA},
+	screen.BlendScreen:   {gl.ONE, gl.ONE_MINUS_SRC_COLOR},
+}
+
+// useBlend configures blending for the next draw with the draw.Op op and the
+// options opts.
+func useBlend(glctx gl.Context, op draw.Op, opts *screen.DrawOptions) {
+	mode := opts.GetBlend(op)
+	if mode == screen.BlendSrc {
 		glctx.Disable(gl.BLEND)
+		return
+	}
+	glctx.Enable(gl.BLEND)
+	glctx.BlendFunc(blendFuncs[mode][0], blendFuncs[mode][1])
+}
+
+// useDepth enables or disables the depth test for the next draw, and returns
+// the clip-space z coordinate for that draw. It must only be called while
+// holding w.glctxMu, with the back buffer bound.
+func (w *windowImpl) useDepth(opts *screen.DrawOptions) (z float32) {
+	if !w.depth || opts == nil {
+		w.glctx.Disable(gl.DEPTH_TEST)
+		return 0
 	}
+	if w.clearDepth {
+		w.clearDepth = false
+		w.glctx.ClearDepthf(1)
+		w.glctx.Clear(gl.DEPTH_BUFFER_BIT)
+	}
+	w.glctx.Enable(gl.DEPTH_TEST)
+	w.glctx.DepthFunc(gl.LEQUAL)
+	// Map from [0, 1] to OpenGL's clip-space [-1, +1].
+	return 2*opts.Depth - 1
+}
+
+// checkGLError checks for any OpenGL errors in the frame about to be
+// published, and handles them according to w.glErrorPolicy. It must only be
+// called while holding w.glctxMu.
+func (w *windowImpl) checkGLError() {
+	if w.glErrorPolicy == screen.GLErrorIgnore {
+		return
+	}
+	// There may be multiple error flags set, and GetError returns and clears
+	// one at a time. The limit guards against a lost context, for which some
+	// implementations report an error forever.
+	var errs []uint32
+	for i := 0; i < 16; i++ {
+		e := w.glctx.GetError()
+		if e == gl.NO_ERROR {
+			break
+		}
+		errs = append(errs, uint32(e))
+	}
+	if len(errs) == 0 {
+		return
+	}
+	if w.glErrorPolicy == screen.GLErrorPanic {
+		panic(fmt.Sprintf("gldriver: GL errors %#x", errs))
+	}
+	log.Printf("gldriver: GL errors %#x; resetting GL state", errs)
+
+	w.glctx.BindBuffer(gl.ARRAY_BUFFER, gl.Buffer{})
+	w.glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{})
+	w.glctx.UseProgram(gl.Program{})
+	w.glctx.Disable(gl.BLEND)
+	w.glctx.Disable(gl.SCISSOR_TEST)
+	w.glctx.Disable(gl.DEPTH_TEST)
+	w.glctx.Disable(gl.STENCIL_TEST)
+	w.glctx.ColorMask(true, true, true, true)
+	// Re-binding the back buffer also resets the viewport.
+	w.backBufferBound = false
 }
 
 func (w *windowImpl) bindBackBuffer() {
@@ -154,54 +416,47 @@ func (w *windowImpl) bindBackBuffer() {
 	w.glctx.Viewport(0, 0, sz.WidthPx, sz.HeightPx)
 }
 
-func (w *windowImpl) fill(mvp f64.Aff3, src color.Color, op draw.Op) {
+func (w *windowImpl) fill(mvp f64.Aff3, src color.Color, op draw.Op, opts *screen.DrawOptions) {
 	w.glctxMu.Lock()
 	defer w.glctxMu.Unlock()
 
+	if w.released {
+		return
+	}
 	if !w.backBufferBound {
 		w.bindBackBuffer()
 	}
 
-	doFill(w.s, w.glctx, mvp, src, op)
-}
-
-func doFill(s *screenImpl, glctx gl.Context, mvp f64.Aff3, src color.Color, op draw.Op) {
-	useOp(glctx, op)
-	if !glctx.IsProgram(s.fill.program) {
-		p, err := compileProgram(glctx, fillVertexSrc, fillFragmentSrc)
-		if err != nil {
-			// TODO: initialize this somewhere else we can better handle the error.
-			panic(err.Error())
-		}
-		s.fill.program = p
-		s.fill.pos = glctx.GetAttribLocation(p, "pos")
-		s.fill.mvp = glctx.GetUniformLocation(p, "mvp")
-		s.fill.color = glctx.GetUniformLocation(p, "color")
-		s.fill.quad = glctx.CreateBuffer()
-
-		glctx.BindBuffer(gl.ARRAY_BUFFER, s.fill.quad)
-		glctx.BufferData(gl.ARRAY_BUFFER, quadCoords, gl.STATIC_DRAW)
+	z := w.useDepth(opts)
+	if !w.useClip() {
+		return
 	}
-	glctx.UseProgram(s.fill.program)
+	doFill(&w.progs, w.glctx, mvp, src, op, opts, z)
+}
 
-	writeAff3(glctx, s.fill.mvp, mvp)
+// doFill must only be called while holding the mutex for glctx.
+func doFill(p *programs, glctx gl.Context, mvp f64.Aff3, src color.Color, op draw.Op, opts *screen.DrawOptions, z float32) {
+	useBlend(glctx, op, opts)
+	p.useFill(glctx)
+	writeAff3(glctx, p.fill.mvp, mvp)
+	glctx.Uniform1f(p.fill.depth, z)
 
-	r, g, b, a := src.RGBA()
+	r, g, b, a := drawer.ScaleColor(src, opts.GetAlpha()).RGBA()
 	glctx.Uniform4f(
-		s.fill.color,
+		p.fill.color,
 		float32(r)/65535,
 		float32(g)/65535,
 		float32(b)/65535,
 		float32(a)/65535,
 	)
 
-	glctx.BindBuffer(gl.ARRAY_BUFFER, s.fill.quad)
-	glctx.EnableVertexAttribArray(s.fill.pos)
-	glctx.VertexAttribPointer(s.fill.pos, 2, gl.FLOAT, false, 0, 0)
+	glctx.BindBuffer(gl.ARRAY_BUFFER, p.fill.quad)
+	glctx.EnableVertexAttribArray(p.fill.pos)
+	glctx.VertexAttribPointer(p.fill.pos, 2, gl.FLOAT, false, 0, 0)
 
 	glctx.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
 
-	glctx.DisableVertexAttribArray(s.fill.pos)
+	glctx.DisableVertexAttribArray(p.fill.pos)
 }
 
 func (w *windowImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
@@ -213,7 +468,7 @@ func (w *windowImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
 		minX, minY,
 		maxX, minY,
 		minX, maxY,
-	), src, op)
+	), src, op, nil)
 }
 
 func (w *windowImpl) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
@@ -228,40 +483,69 @@ func (w *windowImpl) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rec
 		src2dst[3]*maxX+src2dst[4]*minY+src2dst[5],
 		src2dst[0]*minX+src2dst[1]*maxY+src2dst[2],
 		src2dst[3]*minX+src2dst[4]*maxY+src2dst[5],
-	), src, op)
+	), src, op, opts)
 }
 
 func (w *windowImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	if t, ok := src.(*tiledTexture); ok {
+		t.drawOn(w, src2dst, sr, op, opts)
+		return
+	}
 	t := src.(*textureImpl)
-	sr = sr.Intersect(t.Bounds())
+	if t.wrap == screen.WrapClamp {
+		sr = sr.Intersect(t.Bounds())
+	}
 	if sr.Empty() {
 		return
 	}
+	// The texture belongs to the share context, and is guarded by its mutex.
+	w.s.shareMu.Lock()
+	id := t.id
+	w.s.shareMu.Unlock()
+	if id == (gl.Texture{}) {
+		return // Released.
+	}
 
 	w.glctxMu.Lock()
 	defer w.glctxMu.Unlock()
 
+	if w.released {
+		return
+	}
 	if !w.backBufferBound {
 		w.bindBackBuffer()
 	}
 
-	useOp(w.glctx, op)
-	w.glctx.UseProgram(w.s.texture.program)
-
+	z := w.useDepth(opts)
+	if !w.useClip() {
+		return
+	}
 	// Start with src-space left, top, right and bottom.
 	srcL := float64(sr.Min.X)
 	srcT := float64(sr.Min.Y)
 	srcR := float64(sr.Max.X)
 	srcB := float64(sr.Max.Y)
 	// Transform to dst-space via the src2dst matrix, then to a MVP matrix.
-	writeAff3(w.glctx, w.s.texture.mvp, w.mvp(
+	mvp := w.mvp(
 		src2dst[0]*srcL+src2dst[1]*srcT+src2dst[2],
 		src2dst[3]*srcL+src2dst[4]*srcT+src2dst[5],
 		src2dst[0]*srcR+src2dst[1]*srcT+src2dst[2],
 		src2dst[3]*srcR+src2dst[4]*srcT+src2dst[5],
 		src2dst[0]*srcL+src2dst[1]*srcB+src2dst[2],
 		src2dst[3]*srcL+src2dst[4]*srcB+src2dst[5],
-	))
+	)
+	doDraw(&w.progs, w.glctx, mvp, id, t.size, sr, op, opts, z)
+}
+
+// doDraw draws the sr sub-rectangle of the texture id, whose size is size,
+// onto the quad that mvp maps the unit square to. It must only be called
+// while holding the mutex for glctx.
+func doDraw(p *programs, glctx gl.Context, mvp f64.Aff3, id gl.Texture, size image.Point, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions, z float32) {
+	useBlend(glctx, op, opts)
+	p.useTexture(glctx)
+	glctx.Uniform1f(p.texture.depth, z)
+	glctx.Uniform1f(p.texture.alpha, float32(opts.GetAlpha())/0xffff)
+	writeAff3(glctx, p.texture.mvp, mvp)
 
 	// OpenGL's fragment shaders' UV coordinates run from (0,0)-(1,1),
 	// unlike vertex shaders' XY coordinates running from (-1,+1)-(+1,-1).
@@ -278,8 +562,8 @@ func (w *windowImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectang
 	//
 	// The PQRS quad is always axis-aligned. First of all, convert
 	// from pixel space to texture space.
-	tw := float64(t.size.X)
-	th := float64(t.size.Y)
+	tw := float64(size.X)
+	th := float64(size.Y)
 	px := float64(sr.Min.X-0) / tw
 	py := float64(sr.Min.Y-0) / th
 	qx := float64(sr.Max.X-0) / tw
@@ -293,27 +577,27 @@ func (w *windowImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectang
 	//	a10 +   0 + a12 = qy = py
 	//	  0 + a01 + a02 = sx = px
 	//	  0 + a11 + a12 = sy
-	writeAff3(w.glctx, w.s.texture.uvp, f64.Aff3{
+	writeAff3(glctx, p.texture.uvp, f64.Aff3{
 		qx - px, 0, px,
 		0, sy - py, py,
 	})
 
-	w.glctx.ActiveTexture(gl.TEXTURE0)
-	w.glctx.BindTexture(gl.TEXTURE_2D, t.id)
-	w.glctx.Uniform1i(w.s.texture.sample, 0)
+	glctx.ActiveTexture(gl.TEXTURE0)
+	glctx.BindTexture(gl.TEXTURE_2D, id)
+	glctx.Uniform1i(p.texture.sample, 0)
 
-	w.glctx.BindBuffer(gl.ARRAY_BUFFER, w.s.texture.quad)
-	w.glctx.EnableVertexAttribArray(w.s.texture.pos)
-	w.glctx.VertexAttribPointer(w.s.texture.pos, 2, gl.FLOAT, false, 0, 0)
+	glctx.BindBuffer(gl.ARRAY_BUFFER, p.texture.quad)
+	glctx.EnableVertexAttribArray(p.texture.pos)
+	glctx.VertexAttribPointer(p.texture.pos, 2, gl.FLOAT, false, 0, 0)
 
-	w.glctx.BindBuffer(gl.ARRAY_BUFFER, w.s.texture.quad)
-	w.glctx.EnableVertexAttribArray(w.s.texture.inUV)
-	w.glctx.VertexAttribPointer(w.s.texture.inUV, 2, gl.FLOAT, false, 0, 0)
+	glctx.BindBuffer(gl.ARRAY_BUFFER, p.texture.quad)
+	glctx.EnableVertexAttribArray(p.texture.inUV)
+	glctx.VertexAttribPointer(p.texture.inUV, 2, gl.FLOAT, false, 0, 0)
 
-	w.glctx.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+	glctx.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
 
-	w.glctx.DisableVertexAttribArray(w.s.texture.pos)
-	w.glctx.DisableVertexAttribArray(w.s.texture.inUV)
+	glctx.DisableVertexAttribArray(p.texture.pos)
+	glctx.DisableVertexAttribArray(p.texture.inUV)
 }
 
 func (w *windowImpl) Copy(dp image.Point, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
@@ -324,6 +608,10 @@ func (w *windowImpl) Scale(dr image.Rectangle, src screen.Texture, sr image.Rect
 	drawer.Scale(w, dr, src, sr, op, opts)
 }
 
+func (w *windowImpl) DrawImage(dp image.Point, src image.Image, op draw.Op, opts *screen.DrawOptions) {
+	w.imagePool.DrawImage(w.s, w, dp, src, op, opts)
+}
+
 func (w *windowImpl) mvp(tlx, tly, trx, try, blx, bly float64) f64.Aff3 {
 	w.szMu.Lock()
 	sz := w.sz
@@ -365,7 +653,293 @@ func calcMVP(widthPx, heightPx int, tlx, tly, trx, try, blx, bly float64) f64.Af
 	}
 }
 
+func (w *windowImpl) NewLayer(z int, size image.Point) (screen.Layer, error) {
+	return w.layers.NewLayer(w.s, z, size)
+}
+
+func (w *windowImpl) NextFrame() {
+	if !w.isReleased() {
+		nextFrame(w)
+	}
+}
+
+func (w *windowImpl) RenderFrame(fn func(d screen.Drawer)) error {
+	w.glctxMu.Lock()
+	released := w.released
+	w.glctxMu.Unlock()
+	if released {
+		return errReleased
+	}
+
+	fn(w)
+
+	w.glctxMu.Lock()
+	defer w.glctxMu.Unlock()
+	if w.released {
+		return errReleased
+	}
+	// gl.Finish, unlike the gl.Flush in Publish, blocks until the GL driver
+	// has executed all of the drawing.
+	w.glctx.Finish()
+	return nil
+}
+
+var errReleased = errors.New("gldriver: window is released")
+
+func (w *windowImpl) Begin() *screen.Context { return screen.NewContext(w) }
+
+func (w *windowImpl) GLInfo() screen.GLInfo {
+	w.glctxMu.Lock()
+	defer w.glctxMu.Unlock()
+
+	if w.released {
+		return screen.GLInfo{}
+	}
+	return screen.GLInfo{
+		Vendor:   w.glctx.GetString(gl.VENDOR),
+		Renderer: w.glctx.GetString(gl.RENDERER),
+		Version:  w.glctx.GetString(gl.VERSION),
+		Samples:  w.glctx.GetInteger(gl.SAMPLES),
+	}
+}
+
+// SetTitle, SetIcon, SetBadge, SetProgress, SetSize, SetPosition,
+// GetGeometry, SetTextInputRect, SetCursor, SetCursorVisible, SetPointerCapture, StartDrag, SetMenuBar, Minimize,
+// Maximize, Restore and SetFullscreen do not hold glctxMu while calling into
+// the platform, as on Windows that can synchronously deliver a size event,
+// whose handler locks glctxMu. Instead, the platform code itself
+// copes with a concurrent Release.
+
+func (w *windowImpl) SetTitle(title string) {
+	if !w.isReleased() {
+		setTitle(w, title)
+	}
+}
+
+func (w *windowImpl) SetIcon(m image.Image) {
+	if !w.isReleased() {
+		setIcon(w, m)
+	}
+}
+
+func (w *windowImpl) SetBadge(label string) {
+	if !w.isReleased() {
+		setBadge(w, label)
+	}
+}
+
+func (w *windowImpl) SetProgress(progress float64) {
+	if !w.isReleased() {
+		setProgress(w, progress)
+	}
+}
+
+func (w *windowImpl) SetSize(width, height int) {
+	if width > 0 && height > 0 && !w.isReleased() {
+		setSize(w, width, height)
+	}
+}
+
+func (w *windowImpl) SetPosition(p image.Point) {
+	if !w.isReleased() {
+		setPosition(w, p)
+	}
+}
+
+func (w *windowImpl) GetGeometry() (image.Point, image.Point) {
+	if w.isReleased() {
+		return image.Point{}, image.Point{}
+	}
+	return geometry(w)
+}
+
+func (w *windowImpl) SetSizeConstraints(min, max image.Point) {
+	if !w.isReleased() && !w.fixedSize {
+		setSizeConstraints(w, min, max)
+	}
+}
+
+func (w *windowImpl) SetAspectRatio(aspect image.Point) {
+	if !w.isReleased() && !w.fixedSize {
+		setAspectRatio(w, aspect)
+	}
+}
+
+func (w *windowImpl) SetTextInputRect(r image.Rectangle) {
+	if !w.isReleased() {
+		setTextInputRect(w, r)
+	}
+}
+
+func (w *windowImpl) SetCursor(c screen.Cursor) {
+	if !w.isReleased() {
+		setCursor(w, c)
+	}
+}
+
+func (w *windowImpl) SetCursorVisible(visible bool) {
+	if !w.isReleased() {
+		setCursorVisible(w, visible)
+	}
+}
+
+func (w *windowImpl) SetPointerCapture(capture bool) {
+	if !w.isReleased() {
+		setPointerCapture(w, capture)
+	}
+}
+
+func (w *windowImpl) StartDrag(data screen.DragData) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return startDrag(w, data)
+}
+
+func (w *windowImpl) StartMove() error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return startMove(w)
+}
+
+func (w *windowImpl) StartResize(edge screen.WindowEdge) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return startResize(w, edge)
+}
+
+func (w *windowImpl) ShowFileDialog(opts *screen.FileDialogOptions) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return showFileDialog(w, opts)
+}
+
+func (w *windowImpl) Notify(n *screen.Notification) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return postNotification(w, n)
+}
+
+func (w *windowImpl) CloseNotification(id int) {
+	closeNotification(w, id)
+}
+
+func (w *windowImpl) SetMenuBar(bar *screen.Menu) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return setMenuBar(w, bar)
+}
+
+func (w *windowImpl) ShowContextMenu(m *screen.Menu, p image.Point) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return showContextMenu(w, m, p)
+}
+
+func (w *windowImpl) SetAccessTree(root *screen.AccessNode) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return setAccessTree(w, root)
+}
+
+func (w *windowImpl) RegisterHotKey(k key.Code, mods key.Modifiers) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return registerHotKey(w, hotkey.Key{Code: k, Modifiers: mods})
+}
+
+func (w *windowImpl) UnregisterHotKey(k key.Code, mods key.Modifiers) {
+	unregisterHotKey(w, hotkey.Key{Code: k, Modifiers: mods})
+}
+
+func (w *windowImpl) Minimize() {
+	if !w.isReleased() {
+		minimize(w)
+	}
+}
+
+func (w *windowImpl) Maximize() {
+	if !w.isReleased() {
+		maximize(w)
+	}
+}
+
+func (w *windowImpl) Restore() {
+	if !w.isReleased() {
+		restore(w)
+	}
+}
+
+func (w *windowImpl) SetFullscreen(mode screen.FullscreenMode) {
+	if !w.isReleased() {
+		setFullscreen(w, mode)
+	}
+}
+
+func (w *windowImpl) SetAlwaysOnTop(onTop bool) {
+	if !w.isReleased() {
+		setAlwaysOnTop(w, onTop)
+	}
+}
+
+func (w *windowImpl) SetOpacity(opacity float32) {
+	if !w.isReleased() {
+		setOpacity(w, opacity)
+	}
+}
+
+func (w *windowImpl) Raise() {
+	if !w.isReleased() {
+		raise(w)
+	}
+}
+
+func (w *windowImpl) Lower() {
+	if !w.isReleased() {
+		lower(w)
+	}
+}
+
+func (w *windowImpl) ColorSpace() screen.ColorSpace {
+	w.glctxMu.Lock()
+	defer w.glctxMu.Unlock()
+	return w.colorSpace
+}
+
+func (w *windowImpl) SetColorSpace(cs screen.ColorSpace) error {
+	w.glctxMu.Lock()
+	defer w.glctxMu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	if err := setColorSpace(w, cs); err != nil {
+		return err
+	}
+	w.colorSpace = cs
+	return nil
+}
+
+func (w *windowImpl) isReleased() bool {
+	w.glctxMu.Lock()
+	defer w.glctxMu.Unlock()
+	return w.released
+}
+
 func (w *windowImpl) Publish() screen.PublishResult {
+	if !w.occlusion.Wait() {
+		return screen.PublishResult{Dropped: true}
+	}
+	w.layers.Composite(w)
+
 	// gl.Flush is a lightweight (on modern GL drivers) blocking call
 	// that ensures all GL functions pending in the gl package have
 	// been passed onto the GL driver before the app package attempts
@@ -373,17 +947,78 @@ func (w *windowImpl) Publish() screen.PublishResult {
 	//
 	// This enforces that the final receive (for this paint cycle) on
 	// gl.WorkAvailable happens before the send on publish.
+	//
+	// glctxMu is held until the buffers are swapped, so that a concurrent
+	// Release cannot close the window part way through.
 	w.glctxMu.Lock()
+	if w.released {
+		w.glctxMu.Unlock()
+		return screen.PublishResult{Dropped: true}
+	}
+	w.checkGLError()
+	var captured *image.RGBA
+	if w.captures.Active() {
+		captured = w.readFrame()
+	}
 	w.glctx.Flush()
-	w.glctxMu.Unlock()
+	traceFrame(w.glctx)
 
 	w.publish <- struct{}{}
 	res := <-w.publishDone
 
+	if w.depth {
+		w.clearDepth = true
+	}
+	if w.contextLost {
+		// The frame was drawn with the lost context, so it was not shown.
+		res.Dropped = true
+		w.contextLost = false
+		w.resetContext()
+	} else {
+		// The buffers are swapped with a swap interval of one, so the
+		// swap has waited for the display's refresh.
+		w.timing.Presented(&res, time.Now())
+	}
+	w.glctxMu.Unlock()
+
 	select {
 	case w.drawDone <- struct{}{}:
 	default:
 	}
 
+	w.captures.Send(captured)
 	return res
 }
+
+func (w *windowImpl) AddCaptureHook(fn func(f screen.CapturedFrame)) (remove func()) {
+	return w.captures.Add(fn)
+}
+
+// readFrame reads the back buffer back, for the window's capture hooks,
+// before Publish swaps it to the front. It must be called with glctxMu held.
+func (w *windowImpl) readFrame() *image.RGBA {
+	w.szMu.Lock()
+	sz := w.sz
+	w.szMu.Unlock()
+
+	if sz.WidthPx <= 0 || sz.HeightPx <= 0 {
+		return nil
+	}
+	if !w.backBufferBound {
+		w.bindBackBuffer()
+	}
+	m := image.NewRGBA(image.Rect(0, 0, sz.WidthPx, sz.HeightPx))
+	w.glctx.ReadPixels(m.Pix, 0, 0, sz.WidthPx, sz.HeightPx, gl.RGBA, gl.UNSIGNED_BYTE)
+
+	// The back buffer's row 0 is the bottom row in OpenGL's window
+	// coordinates, so the rows are flipped to put the top row first.
+	row := make([]byte, m.Stride)
+	for y0, y1 := 0, sz.HeightPx-1; y0 < y1; y0, y1 = y0+1, y1-1 {
+		p0 := m.Pix[y0*m.Stride : (y0+1)*m.Stride]
+		p1 := m.Pix[y1*m.Stride : (y1+1)*m.Stride]
+		copy(row, p0)
+		copy(p0, p1)
+		copy(p1, row)
+	}
+	return m
+}
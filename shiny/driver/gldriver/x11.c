@@ -6,17 +6,41 @@
 
 #include "_cgo_export.h"
 #include <EGL/egl.h>
+#include <EGL/eglext.h>
+#include <GLES2/gl2.h>
+#include <X11/XKBlib.h>
+#include <X11/Xatom.h>
+#include <X11/Xresource.h>
+#include <X11/Xutil.h>
+#include <X11/extensions/shape.h>
+#include <X11/extensions/Xrender.h>
+#include <locale.h>
 #include <stdio.h>
 #include <stdlib.h>
 #include <string.h>
 
+Atom motif_wm_hints;
+Atom net_frame_extents;
+Atom net_wm_bypass_compositor;
+Atom net_wm_icon;
+Atom net_wm_moveresize;
 Atom net_wm_name;
+Atom net_wm_state;
+Atom net_wm_state_above;
+Atom net_wm_state_fullscreen;
+Atom net_wm_state_hidden;
+Atom net_wm_state_maximized_horz;
+Atom net_wm_state_maximized_vert;
+Atom net_wm_state_modal;
+Atom net_wm_window_opacity;
 Atom utf8_string;
 Atom wm_delete_window;
 Atom wm_protocols;
 Atom wm_take_focus;
+Atom xkb_rules_names;
 
 EGLConfig e_config;
+// e_ctx is the share context. Every window's context shares its objects.
 EGLContext e_ctx;
 EGLDisplay e_dpy;
 Colormap x_colormap;
@@ -24,6 +48,42 @@ Display *x_dpy;
 XVisualInfo *x_visual_info;
 Window x_root;
 
+// x_im is the input method, or NULL if there is none. Each window has an
+// input context, found by XFindContext(x_dpy, win, x_ic_context, etc). The
+// input method draws the text being composed itself, next to the spot set by
+// doSetTextInputRect if its style is XIMPreeditPosition.
+XIM x_im;
+XIMStyle x_im_style;
+XContext x_ic_context;
+
+// A window created with NewWindowOptions.Samples greater than one has its own,
+// multisampled, config, found by findConfig, and its own colormap, for that
+// config's visual. Other windows use e_config, x_visual_info and x_colormap.
+XContext x_config_context;
+
+void openIM();
+void readKeyboardMapping();
+void readRulesNames();
+
+// captured_win is the window, if any, that has grabbed the pointer for
+// doSetPointerCapture, which holds the pointer at (capture_x, capture_y).
+Window captured_win;
+int capture_x, capture_y;
+
+// config_attribs are the attributes of e_config. Those of a multisampled
+// config add EGL_SAMPLE_BUFFERS and EGL_SAMPLES.
+static const EGLint config_attribs[] = {
+	EGL_RENDERABLE_TYPE, EGL_OPENGL_ES2_BIT,
+	EGL_SURFACE_TYPE, EGL_WINDOW_BIT | EGL_PBUFFER_BIT,
+	EGL_BLUE_SIZE, 8,
+	EGL_GREEN_SIZE, 8,
+	EGL_RED_SIZE, 8,
+	EGL_DEPTH_SIZE, 16,
+	EGL_STENCIL_SIZE, 8,
+	EGL_CONFIG_CAVEAT, EGL_NONE,
+	EGL_NONE
+};
+
 // TODO: share code with eglErrString
 char *
 eglGetErrorStr() {
@@ -64,6 +124,12 @@ eglGetErrorStr() {
 
 void
 startDriver() {
+	// Each window's GL context is current on its own thread, and EGL makes
+	// Xlib calls, such as for eglSwapBuffers, from those threads.
+	if (!XInitThreads()) {
+		fprintf(stderr, "XInitThreads failed\n");
+		exit(1);
+	}
 	x_dpy = XOpenDisplay(NULL);
 	if (!x_dpy) {
 		fprintf(stderr, "XOpenDisplay failed\n");
@@ -84,18 +150,10 @@ startDriver() {
 		exit(1);
 	}
 
-	static const EGLint attribs[] = {
-		EGL_RENDERABLE_TYPE, EGL_OPENGL_ES2_BIT,
-		EGL_SURFACE_TYPE, EGL_WINDOW_BIT,
-		EGL_BLUE_SIZE, 8,
-		EGL_GREEN_SIZE, 8,
-		EGL_RED_SIZE, 8,
-		EGL_DEPTH_SIZE, 16,
-		EGL_CONFIG_CAVEAT, EGL_NONE,
-		EGL_NONE
-	};
+	// The share context has a pbuffer surface, and windows' contexts have
+	// window surfaces.
 	EGLint num_configs;
-	if (!eglChooseConfig(e_dpy, attribs, &e_config, 1, &num_configs)) {
+	if (!eglChooseConfig(e_dpy, config_attribs, &e_config, 1, &num_configs)) {
 		fprintf(stderr, "eglChooseConfig failed: %s\n", eglGetErrorStr());
 		exit(1);
 	}
@@ -131,12 +189,41 @@ startDriver() {
 		exit(1);
 	}
 
+	motif_wm_hints = XInternAtom(x_dpy, "_MOTIF_WM_HINTS", False);
+	net_frame_extents = XInternAtom(x_dpy, "_NET_FRAME_EXTENTS", False);
+	net_wm_bypass_compositor = XInternAtom(x_dpy, "_NET_WM_BYPASS_COMPOSITOR", False);
+	net_wm_icon = XInternAtom(x_dpy, "_NET_WM_ICON", False);
+	net_wm_moveresize = XInternAtom(x_dpy, "_NET_WM_MOVERESIZE", False);
 	net_wm_name = XInternAtom(x_dpy, "_NET_WM_NAME", False);
+	net_wm_state = XInternAtom(x_dpy, "_NET_WM_STATE", False);
+	net_wm_state_above = XInternAtom(x_dpy, "_NET_WM_STATE_ABOVE", False);
+	net_wm_state_fullscreen = XInternAtom(x_dpy, "_NET_WM_STATE_FULLSCREEN", False);
+	net_wm_state_hidden = XInternAtom(x_dpy, "_NET_WM_STATE_HIDDEN", False);
+	net_wm_state_maximized_horz = XInternAtom(x_dpy, "_NET_WM_STATE_MAXIMIZED_HORZ", False);
+	net_wm_state_maximized_vert = XInternAtom(x_dpy, "_NET_WM_STATE_MAXIMIZED_VERT", False);
+	net_wm_state_modal = XInternAtom(x_dpy, "_NET_WM_STATE_MODAL", False);
+	net_wm_window_opacity = XInternAtom(x_dpy, "_NET_WM_WINDOW_OPACITY", False);
 	utf8_string = XInternAtom(x_dpy, "UTF8_STRING", False);
 	wm_delete_window = XInternAtom(x_dpy, "WM_DELETE_WINDOW", False);
 	wm_protocols = XInternAtom(x_dpy, "WM_PROTOCOLS", False);
 	wm_take_focus = XInternAtom(x_dpy, "WM_TAKE_FOCUS", False);
+	xkb_rules_names = XInternAtom(x_dpy, "_XKB_RULES_NAMES", False);
+	x_config_context = XUniqueContext();
 
+	openIM();
+
+	// With detectable auto-repeat, a held key repeats as presses without
+	// the releases in between.
+	XkbSetDetectableAutoRepeat(x_dpy, True, NULL);
+	XSelectInput(x_dpy, x_root, PropertyChangeMask);
+	readKeyboardMapping();
+	readRulesNames();
+}
+
+// readKeyboardMapping sends the unshifted and shifted keysyms of the first
+// two groups of each keycode to onKeysym.
+void
+readKeyboardMapping() {
 	const int key_lo = 8;
 	const int key_hi = 255;
 	int keysyms_per_keycode;
@@ -147,10 +234,204 @@ startDriver() {
 	}
 	int k;
 	for (k = key_lo; k <= key_hi; k++) {
-		onKeysym(k,
-			keysyms[(k-key_lo)*keysyms_per_keycode + 0],
-			keysyms[(k-key_lo)*keysyms_per_keycode + 1]);
+		KeySym *syms = &keysyms[(k-key_lo)*keysyms_per_keycode];
+		onKeysym(k, syms[0], syms[1],
+			keysyms_per_keycode >= 4 ? syms[2] : 0,
+			keysyms_per_keycode >= 4 ? syms[3] : 0);
+	}
+	XFree(keysyms);
+}
+
+// readRulesNames sends the root window's _XKB_RULES_NAMES property, which
+// holds consecutive NUL-terminated strings, to onRulesNames.
+void
+readRulesNames() {
+	Atom type;
+	int format;
+	unsigned long n, remaining;
+	unsigned char *data = NULL;
+	if (XGetWindowProperty(x_dpy, x_root, xkb_rules_names, 0, 1024, False, XA_STRING,
+		&type, &format, &n, &remaining, &data) != Success || !data) {
+		onRulesNames(NULL, 0);
+		return;
 	}
+	onRulesNames((char *)data, format == 8 ? n : 0);
+	XFree(data);
+}
+
+void
+openIM() {
+	x_ic_context = XUniqueContext();
+	// Input methods are chosen by the XMODIFIERS environment variable, and
+	// need the locale's character set.
+	if (!setlocale(LC_CTYPE, "") || !XSupportsLocale() || !XSetLocaleModifiers("")) {
+		return;
+	}
+	x_im = XOpenIM(x_dpy, NULL, NULL, NULL);
+	if (!x_im) {
+		return;
+	}
+	XIMStyles *styles = NULL;
+	if (XGetIMValues(x_im, XNQueryInputStyle, &styles, NULL) || !styles) {
+		XCloseIM(x_im);
+		x_im = NULL;
+		return;
+	}
+	// Prefer over-the-spot composition, which can follow the text cursor.
+	x_im_style = 0;
+	int i;
+	for (i = 0; i < styles->count_styles; i++) {
+		XIMStyle s = styles->supported_styles[i];
+		if (s == (XIMPreeditPosition | XIMStatusNothing)) {
+			x_im_style = s;
+			break;
+		}
+		if (s == (XIMPreeditNothing | XIMStatusNothing)) {
+			x_im_style = s;
+		}
+	}
+	XFree(styles);
+	if (!x_im_style) {
+		XCloseIM(x_im);
+		x_im = NULL;
+	}
+}
+
+// findConfig returns the EGL config of the window's surface and context.
+EGLConfig
+findConfig(Window win) {
+	XPointer config;
+	if (XFindContext(x_dpy, win, x_config_context, &config)) {
+		return e_config;
+	}
+	return (EGLConfig)(config);
+}
+
+// chooseMultisampleConfig returns a config like e_config, but with at least
+// samples samples per pixel, and its visual, or NULL if there is none.
+static EGLConfig
+chooseMultisampleConfig(int samples, XVisualInfo **vi) {
+	EGLint attribs[sizeof(config_attribs)/sizeof(config_attribs[0]) + 4];
+	int n = sizeof(config_attribs)/sizeof(config_attribs[0]) - 1;
+	memcpy(attribs, config_attribs, n * sizeof(EGLint));
+	attribs[n++] = EGL_SAMPLE_BUFFERS;
+	attribs[n++] = 1;
+	attribs[n++] = EGL_SAMPLES;
+	attribs[n++] = samples;
+	attribs[n++] = EGL_NONE;
+
+	EGLConfig config;
+	EGLint num_configs, vid;
+	if (!eglChooseConfig(e_dpy, attribs, &config, 1, &num_configs) || num_configs < 1 ||
+		!eglGetConfigAttrib(e_dpy, config, EGL_NATIVE_VISUAL_ID, &vid)) {
+		return NULL;
+	}
+	XVisualInfo visTemplate;
+	visTemplate.visualid = vid;
+	int num_visuals;
+	XVisualInfo *v = XGetVisualInfo(x_dpy, VisualIDMask, &visTemplate, &num_visuals);
+	if (!v) {
+		return NULL;
+	}
+	*vi = v;
+	return config;
+}
+
+XIC
+findIC(Window win) {
+	XPointer ic;
+	if (XFindContext(x_dpy, win, x_ic_context, &ic)) {
+		return NULL;
+	}
+	return (XIC)(ic);
+}
+
+void
+createIC(Window win, long event_mask) {
+	if (!x_im) {
+		return;
+	}
+	XIC ic = XCreateIC(x_im, XNInputStyle, x_im_style, XNClientWindow, win, XNFocusWindow, win, NULL);
+	if (!ic && x_im_style != (XIMPreeditNothing | XIMStatusNothing)) {
+		// Over-the-spot composition can need a font set, which we do not
+		// provide. Fall back to letting the input method place itself.
+		ic = XCreateIC(x_im, XNInputStyle, XIMPreeditNothing | XIMStatusNothing,
+			XNClientWindow, win, XNFocusWindow, win, NULL);
+	}
+	if (!ic) {
+		return;
+	}
+	XSaveContext(x_dpy, win, x_ic_context, (XPointer)(ic));
+
+	// The input method may need to see events that are not otherwise selected.
+	long filter_events = 0;
+	XGetICValues(ic, XNFilterEvents, &filter_events, NULL);
+	XSelectInput(x_dpy, win, event_mask | filter_events);
+}
+
+// onKeyPress sends the text, if any, committed by the input method, or else
+// the key event. Input methods commit text by sending key presses with a zero
+// keycode.
+void
+onKeyPress(XKeyEvent *ev) {
+	XIC ic = findIC(ev->window);
+	if (!ic || ev->keycode != 0) {
+		onKey(ev->window, ev->state, ev->keycode, 1, ev->time);
+		return;
+	}
+	char buf[256];
+	char *text = buf;
+	KeySym keysym;
+	Status status;
+	int n = Xutf8LookupString(ic, ev, buf, sizeof(buf), &keysym, &status);
+	if (status == XBufferOverflow) {
+		text = malloc(n);
+		if (!text) {
+			return;
+		}
+		n = Xutf8LookupString(ic, ev, text, n, &keysym, &status);
+	}
+	if ((status == XLookupChars || status == XLookupBoth) && n > 0) {
+		onText(ev->window, text, n, ev->time);
+	}
+	if (text != buf) {
+		free(text);
+	}
+}
+
+// windowState returns the window's state, as the window manager holds it in
+// the EWMH _NET_WM_STATE property, numbered as for a screen.WindowState: 0 is
+// normal, 1 minimized, 2 maximized and 3 fullscreen.
+int
+windowState(Window win) {
+	int hidden = 0, fullscreen = 0, max_horz = 0, max_vert = 0;
+	Atom type;
+	int format;
+	unsigned long n, remaining;
+	unsigned char *data = NULL;
+	if (XGetWindowProperty(x_dpy, win, net_wm_state, 0, 64, False, XA_ATOM,
+		&type, &format, &n, &remaining, &data) == Success && data) {
+		if (format == 32) {
+			Atom *atoms = (Atom *)data;
+			for (unsigned long i = 0; i < n; i++) {
+				hidden |= atoms[i] == net_wm_state_hidden;
+				fullscreen |= atoms[i] == net_wm_state_fullscreen;
+				max_horz |= atoms[i] == net_wm_state_maximized_horz;
+				max_vert |= atoms[i] == net_wm_state_maximized_vert;
+			}
+		}
+		XFree(data);
+	}
+	if (hidden) {
+		return 1;
+	}
+	if (fullscreen) {
+		return 3;
+	}
+	if (max_horz && max_vert) {
+		return 2;
+	}
+	return 0;
 }
 
 void
@@ -158,21 +439,58 @@ processEvents() {
 	while (XPending(x_dpy)) {
 		XEvent ev;
 		XNextEvent(x_dpy, &ev);
+		if (XFilterEvent(&ev, None)) {
+			// The input method used the event.
+			continue;
+		}
 		switch (ev.type) {
 		case KeyPress:
+			onKeyPress(&ev.xkey);
+			break;
 		case KeyRelease:
-			onKey(ev.xkey.window, ev.xkey.state, ev.xkey.keycode, ev.type == KeyPress ? 1 : 2);
+			onKey(ev.xkey.window, ev.xkey.state, ev.xkey.keycode, 2, ev.xkey.time);
 			break;
 		case ButtonPress:
 		case ButtonRelease:
+			// TODO: send touches and pens, from XInput2's touch and tablet
+			// device events. Selecting them stops the X server from sending
+			// the core pointer events they emulate, so all of the pointer
+			// events would have to come from XInput2.
 			onMouse(ev.xbutton.window, ev.xbutton.x, ev.xbutton.y, ev.xbutton.state, ev.xbutton.button,
-				ev.type == ButtonPress ? 1 : 2);
+				ev.type == ButtonPress ? 1 : 2, ev.xbutton.time);
 			break;
 		case MotionNotify:
-			onMouse(ev.xmotion.window, ev.xmotion.x, ev.xmotion.y, ev.xmotion.state, 0, 0);
+			if (ev.xmotion.window == captured_win && captured_win) {
+				// Warping the pointer back also generates a motion event,
+				// with no relative motion.
+				int dx = ev.xmotion.x - capture_x, dy = ev.xmotion.y - capture_y;
+				if (dx || dy) {
+					onRelativeMouse(ev.xmotion.window, dx, dy, ev.xmotion.time);
+					XWarpPointer(x_dpy, None, captured_win, 0, 0, 0, 0, capture_x, capture_y);
+				}
+				break;
+			}
+			onMouse(ev.xmotion.window, ev.xmotion.x, ev.xmotion.y, ev.xmotion.state, 0, 0, ev.xmotion.time);
+			break;
+		case EnterNotify:
+		case LeaveNotify:
+			// Crossings due to grabs, and into or out of child windows, do
+			// not move the pointer into or out of the window.
+			if (ev.xcrossing.mode != NotifyNormal || ev.xcrossing.detail == NotifyInferior) {
+				break;
+			}
+			onCrossing(ev.xcrossing.window, ev.type == EnterNotify, ev.xcrossing.x, ev.xcrossing.y, ev.xcrossing.time);
 			break;
 		case FocusIn:
 		case FocusOut:
+			{
+				XIC ic = findIC(ev.xfocus.window);
+				if (ic && ev.type == FocusIn) {
+					XSetICFocus(ic);
+				} else if (ic) {
+					XUnsetICFocus(ic);
+				}
+			}
 			onFocus(ev.xmotion.window, ev.type == FocusIn);
 			break;
 		case Expose:
@@ -185,12 +503,29 @@ processEvents() {
 				onExpose(ev.xexpose.window);
 			}
 			break;
+		case VisibilityNotify:
+			onVisibility(ev.xvisibility.window, ev.xvisibility.state);
+			break;
 		case ConfigureNotify:
 			onConfigure(ev.xconfigure.window, ev.xconfigure.x, ev.xconfigure.y,
 				ev.xconfigure.width, ev.xconfigure.height,
 				DisplayWidth(x_dpy, DefaultScreen(x_dpy)),
 				DisplayWidthMM(x_dpy, DefaultScreen(x_dpy)));
 			break;
+		case PropertyNotify:
+			if (ev.xproperty.window == x_root && ev.xproperty.atom == xkb_rules_names) {
+				readRulesNames();
+			} else if (ev.xproperty.atom == net_wm_state) {
+				onWindowState(ev.xproperty.window, windowState(ev.xproperty.window));
+			}
+			break;
+		case MappingNotify:
+			XRefreshKeyboardMapping(&ev.xmapping);
+			if (ev.xmapping.request == MappingKeyboard) {
+				readKeyboardMapping();
+				readRulesNames();
+			}
+			break;
 		case ClientMessage:
 			if ((ev.xclient.message_type != wm_protocols) || (ev.xclient.format != 32)) {
 				break;
@@ -207,53 +542,218 @@ processEvents() {
 }
 
 void
-makeCurrent(uintptr_t surface) {
+makeCurrent(uintptr_t surface, uintptr_t context) {
 	EGLSurface surf = (EGLSurface)(surface);
-	if (!eglMakeCurrent(e_dpy, surf, surf, e_ctx)) {
+	EGLContext ctx = (EGLContext)(context);
+	if (!eglMakeCurrent(e_dpy, surf, surf, ctx)) {
 		fprintf(stderr, "eglMakeCurrent failed: %s\n", eglGetErrorStr());
 		exit(1);
 	}
 }
 
-void
+// swapBuffers returns 1 if the context current on surface was lost, and 0
+// otherwise.
+int
 swapBuffers(uintptr_t surface) {
 	EGLSurface surf = (EGLSurface)(surface);
 	if (!eglSwapBuffers(e_dpy, surf)) {
+		if (eglGetError() == EGL_CONTEXT_LOST) {
+			return 1;
+		}
 		fprintf(stderr, "eglSwapBuffers failed: %s\n", eglGetErrorStr());
 		exit(1);
 	}
+	return 0;
+}
+
+typedef void (*objectLabelFunc)(GLenum identifier, GLuint name, GLsizei length, const GLchar *label);
+
+// objectLabel labels a GL object of the current context, with the KHR_debug
+// extension's glObjectLabelKHR. It returns 0, doing nothing, if the context
+// lacks that extension.
+int
+objectLabel(unsigned int identifier, unsigned int name, char *label, int label_len) {
+	const char *exts = (const char *)glGetString(GL_EXTENSIONS);
+	if (exts == NULL || strstr(exts, "GL_KHR_debug") == NULL) {
+		return 0;
+	}
+	// eglGetProcAddress may return a non-NULL address for functions that
+	// do not exist, so it is only called once the extension is known.
+	objectLabelFunc f = (objectLabelFunc)eglGetProcAddress("glObjectLabelKHR");
+	if (f == NULL) {
+		return 0;
+	}
+	f(identifier, name, label_len, label);
+	return 1;
+}
+
+// The OpenGL ES 3 fence sync functions and constants, which GLES2/gl2.h lacks.
+typedef void *(*fenceSyncFunc)(GLenum condition, GLbitfield flags);
+typedef GLenum (*clientWaitSyncFunc)(void *sync, GLbitfield flags, khronos_uint64_t timeout);
+typedef void (*deleteSyncFunc)(void *sync);
+
+#define GL_SYNC_FLUSH_COMMANDS_BIT 0x0001
+#define GL_SYNC_GPU_COMMANDS_COMPLETE 0x9117
+#define GL_TIMEOUT_EXPIRED 0x911B
+
+static fenceSyncFunc fenceSyncF;
+static clientWaitSyncFunc clientWaitSyncF;
+static deleteSyncFunc deleteSyncF;
+
+// pboSupported looks up the fence sync functions of the current context,
+// which must be an OpenGL ES 3 context. It returns 0 if it lacks them.
+int
+pboSupported() {
+	fenceSyncF = (fenceSyncFunc)eglGetProcAddress("glFenceSync");
+	clientWaitSyncF = (clientWaitSyncFunc)eglGetProcAddress("glClientWaitSync");
+	deleteSyncF = (deleteSyncFunc)eglGetProcAddress("glDeleteSync");
+	return fenceSyncF != NULL && clientWaitSyncF != NULL && deleteSyncF != NULL;
+}
+
+// texSubImageUnpack re-specifies a sub-rectangle of the bound texture as the
+// RGBA pixels at the start of the bound pixel unpack buffer.
+void
+texSubImageUnpack(int x, int y, int width, int height) {
+	glTexSubImage2D(GL_TEXTURE_2D, 0, x, y, width, height, GL_RGBA, GL_UNSIGNED_BYTE, NULL);
+}
+
+uintptr_t
+fenceSync() {
+	return (uintptr_t)(fenceSyncF(GL_SYNC_GPU_COMMANDS_COMPLETE, 0));
+}
+
+// syncSignaled returns whether sync is signaled, without waiting. A failed
+// wait counts as signaled, so that it is not waited for forever.
+int
+syncSignaled(uintptr_t sync, int flush) {
+	return clientWaitSyncF((void *)(sync), flush ? GL_SYNC_FLUSH_COMMANDS_BIT : 0, 0) != GL_TIMEOUT_EXPIRED;
+}
+
+void
+deleteSync(uintptr_t sync) {
+	deleteSyncF((void *)(sync));
+}
+
+// recreateContext replaces a window's lost context, destroying it, with one
+// that has the given config and shares the objects of the current share
+// context, and makes the replacement current on surface. It returns 0 on
+// failure.
+uintptr_t
+recreateContext(uintptr_t surface, uintptr_t context, uintptr_t config) {
+	EGLSurface surf = (EGLSurface)(surface);
+	eglMakeCurrent(e_dpy, EGL_NO_SURFACE, EGL_NO_SURFACE, EGL_NO_CONTEXT);
+	eglDestroyContext(e_dpy, (EGLContext)(context));
+	static const EGLint ctx_attribs[] = {
+		EGL_CONTEXT_CLIENT_VERSION, 3,
+		EGL_NONE
+	};
+	EGLContext ctx = eglCreateContext(e_dpy, (EGLConfig)(config), e_ctx, ctx_attribs);
+	if (!ctx) {
+		fprintf(stderr, "gldriver: eglCreateContext failed: %s\n", eglGetErrorStr());
+		return 0;
+	}
+	if (!eglMakeCurrent(e_dpy, surf, surf, ctx)) {
+		fprintf(stderr, "gldriver: eglMakeCurrent failed: %s\n", eglGetErrorStr());
+		return 0;
+	}
+	return (uintptr_t)(ctx);
 }
 
 void
 doCloseWindow(uintptr_t id) {
 	Window win = (Window)(id);
+	XIC ic = findIC(win);
+	if (ic) {
+		XDeleteContext(x_dpy, win, x_ic_context);
+		XDestroyIC(ic);
+	}
+	if (win == captured_win) {
+		// Destroying the window also ends its grab.
+		captured_win = 0;
+	}
+	Colormap colormap = None;
+	if (findConfig(win) != e_config) {
+		XWindowAttributes attr;
+		if (XGetWindowAttributes(x_dpy, win, &attr)) {
+			colormap = attr.colormap;
+		}
+		XDeleteContext(x_dpy, win, x_config_context);
+	}
 	XDestroyWindow(x_dpy, win);
+	if (colormap != None) {
+		XFreeColormap(x_dpy, colormap);
+	}
 }
 
 uintptr_t
-doNewWindow(int width, int height, char* title, int title_len) {
+doNewWindow(int width, int height, int x, int y, int has_position, int fixed_size, int undecorated, int samples, uintptr_t owner, int modal, char* title, int title_len) {
+	XVisualInfo *vi = x_visual_info;
+	EGLConfig config = NULL;
+	if (samples > 1) {
+		config = chooseMultisampleConfig(samples, &vi);
+	}
+
 	XSetWindowAttributes attr;
-	attr.colormap = x_colormap;
+	attr.colormap = config ? XCreateColormap(x_dpy, x_root, vi->visual, AllocNone) : x_colormap;
 	attr.event_mask =
 		KeyPressMask |
 		KeyReleaseMask |
 		ButtonPressMask |
 		ButtonReleaseMask |
 		PointerMotionMask |
+		EnterWindowMask |
+		LeaveWindowMask |
 		ExposureMask |
 		StructureNotifyMask |
-		FocusChangeMask;
+		FocusChangeMask |
+		PropertyChangeMask |
+		VisibilityChangeMask;
 
 	Window win = XCreateWindow(
-		x_dpy, x_root, 0, 0, width, height, 0, x_visual_info->depth, InputOutput,
-		x_visual_info->visual, CWColormap | CWEventMask, &attr);
+		x_dpy, x_root, x, y, width, height, 0, vi->depth, InputOutput,
+		vi->visual, CWColormap | CWEventMask, &attr);
+	createIC(win, attr.event_mask);
+	if (config) {
+		XSaveContext(x_dpy, win, x_config_context, (XPointer)(config));
+		XFree(vi);
+	}
 
 	XSizeHints sizehints;
 	sizehints.width = width;
 	sizehints.height = height;
 	sizehints.flags = USSize;
+	if (has_position) {
+		sizehints.x = x;
+		sizehints.y = y;
+		sizehints.flags |= USPosition;
+	}
+	if (fixed_size) {
+		sizehints.min_width = sizehints.max_width = width;
+		sizehints.min_height = sizehints.max_height = height;
+		sizehints.flags |= PMinSize | PMaxSize;
+	}
 	XSetNormalHints(x_dpy, win, &sizehints);
 
+	if (undecorated) {
+		// The _MOTIF_WM_HINTS property's flags, functions, decorations,
+		// input_mode and status. A flags of 2 means that only decorations,
+		// of which there are none, is meaningful.
+		long hints[5] = {2, 0, 0, 0, 0};
+		XChangeProperty(x_dpy, win, motif_wm_hints, motif_wm_hints, 32, PropModeReplace,
+			(unsigned char *)hints, 5);
+	}
+
+	if (owner) {
+		// A transient window is kept above, and minimized with, its owner.
+		// Before the window is mapped, its _NET_WM_STATE can be set
+		// directly, instead of by asking the window manager.
+		XSetTransientForHint(x_dpy, win, (Window)(owner));
+		if (modal) {
+			XChangeProperty(x_dpy, win, net_wm_state, XA_ATOM, 32, PropModeReplace,
+				(unsigned char *)&net_wm_state_modal, 1);
+		}
+	}
+
 	Atom atoms[2];
 	atoms[0] = wm_delete_window;
 	atoms[1] = wm_take_focus;
@@ -265,18 +765,544 @@ doNewWindow(int width, int height, char* title, int title_len) {
 	return win;
 }
 
+static short
+clampShort(int x) {
+	return x < -0x8000 ? -0x8000 : x > 0x7fff ? 0x7fff : x;
+}
+
+// doSetShape sets the window's bounding shape to the union of n rectangles,
+// each of which is four elements of rects: x0, y0, x1 and y1. It does
+// nothing if the X server lacks the X Nonrectangular Window Shape extension.
+void
+doSetShape(uintptr_t id, int *rects, int n) {
+	Window win = (Window)(id);
+	int event_base, error_base;
+	if (!XShapeQueryExtension(x_dpy, &event_base, &error_base)) {
+		return;
+	}
+	XRectangle *xrects = malloc(n * sizeof(XRectangle));
+	int i, m = 0;
+	for (i = 0; i < n; i++) {
+		int *r = rects + 4*i;
+		short x0 = clampShort(r[0]), y0 = clampShort(r[1]);
+		short x1 = clampShort(r[2]), y1 = clampShort(r[3]);
+		if (x0 >= x1 || y0 >= y1) {
+			continue;
+		}
+		xrects[m].x = x0;
+		xrects[m].y = y0;
+		xrects[m].width = x1 - x0;
+		xrects[m].height = y1 - y0;
+		m++;
+	}
+	XShapeCombineRectangles(x_dpy, win, ShapeBounding, 0, 0, xrects, m, ShapeSet, Unsorted);
+	free(xrects);
+}
+
+int
+srgbSurfaces() {
+	const char *exts = eglQueryString(e_dpy, EGL_EXTENSIONS);
+	if (!exts) {
+		return 0;
+	}
+	size_t n = strlen("EGL_KHR_gl_colorspace");
+	for (const char *p = exts; (p = strstr(p, "EGL_KHR_gl_colorspace")) != NULL; p += n) {
+		if ((p == exts || p[-1] == ' ') && (p[n] == ' ' || p[n] == 0)) {
+			return 1;
+		}
+	}
+	return 0;
+}
+
 uintptr_t
-doShowWindow(uintptr_t id) {
+doShowWindow(uintptr_t id, int hidden, int srgb, uintptr_t *context, uintptr_t *config) {
 	Window win = (Window)(id);
-	XMapWindow(x_dpy, win);
-	EGLSurface surf = eglCreateWindowSurface(e_dpy, e_config, win, NULL);
+	if (!hidden) {
+		XMapWindow(x_dpy, win);
+	}
+	static const EGLint srgb_attribs[] = {
+		EGL_GL_COLORSPACE_KHR, EGL_GL_COLORSPACE_SRGB_KHR,
+		EGL_NONE
+	};
+	EGLConfig cfg = findConfig(win);
+	EGLSurface surf = eglCreateWindowSurface(e_dpy, cfg, win, srgb ? srgb_attribs : NULL);
 	if (!surf) {
 		fprintf(stderr, "eglCreateWindowSurface failed: %s\n", eglGetErrorStr());
 		exit(1);
 	}
+	static const EGLint ctx_attribs[] = {
+		EGL_CONTEXT_CLIENT_VERSION, 3,
+		EGL_NONE
+	};
+	EGLContext ctx = eglCreateContext(e_dpy, cfg, e_ctx, ctx_attribs);
+	if (!ctx) {
+		fprintf(stderr, "eglCreateContext failed: %s\n", eglGetErrorStr());
+		exit(1);
+	}
+	*context = (uintptr_t)(ctx);
+	*config = (uintptr_t)(cfg);
 	return (uintptr_t)(surf);
 }
 
+void
+doSetTitle(uintptr_t id, char* title, int title_len) {
+	Window win = (Window)(id);
+	XChangeProperty(x_dpy, win, net_wm_name, utf8_string, 8, PropModeReplace, title, title_len);
+}
+
+// doSetIcon sets the window's _NET_WM_ICON property to the n values of data,
+// or deletes it, if n is zero. Xlib sends format 32 properties from an array
+// of longs, whatever their size.
+void
+doSetIcon(uintptr_t id, unsigned long* data, int n) {
+	Window win = (Window)(id);
+	if (n == 0) {
+		XDeleteProperty(x_dpy, win, net_wm_icon);
+		return;
+	}
+	XChangeProperty(x_dpy, win, net_wm_icon, XA_CARDINAL, 32, PropModeReplace, (unsigned char*)data, n);
+}
+
+void
+doSetSize(uintptr_t id, int width, int height, int fixed_size) {
+	Window win = (Window)(id);
+	// A fixed size window's minimum and maximum size hints must change too,
+	// or the window manager may refuse the new size.
+	XSizeHints sizehints;
+	long supplied;
+	if (XGetWMNormalHints(x_dpy, win, &sizehints, &supplied)) {
+		if (fixed_size) {
+			sizehints.min_width = sizehints.max_width = width;
+			sizehints.min_height = sizehints.max_height = height;
+		}
+		sizehints.width = width;
+		sizehints.height = height;
+		XSetWMNormalHints(x_dpy, win, &sizehints);
+	}
+	XResizeWindow(x_dpy, win, width, height);
+}
+
+// doSetSizeConstraints sets the window's minimum and maximum size hints, each
+// of which is removed if both its width and height are zero. Otherwise, a
+// zero minimum width or height is 1, and a zero maximum is the largest that
+// X11 allows.
+void
+doSetSizeConstraints(uintptr_t id, int min_width, int min_height, int max_width, int max_height) {
+	Window win = (Window)(id);
+	XSizeHints sizehints;
+	long supplied;
+	if (!XGetWMNormalHints(x_dpy, win, &sizehints, &supplied)) {
+		return;
+	}
+	sizehints.flags &= ~(PMinSize | PMaxSize);
+	if (min_width > 0 || min_height > 0) {
+		sizehints.min_width = min_width > 0 ? min_width : 1;
+		sizehints.min_height = min_height > 0 ? min_height : 1;
+		sizehints.flags |= PMinSize;
+	}
+	if (max_width > 0 || max_height > 0) {
+		sizehints.max_width = max_width > 0 ? max_width : 0x7fffffff;
+		sizehints.max_height = max_height > 0 ? max_height : 0x7fffffff;
+		sizehints.flags |= PMaxSize;
+	}
+	XSetWMNormalHints(x_dpy, win, &sizehints);
+}
+
+// doSetAspectRatio sets the window's minimum and maximum aspect ratio hints
+// to x:y, or removes them if x or y is zero.
+void
+doSetAspectRatio(uintptr_t id, int x, int y) {
+	Window win = (Window)(id);
+	XSizeHints sizehints;
+	long supplied;
+	if (!XGetWMNormalHints(x_dpy, win, &sizehints, &supplied)) {
+		return;
+	}
+	sizehints.flags &= ~PAspect;
+	if (x > 0 && y > 0) {
+		sizehints.min_aspect.x = sizehints.max_aspect.x = x;
+		sizehints.min_aspect.y = sizehints.max_aspect.y = y;
+		sizehints.flags |= PAspect;
+	}
+	XSetWMNormalHints(x_dpy, win, &sizehints);
+}
+
+void
+doSetPosition(uintptr_t id, int x, int y) {
+	Window win = (Window)(id);
+	// With the default NorthWest window gravity, the window manager places
+	// the top-left corner of the window's frame at the requested position.
+	XMoveWindow(x_dpy, win, x, y);
+}
+
+// sendWMState asks the window manager to add or remove, depending on add, the
+// atoms a and b, either of which may be None, to or from the window's
+// _NET_WM_STATE.
+void
+sendWMState(Window win, int add, Atom a, Atom b) {
+	XEvent ev;
+	memset(&ev, 0, sizeof(ev));
+	ev.xclient.type = ClientMessage;
+	ev.xclient.window = win;
+	ev.xclient.message_type = net_wm_state;
+	ev.xclient.format = 32;
+	ev.xclient.data.l[0] = add ? 1 : 0;
+	ev.xclient.data.l[1] = a;
+	ev.xclient.data.l[2] = b;
+	ev.xclient.data.l[3] = 1; // The source indication, 1 for a normal application.
+	XSendEvent(x_dpy, x_root, False, SubstructureNotifyMask | SubstructureRedirectMask, &ev);
+}
+
+void
+doMinimize(uintptr_t id) {
+	XIconifyWindow(x_dpy, (Window)(id), DefaultScreen(x_dpy));
+}
+
+void
+doMaximize(uintptr_t id) {
+	Window win = (Window)(id);
+	sendWMState(win, 0, net_wm_state_fullscreen, None);
+	sendWMState(win, 1, net_wm_state_maximized_horz, net_wm_state_maximized_vert);
+	// As for doRestore, mapping the window un-minimizes it.
+	XMapWindow(x_dpy, win);
+}
+
+void
+doRestore(uintptr_t id) {
+	Window win = (Window)(id);
+	sendWMState(win, 0, net_wm_state_fullscreen, None);
+	sendWMState(win, 0, net_wm_state_maximized_horz, net_wm_state_maximized_vert);
+	// Mapping a minimized window restores it, as ICCCM section 4.1.4 says.
+	XMapWindow(x_dpy, win);
+}
+
+void
+doSetFullscreen(uintptr_t id, int fullscreen, int exclusive) {
+	Window win = (Window)(id);
+	// The _NET_WM_BYPASS_COMPOSITOR property asks a compositing window
+	// manager to stop compositing the window while it is fullscreen.
+	if (exclusive) {
+		long disable = 1;
+		XChangeProperty(x_dpy, win, net_wm_bypass_compositor, XA_CARDINAL, 32, PropModeReplace,
+			(unsigned char *)&disable, 1);
+	} else {
+		XDeleteProperty(x_dpy, win, net_wm_bypass_compositor);
+	}
+	sendWMState(win, fullscreen, net_wm_state_fullscreen, None);
+}
+
+void
+doSetAlwaysOnTop(uintptr_t id, int on_top) {
+	sendWMState((Window)(id), on_top, net_wm_state_above, None);
+}
+
+// doSetOpacity sets the window's _NET_WM_WINDOW_OPACITY property, where
+// 0xffffffff is opaque, or deletes it if opaque is true.
+void
+doSetOpacity(uintptr_t id, int opaque, unsigned long opacity) {
+	Window win = (Window)(id);
+	if (opaque) {
+		XDeleteProperty(x_dpy, win, net_wm_window_opacity);
+		return;
+	}
+	// Format 32 properties are passed to Xlib as longs.
+	long v = (long)(opacity);
+	XChangeProperty(x_dpy, win, net_wm_window_opacity, XA_CARDINAL, 32, PropModeReplace,
+		(unsigned char *)&v, 1);
+}
+
+void
+doRaise(uintptr_t id) {
+	XRaiseWindow(x_dpy, (Window)(id));
+}
+
+void
+doLower(uintptr_t id) {
+	XLowerWindow(x_dpy, (Window)(id));
+}
+
+// grabLocks are the combinations of the Caps Lock and Num Lock modifiers. A
+// key grab only matches key presses with exactly its modifiers, so a hot key
+// is grabbed once with each of them.
+static const unsigned int grabLocks[4] = {0, LockMask, Mod2Mask, LockMask | Mod2Mask};
+
+static int grabError;
+
+static int
+onGrabError(Display *dpy, XErrorEvent *ev) {
+	grabError = ev->error_code;
+	return 0;
+}
+
+// doGrabKey grabs the key, with the modifiers, from the root window, so that
+// its key presses are sent to the root window. It returns 0 on success,
+// BadAccess if another client has grabbed it, and another X error otherwise.
+int
+doGrabKey(int keycode, unsigned int modifiers) {
+	Window root = DefaultRootWindow(x_dpy);
+	XSync(x_dpy, False);
+	grabError = 0;
+	int (*old)(Display*, XErrorEvent*) = XSetErrorHandler(onGrabError);
+	for (int i = 0; i < 4; i++) {
+		XGrabKey(x_dpy, keycode, modifiers | grabLocks[i], root, False, GrabModeAsync, GrabModeAsync);
+	}
+	XSync(x_dpy, False);
+	XSetErrorHandler(old);
+	if (grabError) {
+		for (int i = 0; i < 4; i++) {
+			XUngrabKey(x_dpy, keycode, modifiers | grabLocks[i], root);
+		}
+	}
+	return grabError;
+}
+
+void
+doUngrabKey(int keycode, unsigned int modifiers) {
+	Window root = DefaultRootWindow(x_dpy);
+	for (int i = 0; i < 4; i++) {
+		XUngrabKey(x_dpy, keycode, modifiers | grabLocks[i], root);
+	}
+}
+
+// doStartMoveResize asks the window manager to move or resize the window,
+// following the pointer until the button that is held down is released. The
+// direction is as for the EWMH _NET_WM_MOVERESIZE message.
+void
+doStartMoveResize(uintptr_t id, int direction) {
+	Window win = (Window)(id);
+	Window root, child;
+	int root_x, root_y, x, y;
+	unsigned int mask;
+	if (!XQueryPointer(x_dpy, win, &root, &child, &root_x, &root_y, &x, &y, &mask)) {
+		return;
+	}
+	int button = 0;
+	if (mask & Button1Mask) {
+		button = 1;
+	} else if (mask & Button2Mask) {
+		button = 2;
+	} else if (mask & Button3Mask) {
+		button = 3;
+	}
+	// The button press has implicitly grabbed the pointer for this window,
+	// and the window manager cannot grab it while this window has it.
+	XUngrabPointer(x_dpy, CurrentTime);
+
+	XEvent ev;
+	memset(&ev, 0, sizeof(ev));
+	ev.xclient.type = ClientMessage;
+	ev.xclient.window = win;
+	ev.xclient.message_type = net_wm_moveresize;
+	ev.xclient.format = 32;
+	ev.xclient.data.l[0] = root_x;
+	ev.xclient.data.l[1] = root_y;
+	ev.xclient.data.l[2] = direction;
+	ev.xclient.data.l[3] = button;
+	ev.xclient.data.l[4] = 1; // The source indication, 1 for a normal application.
+	XSendEvent(x_dpy, x_root, False, SubstructureNotifyMask | SubstructureRedirectMask, &ev);
+}
+
+void
+doSetTextInputRect(uintptr_t id, int x, int y, int width, int height) {
+	Window win = (Window)(id);
+	XIC ic = findIC(win);
+	if (!ic) {
+		return;
+	}
+	XIMStyle style = 0;
+	if (XGetICValues(ic, XNInputStyle, &style, NULL) || !(style & XIMPreeditPosition)) {
+		return;
+	}
+	// The spot is the baseline of the text, at the bottom of the rectangle.
+	XPoint spot;
+	spot.x = x;
+	spot.y = y + height;
+	XVaNestedList attr = XVaCreateNestedList(0, XNSpotLocation, &spot, NULL);
+	XSetICValues(ic, XNPreeditAttributes, attr, NULL);
+	XFree(attr);
+}
+
+uintptr_t
+createGlyphCursor(int glyph) {
+	return (uintptr_t)(XCreateFontCursor(x_dpy, glyph));
+}
+
+uintptr_t
+createBlankCursor() {
+	char zero = 0;
+	Pixmap pm = XCreateBitmapFromData(x_dpy, x_root, &zero, 1, 1);
+	XColor black = {0};
+	Cursor c = XCreatePixmapCursor(x_dpy, pm, pm, &black, &black, 0, 0);
+	XFreePixmap(x_dpy, pm);
+	return (uintptr_t)(c);
+}
+
+// createImageCursor returns a cursor showing the premultiplied BGRA pixels,
+// or 0 if the X server lacks the RENDER extension or a 32-bit visual. It
+// takes ownership of pix, which must have been allocated by malloc.
+uintptr_t
+createImageCursor(char *pix, int width, int height, int hotx, int hoty) {
+	int event_base, error_base;
+	XVisualInfo vi;
+	XRenderPictFormat *format = NULL;
+	if (XRenderQueryExtension(x_dpy, &event_base, &error_base) &&
+		XMatchVisualInfo(x_dpy, DefaultScreen(x_dpy), 32, TrueColor, &vi)) {
+		format = XRenderFindStandardFormat(x_dpy, PictStandardARGB32);
+	}
+	if (!format) {
+		free(pix);
+		return 0;
+	}
+
+	XImage *img = XCreateImage(x_dpy, vi.visual, 32, ZPixmap, 0, pix, width, height, 32, 0);
+	if (!img) {
+		free(pix);
+		return 0;
+	}
+	Pixmap pm = XCreatePixmap(x_dpy, x_root, width, height, 32);
+	GC gc = XCreateGC(x_dpy, pm, 0, NULL);
+	XPutImage(x_dpy, pm, gc, img, 0, 0, 0, 0, width, height);
+	XFreeGC(x_dpy, gc);
+	XDestroyImage(img); // This also frees pix.
+
+	Picture pic = XRenderCreatePicture(x_dpy, pm, format, 0, NULL);
+	Cursor c = XRenderCreateCursor(x_dpy, pic, hotx, hoty);
+	XRenderFreePicture(x_dpy, pic);
+	XFreePixmap(x_dpy, pm);
+	return (uintptr_t)(c);
+}
+
+void
+doDefineCursor(uintptr_t id, uintptr_t cursor) {
+	XDefineCursor(x_dpy, (Window)(id), (Cursor)(cursor));
+}
+
+void
+freeCursor(uintptr_t cursor) {
+	XFreeCursor(x_dpy, (Cursor)(cursor));
+}
+
+// doSetPointerCapture grabs the pointer, confining it to the window and
+// showing the given cursor, or ungrabs it. While it is grabbed, the pointer is
+// warped back to the window's center after each motion.
+//
+// TODO: use XInput2's raw motion events, which are neither accelerated nor
+// stopped by the edges of the screen.
+void
+doSetPointerCapture(uintptr_t id, int capture, uintptr_t cursor) {
+	Window win = (Window)(id);
+	if (!capture) {
+		if (win == captured_win) {
+			XUngrabPointer(x_dpy, CurrentTime);
+			captured_win = 0;
+		}
+		return;
+	}
+	if (win == captured_win) {
+		return;
+	}
+	XWindowAttributes attr;
+	if (!XGetWindowAttributes(x_dpy, win, &attr)) {
+		return;
+	}
+	int status = XGrabPointer(x_dpy, win, False,
+		ButtonPressMask | ButtonReleaseMask | PointerMotionMask,
+		GrabModeAsync, GrabModeAsync, win, (Cursor)(cursor), CurrentTime);
+	if (status != GrabSuccess) {
+		// Another client, such as the window manager, has grabbed the
+		// pointer, or the window is not viewable.
+		fprintf(stderr, "XGrabPointer failed: status %d\n", status);
+		return;
+	}
+	captured_win = win;
+	capture_x = attr.width / 2;
+	capture_y = attr.height / 2;
+	XWarpPointer(x_dpy, None, win, 0, 0, 0, 0, capture_x, capture_y);
+}
+
+void
+doGetGeometry(uintptr_t id, int *x, int *y, int *width, int *height) {
+	Window win = (Window)(id);
+	XWindowAttributes attr;
+	Window child;
+	if (!XGetWindowAttributes(x_dpy, win, &attr) ||
+		!XTranslateCoordinates(x_dpy, win, x_root, 0, 0, x, y, &child)) {
+		*x = *y = *width = *height = 0;
+		return;
+	}
+	*width = attr.width;
+	*height = attr.height;
+
+	// The _NET_FRAME_EXTENTS property, if the window manager sets it, holds
+	// the widths of the frame's left, right, top and bottom borders.
+	Atom type;
+	int format;
+	unsigned long n, remaining;
+	unsigned char *data = NULL;
+	if (XGetWindowProperty(x_dpy, win, net_frame_extents, 0, 4, False, XA_CARDINAL,
+		&type, &format, &n, &remaining, &data) == Success && data) {
+		if (format == 32 && n == 4) {
+			long *extents = (long *)data;
+			*x -= extents[0];
+			*y -= extents[2];
+		}
+		XFree(data);
+	}
+}
+
+char *
+resourceManagerString() {
+	return XResourceManagerString(x_dpy);
+}
+
+void
+doGetDisplay(int *width, int *height, int *width_mm) {
+	int screen = DefaultScreen(x_dpy);
+	*width = DisplayWidth(x_dpy, screen);
+	*height = DisplayHeight(x_dpy, screen);
+	*width_mm = DisplayWidthMM(x_dpy, screen);
+}
+
+uintptr_t
+shareContextCreate() {
+	// The share context is never used to draw, but making a context current
+	// needs a surface, unless EGL_KHR_surfaceless_context is available.
+	static const EGLint attribs[] = {
+		EGL_WIDTH, 1,
+		EGL_HEIGHT, 1,
+		EGL_NONE
+	};
+	EGLSurface surface = eglCreatePbufferSurface(e_dpy, e_config, attribs);
+	if (!surface) {
+		fprintf(stderr, "gldriver: share eglCreatePbufferSurface failed: %s\n", eglGetErrorStr());
+		return 0;
+	}
+	if (!eglMakeCurrent(e_dpy, surface, surface, e_ctx)) {
+		fprintf(stderr, "gldriver: share eglMakeCurrent failed: %s\n", eglGetErrorStr());
+		return 0;
+	}
+	return (uintptr_t)surface;
+}
+
+// shareContextRecreate replaces the share context, after it was lost, and
+// makes the replacement current, like shareContextCreate. The lost context is
+// destroyed, and its objects with it.
+uintptr_t
+shareContextRecreate() {
+	static const EGLint ctx_attribs[] = {
+		EGL_CONTEXT_CLIENT_VERSION, 3,
+		EGL_NONE
+	};
+	EGLContext ctx = eglCreateContext(e_dpy, e_config, EGL_NO_CONTEXT, ctx_attribs);
+	if (!ctx) {
+		fprintf(stderr, "gldriver: share eglCreateContext failed: %s\n", eglGetErrorStr());
+		return 0;
+	}
+	eglDestroyContext(e_dpy, e_ctx);
+	e_ctx = ctx;
+	return shareContextCreate();
+}
+
 uintptr_t
 surfaceCreate() {
 	static const EGLint ctx_attribs[] = {
@@ -7,11 +7,13 @@
 package gldriver
 
 /*
-#cgo linux      LDFLAGS: -lEGL -lGLESv2 -lX11
-#cgo openbsd    LDFLAGS: -L/usr/X11R6/lib/ -lEGL -lGLESv2 -lX11
+#cgo linux      LDFLAGS: -lEGL -lGLESv2 -lX11 -lXext -lXrender
+#cgo openbsd    LDFLAGS: -L/usr/X11R6/lib/ -lEGL -lGLESv2 -lX11 -lXext -lXrender
 
 #cgo openbsd    CFLAGS: -I/usr/X11R6/include/
 
+#include <X11/X.h>
+#include <X11/cursorfont.h>
 #include <stdbool.h>
 #include <stdint.h>
 #include <stdlib.h>
@@ -19,20 +21,70 @@ package gldriver
 char *eglGetErrorStr();
 void startDriver();
 void processEvents();
-void makeCurrent(uintptr_t ctx);
-void swapBuffers(uintptr_t ctx);
+void makeCurrent(uintptr_t surface, uintptr_t ctx);
+int swapBuffers(uintptr_t surface);
+int objectLabel(unsigned int identifier, unsigned int name, char *label, int label_len);
+int pboSupported();
+void texSubImageUnpack(int x, int y, int width, int height);
+uintptr_t fenceSync();
+int syncSignaled(uintptr_t sync, int flush);
+void deleteSync(uintptr_t sync);
+uintptr_t recreateContext(uintptr_t surface, uintptr_t context, uintptr_t config);
 void doCloseWindow(uintptr_t id);
-uintptr_t doNewWindow(int width, int height, char* title, int title_len);
-uintptr_t doShowWindow(uintptr_t id);
+uintptr_t doNewWindow(int width, int height, int x, int y, int has_position, int fixed_size, int undecorated, int samples, uintptr_t owner, int modal, char* title, int title_len);
+void doSetShape(uintptr_t id, int *rects, int n);
+uintptr_t doShowWindow(uintptr_t id, int hidden, int srgb, uintptr_t *ctx, uintptr_t *config);
+int srgbSurfaces();
+void doSetTitle(uintptr_t id, char* title, int title_len);
+void doSetIcon(uintptr_t id, unsigned long* data, int n);
+void doSetSize(uintptr_t id, int width, int height, int fixed_size);
+void doSetSizeConstraints(uintptr_t id, int min_width, int min_height, int max_width, int max_height);
+void doSetAspectRatio(uintptr_t id, int x, int y);
+void doSetPosition(uintptr_t id, int x, int y);
+void doGetGeometry(uintptr_t id, int *x, int *y, int *width, int *height);
+void doGetDisplay(int *width, int *height, int *width_mm);
+char *resourceManagerString();
+void doSetTextInputRect(uintptr_t id, int x, int y, int width, int height);
+uintptr_t createGlyphCursor(int glyph);
+uintptr_t createBlankCursor();
+uintptr_t createImageCursor(char *pix, int width, int height, int hotx, int hoty);
+void doDefineCursor(uintptr_t id, uintptr_t cursor);
+void freeCursor(uintptr_t cursor);
+void doSetPointerCapture(uintptr_t id, int capture, uintptr_t cursor);
+void doMinimize(uintptr_t id);
+void doMaximize(uintptr_t id);
+void doRestore(uintptr_t id);
+void doSetFullscreen(uintptr_t id, int fullscreen, int exclusive);
+void doSetAlwaysOnTop(uintptr_t id, int on_top);
+void doSetOpacity(uintptr_t id, int opaque, unsigned long opacity);
+void doRaise(uintptr_t id);
+void doLower(uintptr_t id);
+void doStartMoveResize(uintptr_t id, int direction);
+int doGrabKey(int keycode, unsigned int modifiers);
+void doUngrabKey(int keycode, unsigned int modifiers);
+uintptr_t shareContextCreate();
+uintptr_t shareContextRecreate();
 uintptr_t surfaceCreate();
 */
 import "C"
 import (
 	"errors"
+	"fmt"
+	"image"
+	"image/draw"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 	"unsafe"
 
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/filedialog"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/hotkey"
+	"golang.org/x/exp/shiny/driver/internal/icon"
+	"golang.org/x/exp/shiny/driver/internal/notify"
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
 	"golang.org/x/exp/shiny/driver/internal/x11key"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/mobile/event/key"
@@ -49,23 +101,97 @@ const handleSizeEventsAtChannelReceive = true
 
 var theKeysyms x11key.KeysymTable
 
+// These variables are only accessed by the exported functions called from
+// processEvents. theKeysDown are the keys that are held down, so that the
+// presses of startDriver's detectable auto-repeat are repeats. theRulesNames
+// are the XKB rules names of the keyboard mapping, theGroup is the XKB group
+// of the last key event, and theLayout is the name of the keyboard layout
+// that the windows were last told of.
+var (
+	theKeysDown   [256]bool
+	theRulesNames x11key.RulesNames
+	theGroup      int
+	theLayout     string
+)
+
+// theClock converts the X server times, in milliseconds, of input events.
+var theClock event.Clock
+
+// theHotKeys are the windows' hot keys, which are key grabs on the root
+// window.
+var theHotKeys hotkey.Table
+
+// xftDPI is the resolution, in dots per inch, of the "Xft.dpi" X resource, or
+// zero if it is not set. Desktop environments set it to the user's chosen,
+// possibly fractional, scaling factor times 96. It is set before any windows
+// are created, and is immutable afterwards.
+//
+// TODO: notice when the RESOURCE_MANAGER property changes.
+var xftDPI float64
+
+// parseXftDPI returns the value of the "Xft.dpi" resource in the X resource
+// database string s, as returned by XResourceManagerString, or zero if there
+// is no such resource.
+func parseXftDPI(s string) float64 {
+	for _, line := range strings.Split(s, "\n") {
+		i := strings.IndexByte(line, ':')
+		if i < 0 || strings.TrimSpace(line[:i]) != "Xft.dpi" {
+			continue
+		}
+		if dpi, err := strconv.ParseFloat(strings.TrimSpace(line[i+1:]), 64); err == nil && dpi > 0 {
+			return dpi
+		}
+	}
+	return 0
+}
+
 func init() {
 	// It might not be necessary, but it probably doesn't hurt to try to make
 	// 'the main thread' be 'the X11 / OpenGL thread'.
 	runtime.LockOSThread()
 }
 
-func newWindow(opts *screen.NewWindowOptions) (uintptr, error) {
+func newWindow(opts *screen.NewWindowOptions, owner uintptr) (uintptr, error) {
 	width, height := optsSize(opts)
+	x, y, hasPosition, fixedSize, undecorated, samples, modal := 0, 0, 0, 0, 0, 0, 0
+	if opts != nil {
+		if opts.Position != nil {
+			x, y, hasPosition = opts.Position.X, opts.Position.Y, 1
+		}
+		if opts.FixedSize {
+			fixedSize = 1
+		}
+		if opts.Undecorated {
+			undecorated = 1
+		}
+		samples = opts.Samples
+		if opts.Modal {
+			modal = 1
+		}
+	}
 
 	title := opts.GetTitle()
 	ctitle := C.CString(title)
 	defer C.free(unsafe.Pointer(ctitle))
 
+	// The shape's rectangles are passed to C as their x0, y0, x1 and y1.
+	var shape []C.int
+	if opts != nil {
+		for _, r := range opts.Shape {
+			shape = append(shape, C.int(r.Min.X), C.int(r.Min.Y), C.int(r.Max.X), C.int(r.Max.Y))
+		}
+	}
+
 	retc := make(chan uintptr)
 	uic <- uiClosure{
 		f: func() uintptr {
-			return uintptr(C.doNewWindow(C.int(width), C.int(height), ctitle, C.int(len(title))))
+			id := C.doNewWindow(C.int(width), C.int(height), C.int(x), C.int(y),
+				C.int(hasPosition), C.int(fixedSize), C.int(undecorated), C.int(samples),
+				C.uintptr_t(owner), C.int(modal), ctitle, C.int(len(title)))
+			if len(shape) > 0 {
+				C.doSetShape(id, &shape[0], C.int(len(shape)/4))
+			}
+			return uintptr(id)
 		},
 		retc: retc,
 	}
@@ -73,55 +199,618 @@ func newWindow(opts *screen.NewWindowOptions) (uintptr, error) {
 }
 
 func initWindow(w *windowImpl) {
-	w.glctx, w.worker = glctx, worker
+	w.glctx, w.worker = gl.NewContext()
+	w.glctx = traceContext(w.glctx, "window")
+}
+
+// objectLabel labels a GL object of the context current on the calling
+// thread. It returns false if the context lacks the KHR_debug extension.
+func objectLabel(identifier, name uint32, label string) bool {
+	clabel := C.CString(label)
+	defer C.free(unsafe.Pointer(clabel))
+	return C.objectLabel(C.uint(identifier), C.uint(name), clabel, C.int(len(label))) != 0
+}
+
+// pboSupported, texSubImageUnpack, fenceSync, syncSignaled and deleteSync
+// make the native calls of pboStage, on the share context's processing
+// thread. pboSupported returns whether the context has the fence sync
+// functions, and must be called before the others.
+
+func pboSupported() bool { return C.pboSupported() != 0 }
+
+func texSubImageUnpack(x, y, width, height int) {
+	C.texSubImageUnpack(C.int(x), C.int(y), C.int(width), C.int(height))
 }
 
-func showWindow(w *windowImpl) {
+func fenceSync() uintptr { return uintptr(C.fenceSync()) }
+
+func syncSignaled(sync uintptr, flush bool) bool {
+	f := C.int(0)
+	if flush {
+		f = 1
+	}
+	return C.syncSignaled(C.uintptr_t(sync), f) != 0
+}
+
+func deleteSync(sync uintptr) { C.deleteSync(C.uintptr_t(sync)) }
+
+type ctxX11 struct {
+	ctx     uintptr // EGLContext
+	surface uintptr // EGLSurface
+	config  uintptr // EGLConfig
+}
+
+func showWindow(w *windowImpl, opts *screen.NewWindowOptions) {
+	hidden := 0
+	if opts != nil && opts.Hidden {
+		hidden = 1
+	}
+	srgb := 0
+	if w.progs.linear {
+		srgb = 1
+	}
+	var ctx, config C.uintptr_t
 	retc := make(chan uintptr)
 	uic <- uiClosure{
 		f: func() uintptr {
-			return uintptr(C.doShowWindow(C.uintptr_t(w.id)))
+			return uintptr(C.doShowWindow(C.uintptr_t(w.id), C.int(hidden), C.int(srgb), &ctx, &config))
 		},
 		retc: retc,
 	}
-	w.ctx = <-retc
+	surface := <-retc
+	w.ctx = ctxX11{
+		ctx:     uintptr(ctx),
+		surface: surface,
+		config:  uintptr(config),
+	}
 	go drawLoop(w)
 }
 
 func closeWindow(id uintptr) {
 	uic <- uiClosure{
 		f: func() uintptr {
+			releaseCursor(id)
 			C.doCloseWindow(C.uintptr_t(id))
 			return 0
 		},
 	}
 }
 
-func drawLoop(w *windowImpl) {
-	glcontextc <- w.ctx.(uintptr)
-	go func() {
-		for range w.publish {
-			publishc <- w
+// windowExists returns whether w has not been released. Release removes w
+// from theScreen.windows before asking the UI thread to destroy the X11
+// window, so a closure running on the UI thread that sees w in the map runs
+// before the X11 window is destroyed. Using a destroyed X11 window would be a
+// fatal X error.
+func windowExists(w *windowImpl) bool {
+	theScreen.mu.Lock()
+	defer theScreen.mu.Unlock()
+	return theScreen.windows[w.id] == w
+}
+
+func setTitle(w *windowImpl, title string) {
+	ctitle := C.CString(title)
+	defer C.free(unsafe.Pointer(ctitle))
+	retc := make(chan uintptr)
+	uic <- uiClosure{
+		f: func() uintptr {
+			if !windowExists(w) {
+				return 0
+			}
+			C.doSetTitle(C.uintptr_t(w.id), ctitle, C.int(len(title)))
+			return 0
+		},
+		retc: retc,
+	}
+	<-retc
+}
+
+func setIcon(w *windowImpl, m image.Image) {
+	var data []C.ulong
+	if m != nil {
+		for _, v := range icon.NetWMIcon(m) {
+			data = append(data, C.ulong(v))
 		}
-	}()
+	}
+	var p *C.ulong
+	if len(data) != 0 {
+		p = &data[0]
+	}
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doSetIcon(C.uintptr_t(w.id), p, C.int(len(data)))
+			}
+			return 0
+		},
+	}
 }
 
-var (
-	glcontextc = make(chan uintptr)
-	publishc   = make(chan *windowImpl)
-	uic        = make(chan uiClosure)
+// setBadge and setProgress do nothing, as X11 window managers have no badges
+// or progress of their own.
+func setBadge(w *windowImpl, label string) {}
 
-	// TODO: don't assume that there is only one window, and hence only
-	// one (global) GL context.
-	//
-	// TODO: should we be able to make a shiny.Texture before having a
-	// shiny.Window's GL context? Should something like gl.IsProgram be a
-	// method instead of a function, and have each shiny.Window have its own
-	// gl.Context?
-	glctx  gl.Context
-	worker gl.Worker
+func setProgress(w *windowImpl, progress float64) {}
+
+func setSize(w *windowImpl, width, height int) {
+	fixedSize := 0
+	if w.fixedSize {
+		fixedSize = 1
+	}
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doSetSize(C.uintptr_t(w.id), C.int(width), C.int(height), C.int(fixedSize))
+			}
+			return 0
+		},
+	}
+}
+
+func setSizeConstraints(w *windowImpl, min, max image.Point) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doSetSizeConstraints(C.uintptr_t(w.id),
+					C.int(min.X), C.int(min.Y), C.int(max.X), C.int(max.Y))
+			}
+			return 0
+		},
+	}
+}
+
+func setAspectRatio(w *windowImpl, aspect image.Point) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doSetAspectRatio(C.uintptr_t(w.id), C.int(aspect.X), C.int(aspect.Y))
+			}
+			return 0
+		},
+	}
+}
+
+func setPosition(w *windowImpl, p image.Point) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doSetPosition(C.uintptr_t(w.id), C.int(p.X), C.int(p.Y))
+			}
+			return 0
+		},
+	}
+}
+
+func geometry(w *windowImpl) (image.Point, image.Point) {
+	var x, y, width, height C.int
+	retc := make(chan uintptr)
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doGetGeometry(C.uintptr_t(w.id), &x, &y, &width, &height)
+			}
+			return 0
+		},
+		retc: retc,
+	}
+	<-retc
+	return image.Point{int(x), int(y)}, image.Point{int(width), int(height)}
+}
+
+func setTextInputRect(w *windowImpl, r image.Rectangle) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doSetTextInputRect(C.uintptr_t(w.id), C.int(r.Min.X), C.int(r.Min.Y), C.int(r.Dx()), C.int(r.Dy()))
+			}
+			return 0
+		},
+	}
+}
+
+// x11Cursor is the cursor set for a window.
+type x11Cursor struct {
+	cursor screen.Cursor
+	hidden bool
+	// owned is the X11 cursor created for the window's cursor image, if any,
+	// which is freed when it is no longer shown.
+	owned C.uintptr_t
+}
+
+// x11Cursors holds the cursor of each window, keyed by the window's id, whose
+// cursor has been set. x11ShapeCursors and x11BlankCursor are X11 cursors,
+// created on first use, that are shared by all windows. Like the X11 calls,
+// they are only accessed on the UI thread.
+var (
+	x11Cursors      = map[uintptr]*x11Cursor{}
+	x11ShapeCursors = map[screen.CursorShape]C.uintptr_t{}
+	x11BlankCursor  C.uintptr_t
 )
 
+// cursorGlyphs are the glyphs, in the X11 "cursor" font, of the
+// screen.CursorShapes. The font has no diagonal resize or not-allowed
+// cursors, so the nearest equivalents are used.
+var cursorGlyphs = [...]C.int{
+	screen.CursorArrow:      C.XC_left_ptr,
+	screen.CursorIBeam:      C.XC_xterm,
+	screen.CursorCrosshair:  C.XC_crosshair,
+	screen.CursorHand:       C.XC_hand2,
+	screen.CursorResizeNS:   C.XC_sb_v_double_arrow,
+	screen.CursorResizeEW:   C.XC_sb_h_double_arrow,
+	screen.CursorResizeNWSE: C.XC_bottom_right_corner,
+	screen.CursorResizeNESW: C.XC_bottom_left_corner,
+	screen.CursorResizeAll:  C.XC_fleur,
+	screen.CursorNotAllowed: C.XC_circle,
+	screen.CursorWait:       C.XC_watch,
+}
+
+func setCursor(w *windowImpl, c screen.Cursor) {
+	if c.HasImage() {
+		// Copy the image, as the caller may modify it after SetCursor
+		// returns, and it is shown again each time that the cursor is shown
+		// after being hidden.
+		m := image.NewRGBA(c.Image.Rect)
+		draw.Draw(m, m.Rect, c.Image, m.Rect.Min, draw.Src)
+		c.Image = m
+	}
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				xc := cursorFor(w.id)
+				xc.cursor = c
+				updateCursor(w.id, xc)
+			}
+			return 0
+		},
+	}
+}
+
+func setCursorVisible(w *windowImpl, visible bool) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				xc := cursorFor(w.id)
+				xc.hidden = !visible
+				updateCursor(w.id, xc)
+			}
+			return 0
+		},
+	}
+}
+
+func cursorFor(id uintptr) *x11Cursor {
+	xc := x11Cursors[id]
+	if xc == nil {
+		xc = &x11Cursor{}
+		x11Cursors[id] = xc
+	}
+	return xc
+}
+
+// updateCursor shows xc for the window. It must be called on the UI thread.
+func updateCursor(id uintptr, xc *x11Cursor) {
+	var c, owned C.uintptr_t
+	switch {
+	case xc.hidden:
+		c = blankCursor()
+	case xc.cursor.HasImage():
+		// The image was copied by setCursor, so its rows are contiguous.
+		// image.RGBA is already alpha-premultiplied, as RENDER requires, so
+		// only the byte order differs.
+		m := xc.cursor.Image
+		pix := C.CBytes(m.Pix) // Freed by createImageCursor.
+		swizzle.BGRA((*[1 << 30]byte)(pix)[:len(m.Pix):len(m.Pix)])
+		owned = C.createImageCursor((*C.char)(pix), C.int(m.Rect.Dx()), C.int(m.Rect.Dy()),
+			C.int(xc.cursor.Hotspot.X), C.int(xc.cursor.Hotspot.Y))
+		c = owned
+	}
+	if c == 0 {
+		// The arrow is the X11 cursor None, which inherits the root window's
+		// cursor, as that is the desktop's usual, and possibly themed, arrow.
+		// Images also fall back to their shape if they cannot be shown.
+		if shape := xc.cursor.Shape; shape > screen.CursorArrow && int(shape) < len(cursorGlyphs) {
+			if x11ShapeCursors[shape] == 0 {
+				x11ShapeCursors[shape] = C.createGlyphCursor(cursorGlyphs[shape])
+			}
+			c = x11ShapeCursors[shape]
+		}
+	}
+	C.doDefineCursor(C.uintptr_t(id), c)
+	// The window keeps the cursor until it is changed, so freeing the old
+	// cursor does not stop it being shown.
+	if xc.owned != 0 {
+		C.freeCursor(xc.owned)
+	}
+	xc.owned = owned
+}
+
+// blankCursor returns an invisible cursor. It must be called on the UI thread.
+func blankCursor() C.uintptr_t {
+	if x11BlankCursor == 0 {
+		x11BlankCursor = C.createBlankCursor()
+	}
+	return x11BlankCursor
+}
+
+// releaseCursor forgets the window's cursor, freeing the X11 cursor created
+// for it, if any. It must be called on the UI thread.
+func releaseCursor(id uintptr) {
+	if xc := x11Cursors[id]; xc != nil && xc.owned != 0 {
+		C.freeCursor(xc.owned)
+	}
+	delete(x11Cursors, id)
+}
+
+func setPointerCapture(w *windowImpl, capture bool) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				v := 0
+				if capture {
+					v = 1
+				}
+				C.doSetPointerCapture(C.uintptr_t(w.id), C.int(v), blankCursor())
+			}
+			return 0
+		},
+	}
+}
+
+func minimize(w *windowImpl) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doMinimize(C.uintptr_t(w.id))
+			}
+			return 0
+		},
+	}
+}
+
+func maximize(w *windowImpl) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doMaximize(C.uintptr_t(w.id))
+			}
+			return 0
+		},
+	}
+}
+
+func restore(w *windowImpl) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doRestore(C.uintptr_t(w.id))
+			}
+			return 0
+		},
+	}
+}
+
+func setFullscreen(w *windowImpl, mode screen.FullscreenMode) {
+	fullscreen, exclusive := 0, 0
+	if mode != screen.FullscreenNone {
+		fullscreen = 1
+	}
+	if mode == screen.FullscreenExclusive {
+		exclusive = 1
+	}
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doSetFullscreen(C.uintptr_t(w.id), C.int(fullscreen), C.int(exclusive))
+			}
+			return 0
+		},
+	}
+}
+
+func setAlwaysOnTop(w *windowImpl, onTop bool) {
+	v := 0
+	if onTop {
+		v = 1
+	}
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doSetAlwaysOnTop(C.uintptr_t(w.id), C.int(v))
+			}
+			return 0
+		},
+	}
+}
+
+func setOpacity(w *windowImpl, opacity float32) {
+	opaque := 0
+	if !(opacity < 1) {
+		opaque = 1
+	} else if !(opacity > 0) {
+		opacity = 0
+	}
+	v := uint32(float64(opacity) * 0xffffffff)
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doSetOpacity(C.uintptr_t(w.id), C.int(opaque), C.ulong(v))
+			}
+			return 0
+		},
+	}
+}
+
+func raise(w *windowImpl) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doRaise(C.uintptr_t(w.id))
+			}
+			return 0
+		},
+	}
+}
+
+func lower(w *windowImpl) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doLower(C.uintptr_t(w.id))
+			}
+			return 0
+		},
+	}
+}
+
+func registerHotKey(w *windowImpl, k hotkey.Key) error {
+	keycode := theKeysyms.Keycode(k.Code)
+	if keycode == 0 {
+		return fmt.Errorf("gldriver: no key has the code %v", k.Code)
+	}
+	return theHotKeys.Register(w, k, func() error {
+		retc := make(chan uintptr)
+		uic <- uiClosure{
+			f: func() uintptr {
+				return uintptr(C.doGrabKey(C.int(keycode), C.uint(x11key.ModifierState(k.Modifiers))))
+			},
+			retc: retc,
+		}
+		switch code := <-retc; code {
+		case 0:
+			return nil
+		case C.BadAccess:
+			return screen.ErrHotKeyInUse
+		default:
+			return fmt.Errorf("gldriver: XGrabKey failed: X error %d", code)
+		}
+	})
+}
+
+func unregisterHotKey(w *windowImpl, k hotkey.Key) {
+	theHotKeys.Unregister(w, k, func() { ungrabKey(k) })
+}
+
+func releaseHotKeys(w *windowImpl) {
+	theHotKeys.Release(w, ungrabKey)
+}
+
+func ungrabKey(k hotkey.Key) {
+	keycode := theKeysyms.Keycode(k.Code)
+	uic <- uiClosure{
+		f: func() uintptr {
+			C.doUngrabKey(C.int(keycode), C.uint(x11key.ModifierState(k.Modifiers)))
+			return 0
+		},
+	}
+}
+
+// moveResizeDirections are the _NET_WM_MOVERESIZE directions, indexed by
+// screen.WindowEdge, and moveResizeMove is the direction that moves the
+// window instead.
+var moveResizeDirections = [...]int{
+	screen.WindowEdgeTop:         1,
+	screen.WindowEdgeBottom:      5,
+	screen.WindowEdgeLeft:        7,
+	screen.WindowEdgeRight:       3,
+	screen.WindowEdgeTopLeft:     0,
+	screen.WindowEdgeTopRight:    2,
+	screen.WindowEdgeBottomLeft:  6,
+	screen.WindowEdgeBottomRight: 4,
+}
+
+const moveResizeMove = 8
+
+func startMove(w *windowImpl) error {
+	startMoveResize(w, moveResizeMove)
+	return nil
+}
+
+func startResize(w *windowImpl, edge screen.WindowEdge) error {
+	if int(edge) >= len(moveResizeDirections) {
+		return errors.New("gldriver: StartResize with invalid edge")
+	}
+	startMoveResize(w, moveResizeDirections[edge])
+	return nil
+}
+
+func startMoveResize(w *windowImpl, direction int) {
+	uic <- uiClosure{
+		f: func() uintptr {
+			if windowExists(w) {
+				C.doStartMoveResize(C.uintptr_t(w.id), C.int(direction))
+			}
+			return 0
+		},
+	}
+}
+
+// x11Frames are the windows waiting for a screen.FrameEvent.
+var x11Frames frame.Requests
+
+// nextFrame sends a screen.FrameEvent at the next tick of a clock running at
+// a typical display's refresh rate.
+//
+// TODO: use GLX_OML_sync_control or the Present extension to wait for the
+// display's actual vertical blank.
+func nextFrame(w *windowImpl) {
+	if x11Frames.Request(w) {
+		go x11Frames.Tick(frame.Interval(0))
+	}
+}
+
+// drawLoop runs the window's GL context on a dedicated OS thread, so that
+// multiple windows do not contend for a single thread or context.
+func drawLoop(w *windowImpl) {
+	runtime.LockOSThread()
+
+	surface := w.ctx.(ctxX11).surface
+	// TODO: do we need to synchronize with seeing a size event for this
+	// window's context before or after calling makeCurrent? Otherwise, are
+	// we racing with the gl.Viewport call? I've occasionally seen a stale
+	// viewport, if the window manager sets the window width and height to
+	// something other than that requested by XCreateWindow, but it's not
+	// easily reproducible.
+	C.makeCurrent(C.uintptr_t(surface), C.uintptr_t(w.ctx.(ctxX11).ctx))
+
+	// TODO(crawshaw): exit this goroutine on Release.
+	workAvailable := w.worker.WorkAvailable()
+	run := traceRun(w.glctx)
+	for {
+		select {
+		case <-workAvailable:
+			w.worker.DoWork()
+		case f := <-run:
+			f()
+		case <-w.publish:
+		loop:
+			for {
+				select {
+				case <-workAvailable:
+					w.worker.DoWork()
+				default:
+					break loop
+				}
+			}
+			lost := C.swapBuffers(C.uintptr_t(surface)) != 0
+			w.contextLost = w.checkContext(lost, func() error {
+				config := w.ctx.(ctxX11).config
+				ctx := C.recreateContext(C.uintptr_t(surface), C.uintptr_t(w.ctx.(ctxX11).ctx), C.uintptr_t(config))
+				if ctx == 0 {
+					return errors.New("gldriver: context re-creation failed")
+				}
+				w.ctx = ctxX11{
+					ctx:     uintptr(ctx),
+					surface: surface,
+					config:  config,
+				}
+				return nil
+			})
+			w.publishDone <- screen.PublishResult{}
+		}
+	}
+}
+
+var uic = make(chan uiClosure)
+
 // uiClosure is a closure to be run on C's UI thread.
 type uiClosure struct {
 	f    func() uintptr
@@ -133,7 +822,9 @@ func main(f func(screen.Screen)) error {
 		return errors.New("gldriver: ES 3 required on X11")
 	}
 	C.startDriver()
-	glctx, worker = gl.NewContext()
+	if rms := C.resourceManagerString(); rms != nil {
+		xftDPI = parseXftDPI(C.GoString(rms))
+	}
 
 	closec := make(chan struct{})
 	go func() {
@@ -149,23 +840,11 @@ func main(f func(screen.Screen)) error {
 	// C.processEvents needs to select on a file descriptor, and the other
 	// cases below select on Go channels.
 	heartbeat := time.NewTicker(time.Second / 60)
-	workAvailable := worker.WorkAvailable()
 
 	for {
 		select {
 		case <-closec:
 			return nil
-		case ctx := <-glcontextc:
-			// TODO: do we need to synchronize with seeing a size event for
-			// this window's context before or after calling makeCurrent?
-			// Otherwise, are we racing with the gl.Viewport call? I've
-			// occasionally seen a stale viewport, if the window manager sets
-			// the window width and height to something other than that
-			// requested by XCreateWindow, but it's not easily reproducible.
-			C.makeCurrent(C.uintptr_t(ctx))
-		case w := <-publishc:
-			C.swapBuffers(C.uintptr_t(w.ctx.(uintptr)))
-			w.publishDone <- screen.PublishResult{}
 		case req := <-uic:
 			ret := req.f()
 			if req.retc != nil {
@@ -173,8 +852,6 @@ func main(f func(screen.Screen)) error {
 			}
 		case <-heartbeat.C:
 			C.processEvents()
-		case <-workAvailable:
-			worker.DoWork()
 		}
 	}
 }
@@ -193,37 +870,124 @@ func onExpose(id uintptr) {
 }
 
 //export onKeysym
-func onKeysym(k, unshifted, shifted uint32) {
-	theKeysyms[k][0] = unshifted
-	theKeysyms[k][1] = shifted
+func onKeysym(k, unshifted, shifted, unshifted2, shifted2 uint32) {
+	theKeysyms[k] = [4]uint32{unshifted, shifted, unshifted2, shifted2}
+}
+
+//export onRulesNames
+func onRulesNames(names *C.char, n C.int) {
+	theRulesNames = x11key.ParseRulesNames(C.GoBytes(unsafe.Pointer(names), n))
+	if theLayout == "" {
+		// This is the initial layout, not a change.
+		theLayout = theRulesNames.LayoutName(0)
+		return
+	}
+	updateLayout(theGroup)
+}
+
+// updateLayout sends a screen.KeyboardLayoutEvent to the windows if the
+// layout of the XKB group differs from the last one that they were sent.
+func updateLayout(group int) {
+	theGroup = group
+	name := theRulesNames.LayoutName(group)
+	if name == theLayout || name == "" {
+		return
+	}
+	theLayout = name
+
+	theScreen.mu.Lock()
+	windows := make([]*windowImpl, 0, len(theScreen.windows))
+	for _, w := range theScreen.windows {
+		windows = append(windows, w)
+	}
+	theScreen.mu.Unlock()
+
+	for _, w := range windows {
+		w.Send(screen.KeyboardLayoutEvent{Layout: name})
+	}
 }
 
 //export onKey
-func onKey(id uintptr, state uint16, detail, dir uint8) {
+func onKey(id uintptr, state uint16, detail, dir uint8, t uint32) {
 	theScreen.mu.Lock()
 	w := theScreen.windows[id]
 	theScreen.mu.Unlock()
 
 	if w == nil {
+		// Key presses of hot keys are sent to the root window.
+		if key.Direction(dir) == key.DirPress {
+			_, c := theKeysyms.Lookup(detail, state)
+			theHotKeys.Send(hotkey.Key{Code: c, Modifiers: x11key.KeyModifiers(state)})
+		}
+		return
+	}
+
+	d := key.Direction(dir)
+	if d == key.DirPress && theKeysDown[detail] {
+		d = key.DirNone
+	}
+	theKeysDown[detail] = d != key.DirRelease
+	if w.inputBlocked() {
 		return
 	}
+	if g := x11key.Group(state); g != theGroup {
+		updateLayout(g)
+	}
 
 	r, c := theKeysyms.Lookup(detail, state)
-	w.Send(key.Event{
-		Rune:      r,
-		Code:      c,
-		Modifiers: x11key.KeyModifiers(state),
-		Direction: key.Direction(dir),
-	})
+	physical := key.CodeUnknown
+	if theRulesNames.Rules == "evdev" {
+		physical = x11key.PhysicalCode(detail)
+	}
+	e := screen.KeyEvent{
+		Event: key.Event{
+			Rune:      r,
+			Code:      c,
+			Modifiers: x11key.KeyModifiers(state),
+			Direction: d,
+		},
+		Physical:   physical,
+		Scancode:   uint32(detail),
+		Unmodified: theKeysyms.Unmodified(detail, state),
+		Repeat:     d == key.DirNone,
+	}
+	at := theClock.Millis(t)
+	w.SendAt(e, at)
+}
+
+//export onText
+func onText(id uintptr, text *C.char, n C.int, t uint32) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[id]
+	theScreen.mu.Unlock()
+
+	if w == nil || w.inputBlocked() {
+		return
+	}
+
+	w.SendAt(screen.TextEvent{
+		Commit: C.GoStringN(text, n),
+	}, theClock.Millis(t))
+}
+
+//export onRelativeMouse
+func onRelativeMouse(id uintptr, dx, dy int32, t uint32) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[id]
+	theScreen.mu.Unlock()
+
+	if w != nil && !w.inputBlocked() {
+		w.SendAt(screen.RelativeMouseEvent{DeltaX: float32(dx), DeltaY: float32(dy)}, theClock.Millis(t))
+	}
 }
 
 //export onMouse
-func onMouse(id uintptr, x, y int32, state uint16, button, dir uint8) {
+func onMouse(id uintptr, x, y int32, state uint16, button, dir uint8, t uint32) {
 	theScreen.mu.Lock()
 	w := theScreen.windows[id]
 	theScreen.mu.Unlock()
 
-	if w == nil {
+	if w == nil || w.inputBlocked() {
 		return
 	}
 
@@ -240,23 +1004,67 @@ func onMouse(id uintptr, x, y int32, state uint16, button, dir uint8) {
 	case 7:
 		btn = mouse.ButtonWheelRight
 	}
+	// Buttons 8 and 9, typically the back and forward side buttons, are
+	// screen.MouseButtonBack and screen.MouseButtonForward. They, and any
+	// higher numbered buttons, are reported unchanged.
+	at := theClock.Millis(t)
 	if btn.IsWheel() {
 		if dir != uint8(mouse.DirPress) {
 			return
 		}
 		dir = uint8(mouse.DirStep)
+		// TODO: send smooth scrolling, from XInput2's scroll valuators.
+		w.SendAt(wheelScrollEvent(float32(x), float32(y), btn, x11key.KeyModifiers(state)), at)
 	}
-	w.Send(mouse.Event{
+	w.SendAt(mouse.Event{
 		X:         float32(x),
 		Y:         float32(y),
 		Button:    btn,
 		Modifiers: x11key.KeyModifiers(state),
 		Direction: mouse.Direction(dir),
-	})
+	}, at)
+}
+
+// wheelScrollEvent returns the screen.ScrollEvent of a step of a wheel
+// button.
+func wheelScrollEvent(x, y float32, b mouse.Button, m key.Modifiers) screen.ScrollEvent {
+	e := screen.ScrollEvent{
+		X:         x,
+		Y:         y,
+		Device:    screen.ScrollDeviceWheel,
+		Modifiers: m,
+	}
+	switch b {
+	case mouse.ButtonWheelUp:
+		e.DeltaY = -1
+	case mouse.ButtonWheelDown:
+		e.DeltaY = +1
+	case mouse.ButtonWheelLeft:
+		e.DeltaX = -1
+	case mouse.ButtonWheelRight:
+		e.DeltaX = +1
+	}
+	return e
+}
+
+//export onCrossing
+func onCrossing(id uintptr, entered bool, x, y int32, t uint32) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[id]
+	theScreen.mu.Unlock()
+
+	if w == nil {
+		return
+	}
+	w.SendAt(screen.CrossingEvent{Entered: entered, X: float32(x), Y: float32(y)}, theClock.Millis(t))
 }
 
 //export onFocus
 func onFocus(id uintptr, focused bool) {
+	// The keys that were held down when the focus moved are released
+	// elsewhere.
+	theKeysDown = [256]bool{}
+
 	theScreen.mu.Lock()
 	w := theScreen.windows[id]
 	theScreen.mu.Unlock()
@@ -283,19 +1091,63 @@ func onConfigure(id uintptr, x, y, width, height, displayWidth, displayWidthMM i
 	w.lifecycler.SendEvent(w, w.glctx)
 
 	const (
-		mmPerInch = 25.4
-		ptPerInch = 72
+		mmPerInch  = 25.4
+		ptPerInch  = 72
+		defaultDPI = 96
 	)
+	// The "Xft.dpi" resource, if set, is the user's choice of resolution.
+	// Otherwise, use the X11 screen's physical resolution.
+	//
+	// TODO: use XRandR to find the physical resolution of the monitor that
+	// the window is on, as the x11driver does.
 	pixelsPerMM := float32(displayWidth) / float32(displayWidthMM)
+	ppp, scale := pixelsPerMM*mmPerInch/ptPerInch, 1.0
+	if xftDPI > 0 {
+		ppp, scale = float32(xftDPI/ptPerInch), xftDPI/defaultDPI
+	}
+	w.sendScale(ppp, scale)
 	w.Send(size.Event{
 		WidthPx:     int(width),
 		HeightPx:    int(height),
-		WidthPt:     geom.Pt(width),
-		HeightPt:    geom.Pt(height),
-		PixelsPerPt: pixelsPerMM * mmPerInch / ptPerInch,
+		WidthPt:     geom.Pt(float32(width) / ppp),
+		HeightPt:    geom.Pt(float32(height) / ppp),
+		PixelsPerPt: ppp,
 	})
 }
 
+//export onWindowState
+func onWindowState(id uintptr, state int32) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[id]
+	theScreen.mu.Unlock()
+
+	if w == nil {
+		return
+	}
+	w.sendState(screen.WindowState(state))
+}
+
+//export onVisibility
+func onVisibility(id uintptr, state int32) {
+	theScreen.mu.Lock()
+	w := theScreen.windows[id]
+	theScreen.mu.Unlock()
+
+	if w == nil {
+		return
+	}
+	// A minimized window, which is unmapped, is sent no VisibilityNotify
+	// events.
+	v := screen.VisibilityFull
+	switch state {
+	case C.VisibilityPartiallyObscured:
+		v = screen.VisibilityPartial
+	case C.VisibilityFullyObscured:
+		v = screen.VisibilityOccluded
+	}
+	w.sendVisibility(v)
+}
+
 //export onDeleteWindow
 func onDeleteWindow(id uintptr) {
 	theScreen.mu.Lock()
@@ -305,11 +1157,122 @@ func onDeleteWindow(id uintptr) {
 	if w == nil {
 		return
 	}
+	if w.interceptClose {
+		w.Send(screen.CloseRequestEvent{})
+		return
+	}
 
 	w.lifecycler.SetDead(true)
 	w.lifecycler.SendEvent(w, w.glctx)
 }
 
+// TODO: read the XSETTINGS accessibility preferences, as the x11driver does.
+func accessibilityPrefs() screen.AccessibilityPrefs { return 0 }
+
+// TODO: read the XSETTINGS theme name, as the x11driver does.
+func colorScheme() screen.ColorScheme { return screen.LightColorScheme }
+
+// TODO: implement the CLIPBOARD selection, as the x11driver does.
+func clipboard() screen.Clipboard {
+	return errClipboard{errors.New("gldriver: the clipboard is not implemented on X11")}
+}
+
+// TODO: implement XDND, as the x11driver does. It needs the selections that
+// the clipboard would use.
+func startDrag(w *windowImpl, data screen.DragData) error {
+	return errors.New("gldriver: drag-and-drop is not implemented on X11")
+}
+
+// showFileDialog runs zenity, as X11 has no file dialogs of its own.
+func showFileDialog(w *windowImpl, opts *screen.FileDialogOptions) error {
+	return filedialog.Start(w, opts, uint32(w.id))
+}
+
+// postNotification runs notify-send, as X11 has no notifications of its own.
+func postNotification(w *windowImpl, n *screen.Notification) error {
+	return notify.Post(w, n)
+}
+
+func closeNotification(w *windowImpl, id int) { notify.Close(w, id) }
+func releaseNotifications(w *windowImpl)      { notify.Release(w) }
+
+// setMenuBar and showContextMenu return an error, as X11 has no menus of its
+// own.
+func setMenuBar(w *windowImpl, bar *screen.Menu) error {
+	return errors.New("gldriver: menus are not supported on X11")
+}
+
+func showContextMenu(w *windowImpl, m *screen.Menu, p image.Point) error {
+	return errors.New("gldriver: menus are not supported on X11")
+}
+
+// setAccessTree returns an error, as X11 has no accessibility API of its own.
+//
+// TODO: implement AT-SPI, over D-Bus.
+func setAccessTree(w *windowImpl, root *screen.AccessNode) error {
+	return errors.New("gldriver: accessibility is not implemented on X11")
+}
+
+// newTrayIcon returns an error.
+//
+// TODO: embed a window in the system tray, by the XEmbed system tray
+// protocol, as the x11driver does.
+func newTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return nil, errors.New("gldriver: tray icons are not supported on X11")
+}
+
+// displays reports the X11 screen as a single display.
+//
+// TODO: use XRandR, as the x11driver does, to find each monitor and its
+// refresh rate, and to send DisplayEvents when they change.
+func displays() []screen.Display {
+	var width, height, widthMM C.int
+	C.doGetDisplay(&width, &height, &widthMM)
+	d := screen.Display{
+		Bounds:   image.Rect(0, 0, int(width), int(height)),
+		WorkArea: image.Rect(0, 0, int(width), int(height)),
+		Scale:    1,
+		Primary:  true,
+	}
+	if xftDPI > 0 {
+		d.Scale = xftDPI / 96
+	}
+	if widthMM > 0 {
+		const mmPerInch = 25.4
+		d.DPI = float64(width) * mmPerInch / float64(widthMM)
+	}
+	return []screen.Display{d}
+}
+
+func shareContextCreate() error {
+	if C.shareContextCreate() == 0 {
+		return errors.New("gldriver: share context creation failed")
+	}
+	return nil
+}
+
+// setColorSpace only accepts sRGB, as X11 has no way to tag a window with
+// another color space.
+func setColorSpace(w *windowImpl, cs screen.ColorSpace) error {
+	if cs != screen.ColorSpaceSRGB {
+		return errors.New("gldriver: color spaces other than sRGB are not supported on X11")
+	}
+	return nil
+}
+
+// srgbSurfaces returns whether the EGL display has the EGL_KHR_gl_colorspace
+// extension, with which doShowWindow can create sRGB-encoded surfaces.
+func srgbSurfaces() bool {
+	return C.srgbSurfaces() != 0
+}
+
+func shareContextRecreate() error {
+	if C.shareContextRecreate() == 0 {
+		return errors.New("gldriver: share context re-creation failed")
+	}
+	return nil
+}
+
 func surfaceCreate() error {
 	if C.surfaceCreate() == 0 {
 		return errors.New("gldriver: surface creation failed")
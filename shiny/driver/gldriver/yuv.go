@@ -0,0 +1,191 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gldriver
+
+import (
+	"image"
+	"image/draw"
+
+	"golang.org/x/exp/shiny/driver/internal/yuv"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/gl"
+)
+
+// UploadYUV implements screen.YUVUploader.
+//
+// It uploads src's planes, unconverted, to the share context's plane
+// textures, and then draws them onto the texture's framebuffer with a
+// fragment shader that converts them to RGBA. Chroma is sampled with the
+// nearest neighbor, as in the software conversion, so that the two agree.
+func (t *textureImpl) UploadYUV(dp image.Point, src *screen.YUVImage, sr image.Rectangle) {
+	if t.compressed != screen.CompressedNone {
+		return
+	}
+	originalSRMin := sr.Min
+	sr = sr.Intersect(src.Rect)
+	if sr.Empty() {
+		return
+	}
+	dp = dp.Add(sr.Min.Sub(originalSRMin))
+	dr := image.Rectangle{dp, dp.Add(sr.Size())}.Intersect(t.Bounds())
+	if dr.Empty() {
+		return
+	}
+	sr.Min = sr.Min.Add(dr.Min.Sub(dp))
+	sr.Max = sr.Min.Add(dr.Size())
+
+	// l and c are the luma and chroma rectangles, relative to the planes'
+	// origins, that cover sr.
+	l := sr.Sub(src.Rect.Min)
+	c := image.Rect(l.Min.X/2, l.Min.Y/2, (l.Max.X+1)/2, (l.Max.Y+1)/2)
+	minX := float64(dr.Min.X)
+	minY := float64(dr.Min.Y)
+	maxX := float64(dr.Max.X)
+	maxY := float64(dr.Max.Y)
+	mvp := t.mvp(
+		minX, minY,
+		maxX, minY,
+		minX, maxY,
+	)
+	m := yuv.MatrixFor(src.ColorSpace, src.FullRange)
+
+	t.s.shareMu.Lock()
+	defer t.s.shareMu.Unlock()
+
+	if t.id == (gl.Texture{}) {
+		return // Released.
+	}
+	glctx := t.s.share
+	p := &t.s.shareProgs
+	p.useYUV(glctx)
+
+	uploadPlane(glctx, p.yuv.planes[0], &p.yuv.sizes[0], gl.LUMINANCE, 1, l.Size(),
+		src.Y[l.Min.Y*src.YStride+l.Min.X:], src.YStride)
+	nv12 := float32(0)
+	if src.Format == screen.YUVNV12 {
+		nv12 = 1
+		uploadPlane(glctx, p.yuv.planes[1], &p.yuv.sizes[1], gl.LUMINANCE_ALPHA, 2, c.Size(),
+			src.U[c.Min.Y*src.UVStride+2*c.Min.X:], src.UVStride)
+	} else {
+		uploadPlane(glctx, p.yuv.planes[1], &p.yuv.sizes[1], gl.LUMINANCE, 1, c.Size(),
+			src.U[c.Min.Y*src.UVStride+c.Min.X:], src.UVStride)
+		uploadPlane(glctx, p.yuv.planes[2], &p.yuv.sizes[2], gl.LUMINANCE, 1, c.Size(),
+			src.V[c.Min.Y*src.UVStride+c.Min.X:], src.UVStride)
+	}
+
+	t.bindFramebuffer()
+	glctx.Viewport(0, 0, t.size.X, t.size.Y)
+	useBlend(glctx, draw.Src, nil)
+
+	writeAff3(glctx, p.yuv.mvp, mvp)
+	// The chroma uniform maps the unit square, covering l, to the chroma
+	// planes' texture coordinates, whose texels each cover 2x2 pixels.
+	cw, ch := float32(c.Dx()), float32(c.Dy())
+	glctx.Uniform4f(p.yuv.chroma,
+		float32(l.Dx())/(2*cw),
+		float32(l.Dy())/(2*ch),
+		(float32(l.Min.X)/2-float32(c.Min.X))/cw,
+		(float32(l.Min.Y)/2-float32(c.Min.Y))/ch,
+	)
+	glctx.Uniform1f(p.yuv.nv12, nv12)
+	glctx.Uniform1f(p.yuv.yOffset, m.YOffset)
+	glctx.Uniform1f(p.yuv.yScale, m.Y)
+	glctx.Uniform3f(p.yuv.uCoef, 0, m.GU, m.BU)
+	glctx.Uniform3f(p.yuv.vCoef, m.RV, m.GV, 0)
+	for i, u := range [3]gl.Uniform{p.yuv.y, p.yuv.u, p.yuv.v} {
+		glctx.ActiveTexture(gl.Enum(gl.TEXTURE0 + i))
+		glctx.BindTexture(gl.TEXTURE_2D, p.yuv.planes[i])
+		glctx.Uniform1i(u, i)
+	}
+	glctx.ActiveTexture(gl.TEXTURE0)
+
+	glctx.BindBuffer(gl.ARRAY_BUFFER, p.yuv.quad)
+	glctx.EnableVertexAttribArray(p.yuv.pos)
+	glctx.VertexAttribPointer(p.yuv.pos, 2, gl.FLOAT, false, 0, 0)
+	glctx.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+	glctx.DisableVertexAttribArray(p.yuv.pos)
+
+	t.changed()
+}
+
+// uploadPlane uploads a plane of bpp bytes per pixel, and the given size, to
+// tex, whose size is *texSize, resizing tex if necessary. Its rows start
+// stride bytes apart in pix. It must only be called while holding the mutex
+// for glctx.
+func uploadPlane(glctx gl.Context, tex gl.Texture, texSize *image.Point, format gl.Enum, bpp int, size image.Point, pix []byte, stride int) {
+	glctx.BindTexture(gl.TEXTURE_2D, tex)
+	if *texSize != size {
+		*texSize = size
+		glctx.TexImage2D(gl.TEXTURE_2D, 0, size.X, size.Y, format, gl.UNSIGNED_BYTE, nil)
+		glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+		glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+		glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	}
+
+	// Plane rows, unlike RGBA ones, need not be 4-byte aligned.
+	glctx.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	defer glctx.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+
+	width := size.X * bpp
+	if width == stride {
+		glctx.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, size.X, size.Y, format, gl.UNSIGNED_BYTE, pix[:stride*size.Y])
+		return
+	}
+	if isContext3(glctx) && stride%bpp == 0 {
+		glctx.PixelStorei(gl.UNPACK_ROW_LENGTH, int32(stride/bpp))
+		glctx.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, size.X, size.Y, format, gl.UNSIGNED_BYTE, pix[:stride*(size.Y-1)+width])
+		glctx.PixelStorei(gl.UNPACK_ROW_LENGTH, 0)
+		return
+	}
+	for y, p := 0, 0; y < size.Y; y++ {
+		glctx.TexSubImage2D(gl.TEXTURE_2D, 0, 0, y, size.X, 1, format, gl.UNSIGNED_BYTE, pix[p:p+width])
+		p += stride
+	}
+}
+
+// The YUV vertex shader passes on both the luma texture coordinates, which
+// are the unit square's, and the chroma ones.
+const yuvVertexSrc = `#version 100
+uniform mat3 mvp;
+uniform vec4 chroma;
+attribute vec3 pos;
+varying vec2 lumaUV;
+varying vec2 chromaUV;
+void main() {
+	vec3 p = pos;
+	p.z = 1.0;
+	gl_Position = vec4((mvp * p).xy, 0, 1);
+	lumaUV = pos.xy;
+	chromaUV = chroma.xy * pos.xy + chroma.zw;
+}
+`
+
+// The YUV fragment shader applies the yuv.Matrix. For NV12, the u sampler's
+// luminance and alpha hold the U and V samples.
+const yuvFragmentSrc = `#version 100
+precision mediump float;
+uniform sampler2D y;
+uniform sampler2D u;
+uniform sampler2D v;
+uniform float nv12;
+uniform float yOffset;
+uniform float yScale;
+uniform vec3 uCoef;
+uniform vec3 vCoef;
+varying vec2 lumaUV;
+varying vec2 chromaUV;
+void main() {
+	vec2 c;
+	if (nv12 > 0.5) {
+		c = texture2D(u, chromaUV).ra;
+	} else {
+		c = vec2(texture2D(u, chromaUV).r, texture2D(v, chromaUV).r);
+	}
+	c -= 128.0 / 255.0;
+	float l = yScale * (texture2D(y, lumaUV).r - yOffset);
+	gl_FragColor = vec4(clamp(l + uCoef*c.x + vCoef*c.y, 0.0, 1.0), 1);
+}
+`
@@ -0,0 +1,411 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package headlessdriver provides a driver for accessing a screen that is
+// implemented entirely in software, without a display server. It is intended
+// for exercising widget and rendering code in automated tests.
+//
+// Its Windows are never shown. Instead, each call to a Window's Publish method
+// captures that Window's back buffer, which can then be inspected by calling
+// Frame.
+package headlessdriver // import "golang.org/x/exp/shiny/driver/headlessdriver"
+
+import (
+	"image"
+
+	"golang.org/x/exp/shiny/driver/internal/hotkey"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+)
+
+// Main is called by the program's main function to run the graphical
+// application.
+//
+// It calls f on a new Screen, returned by NewScreen. It returns when f
+// returns.
+func Main(f func(screen.Screen)) {
+	f(NewScreen())
+}
+
+// NewScreen returns a new headless Screen.
+//
+// Each Screen is independent, so that concurrently running tests need not
+// share one.
+func NewScreen() screen.Screen {
+	return &screenImpl{
+		displays: defaultDisplays,
+		windows:  map[*windowImpl]struct{}{},
+	}
+}
+
+// SetDisplays replaces the displays that s reports, as if displays were
+// connected or disconnected, and sends a screen.DisplayEvent to each of s's
+// Windows.
+//
+// s must be a Screen returned by NewScreen, or SetDisplays will panic.
+func SetDisplays(s screen.Screen, displays []screen.Display) {
+	s.(*screenImpl).setDisplays(displays)
+}
+
+// SetColorScheme changes the color scheme that s reports, as if the user had
+// changed their preference, and sends a screen.ColorSchemeEvent to each of
+// s's Windows if it differs from the previous one.
+//
+// s must be a Screen returned by NewScreen, or SetColorScheme will panic.
+func SetColorScheme(s screen.Screen, scheme screen.ColorScheme) {
+	s.(*screenImpl).setColorScheme(scheme)
+}
+
+// Frame returns a copy of the frame most recently published by w, or nil if
+// w has not been published.
+//
+// w must be a Window returned by a headless Screen, or Frame will panic.
+func Frame(w screen.Window) *image.RGBA {
+	return w.(*windowImpl).frame()
+}
+
+// Title returns w's title, as set by NewWindowOptions.Title or
+// Window.SetTitle.
+//
+// w must be a Window returned by a headless Screen, or Title will panic.
+func Title(w screen.Window) string {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.title
+}
+
+// Icon returns a copy of the icon most recently passed to w's SetIcon method,
+// or nil if there is none.
+//
+// w must be a Window returned by a headless Screen, or Icon will panic.
+func Icon(w screen.Window) *image.RGBA {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	if wi.icon == nil {
+		return nil
+	}
+	m := image.NewRGBA(wi.icon.Rect)
+	copy(m.Pix, wi.icon.Pix)
+	return m
+}
+
+// Badge returns w's badge, as set by w's SetBadge method.
+//
+// w must be a Window returned by a headless Screen, or Badge will panic.
+func Badge(w screen.Window) string {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.badge
+}
+
+// Progress returns w's progress, as set by w's SetProgress method, clamped to
+// 1, or -1 if there is none.
+//
+// w must be a Window returned by a headless Screen, or Progress will panic.
+func Progress(w screen.Window) float64 {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.progress
+}
+
+// Cursor returns the cursor most recently passed to w's SetCursor method, and
+// whether it is visible, as set by w's SetCursorVisible method. A custom
+// cursor's Image is a copy of the one passed to SetCursor.
+//
+// w must be a Window returned by a headless Screen, or Cursor will panic.
+func Cursor(w screen.Window) (c screen.Cursor, visible bool) {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.cursor, !wi.cursorHidden
+}
+
+// PointerCaptured returns whether w has captured the pointer, as set by w's
+// SetPointerCapture method.
+//
+// w must be a Window returned by a headless Screen, or PointerCaptured will
+// panic.
+func PointerCaptured(w screen.Window) bool {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.pointerCaptured
+}
+
+// Drag returns the data most recently passed to w's StartDrag method, and
+// whether StartDrag has been called. Drops on w can be simulated by sending
+// it screen.DragEvents.
+//
+// w must be a Window returned by a headless Screen, or Drag will panic.
+func Drag(w screen.Window) (data screen.DragData, ok bool) {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.drag, wi.dragged
+}
+
+// Moved returns whether w's StartMove method has been called.
+//
+// w must be a Window returned by a headless Screen, or Moved will panic.
+func Moved(w screen.Window) bool {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.moved
+}
+
+// Resized returns the edge most recently passed to w's StartResize method,
+// and whether StartResize has been called.
+//
+// w must be a Window returned by a headless Screen, or Resized will panic.
+func Resized(w screen.Window) (edge screen.WindowEdge, ok bool) {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.resizeEdge, wi.resized
+}
+
+// FileDialog returns the options most recently passed to w's ShowFileDialog
+// method, or the zero value for nil options, and whether ShowFileDialog has
+// been called. The user's choice can be simulated by sending w a
+// screen.FileDialogEvent.
+//
+// w must be a Window returned by a headless Screen, or FileDialog will panic.
+func FileDialog(w screen.Window) (opts screen.FileDialogOptions, ok bool) {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.fileDialog, wi.fileDialogShown
+}
+
+// MenuBar returns a copy of the menu bar most recently passed to w's
+// SetMenuBar method, or nil if there is none. Choosing one of its items can
+// be simulated by sending w a screen.MenuEvent.
+//
+// w must be a Window returned by a headless Screen, or MenuBar will panic.
+func MenuBar(w screen.Window) *screen.Menu {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return copyMenu(wi.menuBar)
+}
+
+// ContextMenu returns a copy of the menu, and the point, most recently passed
+// to w's ShowContextMenu method, and whether ShowContextMenu has been called.
+// A nil menu is returned as an empty one. Choosing one of its items can be
+// simulated by sending w a screen.MenuEvent.
+//
+// w must be a Window returned by a headless Screen, or ContextMenu will
+// panic.
+func ContextMenu(w screen.Window) (m *screen.Menu, p image.Point, ok bool) {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return copyMenu(wi.contextMenu), wi.contextMenuPoint, wi.contextMenu != nil
+}
+
+// AccessTree returns a copy of the tree most recently passed to w's
+// SetAccessTree method, or nil if there is none. An assistive technology's
+// action on one of its nodes can be simulated by sending w a
+// screen.AccessActionEvent.
+//
+// w must be a Window returned by a headless Screen, or AccessTree will panic.
+func AccessTree(w screen.Window) *screen.AccessNode {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return copyAccessNode(wi.accessTree)
+}
+
+// State returns w's state, and its fullscreen mode, as set by w's Minimize,
+// Maximize, Restore and SetFullscreen methods.
+//
+// w must be a Window returned by a headless Screen, or State will panic.
+func State(w screen.Window) (screen.WindowState, screen.FullscreenMode) {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.state, wi.fullscreen
+}
+
+// SizeConstraints returns w's size constraints and aspect ratio, as set by w's
+// SetSizeConstraints and SetAspectRatio methods. They are all zero for a new
+// window, and aspect is zero if the aspect ratio is unlocked.
+//
+// w must be a Window returned by a headless Screen, or SizeConstraints will
+// panic.
+func SizeConstraints(w screen.Window) (min, max, aspect image.Point) {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.minSize, wi.maxSize, wi.aspect
+}
+
+// AlwaysOnTop returns whether w is always on top, as set by w's SetAlwaysOnTop
+// method.
+//
+// w must be a Window returned by a headless Screen, or AlwaysOnTop will panic.
+func AlwaysOnTop(w screen.Window) bool {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.alwaysOnTop
+}
+
+// Opacity returns w's opacity, as set by w's SetOpacity method. It is 1 for a
+// new window.
+//
+// w must be a Window returned by a headless Screen, or Opacity will panic.
+func Opacity(w screen.Window) float32 {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.opacity
+}
+
+// Stack returns s's unreleased Windows from bottom to top, as ordered by
+// creation and by their Raise and Lower methods, with those that are always
+// on top above the others.
+//
+// s must be a Screen returned by NewScreen, or Stack will panic.
+func Stack(s screen.Screen) []screen.Window {
+	si := s.(*screenImpl)
+	si.mu.Lock()
+	stack := append([]*windowImpl(nil), si.stack...)
+	si.mu.Unlock()
+
+	var bottom, top []screen.Window
+	for _, w := range stack {
+		if AlwaysOnTop(w) {
+			top = append(top, w)
+		} else {
+			bottom = append(bottom, w)
+		}
+	}
+	return append(bottom, top...)
+}
+
+// RequestClose simulates the user asking to close w, such as by clicking its
+// close button. If w was created with NewWindowOptions.InterceptClose, it
+// sends w a screen.CloseRequestEvent, or else a lifecycle.Event to
+// lifecycle.StageDead.
+//
+// w must be a Window returned by a headless Screen, or RequestClose will
+// panic.
+func RequestClose(w screen.Window) {
+	w.(*windowImpl).requestClose()
+}
+
+// PressHotKey simulates the user pressing the key k with exactly the
+// modifiers mods, and sends a screen.HotKeyEvent to the Window of s that
+// registered them as a hot key, if any. It reports whether there was one.
+//
+// s must be a Screen returned by NewScreen, or PressHotKey will panic.
+func PressHotKey(s screen.Screen, k key.Code, mods key.Modifiers) bool {
+	return s.(*screenImpl).hotKeys.Send(hotkey.Key{Code: k, Modifiers: mods})
+}
+
+// TextInputRect returns the rectangle most recently passed to w's
+// SetTextInputRect method.
+//
+// w must be a Window returned by a headless Screen, or TextInputRect will
+// panic.
+func TextInputRect(w screen.Window) image.Rectangle {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return wi.textInputRect
+}
+
+// Notifications returns copies of the notifications that w posted, with its
+// Notify method, that are still shown, in the order that they were first
+// posted.
+//
+// w must be a Window returned by a headless Screen, or Notifications will
+// panic.
+func Notifications(w screen.Window) []screen.Notification {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	return append([]screen.Notification(nil), wi.notifications...)
+}
+
+// ActivateNotification simulates the user activating w's notification with
+// the given ID, by clicking on the button whose ID is action, or on the
+// notification itself if action is zero. If the notification is shown, it is
+// removed, and a screen.NotificationEvent is sent to w. It returns whether the
+// notification was shown.
+//
+// w must be a Window returned by a headless Screen, or ActivateNotification
+// will panic.
+func ActivateNotification(w screen.Window, id, action int) bool {
+	wi := w.(*windowImpl)
+	wi.mu.Lock()
+	shown := wi.closeNotification(id)
+	wi.mu.Unlock()
+	if shown {
+		wi.Send(screen.NotificationEvent{ID: id, Action: action})
+	}
+	return shown
+}
+
+// TrayIcons returns s's unreleased tray icons, in the order they were
+// created. A click on one of them can be simulated by sending it a
+// screen.TrayEvent.
+//
+// s must be a Screen returned by NewScreen, or TrayIcons will panic.
+func TrayIcons(s screen.Screen) []screen.TrayIcon {
+	si := s.(*screenImpl)
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	trays := make([]screen.TrayIcon, len(si.trays))
+	for i, t := range si.trays {
+		trays[i] = t
+	}
+	return trays
+}
+
+// TrayIconImage returns a copy of the image most recently passed to t's
+// SetIcon method, or nil if there is none.
+//
+// t must be a TrayIcon returned by a headless Screen, or TrayIconImage will
+// panic.
+func TrayIconImage(t screen.TrayIcon) *image.RGBA {
+	ti := t.(*trayImpl)
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	if ti.icon == nil {
+		return nil
+	}
+	m := image.NewRGBA(ti.icon.Rect)
+	copy(m.Pix, ti.icon.Pix)
+	return m
+}
+
+// TrayTooltip returns t's tooltip, as set by its SetTooltip method.
+//
+// t must be a TrayIcon returned by a headless Screen, or TrayTooltip will
+// panic.
+func TrayTooltip(t screen.TrayIcon) string {
+	ti := t.(*trayImpl)
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	return ti.tooltip
+}
+
+// TrayMenu returns a copy of the menu most recently passed to t's SetMenu
+// method, or nil if there is none. Choosing one of its items can be simulated
+// by sending t a screen.MenuEvent.
+//
+// t must be a TrayIcon returned by a headless Screen, or TrayMenu will panic.
+func TrayMenu(t screen.TrayIcon) *screen.Menu {
+	ti := t.(*trayImpl)
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	return copyMenu(ti.menu)
+}
@@ -0,0 +1,226 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package headlessdriver
+
+import (
+	"image"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/hotkey"
+	"golang.org/x/exp/shiny/driver/internal/swtexture"
+	"golang.org/x/exp/shiny/screen"
+)
+
+type screenImpl struct {
+	swtexture.Allocator
+
+	mu                   sync.Mutex
+	defaultWindowOptions *screen.NewWindowOptions
+	displays             []screen.Display
+	colorScheme          screen.ColorScheme
+	windows              map[*windowImpl]struct{}
+	// stack is the unreleased windows, from bottom to top, as ordered by
+	// their Raise and Lower methods.
+	stack []*windowImpl
+
+	// frames are the windows waiting for a screen.FrameEvent.
+	frames frame.Requests
+
+	clipboard clipboardImpl
+
+	// trays are the unreleased tray icons, in the order they were created.
+	trays []*trayImpl
+
+	// hotKeys are the hot keys registered by the windows, which PressHotKey
+	// sends screen.HotKeyEvents to.
+	hotKeys hotkey.Table
+}
+
+func (s *screenImpl) NewTexture(size image.Point) (screen.Texture, error) {
+	return s.NewTextureWithOptions(size, nil)
+}
+
+func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) {
+	s.mu.Lock()
+	opts = opts.WithDefaults(s.defaultWindowOptions)
+	s.mu.Unlock()
+
+	width, height := 1024, 768
+	hidden := false
+	var position image.Point
+	if opts != nil {
+		if opts.Width > 0 {
+			width = opts.Width
+		}
+		if opts.Height > 0 {
+			height = opts.Height
+		}
+		if opts.Position != nil {
+			position = *opts.Position
+		}
+		hidden = opts.Hidden
+		// FixedSize is meaningless without a window manager.
+	}
+
+	w := &windowImpl{
+		s:        s,
+		back:     image.NewRGBA(image.Rect(0, 0, width, height)),
+		title:    opts.GetTitle(),
+		progress: -1,
+		opacity:  1,
+		position: position,
+	}
+	if opts != nil {
+		w.Priority = opts.EventPriority
+		w.KeepAllMoves = opts.KeepAllMoves
+		w.KeyEvents = opts.KeyEvents
+		w.Queues = opts.EventQueues
+		w.interceptClose = opts.InterceptClose
+		w.clip.Linear = opts.LinearBlending
+	}
+
+	s.mu.Lock()
+	s.windows[w] = struct{}{}
+	s.stack = append(s.stack, w)
+	s.mu.Unlock()
+
+	// A headless window is never really on screen, but it is visible, and
+	// focused, unless it is hidden, so that apps that only paint when
+	// visible behave as they would with a real driver.
+	w.lifecycler.SetVisible(!hidden)
+	w.lifecycler.SetFocused(!hidden)
+	w.lifecycler.SendEvent(w, nil)
+
+	w.sendSize(width, height)
+	return w, nil
+}
+
+func (s *screenImpl) SetDefaultWindowOptions(opts *screen.NewWindowOptions) {
+	opts = opts.WithDefaults(nil)
+
+	s.mu.Lock()
+	s.defaultWindowOptions = opts
+	s.mu.Unlock()
+}
+
+// AccessibilityPrefs returns zero. There is no operating system whose
+// preferences to report.
+func (s *screenImpl) AccessibilityPrefs() screen.AccessibilityPrefs {
+	return 0
+}
+
+// ColorScheme returns the color scheme set by SetColorScheme, which is
+// initially screen.LightColorScheme.
+func (s *screenImpl) ColorScheme() screen.ColorScheme {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.colorScheme
+}
+
+func (s *screenImpl) setColorScheme(scheme screen.ColorScheme) {
+	s.mu.Lock()
+	changed := s.colorScheme != scheme
+	s.colorScheme = scheme
+	windows := make([]*windowImpl, 0, len(s.windows))
+	for w := range s.windows {
+		windows = append(windows, w)
+	}
+	s.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	for _, w := range windows {
+		w.Send(screen.ColorSchemeEvent{Scheme: scheme})
+	}
+}
+
+// Displays returns the displays set by SetDisplays. Initially, there is one
+// 1920x1080 display, at 72 DPI, so that there is 1 pixel per point as for the
+// size.Events that headless Windows are sent.
+func (s *screenImpl) Displays() []screen.Display {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]screen.Display(nil), s.displays...)
+}
+
+var defaultDisplays = []screen.Display{{
+	Name:        "headless",
+	Bounds:      image.Rect(0, 0, 1920, 1080),
+	WorkArea:    image.Rect(0, 0, 1920, 1080),
+	DPI:         72,
+	Scale:       1,
+	RefreshRate: 60,
+	Primary:     true,
+}}
+
+// frameInterval returns the time between frames of the primary display.
+func (s *screenImpl) frameInterval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.displays {
+		if d.Primary {
+			return frame.Interval(d.RefreshRate)
+		}
+	}
+	return frame.Interval(0)
+}
+
+func (s *screenImpl) setDisplays(displays []screen.Display) {
+	displays = append([]screen.Display(nil), displays...)
+
+	s.mu.Lock()
+	s.displays = displays
+	windows := make([]*windowImpl, 0, len(s.windows))
+	for w := range s.windows {
+		windows = append(windows, w)
+	}
+	s.mu.Unlock()
+
+	for _, w := range windows {
+		w.Send(screen.DisplayEvent{Displays: append([]screen.Display(nil), displays...)})
+	}
+}
+
+// Clipboard returns an in-memory clipboard, private to this Screen.
+func (s *screenImpl) Clipboard() screen.Clipboard {
+	return &s.clipboard
+}
+
+type clipboardImpl struct {
+	mu   sync.Mutex
+	text string
+}
+
+func (c *clipboardImpl) ReadText() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.text, nil
+}
+
+func (c *clipboardImpl) WriteText(text string) error {
+	c.mu.Lock()
+	c.text = text
+	c.mu.Unlock()
+	return nil
+}
+
+// restack removes w from s.stack and, if insert is non-nil, inserts it again
+// by replacing s.stack with insert's result. It must be called while holding
+// s.mu.
+func (s *screenImpl) restack(w *windowImpl, insert func(stack []*windowImpl) []*windowImpl) {
+	stack := make([]*windowImpl, 0, len(s.stack))
+	for _, v := range s.stack {
+		if v != w {
+			stack = append(stack, v)
+		}
+	}
+	if insert != nil {
+		stack = insert(stack)
+	}
+	s.stack = stack
+}
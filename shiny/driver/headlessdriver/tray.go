@@ -0,0 +1,92 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package headlessdriver
+
+import (
+	"image"
+	"image/draw"
+	"sync"
+
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/screen"
+)
+
+type trayImpl struct {
+	s *screenImpl
+
+	event.Deque
+
+	mu       sync.Mutex
+	released bool
+	icon     *image.RGBA
+	tooltip  string
+	menu     *screen.Menu
+}
+
+func (s *screenImpl) NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	t := &trayImpl{s: s}
+	if opts != nil {
+		t.SetIcon(opts.Icon)
+		t.SetTooltip(opts.Tooltip)
+		t.SetMenu(opts.Menu)
+	}
+
+	s.mu.Lock()
+	s.trays = append(s.trays, t)
+	s.mu.Unlock()
+	return t, nil
+}
+
+func (t *trayImpl) Release() {
+	t.mu.Lock()
+	released := t.released
+	t.released = true
+	t.mu.Unlock()
+	if released {
+		return
+	}
+
+	s := t.s
+	s.mu.Lock()
+	for i, x := range s.trays {
+		if x == t {
+			s.trays = append(s.trays[:i], s.trays[i+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
+}
+
+func (t *trayImpl) SetIcon(m image.Image) {
+	var c *image.RGBA
+	if m != nil {
+		c = image.NewRGBA(m.Bounds())
+		draw.Draw(c, c.Rect, m, c.Rect.Min, draw.Src)
+	}
+	t.mu.Lock()
+	if !t.released {
+		t.icon = c
+	}
+	t.mu.Unlock()
+}
+
+func (t *trayImpl) SetTooltip(tooltip string) {
+	t.mu.Lock()
+	if !t.released {
+		t.tooltip = tooltip
+	}
+	t.mu.Unlock()
+}
+
+func (t *trayImpl) SetMenu(m *screen.Menu) error {
+	m = copyMenu(m)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.released {
+		return errTrayReleased
+	}
+	t.menu = m
+	return nil
+}
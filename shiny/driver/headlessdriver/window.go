@@ -0,0 +1,746 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package headlessdriver
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/capture"
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/hotkey"
+	"golang.org/x/exp/shiny/driver/internal/lifecycler"
+	"golang.org/x/exp/shiny/driver/internal/swtexture"
+	"golang.org/x/exp/shiny/screen"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/paint"
+	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/geom"
+)
+
+type windowImpl struct {
+	s *screenImpl
+
+	event.Deque
+	lifecycler lifecycler.State
+
+	// interceptClose is whether RequestClose sends a CloseRequestEvent, as
+	// set by NewWindowOptions.InterceptClose.
+	interceptClose bool
+
+	// mu guards back, front, clip, title, icon, badge, progress, position,
+	// textInputRect, cursor, cursorHidden, pointerCaptured, drag, dragged,
+	// moved, resizeEdge, resized, fileDialog, fileDialogShown, menuBar, contextMenu, contextMenuPoint,
+	// accessTree, notifications, state, fullscreen, windowedState, minSize,
+	// maxSize, aspect, alwaysOnTop, opacity, colorSpace and released.
+	// If you need to hold both a windowImpl's mu and a swtexture.Texture's
+	// mu, the lock ordering is to lock the windowImpl's first (and unlock it
+	// last).
+	mu sync.Mutex
+	// back is the back buffer, that the Drawer methods draw to. front is the
+	// most recently published frame, or nil if there is none.
+	back  *image.RGBA
+	front *image.RGBA
+	// clip is the clip stack that the Drawer methods draw subject to.
+	clip  drawer.Clip
+	title string
+	// icon is a copy of the icon most recently passed to SetIcon, or nil.
+	// badge and progress are as most recently passed to SetBadge and
+	// SetProgress, with a negative progress meaning none.
+	icon          *image.RGBA
+	badge         string
+	progress      float64
+	position      image.Point
+	textInputRect image.Rectangle
+	cursor        screen.Cursor
+	cursorHidden  bool
+	// pointerCaptured is whether SetPointerCapture has captured the pointer.
+	// There is no mouse, so no RelativeMouseEvents are ever sent.
+	pointerCaptured bool
+	// drag is the data most recently passed to StartDrag, and dragged is
+	// whether StartDrag has been called. There is nowhere to drop it.
+	drag    screen.DragData
+	dragged bool
+	// moved is whether StartMove has been called. resizeEdge is the edge
+	// most recently passed to StartResize, and resized is whether
+	// StartResize has been called. There is no window manager to move or
+	// resize the window.
+	moved      bool
+	resizeEdge screen.WindowEdge
+	resized    bool
+	// fileDialog is the options most recently passed to ShowFileDialog, and
+	// fileDialogShown is whether ShowFileDialog has been called. There is no
+	// user to choose any files.
+	fileDialog      screen.FileDialogOptions
+	fileDialogShown bool
+	// menuBar is a copy of the menu bar most recently passed to SetMenuBar,
+	// and contextMenu and contextMenuPoint are a copy of the menu, and the
+	// point, most recently passed to ShowContextMenu. There is no user to
+	// choose any items.
+	menuBar          *screen.Menu
+	contextMenu      *screen.Menu
+	contextMenuPoint image.Point
+	// accessTree is a copy of the tree most recently passed to
+	// SetAccessTree. There is no assistive technology to read it.
+	accessTree *screen.AccessNode
+	// notifications are copies of the notifications posted by Notify that
+	// are still shown, in the order that they were first posted.
+	notifications []screen.Notification
+	// state and fullscreen are the window's state and fullscreen mode.
+	// windowedState is the state to return to on leaving fullscreen.
+	state         screen.WindowState
+	fullscreen    screen.FullscreenMode
+	windowedState screen.WindowState
+	// minSize, maxSize and aspect are as set by SetSizeConstraints and
+	// SetAspectRatio. The headless driver has no user to resize its windows,
+	// so they are only recorded.
+	minSize, maxSize, aspect image.Point
+	// alwaysOnTop and opacity are as set by SetAlwaysOnTop and SetOpacity.
+	alwaysOnTop bool
+	opacity     float32
+	// colorSpace is the color space set by SetColorSpace.
+	colorSpace screen.ColorSpace
+	released   bool
+
+	imagePool drawer.ImagePool
+	layers    drawer.Layers
+	captures  capture.Hooks
+	timing    frame.Timing
+}
+
+func (w *windowImpl) Release() {
+	w.mu.Lock()
+	released := w.released
+	w.released = true
+	w.mu.Unlock()
+	if released {
+		return
+	}
+
+	w.imagePool.Release()
+	w.layers.Release()
+	w.StopTimers()
+
+	s := w.s
+	s.mu.Lock()
+	delete(s.windows, w)
+	s.restack(w, nil)
+	s.mu.Unlock()
+
+	s.hotKeys.Release(w, func(hotkey.Key) {})
+}
+
+func (w *windowImpl) requestClose() {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return
+	}
+	if w.interceptClose {
+		w.Send(screen.CloseRequestEvent{})
+		return
+	}
+	w.lifecycler.SetDead(true)
+	w.lifecycler.SendEvent(w, nil)
+}
+
+func (w *windowImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	swtexture.Upload(w.back, dp, src, sr)
+}
+
+func (w *windowImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.clip.Fill(w.back, dr, src, op)
+}
+
+// Draw and DrawUniform use nearest neighbor sampling, so that their output is
+// exact, and easy to compare in tests, for transformations that are just
+// translations. Depth testing is not supported, but blend modes and
+// transparency are composited in software.
+
+func (w *windowImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	t := src.(*swtexture.Texture)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	t.Transform(&w.clip, xdraw.NearestNeighbor, w.back, src2dst, sr, op, opts)
+}
+
+func (w *windowImpl) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.clip.Transform(xdraw.NearestNeighbor, w.back, src2dst, image.NewUniform(src), sr, op, opts)
+}
+
+func (w *windowImpl) PushClip(r image.Rectangle) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PushClip(r)
+}
+
+func (w *windowImpl) PushClipPath(src2dst f64.Aff3, p *screen.Path) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PushClipPath(src2dst, p)
+}
+
+func (w *windowImpl) PopClip() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PopClip()
+}
+
+func (w *windowImpl) Copy(dp image.Point, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Copy(w, dp, src, sr, op, opts)
+}
+
+func (w *windowImpl) Scale(dr image.Rectangle, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Scale(w, dr, src, sr, op, opts)
+}
+
+func (w *windowImpl) DrawImage(dp image.Point, src image.Image, op draw.Op, opts *screen.DrawOptions) {
+	w.imagePool.DrawImage(w.s, w, dp, src, op, opts)
+}
+
+func (w *windowImpl) NewLayer(z int, size image.Point) (screen.Layer, error) {
+	return w.layers.NewLayer(w.s, z, size)
+}
+
+func (w *windowImpl) Publish() screen.PublishResult {
+	composited := w.layers.Composite(w)
+
+	w.mu.Lock()
+	if w.released {
+		w.mu.Unlock()
+		return screen.PublishResult{Dropped: true}
+	}
+	if w.front == nil {
+		w.front = image.NewRGBA(w.back.Rect)
+	}
+	copy(w.front.Pix, w.back.Pix)
+	var captured *image.RGBA
+	if w.captures.Active() {
+		captured = capture.Copy(w.front, w.front.Rect)
+	}
+	w.mu.Unlock()
+
+	res := screen.PublishResult{BackBufferPreserved: !composited}
+	w.timing.Presented(&res, time.Now())
+	w.captures.Send(captured)
+	return res
+}
+
+func (w *windowImpl) AddCaptureHook(fn func(f screen.CapturedFrame)) (remove func()) {
+	return w.captures.Add(fn)
+}
+
+func (w *windowImpl) frame() *image.RGBA {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.front == nil {
+		return nil
+	}
+	m := image.NewRGBA(w.front.Rect)
+	copy(m.Pix, w.front.Pix)
+	return m
+}
+
+// NextFrame sends a screen.FrameEvent at the next tick of a clock running at
+// the primary display's refresh rate, as there is no real display to wait
+// for.
+func (w *windowImpl) NextFrame() {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return
+	}
+	if s := w.s; s.frames.Request(w) {
+		go s.frames.Tick(s.frameInterval())
+	}
+}
+
+func (w *windowImpl) RenderFrame(fn func(d screen.Drawer)) error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+
+	fn(w)
+	return nil
+}
+
+var errReleased = errors.New("headlessdriver: window is released")
+var errTrayReleased = errors.New("headlessdriver: tray icon is released")
+
+func (w *windowImpl) GLInfo() screen.GLInfo { return screen.GLInfo{} }
+
+func (w *windowImpl) Begin() *screen.Context { return screen.NewContext(w) }
+
+func (w *windowImpl) SetTitle(title string) {
+	w.mu.Lock()
+	if !w.released {
+		w.title = title
+	}
+	w.mu.Unlock()
+}
+
+func (w *windowImpl) SetIcon(m image.Image) {
+	var c *image.RGBA
+	if m != nil {
+		c = image.NewRGBA(m.Bounds())
+		draw.Draw(c, c.Rect, m, c.Rect.Min, draw.Src)
+	}
+	w.mu.Lock()
+	if !w.released {
+		w.icon = c
+	}
+	w.mu.Unlock()
+}
+
+func (w *windowImpl) SetBadge(label string) {
+	w.mu.Lock()
+	if !w.released {
+		w.badge = label
+	}
+	w.mu.Unlock()
+}
+
+func (w *windowImpl) SetProgress(progress float64) {
+	if progress < 0 {
+		progress = -1
+	} else if progress > 1 {
+		progress = 1
+	}
+	w.mu.Lock()
+	if !w.released {
+		w.progress = progress
+	}
+	w.mu.Unlock()
+}
+
+// SetSize replaces the back buffer with one of the new size, keeping as much
+// of the old contents as fit.
+func (w *windowImpl) SetSize(width, height int) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	w.mu.Lock()
+	if w.released || w.back.Rect.Dx() == width && w.back.Rect.Dy() == height {
+		w.mu.Unlock()
+		return
+	}
+	back := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(back, back.Rect, w.back, image.Point{}, draw.Src)
+	w.back = back
+	w.mu.Unlock()
+
+	w.sendSize(width, height)
+}
+
+func (w *windowImpl) SetPosition(p image.Point) {
+	w.mu.Lock()
+	if !w.released {
+		w.position = p
+	}
+	w.mu.Unlock()
+}
+
+func (w *windowImpl) GetGeometry() (image.Point, image.Point) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return image.Point{}, image.Point{}
+	}
+	return w.position, w.back.Rect.Size()
+}
+
+func (w *windowImpl) SetSizeConstraints(min, max image.Point) {
+	w.mu.Lock()
+	if !w.released {
+		w.minSize, w.maxSize = min, max
+	}
+	w.mu.Unlock()
+}
+
+func (w *windowImpl) SetAspectRatio(aspect image.Point) {
+	if aspect.X <= 0 || aspect.Y <= 0 {
+		aspect = image.Point{}
+	}
+	w.mu.Lock()
+	if !w.released {
+		w.aspect = aspect
+	}
+	w.mu.Unlock()
+}
+
+func (w *windowImpl) SetTextInputRect(r image.Rectangle) {
+	w.mu.Lock()
+	if !w.released {
+		w.textInputRect = r
+	}
+	w.mu.Unlock()
+}
+
+func (w *windowImpl) SetCursor(c screen.Cursor) {
+	if c.HasImage() {
+		m := image.NewRGBA(c.Image.Rect)
+		draw.Draw(m, m.Rect, c.Image, m.Rect.Min, draw.Src)
+		c.Image = m
+	} else {
+		c.Image = nil
+	}
+	w.mu.Lock()
+	if !w.released {
+		w.cursor = c
+	}
+	w.mu.Unlock()
+}
+
+func (w *windowImpl) SetCursorVisible(visible bool) {
+	w.mu.Lock()
+	if !w.released {
+		w.cursorHidden = !visible
+	}
+	w.mu.Unlock()
+}
+
+func (w *windowImpl) SetPointerCapture(capture bool) {
+	w.mu.Lock()
+	if !w.released {
+		w.pointerCaptured = capture
+	}
+	w.mu.Unlock()
+}
+
+func (w *windowImpl) StartDrag(data screen.DragData) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	w.drag, w.dragged = data, true
+	return nil
+}
+
+func (w *windowImpl) StartMove() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	w.moved = true
+	return nil
+}
+
+func (w *windowImpl) StartResize(edge screen.WindowEdge) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	w.resizeEdge, w.resized = edge, true
+	return nil
+}
+
+func (w *windowImpl) ShowFileDialog(opts *screen.FileDialogOptions) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	w.fileDialog, w.fileDialogShown = screen.FileDialogOptions{}, true
+	if opts != nil {
+		w.fileDialog = *opts
+	}
+	return nil
+}
+
+func (w *windowImpl) SetMenuBar(bar *screen.Menu) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	w.menuBar = copyMenu(bar)
+	return nil
+}
+
+func (w *windowImpl) ShowContextMenu(m *screen.Menu, p image.Point) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	w.contextMenu, w.contextMenuPoint = copyMenu(m), p
+	if w.contextMenu == nil {
+		w.contextMenu = &screen.Menu{}
+	}
+	return nil
+}
+
+// copyMenu returns a deep copy of m, or nil if m is nil.
+func copyMenu(m *screen.Menu) *screen.Menu {
+	if m == nil {
+		return nil
+	}
+	c := &screen.Menu{Title: m.Title}
+	if m.Items != nil {
+		c.Items = make([]screen.MenuItem, len(m.Items))
+		for i, item := range m.Items {
+			item.Submenu = copyMenu(item.Submenu)
+			c.Items[i] = item
+		}
+	}
+	return c
+}
+
+func (w *windowImpl) ColorSpace() screen.ColorSpace {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.colorSpace
+}
+
+// SetColorSpace only records cs, as there is no display to convert the
+// window's pixels to.
+func (w *windowImpl) SetColorSpace(cs screen.ColorSpace) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	if cs > screen.ColorSpaceDisplayP3 {
+		return errors.New("headlessdriver: unsupported color space")
+	}
+	w.colorSpace = cs
+	return nil
+}
+
+func (w *windowImpl) SetAccessTree(root *screen.AccessNode) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	w.accessTree = copyAccessNode(root)
+	return nil
+}
+
+// copyAccessNode returns a deep copy of n, or nil if n is nil.
+func copyAccessNode(n *screen.AccessNode) *screen.AccessNode {
+	if n == nil {
+		return nil
+	}
+	c := *n
+	if n.Children != nil {
+		c.Children = make([]*screen.AccessNode, len(n.Children))
+		for i, child := range n.Children {
+			c.Children[i] = copyAccessNode(child)
+		}
+	}
+	return &c
+}
+
+// RegisterHotKey fails only if another window has registered k and mods, as
+// no other application can have.
+func (w *windowImpl) RegisterHotKey(k key.Code, mods key.Modifiers) error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+	return w.s.hotKeys.Register(w, hotkey.Key{Code: k, Modifiers: mods}, func() error { return nil })
+}
+
+func (w *windowImpl) UnregisterHotKey(k key.Code, mods key.Modifiers) {
+	w.s.hotKeys.Unregister(w, hotkey.Key{Code: k, Modifiers: mods}, func() {})
+}
+
+func (w *windowImpl) Notify(n *screen.Notification) error {
+	c := *n
+	if n.Image != nil {
+		m := image.NewRGBA(n.Image.Bounds())
+		draw.Draw(m, m.Rect, n.Image, m.Rect.Min, draw.Src)
+		c.Image = m
+	}
+	c.Actions = append([]screen.NotificationAction(nil), n.Actions...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return errReleased
+	}
+	for i := range w.notifications {
+		if w.notifications[i].ID == n.ID {
+			w.notifications[i] = c
+			return nil
+		}
+	}
+	w.notifications = append(w.notifications, c)
+	return nil
+}
+
+func (w *windowImpl) CloseNotification(id int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closeNotification(id)
+}
+
+// closeNotification removes the notification with the given ID, returning
+// whether it was shown. It must only be called while holding w.mu.
+func (w *windowImpl) closeNotification(id int) bool {
+	for i, n := range w.notifications {
+		if n.ID == id {
+			w.notifications = append(w.notifications[:i], w.notifications[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Minimize, Maximize, Restore and SetFullscreen only change the window's
+// state, not its size, as there is no display for it to fill.
+
+func (w *windowImpl) Minimize() { w.setState(screen.WindowMinimized, screen.FullscreenNone) }
+
+func (w *windowImpl) Maximize() { w.setState(screen.WindowMaximized, screen.FullscreenNone) }
+
+func (w *windowImpl) Restore() { w.setState(screen.WindowNormal, screen.FullscreenNone) }
+
+func (w *windowImpl) SetFullscreen(mode screen.FullscreenMode) {
+	w.setState(screen.WindowFullscreen, mode)
+}
+
+func (w *windowImpl) SetAlwaysOnTop(onTop bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.released {
+		w.alwaysOnTop = onTop
+	}
+}
+
+func (w *windowImpl) SetOpacity(opacity float32) {
+	if !(opacity < 1) {
+		opacity = 1
+	} else if !(opacity > 0) {
+		opacity = 0
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.released {
+		w.opacity = opacity
+	}
+}
+
+func (w *windowImpl) Raise() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return
+	}
+	s := w.s
+	s.mu.Lock()
+	s.restack(w, func(stack []*windowImpl) []*windowImpl { return append(stack, w) })
+	s.mu.Unlock()
+}
+
+func (w *windowImpl) Lower() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return
+	}
+	s := w.s
+	s.mu.Lock()
+	s.restack(w, func(stack []*windowImpl) []*windowImpl { return append([]*windowImpl{w}, stack...) })
+	s.mu.Unlock()
+}
+
+// setState sets the window's state and fullscreen mode, and sends a
+// screen.WindowStateEvent if the state changed. A WindowFullscreen state
+// with a FullscreenNone mode leaves fullscreen, if the window is fullscreen,
+// for the state that it was in before.
+func (w *windowImpl) setState(state screen.WindowState, mode screen.FullscreenMode) {
+	w.mu.Lock()
+	if w.released {
+		w.mu.Unlock()
+		return
+	}
+	old := w.state
+	if state == screen.WindowFullscreen {
+		switch {
+		case mode == screen.FullscreenNone && old == screen.WindowFullscreen:
+			state = w.windowedState
+		case mode == screen.FullscreenNone:
+			state = old
+		case old == screen.WindowMinimized:
+			w.windowedState = screen.WindowNormal
+		case old != screen.WindowFullscreen:
+			w.windowedState = old
+		}
+	}
+	w.state, w.fullscreen = state, mode
+	w.mu.Unlock()
+
+	if state != old {
+		w.Send(screen.WindowStateEvent{State: state})
+	}
+}
+
+// sendSize sends a size.Event, and then the paint.Event that a real driver
+// would send after the window was resized.
+func (w *windowImpl) sendSize(width, height int) {
+	// There is no physical screen, so there is 1 pixel per point.
+	w.Send(size.Event{
+		WidthPx:     width,
+		HeightPx:    height,
+		WidthPt:     geom.Pt(width),
+		HeightPt:    geom.Pt(height),
+		PixelsPerPt: 1,
+	})
+	w.Send(paint.Event{External: true})
+}
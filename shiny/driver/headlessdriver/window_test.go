@@ -0,0 +1,1223 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package headlessdriver
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/lifecycle"
+	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/paint"
+	"golang.org/x/mobile/event/size"
+)
+
+var (
+	red  = color.RGBA{0xff, 0x00, 0x00, 0xff}
+	blue = color.RGBA{0x00, 0x00, 0xff, 0xff}
+)
+
+func TestNewWindowEvents(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 32, Height: 16})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+
+	if e, ok := w.NextEvent().(lifecycle.Event); !ok || e.To != lifecycle.StageFocused {
+		t.Errorf("first event: got %#v, want a lifecycle.Event to StageFocused", e)
+	}
+	if e, ok := w.NextEvent().(screen.FocusEvent); !ok || !e.Focused {
+		t.Errorf("second event: got %#v, want a focused screen.FocusEvent", e)
+	}
+	if e, ok := w.NextEvent().(size.Event); !ok || e.WidthPx != 32 || e.HeightPx != 16 {
+		t.Errorf("third event: got %#v, want a 32x16 size.Event", e)
+	}
+	if _, ok := w.NextEvent().(paint.Event); !ok {
+		t.Errorf("fourth event: want a paint.Event")
+	}
+}
+
+func TestPublish(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 8, Height: 8})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+
+	if m := Frame(w); m != nil {
+		t.Fatalf("Frame before Publish: got %v, want nil", m.Bounds())
+	}
+
+	tex, err := s.NewTexture(image.Point{2, 2})
+	if err != nil {
+		t.Fatalf("NewTexture: %v", err)
+	}
+	defer tex.Release()
+	tex.Fill(tex.Bounds(), red, draw.Src)
+
+	w.Fill(image.Rect(0, 0, 8, 8), blue, draw.Src)
+	w.Copy(image.Point{3, 4}, tex, tex.Bounds(), draw.Over, nil)
+	if res := w.Publish(); !res.BackBufferPreserved {
+		t.Errorf("Publish: got BackBufferPreserved false, want true")
+	}
+
+	m := Frame(w)
+	if m == nil {
+		t.Fatal("Frame after Publish: got nil")
+	}
+	if got, want := m.Bounds(), image.Rect(0, 0, 8, 8); got != want {
+		t.Fatalf("bounds: got %v, want %v", got, want)
+	}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			want := blue
+			if image.Pt(x, y).In(image.Rect(3, 4, 5, 6)) {
+				want = red
+			}
+			if got := m.RGBAAt(x, y); got != want {
+				t.Errorf("pixel (%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+
+	// Drawing after Publish, but before the next Publish, should not change
+	// the published frame.
+	w.Fill(image.Rect(0, 0, 8, 8), red, draw.Src)
+	if got := Frame(w).RGBAAt(0, 0); got != blue {
+		t.Errorf("unpublished draw: got %v, want %v", got, blue)
+	}
+
+	w.Release()
+	w.Fill(image.Rect(0, 0, 8, 8), red, draw.Src)
+	w.Publish()
+	if got := Frame(w).RGBAAt(0, 0); got != blue {
+		t.Errorf("draw after Release: got %v, want %v", got, blue)
+	}
+}
+
+func TestUpload(t *testing.T) {
+	s := NewScreen()
+	buf, err := s.NewBuffer(image.Point{4, 4})
+	if err != nil {
+		t.Fatalf("NewBuffer: %v", err)
+	}
+	defer buf.Release()
+	draw.Draw(buf.RGBA(), buf.Bounds(), image.NewUniform(red), image.Point{}, draw.Src)
+
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 4, Height: 4})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+
+	// The source rectangle is partly outside of the buffer, so only its
+	// bottom-right 2x2 quadrant, in window-space, should be uploaded.
+	w.Upload(image.Point{0, 0}, buf, image.Rect(-2, -2, 2, 2))
+	w.Publish()
+	m := Frame(w)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			want := color.RGBA{}
+			if x >= 2 && y >= 2 {
+				want = red
+			}
+			if got := m.RGBAAt(x, y); got != want {
+				t.Errorf("pixel (%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestSetGeometry(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 8, Height: 8, Title: "before"})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+	for i := 0; i < 4; i++ {
+		w.NextEvent() // The initial lifecycle, focus, size and paint events.
+	}
+
+	w.SetTitle("after")
+	if got, want := Title(w), "after"; got != want {
+		t.Errorf("Title: got %q, want %q", got, want)
+	}
+	w.SetPosition(image.Point{10, 20})
+	w.SetSize(32, 16)
+	if e, ok := w.NextEvent().(size.Event); !ok || e.WidthPx != 32 || e.HeightPx != 16 {
+		t.Errorf("after SetSize: got %#v, want a 32x16 size.Event", e)
+	}
+	pos, sz := w.GetGeometry()
+	if want := (image.Point{10, 20}); pos != want {
+		t.Errorf("GetGeometry position: got %v, want %v", pos, want)
+	}
+	if want := (image.Point{32, 16}); sz != want {
+		t.Errorf("GetGeometry size: got %v, want %v", sz, want)
+	}
+	w.SetTextInputRect(image.Rect(4, 2, 5, 12))
+	if got, want := TextInputRect(w), image.Rect(4, 2, 5, 12); got != want {
+		t.Errorf("TextInputRect: got %v, want %v", got, want)
+	}
+}
+
+func TestSizeConstraints(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+
+	check := func(desc string, wantMin, wantMax, wantAspect image.Point) {
+		t.Helper()
+		min, max, aspect := SizeConstraints(w)
+		if min != wantMin || max != wantMax || aspect != wantAspect {
+			t.Errorf("%s: got %v, %v, %v, want %v, %v, %v",
+				desc, min, max, aspect, wantMin, wantMax, wantAspect)
+		}
+	}
+	check("new window", image.Point{}, image.Point{}, image.Point{})
+	w.SetSizeConstraints(image.Point{320, 240}, image.Point{0, 1080})
+	check("after SetSizeConstraints", image.Point{320, 240}, image.Point{0, 1080}, image.Point{})
+	w.SetAspectRatio(image.Point{16, 9})
+	check("after SetAspectRatio(16:9)", image.Point{320, 240}, image.Point{0, 1080}, image.Point{16, 9})
+	w.SetAspectRatio(image.Point{4, -3})
+	check("after SetAspectRatio(4:-3)", image.Point{320, 240}, image.Point{0, 1080}, image.Point{})
+	w.SetSizeConstraints(image.Point{}, image.Point{})
+	check("after removing the constraints", image.Point{}, image.Point{}, image.Point{})
+
+	w.Release()
+	w.SetSizeConstraints(image.Point{1, 1}, image.Point{2, 2})
+	check("after Release", image.Point{}, image.Point{}, image.Point{})
+}
+
+func TestSetCursor(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+
+	if c, visible := Cursor(w); c.Shape != screen.CursorArrow || c.HasImage() || !visible {
+		t.Errorf("initial Cursor: got %v, %t, want a visible arrow", c, visible)
+	}
+	w.SetCursor(screen.Cursor{Shape: screen.CursorIBeam})
+	w.SetCursorVisible(false)
+	if c, visible := Cursor(w); c.Shape != screen.CursorIBeam || visible {
+		t.Errorf("hidden I-beam: got %v, %t, want a hidden I-beam", c, visible)
+	}
+
+	m := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	m.SetRGBA(1, 1, red)
+	w.SetCursor(screen.Cursor{Image: m, Hotspot: image.Point{1, 1}})
+	w.SetCursorVisible(true)
+	m.SetRGBA(1, 1, blue)
+	c, visible := Cursor(w)
+	if !c.HasImage() || c.Hotspot != (image.Point{1, 1}) || !visible {
+		t.Fatalf("custom cursor: got %v, %t, want a visible image with hotspot (1, 1)", c, visible)
+	}
+	if got := c.Image.RGBAAt(1, 1); got != red {
+		t.Errorf("custom cursor pixel: got %v, want %v, as it was when SetCursor was called", got, red)
+	}
+}
+
+func TestSetPointerCapture(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+
+	if PointerCaptured(w) {
+		t.Error("new window: got captured, want not captured")
+	}
+	w.SetPointerCapture(true)
+	if !PointerCaptured(w) {
+		t.Error("after SetPointerCapture(true): got not captured, want captured")
+	}
+	w.SetPointerCapture(false)
+	if PointerCaptured(w) {
+		t.Error("after SetPointerCapture(false): got captured, want not captured")
+	}
+
+	w.Release()
+	w.SetPointerCapture(true)
+	if PointerCaptured(w) {
+		t.Error("released window: got captured, want not captured")
+	}
+}
+
+func TestStartDrag(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+
+	if _, ok := Drag(w); ok {
+		t.Error("new window: got a drag, want none")
+	}
+	want := screen.DragData{Files: []string{"/tmp/a.txt"}, Text: "a"}
+	if err := w.StartDrag(want); err != nil {
+		t.Fatalf("StartDrag: %v", err)
+	}
+	if got, ok := Drag(w); !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("after StartDrag: got %+v, %t, want %+v, true", got, ok, want)
+	}
+
+	w.Release()
+	if err := w.StartDrag(want); err == nil {
+		t.Error("StartDrag on a released window: got nil error, want non-nil")
+	}
+}
+
+func TestStartMoveResize(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Undecorated: true})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+
+	if Moved(w) {
+		t.Error("new window: got moved, want not")
+	}
+	if _, ok := Resized(w); ok {
+		t.Error("new window: got resized, want not")
+	}
+	if err := w.StartMove(); err != nil {
+		t.Fatalf("StartMove: %v", err)
+	}
+	if !Moved(w) {
+		t.Error("after StartMove: got not moved, want moved")
+	}
+	if err := w.StartResize(screen.WindowEdgeBottomRight); err != nil {
+		t.Fatalf("StartResize: %v", err)
+	}
+	if edge, ok := Resized(w); !ok || edge != screen.WindowEdgeBottomRight {
+		t.Errorf("after StartResize: got %d, %t, want %d, true", edge, ok, screen.WindowEdgeBottomRight)
+	}
+
+	w.Release()
+	if err := w.StartMove(); err == nil {
+		t.Error("StartMove on a released window: got nil error, want non-nil")
+	}
+	if err := w.StartResize(screen.WindowEdgeTop); err == nil {
+		t.Error("StartResize on a released window: got nil error, want non-nil")
+	}
+}
+
+func TestStacking(t *testing.T) {
+	s := NewScreen()
+	var ws [3]screen.Window
+	for i := range ws {
+		w, err := s.NewWindow(nil)
+		if err != nil {
+			t.Fatalf("NewWindow: %v", err)
+		}
+		ws[i] = w
+	}
+	a, b, c := ws[0], ws[1], ws[2]
+
+	check := func(desc string, want ...screen.Window) {
+		t.Helper()
+		if got := Stack(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got stack %v, want %v", desc, got, want)
+		}
+	}
+	check("new windows", a, b, c)
+	a.Raise()
+	check("after raising a", b, c, a)
+	c.Lower()
+	check("after lowering c", c, b, a)
+	b.SetAlwaysOnTop(true)
+	if !AlwaysOnTop(b) {
+		t.Error("after SetAlwaysOnTop(true): got not always on top")
+	}
+	check("with b always on top", c, a, b)
+	a.Raise()
+	check("after raising a below b", c, a, b)
+	b.SetAlwaysOnTop(false)
+	check("with b not always on top", c, b, a)
+	c.Release()
+	check("after releasing c", b, a)
+
+	if got := Opacity(a); got != 1 {
+		t.Errorf("new window: got opacity %g, want 1", got)
+	}
+	for _, tc := range []struct{ opacity, want float32 }{
+		{0.5, 0.5},
+		{-1, 0},
+		{2, 1},
+	} {
+		a.SetOpacity(tc.opacity)
+		if got := Opacity(a); got != tc.want {
+			t.Errorf("SetOpacity(%g): got opacity %g, want %g", tc.opacity, got, tc.want)
+		}
+	}
+}
+
+func TestShowFileDialog(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+
+	if _, ok := FileDialog(w); ok {
+		t.Error("new window: got a file dialog, want none")
+	}
+	want := screen.FileDialogOptions{
+		Kind:     screen.FileDialogSave,
+		ID:       1,
+		FileName: "a.png",
+		Filters:  []screen.FileFilter{{Name: "Images", Patterns: []string{"*.png"}}},
+	}
+	if err := w.ShowFileDialog(&want); err != nil {
+		t.Fatalf("ShowFileDialog: %v", err)
+	}
+	if got, ok := FileDialog(w); !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("after ShowFileDialog: got %+v, %t, want %+v, true", got, ok, want)
+	}
+	if err := w.ShowFileDialog(nil); err != nil {
+		t.Fatalf("ShowFileDialog(nil): %v", err)
+	}
+	if got, ok := FileDialog(w); !ok || !reflect.DeepEqual(got, screen.FileDialogOptions{}) {
+		t.Errorf("after ShowFileDialog(nil): got %+v, %t, want the zero value, true", got, ok)
+	}
+
+	w.Release()
+	if err := w.ShowFileDialog(nil); err == nil {
+		t.Error("ShowFileDialog on a released window: got nil error, want non-nil")
+	}
+}
+
+func TestMenus(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+
+	if got := MenuBar(w); got != nil {
+		t.Errorf("new window: got menu bar %+v, want nil", got)
+	}
+	if _, _, ok := ContextMenu(w); ok {
+		t.Error("new window: got a context menu, want none")
+	}
+
+	bar := &screen.Menu{Items: []screen.MenuItem{{
+		Title: "File",
+		Submenu: &screen.Menu{Items: []screen.MenuItem{
+			{ID: 1, Title: "Save", Key: key.CodeS, Modifiers: key.ModControl},
+			{Separator: true},
+			{ID: 2, Title: "Quit", Disabled: true},
+		}},
+	}}}
+	want := copyMenu(bar)
+	if err := w.SetMenuBar(bar); err != nil {
+		t.Fatalf("SetMenuBar: %v", err)
+	}
+	bar.Items[0].Submenu.Items[0].Checked = true
+	if got := MenuBar(w); !reflect.DeepEqual(got, want) {
+		t.Errorf("after SetMenuBar, and changing its menu: got %+v, want %+v", got, want)
+	}
+	if err := w.SetMenuBar(nil); err != nil {
+		t.Fatalf("SetMenuBar(nil): %v", err)
+	}
+	if got := MenuBar(w); got != nil {
+		t.Errorf("after SetMenuBar(nil): got %+v, want nil", got)
+	}
+
+	m := &screen.Menu{Items: []screen.MenuItem{{ID: 3, Title: "Copy", Checked: true}}}
+	if err := w.ShowContextMenu(m, image.Point{10, 20}); err != nil {
+		t.Fatalf("ShowContextMenu: %v", err)
+	}
+	if got, p, ok := ContextMenu(w); !ok || !reflect.DeepEqual(got, m) || p != (image.Point{10, 20}) {
+		t.Errorf("after ShowContextMenu: got %+v, %v, %t, want %+v, (10,20), true", got, p, ok, m)
+	}
+
+	w.Release()
+	if err := w.SetMenuBar(bar); err == nil {
+		t.Error("SetMenuBar on a released window: got nil error, want non-nil")
+	}
+	if err := w.ShowContextMenu(m, image.Point{}); err == nil {
+		t.Error("ShowContextMenu on a released window: got nil error, want non-nil")
+	}
+}
+
+func TestAccessTree(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+
+	if got := AccessTree(w); got != nil {
+		t.Errorf("new window: got access tree %+v, want nil", got)
+	}
+	root := &screen.AccessNode{
+		ID:     1,
+		Bounds: image.Rect(0, 0, 100, 50),
+		Children: []*screen.AccessNode{
+			{ID: 2, Role: screen.AccessButton, Name: "OK", Bounds: image.Rect(10, 10, 40, 30)},
+			{ID: 3, Role: screen.AccessTextField, Value: "text", Focused: true},
+		},
+	}
+	want := copyAccessNode(root)
+	if err := w.SetAccessTree(root); err != nil {
+		t.Fatalf("SetAccessTree: %v", err)
+	}
+	root.Children[0].Name = "Cancel"
+	if got := AccessTree(w); !reflect.DeepEqual(got, want) {
+		t.Errorf("after SetAccessTree, and changing its nodes: got %+v, want %+v", got, want)
+	}
+	if err := w.SetAccessTree(nil); err != nil {
+		t.Fatalf("SetAccessTree(nil): %v", err)
+	}
+	if got := AccessTree(w); got != nil {
+		t.Errorf("after SetAccessTree(nil): got %+v, want nil", got)
+	}
+
+	w.Release()
+	if err := w.SetAccessTree(root); err == nil {
+		t.Error("SetAccessTree on a released window: got nil error, want non-nil")
+	}
+}
+
+func TestIconBadgeProgress(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+
+	if got := Icon(w); got != nil {
+		t.Errorf("new window: got icon %v, want nil", got.Rect)
+	}
+	if got := Progress(w); got != -1 {
+		t.Errorf("new window: got progress %v, want -1", got)
+	}
+
+	m := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(m, m.Rect, image.NewUniform(red), image.Point{}, draw.Src)
+	w.SetIcon(m)
+	draw.Draw(m, m.Rect, image.NewUniform(blue), image.Point{}, draw.Src)
+	if got := Icon(w); got == nil || got.RGBAAt(8, 8) != red {
+		t.Errorf("after SetIcon, and changing its image: got %v, want a red icon", got)
+	}
+	w.SetIcon(nil)
+	if got := Icon(w); got != nil {
+		t.Errorf("after SetIcon(nil): got icon %v, want nil", got.Rect)
+	}
+
+	w.SetBadge("3")
+	if got := Badge(w); got != "3" {
+		t.Errorf("Badge: got %q, want %q", got, "3")
+	}
+	for _, tc := range []struct {
+		progress, want float64
+	}{
+		{0.5, 0.5},
+		{2, 1},
+		{-0.5, -1},
+	} {
+		w.SetProgress(tc.progress)
+		if got := Progress(w); got != tc.want {
+			t.Errorf("SetProgress(%v): got %v, want %v", tc.progress, got, tc.want)
+		}
+	}
+
+	w.Release()
+	w.SetBadge("")
+	if got := Badge(w); got != "3" {
+		t.Errorf("SetBadge after Release: got %q, want %q", got, "3")
+	}
+}
+
+func TestWindowState(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+	for i := 0; i < 4; i++ {
+		w.NextEvent() // The initial lifecycle, focus, size and paint events.
+	}
+
+	testCases := []struct {
+		desc      string
+		f         func()
+		wantState screen.WindowState
+		wantMode  screen.FullscreenMode
+	}{
+		{"Maximize", w.Maximize, screen.WindowMaximized, screen.FullscreenNone},
+		{"SetFullscreen(FullscreenBorderless)", func() { w.SetFullscreen(screen.FullscreenBorderless) },
+			screen.WindowFullscreen, screen.FullscreenBorderless},
+		{"SetFullscreen(FullscreenExclusive)", func() { w.SetFullscreen(screen.FullscreenExclusive) },
+			screen.WindowFullscreen, screen.FullscreenExclusive},
+		{"SetFullscreen(FullscreenNone)", func() { w.SetFullscreen(screen.FullscreenNone) },
+			screen.WindowMaximized, screen.FullscreenNone},
+		{"Minimize", w.Minimize, screen.WindowMinimized, screen.FullscreenNone},
+		{"Restore", w.Restore, screen.WindowNormal, screen.FullscreenNone},
+	}
+	prev := screen.WindowNormal
+	for _, tc := range testCases {
+		tc.f()
+		if state, mode := State(w); state != tc.wantState || mode != tc.wantMode {
+			t.Errorf("%s: got %v, %v, want %v, %v", tc.desc, state, mode, tc.wantState, tc.wantMode)
+		}
+		if tc.wantState != prev {
+			if e, ok := w.NextEvent().(screen.WindowStateEvent); !ok || e.State != tc.wantState {
+				t.Errorf("%s: got event %#v, want a WindowStateEvent for %v", tc.desc, e, tc.wantState)
+			}
+		}
+		prev = tc.wantState
+	}
+}
+
+func TestRequestClose(t *testing.T) {
+	s := NewScreen()
+	for _, intercept := range []bool{false, true} {
+		w, err := s.NewWindow(&screen.NewWindowOptions{InterceptClose: intercept})
+		if err != nil {
+			t.Fatalf("NewWindow: %v", err)
+		}
+		for i := 0; i < 4; i++ {
+			w.NextEvent() // The initial lifecycle, focus, size and paint events.
+		}
+
+		RequestClose(w)
+		e := w.NextEvent()
+		if intercept {
+			if _, ok := e.(screen.CloseRequestEvent); !ok {
+				t.Errorf("intercept=%t: got %#v, want a CloseRequestEvent", intercept, e)
+			}
+		} else {
+			if e, ok := e.(lifecycle.Event); !ok || e.To != lifecycle.StageDead {
+				t.Errorf("intercept=%t: got %#v, want a lifecycle.Event to StageDead", intercept, e)
+			}
+		}
+		w.Release()
+	}
+}
+
+func TestHotKeys(t *testing.T) {
+	s := NewScreen()
+	a, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	b, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer b.Release()
+	for i := 0; i < 4; i++ {
+		a.NextEvent() // The initial lifecycle, focus, size and paint events.
+	}
+
+	const k, mods = key.CodeF5, key.ModControl | key.ModShift
+	if err := a.RegisterHotKey(k, mods); err != nil {
+		t.Fatalf("RegisterHotKey: %v", err)
+	}
+	if err := a.RegisterHotKey(k, mods); err != nil {
+		t.Errorf("RegisterHotKey again: %v", err)
+	}
+	if err := b.RegisterHotKey(k, mods); err != screen.ErrHotKeyInUse {
+		t.Errorf("RegisterHotKey by another window: got %v, want ErrHotKeyInUse", err)
+	}
+
+	if PressHotKey(s, k, key.ModControl) {
+		t.Errorf("PressHotKey with other modifiers: got true, want false")
+	}
+	if !PressHotKey(s, k, mods) {
+		t.Fatalf("PressHotKey: got false, want true")
+	}
+	want := screen.HotKeyEvent{Key: k, Modifiers: mods}
+	if e := a.NextEvent(); e != want {
+		t.Errorf("got %#v, want %#v", e, want)
+	}
+
+	a.UnregisterHotKey(k, mods)
+	if PressHotKey(s, k, mods) {
+		t.Errorf("PressHotKey after UnregisterHotKey: got true, want false")
+	}
+	if err := a.RegisterHotKey(k, mods); err != nil {
+		t.Fatalf("RegisterHotKey after UnregisterHotKey: %v", err)
+	}
+	a.Release()
+	if err := b.RegisterHotKey(k, mods); err != nil {
+		t.Errorf("RegisterHotKey after Release: %v", err)
+	}
+	if err := a.RegisterHotKey(k, mods); err == nil {
+		t.Errorf("RegisterHotKey on a released window: got nil error")
+	}
+}
+
+func TestTrayIcon(t *testing.T) {
+	s := NewScreen()
+	m := &screen.Menu{Items: []screen.MenuItem{{ID: 1, Title: "Quit"}}}
+	ti, err := s.NewTrayIcon(&screen.NewTrayIconOptions{Tooltip: "Syncing", Menu: m})
+	if err != nil {
+		t.Fatalf("NewTrayIcon: %v", err)
+	}
+	if got := TrayIcons(s); len(got) != 1 || got[0] != ti {
+		t.Errorf("TrayIcons: got %v, want [%v]", got, ti)
+	}
+	if got := TrayTooltip(ti); got != "Syncing" {
+		t.Errorf("TrayTooltip: got %q, want %q", got, "Syncing")
+	}
+	if got := TrayMenu(ti); !reflect.DeepEqual(got, m) {
+		t.Errorf("TrayMenu: got %+v, want %+v", got, m)
+	}
+	if got := TrayIconImage(ti); got != nil {
+		t.Errorf("TrayIconImage: got %v, want nil", got.Rect)
+	}
+
+	icon := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	icon.Set(3, 4, red)
+	ti.SetIcon(icon)
+	icon.Set(3, 4, blue)
+	if got := TrayIconImage(ti); got == nil || got.At(3, 4) != red {
+		t.Errorf("after SetIcon, and changing its image: got %v, want a red pixel", got)
+	}
+	ti.SetTooltip("Idle")
+	if got := TrayTooltip(ti); got != "Idle" {
+		t.Errorf("after SetTooltip: got %q, want %q", got, "Idle")
+	}
+	if err := ti.SetMenu(nil); err != nil {
+		t.Fatalf("SetMenu(nil): %v", err)
+	}
+	if got := TrayMenu(ti); got != nil {
+		t.Errorf("after SetMenu(nil): got %+v, want nil", got)
+	}
+
+	want := screen.TrayEvent{Button: mouse.ButtonLeft}
+	ti.Send(want)
+	if e := ti.NextEvent(); e != want {
+		t.Errorf("NextEvent: got %#v, want %#v", e, want)
+	}
+
+	ti.Release()
+	ti.Release()
+	if got := TrayIcons(s); len(got) != 0 {
+		t.Errorf("after Release: got %d tray icons, want 0", len(got))
+	}
+	if err := ti.SetMenu(m); err == nil {
+		t.Error("SetMenu on a released tray icon: got nil error, want non-nil")
+	}
+}
+
+func TestLayer(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 8, Height: 8})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+
+	tex, err := s.NewTexture(image.Point{1, 2})
+	if err != nil {
+		t.Fatalf("NewTexture: %v", err)
+	}
+	defer tex.Release()
+	tex.Fill(tex.Bounds(), red, draw.Src)
+
+	l, err := w.NewLayer(0, image.Point{2, 2})
+	if err != nil {
+		t.Fatalf("NewLayer: %v", err)
+	}
+	// The layer's left column is drawn from tex, and its right column stays
+	// transparent.
+	l.Copy(image.Point{}, tex, tex.Bounds(), draw.Src, nil)
+	l.Move(image.Point{3, 4})
+
+	check := func(desc string, inLayer color.RGBA, wantPreserved bool) {
+		w.Fill(image.Rect(0, 0, 8, 8), blue, draw.Src)
+		if res := w.Publish(); res.BackBufferPreserved != wantPreserved {
+			t.Errorf("%s: Publish: got BackBufferPreserved %t, want %t", desc, res.BackBufferPreserved, wantPreserved)
+		}
+		m := Frame(w)
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				want := blue
+				if image.Pt(x, y).In(image.Rect(3, 4, 4, 6)) {
+					want = inLayer
+				}
+				if got := m.RGBAAt(x, y); got != want {
+					t.Errorf("%s: (%d, %d): got %v, want %v", desc, x, y, got, want)
+				}
+			}
+		}
+	}
+	check("moved", red, false)
+
+	l.SetTransparency(0xffff)
+	check("transparent", blue, false)
+
+	l.Release()
+	check("released", blue, true)
+}
+
+func TestCaptureHook(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 4, Height: 2})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+
+	// The hooks modify their frames, and draw to the window, which must
+	// neither change the other hook's frame nor deadlock.
+	var got1, got2 []screen.CapturedFrame
+	remove1 := w.AddCaptureHook(func(f screen.CapturedFrame) {
+		got1 = append(got1, f)
+		f.Image.SetRGBA(0, 0, color.RGBA{})
+		w.Fill(image.Rect(0, 0, 4, 2), red, draw.Src)
+	})
+	w.AddCaptureHook(func(f screen.CapturedFrame) {
+		got2 = append(got2, f)
+	})
+
+	w.Fill(image.Rect(0, 0, 4, 2), blue, draw.Src)
+	w.Publish()
+	if len(got1) != 1 || len(got2) != 1 {
+		t.Fatalf("first Publish: got %d and %d frames, want 1 and 1", len(got1), len(got2))
+	}
+	if got1[0].Image == got2[0].Image {
+		t.Error("first Publish: the hooks were passed the same image")
+	}
+	if got1[0].Time.IsZero() {
+		t.Error("first Publish: zero Time")
+	}
+	m := got2[0].Image
+	if got, want := m.Bounds(), image.Rect(0, 0, 4, 2); got != want {
+		t.Fatalf("first Publish: bounds: got %v, want %v", got, want)
+	}
+	if got := m.RGBAAt(0, 0); got != blue {
+		t.Errorf("first Publish: pixel (0, 0): got %v, want %v", got, blue)
+	}
+
+	remove1()
+	remove1()
+	w.Publish()
+	if len(got1) != 1 || len(got2) != 2 {
+		t.Fatalf("second Publish: got %d and %d frames, want 1 and 2", len(got1), len(got2))
+	}
+	if got := got2[1].Image.RGBAAt(3, 1); got != red {
+		t.Errorf("second Publish: pixel (3, 1): got %v, want %v", got, red)
+	}
+
+	w.Release()
+	w.Publish()
+	if len(got2) != 2 {
+		t.Errorf("Publish after Release: got %d frames, want 2", len(got2))
+	}
+}
+
+func TestDownload(t *testing.T) {
+	s := NewScreen()
+	tex, err := s.NewTexture(image.Point{4, 4})
+	if err != nil {
+		t.Fatalf("NewTexture: %v", err)
+	}
+	tex.Fill(tex.Bounds(), blue, draw.Src)
+	tex.Fill(image.Rect(2, 2, 4, 4), red, draw.Src)
+
+	m, err := tex.Download(image.Point{10, 20}, image.Rect(1, 1, 8, 3))
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if got, want := m.Bounds(), image.Rect(10, 20, 13, 22); got != want {
+		t.Fatalf("bounds: got %v, want %v", got, want)
+	}
+	for y := 20; y < 22; y++ {
+		for x := 10; x < 13; x++ {
+			want := blue
+			if x >= 11 && y >= 21 {
+				want = red
+			}
+			if got := m.RGBAAt(x, y); got != want {
+				t.Errorf("(%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+
+	tex.Release()
+	if _, err := tex.Download(image.Point{}, tex.Bounds()); err == nil {
+		t.Errorf("Download after Release: got nil error, want non-nil")
+	}
+}
+
+func TestUploadYUV(t *testing.T) {
+	s := NewScreen()
+	tex, err := s.NewTexture(image.Point{4, 2})
+	if err != nil {
+		t.Fatalf("NewTexture: %v", err)
+	}
+	defer tex.Release()
+	tex.Fill(tex.Bounds(), blue, draw.Src)
+
+	// A 2x2 image of video range white, uploaded to the texture's right half.
+	src := &screen.YUVImage{
+		Format:   screen.YUVI420,
+		Y:        []byte{235, 235, 235, 235},
+		U:        []byte{128},
+		V:        []byte{128},
+		YStride:  2,
+		UVStride: 1,
+		Rect:     image.Rect(0, 0, 2, 2),
+	}
+	tex.(screen.YUVUploader).UploadYUV(image.Point{2, 0}, src, src.Bounds())
+
+	m, err := tex.Download(image.Point{}, tex.Bounds())
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			want := blue
+			if x >= 2 {
+				want = color.RGBA{0xff, 0xff, 0xff, 0xff}
+			}
+			if got := m.RGBAAt(x, y); got != want {
+				t.Errorf("(%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestTextureFormats(t *testing.T) {
+	s := NewScreen()
+	testCases := []struct {
+		format screen.PixelFormat
+		want   color.RGBA
+	}{
+		{screen.PixelFormatRGBA8, color.RGBA{0x20, 0x40, 0x60, 0x80}},
+		{screen.PixelFormatBGRA8, color.RGBA{0x20, 0x40, 0x60, 0x80}},
+		// An Alpha8 texture keeps only the alpha, and draws as white.
+		{screen.PixelFormatAlpha8, color.RGBA{0x80, 0x80, 0x80, 0x80}},
+		// A Gray8 texture is opaque. Luma is computed from the
+		// alpha-premultiplied channels.
+		{screen.PixelFormatGray8, color.RGBA{0x3a, 0x3a, 0x3a, 0xff}},
+		{screen.PixelFormatRGBA64, color.RGBA{0x20, 0x40, 0x60, 0x80}},
+	}
+	for _, tc := range testCases {
+		tex, err := s.NewTextureWithOptions(image.Point{2, 1}, &screen.NewTextureOptions{Format: tc.format})
+		if err != nil {
+			t.Fatalf("format %d: NewTextureWithOptions: %v", tc.format, err)
+		}
+		if tc.format != screen.PixelFormatBGRA8 && tex.Format() != tc.format {
+			t.Errorf("format %d: Format: got %d", tc.format, tex.Format())
+		}
+
+		// Upload the same color, once from a Buffer and once as BGRA Pixels.
+		b, err := s.NewBuffer(image.Point{1, 1})
+		if err != nil {
+			t.Fatalf("NewBuffer: %v", err)
+		}
+		b.RGBA().SetRGBA(0, 0, color.RGBA{0x20, 0x40, 0x60, 0x80})
+		tex.Upload(image.Point{}, b, b.Bounds())
+		b.Release()
+		p := &screen.Pixels{
+			Format: screen.PixelFormatBGRA8,
+			Pix:    []byte{0x60, 0x40, 0x20, 0x80},
+			Stride: 4,
+			Rect:   image.Rect(0, 0, 1, 1),
+		}
+		tex.(screen.PixelUploader).UploadPixels(image.Point{1, 0}, p, p.Rect)
+
+		m, err := tex.Download(image.Point{}, tex.Bounds())
+		if err != nil {
+			t.Fatalf("format %d: Download: %v", tc.format, err)
+		}
+		for x := 0; x < 2; x++ {
+			if got := m.RGBAAt(x, 0); got != tc.want {
+				t.Errorf("format %d: (%d, 0): got %v, want %v", tc.format, x, got, tc.want)
+			}
+		}
+		tex.Release()
+	}
+	if got := s.TextureMemoryEstimate(); got != 0 {
+		t.Errorf("TextureMemoryEstimate after Release: got %d, want 0", got)
+	}
+}
+
+func TestTextureWrap(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 8, Height: 8})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+
+	testCases := []struct {
+		wrap screen.TextureWrap
+		want string
+	}{
+		{screen.WrapClamp, "rb......"},
+		{screen.WrapRepeat, "rbrbrb.."},
+		{screen.WrapMirror, "rbbrrb.."},
+	}
+	for _, tc := range testCases {
+		tex, err := s.NewTextureWithOptions(image.Point{2, 1}, &screen.NewTextureOptions{Wrap: tc.wrap})
+		if err != nil {
+			t.Fatalf("wrap %d: NewTextureWithOptions: %v", tc.wrap, err)
+		}
+		tex.Fill(image.Rect(0, 0, 1, 1), red, draw.Src)
+		tex.Fill(image.Rect(1, 0, 2, 1), blue, draw.Src)
+
+		w.Fill(image.Rect(0, 0, 8, 8), color.Transparent, draw.Src)
+		w.Copy(image.Point{}, tex, image.Rect(0, 0, 6, 1), draw.Src, nil)
+		w.Publish()
+		tex.Release()
+
+		m := Frame(w)
+		for x, c := range tc.want {
+			want := map[rune]color.RGBA{'r': red, 'b': blue, '.': {}}[c]
+			if got := m.RGBAAt(x, 0); got != want {
+				t.Errorf("wrap %d: (%d, 0): got %v, want %v", tc.wrap, x, got, want)
+			}
+		}
+	}
+}
+
+func TestDisplays(t *testing.T) {
+	s := NewScreen()
+	if d := s.Displays(); len(d) != 1 || !d[0].Primary {
+		t.Fatalf("initial Displays: got %v, want one primary display", d)
+	}
+
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 8, Height: 8})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+	w.NextEvent() // The lifecycle.Event.
+	w.NextEvent() // The screen.FocusEvent.
+	w.NextEvent() // The size.Event.
+	w.NextEvent() // The paint.Event.
+
+	displays := []screen.Display{
+		{Name: "a", Bounds: image.Rect(0, 0, 800, 600), Scale: 1, Primary: true},
+		{Name: "b", Bounds: image.Rect(800, 0, 2400, 1200), Scale: 2},
+	}
+	SetDisplays(s, displays)
+	displays[0].Name = "changed after SetDisplays"
+
+	e, ok := w.NextEvent().(screen.DisplayEvent)
+	if !ok {
+		t.Fatalf("event after SetDisplays: want a screen.DisplayEvent")
+	}
+	for _, got := range [][]screen.Display{e.Displays, s.Displays()} {
+		if len(got) != 2 || got[0].Name != "a" || got[1].Name != "b" {
+			t.Errorf("got %v, want displays a and b", got)
+		}
+	}
+}
+
+func TestSetColorScheme(t *testing.T) {
+	s := NewScreen()
+	if got := s.ColorScheme(); got != screen.LightColorScheme {
+		t.Fatalf("initial ColorScheme: got %v, want light", got)
+	}
+
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 8, Height: 8})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+	w.NextEvent() // The lifecycle.Event.
+	w.NextEvent() // The screen.FocusEvent.
+	w.NextEvent() // The size.Event.
+	w.NextEvent() // The paint.Event.
+
+	SetColorScheme(s, screen.DarkColorScheme)
+	if e, ok := w.NextEvent().(screen.ColorSchemeEvent); !ok || e.Scheme != screen.DarkColorScheme {
+		t.Fatalf("event after SetColorScheme: got %#v, want a dark screen.ColorSchemeEvent", e)
+	}
+	if got := s.ColorScheme(); got != screen.DarkColorScheme {
+		t.Errorf("ColorScheme: got %v, want dark", got)
+	}
+
+	// Setting the same scheme again sends no event.
+	SetColorScheme(s, screen.DarkColorScheme)
+	w.Send("sentinel")
+	if e := w.NextEvent(); e != "sentinel" {
+		t.Errorf("event after an unchanged SetColorScheme: got %#v, want the sentinel", e)
+	}
+}
+
+func TestNextFrame(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 8, Height: 8})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+	w.NextEvent() // The lifecycle.Event.
+	w.NextEvent() // The screen.FocusEvent.
+	w.NextEvent() // The size.Event.
+	w.NextEvent() // The paint.Event.
+
+	for i := 0; i < 2; i++ {
+		// Repeated calls before the FrameEvent are coalesced.
+		w.NextFrame()
+		w.NextFrame()
+		if e, ok := w.NextEvent().(screen.FrameEvent); !ok || e.Time.IsZero() {
+			t.Fatalf("frame %d: got %#v, want a screen.FrameEvent with a time", i, e)
+		}
+	}
+	// The next event is not another FrameEvent.
+	w.Send("sentinel")
+	if e := w.NextEvent(); e != "sentinel" {
+		t.Errorf("after the last frame: got %#v, want the sentinel", e)
+	}
+}
+
+func TestPublishTiming(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 8, Height: 8})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+
+	r0 := w.Publish()
+	if r0.PresentTime.IsZero() || r0.Interval != 0 || r0.Dropped {
+		t.Errorf("first Publish: got %v, %v, %t, want a time, 0, false", r0.PresentTime, r0.Interval, r0.Dropped)
+	}
+	r1 := w.Publish()
+	if want := r1.PresentTime.Sub(r0.PresentTime); r1.Interval != want || r1.Dropped {
+		t.Errorf("second Publish: got %v, %t, want %v, false", r1.Interval, r1.Dropped, want)
+	}
+
+	w.Release()
+	if r := w.Publish(); !r.Dropped || !r.PresentTime.IsZero() {
+		t.Errorf("Publish after Release: got %v, %t, want the zero time, true", r.PresentTime, r.Dropped)
+	}
+}
+
+func TestLinearBlending(t *testing.T) {
+	s := NewScreen()
+	halfWhite := color.RGBA{0x80, 0x80, 0x80, 0x80}
+	for _, linear := range []bool{false, true} {
+		w, err := s.NewWindow(&screen.NewWindowOptions{Width: 2, Height: 2, LinearBlending: linear})
+		if err != nil {
+			t.Fatalf("NewWindow: %v", err)
+		}
+		w.Fill(image.Rect(0, 0, 2, 2), color.Black, draw.Src)
+		w.Fill(image.Rect(0, 0, 2, 2), halfWhite, draw.Over)
+		w.Publish()
+
+		// Half of white, in linear light, encodes to a lighter gray than
+		// half of its sRGB-encoded value.
+		want := uint8(0x80)
+		if linear {
+			want = 0xbc
+		}
+		got := Frame(w).RGBAAt(1, 1)
+		if d := int(got.R) - int(want); d < -1 || d > +1 {
+			t.Errorf("linear=%t: got %v, want gray %#02x", linear, got, want)
+		}
+		w.Release()
+	}
+}
+
+func TestSetColorSpace(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	if got := w.ColorSpace(); got != screen.ColorSpaceSRGB {
+		t.Errorf("initial ColorSpace: got %v, want %v", got, screen.ColorSpaceSRGB)
+	}
+	if err := w.SetColorSpace(screen.ColorSpaceDisplayP3); err != nil {
+		t.Fatalf("SetColorSpace: %v", err)
+	}
+	if got := w.ColorSpace(); got != screen.ColorSpaceDisplayP3 {
+		t.Errorf("ColorSpace: got %v, want %v", got, screen.ColorSpaceDisplayP3)
+	}
+	w.Release()
+	if err := w.SetColorSpace(screen.ColorSpaceSRGB); err == nil {
+		t.Errorf("SetColorSpace after Release: got nil error")
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	s := NewScreen()
+	w, err := s.NewWindow(nil)
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+	for i := 0; i < 4; i++ {
+		w.NextEvent() // The initial lifecycle, focus, size and paint events.
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 2, red)
+	n := &screen.Notification{
+		ID:      1,
+		Title:   "Download",
+		Body:    "In progress",
+		Image:   img,
+		Actions: []screen.NotificationAction{{ID: 7, Title: "Cancel"}},
+	}
+	if err := w.Notify(n); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	img.Set(1, 2, blue)
+	n.Actions[0].Title = "Stop"
+	if err := w.Notify(&screen.Notification{ID: 2, Title: "Upload"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	got := Notifications(w)
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 2 {
+		t.Fatalf("Notifications: got %+v, want IDs 1 and 2", got)
+	}
+	if got[0].Image.At(1, 2) != red || got[0].Actions[0].Title != "Cancel" {
+		t.Errorf("after changing the notification: got %+v, want a copy", got[0])
+	}
+
+	// Posting a notification with the same ID replaces it, in place.
+	if err := w.Notify(&screen.Notification{ID: 1, Title: "Download", Body: "Done"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if got := Notifications(w); len(got) != 2 || got[0].Body != "Done" {
+		t.Errorf("after replacing: got %+v, want the first Body to be Done", got)
+	}
+
+	w.CloseNotification(2)
+	w.CloseNotification(3)
+	if got := Notifications(w); len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("after CloseNotification: got %+v, want ID 1", got)
+	}
+
+	if ActivateNotification(w, 2, 0) {
+		t.Error("ActivateNotification of a closed notification: got true, want false")
+	}
+	if !ActivateNotification(w, 1, 7) {
+		t.Fatal("ActivateNotification: got false, want true")
+	}
+	want := screen.NotificationEvent{ID: 1, Action: 7}
+	if e := w.NextEvent(); e != want {
+		t.Errorf("NextEvent: got %#v, want %#v", e, want)
+	}
+	if got := Notifications(w); len(got) != 0 {
+		t.Errorf("after ActivateNotification: got %+v, want none", got)
+	}
+}
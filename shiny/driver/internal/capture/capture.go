@@ -0,0 +1,98 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package capture calls a window's frame capture hooks, as added by the
+// screen.Window interface's AddCaptureHook method.
+package capture // import "golang.org/x/exp/shiny/driver/internal/capture"
+
+import (
+	"image"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Hooks is a window's capture hooks. The zero value has no hooks.
+//
+// A driver's Publish method checks Active, while the frame is at hand, and
+// only if there are hooks reads the frame back, so that windows that are not
+// captured pay nothing for it. It then calls Send, without holding any of
+// the window's locks, as the hooks may call the window's methods.
+type Hooks struct {
+	mu    sync.Mutex
+	hooks []*hook
+}
+
+type hook struct {
+	fn func(f screen.CapturedFrame)
+}
+
+// Add implements the screen.Window interface's AddCaptureHook method.
+func (h *Hooks) Add(fn func(f screen.CapturedFrame)) (remove func()) {
+	p := &hook{fn}
+	h.mu.Lock()
+	h.hooks = append(h.hooks[:len(h.hooks):len(h.hooks)], p)
+	h.mu.Unlock()
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for i, x := range h.hooks {
+			if x == p {
+				hooks := make([]*hook, 0, len(h.hooks)-1)
+				hooks = append(hooks, h.hooks[:i]...)
+				h.hooks = append(hooks, h.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Active reports whether there are any hooks.
+func (h *Hooks) Active() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.hooks) != 0
+}
+
+// Send calls the hooks, in the order they were added, with the frame m,
+// published now. The first hook is passed m itself, and the others copies
+// of it, so that each can keep its frame. Send does nothing for a nil m,
+// such as when there were no hooks to read the frame back for.
+func (h *Hooks) Send(m *image.RGBA) {
+	if m == nil {
+		return
+	}
+	t := time.Now()
+	h.mu.Lock()
+	hooks := h.hooks
+	h.mu.Unlock()
+
+	// The copies are made before calling any hook, which may modify its
+	// frame.
+	frames := make([]*image.RGBA, len(hooks))
+	for i := range frames {
+		if i == 0 {
+			frames[i] = m
+		} else {
+			frames[i] = Copy(m, m.Rect)
+		}
+	}
+	for i, p := range hooks {
+		p.fn(screen.CapturedFrame{Image: frames[i], Time: t})
+	}
+}
+
+// Copy returns a copy of the r part of src, with r.Min translated to the
+// origin.
+func Copy(src *image.RGBA, r image.Rectangle) *image.RGBA {
+	r = r.Intersect(src.Rect)
+	m := image.NewRGBA(image.Rectangle{Max: r.Size()})
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := src.PixOffset(r.Min.X, y)
+		copy(m.Pix[m.PixOffset(0, y-r.Min.Y):], src.Pix[i:i+4*r.Dx()])
+	}
+	return m
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package capture
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestHooks(t *testing.T) {
+	var h Hooks
+	if h.Active() {
+		t.Fatal("zero Hooks: Active")
+	}
+
+	var order []int
+	var frames []*image.RGBA
+	var pixels []color.RGBA
+	add := func(i int) func() {
+		return h.Add(func(f screen.CapturedFrame) {
+			order = append(order, i)
+			frames = append(frames, f.Image)
+			pixels = append(pixels, f.Image.RGBAAt(0, 0))
+			// Modifying the frame must not modify the other hooks' frames.
+			f.Image.SetRGBA(0, 0, color.RGBA{0, 0, 0, 0xff})
+		})
+	}
+	remove0 := add(0)
+	add(1)
+	add(2)
+	if !h.Active() {
+		t.Fatal("three hooks: not Active")
+	}
+
+	m := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	m.SetRGBA(0, 0, color.RGBA{0xff, 0, 0, 0xff})
+	h.Send(m)
+	if got, want := order, []int{0, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("order: got %v, want %v", got, want)
+	}
+	if frames[0] != m {
+		t.Error("first hook: not passed m")
+	}
+	for i := 1; i < len(frames); i++ {
+		if frames[i] == m {
+			t.Errorf("hook %d: passed m, want a copy", i)
+		}
+		if got, want := pixels[i], (color.RGBA{0xff, 0, 0, 0xff}); got != want {
+			t.Errorf("hook %d: pixel (0, 0): got %v, want %v", i, got, want)
+		}
+	}
+
+	remove0()
+	remove0()
+	order = nil
+	h.Send(m)
+	h.Send(nil)
+	if got, want := order, []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after remove: order: got %v, want %v", got, want)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	src.SetRGBA(2, 3, red)
+
+	m := Copy(src, image.Rect(1, 2, 3, 8))
+	if got, want := m.Bounds(), image.Rect(0, 0, 2, 2); got != want {
+		t.Fatalf("bounds: got %v, want %v", got, want)
+	}
+	if got := m.RGBAAt(1, 1); got != red {
+		t.Errorf("pixel (1, 1): got %v, want %v", got, red)
+	}
+}
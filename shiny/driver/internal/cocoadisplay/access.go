@@ -0,0 +1,141 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+#include <stdint.h>
+#include <stdlib.h>
+
+// accessNode is a screen.AccessNode. Its numChildren children, and their
+// descendants, follow it.
+typedef struct accessNode {
+	int id;
+	int role;
+	char* name;
+	char* value;
+	int x0, y0, x1, y1;
+	int focused;
+	int disabled;
+	int numChildren;
+} accessNode;
+
+void setAccessTree(uintptr_t viewID, accessNode* nodes, int n);
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// accessSenders are the windows that a view's NSAccessibilityElements send
+// their screen.AccessActionEvents to.
+var accessSenders = struct {
+	mu sync.Mutex
+	m  map[uintptr]frame.Sender
+}{
+	m: map[uintptr]frame.Sender{},
+}
+
+// SetAccessTree replaces the NSAccessibilityElements of view, an NSView,
+// with those of the tree whose root is root, or removes them if root is nil.
+// The actions of VoiceOver on them send screen.AccessActionEvents to w.
+//
+// SetAccessTree must not be called on the main thread, which it waits for.
+func SetAccessTree(view uintptr, w frame.Sender, root *screen.AccessNode) {
+	accessSenders.mu.Lock()
+	if root == nil {
+		delete(accessSenders.m, view)
+	} else {
+		accessSenders.m[view] = w
+	}
+	accessSenders.mu.Unlock()
+
+	var f accessFlattener
+	if root != nil {
+		f.flatten(root)
+	}
+	defer f.free()
+	C.setAccessTree(C.uintptr_t(view), f.ptr(), C.int(len(f.nodes)))
+}
+
+// ReleaseAccess forgets the window of view, an NSView, once it is closed.
+func ReleaseAccess(view uintptr) {
+	accessSenders.mu.Lock()
+	delete(accessSenders.m, view)
+	accessSenders.mu.Unlock()
+}
+
+// cocoadisplayAccessAction is called, on the main thread, when VoiceOver
+// performs an action on the element, of view, with the given ID.
+//
+//export cocoadisplayAccessAction
+func cocoadisplayAccessAction(view C.uintptr_t, id, action C.int) {
+	accessSenders.mu.Lock()
+	w := accessSenders.m[uintptr(view)]
+	accessSenders.mu.Unlock()
+
+	if w != nil {
+		w.Send(screen.AccessActionEvent{ID: int(id), Action: screen.AccessAction(action)})
+	}
+}
+
+// accessFlattener flattens a tree of screen.AccessNodes into accessNodes, in
+// C memory, in depth-first order.
+type accessFlattener struct {
+	nodes []C.accessNode
+	strs  []*C.char
+}
+
+func (f *accessFlattener) flatten(n *screen.AccessNode) {
+	c := C.accessNode{
+		id:          C.int(n.ID),
+		role:        C.int(n.Role),
+		name:        f.cString(n.Name),
+		value:       f.cString(n.Value),
+		x0:          C.int(n.Bounds.Min.X),
+		y0:          C.int(n.Bounds.Min.Y),
+		x1:          C.int(n.Bounds.Max.X),
+		y1:          C.int(n.Bounds.Max.Y),
+		numChildren: C.int(len(n.Children)),
+	}
+	if n.Focused {
+		c.focused = 1
+	}
+	if n.Disabled {
+		c.disabled = 1
+	}
+	f.nodes = append(f.nodes, c)
+	for _, child := range n.Children {
+		f.flatten(child)
+	}
+}
+
+func (f *accessFlattener) cString(s string) *C.char {
+	c := C.CString(s)
+	f.strs = append(f.strs, c)
+	return c
+}
+
+func (f *accessFlattener) ptr() *C.accessNode {
+	if len(f.nodes) == 0 {
+		return nil
+	}
+	return &f.nodes[0]
+}
+
+func (f *accessFlattener) free() {
+	for _, c := range f.strs {
+		C.free(unsafe.Pointer(c))
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#import <Cocoa/Cocoa.h>
+#import <objc/runtime.h>
+#include "_cgo_export.h"
+
+// The actions, as for screen.AccessAction.
+enum {
+	actionPress = 0,
+	actionFocus = 1,
+};
+
+// roleOf returns the NSAccessibilityRole of the screen.AccessRole.
+static NSAccessibilityRole roleOf(int role) {
+	switch (role) {
+	case 1:
+		return NSAccessibilityStaticTextRole;
+	case 2:
+		return NSAccessibilityButtonRole;
+	case 3:
+		return NSAccessibilityCheckBoxRole;
+	case 4:
+		return NSAccessibilityTextFieldRole;
+	case 5:
+		return NSAccessibilityTextAreaRole;
+	case 6:
+		return NSAccessibilityImageRole;
+	case 7:
+		return NSAccessibilityListRole;
+	case 8:
+		return NSAccessibilityCellRole;
+	case 9:
+		return NSAccessibilityTableRole;
+	case 10:
+		return NSAccessibilityColumnRole;
+	case 11:
+		return NSAccessibilityCellRole;
+	case 12:
+		return NSAccessibilityScrollAreaRole;
+	case 13:
+		return NSAccessibilitySliderRole;
+	}
+	return NSAccessibilityGroupRole;
+}
+
+// ShinyAccessElement is an element of a view's tree of screen.AccessNodes,
+// which tells Go of VoiceOver's actions on it.
+@interface ShinyAccessElement : NSAccessibilityElement
+@property uintptr_t viewID;
+@property int nodeID;
+// focused is whether the node has the keyboard focus, as Go says.
+@property BOOL focused;
+@end
+
+@implementation ShinyAccessElement
+- (BOOL)accessibilityPerformPress {
+	cocoadisplayAccessAction(self.viewID, self.nodeID, actionPress);
+	return YES;
+}
+
+- (BOOL)isAccessibilityFocused {
+	return self.focused;
+}
+
+// setAccessibilityFocused asks Go for the focus, which it gives the node,
+// or not, in its next tree.
+- (void)setAccessibilityFocused:(BOOL)focused {
+	if (focused) {
+		cocoadisplayAccessAction(self.viewID, self.nodeID, actionFocus);
+	}
+}
+@end
+
+// elementsKey is the key of the NSMutableDictionary, from node IDs to
+// ShinyAccessElements, associated with each view, so that an element whose
+// node is in the next tree is kept.
+static char elementsKey;
+
+// focusKey is the key of the focused ShinyAccessElement associated with each
+// view, if any.
+static char focusKey;
+
+// newElement returns the element of the node at nodes[*i], whose
+// descendants follow it, in elems, reusing those of old. The element's frame
+// is relative to parentFrame, in the view's points. It advances *i past the
+// node and its descendants, and sets *focus to the focused element, if any.
+static ShinyAccessElement* newElement(NSView* view, id parent, NSRect parentFrame, accessNode* nodes, int* i,
+	NSDictionary* old, NSMutableDictionary* elems, ShinyAccessElement** focus) {
+
+	accessNode* n = &nodes[(*i)++];
+	NSNumber* key = [NSNumber numberWithInt:n->id];
+	ShinyAccessElement* e = [old objectForKey:key];
+	if (e == nil) {
+		e = [[[ShinyAccessElement alloc] init] autorelease];
+		e.viewID = (uintptr_t)view;
+		e.nodeID = n->id;
+	}
+	[elems setObject:e forKey:key];
+
+	// Convert from pixels with a top left origin to the view's points.
+	double scale = view.window != nil ? view.window.backingScaleFactor : 1;
+	NSRect frame = NSMakeRect(n->x0 / scale, n->y0 / scale, (n->x1 - n->x0) / scale, (n->y1 - n->y0) / scale);
+	if (!view.isFlipped) {
+		frame.origin.y = view.bounds.size.height - NSMaxY(frame);
+	}
+
+	e.accessibilityParent = parent;
+	e.accessibilityRole = roleOf(n->role);
+	e.accessibilityLabel = [NSString stringWithUTF8String:n->name];
+	e.accessibilityValue = [NSString stringWithUTF8String:n->value];
+	e.accessibilityEnabled = !n->disabled;
+	e.accessibilityFrameInParentSpace = NSOffsetRect(frame, -parentFrame.origin.x, -parentFrame.origin.y);
+	e.focused = n->focused != 0;
+	if (e.focused) {
+		*focus = e;
+	}
+
+	NSMutableArray* children = [NSMutableArray arrayWithCapacity:n->numChildren];
+	for (int j = 0; j < n->numChildren; j++) {
+		[children addObject:newElement(view, e, frame, nodes, i, old, elems, focus)];
+	}
+	e.accessibilityChildren = children;
+	return e;
+}
+
+void setAccessTree(uintptr_t viewID, accessNode* nodes, int n) {
+	NSView* view = (NSView*)viewID;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		NSDictionary* old = objc_getAssociatedObject(view, &elementsKey);
+		NSMutableDictionary* elems = [NSMutableDictionary dictionary];
+		ShinyAccessElement* focus = nil;
+		if (n > 0) {
+			int i = 0;
+			ShinyAccessElement* root = newElement(view, view, NSZeroRect, nodes, &i, old, elems, &focus);
+			view.accessibilityChildren = @[root];
+		} else {
+			view.accessibilityChildren = nil;
+		}
+
+		ShinyAccessElement* prevFocus = objc_getAssociatedObject(view, &focusKey);
+		objc_setAssociatedObject(view, &elementsKey, elems, OBJC_ASSOCIATION_RETAIN);
+		objc_setAssociatedObject(view, &focusKey, focus, OBJC_ASSOCIATION_RETAIN);
+		if (focus != nil && focus != prevFocus) {
+			NSAccessibilityPostNotification(focus, NSAccessibilityFocusedUIElementChangedNotification);
+		}
+	});
+}
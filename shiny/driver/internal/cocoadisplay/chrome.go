@@ -0,0 +1,72 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+#include <stdint.h>
+
+int startWindowMove(uintptr_t viewID);
+int startWindowResize(uintptr_t viewID, int edges);
+*/
+import "C"
+
+import (
+	"errors"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// The edges of a window that startWindowResize moves, as a bit mask.
+const (
+	edgeTop    = 1 << 0
+	edgeBottom = 1 << 1
+	edgeLeft   = 1 << 2
+	edgeRight  = 1 << 3
+)
+
+// resizeEdges are the edges moved by resizing a window by a screen.WindowEdge.
+var resizeEdges = [...]C.int{
+	screen.WindowEdgeTop:         edgeTop,
+	screen.WindowEdgeBottom:      edgeBottom,
+	screen.WindowEdgeLeft:        edgeLeft,
+	screen.WindowEdgeRight:       edgeRight,
+	screen.WindowEdgeTopLeft:     edgeTop | edgeLeft,
+	screen.WindowEdgeTopRight:    edgeTop | edgeRight,
+	screen.WindowEdgeBottomLeft:  edgeBottom | edgeLeft,
+	screen.WindowEdgeBottomRight: edgeBottom | edgeRight,
+}
+
+var errNoMouseButton = errors.New("cocoadisplay: the left mouse button is not held down")
+
+// StartMove starts moving the window of view, an NSView, with the window
+// server's performWindowDragWithEvent, as if the user had dragged its title
+// bar.
+//
+// StartMove must not be called on the main thread, which it waits for.
+func StartMove(view uintptr) error {
+	if C.startWindowMove(C.uintptr_t(view)) == 0 {
+		return errNoMouseButton
+	}
+	return nil
+}
+
+// StartResize starts resizing the window of view, an NSView, by edge. AppKit
+// has no way to start a resize, so the window follows the pointer in a
+// tracking loop, on the main thread, until the left mouse button is released.
+// StartResize does not wait for that loop.
+func StartResize(view uintptr, edge screen.WindowEdge) error {
+	if int(edge) >= len(resizeEdges) {
+		return errors.New("cocoadisplay: StartResize with invalid edge")
+	}
+	if C.startWindowResize(C.uintptr_t(view), resizeEdges[edge]) == 0 {
+		return errNoMouseButton
+	}
+	return nil
+}
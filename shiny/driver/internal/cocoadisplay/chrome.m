@@ -0,0 +1,95 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#import <Cocoa/Cocoa.h>
+#include <stdint.h>
+
+// The edges of a window that startWindowResize moves, as for chrome.go.
+enum {
+	edgeTop    = 1 << 0,
+	edgeBottom = 1 << 1,
+	edgeLeft   = 1 << 2,
+	edgeRight  = 1 << 3,
+};
+
+int startWindowMove(uintptr_t viewID) {
+	NSView* view = (NSView*)viewID;
+	if (([NSEvent pressedMouseButtons] & 1) == 0) {
+		return 0;
+	}
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		if (window == nil) {
+			return;
+		}
+		// As for a drag, the move starts from the mouse event being
+		// handled, if any, or else from an equivalent event.
+		NSEvent* e = [NSApp currentEvent];
+		if (e == nil || e.window != window || (e.type != NSEventTypeLeftMouseDown && e.type != NSEventTypeLeftMouseDragged)) {
+			e = [NSEvent mouseEventWithType:NSEventTypeLeftMouseDown
+				location:[window mouseLocationOutsideOfEventStream]
+				modifierFlags:0
+				timestamp:[[NSProcessInfo processInfo] systemUptime]
+				windowNumber:window.windowNumber
+				context:nil
+				eventNumber:0
+				clickCount:1
+				pressure:1];
+		}
+		[window performWindowDragWithEvent:e];
+	});
+	return 1;
+}
+
+int startWindowResize(uintptr_t viewID, int edges) {
+	NSView* view = (NSView*)viewID;
+	if (([NSEvent pressedMouseButtons] & 1) == 0) {
+		return 0;
+	}
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		if (window == nil) {
+			return;
+		}
+		NSRect start = window.frame;
+		NSSize minSize = window.minSize, maxSize = window.maxSize;
+		// Screen co-ordinates have the y-axis pointing upwards, so the top
+		// edge is at the start's maximum y.
+		NSPoint p0 = [NSEvent mouseLocation];
+		for (;;) {
+			NSEvent* e = [window nextEventMatchingMask:NSEventMaskLeftMouseDragged | NSEventMaskLeftMouseUp];
+			if (e.type == NSEventTypeLeftMouseUp) {
+				break;
+			}
+			NSPoint p = [NSEvent mouseLocation];
+			CGFloat dx = p.x - p0.x, dy = p.y - p0.y;
+			NSRect r = start;
+			if (edges & edgeLeft) {
+				r.size.width -= dx;
+			} else if (edges & edgeRight) {
+				r.size.width += dx;
+			}
+			if (edges & edgeTop) {
+				r.size.height += dy;
+			} else if (edges & edgeBottom) {
+				r.size.height -= dy;
+			}
+			r.size.width = MIN(MAX(r.size.width, minSize.width), maxSize.width);
+			r.size.height = MIN(MAX(r.size.height, minSize.height), maxSize.height);
+			// Moving the left or bottom edge keeps the opposite edge in
+			// place.
+			if (edges & edgeLeft) {
+				r.origin.x = NSMaxX(start) - r.size.width;
+			}
+			if (edges & edgeBottom) {
+				r.origin.y = NSMaxY(start) - r.size.height;
+			}
+			[window setFrame:r display:YES];
+		}
+	});
+	return 1;
+}
@@ -0,0 +1,132 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+// Package cocoadisplay enumerates the displays, or NSScreens, of a Cocoa
+// application, paces frames to their vertical blanks, makes NSCursors,
+// transfers dragged data, sets the dock tile and converts scrolls, for the
+// drivers that use Cocoa.
+package cocoadisplay // import "golang.org/x/exp/shiny/driver/internal/cocoadisplay"
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+#import <Cocoa/Cocoa.h>
+
+typedef struct cocoaDisplay {
+	int x, y, width, height;
+	int workX, workY, workWidth, workHeight;
+	double dpi, scale, refreshRate;
+	int p3;
+	char name[128];
+} cocoaDisplay;
+
+static void getDisplays(cocoaDisplay* ds, int n, int* count) {
+	NSArray<NSScreen *> *screens = [NSScreen screens];
+	// As for the drivers' window positions, convert from points with a
+	// bottom left origin to pixels with a top left origin, relative to the
+	// main screen.
+	NSScreen *main = [NSScreen mainScreen];
+	double mainScale = [main backingScaleFactor];
+	double mainHeight = main.frame.size.height;
+
+	*count = 0;
+	for (NSScreen *screen in screens) {
+		if (*count == n) {
+			break;
+		}
+		cocoaDisplay* d = &ds[(*count)++];
+		NSRect f = screen.frame;
+		d->x = f.origin.x * mainScale;
+		d->y = (mainHeight - (f.origin.y + f.size.height)) * mainScale;
+		d->width = f.size.width * mainScale;
+		d->height = f.size.height * mainScale;
+		NSRect v = screen.visibleFrame;
+		d->workX = v.origin.x * mainScale;
+		d->workY = (mainHeight - (v.origin.y + v.size.height)) * mainScale;
+		d->workWidth = v.size.width * mainScale;
+		d->workHeight = v.size.height * mainScale;
+		d->scale = [screen backingScaleFactor];
+		d->p3 = 0;
+		if (@available(macOS 10.12, *)) {
+			d->p3 = [screen canRepresentDisplayGamut:NSDisplayGamutP3];
+		}
+
+		CGDirectDisplayID id = [[screen.deviceDescription objectForKey:@"NSScreenNumber"] unsignedIntValue];
+		CGSize mm = CGDisplayScreenSize(id);
+		CGDisplayModeRef mode = CGDisplayCopyDisplayMode(id);
+		d->dpi = 0;
+		d->refreshRate = 0;
+		if (mode != NULL) {
+			if (mm.width > 0) {
+				d->dpi = CGDisplayModeGetPixelWidth(mode) * 25.4 / mm.width;
+			}
+			d->refreshRate = CGDisplayModeGetRefreshRate(mode);
+			CGDisplayModeRelease(mode);
+		}
+
+		d->name[0] = 0;
+		if ([screen respondsToSelector:@selector(localizedName)]) {
+			strlcpy(d->name, [[screen localizedName] UTF8String], sizeof(d->name));
+		}
+	}
+}
+
+static int displays(cocoaDisplay* ds, int n) {
+	__block int count = 0;
+	if ([NSThread isMainThread]) {
+		getDisplays(ds, n, &count);
+	} else {
+		dispatch_sync(dispatch_get_main_queue(), ^{
+			getDisplays(ds, n, &count);
+		});
+	}
+	return count;
+}
+*/
+import "C"
+
+import (
+	"image"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// maxDisplays is more displays than a Mac can drive.
+const maxDisplays = 32
+
+// Displays returns the NSScreens, in the order that Cocoa lists them, which
+// starts with the primary display: the one with the menu bar.
+//
+// Like the drivers' window positions, Bounds and WorkArea are in pixels,
+// relative to the top left of the screen containing the key window, at that
+// screen's scale.
+//
+// Cocoa's screens are queried on the main thread so, when called from
+// another thread, Displays waits for the main thread's run loop.
+func Displays() []screen.Display {
+	var ds [maxDisplays]C.cocoaDisplay
+	n := int(C.displays(&ds[0], maxDisplays))
+	displays := make([]screen.Display, n)
+	for i := range displays {
+		d := &ds[i]
+		cs := screen.ColorSpaceSRGB
+		if d.p3 != 0 {
+			cs = screen.ColorSpaceDisplayP3
+		}
+		displays[i] = screen.Display{
+			Name:        C.GoString(&d.name[0]),
+			Bounds:      image.Rect(int(d.x), int(d.y), int(d.x+d.width), int(d.y+d.height)),
+			WorkArea:    image.Rect(int(d.workX), int(d.workY), int(d.workX+d.workWidth), int(d.workY+d.workHeight)),
+			DPI:         float64(d.dpi),
+			Scale:       float64(d.scale),
+			RefreshRate: float64(d.refreshRate),
+			Primary:     i == 0,
+			ColorSpace:  cs,
+		}
+	}
+	return displays
+}
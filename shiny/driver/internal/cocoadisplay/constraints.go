@@ -0,0 +1,38 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+#include <stdint.h>
+
+void setContentSizeLimits(uintptr_t viewID, int minWidth, int minHeight, int maxWidth, int maxHeight);
+void setContentAspectRatio(uintptr_t viewID, int x, int y);
+*/
+import "C"
+
+import (
+	"image"
+)
+
+// SetSizeConstraints sets the contentMinSize and contentMaxSize of the window
+// of view, an NSView, from sizes in pixels. A zero width or height is
+// unconstrained.
+func SetSizeConstraints(view uintptr, min, max image.Point) {
+	C.setContentSizeLimits(C.uintptr_t(view), C.int(min.X), C.int(min.Y), C.int(max.X), C.int(max.Y))
+}
+
+// SetAspectRatio sets the contentAspectRatio of the window of view, an
+// NSView, or clears it if aspect is zero or negative.
+func SetAspectRatio(view uintptr, aspect image.Point) {
+	if aspect.X <= 0 || aspect.Y <= 0 {
+		aspect = image.Point{}
+	}
+	C.setContentAspectRatio(C.uintptr_t(view), C.int(aspect.X), C.int(aspect.Y))
+}
@@ -0,0 +1,36 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#import <Cocoa/Cocoa.h>
+#include <float.h>
+#include <stdint.h>
+
+void setContentSizeLimits(uintptr_t viewID, int minWidth, int minHeight, int maxWidth, int maxHeight) {
+	NSView* view = (NSView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		// The limits are in points, of which the window's screen may have
+		// more than one pixel.
+		CGFloat scale = window.backingScaleFactor;
+		window.contentMinSize = NSMakeSize(minWidth / scale, minHeight / scale);
+		window.contentMaxSize = NSMakeSize(
+			maxWidth > 0 ? maxWidth / scale : FLT_MAX,
+			maxHeight > 0 ? maxHeight / scale : FLT_MAX);
+	});
+}
+
+void setContentAspectRatio(uintptr_t viewID, int x, int y) {
+	NSView* view = (NSView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (x > 0 && y > 0) {
+			view.window.contentAspectRatio = NSMakeSize(x, y);
+		} else {
+			// Setting the resize increments clears the aspect ratio.
+			view.window.contentResizeIncrements = NSMakeSize(1, 1);
+		}
+	});
+}
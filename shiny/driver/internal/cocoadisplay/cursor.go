@@ -0,0 +1,109 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+#import <Cocoa/Cocoa.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+// newShapeCursor returns the NSCursor class's cursor with the given selector,
+// or the arrow if there is no such cursor, as private selectors may be
+// removed by later versions of macOS.
+static uintptr_t newShapeCursor(char* name) {
+	SEL sel = NSSelectorFromString([NSString stringWithUTF8String:name]);
+	NSCursor* c = [NSCursor arrowCursor];
+	if ([NSCursor respondsToSelector:sel]) {
+		c = [NSCursor performSelector:sel];
+	}
+	return (uintptr_t)[c retain];
+}
+
+// newImageCursor returns a cursor showing the premultiplied RGBA pixels, sized
+// in points for the backing scale factor of view's window.
+static uintptr_t newImageCursor(uintptr_t viewID, void* pix, int width, int height, int hotX, int hotY) {
+	NSView* view = (NSView*)viewID;
+	__block NSCursor* c = nil;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		double scale = [NSScreen mainScreen].backingScaleFactor;
+		if (view.window != nil) {
+			scale = view.window.backingScaleFactor;
+		}
+		NSBitmapImageRep* rep = [[NSBitmapImageRep alloc]
+			initWithBitmapDataPlanes:NULL
+			pixelsWide:width
+			pixelsHigh:height
+			bitsPerSample:8
+			samplesPerPixel:4
+			hasAlpha:YES
+			isPlanar:NO
+			colorSpaceName:NSDeviceRGBColorSpace
+			bytesPerRow:4*width
+			bitsPerPixel:32];
+		memcpy([rep bitmapData], pix, 4*width*height);
+		NSImage* img = [[NSImage alloc] initWithSize:NSMakeSize(width/scale, height/scale)];
+		[img addRepresentation:rep];
+		c = [[NSCursor alloc] initWithImage:img hotSpot:NSMakePoint(hotX/scale, hotY/scale)];
+		[img release];
+		[rep release];
+	});
+	return (uintptr_t)c;
+}
+*/
+import "C"
+
+import (
+	"image"
+	"image/draw"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// cursorSelectors are the NSCursor class methods returning the
+// screen.CursorShapes. Those starting with an underscore are private, but
+// have long been used by browsers and toolkits, for want of public
+// equivalents.
+var cursorSelectors = [...]string{
+	screen.CursorArrow:      "arrowCursor",
+	screen.CursorIBeam:      "IBeamCursor",
+	screen.CursorCrosshair:  "crosshairCursor",
+	screen.CursorHand:       "pointingHandCursor",
+	screen.CursorResizeNS:   "resizeUpDownCursor",
+	screen.CursorResizeEW:   "resizeLeftRightCursor",
+	screen.CursorResizeNWSE: "_windowResizeNorthWestSouthEastCursor",
+	screen.CursorResizeNESW: "_windowResizeNorthEastSouthWestCursor",
+	screen.CursorResizeAll:  "_moveCursor",
+	screen.CursorNotAllowed: "operationNotAllowedCursor",
+	screen.CursorWait:       "busyButClickableCursor",
+}
+
+// NewCursor returns an NSCursor, as a uintptr, showing c. An image cursor is
+// sized for the backing scale factor of the window of view, an NSView. The
+// caller owns the returned reference.
+//
+// NewCursor must not be called on the main thread, which it waits for.
+func NewCursor(view uintptr, c screen.Cursor) uintptr {
+	if c.HasImage() {
+		// Copy the image, so that its rows are contiguous.
+		m := image.NewRGBA(c.Image.Rect)
+		draw.Draw(m, m.Rect, c.Image, m.Rect.Min, draw.Src)
+		return uintptr(C.newImageCursor(C.uintptr_t(view), unsafe.Pointer(&m.Pix[0]),
+			C.int(m.Rect.Dx()), C.int(m.Rect.Dy()), C.int(c.Hotspot.X), C.int(c.Hotspot.Y)))
+	}
+	shape := c.Shape
+	if shape < 0 || int(shape) >= len(cursorSelectors) {
+		shape = screen.CursorArrow
+	}
+	name := C.CString(cursorSelectors[shape])
+	defer C.free(unsafe.Pointer(name))
+	return uintptr(C.newShapeCursor(name))
+}
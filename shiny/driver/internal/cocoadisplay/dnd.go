@@ -0,0 +1,295 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa -framework CoreServices
+
+#import <Cocoa/Cocoa.h>
+#import <CoreServices/CoreServices.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+static NSString* const uriListMIME = @"text/uri-list";
+static NSString* const textMIME = @"text/plain;charset=utf-8";
+
+static void registerDragTypes(uintptr_t viewID) {
+	NSView* view = (NSView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[view registerForDraggedTypes:@[
+			NSPasteboardTypeFileURL,
+			NSPasteboardTypeString,
+			NSPasteboardTypeHTML,
+			NSPasteboardTypeRTF,
+			NSPasteboardTypePDF,
+			NSPasteboardTypePNG,
+			NSPasteboardTypeTIFF,
+			@"public.jpeg",
+		]];
+	});
+}
+
+// mimeType returns the MIME type of the pasteboard type, a Uniform Type
+// Identifier, or nil if it has none.
+static NSString* mimeType(NSString* type) {
+	if ([type isEqualToString:NSPasteboardTypeFileURL]) {
+		return uriListMIME;
+	}
+	if ([type isEqualToString:NSPasteboardTypeString]) {
+		return textMIME;
+	}
+	return [(NSString*)UTTypeCopyPreferredTagWithClass((CFStringRef)type, kUTTagClassMIMEType) autorelease];
+}
+
+// pasteboardType is the inverse of mimeType.
+static NSString* pasteboardType(NSString* mime) {
+	if ([mime isEqualToString:uriListMIME]) {
+		return NSPasteboardTypeFileURL;
+	}
+	if ([mime isEqualToString:textMIME]) {
+		return NSPasteboardTypeString;
+	}
+	return [(NSString*)UTTypeCreatePreferredIdentifierForTag(kUTTagClassMIMEType, (CFStringRef)mime, NULL) autorelease];
+}
+
+// joinNUL returns the strings, each followed by a NUL, in a buffer that the
+// caller must free, of *n bytes.
+static char* joinNUL(NSArray<NSString*>* strs, int* n) {
+	*n = 0;
+	for (NSString* s in strs) {
+		*n += strlen([s UTF8String]) + 1;
+	}
+	char* buf = malloc(*n);
+	char* p = buf;
+	for (NSString* s in strs) {
+		const char* u = [s UTF8String];
+		size_t len = strlen(u) + 1;
+		memcpy(p, u, len);
+		p += len;
+	}
+	return buf;
+}
+
+static char* dragTypes(uintptr_t pbID, int* n) {
+	NSPasteboard* pb = (NSPasteboard*)pbID;
+	NSMutableArray<NSString*>* types = [NSMutableArray array];
+	for (NSString* type in [pb types]) {
+		NSString* mime = mimeType(type);
+		if (mime != nil && ![types containsObject:mime]) {
+			[types addObject:mime];
+		}
+	}
+	return joinNUL(types, n);
+}
+
+static char* dragFiles(uintptr_t pbID, int* n) {
+	NSPasteboard* pb = (NSPasteboard*)pbID;
+	NSArray<NSURL*>* urls = [pb readObjectsForClasses:@[[NSURL class]]
+		options:@{NSPasteboardURLReadingFileURLsOnlyKey: @YES}];
+	NSMutableArray<NSString*>* paths = [NSMutableArray array];
+	for (NSURL* url in urls) {
+		[paths addObject:[url path]];
+	}
+	return joinNUL(paths, n);
+}
+
+static void* dragDataForType(uintptr_t pbID, char* mime, int* n) {
+	NSPasteboard* pb = (NSPasteboard*)pbID;
+	NSString* type = pasteboardType([NSString stringWithUTF8String:mime]);
+	NSData* data = type != nil ? [pb dataForType:type] : nil;
+	if (data == nil) {
+		return NULL;
+	}
+	*n = [data length];
+	void* p = malloc(*n > 0 ? *n : 1);
+	memcpy(p, [data bytes], *n);
+	return p;
+}
+
+static uintptr_t newDragItems() {
+	return (uintptr_t)[[NSMutableArray alloc] init];
+}
+
+static void addDragFile(uintptr_t itemsID, char* path) {
+	@autoreleasepool {
+		NSMutableArray* items = (NSMutableArray*)itemsID;
+		[items addObject:[NSURL fileURLWithPath:[NSString stringWithUTF8String:path]]];
+	}
+}
+
+static uintptr_t newPasteboardItem() {
+	return (uintptr_t)[[NSPasteboardItem alloc] init];
+}
+
+static void setPasteboardData(uintptr_t itemID, char* mime, void* data, int n) {
+	@autoreleasepool {
+		NSPasteboardItem* item = (NSPasteboardItem*)itemID;
+		NSString* type = pasteboardType([NSString stringWithUTF8String:mime]);
+		if (type != nil) {
+			[item setData:[NSData dataWithBytes:data length:n] forType:type];
+		}
+	}
+}
+
+static void addDragItem(uintptr_t itemsID, uintptr_t itemID) {
+	NSMutableArray* items = (NSMutableArray*)itemsID;
+	NSPasteboardItem* item = (NSPasteboardItem*)itemID;
+	[items addObject:item];
+	[item release];
+}
+
+// ShinyDragSource is the source of every drag, which offers its data to be
+// copied.
+@interface ShinyDragSource : NSObject<NSDraggingSource>
+@end
+
+@implementation ShinyDragSource
+- (NSDragOperation)draggingSession:(NSDraggingSession*)session sourceOperationMaskForDraggingContext:(NSDraggingContext)context {
+	return NSDragOperationCopy;
+}
+@end
+
+// beginDrag starts dragging the pasteboard writers, and releases them.
+static int beginDrag(uintptr_t viewID, uintptr_t itemsID) {
+	NSView* view = (NSView*)viewID;
+	NSMutableArray* writers = (NSMutableArray*)itemsID;
+	__block int ok = 0;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		static ShinyDragSource* source;
+		if (source == nil) {
+			source = [[ShinyDragSource alloc] init];
+		}
+
+		NSWindow* window = view.window;
+		if (window == nil || writers.count == 0) {
+			return;
+		}
+		// The drag starts from the mouse event being handled, if any, or
+		// else from an equivalent event at the pointer's position.
+		NSPoint p = [window mouseLocationOutsideOfEventStream];
+		NSEvent* e = [NSApp currentEvent];
+		if (e == nil || e.window != window || (e.type != NSEventTypeLeftMouseDown && e.type != NSEventTypeLeftMouseDragged)) {
+			e = [NSEvent mouseEventWithType:NSEventTypeLeftMouseDown
+				location:p
+				modifierFlags:0
+				timestamp:[[NSProcessInfo processInfo] systemUptime]
+				windowNumber:window.windowNumber
+				context:nil
+				eventNumber:0
+				clickCount:1
+				pressure:1];
+		}
+
+		NSPoint local = [view convertPoint:p fromView:nil];
+		NSMutableArray<NSDraggingItem*>* items = [NSMutableArray array];
+		for (id<NSPasteboardWriting> w in writers) {
+			NSDraggingItem* item = [[NSDraggingItem alloc] initWithPasteboardWriter:w];
+			[item setDraggingFrame:NSMakeRect(local.x, local.y, 1, 1) contents:nil];
+			[items addObject:item];
+			[item release];
+		}
+		[view beginDraggingSessionWithItems:items event:e source:source];
+		ok = 1;
+	});
+	[writers release];
+	return ok;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/dnd"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// RegisterDragTypes makes view, an NSView, accept drops of files, text and
+// common types of images and documents. Cocoa only offers a view the drags
+// of the types that it registered for.
+func RegisterDragTypes(view uintptr) {
+	C.registerDragTypes(C.uintptr_t(view))
+}
+
+// DragTypes returns the MIME types of the data on pb, the NSPasteboard of a
+// drag, in the order that the drag source offered them. Each pasteboard type
+// is a Uniform Type Identifier, and those without a MIME type are skipped.
+//
+// DragTypes must be called on the main thread, as are the NSDraggingDestination
+// methods.
+func DragTypes(pb uintptr) []string {
+	var n C.int
+	return splitNUL(C.dragTypes(C.uintptr_t(pb), &n), n)
+}
+
+// DragData returns the dropped data on pb, the NSPasteboard of a drag. Like
+// DragTypes, it must be called on the main thread.
+func DragData(pb uintptr) screen.DragData {
+	data := map[string][]byte{}
+	var files []string
+	for _, typ := range dnd.Wanted(DragTypes(pb)) {
+		if typ == dnd.URIList {
+			var n C.int
+			files = splitNUL(C.dragFiles(C.uintptr_t(pb), &n), n)
+			continue
+		}
+		ctyp := C.CString(typ)
+		var n C.int
+		if p := C.dragDataForType(C.uintptr_t(pb), ctyp, &n); p != nil {
+			data[typ] = C.GoBytes(p, n)
+			C.free(p)
+		}
+		C.free(unsafe.Pointer(ctyp))
+	}
+	d := dnd.Decode(data)
+	d.Files = files
+	return d
+}
+
+// splitNUL returns the NUL terminated strings in the n bytes at p, and frees
+// p.
+func splitNUL(p *C.char, n C.int) []string {
+	if p == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(p))
+	s := strings.Split(C.GoStringN(p, n), "\x00")
+	return s[:len(s)-1]
+}
+
+// StartDrag starts dragging data out of view, an NSView, from the pointer's
+// position. Each file is a dragging item of its own, and the text and other
+// data share another.
+//
+// StartDrag must not be called on the main thread, which it waits for.
+func StartDrag(view uintptr, data screen.DragData) error {
+	items := C.newDragItems()
+	for _, f := range data.Files {
+		path := C.CString(f)
+		C.addDragFile(items, path)
+		C.free(unsafe.Pointer(path))
+	}
+	if _, other := dnd.Encode(screen.DragData{Text: data.Text, Other: data.Other}); len(other) != 0 {
+		item := C.newPasteboardItem()
+		for typ, b := range other {
+			ctyp := C.CString(typ)
+			p := C.CBytes(b)
+			C.setPasteboardData(item, ctyp, p, C.int(len(b)))
+			C.free(p)
+			C.free(unsafe.Pointer(ctyp))
+		}
+		C.addDragItem(items, item)
+	}
+	if C.beginDrag(C.uintptr_t(view), items) == 0 {
+		return errors.New("cocoadisplay: starting a drag failed")
+	}
+	return nil
+}
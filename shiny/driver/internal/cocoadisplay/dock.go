@@ -0,0 +1,72 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+#include <stdlib.h>
+
+void setDockIcon(void* pix, int width, int height);
+void setDockBadge(char* label);
+void setDockProgress(double progress);
+*/
+import "C"
+
+import (
+	"image"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/icon"
+)
+
+// The dock tile is the application's, not a window's, so the last of its
+// windows to set its icon, badge or progress wins.
+
+// SetDockIcon sets the application's icon, in the dock and the application
+// switcher, or restores the icon of its bundle if m is nil.
+//
+// SetDockIcon must not be called on the main thread, which it waits for.
+func SetDockIcon(m image.Image) {
+	if m == nil {
+		C.setDockIcon(nil, 0, 0)
+		return
+	}
+	b := m.Bounds()
+	size := b.Dx()
+	if b.Dy() > size {
+		size = b.Dy()
+	}
+	if size == 0 {
+		C.setDockIcon(nil, 0, 0)
+		return
+	}
+	rgba := icon.Scale(m, size)
+	C.setDockIcon(unsafe.Pointer(&rgba.Pix[0]), C.int(size), C.int(size))
+}
+
+// SetDockBadge sets the badge of the application's dock tile, or removes it
+// if label is empty.
+//
+// SetDockBadge must not be called on the main thread, which it waits for.
+func SetDockBadge(label string) {
+	clabel := C.CString(label)
+	defer C.free(unsafe.Pointer(clabel))
+	C.setDockBadge(clabel)
+}
+
+// SetDockProgress draws a progress bar, from 0 to 1, over the application's
+// dock tile, or removes it if progress is negative.
+//
+// SetDockProgress must not be called on the main thread, which it waits for.
+func SetDockProgress(progress float64) {
+	if progress > 1 {
+		progress = 1
+	}
+	C.setDockProgress(C.double(progress))
+}
@@ -0,0 +1,83 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#import <Cocoa/Cocoa.h>
+#include <string.h>
+
+// ShinyDockProgressView is the content view of the dock tile while it shows
+// progress, which draws the application's icon and a progress bar over it.
+@interface ShinyDockProgressView : NSView
+@property double progress;
+@end
+
+@implementation ShinyDockProgressView
+- (void)drawRect:(NSRect)rect {
+	NSRect b = self.bounds;
+	[[NSApp applicationIconImage] drawInRect:b];
+
+	NSRect bar = NSMakeRect(b.size.width/8, b.size.height/8, b.size.width*3/4, b.size.height/10);
+	double radius = bar.size.height/2;
+	[[NSColor colorWithWhite:0 alpha:0.5] setFill];
+	[[NSBezierPath bezierPathWithRoundedRect:bar xRadius:radius yRadius:radius] fill];
+	bar.size.width *= self.progress;
+	[[NSColor controlAccentColor] setFill];
+	[[NSBezierPath bezierPathWithRoundedRect:bar xRadius:radius yRadius:radius] fill];
+}
+@end
+
+void setDockIcon(void* pix, int width, int height) {
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		if (pix == NULL) {
+			NSApp.applicationIconImage = nil;
+		} else {
+			NSBitmapImageRep* rep = [[NSBitmapImageRep alloc]
+				initWithBitmapDataPlanes:NULL
+				pixelsWide:width
+				pixelsHigh:height
+				bitsPerSample:8
+				samplesPerPixel:4
+				hasAlpha:YES
+				isPlanar:NO
+				colorSpaceName:NSDeviceRGBColorSpace
+				bytesPerRow:4*width
+				bitsPerPixel:32];
+			memcpy([rep bitmapData], pix, 4*width*height);
+			NSImage* img = [[NSImage alloc] initWithSize:NSMakeSize(width, height)];
+			[img addRepresentation:rep];
+			NSApp.applicationIconImage = img;
+			[img release];
+			[rep release];
+		}
+		// A progress view draws the icon, so it must be redrawn.
+		[NSApp.dockTile display];
+	});
+}
+
+void setDockBadge(char* label) {
+	NSString* s = [NSString stringWithUTF8String:label];
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		NSApp.dockTile.badgeLabel = s.length > 0 ? s : nil;
+	});
+}
+
+void setDockProgress(double progress) {
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		NSDockTile* tile = NSApp.dockTile;
+		if (progress < 0) {
+			tile.contentView = nil;
+		} else {
+			ShinyDockProgressView* view = (ShinyDockProgressView*)tile.contentView;
+			if (![view isKindOfClass:[ShinyDockProgressView class]]) {
+				view = [[ShinyDockProgressView alloc] initWithFrame:NSMakeRect(0, 0, tile.size.width, tile.size.height)];
+				tile.contentView = view;
+				[view release];
+			}
+			view.progress = progress;
+		}
+		[tile display];
+	});
+}
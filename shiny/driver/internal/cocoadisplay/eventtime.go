@@ -0,0 +1,36 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+double currentEventTimestamp();
+*/
+import "C"
+
+import (
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/event"
+)
+
+// clock converts the timestamps of NSEvents, in seconds since the system
+// started.
+var clock event.Clock
+
+// EventTime returns the time of the NSEvent that the application is
+// handling, for sending the input events of it at, or now if there is none.
+// It must only be called on the main thread, by the event's handler.
+func EventTime() time.Time {
+	s := float64(C.currentEventTimestamp())
+	if s < 0 {
+		return time.Now()
+	}
+	return clock.Time(time.Duration(s * float64(time.Second)))
+}
@@ -0,0 +1,13 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#import <Cocoa/Cocoa.h>
+
+double currentEventTimestamp() {
+	NSEvent* e = [NSApp currentEvent];
+	return e ? e.timestamp : -1;
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+#include <stdint.h>
+#include <stdlib.h>
+
+char* runFileDialog(uintptr_t viewID, int kind, char* title, char* directory, char* fileName, char* types, int multiple, int* n);
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// ShowFileDialog shows an NSOpenPanel or NSSavePanel, as a sheet of the window
+// of view, an NSView, or as a panel of its own if that window is not visible,
+// and sends its screen.FileDialogEvent to w once the user closes it. It does
+// not wait for the user.
+//
+// The panels have no choice of filters, so they show the files with any of
+// the filters' extensions. A filter pattern other than "*.ext" shows every
+// file.
+func ShowFileDialog(view uintptr, w frame.Sender, opts *screen.FileDialogOptions) {
+	o := screen.FileDialogOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	go func() {
+		title := C.CString(o.Title)
+		directory := C.CString(o.Directory)
+		fileName := C.CString(o.FileName)
+		types := C.CString(strings.Join(fileTypes(o.Filters), "\n"))
+		multiple := 0
+		if o.Multiple {
+			multiple = 1
+		}
+		var n C.int
+		p := C.runFileDialog(C.uintptr_t(view), C.int(o.Kind), title, directory, fileName, types, C.int(multiple), &n)
+		C.free(unsafe.Pointer(types))
+		C.free(unsafe.Pointer(fileName))
+		C.free(unsafe.Pointer(directory))
+		C.free(unsafe.Pointer(title))
+
+		w.Send(screen.FileDialogEvent{
+			ID:    o.ID,
+			Kind:  o.Kind,
+			Paths: splitNUL(p, n),
+		})
+	}()
+}
+
+// fileTypes returns the file name extensions of the filters' patterns, or
+// nil, meaning every file, if any pattern is not of the form "*.ext".
+func fileTypes(filters []screen.FileFilter) []string {
+	var types []string
+	for _, f := range filters {
+		for _, pattern := range f.Patterns {
+			ext := strings.TrimPrefix(pattern, "*.")
+			if ext == pattern || ext == "" || strings.ContainsAny(ext, "*?[\\") {
+				return nil
+			}
+			types = append(types, ext)
+		}
+	}
+	return types
+}
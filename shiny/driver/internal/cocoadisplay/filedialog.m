@@ -0,0 +1,88 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#import <Cocoa/Cocoa.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+// The kinds of file dialog, as for screen.FileDialogKind.
+enum {
+	fileDialogOpen,
+	fileDialogSave,
+	fileDialogDirectory,
+};
+
+char* runFileDialog(uintptr_t viewID, int kind, char* title, char* directory, char* fileName, char* types, int multiple, int* n) {
+	NSView* view = (NSView*)viewID;
+	dispatch_semaphore_t done = dispatch_semaphore_create(0);
+	__block NSArray<NSURL*>* urls = nil;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSSavePanel* panel;
+		if (kind == fileDialogSave) {
+			panel = [NSSavePanel savePanel];
+			if (fileName[0] != 0) {
+				panel.nameFieldStringValue = [NSString stringWithUTF8String:fileName];
+			}
+		} else {
+			NSOpenPanel* openPanel = [NSOpenPanel openPanel];
+			openPanel.canChooseFiles = kind != fileDialogDirectory;
+			openPanel.canChooseDirectories = kind == fileDialogDirectory;
+			openPanel.allowsMultipleSelection = multiple != 0;
+			panel = openPanel;
+		}
+		if (title[0] != 0) {
+			// A sheet has no title bar, and shows its message instead.
+			NSString* t = [NSString stringWithUTF8String:title];
+			panel.title = t;
+			panel.message = t;
+		}
+		if (directory[0] != 0) {
+			panel.directoryURL = [NSURL fileURLWithPath:[NSString stringWithUTF8String:directory] isDirectory:YES];
+		}
+		if (types[0] != 0) {
+			panel.allowedFileTypes = [[NSString stringWithUTF8String:types] componentsSeparatedByString:@"\n"];
+		}
+
+		void (^handler)(NSModalResponse) = ^(NSModalResponse result) {
+			if (result == NSModalResponseOK) {
+				if (kind == fileDialogSave) {
+					urls = [@[panel.URL] retain];
+				} else {
+					urls = [((NSOpenPanel*)panel).URLs retain];
+				}
+			}
+			dispatch_semaphore_signal(done);
+		};
+		NSWindow* window = view.window;
+		if (window != nil && window.visible) {
+			[panel beginSheetModalForWindow:window completionHandler:handler];
+		} else {
+			[panel beginWithCompletionHandler:handler];
+		}
+	});
+	dispatch_semaphore_wait(done, DISPATCH_TIME_FOREVER);
+	dispatch_release(done);
+
+	*n = 0;
+	if (urls == nil) {
+		return NULL;
+	}
+	for (NSURL* url in urls) {
+		*n += strlen(url.fileSystemRepresentation) + 1;
+	}
+	char* buf = malloc(*n > 0 ? *n : 1);
+	char* p = buf;
+	for (NSURL* url in urls) {
+		const char* path = url.fileSystemRepresentation;
+		size_t len = strlen(path) + 1;
+		memcpy(p, path, len);
+		p += len;
+	}
+	[urls release];
+	return buf;
+}
@@ -0,0 +1,48 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo LDFLAGS: -framework CoreVideo
+
+int startDisplayLink();
+*/
+import "C"
+
+import (
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/frame"
+)
+
+// frames are the windows waiting for a screen.FrameEvent.
+var frames frame.Requests
+
+// NextFrame asks for a screen.FrameEvent to be sent to w at the next vertical
+// blank, as signaled by a CVDisplayLink. The display link runs only while
+// there are windows waiting for frames.
+//
+// TODO: set the display link's display to that of the key window, instead of
+// whichever active display CoreVideo chooses.
+func NextFrame(w frame.Sender) {
+	if frames.Request(w) && C.startDisplayLink() == 0 {
+		// There is no display link, such as when every display is asleep,
+		// so pace the frames by a clock instead.
+		go frames.Tick(frame.Interval(0))
+	}
+}
+
+// cocoadisplayFrame is called, on the display link's thread, at each vertical
+// blank. It returns whether the display link should keep running.
+//
+//export cocoadisplayFrame
+func cocoadisplayFrame() C.int {
+	if frames.Fire(time.Now()) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#include "_cgo_export.h"
+#include <pthread.h>
+#import <CoreVideo/CoreVideo.h>
+
+static CVDisplayLinkRef displayLink;
+
+// displayLinkMu serializes starting the display link with stopping it from
+// its callback, so that a start is never undone by a concurrent stop.
+static pthread_mutex_t displayLinkMu = PTHREAD_MUTEX_INITIALIZER;
+
+static CVReturn onDisplayLink(CVDisplayLinkRef link, const CVTimeStamp* now, const CVTimeStamp* outputTime, CVOptionFlags flagsIn, CVOptionFlags* flagsOut, void* context) {
+	pthread_mutex_lock(&displayLinkMu);
+	if (!cocoadisplayFrame()) {
+		CVDisplayLinkStop(link);
+	}
+	pthread_mutex_unlock(&displayLinkMu);
+	return kCVReturnSuccess;
+}
+
+int startDisplayLink() {
+	int ok = 0;
+	pthread_mutex_lock(&displayLinkMu);
+	if (displayLink == NULL && CVDisplayLinkCreateWithActiveCGDisplays(&displayLink) == kCVReturnSuccess) {
+		CVDisplayLinkSetOutputCallback(displayLink, &onDisplayLink, NULL);
+	}
+	if (displayLink != NULL) {
+		ok = CVDisplayLinkIsRunning(displayLink) || CVDisplayLinkStart(displayLink) == kCVReturnSuccess;
+	}
+	pthread_mutex_unlock(&displayLinkMu);
+	return ok;
+}
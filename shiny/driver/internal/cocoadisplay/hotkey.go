@@ -0,0 +1,147 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa -framework Carbon
+
+#include <stdint.h>
+
+int registerHotKey(uint32_t id, int vkcode, int modifiers, uintptr_t* ref);
+void unregisterHotKey(uintptr_t ref);
+*/
+import "C"
+
+import (
+	"errors"
+	"sync"
+
+	"golang.org/x/exp/shiny/driver/internal/cocoakey"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/hotkey"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+)
+
+// Carbon's modifier flags, from <HIToolbox/Events.h>.
+const (
+	cmdKey     = 1 << 8
+	shiftKey   = 1 << 9
+	optionKey  = 1 << 11
+	controlKey = 1 << 12
+)
+
+// eventHotKeyExistsErr is the OSStatus of RegisterEventHotKey when another
+// application has registered the hot key.
+const eventHotKeyExistsErr = -9878
+
+var (
+	hotKeys hotkey.Table
+
+	// hotKeyRefs are the EventHotKeyRefs of the registered hot keys.
+	hotKeyRefs = struct {
+		mu sync.Mutex
+		m  map[hotkey.Key]C.uintptr_t
+	}{
+		m: map[hotkey.Key]C.uintptr_t{},
+	}
+)
+
+// RegisterHotKey registers the hot key k, pressed with exactly mods, for w,
+// which is sent a screen.HotKeyEvent whenever it is pressed, whichever
+// application is active.
+//
+// RegisterHotKey must not be called on the main thread, which it waits for.
+func RegisterHotKey(w frame.Sender, k key.Code, mods key.Modifiers) error {
+	vkcode, ok := vkcodeOf(k)
+	if !ok {
+		return errors.New("cocoadisplay: no virtual key code for hot key")
+	}
+	hk := hotkey.Key{Code: k, Modifiers: mods}
+	return hotKeys.Register(w, hk, func() error {
+		var ref C.uintptr_t
+		switch status := C.registerHotKey(hotKeyID(hk), C.int(vkcode), C.int(carbonModifiers(mods)), &ref); status {
+		case 0:
+		case eventHotKeyExistsErr:
+			return screen.ErrHotKeyInUse
+		default:
+			return errors.New("cocoadisplay: RegisterEventHotKey failed")
+		}
+		hotKeyRefs.mu.Lock()
+		hotKeyRefs.m[hk] = ref
+		hotKeyRefs.mu.Unlock()
+		return nil
+	})
+}
+
+// UnregisterHotKey unregisters the hot key k, pressed with exactly mods, if w
+// registered it.
+func UnregisterHotKey(w frame.Sender, k key.Code, mods key.Modifiers) {
+	hk := hotkey.Key{Code: k, Modifiers: mods}
+	hotKeys.Unregister(w, hk, func() { unregisterHotKey(hk) })
+}
+
+// ReleaseHotKeys unregisters every hot key that w registered, once w is
+// released.
+func ReleaseHotKeys(w frame.Sender) {
+	hotKeys.Release(w, unregisterHotKey)
+}
+
+func unregisterHotKey(k hotkey.Key) {
+	hotKeyRefs.mu.Lock()
+	ref, ok := hotKeyRefs.m[k]
+	delete(hotKeyRefs.m, k)
+	hotKeyRefs.mu.Unlock()
+
+	if ok {
+		C.unregisterHotKey(ref)
+	}
+}
+
+// hotKeyID packs k into the ID of its EventHotKeyRef, so that
+// cocoadisplayHotKey can unpack it.
+func hotKeyID(k hotkey.Key) C.uint32_t {
+	return C.uint32_t(k.Code)<<8 | C.uint32_t(k.Modifiers)
+}
+
+// vkcodeOf returns the virtual key code that cocoakey.Code maps to k.
+func vkcodeOf(k key.Code) (uint16, bool) {
+	for vkcode := uint16(0); vkcode < 0x80; vkcode++ {
+		if cocoakey.Code(vkcode) == k {
+			return vkcode, true
+		}
+	}
+	return 0, false
+}
+
+func carbonModifiers(m key.Modifiers) (flags int) {
+	if m&key.ModShift != 0 {
+		flags |= shiftKey
+	}
+	if m&key.ModControl != 0 {
+		flags |= controlKey
+	}
+	if m&key.ModAlt != 0 {
+		flags |= optionKey
+	}
+	if m&key.ModMeta != 0 {
+		flags |= cmdKey
+	}
+	return flags
+}
+
+// cocoadisplayHotKey is called, on the main thread, when the hot key with
+// the given ID is pressed.
+//
+//export cocoadisplayHotKey
+func cocoadisplayHotKey(id C.uint32_t) {
+	hotKeys.Send(hotkey.Key{
+		Code:      key.Code(id >> 8),
+		Modifiers: key.Modifiers(id & 0xff),
+	})
+}
@@ -0,0 +1,52 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#import <Carbon/Carbon.h>
+#import <Cocoa/Cocoa.h>
+#include <stdint.h>
+#include "_cgo_export.h"
+
+// hotKeySignature is the signature of the IDs of the application's hot keys.
+static const OSType hotKeySignature = 'shny';
+
+static OSStatus onHotKey(EventHandlerCallRef next, EventRef event, void* data) {
+	EventHotKeyID id;
+	OSStatus status = GetEventParameter(event, kEventParamDirectObject,
+		typeEventHotKeyID, NULL, sizeof(id), NULL, &id);
+	if (status != noErr || id.signature != hotKeySignature) {
+		return eventNotHandledErr;
+	}
+	cocoadisplayHotKey(id.id);
+	return noErr;
+}
+
+int registerHotKey(uint32_t id, int vkcode, int modifiers, uintptr_t* ref) {
+	__block OSStatus status;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		static BOOL installed = NO;
+		if (!installed) {
+			EventTypeSpec spec = { kEventClassKeyboard, kEventHotKeyPressed };
+			status = InstallApplicationEventHandler(&onHotKey, 1, &spec, NULL, NULL);
+			if (status != noErr) {
+				return;
+			}
+			installed = YES;
+		}
+		EventHotKeyID hotKeyID = { hotKeySignature, id };
+		EventHotKeyRef hotKeyRef = NULL;
+		status = RegisterEventHotKey(vkcode, modifiers, hotKeyID,
+			GetApplicationEventTarget(), 0, &hotKeyRef);
+		*ref = (uintptr_t)hotKeyRef;
+	});
+	return status;
+}
+
+void unregisterHotKey(uintptr_t ref) {
+	dispatch_async(dispatch_get_main_queue(), ^{
+		UnregisterEventHotKey((EventHotKeyRef)ref);
+	});
+}
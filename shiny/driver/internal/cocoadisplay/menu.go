@@ -0,0 +1,279 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+#include <stdint.h>
+#include <stdlib.h>
+
+// menuItem is a screen.MenuItem. The items of its submenu, if numItems is
+// not -1, follow it.
+typedef struct menuItem {
+	char* title;
+	int tag;
+	int separator;
+	int numItems;
+	char* keyEquivalent;
+	int modifiers;
+	int enabled;
+	int checked;
+} menuItem;
+
+void setMenuBar(uintptr_t viewID, menuItem* items, int n);
+void showContextMenu(uintptr_t viewID, menuItem* items, int n, double x, double y);
+void setStatusItemMenu(uintptr_t itemID, menuItem* items, int n);
+*/
+import "C"
+
+import (
+	"image"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+)
+
+// Each NSMenuItem that can be chosen has a tag, which maps to the window and
+// the screen.MenuItem.ID of its screen.MenuEvent. A view's menu bar, and its
+// most recent context menu, hold their tags until they are replaced, or until
+// ReleaseMenus.
+
+type menuTarget struct {
+	w  frame.Sender
+	id int
+}
+
+type menuKey struct {
+	view    uintptr
+	context bool
+}
+
+var menus = struct {
+	mu      sync.Mutex
+	nextTag int
+	targets map[int]menuTarget
+	tags    map[menuKey][]int
+}{
+	targets: map[int]menuTarget{},
+	tags:    map[menuKey][]int{},
+}
+
+// SetMenuBar sets the menu bar of the window of view, an NSView, which is
+// the main menu, after the application menu, while that window is the key
+// window. Choosing its items sends screen.MenuEvents to w.
+//
+// SetMenuBar must not be called on the main thread, which it waits for.
+func SetMenuBar(view uintptr, w frame.Sender, bar *screen.Menu) {
+	if bar == nil {
+		releaseTags(menuKey{view, false}, nil)
+		C.setMenuBar(C.uintptr_t(view), nil, -1)
+		return
+	}
+	var f menuFlattener
+	f.flatten(w, bar)
+	defer f.free()
+	releaseTags(menuKey{view, false}, f.tags)
+	C.setMenuBar(C.uintptr_t(view), f.ptr(), C.int(len(bar.Items)))
+}
+
+// ShowContextMenu pops up m at p, in the pixels of view, an NSView. It does
+// not wait for the user to choose. The chosen item, if any, sends its
+// screen.MenuEvent to w.
+//
+// ShowContextMenu must not be called on the main thread, which it waits for.
+func ShowContextMenu(view uintptr, w frame.Sender, m *screen.Menu, p image.Point) {
+	if m == nil {
+		m = &screen.Menu{}
+	}
+	var f menuFlattener
+	f.flatten(w, m)
+	defer f.free()
+	releaseTags(menuKey{view, true}, f.tags)
+	C.showContextMenu(C.uintptr_t(view), f.ptr(), C.int(len(m.Items)), C.double(p.X), C.double(p.Y))
+}
+
+// setTrayMenu sets the menu of item, an NSStatusItem, which is shown when it
+// is clicked, or removes it if m is nil. Choosing its items sends
+// screen.MenuEvents to t.
+func setTrayMenu(item uintptr, t frame.Sender, m *screen.Menu) {
+	if m == nil {
+		releaseTags(menuKey{item, false}, nil)
+		C.setStatusItemMenu(C.uintptr_t(item), nil, -1)
+		return
+	}
+	var f menuFlattener
+	f.flatten(t, m)
+	defer f.free()
+	releaseTags(menuKey{item, false}, f.tags)
+	C.setStatusItemMenu(C.uintptr_t(item), f.ptr(), C.int(len(m.Items)))
+}
+
+// ReleaseMenus forgets the menus of view, an NSView, once it is closed.
+func ReleaseMenus(view uintptr) {
+	releaseTags(menuKey{view, false}, nil)
+	releaseTags(menuKey{view, true}, nil)
+}
+
+// releaseTags replaces the tags of k's menu with tags, forgetting the old
+// ones.
+func releaseTags(k menuKey, tags []int) {
+	menus.mu.Lock()
+	defer menus.mu.Unlock()
+
+	for _, tag := range menus.tags[k] {
+		delete(menus.targets, tag)
+	}
+	if tags == nil {
+		delete(menus.tags, k)
+	} else {
+		menus.tags[k] = tags
+	}
+}
+
+// cocoadisplayMenuChosen is called, on the main thread, when the user chooses
+// the NSMenuItem with the given tag.
+//
+//export cocoadisplayMenuChosen
+func cocoadisplayMenuChosen(tag C.int) {
+	menus.mu.Lock()
+	t, ok := menus.targets[int(tag)]
+	menus.mu.Unlock()
+
+	if ok {
+		t.w.Send(screen.MenuEvent{ID: t.id})
+	}
+}
+
+// menuFlattener flattens a screen.Menu into menuItems, in C memory, tagging
+// the items that can be chosen.
+type menuFlattener struct {
+	items []C.menuItem
+	strs  []*C.char
+	tags  []int
+}
+
+func (f *menuFlattener) flatten(w frame.Sender, m *screen.Menu) {
+	for i := range m.Items {
+		item := &m.Items[i]
+		c := C.menuItem{
+			title:         f.cString(item.Title),
+			numItems:      -1,
+			keyEquivalent: f.cString(""),
+		}
+		if !item.Disabled {
+			c.enabled = 1
+		}
+		switch {
+		case item.Separator:
+			c.separator = 1
+		case item.Submenu != nil:
+			c.numItems = C.int(len(item.Submenu.Items))
+			f.items = append(f.items, c)
+			f.flatten(w, item.Submenu)
+			continue
+		default:
+			c.tag = C.int(f.newTag(w, item.ID))
+			if item.Checked {
+				c.checked = 1
+			}
+			if s := keyEquivalent(item.Key); s != "" {
+				c.keyEquivalent = f.cString(s)
+				c.modifiers = C.int(item.Modifiers)
+			}
+		}
+		f.items = append(f.items, c)
+	}
+}
+
+func (f *menuFlattener) newTag(w frame.Sender, id int) int {
+	menus.mu.Lock()
+	defer menus.mu.Unlock()
+
+	// Tags are never 0, the tag of every other NSMenuItem.
+	menus.nextTag++
+	if menus.nextTag == 1<<31-1 {
+		menus.nextTag = 1
+	}
+	menus.targets[menus.nextTag] = menuTarget{w, id}
+	f.tags = append(f.tags, menus.nextTag)
+	return menus.nextTag
+}
+
+func (f *menuFlattener) cString(s string) *C.char {
+	c := C.CString(s)
+	f.strs = append(f.strs, c)
+	return c
+}
+
+func (f *menuFlattener) ptr() *C.menuItem {
+	if len(f.items) == 0 {
+		return nil
+	}
+	return &f.items[0]
+}
+
+func (f *menuFlattener) free() {
+	for _, c := range f.strs {
+		C.free(unsafe.Pointer(c))
+	}
+}
+
+// keyEquivalents are the NSMenuItem key equivalents of the keys, other than
+// letters, digits and function keys.
+var keyEquivalents = map[key.Code]string{
+	key.CodeReturnEnter:        "\r",
+	key.CodeEscape:             "\x1b",
+	key.CodeDeleteBackspace:    "\b",
+	key.CodeTab:                "\t",
+	key.CodeSpacebar:           " ",
+	key.CodeHyphenMinus:        "-",
+	key.CodeEqualSign:          "=",
+	key.CodeLeftSquareBracket:  "[",
+	key.CodeRightSquareBracket: "]",
+	key.CodeSemicolon:          ";",
+	key.CodeApostrophe:         "'",
+	key.CodeComma:              ",",
+	key.CodeFullStop:           ".",
+	key.CodeSlash:              "/",
+	key.CodeBackslash:          "\\",
+	key.CodeGraveAccent:        "`",
+	// The function key characters of the NSEvent documentation.
+	key.CodeUpArrow:       "\uf700",
+	key.CodeDownArrow:     "\uf701",
+	key.CodeLeftArrow:     "\uf702",
+	key.CodeRightArrow:    "\uf703",
+	key.CodeInsert:        "\uf727",
+	key.CodeDeleteForward: "\uf728",
+	key.CodeHome:          "\uf729",
+	key.CodeEnd:           "\uf72b",
+	key.CodePageUp:        "\uf72c",
+	key.CodePageDown:      "\uf72d",
+}
+
+// keyEquivalent returns the NSMenuItem key equivalent of the key, or "" if it
+// has none. Letters are lower case, as an upper case letter implies the
+// shift key.
+func keyEquivalent(code key.Code) string {
+	switch {
+	case key.CodeA <= code && code <= key.CodeZ:
+		return string(rune('a' + code - key.CodeA))
+	case key.Code1 <= code && code <= key.Code9:
+		return string(rune('1' + code - key.Code1))
+	case code == key.Code0:
+		return "0"
+	case key.CodeF1 <= code && code <= key.CodeF12:
+		return string(rune(0xf704 + code - key.CodeF1))
+	case key.CodeF13 <= code && code <= key.CodeF24:
+		return string(rune(0xf704 + 12 + code - key.CodeF13))
+	}
+	return keyEquivalents[code]
+}
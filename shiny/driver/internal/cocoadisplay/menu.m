@@ -0,0 +1,181 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#import <Cocoa/Cocoa.h>
+#import <objc/runtime.h>
+#include "_cgo_export.h"
+
+// The modifiers of a shortcut, as for key.Modifiers.
+enum {
+	modShift   = 1 << 0,
+	modControl = 1 << 1,
+	modAlt     = 1 << 2,
+	modMeta    = 1 << 3,
+};
+
+// ShinyMenuTarget is the target of every menu item that can be chosen, which
+// tells Go the item's tag.
+@interface ShinyMenuTarget : NSObject
+- (void)chooseItem:(NSMenuItem*)item;
+@end
+
+@implementation ShinyMenuTarget
+- (void)chooseItem:(NSMenuItem*)item {
+	cocoadisplayMenuChosen((int)item.tag);
+}
+@end
+
+static ShinyMenuTarget* menuTarget;
+
+// menuBarKey is the key of the menu bar associated with each window.
+static char menuBarKey;
+
+// appMenuItem is the item of the application menu, of Hide and Quit, that the
+// drivers make the first item of the main menu.
+static NSMenuItem* appMenuItem;
+
+// newMenu returns a menu, which the caller must release, of the n items
+// starting at items[*i], and the items of their submenus, which follow each
+// of them. It advances *i past them.
+static NSMenu* newMenu(NSString* title, menuItem* items, int n, int* i) {
+	NSMenu* menu = [[NSMenu alloc] initWithTitle:title];
+	// The items are enabled, or not, as Go says, not as their targets say.
+	menu.autoenablesItems = NO;
+	for (int j = 0; j < n; j++) {
+		menuItem* m = &items[(*i)++];
+		if (m->separator) {
+			[menu addItem:[NSMenuItem separatorItem]];
+			continue;
+		}
+
+		NSString* t = [NSString stringWithUTF8String:m->title];
+		NSMenuItem* item = [[NSMenuItem alloc] initWithTitle:t action:nil keyEquivalent:@""];
+		if (m->numItems >= 0) {
+			NSMenu* submenu = newMenu(t, items, m->numItems, i);
+			item.submenu = submenu;
+			[submenu release];
+		} else {
+			item.target = menuTarget;
+			item.action = @selector(chooseItem:);
+			item.tag = m->tag;
+			item.keyEquivalent = [NSString stringWithUTF8String:m->keyEquivalent];
+			NSEventModifierFlags mask = 0;
+			if (m->modifiers & modShift) {
+				mask |= NSEventModifierFlagShift;
+			}
+			if (m->modifiers & modControl) {
+				mask |= NSEventModifierFlagControl;
+			}
+			if (m->modifiers & modAlt) {
+				mask |= NSEventModifierFlagOption;
+			}
+			if (m->modifiers & modMeta) {
+				mask |= NSEventModifierFlagCommand;
+			}
+			item.keyEquivalentModifierMask = mask;
+			item.state = m->checked ? NSControlStateValueOn : NSControlStateValueOff;
+		}
+		item.enabled = m->enabled != 0;
+		[menu addItem:item];
+		[item release];
+	}
+	return menu;
+}
+
+// installMenuBar makes the menu bar of window, or one with no menus of its
+// own if it has none, the main menu. It must be called on the main thread.
+static void installMenuBar(NSWindow* window) {
+	NSMenu* main = [NSApp mainMenu];
+	if (main.numberOfItems > 0) {
+		// Each new window replaces the main menu, and its application menu,
+		// so the item is that of the current main menu.
+		[appMenuItem release];
+		appMenuItem = [[main itemAtIndex:0] retain];
+	}
+	NSMenu* bar = objc_getAssociatedObject(window, &menuBarKey);
+	if (bar == nil) {
+		bar = [[[NSMenu alloc] init] autorelease];
+	}
+	if (appMenuItem != nil && appMenuItem.menu != bar) {
+		[appMenuItem.menu removeItem:appMenuItem];
+		[bar insertItem:appMenuItem atIndex:0];
+	}
+	[NSApp setMainMenu:bar];
+}
+
+// initMenus makes the menu target, and makes the main menu follow the key
+// window, once. Until then, the main menu is the drivers' own. It must be
+// called on the main thread.
+static void initMenus() {
+	if (menuTarget != nil) {
+		return;
+	}
+	menuTarget = [[ShinyMenuTarget alloc] init];
+	[[NSNotificationCenter defaultCenter] addObserverForName:NSWindowDidBecomeKeyNotification
+		object:nil
+		queue:nil
+		usingBlock:^(NSNotification* note) {
+			installMenuBar(note.object);
+		}];
+}
+
+void setMenuBar(uintptr_t viewID, menuItem* items, int n) {
+	NSView* view = (NSView*)viewID;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		initMenus();
+		NSWindow* window = view.window;
+		if (window == nil) {
+			return;
+		}
+		NSMenu* bar = nil;
+		if (n >= 0) {
+			int i = 0;
+			bar = newMenu(@"", items, n, &i);
+		}
+		objc_setAssociatedObject(window, &menuBarKey, bar, OBJC_ASSOCIATION_RETAIN);
+		[bar release];
+		if (window.isKeyWindow) {
+			installMenuBar(window);
+		}
+	});
+}
+
+void showContextMenu(uintptr_t viewID, menuItem* items, int n, double x, double y) {
+	NSView* view = (NSView*)viewID;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		initMenus();
+		int i = 0;
+		NSMenu* menu = newMenu(@"", items, n, &i);
+
+		// Convert from pixels with a top left origin to the view's points.
+		double scale = view.window != nil ? view.window.backingScaleFactor : 1;
+		NSPoint p = NSMakePoint(x / scale, y / scale);
+		if (!view.isFlipped) {
+			p.y = view.bounds.size.height - p.y;
+		}
+		// The menu tracks the mouse in a modal loop of its own, so it is
+		// popped up after returning.
+		dispatch_async(dispatch_get_main_queue(), ^{
+			[menu popUpMenuPositioningItem:nil atLocation:p inView:view];
+			[menu release];
+		});
+	});
+}
+
+void setStatusItemMenu(uintptr_t itemID, menuItem* items, int n) {
+	NSStatusItem* statusItem = (NSStatusItem*)itemID;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		initMenus();
+		NSMenu* menu = nil;
+		if (n >= 0) {
+			int i = 0;
+			menu = newMenu(@"", items, n, &i);
+		}
+		statusItem.menu = menu;
+		[menu release];
+	});
+}
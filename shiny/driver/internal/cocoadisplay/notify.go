@@ -0,0 +1,186 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa -framework UserNotifications
+
+#include <stdlib.h>
+
+char* postNotification(char* identifier, char* title, char* body, char* imagePath,
+	int* actionIDs, char** actionTitles, int numActions);
+void removeNotification(char* identifier);
+*/
+import "C"
+
+import (
+	"errors"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Each posted notification has a tag, which is its UNNotificationRequest's
+// identifier, and which maps to the window that posted it and its
+// screen.Notification.ID. A notification holds its tag until it is
+// activated, closed, or replaced, or until ReleaseNotifications.
+
+type notificationKey struct {
+	w  frame.Sender
+	id int
+}
+
+var notifications = struct {
+	mu      sync.Mutex
+	nextTag int
+	targets map[int]notificationKey
+	tags    map[notificationKey]int
+}{
+	targets: map[int]notificationKey{},
+	tags:    map[notificationKey]int{},
+}
+
+// PostNotification posts n, for w, with the UNUserNotificationCenter, asking
+// the user for permission first if they have not yet been asked. Activating
+// it sends a screen.NotificationEvent to w. It replaces a notification of
+// w's with the same ID.
+//
+// PostNotification returns an error if the application has no bundle, which
+// notifications need, or if the user has not allowed them.
+//
+// PostNotification must not be called on the main thread, which it waits for.
+func PostNotification(w frame.Sender, n *screen.Notification) error {
+	imagePath := ""
+	if n.Image != nil {
+		// The notification's attachment moves the file into its own store.
+		f, err := ioutil.TempFile("", "shiny-notification-*.png")
+		if err != nil {
+			return err
+		}
+		err = png.Encode(f, n.Image)
+		f.Close()
+		defer os.Remove(f.Name())
+		if err != nil {
+			return err
+		}
+		imagePath = f.Name()
+	}
+
+	k := notificationKey{w, n.ID}
+	notifications.mu.Lock()
+	tag, ok := notifications.tags[k]
+	if !ok {
+		// Tags are never 0, so that a zero tag of a response is invalid.
+		notifications.nextTag++
+		if notifications.nextTag == 1<<31-1 {
+			notifications.nextTag = 1
+		}
+		tag = notifications.nextTag
+		notifications.tags[k] = tag
+		notifications.targets[tag] = k
+	}
+	notifications.mu.Unlock()
+
+	var strs []*C.char
+	cString := func(s string) *C.char {
+		c := C.CString(s)
+		strs = append(strs, c)
+		return c
+	}
+	defer func() {
+		for _, c := range strs {
+			C.free(unsafe.Pointer(c))
+		}
+	}()
+	var ids []C.int
+	var titles []*C.char
+	for _, a := range n.Actions {
+		ids = append(ids, C.int(a.ID))
+		titles = append(titles, cString(a.Title))
+	}
+	var idsPtr *C.int
+	var titlesPtr **C.char
+	if len(n.Actions) > 0 {
+		idsPtr, titlesPtr = &ids[0], &titles[0]
+	}
+	cimagePath := (*C.char)(nil)
+	if imagePath != "" {
+		cimagePath = cString(imagePath)
+	}
+	errMsg := C.postNotification(cString(strconv.Itoa(tag)), cString(n.Title), cString(n.Body),
+		cimagePath, idsPtr, titlesPtr, C.int(len(n.Actions)))
+	if errMsg != nil {
+		defer C.free(unsafe.Pointer(errMsg))
+		forgetNotification(tag)
+		return errors.New("cocoadisplay: " + C.GoString(errMsg))
+	}
+	return nil
+}
+
+// CloseNotification removes w's notification with the given ID, if it is
+// shown.
+func CloseNotification(w frame.Sender, id int) {
+	notifications.mu.Lock()
+	tag, ok := notifications.tags[notificationKey{w, id}]
+	notifications.mu.Unlock()
+	if ok {
+		forgetNotification(tag)
+		removeNotification(tag)
+	}
+}
+
+// ReleaseNotifications removes w's notifications, once it is released.
+func ReleaseNotifications(w frame.Sender) {
+	var tags []int
+	notifications.mu.Lock()
+	for k, tag := range notifications.tags {
+		if k.w == w {
+			tags = append(tags, tag)
+		}
+	}
+	notifications.mu.Unlock()
+	for _, tag := range tags {
+		forgetNotification(tag)
+		removeNotification(tag)
+	}
+}
+
+func forgetNotification(tag int) {
+	notifications.mu.Lock()
+	defer notifications.mu.Unlock()
+	delete(notifications.tags, notifications.targets[tag])
+	delete(notifications.targets, tag)
+}
+
+func removeNotification(tag int) {
+	identifier := C.CString(strconv.Itoa(tag))
+	defer C.free(unsafe.Pointer(identifier))
+	C.removeNotification(identifier)
+}
+
+// cocoadisplayNotificationActivated is called when the user activates the
+// notification with the given tag, choosing the action with the given ID,
+// or 0 for the notification itself.
+//
+//export cocoadisplayNotificationActivated
+func cocoadisplayNotificationActivated(tag, action C.int) {
+	notifications.mu.Lock()
+	k, ok := notifications.targets[int(tag)]
+	notifications.mu.Unlock()
+	if !ok {
+		return
+	}
+	forgetNotification(int(tag))
+	k.w.Send(screen.NotificationEvent{ID: k.id, Action: int(action)})
+}
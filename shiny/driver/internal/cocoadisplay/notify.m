@@ -0,0 +1,156 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#import <Cocoa/Cocoa.h>
+#import <UserNotifications/UserNotifications.h>
+#include <string.h>
+#include "_cgo_export.h"
+
+// ShinyNotificationDelegate tells Go which notifications the user activates,
+// and shows notifications even while the application is active.
+@interface ShinyNotificationDelegate : NSObject<UNUserNotificationCenterDelegate>
+@end
+
+@implementation ShinyNotificationDelegate
+- (void)userNotificationCenter:(UNUserNotificationCenter*)center
+	willPresentNotification:(UNNotification*)notification
+	withCompletionHandler:(void (^)(UNNotificationPresentationOptions))completionHandler {
+	if (@available(macOS 11.0, *)) {
+		completionHandler(UNNotificationPresentationOptionBanner | UNNotificationPresentationOptionList);
+	} else {
+		completionHandler(UNNotificationPresentationOptionAlert);
+	}
+}
+
+- (void)userNotificationCenter:(UNUserNotificationCenter*)center
+	didReceiveNotificationResponse:(UNNotificationResponse*)response
+	withCompletionHandler:(void (^)(void))completionHandler {
+	NSString* action = response.actionIdentifier;
+	int tag = response.notification.request.identifier.intValue;
+	if ([action isEqualToString:UNNotificationDefaultActionIdentifier]) {
+		cocoadisplayNotificationActivated(tag, 0);
+	} else if (![action isEqualToString:UNNotificationDismissActionIdentifier]) {
+		cocoadisplayNotificationActivated(tag, action.intValue);
+	}
+	completionHandler();
+}
+@end
+
+static ShinyNotificationDelegate* notificationDelegate;
+
+// categories are the categories, by their identifiers, of the notifications
+// with actions. Each such notification has a category of its own, whose
+// identifier is the notification's.
+static NSMutableDictionary<NSString*, UNNotificationCategory*>* categories;
+
+// newError returns a copy of the error's description, which the caller must
+// free.
+static char* newError(NSString* desc) {
+	return strdup(desc.UTF8String);
+}
+
+char* postNotification(char* identifier, char* title, char* body, char* imagePath,
+	int* actionIDs, char** actionTitles, int numActions) {
+	// It runs on a goroutine's thread, which has no autorelease pool.
+	@autoreleasepool {
+		if (NSBundle.mainBundle.bundleIdentifier == nil) {
+			return newError(@"notifications need an application bundle");
+		}
+		UNUserNotificationCenter* center = UNUserNotificationCenter.currentNotificationCenter;
+		NSString* ident = [NSString stringWithUTF8String:identifier];
+
+		dispatch_sync(dispatch_get_main_queue(), ^{
+			if (notificationDelegate == nil) {
+				notificationDelegate = [[ShinyNotificationDelegate alloc] init];
+				center.delegate = notificationDelegate;
+				categories = [[NSMutableDictionary alloc] init];
+			}
+			if (numActions > 0) {
+				NSMutableArray* actions = [NSMutableArray arrayWithCapacity:numActions];
+				for (int i = 0; i < numActions; i++) {
+					[actions addObject:[UNNotificationAction
+						actionWithIdentifier:[NSString stringWithFormat:@"%d", actionIDs[i]]
+						title:[NSString stringWithUTF8String:actionTitles[i]]
+						options:UNNotificationActionOptionForeground]];
+				}
+				categories[ident] = [UNNotificationCategory categoryWithIdentifier:ident
+					actions:actions
+					intentIdentifiers:@[]
+					options:0];
+			} else {
+				[categories removeObjectForKey:ident];
+			}
+			[center setNotificationCategories:[NSSet setWithArray:categories.allValues]];
+		});
+
+		__block NSError* err = nil;
+		__block BOOL granted = NO;
+		dispatch_semaphore_t done = dispatch_semaphore_create(0);
+		[center requestAuthorizationWithOptions:UNAuthorizationOptionAlert|UNAuthorizationOptionSound
+			completionHandler:^(BOOL g, NSError* e) {
+				granted = g;
+				err = [e retain];
+				dispatch_semaphore_signal(done);
+			}];
+		dispatch_semaphore_wait(done, DISPATCH_TIME_FOREVER);
+		if (err != nil) {
+			char* msg = newError(err.localizedDescription);
+			[err release];
+			dispatch_release(done);
+			return msg;
+		}
+		if (!granted) {
+			dispatch_release(done);
+			return newError(@"notifications are not permitted");
+		}
+
+		UNMutableNotificationContent* content = [[[UNMutableNotificationContent alloc] init] autorelease];
+		content.title = [NSString stringWithUTF8String:title];
+		content.body = [NSString stringWithUTF8String:body];
+		if (numActions > 0) {
+			content.categoryIdentifier = ident;
+		}
+		if (imagePath != NULL) {
+			NSURL* url = [NSURL fileURLWithPath:[NSString stringWithUTF8String:imagePath]];
+			UNNotificationAttachment* a = [UNNotificationAttachment attachmentWithIdentifier:@"image"
+				URL:url options:nil error:nil];
+			if (a != nil) {
+				content.attachments = @[a];
+			}
+		}
+		UNNotificationRequest* request = [UNNotificationRequest requestWithIdentifier:ident
+			content:content trigger:nil];
+		[center addNotificationRequest:request withCompletionHandler:^(NSError* e) {
+			err = [e retain];
+			dispatch_semaphore_signal(done);
+		}];
+		dispatch_semaphore_wait(done, DISPATCH_TIME_FOREVER);
+		dispatch_release(done);
+		if (err != nil) {
+			char* msg = newError(err.localizedDescription);
+			[err release];
+			return msg;
+		}
+		return NULL;
+	}
+}
+
+void removeNotification(char* identifier) {
+	if (NSBundle.mainBundle.bundleIdentifier == nil) {
+		return;
+	}
+	NSString* ident = [NSString stringWithUTF8String:identifier];
+	UNUserNotificationCenter* center = UNUserNotificationCenter.currentNotificationCenter;
+	[center removePendingNotificationRequestsWithIdentifiers:@[ident]];
+	[center removeDeliveredNotificationsWithIdentifiers:@[ident]];
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if ([categories objectForKey:ident] != nil) {
+			[categories removeObjectForKey:ident];
+			[center setNotificationCategories:[NSSet setWithArray:categories.allValues]];
+		}
+	});
+}
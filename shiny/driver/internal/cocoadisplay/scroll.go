@@ -0,0 +1,104 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+import (
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/mouse"
+)
+
+// NSEventPhase values.
+const (
+	phaseBegan      = 0x1
+	phaseStationary = 0x2
+	phaseChanged    = 0x4
+	phaseEnded      = 0x8
+	phaseCancelled  = 0x10
+	phaseMayBegin   = 0x20
+)
+
+// preciseStep is how far, in points, precise scrolling, such as from a
+// trackpad, scrolls per screen.ScrollEvent step. It is about the height of a
+// line, which is what imprecise scrolling, from a mouse wheel, counts.
+const preciseStep = 10
+
+// Scroller converts a window's NSEventTypeScrollWheel events to
+// screen.ScrollEvents, and to the mouse.Events of their whole steps.
+type Scroller struct {
+	// steps accumulate the scrolling along each axis that does not yet
+	// make a whole step.
+	steps [2]float32
+}
+
+// Scroll returns the events of a scroll, at x and y, of an NSEvent's
+// scrollingDeltaX and scrollingDeltaY, hasPreciseScrollingDeltas, phase and
+// momentumPhase. It returns false, and no events, for a phase that only says
+// that a scroll may begin.
+//
+// The deltas are already reversed, if need be, by the "natural scrolling"
+// setting. This means the same trackpad or mouse motion on macOS and Linux
+// can scroll in opposite directions, but the direction matches what other
+// programs on the OS do.
+func (s *Scroller) Scroll(x, y, dx, dy float32, precise bool, phase, momentumPhase uint32, m key.Modifiers) (e screen.ScrollEvent, wheel []mouse.Event, ok bool) {
+	e = screen.ScrollEvent{
+		X:         x,
+		Y:         y,
+		Device:    screen.ScrollDeviceWheel,
+		Modifiers: m,
+	}
+	step := float32(1)
+	if precise {
+		e.Device, step = screen.ScrollDeviceTrackpad, preciseStep
+	}
+	switch {
+	case phase&phaseBegan != 0:
+		e.Phase = screen.ScrollBegin
+	case phase&(phaseChanged|phaseStationary) != 0:
+		e.Phase = screen.ScrollChange
+	case phase&(phaseEnded|phaseCancelled) != 0:
+		e.Phase = screen.ScrollEnd
+	case momentumPhase&(phaseBegan|phaseChanged|phaseStationary) != 0:
+		e.Phase = screen.ScrollMomentum
+	case momentumPhase&(phaseEnded|phaseCancelled) != 0:
+		e.Phase = screen.ScrollMomentumEnd
+	case phase&phaseMayBegin != 0:
+		return screen.ScrollEvent{}, nil, false
+	}
+	// Cocoa's deltas, unlike screen.ScrollEvent's, are positive up and
+	// left.
+	e.DeltaX, e.DeltaY = -dx/step, -dy/step
+	if e.Phase == screen.ScrollBegin {
+		s.steps = [2]float32{}
+	}
+
+	me := mouse.Event{
+		X:         x,
+		Y:         y,
+		Direction: mouse.DirStep,
+		Modifiers: m,
+	}
+	s.steps[0] += e.DeltaX
+	s.steps[1] += e.DeltaY
+	for ; s.steps[1] >= 1; s.steps[1]-- {
+		me.Button = mouse.ButtonWheelDown
+		wheel = append(wheel, me)
+	}
+	for ; s.steps[1] <= -1; s.steps[1]++ {
+		me.Button = mouse.ButtonWheelUp
+		wheel = append(wheel, me)
+	}
+	for ; s.steps[0] >= 1; s.steps[0]-- {
+		me.Button = mouse.ButtonWheelRight
+		wheel = append(wheel, me)
+	}
+	for ; s.steps[0] <= -1; s.steps[0]++ {
+		me.Button = mouse.ButtonWheelLeft
+		wheel = append(wheel, me)
+	}
+	return e, wheel, true
+}
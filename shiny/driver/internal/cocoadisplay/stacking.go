@@ -0,0 +1,68 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+#include <stdint.h>
+
+void setWindowFloating(uintptr_t viewID, int floating);
+void setWindowAlpha(uintptr_t viewID, double alpha);
+void orderWindow(uintptr_t viewID, int front);
+void showOwnedWindow(uintptr_t viewID, uintptr_t ownerID, int modal);
+void releaseOwnedWindow(uintptr_t viewID);
+*/
+import "C"
+
+// SetAlwaysOnTop puts the window of view, an NSView, at the floating window
+// level, above normal windows, as for a utility panel, or back at the normal
+// level.
+func SetAlwaysOnTop(view uintptr, onTop bool) {
+	v := 0
+	if onTop {
+		v = 1
+	}
+	C.setWindowFloating(C.uintptr_t(view), C.int(v))
+}
+
+// SetOpacity sets the alphaValue of the window of view, an NSView.
+func SetOpacity(view uintptr, opacity float32) {
+	if !(opacity < 1) {
+		opacity = 1
+	} else if !(opacity > 0) {
+		opacity = 0
+	}
+	C.setWindowAlpha(C.uintptr_t(view), C.double(opacity))
+}
+
+// Raise orders the window of view, an NSView, in front of the other windows
+// at its level, without making it the key window.
+func Raise(view uintptr) { C.orderWindow(C.uintptr_t(view), 1) }
+
+// Lower orders the window of view, an NSView, behind the other windows at its
+// level.
+func Lower(view uintptr) { C.orderWindow(C.uintptr_t(view), 0) }
+
+// ShowOwned shows the window of view, an NSView, as owned by the window of
+// owner, another NSView. A modal window is shown as a sheet, attached to the
+// owner's title bar, which blocks the owner's input until the sheet ends.
+// Otherwise, the window is a child window of the owner's, ordered above it
+// and moved and minimized with it.
+func ShowOwned(view, owner uintptr, modal bool) {
+	m := 0
+	if modal {
+		m = 1
+	}
+	C.showOwnedWindow(C.uintptr_t(view), C.uintptr_t(owner), C.int(m))
+}
+
+// ReleaseOwned ends the sheet, or removes the child window, that is the
+// window of view, an NSView, if ShowOwned showed it. It is called before the
+// window closes, and does nothing for other windows.
+func ReleaseOwned(view uintptr) { C.releaseOwnedWindow(C.uintptr_t(view)) }
@@ -0,0 +1,68 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#import <Cocoa/Cocoa.h>
+#include <stdint.h>
+
+void setWindowFloating(uintptr_t viewID, int floating) {
+	NSView* view = (NSView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		view.window.level = floating ? NSFloatingWindowLevel : NSNormalWindowLevel;
+	});
+}
+
+void setWindowAlpha(uintptr_t viewID, double alpha) {
+	NSView* view = (NSView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		view.window.alphaValue = alpha;
+	});
+}
+
+void orderWindow(uintptr_t viewID, int front) {
+	NSView* view = (NSView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (front) {
+			[view.window orderFront:nil];
+		} else {
+			[view.window orderBack:nil];
+		}
+	});
+}
+
+void showOwnedWindow(uintptr_t viewID, uintptr_t ownerID, int modal) {
+	NSView* view = (NSView*)viewID;
+	NSView* owner = (NSView*)ownerID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow* parent = owner.window;
+		if (parent == nil) {
+			// The owner has been closed.
+			[view.window makeKeyAndOrderFront:nil];
+			return;
+		}
+		if (modal) {
+			[parent beginSheet:view.window completionHandler:nil];
+			return;
+		}
+		[parent addChildWindow:view.window ordered:NSWindowAbove];
+		[view.window makeKeyAndOrderFront:nil];
+	});
+}
+
+void releaseOwnedWindow(uintptr_t viewID) {
+	NSView* view = (NSView*)viewID;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		if (window == nil) {
+			return;
+		}
+		if (window.sheetParent != nil) {
+			[window.sheetParent endSheet:window];
+		} else if (window.parentWindow != nil) {
+			[window.parentWindow removeChildWindow:window];
+		}
+	});
+}
@@ -0,0 +1,158 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package cocoadisplay
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+#include <stdint.h>
+#include <stdlib.h>
+
+uintptr_t newStatusItem(void);
+void setStatusItemIcon(uintptr_t itemID, void* pix, int size);
+void setStatusItemTooltip(uintptr_t itemID, char* tooltip);
+void releaseStatusItem(uintptr_t itemID);
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/icon"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/mouse"
+)
+
+// trayIconSize is the size, in pixels, of a tray icon's image, which is shown
+// in 18 by 18 points of the menu bar, at a backing scale factor of up to 2.
+const trayIconSize = 36
+
+// TrayIcon implements the screen.TrayIcon interface with an NSStatusItem in
+// the menu bar.
+type TrayIcon struct {
+	event.Deque
+
+	item uintptr
+
+	mu       sync.Mutex
+	released bool
+}
+
+// trayIcons holds the unreleased tray icons, by their NSStatusItems.
+var trayIcons = struct {
+	mu sync.Mutex
+	m  map[uintptr]*TrayIcon
+}{
+	m: map[uintptr]*TrayIcon{},
+}
+
+// NewTrayIcon adds a new NSStatusItem to the menu bar.
+//
+// NewTrayIcon must not be called on the main thread, which it waits for.
+func NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	if opts == nil {
+		opts = &screen.NewTrayIconOptions{}
+	}
+	item := uintptr(C.newStatusItem())
+	if item == 0 {
+		return nil, errors.New("cocoadisplay: NSStatusItem creation failed")
+	}
+	t := &TrayIcon{item: item}
+	trayIcons.mu.Lock()
+	trayIcons.m[item] = t
+	trayIcons.mu.Unlock()
+
+	t.setIcon(opts.Icon)
+	t.setTooltip(opts.Tooltip)
+	if opts.Menu != nil {
+		setTrayMenu(item, t, opts.Menu)
+	}
+	return t, nil
+}
+
+func (t *TrayIcon) Release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.released {
+		return
+	}
+	t.released = true
+
+	trayIcons.mu.Lock()
+	delete(trayIcons.m, t.item)
+	trayIcons.mu.Unlock()
+	releaseTags(menuKey{t.item, false}, nil)
+	C.releaseStatusItem(C.uintptr_t(t.item))
+}
+
+func (t *TrayIcon) SetIcon(m image.Image) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.released {
+		t.setIcon(m)
+	}
+}
+
+func (t *TrayIcon) SetTooltip(tooltip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.released {
+		t.setTooltip(tooltip)
+	}
+}
+
+func (t *TrayIcon) SetMenu(m *screen.Menu) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.released {
+		return errors.New("cocoadisplay: tray icon is released")
+	}
+	setTrayMenu(t.item, t, m)
+	return nil
+}
+
+func (t *TrayIcon) setIcon(m image.Image) {
+	if m == nil || m.Bounds().Empty() {
+		C.setStatusItemIcon(C.uintptr_t(t.item), nil, 0)
+		return
+	}
+	rgba := icon.Scale(m, trayIconSize)
+	C.setStatusItemIcon(C.uintptr_t(t.item), unsafe.Pointer(&rgba.Pix[0]), trayIconSize)
+}
+
+func (t *TrayIcon) setTooltip(tooltip string) {
+	ctooltip := C.CString(tooltip)
+	defer C.free(unsafe.Pointer(ctooltip))
+	C.setStatusItemTooltip(C.uintptr_t(t.item), ctooltip)
+}
+
+// cocoadisplayTrayClicked is called, on the main thread, when the user clicks
+// an NSStatusItem that has no menu. button is 1, 2 or 3 for the left, middle
+// or right button.
+//
+//export cocoadisplayTrayClicked
+func cocoadisplayTrayClicked(item C.uintptr_t, button C.int) {
+	trayIcons.mu.Lock()
+	t := trayIcons.m[uintptr(item)]
+	trayIcons.mu.Unlock()
+	if t == nil {
+		return
+	}
+	b := mouse.ButtonLeft
+	switch button {
+	case 2:
+		b = mouse.ButtonMiddle
+	case 3:
+		b = mouse.ButtonRight
+	}
+	t.Send(screen.TrayEvent{Button: b})
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#import <Cocoa/Cocoa.h>
+#include <stdint.h>
+#include <string.h>
+#include "_cgo_export.h"
+
+// ShinyTrayTarget is the target of every status item's button, which tells Go
+// which status item was clicked, and with which mouse button. A status item
+// with a menu shows it instead.
+@interface ShinyTrayTarget : NSObject
+@property(assign) NSStatusItem* statusItem;
+- (void)click:(id)sender;
+@end
+
+@implementation ShinyTrayTarget
+- (void)click:(id)sender {
+	int button = 1;
+	switch (NSApp.currentEvent.type) {
+	case NSEventTypeRightMouseUp:
+		button = 3;
+		break;
+	case NSEventTypeOtherMouseUp:
+		button = 2;
+		break;
+	default:
+		break;
+	}
+	cocoadisplayTrayClicked((uintptr_t)self.statusItem, button);
+}
+@end
+
+uintptr_t newStatusItem(void) {
+	__block NSStatusItem* statusItem;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		statusItem = [[NSStatusBar.systemStatusBar statusItemWithLength:NSSquareStatusItemLength] retain];
+		ShinyTrayTarget* target = [[ShinyTrayTarget alloc] init];
+		target.statusItem = statusItem;
+		// The button does not retain its target, which is released with
+		// the status item.
+		statusItem.button.target = target;
+		statusItem.button.action = @selector(click:);
+		[statusItem.button sendActionOn:NSEventMaskLeftMouseUp|NSEventMaskRightMouseUp|NSEventMaskOtherMouseUp];
+	});
+	return (uintptr_t)statusItem;
+}
+
+void setStatusItemIcon(uintptr_t itemID, void* pix, int size) {
+	NSStatusItem* statusItem = (NSStatusItem*)itemID;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		if (pix == NULL) {
+			statusItem.button.image = nil;
+			return;
+		}
+		NSBitmapImageRep* rep = [[NSBitmapImageRep alloc]
+			initWithBitmapDataPlanes:NULL
+			pixelsWide:size
+			pixelsHigh:size
+			bitsPerSample:8
+			samplesPerPixel:4
+			hasAlpha:YES
+			isPlanar:NO
+			colorSpaceName:NSDeviceRGBColorSpace
+			bytesPerRow:4*size
+			bitsPerPixel:32];
+		memcpy([rep bitmapData], pix, 4*size*size);
+		// The image is drawn in points, at half of its size in pixels.
+		NSImage* img = [[NSImage alloc] initWithSize:NSMakeSize(size/2, size/2)];
+		[img addRepresentation:rep];
+		statusItem.button.image = img;
+		[img release];
+		[rep release];
+	});
+}
+
+void setStatusItemTooltip(uintptr_t itemID, char* tooltip) {
+	NSStatusItem* statusItem = (NSStatusItem*)itemID;
+	NSString* s = [NSString stringWithUTF8String:tooltip];
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		statusItem.button.toolTip = s.length > 0 ? s : nil;
+	});
+}
+
+void releaseStatusItem(uintptr_t itemID) {
+	NSStatusItem* statusItem = (NSStatusItem*)itemID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		id target = statusItem.button.target;
+		statusItem.button.target = nil;
+		[target release];
+		[NSStatusBar.systemStatusBar removeStatusItem:statusItem];
+		[statusItem release];
+	});
+}
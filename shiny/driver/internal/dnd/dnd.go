@@ -0,0 +1,135 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dnd converts between screen.DragData and the MIME-typed data that
+// drag-and-drop protocols, such as X11's XDND and Wayland's, transfer.
+package dnd // import "golang.org/x/exp/shiny/driver/internal/dnd"
+
+import (
+	"bytes"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+const (
+	// URIList is the MIME type of a list of URIs, one per line, as specified
+	// by RFC 2483. Dragged files are file URIs.
+	URIList = "text/uri-list"
+	// Text is the MIME type of UTF-8 text.
+	Text = "text/plain;charset=utf-8"
+	// utf8String is the X11 target for UTF-8 text, which some X11 drag
+	// sources offer instead of a MIME type.
+	utf8String = "UTF8_STRING"
+)
+
+// isText returns whether typ is a type of UTF-8 text. Plain "text/plain" has
+// no particular charset, but is UTF-8 in practice.
+func isText(typ string) bool {
+	return typ == Text || typ == "text/plain" || typ == utf8String
+}
+
+// Wanted returns those of the offered types whose data Decode uses, in the
+// order to ask for them. Only one type of text is wanted, preferring Text.
+func Wanted(offered []string) []string {
+	text := ""
+	var wanted []string
+	for _, typ := range offered {
+		switch {
+		case isText(typ):
+			if text == "" || typ == Text {
+				text = typ
+			}
+		case strings.Contains(typ, "/"):
+			// Anything else that is not a MIME type, such as an X11
+			// target like TARGETS, is not data.
+			wanted = append(wanted, typ)
+		}
+	}
+	if text != "" {
+		wanted = append(wanted, text)
+	}
+	return wanted
+}
+
+// Decode returns the DragData of the received data, keyed by type. The files
+// are those of the URIList's file URIs.
+func Decode(data map[string][]byte) screen.DragData {
+	var d screen.DragData
+	for typ, b := range data {
+		switch {
+		case typ == URIList:
+			d.Files = ParseURIList(b)
+		case isText(typ):
+			if d.Text == "" || typ == Text {
+				d.Text = string(b)
+			}
+		default:
+			if d.Other == nil {
+				d.Other = map[string][]byte{}
+			}
+			d.Other[typ] = b
+		}
+	}
+	return d
+}
+
+// Encode returns the types and data, keyed by type, with which to offer d,
+// in the order to offer them.
+func Encode(d screen.DragData) (types []string, data map[string][]byte) {
+	data = map[string][]byte{}
+	if len(d.Files) != 0 {
+		types = append(types, URIList)
+		data[URIList] = FormatURIList(d.Files)
+	}
+	if d.Text != "" {
+		types = append(types, Text, "text/plain")
+		data[Text] = []byte(d.Text)
+		data["text/plain"] = data[Text]
+	}
+	other := make([]string, 0, len(d.Other))
+	for typ := range d.Other {
+		if _, ok := data[typ]; !ok {
+			other = append(other, typ)
+		}
+	}
+	sort.Strings(other)
+	for _, typ := range other {
+		types = append(types, typ)
+		data[typ] = d.Other[typ]
+	}
+	return types, data
+}
+
+// ParseURIList returns the paths of the local files in a text/uri-list.
+// Other URIs, such as those of web pages or of other hosts' files, are
+// skipped.
+func ParseURIList(b []byte) []string {
+	var files []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+			continue
+		}
+		files = append(files, u.Path)
+	}
+	return files
+}
+
+// FormatURIList returns a text/uri-list of the file URIs of the given paths.
+func FormatURIList(files []string) []byte {
+	var buf bytes.Buffer
+	for _, f := range files {
+		u := url.URL{Scheme: "file", Path: f}
+		buf.WriteString(u.String())
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dnd
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestURIList(t *testing.T) {
+	files := []string{"/home/gopher/a b.txt", "/tmp/日本.png"}
+	b := FormatURIList(files)
+	if got, want := string(b), "file:///home/gopher/a%20b.txt\r\nfile:///tmp/%E6%97%A5%E6%9C%AC.png\r\n"; got != want {
+		t.Errorf("FormatURIList: got %q, want %q", got, want)
+	}
+	if got := ParseURIList(b); !reflect.DeepEqual(got, files) {
+		t.Errorf("ParseURIList: got %q, want %q", got, files)
+	}
+
+	const list = "# A comment.\r\nhttps://go.dev/\r\nfile://otherhost/x\r\nfile://localhost/y\nfile:/z"
+	if got, want := ParseURIList([]byte(list)), []string{"/y", "/z"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseURIList(%q): got %q, want %q", list, got, want)
+	}
+}
+
+func TestWanted(t *testing.T) {
+	offered := []string{"TARGETS", "text/plain", "text/uri-list", "UTF8_STRING", "text/plain;charset=utf-8", "image/png"}
+	got := Wanted(offered)
+	want := []string{"text/uri-list", "image/png", "text/plain;charset=utf-8"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	d := screen.DragData{
+		Files: []string{"/a", "/b"},
+		Text:  "Hello, 世界",
+		Other: map[string][]byte{
+			"image/png": {0x89, 'P', 'N', 'G'},
+			"text/html": []byte("<b>Hello</b>"),
+		},
+	}
+	types, data := Encode(d)
+	wantTypes := []string{URIList, Text, "text/plain", "image/png", "text/html"}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("Encode types: got %q, want %q", types, wantTypes)
+	}
+	received := map[string][]byte{}
+	for _, typ := range Wanted(types) {
+		received[typ] = data[typ]
+	}
+	if got := Decode(received); !reflect.DeepEqual(got, d) {
+		t.Errorf("Decode: got %+v, want %+v", got, d)
+	}
+}
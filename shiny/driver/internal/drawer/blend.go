@@ -0,0 +1,106 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/image/math/f64"
+)
+
+// factor is a blend factor, multiplying either the source or destination
+// color of a blend.
+type factor uint8
+
+const (
+	zero factor = iota
+	one
+	srcAlpha
+	oneMinusSrcAlpha
+	dstAlpha
+	oneMinusDstAlpha
+	dstColor
+	oneMinusSrcColor
+)
+
+// blendFactors are the source and destination factors of each
+// screen.BlendMode, as documented there.
+var blendFactors = [screen.NumBlendModes][2]factor{
+	screen.BlendClear:    {zero, zero},
+	screen.BlendSrc:      {one, zero},
+	screen.BlendDst:      {zero, one},
+	screen.BlendSrcOver:  {one, oneMinusSrcAlpha},
+	screen.BlendDstOver:  {oneMinusDstAlpha, one},
+	screen.BlendSrcIn:    {dstAlpha, zero},
+	screen.BlendDstIn:    {zero, srcAlpha},
+	screen.BlendSrcOut:   {oneMinusDstAlpha, zero},
+	screen.BlendDstOut:   {zero, oneMinusSrcAlpha},
+	screen.BlendSrcAtop:  {dstAlpha, oneMinusSrcAlpha},
+	screen.BlendDstAtop:  {oneMinusDstAlpha, srcAlpha},
+	screen.BlendXor:      {oneMinusDstAlpha, oneMinusSrcAlpha},
+	screen.BlendAdd:      {one, one},
+	screen.BlendMultiply: {dstColor, oneMinusSrcAlpha},
+	screen.BlendScreen:   {one, oneMinusSrcColor},
+}
+
+// eval returns the value, in the range [0, 0xff], of f for the channel c of
+// the alpha-premultiplied source and destination pixels s and d.
+func (f factor) eval(c int, s, d *[4]uint32) uint32 {
+	switch f {
+	case one:
+		return 0xff
+	case srcAlpha:
+		return s[3]
+	case oneMinusSrcAlpha:
+		return 0xff - s[3]
+	case dstAlpha:
+		return d[3]
+	case oneMinusDstAlpha:
+		return 0xff - d[3]
+	case dstColor:
+		return d[c]
+	case oneMinusSrcColor:
+		return 0xff - s[c]
+	}
+	return 0
+}
+
+// transformBounds returns the smallest rectangle containing sr transformed by
+// src2dst.
+func transformBounds(src2dst *f64.Aff3, sr image.Rectangle) image.Rectangle {
+	minX, minY := math.Inf(+1), math.Inf(+1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range [4]image.Point{
+		sr.Min, {sr.Max.X, sr.Min.Y}, sr.Max, {sr.Min.X, sr.Max.Y},
+	} {
+		x := src2dst[0]*float64(p.X) + src2dst[1]*float64(p.Y) + src2dst[2]
+		y := src2dst[3]*float64(p.X) + src2dst[4]*float64(p.Y) + src2dst[5]
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	return image.Rect(
+		int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX)), int(math.Ceil(maxY)),
+	)
+}
+
+// ScaleColor returns c with its alpha-premultiplied channels scaled by
+// alpha / 0xffff.
+func ScaleColor(c color.Color, alpha uint16) color.Color {
+	if alpha == 0xffff {
+		return c
+	}
+	r, g, b, a := c.RGBA()
+	m := uint32(alpha)
+	return color.RGBA64{
+		uint16(r * m / 0xffff),
+		uint16(g * m / 0xffff),
+		uint16(b * m / 0xffff),
+		uint16(a * m / 0xffff),
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+func TestTransform(t *testing.T) {
+	src := image.NewUniform(color.RGBA{0x80, 0x00, 0x00, 0x80})
+	dstColor := color.RGBA{0x00, 0x40, 0x00, 0xff}
+
+	testCases := []struct {
+		desc string
+		opts *screen.DrawOptions
+		want color.RGBA
+	}{
+		{"nil", nil, color.RGBA{0x80, 0x1f, 0x00, 0xff}},
+		{"src", &screen.DrawOptions{Blend: screen.BlendSrc}, color.RGBA{0x80, 0x00, 0x00, 0x80}},
+		{"clear", &screen.DrawOptions{Blend: screen.BlendClear}, color.RGBA{}},
+		{"dst", &screen.DrawOptions{Blend: screen.BlendDst}, dstColor},
+		{"dstOut", &screen.DrawOptions{Blend: screen.BlendDstOut}, color.RGBA{0x00, 0x1f, 0x00, 0x7f}},
+		{"add", &screen.DrawOptions{Blend: screen.BlendAdd}, color.RGBA{0x80, 0x40, 0x00, 0xff}},
+		{"multiply", &screen.DrawOptions{Blend: screen.BlendMultiply}, color.RGBA{0x00, 0x1f, 0x00, 0xff}},
+		{"screen", &screen.DrawOptions{Blend: screen.BlendScreen}, color.RGBA{0x80, 0x40, 0x00, 0xff}},
+		{"transparent", &screen.DrawOptions{Transparency: 0xffff}, dstColor},
+		{"halfTransparent", &screen.DrawOptions{Transparency: 0x8000}, color.RGBA{0x3f, 0x30, 0x00, 0xff}},
+	}
+	for _, tc := range testCases {
+		dst := image.NewRGBA(image.Rect(0, 0, 4, 1))
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(dstColor), image.Point{}, draw.Src)
+
+		// Draw onto only the middle two pixels.
+		var c Clip
+		c.Transform(xdraw.NearestNeighbor, dst, f64.Aff3{1, 0, 1, 0, 1, 0}, src, image.Rect(0, 0, 2, 1), draw.Over, tc.opts)
+
+		for x := 0; x < 4; x++ {
+			want := tc.want
+			if x == 0 || x == 3 {
+				want = dstColor
+			}
+			if got := dst.RGBAAt(x, 0); got != want {
+				t.Errorf("%s: x=%d: got %v, want %v", tc.desc, x, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/exp/shiny/screen"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/vector"
+)
+
+// Clip is a clip stack, implementing the screen.Clipper interface, for
+// Drawers backed by an *image.RGBA. Its Transform and Fill methods draw
+// subject to it. The zero value is an empty stack, which clips nothing.
+type Clip struct {
+	// Linear is whether Transform and Fill blend in linear light, as for
+	// screen.NewWindowOptions.LinearBlending, instead of on the sRGB-encoded
+	// values of dst's pixels.
+	Linear bool
+
+	stack []clipRegion
+}
+
+// clipRegion is the intersection of the regions pushed so far. Drawing is
+// clipped to r and, if mask is non-nil, by mask, whose bounds contain r.
+type clipRegion struct {
+	r    image.Rectangle
+	mask *image.Alpha
+}
+
+// PushClip implements screen.Clipper.
+func (c *Clip) PushClip(r image.Rectangle) {
+	var mask *image.Alpha
+	if n := len(c.stack); n > 0 {
+		r = r.Intersect(c.stack[n-1].r)
+		mask = c.stack[n-1].mask
+	}
+	c.stack = append(c.stack, clipRegion{r, mask})
+}
+
+// PushClipPath implements screen.Clipper. The path is rasterized with
+// anti-aliasing.
+func (c *Clip) PushClipPath(src2dst f64.Aff3, p *screen.Path) {
+	var contours [][]f64.Vec2
+	minX, minY := math.Inf(+1), math.Inf(+1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	p.Flatten(src2dst, 0, func(contour []f64.Vec2) {
+		contours = append(contours, append([]f64.Vec2(nil), contour...))
+		for _, v := range contour {
+			minX, maxX = math.Min(minX, v[0]), math.Max(maxX, v[0])
+			minY, maxY = math.Min(minY, v[1]), math.Max(maxY, v[1])
+		}
+	})
+	var r image.Rectangle
+	if len(contours) > 0 {
+		r = image.Rect(
+			int(math.Floor(minX)), int(math.Floor(minY)),
+			int(math.Ceil(maxX)), int(math.Ceil(maxY)),
+		)
+	}
+	var prev *image.Alpha
+	if n := len(c.stack); n > 0 {
+		r = r.Intersect(c.stack[n-1].r)
+		prev = c.stack[n-1].mask
+	}
+	if r.Empty() {
+		c.stack = append(c.stack, clipRegion{})
+		return
+	}
+
+	z := vector.NewRasterizer(r.Dx(), r.Dy())
+	ox, oy := float64(r.Min.X), float64(r.Min.Y)
+	for _, contour := range contours {
+		z.MoveTo(float32(contour[0][0]-ox), float32(contour[0][1]-oy))
+		for _, v := range contour[1:] {
+			z.LineTo(float32(v[0]-ox), float32(v[1]-oy))
+		}
+		z.ClosePath()
+	}
+	mask := image.NewAlpha(r)
+	z.Draw(mask, r, image.Opaque, image.Point{})
+	if prev != nil {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				i := mask.PixOffset(x, y)
+				mask.Pix[i] = uint8(uint32(mask.Pix[i]) * uint32(prev.Pix[prev.PixOffset(x, y)]) / 0xff)
+			}
+		}
+	}
+	c.stack = append(c.stack, clipRegion{r, mask})
+}
+
+// PopClip implements screen.Clipper.
+func (c *Clip) PopClip() {
+	if n := len(c.stack); n > 0 {
+		c.stack = c.stack[:n-1]
+	}
+}
+
+// Fill implements the Fill method of the screen.Uploader interface, onto dst.
+func (c *Clip) Fill(dst *image.RGBA, dr image.Rectangle, src color.Color, op draw.Op) {
+	if len(c.stack) == 0 && (!c.Linear || op == draw.Src) {
+		draw.Draw(dst, dr, image.NewUniform(src), image.Point{}, op)
+		return
+	}
+	c.Transform(xdraw.NearestNeighbor, dst, f64.Aff3{1, 0, 0, 0, 1, 0}, image.NewUniform(src), dr, op, nil)
+}
+
+// Transform implements the Draw and DrawUniform methods of the screen.Drawer
+// interface, onto dst, by calling t's Transform method.
+//
+// If opts asks for a blend mode other than BlendSrc or BlendSrcOver, or for
+// transparency, or if the clip is not rectangular, or if c.Linear is set and
+// the blend mode is not BlendSrc, then the sampled source is composited in
+// software instead.
+func (c *Clip) Transform(t xdraw.Transformer, dst *image.RGBA, src2dst f64.Aff3, src image.Image, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	clip := clipRegion{r: dst.Bounds()}
+	if n := len(c.stack); n > 0 {
+		clip = c.stack[n-1]
+		clip.r = clip.r.Intersect(dst.Bounds())
+		if clip.r.Empty() {
+			return
+		}
+	}
+
+	mode, alpha := opts.GetBlend(op), uint32(opts.GetAlpha())
+	if alpha == 0xffff && clip.mask == nil {
+		// Clipping a rectangle is just drawing onto a sub-image.
+		d := dst.SubImage(clip.r).(*image.RGBA)
+		switch {
+		case mode == screen.BlendSrc:
+			transform(t, d, src2dst, src, sr, draw.Src)
+			return
+		case mode == screen.BlendSrcOver && !c.Linear:
+			transform(t, d, src2dst, src, sr, draw.Over)
+			return
+		}
+	}
+
+	dr := transformBounds(&src2dst, sr).Intersect(clip.r)
+	if dr.Empty() {
+		return
+	}
+	// Sample the source, and which dst pixels it covers, into scratch images.
+	tmp := image.NewRGBA(dr)
+	transform(t, tmp, src2dst, src, sr, draw.Src)
+	cov := image.NewAlpha(dr)
+	transform(t, cov, src2dst, image.Opaque, sr, draw.Src)
+	if c.Linear {
+		compositeLinear(dst, tmp, cov, clip.mask, dr, mode, alpha)
+		return
+	}
+
+	fs, fd := blendFactors[mode][0], blendFactors[mode][1]
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			m := uint32(cov.Pix[cov.PixOffset(x, y)])
+			if clip.mask != nil {
+				m = m * uint32(clip.mask.Pix[clip.mask.PixOffset(x, y)]) / 0xff
+			}
+			if m == 0 {
+				continue
+			}
+			sp := tmp.Pix[tmp.PixOffset(x, y):]
+			dp := dst.Pix[dst.PixOffset(x, y):]
+			var s, d [4]uint32
+			for i := range s {
+				s[i] = uint32(sp[i]) * alpha / 0xffff
+				d[i] = uint32(dp[i])
+			}
+			for i := range s {
+				r := (s[i]*fs.eval(i, &s, &d) + d[i]*fd.eval(i, &s, &d)) / 0xff
+				if r > 0xff {
+					r = 0xff
+				}
+				// Interpolate by the coverage, for the quad's and the clip's
+				// edges.
+				dp[i] = uint8((d[i]*(0xff-m) + r*m) / 0xff)
+			}
+		}
+	}
+}
+
+// transform is t.Transform, except that it copies whole-pixel translations
+// itself: x/image/draw's short cut for those offsets the destination by
+// sr.Min.X, rather than sr.Min.Y, vertically.
+func transform(t xdraw.Transformer, dst xdraw.Image, src2dst f64.Aff3, src image.Image, sr image.Rectangle, op draw.Op) {
+	if src2dst[0] == 1 && src2dst[1] == 0 && src2dst[3] == 0 && src2dst[4] == 1 {
+		dx, dy := int(src2dst[2]), int(src2dst[5])
+		if float64(dx) == src2dst[2] && float64(dy) == src2dst[5] {
+			xdraw.Copy(dst, sr.Min.Add(image.Point{dx, dy}), src, sr, op, nil)
+			return
+		}
+	}
+	t.Transform(dst, src2dst, src, sr, op, nil)
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+func TestClip(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	dst := image.NewRGBA(image.Rect(0, 0, 8, 8))
+
+	var c Clip
+	c.PushClip(image.Rect(0, 0, 6, 8))
+	p := &screen.Path{}
+	// A triangle covering the top-right half of the square (2, 2)-(8, 8).
+	p.MoveTo(1, 1)
+	p.LineTo(4, 1)
+	p.LineTo(4, 4)
+	p.Close()
+	c.PushClipPath(f64.Aff3{2, 0, 0, 0, 2, 0}, p)
+	c.Fill(dst, dst.Bounds(), white, draw.Src)
+	c.PopClip()
+	c.PopClip()
+	// With an empty clip stack, nothing is clipped.
+	c.Fill(dst, image.Rect(0, 7, 1, 8), white, draw.Src)
+
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			var want uint8
+			switch {
+			case x == 0 && y == 7:
+				want = 0xff
+			case x >= 6 || y < 2:
+				// Outside the rectangle or above the triangle.
+			case x > y:
+				want = 0xff
+			case x == y:
+				// A partially covered pixel, on the triangle's diagonal.
+				want = 0x7f
+			}
+			got := dst.RGBAAt(x, y).A
+			if want == 0x7f {
+				if got == 0 || got == 0xff {
+					t.Errorf("(%d, %d): got alpha %#02x, want partial", x, y, got)
+				}
+			} else if got != want {
+				t.Errorf("(%d, %d): got alpha %#02x, want %#02x", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestTransformTranslation(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(src, image.Rect(4, 0, 6, 2), image.Black, image.Point{}, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, 8, 8))
+
+	// Drawing sr = (4, 0)-(6, 2) translated by (-3, 2) fills (1, 2)-(3, 4).
+	var c Clip
+	c.Transform(xdraw.NearestNeighbor, dst, f64.Aff3{1, 0, -3, 0, 1, 2}, src, image.Rect(4, 0, 6, 2), draw.Over, nil)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			painted := dst.RGBAAt(x, y).A != 0
+			if want := image.Pt(x, y).In(image.Rect(1, 2, 3, 4)); painted != want {
+				t.Errorf("(%d, %d): painted: got %t, want %t", x, y, painted, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"image"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// CompressedBytes returns the number of bytes of a Texture of the given size
+// whose pixels are compressed in format f.
+func CompressedBytes(f screen.CompressedFormat, size image.Point) int64 {
+	b := f.BlockSize()
+	cols, rows := (size.X+b.X-1)/b.X, (size.Y+b.Y-1)/b.Y
+	return int64(cols) * int64(rows) * int64(f.BlockBytes())
+}
+
+// ClipCompressed implements the clipping of the UploadCompressed method of
+// the screen.CompressedUploader interface, onto a Texture of the given size
+// and compressed format f. It returns the destination rectangle, and src's
+// blocks that cover it, packed so that each row of blocks directly follows
+// the previous one, sharing src's data if they already are. It returns a nil
+// pix if the upload is to be ignored.
+func ClipCompressed(size image.Point, f screen.CompressedFormat, dp image.Point, src *screen.CompressedImage, sr image.Rectangle) (dr image.Rectangle, pix []byte) {
+	if src.Format != f || f == screen.CompressedNone {
+		return image.Rectangle{}, nil
+	}
+	src2dst := dp.Sub(sr.Min)
+	sr = sr.Intersect(src.Rect)
+	dr = sr.Add(src2dst).Intersect(image.Rectangle{Max: size})
+	if dr.Empty() {
+		return image.Rectangle{}, nil
+	}
+	sp := dr.Min.Sub(src2dst)
+
+	b := f.BlockSize()
+	o := sp.Sub(src.Rect.Min)
+	if o.X%b.X != 0 || o.Y%b.Y != 0 || dr.Min.X%b.X != 0 || dr.Min.Y%b.Y != 0 ||
+		(dr.Max.X%b.X != 0 && dr.Max.X != size.X) || (dr.Max.Y%b.Y != 0 && dr.Max.Y != size.Y) {
+		return image.Rectangle{}, nil
+	}
+
+	cols, rows := (dr.Dx()+b.X-1)/b.X, (dr.Dy()+b.Y-1)/b.Y
+	rowBytes := cols * f.BlockBytes()
+	i := src.PixOffset(sp.X, sp.Y)
+	if i+(rows-1)*src.Stride+rowBytes > len(src.Pix) {
+		return image.Rectangle{}, nil
+	}
+	if src.Stride == rowBytes {
+		return dr, src.Pix[i : i+rows*rowBytes]
+	}
+	pix = make([]byte, rows*rowBytes)
+	for y := 0; y < rows; y++ {
+		copy(pix[y*rowBytes:(y+1)*rowBytes], src.Pix[i+y*src.Stride:])
+	}
+	return dr, pix
+}
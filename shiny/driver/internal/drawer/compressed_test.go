@@ -0,0 +1,67 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestClipCompressed(t *testing.T) {
+	// src is 3x2 blocks of BC1, each of whose 8 bytes are its index, with
+	// 8 bytes of padding at the end of each row.
+	src := &screen.CompressedImage{
+		Format: screen.CompressedBC1,
+		Pix:    make([]byte, 2*32),
+		Stride: 32,
+		Rect:   image.Rect(4, 0, 14, 8),
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			copy(src.Pix[y*32+x*8:], bytes.Repeat([]byte{byte(3*y + x)}, 8))
+		}
+	}
+	// blocks returns the bytes of the given blocks of src.
+	blocks := func(indexes ...byte) []byte {
+		var b []byte
+		for _, i := range indexes {
+			b = append(b, bytes.Repeat([]byte{i}, 8)...)
+		}
+		return b
+	}
+	size := image.Point{10, 10}
+
+	testCases := []struct {
+		desc    string
+		dp      image.Point
+		sr      image.Rectangle
+		wantDR  image.Rectangle
+		wantPix []byte
+	}{
+		{"all", image.Point{}, src.Rect, image.Rect(0, 0, 10, 8), blocks(0, 1, 2, 3, 4, 5)},
+		{"clipped to dst", image.Point{4, 4}, src.Rect, image.Rect(4, 4, 10, 10), blocks(0, 1, 3, 4)},
+		{"one block", image.Point{8, 0}, image.Rect(8, 4, 12, 8), image.Rect(8, 0, 10, 4), blocks(4)},
+		{"misaligned dst", image.Point{2, 0}, src.Rect, image.Rectangle{}, nil},
+		{"misaligned src", image.Point{}, image.Rect(6, 0, 10, 4), image.Rectangle{}, nil},
+		{"partial block", image.Point{}, image.Rect(4, 0, 6, 4), image.Rectangle{}, nil},
+		{"outside", image.Point{20, 0}, src.Rect, image.Rectangle{}, nil},
+	}
+	for _, tc := range testCases {
+		dr, pix := ClipCompressed(size, screen.CompressedBC1, tc.dp, src, tc.sr)
+		if dr != tc.wantDR || !bytes.Equal(pix, tc.wantPix) {
+			t.Errorf("%s: got %v, %v, want %v, %v", tc.desc, dr, pix, tc.wantDR, tc.wantPix)
+		}
+	}
+
+	if _, pix := ClipCompressed(size, screen.CompressedBC3, image.Point{}, src, src.Rect); pix != nil {
+		t.Errorf("mismatched format: got %v, want nil", pix)
+	}
+	if got, want := CompressedBytes(screen.CompressedBC3, size), int64(9*16); got != want {
+		t.Errorf("CompressedBytes: got %d, want %d", got, want)
+	}
+}
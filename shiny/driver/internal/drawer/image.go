@@ -0,0 +1,95 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"image"
+	"image/draw"
+	"sync"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// ImagePool implements the DrawImage method of the screen.Window interface by
+// converting the image to a Buffer, uploading that to a Texture and calling
+// the Copy method of the screen.Drawer interface. The Buffer and Texture are
+// re-used by subsequent calls, growing as necessary.
+//
+// The zero value is ready to use.
+type ImagePool struct {
+	mu  sync.Mutex
+	buf screen.Buffer
+	tex screen.Texture
+}
+
+// DrawImage implements the DrawImage method of the screen.Window interface.
+// The Screen s is used to allocate the pooled Buffer and Texture.
+//
+// If that allocation fails, nothing is drawn.
+func (p *ImagePool) DrawImage(s screen.Screen, dst screen.Drawer, dp image.Point, src image.Image, op draw.Op, opts *screen.DrawOptions) {
+	sb := src.Bounds()
+	if sb.Empty() {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.grow(s, sb.Size()); err != nil {
+		return
+	}
+	sr := image.Rectangle{Max: sb.Size()}
+	draw.Draw(p.buf.RGBA(), sr, src, sb.Min, draw.Src)
+	p.tex.Upload(image.Point{}, p.buf, sr)
+	dst.Copy(dp, p.tex, sr, op, opts)
+}
+
+// grow ensures that p's Buffer and Texture are at least as large as size.
+func (p *ImagePool) grow(s screen.Screen, size image.Point) error {
+	if p.tex != nil {
+		old := p.tex.Size()
+		if old.X >= size.X && old.Y >= size.Y {
+			return nil
+		}
+		if size.X < old.X {
+			size.X = old.X
+		}
+		if size.Y < old.Y {
+			size.Y = old.Y
+		}
+		p.release()
+	}
+
+	buf, err := s.NewBuffer(size)
+	if err != nil {
+		return err
+	}
+	tex, err := s.NewTexture(size)
+	if err != nil {
+		buf.Release()
+		return err
+	}
+	p.buf, p.tex = buf, tex
+	return nil
+}
+
+// Release releases p's Buffer and Texture, if any. The ImagePool may still be
+// used after Release, in which case they will be re-allocated.
+func (p *ImagePool) Release() {
+	p.mu.Lock()
+	p.release()
+	p.mu.Unlock()
+}
+
+func (p *ImagePool) release() {
+	if p.tex != nil {
+		p.tex.Release()
+		p.tex = nil
+	}
+	if p.buf != nil {
+		p.buf.Release()
+		p.buf = nil
+	}
+}
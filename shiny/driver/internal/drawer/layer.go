@@ -0,0 +1,199 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+	"sync"
+
+	"golang.org/x/exp/shiny/screen"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// Layers implements the NewLayer method of the screen.Window interface, with
+// each Layer backed by a Texture. The Window's Publish method should call
+// Composite before presenting the window's contents.
+//
+// Drawing on a Layer calls the corresponding method of its Texture, if that
+// Texture implements screen.Drawer. Otherwise, it is emulated by downloading
+// the affected pixels, compositing them in software and uploading the result.
+//
+// The zero value is ready to use.
+type Layers struct {
+	mu sync.Mutex
+	// layers is sorted by z, and then by creation order.
+	layers []*layer
+}
+
+// NewLayer implements the NewLayer method of the screen.Window interface. The
+// Screen s is used to allocate the Layer's Texture.
+func (ls *Layers) NewLayer(s screen.Screen, z int, size image.Point) (screen.Layer, error) {
+	t, err := s.NewTexture(size)
+	if err != nil {
+		return nil, err
+	}
+	t.Fill(t.Bounds(), image.Transparent, draw.Src)
+	d, _ := t.(screen.Drawer)
+	l := &layer{
+		Texture: t,
+		s:       s,
+		d:       d,
+		ls:      ls,
+		z:       z,
+		src2dst: f64.Aff3{1, 0, 0, 0, 1, 0},
+	}
+
+	ls.mu.Lock()
+	i := sort.Search(len(ls.layers), func(i int) bool { return ls.layers[i].z > z })
+	ls.layers = append(ls.layers, nil)
+	copy(ls.layers[i+1:], ls.layers[i:])
+	ls.layers[i] = l
+	ls.mu.Unlock()
+	return l, nil
+}
+
+// Composite draws every Layer on dst, in Z order. It returns whether there
+// were any Layers. If so, the contents of dst are no longer only what the
+// Window's user drew, and its Publish method should not report that the back
+// buffer was preserved.
+func (ls *Layers) Composite(dst screen.Drawer) (composited bool) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	for _, l := range ls.layers {
+		l.mu.Lock()
+		src2dst, transparency := l.src2dst, l.transparency
+		l.mu.Unlock()
+		var opts *screen.DrawOptions
+		if transparency != 0 {
+			opts = &screen.DrawOptions{Transparency: transparency}
+		}
+		dst.Draw(src2dst, l.Texture, l.Bounds(), draw.Over, opts)
+	}
+	return len(ls.layers) != 0
+}
+
+// Release releases every Layer.
+func (ls *Layers) Release() {
+	ls.mu.Lock()
+	layers := ls.layers
+	ls.layers = nil
+	ls.mu.Unlock()
+
+	for _, l := range layers {
+		l.Texture.Release()
+	}
+}
+
+type layer struct {
+	screen.Texture
+	s screen.Screen
+	// d is the Texture as a screen.Drawer, or nil if it cannot be drawn on.
+	d  screen.Drawer
+	ls *Layers
+	z  int
+
+	// mu guards src2dst and transparency.
+	mu           sync.Mutex
+	src2dst      f64.Aff3
+	transparency uint16
+}
+
+func (l *layer) Release() {
+	l.ls.mu.Lock()
+	found := false
+	for i, m := range l.ls.layers {
+		if m == l {
+			l.ls.layers = append(l.ls.layers[:i], l.ls.layers[i+1:]...)
+			found = true
+			break
+		}
+	}
+	l.ls.mu.Unlock()
+
+	// If l was not found, it has already been released, possibly by the
+	// Layers' Release method.
+	if found {
+		l.Texture.Release()
+	}
+}
+
+func (l *layer) Z() int {
+	return l.z
+}
+
+func (l *layer) Move(dp image.Point) {
+	l.SetTransform(f64.Aff3{
+		1, 0, float64(dp.X),
+		0, 1, float64(dp.Y),
+	})
+}
+
+func (l *layer) SetTransform(src2dst f64.Aff3) {
+	l.mu.Lock()
+	l.src2dst = src2dst
+	l.mu.Unlock()
+}
+
+func (l *layer) SetTransparency(transparency uint16) {
+	l.mu.Lock()
+	l.transparency = transparency
+	l.mu.Unlock()
+}
+
+func (l *layer) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	if l.d != nil {
+		l.d.Draw(src2dst, src, sr, op, opts)
+		return
+	}
+	m, err := src.Download(sr.Min, sr)
+	if err != nil {
+		return
+	}
+	l.emulate(src2dst, m, m.Rect, op, opts)
+}
+
+func (l *layer) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	if l.d != nil {
+		l.d.DrawUniform(src2dst, src, sr, op, opts)
+		return
+	}
+	l.emulate(src2dst, image.NewUniform(src), sr, op, opts)
+}
+
+func (l *layer) Copy(dp image.Point, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	Copy(l, dp, src, sr, op, opts)
+}
+
+func (l *layer) Scale(dr image.Rectangle, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	Scale(l, dr, src, sr, op, opts)
+}
+
+// emulate draws src on the Layer's Texture, which cannot be drawn on, by
+// compositing the pixels under src's transformed bounds in software.
+func (l *layer) emulate(src2dst f64.Aff3, src image.Image, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	dr := transformBounds(&src2dst, sr).Intersect(l.Bounds())
+	if dr.Empty() {
+		return
+	}
+	dst, err := l.Texture.Download(dr.Min, dr)
+	if err != nil {
+		return
+	}
+	var c Clip
+	c.Transform(xdraw.NearestNeighbor, dst, src2dst, src, sr, op, opts)
+
+	b, err := l.s.NewBuffer(dr.Size())
+	if err != nil {
+		return
+	}
+	defer b.Release()
+	draw.Draw(b.RGBA(), b.Bounds(), dst, dr.Min, draw.Src)
+	l.Texture.Upload(dr.Min, b, b.Bounds())
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// compositeLinear is Transform's software compositing of the sampled source
+// src, and the coverage cov, onto dr of dst, for a Clip whose Linear field is
+// set. The alpha-premultiplied, sRGB-encoded source and destination pixels are
+// decoded to linear light, blended and interpolated by the coverage, and the
+// result is encoded again.
+func compositeLinear(dst, src *image.RGBA, cov, mask *image.Alpha, dr image.Rectangle, mode screen.BlendMode, alpha uint32) {
+	fs, fd := blendFactors[mode][0], blendFactors[mode][1]
+	k := float32(alpha) / 0xffff
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			m := float32(cov.Pix[cov.PixOffset(x, y)]) / 0xff
+			if mask != nil {
+				m *= float32(mask.Pix[mask.PixOffset(x, y)]) / 0xff
+			}
+			if m == 0 {
+				continue
+			}
+			dp := dst.Pix[dst.PixOffset(x, y):]
+			var s, d, r [4]float32
+			decodeLinear(&s, src.Pix[src.PixOffset(x, y):])
+			decodeLinear(&d, dp)
+			for i := range s {
+				s[i] *= k
+			}
+			for i := range r {
+				v := s[i]*fs.evalLinear(i, &s, &d) + d[i]*fd.evalLinear(i, &s, &d)
+				if v > 1 {
+					v = 1
+				}
+				r[i] = d[i]*(1-m) + v*m
+			}
+			encodeLinear(dp, &r)
+		}
+	}
+}
+
+// evalLinear is like eval, but for pixels in linear light, whose channels and
+// factors are in the range [0, 1].
+func (f factor) evalLinear(c int, s, d *[4]float32) float32 {
+	switch f {
+	case one:
+		return 1
+	case srcAlpha:
+		return s[3]
+	case oneMinusSrcAlpha:
+		return 1 - s[3]
+	case dstAlpha:
+		return d[3]
+	case oneMinusDstAlpha:
+		return 1 - d[3]
+	case dstColor:
+		return d[c]
+	case oneMinusSrcColor:
+		return 1 - s[c]
+	}
+	return 0
+}
+
+// decodeLinear sets c to the pixel p[:4], converted from alpha-premultiplied
+// sRGB-encoded bytes to alpha-premultiplied linear light.
+func decodeLinear(c *[4]float32, p []uint8) {
+	a := float32(p[3]) / 0xff
+	c[3] = a
+	for i := 0; i < 3; i++ {
+		if a == 0 {
+			c[i] = 0
+			continue
+		}
+		c[i] = srgbToLinear(float32(p[i])/0xff/a) * a
+	}
+}
+
+// encodeLinear is the inverse of decodeLinear, setting p[:4] to c.
+func encodeLinear(p []uint8, c *[4]float32) {
+	a := c[3]
+	p[3] = uint8(a*0xff + 0.5)
+	for i := 0; i < 3; i++ {
+		if a <= 0 {
+			p[i] = 0
+			continue
+		}
+		v := linearToSRGB(c[i]/a) * a
+		if v > a {
+			// Keep p alpha-premultiplied, despite rounding.
+			v = a
+		}
+		p[i] = uint8(v*0xff + 0.5)
+	}
+}
+
+// srgbToLinear and linearToSRGB convert a channel, in the range [0, 1],
+// between the sRGB transfer function's encoding and linear light.
+func srgbToLinear(v float32) float32 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return float32(math.Pow((float64(v)+0.055)/1.055, 2.4))
+}
+
+func linearToSRGB(v float32) float32 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return float32(1.055*math.Pow(float64(v), 1/2.4) - 0.055)
+}
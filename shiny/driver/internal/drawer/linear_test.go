@@ -0,0 +1,62 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestFillLinear(t *testing.T) {
+	black := color.RGBA{0x00, 0x00, 0x00, 0xff}
+	halfWhite := color.RGBA{0x80, 0x80, 0x80, 0x80}
+	testCases := []struct {
+		linear bool
+		op     draw.Op
+		want   uint8
+	}{
+		// Blending sRGB-encoded values gives a dark gray, of half the
+		// encoded value.
+		{false, draw.Over, 0x80},
+		// Half of white, in linear light, encodes to a lighter gray.
+		{true, draw.Over, 0xbc},
+		// Without blending, Linear makes no difference.
+		{false, draw.Src, 0x80},
+		{true, draw.Src, 0x80},
+	}
+	for _, tc := range testCases {
+		dst := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(black), image.Point{}, draw.Src)
+		c := Clip{Linear: tc.linear}
+		c.Fill(dst, image.Rect(1, 1, 3, 3), halfWhite, tc.op)
+
+		if got := dst.RGBAAt(0, 0); got != black {
+			t.Errorf("linear=%t, op=%v: outside pixel: got %v, want %v", tc.linear, tc.op, got, black)
+		}
+		got := dst.RGBAAt(1, 1)
+		if d := int(got.R) - int(tc.want); d < -1 || d > +1 || got.R != got.G || got.R != got.B {
+			t.Errorf("linear=%t, op=%v: got %v, want gray %#02x", tc.linear, tc.op, got, tc.want)
+		}
+	}
+}
+
+func TestLinearRoundTrip(t *testing.T) {
+	for a := 0; a < 0x100; a += 0x11 {
+		for v := 0; v <= a; v++ {
+			p := []uint8{uint8(v), uint8(v), uint8(v), uint8(a)}
+			var c [4]float32
+			decodeLinear(&c, p)
+			q := make([]uint8, 4)
+			encodeLinear(q, &c)
+			for i := range p {
+				if d := int(q[i]) - int(p[i]); d < -1 || d > +1 {
+					t.Fatalf("%v: round trip gave %v", p, q)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"image"
+	"image/draw"
+
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// NewImage returns a new image with the given bounds whose pixels are stored
+// in the given format. A PixelFormatBGRA8 image is stored as RGBA, since
+// images reorder channels as they are drawn.
+func NewImage(format screen.PixelFormat, r image.Rectangle) draw.Image {
+	switch format {
+	case screen.PixelFormatAlpha8:
+		return image.NewAlpha(r)
+	case screen.PixelFormatGray8:
+		return image.NewGray(r)
+	case screen.PixelFormatRGBA64:
+		return image.NewRGBA64(r)
+	}
+	return image.NewRGBA(r)
+}
+
+// clipPixels clips sr to src's bounds, and dp by the same amount, returning
+// the empty rectangle if nothing remains.
+func clipPixels(dp image.Point, src *screen.Pixels, sr image.Rectangle) (image.Point, image.Rectangle) {
+	originalSRMin := sr.Min
+	sr = sr.Intersect(src.Rect)
+	return dp.Add(sr.Min.Sub(originalSRMin)), sr
+}
+
+// pixelsImage returns an image whose pixels within sr are those of src. It
+// shares src's pixel data unless they are PixelFormatBGRA8, which it copies
+// and swizzles.
+func pixelsImage(src *screen.Pixels, sr image.Rectangle) image.Image {
+	switch src.Format {
+	case screen.PixelFormatAlpha8:
+		return &image.Alpha{Pix: src.Pix, Stride: src.Stride, Rect: src.Rect}
+	case screen.PixelFormatGray8:
+		return &image.Gray{Pix: src.Pix, Stride: src.Stride, Rect: src.Rect}
+	case screen.PixelFormatRGBA64:
+		return &image.RGBA64{Pix: src.Pix, Stride: src.Stride, Rect: src.Rect}
+	case screen.PixelFormatBGRA8:
+		m := image.NewRGBA(sr)
+		for y := sr.Min.Y; y < sr.Max.Y; y++ {
+			row := m.Pix[m.PixOffset(sr.Min.X, y):][:4*sr.Dx()]
+			swizzle.CopyBGRA(row, src.Pix[src.PixOffset(sr.Min.X, y):][:len(row)])
+		}
+		return m
+	}
+	return &image.RGBA{Pix: src.Pix, Stride: src.Stride, Rect: src.Rect}
+}
+
+// UploadPixels implements the UploadPixels method of the screen.PixelUploader
+// interface, onto dst, which should be an image returned by NewImage. Pixels
+// in dst's own format are copied row by row; others are converted by
+// draw.Draw.
+func UploadPixels(dst draw.Image, dp image.Point, src *screen.Pixels, sr image.Rectangle) {
+	dp, sr = clipPixels(dp, src, sr)
+	if sr.Empty() {
+		return
+	}
+	dr := image.Rectangle{dp, dp.Add(sr.Size())}.Intersect(dst.Bounds())
+	if dr.Empty() {
+		return
+	}
+	sr.Min = sr.Min.Add(dr.Min.Sub(dp))
+
+	if d, ok := screen.PixelsOf(dst); ok && d.Format == src.Format {
+		n := dr.Dx() * d.Format.BytesPerPixel()
+		for y := 0; y < dr.Dy(); y++ {
+			copy(d.Pix[d.PixOffset(dr.Min.X, dr.Min.Y+y):][:n], src.Pix[src.PixOffset(sr.Min.X, sr.Min.Y+y):])
+		}
+		return
+	}
+	draw.Draw(dst, dr, pixelsImage(src, image.Rectangle{sr.Min, sr.Min.Add(dr.Size())}), sr.Min, draw.Src)
+}
+
+// ConvertPixels returns the pixels of src within sr, which must be inside
+// src's bounds, as they would be stored by a texture of the given format,
+// converted to RGBA. It is for drivers whose textures are all stored as RGBA,
+// or as BGRA, whatever their nominal format.
+func ConvertPixels(format screen.PixelFormat, src *screen.Pixels, sr image.Rectangle) *image.RGBA {
+	m := NewImage(format, sr)
+	UploadPixels(m, sr.Min, src, sr)
+	if rgba, ok := m.(*image.RGBA); ok {
+		return rgba
+	}
+	rgba := image.NewRGBA(sr)
+	draw.Draw(rgba, sr, m, sr.Min, draw.Src)
+	return rgba
+}
+
+// UploadPixelsBuffer implements the UploadPixels method of the
+// screen.PixelUploader interface for a dst that can only upload Buffers, by
+// converting src into a Buffer obtained from s.
+func UploadPixelsBuffer(s screen.Screen, dst screen.Uploader, dp image.Point, src *screen.Pixels, sr image.Rectangle) {
+	dp, sr = clipPixels(dp, src, sr)
+	if sr.Empty() {
+		return
+	}
+	b, err := s.NewBuffer(sr.Size())
+	if err != nil {
+		return
+	}
+	defer b.Release()
+	draw.Draw(b.RGBA(), b.Bounds(), pixelsImage(src, sr), sr.Min, draw.Src)
+	dst.Upload(dp, b, b.Bounds())
+}
+
+// RGBA calls f with an *image.RGBA holding dst's pixels, for drawing code
+// that only handles RGBA destinations. If dst, an image returned by NewImage,
+// is in another format, then f's changes are converted back into dst's.
+func RGBA(dst draw.Image, f func(m *image.RGBA)) {
+	if m, ok := dst.(*image.RGBA); ok {
+		f(m)
+		return
+	}
+	m := image.NewRGBA(dst.Bounds())
+	draw.Draw(m, m.Rect, dst, m.Rect.Min, draw.Src)
+	f(m)
+	draw.Draw(dst, m.Rect, m, m.Rect.Min, draw.Src)
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/exp/shiny/screen"
+	xdraw "golang.org/x/image/draw"
+)
+
+// Filter returns the Transformer that samples a texture with the filter f,
+// or def if f is screen.FilterDefault.
+func Filter(f screen.TextureFilter, def xdraw.Transformer) xdraw.Transformer {
+	switch f {
+	case screen.FilterLinear:
+		return xdraw.ApproxBiLinear
+	case screen.FilterNearest:
+		return xdraw.NearestNeighbor
+	}
+	return def
+}
+
+// Wrap returns an image whose pixels are those of m, extended beyond m's
+// bounds as w says. For screen.WrapClamp, it returns m itself, so that draws
+// clip to m's bounds.
+func Wrap(m image.Image, w screen.TextureWrap) image.Image {
+	if w == screen.WrapClamp || m.Bounds().Empty() {
+		return m
+	}
+	return &wrapped{m, w == screen.WrapMirror}
+}
+
+// wrapped is an image that tiles m infinitely, like an image.Uniform tiles a
+// single color.
+type wrapped struct {
+	m      image.Image
+	mirror bool
+}
+
+func (w *wrapped) ColorModel() color.Model { return w.m.ColorModel() }
+func (w *wrapped) Bounds() image.Rectangle { return image.Rect(-1e9, -1e9, 1e9, 1e9) }
+
+func (w *wrapped) At(x, y int) color.Color {
+	b := w.m.Bounds()
+	return w.m.At(wrapCoord(x, b.Min.X, b.Max.X, w.mirror), wrapCoord(y, b.Min.Y, b.Max.Y, w.mirror))
+}
+
+// wrapCoord maps x into the range [min, max), by repeating that range or, if
+// mirror, by reflecting it at every edge.
+func wrapCoord(x, min, max int, mirror bool) int {
+	n := max - min
+	i, tile := (x-min)%n, (x-min)/n
+	if i < 0 {
+		i, tile = i+n, tile-1
+	}
+	if mirror && tile%2 != 0 {
+		i = n - 1 - i
+	}
+	return min + i
+}
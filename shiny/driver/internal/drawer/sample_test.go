@@ -0,0 +1,28 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package drawer
+
+import (
+	"testing"
+)
+
+func TestWrapCoord(t *testing.T) {
+	testCases := []struct {
+		mirror bool
+		want   [12]int
+	}{
+		{false, [12]int{1, 2, 3, 1, 2, 3, 1, 2, 3, 1, 2, 3}},
+		{true, [12]int{3, 2, 1, 1, 2, 3, 3, 2, 1, 1, 2, 3}},
+	}
+	for _, tc := range testCases {
+		// The range [1, 4) is sampled from x = -2 to x = 9.
+		for i, want := range tc.want {
+			x := i - 2
+			if got := wrapCoord(x, 1, 4, tc.mirror); got != want {
+				t.Errorf("mirror=%t: wrapCoord(%d): got %d, want %d", tc.mirror, x, got, want)
+			}
+		}
+	}
+}
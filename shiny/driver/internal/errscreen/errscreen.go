@@ -23,3 +23,22 @@ type stub struct {
 func (s stub) NewBuffer(size image.Point) (screen.Buffer, error)              { return nil, s.err }
 func (s stub) NewTexture(size image.Point) (screen.Texture, error)            { return nil, s.err }
 func (s stub) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) { return nil, s.err }
+func (s stub) SetDefaultWindowOptions(opts *screen.NewWindowOptions)          {}
+func (s stub) TextureMemoryEstimate() int64                                   { return 0 }
+func (s stub) AccessibilityPrefs() screen.AccessibilityPrefs                  { return 0 }
+func (s stub) ColorScheme() screen.ColorScheme                                { return screen.LightColorScheme }
+func (s stub) Clipboard() screen.Clipboard                                    { return clipboard(s) }
+func (s stub) Displays() []screen.Display                                     { return nil }
+
+func (s stub) NewTextureWithOptions(size image.Point, opts *screen.NewTextureOptions) (screen.Texture, error) {
+	return nil, s.err
+}
+
+func (s stub) NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return nil, s.err
+}
+
+type clipboard stub
+
+func (c clipboard) ReadText() (string, error)   { return "", c.err }
+func (c clipboard) WriteText(text string) error { return c.err }
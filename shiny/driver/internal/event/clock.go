@@ -0,0 +1,50 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+// maxClockLag is how far behind now a converted time can be before a Clock
+// assumes that its native clock wrapped, jumped or drifted, and resets.
+const maxClockLag = time.Second
+
+// Clock converts the times of native events, which count from an arbitrary
+// epoch, such as the system's boot, to time.Times that read the same
+// monotonic clock as time.Now. The zero value is usable.
+//
+// The offset between the two clocks is the smallest seen so far, so that an
+// event is never later than when it was converted, and converted times never
+// go backwards.
+type Clock struct {
+	mu     sync.Mutex
+	offset time.Time // The time.Time of the native epoch. Zero if unknown.
+	last   time.Time
+}
+
+// Time converts native, the time of an event since the native epoch.
+func (c *Clock) Time(native time.Duration) time.Time {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := c.offset.Add(native)
+	if c.offset.IsZero() || t.After(now) || now.Sub(t) > maxClockLag {
+		c.offset, t = now.Add(-native), now
+	}
+	if t.Before(c.last) {
+		t = c.last
+	}
+	c.last = t
+	return t
+}
+
+// Millis converts a native time in milliseconds, such as an X11, Wayland or
+// Windows event's time.
+func (c *Clock) Millis(ms uint32) time.Time {
+	return c.Time(time.Duration(ms) * time.Millisecond)
+}
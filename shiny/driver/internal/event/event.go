@@ -7,19 +7,107 @@ package event // import "golang.org/x/exp/shiny/driver/internal/event"
 
 import (
 	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/mouse"
 )
 
-// Deque is an infinitely buffered double-ended queue of events. The zero value
-// is usable, but a Deque value must not be copied.
+// Deque is a buffered double-ended queue of events. The zero value is usable,
+// and unbounded, but a Deque value must not be copied.
 type Deque struct {
+	// Priority, if non-nil, returns the priority of an event passed to Send.
+	// Pending events of higher priority are returned by NextEvent before
+	// those of lower priority. Events of equal priority are returned in FIFO
+	// order. Events passed to SendFirst take precedence over all others. If
+	// nil, every event has the same priority.
+	//
+	// It must be set, if at all, before the Deque is first used.
+	Priority func(event interface{}) int
+
+	// KeepAllMoves is whether to keep every mouse motion event. Otherwise, a
+	// mouse.Event or screen.RelativeMouseEvent that is sent while the last
+	// pending event of its priority is a like event replaces that event,
+	// so that an app that cannot keep up with fast motion sees the latest
+	// position, or the sum of the movements, rather than a backlog.
+	//
+	// It must be set, if at all, before the Deque is first used.
+	KeepAllMoves bool
+
+	// KeyEvents is whether a screen.KeyEvent passed to Send is kept.
+	// Otherwise, it is replaced by its Event, a key.Event, so that drivers
+	// need only send the one event.
+	//
+	// It must be set, if at all, before the Deque is first used.
+	KeyEvents bool
+
+	// Queues, keyed by priority, bound the queues of events passed to Send.
+	// The queues of priorities without an entry are unbounded.
+	//
+	// It must be set, if at all, before the Deque is first used.
+	Queues map[int]screen.EventQueueOptions
+
 	mu    sync.Mutex
-	cond  sync.Cond     // cond.L is lazily initialized to &Deque.mu.
-	back  []interface{} // FIFO.
-	front []interface{} // LIFO.
+	cond  sync.Cond // cond.L is lazily initialized to &Deque.mu.
+	back  []class   // Sorted by decreasing priority.
+	front []entry   // LIFO.
+
+	// space is signaled, if there are blocked senders, when an event is
+	// taken from back. Its L is lazily initialized to &Deque.mu.
+	space   sync.Cond
+	blocked int
+
+	// eventTime is the time of the event that NextUnfilteredEvent most
+	// recently returned.
+	eventTime time.Time
+
+	// filters are the event filters, in the order they were added. The
+	// slice is replaced, not modified, when a filter is added or removed,
+	// so that Filter can call them without holding mu.
+	filters []*filter
+
+	// timers are the Timers that SendLater scheduled, and wakeup fires when
+	// the earliest of them is due. timersStopped is whether StopTimers has
+	// been called. lastFrame is the time of the latest FrameEvent sent.
+	timers        timerHeap
+	wakeup        *time.Timer
+	timersStopped bool
+	lastFrame     time.Time
+}
+
+// filter is an event filter. It is a pointer, so that it can be removed.
+type filter struct {
+	f screen.EventFilter
+}
+
+// class is the FIFO queue of events of a given priority.
+type class struct {
+	priority int
+	events   []entry
+	opts     screen.EventQueueOptions
+	stats    screen.EventQueueStats
+}
+
+// entry is an event and the time that it happened.
+type entry struct {
+	e interface{}
+	t time.Time
 }
 
-// NextEvent implements the screen.EventDeque interface.
+// NextEvent implements the screen.EventDeque interface. It returns the next
+// event that the filters pass on.
 func (q *Deque) NextEvent() interface{} {
+	for {
+		if e := q.Filter(q.NextUnfilteredEvent()); e != nil {
+			return e
+		}
+	}
+}
+
+// NextUnfilteredEvent is like NextEvent, but returns the next event whether or
+// not the filters would pass it on. A driver that needs to see every event
+// calls it, and then Filter.
+func (q *Deque) NextUnfilteredEvent() interface{} {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	if q.cond.L == nil {
@@ -30,29 +118,232 @@ func (q *Deque) NextEvent() interface{} {
 		if n := len(q.front); n > 0 {
 			e := q.front[n-1]
 			q.front = q.front[:n-1]
-			return e
+			q.eventTime = e.t
+			return e.e
 		}
 
-		if n := len(q.back); n > 0 {
-			e := q.back[0]
-			q.back = q.back[1:]
-			return e
+		for i := range q.back {
+			c := &q.back[i]
+			if len(c.events) > 0 {
+				e := c.events[0]
+				c.events[0] = entry{} // Allow e to be garbage collected.
+				c.events = c.events[1:]
+				q.eventTime = e.t
+				if q.blocked > 0 {
+					q.space.Broadcast()
+				}
+				return e.e
+			}
 		}
 
 		q.cond.Wait()
 	}
 }
 
-// Send implements the screen.EventDeque interface.
+// EventTime implements the screen.Window interface.
+func (q *Deque) EventTime() time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.eventTime
+}
+
+// Send implements the screen.EventDeque interface. The event's time is now.
 func (q *Deque) Send(event interface{}) {
+	q.SendAt(event, time.Now())
+}
+
+// SendAt is like Send, but for an event that happened at time t, such as an
+// input event whose time the platform reported. The screen package's input
+// events whose Time is zero are given t as their Time.
+func (q *Deque) SendAt(event interface{}, t time.Time) {
+	switch e := event.(type) {
+	case screen.FrameEvent:
+		q.frameSent(t)
+	case screen.KeyEvent:
+		if !q.KeyEvents {
+			event = e.Event
+		}
+	}
+	q.sendAt(stamp(event, t), t)
+}
+
+// stamp returns event, with its Time set to t if it is one of the screen
+// package's input events and its Time is zero.
+func stamp(event interface{}, t time.Time) interface{} {
+	switch e := event.(type) {
+	case screen.CrossingEvent:
+		if e.Time.IsZero() {
+			e.Time = t
+		}
+		return e
+	case screen.KeyEvent:
+		if e.Time.IsZero() {
+			e.Time = t
+		}
+		return e
+	case screen.PenEvent:
+		if e.Time.IsZero() {
+			e.Time = t
+		}
+		return e
+	case screen.RelativeMouseEvent:
+		if e.Time.IsZero() {
+			e.Time = t
+		}
+		return e
+	case screen.ScrollEvent:
+		if e.Time.IsZero() {
+			e.Time = t
+		}
+		return e
+	case screen.TextEvent:
+		if e.Time.IsZero() {
+			e.Time = t
+		}
+		return e
+	}
+	return event
+}
+
+func (q *Deque) sendAt(event interface{}, t time.Time) {
+	p := 0
+	if q.Priority != nil {
+		p = q.Priority(event)
+	}
+
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	if q.cond.L == nil {
 		q.cond.L = &q.mu
 	}
 
-	q.back = append(q.back, event)
-	q.cond.Signal()
+	for {
+		c := q.class(p)
+		n := len(c.events)
+		if n > 0 && !q.KeepAllMoves {
+			if e, ok := coalesce(c.events[n-1].e, event); ok {
+				c.events[n-1] = entry{e, t}
+				c.stats.Coalesced++
+				return
+			}
+		}
+		if c.opts.Capacity > 0 && n >= c.opts.Capacity {
+			switch c.opts.Overflow {
+			case screen.OverflowBlock:
+				// Other senders may add classes while this one waits,
+				// moving c, so look it up again.
+				if q.space.L == nil {
+					q.space.L = &q.mu
+				}
+				q.blocked++
+				q.space.Wait()
+				q.blocked--
+				continue
+			case screen.OverflowCoalesce:
+				if e, ok := coalesce(c.events[n-1].e, event); ok {
+					c.events[n-1] = entry{e, t}
+					c.stats.Coalesced++
+					return
+				}
+			}
+			c.events[0] = entry{}
+			c.events = c.events[1:]
+			c.stats.Dropped++
+		}
+		c.events = append(c.events, entry{event, t})
+		if len(c.events) > c.stats.MaxPending {
+			c.stats.MaxPending = len(c.events)
+		}
+		q.cond.Signal()
+		return
+	}
+}
+
+// class returns the class of priority p, adding it if there is none. It must
+// be called with q.mu held.
+func (q *Deque) class(p int) *class {
+	// There are typically very few priority classes, so a linear search is
+	// fine.
+	i := 0
+	for ; i < len(q.back) && q.back[i].priority > p; i++ {
+	}
+	if i == len(q.back) || q.back[i].priority != p {
+		q.back = append(q.back, class{})
+		copy(q.back[i+1:], q.back[i:])
+		q.back[i] = class{priority: p, opts: q.Queues[p]}
+		q.back[i].stats.Priority = p
+	}
+	return &q.back[i]
+}
+
+// EventQueueStats implements the screen.Window interface.
+func (q *Deque) EventQueueStats() []screen.EventQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := make([]screen.EventQueueStats, len(q.back))
+	for i := range q.back {
+		stats[i] = q.back[i].stats
+		stats[i].Pending = len(q.back[i].events)
+	}
+	return stats
+}
+
+// coalesce returns the single event that replaces the consecutive events
+// prev and next, if they are mouse motion events that can be merged.
+func coalesce(prev, next interface{}) (interface{}, bool) {
+	switch n := next.(type) {
+	case mouse.Event:
+		p, ok := prev.(mouse.Event)
+		if ok && p.Direction == mouse.DirNone && n.Direction == mouse.DirNone &&
+			p.Button == n.Button && p.Modifiers == n.Modifiers {
+			return n, true
+		}
+	case screen.RelativeMouseEvent:
+		if p, ok := prev.(screen.RelativeMouseEvent); ok {
+			n.DeltaX += p.DeltaX
+			n.DeltaY += p.DeltaY
+			return n, true
+		}
+	}
+	return nil, false
+}
+
+// AddEventFilter implements the screen.Window interface.
+func (q *Deque) AddEventFilter(f screen.EventFilter) (remove func()) {
+	p := &filter{f}
+	q.mu.Lock()
+	q.filters = append(q.filters[:len(q.filters):len(q.filters)], p)
+	q.mu.Unlock()
+
+	return func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		for i, x := range q.filters {
+			if x == p {
+				filters := make([]*filter, 0, len(q.filters)-1)
+				filters = append(filters, q.filters[:i]...)
+				q.filters = append(filters, q.filters[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Filter passes e through the filters, in the order they were added,
+// returning the event that the last of them passes on, or nil if one of them
+// consumed it.
+func (q *Deque) Filter(e interface{}) interface{} {
+	q.mu.Lock()
+	filters := q.filters
+	q.mu.Unlock()
+
+	for _, p := range filters {
+		if e = p.f(e); e == nil {
+			break
+		}
+	}
+	return e
 }
 
 // SendFirst implements the screen.EventDeque interface.
@@ -63,6 +354,6 @@ func (q *Deque) SendFirst(event interface{}) {
 		q.cond.L = &q.mu
 	}
 
-	q.front = append(q.front, event)
+	q.front = append(q.front, entry{event, time.Now()})
 	q.cond.Signal()
 }
@@ -0,0 +1,312 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/mouse"
+)
+
+func TestDequePriority(t *testing.T) {
+	// Events are strings, whose priority is their first byte.
+	q := &Deque{
+		Priority: func(e interface{}) int {
+			return int(e.(string)[0] - '0')
+		},
+	}
+	for _, e := range []string{"0a", "0b", "2a", "1a", "0c", "2b", "1b"} {
+		q.Send(e)
+	}
+	q.SendFirst("0first")
+
+	var got []string
+	for i := 0; i < 8; i++ {
+		got = append(got, q.NextEvent().(string))
+	}
+	want := []string{"0first", "2a", "2b", "1a", "1b", "0a", "0b", "0c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// Sending more events after the queues have drained should work too.
+	q.Send("0d")
+	q.Send("3a")
+	if got, want := q.NextEvent(), "3a"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := q.NextEvent(), "0d"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDequeFIFO(t *testing.T) {
+	q := &Deque{}
+	for i := 0; i < 4; i++ {
+		q.Send(i)
+	}
+	for i := 0; i < 4; i++ {
+		if got := q.NextEvent(); got != i {
+			t.Errorf("got %v, want %d", got, i)
+		}
+	}
+}
+
+func TestDequeFilters(t *testing.T) {
+	q := &Deque{}
+	var seen []string
+	removeObserver := q.AddEventFilter(func(e interface{}) interface{} {
+		seen = append(seen, e.(string))
+		return e
+	})
+	// The second filter consumes "b", and remaps "c" to "C".
+	q.AddEventFilter(func(e interface{}) interface{} {
+		switch e {
+		case "b":
+			return nil
+		case "c":
+			return "C"
+		}
+		return e
+	})
+	// The third filter sends an event after each "a", which it sees only
+	// after the first two filters.
+	q.AddEventFilter(func(e interface{}) interface{} {
+		if e == "a" {
+			q.SendFirst("a2")
+		}
+		return e
+	})
+
+	for _, e := range []string{"a", "b", "c", "d"} {
+		q.Send(e)
+	}
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, q.NextEvent().(string))
+	}
+	if want := []string{"a", "a2", "C", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if want := []string{"a", "a2", "b", "c", "d"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("seen: got %q, want %q", seen, want)
+	}
+
+	// Removing a filter is idempotent, and leaves the others in place.
+	removeObserver()
+	removeObserver()
+	q.Send("b")
+	q.Send("e")
+	if got, want := q.NextEvent(), "e"; got != want {
+		t.Errorf("after removing the observer: got %q, want %q", got, want)
+	}
+	if len(seen) != 5 {
+		t.Errorf("after removing the observer: saw %q", seen[5:])
+	}
+}
+
+func TestDequeCoalesce(t *testing.T) {
+	t0 := time.Now()
+	at := func(ms int) time.Time { return t0.Add(time.Duration(ms) * time.Millisecond) }
+	q := &Deque{}
+	q.SendAt(mouse.Event{X: 1}, at(1))
+	q.SendAt(mouse.Event{X: 2}, at(2))
+	q.SendAt(mouse.Event{X: 3, Button: mouse.ButtonLeft, Direction: mouse.DirPress}, at(3))
+	q.SendAt(mouse.Event{X: 4, Button: mouse.ButtonLeft}, at(4))
+	q.SendAt(mouse.Event{X: 5, Button: mouse.ButtonLeft}, at(5))
+	q.SendAt(screen.RelativeMouseEvent{DeltaX: 1, DeltaY: 2}, at(6))
+	q.SendAt(screen.RelativeMouseEvent{DeltaX: 3, DeltaY: -1}, at(7))
+	q.SendAt(mouse.Event{X: 8}, at(8))
+
+	want := []struct {
+		e  interface{}
+		ms int
+	}{
+		{mouse.Event{X: 2}, 2},
+		{mouse.Event{X: 3, Button: mouse.ButtonLeft, Direction: mouse.DirPress}, 3},
+		{mouse.Event{X: 5, Button: mouse.ButtonLeft}, 5},
+		{screen.RelativeMouseEvent{DeltaX: 4, DeltaY: 1, Time: at(7)}, 7},
+		{mouse.Event{X: 8}, 8},
+	}
+	for i, w := range want {
+		if got := q.NextEvent(); got != w.e {
+			t.Errorf("event %d: got %#v, want %#v", i, got, w.e)
+		}
+		if got := q.EventTime(); !got.Equal(at(w.ms)) {
+			t.Errorf("event %d: time: got %v, want %v", i, got.Sub(t0), at(w.ms).Sub(t0))
+		}
+	}
+
+	// Events that were already returned are not replaced, and nor are
+	// any events if KeepAllMoves is set.
+	q.Send(mouse.Event{X: 9})
+	q.NextEvent()
+	q.Send(mouse.Event{X: 10})
+	if got, want := q.NextEvent(), (mouse.Event{X: 10}); got != want {
+		t.Errorf("after NextEvent: got %#v, want %#v", got, want)
+	}
+	q = &Deque{KeepAllMoves: true}
+	q.Send(mouse.Event{X: 1})
+	q.Send(mouse.Event{X: 2})
+	if got, want := q.NextEvent(), (mouse.Event{X: 1}); got != want {
+		t.Errorf("KeepAllMoves: got %#v, want %#v", got, want)
+	}
+}
+
+func TestDequeKeyEvents(t *testing.T) {
+	e := screen.KeyEvent{
+		Event:    key.Event{Rune: 'a', Code: key.CodeA, Direction: key.DirPress},
+		Physical: key.CodeQ,
+	}
+	q := &Deque{}
+	q.Send(e)
+	if got, want := q.NextEvent(), e.Event; got != want {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+	q = &Deque{KeyEvents: true}
+	q.Send(e)
+	got := q.NextEvent()
+	if e.Time = q.EventTime(); got != e {
+		t.Errorf("KeyEvents: got %#v, want %#v", got, e)
+	}
+}
+
+func TestClock(t *testing.T) {
+	var c Clock
+	base := time.Hour
+	t0 := c.Time(base)
+	if d := time.Since(t0); d < 0 || d > time.Second {
+		t.Fatalf("first time: got %v from now", -d)
+	}
+	// A later native time converts to later, but never future, times.
+	t1 := c.Time(base + 5*time.Millisecond)
+	if !t1.After(t0) && !t1.Equal(t0) {
+		t.Errorf("second time: got %v before the first", t0.Sub(t1))
+	}
+	if t1.After(time.Now()) {
+		t.Errorf("second time: got a time in the future")
+	}
+	// A native clock that wraps around does not go backwards.
+	if t2 := c.Millis(0); t2.Before(t1) {
+		t.Errorf("after wrapping: got %v before the previous time", t1.Sub(t2))
+	}
+}
+
+func TestDequeBounded(t *testing.T) {
+	// Events are ints, whose priority is their sign.
+	q := &Deque{
+		Priority: func(e interface{}) int {
+			if e.(int) < 0 {
+				return -1
+			}
+			return 0
+		},
+		Queues: map[int]screen.EventQueueOptions{
+			0:  {Capacity: 2, Overflow: screen.OverflowDropOldest},
+			-1: {Capacity: 1, Overflow: screen.OverflowBlock},
+		},
+	}
+	for i := 1; i <= 4; i++ {
+		q.Send(i)
+	}
+	q.Send(-1)
+	blocked := make(chan struct{})
+	go func() {
+		q.Send(-2)
+		close(blocked)
+	}()
+
+	want := []screen.EventQueueStats{
+		{Priority: 0, Pending: 2, MaxPending: 2, Dropped: 2},
+		{Priority: -1, Pending: 1, MaxPending: 1},
+	}
+	if got := q.EventQueueStats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("stats: got %+v, want %+v", got, want)
+	}
+	select {
+	case <-blocked:
+		t.Fatal("Send to a full OverflowBlock queue did not block")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	var got []int
+	for i := 0; i < 4; i++ {
+		got = append(got, q.NextEvent().(int))
+	}
+	<-blocked
+	if want := []int{3, 4, -1, -2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDequeSendLater(t *testing.T) {
+	q := &Deque{}
+	now := time.Now()
+	at := now.Add(20 * time.Millisecond)
+	q.SendLater("tick", now.Add(10*time.Millisecond), 10*time.Millisecond)
+	stopped := q.SendLater("never", now.Add(5*time.Millisecond), 0)
+	q.SendLater("once", at, 0)
+	if !stopped.Stop() {
+		t.Fatal("Stop: got false, want true")
+	}
+	if stopped.Stop() {
+		t.Fatal("second Stop: got true, want false")
+	}
+
+	// The first "tick" is due before "once", and the second with it, in
+	// either order.
+	var got []string
+	for len(got) < 3 {
+		e := q.NextEvent().(string)
+		if e == "once" && !q.EventTime().Equal(at) {
+			t.Errorf("EventTime: got %v, want %v", q.EventTime(), at)
+		}
+		got = append(got, e)
+	}
+	first := got[0]
+	sort.Strings(got)
+	if want := []string{"once", "tick", "tick"}; first != "tick" || !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, first %q, want %q, first \"tick\"", got, first, want)
+	}
+
+	// After StopTimers, no Timer sends, even if it is reset.
+	q.StopTimers()
+	if tm := q.SendLater("late", time.Now(), 0); tm.Reset(time.Now()) {
+		t.Fatal("Reset after StopTimers: got true, want false")
+	}
+	time.Sleep(30 * time.Millisecond)
+	q.Send("last")
+	if got := q.NextEvent(); got != "last" {
+		t.Fatalf("after StopTimers: got %q, want %q", got, "last")
+	}
+}
+
+func TestDequeSendLaterFrame(t *testing.T) {
+	q := &Deque{}
+	frame := time.Now()
+	q.Send(screen.FrameEvent{Time: frame})
+	q.NextEvent()
+
+	// The second FrameEvent, 16ms after the first, is sent with the Timer
+	// due 5ms after it, but not with the one due 50ms after it.
+	q.SendLater("soon", frame.Add(21*time.Millisecond), 0)
+	later := q.SendLater("later", frame.Add(66*time.Millisecond), 0)
+	defer later.Stop()
+	q.SendAt(screen.FrameEvent{Time: frame.Add(16 * time.Millisecond)}, frame.Add(16*time.Millisecond))
+	if got := q.NextEvent(); got != "soon" {
+		t.Fatalf("first event: got %v, want %q", got, "soon")
+	}
+	if got, ok := q.NextEvent().(screen.FrameEvent); !ok {
+		t.Fatalf("second event: got %v, want a FrameEvent", got)
+	}
+	if !later.Stop() {
+		t.Fatal(`Stop "later": got false, want true`)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"container/heap"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+const (
+	// timerSlack is how soon after a wakeup a Timer can be due, and still be
+	// sent with the Timers due at the wakeup.
+	timerSlack = time.Millisecond
+
+	// maxFrameInterval is the longest time between two FrameEvents for which
+	// the Deque considers its window to be animating, and sends the Timers
+	// due within half of that time with the second one.
+	maxFrameInterval = 100 * time.Millisecond
+)
+
+// timer is a screen.Timer of a Deque.
+type timer struct {
+	q     *Deque
+	event interface{}
+	when  time.Time
+	every time.Duration
+	index int // In q.timers, or -1 if not scheduled.
+}
+
+// timerHeap is a min-heap of timers, ordered by when.
+type timerHeap []*timer
+
+func (h timerHeap) Len() int           { return len(h) }
+func (h timerHeap) Less(i, j int) bool { return h[i].when.Before(h[j].when) }
+
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timerHeap) Push(x interface{}) {
+	t := x.(*timer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timerHeap) Pop() interface{} {
+	old := *h
+	n := len(old) - 1
+	t := old[n]
+	old[n] = nil
+	t.index = -1
+	*h = old[:n]
+	return t
+}
+
+// SendLater implements the screen.Window interface.
+func (q *Deque) SendLater(event interface{}, at time.Time, every time.Duration) screen.Timer {
+	t := &timer{q: q, event: event, every: every, index: -1}
+	t.Reset(at)
+	return t
+}
+
+// StopTimers stops every Timer, present and future, of q. A driver calls it
+// when q's window is released.
+func (q *Deque) StopTimers() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, t := range q.timers {
+		t.index = -1
+	}
+	q.timers = nil
+	q.timersStopped = true
+	q.armTimers()
+}
+
+func (t *timer) Stop() bool {
+	q := t.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if t.index < 0 {
+		return false
+	}
+	heap.Remove(&q.timers, t.index)
+	q.armTimers()
+	return true
+}
+
+func (t *timer) Reset(at time.Time) bool {
+	q := t.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	active := t.index >= 0
+	if q.timersStopped {
+		return active
+	}
+	t.when = at
+	if active {
+		heap.Fix(&q.timers, t.index)
+	} else {
+		heap.Push(&q.timers, t)
+	}
+	q.armTimers()
+	return active
+}
+
+// armTimers sets q's wakeup for its earliest Timer. It must be called with
+// q.mu held.
+func (q *Deque) armTimers() {
+	if len(q.timers) == 0 {
+		if q.wakeup != nil {
+			q.wakeup.Stop()
+		}
+		return
+	}
+	d := time.Until(q.timers[0].when)
+	if q.wakeup == nil {
+		q.wakeup = time.AfterFunc(d, q.wake)
+	} else {
+		q.wakeup.Reset(d)
+	}
+}
+
+func (q *Deque) wake() {
+	q.sendTimers(time.Now().Add(timerSlack))
+}
+
+// frameSent is called when a screen.FrameEvent for the time t is sent, to
+// send the Timers due within half a frame of it first.
+func (q *Deque) frameSent(t time.Time) {
+	q.mu.Lock()
+	interval := t.Sub(q.lastFrame)
+	q.lastFrame = t
+	q.mu.Unlock()
+
+	if 0 < interval && interval <= maxFrameInterval {
+		q.sendTimers(t.Add(interval / 2))
+	}
+}
+
+// sendTimers sends the events of the Timers due by deadline, and reschedules
+// those that repeat.
+func (q *Deque) sendTimers(deadline time.Time) {
+	q.mu.Lock()
+	var due []entry
+	now := time.Now()
+	for len(q.timers) > 0 && !q.timers[0].when.After(deadline) {
+		t := q.timers[0]
+		due = append(due, entry{t.event, t.when})
+		if t.every <= 0 {
+			heap.Pop(&q.timers)
+			continue
+		}
+		t.when = t.when.Add(t.every)
+		if t.when.Before(now) {
+			// Skip the sends that t missed.
+			t.when = t.when.Add((now.Sub(t.when)/t.every + 1) * t.every)
+		}
+		heap.Fix(&q.timers, 0)
+	}
+	q.armTimers()
+	q.mu.Unlock()
+
+	for _, e := range due {
+		q.sendAt(e.e, e.t)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package filedialog shows file dialogs, for drivers whose platforms have no
+// file dialogs of their own, such as X11 and Wayland, by running zenity.
+//
+// TODO: use the XDG desktop portal's FileChooser, over D-Bus, when it is
+// available, and kdialog on KDE.
+package filedialog // import "golang.org/x/exp/shiny/driver/internal/filedialog"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Sender is a window that can be sent events, such as an event.Deque.
+type Sender interface {
+	Send(event interface{})
+}
+
+// Start shows a file dialog, and sends its screen.FileDialogEvent to w once
+// the user closes it. It does not wait for the user. parent, if non-zero, is
+// the X11 window that the dialog is transient for.
+//
+// Start returns an error if zenity is not installed.
+func Start(w Sender, opts *screen.FileDialogOptions, parent uint32) error {
+	if opts == nil {
+		opts = &screen.FileDialogOptions{}
+	}
+	path, err := exec.LookPath("zenity")
+	if err != nil {
+		return errors.New("filedialog: zenity is not installed")
+	}
+	cmd := exec.Command(path, zenityArgs(opts, parent)...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("filedialog: starting zenity failed: %v", err)
+	}
+
+	e := screen.FileDialogEvent{ID: opts.ID, Kind: opts.Kind}
+	go func() {
+		err := cmd.Wait()
+		switch ee, ok := err.(*exec.ExitError); {
+		case err == nil:
+			e.Paths = parsePaths(stdout.String())
+		case ok && ee.ExitCode() == 1:
+			// zenity exits with a status of 1 when the user cancels.
+		default:
+			e.Err = fmt.Errorf("filedialog: zenity failed: %v", err)
+		}
+		w.Send(e)
+	}()
+	return nil
+}
+
+// separator separates the chosen paths in zenity's output. File names can
+// contain newlines, but rarely do.
+const separator = "\n"
+
+func zenityArgs(opts *screen.FileDialogOptions, parent uint32) []string {
+	args := []string{"--file-selection", "--separator=" + separator}
+	if opts.Title != "" {
+		args = append(args, "--title="+opts.Title)
+	}
+	if parent != 0 {
+		args = append(args, fmt.Sprintf("--attach=%d", parent))
+	}
+	switch opts.Kind {
+	case screen.FileDialogSave:
+		args = append(args, "--save", "--confirm-overwrite")
+	case screen.FileDialogDirectory:
+		args = append(args, "--directory")
+	}
+	if opts.Multiple && opts.Kind != screen.FileDialogSave {
+		args = append(args, "--multiple")
+	}
+
+	// zenity starts in the directory of --filename, which is the directory
+	// itself if the name ends in a slash.
+	if opts.Kind == screen.FileDialogSave && opts.FileName != "" {
+		args = append(args, "--filename="+filepath.Join(opts.Directory, opts.FileName))
+	} else if opts.Directory != "" {
+		args = append(args, "--filename="+strings.TrimSuffix(opts.Directory, "/")+"/")
+	}
+
+	if opts.Kind != screen.FileDialogDirectory {
+		for _, f := range opts.Filters {
+			args = append(args, "--file-filter="+f.Name+" | "+strings.Join(f.Patterns, " "))
+		}
+	}
+	return args
+}
+
+// parsePaths returns the paths in zenity's output, which ends with a
+// newline.
+func parsePaths(out string) []string {
+	out = strings.TrimSuffix(out, "\n")
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, separator)
+}
@@ -0,0 +1,71 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package filedialog
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestZenityArgs(t *testing.T) {
+	images := screen.FileFilter{Name: "Images", Patterns: []string{"*.png", "*.jpg"}}
+	testCases := []struct {
+		opts   screen.FileDialogOptions
+		parent uint32
+		want   []string
+	}{{
+		opts: screen.FileDialogOptions{},
+		want: []string{"--file-selection", "--separator=\n"},
+	}, {
+		opts: screen.FileDialogOptions{
+			Title:     "Open",
+			Directory: "/home/gopher/",
+			Filters:   []screen.FileFilter{images},
+			Multiple:  true,
+		},
+		parent: 42,
+		want: []string{"--file-selection", "--separator=\n", "--title=Open", "--attach=42",
+			"--multiple", "--filename=/home/gopher/", "--file-filter=Images | *.png *.jpg"},
+	}, {
+		opts: screen.FileDialogOptions{
+			Kind:      screen.FileDialogSave,
+			Directory: "/tmp",
+			FileName:  "a.png",
+			Multiple:  true,
+		},
+		want: []string{"--file-selection", "--separator=\n", "--save", "--confirm-overwrite",
+			"--filename=/tmp/a.png"},
+	}, {
+		opts: screen.FileDialogOptions{
+			Kind:      screen.FileDialogDirectory,
+			Directory: "/tmp",
+			Filters:   []screen.FileFilter{images},
+		},
+		want: []string{"--file-selection", "--separator=\n", "--directory", "--filename=/tmp/"},
+	}}
+	for _, tc := range testCases {
+		if got := zenityArgs(&tc.opts, tc.parent); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%+v: got %q, want %q", tc.opts, got, tc.want)
+		}
+	}
+}
+
+func TestParsePaths(t *testing.T) {
+	testCases := []struct {
+		out  string
+		want []string
+	}{
+		{"", nil},
+		{"/tmp/a b.txt\n", []string{"/tmp/a b.txt"}},
+		{"/tmp/a\n/tmp/b\n", []string{"/tmp/a", "/tmp/b"}},
+	}
+	for _, tc := range testCases {
+		if got := parsePaths(tc.out); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: got %q, want %q", tc.out, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package frame coalesces windows' requests for screen.FrameEvents, so that a
+// driver can send them from a single frame source, such as a display link or
+// a wait for the compositor. It also times the frames that windows present,
+// for their screen.PublishResults, and throttles the frames of occluded
+// windows.
+package frame // import "golang.org/x/exp/shiny/driver/internal/frame"
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// DefaultRate is the refresh rate, in hertz, assumed when a display's actual
+// rate is unknown.
+const DefaultRate = 60
+
+// Interval returns the time between frames of a display refreshing at hz, or
+// at DefaultRate if hz is not positive.
+func Interval(hz float64) time.Duration {
+	if hz <= 0 {
+		hz = DefaultRate
+	}
+	return time.Duration(float64(time.Second) / hz)
+}
+
+// Sender is a window that can be sent events, such as an event.Deque.
+type Sender interface {
+	Send(event interface{})
+}
+
+// Requests is the set of windows waiting for a screen.FrameEvent. The zero
+// value is an empty set, and its frame source is stopped.
+//
+// A driver calls Request for each call to a window's NextFrame method. When
+// Request says to start the frame source, the driver calls Fire at each frame,
+// until Fire says that there are no more requests, at which point the frame
+// source should stop.
+type Requests struct {
+	mu      sync.Mutex
+	windows []Sender
+	running bool
+}
+
+// Request adds w to the set, if it is not already there, and reports whether
+// the caller should start the frame source.
+func (r *Requests) Request(w Sender) (start bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, x := range r.windows {
+		if x == w {
+			return false
+		}
+	}
+	r.windows = append(r.windows, w)
+	start = !r.running
+	r.running = true
+	return start
+}
+
+// Fire sends a screen.FrameEvent, with the given time, to every window in the
+// set, and empties the set. It reports whether there were any windows. If
+// not, the frame source is considered stopped, and the next Request will say
+// to start it again.
+func (r *Requests) Fire(t time.Time) (more bool) {
+	r.mu.Lock()
+	windows := r.windows
+	r.windows = nil
+	r.running = len(windows) != 0
+	r.mu.Unlock()
+
+	for _, w := range windows {
+		w.Send(screen.FrameEvent{Time: t})
+	}
+	return len(windows) != 0
+}
+
+// Tick is a frame source for when the display cannot be waited for. It calls
+// Fire every interval, until Fire returns false.
+func (r *Requests) Tick(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for now := range t.C {
+		if !r.Fire(now) {
+			return
+		}
+	}
+}
+
+// Timing times the frames that a window presents, for the PresentTime and
+// Interval of its screen.PublishResults. The zero value has presented no
+// frames.
+type Timing struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// Presented sets r's timing fields for a frame presented at t.
+func (m *Timing) Presented(r *screen.PublishResult, t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r.PresentTime = t
+	if !m.last.IsZero() {
+		r.Interval = t.Sub(m.last)
+	}
+	m.last = t
+}
+
+// OccludedInterval is the least time between the frames that an occluded
+// window presents, with screen.OccludedPublishThrottle.
+const OccludedInterval = time.Second
+
+// Occlusion tracks a window's screen.Visibility, and applies its
+// screen.OccludedPublishPolicy to the frames that it publishes. The zero value
+// is fully visible, and presents every frame.
+//
+// A driver calls Set for each change of the window's visibility, and Wait at
+// the start of each Publish.
+type Occlusion struct {
+	// Policy is the window's NewWindowOptions.OccludedPublish. It must not
+	// change after the first call to Wait.
+	Policy screen.OccludedPublishPolicy
+
+	mu   sync.Mutex
+	v    screen.Visibility
+	last time.Time
+	// wake, if non-nil, is closed when the visibility changes, to end a
+	// throttled Wait early.
+	wake chan struct{}
+}
+
+// Set records the window's visibility, and reports whether it changed, in
+// which case the driver sends a screen.VisibilityEvent.
+func (o *Occlusion) Set(v screen.Visibility) (changed bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if v == o.v {
+		return false
+	}
+	o.v = v
+	if o.wake != nil {
+		close(o.wake)
+		o.wake = nil
+	}
+	return true
+}
+
+// Visibility returns the window's visibility, as last Set.
+func (o *Occlusion) Visibility() screen.Visibility {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.v
+}
+
+// Wait reports whether to present the frame being published, after waiting,
+// for screen.OccludedPublishThrottle, until OccludedInterval after the last
+// frame presented, or until the visibility changes. It does not wait while
+// the window is visible, nor for other policies.
+func (o *Occlusion) Wait() (present bool) {
+	o.mu.Lock()
+	if o.Policy == screen.OccludedPublishNormal || o.v < screen.VisibilityOccluded {
+		o.last = time.Now()
+		o.mu.Unlock()
+		return true
+	}
+	if o.Policy == screen.OccludedPublishSuppress {
+		o.mu.Unlock()
+		return false
+	}
+	d := OccludedInterval - time.Since(o.last)
+	if o.wake == nil {
+		o.wake = make(chan struct{})
+	}
+	wake := o.wake
+	o.mu.Unlock()
+
+	if d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-wake:
+		}
+		t.Stop()
+	}
+
+	o.mu.Lock()
+	o.last = time.Now()
+	o.mu.Unlock()
+	return true
+}
@@ -0,0 +1,137 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frame
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+type window struct {
+	events []interface{}
+}
+
+func (w *window) Send(event interface{}) {
+	w.events = append(w.events, event)
+}
+
+func TestRequests(t *testing.T) {
+	var r Requests
+	a, b := &window{}, &window{}
+
+	if !r.Request(a) {
+		t.Fatal("first Request: got false, want true")
+	}
+	if r.Request(a) {
+		t.Fatal("repeated Request: got true, want false")
+	}
+	if r.Request(b) {
+		t.Fatal("Request while running: got true, want false")
+	}
+
+	now := time.Now()
+	if !r.Fire(now) {
+		t.Fatal("Fire with requests: got false, want true")
+	}
+	for i, w := range []*window{a, b} {
+		if len(w.events) != 1 || w.events[0] != (screen.FrameEvent{Time: now}) {
+			t.Errorf("window %d: got events %v, want one FrameEvent", i, w.events)
+		}
+	}
+
+	// The frame source is still running, until a Fire without requests.
+	if r.Request(a) {
+		t.Fatal("Request after Fire: got true, want false")
+	}
+	if !r.Fire(now) {
+		t.Fatal("second Fire: got false, want true")
+	}
+	if r.Fire(now) {
+		t.Fatal("Fire without requests: got true, want false")
+	}
+	if !r.Request(a) {
+		t.Fatal("Request after stopping: got false, want true")
+	}
+}
+
+func TestInterval(t *testing.T) {
+	if got, want := Interval(50), 20*time.Millisecond; got != want {
+		t.Errorf("50Hz: got %v, want %v", got, want)
+	}
+	if got, want := Interval(0), time.Second/DefaultRate; got != want {
+		t.Errorf("unknown rate: got %v, want %v", got, want)
+	}
+}
+
+func TestTiming(t *testing.T) {
+	var m Timing
+	t0 := time.Unix(100, 0)
+
+	var r0 screen.PublishResult
+	m.Presented(&r0, t0)
+	if r0.PresentTime != t0 || r0.Interval != 0 {
+		t.Errorf("first frame: got %v, %v, want %v, 0", r0.PresentTime, r0.Interval, t0)
+	}
+
+	var r1 screen.PublishResult
+	t1 := t0.Add(33 * time.Millisecond)
+	m.Presented(&r1, t1)
+	if r1.PresentTime != t1 || r1.Interval != 33*time.Millisecond {
+		t.Errorf("second frame: got %v, %v, want %v, 33ms", r1.PresentTime, r1.Interval, t1)
+	}
+}
+
+func TestOcclusion(t *testing.T) {
+	var o Occlusion
+	if o.Set(screen.VisibilityFull) {
+		t.Error("Set(VisibilityFull) of a new Occlusion: got true, want false")
+	}
+	if !o.Set(screen.VisibilityOccluded) {
+		t.Error("Set(VisibilityOccluded): got false, want true")
+	}
+	if !o.Wait() {
+		t.Error("Wait with OccludedPublishNormal: got false, want true")
+	}
+
+	o.Policy = screen.OccludedPublishSuppress
+	if o.Wait() {
+		t.Error("occluded Wait with OccludedPublishSuppress: got true, want false")
+	}
+	o.Set(screen.VisibilityPartial)
+	if !o.Wait() {
+		t.Error("partially visible Wait with OccludedPublishSuppress: got false, want true")
+	}
+}
+
+func TestOcclusionThrottle(t *testing.T) {
+	o := Occlusion{Policy: screen.OccludedPublishThrottle}
+	o.Set(screen.VisibilityMinimized)
+	// The first frame is presented at once, as none has been yet.
+	start := time.Now()
+	if !o.Wait() {
+		t.Fatal("first Wait: got false, want true")
+	}
+	if d := time.Since(start); d >= OccludedInterval {
+		t.Fatalf("first Wait: took %v, want less than %v", d, OccludedInterval)
+	}
+
+	// The second waits until the window is visible again.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		o.Set(screen.VisibilityFull)
+	}()
+	start = time.Now()
+	if !o.Wait() {
+		t.Fatal("second Wait: got false, want true")
+	}
+	if d := time.Since(start); d < 10*time.Millisecond || d >= OccludedInterval {
+		t.Fatalf("second Wait: took %v, want between 10ms and %v", d, OccludedInterval)
+	}
+	if got := o.Visibility(); got != screen.VisibilityFull {
+		t.Errorf("Visibility: got %v, want %v", got, screen.VisibilityFull)
+	}
+}
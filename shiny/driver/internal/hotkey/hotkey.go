@@ -0,0 +1,107 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hotkey keeps track of which windows registered which hot keys, for
+// drivers whose platforms grab global keyboard shortcuts for the application,
+// rather than for one of its windows.
+package hotkey // import "golang.org/x/exp/shiny/driver/internal/hotkey"
+
+import (
+	"sync"
+
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+)
+
+// Key is a hot key: a key pressed with exactly the given modifiers.
+type Key struct {
+	Code      key.Code
+	Modifiers key.Modifiers
+}
+
+// Table maps hot keys to the windows that registered them. The zero value is
+// an empty table. Its methods are safe for concurrent use, and do not hold its
+// lock while calling grab or ungrab, so that those may wait for the thread
+// that calls Send.
+type Table struct {
+	mu      sync.Mutex
+	windows map[Key]frame.Sender
+}
+
+// Register registers k for w, calling grab to grab k from the platform, unless
+// w has already registered k. It returns screen.ErrHotKeyInUse if another
+// window has registered k, and grab's error if grab fails, in which case k is
+// not registered.
+func (t *Table) Register(w frame.Sender, k Key, grab func() error) error {
+	t.mu.Lock()
+	if x, ok := t.windows[k]; ok {
+		t.mu.Unlock()
+		if x == w {
+			return nil
+		}
+		return screen.ErrHotKeyInUse
+	}
+	if t.windows == nil {
+		t.windows = map[Key]frame.Sender{}
+	}
+	// Holding k while it is grabbed stops another window from grabbing it
+	// too.
+	t.windows[k] = w
+	t.mu.Unlock()
+
+	if err := grab(); err != nil {
+		t.mu.Lock()
+		delete(t.windows, k)
+		t.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Unregister unregisters k for w, calling ungrab if w had registered k.
+func (t *Table) Unregister(w frame.Sender, k Key, ungrab func()) {
+	t.mu.Lock()
+	registered := t.windows[k] == w
+	if registered {
+		delete(t.windows, k)
+	}
+	t.mu.Unlock()
+
+	if registered {
+		ungrab()
+	}
+}
+
+// Release unregisters every hot key that w registered, calling ungrab for each
+// of them, once w is released.
+func (t *Table) Release(w frame.Sender, ungrab func(k Key)) {
+	var keys []Key
+	t.mu.Lock()
+	for k, x := range t.windows {
+		if x == w {
+			keys = append(keys, k)
+			delete(t.windows, k)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, k := range keys {
+		ungrab(k)
+	}
+}
+
+// Send sends a screen.HotKeyEvent for k to the window that registered k, and
+// reports whether there was one.
+func (t *Table) Send(k Key) bool {
+	t.mu.Lock()
+	w := t.windows[k]
+	t.mu.Unlock()
+
+	if w == nil {
+		return false
+	}
+	w.Send(screen.HotKeyEvent{Key: k.Code, Modifiers: k.Modifiers})
+	return true
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hotkey
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+)
+
+type recorder struct {
+	events []interface{}
+}
+
+func (r *recorder) Send(event interface{}) {
+	r.events = append(r.events, event)
+}
+
+func TestTable(t *testing.T) {
+	var (
+		tab    Table
+		a, b   recorder
+		grabs  []Key
+		ungrab []Key
+	)
+	space := Key{Code: key.CodeSpacebar, Modifiers: key.ModAlt}
+	shot := Key{Code: key.CodeP, Modifiers: key.ModControl | key.ModShift}
+	grab := func(k Key) func() error {
+		return func() error {
+			grabs = append(grabs, k)
+			return nil
+		}
+	}
+
+	if err := tab.Register(&a, space, grab(space)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := tab.Register(&a, space, grab(space)); err != nil {
+		t.Fatalf("Register again: %v", err)
+	}
+	if err := tab.Register(&b, space, grab(space)); err != screen.ErrHotKeyInUse {
+		t.Fatalf("Register by another window: got %v, want ErrHotKeyInUse", err)
+	}
+	if want := []Key{space}; !reflect.DeepEqual(grabs, want) {
+		t.Fatalf("grabs: got %v, want %v", grabs, want)
+	}
+
+	errGrab := errors.New("grab failed")
+	if err := tab.Register(&b, shot, func() error { return errGrab }); err != errGrab {
+		t.Fatalf("Register with a failing grab: got %v, want %v", err, errGrab)
+	}
+	if tab.Send(shot) {
+		t.Fatal("Send of a key whose grab failed: got sent")
+	}
+	if err := tab.Register(&b, shot, grab(shot)); err != nil {
+		t.Fatalf("Register after a failing grab: %v", err)
+	}
+
+	if !tab.Send(space) || !tab.Send(shot) {
+		t.Fatal("Send: got not sent")
+	}
+	if tab.Send(Key{Code: key.CodeSpacebar}) {
+		t.Error("Send without the modifiers: got sent")
+	}
+	want := []interface{}{screen.HotKeyEvent{Key: key.CodeSpacebar, Modifiers: key.ModAlt}}
+	if !reflect.DeepEqual(a.events, want) {
+		t.Errorf("a's events: got %v, want %v", a.events, want)
+	}
+
+	tab.Unregister(&b, space, func() { ungrab = append(ungrab, space) })
+	if len(ungrab) != 0 {
+		t.Errorf("Unregister by another window: got ungrabs %v", ungrab)
+	}
+	tab.Unregister(&a, space, func() { ungrab = append(ungrab, space) })
+	tab.Release(&b, func(k Key) { ungrab = append(ungrab, k) })
+	if want := []Key{space, shot}; !reflect.DeepEqual(ungrab, want) {
+		t.Errorf("ungrabs: got %v, want %v", ungrab, want)
+	}
+	if tab.Send(space) || tab.Send(shot) {
+		t.Error("Send after unregistering: got sent")
+	}
+}
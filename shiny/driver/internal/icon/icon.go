@@ -0,0 +1,66 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package icon scales window icons, as set by screen.Window.SetIcon, to the
+// sizes that platforms ask for.
+package icon // import "golang.org/x/exp/shiny/driver/internal/icon"
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// NetWMIconSizes are the sizes of the icons in an EWMH _NET_WM_ICON property.
+// Window managers choose among them, and scale the nearest when none is the
+// size they want. Together, they fit in a single X11 request, unlike a large
+// icon on its own.
+var NetWMIconSizes = []int{16, 24, 32, 48, 64, 128}
+
+// Scale returns m scaled to fit a square of size by size pixels, keeping its
+// aspect ratio, centered on a transparent background.
+func Scale(m image.Image, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	b := m.Bounds()
+	if b.Empty() {
+		return dst
+	}
+	w, h := size, size
+	if b.Dx() > b.Dy() {
+		h = (size*b.Dy() + b.Dx()/2) / b.Dx()
+	} else {
+		w = (size*b.Dx() + b.Dy()/2) / b.Dy()
+	}
+	r := image.Rect(0, 0, w, h).Add(image.Pt((size-w)/2, (size-h)/2))
+	if r.Dx() == b.Dx() && r.Dy() == b.Dy() {
+		draw.Draw(dst, r, m, b.Min, draw.Src)
+	} else {
+		xdraw.CatmullRom.Scale(dst, r, m, b, draw.Src, nil)
+	}
+	return dst
+}
+
+// NetWMIcon returns the data of an EWMH _NET_WM_ICON property, of m at each
+// of the NetWMIconSizes: for each, the width and height, followed by the
+// pixels, in rows from the top, as non-premultiplied ARGB.
+func NetWMIcon(m image.Image) []uint32 {
+	n := 0
+	for _, size := range NetWMIconSizes {
+		n += 2 + size*size
+	}
+	data := make([]uint32, 0, n)
+	for _, size := range NetWMIconSizes {
+		s := Scale(m, size)
+		data = append(data, uint32(size), uint32(size))
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				c := color.NRGBAModel.Convert(s.RGBAAt(x, y)).(color.NRGBA)
+				data = append(data, uint32(c.A)<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B))
+			}
+		}
+	}
+	return data
+}
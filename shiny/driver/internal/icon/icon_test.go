@@ -0,0 +1,71 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icon
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestScale(t *testing.T) {
+	red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+	m := image.NewRGBA(image.Rect(10, 10, 30, 20))
+	for y := 10; y < 20; y++ {
+		for x := 10; x < 30; x++ {
+			m.SetRGBA(x, y, red)
+		}
+	}
+
+	// A wide image is centered vertically, between transparent rows.
+	s := Scale(m, 8)
+	if got, want := s.Bounds(), image.Rect(0, 0, 8, 8); got != want {
+		t.Fatalf("bounds: got %v, want %v", got, want)
+	}
+	for y := 0; y < 8; y++ {
+		want := color.RGBA{}
+		if 2 <= y && y < 6 {
+			want = red
+		}
+		for x := 0; x < 8; x++ {
+			if got := s.RGBAAt(x, y); got != want {
+				t.Errorf("(%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+
+	// An image that is already the size is copied.
+	sq := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	sq.SetRGBA(1, 2, red)
+	if got := Scale(sq, 4); got.RGBAAt(1, 2) != red || got.RGBAAt(2, 1) != (color.RGBA{}) {
+		t.Errorf("same size: got %v and %v at (1, 2) and (2, 1), want %v and transparent",
+			got.RGBAAt(1, 2), got.RGBAAt(2, 1), red)
+	}
+}
+
+func TestNetWMIcon(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	// Half transparent blue, premultiplied.
+	m.SetRGBA(0, 0, color.RGBA{0x00, 0x00, 0x80, 0x80})
+
+	data := NetWMIcon(m)
+	i := 0
+	for _, size := range NetWMIconSizes {
+		if i+2 > len(data) {
+			t.Fatalf("size %d: data ended at %d", size, i)
+		}
+		if data[i] != uint32(size) || data[i+1] != uint32(size) {
+			t.Fatalf("size %d: got %d by %d", size, data[i], data[i+1])
+		}
+		i += 2
+		if got, want := data[i], uint32(0x800000ff); got != want {
+			t.Errorf("size %d: first pixel: got %#08x, want %#08x", size, got, want)
+		}
+		i += size * size
+	}
+	if i != len(data) {
+		t.Errorf("got %d values, want %d", len(data), i)
+	}
+}
@@ -9,11 +9,15 @@
 // to another should not send multiple events from StageVisible to
 // StageVisible, even though the underlying window system's message might only
 // hold the new position, and not whether the window was previously visible.
+//
+// It also sends a screen.FocusEvent whenever the window gains or loses the
+// keyboard focus.
 package lifecycler // import "golang.org/x/exp/shiny/driver/internal/lifecycler"
 
 import (
 	"sync"
 
+	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/mobile/event/lifecycle"
 )
 
@@ -24,6 +28,9 @@ type State struct {
 	dead    bool
 	focused bool
 	visible bool
+
+	// sentFocused is the Focused field of the last screen.FocusEvent sent.
+	sentFocused bool
 }
 
 func (s *State) SetDead(b bool) {
@@ -61,6 +68,13 @@ func (s *State) SendEvent(r Sender, drawContext interface{}) {
 		to = lifecycle.StageVisible
 	}
 	s.stage = to
+	// A dead window is not sent a FocusEvent, as it is not sent any event
+	// after its StageDead lifecycle.Event.
+	focusChanged := !s.dead && s.focused != s.sentFocused
+	if focusChanged {
+		s.sentFocused = s.focused
+	}
+	focused := s.focused
 	s.mu.Unlock()
 
 	if from != to {
@@ -72,6 +86,9 @@ func (s *State) SendEvent(r Sender, drawContext interface{}) {
 			DrawContext: drawContext,
 		})
 	}
+	if focusChanged {
+		r.Send(screen.FocusEvent{Focused: focused})
+	}
 }
 
 // Sender is who to send the lifecycle event to.
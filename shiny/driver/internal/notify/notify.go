@@ -0,0 +1,222 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package notify posts desktop notifications, for drivers whose platforms
+// have no notifications of their own, such as X11 and Wayland, by running
+// notify-send, which sends them to the freedesktop.org notification server.
+//
+// Each notification has a notify-send process, which waits for the user to
+// activate or close it. Its first line of output is the server's ID of the
+// notification, which replaces or closes it, and its second line, if any, is
+// the name of the chosen action.
+//
+// TODO: call the org.freedesktop.Notifications D-Bus interface directly,
+// which would also tell apart notifications that were closed.
+package notify // import "golang.org/x/exp/shiny/driver/internal/notify"
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Sender is a window that can be sent events, such as an event.Deque.
+type Sender interface {
+	Send(event interface{})
+}
+
+// defaultAction is the name of the action of clicking on the notification
+// itself. Notification servers do not show it as a button.
+const defaultAction = "default"
+
+type key struct {
+	w  Sender
+	id int
+}
+
+// posted is a notification, and its notify-send process.
+type posted struct {
+	cmd *exec.Cmd
+
+	// serverID is the notification server's ID of the notification, or
+	// zero until notify-send prints it. It is guarded by notifications.mu.
+	serverID uint32
+}
+
+var notifications = struct {
+	mu sync.Mutex
+	m  map[key]*posted
+}{
+	m: map[key]*posted{},
+}
+
+// Post posts the notification n, for w, which is sent a
+// screen.NotificationEvent when the user activates it. It replaces a
+// notification of w's with the same ID.
+//
+// Post returns an error if notify-send is not installed.
+func Post(w Sender, n *screen.Notification) error {
+	path, err := exec.LookPath("notify-send")
+	if err != nil {
+		return errors.New("notify: notify-send is not installed")
+	}
+	imagePath := ""
+	if n.Image != nil {
+		if imagePath, err = writeImage(n.Image); err != nil {
+			return err
+		}
+	}
+	k := key{w, n.ID}
+
+	notifications.mu.Lock()
+	defer notifications.mu.Unlock()
+
+	replaceID := uint32(0)
+	if old := notifications.m[k]; old != nil {
+		replaceID = old.serverID
+		old.cmd.Process.Kill()
+		delete(notifications.m, k)
+	}
+	p := &posted{
+		cmd: exec.Command(path, notifySendArgs(n, imagePath, replaceID)...),
+	}
+	stdout, err := p.cmd.StdoutPipe()
+	if err == nil {
+		err = p.cmd.Start()
+	}
+	if err != nil {
+		if imagePath != "" {
+			os.Remove(imagePath)
+		}
+		return fmt.Errorf("notify: starting notify-send failed: %v", err)
+	}
+	notifications.m[k] = p
+
+	go func() {
+		sc := bufio.NewScanner(stdout)
+		for i := 0; sc.Scan(); i++ {
+			notifications.mu.Lock()
+			current := notifications.m[k] == p
+			if current && i == 0 {
+				if id, err := strconv.ParseUint(sc.Text(), 10, 32); err == nil {
+					p.serverID = uint32(id)
+				}
+			}
+			notifications.mu.Unlock()
+			if current && i > 0 {
+				if action, ok := parseAction(sc.Text()); ok {
+					w.Send(screen.NotificationEvent{ID: k.id, Action: action})
+				}
+			}
+		}
+		p.cmd.Wait()
+		if imagePath != "" {
+			os.Remove(imagePath)
+		}
+		notifications.mu.Lock()
+		if notifications.m[k] == p {
+			delete(notifications.m, k)
+		}
+		notifications.mu.Unlock()
+	}()
+	return nil
+}
+
+// Close closes w's notification with the given ID, if it is still shown.
+func Close(w Sender, id int) {
+	notifications.mu.Lock()
+	p := notifications.m[key{w, id}]
+	serverID := uint32(0)
+	if p != nil {
+		serverID = p.serverID
+		delete(notifications.m, key{w, id})
+	}
+	notifications.mu.Unlock()
+	if p == nil {
+		return
+	}
+	p.cmd.Process.Kill()
+	if serverID == 0 {
+		return
+	}
+	// notify-send cannot close notifications, but gdbus, which is
+	// installed with GLib, as notify-send is, can call the server.
+	if path, err := exec.LookPath("gdbus"); err == nil {
+		exec.Command(path, gdbusCloseArgs(serverID)...).Run()
+	}
+}
+
+// Release forgets w's notifications, once it is released. They are still
+// shown, but activating them sends no events.
+func Release(w Sender) {
+	notifications.mu.Lock()
+	defer notifications.mu.Unlock()
+	for k, p := range notifications.m {
+		if k.w == w {
+			p.cmd.Process.Kill()
+			delete(notifications.m, k)
+		}
+	}
+}
+
+func notifySendArgs(n *screen.Notification, imagePath string, replaceID uint32) []string {
+	args := []string{"--print-id", "--wait", "--action=" + defaultAction + "=Open"}
+	for _, a := range n.Actions {
+		args = append(args, fmt.Sprintf("--action=%d=%s", a.ID, a.Title))
+	}
+	if imagePath != "" {
+		args = append(args, "--icon="+imagePath)
+	}
+	if replaceID != 0 {
+		args = append(args, fmt.Sprintf("--replace-id=%d", replaceID))
+	}
+	return append(args, "--", n.Title, n.Body)
+}
+
+func gdbusCloseArgs(serverID uint32) []string {
+	return []string{
+		"call", "--session",
+		"--dest=org.freedesktop.Notifications",
+		"--object-path=/org/freedesktop/Notifications",
+		"--method=org.freedesktop.Notifications.CloseNotification",
+		strconv.FormatUint(uint64(serverID), 10),
+	}
+}
+
+// parseAction returns the NotificationAction.ID of an action name printed by
+// notify-send.
+func parseAction(name string) (action int, ok bool) {
+	if name == defaultAction {
+		return 0, true
+	}
+	action, err := strconv.Atoi(name)
+	return action, err == nil
+}
+
+// writeImage writes m to a temporary PNG file, as the notification server
+// reads images from files, returning its path.
+func writeImage(m image.Image) (string, error) {
+	f, err := ioutil.TempFile("", "shiny-notification-*.png")
+	if err != nil {
+		return "", fmt.Errorf("notify: creating image file failed: %v", err)
+	}
+	err = png.Encode(f, m)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("notify: writing image file failed: %v", err)
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestNotifySendArgs(t *testing.T) {
+	testCases := []struct {
+		n         screen.Notification
+		imagePath string
+		replaceID uint32
+		want      []string
+	}{{
+		n:    screen.Notification{Title: "Hello"},
+		want: []string{"--print-id", "--wait", "--action=default=Open", "--", "Hello", ""},
+	}, {
+		n: screen.Notification{
+			ID:    7,
+			Title: "-Download",
+			Body:  "Done",
+			Actions: []screen.NotificationAction{
+				{ID: 1, Title: "Show"},
+				{ID: 2, Title: "Delete = Forget"},
+			},
+		},
+		imagePath: "/tmp/a.png",
+		replaceID: 42,
+		want: []string{"--print-id", "--wait", "--action=default=Open",
+			"--action=1=Show", "--action=2=Delete = Forget", "--icon=/tmp/a.png",
+			"--replace-id=42", "--", "-Download", "Done"},
+	}}
+	for _, tc := range testCases {
+		if got := notifySendArgs(&tc.n, tc.imagePath, tc.replaceID); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%+v: got %q, want %q", tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	testCases := []struct {
+		name   string
+		action int
+		ok     bool
+	}{
+		{"default", 0, true},
+		{"3", 3, true},
+		{"-1", -1, true},
+		{"", 0, false},
+		{"open", 0, false},
+	}
+	for _, tc := range testCases {
+		action, ok := parseAction(tc.name)
+		if action != tc.action || ok != tc.ok {
+			t.Errorf("%q: got %d, %t, want %d, %t", tc.name, action, ok, tc.action, tc.ok)
+		}
+	}
+}
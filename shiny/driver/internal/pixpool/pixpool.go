@@ -0,0 +1,75 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pixpool recycles the pixel slices of released Buffers, so that
+// programs that allocate and release a Buffer every frame do not generate
+// garbage every frame.
+package pixpool // import "golang.org/x/exp/shiny/driver/internal/pixpool"
+
+import (
+	"image"
+	"math/bits"
+	"sync"
+)
+
+// minClass is the size class of the smallest pooled slices. Smaller slices
+// are not worth pooling.
+const minClass = 12
+
+// Pool is a pool of byte slices, bucketed by capacity into powers of two.
+// Slices are held by sync.Pools, so that an idle Pool's memory is eventually
+// reclaimed by the garbage collector.
+//
+// The zero value is ready to use. It is safe for concurrent use.
+type Pool struct {
+	classes [64]sync.Pool // Of *[]byte.
+}
+
+// class returns the size class of slices of length n, the smallest c such
+// that n <= 1<<c.
+func class(n int) int {
+	return bits.Len(uint(n - 1))
+}
+
+// Get returns a zeroed slice of length n.
+func (p *Pool) Get(n int) []byte {
+	c := class(n)
+	if n <= 0 || c < minClass {
+		return make([]byte, n)
+	}
+	if x, _ := p.classes[c].Get().(*[]byte); x != nil {
+		b := (*x)[:n]
+		for i := range b {
+			b[i] = 0
+		}
+		return b
+	}
+	return make([]byte, n, 1<<uint(c))
+}
+
+// Put returns b, which must have been returned by Get, to the pool. The
+// caller must not use b afterwards.
+func (p *Pool) Put(b []byte) {
+	n := cap(b)
+	c := class(n)
+	if n == 0 || c < minClass || n != 1<<uint(c) {
+		return
+	}
+	b = b[:0]
+	p.classes[c].Put(&b)
+}
+
+// NewRGBA is like image.NewRGBA(image.Rectangle{Max: size}), except that its
+// pixels are obtained from p.
+func (p *Pool) NewRGBA(size image.Point) image.RGBA {
+	if size.X < 0 || size.Y < 0 {
+		// Let the image package reject invalid sizes.
+		return *image.NewRGBA(image.Rectangle{Max: size})
+	}
+	return image.RGBA{
+		Pix:    p.Get(4 * size.X * size.Y),
+		Stride: 4 * size.X,
+		Rect:   image.Rectangle{Max: size},
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pixpool
+
+import (
+	"testing"
+)
+
+func TestGetPut(t *testing.T) {
+	var p Pool
+	for _, n := range []int{0, 1, 100, 4096, 4097, 1 << 20} {
+		b := p.Get(n)
+		if len(b) != n {
+			t.Fatalf("Get(%d): got length %d", n, len(b))
+		}
+		for i := range b {
+			b[i] = 0xff
+		}
+		p.Put(b)
+
+		// A recycled slice is zeroed. sync.Pool may drop b, in which case
+		// c is a new slice, which is also zeroed.
+		c := p.Get(n)
+		if len(c) != n {
+			t.Fatalf("Get(%d) after Put: got length %d", n, len(c))
+		}
+		for i, x := range c {
+			if x != 0 {
+				t.Fatalf("Get(%d) after Put: byte %d: got %#02x, want 0", n, i, x)
+			}
+		}
+		p.Put(c)
+	}
+}
+
+func TestClass(t *testing.T) {
+	for _, tc := range []struct{ n, want int }{
+		{1, 0}, {2, 1}, {3, 2}, {4096, 12}, {4097, 13}, {1 << 20, 20},
+	} {
+		if got := class(tc.n); got != tc.want {
+			t.Errorf("class(%d): got %d, want %d", tc.n, got, tc.want)
+		}
+	}
+}
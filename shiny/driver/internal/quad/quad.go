@@ -0,0 +1,51 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package quad computes the vertices of the quadrilaterals that GPU drivers
+// draw textures and uniform colors with.
+package quad // import "golang.org/x/exp/shiny/driver/internal/quad"
+
+import (
+	"image"
+
+	"golang.org/x/image/math/f64"
+)
+
+// Vertices returns the vertices of a triangle strip that covers the source
+// rectangle sr, transformed by src2dst, for drawing onto a destination texture
+// of size dstSize. Each vertex is a position, in normalized device
+// co-ordinates, followed by a texture co-ordinate into a source texture of
+// size srcSize. A zero srcSize, as for a uniform color, means that the
+// texture co-ordinates are all zero.
+//
+// Normalized device co-ordinates range from -1 to +1, with +1 being the top
+// of the destination, and texture co-ordinates range from 0 to 1, with 0
+// being the top of the source.
+func Vertices(src2dst f64.Aff3, sr image.Rectangle, srcSize, dstSize image.Point) [16]float32 {
+	corners := [4]image.Point{
+		{sr.Min.X, sr.Min.Y},
+		{sr.Max.X, sr.Min.Y},
+		{sr.Min.X, sr.Max.Y},
+		{sr.Max.X, sr.Max.Y},
+	}
+	var v [16]float32
+	for i, p := range corners {
+		sx, sy := float64(p.X), float64(p.Y)
+		dx := src2dst[0]*sx + src2dst[1]*sy + src2dst[2]
+		dy := src2dst[3]*sx + src2dst[4]*sy + src2dst[5]
+		v[4*i+0] = float32(2*dx/float64(dstSize.X) - 1)
+		v[4*i+1] = float32(1 - 2*dy/float64(dstSize.Y))
+		if srcSize.X > 0 && srcSize.Y > 0 {
+			v[4*i+2] = float32(sx / float64(srcSize.X))
+			v[4*i+3] = float32(sy / float64(srcSize.Y))
+		}
+	}
+	return v
+}
+
+// Identity is the identity transformation.
+var Identity = f64.Aff3{
+	1, 0, 0,
+	0, 1, 0,
+}
@@ -0,0 +1,59 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quad
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+)
+
+func TestVertices(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		src2dst f64.Aff3
+		sr      image.Rectangle
+		srcSize image.Point
+		want    [16]float32
+	}{{
+		desc:    "whole destination",
+		src2dst: Identity,
+		sr:      image.Rect(0, 0, 100, 100),
+		srcSize: image.Point{100, 100},
+		want: [16]float32{
+			-1, +1, 0, 0,
+			+1, +1, 1, 0,
+			-1, -1, 0, 1,
+			+1, -1, 1, 1,
+		},
+	}, {
+		desc:    "translated sub-rectangle",
+		src2dst: f64.Aff3{1, 0, 50, 0, 1, 25},
+		sr:      image.Rect(50, 0, 100, 25),
+		srcSize: image.Point{200, 100},
+		want: [16]float32{
+			+1, 0.5, 0.25, 0,
+			+2, 0.5, 0.5, 0,
+			+1, 0, 0.25, 0.25,
+			+2, 0, 0.5, 0.25,
+		},
+	}, {
+		desc:    "uniform color, scaled",
+		src2dst: f64.Aff3{2, 0, 0, 0, 4, 0},
+		sr:      image.Rect(0, 0, 25, 25),
+		want: [16]float32{
+			-1, +1, 0, 0,
+			0, +1, 0, 0,
+			-1, -1, 0, 0,
+			0, -1, 0, 0,
+		},
+	}}
+	for _, tc := range testCases {
+		if got := Vertices(tc.src2dst, tc.sr, tc.srcSize, image.Point{100, 100}); got != tc.want {
+			t.Errorf("%s:\ngot  %v\nwant %v", tc.desc, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package swizzle
+
+// Over composites the pixels of src over those of dst, as draw.Over does.
+// Both are alpha-premultiplied, in the same byte order, which may be RGBA or
+// BGRA, as alpha is the fourth byte of both. dst and src may be the same
+// slice, but must not otherwise overlap.
+//
+// Each channel is computed in 8-bit precision, rounding to nearest, so the
+// result may differ from that of draw.Over, which computes in 16-bit
+// precision and truncates, by one.
+//
+// It panics if the src slice length is not a multiple of 4, or if dst is
+// shorter than src.
+func Over(dst, src []byte) {
+	doOver(dst, src, false)
+}
+
+// OverBGRA is like Over, except that src is in the other byte order to dst:
+// it composites RGBA pixels over BGRA ones, or BGRA pixels over RGBA ones.
+func OverBGRA(dst, src []byte) {
+	doOver(dst, src, true)
+}
+
+func doOver(dst, src []byte, swap bool) {
+	if len(src)%4 != 0 {
+		panic("input slice length is not a multiple of 4")
+	}
+	if len(dst) < len(src) {
+		panic("output slice is shorter than input slice")
+	}
+	dst = dst[:len(src)]
+
+	if useOver {
+		n := len(src) &^ (overSize - 1)
+		over(dst[:n], src[:n], swap)
+		dst, src = dst[n:], src[n:]
+	}
+
+	for i := 0; i < len(src); i += 4 {
+		s := src[i : i+4 : i+4]
+		d := dst[i : i+4 : i+4]
+		sr, sg, sb, sa := s[0], s[1], s[2], s[3]
+		if swap {
+			sr, sb = sb, sr
+		}
+		if sa == 0xff {
+			d[0], d[1], d[2], d[3] = sr, sg, sb, sa
+			continue
+		}
+		k := 0xff - uint32(sa)
+		d[0] = addSat(mul255(d[0], k), sr)
+		d[1] = addSat(mul255(d[1], k), sg)
+		d[2] = addSat(mul255(d[2], k), sb)
+		d[3] = addSat(mul255(d[3], k), sa)
+	}
+}
+
+// mul255 returns x * k / 0xff, rounded to nearest.
+func mul255(x uint8, k uint32) uint8 {
+	t := uint32(x)*k + 0x80
+	return uint8((t + t>>8) >> 8)
+}
+
+// addSat returns x + y, saturating at 0xff.
+func addSat(x, y uint8) uint8 {
+	if s := uint32(x) + uint32(y); s <= 0xff {
+		return uint8(s)
+	}
+	return 0xff
+}
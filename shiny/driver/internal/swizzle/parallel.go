@@ -0,0 +1,60 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package swizzle
+
+import (
+	"runtime"
+	"sync"
+)
+
+// minBandBytes is the fewest bytes that Parallel gives each goroutine, below
+// which starting one costs more than it saves.
+const minBandBytes = 256 << 10
+
+// Parallel calls f for each of height rows of dst, and of src, with the row's
+// width bytes of each, such as Over or CopyBGRA. The rows of dst and src start
+// dstStride and srcStride bytes apart. If src is nil, f is passed a nil src
+// row. f may be called concurrently for different rows: large enough images
+// are split into bands of consecutive rows, one per CPU, that are processed
+// on separate goroutines. Parallel returns after every call to f returns.
+func Parallel(dst []byte, dstStride int, src []byte, srcStride int, width, height int, f func(dst, src []byte)) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	bands := runtime.GOMAXPROCS(0)
+	if n := width * height / minBandBytes; bands > n {
+		bands = n
+	}
+	if bands > height {
+		bands = height
+	}
+	if bands <= 1 {
+		rows(dst, dstStride, src, srcStride, width, 0, height, f)
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(bands - 1)
+	for i := 1; i < bands; i++ {
+		y0, y1 := height*i/bands, height*(i+1)/bands
+		go func() {
+			defer wg.Done()
+			rows(dst, dstStride, src, srcStride, width, y0, y1, f)
+		}()
+	}
+	rows(dst, dstStride, src, srcStride, width, 0, height/bands, f)
+	wg.Wait()
+}
+
+// rows calls f for rows y0 to y1 of dst and src, as for Parallel.
+func rows(dst []byte, dstStride int, src []byte, srcStride int, width, y0, y1 int, f func(dst, src []byte)) {
+	for y := y0; y < y1; y++ {
+		var s []byte
+		if src != nil {
+			s = src[y*srcStride:][:width]
+		}
+		f(dst[y*dstStride:][:width], s)
+	}
+}
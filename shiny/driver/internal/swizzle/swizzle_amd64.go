@@ -9,9 +9,28 @@ package swizzle
 // Note that this is SSSE3, not SSE3.
 func haveSSSE3() bool
 
+// haveAVX2 returns whether the CPU supports AVX2 instructions, and the OS
+// saves the 256-bit registers that they use.
+func haveAVX2() bool
+
 var useBGRA16 = haveSSSE3()
 
 const useBGRA4 = true
 
 func bgra16(p []byte)
 func bgra4(p []byte)
+
+// On amd64, copyBGRA and over use AVX2, and process 32 bytes, or 8 pixels, at
+// a time.
+var (
+	useCopyBGRA = haveAVX2()
+	useOver     = useCopyBGRA
+)
+
+const (
+	copyBGRASize = 32
+	overSize     = 32
+)
+
+func copyBGRA(dst, src []byte)
+func over(dst, src []byte, swap bool)
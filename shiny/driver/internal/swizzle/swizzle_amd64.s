@@ -74,3 +74,150 @@ loop:
 	JMP	loop
 done:
 	RET
+
+// func haveAVX2() bool
+TEXT ·haveAVX2(SB),NOSPLIT,$0-1
+	// AVX2 needs the CPU to support AVX and AVX2, and the OS to save the
+	// YMM registers, as reported by XGETBV once OSXSAVE is set.
+	MOVL	$1, AX
+	XORL	CX, CX
+	CPUID
+	ANDL	$0x18000000, CX
+	CMPL	CX, $0x18000000
+	JNE	no
+	XORL	CX, CX
+	XGETBV
+	ANDL	$6, AX
+	CMPL	AX, $6
+	JNE	no
+	MOVL	$7, AX
+	XORL	CX, CX
+	CPUID
+	SHRL	$5, BX
+	ANDL	$1, BX
+	MOVB	BX, ret+0(FP)
+	RET
+no:
+	MOVB	$0, ret+0(FP)
+	RET
+
+// identityMask and bgraMask are VPSHUFB control masks that leave pixels as
+// they are, or swap their first and third bytes. VPSHUFB shuffles each
+// 16-byte lane separately, so both lanes are the same.
+DATA identityMask<>+0x00(SB)/8, $0x0706050403020100
+DATA identityMask<>+0x08(SB)/8, $0x0f0e0d0c0b0a0908
+DATA identityMask<>+0x10(SB)/8, $0x0706050403020100
+DATA identityMask<>+0x18(SB)/8, $0x0f0e0d0c0b0a0908
+GLOBL identityMask<>(SB), (NOPTR+RODATA), $32
+
+DATA bgraMask<>+0x00(SB)/8, $0x0704050603000102
+DATA bgraMask<>+0x08(SB)/8, $0x0f0c0d0e0b08090a
+DATA bgraMask<>+0x10(SB)/8, $0x0704050603000102
+DATA bgraMask<>+0x18(SB)/8, $0x0f0c0d0e0b08090a
+GLOBL bgraMask<>(SB), (NOPTR+RODATA), $32
+
+// alphaMask is a VPSHUFB control mask that copies each pixel's alpha, its
+// fourth byte, to all four of its bytes.
+DATA alphaMask<>+0x00(SB)/8, $0x0707070703030303
+DATA alphaMask<>+0x08(SB)/8, $0x0f0f0f0f0b0b0b0b
+DATA alphaMask<>+0x10(SB)/8, $0x0707070703030303
+DATA alphaMask<>+0x18(SB)/8, $0x0f0f0f0f0b0b0b0b
+GLOBL alphaMask<>(SB), (NOPTR+RODATA), $32
+
+// func copyBGRA(dst, src []byte)
+TEXT ·copyBGRA(SB),NOSPLIT,$0-48
+	MOVQ	dst_base+0(FP), DI
+	MOVQ	src_base+24(FP), SI
+	MOVQ	src_len+32(FP), CX
+
+	// Sanity check that len is a multiple of 32.
+	MOVQ	CX, AX
+	ANDQ	$31, AX
+	JNZ	done
+
+	VMOVDQU	bgraMask<>(SB), Y0
+
+	ADDQ	SI, CX
+loop:
+	CMPQ	SI, CX
+	JEQ	done
+
+	VMOVDQU	(SI), Y1
+	VPSHUFB	Y0, Y1, Y1
+	VMOVDQU	Y1, (DI)
+
+	ADDQ	$32, SI
+	ADDQ	$32, DI
+	JMP	loop
+done:
+	VZEROUPPER
+	RET
+
+// func over(dst, src []byte, swap bool)
+TEXT ·over(SB),NOSPLIT,$0-49
+	MOVQ	dst_base+0(FP), DI
+	MOVQ	src_base+24(FP), SI
+	MOVQ	src_len+32(FP), CX
+
+	// Sanity check that len is a multiple of 32.
+	MOVQ	CX, AX
+	ANDQ	$31, AX
+	JNZ	done
+
+	// Y0 shuffles the source pixels, if swap is set, and Y1 broadcasts their
+	// alpha. Y13 is all ones, Y14 is 0x0080 in every 16-bit word, and Y15 is
+	// zero.
+	LEAQ	identityMask<>(SB), AX
+	LEAQ	bgraMask<>(SB), BX
+	CMPB	swap+48(FP), $0
+	CMOVQNE	BX, AX
+	VMOVDQU	(AX), Y0
+	VMOVDQU	alphaMask<>(SB), Y1
+	VPCMPEQB	Y13, Y13, Y13
+	VPSRLW	$15, Y13, Y14
+	VPSLLW	$7, Y14, Y14
+	VPXOR	Y15, Y15, Y15
+
+	ADDQ	SI, CX
+loop:
+	CMPQ	SI, CX
+	JEQ	done
+
+	// Y2 is the source, Y3 the destination, and Y4 is 0xff minus the
+	// source alpha, for each of 8 pixels.
+	VMOVDQU	(SI), Y2
+	VPSHUFB	Y0, Y2, Y2
+	VMOVDQU	(DI), Y3
+	VPSHUFB	Y1, Y2, Y4
+	VPXOR	Y13, Y4, Y4
+
+	// Widen to 16 bits, the low and high halves of each lane separately,
+	// and multiply.
+	VPUNPCKLBW	Y15, Y3, Y5
+	VPUNPCKHBW	Y15, Y3, Y6
+	VPUNPCKLBW	Y15, Y4, Y7
+	VPUNPCKHBW	Y15, Y4, Y8
+	VPMULLW	Y7, Y5, Y5
+	VPMULLW	Y8, Y6, Y6
+
+	// Divide by 0xff, rounding: t += 0x80; t = (t + t>>8) >> 8.
+	VPADDW	Y14, Y5, Y5
+	VPADDW	Y14, Y6, Y6
+	VPSRLW	$8, Y5, Y7
+	VPSRLW	$8, Y6, Y8
+	VPADDW	Y7, Y5, Y5
+	VPADDW	Y8, Y6, Y6
+	VPSRLW	$8, Y5, Y5
+	VPSRLW	$8, Y6, Y6
+
+	// Narrow back to 8 bits, and add the source.
+	VPACKUSWB	Y6, Y5, Y5
+	VPADDUSB	Y2, Y5, Y5
+	VMOVDQU	Y5, (DI)
+
+	ADDQ	$32, SI
+	ADDQ	$32, DI
+	JMP	loop
+done:
+	VZEROUPPER
+	RET
@@ -0,0 +1,23 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package swizzle
+
+// On arm64, copyBGRA uses NEON, which every arm64 CPU supports, and processes
+// 16 bytes, or 4 pixels, at a time. BGRA uses copyBGRA in place, and over is
+// not implemented in assembly.
+const (
+	useBGRA16   = false
+	useBGRA4    = false
+	useCopyBGRA = true
+	useOver     = false
+
+	copyBGRASize = 16
+	overSize     = 4
+)
+
+func bgra16(p []byte) { panic("unreachable") }
+func bgra4(p []byte)  { panic("unreachable") }
+func copyBGRA(dst, src []byte)
+func over(dst, src []byte, swap bool) { panic("unreachable") }
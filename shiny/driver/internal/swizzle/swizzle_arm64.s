@@ -0,0 +1,35 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "textflag.h"
+
+// bgraMask is a VTBL index vector that swaps the first and third bytes of
+// each pixel.
+DATA bgraMask<>+0x00(SB)/8, $0x0704050603000102
+DATA bgraMask<>+0x08(SB)/8, $0x0f0c0d0e0b08090a
+GLOBL bgraMask<>(SB), (NOPTR+RODATA), $16
+
+// func copyBGRA(dst, src []byte)
+TEXT ·copyBGRA(SB),NOSPLIT,$0-48
+	MOVD	dst_base+0(FP), R0
+	MOVD	src_base+24(FP), R1
+	MOVD	src_len+32(FP), R2
+
+	// Sanity check that len is a multiple of 16.
+	AND	$15, R2, R3
+	CBNZ	R3, done
+
+	MOVD	$bgraMask<>(SB), R3
+	VLD1	(R3), [V0.B16]
+loop:
+	CBZ	R2, done
+
+	VLD1.P	16(R1), [V1.B16]
+	VTBL	V0.B16, [V1.B16], V1.B16
+	VST1.P	[V1.B16], 16(R0)
+
+	SUB	$16, R2
+	B	loop
+done:
+	RET
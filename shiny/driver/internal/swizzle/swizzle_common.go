@@ -3,7 +3,8 @@
 // license that can be found in the LICENSE file.
 
 // Package swizzle provides functions for converting between RGBA pixel
-// formats.
+// formats, and for compositing alpha-premultiplied pixels, using SIMD
+// instructions where available.
 package swizzle // import "golang.org/x/exp/shiny/driver/internal/swizzle"
 
 // BGRA converts a pixel buffer between Go's RGBA and other systems' BGRA byte
@@ -15,7 +16,12 @@ func BGRA(p []byte) {
 		panic("input slice length is not a multiple of 4")
 	}
 
-	// Use asm code for 16- or 4-byte chunks, if supported.
+	// Use asm code for 32-, 16- or 4-byte chunks, if supported.
+	if useCopyBGRA {
+		n := len(p) &^ (copyBGRASize - 1)
+		copyBGRA(p[:n], p[:n])
+		p = p[n:]
+	}
 	if useBGRA16 {
 		n := len(p) &^ (16 - 1)
 		bgra16(p[:n])
@@ -29,3 +35,30 @@ func BGRA(p []byte) {
 		p[i+0], p[i+2] = p[i+2], p[i+0]
 	}
 }
+
+// CopyBGRA copies src to dst, converting between RGBA and BGRA byte orders. dst
+// and src may be the same slice, to convert in place, but must not otherwise
+// overlap.
+//
+// It panics if the src slice length is not a multiple of 4, or if dst is
+// shorter than src.
+func CopyBGRA(dst, src []byte) {
+	if len(src)%4 != 0 {
+		panic("input slice length is not a multiple of 4")
+	}
+	if len(dst) < len(src) {
+		panic("output slice is shorter than input slice")
+	}
+	dst = dst[:len(src)]
+
+	if useCopyBGRA {
+		n := len(src) &^ (copyBGRASize - 1)
+		copyBGRA(dst[:n], src[:n])
+		dst, src = dst[n:], src[n:]
+	}
+
+	for i := 0; i < len(src); i += 4 {
+		r, g, b, a := src[i+0], src[i+1], src[i+2], src[i+3]
+		dst[i+0], dst[i+1], dst[i+2], dst[i+3] = b, g, r, a
+	}
+}
@@ -2,14 +2,21 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build !amd64
+// +build !amd64,!arm64
 
 package swizzle
 
 const (
-	useBGRA16 = false
-	useBGRA4  = false
+	useBGRA16   = false
+	useBGRA4    = false
+	useCopyBGRA = false
+	useOver     = false
+
+	copyBGRASize = 4
+	overSize     = 4
 )
 
-func bgra16(p []byte) { panic("unreachable") }
-func bgra4(p []byte)  { panic("unreachable") }
+func bgra16(p []byte)                 { panic("unreachable") }
+func bgra4(p []byte)                  { panic("unreachable") }
+func copyBGRA(dst, src []byte)        { panic("unreachable") }
+func over(dst, src []byte, swap bool) { panic("unreachable") }
@@ -6,7 +6,11 @@ package swizzle
 
 import (
 	"bytes"
+	"image"
+	"image/draw"
 	"math/rand"
+	"runtime"
+	"sync/atomic"
 	"testing"
 )
 
@@ -73,6 +77,149 @@ func pureGoBGRA(p []byte) {
 	}
 }
 
+func TestCopyBGRA(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	src := make([]byte, 1024)
+	for i := range src {
+		src[i] = uint8(r.Intn(256))
+	}
+	want := append([]byte(nil), src...)
+	pureGoBGRA(want)
+
+	for n := 0; n <= len(src); n += 4 {
+		dst := make([]byte, len(src))
+		CopyBGRA(dst, src[:n])
+		if !bytes.Equal(dst[:n], want[:n]) || !bytes.Equal(dst[n:], make([]byte, len(src)-n)) {
+			t.Fatalf("n=%d: got %v, want %v", n, dst, want[:n])
+		}
+	}
+
+	// Copying in place is the same as BGRA.
+	got := append([]byte(nil), src...)
+	CopyBGRA(got, got)
+	if !bytes.Equal(got, want) {
+		t.Errorf("in place: got %v, want %v", got, want)
+	}
+}
+
+// pureGoOver is a reference implementation of Over, that works in 16-bit
+// precision, as image/draw does, but rounds to nearest.
+func pureGoOver(dst, src []byte, swap bool) {
+	for i := 0; i < len(src); i += 4 {
+		s := [4]uint32{uint32(src[i+0]), uint32(src[i+1]), uint32(src[i+2]), uint32(src[i+3])}
+		if swap {
+			s[0], s[2] = s[2], s[0]
+		}
+		for j := 0; j < 4; j++ {
+			v := s[j] + (uint32(dst[i+j])*(0xff-s[3])+0x7f)/0xff
+			if v > 0xff {
+				v = 0xff
+			}
+			dst[i+j] = uint8(v)
+		}
+	}
+}
+
+func TestOver(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randPremul := func(b []byte) {
+		for i := 0; i < len(b); i += 4 {
+			a := r.Intn(256)
+			switch r.Intn(4) {
+			case 0:
+				a = 0
+			case 1:
+				a = 0xff
+			}
+			for j := 0; j < 3; j++ {
+				b[i+j] = uint8(r.Intn(a + 1))
+			}
+			b[i+3] = uint8(a)
+		}
+	}
+
+	for _, swap := range []bool{false, true} {
+		for i := 0; i < 1000; i++ {
+			n := 4 * r.Intn(100)
+			src := make([]byte, n)
+			dst := make([]byte, n)
+			randPremul(src)
+			randPremul(dst)
+			want := append([]byte(nil), dst...)
+			pureGoOver(want, src, swap)
+			if swap {
+				OverBGRA(dst, src)
+			} else {
+				Over(dst, src)
+			}
+			if !bytes.Equal(dst, want) {
+				t.Fatalf("swap=%t, n=%d: got %v, want %v", swap, n, dst, want)
+			}
+		}
+	}
+}
+
+func TestOverMatchesDraw(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	const w, h = 64, 4
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(src.Pix); i += 4 {
+		a := r.Intn(256)
+		for j := 0; j < 3; j++ {
+			src.Pix[i+j] = uint8(r.Intn(a + 1))
+			dst.Pix[i+j] = uint8(r.Intn(256))
+		}
+		src.Pix[i+3], dst.Pix[i+3] = uint8(a), 0xff
+	}
+	got := append([]byte(nil), dst.Pix...)
+	Over(got, src.Pix)
+	draw.Draw(dst, dst.Bounds(), src, image.Point{}, draw.Over)
+	for i, want := range dst.Pix {
+		if d := int(got[i]) - int(want); d < -1 || 1 < d {
+			t.Fatalf("byte %d: got %#02x, draw.Over gives %#02x", i, got[i], want)
+		}
+	}
+}
+
+func TestParallel(t *testing.T) {
+	// The image is large enough to be split into bands.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	const w, h = 4 * 1024, 1024
+	const dstStride, srcStride = w + 8, w + 4
+	dst := make([]byte, dstStride*h)
+	src := make([]byte, srcStride*h)
+	for y := 0; y < h; y++ {
+		src[y*srcStride] = uint8(y)
+	}
+
+	var calls int32
+	Parallel(dst, dstStride, src, srcStride, w, h, func(d, s []byte) {
+		atomic.AddInt32(&calls, 1)
+		if len(d) != w || len(s) != w {
+			t.Errorf("got rows of %d and %d bytes, want %d", len(d), len(s), w)
+		}
+		copy(d, s)
+	})
+	if calls != h {
+		t.Errorf("got %d calls, want %d", calls, h)
+	}
+	for y := 0; y < h; y++ {
+		if got := dst[y*dstStride]; got != uint8(y) {
+			t.Fatalf("row %d: got %d, want %d", y, got, uint8(y))
+		}
+		if got := dst[y*dstStride+w]; got != 0 {
+			t.Fatalf("row %d: wrote past the row's width", y)
+		}
+	}
+
+	Parallel(dst, dstStride, nil, 0, w, 1, func(d, s []byte) {
+		if s != nil {
+			t.Errorf("nil src: got a non-nil src row")
+		}
+	})
+}
+
 func benchmarkBGRA(b *testing.B, f func([]byte)) {
 	const w, h = 1920, 1080 // 1080p RGBA.
 	buf := make([]byte, 4*w*h)
@@ -84,3 +231,42 @@ func benchmarkBGRA(b *testing.B, f func([]byte)) {
 
 func BenchmarkBGRA(b *testing.B)       { benchmarkBGRA(b, BGRA) }
 func BenchmarkPureGoBGRA(b *testing.B) { benchmarkBGRA(b, pureGoBGRA) }
+
+func BenchmarkCopyBGRA(b *testing.B) {
+	const w, h = 1920, 1080 // 1080p RGBA.
+	dst := make([]byte, 4*w*h)
+	src := make([]byte, 4*w*h)
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CopyBGRA(dst, src)
+	}
+}
+
+func BenchmarkOver(b *testing.B) {
+	const w, h = 1920, 1080 // 1080p RGBA.
+	dst := make([]byte, 4*w*h)
+	src := make([]byte, 4*w*h)
+	for i := range src {
+		src[i] = uint8(i)
+	}
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Over(dst, src)
+	}
+}
+
+func BenchmarkParallelOver(b *testing.B) {
+	const w, h = 3840, 2160 // 4K RGBA.
+	dst := make([]byte, 4*w*h)
+	src := make([]byte, 4*w*h)
+	for i := range src {
+		src[i] = uint8(i)
+	}
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Parallel(dst, 4*w, src, 4*w, 4*w, h, Over)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package swtexture provides a screen.Texture and screen.Buffer whose pixels
+// are held in memory and drawn in software, for drivers that composite their
+// windows themselves, such as the headless, Wayland, WebAssembly and mobile
+// drivers. Drawing on them is done by the time that Draw, Copy, Fill or
+// Upload returns, so that those drivers' RenderFrame need not wait for it.
+package swtexture // import "golang.org/x/exp/shiny/driver/internal/swtexture"
+
+import (
+	"image"
+	"sync/atomic"
+
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/pixpool"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Allocator implements the NewBuffer, NewTextureWithOptions and
+// TextureMemoryEstimate methods of the screen.Screen interface.
+//
+// The zero value is ready to use. It is safe for concurrent use.
+type Allocator struct {
+	textureBytes atomic.Int64
+
+	// pixPool recycles the pixels of released Buffers.
+	pixPool pixpool.Pool
+}
+
+// NewBuffer implements screen.Screen.
+func (a *Allocator) NewBuffer(size image.Point) (screen.Buffer, error) {
+	return &bufferImpl{
+		a:    a,
+		rgba: a.pixPool.NewRGBA(size),
+		size: size,
+	}, nil
+}
+
+// NewTextureWithOptions implements screen.Screen. Mipmaps are not kept, and
+// compressed textures are not supported.
+func (a *Allocator) NewTextureWithOptions(size image.Point, opts *screen.NewTextureOptions) (screen.Texture, error) {
+	if opts != nil && opts.Compressed != screen.CompressedNone {
+		return nil, screen.ErrUnsupportedFormat
+	}
+	format := opts.GetFormat()
+	if format == screen.PixelFormatBGRA8 {
+		format = screen.PixelFormatRGBA8
+	}
+	t := &Texture{
+		a:      a,
+		size:   size,
+		format: format,
+		m:      drawer.NewImage(format, image.Rectangle{Max: size}),
+	}
+	if opts != nil {
+		t.filter, t.wrap = opts.Filter, opts.Wrap
+	}
+	a.textureBytes.Add(t.bytes())
+	return t, nil
+}
+
+// TextureMemoryEstimate implements screen.Screen.
+func (a *Allocator) TextureMemoryEstimate() int64 {
+	return a.textureBytes.Load()
+}
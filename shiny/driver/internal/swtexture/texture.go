@@ -0,0 +1,176 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package swtexture
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/yuv"
+	"golang.org/x/exp/shiny/screen"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// Texture is a screen.Texture held in memory. It also implements
+// screen.Drawer, so that it can back a screen.Layer.
+type Texture struct {
+	a      *Allocator
+	size   image.Point
+	format screen.PixelFormat
+	filter screen.TextureFilter
+	wrap   screen.TextureWrap
+
+	// mu guards m and released.
+	mu       sync.Mutex
+	m        draw.Image
+	released bool
+}
+
+func (t *Texture) Size() image.Point          { return t.size }
+func (t *Texture) Bounds() image.Rectangle    { return image.Rectangle{Max: t.size} }
+func (t *Texture) Format() screen.PixelFormat { return t.format }
+
+// bytes returns the estimated memory used by the texture.
+func (t *Texture) bytes() int64 {
+	return int64(t.format.BytesPerPixel()) * int64(t.size.X) * int64(t.size.Y)
+}
+
+func (t *Texture) Release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return
+	}
+	t.released = true
+	t.a.textureBytes.Add(-t.bytes())
+}
+
+func (t *Texture) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	Upload(t.m, dp, src, sr)
+}
+
+func (t *Texture) UploadPixels(dp image.Point, src *screen.Pixels, sr image.Rectangle) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	drawer.UploadPixels(t.m, dp, src, sr)
+}
+
+func (t *Texture) UploadYUV(dp image.Point, src *screen.YUVImage, sr image.Rectangle) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	drawer.RGBA(t.m, func(m *image.RGBA) {
+		yuv.Convert(m, dp, src, sr)
+	})
+}
+
+func (t *Texture) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	draw.Draw(t.m, dr, image.NewUniform(src), image.Point{}, op)
+}
+
+// Draw, DrawUniform, Copy and Scale draw on the texture, so that it can back
+// a screen.Layer. Unless src was created with another filter, they use
+// nearest neighbor sampling. Draw reads a copy of src's pixels, so that
+// drawing a texture on itself is well defined.
+
+func (t *Texture) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	u := src.(*Texture)
+	r := sr
+	if u.wrap != screen.WrapClamp {
+		r = u.Bounds()
+	}
+	m, err := u.Download(r.Min, r)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return
+	}
+	drawer.RGBA(t.m, func(dst *image.RGBA) {
+		var c drawer.Clip
+		c.Transform(drawer.Filter(u.filter, xdraw.NearestNeighbor), dst, src2dst, drawer.Wrap(m, u.wrap), sr, op, opts)
+	})
+}
+
+func (t *Texture) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return
+	}
+	drawer.RGBA(t.m, func(dst *image.RGBA) {
+		var c drawer.Clip
+		c.Transform(xdraw.NearestNeighbor, dst, src2dst, image.NewUniform(src), sr, op, opts)
+	})
+}
+
+func (t *Texture) Copy(dp image.Point, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Copy(t, dp, src, sr, op, opts)
+}
+
+func (t *Texture) Scale(dr image.Rectangle, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Scale(t, dr, src, sr, op, opts)
+}
+
+// Transform draws the sr part of t onto dst, subject to c, as c.Transform
+// does. It samples with t's filter, or with def if t was created without one,
+// and wraps as t was created to. It does nothing if t is released.
+//
+// If the caller also holds a lock that guards dst, it should lock that before
+// calling Transform.
+func (t *Texture) Transform(c *drawer.Clip, def xdraw.Transformer, dst *image.RGBA, src2dst f64.Aff3, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return
+	}
+	c.Transform(drawer.Filter(t.filter, def), dst, src2dst, drawer.Wrap(t.m, t.wrap), sr, op, opts)
+}
+
+func (t *Texture) Download(dp image.Point, sr image.Rectangle) (*image.RGBA, error) {
+	r := sr.Intersect(t.Bounds())
+	m := image.NewRGBA(r.Add(dp.Sub(sr.Min)))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return nil, errTextureReleased
+	}
+	draw.Draw(m, m.Rect, t.m, r.Min, draw.Src)
+	return m, nil
+}
+
+var errTextureReleased = errors.New("swtexture: texture is released")
+
+// Upload implements the screen.Uploader interface's Upload method, onto dst.
+func Upload(dst draw.Image, dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	originalSRMin := sr.Min
+	sr = sr.Intersect(src.Bounds())
+	if sr.Empty() {
+		return
+	}
+	dp = dp.Add(sr.Min.Sub(originalSRMin))
+	draw.Draw(dst, image.Rectangle{Min: dp, Max: dp.Add(sr.Size())}, src.RGBA(), sr.Min, draw.Src)
+}
@@ -0,0 +1,655 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"image"
+	"math"
+	"runtime"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Accessibility uses UI Automation. A window whose tree has been set by
+// SetAccessTree answers WM_GETOBJECT with the provider of its tree's root.
+// Each node of the tree is an accessElement, a Go value whose first fields
+// point to the vtables of the COM interfaces that it implements, as for
+// drag-and-drop. Providers that do not ask for COM threading are called on
+// the UI thread, where their trees are also updated, so, like the menus,
+// they are only accessed on that thread.
+//
+// Unlike drag-and-drop's objects, elements are reference counted, as UI
+// Automation may hold on to them after they are removed from their tree.
+// Go keeps them alive while they are in a tree, or referenced.
+
+var (
+	iidIRawElementProviderSimple       = _GUID{0xd6dd68d1, 0x86fd, 0x4332, [8]byte{0x86, 0x66, 0x9a, 0xbe, 0xde, 0xa2, 0xd2, 0x4c}}
+	iidIRawElementProviderFragment     = _GUID{0xf7063da8, 0x8359, 0x439c, [8]byte{0x92, 0x97, 0xbb, 0xc5, 0x29, 0x9a, 0x7d, 0x87}}
+	iidIRawElementProviderFragmentRoot = _GUID{0x620ce2a5, 0xab8f, 0x40a9, [8]byte{0x86, 0xcb, 0xde, 0x3c, 0x75, 0x59, 0x9b, 0x58}}
+	iidIInvokeProvider                 = _GUID{0x54fcb24b, 0xe18e, 0x47a2, [8]byte{0xb4, 0xd3, 0xec, 0xcb, 0xe7, 0x75, 0x99, 0xa2}}
+	iidIValueProvider                  = _GUID{0xc7935180, 0x6fb3, 0x4201, [8]byte{0xb1, 0x74, 0x7d, 0xf7, 0x3a, 0xdb, 0xf6, 0x4a}}
+)
+
+const (
+	_E_ACCESSDENIED             = 0x80070005
+	_E_OUTOFMEMORY              = 0x8007000e
+	_UIA_E_ELEMENTNOTAVAILABLE  = 0x80040201
+	_UiaRootObjectId            = -25
+	_UiaAppendRuntimeId         = 3
+	_ProviderOptions_ServerSide = 1
+
+	_UIA_InvokePatternId               = 10000
+	_UIA_ValuePatternId                = 10002
+	_UIA_AutomationFocusChangedEventId = 20005
+
+	_UIA_ControlTypePropertyId         = 30003
+	_UIA_NamePropertyId                = 30005
+	_UIA_HasKeyboardFocusPropertyId    = 30008
+	_UIA_IsKeyboardFocusablePropertyId = 30009
+	_UIA_IsEnabledPropertyId           = 30010
+	_UIA_AutomationIdPropertyId        = 30011
+
+	_NavigateDirection_Parent          = 0
+	_NavigateDirection_NextSibling     = 1
+	_NavigateDirection_PreviousSibling = 2
+	_NavigateDirection_FirstChild      = 3
+	_NavigateDirection_LastChild       = 4
+
+	_VT_EMPTY = 0
+	_VT_I4    = 3
+	_VT_BSTR  = 8
+	_VT_BOOL  = 11
+)
+
+// controlTypes are the UI Automation control types of the roles.
+var controlTypes = [...]int32{
+	screen.AccessGroup:        50026,
+	screen.AccessLabel:        50020,
+	screen.AccessButton:       50000,
+	screen.AccessCheckBox:     50002,
+	screen.AccessTextField:    50004,
+	screen.AccessTextEditor:   50004,
+	screen.AccessImage:        50006,
+	screen.AccessList:         50008,
+	screen.AccessListItem:     50007,
+	screen.AccessTable:        50036,
+	screen.AccessColumnHeader: 50035,
+	screen.AccessCell:         50029,
+	screen.AccessScrollArea:   50033,
+	screen.AccessSlider:       50015,
+}
+
+type _VARIANT struct {
+	VT  uint16
+	_   [3]uint16
+	Val uintptr
+	_   uintptr
+}
+
+type _UiaRect struct {
+	Left, Top, Width, Height float64
+}
+
+// accessElement is a node of an accessTree. Its first fields are its COM
+// interfaces, each of which points to its vtable.
+type accessElement struct {
+	simple       *[7]uintptr
+	fragment     *[9]uintptr
+	fragmentRoot *[5]uintptr
+	invoke       *[4]uintptr
+	value        *[6]uintptr
+
+	refs int32
+
+	// tree is the tree that the element is in, or nil if it has been
+	// removed. node is its node, without its children.
+	tree     *accessTree
+	node     screen.AccessNode
+	parent   *accessElement
+	children []*accessElement
+}
+
+// The offsets of an accessElement's interfaces, which their methods subtract
+// from the interface pointers that they are called with.
+const (
+	offsetSimple       = unsafe.Offsetof(accessElement{}.simple)
+	offsetFragment     = unsafe.Offsetof(accessElement{}.fragment)
+	offsetFragmentRoot = unsafe.Offsetof(accessElement{}.fragmentRoot)
+	offsetInvoke       = unsafe.Offsetof(accessElement{}.invoke)
+	offsetValue        = unsafe.Offsetof(accessElement{}.value)
+)
+
+var (
+	simpleVtbl = [7]uintptr{
+		elementQueryInterface(offsetSimple),
+		elementAddRef(offsetSimple),
+		elementRelease(offsetSimple),
+		syscall.NewCallback(simpleProviderOptions),
+		syscall.NewCallback(simpleGetPatternProvider),
+		syscall.NewCallback(simpleGetPropertyValue),
+		syscall.NewCallback(simpleHostRawElementProvider),
+	}
+	fragmentVtbl = [9]uintptr{
+		elementQueryInterface(offsetFragment),
+		elementAddRef(offsetFragment),
+		elementRelease(offsetFragment),
+		syscall.NewCallback(fragmentNavigate),
+		syscall.NewCallback(fragmentGetRuntimeId),
+		syscall.NewCallback(fragmentBoundingRectangle),
+		syscall.NewCallback(fragmentGetEmbeddedFragmentRoots),
+		syscall.NewCallback(fragmentSetFocus),
+		syscall.NewCallback(fragmentFragmentRoot),
+	}
+	fragmentRootVtbl = [5]uintptr{
+		elementQueryInterface(offsetFragmentRoot),
+		elementAddRef(offsetFragmentRoot),
+		elementRelease(offsetFragmentRoot),
+		syscall.NewCallback(fragmentRootElementProviderFromPoint),
+		syscall.NewCallback(fragmentRootGetFocus),
+	}
+	invokeVtbl = [4]uintptr{
+		elementQueryInterface(offsetInvoke),
+		elementAddRef(offsetInvoke),
+		elementRelease(offsetInvoke),
+		syscall.NewCallback(invokeInvoke),
+	}
+	valueVtbl = [6]uintptr{
+		elementQueryInterface(offsetValue),
+		elementAddRef(offsetValue),
+		elementRelease(offsetValue),
+		syscall.NewCallback(valueSetValue),
+		syscall.NewCallback(valueValue),
+		syscall.NewCallback(valueIsReadOnly),
+	}
+)
+
+// liveElements are the elements that are in a tree, or referenced by UI
+// Automation, which keeps them alive.
+var liveElements = map[*accessElement]bool{}
+
+func newAccessElement() *accessElement {
+	e := &accessElement{
+		simple:       &simpleVtbl,
+		fragment:     &fragmentVtbl,
+		fragmentRoot: &fragmentRootVtbl,
+		invoke:       &invokeVtbl,
+		value:        &valueVtbl,
+	}
+	liveElements[e] = true
+	return e
+}
+
+// elementAt returns the element whose interface at the given offset is this.
+func elementAt(this, offset uintptr) *accessElement {
+	return (*accessElement)(Pointer(this - offset))
+}
+
+// ptr returns the element's interface at the given offset, after adding a
+// reference to it, for returning to UI Automation.
+func (e *accessElement) ptr(offset uintptr) uintptr {
+	e.refs++
+	return uintptr(unsafe.Pointer(e)) + offset
+}
+
+func (e *accessElement) invokable() bool {
+	switch e.node.Role {
+	case screen.AccessButton, screen.AccessCheckBox, screen.AccessListItem, screen.AccessColumnHeader:
+		return true
+	}
+	return false
+}
+
+func (e *accessElement) hasValue() bool {
+	switch e.node.Role {
+	case screen.AccessTextField, screen.AccessTextEditor, screen.AccessSlider:
+		return true
+	}
+	return e.node.Value != ""
+}
+
+func (e *accessElement) send(action screen.AccessAction) {
+	AccessActionEvent(e.tree.hwnd, screen.AccessActionEvent{ID: e.node.ID, Action: action})
+}
+
+func elementQueryInterface(offset uintptr) uintptr {
+	return syscall.NewCallback(func(this uintptr, riid *_GUID, ppv *uintptr) uintptr {
+		e := elementAt(this, offset)
+		o := ^uintptr(0)
+		switch *riid {
+		case iidIUnknown, iidIRawElementProviderSimple:
+			o = offsetSimple
+		case iidIRawElementProviderFragment:
+			o = offsetFragment
+		case iidIRawElementProviderFragmentRoot:
+			if e.parent == nil {
+				o = offsetFragmentRoot
+			}
+		case iidIInvokeProvider:
+			if e.invokable() {
+				o = offsetInvoke
+			}
+		case iidIValueProvider:
+			if e.hasValue() {
+				o = offsetValue
+			}
+		}
+		if o == ^uintptr(0) {
+			*ppv = 0
+			return _E_NOINTERFACE
+		}
+		*ppv = e.ptr(o)
+		return _S_OK
+	})
+}
+
+func elementAddRef(offset uintptr) uintptr {
+	return syscall.NewCallback(func(this uintptr) uintptr {
+		e := elementAt(this, offset)
+		e.refs++
+		return uintptr(e.refs)
+	})
+}
+
+func elementRelease(offset uintptr) uintptr {
+	return syscall.NewCallback(func(this uintptr) uintptr {
+		e := elementAt(this, offset)
+		e.refs--
+		if e.refs <= 0 && e.tree == nil {
+			delete(liveElements, e)
+		}
+		return uintptr(e.refs)
+	})
+}
+
+func simpleProviderOptions(this uintptr, options *int32) uintptr {
+	*options = _ProviderOptions_ServerSide
+	return _S_OK
+}
+
+func simpleGetPatternProvider(this uintptr, pattern int32, out *uintptr) uintptr {
+	e := elementAt(this, offsetSimple)
+	*out = 0
+	switch {
+	case e.tree == nil:
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	case pattern == _UIA_InvokePatternId && e.invokable():
+		*out = e.ptr(offsetInvoke)
+	case pattern == _UIA_ValuePatternId && e.hasValue():
+		*out = e.ptr(offsetValue)
+	}
+	return _S_OK
+}
+
+func simpleGetPropertyValue(this uintptr, property int32, v *_VARIANT) uintptr {
+	e := elementAt(this, offsetSimple)
+	*v = _VARIANT{}
+	if e.tree == nil {
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	}
+	n := &e.node
+	switch property {
+	case _UIA_ControlTypePropertyId:
+		if int(n.Role) < len(controlTypes) {
+			v.VT, v.Val = _VT_I4, uintptr(uint32(controlTypes[n.Role]))
+		}
+	case _UIA_NamePropertyId:
+		v.VT, v.Val = _VT_BSTR, bstr(n.Name)
+	case _UIA_AutomationIdPropertyId:
+		v.VT, v.Val = _VT_BSTR, bstr(strconv.Itoa(n.ID))
+	case _UIA_HasKeyboardFocusPropertyId:
+		v.VT, v.Val = _VT_BOOL, variantBool(n.Focused)
+	case _UIA_IsKeyboardFocusablePropertyId:
+		v.VT, v.Val = _VT_BOOL, variantBool(n.Role == screen.AccessTextField || n.Role == screen.AccessTextEditor)
+	case _UIA_IsEnabledPropertyId:
+		v.VT, v.Val = _VT_BOOL, variantBool(!n.Disabled)
+	}
+	return _S_OK
+}
+
+func bstr(s string) uintptr {
+	p, err := syscall.UTF16PtrFromString(s)
+	if err != nil {
+		return 0
+	}
+	return _SysAllocString(p)
+}
+
+// variantBool returns b as a VARIANT_BOOL, which is -1 for true.
+func variantBool(b bool) uintptr {
+	if b {
+		return 0xffff
+	}
+	return 0
+}
+
+func simpleHostRawElementProvider(this uintptr, out *uintptr) uintptr {
+	e := elementAt(this, offsetSimple)
+	*out = 0
+	if e.tree == nil {
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	}
+	// The root is hosted by the window, whose provider gives it the
+	// window's own properties, such as its bounds and title.
+	if e.parent == nil {
+		return uintptr(uint32(_UiaHostProviderFromHwnd(e.tree.hwnd, out)))
+	}
+	return _S_OK
+}
+
+func fragmentNavigate(this uintptr, direction int32, out *uintptr) uintptr {
+	e := elementAt(this, offsetFragment)
+	*out = 0
+	if e.tree == nil {
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	}
+	var to *accessElement
+	switch direction {
+	case _NavigateDirection_Parent:
+		to = e.parent
+	case _NavigateDirection_NextSibling, _NavigateDirection_PreviousSibling:
+		if e.parent == nil {
+			break
+		}
+		siblings := e.parent.children
+		for i, c := range siblings {
+			if c != e {
+				continue
+			}
+			if j := i + 1; direction == _NavigateDirection_NextSibling && j < len(siblings) {
+				to = siblings[j]
+			} else if j := i - 1; direction == _NavigateDirection_PreviousSibling && j >= 0 {
+				to = siblings[j]
+			}
+			break
+		}
+	case _NavigateDirection_FirstChild:
+		if len(e.children) != 0 {
+			to = e.children[0]
+		}
+	case _NavigateDirection_LastChild:
+		if len(e.children) != 0 {
+			to = e.children[len(e.children)-1]
+		}
+	}
+	if to != nil {
+		*out = to.ptr(offsetFragment)
+	}
+	return _S_OK
+}
+
+func fragmentGetRuntimeId(this uintptr, out *uintptr) uintptr {
+	e := elementAt(this, offsetFragment)
+	*out = 0
+	if e.tree == nil {
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	}
+	// The root's runtime ID is its host's.
+	if e.parent == nil {
+		return _S_OK
+	}
+	sa := _SafeArrayCreateVector(_VT_I4, 0, 2)
+	if sa == 0 {
+		return _E_OUTOFMEMORY
+	}
+	for i, v := range [2]int32{_UiaAppendRuntimeId, int32(e.node.ID)} {
+		index := int32(i)
+		_SafeArrayPutElement(sa, &index, unsafe.Pointer(&v))
+	}
+	*out = sa
+	return _S_OK
+}
+
+func fragmentBoundingRectangle(this uintptr, r *_UiaRect) uintptr {
+	e := elementAt(this, offsetFragment)
+	*r = _UiaRect{}
+	if e.tree == nil {
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	}
+	// The bounds are in client pixels, and UI Automation's are in screen
+	// pixels.
+	b := e.node.Bounds
+	pt := _POINT{X: int32(b.Min.X), Y: int32(b.Min.Y)}
+	_ClientToScreen(e.tree.hwnd, &pt)
+	*r = _UiaRect{
+		Left:   float64(pt.X),
+		Top:    float64(pt.Y),
+		Width:  float64(b.Dx()),
+		Height: float64(b.Dy()),
+	}
+	return _S_OK
+}
+
+func fragmentGetEmbeddedFragmentRoots(this uintptr, out *uintptr) uintptr {
+	*out = 0
+	return _S_OK
+}
+
+func fragmentSetFocus(this uintptr) uintptr {
+	e := elementAt(this, offsetFragment)
+	if e.tree == nil {
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	}
+	e.send(screen.AccessFocus)
+	return _S_OK
+}
+
+func fragmentFragmentRoot(this uintptr, out *uintptr) uintptr {
+	e := elementAt(this, offsetFragment)
+	*out = 0
+	if e.tree == nil {
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	}
+	*out = e.tree.root.ptr(offsetFragmentRoot)
+	return _S_OK
+}
+
+// fragmentRootElementProviderFromPoint takes the point, in screen pixels, as
+// two doubles. On 32-bit x86 they are on the stack, in a, b, c and d, but on
+// 64-bit Windows they are in floating-point registers, which callbacks cannot
+// read, so the mouse pointer's position, which is where the point usually
+// comes from, is used instead.
+func fragmentRootElementProviderFromPoint(this, a, b, c, d, e uintptr) uintptr {
+	var (
+		out *uintptr
+		pt  _POINT
+	)
+	switch runtime.GOARCH {
+	case "386":
+		pt.X = int32(math.Float64frombits(uint64(a) | uint64(b)<<32))
+		pt.Y = int32(math.Float64frombits(uint64(c) | uint64(d)<<32))
+		out = (*uintptr)(Pointer(e))
+	case "arm64":
+		out = (*uintptr)(Pointer(a))
+		_GetCursorPos(&pt)
+	default:
+		out = (*uintptr)(Pointer(c))
+		_GetCursorPos(&pt)
+	}
+	*out = 0
+
+	root := elementAt(this, offsetFragmentRoot)
+	if root.tree == nil {
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	}
+	_ScreenToClient(root.tree.hwnd, &pt)
+	p := image.Point{int(pt.X), int(pt.Y)}
+	if !p.In(root.node.Bounds) {
+		return _S_OK
+	}
+	// Find the deepest element at p, preferring later siblings, which are
+	// painted over earlier ones.
+	at := root
+	for found := true; found; {
+		found = false
+		for i := len(at.children) - 1; i >= 0; i-- {
+			if c := at.children[i]; p.In(c.node.Bounds) {
+				at, found = c, true
+				break
+			}
+		}
+	}
+	*out = at.ptr(offsetFragment)
+	return _S_OK
+}
+
+func fragmentRootGetFocus(this uintptr, out *uintptr) uintptr {
+	root := elementAt(this, offsetFragmentRoot)
+	*out = 0
+	if root.tree == nil {
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	}
+	if e := root.tree.elements[root.tree.focus]; e != nil && e != root && e.node.Focused {
+		*out = e.ptr(offsetFragment)
+	}
+	return _S_OK
+}
+
+func invokeInvoke(this uintptr) uintptr {
+	e := elementAt(this, offsetInvoke)
+	if e.tree == nil {
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	}
+	e.send(screen.AccessPress)
+	return _S_OK
+}
+
+// valueSetValue fails, as the values are read only. Assistive technologies
+// type into text fields as the user does.
+func valueSetValue(this uintptr, v *uint16) uintptr {
+	return _E_ACCESSDENIED
+}
+
+func valueValue(this uintptr, out *uintptr) uintptr {
+	e := elementAt(this, offsetValue)
+	*out = 0
+	if e.tree == nil {
+		return _UIA_E_ELEMENTNOTAVAILABLE
+	}
+	*out = bstr(e.node.Value)
+	return _S_OK
+}
+
+func valueIsReadOnly(this uintptr, out *int32) uintptr {
+	*out = 1
+	return _S_OK
+}
+
+// accessTree is the tree of a window, set by SetAccessTree.
+type accessTree struct {
+	hwnd syscall.Handle
+	root *accessElement
+
+	// elements are the tree's elements, keyed by their nodes' IDs. focus is
+	// the ID of the focused element, if any.
+	elements map[int]*accessElement
+	focus    int
+
+	// returned is whether the root has been returned for WM_GETOBJECT.
+	returned bool
+}
+
+// accessTrees are the windows' trees. They are only used on the UI thread.
+var accessTrees = map[syscall.Handle]*accessTree{}
+
+// SetAccessTree sets hwnd's tree of UI Automation elements, or removes it,
+// for a nil root.
+func SetAccessTree(hwnd syscall.Handle, root *screen.AccessNode) error {
+	SendMessage(hwnd, msgSetAccessTree, 0, uintptr(unsafe.Pointer(root)))
+	return nil
+}
+
+func sendSetAccessTree(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	root := (*screen.AccessNode)(Pointer(lParam))
+	if root == nil {
+		releaseAccessTree(hwnd)
+		return 0
+	}
+	t := accessTrees[hwnd]
+	if t == nil {
+		t = &accessTree{hwnd: hwnd, focus: -1}
+		accessTrees[hwnd] = t
+	}
+	t.update(root)
+	return 0
+}
+
+// update replaces the tree's elements with those of root's tree, re-using
+// the elements of the nodes with the same IDs.
+func (t *accessTree) update(root *screen.AccessNode) {
+	old := t.elements
+	t.elements = make(map[int]*accessElement, len(old))
+	focus := -1
+	var build func(n *screen.AccessNode, parent *accessElement) *accessElement
+	build = func(n *screen.AccessNode, parent *accessElement) *accessElement {
+		e := old[n.ID]
+		if e == nil || t.elements[n.ID] != nil {
+			e = newAccessElement()
+		}
+		delete(old, n.ID)
+		if t.elements[n.ID] == nil {
+			t.elements[n.ID] = e
+		}
+		e.tree, e.parent = t, parent
+		e.node = *n
+		e.node.Children = nil
+		e.children = e.children[:0]
+		if n.Focused {
+			focus = n.ID
+		}
+		for _, c := range n.Children {
+			e.children = append(e.children, build(c, e))
+		}
+		return e
+	}
+	t.root = build(root, nil)
+	for _, e := range old {
+		e.detach()
+	}
+
+	if focus != t.focus {
+		t.focus = focus
+		if e := t.elements[focus]; e != nil && _UiaClientsAreListening() {
+			_UiaRaiseAutomationEvent(uintptr(unsafe.Pointer(e))+offsetSimple, _UIA_AutomationFocusChangedEventId)
+		}
+	}
+}
+
+// detach removes the element from its tree, after which its methods fail.
+func (e *accessElement) detach() {
+	e.tree, e.parent, e.children = nil, nil, nil
+	if e.refs <= 0 {
+		delete(liveElements, e)
+	}
+}
+
+// releaseAccessTree forgets hwnd's tree, when it is removed or hwnd is
+// destroyed.
+func releaseAccessTree(hwnd syscall.Handle) {
+	t := accessTrees[hwnd]
+	if t == nil {
+		return
+	}
+	delete(accessTrees, hwnd)
+	for _, e := range t.elements {
+		e.detach()
+	}
+	if t.returned {
+		// Tell UI Automation that the provider is gone.
+		_UiaReturnRawElementProvider(hwnd, 0, 0, 0)
+	}
+}
+
+func sendGetObject(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	if t := accessTrees[hwnd]; t != nil && int32(lParam) == _UiaRootObjectId {
+		t.returned = true
+		return _UiaReturnRawElementProvider(hwnd, wParam, lParam, uintptr(unsafe.Pointer(t.root))+offsetSimple)
+	}
+	return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+}
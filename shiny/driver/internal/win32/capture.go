@@ -0,0 +1,104 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// capturedWindow is the window, if any, that has captured the pointer. Like
+// the windows, it is only accessed on the thread that runs the message loop.
+var capturedWindow syscall.Handle
+
+// SetPointerCapture captures or releases the pointer for hwnd. While it is
+// captured, the pointer is hidden and clipped to the center of hwnd's client
+// area, and the mouse's motion is read as raw input, which is neither
+// accelerated nor limited by the edges of the screen. Losing the keyboard
+// focus releases the capture.
+func SetPointerCapture(hwnd syscall.Handle, capture bool) {
+	v := uintptr(0)
+	if capture {
+		v = 1
+	}
+	SendMessage(hwnd, msgSetPointerCapture, v, 0)
+}
+
+func sendSetPointerCapture(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	if wParam == 0 {
+		releasePointerCapture(hwnd)
+		return 0
+	}
+	if hwnd == capturedWindow {
+		return 0
+	}
+	if capturedWindow != 0 {
+		releasePointerCapture(capturedWindow)
+	}
+	rid := _RAWINPUTDEVICE{
+		UsagePage: _HID_USAGE_PAGE_GENERIC,
+		Usage:     _HID_USAGE_GENERIC_MOUSE,
+		Target:    hwnd,
+	}
+	if _RegisterRawInputDevices(&rid, 1, uint32(unsafe.Sizeof(rid))) != nil {
+		return 0
+	}
+	capturedWindow = hwnd
+
+	var r _RECT
+	if _GetClientRect(hwnd, &r) == nil {
+		p := _POINT{(r.Left + r.Right) / 2, (r.Top + r.Bottom) / 2}
+		_ClientToScreen(hwnd, &p)
+		_ClipCursor(&_RECT{Left: p.X, Top: p.Y, Right: p.X + 1, Bottom: p.Y + 1})
+	}
+	_SetCursor(0)
+	return 0
+}
+
+// releasePointerCapture releases the pointer, if hwnd has captured it, and
+// shows hwnd's cursor again.
+func releasePointerCapture(hwnd syscall.Handle) {
+	if hwnd != capturedWindow {
+		return
+	}
+	capturedWindow = 0
+	rid := _RAWINPUTDEVICE{
+		UsagePage: _HID_USAGE_PAGE_GENERIC,
+		Usage:     _HID_USAGE_GENERIC_MOUSE,
+		Flags:     _RIDEV_REMOVE,
+	}
+	_RegisterRawInputDevices(&rid, 1, uint32(unsafe.Sizeof(rid)))
+	_ClipCursor(nil)
+
+	wc := windowCursors[hwnd]
+	if wc == nil {
+		arrow, _ := _LoadCursor(0, _IDC_ARROW)
+		wc = &windowCursor{cursor: arrow}
+	}
+	updateCursor(hwnd, wc)
+}
+
+// sendRawInput handles WM_INPUT, which is only sent while a window has
+// captured the pointer.
+func sendRawInput(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	var ri _RAWINPUT
+	n := uint32(unsafe.Sizeof(ri))
+	ok := _GetRawInputData(syscall.Handle(lParam), _RID_INPUT, unsafe.Pointer(&ri), &n, uint32(unsafe.Sizeof(ri.Header))) != ^uint32(0)
+	// Some devices, such as tablets and remote desktops, report absolute
+	// positions instead of motion.
+	if ok && hwnd == capturedWindow && ri.Header.Type == _RIM_TYPEMOUSE &&
+		ri.Mouse.Flags&_MOUSE_MOVE_ABSOLUTE == 0 && (ri.Mouse.LastX != 0 || ri.Mouse.LastY != 0) {
+		RelativeMouseEvent(hwnd, screen.RelativeMouseEvent{
+			DeltaX: float32(ri.Mouse.LastX),
+			DeltaY: float32(ri.Mouse.LastY),
+		})
+	}
+	// DefWindowProc frees the raw input.
+	return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"syscall"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// undecorated holds the windows created with
+// screen.NewWindowOptions.Undecorated. Like the windows, it is only accessed
+// on the thread that runs the message loop.
+var undecorated = map[syscall.Handle]bool{}
+
+// sendNCCalcSize makes an undecorated window's client area cover the whole
+// window, so that its sizing border, and the thin caption that Windows draws
+// above it, are not shown.
+func sendNCCalcSize(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	if wParam == 0 || !undecorated[hwnd] {
+		return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+	}
+	if _IsZoomed(hwnd) && fullscreenWindows[hwnd] == nil {
+		// A maximized window overhangs its monitor by its sizing border,
+		// which would otherwise hide the edges of its client area. The
+		// first field of the NCCALCSIZE_PARAMS is the new window rectangle.
+		r := (*_RECT)(Pointer(lParam))
+		dx := _GetSystemMetrics(_SM_CXFRAME) + _GetSystemMetrics(_SM_CXPADDEDBORDER)
+		dy := _GetSystemMetrics(_SM_CYFRAME) + _GetSystemMetrics(_SM_CXPADDEDBORDER)
+		r.Left += dx
+		r.Top += dy
+		r.Right -= dx
+		r.Bottom -= dy
+	}
+	return 0
+}
+
+// hitTests are the non-client hit test codes, indexed by screen.WindowEdge,
+// of the parts of a window's frame that resize it by that edge.
+var hitTests = [...]uintptr{
+	screen.WindowEdgeTop:         _HTTOP,
+	screen.WindowEdgeBottom:      _HTBOTTOM,
+	screen.WindowEdgeLeft:        _HTLEFT,
+	screen.WindowEdgeRight:       _HTRIGHT,
+	screen.WindowEdgeTopLeft:     _HTTOPLEFT,
+	screen.WindowEdgeTopRight:    _HTTOPRIGHT,
+	screen.WindowEdgeBottomLeft:  _HTBOTTOMLEFT,
+	screen.WindowEdgeBottomRight: _HTBOTTOMRIGHT,
+}
+
+// StartMove starts moving hwnd as if the user had pressed the left mouse
+// button on its caption. The move runs in DefWindowProc's modal loop, on the
+// UI thread, after StartMove returns, and ends when the button is released.
+func StartMove(hwnd syscall.Handle) {
+	SendMessage(hwnd, msgStartMoveResize, _HTCAPTION, 0)
+}
+
+// StartResize is like StartMove, but resizes hwnd by the given edge of its
+// frame. It does nothing for an invalid edge.
+func StartResize(hwnd syscall.Handle, edge screen.WindowEdge) {
+	if int(edge) >= len(hitTests) {
+		return
+	}
+	SendMessage(hwnd, msgStartMoveResize, hitTests[edge], 0)
+}
+
+func sendStartMoveResize(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	var pt _POINT
+	if _GetCursorPos(&pt) != nil {
+		return 0
+	}
+	// Posting, rather than sending, the message lets the caller of
+	// SendMessage return before the modal loop starts. Its lParam is the
+	// pointer's position, in screen co-ordinates.
+	_PostMessage(hwnd, _WM_NCLBUTTONDOWN, wParam, uintptr(uint16(pt.X))|uintptr(uint16(pt.Y))<<16)
+	return 0
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Clipboard implements the screen.Clipboard interface using the Windows
+// clipboard. The text is stored in the CF_UNICODETEXT format.
+type Clipboard struct{}
+
+func (Clipboard) ReadText() (string, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := openClipboard(); err != nil {
+		return "", err
+	}
+	defer _CloseClipboard()
+
+	mem, err := _GetClipboardData(_CF_UNICODETEXT)
+	if err != nil {
+		// The clipboard is empty or does not hold text.
+		return "", nil
+	}
+	p, err := _GlobalLock(mem)
+	if err != nil {
+		return "", fmt.Errorf("win32: GlobalLock failed: %v", err)
+	}
+	defer _GlobalUnlock(mem)
+
+	// The text is NUL terminated.
+	return windows.UTF16PtrToString((*uint16)(Pointer(p))), nil
+}
+
+func (Clipboard) WriteText(text string) error {
+	u, err := syscall.UTF16FromString(text)
+	if err != nil {
+		return fmt.Errorf("win32: invalid clipboard text: %v", err)
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := openClipboard(); err != nil {
+		return err
+	}
+	defer _CloseClipboard()
+
+	if err := _EmptyClipboard(); err != nil {
+		return fmt.Errorf("win32: EmptyClipboard failed: %v", err)
+	}
+	mem, err := _GlobalAlloc(_GMEM_MOVEABLE, uintptr(2*len(u)))
+	if err != nil {
+		return fmt.Errorf("win32: GlobalAlloc failed: %v", err)
+	}
+	p, err := _GlobalLock(mem)
+	if err != nil {
+		_GlobalFree(mem)
+		return fmt.Errorf("win32: GlobalLock failed: %v", err)
+	}
+	copy(unsafe.Slice((*uint16)(Pointer(p)), len(u)), u)
+	_GlobalUnlock(mem)
+
+	// On success, the system owns mem, and we must not free it.
+	if _, err := _SetClipboardData(_CF_UNICODETEXT, mem); err != nil {
+		_GlobalFree(mem)
+		return fmt.Errorf("win32: SetClipboardData failed: %v", err)
+	}
+	return nil
+}
+
+// openClipboard opens the clipboard, on behalf of the screen window, retrying
+// for a short while if another application has it open.
+//
+// The screen window is needed, rather than a NULL window, for EmptyClipboard
+// to make us the clipboard's owner. Otherwise, SetClipboardData fails.
+func openClipboard() (err error) {
+	for i := 0; i < 10; i++ {
+		if err = _OpenClipboard(screenHWND); err == nil {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("win32: OpenClipboard failed: %v", err)
+}
@@ -0,0 +1,123 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"image"
+	"syscall"
+	"unsafe"
+)
+
+// sizeConstraints are a window's SetSizeConstraints and SetAspectRatio
+// arguments, in client area pixels.
+type sizeConstraints struct {
+	min, max, aspect image.Point
+}
+
+// windowConstraints holds the constraints of the windows that have any. Like
+// the windows, it is only accessed on the thread that runs the message loop.
+var windowConstraints = map[syscall.Handle]sizeConstraints{}
+
+// SetSizeConstraints sets the least and greatest sizes of hwnd's client area
+// that the user can resize it to. A zero width or height is unconstrained.
+func SetSizeConstraints(hwnd syscall.Handle, min, max image.Point) {
+	r := image.Rectangle{min, max}
+	SendMessage(hwnd, msgSetConstraints, 0, uintptr(unsafe.Pointer(&r)))
+}
+
+func sendSetConstraints(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	r := *(*image.Rectangle)(Pointer(lParam))
+	c := windowConstraints[hwnd]
+	c.min, c.max = r.Min, r.Max
+	setConstraints(hwnd, c)
+	return 0
+}
+
+// SetAspectRatio keeps the ratio of the width to the height of hwnd's client
+// area at that of aspect while the user resizes it, unless aspect is zero.
+func SetAspectRatio(hwnd syscall.Handle, aspect image.Point) {
+	SendMessage(hwnd, msgSetAspectRatio, 0, uintptr(unsafe.Pointer(&aspect)))
+}
+
+func sendSetAspectRatio(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	c := windowConstraints[hwnd]
+	c.aspect = *(*image.Point)(Pointer(lParam))
+	if c.aspect.X <= 0 || c.aspect.Y <= 0 {
+		c.aspect = image.Point{}
+	}
+	setConstraints(hwnd, c)
+	return 0
+}
+
+func setConstraints(hwnd syscall.Handle, c sizeConstraints) {
+	if c == (sizeConstraints{}) {
+		delete(windowConstraints, hwnd)
+	} else {
+		windowConstraints[hwnd] = c
+	}
+}
+
+// frameSize returns how much larger hwnd's window rectangle is than its
+// client area.
+func frameSize(hwnd syscall.Handle) (dx, dy int32) {
+	var wr, cr _RECT
+	if _GetWindowRect(hwnd, &wr) != nil || _GetClientRect(hwnd, &cr) != nil {
+		return 0, 0
+	}
+	return (wr.Right - wr.Left) - (cr.Right - cr.Left), (wr.Bottom - wr.Top) - (cr.Bottom - cr.Top)
+}
+
+// sendGetMinMaxInfo adds the size of hwnd's frame to its client area
+// constraints, to give the least and greatest sizes, of the whole window,
+// that it may be resized to. A fullscreen window is unconstrained.
+func sendGetMinMaxInfo(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	c, ok := windowConstraints[hwnd]
+	if !ok || fullscreenWindows[hwnd] != nil || (c.min == image.Point{} && c.max == image.Point{}) {
+		return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+	}
+	mmi := (*_MINMAXINFO)(Pointer(lParam))
+	dx, dy := frameSize(hwnd)
+	if c.min.X > 0 {
+		mmi.MinTrackSize.X = int32(c.min.X) + dx
+	}
+	if c.min.Y > 0 {
+		mmi.MinTrackSize.Y = int32(c.min.Y) + dy
+	}
+	if c.max.X > 0 {
+		mmi.MaxTrackSize.X = int32(c.max.X) + dx
+	}
+	if c.max.Y > 0 {
+		mmi.MaxTrackSize.Y = int32(c.max.Y) + dy
+	}
+	return 0
+}
+
+// sendSizing adjusts the window rectangle that the user is resizing hwnd to,
+// so that its client area has hwnd's aspect ratio. The edge being dragged,
+// wParam, decides which dimension follows the other: dragging the top or
+// bottom edge changes the width, and dragging any other edge or corner
+// changes the height.
+func sendSizing(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	a := windowConstraints[hwnd].aspect
+	if a == (image.Point{}) {
+		return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+	}
+	r := (*_RECT)(Pointer(lParam))
+	dx, dy := frameSize(hwnd)
+	switch wParam {
+	case _WMSZ_TOP, _WMSZ_BOTTOM:
+		height := int(r.Bottom - r.Top - dy)
+		r.Right = r.Left + dx + int32(height*a.X/a.Y)
+	case _WMSZ_TOPLEFT, _WMSZ_TOPRIGHT:
+		width := int(r.Right - r.Left - dx)
+		r.Top = r.Bottom - dy - int32(width*a.Y/a.X)
+	default:
+		width := int(r.Right - r.Left - dx)
+		r.Bottom = r.Top + dy + int32(width*a.Y/a.X)
+	}
+	return 1
+}
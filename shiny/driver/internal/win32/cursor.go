@@ -0,0 +1,236 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"image"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// standardCursors are the system cursors of the screen.CursorShapes.
+var standardCursors = [...]uintptr{
+	screen.CursorArrow:      _IDC_ARROW,
+	screen.CursorIBeam:      _IDC_IBEAM,
+	screen.CursorCrosshair:  _IDC_CROSS,
+	screen.CursorHand:       _IDC_HAND,
+	screen.CursorResizeNS:   _IDC_SIZENS,
+	screen.CursorResizeEW:   _IDC_SIZEWE,
+	screen.CursorResizeNWSE: _IDC_SIZENWSE,
+	screen.CursorResizeNESW: _IDC_SIZENESW,
+	screen.CursorResizeAll:  _IDC_SIZEALL,
+	screen.CursorNotAllowed: _IDC_NO,
+	screen.CursorWait:       _IDC_WAIT,
+}
+
+// windowCursor is the cursor set for a window's client area.
+type windowCursor struct {
+	cursor syscall.Handle
+	// owned is whether cursor was created for the window, rather than being
+	// one of the system's shared cursors, and so must be destroyed.
+	owned  bool
+	hidden bool
+}
+
+// windowCursors holds the cursor of each window whose cursor has been set.
+// Other windows show their window class's arrow. Like the windows, it is only
+// accessed on the thread that runs the message loop.
+var windowCursors = map[syscall.Handle]*windowCursor{}
+
+// SetCursor sets the cursor shown over hwnd's client area.
+func SetCursor(hwnd syscall.Handle, c screen.Cursor) {
+	SendMessage(hwnd, msgSetCursor, 0, uintptr(unsafe.Pointer(&c)))
+}
+
+// SetCursorVisible shows or hides the cursor over hwnd's client area.
+func SetCursorVisible(hwnd syscall.Handle, visible bool) {
+	v := uintptr(0)
+	if visible {
+		v = 1
+	}
+	SendMessage(hwnd, msgSetCursorVisible, v, 0)
+}
+
+func sendSetCursor(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	c := *(*screen.Cursor)(Pointer(lParam))
+	var (
+		h     syscall.Handle
+		owned bool
+	)
+	if c.HasImage() {
+		// If the image cannot be made into a cursor, such as when it is too
+		// large, fall back to c's shape.
+		if x, err := createCursor(c.Image, c.Hotspot); err == nil {
+			h, owned = x, true
+		}
+	}
+	if h == 0 {
+		shape := c.Shape
+		if shape < 0 || int(shape) >= len(standardCursors) {
+			shape = screen.CursorArrow
+		}
+		h, _ = _LoadCursor(0, standardCursors[shape])
+	}
+
+	wc := windowCursors[hwnd]
+	if wc == nil {
+		wc = &windowCursor{}
+		windowCursors[hwnd] = wc
+	}
+	old, oldOwned := wc.cursor, wc.owned
+	wc.cursor, wc.owned = h, owned
+	updateCursor(hwnd, wc)
+	if oldOwned {
+		_DestroyIcon(old)
+	}
+	return 0
+}
+
+func sendSetCursorVisible(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	wc := windowCursors[hwnd]
+	if wc == nil {
+		arrow, _ := _LoadCursor(0, _IDC_ARROW)
+		wc = &windowCursor{cursor: arrow}
+		windowCursors[hwnd] = wc
+	}
+	wc.hidden = wParam == 0
+	updateCursor(hwnd, wc)
+	return 0
+}
+
+// sendCursor handles WM_SETCURSOR, which the system sends whenever the mouse
+// moves, for the window to show its cursor.
+func sendCursor(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	wc := windowCursors[hwnd]
+	// The low word of lParam is the hit test code. Over the window's frame,
+	// DefWindowProc shows the resize cursors.
+	if hwnd == capturedWindow && lParam&0xffff == _HTCLIENT {
+		_SetCursor(0)
+		return 1
+	}
+	if wc == nil || lParam&0xffff != _HTCLIENT {
+		return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+	}
+	_SetCursor(wc.shown())
+	return 1 // The cursor was set.
+}
+
+// shown returns the cursor to show, which is none if it is hidden.
+func (wc *windowCursor) shown() syscall.Handle {
+	if wc.hidden {
+		return 0
+	}
+	return wc.cursor
+}
+
+var procWindowFromPoint = moduser32.NewProc("WindowFromPoint")
+
+// updateCursor shows wc immediately if the mouse is over hwnd's client area,
+// rather than waiting for the mouse to next move.
+func updateCursor(hwnd syscall.Handle, wc *windowCursor) {
+	var p _POINT
+	if _GetCursorPos(&p) != nil {
+		return
+	}
+	// WindowFromPoint takes a POINT by value, which is a single argument on
+	// 64-bit Windows, and two on 32-bit Windows.
+	var h uintptr
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		h, _, _ = syscall.Syscall(procWindowFromPoint.Addr(), 1, *(*uintptr)(unsafe.Pointer(&p)), 0, 0)
+	} else {
+		h, _, _ = syscall.Syscall(procWindowFromPoint.Addr(), 2, uintptr(p.X), uintptr(p.Y), 0)
+	}
+	if syscall.Handle(h) != hwnd || !_ScreenToClient(hwnd, &p) {
+		return
+	}
+	var r _RECT
+	if _GetClientRect(hwnd, &r) != nil {
+		return
+	}
+	if p.X >= r.Left && p.X < r.Right && p.Y >= r.Top && p.Y < r.Bottom {
+		_SetCursor(wc.shown())
+	}
+}
+
+// releaseCursor forgets hwnd's cursor, destroying it if it was created for
+// hwnd.
+func releaseCursor(hwnd syscall.Handle) {
+	if wc := windowCursors[hwnd]; wc != nil && wc.owned {
+		_DestroyIcon(wc.cursor)
+	}
+	delete(windowCursors, hwnd)
+}
+
+// createCursor returns a new cursor, which the caller must destroy with
+// DestroyIcon, showing m.
+func createCursor(m *image.RGBA, hotspot image.Point) (syscall.Handle, error) {
+	return createIconIndirect(m, false, hotspot)
+}
+
+// createIcon returns a new icon, which the caller must destroy with
+// DestroyIcon, showing m.
+func createIcon(m *image.RGBA) (syscall.Handle, error) {
+	return createIconIndirect(m, true, image.Point{})
+}
+
+// createIconIndirect returns a new icon, or a cursor with the given hotspot,
+// showing m.
+func createIconIndirect(m *image.RGBA, icon bool, hotspot image.Point) (syscall.Handle, error) {
+	b := m.Bounds()
+	width, height := b.Dx(), b.Dy()
+	bmi := _BITMAPINFOHEADER{
+		Size:        uint32(unsafe.Sizeof(_BITMAPINFOHEADER{})),
+		Width:       int32(width),
+		Height:      -int32(height), // A negative height is a top-down bitmap.
+		Planes:      1,
+		BitCount:    32,
+		Compression: _BI_RGB,
+	}
+	var bits unsafe.Pointer
+	color, err := _CreateDIBSection(0, &bmi, _DIB_RGB_COLORS, &bits, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer _DeleteObject(color)
+	// The color bitmap's alpha channel is the icon's transparency, so the
+	// mask is unused, but CreateIconIndirect requires one.
+	mask, err := _CreateBitmap(int32(width), int32(height), 1, 1, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer _DeleteObject(mask)
+
+	// The bitmap is BGRA, and not alpha-premultiplied, unlike an image.RGBA.
+	dst := (*[1 << 30]byte)(bits)[: 4*width*height : 4*width*height]
+	for y := 0; y < height; y++ {
+		src := m.Pix[m.PixOffset(b.Min.X, b.Min.Y+y):]
+		row := dst[4*width*y:]
+		for x := 0; x < width; x++ {
+			r, g, bl, a := src[4*x+0], src[4*x+1], src[4*x+2], src[4*x+3]
+			if a != 0 && a != 0xff {
+				r = byte(int(r) * 0xff / int(a))
+				g = byte(int(g) * 0xff / int(a))
+				bl = byte(int(bl) * 0xff / int(a))
+			}
+			row[4*x+0], row[4*x+1], row[4*x+2], row[4*x+3] = bl, g, r, a
+		}
+	}
+
+	fIcon := int32(0)
+	if icon {
+		fIcon = 1
+	}
+	return _CreateIconIndirect(&_ICONINFO{
+		FIcon:    fIcon,
+		XHotspot: uint32(hotspot.X),
+		YHotspot: uint32(hotspot.Y),
+		HbmMask:  mask,
+		HbmColor: color,
+	})
+}
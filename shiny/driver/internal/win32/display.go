@@ -0,0 +1,88 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"image"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+var (
+	enumMonitorsMu  sync.Mutex
+	enumMonitors    []syscall.Handle
+	enumMonitorProc = syscall.NewCallback(func(monitor, dc syscall.Handle, r *_RECT, data uintptr) uintptr {
+		enumMonitors = append(enumMonitors, monitor)
+		return 1 // Continue the enumeration.
+	})
+)
+
+// Displays returns the monitors that make up the desktop, primary first.
+//
+// The process is per-monitor DPI aware, so the bounds are in physical
+// pixels, the same as window positions.
+func Displays() []screen.Display {
+	enumMonitorsMu.Lock()
+	enumMonitors = nil
+	_EnumDisplayMonitors(0, nil, enumMonitorProc, 0)
+	monitors := enumMonitors
+	enumMonitors = nil
+	enumMonitorsMu.Unlock()
+
+	var displays []screen.Display
+	for _, m := range monitors {
+		mi := _MONITORINFOEX{
+			CbSize: uint32(unsafe.Sizeof(_MONITORINFOEX{})),
+		}
+		if err := _GetMonitorInfo(m, &mi); err != nil {
+			continue
+		}
+		d := screen.Display{
+			Name:     syscall.UTF16ToString(mi.SzDevice[:]),
+			Bounds:   rect(mi.RcMonitor),
+			WorkArea: rect(mi.RcWork),
+			Scale:    1,
+			Primary:  mi.DwFlags&_MONITORINFOF_PRIMARY != 0,
+		}
+		dm := _DEVMODE{
+			DmSize: uint16(unsafe.Sizeof(_DEVMODE{})),
+		}
+		// A frequency of 0 or 1 means the hardware's default rate.
+		if err := _EnumDisplaySettings(&mi.SzDevice[0], _ENUM_CURRENT_SETTINGS, &dm); err == nil && dm.DmDisplayFrequency > 1 {
+			d.RefreshRate = float64(dm.DmDisplayFrequency)
+		}
+		// GetDpiForMonitor, from Windows 8.1, reports both the DPI that
+		// Windows scales by and the physical DPI.
+		if procGetDpiForMonitor.Find() == nil {
+			var x, y uint32
+			if _GetDpiForMonitor(m, _MDT_EFFECTIVE_DPI, &x, &y) == 0 && x != 0 {
+				d.Scale = float64(x) / _USER_DEFAULT_SCREEN_DPI
+			}
+			if _GetDpiForMonitor(m, _MDT_RAW_DPI, &x, &y) == 0 {
+				d.DPI = float64(x)
+			}
+		}
+		if d.Primary {
+			displays = append([]screen.Display{d}, displays...)
+		} else {
+			displays = append(displays, d)
+		}
+	}
+	return displays
+}
+
+func rect(r _RECT) image.Rectangle {
+	return image.Rect(int(r.Left), int(r.Top), int(r.Right), int(r.Bottom))
+}
+
+func sendDisplayChange(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	DisplayEvent(hwnd, screen.DisplayEvent{Displays: Displays()})
+	return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+}
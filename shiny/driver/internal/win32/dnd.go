@@ -0,0 +1,473 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/dnd"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Drag-and-drop uses OLE. Each window registers a dropTarget, implementing
+// the IDropTarget COM interface, and StartDrag passes theDropSource,
+// implementing IDropSource, to DoDragDrop. Both are Go values whose first
+// field points to a vtable of syscall.NewCallback functions, which OLE calls
+// on the UI thread. The values are kept alive by Go, not by COM reference
+// counting.
+
+var (
+	iidIUnknown    = _GUID{0x00000000, 0x0000, 0x0000, [8]byte{0xc0, 0, 0, 0, 0, 0, 0, 0x46}}
+	iidIDataObject = _GUID{0x0000010e, 0x0000, 0x0000, [8]byte{0xc0, 0, 0, 0, 0, 0, 0, 0x46}}
+	iidIDropSource = _GUID{0x00000121, 0x0000, 0x0000, [8]byte{0xc0, 0, 0, 0, 0, 0, 0, 0x46}}
+	iidIDropTarget = _GUID{0x00000122, 0x0000, 0x0000, [8]byte{0xc0, 0, 0, 0, 0, 0, 0, 0x46}}
+)
+
+// The indexes of the methods, in their vtables, of the COM objects that OLE
+// passes to us.
+const (
+	methodRelease       = 2
+	methodGetData       = 3
+	methodSetData       = 7
+	methodEnumFormatEtc = 8
+	methodNext          = 3
+)
+
+// oleInitialized is whether OleInitialize succeeded on the UI thread. If not,
+// windows are not drop targets and StartDrag fails.
+var oleInitialized bool
+
+// comCall calls the method'th method of the COM object obj.
+func comCall(obj uintptr, method int, args ...uintptr) uintptr {
+	vtbl := *(*uintptr)(Pointer(obj))
+	fn := *(*uintptr)(Pointer(vtbl + uintptr(method)*unsafe.Sizeof(vtbl)))
+	r, _, _ := syscall.SyscallN(fn, append([]uintptr{obj}, args...)...)
+	return r
+}
+
+// comQueryInterface returns an IUnknown.QueryInterface callback for objects
+// that implement the interface iid.
+func comQueryInterface(iid *_GUID) uintptr {
+	return syscall.NewCallback(func(this uintptr, riid *_GUID, ppv *uintptr) uintptr {
+		if *riid != iidIUnknown && *riid != *iid {
+			*ppv = 0
+			return _E_NOINTERFACE
+		}
+		*ppv = this
+		return _S_OK
+	})
+}
+
+// comRef is the IUnknown.AddRef and IUnknown.Release callback, which does
+// nothing, as our COM objects live for as long as Go keeps them.
+var comRef = syscall.NewCallback(func(this uintptr) uintptr { return 1 })
+
+type dropTarget struct {
+	vtbl *[7]uintptr
+	hwnd syscall.Handle
+
+	// formats are the usable formats of the data being dragged over the
+	// window, if any.
+	formats []dataFormat
+	types   []string
+}
+
+var dropTargetVtbl = [7]uintptr{
+	comQueryInterface(&iidIDropTarget),
+	comRef,
+	comRef,
+	syscall.NewCallback(dropTargetDragEnter),
+	syscall.NewCallback(dropTargetDragOver),
+	syscall.NewCallback(dropTargetDragLeave),
+	syscall.NewCallback(dropTargetDrop),
+}
+
+// dropTargets are the registered drop targets, keyed by window. It is only
+// used on the UI thread.
+var dropTargets = map[syscall.Handle]*dropTarget{}
+
+// registerDropTarget makes hwnd accept drops. It must be called on the UI
+// thread.
+func registerDropTarget(hwnd syscall.Handle) {
+	if !oleInitialized {
+		return
+	}
+	t := &dropTarget{vtbl: &dropTargetVtbl, hwnd: hwnd}
+	if _RegisterDragDrop(hwnd, uintptr(unsafe.Pointer(t))) >= 0 {
+		dropTargets[hwnd] = t
+	}
+}
+
+// revokeDropTarget undoes registerDropTarget. It must be called on the UI
+// thread, before hwnd is destroyed.
+func revokeDropTarget(hwnd syscall.Handle) {
+	if _, ok := dropTargets[hwnd]; ok {
+		_RevokeDragDrop(hwnd)
+		delete(dropTargets, hwnd)
+	}
+}
+
+// pointlArgs returns the POINTL, passed by value, and the effect pointer that
+// end the arguments of the IDropTarget methods. The POINTL is one argument on
+// 64-bit Windows and two on 32-bit Windows, so the methods' callbacks take
+// enough arguments for either.
+func pointlArgs(a, b, c uintptr) (pt _POINT, effect *uint32) {
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		return _POINT{X: int32(a), Y: int32(uint64(a) >> 32)}, (*uint32)(Pointer(b))
+	}
+	return _POINT{X: int32(a), Y: int32(b)}, (*uint32)(Pointer(c))
+}
+
+func (t *dropTarget) send(typ screen.DragType, pt _POINT, data screen.DragData) {
+	// The point is in screen co-ordinates.
+	_ScreenToClient(t.hwnd, &pt)
+	DragEvent(t.hwnd, screen.DragEvent{
+		Type:  typ,
+		X:     float32(pt.X),
+		Y:     float32(pt.Y),
+		Types: t.types,
+		Data:  data,
+	})
+}
+
+func dropTargetDragEnter(this, dataObject, keyState, a, b, c uintptr) uintptr {
+	t := (*dropTarget)(Pointer(this))
+	pt, effect := pointlArgs(a, b, c)
+	t.formats = dataObjectFormats(dataObject)
+	t.types = make([]string, len(t.formats))
+	for i, f := range t.formats {
+		t.types[i] = f.typ
+	}
+	t.send(screen.DragEnter, pt, screen.DragData{})
+	*effect = _DROPEFFECT_COPY
+	return _S_OK
+}
+
+func dropTargetDragOver(this, keyState, a, b, c uintptr) uintptr {
+	t := (*dropTarget)(Pointer(this))
+	pt, effect := pointlArgs(a, b, c)
+	t.send(screen.DragOver, pt, screen.DragData{})
+	*effect = _DROPEFFECT_COPY
+	return _S_OK
+}
+
+func dropTargetDragLeave(this uintptr) uintptr {
+	t := (*dropTarget)(Pointer(this))
+	DragEvent(t.hwnd, screen.DragEvent{Type: screen.DragLeave, Types: t.types})
+	t.formats, t.types = nil, nil
+	return _S_OK
+}
+
+func dropTargetDrop(this, dataObject, keyState, a, b, c uintptr) uintptr {
+	t := (*dropTarget)(Pointer(this))
+	pt, effect := pointlArgs(a, b, c)
+	t.send(screen.Drop, pt, readDataObject(dataObject, t.formats))
+	t.formats, t.types = nil, nil
+	*effect = _DROPEFFECT_COPY
+	return _S_OK
+}
+
+// dataFormat is a clipboard format, and its MIME type.
+type dataFormat struct {
+	cf  uint16
+	typ string
+}
+
+// dataObjectFormats returns the formats, held in global memory, of the
+// IDataObject obj. Files and text are in the standard CF_HDROP and
+// CF_UNICODETEXT formats, and other data is in registered formats named
+// after their MIME types. Any other formats are skipped.
+func dataObjectFormats(obj uintptr) []dataFormat {
+	var enum uintptr
+	if comCall(obj, methodEnumFormatEtc, _DATADIR_GET, uintptr(unsafe.Pointer(&enum))) != _S_OK {
+		return nil
+	}
+	defer comCall(enum, methodRelease)
+
+	var formats []dataFormat
+	seen := map[uint16]bool{}
+	for {
+		var fe _FORMATETC
+		if comCall(enum, methodNext, 1, uintptr(unsafe.Pointer(&fe)), 0) != _S_OK {
+			break
+		}
+		if fe.Ptd != 0 {
+			_CoTaskMemFree(fe.Ptd)
+		}
+		if fe.Tymed&_TYMED_HGLOBAL == 0 || seen[fe.CfFormat] {
+			continue
+		}
+		seen[fe.CfFormat] = true
+		if typ := formatType(fe.CfFormat); typ != "" {
+			formats = append(formats, dataFormat{fe.CfFormat, typ})
+		}
+	}
+	return formats
+}
+
+// formatType returns the MIME type of the clipboard format cf, or "" if it
+// has none.
+func formatType(cf uint16) string {
+	switch cf {
+	case _CF_HDROP:
+		return dnd.URIList
+	case _CF_UNICODETEXT:
+		return dnd.Text
+	}
+	var buf [256]uint16
+	n := _GetClipboardFormatName(uint32(cf), &buf[0], int32(len(buf)))
+	if n <= 0 {
+		// cf is a standard format, not a registered one.
+		return ""
+	}
+	if name := syscall.UTF16ToString(buf[:n]); strings.Contains(name, "/") {
+		return name
+	}
+	return ""
+}
+
+// readDataObject returns the data, in the given formats, of the IDataObject
+// obj.
+func readDataObject(obj uintptr, formats []dataFormat) (d screen.DragData) {
+	for _, f := range formats {
+		fe := _FORMATETC{
+			CfFormat: f.cf,
+			DwAspect: _DVASPECT_CONTENT,
+			Lindex:   -1,
+			Tymed:    _TYMED_HGLOBAL,
+		}
+		var m _STGMEDIUM
+		if comCall(obj, methodGetData, uintptr(unsafe.Pointer(&fe)), uintptr(unsafe.Pointer(&m))) != _S_OK {
+			continue
+		}
+		if m.Tymed == _TYMED_HGLOBAL {
+			switch f.cf {
+			case _CF_HDROP:
+				d.Files = dropFiles(m.HGlobal)
+			case _CF_UNICODETEXT:
+				u := globalBytes(m.HGlobal)
+				d.Text = syscall.UTF16ToString((*[1 << 29]uint16)(unsafe.Pointer(&u[0]))[: len(u)/2 : len(u)/2])
+			default:
+				if d.Other == nil {
+					d.Other = map[string][]byte{}
+				}
+				d.Other[f.typ] = globalBytes(m.HGlobal)
+			}
+		}
+		_ReleaseStgMedium(&m)
+	}
+	return d
+}
+
+// dropFiles returns the paths in the HDROP h.
+func dropFiles(h syscall.Handle) []string {
+	n := _DragQueryFile(h, 0xffffffff, nil, 0)
+	files := make([]string, 0, n)
+	for i := uint32(0); i < n; i++ {
+		buf := make([]uint16, _DragQueryFile(h, i, nil, 0)+1)
+		_DragQueryFile(h, i, &buf[0], uint32(len(buf)))
+		files = append(files, syscall.UTF16ToString(buf))
+	}
+	return files
+}
+
+// globalBytes returns a copy of the contents of the global memory object mem.
+// Its size may be rounded up, beyond the end of the data. It is never empty,
+// so that text can be read from its first element.
+func globalBytes(mem syscall.Handle) []byte {
+	n := int(_GlobalSize(mem))
+	b := make([]byte, n, n+2)
+	if p, err := _GlobalLock(mem); err == nil {
+		copy(b, unsafe.Slice((*byte)(Pointer(p)), n))
+		_GlobalUnlock(mem)
+	}
+	if n == 0 {
+		b = b[:2]
+	}
+	return b
+}
+
+type dropSource struct {
+	vtbl *[5]uintptr
+}
+
+var dropSourceVtbl = [5]uintptr{
+	comQueryInterface(&iidIDropSource),
+	comRef,
+	comRef,
+	syscall.NewCallback(dropSourceQueryContinueDrag),
+	syscall.NewCallback(dropSourceGiveFeedback),
+}
+
+var theDropSource = &dropSource{vtbl: &dropSourceVtbl}
+
+// dropSourceQueryContinueDrag drops the data when the mouse buttons are
+// released, and cancels the drag when escape is pressed.
+func dropSourceQueryContinueDrag(this, escapePressed, keyState uintptr) uintptr {
+	switch {
+	case uint32(escapePressed) != 0:
+		return _DRAGDROP_S_CANCEL
+	case uint32(keyState)&(_MK_LBUTTON|_MK_RBUTTON|_MK_MBUTTON) == 0:
+		return _DRAGDROP_S_DROP
+	}
+	return _S_OK
+}
+
+func dropSourceGiveFeedback(this, effect uintptr) uintptr {
+	return _DRAGDROP_S_USEDEFAULTCURSORS
+}
+
+type startDragParams struct {
+	data screen.DragData
+	err  error
+}
+
+// StartDrag starts dragging data out of hwnd. The drag runs in DoDragDrop's
+// modal loop, on the UI thread, after StartDrag returns.
+func StartDrag(hwnd syscall.Handle, data screen.DragData) error {
+	p := startDragParams{data: data}
+	SendMessage(hwnd, msgStartDrag, 0, uintptr(unsafe.Pointer(&p)))
+	return p.err
+}
+
+func sendStartDrag(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	p := (*startDragParams)(Pointer(lParam))
+	if !oleInitialized {
+		p.err = errors.New("win32: OleInitialize failed")
+		return 0
+	}
+	obj, err := newDataObject(p.data)
+	if err != nil {
+		p.err = err
+		return 0
+	}
+	// DoDragDrop does not return until the drag finishes, so it is called
+	// from a message of its own, which is not sent synchronously.
+	if !_PostMessage(hwnd, msgDoDragDrop, obj, 0) {
+		comCall(obj, methodRelease)
+		p.err = errors.New("win32: PostMessage failed")
+	}
+	return 0
+}
+
+func sendDoDragDrop(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	var effect uint32
+	_DoDragDrop(wParam, uintptr(unsafe.Pointer(theDropSource)), _DROPEFFECT_COPY, &effect)
+	comCall(wParam, methodRelease)
+	return 0
+}
+
+// newDataObject returns an IDataObject, which the caller must release,
+// holding d. The shell's generic data object stores the data, in the formats
+// that dataObjectFormats reads.
+func newDataObject(d screen.DragData) (obj uintptr, err error) {
+	if hr := _SHCreateDataObject(0, 0, 0, 0, &iidIDataObject, &obj); hr < 0 {
+		return 0, fmt.Errorf("win32: SHCreateDataObject failed: %#x", uint32(hr))
+	}
+	set := func(cf uint16, b []byte) error {
+		mem, err := globalAlloc(b)
+		if err != nil {
+			return err
+		}
+		fe := _FORMATETC{
+			CfFormat: cf,
+			DwAspect: _DVASPECT_CONTENT,
+			Lindex:   -1,
+			Tymed:    _TYMED_HGLOBAL,
+		}
+		m := _STGMEDIUM{Tymed: _TYMED_HGLOBAL, HGlobal: mem}
+		// On success, the data object owns mem, and we must not free it.
+		if hr := int32(comCall(obj, methodSetData, uintptr(unsafe.Pointer(&fe)), uintptr(unsafe.Pointer(&m)), 1)); hr < 0 {
+			_GlobalFree(mem)
+			return fmt.Errorf("win32: IDataObject.SetData failed: %#x", uint32(hr))
+		}
+		return nil
+	}
+	if err := setDataObject(d, set); err != nil {
+		comCall(obj, methodRelease)
+		return 0, err
+	}
+	return obj, nil
+}
+
+// setDataObject calls set with each format, and its contents, of d.
+func setDataObject(d screen.DragData, set func(cf uint16, b []byte) error) error {
+	if len(d.Files) != 0 {
+		// A DROPFILES header is followed by a double-NUL terminated list of
+		// NUL terminated paths.
+		var files []uint16
+		for _, f := range d.Files {
+			u, err := syscall.UTF16FromString(f)
+			if err != nil {
+				return fmt.Errorf("win32: invalid file path %q: %v", f, err)
+			}
+			files = append(files, u...)
+		}
+		files = append(files, 0)
+		hdr := unsafe.Sizeof(_DROPFILES{})
+		b := make([]byte, int(hdr)+2*len(files))
+		*(*_DROPFILES)(unsafe.Pointer(&b[0])) = _DROPFILES{PFiles: uint32(hdr), FWide: 1}
+		copy(b[hdr:], utf16Bytes(files))
+		if err := set(_CF_HDROP, b); err != nil {
+			return err
+		}
+	}
+	if d.Text != "" {
+		u, err := syscall.UTF16FromString(d.Text)
+		if err != nil {
+			return fmt.Errorf("win32: invalid drag text: %v", err)
+		}
+		if err := set(_CF_UNICODETEXT, utf16Bytes(u)); err != nil {
+			return err
+		}
+	}
+	types := make([]string, 0, len(d.Other))
+	for typ := range d.Other {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		name, err := syscall.UTF16PtrFromString(typ)
+		if err != nil {
+			return fmt.Errorf("win32: invalid drag type %q: %v", typ, err)
+		}
+		cf, err := _RegisterClipboardFormat(name)
+		if err != nil {
+			return fmt.Errorf("win32: RegisterClipboardFormat failed: %v", err)
+		}
+		if err := set(uint16(cf), d.Other[typ]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// globalAlloc returns a moveable global memory object holding a copy of b.
+func globalAlloc(b []byte) (syscall.Handle, error) {
+	mem, err := _GlobalAlloc(_GMEM_MOVEABLE, uintptr(len(b)))
+	if err != nil {
+		return 0, fmt.Errorf("win32: GlobalAlloc failed: %v", err)
+	}
+	p, err := _GlobalLock(mem)
+	if err != nil {
+		_GlobalFree(mem)
+		return 0, fmt.Errorf("win32: GlobalLock failed: %v", err)
+	}
+	copy(unsafe.Slice((*byte)(Pointer(p)), len(b)), b)
+	_GlobalUnlock(mem)
+	return mem, nil
+}
+
+// utf16Bytes returns the bytes of u, which must not be empty.
+func utf16Bytes(u []uint16) []byte {
+	return (*[1 << 30]byte)(unsafe.Pointer(&u[0]))[: 2*len(u) : 2*len(u)]
+}
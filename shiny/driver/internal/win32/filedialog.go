@@ -0,0 +1,243 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/sys/windows"
+)
+
+// File dialogs are the shell's Common Item Dialogs, the FileOpenDialog and
+// FileSaveDialog COM objects. ShowFileDialog creates and sets up the dialog on
+// the UI thread, where OleInitialize initialized COM, and the dialog is then
+// shown from a message of its own, as IFileDialog.Show does not return until
+// the user closes it.
+
+var (
+	clsidFileOpenDialog = _GUID{0xdc1c5a9c, 0xe88a, 0x4dde, [8]byte{0xa5, 0xa1, 0x60, 0xf8, 0x2a, 0x20, 0xae, 0xf7}}
+	clsidFileSaveDialog = _GUID{0xc0b4e2f3, 0xba21, 0x4773, [8]byte{0x8d, 0xba, 0x33, 0x5e, 0xc9, 0x46, 0xeb, 0x8b}}
+	iidIFileOpenDialog  = _GUID{0xd57c7288, 0xd4ad, 0x4768, [8]byte{0xbe, 0x02, 0x9d, 0x96, 0x95, 0x32, 0xd9, 0x60}}
+	iidIFileSaveDialog  = _GUID{0x84bccd23, 0x5fde, 0x4cdb, [8]byte{0xae, 0xa4, 0xaf, 0x64, 0xb8, 0x3d, 0x78, 0xab}}
+	iidIShellItem       = _GUID{0x43826d1e, 0xe718, 0x42ee, [8]byte{0xbc, 0x55, 0xa1, 0xe2, 0x61, 0xc3, 0x7b, 0xfe}}
+)
+
+// The indexes of the methods, in their vtables, of IFileDialog, and of the
+// IShellItem and IShellItemArray that hold its results.
+const (
+	methodShow           = 3
+	methodSetFileTypes   = 4
+	methodSetOptions     = 9
+	methodGetOptions     = 10
+	methodSetFolder      = 12
+	methodSetFileName    = 15
+	methodSetTitle       = 17
+	methodGetResult      = 20
+	methodGetResults     = 27 // Of IFileOpenDialog only.
+	methodGetDisplayName = 5  // Of IShellItem.
+	methodGetCount       = 7  // Of IShellItemArray.
+	methodGetItemAt      = 8  // Of IShellItemArray.
+)
+
+// fileDialogs holds the options of each dialog that is waiting to be shown.
+// Like the windows, it is only accessed on the thread that runs the message
+// loop.
+var fileDialogs = map[uintptr]screen.FileDialogOptions{}
+
+type showFileDialogParams struct {
+	opts screen.FileDialogOptions
+	err  error
+}
+
+// ShowFileDialog shows a file dialog owned by hwnd, and sends its
+// screen.FileDialogEvent once the user closes it. The dialog runs in its own
+// modal loop, on the UI thread, after ShowFileDialog returns.
+func ShowFileDialog(hwnd syscall.Handle, opts *screen.FileDialogOptions) error {
+	var p showFileDialogParams
+	if opts != nil {
+		p.opts = *opts
+	}
+	SendMessage(hwnd, msgShowFileDialog, 0, uintptr(unsafe.Pointer(&p)))
+	return p.err
+}
+
+func sendShowFileDialog(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	p := (*showFileDialogParams)(Pointer(lParam))
+	if !oleInitialized {
+		p.err = errors.New("win32: OleInitialize failed")
+		return 0
+	}
+	obj, err := newFileDialog(&p.opts)
+	if err != nil {
+		p.err = err
+		return 0
+	}
+	fileDialogs[obj] = p.opts
+	if !_PostMessage(hwnd, msgRunFileDialog, obj, 0) {
+		delete(fileDialogs, obj)
+		comCall(obj, methodRelease)
+		p.err = errors.New("win32: PostMessage failed")
+	}
+	return 0
+}
+
+func sendRunFileDialog(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	obj := wParam
+	opts := fileDialogs[obj]
+	delete(fileDialogs, obj)
+
+	e := screen.FileDialogEvent{ID: opts.ID, Kind: opts.Kind}
+	switch hr := int32(comCall(obj, methodShow, uintptr(hwnd))); {
+	case uint32(hr) == _HRESULT_ERROR_CANCELLED:
+	case hr < 0:
+		e.Err = fmt.Errorf("win32: IFileDialog.Show failed: %#x", uint32(hr))
+	default:
+		e.Paths, e.Err = fileDialogPaths(obj, opts.Kind)
+	}
+	comCall(obj, methodRelease)
+	FileDialogEvent(hwnd, e)
+	return 0
+}
+
+// newFileDialog returns an IFileOpenDialog or IFileSaveDialog, which the
+// caller must release, set up by opts.
+func newFileDialog(opts *screen.FileDialogOptions) (obj uintptr, err error) {
+	clsid, iid := &clsidFileOpenDialog, &iidIFileOpenDialog
+	if opts.Kind == screen.FileDialogSave {
+		clsid, iid = &clsidFileSaveDialog, &iidIFileSaveDialog
+	}
+	if hr := _CoCreateInstance(clsid, 0, _CLSCTX_INPROC_SERVER, iid, &obj); hr < 0 {
+		return 0, fmt.Errorf("win32: CoCreateInstance failed: %#x", uint32(hr))
+	}
+	if err := setUpFileDialog(obj, opts); err != nil {
+		comCall(obj, methodRelease)
+		return 0, err
+	}
+	return obj, nil
+}
+
+func setUpFileDialog(obj uintptr, opts *screen.FileDialogOptions) error {
+	var fos uint32
+	if hr := int32(comCall(obj, methodGetOptions, uintptr(unsafe.Pointer(&fos)))); hr < 0 {
+		return fmt.Errorf("win32: IFileDialog.GetOptions failed: %#x", uint32(hr))
+	}
+	// Only files with paths can be returned as paths.
+	fos |= _FOS_FORCEFILESYSTEM
+	switch opts.Kind {
+	case screen.FileDialogSave:
+		fos |= _FOS_OVERWRITEPROMPT
+	case screen.FileDialogDirectory:
+		fos |= _FOS_PICKFOLDERS
+	}
+	if opts.Multiple && opts.Kind != screen.FileDialogSave {
+		fos |= _FOS_ALLOWMULTISELECT
+	}
+	comCall(obj, methodSetOptions, uintptr(fos))
+
+	if opts.Title != "" {
+		title, err := syscall.UTF16PtrFromString(opts.Title)
+		if err != nil {
+			return err
+		}
+		comCall(obj, methodSetTitle, uintptr(unsafe.Pointer(title)))
+	}
+	if opts.Kind == screen.FileDialogSave && opts.FileName != "" {
+		name, err := syscall.UTF16PtrFromString(opts.FileName)
+		if err != nil {
+			return err
+		}
+		comCall(obj, methodSetFileName, uintptr(unsafe.Pointer(name)))
+	}
+	if opts.Directory != "" {
+		dir, err := syscall.UTF16PtrFromString(opts.Directory)
+		if err != nil {
+			return err
+		}
+		// A directory that does not exist is ignored, as it is by the
+		// other platforms' dialogs.
+		var item uintptr
+		if _SHCreateItemFromParsingName(dir, 0, &iidIShellItem, &item) >= 0 {
+			comCall(obj, methodSetFolder, item)
+			comCall(item, methodRelease)
+		}
+	}
+
+	if opts.Kind != screen.FileDialogDirectory && len(opts.Filters) > 0 {
+		specs := make([]_COMDLG_FILTERSPEC, len(opts.Filters))
+		for i, f := range opts.Filters {
+			name, err := syscall.UTF16PtrFromString(f.Name)
+			if err != nil {
+				return err
+			}
+			spec, err := syscall.UTF16PtrFromString(strings.Join(f.Patterns, ";"))
+			if err != nil {
+				return err
+			}
+			specs[i] = _COMDLG_FILTERSPEC{PszName: name, PszSpec: spec}
+		}
+		// The dialog copies the filters.
+		if hr := int32(comCall(obj, methodSetFileTypes, uintptr(len(specs)), uintptr(unsafe.Pointer(&specs[0])))); hr < 0 {
+			return fmt.Errorf("win32: IFileDialog.SetFileTypes failed: %#x", uint32(hr))
+		}
+	}
+	return nil
+}
+
+// fileDialogPaths returns the paths that the user chose in the dialog obj.
+func fileDialogPaths(obj uintptr, kind screen.FileDialogKind) ([]string, error) {
+	if kind == screen.FileDialogSave {
+		var item uintptr
+		if hr := int32(comCall(obj, methodGetResult, uintptr(unsafe.Pointer(&item)))); hr < 0 {
+			return nil, fmt.Errorf("win32: IFileDialog.GetResult failed: %#x", uint32(hr))
+		}
+		defer comCall(item, methodRelease)
+		path, err := shellItemPath(item)
+		if err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+
+	var items uintptr
+	if hr := int32(comCall(obj, methodGetResults, uintptr(unsafe.Pointer(&items)))); hr < 0 {
+		return nil, fmt.Errorf("win32: IFileOpenDialog.GetResults failed: %#x", uint32(hr))
+	}
+	defer comCall(items, methodRelease)
+	var n uint32
+	if hr := int32(comCall(items, methodGetCount, uintptr(unsafe.Pointer(&n)))); hr < 0 {
+		return nil, fmt.Errorf("win32: IShellItemArray.GetCount failed: %#x", uint32(hr))
+	}
+	paths := make([]string, 0, n)
+	for i := uint32(0); i < n; i++ {
+		var item uintptr
+		if hr := int32(comCall(items, methodGetItemAt, uintptr(i), uintptr(unsafe.Pointer(&item)))); hr < 0 {
+			return nil, fmt.Errorf("win32: IShellItemArray.GetItemAt failed: %#x", uint32(hr))
+		}
+		path, err := shellItemPath(item)
+		comCall(item, methodRelease)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// shellItemPath returns the file system path of an IShellItem.
+func shellItemPath(item uintptr) (string, error) {
+	var p *uint16
+	if hr := int32(comCall(item, methodGetDisplayName, _SIGDN_FILESYSPATH, uintptr(unsafe.Pointer(&p)))); hr < 0 {
+		return "", fmt.Errorf("win32: IShellItem.GetDisplayName failed: %#x", uint32(hr))
+	}
+	defer _CoTaskMemFree(uintptr(unsafe.Pointer(p)))
+	return windows.UTF16PtrToString(p), nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/frame"
+)
+
+// frames are the windows waiting for a screen.FrameEvent.
+var frames frame.Requests
+
+// NextFrame asks for a screen.FrameEvent to be sent to w when the Desktop
+// Window Manager next composes the desktop, which it does at each of the
+// display's vertical blanks.
+func NextFrame(w frame.Sender) {
+	if frames.Request(w) {
+		go waitForFrames()
+	}
+}
+
+// waitForFrames is the frame source for NextFrame. DwmFlush blocks the calling
+// thread until the next composition, so it runs on its own goroutine.
+func waitForFrames() {
+	for {
+		// DwmFlush fails when desktop composition is disabled, as it can be
+		// before Windows 8. The frames are then paced by a clock instead.
+		if procDwmFlush.Find() != nil || _DwmFlush() != 0 {
+			frames.Tick(frame.Interval(0))
+			return
+		}
+		if !frames.Fire(time.Now()) {
+			return
+		}
+	}
+}
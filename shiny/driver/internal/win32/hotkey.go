@@ -0,0 +1,130 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+)
+
+const (
+	_MOD_ALT      = 0x0001
+	_MOD_CONTROL  = 0x0002
+	_MOD_SHIFT    = 0x0004
+	_MOD_WIN      = 0x0008
+	_MOD_NOREPEAT = 0x4000
+)
+
+const _ERROR_HOTKEY_ALREADY_REGISTERED syscall.Errno = 1409
+
+// hotKeys holds, by their IDs, the hot keys that each window registered.
+// Like the windows, it is only accessed on the thread that runs the message
+// loop.
+var hotKeys = map[syscall.Handle]map[int32]screen.HotKeyEvent{}
+
+type hotKeyParams struct {
+	e   screen.HotKeyEvent
+	err error
+}
+
+// RegisterHotKey registers the hot key k, pressed with exactly mods, for
+// hwnd, which is sent a HotKeyEvent whenever it is pressed, whichever window
+// has the keyboard focus.
+func RegisterHotKey(hwnd syscall.Handle, k key.Code, mods key.Modifiers) error {
+	p := hotKeyParams{e: screen.HotKeyEvent{Key: k, Modifiers: mods}}
+	SendMessage(hwnd, msgRegisterHotKey, 0, uintptr(unsafe.Pointer(&p)))
+	return p.err
+}
+
+func sendRegisterHotKey(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	p := (*hotKeyParams)(Pointer(lParam))
+	vk, ok := virtualKeyCodes()[p.e.Key]
+	if !ok {
+		p.err = errors.New("win32: no virtual key code for hot key")
+		return 0
+	}
+	mods := hotKeyModifiers(p.e.Modifiers)
+	id := int32(vk)<<4 | int32(mods)
+	if _, ok := hotKeys[hwnd][id]; ok {
+		return 0
+	}
+	if err := _RegisterHotKey(hwnd, id, mods|_MOD_NOREPEAT, uint32(vk)); err != nil {
+		if err == _ERROR_HOTKEY_ALREADY_REGISTERED {
+			err = screen.ErrHotKeyInUse
+		}
+		p.err = err
+		return 0
+	}
+	if hotKeys[hwnd] == nil {
+		hotKeys[hwnd] = map[int32]screen.HotKeyEvent{}
+	}
+	hotKeys[hwnd][id] = p.e
+	return 0
+}
+
+// UnregisterHotKey unregisters the hot key k, pressed with exactly mods, if
+// hwnd registered it.
+func UnregisterHotKey(hwnd syscall.Handle, k key.Code, mods key.Modifiers) {
+	p := hotKeyParams{e: screen.HotKeyEvent{Key: k, Modifiers: mods}}
+	SendMessage(hwnd, msgUnregisterHotKey, 0, uintptr(unsafe.Pointer(&p)))
+}
+
+func sendUnregisterHotKey(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	p := (*hotKeyParams)(Pointer(lParam))
+	for id, e := range hotKeys[hwnd] {
+		if e == p.e {
+			_UnregisterHotKey(hwnd, id)
+			delete(hotKeys[hwnd], id)
+		}
+	}
+	return 0
+}
+
+// ReleaseHotKeys unregisters every hot key that hwnd registered. Destroying
+// hwnd also unregisters them.
+func ReleaseHotKeys(hwnd syscall.Handle) {
+	SendMessage(hwnd, msgReleaseHotKeys, 0, 0)
+}
+
+func sendReleaseHotKeys(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	releaseHotKeys(hwnd)
+	return 0
+}
+
+func releaseHotKeys(hwnd syscall.Handle) {
+	for id := range hotKeys[hwnd] {
+		_UnregisterHotKey(hwnd, id)
+	}
+	delete(hotKeys, hwnd)
+}
+
+func sendHotKey(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	if e, ok := hotKeys[hwnd][int32(wParam)]; ok {
+		HotKeyEvent(hwnd, e)
+	}
+	return 0
+}
+
+func hotKeyModifiers(m key.Modifiers) (mods uint32) {
+	if m&key.ModShift != 0 {
+		mods |= _MOD_SHIFT
+	}
+	if m&key.ModControl != 0 {
+		mods |= _MOD_CONTROL
+	}
+	if m&key.ModAlt != 0 {
+		mods |= _MOD_ALT
+	}
+	if m&key.ModMeta != 0 {
+		mods |= _MOD_WIN
+	}
+	return mods
+}
@@ -0,0 +1,123 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"image"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// The IME (Input Method Editor) messages are described at
+// https://msdn.microsoft.com/en-us/library/windows/desktop/dd318650(v=vs.85).aspx
+//
+// The composition string is drawn by the application, from the Preedit of
+// the screen.TextEvents that we send, rather than by the IME's own composition
+// window. The IME still draws its candidate window, next to the rectangle
+// passed to SetTextInputRect.
+
+// SetTextInputRect tells the IME where hwnd's text cursor is.
+func SetTextInputRect(hwnd syscall.Handle, r image.Rectangle) {
+	// The IME context can only be changed by the thread that created hwnd.
+	SendMessage(hwnd, msgSetTextInputRect, 0, uintptr(unsafe.Pointer(&r)))
+}
+
+func sendSetTextInputRect(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	r := *(*image.Rectangle)(Pointer(lParam))
+	imc := _ImmGetContext(hwnd)
+	if imc == 0 {
+		return 0
+	}
+	defer _ImmReleaseContext(hwnd, imc)
+
+	area := _RECT{
+		Left:   int32(r.Min.X),
+		Top:    int32(r.Min.Y),
+		Right:  int32(r.Max.X),
+		Bottom: int32(r.Max.Y),
+	}
+	_ImmSetCompositionWindow(imc, &_COMPOSITIONFORM{
+		Style:      _CFS_POINT,
+		CurrentPos: _POINT{area.Left, area.Top},
+		Area:       area,
+	})
+	// Place the candidate window below the text cursor, without covering it.
+	_ImmSetCandidateWindow(imc, &_CANDIDATEFORM{
+		Style:      _CFS_EXCLUDE,
+		CurrentPos: _POINT{area.Left, area.Bottom},
+		Area:       area,
+	})
+	return 0
+}
+
+func sendIMESetContext(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	// Hide the IME's composition window, as the application draws the
+	// composition string.
+	lParam &^= _ISC_SHOWUICOMPOSITIONWINDOW
+	return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+}
+
+func sendIMEComposition(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	switch uMsg {
+	case _WM_IME_STARTCOMPOSITION:
+		// Don't call DefWindowProc, which would open the IME's composition
+		// window.
+		return 0
+	case _WM_IME_ENDCOMPOSITION:
+		TextEvent(hwnd, screen.TextEvent{})
+		return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+	}
+
+	imc := _ImmGetContext(hwnd)
+	if imc == 0 {
+		return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+	}
+	defer _ImmReleaseContext(hwnd, imc)
+
+	var e screen.TextEvent
+	if lParam&_GCS_RESULTSTR != 0 {
+		e.Commit, _ = compositionString(imc, _GCS_RESULTSTR)
+	}
+	if lParam&_GCS_COMPSTR != 0 {
+		var u []uint16
+		e.Preedit, u = compositionString(imc, _GCS_COMPSTR)
+		cursor := len(u)
+		if lParam&_GCS_CURSORPOS != 0 {
+			// The cursor position is returned as the result, in UTF-16
+			// code units.
+			cursor = int(_ImmGetCompositionString(imc, _GCS_CURSORPOS, nil, 0))
+			if cursor < 0 || len(u) < cursor {
+				cursor = len(u)
+			}
+		}
+		e.PreeditCursor = len(string(utf16.Decode(u[:cursor])))
+	}
+	TextEvent(hwnd, e)
+	// Don't call DefWindowProc, which would send the result string as
+	// WM_IME_CHAR messages.
+	return 0
+}
+
+// compositionString returns the IME's composition or result string, as
+// selected by index, both as a string and as UTF-16.
+func compositionString(imc syscall.Handle, index uint32) (string, []uint16) {
+	// The length is in bytes, not UTF-16 code units.
+	n := _ImmGetCompositionString(imc, index, nil, 0)
+	if n <= 0 {
+		return "", nil
+	}
+	u := make([]uint16, n/2)
+	n = _ImmGetCompositionString(imc, index, unsafe.Pointer(&u[0]), uint32(n))
+	if n <= 0 {
+		return "", nil
+	}
+	u = u[:n/2]
+	return string(utf16.Decode(u)), u
+}
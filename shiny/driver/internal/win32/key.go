@@ -11,6 +11,7 @@ import (
 	"syscall"
 	"unicode/utf16"
 
+	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/mobile/event/key"
 )
 
@@ -325,17 +326,53 @@ func readRune(vKey uint32, scanCode uint8) rune {
 	return utf16.Decode(buf[:ret])[0]
 }
 
+// readUnmodifiedRune returns what the key types with no modifier keys held,
+// or -1 if it types no character or is a dead key. Unlike readRune, it does
+// not change the keyboard state.
+func readUnmodifiedRune(vKey uint32, scanCode uint8) rune {
+	var (
+		keystate [256]byte
+		buf      [4]uint16
+	)
+	layout := _GetKeyboardLayout(0)
+	ret := _ToUnicodeEx(vKey, uint32(scanCode), &keystate[0], &buf[0], int32(len(buf)), _TOUNICODE_NOSTATE, layout)
+	if ret < 1 {
+		return -1
+	}
+	return utf16.Decode(buf[:ret])[0]
+}
+
 func sendKeyEvent(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
-	e := key.Event{
-		Rune:      readRune(uint32(wParam), uint8(lParam>>16)),
-		Code:      convVirtualKeyCode(uint32(wParam)),
-		Modifiers: keyModifiers(),
+	if wParam == _VK_PROCESSKEY {
+		// The key press is being handled by the IME, which will send
+		// WM_IME_COMPOSITION messages instead.
+		return 0
+	}
+	// Bits 16 to 23 of lParam are the key's scan code, and bit 24 is
+	// whether it is an extended key, whose scan code has an 0xe0 prefix.
+	scanCode := uint32(lParam>>16) & 0xff
+	if lParam&(1<<24) != 0 {
+		scanCode |= 0xe000
+	}
+	// The unmodified rune is read first, as reading the rune consumes a
+	// pending dead key.
+	unmodified := readUnmodifiedRune(uint32(wParam), uint8(scanCode))
+	e := screen.KeyEvent{
+		Event: key.Event{
+			Rune:      readRune(uint32(wParam), uint8(scanCode)),
+			Code:      convVirtualKeyCode(uint32(wParam)),
+			Modifiers: keyModifiers(),
+		},
+		Physical:   scanCodes[scanCode],
+		Scancode:   scanCode,
+		Unmodified: unmodified,
 	}
 	switch uMsg {
 	case _WM_KEYDOWN:
 		const prevMask = 1 << 30
 		if repeat := lParam&prevMask == prevMask; repeat {
 			e.Direction = key.DirNone
+			e.Repeat = true
 		} else {
 			e.Direction = key.DirPress
 		}
@@ -348,3 +385,139 @@ func sendKeyEvent(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lRe
 	KeyEvent(hwnd, e)
 	return 0
 }
+
+// sendInputLangChange sends the name of the new keyboard layout, such as
+// "0000040C" for a French layout, when the user switches layouts.
+func sendInputLangChange(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	var buf [_KL_NAMELENGTH]uint16
+	if err := _GetKeyboardLayoutName(&buf[0]); err == nil {
+		KeyboardLayoutEvent(hwnd, screen.KeyboardLayoutEvent{
+			Layout: syscall.UTF16ToString(buf[:]),
+		})
+	}
+	// An application that handles the message returns nonzero.
+	return 1
+}
+
+// scanCodes maps from the scan codes of the keys of a PC keyboard, with
+// 0xe000 added for the extended keys, to key.Code values.
+var scanCodes = map[uint32]key.Code{
+	0x01:   key.CodeEscape,
+	0x02:   key.Code1,
+	0x03:   key.Code2,
+	0x04:   key.Code3,
+	0x05:   key.Code4,
+	0x06:   key.Code5,
+	0x07:   key.Code6,
+	0x08:   key.Code7,
+	0x09:   key.Code8,
+	0x0a:   key.Code9,
+	0x0b:   key.Code0,
+	0x0c:   key.CodeHyphenMinus,
+	0x0d:   key.CodeEqualSign,
+	0x0e:   key.CodeDeleteBackspace,
+	0x0f:   key.CodeTab,
+	0x10:   key.CodeQ,
+	0x11:   key.CodeW,
+	0x12:   key.CodeE,
+	0x13:   key.CodeR,
+	0x14:   key.CodeT,
+	0x15:   key.CodeY,
+	0x16:   key.CodeU,
+	0x17:   key.CodeI,
+	0x18:   key.CodeO,
+	0x19:   key.CodeP,
+	0x1a:   key.CodeLeftSquareBracket,
+	0x1b:   key.CodeRightSquareBracket,
+	0x1c:   key.CodeReturnEnter,
+	0x1d:   key.CodeLeftControl,
+	0x1e:   key.CodeA,
+	0x1f:   key.CodeS,
+	0x20:   key.CodeD,
+	0x21:   key.CodeF,
+	0x22:   key.CodeG,
+	0x23:   key.CodeH,
+	0x24:   key.CodeJ,
+	0x25:   key.CodeK,
+	0x26:   key.CodeL,
+	0x27:   key.CodeSemicolon,
+	0x28:   key.CodeApostrophe,
+	0x29:   key.CodeGraveAccent,
+	0x2a:   key.CodeLeftShift,
+	0x2b:   key.CodeBackslash,
+	0x2c:   key.CodeZ,
+	0x2d:   key.CodeX,
+	0x2e:   key.CodeC,
+	0x2f:   key.CodeV,
+	0x30:   key.CodeB,
+	0x31:   key.CodeN,
+	0x32:   key.CodeM,
+	0x33:   key.CodeComma,
+	0x34:   key.CodeFullStop,
+	0x35:   key.CodeSlash,
+	0x36:   key.CodeRightShift,
+	0x37:   key.CodeKeypadAsterisk,
+	0x38:   key.CodeLeftAlt,
+	0x39:   key.CodeSpacebar,
+	0x3a:   key.CodeCapsLock,
+	0x3b:   key.CodeF1,
+	0x3c:   key.CodeF2,
+	0x3d:   key.CodeF3,
+	0x3e:   key.CodeF4,
+	0x3f:   key.CodeF5,
+	0x40:   key.CodeF6,
+	0x41:   key.CodeF7,
+	0x42:   key.CodeF8,
+	0x43:   key.CodeF9,
+	0x44:   key.CodeF10,
+	0x45:   key.CodePause,
+	0x47:   key.CodeKeypad7,
+	0x48:   key.CodeKeypad8,
+	0x49:   key.CodeKeypad9,
+	0x4a:   key.CodeKeypadHyphenMinus,
+	0x4b:   key.CodeKeypad4,
+	0x4c:   key.CodeKeypad5,
+	0x4d:   key.CodeKeypad6,
+	0x4e:   key.CodeKeypadPlusSign,
+	0x4f:   key.CodeKeypad1,
+	0x50:   key.CodeKeypad2,
+	0x51:   key.CodeKeypad3,
+	0x52:   key.CodeKeypad0,
+	0x53:   key.CodeKeypadFullStop,
+	0x57:   key.CodeF11,
+	0x58:   key.CodeF12,
+	0x59:   key.CodeKeypadEqualSign,
+	0x64:   key.CodeF13,
+	0x65:   key.CodeF14,
+	0x66:   key.CodeF15,
+	0x67:   key.CodeF16,
+	0x68:   key.CodeF17,
+	0x69:   key.CodeF18,
+	0x6a:   key.CodeF19,
+	0x6b:   key.CodeF20,
+	0x6c:   key.CodeF21,
+	0x6d:   key.CodeF22,
+	0x6e:   key.CodeF23,
+	0x76:   key.CodeF24,
+	0xe01c: key.CodeKeypadEnter,
+	0xe01d: key.CodeRightControl,
+	0xe020: key.CodeMute,
+	0xe02e: key.CodeVolumeDown,
+	0xe030: key.CodeVolumeUp,
+	0xe035: key.CodeKeypadSlash,
+	0xe038: key.CodeRightAlt,
+	0xe045: key.CodeKeypadNumLock,
+	0xe047: key.CodeHome,
+	0xe048: key.CodeUpArrow,
+	0xe049: key.CodePageUp,
+	0xe04b: key.CodeLeftArrow,
+	0xe04d: key.CodeRightArrow,
+	0xe04f: key.CodeEnd,
+	0xe050: key.CodeDownArrow,
+	0xe051: key.CodePageDown,
+	0xe052: key.CodeInsert,
+	0xe053: key.CodeDeleteForward,
+	0xe05b: key.CodeLeftGUI,
+	0xe05c: key.CodeRightGUI,
+	0xe05d: key.CodeCompose,
+}
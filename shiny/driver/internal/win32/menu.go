@@ -0,0 +1,370 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+)
+
+// Each menu item that can be chosen has a command, numbered from 1, that is
+// sent in a WM_COMMAND message, or returned by TrackPopupMenu, when the item
+// is chosen. A menu bar's keyboard shortcuts are an accelerator table, which
+// the message loop translates into the same commands.
+
+// maxMenuCommand is the largest command, as commands are 16 bits.
+const maxMenuCommand = 0xffff
+
+// menuBar is a window's menu bar, and its accelerator table, if any. ids[i]
+// is the screen.MenuItem.ID of command i+1.
+type menuBar struct {
+	menu  syscall.Handle
+	accel syscall.Handle
+	ids   []int
+}
+
+// contextMenu is a context menu that is waiting to be shown, at p.
+type contextMenu struct {
+	ids []int
+	p   image.Point
+}
+
+// menuBars holds each window's menu bar, and contextMenus holds the context
+// menus that are waiting to be shown. Like the windows, they are only
+// accessed on the thread that runs the message loop.
+var (
+	menuBars     = map[syscall.Handle]*menuBar{}
+	contextMenus = map[syscall.Handle]*contextMenu{}
+)
+
+type setMenuBarParams struct {
+	bar *screen.Menu
+	err error
+}
+
+// SetMenuBar sets hwnd's menu bar, or removes it, for a nil bar. Shortcuts
+// with the key.ModMeta modifier, the Windows key, which the system keeps for
+// itself, are ignored.
+func SetMenuBar(hwnd syscall.Handle, bar *screen.Menu) error {
+	p := setMenuBarParams{bar: bar}
+	SendMessage(hwnd, msgSetMenuBar, 0, uintptr(unsafe.Pointer(&p)))
+	return p.err
+}
+
+func sendSetMenuBar(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	p := (*setMenuBarParams)(Pointer(lParam))
+	var mb *menuBar
+	if p.bar != nil {
+		var b menuBuilder
+		menu, err := b.newMenu(p.bar, false)
+		if err != nil {
+			p.err = err
+			return 0
+		}
+		mb = &menuBar{menu: menu, ids: b.ids}
+		if len(b.accels) != 0 {
+			mb.accel, err = _CreateAcceleratorTable(&b.accels[0], int32(len(b.accels)))
+			if err != nil {
+				_DestroyMenu(menu)
+				p.err = fmt.Errorf("win32: CreateAcceleratorTable failed: %v", err)
+				return 0
+			}
+		}
+	}
+
+	var menu syscall.Handle
+	if mb != nil {
+		menu = mb.menu
+	}
+	if err := _SetMenu(hwnd, menu); err != nil {
+		if mb != nil {
+			mb.release()
+		}
+		p.err = fmt.Errorf("win32: SetMenu failed: %v", err)
+		return 0
+	}
+	if old := menuBars[hwnd]; old != nil {
+		old.release()
+	}
+	if mb != nil {
+		menuBars[hwnd] = mb
+	} else {
+		delete(menuBars, hwnd)
+	}
+	return 0
+}
+
+func (mb *menuBar) release() {
+	_DestroyMenu(mb.menu)
+	if mb.accel != 0 {
+		_DestroyAcceleratorTable(mb.accel)
+	}
+}
+
+// releaseMenuBar forgets hwnd's menu bar, when it is destroyed. DestroyWindow
+// destroys the menu itself.
+func releaseMenuBar(hwnd syscall.Handle) {
+	if mb := menuBars[hwnd]; mb != nil && mb.accel != 0 {
+		_DestroyAcceleratorTable(mb.accel)
+	}
+	delete(menuBars, hwnd)
+}
+
+// translateAccelerator reports whether m is a key press of one of the
+// shortcuts of its window's menu bar, which it sends as a WM_COMMAND.
+func translateAccelerator(m *_MSG) bool {
+	mb := menuBars[m.HWND]
+	return mb != nil && mb.accel != 0 && _TranslateAccelerator(m.HWND, mb.accel, m) != 0
+}
+
+func sendCommand(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	// Commands from menus and accelerators have no control window.
+	if lParam != 0 {
+		return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+	}
+	if mb := menuBars[hwnd]; mb != nil {
+		if cmd := int(_LOWORD(wParam)); 0 < cmd && cmd <= len(mb.ids) {
+			MenuEvent(hwnd, screen.MenuEvent{ID: mb.ids[cmd-1]})
+		}
+	}
+	return 0
+}
+
+type showContextMenuParams struct {
+	m   *screen.Menu
+	p   image.Point
+	err error
+}
+
+// ShowContextMenu shows m at p, in hwnd's client area pixels, and sends the
+// screen.MenuEvent of the chosen item, if any. Like a file dialog, the menu
+// runs in its own modal loop, on the UI thread, after ShowContextMenu
+// returns.
+func ShowContextMenu(hwnd syscall.Handle, m *screen.Menu, p image.Point) error {
+	params := showContextMenuParams{m: m, p: p}
+	if params.m == nil {
+		params.m = &screen.Menu{}
+	}
+	SendMessage(hwnd, msgShowContextMenu, 0, uintptr(unsafe.Pointer(&params)))
+	return params.err
+}
+
+func sendShowContextMenu(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	p := (*showContextMenuParams)(Pointer(lParam))
+	var b menuBuilder
+	menu, err := b.newMenu(p.m, true)
+	if err != nil {
+		p.err = err
+		return 0
+	}
+	contextMenus[menu] = &contextMenu{ids: b.ids, p: p.p}
+	if !_PostMessage(hwnd, msgTrackContextMenu, uintptr(menu), 0) {
+		delete(contextMenus, menu)
+		_DestroyMenu(menu)
+		p.err = errors.New("win32: PostMessage failed")
+	}
+	return 0
+}
+
+func sendTrackContextMenu(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	menu := syscall.Handle(wParam)
+	cm := contextMenus[menu]
+	delete(contextMenus, menu)
+	if cm == nil {
+		return 0
+	}
+	pt := _POINT{X: int32(cm.p.X), Y: int32(cm.p.Y)}
+	_ClientToScreen(hwnd, &pt)
+	cmd := int(_TrackPopupMenu(menu, _TPM_RETURNCMD|_TPM_RIGHTBUTTON, pt.X, pt.Y, 0, hwnd, nil))
+	_DestroyMenu(menu)
+	if 0 < cmd && cmd <= len(cm.ids) {
+		MenuEvent(hwnd, screen.MenuEvent{ID: cm.ids[cmd-1]})
+	}
+	return 0
+}
+
+// menuBuilder builds the Win32 menus of a screen.Menu, numbering the
+// commands of their items, and collecting their shortcuts.
+type menuBuilder struct {
+	ids    []int
+	accels []_ACCEL
+}
+
+// newMenu returns a new menu, or popup menu, and its submenus, holding m's
+// items. The caller must destroy it, unless it becomes a window's menu.
+func (b *menuBuilder) newMenu(m *screen.Menu, popup bool) (syscall.Handle, error) {
+	create, name := _CreateMenu, "CreateMenu"
+	if popup {
+		create, name = _CreatePopupMenu, "CreatePopupMenu"
+	}
+	menu, err := create()
+	if err != nil {
+		return 0, fmt.Errorf("win32: %s failed: %v", name, err)
+	}
+	for i := range m.Items {
+		if err := b.appendItem(menu, &m.Items[i]); err != nil {
+			_DestroyMenu(menu)
+			return 0, err
+		}
+	}
+	return menu, nil
+}
+
+func (b *menuBuilder) appendItem(menu syscall.Handle, item *screen.MenuItem) error {
+	if item.Separator {
+		if err := _AppendMenu(menu, _MF_SEPARATOR, 0, nil); err != nil {
+			return fmt.Errorf("win32: AppendMenu failed: %v", err)
+		}
+		return nil
+	}
+
+	// An ampersand marks the next character as the item's mnemonic, unless
+	// it is doubled.
+	title := strings.Replace(item.Title, "&", "&&", -1)
+	flags := uint32(_MF_STRING)
+	if item.Disabled {
+		flags |= _MF_GRAYED
+	}
+	if item.Checked {
+		flags |= _MF_CHECKED
+	}
+	var id uintptr
+	var submenu syscall.Handle
+	if item.Submenu != nil {
+		var err error
+		if submenu, err = b.newMenu(item.Submenu, true); err != nil {
+			return err
+		}
+		flags |= _MF_POPUP
+		id = uintptr(submenu)
+	} else {
+		if len(b.ids) == maxMenuCommand {
+			return errors.New("win32: too many menu items")
+		}
+		b.ids = append(b.ids, item.ID)
+		cmd := uint16(len(b.ids))
+		id = uintptr(cmd)
+		if a, text, ok := accelerator(item.Key, item.Modifiers); ok {
+			a.Cmd = cmd
+			b.accels = append(b.accels, a)
+			// Text after a tab is right-aligned, as shortcuts are shown.
+			title += "\t" + text
+		}
+	}
+
+	t, err := syscall.UTF16PtrFromString(title)
+	if err == nil {
+		err = _AppendMenu(menu, flags, id, t)
+		if err != nil {
+			err = fmt.Errorf("win32: AppendMenu failed: %v", err)
+		}
+	}
+	if err != nil && submenu != 0 {
+		_DestroyMenu(submenu)
+	}
+	return err
+}
+
+// accelerator returns the accelerator table entry, without its command, and
+// the text shown for a shortcut, and whether the shortcut can be one.
+func accelerator(code key.Code, mods key.Modifiers) (a _ACCEL, text string, ok bool) {
+	if code == key.CodeUnknown || mods&key.ModMeta != 0 {
+		return _ACCEL{}, "", false
+	}
+	vk, ok := virtualKeyCodes()[code]
+	if !ok {
+		return _ACCEL{}, "", false
+	}
+	name := keyName(code)
+	if name == "" {
+		return _ACCEL{}, "", false
+	}
+
+	a = _ACCEL{FVirt: _FVIRTKEY, Key: vk}
+	if mods&key.ModControl != 0 {
+		a.FVirt |= _FCONTROL
+		text += "Ctrl+"
+	}
+	if mods&key.ModAlt != 0 {
+		a.FVirt |= _FALT
+		text += "Alt+"
+	}
+	if mods&key.ModShift != 0 {
+		a.FVirt |= _FSHIFT
+		text += "Shift+"
+	}
+	return a, text + name, true
+}
+
+var vkCodes map[key.Code]uint16
+
+// virtualKeyCodes returns the Win32 virtual key code of each key.Code, the
+// inverse of convVirtualKeyCode, which shares many codes between keys. It
+// must only be called on the thread that runs the message loop.
+func virtualKeyCodes() map[key.Code]uint16 {
+	if vkCodes == nil {
+		vkCodes = map[key.Code]uint16{}
+		for vk := uint32(1); vk < 0xff; vk++ {
+			if code := convVirtualKeyCode(vk); code != key.CodeUnknown {
+				if _, ok := vkCodes[code]; !ok {
+					vkCodes[code] = uint16(vk)
+				}
+			}
+		}
+	}
+	return vkCodes
+}
+
+// keyNames are the names of the keys, other than letters, digits and function
+// keys, that are shown in shortcuts.
+var keyNames = map[key.Code]string{
+	key.CodeReturnEnter:     "Enter",
+	key.CodeEscape:          "Esc",
+	key.CodeDeleteBackspace: "Backspace",
+	key.CodeTab:             "Tab",
+	key.CodeSpacebar:        "Space",
+	key.CodeHyphenMinus:     "-",
+	key.CodeEqualSign:       "=",
+	key.CodeComma:           ",",
+	key.CodeFullStop:        ".",
+	key.CodeSlash:           "/",
+	key.CodeInsert:          "Ins",
+	key.CodeDeleteForward:   "Del",
+	key.CodeHome:            "Home",
+	key.CodeEnd:             "End",
+	key.CodePageUp:          "PgUp",
+	key.CodePageDown:        "PgDn",
+	key.CodeLeftArrow:       "Left",
+	key.CodeRightArrow:      "Right",
+	key.CodeUpArrow:         "Up",
+	key.CodeDownArrow:       "Down",
+}
+
+// keyName returns the name of the key, as shown in shortcuts, or "" if it has
+// none.
+func keyName(code key.Code) string {
+	switch {
+	case key.CodeA <= code && code <= key.CodeZ:
+		return string(rune('A' + code - key.CodeA))
+	case key.Code1 <= code && code <= key.Code9:
+		return string(rune('1' + code - key.Code1))
+	case code == key.Code0:
+		return "0"
+	case key.CodeF1 <= code && code <= key.CodeF12:
+		return fmt.Sprintf("F%d", 1+code-key.CodeF1)
+	case key.CodeF13 <= code && code <= key.CodeF24:
+		return fmt.Sprintf("F%d", 13+code-key.CodeF13)
+	}
+	return keyNames[code]
+}
@@ -0,0 +1,171 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/icon"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Notifications are the balloons of the notification icon, a notification
+// area icon of the tray window, whose ID is 0, unlike the tray icons. It is
+// only added while a notification is shown, with the icon of the window that
+// posted it. Windows 10 and later show balloons as toast notifications, but
+// only one at a time, and without buttons.
+//
+// TODO: post toast notifications with the Windows Runtime's
+// ToastNotificationManager, whose notifications have buttons, and stay in
+// the Action Center. That needs the application to have an AppUserModelID,
+// and COM activation.
+
+const notificationIconID = 0
+
+const (
+	_NIF_INFO = 0x10
+
+	_NIIF_NONE       = 0x0
+	_NIIF_USER       = 0x4
+	_NIIF_LARGE_ICON = 0x20
+
+	_NIN_BALLOONHIDE      = _WM_USER + 3
+	_NIN_BALLOONTIMEOUT   = _WM_USER + 4
+	_NIN_BALLOONUSERCLICK = _WM_USER + 5
+)
+
+// notification is the shown notification, if hwnd is non-zero. balloonIcon
+// is its image, if any. Like the windows, it is only accessed on the thread
+// that runs the message loop.
+var notification struct {
+	hwnd        syscall.Handle
+	id          int
+	balloonIcon syscall.Handle
+}
+
+type notifyParams struct {
+	n   *screen.Notification
+	err error
+}
+
+// Notify shows n in a balloon of the notification icon, replacing the shown
+// notification, if any. Activating it sends hwnd a NotificationEvent.
+func Notify(hwnd syscall.Handle, n *screen.Notification) error {
+	p := notifyParams{n: n}
+	SendMessage(hwnd, msgNotify, 0, uintptr(unsafe.Pointer(&p)))
+	return p.err
+}
+
+func sendNotify(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	p := (*notifyParams)(Pointer(lParam))
+	if trayHWND == 0 {
+		if p.err = initTrayWindow(); p.err != nil {
+			return 0
+		}
+	}
+	hIcon := hDefaultIcon
+	if wt := windowTaskbars[hwnd]; wt != nil && wt.smallIcon != 0 {
+		hIcon = wt.smallIcon
+	}
+	d := _NOTIFYICONDATA{
+		CbSize:           uint32(unsafe.Sizeof(_NOTIFYICONDATA{})),
+		HWnd:             trayHWND,
+		UID:              notificationIconID,
+		UFlags:           _NIF_MESSAGE | _NIF_ICON | _NIF_INFO,
+		UCallbackMessage: msgTrayNotify,
+		HIcon:            hIcon,
+		DwInfoFlags:      _NIIF_NONE,
+	}
+	// The strings are truncated to fit, leaving their NUL terminators. An
+	// empty body would remove the balloon, rather than show it.
+	body := p.n.Body
+	if body == "" {
+		body = " "
+	}
+	if s, err := syscall.UTF16FromString(p.n.Title); err == nil {
+		copy(d.SzInfoTitle[:len(d.SzInfoTitle)-1], s)
+	}
+	if s, err := syscall.UTF16FromString(body); err == nil {
+		copy(d.SzInfo[:len(d.SzInfo)-1], s)
+	}
+	var balloonIcon syscall.Handle
+	if p.n.Image != nil {
+		balloonIcon, _ = createIcon(icon.Scale(p.n.Image, int(_GetSystemMetrics(_SM_CXICON))))
+		if balloonIcon != 0 {
+			d.DwInfoFlags = _NIIF_USER | _NIIF_LARGE_ICON
+			d.HBalloonIcon = balloonIcon
+		}
+	}
+
+	message := uint32(_NIM_MODIFY)
+	if notification.hwnd == 0 {
+		message = _NIM_ADD
+	}
+	if !_Shell_NotifyIcon(message, &d) {
+		destroyIcons(balloonIcon)
+		p.err = errors.New("win32: Shell_NotifyIcon failed")
+		return 0
+	}
+	destroyIcons(notification.balloonIcon)
+	notification.hwnd, notification.id, notification.balloonIcon = hwnd, p.n.ID, balloonIcon
+	return 0
+}
+
+// CloseNotification removes hwnd's notification with the given ID, if it is
+// shown.
+func CloseNotification(hwnd syscall.Handle, id int) {
+	SendMessage(hwnd, msgCloseNotification, uintptr(id), 0)
+}
+
+func sendCloseNotification(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	if notification.hwnd == hwnd && notification.id == int(wParam) {
+		closeNotification()
+	}
+	return 0
+}
+
+// releaseNotification removes hwnd's notification, if it is shown, when hwnd
+// is destroyed.
+func releaseNotification(hwnd syscall.Handle) {
+	if notification.hwnd == hwnd {
+		closeNotification()
+	}
+}
+
+// closeNotification deletes the notification icon, which removes its
+// balloon.
+func closeNotification() {
+	if notification.hwnd == 0 {
+		return
+	}
+	d := _NOTIFYICONDATA{
+		CbSize: uint32(unsafe.Sizeof(_NOTIFYICONDATA{})),
+		HWnd:   trayHWND,
+		UID:    notificationIconID,
+	}
+	_Shell_NotifyIcon(_NIM_DELETE, &d)
+	destroyIcons(notification.balloonIcon)
+	notification.hwnd, notification.id, notification.balloonIcon = 0, 0, 0
+}
+
+// sendNotificationIconNotify handles a notification of the notification
+// icon, whose lParam is the mouse or balloon message. Clicking the balloon or
+// the icon activates the notification.
+func sendNotificationIconNotify(lParam uintptr) {
+	switch lParam {
+	case _NIN_BALLOONUSERCLICK, _WM_LBUTTONUP:
+		hwnd, id := notification.hwnd, notification.id
+		closeNotification()
+		if hwnd != 0 {
+			NotificationEvent(hwnd, screen.NotificationEvent{ID: id})
+		}
+	case _NIN_BALLOONHIDE, _NIN_BALLOONTIMEOUT:
+		closeNotification()
+	}
+}
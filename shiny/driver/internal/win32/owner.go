@@ -0,0 +1,55 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"syscall"
+)
+
+// modalOwners holds, for each window created with
+// screen.NewWindowOptions.Modal, the owner window that it disabled. Like the
+// windows, it is only accessed on the thread that runs the message loop.
+var modalOwners = map[syscall.Handle]syscall.Handle{}
+
+// disabledCounts holds, for each disabled owner window, how many modal windows
+// disabled it, so that it is only enabled again when the last is released.
+var disabledCounts = map[syscall.Handle]int{}
+
+// disableOwner disables owner, which is sent no mouse or keyboard input while
+// the modal window hwnd exists.
+func disableOwner(hwnd, owner syscall.Handle) {
+	modalOwners[hwnd] = owner
+	disabledCounts[owner]++
+	_EnableWindow(owner, false)
+}
+
+// enableOwner enables the owner that the modal window hwnd disabled, if any,
+// unless other modal windows still disable it.
+func enableOwner(hwnd syscall.Handle) {
+	owner, ok := modalOwners[hwnd]
+	if !ok {
+		return
+	}
+	delete(modalOwners, hwnd)
+	if disabledCounts[owner]--; disabledCounts[owner] > 0 {
+		return
+	}
+	delete(disabledCounts, owner)
+	_EnableWindow(owner, true)
+}
+
+// EnableOwner enables the owner that hwnd, a window created with
+// screen.NewWindowOptions.Modal, disabled. Release does so itself, but
+// drivers that close their windows without releasing them call it instead.
+func EnableOwner(hwnd syscall.Handle) {
+	SendMessage(hwnd, msgEnableOwner, 0, 0)
+}
+
+func sendEnableOwner(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	enableOwner(hwnd)
+	return 0
+}
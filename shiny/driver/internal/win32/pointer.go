@@ -0,0 +1,111 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"syscall"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/touch"
+)
+
+// Since Windows 8, touch screens and pens send WM_POINTER messages, which
+// are sent as touch.Events and screen.PenEvents. Mice only send them to
+// programs that call EnableMouseInPointer, which this package does not, so
+// that mice keep sending the mouse messages handled by sendMouseEvent.
+// WM_POINTER messages that are not handled here are passed to DefWindowProc,
+// which turns them into mouse messages too.
+
+func sendPointerEvent(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	id := uint32(wParam & 0xffff)
+	var typ uint32
+	if _GetPointerType(id, &typ) != nil {
+		return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+	}
+	switch typ {
+	case _PT_TOUCH:
+		sendTouch(hwnd, uMsg, id, lParam)
+		return 0
+	case _PT_PEN:
+		if sendPen(hwnd, uMsg, id) {
+			return 0
+		}
+	}
+	return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+}
+
+// pointerPosition returns the window coordinates of a WM_POINTER message's
+// lParam, which holds screen coordinates.
+func pointerPosition(hwnd syscall.Handle, lParam uintptr) (x, y float32) {
+	p := _POINT{
+		_GET_X_LPARAM(lParam),
+		_GET_Y_LPARAM(lParam),
+	}
+	_ScreenToClient(hwnd, &p)
+	return float32(p.X), float32(p.Y)
+}
+
+func sendTouch(hwnd syscall.Handle, uMsg uint32, id uint32, lParam uintptr) {
+	e := touch.Event{
+		Sequence: touch.Sequence(id),
+	}
+	switch uMsg {
+	case _WM_POINTERDOWN:
+		e.Type = touch.TypeBegin
+	case _WM_POINTERUPDATE:
+		e.Type = touch.TypeMove
+	case _WM_POINTERUP:
+		e.Type = touch.TypeEnd
+	}
+	e.X, e.Y = pointerPosition(hwnd, lParam)
+	TouchEvent(hwnd, e)
+}
+
+// sendPen sends the screen.PenEvent of a pen's WM_POINTER message, returning
+// false if its pen information could not be got.
+func sendPen(hwnd syscall.Handle, uMsg uint32, id uint32) bool {
+	var pi _POINTER_PEN_INFO
+	if _GetPointerPenInfo(id, &pi) != nil {
+		return false
+	}
+	p := pi.PointerInfo.PtPixelLocation
+	_ScreenToClient(hwnd, &p)
+	inContact := pi.PointerInfo.PointerFlags&_POINTER_FLAG_INCONTACT != 0
+	e := screen.PenEvent{
+		X:         float32(p.X),
+		Y:         float32(p.Y),
+		Eraser:    pi.PenFlags&(_PEN_FLAG_ERASER|_PEN_FLAG_INVERTED) != 0,
+		Barrel:    pi.PenFlags&_PEN_FLAG_BARREL != 0,
+		Modifiers: keyModifiers(),
+	}
+	switch {
+	case uMsg == _WM_POINTERDOWN:
+		e.Type = screen.PenDown
+	case uMsg == _WM_POINTERUP:
+		e.Type = screen.PenUp
+	case inContact:
+		e.Type = screen.PenMove
+	default:
+		e.Type = screen.PenHover
+	}
+	if e.Type == screen.PenDown || e.Type == screen.PenMove {
+		e.Pressure = 0.5
+		if pi.PenMask&_PEN_MASK_PRESSURE != 0 {
+			e.Pressure = float32(pi.Pressure) / penMaxPressure
+		}
+	}
+	// Windows' tilts, like screen.PenEvent's, are in degrees, with a
+	// positive Y tilt towards the user.
+	if pi.PenMask&_PEN_MASK_TILT_X != 0 {
+		e.TiltX = float32(pi.TiltX)
+	}
+	if pi.PenMask&_PEN_MASK_TILT_Y != 0 {
+		e.TiltY = float32(pi.TiltY)
+	}
+	PenEvent(hwnd, e)
+	return true
+}
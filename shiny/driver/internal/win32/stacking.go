@@ -0,0 +1,74 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"syscall"
+)
+
+// SetAlwaysOnTop makes hwnd a topmost window, above all non-topmost windows,
+// or not.
+func SetAlwaysOnTop(hwnd syscall.Handle, onTop bool) {
+	insertAfter := _HWND_NOTOPMOST
+	if onTop {
+		insertAfter = _HWND_TOPMOST
+	}
+	SendMessage(hwnd, msgSetWindowOrder, uintptr(insertAfter), 0)
+}
+
+// Raise moves hwnd to the top of the windows at its level, topmost or not,
+// without activating it.
+func Raise(hwnd syscall.Handle) {
+	SendMessage(hwnd, msgSetWindowOrder, uintptr(_HWND_TOP), 0)
+}
+
+// Lower moves hwnd to the bottom of the windows at its level. A topmost
+// window that is lowered is no longer topmost.
+func Lower(hwnd syscall.Handle) {
+	SendMessage(hwnd, msgSetWindowOrder, uintptr(_HWND_BOTTOM), 0)
+}
+
+func sendSetWindowOrder(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	_SetWindowPos(hwnd, syscall.Handle(wParam), 0, 0, 0, 0,
+		_SWP_NOMOVE|_SWP_NOSIZE|_SWP_NOACTIVATE|_SWP_NOOWNERZORDER)
+	return 0
+}
+
+// SetOpacity makes hwnd a layered window whose every pixel has the constant
+// alpha, or, for an alpha of 255, no longer a layered window. It must not be
+// called for a window created with screen.NewWindowOptions.Transparent,
+// whose layered contents are set by UpdateLayeredWindow, with an alpha of its
+// own.
+func SetOpacity(hwnd syscall.Handle, alpha byte) {
+	SendMessage(hwnd, msgSetOpacity, uintptr(alpha), 0)
+}
+
+func sendSetOpacity(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	exstyle := uint32(_GetWindowLong(hwnd, _GWL_EXSTYLE))
+	if wParam == 255 {
+		if exstyle&_WS_EX_LAYERED != 0 {
+			_SetWindowLong(hwnd, _GWL_EXSTYLE, int32(exstyle&^_WS_EX_LAYERED))
+		}
+		return 0
+	}
+	if exstyle&_WS_EX_LAYERED == 0 {
+		_SetWindowLong(hwnd, _GWL_EXSTYLE, int32(exstyle|_WS_EX_LAYERED))
+	}
+	_SetLayeredWindowAttributes(hwnd, 0, byte(wParam), _LWA_ALPHA)
+	return 0
+}
+
+// Opacity returns the alpha, from 0 to 255, for a window opacity, from 0 to 1.
+func Opacity(opacity float32) byte {
+	if !(opacity < 1) {
+		return 255
+	}
+	if !(opacity > 0) {
+		return 0
+	}
+	return byte(opacity*255 + 0.5)
+}
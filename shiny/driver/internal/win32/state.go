@@ -0,0 +1,144 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// fullscreenWindow is a fullscreen window's style and placement from before
+// it entered fullscreen, to restore when it leaves.
+type fullscreenWindow struct {
+	style     int32
+	placement _WINDOWPLACEMENT
+}
+
+// fullscreenWindows holds the windows that are fullscreen, and windowStates
+// holds each window's state as of its last size event, so that a change can
+// be sent as a screen.WindowStateEvent. Like the windows, they are only
+// accessed on the thread that runs the message loop.
+var (
+	fullscreenWindows = map[syscall.Handle]*fullscreenWindow{}
+	windowStates      = map[syscall.Handle]screen.WindowState{}
+)
+
+// Minimize minimizes hwnd.
+func Minimize(hwnd syscall.Handle) {
+	SendMessage(hwnd, msgSetWindowState, uintptr(screen.WindowMinimized), 0)
+}
+
+// Maximize maximizes hwnd, leaving fullscreen if it is fullscreen.
+func Maximize(hwnd syscall.Handle) {
+	SendMessage(hwnd, msgSetWindowState, uintptr(screen.WindowMaximized), 0)
+}
+
+// Restore restores hwnd from being minimized, maximized or fullscreen.
+func Restore(hwnd syscall.Handle) {
+	SendMessage(hwnd, msgSetWindowState, uintptr(screen.WindowNormal), 0)
+}
+
+// SetFullscreen makes hwnd fullscreen, or not, for FullscreenNone. A
+// fullscreen window is a popup window, without a frame, that covers its
+// monitor. The desktop window manager gives such a window the display to
+// itself, without composition, as it would for exclusive fullscreen, so
+// FullscreenExclusive is the same as FullscreenBorderless.
+func SetFullscreen(hwnd syscall.Handle, mode screen.FullscreenMode) {
+	SendMessage(hwnd, msgSetWindowState, uintptr(screen.WindowFullscreen), uintptr(mode))
+}
+
+func sendSetWindowState(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	switch screen.WindowState(wParam) {
+	case screen.WindowMinimized:
+		_ShowWindow(hwnd, _SW_MINIMIZE)
+	case screen.WindowMaximized:
+		leaveFullscreen(hwnd)
+		_ShowWindow(hwnd, _SW_MAXIMIZE)
+	case screen.WindowNormal:
+		leaveFullscreen(hwnd)
+		// Restoring a window that was minimized while maximized maximizes
+		// it again, so it needs restoring twice.
+		_ShowWindow(hwnd, _SW_RESTORE)
+		if _IsZoomed(hwnd) {
+			_ShowWindow(hwnd, _SW_RESTORE)
+		}
+	case screen.WindowFullscreen:
+		if mode := screen.FullscreenMode(lParam); mode == screen.FullscreenNone {
+			leaveFullscreen(hwnd)
+		} else {
+			enterFullscreen(hwnd)
+		}
+	}
+	// Changing the style, without resizing, sends no size event.
+	updateWindowState(hwnd)
+	return 0
+}
+
+// enterFullscreen makes hwnd a popup window that covers its monitor.
+func enterFullscreen(hwnd syscall.Handle) {
+	if fullscreenWindows[hwnd] != nil {
+		return
+	}
+	fw := &fullscreenWindow{style: _GetWindowLong(hwnd, _GWL_STYLE)}
+	fw.placement.Length = uint32(unsafe.Sizeof(fw.placement))
+	if _GetWindowPlacement(hwnd, &fw.placement) != nil {
+		return
+	}
+	mi := _MONITORINFOEX{CbSize: uint32(unsafe.Sizeof(_MONITORINFOEX{}))}
+	if _GetMonitorInfo(_MonitorFromWindow(hwnd, _MONITOR_DEFAULTTONEAREST), &mi) != nil {
+		return
+	}
+	fullscreenWindows[hwnd] = fw
+
+	_SetWindowLong(hwnd, _GWL_STYLE, int32(uint32(fw.style)&^_WS_OVERLAPPEDWINDOW|_WS_POPUP))
+	r := mi.RcMonitor
+	_SetWindowPos(hwnd, 0, r.Left, r.Top, r.Right-r.Left, r.Bottom-r.Top,
+		_SWP_NOOWNERZORDER|_SWP_FRAMECHANGED)
+}
+
+// leaveFullscreen restores hwnd's style and placement from before it entered
+// fullscreen, if it is fullscreen.
+func leaveFullscreen(hwnd syscall.Handle) {
+	fw := fullscreenWindows[hwnd]
+	if fw == nil {
+		return
+	}
+	delete(fullscreenWindows, hwnd)
+
+	_SetWindowLong(hwnd, _GWL_STYLE, fw.style)
+	_SetWindowPlacement(hwnd, &fw.placement)
+	_SetWindowPos(hwnd, 0, 0, 0, 0, 0,
+		_SWP_NOMOVE|_SWP_NOSIZE|_SWP_NOZORDER|_SWP_NOOWNERZORDER|_SWP_FRAMECHANGED)
+}
+
+// updateWindowState sends a screen.WindowStateEvent if hwnd's state changed
+// since it was last updated. There is no event for hwnd's initial state.
+func updateWindowState(hwnd syscall.Handle) {
+	state := screen.WindowNormal
+	switch {
+	case _IsIconic(hwnd):
+		state = screen.WindowMinimized
+	case fullscreenWindows[hwnd] != nil:
+		state = screen.WindowFullscreen
+	case _IsZoomed(hwnd):
+		state = screen.WindowMaximized
+	}
+	if old, ok := windowStates[hwnd]; ok && old != state {
+		WindowStateEvent(hwnd, screen.WindowStateEvent{State: state})
+	}
+	windowStates[hwnd] = state
+	updateVisibility(hwnd)
+}
+
+// releaseWindowState forgets hwnd's state, when it is destroyed.
+func releaseWindowState(hwnd syscall.Handle) {
+	delete(fullscreenWindows, hwnd)
+	delete(windowStates, hwnd)
+	delete(windowVisibilities, hwnd)
+}
@@ -48,6 +48,70 @@ type _WNDCLASS struct {
 	LpszClassName *uint16
 }
 
+type _HIGHCONTRAST struct {
+	CbSize            uint32
+	DwFlags           uint32
+	LpszDefaultScheme *uint16
+}
+
+type _WINDOWPLACEMENT struct {
+	Length           uint32
+	Flags            uint32
+	ShowCmd          uint32
+	PtMinPosition    _POINT
+	PtMaxPosition    _POINT
+	RcNormalPosition _RECT
+}
+
+type _MONITORINFOEX struct {
+	CbSize    uint32
+	RcMonitor _RECT
+	RcWork    _RECT
+	DwFlags   uint32
+	SzDevice  [32]uint16
+}
+
+// _DEVMODE is the display device variant of DEVMODEW.
+type _DEVMODE struct {
+	DmDeviceName       [32]uint16
+	DmSpecVersion      uint16
+	DmDriverVersion    uint16
+	DmSize             uint16
+	DmDriverExtra      uint16
+	DmFields           uint32
+	DmPosition         _POINT
+	DmDisplayOrient    uint32
+	DmDisplayFixedOut  uint32
+	DmColor            int16
+	DmDuplex           int16
+	DmYResolution      int16
+	DmTTOption         int16
+	DmCollate          int16
+	DmFormName         [32]uint16
+	DmLogPixels        uint16
+	DmBitsPerPel       uint32
+	DmPelsWidth        uint32
+	DmPelsHeight       uint32
+	DmDisplayFlags     uint32
+	DmDisplayFrequency uint32
+	DmICMMethod        uint32
+	DmICMIntent        uint32
+	DmMediaType        uint32
+	DmDitherType       uint32
+	DmReserved1        uint32
+	DmReserved2        uint32
+	DmPanningWidth     uint32
+	DmPanningHeight    uint32
+}
+
+type _MINMAXINFO struct {
+	Reserved     _POINT
+	MaxSize      _POINT
+	MaxPosition  _POINT
+	MinTrackSize _POINT
+	MaxTrackSize _POINT
+}
+
 type _WINDOWPOS struct {
 	HWND            syscall.Handle
 	HWNDInsertAfter syscall.Handle
@@ -63,7 +127,18 @@ const (
 	_WM_KILLFOCUS        = 8
 	_WM_PAINT            = 15
 	_WM_CLOSE            = 16
+	_WM_GETMINMAXINFO    = 36
+	_WM_SETTINGCHANGE    = 26
+	_WM_SETCURSOR        = 32
+	_WM_GETOBJECT        = 61
+	_WM_INPUTLANGCHANGE  = 81
+	_WM_SETICON          = 128
+	_WM_NCCALCSIZE       = 131
+	_WM_NCLBUTTONDOWN    = 161
 	_WM_WINDOWPOSCHANGED = 71
+	_WM_DISPLAYCHANGE    = 126
+	_WM_INPUT            = 255
+	_WM_COMMAND          = 273
 	_WM_KEYDOWN          = 256
 	_WM_KEYUP            = 257
 	_WM_SYSKEYDOWN       = 260
@@ -76,9 +151,50 @@ const (
 	_WM_RBUTTONUP        = 517
 	_WM_MBUTTONDOWN      = 519
 	_WM_MBUTTONUP        = 520
+	_WM_XBUTTONDOWN      = 523
+	_WM_XBUTTONUP        = 524
+	_WM_MOUSEHWHEEL      = 526
+	_WM_SIZING           = 532
+	_WM_HOTKEY           = 786
+	_WM_POINTERUPDATE    = 581
+	_WM_POINTERDOWN      = 582
+	_WM_POINTERUP        = 583
+	_WM_MOUSELEAVE       = 675
+	_WM_DPICHANGED       = 736
 	_WM_USER             = 0x0400
 )
 
+const (
+	_WM_IME_STARTCOMPOSITION = 269
+	_WM_IME_ENDCOMPOSITION   = 270
+	_WM_IME_COMPOSITION      = 271
+	_WM_IME_SETCONTEXT       = 641
+)
+
+const (
+	_GCS_COMPSTR   = 0x0008
+	_GCS_CURSORPOS = 0x0080
+	_GCS_RESULTSTR = 0x0800
+
+	_ISC_SHOWUICOMPOSITIONWINDOW = 0x80000000
+
+	_CFS_POINT   = 0x0002
+	_CFS_EXCLUDE = 0x0080
+)
+
+type _COMPOSITIONFORM struct {
+	Style      uint32
+	CurrentPos _POINT
+	Area       _RECT
+}
+
+type _CANDIDATEFORM struct {
+	Index      uint32
+	Style      uint32
+	CurrentPos _POINT
+	Area       _RECT
+}
+
 const (
 	_WS_OVERLAPPED       = 0x00000000
 	_WS_CAPTION          = 0x00C00000
@@ -87,14 +203,28 @@ const (
 	_WS_MINIMIZEBOX      = 0x00020000
 	_WS_MAXIMIZEBOX      = 0x00010000
 	_WS_OVERLAPPEDWINDOW = _WS_OVERLAPPED | _WS_CAPTION | _WS_SYSMENU | _WS_THICKFRAME | _WS_MINIMIZEBOX | _WS_MAXIMIZEBOX
+
+	_WS_EX_LAYERED = 0x00080000
 )
 
+const _RGN_OR = 2
+
 const (
 	_VK_SHIFT   = 16
 	_VK_CONTROL = 17
 	_VK_MENU    = 18
 	_VK_LWIN    = 0x5B
 	_VK_RWIN    = 0x5C
+
+	_VK_PROCESSKEY = 0xE5
+)
+
+const (
+	_KL_NAMELENGTH = 9
+
+	// _TOUNICODE_NOSTATE is ToUnicodeEx's flag, since Windows 10 version
+	// 1607, to not change the keyboard state, such as a pending dead key.
+	_TOUNICODE_NOSTATE = 0x4
 )
 
 const (
@@ -103,23 +233,250 @@ const (
 	_MK_RBUTTON = 0x0002
 )
 
+const (
+	_XBUTTON1 = 0x0001
+	_XBUTTON2 = 0x0002
+)
+
 const (
 	_COLOR_BTNFACE = 15
 )
 
 const (
 	_IDI_APPLICATION = 32512
-	_IDC_ARROW       = 32512
+
+	_IDC_ARROW    = 32512
+	_IDC_IBEAM    = 32513
+	_IDC_WAIT     = 32514
+	_IDC_CROSS    = 32515
+	_IDC_SIZENWSE = 32642
+	_IDC_SIZENESW = 32643
+	_IDC_SIZEWE   = 32644
+	_IDC_SIZENS   = 32645
+	_IDC_SIZEALL  = 32646
+	_IDC_NO       = 32648
+	_IDC_HAND     = 32649
+)
+
+const (
+	_HTCLIENT      = 1
+	_HTCAPTION     = 2
+	_HTLEFT        = 10
+	_HTRIGHT       = 11
+	_HTTOP         = 12
+	_HTTOPLEFT     = 13
+	_HTTOPRIGHT    = 14
+	_HTBOTTOM      = 15
+	_HTBOTTOMLEFT  = 16
+	_HTBOTTOMRIGHT = 17
+)
+
+const (
+	_WMSZ_LEFT        = 1
+	_WMSZ_RIGHT       = 2
+	_WMSZ_TOP         = 3
+	_WMSZ_TOPLEFT     = 4
+	_WMSZ_TOPRIGHT    = 5
+	_WMSZ_BOTTOM      = 6
+	_WMSZ_BOTTOMLEFT  = 7
+	_WMSZ_BOTTOMRIGHT = 8
+)
+
+const (
+	_ICON_SMALL = 0
+	_ICON_BIG   = 1
+
+	_SM_CXICON   = 11
+	_SM_CXFRAME  = 32
+	_SM_CYFRAME  = 33
+	_SM_CXSMICON = 49
+
+	_SM_CXPADDEDBORDER = 92
+)
+
+const (
+	_TBPF_NOPROGRESS = 0
+	_TBPF_NORMAL     = 2
+)
+
+type _TRACKMOUSEEVENT struct {
+	Size      uint32
+	Flags     uint32
+	Track     syscall.Handle
+	HoverTime uint32
+}
+
+const _TME_LEAVE = 0x00000002
+
+type _RAWINPUTDEVICE struct {
+	UsagePage uint16
+	Usage     uint16
+	Flags     uint32
+	Target    syscall.Handle
+}
+
+type _RAWINPUTHEADER struct {
+	Type   uint32
+	Size   uint32
+	Device syscall.Handle
+	WParam uintptr
+}
+
+type _RAWMOUSE struct {
+	Flags            uint16
+	_                uint16
+	ButtonFlags      uint16
+	ButtonData       uint16
+	RawButtons       uint32
+	LastX            int32
+	LastY            int32
+	ExtraInformation uint32
+}
+
+// _RAWINPUT is a RAWINPUT whose data is a RAWMOUSE, the only kind of raw
+// input this package registers for.
+type _RAWINPUT struct {
+	Header _RAWINPUTHEADER
+	Mouse  _RAWMOUSE
+}
+
+const (
+	_HID_USAGE_PAGE_GENERIC  = 0x01
+	_HID_USAGE_GENERIC_MOUSE = 0x02
+
+	_RIDEV_REMOVE = 0x00000001
+
+	_RID_INPUT = 0x10000003
+
+	_RIM_TYPEMOUSE = 0
+
+	_MOUSE_MOVE_ABSOLUTE = 0x01
+)
+
+type _POINTER_INFO struct {
+	PointerType           uint32
+	PointerId             uint32
+	FrameId               uint32
+	PointerFlags          uint32
+	SourceDevice          syscall.Handle
+	HwndTarget            syscall.Handle
+	PtPixelLocation       _POINT
+	PtHimetricLocation    _POINT
+	PtPixelLocationRaw    _POINT
+	PtHimetricLocationRaw _POINT
+	Time                  uint32
+	HistoryCount          uint32
+	InputData             int32
+	KeyStates             uint32
+	PerformanceCount      uint64
+	ButtonChangeType      uint32
+	// The padding makes the struct as big on 386, where Go aligns a uint64
+	// to 4 bytes, as it is in C, which aligns it to 8.
+	_ uint32
+}
+
+type _POINTER_PEN_INFO struct {
+	PointerInfo _POINTER_INFO
+	PenFlags    uint32
+	PenMask     uint32
+	Pressure    uint32
+	Rotation    uint32
+	TiltX       int32
+	TiltY       int32
+}
+
+const (
+	_PT_TOUCH = 2
+	_PT_PEN   = 3
+
+	_POINTER_FLAG_INCONTACT = 0x00000004
+
+	_PEN_FLAG_BARREL   = 0x00000001
+	_PEN_FLAG_INVERTED = 0x00000002
+	_PEN_FLAG_ERASER   = 0x00000004
+
+	_PEN_MASK_PRESSURE = 0x00000001
+	_PEN_MASK_TILT_X   = 0x00000004
+	_PEN_MASK_TILT_Y   = 0x00000008
+
+	// penMaxPressure is the most pressure of a POINTER_PEN_INFO.
+	penMaxPressure = 1024
 )
 
+type _ICONINFO struct {
+	FIcon    int32
+	XHotspot uint32
+	YHotspot uint32
+	HbmMask  syscall.Handle
+	HbmColor syscall.Handle
+}
+
+type _BITMAPINFOHEADER struct {
+	Size          uint32
+	Width         int32
+	Height        int32
+	Planes        uint16
+	BitCount      uint16
+	Compression   uint32
+	SizeImage     uint32
+	XPelsPerMeter int32
+	YPelsPerMeter int32
+	ClrUsed       uint32
+	ClrImportant  uint32
+}
+
 const (
 	_CW_USEDEFAULT = 0x80000000 - 0x100000000
 
+	_SW_MAXIMIZE    = 3
+	_SW_MINIMIZE    = 6
+	_SW_RESTORE     = 9
 	_SW_SHOWDEFAULT = 10
 
 	_HWND_MESSAGE = syscall.Handle(^uintptr(2)) // -3
 
-	_SWP_NOSIZE = 0x0001
+	_HWND_TOP       = syscall.Handle(0)
+	_HWND_BOTTOM    = syscall.Handle(1)
+	_HWND_TOPMOST   = syscall.Handle(^uintptr(0)) // -1
+	_HWND_NOTOPMOST = syscall.Handle(^uintptr(1)) // -2
+
+	_SWP_NOSIZE        = 0x0001
+	_SWP_NOMOVE        = 0x0002
+	_SWP_NOZORDER      = 0x0004
+	_SWP_NOACTIVATE    = 0x0010
+	_SWP_FRAMECHANGED  = 0x0020
+	_SWP_NOOWNERZORDER = 0x0200
+
+	_GWL_STYLE   = -16
+	_GWL_EXSTYLE = -20
+
+	_LWA_ALPHA = 0x00000002
+
+	_WS_POPUP = 0x80000000
+
+	_MONITOR_DEFAULTTONEAREST = 0x00000002
+
+	_DPI_AWARENESS_CONTEXT_PER_MONITOR_AWARE_V2 = ^uintptr(3) // -4
+
+	_USER_DEFAULT_SCREEN_DPI = 96
+)
+
+const (
+	_MONITORINFOF_PRIMARY = 0x00000001
+
+	_ENUM_CURRENT_SETTINGS = 0xffffffff
+
+	_MDT_EFFECTIVE_DPI = 0
+	_MDT_RAW_DPI       = 2
+)
+
+const (
+	_SPI_GETHIGHCONTRAST        = 0x0042
+	_SPI_SETHIGHCONTRAST        = 0x0043
+	_SPI_GETCLIENTAREAANIMATION = 0x1042
+	_SPI_SETCLIENTAREAANIMATION = 0x1043
+	_SPI_SETWORKAREA            = 0x002f
+	_HCF_HIGHCONTRASTON         = 0x00000001
 )
 
 const (
@@ -134,6 +491,95 @@ const (
 	_WHEEL_DELTA = 120
 )
 
+const (
+	_CF_UNICODETEXT = 13
+	_CF_HDROP       = 15
+	_GMEM_MOVEABLE  = 0x0002
+	_GMEM_ZEROINIT  = 0x0040
+)
+
+type _GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+type _FORMATETC struct {
+	CfFormat uint16
+	Ptd      uintptr
+	DwAspect uint32
+	Lindex   int32
+	Tymed    uint32
+}
+
+type _STGMEDIUM struct {
+	Tymed          uint32
+	HGlobal        syscall.Handle
+	PUnkForRelease uintptr
+}
+
+type _DROPFILES struct {
+	PFiles uint32
+	Pt     _POINT
+	FNC    int32
+	FWide  int32
+}
+
+const (
+	_S_OK          = 0
+	_E_NOINTERFACE = 0x80004002
+
+	_DRAGDROP_S_DROP              = 0x00040100
+	_DRAGDROP_S_CANCEL            = 0x00040101
+	_DRAGDROP_S_USEDEFAULTCURSORS = 0x00040102
+	_DROPEFFECT_NONE              = 0
+	_DROPEFFECT_COPY              = 1
+	_DATADIR_GET                  = 1
+	_DVASPECT_CONTENT             = 1
+	_TYMED_HGLOBAL                = 1
+)
+
+type _COMDLG_FILTERSPEC struct {
+	PszName *uint16
+	PszSpec *uint16
+}
+
+const (
+	_CLSCTX_INPROC_SERVER = 1
+
+	_FOS_OVERWRITEPROMPT  = 0x2
+	_FOS_PICKFOLDERS      = 0x20
+	_FOS_FORCEFILESYSTEM  = 0x40
+	_FOS_ALLOWMULTISELECT = 0x200
+
+	_SIGDN_FILESYSPATH = 0x80058000
+
+	_HRESULT_ERROR_CANCELLED = 0x800704c7
+)
+
+type _ACCEL struct {
+	FVirt byte
+	Key   uint16
+	Cmd   uint16
+}
+
+const (
+	_FVIRTKEY = 0x01
+	_FSHIFT   = 0x04
+	_FCONTROL = 0x08
+	_FALT     = 0x10
+
+	_MF_STRING    = 0x0
+	_MF_GRAYED    = 0x1
+	_MF_CHECKED   = 0x8
+	_MF_POPUP     = 0x10
+	_MF_SEPARATOR = 0x800
+
+	_TPM_RIGHTBUTTON = 0x2
+	_TPM_RETURNCMD   = 0x100
+)
+
 func _GET_X_LPARAM(lp uintptr) int32 {
 	return int32(_LOWORD(lp))
 }
@@ -146,6 +592,10 @@ func _GET_WHEEL_DELTA_WPARAM(lp uintptr) int16 {
 	return int16(_HIWORD(lp))
 }
 
+func _GET_XBUTTON_WPARAM(wp uintptr) uint16 {
+	return _HIWORD(wp)
+}
+
 func _LOWORD(l uintptr) uint16 {
 	return uint16(uint32(l))
 }
@@ -163,23 +613,122 @@ func _HIWORD(l uintptr) uint16 {
 //sys	ReleaseDC(hwnd syscall.Handle, dc syscall.Handle) (err error) = user32.ReleaseDC
 //sys	sendMessage(hwnd syscall.Handle, uMsg uint32, wParam uintptr, lParam uintptr) (lResult uintptr) = user32.SendMessageW
 
+//sys	_AppendMenu(menu syscall.Handle, flags uint32, id uintptr, item *uint16) (err error) = user32.AppendMenuW
+//sys	_CloseClipboard() (err error) = user32.CloseClipboard
+//sys	_CreateAcceleratorTable(accels *_ACCEL, n int32) (accel syscall.Handle, err error) = user32.CreateAcceleratorTableW
+//sys	_CreateMenu() (menu syscall.Handle, err error) = user32.CreateMenu
+//sys	_CreatePopupMenu() (menu syscall.Handle, err error) = user32.CreatePopupMenu
 //sys	_CreateWindowEx(exstyle uint32, className *uint16, windowText *uint16, style uint32, x int32, y int32, width int32, height int32, parent syscall.Handle, menu syscall.Handle, hInstance syscall.Handle, lpParam uintptr) (hwnd syscall.Handle, err error) = user32.CreateWindowExW
 //sys	_DefWindowProc(hwnd syscall.Handle, uMsg uint32, wParam uintptr, lParam uintptr) (lResult uintptr) = user32.DefWindowProcW
+//sys	_DestroyAcceleratorTable(accel syscall.Handle) (ok bool) = user32.DestroyAcceleratorTable
+//sys	_DestroyMenu(menu syscall.Handle) (err error) = user32.DestroyMenu
 //sys	_DestroyWindow(hwnd syscall.Handle) (err error) = user32.DestroyWindow
+//sys	_ClipCursor(rect *_RECT) (err error) = user32.ClipCursor
+//sys	_CreateIconIndirect(iconInfo *_ICONINFO) (icon syscall.Handle, err error) = user32.CreateIconIndirect
+//sys	_DestroyIcon(icon syscall.Handle) (err error) = user32.DestroyIcon
 //sys	_DispatchMessage(msg *_MSG) (ret int32) = user32.DispatchMessageW
+//sys	_EmptyClipboard() (err error) = user32.EmptyClipboard
+//sys	_EnableWindow(hwnd syscall.Handle, enable bool) (wasDisabled bool) = user32.EnableWindow
+//sys	_EnumDisplayMonitors(dc syscall.Handle, clip *_RECT, fn uintptr, data uintptr) (err error) = user32.EnumDisplayMonitors
+//sys	_EnumDisplaySettings(deviceName *uint16, modeNum uint32, devMode *_DEVMODE) (err error) = user32.EnumDisplaySettingsW
+//sys	_GetClipboardData(format uint32) (mem syscall.Handle, err error) = user32.GetClipboardData
+//sys	_GetClipboardFormatName(format uint32, name *uint16, size int32) (n int32) = user32.GetClipboardFormatNameW
+//sys	_GetDpiForWindow(hwnd syscall.Handle) (dpi uint32) = user32.GetDpiForWindow
 //sys	_GetClientRect(hwnd syscall.Handle, rect *_RECT) (err error) = user32.GetClientRect
+//sys	_GetCursorPos(pt *_POINT) (err error) = user32.GetCursorPos
+//sys	_GetRawInputData(rawInput syscall.Handle, command uint32, data unsafe.Pointer, size *uint32, headerSize uint32) (ret uint32) = user32.GetRawInputData
+//sys	_GetSystemMetrics(index int32) (value int32) = user32.GetSystemMetrics
 //sys	_GetWindowRect(hwnd syscall.Handle, rect *_RECT) (err error) = user32.GetWindowRect
+//sys	_GetWindowLong(hwnd syscall.Handle, index int32) (value int32) = user32.GetWindowLongW
+//sys	_GetWindowPlacement(hwnd syscall.Handle, wp *_WINDOWPLACEMENT) (err error) = user32.GetWindowPlacement
 //sys   _GetKeyboardLayout(threadID uint32) (locale syscall.Handle) = user32.GetKeyboardLayout
+//sys	_GetKeyboardLayoutName(name *uint16) (err error) = user32.GetKeyboardLayoutNameW
 //sys   _GetKeyboardState(lpKeyState *byte) (err error) = user32.GetKeyboardState
 //sys	_GetKeyState(virtkey int32) (keystatus int16) = user32.GetKeyState
 //sys	_GetMessage(msg *_MSG, hwnd syscall.Handle, msgfiltermin uint32, msgfiltermax uint32) (ret int32, err error) [failretval==-1] = user32.GetMessageW
+//sys	_GetMessageTime() (time int32) = user32.GetMessageTime
+//sys	_GetMonitorInfo(monitor syscall.Handle, mi *_MONITORINFOEX) (err error) = user32.GetMonitorInfoW
+//sys	_GetPointerPenInfo(pointerID uint32, penInfo *_POINTER_PEN_INFO) (err error) = user32.GetPointerPenInfo
+//sys	_GetPointerType(pointerID uint32, pointerType *uint32) (err error) = user32.GetPointerType
+//sys	_IsIconic(hwnd syscall.Handle) (iconic bool) = user32.IsIconic
+//sys	_IsZoomed(hwnd syscall.Handle) (zoomed bool) = user32.IsZoomed
 //sys	_LoadCursor(hInstance syscall.Handle, cursorName uintptr) (cursor syscall.Handle, err error) = user32.LoadCursorW
 //sys	_LoadIcon(hInstance syscall.Handle, iconName uintptr) (icon syscall.Handle, err error) = user32.LoadIconW
+//sys	_MonitorFromWindow(hwnd syscall.Handle, flags uint32) (monitor syscall.Handle) = user32.MonitorFromWindow
 //sys	_MoveWindow(hwnd syscall.Handle, x int32, y int32, w int32, h int32, repaint bool) (err error) = user32.MoveWindow
+//sys	_OpenClipboard(hwnd syscall.Handle) (err error) = user32.OpenClipboard
 //sys	_PostMessage(hwnd syscall.Handle, uMsg uint32, wParam uintptr, lParam uintptr) (lResult bool) = user32.PostMessageW
 //sys   _PostQuitMessage(exitCode int32) = user32.PostQuitMessage
 //sys	_RegisterClass(wc *_WNDCLASS) (atom uint16, err error) = user32.RegisterClassW
+//sys	_RegisterClipboardFormat(name *uint16) (format uint32, err error) = user32.RegisterClipboardFormatW
+//sys	_RegisterHotKey(hwnd syscall.Handle, id int32, modifiers uint32, vk uint32) (err error) = user32.RegisterHotKey
+//sys	_RegisterRawInputDevices(devices *_RAWINPUTDEVICE, numDevices uint32, size uint32) (err error) = user32.RegisterRawInputDevices
+//sys	_RegisterWindowMessage(name *uint16) (uMsg uint32, err error) = user32.RegisterWindowMessageW
+//sys	_SystemParametersInfo(uiAction uint32, uiParam uint32, pvParam unsafe.Pointer, fWinIni uint32) (err error) = user32.SystemParametersInfoW
+//sys	_SetClipboardData(format uint32, mem syscall.Handle) (h syscall.Handle, err error) = user32.SetClipboardData
+//sys	_SetCursor(cursor syscall.Handle) (prev syscall.Handle) = user32.SetCursor
+//sys	_SetForegroundWindow(hwnd syscall.Handle) (ok bool) = user32.SetForegroundWindow
+//sys	_SetLayeredWindowAttributes(hwnd syscall.Handle, key uint32, alpha byte, flags uint32) (err error) = user32.SetLayeredWindowAttributes
+//sys	_SetMenu(hwnd syscall.Handle, menu syscall.Handle) (err error) = user32.SetMenu
+//sys	_SetProcessDpiAwarenessContext(value uintptr) (err error) = user32.SetProcessDpiAwarenessContext
+//sys	_SetWindowLong(hwnd syscall.Handle, index int32, value int32) (prev int32) = user32.SetWindowLongW
+//sys	_SetWindowPlacement(hwnd syscall.Handle, wp *_WINDOWPLACEMENT) (err error) = user32.SetWindowPlacement
+//sys	_SetWindowPos(hwnd syscall.Handle, insertAfter syscall.Handle, x int32, y int32, cx int32, cy int32, flags uint32) (err error) = user32.SetWindowPos
+//sys	_SetWindowRgn(hwnd syscall.Handle, rgn syscall.Handle, redraw bool) (err error) = user32.SetWindowRgn
+//sys	_SetWindowText(hwnd syscall.Handle, text *uint16) (err error) = user32.SetWindowTextW
 //sys	_ShowWindow(hwnd syscall.Handle, cmdshow int32) (wasvisible bool) = user32.ShowWindow
 //sys	_ScreenToClient(hwnd syscall.Handle, lpPoint *_POINT) (ok bool) = user32.ScreenToClient
+//sys	_ClientToScreen(hwnd syscall.Handle, lpPoint *_POINT) (ok bool) = user32.ClientToScreen
 //sys   _ToUnicodeEx(wVirtKey uint32, wScanCode uint32, lpKeyState *byte, pwszBuff *uint16, cchBuff int32, wFlags uint32, dwhkl syscall.Handle) (ret int32) = user32.ToUnicodeEx
+//sys	_TrackMouseEvent(tme *_TRACKMOUSEEVENT) (err error) = user32.TrackMouseEvent
+//sys	_TrackPopupMenu(menu syscall.Handle, flags uint32, x int32, y int32, reserved int32, hwnd syscall.Handle, rect *_RECT) (ret int32) = user32.TrackPopupMenu
+//sys	_TranslateAccelerator(hwnd syscall.Handle, accel syscall.Handle, msg *_MSG) (ret int32) = user32.TranslateAcceleratorW
 //sys	_TranslateMessage(msg *_MSG) (done bool) = user32.TranslateMessage
+//sys	_UnregisterHotKey(hwnd syscall.Handle, id int32) (err error) = user32.UnregisterHotKey
+//sys	_SetWinEventHook(eventMin uint32, eventMax uint32, module syscall.Handle, fn uintptr, process uint32, thread uint32, flags uint32) (hook syscall.Handle) = user32.SetWinEventHook
+//sys	_UnhookWinEvent(hook syscall.Handle) (ok bool) = user32.UnhookWinEvent
+
+//sys	_GlobalAlloc(flags uint32, size uintptr) (mem syscall.Handle, err error) = kernel32.GlobalAlloc
+//sys	_GlobalFree(mem syscall.Handle) (err error) [failretval!=0] = kernel32.GlobalFree
+//sys	_GlobalLock(mem syscall.Handle) (ptr uintptr, err error) = kernel32.GlobalLock
+//sys	_GlobalSize(mem syscall.Handle) (size uintptr) = kernel32.GlobalSize
+//sys	_GlobalUnlock(mem syscall.Handle) = kernel32.GlobalUnlock
+
+//sys	_ImmGetCompositionString(imc syscall.Handle, index uint32, buf unsafe.Pointer, bufLen uint32) (ret int32) = imm32.ImmGetCompositionStringW
+//sys	_ImmGetContext(hwnd syscall.Handle) (imc syscall.Handle) = imm32.ImmGetContext
+//sys	_ImmReleaseContext(hwnd syscall.Handle, imc syscall.Handle) (ok bool) = imm32.ImmReleaseContext
+//sys	_ImmSetCandidateWindow(imc syscall.Handle, form *_CANDIDATEFORM) (ok bool) = imm32.ImmSetCandidateWindow
+//sys	_ImmSetCompositionWindow(imc syscall.Handle, form *_COMPOSITIONFORM) (ok bool) = imm32.ImmSetCompositionWindow
+
+//sys	_GetDpiForMonitor(monitor syscall.Handle, dpiType uint32, dpiX *uint32, dpiY *uint32) (hr int32) = shcore.GetDpiForMonitor
+
+//sys	_DwmFlush() (hr int32) = dwmapi.DwmFlush
+//sys	_DwmGetWindowAttribute(hwnd syscall.Handle, attr uint32, value unsafe.Pointer, size uint32) (hr int32) = dwmapi.DwmGetWindowAttribute
+
+//sys	_CoCreateInstance(clsid *_GUID, outer uintptr, clsContext uint32, iid *_GUID, obj *uintptr) (hr int32) = ole32.CoCreateInstance
+//sys	_CoTaskMemFree(mem uintptr) = ole32.CoTaskMemFree
+//sys	_DoDragDrop(dataObject uintptr, dropSource uintptr, okEffects uint32, effect *uint32) (hr int32) = ole32.DoDragDrop
+//sys	_OleInitialize(reserved uintptr) (hr int32) = ole32.OleInitialize
+//sys	_RegisterDragDrop(hwnd syscall.Handle, dropTarget uintptr) (hr int32) = ole32.RegisterDragDrop
+//sys	_ReleaseStgMedium(medium *_STGMEDIUM) = ole32.ReleaseStgMedium
+//sys	_RevokeDragDrop(hwnd syscall.Handle) (hr int32) = ole32.RevokeDragDrop
+
+//sys	_SafeArrayCreateVector(vt uint16, lbound int32, n uint32) (sa uintptr) = oleaut32.SafeArrayCreateVector
+//sys	_SafeArrayPutElement(sa uintptr, index *int32, v unsafe.Pointer) (hr int32) = oleaut32.SafeArrayPutElement
+//sys	_SysAllocString(s *uint16) (bstr uintptr) = oleaut32.SysAllocString
+
+//sys	_UiaClientsAreListening() (listening bool) = uiautomationcore.UiaClientsAreListening
+//sys	_UiaHostProviderFromHwnd(hwnd syscall.Handle, provider *uintptr) (hr int32) = uiautomationcore.UiaHostProviderFromHwnd
+//sys	_UiaRaiseAutomationEvent(provider uintptr, id int32) (hr int32) = uiautomationcore.UiaRaiseAutomationEvent
+//sys	_UiaReturnRawElementProvider(hwnd syscall.Handle, wParam uintptr, lParam uintptr, provider uintptr) (lResult uintptr) = uiautomationcore.UiaReturnRawElementProvider
+
+//sys	_DragQueryFile(drop syscall.Handle, file uint32, name *uint16, size uint32) (n uint32) = shell32.DragQueryFileW
+//sys	_SHCreateDataObject(folder uintptr, count uint32, items uintptr, inner uintptr, iid *_GUID, obj *uintptr) (hr int32) = shell32.SHCreateDataObject
+//sys	_SHCreateItemFromParsingName(path *uint16, bindCtx uintptr, iid *_GUID, obj *uintptr) (hr int32) = shell32.SHCreateItemFromParsingName
+//sys	_Shell_NotifyIcon(message uint32, data *_NOTIFYICONDATA) (ok bool) = shell32.Shell_NotifyIconW
+
+//sys	_CombineRgn(dst syscall.Handle, src1 syscall.Handle, src2 syscall.Handle, mode int32) (ret int32) = gdi32.CombineRgn
+//sys	_CreateBitmap(width int32, height int32, planes uint32, bitCount uint32, bits unsafe.Pointer) (bitmap syscall.Handle, err error) = gdi32.CreateBitmap
+//sys	_CreateDIBSection(dc syscall.Handle, bmi *_BITMAPINFOHEADER, usage uint32, bits *unsafe.Pointer, section syscall.Handle, offset uint32) (bitmap syscall.Handle, err error) = gdi32.CreateDIBSection
+//sys	_CreateRectRgn(left int32, top int32, right int32, bottom int32) (rgn syscall.Handle, err error) = gdi32.CreateRectRgn
+//sys	_DeleteObject(object syscall.Handle) (err error) = gdi32.DeleteObject
@@ -0,0 +1,252 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"image"
+	"image/color"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/icon"
+)
+
+// The taskbar button's badge and progress are set with ITaskbarList3, which
+// only works once the taskbar has created the window's button, and which
+// forgets them if Explorer restarts. Either way, the taskbar sends the
+// window the registered TaskbarButtonCreated message, upon which they are
+// set again.
+
+var (
+	clsidTaskbarList = _GUID{0x56fdf344, 0xfd6d, 0x11d0, [8]byte{0x95, 0x8a, 0x00, 0x60, 0x97, 0xc9, 0xa0, 0x90}}
+	iidITaskbarList3 = _GUID{0xea1afb91, 0x9e28, 0x4b86, [8]byte{0x90, 0xe9, 0x9e, 0x9f, 0x8a, 0x5e, 0xef, 0xaf}}
+)
+
+// ITaskbarList3 method indexes into the vtable.
+const (
+	methodHrInit           = 3
+	methodSetProgressValue = 9
+	methodSetProgressState = 10
+	methodSetOverlayIcon   = 18
+)
+
+// progressTotal is the total of ITaskbarList3.SetProgressValue, of which the
+// progress is a fraction.
+const progressTotal = 10000
+
+// msgTaskbarButtonCreated is the TaskbarButtonCreated message, registered
+// by Main, or 0 if that failed.
+var msgTaskbarButtonCreated uint32
+
+// taskbarList is the ITaskbarList3, created on the UI thread when first
+// needed, or 0 if it could not be created.
+var (
+	taskbarList     uintptr
+	taskbarListInit bool
+)
+
+// windowTaskbar is a window's icon, and its taskbar button's badge and
+// progress.
+type windowTaskbar struct {
+	bigIcon   syscall.Handle
+	smallIcon syscall.Handle
+	badge     string
+	badgeIcon syscall.Handle
+	// progress is negative for no progress.
+	progress float64
+}
+
+// windowTaskbars holds the windows whose icon, badge or progress has been
+// set. Like the windows, it is only accessed on the thread that runs the
+// message loop.
+var windowTaskbars = map[syscall.Handle]*windowTaskbar{}
+
+// SetIcon sets hwnd's icon, shown in its title bar and on its taskbar
+// button, or restores its window class's icon if m is nil.
+func SetIcon(hwnd syscall.Handle, m image.Image) {
+	SendMessage(hwnd, msgSetIcon, 0, uintptr(unsafe.Pointer(&m)))
+}
+
+// SetBadge sets the badge of hwnd's taskbar button, which is an overlay icon
+// whose description is the label, or removes it if label is empty.
+func SetBadge(hwnd syscall.Handle, label string) {
+	SendMessage(hwnd, msgSetBadge, 0, uintptr(unsafe.Pointer(&label)))
+}
+
+// SetProgress sets the progress shown by hwnd's taskbar button, from 0 to 1,
+// or removes it if progress is negative.
+func SetProgress(hwnd syscall.Handle, progress float64) {
+	SendMessage(hwnd, msgSetProgress, 0, uintptr(unsafe.Pointer(&progress)))
+}
+
+// initTaskbar registers the TaskbarButtonCreated message. It is not an error
+// if that fails, as the badge and progress are then set, if at all, when they
+// change.
+func initTaskbar() {
+	name, err := syscall.UTF16PtrFromString("TaskbarButtonCreated")
+	if err != nil {
+		return
+	}
+	if msgTaskbarButtonCreated, err = _RegisterWindowMessage(name); err == nil {
+		windowMsgs[msgTaskbarButtonCreated] = sendTaskbarButtonCreated
+	}
+}
+
+// getTaskbar returns hwnd's windowTaskbar, adding one if it has none.
+func getTaskbar(hwnd syscall.Handle) *windowTaskbar {
+	wt := windowTaskbars[hwnd]
+	if wt == nil {
+		wt = &windowTaskbar{progress: -1}
+		windowTaskbars[hwnd] = wt
+	}
+	return wt
+}
+
+func sendSetIcon(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	m := *(*image.Image)(Pointer(lParam))
+	wt := getTaskbar(hwnd)
+	var big, small syscall.Handle
+	if m != nil {
+		big, _ = createIcon(icon.Scale(m, int(_GetSystemMetrics(_SM_CXICON))))
+		small, _ = createIcon(icon.Scale(m, int(_GetSystemMetrics(_SM_CXSMICON))))
+	}
+	SendMessage(hwnd, _WM_SETICON, _ICON_BIG, uintptr(big))
+	SendMessage(hwnd, _WM_SETICON, _ICON_SMALL, uintptr(small))
+	destroyIcons(wt.bigIcon, wt.smallIcon)
+	wt.bigIcon, wt.smallIcon = big, small
+	return 0
+}
+
+func sendSetBadge(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	label := *(*string)(Pointer(lParam))
+	wt := getTaskbar(hwnd)
+	if label != "" && wt.badgeIcon == 0 {
+		wt.badgeIcon, _ = createIcon(badgeImage(int(_GetSystemMetrics(_SM_CXSMICON))))
+	}
+	wt.badge = label
+	wt.updateBadge(hwnd)
+	if label == "" {
+		destroyIcons(wt.badgeIcon)
+		wt.badgeIcon = 0
+	}
+	return 0
+}
+
+func sendSetProgress(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	progress := *(*float64)(Pointer(lParam))
+	if progress > 1 {
+		progress = 1
+	}
+	wt := getTaskbar(hwnd)
+	wt.progress = progress
+	wt.updateProgress(hwnd)
+	return 0
+}
+
+// sendTaskbarButtonCreated handles the TaskbarButtonCreated message, setting
+// the badge and progress of the new taskbar button.
+func sendTaskbarButtonCreated(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	if wt := windowTaskbars[hwnd]; wt != nil {
+		wt.updateBadge(hwnd)
+		wt.updateProgress(hwnd)
+	}
+	return 0
+}
+
+// getTaskbarList returns the ITaskbarList3, or 0 if there is none.
+func getTaskbarList() uintptr {
+	if taskbarListInit {
+		return taskbarList
+	}
+	taskbarListInit = true
+	if !oleInitialized {
+		return 0
+	}
+	var obj uintptr
+	if _CoCreateInstance(&clsidTaskbarList, 0, _CLSCTX_INPROC_SERVER, &iidITaskbarList3, &obj) < 0 {
+		return 0
+	}
+	if int32(comCall(obj, methodHrInit)) < 0 {
+		comCall(obj, methodRelease)
+		return 0
+	}
+	taskbarList = obj
+	return taskbarList
+}
+
+func (wt *windowTaskbar) updateBadge(hwnd syscall.Handle) {
+	tl := getTaskbarList()
+	if tl == 0 {
+		return
+	}
+	if wt.badge == "" {
+		comCall(tl, methodSetOverlayIcon, uintptr(hwnd), 0, 0)
+		return
+	}
+	desc, err := syscall.UTF16PtrFromString(wt.badge)
+	if err != nil {
+		return
+	}
+	comCall(tl, methodSetOverlayIcon, uintptr(hwnd), uintptr(wt.badgeIcon), uintptr(unsafe.Pointer(desc)))
+}
+
+func (wt *windowTaskbar) updateProgress(hwnd syscall.Handle) {
+	tl := getTaskbarList()
+	if tl == 0 {
+		return
+	}
+	if wt.progress < 0 {
+		comCall(tl, methodSetProgressState, uintptr(hwnd), _TBPF_NOPROGRESS)
+		return
+	}
+	comCall(tl, methodSetProgressState, uintptr(hwnd), _TBPF_NORMAL)
+	args := []uintptr{uintptr(hwnd)}
+	args = appendUint64(args, uint64(wt.progress*progressTotal))
+	args = appendUint64(args, progressTotal)
+	comCall(tl, methodSetProgressValue, args...)
+}
+
+// appendUint64 appends x, as the arguments of a ULONGLONG parameter, which is
+// two arguments, low word first, on 32-bit Windows.
+func appendUint64(args []uintptr, x uint64) []uintptr {
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		return append(args, uintptr(x))
+	}
+	return append(args, uintptr(uint32(x)), uintptr(x>>32))
+}
+
+// badgeImage returns the badge's overlay icon, a red dot, size pixels square.
+func badgeImage(size int) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, size, size))
+	r := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)+0.5-r, float64(y)+0.5-r
+			if dx*dx+dy*dy <= r*r {
+				m.SetRGBA(x, y, color.RGBA{0xe0, 0x20, 0x20, 0xff})
+			}
+		}
+	}
+	return m
+}
+
+func destroyIcons(icons ...syscall.Handle) {
+	for _, h := range icons {
+		if h != 0 {
+			_DestroyIcon(h)
+		}
+	}
+}
+
+// releaseTaskbar forgets hwnd's icon, badge and progress, when it is
+// destroyed.
+func releaseTaskbar(hwnd syscall.Handle) {
+	if wt := windowTaskbars[hwnd]; wt != nil {
+		destroyIcons(wt.bigIcon, wt.smallIcon, wt.badgeIcon)
+	}
+	delete(windowTaskbars, hwnd)
+}
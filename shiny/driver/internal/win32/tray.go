@@ -0,0 +1,375 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/icon"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/mouse"
+)
+
+// Tray icons are notification area icons, added by Shell_NotifyIcon, whose
+// notifications are sent to the tray window, a hidden top-level window of the
+// screen window's class. Unlike the message-only screen window, it is sent
+// the TaskbarCreated message when Explorer restarts, upon which the icons are
+// added again, and it can be the foreground window while a tray icon's menu
+// is shown, so that clicking elsewhere closes the menu.
+
+type _NOTIFYICONDATA struct {
+	CbSize           uint32
+	HWnd             syscall.Handle
+	UID              uint32
+	UFlags           uint32
+	UCallbackMessage uint32
+	HIcon            syscall.Handle
+	SzTip            [128]uint16
+	DwState          uint32
+	DwStateMask      uint32
+	SzInfo           [256]uint16
+	UVersion         uint32
+	SzInfoTitle      [64]uint16
+	DwInfoFlags      uint32
+	GuidItem         _GUID
+	HBalloonIcon     syscall.Handle
+}
+
+const (
+	_NIM_ADD    = 0
+	_NIM_MODIFY = 1
+	_NIM_DELETE = 2
+
+	_NIF_MESSAGE = 0x1
+	_NIF_ICON    = 0x2
+	_NIF_TIP     = 0x4
+
+	_WM_NULL = 0
+)
+
+// TrayIcon implements the screen.TrayIcon interface with a notification area
+// icon.
+type TrayIcon struct {
+	event.Deque
+
+	id uint32
+
+	mu       sync.Mutex
+	released bool
+}
+
+// trayIcon is a TrayIcon's notification area icon, and its menu, if any.
+// ids[i] is the screen.MenuItem.ID of command i+1.
+type trayIcon struct {
+	t       *TrayIcon
+	icon    syscall.Handle
+	tooltip string
+	menu    syscall.Handle
+	ids     []int
+
+	// shown is the menu that is being shown, if any, which the menu's modal
+	// loop destroys, if it was replaced, once it closes.
+	shown syscall.Handle
+}
+
+var (
+	// trayHWND is the tray window, created with the first tray icon.
+	trayHWND syscall.Handle
+
+	// msgTaskbarCreated is the TaskbarCreated message, registered by Main,
+	// or 0 if that failed.
+	msgTaskbarCreated uint32
+
+	// trayIcons holds the unreleased tray icons, by their IDs, which start
+	// at 1, after the notification icon's. Like the windows, it is only
+	// accessed on the thread that runs the message loop.
+	trayIcons  = map[uint32]*trayIcon{}
+	nextTrayID uint32
+)
+
+// initTray registers the TaskbarCreated message. It is not an error if that
+// fails, as the tray icons are then only lost if Explorer restarts.
+func initTray() {
+	name, err := syscall.UTF16PtrFromString("TaskbarCreated")
+	if err != nil {
+		return
+	}
+	if msgTaskbarCreated, err = _RegisterWindowMessage(name); err == nil {
+		screenMsgs[msgTaskbarCreated] = sendTaskbarCreated
+	}
+}
+
+// Which fields of updateTrayParams to update.
+const (
+	updateTrayIcon = 1 << iota
+	updateTrayTooltip
+	updateTrayMenu
+)
+
+type updateTrayParams struct {
+	t       *TrayIcon
+	fields  int
+	icon    image.Image
+	tooltip string
+	menu    *screen.Menu
+	err     error
+}
+
+// NewTrayIcon adds a new notification area icon.
+func NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	if opts == nil {
+		opts = &screen.NewTrayIconOptions{}
+	}
+	p := updateTrayParams{
+		t:       &TrayIcon{},
+		fields:  updateTrayIcon | updateTrayTooltip | updateTrayMenu,
+		icon:    opts.Icon,
+		tooltip: opts.Tooltip,
+		menu:    opts.Menu,
+	}
+	SendScreenMessage(msgNewTrayIcon, 0, uintptr(unsafe.Pointer(&p)))
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.t, nil
+}
+
+func sendNewTrayIcon(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	p := (*updateTrayParams)(Pointer(lParam))
+	if trayHWND == 0 {
+		if p.err = initTrayWindow(); p.err != nil {
+			return 0
+		}
+	}
+	nextTrayID++
+	p.t.id = nextTrayID
+	ti := &trayIcon{t: p.t}
+	if p.err = ti.update(p); p.err != nil {
+		return 0
+	}
+	if !ti.notify(_NIM_ADD) {
+		ti.release()
+		p.err = errors.New("win32: Shell_NotifyIcon failed")
+		return 0
+	}
+	trayIcons[p.t.id] = ti
+	return 0
+}
+
+func initTrayWindow() (err error) {
+	swc, err := syscall.UTF16PtrFromString(screenWindowClass)
+	if err != nil {
+		return err
+	}
+	emptyString, err := syscall.UTF16PtrFromString("")
+	if err != nil {
+		return err
+	}
+	trayHWND, err = _CreateWindowEx(0,
+		swc, emptyString,
+		_WS_OVERLAPPED,
+		_CW_USEDEFAULT, _CW_USEDEFAULT,
+		_CW_USEDEFAULT, _CW_USEDEFAULT,
+		0, 0, hThisInstance, 0)
+	if err != nil {
+		return fmt.Errorf("win32: CreateWindowEx failed: %v", err)
+	}
+	return nil
+}
+
+func (t *TrayIcon) Release() {
+	t.mu.Lock()
+	released := t.released
+	t.released = true
+	t.mu.Unlock()
+	if !released {
+		SendScreenMessage(msgReleaseTrayIcon, uintptr(t.id), 0)
+	}
+}
+
+func sendReleaseTrayIcon(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	if ti := trayIcons[uint32(wParam)]; ti != nil {
+		ti.notify(_NIM_DELETE)
+		ti.release()
+		delete(trayIcons, uint32(wParam))
+	}
+	return 0
+}
+
+// releaseTrayIcons removes every tray icon, when Main returns, as Explorer
+// would otherwise show them until the pointer hovers over them.
+func releaseTrayIcons() {
+	closeNotification()
+	for id, ti := range trayIcons {
+		ti.notify(_NIM_DELETE)
+		ti.release()
+		delete(trayIcons, id)
+	}
+	if trayHWND != 0 {
+		_DestroyWindow(trayHWND)
+		trayHWND = 0
+	}
+}
+
+func (t *TrayIcon) SetIcon(m image.Image) {
+	t.update(&updateTrayParams{fields: updateTrayIcon, icon: m})
+}
+
+func (t *TrayIcon) SetTooltip(tooltip string) {
+	t.update(&updateTrayParams{fields: updateTrayTooltip, tooltip: tooltip})
+}
+
+func (t *TrayIcon) SetMenu(m *screen.Menu) error {
+	return t.update(&updateTrayParams{fields: updateTrayMenu, menu: m})
+}
+
+func (t *TrayIcon) update(p *updateTrayParams) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.released {
+		return errors.New("win32: tray icon is released")
+	}
+	p.t = t
+	SendScreenMessage(msgUpdateTrayIcon, 0, uintptr(unsafe.Pointer(p)))
+	return p.err
+}
+
+func sendUpdateTrayIcon(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	p := (*updateTrayParams)(Pointer(lParam))
+	ti := trayIcons[p.t.id]
+	if ti == nil {
+		return 0
+	}
+	if p.err = ti.update(p); p.err == nil {
+		ti.notify(_NIM_MODIFY)
+	}
+	return 0
+}
+
+// update updates the fields of ti that p says to.
+func (ti *trayIcon) update(p *updateTrayParams) error {
+	if p.fields&updateTrayMenu != 0 {
+		var menu syscall.Handle
+		var ids []int
+		if p.menu != nil {
+			var b menuBuilder
+			var err error
+			if menu, err = b.newMenu(p.menu, true); err != nil {
+				return err
+			}
+			ids = b.ids
+		}
+		ti.destroyMenu()
+		ti.menu, ti.ids = menu, ids
+	}
+	if p.fields&updateTrayIcon != 0 {
+		var h syscall.Handle
+		if p.icon != nil {
+			h, _ = createIcon(icon.Scale(p.icon, int(_GetSystemMetrics(_SM_CXSMICON))))
+		}
+		destroyIcons(ti.icon)
+		ti.icon = h
+	}
+	if p.fields&updateTrayTooltip != 0 {
+		ti.tooltip = p.tooltip
+	}
+	return nil
+}
+
+// notify calls Shell_NotifyIcon with the given message, and ti's icon and
+// tooltip.
+func (ti *trayIcon) notify(message uint32) bool {
+	d := _NOTIFYICONDATA{
+		CbSize:           uint32(unsafe.Sizeof(_NOTIFYICONDATA{})),
+		HWnd:             trayHWND,
+		UID:              ti.t.id,
+		UFlags:           _NIF_MESSAGE | _NIF_ICON | _NIF_TIP,
+		UCallbackMessage: msgTrayNotify,
+		HIcon:            ti.icon,
+	}
+	if tip, err := syscall.UTF16FromString(ti.tooltip); err == nil {
+		// The tooltip is truncated to fit, leaving its NUL terminator.
+		copy(d.SzTip[:len(d.SzTip)-1], tip)
+	}
+	return _Shell_NotifyIcon(message, &d)
+}
+
+func (ti *trayIcon) release() {
+	destroyIcons(ti.icon)
+	ti.destroyMenu()
+}
+
+// destroyMenu destroys ti's menu, unless it is being shown.
+func (ti *trayIcon) destroyMenu() {
+	if ti.menu != 0 && ti.menu != ti.shown {
+		_DestroyMenu(ti.menu)
+	}
+}
+
+// sendTaskbarCreated adds the tray icons again, when Explorer restarts, and
+// forgets the notification.
+func sendTaskbarCreated(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	// The notification's balloon is gone.
+	destroyIcons(notification.balloonIcon)
+	notification.hwnd, notification.id, notification.balloonIcon = 0, 0, 0
+	for _, ti := range trayIcons {
+		ti.notify(_NIM_ADD)
+	}
+	return 0
+}
+
+// sendTrayNotify handles a tray icon's notification, whose wParam is the
+// icon's ID, and whose lParam is the mouse message.
+func sendTrayNotify(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	if wParam == notificationIconID {
+		sendNotificationIconNotify(lParam)
+		return 0
+	}
+	ti := trayIcons[uint32(wParam)]
+	if ti == nil {
+		return 0
+	}
+	switch lParam {
+	case _WM_LBUTTONUP:
+		ti.t.Send(screen.TrayEvent{Button: mouse.ButtonLeft})
+	case _WM_MBUTTONUP:
+		ti.t.Send(screen.TrayEvent{Button: mouse.ButtonMiddle})
+	case _WM_RBUTTONUP:
+		if ti.menu == 0 {
+			ti.t.Send(screen.TrayEvent{Button: mouse.ButtonRight})
+			break
+		}
+		var pt _POINT
+		if _GetCursorPos(&pt) != nil {
+			break
+		}
+		// The menu closes when the tray window stops being the foreground
+		// window, and the message posted after it is the documented way to
+		// make it close the next time too.
+		menu, ids := ti.menu, ti.ids
+		ti.shown = menu
+		_SetForegroundWindow(hwnd)
+		cmd := int(_TrackPopupMenu(menu, _TPM_RETURNCMD|_TPM_RIGHTBUTTON, pt.X, pt.Y, 0, hwnd, nil))
+		_PostMessage(hwnd, _WM_NULL, 0, 0)
+		ti.shown = 0
+		// The menu's modal loop dispatches messages, which may have
+		// replaced the menu or released the icon.
+		if trayIcons[uint32(wParam)] != ti || ti.menu != menu {
+			_DestroyMenu(menu)
+		}
+		if 0 < cmd && cmd <= len(ids) {
+			ti.t.Send(screen.MenuEvent{ID: ids[cmd-1]})
+		}
+	}
+	return 0
+}
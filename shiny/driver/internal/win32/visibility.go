@@ -0,0 +1,89 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package win32
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+const (
+	_DWMWA_CLOAKED = 14
+
+	_EVENT_OBJECT_CLOAKED   = 0x8017
+	_EVENT_OBJECT_UNCLOAKED = 0x8018
+
+	_OBJID_WINDOW = 0
+
+	_WINEVENT_OUTOFCONTEXT = 0
+)
+
+// windowVisibilities holds each window's visibility as of its last
+// screen.VisibilityEvent, or none for a fully visible window. Like the
+// windows, it is only accessed on the thread that runs the message loop.
+var windowVisibilities = map[syscall.Handle]screen.Visibility{}
+
+// updateVisibility sends a screen.VisibilityEvent if hwnd's visibility
+// changed. Windows does not say whether other windows cover a window, and so
+// a window is only occluded when the Desktop Window Manager cloaks it, such
+// as when it is on another virtual desktop.
+func updateVisibility(hwnd syscall.Handle) {
+	v := screen.VisibilityFull
+	switch {
+	case _IsIconic(hwnd):
+		v = screen.VisibilityMinimized
+	case isCloaked(hwnd):
+		v = screen.VisibilityOccluded
+	}
+	if v == windowVisibilities[hwnd] {
+		return
+	}
+	if v == screen.VisibilityFull {
+		delete(windowVisibilities, hwnd)
+	} else {
+		windowVisibilities[hwnd] = v
+	}
+	VisibilityEvent(hwnd, screen.VisibilityEvent{Visibility: v})
+}
+
+// isCloaked returns whether the Desktop Window Manager has cloaked hwnd, so
+// that it is not shown, even though it is visible and not minimized. Before
+// Windows 8, windows are never cloaked.
+func isCloaked(hwnd syscall.Handle) bool {
+	if procDwmGetWindowAttribute.Find() != nil {
+		return false
+	}
+	var cloaked uint32
+	hr := _DwmGetWindowAttribute(hwnd, _DWMWA_CLOAKED, unsafe.Pointer(&cloaked), uint32(unsafe.Sizeof(cloaked)))
+	return hr >= 0 && cloaked != 0
+}
+
+// initCloakHook watches for the cloaking and uncloaking of the process's
+// windows, which send no window messages, only WinEvents. The out-of-context
+// hook's callback is called by the message loop of the thread that set it.
+// It returns zero if the hook cannot be set.
+func initCloakHook() syscall.Handle {
+	if procSetWinEventHook.Find() != nil {
+		return 0
+	}
+	return _SetWinEventHook(_EVENT_OBJECT_CLOAKED, _EVENT_OBJECT_UNCLOAKED, 0,
+		syscall.NewCallback(cloakHook), uint32(syscall.Getpid()), 0, _WINEVENT_OUTOFCONTEXT)
+}
+
+func cloakHook(hook, event, hwnd, idObject, idChild, thread, time uintptr) uintptr {
+	if int32(idObject) != _OBJID_WINDOW {
+		return 0
+	}
+	// Only the windows that have been sized, and so have a state, are the
+	// driver's windows, rather than the screen or tray windows.
+	if _, ok := windowStates[syscall.Handle(hwnd)]; ok {
+		updateVisibility(syscall.Handle(hwnd))
+	}
+	return 0
+}
@@ -11,18 +11,24 @@ package win32 // import "golang.org/x/exp/shiny/driver/internal/win32"
 
 import (
 	"fmt"
+	"image"
 	"runtime"
 	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 
+	"golang.org/x/exp/shiny/driver/internal/event"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/mobile/event/key"
 	"golang.org/x/mobile/event/lifecycle"
 	"golang.org/x/mobile/event/mouse"
 	"golang.org/x/mobile/event/paint"
 	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/event/touch"
 	"golang.org/x/mobile/geom"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
 )
 
 // screenHWND is the handle to the "Screen window".
@@ -36,6 +42,38 @@ const (
 	msgCreateWindow = _WM_USER + iota
 	msgMainCallback
 	msgShow
+	msgRelease
+	msgEnableOwner
+	msgSetTextInputRect
+	msgSetCursor
+	msgSetCursorVisible
+	msgSetPointerCapture
+	msgStartDrag
+	msgStartMoveResize
+	msgDoDragDrop
+	msgSetWindowState
+	msgSetWindowOrder
+	msgSetOpacity
+	msgSetConstraints
+	msgSetAspectRatio
+	msgShowFileDialog
+	msgRunFileDialog
+	msgSetMenuBar
+	msgShowContextMenu
+	msgTrackContextMenu
+	msgSetIcon
+	msgSetBadge
+	msgSetProgress
+	msgSetAccessTree
+	msgNewTrayIcon
+	msgUpdateTrayIcon
+	msgReleaseTrayIcon
+	msgTrayNotify
+	msgRegisterHotKey
+	msgUnregisterHotKey
+	msgReleaseHotKeys
+	msgNotify
+	msgCloseNotification
 	msgQuit
 	msgLast
 )
@@ -60,7 +98,7 @@ func (m *userWM) next() uint32 {
 
 var currentUserWM userWM
 
-func newWindow(opts *screen.NewWindowOptions) (syscall.Handle, error) {
+func newWindow(opts *screen.NewWindowOptions, owner syscall.Handle) (syscall.Handle, error) {
 	// TODO(brainman): convert windowClass to *uint16 once (in initWindowClass)
 	wcname, err := syscall.UTF16PtrFromString(windowClass)
 	if err != nil {
@@ -70,18 +108,48 @@ func newWindow(opts *screen.NewWindowOptions) (syscall.Handle, error) {
 	if err != nil {
 		return 0, err
 	}
-	hwnd, err := _CreateWindowEx(0,
+	style := uint32(_WS_OVERLAPPEDWINDOW)
+	if opts != nil && opts.Undecorated {
+		// The sizing border is kept, so that the window can be resized and
+		// snapped to the screen's edges, but sendNCCalcSize gives it no
+		// width.
+		style = _WS_POPUP | _WS_THICKFRAME | _WS_SYSMENU | _WS_MINIMIZEBOX | _WS_MAXIMIZEBOX
+	}
+	if opts != nil && opts.FixedSize {
+		style &^= _WS_THICKFRAME | _WS_MAXIMIZEBOX
+	}
+	x, y := int32(_CW_USEDEFAULT), int32(_CW_USEDEFAULT)
+	if opts != nil && opts.Position != nil {
+		x, y = int32(opts.Position.X), int32(opts.Position.Y)
+	}
+	exstyle := uint32(0)
+	if opts != nil && opts.Transparent {
+		// A layered window's contents are set by UpdateLayeredWindow, with
+		// per-pixel alpha, instead of by painting.
+		exstyle |= _WS_EX_LAYERED
+	}
+	hwnd, err := _CreateWindowEx(exstyle,
 		wcname, title,
-		_WS_OVERLAPPEDWINDOW,
-		_CW_USEDEFAULT, _CW_USEDEFAULT,
+		style,
+		x, y,
 		_CW_USEDEFAULT, _CW_USEDEFAULT,
-		0, 0, hThisInstance, 0)
+		owner, 0, hThisInstance, 0)
 	if err != nil {
 		return 0, err
 	}
 	// TODO(andlabs): use proper nCmdShow
 	// TODO(andlabs): call UpdateWindow()
 
+	registerDropTarget(hwnd)
+	if opts != nil && opts.InterceptClose {
+		interceptClose[hwnd] = true
+	}
+	if opts != nil && opts.Undecorated {
+		undecorated[hwnd] = true
+	}
+	if owner != 0 && opts.Modal {
+		disableOwner(hwnd, owner)
+	}
 	return hwnd, nil
 }
 
@@ -90,6 +158,11 @@ func ResizeClientRect(hwnd syscall.Handle, opts *screen.NewWindowOptions) error
 	if opts == nil || opts.Width <= 0 || opts.Height <= 0 {
 		return nil
 	}
+	return SetSize(hwnd, opts.Width, opts.Height)
+}
+
+// SetSize makes hwnd client rectangle width by height in size.
+func SetSize(hwnd syscall.Handle, width, height int) error {
 	var cr, wr _RECT
 	err := _GetClientRect(hwnd, &cr)
 	if err != nil {
@@ -99,35 +172,140 @@ func ResizeClientRect(hwnd syscall.Handle, opts *screen.NewWindowOptions) error
 	if err != nil {
 		return err
 	}
-	w := (wr.Right - wr.Left) - (cr.Right - int32(opts.Width))
-	h := (wr.Bottom - wr.Top) - (cr.Bottom - int32(opts.Height))
+	w := (wr.Right - wr.Left) - (cr.Right - int32(width))
+	h := (wr.Bottom - wr.Top) - (cr.Bottom - int32(height))
 	return _MoveWindow(hwnd, wr.Left, wr.Top, w, h, false)
 }
 
+// SetShape sets hwnd's window region to the union of rs, in client area
+// co-ordinates, or removes its region if rs is empty. Outside its region,
+// a window is neither drawn nor hit by the mouse.
+func SetShape(hwnd syscall.Handle, rs []image.Rectangle) error {
+	if len(rs) == 0 {
+		return _SetWindowRgn(hwnd, 0, true)
+	}
+	// A window region is relative to the top-left of the window's frame,
+	// not of its client area.
+	var wr _RECT
+	if err := _GetWindowRect(hwnd, &wr); err != nil {
+		return err
+	}
+	var origin _POINT
+	_ClientToScreen(hwnd, &origin)
+	dx, dy := int(origin.X-wr.Left), int(origin.Y-wr.Top)
+
+	rgn, err := _CreateRectRgn(0, 0, 0, 0)
+	if err != nil {
+		return err
+	}
+	for _, r := range rs {
+		r = r.Add(image.Point{dx, dy})
+		rrgn, err := _CreateRectRgn(int32(r.Min.X), int32(r.Min.Y), int32(r.Max.X), int32(r.Max.Y))
+		if err != nil {
+			_DeleteObject(rgn)
+			return err
+		}
+		_CombineRgn(rgn, rgn, rrgn, _RGN_OR)
+		_DeleteObject(rrgn)
+	}
+	// The system owns the region once SetWindowRgn succeeds.
+	if err := _SetWindowRgn(hwnd, rgn, true); err != nil {
+		_DeleteObject(rgn)
+		return err
+	}
+	return nil
+}
+
+// SetPosition moves hwnd so that the top-left corner of its frame is at p,
+// keeping its size.
+func SetPosition(hwnd syscall.Handle, p image.Point) error {
+	var wr _RECT
+	if err := _GetWindowRect(hwnd, &wr); err != nil {
+		return err
+	}
+	return _MoveWindow(hwnd, int32(p.X), int32(p.Y), wr.Right-wr.Left, wr.Bottom-wr.Top, false)
+}
+
+// Geometry returns the top-left corner of hwnd's frame, in screen
+// co-ordinates, and the size of its client rectangle.
+func Geometry(hwnd syscall.Handle) (position, size image.Point, err error) {
+	var cr, wr _RECT
+	if err := _GetClientRect(hwnd, &cr); err != nil {
+		return image.Point{}, image.Point{}, err
+	}
+	if err := _GetWindowRect(hwnd, &wr); err != nil {
+		return image.Point{}, image.Point{}, err
+	}
+	return image.Point{int(wr.Left), int(wr.Top)}, image.Point{int(cr.Right - cr.Left), int(cr.Bottom - cr.Top)}, nil
+}
+
+// SetTitle sets hwnd's title.
+func SetTitle(hwnd syscall.Handle, title string) error {
+	t, err := syscall.UTF16PtrFromString(title)
+	if err != nil {
+		return err
+	}
+	return _SetWindowText(hwnd, t)
+}
+
 // Show shows a newly created window.
 // It sends the appropriate lifecycle events, makes the window appear
 // on the screen, and sends an initial size event.
+// If opts.Hidden is set, the window does not appear, and only the
+// size event is sent.
 //
 // This is a separate step from NewWindow to give the driver a chance
 // to setup its internal state for a window before events start being
 // delivered.
-func Show(hwnd syscall.Handle) {
-	SendMessage(hwnd, msgShow, 0, 0)
+func Show(hwnd syscall.Handle, opts *screen.NewWindowOptions) {
+	hidden := uintptr(0)
+	if opts != nil && opts.Hidden {
+		hidden = 1
+	}
+	SendMessage(hwnd, msgShow, hidden, 0)
 }
 
+// Release destroys the window. DestroyWindow must be called on the thread
+// that created the window, so this is done by the window's message handler.
 func Release(hwnd syscall.Handle) {
+	SendMessage(hwnd, msgRelease, 0, 0)
+}
+
+func sendRelease(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	revokeDropTarget(hwnd)
+	releaseHotKeys(hwnd)
+	releaseNotification(hwnd)
+	// The owner of a modal window is enabled before the window is
+	// destroyed, so that Windows activates it instead of another app's
+	// window.
+	enableOwner(hwnd)
 	// TODO(andlabs): check for errors from this?
-	// TODO(andlabs): remove unsafe
 	_DestroyWindow(hwnd)
-	// TODO(andlabs): what happens if we're still painting?
+	delete(windowDPI, hwnd)
+	releasePointerCapture(hwnd)
+	releaseCursor(hwnd)
+	if hwnd == trackedWindow {
+		trackedWindow = 0
+	}
+	releaseWindowState(hwnd)
+	delete(interceptClose, hwnd)
+	delete(undecorated, hwnd)
+	delete(windowConstraints, hwnd)
+	releaseMenuBar(hwnd)
+	releaseTaskbar(hwnd)
+	releaseAccessTree(hwnd)
+	return 0
 }
 
 func sendFocus(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
 	switch uMsg {
 	case _WM_SETFOCUS:
 		LifecycleEvent(hwnd, lifecycle.StageFocused)
+		FocusEvent(hwnd, screen.FocusEvent{Focused: true})
 	case _WM_KILLFOCUS:
+		releasePointerCapture(hwnd)
 		LifecycleEvent(hwnd, lifecycle.StageVisible)
+		FocusEvent(hwnd, screen.FocusEvent{Focused: false})
 	default:
 		panic(fmt.Sprintf("unexpected focus message: %d", uMsg))
 	}
@@ -135,14 +313,16 @@ func sendFocus(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResul
 }
 
 func sendShow(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
-	LifecycleEvent(hwnd, lifecycle.StageVisible)
-	_ShowWindow(hwnd, _SW_SHOWDEFAULT)
+	if hidden := wParam != 0; !hidden {
+		LifecycleEvent(hwnd, lifecycle.StageVisible)
+		_ShowWindow(hwnd, _SW_SHOWDEFAULT)
+	}
 	sendSize(hwnd)
 	return 0
 }
 
 func sendSizeEvent(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
-	wp := (*_WINDOWPOS)(unsafe.Pointer(lParam))
+	wp := (*_WINDOWPOS)(Pointer(lParam))
 	if wp.Flags&_SWP_NOSIZE != 0 {
 		return 0
 	}
@@ -151,6 +331,8 @@ func sendSizeEvent(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lR
 }
 
 func sendSize(hwnd syscall.Handle) {
+	updateWindowState(hwnd)
+
 	var r _RECT
 	if err := _GetClientRect(hwnd, &r); err != nil {
 		panic(err) // TODO(andlabs)
@@ -159,22 +341,128 @@ func sendSize(hwnd syscall.Handle) {
 	width := int(r.Right - r.Left)
 	height := int(r.Bottom - r.Top)
 
-	// TODO(andlabs): don't assume that PixelsPerPt == 1
+	const ptPerInch = 72
+	dpi := dpiForWindow(hwnd)
+	ppp := float32(dpi) / ptPerInch
+	if old, ok := windowDPI[hwnd]; ok && old != dpi {
+		ScaleEvent(hwnd, screen.ScaleEvent{
+			PixelsPerPt: ppp,
+			Scale:       float64(dpi) / _USER_DEFAULT_SCREEN_DPI,
+		})
+	}
+	windowDPI[hwnd] = dpi
 	SizeEvent(hwnd, size.Event{
 		WidthPx:     width,
 		HeightPx:    height,
-		WidthPt:     geom.Pt(width),
-		HeightPt:    geom.Pt(height),
-		PixelsPerPt: 1,
+		WidthPt:     geom.Pt(float32(width) / ppp),
+		HeightPt:    geom.Pt(float32(height) / ppp),
+		PixelsPerPt: ppp,
 	})
 }
 
+// windowDPI holds the resolution of each window's last size event, so that a
+// change can be sent as a screen.ScaleEvent. Like the windows, it is only
+// accessed on the thread that runs the message loop.
+var windowDPI = map[syscall.Handle]uint32{}
+
+// dpiForWindow returns the resolution of the monitor that hwnd is on. Before
+// Windows 10, the process is not per-monitor DPI aware, and the system
+// scales the window's contents instead.
+func dpiForWindow(hwnd syscall.Handle) uint32 {
+	if procGetDpiForWindow.Find() != nil {
+		return _USER_DEFAULT_SCREEN_DPI
+	}
+	if dpi := _GetDpiForWindow(hwnd); dpi != 0 {
+		return dpi
+	}
+	return _USER_DEFAULT_SCREEN_DPI
+}
+
+// sendDPIChanged resizes the window to the rectangle suggested by the system
+// when the window moves to a monitor with a different resolution. The
+// resulting WM_WINDOWPOSCHANGED message sends the size event with the new
+// scale, unless the window's size in pixels is unchanged, in which case the
+// size event is sent here.
+func sendDPIChanged(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	r := (*_RECT)(Pointer(lParam))
+	_MoveWindow(hwnd, r.Left, r.Top, r.Right-r.Left, r.Bottom-r.Top, true)
+	if windowDPI[hwnd] != dpiForWindow(hwnd) {
+		sendSize(hwnd)
+	}
+	return 0
+}
+
+// interceptClose holds the windows created with
+// NewWindowOptions.InterceptClose, whose WM_CLOSE messages send a
+// screen.CloseRequestEvent. Like the windows, it is only accessed on the
+// thread that runs the message loop.
+var interceptClose = map[syscall.Handle]bool{}
+
+// sendClose handles WM_CLOSE, which is sent when the user asks to close the
+// window. The window is not destroyed, as DefWindowProc would, until the
+// driver calls Release.
 func sendClose(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	if interceptClose[hwnd] {
+		CloseRequestEvent(hwnd, screen.CloseRequestEvent{})
+		return 0
+	}
 	LifecycleEvent(hwnd, lifecycle.StageDead)
 	return 0
 }
 
+// wheelDeltas are the WM_MOUSEWHEEL and WM_MOUSEHWHEEL deltas, of
+// wheelWindow, that do not yet make a whole step. They are only accessed on
+// the thread that runs the message loop.
+var (
+	wheelWindow syscall.Handle
+	wheelDeltas [2]int32
+)
+
+// trackedWindow is the window, if any, that the pointer is in, and that
+// TrackMouseEvent will send a WM_MOUSELEAVE message to when the pointer
+// leaves it. It is only accessed on the thread that runs the message loop.
+var trackedWindow syscall.Handle
+
+// trackPointer sends a screen.CrossingEvent to the window hwnd, if the
+// pointer, at (x, y), has just entered it, and asks to be told when the
+// pointer leaves it.
+func trackPointer(hwnd syscall.Handle, x, y int32) {
+	if hwnd == trackedWindow {
+		return
+	}
+	tme := _TRACKMOUSEEVENT{
+		Size:  uint32(unsafe.Sizeof(_TRACKMOUSEEVENT{})),
+		Flags: _TME_LEAVE,
+		Track: hwnd,
+	}
+	if _TrackMouseEvent(&tme) != nil {
+		return
+	}
+	trackedWindow = hwnd
+	CrossingEvent(hwnd, screen.CrossingEvent{Entered: true, X: float32(x), Y: float32(y)})
+}
+
+func sendMouseLeave(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	if hwnd != trackedWindow {
+		return 0
+	}
+	trackedWindow = 0
+	// WM_MOUSELEAVE does not say where the pointer is.
+	var p _POINT
+	_GetCursorPos(&p)
+	_ScreenToClient(hwnd, &p)
+	CrossingEvent(hwnd, screen.CrossingEvent{X: float32(p.X), Y: float32(p.Y)})
+	return 0
+}
+
 func sendMouseEvent(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	if uMsg == _WM_MOUSEMOVE {
+		trackPointer(hwnd, _GET_X_LPARAM(lParam), _GET_Y_LPARAM(lParam))
+	}
+	if uMsg == _WM_MOUSEMOVE && hwnd == capturedWindow {
+		// The motion is sent as a screen.RelativeMouseEvent by sendRawInput.
+		return 0
+	}
 	e := mouse.Event{
 		X:         float32(_GET_X_LPARAM(lParam)),
 		Y:         float32(_GET_Y_LPARAM(lParam)),
@@ -184,11 +472,11 @@ func sendMouseEvent(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (l
 	switch uMsg {
 	case _WM_MOUSEMOVE:
 		e.Direction = mouse.DirNone
-	case _WM_LBUTTONDOWN, _WM_MBUTTONDOWN, _WM_RBUTTONDOWN:
+	case _WM_LBUTTONDOWN, _WM_MBUTTONDOWN, _WM_RBUTTONDOWN, _WM_XBUTTONDOWN:
 		e.Direction = mouse.DirPress
-	case _WM_LBUTTONUP, _WM_MBUTTONUP, _WM_RBUTTONUP:
+	case _WM_LBUTTONUP, _WM_MBUTTONUP, _WM_RBUTTONUP, _WM_XBUTTONUP:
 		e.Direction = mouse.DirRelease
-	case _WM_MOUSEWHEEL:
+	case _WM_MOUSEWHEEL, _WM_MOUSEHWHEEL:
 		// TODO: On a trackpad, a scroll can be a drawn-out affair with a
 		// distinct beginning and end. Should the intermediate events be
 		// DirNone?
@@ -215,14 +503,59 @@ func sendMouseEvent(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (l
 		e.Button = mouse.ButtonMiddle
 	case _WM_RBUTTONDOWN, _WM_RBUTTONUP:
 		e.Button = mouse.ButtonRight
-	case _WM_MOUSEWHEEL:
-		// TODO: handle horizontal scrolling
-		delta := _GET_WHEEL_DELTA_WPARAM(wParam) / _WHEEL_DELTA
+	case _WM_XBUTTONDOWN, _WM_XBUTTONUP:
+		switch _GET_XBUTTON_WPARAM(wParam) {
+		case _XBUTTON1:
+			e.Button = screen.MouseButtonBack
+		case _XBUTTON2:
+			e.Button = screen.MouseButtonForward
+		default:
+			return 0
+		}
+		MouseEvent(hwnd, e)
+		// Unlike other mouse button messages, an application should return
+		// TRUE from processing WM_XBUTTONDOWN or WM_XBUTTONUP.
+		return 1
+	case _WM_MOUSEWHEEL, _WM_MOUSEHWHEEL:
+		// For WM_MOUSEWHEEL, a positive delta means that the wheel was
+		// rotated forward, away from the user. For WM_MOUSEHWHEEL, it means
+		// that the wheel was tilted to the right.
+		pos, neg := mouse.ButtonWheelUp, mouse.ButtonWheelDown
+		axis := 1
+		if uMsg == _WM_MOUSEHWHEEL {
+			pos, neg = mouse.ButtonWheelRight, mouse.ButtonWheelLeft
+			axis = 0
+		}
+		raw := int32(_GET_WHEEL_DELTA_WPARAM(wParam))
+
+		// Windows does not say which device scrolled, nor whether a
+		// touchpad's fingers are still down. Its vertical deltas, unlike
+		// screen.ScrollEvent's, are positive upwards.
+		se := screen.ScrollEvent{
+			X:         e.X,
+			Y:         e.Y,
+			Modifiers: e.Modifiers,
+		}
+		if axis == 0 {
+			se.DeltaX = float32(raw) / _WHEEL_DELTA
+		} else {
+			se.DeltaY = -float32(raw) / _WHEEL_DELTA
+		}
+		ScrollEvent(hwnd, se)
+
+		// Touchpads and smooth wheels send fractions of a step, which
+		// add up until they make a whole step.
+		if hwnd != wheelWindow {
+			wheelWindow, wheelDeltas = hwnd, [2]int32{}
+		}
+		wheelDeltas[axis] += raw
+		delta := wheelDeltas[axis] / _WHEEL_DELTA
+		wheelDeltas[axis] -= delta * _WHEEL_DELTA
 		switch {
 		case delta > 0:
-			e.Button = mouse.ButtonWheelUp
+			e.Button = pos
 		case delta < 0:
-			e.Button = mouse.ButtonWheelDown
+			e.Button = neg
 			delta = -delta
 		default:
 			return
@@ -261,23 +594,114 @@ func keyModifiers() (m key.Modifiers) {
 	return m
 }
 
+// messageClock converts the times of messages, in milliseconds since the
+// system started.
+var messageClock event.Clock
+
+// MessageTime returns the time of the message being handled, as reported by
+// GetMessageTime, for the callbacks below to send input events at. It must
+// only be called by the callbacks, on the thread that runs the message loop.
+func MessageTime() time.Time {
+	return messageClock.Millis(uint32(_GetMessageTime()))
+}
+
 var (
 	MouseEvent     func(hwnd syscall.Handle, e mouse.Event)
 	PaintEvent     func(hwnd syscall.Handle, e paint.Event)
 	SizeEvent      func(hwnd syscall.Handle, e size.Event)
-	KeyEvent       func(hwnd syscall.Handle, e key.Event)
+	KeyEvent       func(hwnd syscall.Handle, e screen.KeyEvent)
 	LifecycleEvent func(hwnd syscall.Handle, e lifecycle.Stage)
 
+	AccessibilityEvent  func(hwnd syscall.Handle, e screen.AccessibilityEvent)
+	ColorSchemeEvent    func(hwnd syscall.Handle, e screen.ColorSchemeEvent)
+	DisplayEvent        func(hwnd syscall.Handle, e screen.DisplayEvent)
+	ScaleEvent          func(hwnd syscall.Handle, e screen.ScaleEvent)
+	TextEvent           func(hwnd syscall.Handle, e screen.TextEvent)
+	RelativeMouseEvent  func(hwnd syscall.Handle, e screen.RelativeMouseEvent)
+	DragEvent           func(hwnd syscall.Handle, e screen.DragEvent)
+	WindowStateEvent    func(hwnd syscall.Handle, e screen.WindowStateEvent)
+	VisibilityEvent     func(hwnd syscall.Handle, e screen.VisibilityEvent)
+	CloseRequestEvent   func(hwnd syscall.Handle, e screen.CloseRequestEvent)
+	FileDialogEvent     func(hwnd syscall.Handle, e screen.FileDialogEvent)
+	MenuEvent           func(hwnd syscall.Handle, e screen.MenuEvent)
+	AccessActionEvent   func(hwnd syscall.Handle, e screen.AccessActionEvent)
+	ScrollEvent         func(hwnd syscall.Handle, e screen.ScrollEvent)
+	TouchEvent          func(hwnd syscall.Handle, e touch.Event)
+	PenEvent            func(hwnd syscall.Handle, e screen.PenEvent)
+	CrossingEvent       func(hwnd syscall.Handle, e screen.CrossingEvent)
+	FocusEvent          func(hwnd syscall.Handle, e screen.FocusEvent)
+	HotKeyEvent         func(hwnd syscall.Handle, e screen.HotKeyEvent)
+	KeyboardLayoutEvent func(hwnd syscall.Handle, e screen.KeyboardLayoutEvent)
+	NotificationEvent   func(hwnd syscall.Handle, e screen.NotificationEvent)
+
 	// TODO: use the golang.org/x/exp/shiny/driver/internal/lifecycler package
 	// instead of or together with the LifecycleEvent callback?
 )
 
 func sendPaint(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
-	PaintEvent(hwnd, paint.Event{})
+	PaintEvent(hwnd, paint.Event{External: true})
 	return _DefWindowProc(hwnd, uMsg, wParam, lParam)
 }
 
-var screenMsgs = map[uint32]func(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr){}
+func sendSettingChange(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr) {
+	switch wParam {
+	case _SPI_SETCLIENTAREAANIMATION, _SPI_SETHIGHCONTRAST:
+		AccessibilityEvent(hwnd, screen.AccessibilityEvent{
+			Prefs: AccessibilityPrefs(),
+		})
+	case _SPI_SETWORKAREA:
+		DisplayEvent(hwnd, screen.DisplayEvent{Displays: Displays()})
+	case 0:
+		// Changing the app mode, or the accent color, broadcasts the
+		// "ImmersiveColorSet" setting.
+		if lParam != 0 && windows.UTF16PtrToString((*uint16)(Pointer(lParam))) == "ImmersiveColorSet" {
+			ColorSchemeEvent(hwnd, screen.ColorSchemeEvent{
+				Scheme: ColorScheme(),
+			})
+		}
+	}
+	return _DefWindowProc(hwnd, uMsg, wParam, lParam)
+}
+
+// ColorScheme returns the user's app mode, light or dark, which Windows 10 and
+// later store in the registry. Earlier versions have no dark mode.
+func ColorScheme() screen.ColorScheme {
+	k, err := registry.OpenKey(registry.CURRENT_USER,
+		`Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`, registry.QUERY_VALUE)
+	if err != nil {
+		return screen.LightColorScheme
+	}
+	defer k.Close()
+	if v, _, err := k.GetIntegerValue("AppsUseLightTheme"); err == nil && v == 0 {
+		return screen.DarkColorScheme
+	}
+	return screen.LightColorScheme
+}
+
+// AccessibilityPrefs returns the user's accessibility preferences.
+//
+// TODO: report ReduceTransparency, which Windows stores in the registry
+// rather than exposing through SystemParametersInfo.
+func AccessibilityPrefs() (p screen.AccessibilityPrefs) {
+	var animation uint32
+	if err := _SystemParametersInfo(_SPI_GETCLIENTAREAANIMATION, 0, unsafe.Pointer(&animation), 0); err == nil && animation == 0 {
+		p |= screen.ReduceMotion
+	}
+	hc := _HIGHCONTRAST{
+		CbSize: uint32(unsafe.Sizeof(_HIGHCONTRAST{})),
+	}
+	if err := _SystemParametersInfo(_SPI_GETHIGHCONTRAST, hc.CbSize, unsafe.Pointer(&hc), 0); err == nil && hc.DwFlags&_HCF_HIGHCONTRASTON != 0 {
+		p |= screen.HighContrast
+	}
+	return p
+}
+
+var screenMsgs = map[uint32]func(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) (lResult uintptr){
+	msgNewTrayIcon:     sendNewTrayIcon,
+	msgUpdateTrayIcon:  sendUpdateTrayIcon,
+	msgReleaseTrayIcon: sendReleaseTrayIcon,
+	msgTrayNotify:      sendTrayNotify,
+}
 
 func AddScreenMsg(fn func(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr)) uint32 {
 	uMsg := currentUserWM.next()
@@ -291,8 +715,8 @@ func AddScreenMsg(fn func(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintp
 func screenWindowWndProc(hwnd syscall.Handle, uMsg uint32, wParam uintptr, lParam uintptr) (lResult uintptr) {
 	switch uMsg {
 	case msgCreateWindow:
-		p := (*newWindowParams)(unsafe.Pointer(lParam))
-		p.w, p.err = newWindow(p.opts)
+		p := (*newWindowParams)(Pointer(lParam))
+		p.w, p.err = newWindow(p.opts, p.owner)
 	case msgMainCallback:
 		go func() {
 			mainCallback()
@@ -319,8 +743,47 @@ var windowMsgs = map[uint32]func(hwnd syscall.Handle, uMsg uint32, wParam, lPara
 	_WM_KILLFOCUS:        sendFocus,
 	_WM_PAINT:            sendPaint,
 	msgShow:              sendShow,
+	msgRelease:           sendRelease,
+	msgEnableOwner:       sendEnableOwner,
 	_WM_WINDOWPOSCHANGED: sendSizeEvent,
+	_WM_DPICHANGED:       sendDPIChanged,
 	_WM_CLOSE:            sendClose,
+	_WM_SETTINGCHANGE:    sendSettingChange,
+	_WM_DISPLAYCHANGE:    sendDisplayChange,
+	msgSetTextInputRect:  sendSetTextInputRect,
+	msgSetCursor:         sendSetCursor,
+	msgSetCursorVisible:  sendSetCursorVisible,
+	msgSetPointerCapture: sendSetPointerCapture,
+	msgStartDrag:         sendStartDrag,
+	msgStartMoveResize:   sendStartMoveResize,
+	msgDoDragDrop:        sendDoDragDrop,
+	msgSetWindowState:    sendSetWindowState,
+	msgSetWindowOrder:    sendSetWindowOrder,
+	msgSetOpacity:        sendSetOpacity,
+	msgSetConstraints:    sendSetConstraints,
+	msgSetAspectRatio:    sendSetAspectRatio,
+	msgShowFileDialog:    sendShowFileDialog,
+	msgRunFileDialog:     sendRunFileDialog,
+	msgSetMenuBar:        sendSetMenuBar,
+	msgShowContextMenu:   sendShowContextMenu,
+	msgTrackContextMenu:  sendTrackContextMenu,
+	msgSetIcon:           sendSetIcon,
+	msgSetBadge:          sendSetBadge,
+	msgSetProgress:       sendSetProgress,
+	msgSetAccessTree:     sendSetAccessTree,
+	msgRegisterHotKey:    sendRegisterHotKey,
+	msgUnregisterHotKey:  sendUnregisterHotKey,
+	msgReleaseHotKeys:    sendReleaseHotKeys,
+	msgNotify:            sendNotify,
+	msgCloseNotification: sendCloseNotification,
+	_WM_GETOBJECT:        sendGetObject,
+	_WM_COMMAND:          sendCommand,
+	_WM_SETCURSOR:        sendCursor,
+	_WM_NCCALCSIZE:       sendNCCalcSize,
+	_WM_GETMINMAXINFO:    sendGetMinMaxInfo,
+	_WM_SIZING:           sendSizing,
+	_WM_INPUT:            sendRawInput,
+	_WM_HOTKEY:           sendHotKey,
 
 	_WM_LBUTTONDOWN: sendMouseEvent,
 	_WM_LBUTTONUP:   sendMouseEvent,
@@ -328,12 +791,26 @@ var windowMsgs = map[uint32]func(hwnd syscall.Handle, uMsg uint32, wParam, lPara
 	_WM_MBUTTONUP:   sendMouseEvent,
 	_WM_RBUTTONDOWN: sendMouseEvent,
 	_WM_RBUTTONUP:   sendMouseEvent,
+	_WM_XBUTTONDOWN: sendMouseEvent,
+	_WM_XBUTTONUP:   sendMouseEvent,
 	_WM_MOUSEMOVE:   sendMouseEvent,
 	_WM_MOUSEWHEEL:  sendMouseEvent,
+	_WM_MOUSEHWHEEL: sendMouseEvent,
+	_WM_MOUSELEAVE:  sendMouseLeave,
+
+	_WM_POINTERDOWN:   sendPointerEvent,
+	_WM_POINTERUPDATE: sendPointerEvent,
+	_WM_POINTERUP:     sendPointerEvent,
 
 	_WM_KEYDOWN: sendKeyEvent,
 	_WM_KEYUP:   sendKeyEvent,
 	// TODO case _WM_SYSKEYDOWN, _WM_SYSKEYUP:
+	_WM_INPUTLANGCHANGE: sendInputLangChange,
+
+	_WM_IME_SETCONTEXT:       sendIMESetContext,
+	_WM_IME_STARTCOMPOSITION: sendIMEComposition,
+	_WM_IME_COMPOSITION:      sendIMEComposition,
+	_WM_IME_ENDCOMPOSITION:   sendIMEComposition,
 }
 
 func AddWindowMsg(fn func(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr)) uint32 {
@@ -354,14 +831,19 @@ func windowWndProc(hwnd syscall.Handle, uMsg uint32, wParam uintptr, lParam uint
 }
 
 type newWindowParams struct {
-	opts *screen.NewWindowOptions
-	w    syscall.Handle
-	err  error
+	opts  *screen.NewWindowOptions
+	owner syscall.Handle
+	w     syscall.Handle
+	err   error
 }
 
-func NewWindow(opts *screen.NewWindowOptions) (syscall.Handle, error) {
+// NewWindow creates a window. If owner is non-zero, the window is owned by
+// it, and so stays above it and is minimized with it, and, for
+// opts.Modal, disables it until the window is released.
+func NewWindow(opts *screen.NewWindowOptions, owner syscall.Handle) (syscall.Handle, error) {
 	var p newWindowParams
 	p.opts = opts
+	p.owner = owner
 	SendScreenMessage(msgCreateWindow, 0, uintptr(unsafe.Pointer(&p)))
 	return p.w, p.err
 }
@@ -385,8 +867,11 @@ func initWindowClass() (err error) {
 	return err
 }
 
+// screenWindowClass is the class of the screen window, and of the tray
+// window, which are never shown.
+const screenWindowClass = "shiny_ScreenWindow"
+
 func initScreenWindow() (err error) {
-	const screenWindowClass = "shiny_ScreenWindow"
 	swc, err := syscall.UTF16PtrFromString(screenWindowClass)
 	if err != nil {
 		return err
@@ -444,6 +929,14 @@ func SendMessage(hwnd syscall.Handle, uMsg uint32, wParam uintptr, lParam uintpt
 	return sendMessage(hwnd, uMsg, wParam, lParam)
 }
 
+// Pointer returns the pointer held by p, a uintptr that Windows passed or
+// returned, such as a message's lParam or a callback's argument. Converting
+// through p's address, rather than converting p itself, keeps go vet from
+// reporting a conversion that it cannot tell is valid.
+func Pointer(p uintptr) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&p))
+}
+
 var mainCallback func()
 
 func Main(f func()) (retErr error) {
@@ -452,6 +945,23 @@ func Main(f func()) (retErr error) {
 	// to the thread that created the respective window.
 	runtime.LockOSThread()
 
+	// Render at each monitor's native resolution, rather than having the
+	// system scale a 96 DPI rendering, which is blurry at fractional scales
+	// such as 150%. Per-monitor (v2) DPI awareness needs Windows 10, version
+	// 1703 or later. It is not an error if it is unavailable.
+	if procSetProcessDpiAwarenessContext.Find() == nil {
+		_SetProcessDpiAwarenessContext(_DPI_AWARENESS_CONTEXT_PER_MONITOR_AWARE_V2)
+	}
+
+	// Drag-and-drop needs OLE, initialized on the thread whose windows are
+	// drop targets. It is not an error if that fails.
+	oleInitialized = _OleInitialize(0) >= 0
+	initTaskbar()
+	initTray()
+	if hook := initCloakHook(); hook != 0 {
+		defer _UnhookWinEvent(hook)
+	}
+
 	if err := initCommon(); err != nil {
 		return err
 	}
@@ -460,6 +970,7 @@ func Main(f func()) (retErr error) {
 		return err
 	}
 	defer func() {
+		releaseTrayIcons()
 		// TODO(andlabs): log an error if this fails?
 		_DestroyWindow(screenHWND)
 		// TODO(andlabs): unregister window class
@@ -483,6 +994,9 @@ func Main(f func()) (retErr error) {
 		if done == 0 { // WM_QUIT
 			break
 		}
+		if translateAccelerator(&m) {
+			continue
+		}
 		_TranslateMessage(&m)
 		_DispatchMessage(&m)
 	}
@@ -37,31 +37,130 @@ func errnoErr(e syscall.Errno) error {
 }
 
 var (
-	moduser32 = windows.NewLazySystemDLL("user32.dll")
-
-	procGetDC             = moduser32.NewProc("GetDC")
-	procReleaseDC         = moduser32.NewProc("ReleaseDC")
-	procSendMessageW      = moduser32.NewProc("SendMessageW")
-	procCreateWindowExW   = moduser32.NewProc("CreateWindowExW")
-	procDefWindowProcW    = moduser32.NewProc("DefWindowProcW")
-	procDestroyWindow     = moduser32.NewProc("DestroyWindow")
-	procDispatchMessageW  = moduser32.NewProc("DispatchMessageW")
-	procGetClientRect     = moduser32.NewProc("GetClientRect")
-	procGetWindowRect     = moduser32.NewProc("GetWindowRect")
-	procGetKeyboardLayout = moduser32.NewProc("GetKeyboardLayout")
-	procGetKeyboardState  = moduser32.NewProc("GetKeyboardState")
-	procGetKeyState       = moduser32.NewProc("GetKeyState")
-	procGetMessageW       = moduser32.NewProc("GetMessageW")
-	procLoadCursorW       = moduser32.NewProc("LoadCursorW")
-	procLoadIconW         = moduser32.NewProc("LoadIconW")
-	procMoveWindow        = moduser32.NewProc("MoveWindow")
-	procPostMessageW      = moduser32.NewProc("PostMessageW")
-	procPostQuitMessage   = moduser32.NewProc("PostQuitMessage")
-	procRegisterClassW    = moduser32.NewProc("RegisterClassW")
-	procShowWindow        = moduser32.NewProc("ShowWindow")
-	procScreenToClient    = moduser32.NewProc("ScreenToClient")
-	procToUnicodeEx       = moduser32.NewProc("ToUnicodeEx")
-	procTranslateMessage  = moduser32.NewProc("TranslateMessage")
+	moddwmapi           = windows.NewLazySystemDLL("dwmapi.dll")
+	modgdi32            = windows.NewLazySystemDLL("gdi32.dll")
+	modimm32            = windows.NewLazySystemDLL("imm32.dll")
+	modkernel32         = windows.NewLazySystemDLL("kernel32.dll")
+	modole32            = windows.NewLazySystemDLL("ole32.dll")
+	modoleaut32         = windows.NewLazySystemDLL("oleaut32.dll")
+	modshcore           = windows.NewLazySystemDLL("shcore.dll")
+	modshell32          = windows.NewLazySystemDLL("shell32.dll")
+	moduiautomationcore = windows.NewLazySystemDLL("uiautomationcore.dll")
+	moduser32           = windows.NewLazySystemDLL("user32.dll")
+
+	procGetDC                         = moduser32.NewProc("GetDC")
+	procReleaseDC                     = moduser32.NewProc("ReleaseDC")
+	procSendMessageW                  = moduser32.NewProc("SendMessageW")
+	procAppendMenuW                   = moduser32.NewProc("AppendMenuW")
+	procCloseClipboard                = moduser32.NewProc("CloseClipboard")
+	procCreateAcceleratorTableW       = moduser32.NewProc("CreateAcceleratorTableW")
+	procCreateMenu                    = moduser32.NewProc("CreateMenu")
+	procCreatePopupMenu               = moduser32.NewProc("CreatePopupMenu")
+	procCreateWindowExW               = moduser32.NewProc("CreateWindowExW")
+	procDefWindowProcW                = moduser32.NewProc("DefWindowProcW")
+	procDestroyAcceleratorTable       = moduser32.NewProc("DestroyAcceleratorTable")
+	procDestroyMenu                   = moduser32.NewProc("DestroyMenu")
+	procDestroyWindow                 = moduser32.NewProc("DestroyWindow")
+	procClipCursor                    = moduser32.NewProc("ClipCursor")
+	procCreateIconIndirect            = moduser32.NewProc("CreateIconIndirect")
+	procDestroyIcon                   = moduser32.NewProc("DestroyIcon")
+	procDispatchMessageW              = moduser32.NewProc("DispatchMessageW")
+	procEmptyClipboard                = moduser32.NewProc("EmptyClipboard")
+	procEnableWindow                  = moduser32.NewProc("EnableWindow")
+	procEnumDisplayMonitors           = moduser32.NewProc("EnumDisplayMonitors")
+	procEnumDisplaySettingsW          = moduser32.NewProc("EnumDisplaySettingsW")
+	procGetClipboardData              = moduser32.NewProc("GetClipboardData")
+	procGetClipboardFormatNameW       = moduser32.NewProc("GetClipboardFormatNameW")
+	procGetDpiForWindow               = moduser32.NewProc("GetDpiForWindow")
+	procGetClientRect                 = moduser32.NewProc("GetClientRect")
+	procGetCursorPos                  = moduser32.NewProc("GetCursorPos")
+	procGetRawInputData               = moduser32.NewProc("GetRawInputData")
+	procGetSystemMetrics              = moduser32.NewProc("GetSystemMetrics")
+	procGetWindowRect                 = moduser32.NewProc("GetWindowRect")
+	procGetWindowLongW                = moduser32.NewProc("GetWindowLongW")
+	procGetWindowPlacement            = moduser32.NewProc("GetWindowPlacement")
+	procGetKeyboardLayout             = moduser32.NewProc("GetKeyboardLayout")
+	procGetKeyboardLayoutNameW        = moduser32.NewProc("GetKeyboardLayoutNameW")
+	procGetKeyboardState              = moduser32.NewProc("GetKeyboardState")
+	procGetKeyState                   = moduser32.NewProc("GetKeyState")
+	procGetMessageW                   = moduser32.NewProc("GetMessageW")
+	procGetMessageTime                = moduser32.NewProc("GetMessageTime")
+	procGetMonitorInfoW               = moduser32.NewProc("GetMonitorInfoW")
+	procGetPointerPenInfo             = moduser32.NewProc("GetPointerPenInfo")
+	procGetPointerType                = moduser32.NewProc("GetPointerType")
+	procIsIconic                      = moduser32.NewProc("IsIconic")
+	procIsZoomed                      = moduser32.NewProc("IsZoomed")
+	procLoadCursorW                   = moduser32.NewProc("LoadCursorW")
+	procLoadIconW                     = moduser32.NewProc("LoadIconW")
+	procMonitorFromWindow             = moduser32.NewProc("MonitorFromWindow")
+	procMoveWindow                    = moduser32.NewProc("MoveWindow")
+	procOpenClipboard                 = moduser32.NewProc("OpenClipboard")
+	procPostMessageW                  = moduser32.NewProc("PostMessageW")
+	procPostQuitMessage               = moduser32.NewProc("PostQuitMessage")
+	procRegisterClassW                = moduser32.NewProc("RegisterClassW")
+	procRegisterClipboardFormatW      = moduser32.NewProc("RegisterClipboardFormatW")
+	procRegisterHotKey                = moduser32.NewProc("RegisterHotKey")
+	procRegisterRawInputDevices       = moduser32.NewProc("RegisterRawInputDevices")
+	procRegisterWindowMessageW        = moduser32.NewProc("RegisterWindowMessageW")
+	procSystemParametersInfoW         = moduser32.NewProc("SystemParametersInfoW")
+	procSetClipboardData              = moduser32.NewProc("SetClipboardData")
+	procSetCursor                     = moduser32.NewProc("SetCursor")
+	procSetForegroundWindow           = moduser32.NewProc("SetForegroundWindow")
+	procSetLayeredWindowAttributes    = moduser32.NewProc("SetLayeredWindowAttributes")
+	procSetMenu                       = moduser32.NewProc("SetMenu")
+	procSetProcessDpiAwarenessContext = moduser32.NewProc("SetProcessDpiAwarenessContext")
+	procSetWindowLongW                = moduser32.NewProc("SetWindowLongW")
+	procSetWindowPlacement            = moduser32.NewProc("SetWindowPlacement")
+	procSetWindowPos                  = moduser32.NewProc("SetWindowPos")
+	procSetWindowRgn                  = moduser32.NewProc("SetWindowRgn")
+	procSetWindowTextW                = moduser32.NewProc("SetWindowTextW")
+	procShowWindow                    = moduser32.NewProc("ShowWindow")
+	procScreenToClient                = moduser32.NewProc("ScreenToClient")
+	procClientToScreen                = moduser32.NewProc("ClientToScreen")
+	procToUnicodeEx                   = moduser32.NewProc("ToUnicodeEx")
+	procTrackMouseEvent               = moduser32.NewProc("TrackMouseEvent")
+	procTrackPopupMenu                = moduser32.NewProc("TrackPopupMenu")
+	procTranslateAcceleratorW         = moduser32.NewProc("TranslateAcceleratorW")
+	procTranslateMessage              = moduser32.NewProc("TranslateMessage")
+	procUnregisterHotKey              = moduser32.NewProc("UnregisterHotKey")
+	procSetWinEventHook               = moduser32.NewProc("SetWinEventHook")
+	procUnhookWinEvent                = moduser32.NewProc("UnhookWinEvent")
+	procGlobalAlloc                   = modkernel32.NewProc("GlobalAlloc")
+	procGlobalFree                    = modkernel32.NewProc("GlobalFree")
+	procGlobalLock                    = modkernel32.NewProc("GlobalLock")
+	procGlobalSize                    = modkernel32.NewProc("GlobalSize")
+	procGlobalUnlock                  = modkernel32.NewProc("GlobalUnlock")
+	procImmGetCompositionStringW      = modimm32.NewProc("ImmGetCompositionStringW")
+	procImmGetContext                 = modimm32.NewProc("ImmGetContext")
+	procImmReleaseContext             = modimm32.NewProc("ImmReleaseContext")
+	procImmSetCandidateWindow         = modimm32.NewProc("ImmSetCandidateWindow")
+	procImmSetCompositionWindow       = modimm32.NewProc("ImmSetCompositionWindow")
+	procGetDpiForMonitor              = modshcore.NewProc("GetDpiForMonitor")
+	procDwmFlush                      = moddwmapi.NewProc("DwmFlush")
+	procDwmGetWindowAttribute         = moddwmapi.NewProc("DwmGetWindowAttribute")
+	procCoCreateInstance              = modole32.NewProc("CoCreateInstance")
+	procCoTaskMemFree                 = modole32.NewProc("CoTaskMemFree")
+	procDoDragDrop                    = modole32.NewProc("DoDragDrop")
+	procOleInitialize                 = modole32.NewProc("OleInitialize")
+	procRegisterDragDrop              = modole32.NewProc("RegisterDragDrop")
+	procReleaseStgMedium              = modole32.NewProc("ReleaseStgMedium")
+	procRevokeDragDrop                = modole32.NewProc("RevokeDragDrop")
+	procSafeArrayCreateVector         = modoleaut32.NewProc("SafeArrayCreateVector")
+	procSafeArrayPutElement           = modoleaut32.NewProc("SafeArrayPutElement")
+	procSysAllocString                = modoleaut32.NewProc("SysAllocString")
+	procUiaClientsAreListening        = moduiautomationcore.NewProc("UiaClientsAreListening")
+	procUiaHostProviderFromHwnd       = moduiautomationcore.NewProc("UiaHostProviderFromHwnd")
+	procUiaRaiseAutomationEvent       = moduiautomationcore.NewProc("UiaRaiseAutomationEvent")
+	procUiaReturnRawElementProvider   = moduiautomationcore.NewProc("UiaReturnRawElementProvider")
+	procDragQueryFileW                = modshell32.NewProc("DragQueryFileW")
+	procSHCreateDataObject            = modshell32.NewProc("SHCreateDataObject")
+	procSHCreateItemFromParsingName   = modshell32.NewProc("SHCreateItemFromParsingName")
+	procShell_NotifyIconW             = modshell32.NewProc("Shell_NotifyIconW")
+	procCombineRgn                    = modgdi32.NewProc("CombineRgn")
+	procCreateBitmap                  = modgdi32.NewProc("CreateBitmap")
+	procCreateDIBSection              = modgdi32.NewProc("CreateDIBSection")
+	procCreateRectRgn                 = modgdi32.NewProc("CreateRectRgn")
+	procDeleteObject                  = modgdi32.NewProc("DeleteObject")
 )
 
 func GetDC(hwnd syscall.Handle) (dc syscall.Handle, err error) {
@@ -95,6 +194,69 @@ func sendMessage(hwnd syscall.Handle, uMsg uint32, wParam uintptr, lParam uintpt
 	return
 }
 
+func _AppendMenu(menu syscall.Handle, flags uint32, id uintptr, item *uint16) (err error) {
+	r1, _, e1 := syscall.Syscall6(procAppendMenuW.Addr(), 4, uintptr(menu), uintptr(flags), uintptr(id), uintptr(unsafe.Pointer(item)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _CloseClipboard() (err error) {
+	r1, _, e1 := syscall.Syscall(procCloseClipboard.Addr(), 0, 0, 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _CreateAcceleratorTable(accels *_ACCEL, n int32) (accel syscall.Handle, err error) {
+	r0, _, e1 := syscall.Syscall(procCreateAcceleratorTableW.Addr(), 2, uintptr(unsafe.Pointer(accels)), uintptr(n), 0)
+	accel = syscall.Handle(r0)
+	if accel == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _CreateMenu() (menu syscall.Handle, err error) {
+	r0, _, e1 := syscall.Syscall(procCreateMenu.Addr(), 0, 0, 0, 0)
+	menu = syscall.Handle(r0)
+	if menu == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _CreatePopupMenu() (menu syscall.Handle, err error) {
+	r0, _, e1 := syscall.Syscall(procCreatePopupMenu.Addr(), 0, 0, 0, 0)
+	menu = syscall.Handle(r0)
+	if menu == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
 func _CreateWindowEx(exstyle uint32, className *uint16, windowText *uint16, style uint32, x int32, y int32, width int32, height int32, parent syscall.Handle, menu syscall.Handle, hInstance syscall.Handle, lpParam uintptr) (hwnd syscall.Handle, err error) {
 	r0, _, e1 := syscall.Syscall12(procCreateWindowExW.Addr(), 12, uintptr(exstyle), uintptr(unsafe.Pointer(className)), uintptr(unsafe.Pointer(windowText)), uintptr(style), uintptr(x), uintptr(y), uintptr(width), uintptr(height), uintptr(parent), uintptr(menu), uintptr(hInstance), uintptr(lpParam))
 	hwnd = syscall.Handle(r0)
@@ -114,6 +276,24 @@ func _DefWindowProc(hwnd syscall.Handle, uMsg uint32, wParam uintptr, lParam uin
 	return
 }
 
+func _DestroyAcceleratorTable(accel syscall.Handle) (ok bool) {
+	r0, _, _ := syscall.Syscall(procDestroyAcceleratorTable.Addr(), 1, uintptr(accel), 0, 0)
+	ok = r0 != 0
+	return
+}
+
+func _DestroyMenu(menu syscall.Handle) (err error) {
+	r1, _, e1 := syscall.Syscall(procDestroyMenu.Addr(), 1, uintptr(menu), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
 func _DestroyWindow(hwnd syscall.Handle) (err error) {
 	r1, _, e1 := syscall.Syscall(procDestroyWindow.Addr(), 1, uintptr(hwnd), 0, 0)
 	if r1 == 0 {
@@ -126,12 +306,120 @@ func _DestroyWindow(hwnd syscall.Handle) (err error) {
 	return
 }
 
+func _ClipCursor(rect *_RECT) (err error) {
+	r1, _, e1 := syscall.Syscall(procClipCursor.Addr(), 1, uintptr(unsafe.Pointer(rect)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _CreateIconIndirect(iconInfo *_ICONINFO) (icon syscall.Handle, err error) {
+	r0, _, e1 := syscall.Syscall(procCreateIconIndirect.Addr(), 1, uintptr(unsafe.Pointer(iconInfo)), 0, 0)
+	icon = syscall.Handle(r0)
+	if icon == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _DestroyIcon(icon syscall.Handle) (err error) {
+	r1, _, e1 := syscall.Syscall(procDestroyIcon.Addr(), 1, uintptr(icon), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
 func _DispatchMessage(msg *_MSG) (ret int32) {
 	r0, _, _ := syscall.Syscall(procDispatchMessageW.Addr(), 1, uintptr(unsafe.Pointer(msg)), 0, 0)
 	ret = int32(r0)
 	return
 }
 
+func _EmptyClipboard() (err error) {
+	r1, _, e1 := syscall.Syscall(procEmptyClipboard.Addr(), 0, 0, 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _EnableWindow(hwnd syscall.Handle, enable bool) (wasDisabled bool) {
+	var _p0 uint32
+	if enable {
+		_p0 = 1
+	}
+	r0, _, _ := syscall.Syscall(procEnableWindow.Addr(), 2, uintptr(hwnd), uintptr(_p0), 0)
+	wasDisabled = r0 != 0
+	return
+}
+
+func _EnumDisplayMonitors(dc syscall.Handle, clip *_RECT, fn uintptr, data uintptr) (err error) {
+	r1, _, e1 := syscall.Syscall6(procEnumDisplayMonitors.Addr(), 4, uintptr(dc), uintptr(unsafe.Pointer(clip)), uintptr(fn), uintptr(data), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _EnumDisplaySettings(deviceName *uint16, modeNum uint32, devMode *_DEVMODE) (err error) {
+	r1, _, e1 := syscall.Syscall(procEnumDisplaySettingsW.Addr(), 3, uintptr(unsafe.Pointer(deviceName)), uintptr(modeNum), uintptr(unsafe.Pointer(devMode)))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _GetClipboardData(format uint32) (mem syscall.Handle, err error) {
+	r0, _, e1 := syscall.Syscall(procGetClipboardData.Addr(), 1, uintptr(format), 0, 0)
+	mem = syscall.Handle(r0)
+	if mem == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _GetClipboardFormatName(format uint32, name *uint16, size int32) (n int32) {
+	r0, _, _ := syscall.Syscall(procGetClipboardFormatNameW.Addr(), 3, uintptr(format), uintptr(unsafe.Pointer(name)), uintptr(size))
+	n = int32(r0)
+	return
+}
+
+func _GetDpiForWindow(hwnd syscall.Handle) (dpi uint32) {
+	r0, _, _ := syscall.Syscall(procGetDpiForWindow.Addr(), 1, uintptr(hwnd), 0, 0)
+	dpi = uint32(r0)
+	return
+}
+
 func _GetClientRect(hwnd syscall.Handle, rect *_RECT) (err error) {
 	r1, _, e1 := syscall.Syscall(procGetClientRect.Addr(), 2, uintptr(hwnd), uintptr(unsafe.Pointer(rect)), 0)
 	if r1 == 0 {
@@ -144,6 +432,30 @@ func _GetClientRect(hwnd syscall.Handle, rect *_RECT) (err error) {
 	return
 }
 
+func _GetCursorPos(pt *_POINT) (err error) {
+	r1, _, e1 := syscall.Syscall(procGetCursorPos.Addr(), 1, uintptr(unsafe.Pointer(pt)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _GetRawInputData(rawInput syscall.Handle, command uint32, data unsafe.Pointer, size *uint32, headerSize uint32) (ret uint32) {
+	r0, _, _ := syscall.Syscall6(procGetRawInputData.Addr(), 5, uintptr(rawInput), uintptr(command), uintptr(data), uintptr(unsafe.Pointer(size)), uintptr(headerSize), 0)
+	ret = uint32(r0)
+	return
+}
+
+func _GetSystemMetrics(index int32) (value int32) {
+	r0, _, _ := syscall.Syscall(procGetSystemMetrics.Addr(), 1, uintptr(index), 0, 0)
+	value = int32(r0)
+	return
+}
+
 func _GetWindowRect(hwnd syscall.Handle, rect *_RECT) (err error) {
 	r1, _, e1 := syscall.Syscall(procGetWindowRect.Addr(), 2, uintptr(hwnd), uintptr(unsafe.Pointer(rect)), 0)
 	if r1 == 0 {
@@ -156,12 +468,42 @@ func _GetWindowRect(hwnd syscall.Handle, rect *_RECT) (err error) {
 	return
 }
 
+func _GetWindowLong(hwnd syscall.Handle, index int32) (value int32) {
+	r0, _, _ := syscall.Syscall(procGetWindowLongW.Addr(), 2, uintptr(hwnd), uintptr(index), 0)
+	value = int32(r0)
+	return
+}
+
+func _GetWindowPlacement(hwnd syscall.Handle, wp *_WINDOWPLACEMENT) (err error) {
+	r1, _, e1 := syscall.Syscall(procGetWindowPlacement.Addr(), 2, uintptr(hwnd), uintptr(unsafe.Pointer(wp)), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
 func _GetKeyboardLayout(threadID uint32) (locale syscall.Handle) {
 	r0, _, _ := syscall.Syscall(procGetKeyboardLayout.Addr(), 1, uintptr(threadID), 0, 0)
 	locale = syscall.Handle(r0)
 	return
 }
 
+func _GetKeyboardLayoutName(name *uint16) (err error) {
+	r1, _, e1 := syscall.Syscall(procGetKeyboardLayoutNameW.Addr(), 1, uintptr(unsafe.Pointer(name)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
 func _GetKeyboardState(lpKeyState *byte) (err error) {
 	r1, _, e1 := syscall.Syscall(procGetKeyboardState.Addr(), 1, uintptr(unsafe.Pointer(lpKeyState)), 0, 0)
 	if r1 == 0 {
@@ -193,6 +535,60 @@ func _GetMessage(msg *_MSG, hwnd syscall.Handle, msgfiltermin uint32, msgfilterm
 	return
 }
 
+func _GetMessageTime() (time int32) {
+	r0, _, _ := syscall.Syscall(procGetMessageTime.Addr(), 0, 0, 0, 0)
+	time = int32(r0)
+	return
+}
+
+func _GetMonitorInfo(monitor syscall.Handle, mi *_MONITORINFOEX) (err error) {
+	r1, _, e1 := syscall.Syscall(procGetMonitorInfoW.Addr(), 2, uintptr(monitor), uintptr(unsafe.Pointer(mi)), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _GetPointerPenInfo(pointerID uint32, penInfo *_POINTER_PEN_INFO) (err error) {
+	r1, _, e1 := syscall.Syscall(procGetPointerPenInfo.Addr(), 2, uintptr(pointerID), uintptr(unsafe.Pointer(penInfo)), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _GetPointerType(pointerID uint32, pointerType *uint32) (err error) {
+	r1, _, e1 := syscall.Syscall(procGetPointerType.Addr(), 2, uintptr(pointerID), uintptr(unsafe.Pointer(pointerType)), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _IsIconic(hwnd syscall.Handle) (iconic bool) {
+	r0, _, _ := syscall.Syscall(procIsIconic.Addr(), 1, uintptr(hwnd), 0, 0)
+	iconic = r0 != 0
+	return
+}
+
+func _IsZoomed(hwnd syscall.Handle) (zoomed bool) {
+	r0, _, _ := syscall.Syscall(procIsZoomed.Addr(), 1, uintptr(hwnd), 0, 0)
+	zoomed = r0 != 0
+	return
+}
+
 func _LoadCursor(hInstance syscall.Handle, cursorName uintptr) (cursor syscall.Handle, err error) {
 	r0, _, e1 := syscall.Syscall(procLoadCursorW.Addr(), 2, uintptr(hInstance), uintptr(cursorName), 0)
 	cursor = syscall.Handle(r0)
@@ -219,6 +615,12 @@ func _LoadIcon(hInstance syscall.Handle, iconName uintptr) (icon syscall.Handle,
 	return
 }
 
+func _MonitorFromWindow(hwnd syscall.Handle, flags uint32) (monitor syscall.Handle) {
+	r0, _, _ := syscall.Syscall(procMonitorFromWindow.Addr(), 2, uintptr(hwnd), uintptr(flags), 0)
+	monitor = syscall.Handle(r0)
+	return
+}
+
 func _MoveWindow(hwnd syscall.Handle, x int32, y int32, w int32, h int32, repaint bool) (err error) {
 	var _p0 uint32
 	if repaint {
@@ -237,6 +639,18 @@ func _MoveWindow(hwnd syscall.Handle, x int32, y int32, w int32, h int32, repain
 	return
 }
 
+func _OpenClipboard(hwnd syscall.Handle) (err error) {
+	r1, _, e1 := syscall.Syscall(procOpenClipboard.Addr(), 1, uintptr(hwnd), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
 func _PostMessage(hwnd syscall.Handle, uMsg uint32, wParam uintptr, lParam uintptr) (lResult bool) {
 	r0, _, _ := syscall.Syscall6(procPostMessageW.Addr(), 4, uintptr(hwnd), uintptr(uMsg), uintptr(wParam), uintptr(lParam), 0, 0)
 	lResult = r0 != 0
@@ -261,20 +675,233 @@ func _RegisterClass(wc *_WNDCLASS) (atom uint16, err error) {
 	return
 }
 
-func _ShowWindow(hwnd syscall.Handle, cmdshow int32) (wasvisible bool) {
-	r0, _, _ := syscall.Syscall(procShowWindow.Addr(), 2, uintptr(hwnd), uintptr(cmdshow), 0)
-	wasvisible = r0 != 0
+func _RegisterClipboardFormat(name *uint16) (format uint32, err error) {
+	r0, _, e1 := syscall.Syscall(procRegisterClipboardFormatW.Addr(), 1, uintptr(unsafe.Pointer(name)), 0, 0)
+	format = uint32(r0)
+	if format == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
 	return
 }
 
-func _ScreenToClient(hwnd syscall.Handle, lpPoint *_POINT) (ok bool) {
-	r0, _, _ := syscall.Syscall(procScreenToClient.Addr(), 2, uintptr(hwnd), uintptr(unsafe.Pointer(lpPoint)), 0)
-	ok = r0 != 0
+func _RegisterHotKey(hwnd syscall.Handle, id int32, modifiers uint32, vk uint32) (err error) {
+	r1, _, e1 := syscall.Syscall6(procRegisterHotKey.Addr(), 4, uintptr(hwnd), uintptr(id), uintptr(modifiers), uintptr(vk), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
 	return
 }
 
-func _ToUnicodeEx(wVirtKey uint32, wScanCode uint32, lpKeyState *byte, pwszBuff *uint16, cchBuff int32, wFlags uint32, dwhkl syscall.Handle) (ret int32) {
-	r0, _, _ := syscall.Syscall9(procToUnicodeEx.Addr(), 7, uintptr(wVirtKey), uintptr(wScanCode), uintptr(unsafe.Pointer(lpKeyState)), uintptr(unsafe.Pointer(pwszBuff)), uintptr(cchBuff), uintptr(wFlags), uintptr(dwhkl), 0, 0)
+func _RegisterRawInputDevices(devices *_RAWINPUTDEVICE, numDevices uint32, size uint32) (err error) {
+	r1, _, e1 := syscall.Syscall(procRegisterRawInputDevices.Addr(), 3, uintptr(unsafe.Pointer(devices)), uintptr(numDevices), uintptr(size))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _RegisterWindowMessage(name *uint16) (uMsg uint32, err error) {
+	r0, _, e1 := syscall.Syscall(procRegisterWindowMessageW.Addr(), 1, uintptr(unsafe.Pointer(name)), 0, 0)
+	uMsg = uint32(r0)
+	if uMsg == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _SystemParametersInfo(uiAction uint32, uiParam uint32, pvParam unsafe.Pointer, fWinIni uint32) (err error) {
+	r1, _, e1 := syscall.Syscall6(procSystemParametersInfoW.Addr(), 4, uintptr(uiAction), uintptr(uiParam), uintptr(pvParam), uintptr(fWinIni), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _SetClipboardData(format uint32, mem syscall.Handle) (h syscall.Handle, err error) {
+	r0, _, e1 := syscall.Syscall(procSetClipboardData.Addr(), 2, uintptr(format), uintptr(mem), 0)
+	h = syscall.Handle(r0)
+	if h == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _SetCursor(cursor syscall.Handle) (prev syscall.Handle) {
+	r0, _, _ := syscall.Syscall(procSetCursor.Addr(), 1, uintptr(cursor), 0, 0)
+	prev = syscall.Handle(r0)
+	return
+}
+
+func _SetForegroundWindow(hwnd syscall.Handle) (ok bool) {
+	r0, _, _ := syscall.Syscall(procSetForegroundWindow.Addr(), 1, uintptr(hwnd), 0, 0)
+	ok = r0 != 0
+	return
+}
+
+func _SetLayeredWindowAttributes(hwnd syscall.Handle, key uint32, alpha byte, flags uint32) (err error) {
+	r1, _, e1 := syscall.Syscall6(procSetLayeredWindowAttributes.Addr(), 4, uintptr(hwnd), uintptr(key), uintptr(alpha), uintptr(flags), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _SetMenu(hwnd syscall.Handle, menu syscall.Handle) (err error) {
+	r1, _, e1 := syscall.Syscall(procSetMenu.Addr(), 2, uintptr(hwnd), uintptr(menu), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _SetProcessDpiAwarenessContext(value uintptr) (err error) {
+	r1, _, e1 := syscall.Syscall(procSetProcessDpiAwarenessContext.Addr(), 1, uintptr(value), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _SetWindowLong(hwnd syscall.Handle, index int32, value int32) (prev int32) {
+	r0, _, _ := syscall.Syscall(procSetWindowLongW.Addr(), 3, uintptr(hwnd), uintptr(index), uintptr(value))
+	prev = int32(r0)
+	return
+}
+
+func _SetWindowPlacement(hwnd syscall.Handle, wp *_WINDOWPLACEMENT) (err error) {
+	r1, _, e1 := syscall.Syscall(procSetWindowPlacement.Addr(), 2, uintptr(hwnd), uintptr(unsafe.Pointer(wp)), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _SetWindowPos(hwnd syscall.Handle, insertAfter syscall.Handle, x int32, y int32, cx int32, cy int32, flags uint32) (err error) {
+	r1, _, e1 := syscall.Syscall9(procSetWindowPos.Addr(), 7, uintptr(hwnd), uintptr(insertAfter), uintptr(x), uintptr(y), uintptr(cx), uintptr(cy), uintptr(flags), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _SetWindowRgn(hwnd syscall.Handle, rgn syscall.Handle, redraw bool) (err error) {
+	var _p0 uint32
+	if redraw {
+		_p0 = 1
+	} else {
+		_p0 = 0
+	}
+	r1, _, e1 := syscall.Syscall(procSetWindowRgn.Addr(), 3, uintptr(hwnd), uintptr(rgn), uintptr(_p0))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _SetWindowText(hwnd syscall.Handle, text *uint16) (err error) {
+	r1, _, e1 := syscall.Syscall(procSetWindowTextW.Addr(), 2, uintptr(hwnd), uintptr(unsafe.Pointer(text)), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _ShowWindow(hwnd syscall.Handle, cmdshow int32) (wasvisible bool) {
+	r0, _, _ := syscall.Syscall(procShowWindow.Addr(), 2, uintptr(hwnd), uintptr(cmdshow), 0)
+	wasvisible = r0 != 0
+	return
+}
+
+func _ScreenToClient(hwnd syscall.Handle, lpPoint *_POINT) (ok bool) {
+	r0, _, _ := syscall.Syscall(procScreenToClient.Addr(), 2, uintptr(hwnd), uintptr(unsafe.Pointer(lpPoint)), 0)
+	ok = r0 != 0
+	return
+}
+
+func _ClientToScreen(hwnd syscall.Handle, lpPoint *_POINT) (ok bool) {
+	r0, _, _ := syscall.Syscall(procClientToScreen.Addr(), 2, uintptr(hwnd), uintptr(unsafe.Pointer(lpPoint)), 0)
+	ok = r0 != 0
+	return
+}
+
+func _ToUnicodeEx(wVirtKey uint32, wScanCode uint32, lpKeyState *byte, pwszBuff *uint16, cchBuff int32, wFlags uint32, dwhkl syscall.Handle) (ret int32) {
+	r0, _, _ := syscall.Syscall9(procToUnicodeEx.Addr(), 7, uintptr(wVirtKey), uintptr(wScanCode), uintptr(unsafe.Pointer(lpKeyState)), uintptr(unsafe.Pointer(pwszBuff)), uintptr(cchBuff), uintptr(wFlags), uintptr(dwhkl), 0, 0)
+	ret = int32(r0)
+	return
+}
+
+func _TrackMouseEvent(tme *_TRACKMOUSEEVENT) (err error) {
+	r1, _, e1 := syscall.Syscall(procTrackMouseEvent.Addr(), 1, uintptr(unsafe.Pointer(tme)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _TrackPopupMenu(menu syscall.Handle, flags uint32, x int32, y int32, reserved int32, hwnd syscall.Handle, rect *_RECT) (ret int32) {
+	r0, _, _ := syscall.Syscall9(procTrackPopupMenu.Addr(), 7, uintptr(menu), uintptr(flags), uintptr(x), uintptr(y), uintptr(reserved), uintptr(hwnd), uintptr(unsafe.Pointer(rect)), 0, 0)
+	ret = int32(r0)
+	return
+}
+
+func _TranslateAccelerator(hwnd syscall.Handle, accel syscall.Handle, msg *_MSG) (ret int32) {
+	r0, _, _ := syscall.Syscall(procTranslateAcceleratorW.Addr(), 3, uintptr(hwnd), uintptr(accel), uintptr(unsafe.Pointer(msg)))
 	ret = int32(r0)
 	return
 }
@@ -284,3 +911,287 @@ func _TranslateMessage(msg *_MSG) (done bool) {
 	done = r0 != 0
 	return
 }
+
+func _UnregisterHotKey(hwnd syscall.Handle, id int32) (err error) {
+	r1, _, e1 := syscall.Syscall(procUnregisterHotKey.Addr(), 2, uintptr(hwnd), uintptr(id), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _SetWinEventHook(eventMin uint32, eventMax uint32, module syscall.Handle, fn uintptr, process uint32, thread uint32, flags uint32) (hook syscall.Handle) {
+	r0, _, _ := syscall.Syscall9(procSetWinEventHook.Addr(), 7, uintptr(eventMin), uintptr(eventMax), uintptr(module), uintptr(fn), uintptr(process), uintptr(thread), uintptr(flags), 0, 0)
+	hook = syscall.Handle(r0)
+	return
+}
+
+func _UnhookWinEvent(hook syscall.Handle) (ok bool) {
+	r0, _, _ := syscall.Syscall(procUnhookWinEvent.Addr(), 1, uintptr(hook), 0, 0)
+	ok = r0 != 0
+	return
+}
+
+func _GlobalAlloc(flags uint32, size uintptr) (mem syscall.Handle, err error) {
+	r0, _, e1 := syscall.Syscall(procGlobalAlloc.Addr(), 2, uintptr(flags), uintptr(size), 0)
+	mem = syscall.Handle(r0)
+	if mem == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _GlobalFree(mem syscall.Handle) (err error) {
+	r1, _, e1 := syscall.Syscall(procGlobalFree.Addr(), 1, uintptr(mem), 0, 0)
+	if r1 != 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _GlobalLock(mem syscall.Handle) (ptr uintptr, err error) {
+	r0, _, e1 := syscall.Syscall(procGlobalLock.Addr(), 1, uintptr(mem), 0, 0)
+	ptr = uintptr(r0)
+	if ptr == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _GlobalSize(mem syscall.Handle) (size uintptr) {
+	r0, _, _ := syscall.Syscall(procGlobalSize.Addr(), 1, uintptr(mem), 0, 0)
+	size = uintptr(r0)
+	return
+}
+
+func _GlobalUnlock(mem syscall.Handle) {
+	syscall.Syscall(procGlobalUnlock.Addr(), 1, uintptr(mem), 0, 0)
+	return
+}
+
+func _ImmGetCompositionString(imc syscall.Handle, index uint32, buf unsafe.Pointer, bufLen uint32) (ret int32) {
+	r0, _, _ := syscall.Syscall6(procImmGetCompositionStringW.Addr(), 4, uintptr(imc), uintptr(index), uintptr(buf), uintptr(bufLen), 0, 0)
+	ret = int32(r0)
+	return
+}
+
+func _ImmGetContext(hwnd syscall.Handle) (imc syscall.Handle) {
+	r0, _, _ := syscall.Syscall(procImmGetContext.Addr(), 1, uintptr(hwnd), 0, 0)
+	imc = syscall.Handle(r0)
+	return
+}
+
+func _ImmReleaseContext(hwnd syscall.Handle, imc syscall.Handle) (ok bool) {
+	r0, _, _ := syscall.Syscall(procImmReleaseContext.Addr(), 2, uintptr(hwnd), uintptr(imc), 0)
+	ok = r0 != 0
+	return
+}
+
+func _ImmSetCandidateWindow(imc syscall.Handle, form *_CANDIDATEFORM) (ok bool) {
+	r0, _, _ := syscall.Syscall(procImmSetCandidateWindow.Addr(), 2, uintptr(imc), uintptr(unsafe.Pointer(form)), 0)
+	ok = r0 != 0
+	return
+}
+
+func _ImmSetCompositionWindow(imc syscall.Handle, form *_COMPOSITIONFORM) (ok bool) {
+	r0, _, _ := syscall.Syscall(procImmSetCompositionWindow.Addr(), 2, uintptr(imc), uintptr(unsafe.Pointer(form)), 0)
+	ok = r0 != 0
+	return
+}
+
+func _GetDpiForMonitor(monitor syscall.Handle, dpiType uint32, dpiX *uint32, dpiY *uint32) (hr int32) {
+	r0, _, _ := syscall.Syscall6(procGetDpiForMonitor.Addr(), 4, uintptr(monitor), uintptr(dpiType), uintptr(unsafe.Pointer(dpiX)), uintptr(unsafe.Pointer(dpiY)), 0, 0)
+	hr = int32(r0)
+	return
+}
+
+func _DwmFlush() (hr int32) {
+	r0, _, _ := syscall.Syscall(procDwmFlush.Addr(), 0, 0, 0, 0)
+	hr = int32(r0)
+	return
+}
+
+func _DwmGetWindowAttribute(hwnd syscall.Handle, attr uint32, value unsafe.Pointer, size uint32) (hr int32) {
+	r0, _, _ := syscall.Syscall6(procDwmGetWindowAttribute.Addr(), 4, uintptr(hwnd), uintptr(attr), uintptr(value), uintptr(size), 0, 0)
+	hr = int32(r0)
+	return
+}
+
+func _CoCreateInstance(clsid *_GUID, outer uintptr, clsContext uint32, iid *_GUID, obj *uintptr) (hr int32) {
+	r0, _, _ := syscall.Syscall6(procCoCreateInstance.Addr(), 5, uintptr(unsafe.Pointer(clsid)), uintptr(outer), uintptr(clsContext), uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(obj)), 0)
+	hr = int32(r0)
+	return
+}
+
+func _CoTaskMemFree(mem uintptr) {
+	syscall.Syscall(procCoTaskMemFree.Addr(), 1, uintptr(mem), 0, 0)
+	return
+}
+
+func _DoDragDrop(dataObject uintptr, dropSource uintptr, okEffects uint32, effect *uint32) (hr int32) {
+	r0, _, _ := syscall.Syscall6(procDoDragDrop.Addr(), 4, uintptr(dataObject), uintptr(dropSource), uintptr(okEffects), uintptr(unsafe.Pointer(effect)), 0, 0)
+	hr = int32(r0)
+	return
+}
+
+func _OleInitialize(reserved uintptr) (hr int32) {
+	r0, _, _ := syscall.Syscall(procOleInitialize.Addr(), 1, uintptr(reserved), 0, 0)
+	hr = int32(r0)
+	return
+}
+
+func _RegisterDragDrop(hwnd syscall.Handle, dropTarget uintptr) (hr int32) {
+	r0, _, _ := syscall.Syscall(procRegisterDragDrop.Addr(), 2, uintptr(hwnd), uintptr(dropTarget), 0)
+	hr = int32(r0)
+	return
+}
+
+func _ReleaseStgMedium(medium *_STGMEDIUM) {
+	syscall.Syscall(procReleaseStgMedium.Addr(), 1, uintptr(unsafe.Pointer(medium)), 0, 0)
+	return
+}
+
+func _RevokeDragDrop(hwnd syscall.Handle) (hr int32) {
+	r0, _, _ := syscall.Syscall(procRevokeDragDrop.Addr(), 1, uintptr(hwnd), 0, 0)
+	hr = int32(r0)
+	return
+}
+
+func _SafeArrayCreateVector(vt uint16, lbound int32, n uint32) (sa uintptr) {
+	r0, _, _ := syscall.Syscall(procSafeArrayCreateVector.Addr(), 3, uintptr(vt), uintptr(lbound), uintptr(n))
+	sa = uintptr(r0)
+	return
+}
+
+func _SafeArrayPutElement(sa uintptr, index *int32, v unsafe.Pointer) (hr int32) {
+	r0, _, _ := syscall.Syscall(procSafeArrayPutElement.Addr(), 3, uintptr(sa), uintptr(unsafe.Pointer(index)), uintptr(v))
+	hr = int32(r0)
+	return
+}
+
+func _SysAllocString(s *uint16) (bstr uintptr) {
+	r0, _, _ := syscall.Syscall(procSysAllocString.Addr(), 1, uintptr(unsafe.Pointer(s)), 0, 0)
+	bstr = uintptr(r0)
+	return
+}
+
+func _UiaClientsAreListening() (listening bool) {
+	r0, _, _ := syscall.Syscall(procUiaClientsAreListening.Addr(), 0, 0, 0, 0)
+	listening = r0 != 0
+	return
+}
+
+func _UiaHostProviderFromHwnd(hwnd syscall.Handle, provider *uintptr) (hr int32) {
+	r0, _, _ := syscall.Syscall(procUiaHostProviderFromHwnd.Addr(), 2, uintptr(hwnd), uintptr(unsafe.Pointer(provider)), 0)
+	hr = int32(r0)
+	return
+}
+
+func _UiaRaiseAutomationEvent(provider uintptr, id int32) (hr int32) {
+	r0, _, _ := syscall.Syscall(procUiaRaiseAutomationEvent.Addr(), 2, uintptr(provider), uintptr(id), 0)
+	hr = int32(r0)
+	return
+}
+
+func _UiaReturnRawElementProvider(hwnd syscall.Handle, wParam uintptr, lParam uintptr, provider uintptr) (lResult uintptr) {
+	r0, _, _ := syscall.Syscall6(procUiaReturnRawElementProvider.Addr(), 4, uintptr(hwnd), uintptr(wParam), uintptr(lParam), uintptr(provider), 0, 0)
+	lResult = uintptr(r0)
+	return
+}
+
+func _DragQueryFile(drop syscall.Handle, file uint32, name *uint16, size uint32) (n uint32) {
+	r0, _, _ := syscall.Syscall6(procDragQueryFileW.Addr(), 4, uintptr(drop), uintptr(file), uintptr(unsafe.Pointer(name)), uintptr(size), 0, 0)
+	n = uint32(r0)
+	return
+}
+
+func _SHCreateDataObject(folder uintptr, count uint32, items uintptr, inner uintptr, iid *_GUID, obj *uintptr) (hr int32) {
+	r0, _, _ := syscall.Syscall6(procSHCreateDataObject.Addr(), 6, uintptr(folder), uintptr(count), uintptr(items), uintptr(inner), uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(obj)))
+	hr = int32(r0)
+	return
+}
+
+func _SHCreateItemFromParsingName(path *uint16, bindCtx uintptr, iid *_GUID, obj *uintptr) (hr int32) {
+	r0, _, _ := syscall.Syscall6(procSHCreateItemFromParsingName.Addr(), 4, uintptr(unsafe.Pointer(path)), uintptr(bindCtx), uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(obj)), 0, 0)
+	hr = int32(r0)
+	return
+}
+
+func _Shell_NotifyIcon(message uint32, data *_NOTIFYICONDATA) (ok bool) {
+	r0, _, _ := syscall.Syscall(procShell_NotifyIconW.Addr(), 2, uintptr(message), uintptr(unsafe.Pointer(data)), 0)
+	ok = r0 != 0
+	return
+}
+
+func _CombineRgn(dst syscall.Handle, src1 syscall.Handle, src2 syscall.Handle, mode int32) (ret int32) {
+	r0, _, _ := syscall.Syscall6(procCombineRgn.Addr(), 4, uintptr(dst), uintptr(src1), uintptr(src2), uintptr(mode), 0, 0)
+	ret = int32(r0)
+	return
+}
+
+func _CreateBitmap(width int32, height int32, planes uint32, bitCount uint32, bits unsafe.Pointer) (bitmap syscall.Handle, err error) {
+	r0, _, e1 := syscall.Syscall6(procCreateBitmap.Addr(), 5, uintptr(width), uintptr(height), uintptr(planes), uintptr(bitCount), uintptr(bits), 0)
+	bitmap = syscall.Handle(r0)
+	if bitmap == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _CreateDIBSection(dc syscall.Handle, bmi *_BITMAPINFOHEADER, usage uint32, bits *unsafe.Pointer, section syscall.Handle, offset uint32) (bitmap syscall.Handle, err error) {
+	r0, _, e1 := syscall.Syscall6(procCreateDIBSection.Addr(), 6, uintptr(dc), uintptr(unsafe.Pointer(bmi)), uintptr(usage), uintptr(unsafe.Pointer(bits)), uintptr(section), uintptr(offset))
+	bitmap = syscall.Handle(r0)
+	if bitmap == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _CreateRectRgn(left int32, top int32, right int32, bottom int32) (rgn syscall.Handle, err error) {
+	r0, _, e1 := syscall.Syscall6(procCreateRectRgn.Addr(), 4, uintptr(left), uintptr(top), uintptr(right), uintptr(bottom), 0, 0)
+	rgn = syscall.Handle(r0)
+	if rgn == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _DeleteObject(object syscall.Handle) (err error) {
+	r1, _, e1 := syscall.Syscall(procDeleteObject.Addr(), 1, uintptr(object), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = errnoErr(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
@@ -0,0 +1,184 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11key
+
+import (
+	"strings"
+
+	"golang.org/x/mobile/event/key"
+)
+
+// PhysicalCode returns the code of the physical key whose keycode is the
+// given one, independent of the keyboard layout, or key.CodeUnknown if it is
+// not known. It assumes evdev keycodes, which are the Linux kernel's input
+// event codes plus 8, as used by X servers whose XKB rules are "evdev", and by
+// Wayland compositors.
+func PhysicalCode(keycode uint8) key.Code {
+	if i := int(keycode) - 8; 0 <= i && i < len(evdevCodes) {
+		return evdevCodes[i]
+	}
+	return key.CodeUnknown
+}
+
+// evdevCodes maps from the Linux kernel's input event codes, from
+// /usr/include/linux/input-event-codes.h, to key.Code values.
+var evdevCodes = [...]key.Code{
+	1:   key.CodeEscape,
+	2:   key.Code1,
+	3:   key.Code2,
+	4:   key.Code3,
+	5:   key.Code4,
+	6:   key.Code5,
+	7:   key.Code6,
+	8:   key.Code7,
+	9:   key.Code8,
+	10:  key.Code9,
+	11:  key.Code0,
+	12:  key.CodeHyphenMinus,
+	13:  key.CodeEqualSign,
+	14:  key.CodeDeleteBackspace,
+	15:  key.CodeTab,
+	16:  key.CodeQ,
+	17:  key.CodeW,
+	18:  key.CodeE,
+	19:  key.CodeR,
+	20:  key.CodeT,
+	21:  key.CodeY,
+	22:  key.CodeU,
+	23:  key.CodeI,
+	24:  key.CodeO,
+	25:  key.CodeP,
+	26:  key.CodeLeftSquareBracket,
+	27:  key.CodeRightSquareBracket,
+	28:  key.CodeReturnEnter,
+	29:  key.CodeLeftControl,
+	30:  key.CodeA,
+	31:  key.CodeS,
+	32:  key.CodeD,
+	33:  key.CodeF,
+	34:  key.CodeG,
+	35:  key.CodeH,
+	36:  key.CodeJ,
+	37:  key.CodeK,
+	38:  key.CodeL,
+	39:  key.CodeSemicolon,
+	40:  key.CodeApostrophe,
+	41:  key.CodeGraveAccent,
+	42:  key.CodeLeftShift,
+	43:  key.CodeBackslash,
+	44:  key.CodeZ,
+	45:  key.CodeX,
+	46:  key.CodeC,
+	47:  key.CodeV,
+	48:  key.CodeB,
+	49:  key.CodeN,
+	50:  key.CodeM,
+	51:  key.CodeComma,
+	52:  key.CodeFullStop,
+	53:  key.CodeSlash,
+	54:  key.CodeRightShift,
+	55:  key.CodeKeypadAsterisk,
+	56:  key.CodeLeftAlt,
+	57:  key.CodeSpacebar,
+	58:  key.CodeCapsLock,
+	59:  key.CodeF1,
+	60:  key.CodeF2,
+	61:  key.CodeF3,
+	62:  key.CodeF4,
+	63:  key.CodeF5,
+	64:  key.CodeF6,
+	65:  key.CodeF7,
+	66:  key.CodeF8,
+	67:  key.CodeF9,
+	68:  key.CodeF10,
+	69:  key.CodeKeypadNumLock,
+	71:  key.CodeKeypad7,
+	72:  key.CodeKeypad8,
+	73:  key.CodeKeypad9,
+	74:  key.CodeKeypadHyphenMinus,
+	75:  key.CodeKeypad4,
+	76:  key.CodeKeypad5,
+	77:  key.CodeKeypad6,
+	78:  key.CodeKeypadPlusSign,
+	79:  key.CodeKeypad1,
+	80:  key.CodeKeypad2,
+	81:  key.CodeKeypad3,
+	82:  key.CodeKeypad0,
+	83:  key.CodeKeypadFullStop,
+	87:  key.CodeF11,
+	88:  key.CodeF12,
+	96:  key.CodeKeypadEnter,
+	97:  key.CodeRightControl,
+	98:  key.CodeKeypadSlash,
+	100: key.CodeRightAlt,
+	102: key.CodeHome,
+	103: key.CodeUpArrow,
+	104: key.CodePageUp,
+	105: key.CodeLeftArrow,
+	106: key.CodeRightArrow,
+	107: key.CodeEnd,
+	108: key.CodeDownArrow,
+	109: key.CodePageDown,
+	110: key.CodeInsert,
+	111: key.CodeDeleteForward,
+	113: key.CodeMute,
+	114: key.CodeVolumeDown,
+	115: key.CodeVolumeUp,
+	117: key.CodeKeypadEqualSign,
+	119: key.CodePause,
+	125: key.CodeLeftGUI,
+	126: key.CodeRightGUI,
+	127: key.CodeCompose,
+	138: key.CodeHelp,
+	183: key.CodeF13,
+	184: key.CodeF14,
+	185: key.CodeF15,
+	186: key.CodeF16,
+	187: key.CodeF17,
+	188: key.CodeF18,
+	189: key.CodeF19,
+	190: key.CodeF20,
+	191: key.CodeF21,
+	192: key.CodeF22,
+	193: key.CodeF23,
+	194: key.CodeF24,
+}
+
+// RulesNames are the names that an X server's keyboard mapping was made
+// from, as in the _XKB_RULES_NAMES property of the root window, which holds
+// them as consecutive NUL-terminated strings.
+type RulesNames struct {
+	Rules, Model, Layout, Variant, Options string
+}
+
+// ParseRulesNames parses the value of an _XKB_RULES_NAMES property.
+func ParseRulesNames(b []byte) RulesNames {
+	var f [5]string
+	for i := range f {
+		j := strings.IndexByte(string(b), 0)
+		if j < 0 {
+			f[i] = string(b)
+			break
+		}
+		f[i], b = string(b[:j]), b[j+1:]
+	}
+	return RulesNames{f[0], f[1], f[2], f[3], f[4]}
+}
+
+// LayoutName returns the name of the layout of the given group, such as "fr"
+// or, with a variant, "us(dvorak)". The Layout and Variant names list one
+// layout or variant per group, separated by commas, such as "us,fr" and
+// "dvorak,". It returns "" if there is no such group.
+func (n *RulesNames) LayoutName(group int) string {
+	layouts := strings.Split(n.Layout, ",")
+	if group < 0 || len(layouts) <= group || layouts[group] == "" {
+		return ""
+	}
+	name := layouts[group]
+	if variants := strings.Split(n.Variant, ","); group < len(variants) && variants[group] != "" {
+		name += "(" + variants[group] + ")"
+	}
+	return name
+}
@@ -26,35 +26,104 @@ const (
 	Button5Mask = 1 << 12
 )
 
-type KeysymTable [256][2]uint32
+// KeysymTable holds, for each keycode, the unshifted and shifted keysyms of
+// the first two groups, or layouts, of a keyboard mapping: those that the
+// core X11 protocol can describe.
+type KeysymTable [256][4]uint32
 
+// Lookup returns the rune and code of a key event. The rune is that of the
+// keysym for the event's group and shift state, or -1 if the keysym is not a
+// Unicode character. The code is that of the US keyboard's key for the first
+// group's unshifted keysym, so that it does not depend on the group, but it
+// does depend on the first group's layout, such as key.CodeA for the AZERTY
+// layout's 'a' key, where a QWERTY keyboard's 'q' key is.
 func (t *KeysymTable) Lookup(detail uint8, state uint16) (rune, key.Code) {
+	syms := t.groupKeysyms(detail, state)
+
 	// The key event's rune depends on whether the shift key is down.
-	unshifted := rune(t[detail][0])
-	r := unshifted
+	r := KeysymRune(syms[0])
 	if state&ShiftMask != 0 {
-		r = rune(t[detail][1])
 		// In X11, a zero keysym when shift is down means to use what the
 		// keysym is when shift is up.
-		if r == 0 {
-			r = unshifted
+		if syms[1] != 0 {
+			r = KeysymRune(syms[1])
 		}
 	}
 
 	// The key event's code is independent of whether the shift key is down.
 	var c key.Code
-	if 0 <= unshifted && unshifted < 0x80 {
+	if unshifted := rune(t[detail][0]); 0 <= unshifted && unshifted < 0x80 {
 		// TODO: distinguish the regular '2' key and number-pad '2' key (with
 		// Num-Lock).
 		c = asciiKeycodes[unshifted]
-	} else {
-		r, c = -1, nonUnicodeKeycodes[unshifted]
+	} else if x, ok := nonUnicodeKeycodes[unshifted]; ok {
+		r, c = -1, x
 	}
-
-	// TODO: Unicode-but-not-ASCII keysyms like the Swiss keyboard's 'ö'.
 	return r, c
 }
 
+// Unmodified returns the rune of the unshifted keysym of a key event's key,
+// in the event's group, or -1 if it is not a Unicode character.
+func (t *KeysymTable) Unmodified(detail uint8, state uint16) rune {
+	if _, ok := nonUnicodeKeycodes[rune(t[detail][0])]; ok {
+		return -1
+	}
+	return KeysymRune(t.groupKeysyms(detail, state)[0])
+}
+
+// groupKeysyms returns the unshifted and shifted keysyms of the key in the
+// state's group. As in X11, a key with no keysyms in the second group has
+// those of the first group.
+func (t *KeysymTable) groupKeysyms(detail uint8, state uint16) []uint32 {
+	syms := t[detail][:]
+	if Group(state) != 0 && (syms[2] != 0 || syms[3] != 0) {
+		return syms[2:4]
+	}
+	return syms[0:2]
+}
+
+// Group returns the XKB group, or layout, of a key or pointer event's state:
+// 0 for the first group, 1 for the second and so on. The XKB extension
+// reports it in bits 13 and 14 of the state, even to clients that do not use
+// the extension.
+func Group(state uint16) int {
+	return int(state>>13) & 3
+}
+
+// Keycode returns the keycode of the key whose code is c, or zero if there is
+// no such key.
+func (t *KeysymTable) Keycode(c key.Code) uint8 {
+	// Keycodes below 8 are never used.
+	for detail := 8; detail < len(t); detail++ {
+		if _, got := t.Lookup(uint8(detail), 0); got == c {
+			return uint8(detail)
+		}
+	}
+	return 0
+}
+
+// LockMasks are the combinations of the Caps Lock and Num Lock modifiers. A
+// key grab only matches key presses with exactly its modifiers, so a hot key
+// is grabbed once with each of them, to work whichever locks are on.
+var LockMasks = [...]uint16{0, LockMask, Mod2Mask, LockMask | Mod2Mask}
+
+// ModifierState returns the modifier state, the inverse of KeyModifiers.
+func ModifierState(m key.Modifiers) (state uint16) {
+	if m&key.ModShift != 0 {
+		state |= ShiftMask
+	}
+	if m&key.ModControl != 0 {
+		state |= ControlMask
+	}
+	if m&key.ModAlt != 0 {
+		state |= Mod1Mask
+	}
+	if m&key.ModMeta != 0 {
+		state |= Mod4Mask
+	}
+	return state
+}
+
 func KeyModifiers(state uint16) (m key.Modifiers) {
 	if state&ShiftMask != 0 {
 		m |= key.ModShift
@@ -71,6 +140,54 @@ func KeyModifiers(state uint16) (m key.Modifiers) {
 	return m
 }
 
+// KeysymCode returns the rune and code of a keysym, such as that of a VNC
+// client's key event. Unlike the keysyms of a KeysymTable, it is the keysym
+// of the key and the modifiers together, such as 'A' when shift is down, so
+// its code is that of the US keyboard's key for it. The rune is -1 for a
+// keysym that is not a Unicode character, such as "Page Up" or "F1".
+func KeysymCode(keysym uint32) (rune, key.Code) {
+	if c, ok := nonUnicodeKeycodes[rune(keysym)]; ok {
+		return -1, c
+	}
+	r := KeysymRune(keysym)
+	if r < 0 {
+		return -1, key.CodeUnknown
+	}
+	u := r
+	if 'A' <= u && u <= 'Z' {
+		u += 'a' - 'A'
+	} else if x, ok := usShifted[u]; ok {
+		u = x
+	}
+	if u < 0x80 {
+		return r, asciiKeycodes[u]
+	}
+	return r, key.CodeUnknown
+}
+
+// KeysymRune returns the Unicode character of a keysym, or -1 if it is not
+// one, such as "Page Up", "F1" or a keysym for a character without a Unicode
+// code point of its own, such as a dead key.
+func KeysymRune(keysym uint32) rune {
+	switch {
+	case 0x20 <= keysym && keysym < 0x7f, 0xa0 <= keysym && keysym < 0x100:
+		// Latin-1 keysyms are their Unicode code points.
+		return rune(keysym)
+	case 0x01000100 <= keysym && keysym < 0x01110000:
+		return rune(keysym - 0x01000000)
+	}
+	return -1
+}
+
+// usShifted maps the ASCII runes typed with shift on a US keyboard to those
+// of the same keys without shift.
+var usShifted = map[rune]rune{
+	'!': '1', '@': '2', '#': '3', '$': '4', '%': '5',
+	'^': '6', '&': '7', '*': '8', '(': '9', ')': '0',
+	'_': '-', '+': '=', '{': '[', '}': ']', '|': '\\',
+	':': ';', '"': '\'', '~': '`', '<': ',', '>': '.', '?': '/',
+}
+
 // These constants come from /usr/include/X11/{keysymdef,XF86keysym}.h
 const (
 	xkISOLeftTab = 0xfe20
@@ -0,0 +1,82 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11key
+
+import (
+	"testing"
+
+	"golang.org/x/mobile/event/key"
+)
+
+func TestLookup(t *testing.T) {
+	// Keycode 24 is a QWERTY keyboard's 'q' key. Here, the first group is a
+	// French AZERTY layout, and the second is a Russian layout. Keycode 11 is
+	// the '2' key, and keycode 36 is Return.
+	var kt KeysymTable
+	kt[24] = [4]uint32{'a', 'A', 0x01000439, 0x01000419}
+	kt[11] = [4]uint32{0xe9, '2'}
+	kt[36] = [4]uint32{xkReturn}
+	const group2 = 1 << 13
+
+	testCases := []struct {
+		detail         uint8
+		state          uint16
+		wantRune       rune
+		wantCode       key.Code
+		wantUnmodified rune
+	}{
+		{24, 0, 'a', key.CodeA, 'a'},
+		{24, ShiftMask | ControlMask, 'A', key.CodeA, 'a'},
+		{24, group2, 'й', key.CodeA, 'й'},
+		{24, group2 | ShiftMask, 'Й', key.CodeA, 'й'},
+		{11, 0, 'é', key.CodeUnknown, 'é'},
+		{11, ShiftMask, '2', key.CodeUnknown, 'é'},
+		// The second group has no keysyms for keycode 11, so it has those of
+		// the first group.
+		{11, group2, 'é', key.CodeUnknown, 'é'},
+		{36, ShiftMask, -1, key.CodeReturnEnter, -1},
+	}
+	for _, tc := range testCases {
+		r, c := kt.Lookup(tc.detail, tc.state)
+		if r != tc.wantRune || c != tc.wantCode {
+			t.Errorf("Lookup(%d, %#x): got %q, %v, want %q, %v", tc.detail, tc.state, r, c, tc.wantRune, tc.wantCode)
+		}
+		if got := kt.Unmodified(tc.detail, tc.state); got != tc.wantUnmodified {
+			t.Errorf("Unmodified(%d, %#x): got %q, want %q", tc.detail, tc.state, got, tc.wantUnmodified)
+		}
+	}
+}
+
+func TestPhysicalCode(t *testing.T) {
+	testCases := []struct {
+		keycode uint8
+		want    key.Code
+	}{
+		{0, key.CodeUnknown},
+		{9, key.CodeEscape},
+		{24, key.CodeQ},
+		{38, key.CodeA},
+		{111, key.CodeUpArrow},
+		{255, key.CodeUnknown},
+	}
+	for _, tc := range testCases {
+		if got := PhysicalCode(tc.keycode); got != tc.want {
+			t.Errorf("PhysicalCode(%d): got %v, want %v", tc.keycode, got, tc.want)
+		}
+	}
+}
+
+func TestRulesNames(t *testing.T) {
+	n := ParseRulesNames([]byte("evdev\x00pc105\x00us,fr\x00dvorak,\x00grp:alt_shift_toggle\x00"))
+	want := RulesNames{"evdev", "pc105", "us,fr", "dvorak,", "grp:alt_shift_toggle"}
+	if n != want {
+		t.Fatalf("ParseRulesNames: got %+v, want %+v", n, want)
+	}
+	for group, want := range []string{"us(dvorak)", "fr", ""} {
+		if got := n.LayoutName(group); got != want {
+			t.Errorf("LayoutName(%d): got %q, want %q", group, got, want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yuv
+
+// useSSE2 is always true, as every amd64 CPU supports SSE2 instructions.
+const useSSE2 = true
+
+func convert8(dst, y, u, v []byte, k *coefficients)
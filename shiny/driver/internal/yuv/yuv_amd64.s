@@ -0,0 +1,103 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "textflag.h"
+
+// func convert8(dst, y, u, v []byte, k *coefficients)
+//
+// It converts 8 pixels per iteration, and so len(y) must be a multiple of 8,
+// with len(u) and len(v) at least len(y)/2 and len(dst) at least 4*len(y).
+// The arithmetic matches that of the pixel function exactly.
+TEXT ·convert8(SB),NOSPLIT,$0-104
+	MOVQ	dst_base+0(FP), DI
+	MOVQ	y_base+24(FP), SI
+	MOVQ	y_len+32(FP), DX
+	MOVQ	u_base+48(FP), BX
+	MOVQ	v_base+72(FP), CX
+	MOVQ	k+96(FP), AX
+
+	// Sanity check that len(y) is a multiple of 8.
+	MOVQ	DX, R8
+	ANDQ	$7, R8
+	JNZ	done
+
+	// Load each coefficient, already replicated across 8 words, along with
+	// the zero in X7 and the opaque alpha bytes in X6.
+	MOVOU	0(AX), X8
+	MOVOU	16(AX), X9
+	MOVOU	32(AX), X10
+	MOVOU	48(AX), X11
+	MOVOU	64(AX), X12
+	MOVOU	80(AX), X13
+	MOVOU	96(AX), X14
+	MOVOU	112(AX), X15
+	PXOR	X7, X7
+	PCMPEQB	X6, X6
+
+	ADDQ	SI, DX
+loop:
+	CMPQ	SI, DX
+	JEQ	done
+
+	// X0 = mulhi((y - yOffset) << 7, y coefficient), as 8 words.
+	MOVQ	(SI), X0
+	PUNPCKLBW	X7, X0
+	PSUBW	X8, X0
+	PSLLW	$7, X0
+	PMULHW	X9, X0
+
+	// X1 = (u - 128) << 7 and X2 = (v - 128) << 7, with each of the 4
+	// chroma samples duplicated for 2 pixels.
+	MOVL	(BX), X1
+	PUNPCKLBW	X1, X1
+	PUNPCKLBW	X7, X1
+	PSUBW	X14, X1
+	PSLLW	$7, X1
+	MOVL	(CX), X2
+	PUNPCKLBW	X2, X2
+	PUNPCKLBW	X7, X2
+	PSUBW	X14, X2
+	PSLLW	$7, X2
+
+	// X3, X4 and X5 are red, green and blue, with 3 fractional bits.
+	MOVO	X2, X3
+	PMULHW	X10, X3
+	PADDW	X0, X3
+	MOVO	X1, X4
+	PMULHW	X11, X4
+	MOVO	X2, X5
+	PMULHW	X12, X5
+	PADDW	X5, X4
+	PADDW	X0, X4
+	MOVO	X1, X5
+	PMULHW	X13, X5
+	PADDW	X0, X5
+
+	// Round, and saturate to bytes.
+	PADDW	X15, X3
+	PSRAW	$3, X3
+	PACKUSWB	X3, X3
+	PADDW	X15, X4
+	PSRAW	$3, X4
+	PACKUSWB	X4, X4
+	PADDW	X15, X5
+	PSRAW	$3, X5
+	PACKUSWB	X5, X5
+
+	// Interleave the red, green, blue and alpha bytes.
+	PUNPCKLBW	X4, X3
+	PUNPCKLBW	X6, X5
+	MOVO	X3, X4
+	PUNPCKLWL	X5, X3
+	PUNPCKHWL	X5, X4
+	MOVOU	X3, 0(DI)
+	MOVOU	X4, 16(DI)
+
+	ADDQ	$8, SI
+	ADDQ	$4, BX
+	ADDQ	$4, CX
+	ADDQ	$32, DI
+	JMP	loop
+done:
+	RET
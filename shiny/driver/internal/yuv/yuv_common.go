@@ -0,0 +1,201 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package yuv provides functions for converting YUV images to RGBA.
+package yuv // import "golang.org/x/exp/shiny/driver/internal/yuv"
+
+import (
+	"image"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// coefficients are a YUV to RGB conversion matrix in fixed point. The y, rv,
+// gu, gv and bu matrix elements are multiples of 1/4096. Each field holds 8
+// copies of its value, so that SIMD code can load it directly.
+type coefficients struct {
+	yOffset, y, rv, gu, gv, bu [8]int16
+	uvOffset, round            [8]int16
+}
+
+func makeCoefficients(yOffset, y, rv, gu, gv, bu int16) *coefficients {
+	k := &coefficients{}
+	for i := 0; i < 8; i++ {
+		k.yOffset[i] = yOffset
+		k.y[i] = y
+		k.rv[i] = rv
+		k.gu[i] = gu
+		k.gv[i] = gv
+		k.bu[i] = bu
+		k.uvOffset[i] = 128
+		k.round[i] = 4
+	}
+	return k
+}
+
+// coefficientTable is indexed by color space and then by whether the samples
+// use the full range.
+var coefficientTable = [2][2]*coefficients{
+	screen.YUVBT601: {
+		makeCoefficients(16, 4769, 6537, -1605, -3330, 8263),
+		makeCoefficients(0, 4096, 5743, -1410, -2925, 7258),
+	},
+	screen.YUVBT709: {
+		makeCoefficients(16, 4769, 7343, -873, -2183, 8652),
+		makeCoefficients(0, 4096, 6450, -767, -1917, 7601),
+	},
+}
+
+func lookup(cs screen.YUVColorSpace, fullRange bool) *coefficients {
+	if int(cs) >= len(coefficientTable) {
+		cs = screen.YUVBT601
+	}
+	if fullRange {
+		return coefficientTable[cs][1]
+	}
+	return coefficientTable[cs][0]
+}
+
+// Matrix is a YUV to RGB conversion matrix, for samples normalized to the
+// range [0, 1], where c is 128/255:
+//
+//	R = Y*(y-YOffset)              + RV*(v-c)
+//	G = Y*(y-YOffset) + GU*(u-c) + GV*(v-c)
+//	B = Y*(y-YOffset) + BU*(u-c)
+type Matrix struct {
+	YOffset, Y, RV, GU, GV, BU float32
+}
+
+// MatrixFor returns the Matrix for a YUVImage's color space and range. It is
+// the same matrix that Convert uses, so that GPU and software conversions
+// agree.
+func MatrixFor(cs screen.YUVColorSpace, fullRange bool) Matrix {
+	k := lookup(cs, fullRange)
+	return Matrix{
+		YOffset: float32(k.yOffset[0]) / 255,
+		Y:       float32(k.y[0]) / 4096,
+		RV:      float32(k.rv[0]) / 4096,
+		GU:      float32(k.gu[0]) / 4096,
+		GV:      float32(k.gv[0]) / 4096,
+		BU:      float32(k.bu[0]) / 4096,
+	}
+}
+
+// mulhi returns the high 16 bits of the 32-bit product of a and b, like the
+// PMULHW instruction.
+func mulhi(a, b int16) int16 {
+	return int16((int32(a) * int32(b)) >> 16)
+}
+
+func clamp(x int16) byte {
+	if x < 0 {
+		return 0
+	}
+	if x > 0xff {
+		return 0xff
+	}
+	return byte(x)
+}
+
+// pixel converts a single pixel to RGBA, writing it to dst[:4].
+func pixel(dst []byte, y, u, v byte, k *coefficients) {
+	c := mulhi((int16(y)-k.yOffset[0])<<7, k.y[0])
+	d := (int16(u) - 128) << 7
+	e := (int16(v) - 128) << 7
+	r := c + mulhi(e, k.rv[0])
+	g := c + mulhi(d, k.gu[0]) + mulhi(e, k.gv[0])
+	b := c + mulhi(d, k.bu[0])
+	dst[0] = clamp((r + 4) >> 3)
+	dst[1] = clamp((g + 4) >> 3)
+	dst[2] = clamp((b + 4) >> 3)
+	dst[3] = 0xff
+}
+
+// row converts the pixels whose luma samples are y to RGBA, writing them to
+// dst. The pixels y[2*i] and y[2*i+1] share the chroma samples u[i] and v[i].
+func row(dst, y, u, v []byte, k *coefficients) {
+	n := 0
+	if useSSE2 {
+		n = len(y) &^ 7
+		if n > 0 {
+			convert8(dst[:4*n], y[:n], u[:n/2], v[:n/2], k)
+		}
+	}
+	for i := n; i < len(y); i++ {
+		pixel(dst[4*i:], y[i], u[i/2], v[i/2], k)
+	}
+}
+
+// Convert converts the sub-image defined by src and sr to RGBA, writing it to
+// dst such that sr.Min in src-space aligns with dp in dst-space.
+func Convert(dst *image.RGBA, dp image.Point, src *screen.YUVImage, sr image.Rectangle) {
+	originalSRMin := sr.Min
+	sr = sr.Intersect(src.Rect)
+	if sr.Empty() {
+		return
+	}
+	dp = dp.Add(sr.Min.Sub(originalSRMin))
+	dr := image.Rectangle{dp, dp.Add(sr.Size())}.Intersect(dst.Rect)
+	if dr.Empty() {
+		return
+	}
+	sr.Min = sr.Min.Add(dr.Min.Sub(dp))
+
+	k := lookup(src.ColorSpace, src.FullRange)
+	width := dr.Dx()
+	var uBuf, vBuf []byte
+	if src.Format == screen.YUVNV12 {
+		uBuf = make([]byte, (width+2)/2)
+		vBuf = make([]byte, (width+2)/2)
+	}
+
+	for j := 0; j < dr.Dy(); j++ {
+		sx := sr.Min.X - src.Rect.Min.X
+		sy := sr.Min.Y + j - src.Rect.Min.Y
+		y := src.Y[sy*src.YStride+sx:][:width]
+		out := dst.Pix[dst.PixOffset(dr.Min.X, dr.Min.Y+j):][:4*width]
+		off := (sy / 2) * src.UVStride
+
+		// A leading pixel at an odd x shares its chroma samples, u[0] and
+		// v[0], with the pixel to its left, which is not converted. The
+		// remaining pixels start at an even x.
+		x := sx % 2
+		n := (width - x + 1) / 2
+
+		var u, v []byte
+		if src.Format == screen.YUVNV12 {
+			uv := src.U[off+2*(sx/2):][:2*(n+x)]
+			u, v = uBuf[:n+x], vBuf[:n+x]
+			for i := range u {
+				u[i], v[i] = uv[2*i], uv[2*i+1]
+			}
+		} else {
+			u, v = src.U[off+sx/2:][:n+x], src.V[off+sx/2:][:n+x]
+		}
+		if x != 0 {
+			pixel(out, y[0], u[0], v[0], k)
+		}
+		row(out[4*x:], y[x:], u[x:], v[x:], k)
+	}
+}
+
+// Upload implements the UploadYUV method of the screen.YUVUploader interface
+// for a dst that can only upload Buffers, by converting src into a Buffer
+// obtained from s.
+func Upload(s screen.Screen, dst screen.Uploader, dp image.Point, src *screen.YUVImage, sr image.Rectangle) {
+	originalSRMin := sr.Min
+	sr = sr.Intersect(src.Rect)
+	if sr.Empty() {
+		return
+	}
+	dp = dp.Add(sr.Min.Sub(originalSRMin))
+
+	b, err := s.NewBuffer(sr.Size())
+	if err != nil {
+		return
+	}
+	defer b.Release()
+	Convert(b.RGBA(), image.Point{}, src, sr)
+	dst.Upload(dp, b, b.Bounds())
+}
@@ -0,0 +1,11 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !amd64
+
+package yuv
+
+const useSSE2 = false
+
+func convert8(dst, y, u, v []byte, k *coefficients) { panic("unreachable") }
@@ -0,0 +1,133 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yuv
+
+import (
+	"bytes"
+	"image"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+func pureGoRow(dst, y, u, v []byte, k *coefficients) {
+	for i := range y {
+		pixel(dst[4*i:], y[i], u[i/2], v[i/2], k)
+	}
+}
+
+func TestRowRandomInput(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	y := make([]byte, 256)
+	u := make([]byte, 128)
+	v := make([]byte, 128)
+	fastBuf := make([]byte, 4*len(y))
+	slowBuf := make([]byte, 4*len(y))
+	for _, k := range []*coefficients{
+		lookup(screen.YUVBT601, false),
+		lookup(screen.YUVBT601, true),
+		lookup(screen.YUVBT709, false),
+		lookup(screen.YUVBT709, true),
+	} {
+		for i := 0; i < 1000; i++ {
+			r.Read(y)
+			r.Read(u)
+			r.Read(v)
+			n := r.Intn(len(y))
+			row(fastBuf, y[:n], u, v, k)
+			pureGoRow(slowBuf, y[:n], u, v, k)
+			if !bytes.Equal(fastBuf[:4*n], slowBuf[:4*n]) {
+				t.Fatalf("iter %d: n=%d: got % x, want % x", i, n, fastBuf[:4*n], slowBuf[:4*n])
+			}
+		}
+	}
+}
+
+func TestPixel(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		cs        screen.YUVColorSpace
+		fullRange bool
+		y, u, v   byte
+		want      [3]byte
+	}{
+		{"601 black", screen.YUVBT601, false, 16, 128, 128, [3]byte{0x00, 0x00, 0x00}},
+		{"601 white", screen.YUVBT601, false, 235, 128, 128, [3]byte{0xff, 0xff, 0xff}},
+		{"601 red", screen.YUVBT601, false, 81, 90, 240, [3]byte{0xff, 0x00, 0x00}},
+		{"601 full gray", screen.YUVBT601, true, 128, 128, 128, [3]byte{0x80, 0x80, 0x80}},
+		{"709 green", screen.YUVBT709, false, 173, 42, 26, [3]byte{0x00, 0xff, 0x00}},
+		{"709 blue", screen.YUVBT709, false, 32, 240, 118, [3]byte{0x00, 0x00, 0xff}},
+	}
+	for _, tc := range testCases {
+		var got [4]byte
+		pixel(got[:], tc.y, tc.u, tc.v, lookup(tc.cs, tc.fullRange))
+		for i := 0; i < 3; i++ {
+			if d := int(got[i]) - int(tc.want[i]); d < -2 || d > +2 {
+				t.Errorf("%s: got %v, want %v", tc.desc, got[:3], tc.want)
+				break
+			}
+		}
+		if got[3] != 0xff {
+			t.Errorf("%s: alpha: got %#02x, want 0xff", tc.desc, got[3])
+		}
+	}
+}
+
+// TestConvertFormats checks that I420 and NV12 images with equal samples
+// convert equally, including for sub-images at odd offsets.
+func TestConvertFormats(t *testing.T) {
+	const w, h = 21, 7
+	r := rand.New(rand.NewSource(1))
+	i420 := &screen.YUVImage{
+		Format:   screen.YUVI420,
+		Y:        make([]byte, w*h),
+		U:        make([]byte, 11*4),
+		V:        make([]byte, 11*4),
+		YStride:  w,
+		UVStride: 11,
+		Rect:     image.Rect(5, 5, 5+w, 5+h),
+	}
+	r.Read(i420.Y)
+	r.Read(i420.U)
+	r.Read(i420.V)
+	nv12 := &screen.YUVImage{
+		Format:   screen.YUVNV12,
+		Y:        i420.Y,
+		U:        make([]byte, 22*4),
+		YStride:  w,
+		UVStride: 22,
+		Rect:     i420.Rect,
+	}
+	for i := range i420.U {
+		nv12.U[2*i], nv12.U[2*i+1] = i420.U[i], i420.V[i]
+	}
+
+	for _, sr := range []image.Rectangle{
+		i420.Rect,
+		image.Rect(6, 6, 25, 11),
+		image.Rect(0, 0, 7, 7),
+		image.Rect(24, 5, 100, 100),
+	} {
+		a := image.NewRGBA(image.Rect(0, 0, w+2, h+2))
+		b := image.NewRGBA(image.Rect(0, 0, w+2, h+2))
+		Convert(a, image.Point{1, 1}, i420, sr)
+		Convert(b, image.Point{1, 1}, nv12, sr)
+		if !bytes.Equal(a.Pix, b.Pix) {
+			t.Errorf("sr=%v: I420 and NV12 conversions differ", sr)
+		}
+
+		// Check one pixel against a direct conversion.
+		p := sr.Intersect(i420.Rect).Min
+		q := image.Point{1, 1}.Add(p.Sub(sr.Min))
+		sx, sy := p.X-i420.Rect.Min.X, p.Y-i420.Rect.Min.Y
+		c := (sy/2)*i420.UVStride + sx/2
+		var want [4]byte
+		pixel(want[:], i420.Y[sy*w+sx], i420.U[c], i420.V[c], lookup(screen.YUVBT601, false))
+		if got := a.Pix[a.PixOffset(q.X, q.Y):][:4]; !bytes.Equal(got, want[:]) {
+			t.Errorf("sr=%v: pixel %v: got % x, want % x", sr, p, got, want)
+		}
+	}
+}
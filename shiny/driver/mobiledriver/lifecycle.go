@@ -0,0 +1,19 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mobiledriver
+
+import (
+	"golang.org/x/exp/shiny/driver/internal/lifecycler"
+	"golang.org/x/mobile/event/lifecycle"
+)
+
+// setStage sets a window's lifecycle state from the app's lifecycle stage.
+// The window is focused while the app is, and visible while it is, and it
+// dies with the app, once the app is being destroyed.
+func setStage(l *lifecycler.State, stage lifecycle.Stage) {
+	l.SetDead(stage == lifecycle.StageDead)
+	l.SetVisible(stage >= lifecycle.StageVisible)
+	l.SetFocused(stage >= lifecycle.StageFocused)
+}
@@ -0,0 +1,80 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mobiledriver
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/exp/shiny/driver/internal/lifecycler"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/lifecycle"
+)
+
+type sender struct {
+	events []lifecycle.Event
+	focus  []bool
+}
+
+func (s *sender) Send(event interface{}) {
+	if e, ok := event.(screen.FocusEvent); ok {
+		s.focus = append(s.focus, e.Focused)
+		return
+	}
+	s.events = append(s.events, event.(lifecycle.Event))
+}
+
+func TestSetStage(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		stages    []lifecycle.Stage
+		want      []lifecycle.Stage
+		wantFocus []bool
+	}{{
+		desc:      "start",
+		stages:    []lifecycle.Stage{lifecycle.StageAlive, lifecycle.StageVisible, lifecycle.StageFocused},
+		want:      []lifecycle.Stage{lifecycle.StageAlive, lifecycle.StageVisible, lifecycle.StageFocused},
+		wantFocus: []bool{true},
+	}, {
+		desc:      "background and resume",
+		stages:    []lifecycle.Stage{lifecycle.StageFocused, lifecycle.StageVisible, lifecycle.StageAlive, lifecycle.StageVisible, lifecycle.StageFocused},
+		want:      []lifecycle.Stage{lifecycle.StageFocused, lifecycle.StageVisible, lifecycle.StageAlive, lifecycle.StageVisible, lifecycle.StageFocused},
+		wantFocus: []bool{true, false, true},
+	}, {
+		desc:      "repeated stages",
+		stages:    []lifecycle.Stage{lifecycle.StageVisible, lifecycle.StageVisible, lifecycle.StageFocused, lifecycle.StageFocused},
+		want:      []lifecycle.Stage{lifecycle.StageVisible, lifecycle.StageFocused},
+		wantFocus: []bool{true},
+	}, {
+		desc:      "destroyed",
+		stages:    []lifecycle.Stage{lifecycle.StageFocused, lifecycle.StageDead},
+		want:      []lifecycle.Stage{lifecycle.StageFocused, lifecycle.StageDead},
+		wantFocus: []bool{true},
+	}}
+	for _, tc := range testCases {
+		var (
+			l lifecycler.State
+			s sender
+		)
+		for _, stage := range tc.stages {
+			setStage(&l, stage)
+			l.SendEvent(&s, nil)
+		}
+		if len(s.events) != len(tc.want) {
+			t.Errorf("%s: got %d events %v, want %d", tc.desc, len(s.events), s.events, len(tc.want))
+			continue
+		}
+		from := lifecycle.StageDead
+		for i, e := range s.events {
+			if e.From != from || e.To != tc.want[i] {
+				t.Errorf("%s: event %d: got %v to %v, want %v to %v", tc.desc, i, e.From, e.To, from, tc.want[i])
+			}
+			from = e.To
+		}
+		if fmt.Sprint(s.focus) != fmt.Sprint(tc.wantFocus) {
+			t.Errorf("%s: focus events: got %v, want %v", tc.desc, s.focus, tc.wantFocus)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mobiledriver provides a driver for accessing a screen on Android
+// and iOS, built on the golang.org/x/mobile/app package.
+//
+// An app has a single surface, so there is at most one unreleased window at a
+// time, which is always the size of that surface. The app's lifecycle events
+// set the window's lifecycle stage, and its size, touch and key events are
+// sent to the window as they are. Drawing is done in software, and the window
+// is published by uploading its pixels to an OpenGL ES texture, drawn with
+// the GL context that the app provides while it is visible.
+//
+// TODO: draw with that GL context, as the gldriver does, instead of in
+// software.
+package mobiledriver // import "golang.org/x/exp/shiny/driver/mobiledriver"
+
+import (
+	"golang.org/x/exp/shiny/driver/internal/errscreen"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Main is called by the program's main function to run the graphical
+// application.
+//
+// It calls f on the Screen, possibly in a separate goroutine, as some OS-
+// specific libraries require being on 'the main thread'. It returns when f
+// returns.
+func Main(f func(screen.Screen)) {
+	if err := main(f); err != nil {
+		f(errscreen.Stub(err))
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !android,!ios
+
+package mobiledriver
+
+import (
+	"fmt"
+	"runtime"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+func main(f func(screen.Screen)) error {
+	return fmt.Errorf("mobiledriver: unsupported GOOS/GOARCH %s/%s", runtime.GOOS, runtime.GOARCH)
+}
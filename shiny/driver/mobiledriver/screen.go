@@ -0,0 +1,228 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build android ios
+
+package mobiledriver
+
+import (
+	"errors"
+	"image"
+	"sync"
+
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/swtexture"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/app"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/lifecycle"
+	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/paint"
+	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/event/touch"
+	"golang.org/x/mobile/gl"
+)
+
+func main(f func(screen.Screen)) error {
+	app.Main(func(a app.App) {
+		s := newScreenImpl(a)
+		go s.pump()
+		f(s)
+	})
+	return nil
+}
+
+type screenImpl struct {
+	swtexture.Allocator
+
+	app app.App
+
+	// frames are the windows waiting for a screen.FrameEvent.
+	frames frame.Requests
+
+	clipboard clipboardImpl
+
+	mu                   sync.Mutex
+	defaultWindowOptions *screen.NewWindowOptions
+	// window is the unreleased window, if any, shown on the app's surface.
+	window *windowImpl
+	// stage and sz are the app's lifecycle stage and surface size, as of its
+	// latest events, so that a new window starts with them. glctx is the
+	// app's GL context, which is only valid while the app is visible, and is
+	// otherwise nil.
+	stage lifecycle.Stage
+	sz    size.Event
+	glctx gl.Context
+}
+
+func newScreenImpl(a app.App) *screenImpl {
+	return &screenImpl{
+		app: a,
+		// The app is running, even if it has not yet sent its first
+		// lifecycle event.
+		stage: lifecycle.StageAlive,
+	}
+}
+
+// pump sends the app's events to the window, for as long as the app runs.
+// Events that arrive when there is no window only update the state that the
+// next window starts with.
+func (s *screenImpl) pump() {
+	for e := range s.app.Events() {
+		// The app's filters include one that sets the GL viewport to the
+		// surface's size.
+		switch e := s.app.Filter(e).(type) {
+		case lifecycle.Event:
+			s.onLifecycle(e)
+		case size.Event:
+			s.onSize(e)
+		case key.Event, mouse.Event, paint.Event, touch.Event:
+			if w := s.currentWindow(); w != nil {
+				w.Send(e)
+			}
+		}
+	}
+}
+
+func (s *screenImpl) currentWindow() *windowImpl {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.window
+}
+
+func (s *screenImpl) onLifecycle(e lifecycle.Event) {
+	s.mu.Lock()
+	s.stage = e.To
+	cross := e.Crosses(lifecycle.StageVisible)
+	switch cross {
+	case lifecycle.CrossOn:
+		s.glctx, _ = e.DrawContext.(gl.Context)
+	case lifecycle.CrossOff:
+		s.glctx = nil
+	}
+	glctx, w := s.glctx, s.window
+	s.mu.Unlock()
+
+	if w == nil {
+		return
+	}
+	if cross != lifecycle.CrossNone {
+		w.setGLContext(glctx)
+	}
+	setStage(&w.lifecycler, e.To)
+	w.lifecycler.SendEvent(w, nil)
+}
+
+func (s *screenImpl) onSize(e size.Event) {
+	s.mu.Lock()
+	s.sz = e
+	w := s.window
+	s.mu.Unlock()
+
+	if w != nil {
+		w.resize(e)
+	}
+}
+
+func (s *screenImpl) NewTexture(size image.Point) (screen.Texture, error) {
+	return s.NewTextureWithOptions(size, nil)
+}
+
+// NewWindow returns a window on the app's surface, or an error if there is
+// already an unreleased window. The window is the surface's size, whatever
+// the options' Width and Height, and is never hidden.
+func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.window != nil {
+		return nil, errors.New("mobiledriver: the app already has a window")
+	}
+	opts = opts.WithDefaults(s.defaultWindowOptions)
+	w := newWindowImpl(s, opts, s.sz, s.glctx)
+	s.window = w
+
+	// The window's first events are sent while holding s.mu, so that they
+	// come before those of the app's next events.
+	setStage(&w.lifecycler, s.stage)
+	w.lifecycler.SendEvent(w, nil)
+	if s.sz.WidthPx > 0 && s.sz.HeightPx > 0 {
+		w.pixelsPerPt = s.sz.PixelsPerPt
+		w.Send(s.sz)
+		w.Send(paint.Event{External: true})
+	}
+	return w, nil
+}
+
+func (s *screenImpl) SetDefaultWindowOptions(opts *screen.NewWindowOptions) {
+	opts = opts.WithDefaults(nil)
+
+	s.mu.Lock()
+	s.defaultWindowOptions = opts
+	s.mu.Unlock()
+}
+
+// AccessibilityPrefs returns no preferences, as the app package does not
+// report the system's.
+func (s *screenImpl) AccessibilityPrefs() screen.AccessibilityPrefs { return 0 }
+
+// ColorScheme returns screen.LightColorScheme, as the app package does not
+// report the system's color scheme.
+func (s *screenImpl) ColorScheme() screen.ColorScheme { return screen.LightColorScheme }
+
+// Clipboard returns an in-memory clipboard, private to this Screen, as the app
+// package has no access to the system's.
+func (s *screenImpl) Clipboard() screen.Clipboard {
+	return &s.clipboard
+}
+
+// NewTrayIcon returns an error, as phones have no system tray.
+func (s *screenImpl) NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return nil, errors.New("mobiledriver: NewTrayIcon is not supported")
+}
+
+// Displays returns the display that the app is shown on, as big as the app's
+// surface, which is all that the app package knows of it.
+func (s *screenImpl) Displays() []screen.Display {
+	s.mu.Lock()
+	sz := s.sz
+	s.mu.Unlock()
+
+	if sz.WidthPx <= 0 || sz.HeightPx <= 0 {
+		return nil
+	}
+	r := image.Rect(0, 0, sz.WidthPx, sz.HeightPx)
+	return []screen.Display{{
+		Bounds:   r,
+		WorkArea: r,
+		// A point is 1/72nd of an inch.
+		DPI:     float64(sz.PixelsPerPt) * 72,
+		Scale:   scale(sz.PixelsPerPt),
+		Primary: true,
+	}}
+}
+
+// scale returns the scale of a surface with ppp pixels per point, relative to
+// a 160 DPI display, which is what Android and iOS consider unscaled.
+func scale(ppp float32) float64 {
+	return float64(ppp) * 72 / 160
+}
+
+type clipboardImpl struct {
+	mu   sync.Mutex
+	text string
+}
+
+func (c *clipboardImpl) ReadText() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.text, nil
+}
+
+func (c *clipboardImpl) WriteText(text string) error {
+	c.mu.Lock()
+	c.text = text
+	c.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,475 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build android ios
+
+package mobiledriver
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/capture"
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/lifecycler"
+	"golang.org/x/exp/shiny/driver/internal/swtexture"
+	"golang.org/x/exp/shiny/screen"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/exp/gl/glutil"
+	"golang.org/x/mobile/geom"
+	"golang.org/x/mobile/gl"
+)
+
+type windowImpl struct {
+	s *screenImpl
+
+	event.Deque
+	lifecycler lifecycler.State
+
+	// pixelsPerPt is the window's scale as of its last size event. It is
+	// only accessed by the screenImpl's pump, after NewWindow sets it.
+	pixelsPerPt float32
+
+	// mu guards back, clip, sz, glctx, images, img and released.
+	// If you need to hold both a windowImpl's mu and a swtexture.Texture's
+	// mu, the lock ordering is to lock the windowImpl's first (and unlock it
+	// last).
+	mu sync.Mutex
+	// back is the back buffer, that the Drawer methods draw to.
+	back *image.RGBA
+	// clip is the clip stack that the Drawer methods draw subject to.
+	clip drawer.Clip
+	// sz is the surface's size, as of the app's last size event.
+	sz size.Event
+	// glctx is the app's GL context, or nil while the app is not visible.
+	// images and img are the GL objects, created in glctx, that Publish
+	// draws the back buffer to the surface with. They are created by the
+	// first Publish after glctx is set, or, for img, after each resize.
+	glctx    gl.Context
+	images   *glutil.Images
+	img      *glutil.Image
+	released bool
+
+	imagePool drawer.ImagePool
+	layers    drawer.Layers
+	captures  capture.Hooks
+	timing    frame.Timing
+}
+
+func newWindowImpl(s *screenImpl, opts *screen.NewWindowOptions, sz size.Event, glctx gl.Context) *windowImpl {
+	w := &windowImpl{
+		s:     s,
+		back:  image.NewRGBA(image.Rect(0, 0, sz.WidthPx, sz.HeightPx)),
+		sz:    sz,
+		glctx: glctx,
+	}
+	if opts != nil {
+		w.Priority = opts.EventPriority
+		w.KeepAllMoves = opts.KeepAllMoves
+		w.Queues = opts.EventQueues
+		w.clip.Linear = opts.LinearBlending
+	}
+	return w
+}
+
+// resize resizes the back buffer to the surface's new size, keeping as much
+// of the old contents as fit, and sends the size event.
+func (w *windowImpl) resize(e size.Event) {
+	sz := image.Point{e.WidthPx, e.HeightPx}
+
+	w.mu.Lock()
+	if w.released {
+		w.mu.Unlock()
+		return
+	}
+	w.sz = e
+	if w.back.Rect.Size() != sz {
+		back := image.NewRGBA(image.Rectangle{Max: sz})
+		draw.Draw(back, back.Rect, w.back, image.Point{}, draw.Src)
+		w.back = back
+		w.releaseImage()
+	}
+	w.mu.Unlock()
+
+	if e.PixelsPerPt != w.pixelsPerPt {
+		initial := w.pixelsPerPt == 0
+		w.pixelsPerPt = e.PixelsPerPt
+		if !initial {
+			w.Send(screen.ScaleEvent{PixelsPerPt: e.PixelsPerPt, Scale: scale(e.PixelsPerPt)})
+		}
+	}
+	w.Send(e)
+}
+
+// setGLContext replaces the app's GL context, releasing the GL objects made in
+// the previous one.
+func (w *windowImpl) setGLContext(glctx gl.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.releaseGL()
+	if !w.released {
+		w.glctx = glctx
+	}
+}
+
+// releaseGL and releaseImage must only be called while holding w.mu.
+
+func (w *windowImpl) releaseGL() {
+	w.releaseImage()
+	if w.images != nil {
+		w.images.Release()
+		w.images = nil
+	}
+	w.glctx = nil
+}
+
+func (w *windowImpl) releaseImage() {
+	if w.img != nil {
+		w.img.Release()
+		w.img = nil
+	}
+}
+
+// present draws the back buffer to the surface. It must only be called while
+// holding w.mu.
+func (w *windowImpl) present() {
+	sz := w.back.Rect.Size()
+	if w.glctx == nil || sz.X <= 0 || sz.Y <= 0 {
+		return
+	}
+	if w.images == nil {
+		w.images = glutil.NewImages(w.glctx)
+	}
+	if w.img == nil {
+		w.img = w.images.NewImage(sz.X, sz.Y)
+	}
+	draw.Draw(w.img.RGBA, w.img.RGBA.Rect, w.back, image.Point{}, draw.Src)
+	w.img.Upload()
+
+	// The back buffer's alpha is ignored, as for the other drivers, by
+	// drawing it over opaque black.
+	w.glctx.ClearColor(0, 0, 0, 1)
+	w.glctx.Clear(gl.COLOR_BUFFER_BIT)
+	w.img.Draw(w.sz,
+		geom.Point{},
+		geom.Point{X: w.sz.WidthPt},
+		geom.Point{Y: w.sz.HeightPt},
+		w.back.Rect)
+}
+
+func (w *windowImpl) Release() {
+	w.mu.Lock()
+	released := w.released
+	w.released = true
+	if !released {
+		w.releaseGL()
+	}
+	w.mu.Unlock()
+	if released {
+		return
+	}
+
+	w.imagePool.Release()
+	w.layers.Release()
+	w.StopTimers()
+
+	s := w.s
+	s.mu.Lock()
+	if s.window == w {
+		s.window = nil
+	}
+	s.mu.Unlock()
+}
+
+func (w *windowImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	swtexture.Upload(w.back, dp, src, sr)
+}
+
+func (w *windowImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.clip.Fill(w.back, dr, src, op)
+}
+
+// Draw and DrawUniform use bilinear sampling, which is exact for
+// transformations that are just translations. Depth testing is not supported,
+// but blend modes and transparency are composited in software.
+
+func (w *windowImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	t := src.(*swtexture.Texture)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	t.Transform(&w.clip, xdraw.ApproxBiLinear, w.back, src2dst, sr, op, opts)
+}
+
+func (w *windowImpl) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.clip.Transform(xdraw.ApproxBiLinear, w.back, src2dst, image.NewUniform(src), sr, op, opts)
+}
+
+func (w *windowImpl) PushClip(r image.Rectangle) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PushClip(r)
+}
+
+func (w *windowImpl) PushClipPath(src2dst f64.Aff3, p *screen.Path) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PushClipPath(src2dst, p)
+}
+
+func (w *windowImpl) PopClip() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PopClip()
+}
+
+func (w *windowImpl) Copy(dp image.Point, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Copy(w, dp, src, sr, op, opts)
+}
+
+func (w *windowImpl) Scale(dr image.Rectangle, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Scale(w, dr, src, sr, op, opts)
+}
+
+func (w *windowImpl) DrawImage(dp image.Point, src image.Image, op draw.Op, opts *screen.DrawOptions) {
+	w.imagePool.DrawImage(w.s, w, dp, src, op, opts)
+}
+
+func (w *windowImpl) NewLayer(z int, size image.Point) (screen.Layer, error) {
+	return w.layers.NewLayer(w.s, z, size)
+}
+
+// Publish draws the back buffer to the app's surface, and swaps it to the
+// screen. It drops the frame while the app is not visible.
+func (w *windowImpl) Publish() screen.PublishResult {
+	composited := w.layers.Composite(w)
+
+	w.mu.Lock()
+	if w.released || w.glctx == nil {
+		w.mu.Unlock()
+		return screen.PublishResult{Dropped: true}
+	}
+	w.present()
+	var captured *image.RGBA
+	if w.captures.Active() {
+		captured = capture.Copy(w.back, w.back.Rect)
+	}
+	w.mu.Unlock()
+
+	w.s.app.Publish()
+	// The back buffer is copied, not flipped, to the surface, so its
+	// contents are preserved. Compositing any layers overwrites the
+	// contents, though.
+	res := screen.PublishResult{BackBufferPreserved: !composited}
+	// app.Publish waits for the buffers to be swapped, which the platform
+	// synchronizes with the display.
+	w.timing.Presented(&res, time.Now())
+	w.captures.Send(captured)
+	return res
+}
+
+func (w *windowImpl) AddCaptureHook(fn func(f screen.CapturedFrame)) (remove func()) {
+	return w.captures.Add(fn)
+}
+
+// NextFrame sends a screen.FrameEvent at the next tick of a clock running at
+// frame.DefaultRate, as the app package has no display link to wait for.
+func (w *windowImpl) NextFrame() {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return
+	}
+	if s := w.s; s.frames.Request(w) {
+		go s.frames.Tick(frame.Interval(0))
+	}
+}
+
+func (w *windowImpl) RenderFrame(fn func(d screen.Drawer)) error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+
+	fn(w)
+	return nil
+}
+
+var errReleased = errors.New("mobiledriver: window is released")
+
+func (w *windowImpl) GLInfo() screen.GLInfo { return screen.GLInfo{} }
+
+func (w *windowImpl) Begin() *screen.Context { return screen.NewContext(w) }
+
+// SetTitle, SetIcon, SetBadge and SetProgress do nothing, as an app's title,
+// icon and badge are set by its package, not by the app while it runs.
+func (w *windowImpl) SetTitle(title string) {}
+
+func (w *windowImpl) SetIcon(m image.Image) {}
+
+func (w *windowImpl) SetBadge(label string) {}
+
+func (w *windowImpl) SetProgress(progress float64) {}
+
+// SetSize, SetPosition, SetSizeConstraints and SetAspectRatio do nothing, as
+// the window is always the size of the app's surface.
+func (w *windowImpl) SetSize(width, height int) {}
+
+func (w *windowImpl) SetPosition(p image.Point) {}
+
+func (w *windowImpl) SetSizeConstraints(min, max image.Point) {}
+
+func (w *windowImpl) SetAspectRatio(aspect image.Point) {}
+
+// GetGeometry returns the surface's size, at the origin.
+func (w *windowImpl) GetGeometry() (image.Point, image.Point) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return image.Point{}, image.Point{}
+	}
+	return image.Point{}, w.back.Rect.Size()
+}
+
+// TODO: show the software keyboard, once the app package can.
+func (w *windowImpl) SetTextInputRect(r image.Rectangle) {}
+
+// SetCursor, SetCursorVisible and SetPointerCapture do nothing, as phones have
+// no mouse cursor.
+func (w *windowImpl) SetCursor(c screen.Cursor) {}
+
+func (w *windowImpl) SetCursorVisible(visible bool) {}
+
+func (w *windowImpl) SetPointerCapture(capture bool) {}
+
+// StartDrag, StartMove and StartResize return an error, as the app's surface
+// fills its screen.
+func (w *windowImpl) StartDrag(data screen.DragData) error {
+	return w.errUnsupported("StartDrag")
+}
+
+func (w *windowImpl) StartMove() error {
+	return w.errUnsupported("StartMove")
+}
+
+func (w *windowImpl) StartResize(edge screen.WindowEdge) error {
+	return w.errUnsupported("StartResize")
+}
+
+// RegisterHotKey returns an error, as an app is only sent the key presses made
+// while it has the keyboard focus.
+func (w *windowImpl) RegisterHotKey(k key.Code, mods key.Modifiers) error {
+	return w.errUnsupported("RegisterHotKey")
+}
+
+func (w *windowImpl) UnregisterHotKey(k key.Code, mods key.Modifiers) {}
+
+// Minimize, Maximize, Restore, SetFullscreen, SetAlwaysOnTop, SetOpacity, Raise
+// and Lower do nothing, as the app's surface always fills its screen, above
+// any other app's.
+func (w *windowImpl) Minimize() {}
+
+func (w *windowImpl) Maximize() {}
+
+func (w *windowImpl) Restore() {}
+
+func (w *windowImpl) SetFullscreen(mode screen.FullscreenMode) {}
+
+func (w *windowImpl) SetAlwaysOnTop(onTop bool) {}
+
+func (w *windowImpl) SetOpacity(opacity float32) {}
+
+func (w *windowImpl) Raise() {}
+
+func (w *windowImpl) Lower() {}
+
+// ShowFileDialog, SetMenuBar, ShowContextMenu, Notify and SetAccessTree
+// return an error, as the app package has no access to the system's file
+// pickers, menus, notifications or accessibility services.
+//
+// TODO: call them through the Android and iOS SDKs, as the
+// golang.org/x/mobile/bind package does.
+func (w *windowImpl) ShowFileDialog(opts *screen.FileDialogOptions) error {
+	return w.errUnsupported("ShowFileDialog")
+}
+
+func (w *windowImpl) SetMenuBar(bar *screen.Menu) error {
+	return w.errUnsupported("SetMenuBar")
+}
+
+func (w *windowImpl) ShowContextMenu(m *screen.Menu, p image.Point) error {
+	return w.errUnsupported("ShowContextMenu")
+}
+
+func (w *windowImpl) Notify(n *screen.Notification) error {
+	return w.errUnsupported("Notify")
+}
+
+func (w *windowImpl) CloseNotification(id int) {}
+
+func (w *windowImpl) SetAccessTree(root *screen.AccessNode) error {
+	return w.errUnsupported("SetAccessTree")
+}
+
+func (w *windowImpl) errUnsupported(method string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return errReleased
+	}
+	return errors.New("mobiledriver: " + method + " is not supported")
+}
+
+func (w *windowImpl) ColorSpace() screen.ColorSpace { return screen.ColorSpaceSRGB }
+
+// SetColorSpace only accepts screen.ColorSpaceSRGB, as the app's surface is
+// created in sRGB.
+func (w *windowImpl) SetColorSpace(cs screen.ColorSpace) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	if cs != screen.ColorSpaceSRGB {
+		return errors.New("mobiledriver: color spaces other than sRGB are not supported")
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package mtldriver
+
+import (
+	"image"
+)
+
+// bufferImpl is in Go memory. Uploads copy it to an MTLBuffer, from which the
+// GPU blits to the destination texture.
+type bufferImpl struct {
+	s    *screenImpl
+	rgba image.RGBA
+	size image.Point
+}
+
+func (b *bufferImpl) Size() image.Point       { return b.size }
+func (b *bufferImpl) Bounds() image.Rectangle { return image.Rectangle{Max: b.size} }
+func (b *bufferImpl) RGBA() *image.RGBA       { return &b.rgba }
+
+func (b *bufferImpl) Release() {
+	// Uploads are synchronous, so the pixels can be recycled immediately.
+	b.s.pixPool.Put(b.rgba.Pix)
+	b.rgba.Pix = nil
+}
@@ -0,0 +1,639 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package mtldriver
+
+// The C functions, and the Go functions exported to C, are prefixed with mtl
+// as they share a namespace with the gldriver's, which may be linked into
+// the same program.
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+#import <Cocoa/Cocoa.h>
+#include <pthread.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+void mtlStartDriver();
+void mtlStopDriver();
+uintptr_t mtlNewWindow(int width, int height, int x, int y, int hasPosition, int fixedSize, char* title, int interceptClose, int transparent, int undecorated);
+void mtlShowWindow(uintptr_t id, int hidden, int owned);
+void mtlCloseWindow(uintptr_t id);
+void mtlSetTitle(uintptr_t id, char* title);
+void mtlSetSize(uintptr_t id, int width, int height);
+void mtlSetPosition(uintptr_t id, int x, int y);
+void mtlGetGeometry(uintptr_t id, int* x, int* y, int* width, int* height);
+void mtlSetTextInputRect(uintptr_t id, int x, int y, int width, int height);
+void mtlSetCursor(uintptr_t id, uintptr_t cursor);
+void mtlSetCursorVisible(uintptr_t id, int visible);
+void mtlSetPointerCapture(uintptr_t id, int capture);
+void mtlMinimize(uintptr_t id);
+void mtlMaximize(uintptr_t id);
+void mtlRestore(uintptr_t id);
+void mtlSetFullscreen(uintptr_t id, int fullscreen);
+void mtlSetColorSpace(uintptr_t id, int p3);
+void mtlGetAccessibilityPrefs(int* reduceMotion, int* increaseContrast, int* reduceTransparency);
+int mtlIsDarkMode();
+char* mtlClipboardReadText();
+int mtlClipboardWriteText(char* text, int len);
+uint64_t mtlThreadID();
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"log"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/cocoadisplay"
+	"golang.org/x/exp/shiny/driver/internal/cocoakey"
+	"golang.org/x/exp/shiny/driver/internal/lifecycler"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/geom"
+)
+
+var initThreadID C.uint64_t
+
+func init() {
+	// Lock the goroutine responsible for initialization to an OS thread.
+	// This means the goroutine running main (and calling mtlStartDriver
+	// below) is locked to the OS thread that started the program. This is
+	// necessary for the correct delivery of Cocoa events to the process.
+	runtime.LockOSThread()
+	initThreadID = C.mtlThreadID()
+}
+
+var mainCallback func(screen.Screen)
+
+func main(f func(screen.Screen)) error {
+	if tid := C.mtlThreadID(); tid != initThreadID {
+		log.Fatalf("mtldriver.Main called on thread %d, but mtldriver.init ran on %d", tid, initThreadID)
+	}
+	if err := metalInit(); err != nil {
+		return err
+	}
+
+	mainCallback = f
+	C.mtlStartDriver()
+	return nil
+}
+
+//export mtlDriverStarted
+func mtlDriverStarted() {
+	go func() {
+		mainCallback(theScreen)
+		C.mtlStopDriver()
+	}()
+}
+
+func newWindow(opts *screen.NewWindowOptions, width, height int) uintptr {
+	title := C.CString(opts.GetTitle())
+	defer C.free(unsafe.Pointer(title))
+
+	x, y, hasPosition, fixedSize, interceptClose, transparent, undecorated := 0, 0, 0, 0, 0, 0, 0
+	if opts != nil {
+		if opts.Position != nil {
+			x, y, hasPosition = opts.Position.X, opts.Position.Y, 1
+		}
+		if opts.FixedSize {
+			fixedSize = 1
+		}
+		if opts.InterceptClose {
+			interceptClose = 1
+		}
+		if opts.Transparent {
+			transparent = 1
+		}
+		if opts.Undecorated {
+			undecorated = 1
+		}
+	}
+	id := uintptr(C.mtlNewWindow(C.int(width), C.int(height), C.int(x), C.int(y),
+		C.int(hasPosition), C.int(fixedSize), title, C.int(interceptClose), C.int(transparent), C.int(undecorated)))
+	cocoadisplay.RegisterDragTypes(id)
+	return id
+}
+
+func showWindow(w *windowImpl, opts *screen.NewWindowOptions) {
+	hidden, owned := 0, 0
+	if w.hidden {
+		hidden = 1
+	} else if opts != nil {
+		if owner, ok := opts.Owner.(*windowImpl); ok {
+			cocoadisplay.ShowOwned(w.id, owner.id, opts.Modal)
+			owned = 1
+		}
+	}
+	C.mtlShowWindow(C.uintptr_t(w.id), C.int(hidden), C.int(owned))
+}
+
+func closeWindow(id uintptr) {
+	cocoadisplay.ReleaseOwned(id)
+	C.mtlCloseWindow(C.uintptr_t(id))
+	cocoadisplay.ReleaseMenus(id)
+	cocoadisplay.ReleaseAccess(id)
+}
+
+func setTitle(w *windowImpl, title string) {
+	ctitle := C.CString(title)
+	defer C.free(unsafe.Pointer(ctitle))
+	C.mtlSetTitle(C.uintptr_t(w.id), ctitle)
+}
+
+func setIcon(w *windowImpl, m image.Image) { cocoadisplay.SetDockIcon(m) }
+
+func setBadge(w *windowImpl, label string) { cocoadisplay.SetDockBadge(label) }
+
+func setProgress(w *windowImpl, progress float64) { cocoadisplay.SetDockProgress(progress) }
+
+func setSize(w *windowImpl, width, height int) {
+	C.mtlSetSize(C.uintptr_t(w.id), C.int(width), C.int(height))
+}
+
+func setPosition(w *windowImpl, p image.Point) {
+	C.mtlSetPosition(C.uintptr_t(w.id), C.int(p.X), C.int(p.Y))
+}
+
+func geometry(w *windowImpl) (image.Point, image.Point) {
+	var x, y, width, height C.int
+	C.mtlGetGeometry(C.uintptr_t(w.id), &x, &y, &width, &height)
+	return image.Point{int(x), int(y)}, image.Point{int(width), int(height)}
+}
+
+func setTextInputRect(w *windowImpl, r image.Rectangle) {
+	C.mtlSetTextInputRect(C.uintptr_t(w.id), C.int(r.Min.X), C.int(r.Min.Y), C.int(r.Dx()), C.int(r.Dy()))
+}
+
+// setCursor passes ownership of the new NSCursor to the view.
+func setCursor(w *windowImpl, c screen.Cursor) {
+	C.mtlSetCursor(C.uintptr_t(w.id), C.uintptr_t(cocoadisplay.NewCursor(w.id, c)))
+}
+
+func setCursorVisible(w *windowImpl, visible bool) {
+	v := 0
+	if visible {
+		v = 1
+	}
+	C.mtlSetCursorVisible(C.uintptr_t(w.id), C.int(v))
+}
+
+func setPointerCapture(w *windowImpl, capture bool) {
+	v := 0
+	if capture {
+		v = 1
+	}
+	C.mtlSetPointerCapture(C.uintptr_t(w.id), C.int(v))
+}
+
+func startDrag(w *windowImpl, data screen.DragData) error {
+	return cocoadisplay.StartDrag(w.id, data)
+}
+
+func startMove(w *windowImpl) error {
+	return cocoadisplay.StartMove(w.id)
+}
+
+func startResize(w *windowImpl, edge screen.WindowEdge) error {
+	return cocoadisplay.StartResize(w.id, edge)
+}
+
+func showFileDialog(w *windowImpl, opts *screen.FileDialogOptions) error {
+	cocoadisplay.ShowFileDialog(w.id, w, opts)
+	return nil
+}
+
+func setMenuBar(w *windowImpl, bar *screen.Menu) error {
+	cocoadisplay.SetMenuBar(w.id, w, bar)
+	return nil
+}
+
+func showContextMenu(w *windowImpl, m *screen.Menu, p image.Point) error {
+	cocoadisplay.ShowContextMenu(w.id, w, m, p)
+	return nil
+}
+
+func setAccessTree(w *windowImpl, root *screen.AccessNode) error {
+	cocoadisplay.SetAccessTree(w.id, w, root)
+	return nil
+}
+
+func minimize(w *windowImpl) { C.mtlMinimize(C.uintptr_t(w.id)) }
+
+func maximize(w *windowImpl) { C.mtlMaximize(C.uintptr_t(w.id)) }
+
+func restore(w *windowImpl) { C.mtlRestore(C.uintptr_t(w.id)) }
+
+// setFullscreen uses macOS's fullscreen, which gives the window a space of
+// its own. As for the gldriver, FullscreenExclusive is the same as
+// FullscreenBorderless.
+func setFullscreen(w *windowImpl, mode screen.FullscreenMode) {
+	v := 0
+	if mode != screen.FullscreenNone {
+		v = 1
+	}
+	C.mtlSetFullscreen(C.uintptr_t(w.id), C.int(v))
+}
+
+func setSizeConstraints(w *windowImpl, min, max image.Point) {
+	cocoadisplay.SetSizeConstraints(w.id, min, max)
+}
+
+func setAspectRatio(w *windowImpl, aspect image.Point) { cocoadisplay.SetAspectRatio(w.id, aspect) }
+
+func setAlwaysOnTop(w *windowImpl, onTop bool) { cocoadisplay.SetAlwaysOnTop(w.id, onTop) }
+
+func setOpacity(w *windowImpl, opacity float32) { cocoadisplay.SetOpacity(w.id, opacity) }
+
+func raise(w *windowImpl) { cocoadisplay.Raise(w.id) }
+
+func lower(w *windowImpl) { cocoadisplay.Lower(w.id) }
+
+// setColorSpace sets the color space of the window's CAMetalLayer, which
+// macOS converts the layer's pixels from.
+func setColorSpace(w *windowImpl, cs screen.ColorSpace) error {
+	switch cs {
+	case screen.ColorSpaceSRGB:
+		C.mtlSetColorSpace(C.uintptr_t(w.id), 0)
+	case screen.ColorSpaceDisplayP3:
+		C.mtlSetColorSpace(C.uintptr_t(w.id), 1)
+	default:
+		return errors.New("mtldriver: unsupported color space")
+	}
+	return nil
+}
+
+func window(id uintptr) *windowImpl {
+	theScreen.mu.Lock()
+	defer theScreen.mu.Unlock()
+	return theScreen.windows[id]
+}
+
+//export mtlSetGeom
+func mtlSetGeom(id uintptr, ppp float32, scale float64, widthPx, heightPx int) {
+	w := window(id)
+	if w == nil {
+		return // closing window
+	}
+	w.sendScale(ppp, scale)
+	w.resize(size.Event{
+		WidthPx:     widthPx,
+		HeightPx:    heightPx,
+		WidthPt:     geom.Pt(float32(widthPx) / ppp),
+		HeightPt:    geom.Pt(float32(heightPx) / ppp),
+		PixelsPerPt: ppp,
+	})
+}
+
+//export mtlWindowStateChanged
+func mtlWindowStateChanged(id uintptr, state int32) {
+	if w := window(id); w != nil {
+		w.sendState(screen.WindowState(state))
+	}
+}
+
+//export mtlVisibilityChanged
+func mtlVisibilityChanged(id uintptr, visible bool) {
+	if w := window(id); w != nil {
+		w.occluded = !visible
+		w.sendVisibility()
+	}
+}
+
+//export mtlWindowClosing
+func mtlWindowClosing(id uintptr) {
+	sendLifecycle(id, (*lifecycler.State).SetDead, true)
+}
+
+//export mtlWindowCloseRequested
+func mtlWindowCloseRequested(id uintptr) {
+	sendWindowEvent(id, screen.CloseRequestEvent{})
+}
+
+func sendWindowEvent(id uintptr, e interface{}) {
+	w := window(id)
+	if w == nil {
+		return // closing window
+	}
+	w.Send(e)
+}
+
+// sendInputEvent is like sendWindowEvent, but for an input event, which is
+// sent at the time of the NSEvent that it came from.
+func sendInputEvent(id uintptr, e interface{}) {
+	w := window(id)
+	if w == nil {
+		return // closing window
+	}
+	w.SendAt(e, cocoadisplay.EventTime())
+}
+
+func cocoaMouseDir(ty int32) mouse.Direction {
+	switch ty {
+	case C.NSLeftMouseDown, C.NSRightMouseDown, C.NSOtherMouseDown:
+		return mouse.DirPress
+	case C.NSLeftMouseUp, C.NSRightMouseUp, C.NSOtherMouseUp:
+		return mouse.DirRelease
+	default: // dragged
+		return mouse.DirNone
+	}
+}
+
+func cocoaMouseButton(button int32) mouse.Button {
+	switch button {
+	case 0:
+		return mouse.ButtonLeft
+	case 1:
+		return mouse.ButtonRight
+	case 2:
+		return mouse.ButtonMiddle
+	case 3:
+		return screen.MouseButtonBack
+	case 4:
+		return screen.MouseButtonForward
+	default:
+		if button < 0 {
+			return mouse.ButtonNone
+		}
+		// Map any further buttons, from 5 upwards, to 10 upwards.
+		return mouse.Button(button + 5)
+	}
+}
+
+//export mtlRelativeMouseEvent
+func mtlRelativeMouseEvent(id uintptr, dx, dy float32) {
+	sendInputEvent(id, screen.RelativeMouseEvent{DeltaX: dx, DeltaY: dy})
+}
+
+//export mtlDragEvent
+func mtlDragEvent(id uintptr, ty int32, x, y float32, pb uintptr) {
+	e := screen.DragEvent{
+		Type:  screen.DragType(ty),
+		X:     x,
+		Y:     y,
+		Types: cocoadisplay.DragTypes(pb),
+	}
+	switch e.Type {
+	case screen.DragLeave:
+		e.X, e.Y = 0, 0
+	case screen.Drop:
+		e.Data = cocoadisplay.DragData(pb)
+	}
+	sendWindowEvent(id, e)
+}
+
+//export mtlPenEvent
+func mtlPenEvent(id uintptr, x, y float32, ty int32, pressure, tiltX, tiltY float32, eraser, barrel int32, flags uint32) {
+	e := screen.PenEvent{
+		X:         x,
+		Y:         y,
+		Type:      screen.PenType(ty),
+		TiltX:     tiltX,
+		TiltY:     tiltY,
+		Eraser:    eraser != 0,
+		Barrel:    barrel != 0,
+		Modifiers: cocoakey.Modifiers(flags),
+	}
+	if e.Type == screen.PenDown || e.Type == screen.PenMove {
+		e.Pressure = pressure
+	}
+	sendInputEvent(id, e)
+}
+
+// scroller converts the scrolls of scrollerID, the window most recently
+// scrolled. Like the window's other events, they are only sent on the main
+// thread.
+var (
+	scroller   cocoadisplay.Scroller
+	scrollerID uintptr
+)
+
+//export mtlScrollEvent
+func mtlScrollEvent(id uintptr, x, y, dx, dy float32, precise int32, phase, momentumPhase uint32, flags uint32) {
+	if id != scrollerID {
+		scroller, scrollerID = cocoadisplay.Scroller{}, id
+	}
+	e, wheel, ok := scroller.Scroll(x, y, dx, dy, precise != 0, phase, momentumPhase, cocoakey.Modifiers(flags))
+	if !ok {
+		return
+	}
+	sendInputEvent(id, e)
+	for _, e := range wheel {
+		sendInputEvent(id, e)
+	}
+}
+
+//export mtlMouseEvent
+func mtlMouseEvent(id uintptr, x, y float32, ty, button int32, flags uint32) {
+	cmButton := mouse.ButtonNone
+	switch ty {
+	default:
+		cmButton = cocoaMouseButton(button)
+	case C.NSMouseMoved, C.NSLeftMouseDragged, C.NSRightMouseDragged, C.NSOtherMouseDragged:
+		// No-op.
+	}
+	sendInputEvent(id, mouse.Event{
+		X:         x,
+		Y:         y,
+		Button:    cmButton,
+		Direction: cocoaMouseDir(ty),
+		Modifiers: cocoakey.Modifiers(flags),
+	})
+}
+
+//export mtlCrossingEvent
+func mtlCrossingEvent(id uintptr, entered bool, x, y float32) {
+	sendInputEvent(id, screen.CrossingEvent{Entered: entered, X: x, Y: y})
+}
+
+//export mtlKeyEvent
+func mtlKeyEvent(id uintptr, runeVal, unmodified rune, dir uint8, code uint16, flags uint32) {
+	// Cocoa's virtual key codes are those of the keys' positions on an ANSI
+	// keyboard, whatever the layout, so the Code is that of the physical key.
+	e := screen.KeyEvent{
+		Event: key.Event{
+			Rune:      cocoakey.Rune(runeVal),
+			Direction: key.Direction(dir),
+			Code:      cocoakey.Code(code),
+			Modifiers: cocoakey.Modifiers(flags),
+		},
+		Physical:   cocoakey.Code(code),
+		Scancode:   uint32(code),
+		Unmodified: cocoakey.Rune(unmodified),
+		Repeat:     key.Direction(dir) == key.DirNone,
+	}
+	sendInputEvent(id, e)
+}
+
+//export mtlTextEvent
+func mtlTextEvent(id uintptr, preedit *C.char, preeditCursor C.int, commit *C.char) {
+	sendInputEvent(id, screen.TextEvent{
+		Preedit:       C.GoString(preedit),
+		PreeditCursor: int(preeditCursor),
+		Commit:        C.GoString(commit),
+	})
+}
+
+//export mtlFlagEvent
+func mtlFlagEvent(id uintptr, flags uint32) {
+	for _, mod := range cocoakey.Mods {
+		if flags&mod.Flags == mod.Flags && lastFlags&mod.Flags != mod.Flags {
+			mtlKeyEvent(id, -1, -1, C.NSKeyDown, mod.Code, flags)
+		}
+		if lastFlags&mod.Flags == mod.Flags && flags&mod.Flags != mod.Flags {
+			mtlKeyEvent(id, -1, -1, C.NSKeyUp, mod.Code, flags)
+		}
+	}
+	lastFlags = flags
+}
+
+var lastFlags uint32
+
+func sendLifecycle(id uintptr, setter func(*lifecycler.State, bool), val bool) {
+	w := window(id)
+	if w == nil {
+		return
+	}
+	setter(&w.lifecycler, val)
+	w.lifecycler.SendEvent(w, nil)
+}
+
+func sendLifecycleAll(dead bool) {
+	windows := []*windowImpl{}
+
+	theScreen.mu.Lock()
+	for _, w := range theScreen.windows {
+		windows = append(windows, w)
+	}
+	theScreen.mu.Unlock()
+
+	for _, w := range windows {
+		w.lifecycler.SetFocused(false)
+		w.lifecycler.SetVisible(false)
+		if dead {
+			w.lifecycler.SetDead(true)
+		}
+		w.lifecycler.SendEvent(w, nil)
+	}
+}
+
+//export mtlLifecycleDeadAll
+func mtlLifecycleDeadAll() { sendLifecycleAll(true) }
+
+//export mtlLifecycleHideAll
+func mtlLifecycleHideAll() { sendLifecycleAll(false) }
+
+//export mtlLifecycleVisible
+func mtlLifecycleVisible(id uintptr, val bool) {
+	sendLifecycle(id, (*lifecycler.State).SetVisible, val)
+}
+
+//export mtlLifecycleFocused
+func mtlLifecycleFocused(id uintptr, val bool) {
+	sendLifecycle(id, (*lifecycler.State).SetFocused, val)
+}
+
+func accessibilityPrefs() (p screen.AccessibilityPrefs) {
+	var reduceMotion, increaseContrast, reduceTransparency C.int
+	C.mtlGetAccessibilityPrefs(&reduceMotion, &increaseContrast, &reduceTransparency)
+	if reduceMotion != 0 {
+		p |= screen.ReduceMotion
+	}
+	if increaseContrast != 0 {
+		p |= screen.HighContrast
+	}
+	if reduceTransparency != 0 {
+		p |= screen.ReduceTransparency
+	}
+	return p
+}
+
+func colorScheme() screen.ColorScheme {
+	if C.mtlIsDarkMode() != 0 {
+		return screen.DarkColorScheme
+	}
+	return screen.LightColorScheme
+}
+
+// lastLayout is the keyboard input source that the windows were last told
+// of. It is only accessed on the main thread.
+var lastLayout string
+
+//export mtlKeyboardLayoutChanged
+func mtlKeyboardLayoutChanged(source *C.char) {
+	layout := C.GoString(source)
+	if layout == lastLayout {
+		// Each window's input context posts the notification.
+		return
+	}
+	lastLayout = layout
+	e := screen.KeyboardLayoutEvent{Layout: layout}
+
+	theScreen.mu.Lock()
+	for _, w := range theScreen.windows {
+		w.Send(e)
+	}
+	theScreen.mu.Unlock()
+}
+
+//export mtlColorSchemeChanged
+func mtlColorSchemeChanged() {
+	e := screen.ColorSchemeEvent{Scheme: colorScheme()}
+
+	theScreen.mu.Lock()
+	for _, w := range theScreen.windows {
+		w.Send(e)
+	}
+	theScreen.mu.Unlock()
+}
+
+//export mtlAccessibilityChanged
+func mtlAccessibilityChanged() {
+	e := screen.AccessibilityEvent{Prefs: accessibilityPrefs()}
+
+	theScreen.mu.Lock()
+	for _, w := range theScreen.windows {
+		w.Send(e)
+	}
+	theScreen.mu.Unlock()
+}
+
+//export mtlDisplaysChanged
+func mtlDisplaysChanged() {
+	displays := cocoadisplay.Displays()
+
+	theScreen.mu.Lock()
+	for _, w := range theScreen.windows {
+		w.Send(screen.DisplayEvent{Displays: append([]screen.Display(nil), displays...)})
+	}
+	theScreen.mu.Unlock()
+}
+
+// clipboardImpl is the general pasteboard.
+type clipboardImpl struct{}
+
+func (clipboardImpl) ReadText() (string, error) {
+	text := C.mtlClipboardReadText()
+	if text == nil {
+		return "", nil
+	}
+	defer C.free(unsafe.Pointer(text))
+	return C.GoString(text), nil
+}
+
+func (clipboardImpl) WriteText(text string) error {
+	b := C.CString(text)
+	defer C.free(unsafe.Pointer(b))
+	if C.mtlClipboardWriteText(b, C.int(len(text))) == 0 {
+		return errors.New("mtldriver: writing to the pasteboard failed")
+	}
+	return nil
+}
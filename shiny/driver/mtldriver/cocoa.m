@@ -0,0 +1,956 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#include "_cgo_export.h"
+#include <pthread.h>
+#include <stdio.h>
+#include <string.h>
+
+#import <Cocoa/Cocoa.h>
+#import <Foundation/Foundation.h>
+#import <Metal/Metal.h>
+#import <QuartzCore/CAMetalLayer.h>
+
+// The variables did not exist on older OS X releases,
+// we use the old variables deprecated on macOS to define them.
+#if __MAC_OS_X_VERSION_MAX_ALLOWED < 101200
+enum
+{
+    NSEventTypeScrollWheel = NSScrollWheel,
+    NSEventTypeKeyDown = NSKeyDown,
+    NSEventModifierFlagCommand = NSCommandKeyMask
+};
+enum
+{
+    NSWindowStyleMaskTitled = NSTitledWindowMask,
+    NSWindowStyleMaskResizable = NSResizableWindowMask,
+    NSWindowStyleMaskMiniaturizable = NSMiniaturizableWindowMask,
+    NSWindowStyleMaskClosable = NSClosableWindowMask,
+    NSWindowStyleMaskFullScreen = NSFullScreenWindowMask
+};
+#endif
+
+// mtlDevice is defined in metal.m.
+id<MTLDevice> mtlDevice();
+
+uint64_t mtlThreadID() {
+	uint64_t id;
+	if (pthread_threadid_np(pthread_self(), &id)) {
+		abort();
+	}
+	return id;
+}
+
+@interface ScreenMetalView : NSView<NSWindowDelegate, NSTextInputClient>
+{
+	// metalLayer is the view's backing layer, which is set when the view is
+	// created and never changes, so that mtlPresent can use it from any
+	// thread.
+	CAMetalLayer* metalLayer;
+	// closed is whether windowWillClose has been called.
+	BOOL closed;
+	// markedText is the text that the input method is composing, or nil.
+	NSString* markedText;
+	// textInputRect is the text cursor, in pixels with a top left origin,
+	// as set by mtlSetTextInputRect.
+	NSRect textInputRect;
+	// keyEvent is the key press being interpreted by the input method, or
+	// nil. keyConsumed is whether the input method used it to compose text.
+	NSEvent* keyEvent;
+	BOOL keyConsumed;
+	// cursor is the cursor shown over the view, or nil for the arrow, as set
+	// by mtlSetCursor. cursorHidden is whether it is hidden instead.
+	NSCursor* cursor;
+	BOOL cursorHidden;
+	// pointerCaptured is whether the window has captured the pointer, as
+	// set by mtlSetPointerCapture.
+	BOOL pointerCaptured;
+	// penEraser is whether the pen on a drawing tablet, if any, near the
+	// tablet is an eraser, from the most recent proximity event.
+	BOOL penEraser;
+	// interceptClose is whether the user's requests to close the window are
+	// sent to Go instead of closing it, as set by mtlNewWindow. closing is
+	// whether Go is closing the window, by mtlCloseWindow.
+	BOOL interceptClose;
+	BOOL closing;
+}
+- (CAMetalLayer*)metalLayer;
+- (void)setTextInputRect:(NSRect)r;
+- (void)setScreenCursor:(NSCursor*)c;
+- (void)setCursorHidden:(BOOL)hidden;
+- (void)setPointerCaptured:(BOOL)captured;
+- (void)setInterceptClose:(BOOL)intercept;
+- (void)setClosing;
+- (void)callSetGeom;
+- (void)callSetState;
+@end
+
+// mtlBlankCursor returns a transparent cursor, for hiding the cursor over a
+// single view, unlike [NSCursor hide], which hides it everywhere.
+static NSCursor* mtlBlankCursor() {
+	static NSCursor* c = nil;
+	if (c == nil) {
+		NSImage* img = [[NSImage alloc] initWithSize:NSMakeSize(1, 1)];
+		c = [[NSCursor alloc] initWithImage:img hotSpot:NSZeroPoint];
+		[img release];
+	}
+	return c;
+}
+
+// mtlUnmodifiedRune returns what the key of the key event types with no
+// modifier keys held, or -1 if it types no character. Unlike
+// charactersByApplyingModifiers, charactersIgnoringModifiers, on older
+// systems, still applies the Shift key.
+static int32_t mtlUnmodifiedRune(NSEvent *theEvent) {
+	NSString* s = theEvent.charactersIgnoringModifiers;
+	if ([theEvent respondsToSelector:@selector(charactersByApplyingModifiers:)]) {
+		s = [theEvent charactersByApplyingModifiers:0];
+	}
+	if (s.length == 0) {
+		return -1;
+	}
+	uint8_t buf[4] = {0, 0, 0, 0};
+	if (![s getBytes:buf
+			maxLength:4
+			usedLength:nil
+			encoding:NSUTF32LittleEndianStringEncoding
+			options:NSStringEncodingConversionAllowLossy
+			range:[s rangeOfComposedCharacterSequenceAtIndex:0]
+			remainingRange:nil]) {
+		return -1;
+	}
+	return (int32_t)((uint32_t)buf[0]<<0 | (uint32_t)buf[1]<<8 | (uint32_t)buf[2]<<16 | (uint32_t)buf[3]<<24);
+}
+
+CAMetalLayer* mtlViewLayer(uintptr_t viewID) {
+	return [(ScreenMetalView*)viewID metalLayer];
+}
+
+@implementation ScreenMetalView
+- (instancetype)initWithFrame:(NSRect)frame {
+	self = [super initWithFrame:frame];
+	if (self == nil) {
+		return nil;
+	}
+	metalLayer = [[CAMetalLayer alloc] init];
+	metalLayer.device = mtlDevice();
+	metalLayer.pixelFormat = MTLPixelFormatBGRA8Unorm;
+	// The back buffer is blitted to the drawable, which is not possible
+	// if the drawable is only a render target.
+	metalLayer.framebufferOnly = NO;
+	// Setting the layer before wantsLayer makes this a layer-hosting view,
+	// whose layer AppKit does not redraw itself.
+	[self setLayer:metalLayer];
+	[self setWantsLayer:YES];
+	return self;
+}
+
+- (void)dealloc {
+	[metalLayer release];
+	[markedText release];
+	[cursor release];
+	[super dealloc];
+}
+
+- (CAMetalLayer*)metalLayer {
+	return metalLayer;
+}
+
+- (BOOL)acceptsFirstResponder {
+	return YES;
+}
+
+- (void)callSetGeom {
+	NSWindow* window = self.window;
+	if (window == nil) {
+		return; // Not yet, or no longer, in a window.
+	}
+
+	// Calculate screen PPI, as for the gldriver.
+	NSScreen *screen = window.screen;
+	if (screen == nil) {
+		screen = [NSScreen mainScreen]; // The window is off screen.
+	}
+	double screenPixW = [screen frame].size.width * [screen backingScaleFactor];
+
+	CGDirectDisplayID display = (CGDirectDisplayID)[[[screen deviceDescription] valueForKey:@"NSScreenNumber"] intValue];
+	CGSize screenSizeMM = CGDisplayScreenSize(display); // in millimeters
+	float ppi = 25.4 * screenPixW / screenSizeMM.width;
+	float pixelsPerPt = ppi/72.0;
+
+	// The width and height are the view's bounds, in actual pixels rather
+	// than logical pixels. The layer's drawables are that size too.
+	NSRect r = [self convertRectToBacking:[self bounds]];
+	int w = r.size.width;
+	int h = r.size.height;
+	metalLayer.contentsScale = [window backingScaleFactor];
+	metalLayer.drawableSize = CGSizeMake(MAX(w, 1), MAX(h, 1));
+
+	mtlSetGeom((GoUintptr)self, pixelsPerPt, [window backingScaleFactor], w, h);
+}
+
+// callSetState reports the window's state, numbered as for a
+// screen.WindowState. Zooming, Cocoa's maximizing, has no notification of
+// its own, so callSetState is also called when the view is resized.
+- (void)callSetState {
+	NSWindow* window = self.window;
+	int state = 0;
+	if (window.miniaturized) {
+		state = 1;
+	} else if (window.styleMask & NSWindowStyleMaskFullScreen) {
+		state = 3;
+	} else if (window.zoomed) {
+		state = 2;
+	}
+	mtlWindowStateChanged((GoUintptr)self, state);
+}
+
+- (void)setFrameSize:(NSSize)size {
+	[super setFrameSize:size];
+	[self callSetGeom];
+	[self callSetState];
+}
+
+- (void)viewDidChangeBackingProperties {
+	[super viewDidChangeBackingProperties];
+	[self callSetGeom];
+}
+
+- (void)mouseEventNS:(NSEvent *)theEvent {
+	NSPoint p = [theEvent locationInWindow];
+	double h = self.frame.size.height;
+
+	// Both h and p are measured in Cocoa pixels, which are a fraction of
+	// physical pixels, so we multiply by backingScaleFactor.
+	double scale = [self.window backingScaleFactor];
+
+	double x = p.x * scale;
+	double y = (h - p.y) * scale - 1; // flip origin from bottom-left to top-left.
+
+	if (pointerCaptured && (theEvent.type == NSEventTypeMouseMoved ||
+		theEvent.type == NSEventTypeLeftMouseDragged ||
+		theEvent.type == NSEventTypeRightMouseDragged ||
+		theEvent.type == NSEventTypeOtherMouseDragged)) {
+		// The event's deltas, unlike its location, are positive downwards.
+		mtlRelativeMouseEvent((GoUintptr)self, theEvent.deltaX * scale, theEvent.deltaY * scale);
+		return;
+	}
+
+	// Pens on drawing tablets send mouse events, with the tablet point
+	// subtype, that are sent as screen.PenEvents instead. The pen's types
+	// are those of screen.PenType.
+	//
+	// Trackpads send NSTouches, but they are not sent as touch.Events, as
+	// they do not touch the window.
+	int penType = -1;
+	switch (theEvent.type) {
+	case NSEventTypeMouseMoved:
+		penType = 0;
+		break;
+	case NSEventTypeLeftMouseDown:
+		penType = 1;
+		break;
+	case NSEventTypeLeftMouseDragged:
+		penType = 2;
+		break;
+	case NSEventTypeLeftMouseUp:
+		penType = 3;
+		break;
+	}
+	if (penType >= 0 && theEvent.subtype == NSEventSubtypeTabletPoint) {
+		// The tilts are from -1 to 1, and, unlike screen.PenEvent's, the
+		// Y tilt is positive upwards.
+		NSPoint tilt = theEvent.tilt;
+		int barrel = (theEvent.buttonMask & (NSEventButtonMaskPenLowerSide | NSEventButtonMaskPenUpperSide)) != 0;
+		mtlPenEvent((GoUintptr)self, x, y, penType, theEvent.pressure, tilt.x * 90, -tilt.y * 90,
+			penEraser, barrel, theEvent.modifierFlags);
+		return;
+	}
+
+	if (theEvent.type == NSEventTypeScrollWheel) {
+		mtlScrollEvent((GoUintptr)self, x, y, theEvent.scrollingDeltaX, theEvent.scrollingDeltaY,
+			theEvent.hasPreciseScrollingDeltas, theEvent.phase, theEvent.momentumPhase, theEvent.modifierFlags);
+		return;
+	}
+
+	mtlMouseEvent((GoUintptr)self, x, y, theEvent.type, theEvent.buttonNumber, theEvent.modifierFlags);
+}
+
+- (void)crossingEventNS:(NSEvent *)theEvent entered:(int)entered {
+	NSPoint p = [theEvent locationInWindow];
+	double h = self.frame.size.height;
+	double scale = [self.window backingScaleFactor];
+	mtlCrossingEvent((GoUintptr)self, entered, p.x * scale, (h - p.y) * scale - 1);
+}
+
+- (void)mouseEntered:(NSEvent *)theEvent      { [self crossingEventNS:theEvent entered:1]; }
+- (void)mouseExited:(NSEvent *)theEvent       { [self crossingEventNS:theEvent entered:0]; }
+- (void)mouseMoved:(NSEvent *)theEvent        { [self mouseEventNS:theEvent]; }
+- (void)mouseDown:(NSEvent *)theEvent         { [self mouseEventNS:theEvent]; }
+- (void)mouseUp:(NSEvent *)theEvent           { [self mouseEventNS:theEvent]; }
+- (void)mouseDragged:(NSEvent *)theEvent      { [self mouseEventNS:theEvent]; }
+- (void)rightMouseDown:(NSEvent *)theEvent    { [self mouseEventNS:theEvent]; }
+- (void)rightMouseUp:(NSEvent *)theEvent      { [self mouseEventNS:theEvent]; }
+- (void)rightMouseDragged:(NSEvent *)theEvent { [self mouseEventNS:theEvent]; }
+- (void)otherMouseDown:(NSEvent *)theEvent    { [self mouseEventNS:theEvent]; }
+- (void)otherMouseUp:(NSEvent *)theEvent      { [self mouseEventNS:theEvent]; }
+- (void)otherMouseDragged:(NSEvent *)theEvent { [self mouseEventNS:theEvent]; }
+- (void)scrollWheel:(NSEvent *)theEvent       { [self mouseEventNS:theEvent]; }
+
+- (void)tabletProximity:(NSEvent *)theEvent {
+	penEraser = theEvent.isEnteringProximity && theEvent.pointingDeviceType == NSPointingDeviceTypeEraser;
+}
+
+// raw modifier key presses
+- (void)flagsChanged:(NSEvent *)theEvent {
+	mtlFlagEvent((GoUintptr)self, theEvent.modifierFlags);
+}
+
+// overrides special handling of escape and tab
+- (BOOL)performKeyEquivalent:(NSEvent *)theEvent {
+	if ((theEvent.modifierFlags & NSEventModifierFlagCommand) != 0) {
+		[self key:theEvent];
+	} else {
+		[self keyDown:theEvent];
+	}
+	return YES;
+}
+
+// keyDown offers the key press to the input method, which calls back into
+// the NSTextInputClient methods below. It is sent as a key event unless the
+// input method used it to compose text.
+- (void)keyDown:(NSEvent *)theEvent {
+	keyEvent = theEvent;
+	keyConsumed = NO;
+	[self interpretKeyEvents:[NSArray arrayWithObject:theEvent]];
+	keyEvent = nil;
+	if (!keyConsumed) {
+		[self key:theEvent];
+	}
+}
+
+- (void)keyUp:(NSEvent *)theEvent { [self key:theEvent]; }
+
+- (void)key:(NSEvent *)theEvent {
+	NSRange range = [theEvent.characters rangeOfComposedCharacterSequenceAtIndex:0];
+
+	uint8_t buf[4] = {0, 0, 0, 0};
+	if (![theEvent.characters getBytes:buf
+			maxLength:4
+			usedLength:nil
+			encoding:NSUTF32LittleEndianStringEncoding
+			options:NSStringEncodingConversionAllowLossy
+			range:range
+			remainingRange:nil]) {
+		NSLog(@"failed to read key event %@", theEvent);
+		return;
+	}
+
+	uint32_t rune = (uint32_t)buf[0]<<0 | (uint32_t)buf[1]<<8 | (uint32_t)buf[2]<<16 | (uint32_t)buf[3]<<24;
+
+	uint8_t direction;
+	if ([theEvent isARepeat]) {
+		direction = 0;
+	} else if (theEvent.type == NSEventTypeKeyDown) {
+		direction = 1;
+	} else {
+		direction = 2;
+	}
+	mtlKeyEvent((GoUintptr)self, (int32_t)rune, mtlUnmodifiedRune(theEvent), direction, theEvent.keyCode, theEvent.modifierFlags);
+}
+
+// NSTextInputClient methods.
+
+- (void)insertText:(id)string replacementRange:(NSRange)replacementRange {
+	NSString* s = [string isKindOfClass:[NSAttributedString class]] ? [string string] : string;
+	BOOL composing = [self hasMarkedText];
+	[markedText release];
+	markedText = nil;
+	if (!composing && keyEvent != nil && [s isEqualToString:keyEvent.characters]) {
+		// Plain typing, which is sent as a key event.
+		return;
+	}
+	keyConsumed = YES;
+	mtlTextEvent((GoUintptr)self, "", 0, (char*)[s UTF8String]);
+}
+
+- (void)setMarkedText:(id)string selectedRange:(NSRange)selectedRange replacementRange:(NSRange)replacementRange {
+	NSString* s = [string isKindOfClass:[NSAttributedString class]] ? [string string] : string;
+	[markedText release];
+	markedText = [s copy];
+	keyConsumed = YES;
+
+	// Convert the cursor from UTF-16 code units to UTF-8 bytes.
+	NSUInteger i = MIN(selectedRange.location, [s length]);
+	int cursor = (int)strlen([[s substringToIndex:i] UTF8String]);
+	mtlTextEvent((GoUintptr)self, (char*)[s UTF8String], cursor, "");
+}
+
+- (void)unmarkText {
+	if (![self hasMarkedText]) {
+		return;
+	}
+	// Commit the text being composed.
+	NSString* s = markedText;
+	markedText = nil;
+	mtlTextEvent((GoUintptr)self, "", 0, (char*)[s UTF8String]);
+	[s release];
+}
+
+- (BOOL)hasMarkedText {
+	return markedText != nil && [markedText length] > 0;
+}
+
+- (NSRange)markedRange {
+	if (![self hasMarkedText]) {
+		return NSMakeRange(NSNotFound, 0);
+	}
+	return NSMakeRange(0, [markedText length]);
+}
+
+- (NSRange)selectedRange {
+	return NSMakeRange(NSNotFound, 0);
+}
+
+- (NSArray*)validAttributesForMarkedText {
+	return [NSArray array];
+}
+
+- (NSAttributedString*)attributedSubstringForProposedRange:(NSRange)range actualRange:(NSRangePointer)actualRange {
+	return nil;
+}
+
+- (NSUInteger)characterIndexForPoint:(NSPoint)point {
+	return NSNotFound;
+}
+
+- (NSRect)firstRectForCharacterRange:(NSRange)range actualRange:(NSRangePointer)actualRange {
+	// Convert textInputRect from pixels with a top left origin to screen
+	// co-ordinates, for placing the candidate window.
+	double scale = [self.window backingScaleFactor];
+	NSRect r = NSMakeRect(
+		textInputRect.origin.x / scale,
+		self.bounds.size.height - (textInputRect.origin.y + textInputRect.size.height) / scale,
+		textInputRect.size.width / scale,
+		textInputRect.size.height / scale);
+	r = [self convertRect:r toView:nil];
+	return [self.window convertRectToScreen:r];
+}
+
+- (void)doCommandBySelector:(SEL)selector {
+	// Commands, such as those bound to the arrow keys, are sent as key
+	// events by keyDown. Not calling super avoids a system beep.
+}
+
+- (void)setTextInputRect:(NSRect)r {
+	textInputRect = r;
+	[[self inputContext] invalidateCharacterCoordinates];
+}
+
+- (void)setScreenCursor:(NSCursor*)c {
+	[cursor release];
+	cursor = c;
+	[self.window invalidateCursorRectsForView:self];
+}
+
+- (void)setCursorHidden:(BOOL)hidden {
+	cursorHidden = hidden;
+	[self.window invalidateCursorRectsForView:self];
+}
+
+- (void)setPointerCaptured:(BOOL)captured {
+	if (captured == pointerCaptured) {
+		return;
+	}
+	pointerCaptured = captured;
+	// Dissociating the mouse from the cursor holds the cursor in place,
+	// while the mouse's motion is still reported in events' deltas.
+	CGAssociateMouseAndMouseCursorPosition(!captured);
+	if (captured) {
+		[NSCursor hide];
+	} else {
+		[NSCursor unhide];
+	}
+}
+
+- (void)setInterceptClose:(BOOL)intercept {
+	interceptClose = intercept;
+}
+
+- (void)setClosing {
+	closing = YES;
+}
+
+- (void)resetCursorRects {
+	NSCursor* c = cursorHidden ? mtlBlankCursor() : cursor;
+	if (c != nil) {
+		[self addCursorRect:[self bounds] cursor:c];
+	}
+}
+
+// sendDragEvent sends a screen.DragEvent of the given type, and accepts the
+// drag as a copy.
+- (NSDragOperation)sendDragEvent:(id<NSDraggingInfo>)sender type:(int)type {
+	NSPoint p = [sender draggingLocation];
+	double h = self.frame.size.height;
+	double scale = [self.window backingScaleFactor];
+	mtlDragEvent((GoUintptr)self, type, p.x * scale, (h - p.y) * scale, (GoUintptr)[sender draggingPasteboard]);
+	return NSDragOperationCopy;
+}
+
+- (NSDragOperation)draggingEntered:(id<NSDraggingInfo>)sender {
+	return [self sendDragEvent:sender type:0];
+}
+
+- (NSDragOperation)draggingUpdated:(id<NSDraggingInfo>)sender {
+	return [self sendDragEvent:sender type:1];
+}
+
+- (void)draggingExited:(id<NSDraggingInfo>)sender {
+	[self sendDragEvent:sender type:2];
+}
+
+- (BOOL)performDragOperation:(id<NSDraggingInfo>)sender {
+	[self sendDragEvent:sender type:3];
+	return YES;
+}
+
+- (void)windowDidChangeScreen:(NSNotification *)notification {
+	// The new screen may have a different resolution.
+	[self callSetGeom];
+}
+
+- (void)windowDidChangeScreenProfile:(NSNotification *)notification {
+	[self callSetGeom];
+}
+
+- (void)windowDidChangeOcclusionState:(NSNotification *)notification {
+	BOOL visible = (self.window.occlusionState & NSWindowOcclusionStateVisible) != 0;
+	mtlLifecycleVisible((GoUintptr)self, visible);
+	mtlVisibilityChanged((GoUintptr)self, visible);
+}
+
+- (void)windowDidMiniaturize:(NSNotification *)notification {
+	[self callSetState];
+}
+
+- (void)windowDidDeminiaturize:(NSNotification *)notification {
+	[self callSetState];
+}
+
+- (void)windowDidEnterFullScreen:(NSNotification *)notification {
+	[self callSetState];
+}
+
+- (void)windowDidExitFullScreen:(NSNotification *)notification {
+	[self callSetState];
+}
+
+- (void)windowDidBecomeKey:(NSNotification *)notification {
+	mtlLifecycleFocused((GoUintptr)self, true);
+}
+
+- (void)windowDidResignKey:(NSNotification *)notification {
+	// The pointer capture ends when the window loses the focus, as the
+	// pointer would otherwise stay held for an application that is not
+	// being used.
+	[self setPointerCaptured:NO];
+	mtlLifecycleFocused((GoUintptr)self, false);
+	if ([NSApp isHidden]) {
+		mtlLifecycleVisible((GoUintptr)self, false);
+	}
+}
+
+- (BOOL)windowShouldClose:(NSWindow *)sender {
+	if (!interceptClose || closing) {
+		return YES;
+	}
+	mtlWindowCloseRequested((GoUintptr)self);
+	return NO;
+}
+
+- (void)windowWillClose:(NSNotification *)notification {
+	if (closed) {
+		return;
+	}
+	closed = YES;
+	[self setPointerCaptured:NO];
+	mtlWindowClosing((GoUintptr)self);
+}
+@end
+
+@interface ScreenMetalAppDelegate : NSObject<NSApplicationDelegate>
+{
+}
+@end
+
+@implementation ScreenMetalAppDelegate
+- (void)applicationDidFinishLaunching:(NSNotification *)aNotification {
+	[[[NSWorkspace sharedWorkspace] notificationCenter] addObserver:self
+		selector:@selector(accessibilityDisplayOptionsDidChange:)
+		name:NSWorkspaceAccessibilityDisplayOptionsDidChangeNotification
+		object:nil];
+	[[NSNotificationCenter defaultCenter] addObserver:self
+		selector:@selector(screenParametersDidChange:)
+		name:NSApplicationDidChangeScreenParametersNotification
+		object:nil];
+	// There is no public notification for changes to the system appearance,
+	// but System Preferences posts this one.
+	[[NSDistributedNotificationCenter defaultCenter] addObserver:self
+		selector:@selector(interfaceThemeDidChange:)
+		name:@"AppleInterfaceThemeChangedNotification"
+		object:nil];
+	[[NSNotificationCenter defaultCenter] addObserver:self
+		selector:@selector(keyboardSelectionDidChange:)
+		name:NSTextInputContextKeyboardSelectionDidChangeNotification
+		object:nil];
+	mtlDriverStarted();
+	[[NSRunningApplication currentApplication] activateWithOptions:(NSApplicationActivateAllWindows | NSApplicationActivateIgnoringOtherApps)];
+}
+
+- (void)applicationWillTerminate:(NSNotification *)aNotification {
+	mtlLifecycleDeadAll();
+}
+
+- (void)applicationWillHide:(NSNotification *)aNotification {
+	mtlLifecycleHideAll();
+}
+
+- (void)accessibilityDisplayOptionsDidChange:(NSNotification *)aNotification {
+	mtlAccessibilityChanged();
+}
+
+- (void)interfaceThemeDidChange:(NSNotification *)aNotification {
+	mtlColorSchemeChanged();
+}
+
+- (void)keyboardSelectionDidChange:(NSNotification *)aNotification {
+	NSTextInputContext* ctx = aNotification.object;
+	NSString* source = ctx.selectedKeyboardInputSource;
+	if (source != nil) {
+		mtlKeyboardLayoutChanged((char*)[source UTF8String]);
+	}
+}
+
+- (void)screenParametersDidChange:(NSNotification *)aNotification {
+	mtlDisplaysChanged();
+}
+@end
+
+void mtlGetAccessibilityPrefs(int* reduceMotion, int* increaseContrast, int* reduceTransparency) {
+	NSWorkspace* ws = [NSWorkspace sharedWorkspace];
+	*reduceMotion = 0;
+	if ([ws respondsToSelector:@selector(accessibilityDisplayShouldReduceMotion)]) {
+		*reduceMotion = ws.accessibilityDisplayShouldReduceMotion;
+	}
+	*increaseContrast = ws.accessibilityDisplayShouldIncreaseContrast;
+	*reduceTransparency = ws.accessibilityDisplayShouldReduceTransparency;
+}
+
+// mtlIsDarkMode reports whether the user has chosen the dark appearance. Unlike
+// NSApp.effectiveAppearance, the user defaults are safe to read from any
+// thread.
+int mtlIsDarkMode() {
+	NSString* style = [[NSUserDefaults standardUserDefaults] stringForKey:@"AppleInterfaceStyle"];
+	return [style isEqualToString:@"Dark"];
+}
+
+char* mtlClipboardReadText() {
+	__block char* text = NULL;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		NSString* s = [[NSPasteboard generalPasteboard] stringForType:NSPasteboardTypeString];
+		if (s != nil) {
+			text = strdup([s UTF8String]);
+		}
+	});
+	return text;
+}
+
+int mtlClipboardWriteText(char* text, int len) {
+	__block BOOL ok = NO;
+	NSString* s = [[NSString alloc] initWithBytes:text length:len encoding:NSUTF8StringEncoding];
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		NSPasteboard* pb = [NSPasteboard generalPasteboard];
+		[pb clearContents];
+		ok = [pb setString:s forType:NSPasteboardTypeString];
+	});
+	[s release];
+	return ok;
+}
+
+uintptr_t mtlNewWindow(int width, int height, int x, int y, int hasPosition, int fixedSize, char* title, int interceptClose, int transparent, int undecorated) {
+	NSScreen *screen = [NSScreen mainScreen];
+	double w = (double)width / [screen backingScaleFactor];
+	double h = (double)height / [screen backingScaleFactor];
+	// Cocoa's screen co-ordinates have their origin at the bottom left of
+	// the main screen, and are in points, not pixels.
+	NSPoint topLeft = NSMakePoint(
+		(double)x / [screen backingScaleFactor],
+		screen.frame.size.height - (double)y / [screen backingScaleFactor]);
+	__block ScreenMetalView* view = NULL;
+
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		id menuBar = [NSMenu new];
+		id menuItem = [NSMenuItem new];
+		[menuBar addItem:menuItem];
+		[NSApp setMainMenu:menuBar];
+
+		id menu = [NSMenu new];
+		NSString* name = [[NSString alloc] initWithUTF8String:title];
+
+		id hideMenuItem = [[NSMenuItem alloc] initWithTitle:@"Hide"
+			action:@selector(hide:) keyEquivalent:@"h"];
+		[menu addItem:hideMenuItem];
+
+		id quitMenuItem = [[NSMenuItem alloc] initWithTitle:@"Quit"
+			action:@selector(terminate:) keyEquivalent:@"q"];
+		[menu addItem:quitMenuItem];
+		[menuItem setSubmenu:menu];
+
+		NSRect rect = NSMakeRect(0, 0, w, h);
+
+		NSWindow* window = [[NSWindow alloc] initWithContentRect:rect
+				styleMask:NSWindowStyleMaskTitled
+				backing:NSBackingStoreBuffered
+				defer:NO];
+		if (!fixedSize) {
+			window.styleMask |= NSWindowStyleMaskResizable;
+		}
+		window.styleMask |= NSWindowStyleMaskMiniaturizable;
+		window.styleMask |= NSWindowStyleMaskClosable;
+		window.collectionBehavior |= NSWindowCollectionBehaviorFullScreenPrimary;
+		window.title = name;
+		if (undecorated) {
+			// The content covers the title bar, which is transparent and
+			// has neither a title nor buttons, but the window is still
+			// titled, so that it can become key and be minimized.
+			window.styleMask |= NSWindowStyleMaskFullSizeContentView;
+			window.titlebarAppearsTransparent = YES;
+			window.titleVisibility = NSWindowTitleHidden;
+			[window standardWindowButton:NSWindowCloseButton].hidden = YES;
+			[window standardWindowButton:NSWindowMiniaturizeButton].hidden = YES;
+			[window standardWindowButton:NSWindowZoomButton].hidden = YES;
+		}
+		if (hasPosition) {
+			[window setFrameTopLeftPoint:topLeft];
+		} else {
+			[window cascadeTopLeftFromPoint:NSMakePoint(20,20)];
+		}
+		[window setAcceptsMouseMovedEvents:YES];
+
+		view = [[ScreenMetalView alloc] initWithFrame:rect];
+		if (transparent) {
+			// The window, and its layer, are composited with per-pixel
+			// alpha over whatever is behind them.
+			window.opaque = NO;
+			window.backgroundColor = [NSColor clearColor];
+			view.metalLayer.opaque = NO;
+		}
+		[view setInterceptClose:interceptClose];
+		[window setContentView:view];
+		// The tracking area follows the view's size, so that the view is
+		// told whenever the pointer enters or leaves the window's content.
+		[view addTrackingArea:[[NSTrackingArea alloc] initWithRect:NSZeroRect
+			options:NSTrackingMouseEnteredAndExited | NSTrackingActiveAlways | NSTrackingInVisibleRect
+			owner:view userInfo:nil]];
+		[window setDelegate:view];
+		[window makeFirstResponder:view];
+	});
+
+	return (uintptr_t)view;
+}
+
+void mtlShowWindow(uintptr_t viewID, int hidden, int owned) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (!hidden) {
+			// An owned window has already been shown, by
+			// cocoadisplay.ShowOwned.
+			if (!owned) {
+				[view.window makeKeyAndOrderFront:view.window];
+			}
+			mtlLifecycleVisible((GoUintptr)view, true);
+		}
+		// The view was sized before the window was registered with the
+		// Go side. Send its size now, which a hidden window needs too.
+		[view callSetGeom];
+	});
+}
+
+void mtlSetTitle(uintptr_t viewID, char* title) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	NSString* name = [[NSString alloc] initWithUTF8String:title];
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		if (view.window != nil) {
+			view.window.title = name;
+		}
+	});
+	[name release];
+}
+
+void mtlSetSize(uintptr_t viewID, int width, int height) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		if (window == nil) {
+			return; // The window has been closed.
+		}
+		double scale = [window backingScaleFactor];
+		NSRect old = window.frame;
+		NSRect frame = [window frameRectForContentRect:NSMakeRect(0, 0, width / scale, height / scale)];
+		// Keep the top left corner where it is. Cocoa's origin is the
+		// bottom left corner.
+		frame.origin.x = old.origin.x;
+		frame.origin.y = old.origin.y + old.size.height - frame.size.height;
+		[window setFrame:frame display:YES];
+	});
+}
+
+void mtlSetPosition(uintptr_t viewID, int x, int y) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		// As for mtlNewWindow, convert from pixels with a top left origin.
+		NSScreen *screen = [NSScreen mainScreen];
+		NSPoint topLeft = NSMakePoint(
+			(double)x / [screen backingScaleFactor],
+			screen.frame.size.height - (double)y / [screen backingScaleFactor]);
+		[view.window setFrameTopLeftPoint:topLeft]; // A no-op if closed.
+	});
+}
+
+void mtlMinimize(uintptr_t viewID) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[view.window miniaturize:nil]; // A no-op if closed.
+	});
+}
+
+// mtlMaximize and mtlRestore leave fullscreen, if the window is fullscreen,
+// before zooming or unzooming it.
+//
+// TODO: wait for the animation out of fullscreen to finish before zooming,
+// which may otherwise be ignored.
+void mtlMaximize(uintptr_t viewID) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		if (window.styleMask & NSWindowStyleMaskFullScreen) {
+			[window toggleFullScreen:nil];
+		}
+		[window deminiaturize:nil];
+		if (!window.zoomed) {
+			[window zoom:nil];
+		}
+	});
+}
+
+void mtlRestore(uintptr_t viewID) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		if (window.styleMask & NSWindowStyleMaskFullScreen) {
+			[window toggleFullScreen:nil];
+		}
+		[window deminiaturize:nil];
+		if (window.zoomed) {
+			[window zoom:nil];
+		}
+	});
+}
+
+void mtlSetFullscreen(uintptr_t viewID, int fullscreen) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSWindow* window = view.window;
+		BOOL isFullscreen = (window.styleMask & NSWindowStyleMaskFullScreen) != 0;
+		if (isFullscreen != (fullscreen != 0)) {
+			[window toggleFullScreen:nil];
+		}
+	});
+}
+
+void mtlSetColorSpace(uintptr_t viewID, int p3) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		CGColorSpaceRef cs = CGColorSpaceCreateWithName(p3 ? kCGColorSpaceDisplayP3 : kCGColorSpaceSRGB);
+		[view metalLayer].colorspace = cs;
+		CGColorSpaceRelease(cs);
+	});
+}
+
+void mtlSetTextInputRect(uintptr_t viewID, int x, int y, int width, int height) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[view setTextInputRect:NSMakeRect(x, y, width, height)];
+	});
+}
+
+void mtlSetCursor(uintptr_t viewID, uintptr_t cursorID) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	NSCursor* cursor = (NSCursor*)cursorID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[view setScreenCursor:cursor];
+	});
+}
+
+void mtlSetPointerCapture(uintptr_t viewID, int capture) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (view.window == nil || !view.window.isKeyWindow) {
+			capture = 0; // Only the key window can capture the pointer.
+		}
+		[view setPointerCaptured:capture];
+	});
+}
+
+void mtlSetCursorVisible(uintptr_t viewID, int visible) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[view setCursorHidden:!visible];
+	});
+}
+
+void mtlGetGeometry(uintptr_t viewID, int* x, int* y, int* width, int* height) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	*x = *y = *width = *height = 0;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		if (view.window == nil) {
+			return; // The window has been closed.
+		}
+		NSScreen *screen = [NSScreen mainScreen];
+		double scale = [screen backingScaleFactor];
+		NSRect frame = view.window.frame;
+		*x = frame.origin.x * scale;
+		*y = (screen.frame.size.height - (frame.origin.y + frame.size.height)) * scale;
+		NSRect r = [view convertRectToBacking:[view bounds]];
+		*width = r.size.width;
+		*height = r.size.height;
+	});
+}
+
+void mtlCloseWindow(uintptr_t viewID) {
+	ScreenMetalView* view = (ScreenMetalView*)viewID;
+	dispatch_sync(dispatch_get_main_queue(), ^{
+		[view setClosing];
+		[view.window performClose:view];
+	});
+}
+
+void mtlStartDriver() {
+	[NSAutoreleasePool new];
+	[NSApplication sharedApplication];
+	[NSApp setActivationPolicy:NSApplicationActivationPolicyRegular];
+	ScreenMetalAppDelegate* delegate = [[ScreenMetalAppDelegate alloc] init];
+	[NSApp setDelegate:delegate];
+	[NSApp run];
+}
+
+void mtlStopDriver() {
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[NSApp terminate:nil];
+	});
+}
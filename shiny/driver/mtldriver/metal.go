@@ -0,0 +1,167 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package mtldriver
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Metal -framework QuartzCore
+#include <stdint.h>
+
+int mtlAvailable();
+int mtlInit();
+uintptr_t mtlNewTexture(int width, int height, int mipmapped);
+int mtlSupportsCompressed(int format);
+uintptr_t mtlNewCompressedTexture(int width, int height, int format);
+void mtlGenerateMipmaps(uintptr_t t);
+void mtlReleaseTexture(uintptr_t t);
+void mtlUploadTexture(uintptr_t t, int x, int y, int width, int height, void* pix, int stride, int rows);
+void mtlCopyTexture(uintptr_t dst, uintptr_t src, int width, int height);
+void mtlDownloadTexture(uintptr_t t, int x, int y, int width, int height, void* pix, int stride);
+void mtlDrawQuad(uintptr_t dst, uintptr_t src, float* vertices, float* color, int mode, int nearest, int wrap);
+int mtlPresent(uintptr_t view, uintptr_t back, int width, int height);
+void mtlFinish();
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"unsafe"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// The functions in this file may be called from any goroutine. They encode
+// each operation in its own command buffer, and Metal executes a queue's
+// command buffers in the order that they are committed, so operations on the
+// same texture happen in the order that they are called.
+
+func available() bool {
+	return C.mtlAvailable() != 0
+}
+
+// metalInit creates the Metal device, command queue and render pipelines.
+func metalInit() error {
+	if C.mtlInit() == 0 {
+		return errors.New("mtldriver: Metal initialization failed")
+	}
+	return nil
+}
+
+// newTexture returns a new id<MTLTexture>, whose contents are transparent. If
+// mipmapped, it has a full chain of mipmap levels, which generateMipmaps
+// fills in.
+func newTexture(sz image.Point, mipmapped bool) (uintptr, error) {
+	if sz.X <= 0 || sz.Y <= 0 {
+		return 0, errors.New("mtldriver: texture size must be positive")
+	}
+	m := 0
+	if mipmapped {
+		m = 1
+	}
+	t := uintptr(C.mtlNewTexture(C.int(sz.X), C.int(sz.Y), C.int(m)))
+	if t == 0 {
+		return 0, errors.New("mtldriver: texture creation failed")
+	}
+	return t, nil
+}
+
+// supportsCompressed returns whether the device supports textures that are
+// compressed in the format f.
+func supportsCompressed(f screen.CompressedFormat) bool {
+	return C.mtlSupportsCompressed(C.int(f)) != 0
+}
+
+// newCompressedTexture returns a new id<MTLTexture> whose pixels are
+// compressed in the format f, with undefined contents.
+func newCompressedTexture(sz image.Point, f screen.CompressedFormat) (uintptr, error) {
+	if sz.X <= 0 || sz.Y <= 0 {
+		return 0, errors.New("mtldriver: texture size must be positive")
+	}
+	t := uintptr(C.mtlNewCompressedTexture(C.int(sz.X), C.int(sz.Y), C.int(f)))
+	if t == 0 {
+		return 0, errors.New("mtldriver: texture creation failed")
+	}
+	return t, nil
+}
+
+func releaseTexture(t uintptr) {
+	C.mtlReleaseTexture(C.uintptr_t(t))
+}
+
+// uploadTexture copies pix, BGRA pixels with the given stride, to the
+// rectangle r of the texture t.
+func uploadTexture(t uintptr, r image.Rectangle, pix []byte, stride int) {
+	C.mtlUploadTexture(C.uintptr_t(t), C.int(r.Min.X), C.int(r.Min.Y), C.int(r.Dx()), C.int(r.Dy()),
+		unsafe.Pointer(&pix[0]), C.int(stride), C.int(r.Dy()))
+}
+
+// uploadCompressed copies pix, rows of blocks each of the given stride, to
+// the rectangle r of the compressed texture t.
+func uploadCompressed(t uintptr, r image.Rectangle, pix []byte, stride, rows int) {
+	C.mtlUploadTexture(C.uintptr_t(t), C.int(r.Min.X), C.int(r.Min.Y), C.int(r.Dx()), C.int(r.Dy()),
+		unsafe.Pointer(&pix[0]), C.int(stride), C.int(rows))
+}
+
+// generateMipmaps regenerates the mipmap levels of the texture t from its
+// first level.
+func generateMipmaps(t uintptr) {
+	C.mtlGenerateMipmaps(C.uintptr_t(t))
+}
+
+// copyTexture copies the top left sz pixels of src to dst.
+func copyTexture(dst, src uintptr, sz image.Point) {
+	C.mtlCopyTexture(C.uintptr_t(dst), C.uintptr_t(src), C.int(sz.X), C.int(sz.Y))
+}
+
+// downloadTexture copies the rectangle r of the texture t to pix, as BGRA
+// pixels with the given stride, waiting for any pending drawing to finish.
+func downloadTexture(t uintptr, r image.Rectangle, pix []byte, stride int) {
+	C.mtlDownloadTexture(C.uintptr_t(t), C.int(r.Min.X), C.int(r.Min.Y), C.int(r.Dx()), C.int(r.Dy()),
+		unsafe.Pointer(&pix[0]), C.int(stride))
+}
+
+// drawQuad draws the triangle strip v, as returned by quad.Vertices, onto dst,
+// blending as opts and op ask. If src is non-nil, its texture is sampled, as
+// its filter and wrap options ask. Otherwise, the quad is filled with the
+// color c.
+func drawQuad(dst uintptr, src *textureImpl, v [16]float32, c color.Color, op draw.Op, opts *screen.DrawOptions) {
+	// The texture shader multiplies by rgba, for transparency.
+	k := float32(opts.GetAlpha()) / 0xffff
+	rgba := [4]float32{k, k, k, k}
+	id, nearest, wrap := uintptr(0), 0, 0
+	if src != nil {
+		id, wrap = src.id, int(src.wrap)
+		if src.filter == screen.FilterNearest {
+			nearest = 1
+		}
+	} else {
+		// The color is alpha-premultiplied, as the blend functions expect.
+		r, g, b, a := c.RGBA()
+		rgba = [4]float32{
+			k * float32(r) / 0xffff,
+			k * float32(g) / 0xffff,
+			k * float32(b) / 0xffff,
+			k * float32(a) / 0xffff,
+		}
+	}
+	C.mtlDrawQuad(C.uintptr_t(dst), C.uintptr_t(id), (*C.float)(&v[0]), (*C.float)(&rgba[0]), C.int(opts.GetBlend(op)),
+		C.int(nearest), C.int(wrap))
+}
+
+// present blits the back buffer to the next drawable of the view's
+// CAMetalLayer, and presents it. It blocks until a drawable is available.
+func present(view, back uintptr, sz image.Point) bool {
+	return C.mtlPresent(C.uintptr_t(view), C.uintptr_t(back), C.int(sz.X), C.int(sz.Y)) != 0
+}
+
+// finish waits for all committed command buffers to finish executing.
+func finish() {
+	C.mtlFinish()
+}
@@ -0,0 +1,398 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin
+// +build !ios
+
+#include <stdint.h>
+#include <string.h>
+
+#import <Foundation/Foundation.h>
+#import <Metal/Metal.h>
+#import <QuartzCore/CAMetalLayer.h>
+
+// mtlViewLayer is defined in cocoa.m.
+CAMetalLayer* mtlViewLayer(uintptr_t view);
+
+static id<MTLDevice> device;
+static id<MTLCommandQueue> queue;
+
+// numBlendModes is screen.NumBlendModes.
+#define numBlendModes 16
+
+// blendFactors are the source and destination blend factors of each
+// screen.BlendMode, in the same order, as documented there. The first,
+// BlendDefault, is never drawn with.
+static const MTLBlendFactor blendFactors[numBlendModes][2] = {
+	{MTLBlendFactorOne, MTLBlendFactorOneMinusSourceAlpha},                      // BlendDefault
+	{MTLBlendFactorZero, MTLBlendFactorZero},                                    // BlendClear
+	{MTLBlendFactorOne, MTLBlendFactorZero},                                     // BlendSrc
+	{MTLBlendFactorZero, MTLBlendFactorOne},                                     // BlendDst
+	{MTLBlendFactorOne, MTLBlendFactorOneMinusSourceAlpha},                      // BlendSrcOver
+	{MTLBlendFactorOneMinusDestinationAlpha, MTLBlendFactorOne},                 // BlendDstOver
+	{MTLBlendFactorDestinationAlpha, MTLBlendFactorZero},                        // BlendSrcIn
+	{MTLBlendFactorZero, MTLBlendFactorSourceAlpha},                             // BlendDstIn
+	{MTLBlendFactorOneMinusDestinationAlpha, MTLBlendFactorZero},                // BlendSrcOut
+	{MTLBlendFactorZero, MTLBlendFactorOneMinusSourceAlpha},                     // BlendDstOut
+	{MTLBlendFactorDestinationAlpha, MTLBlendFactorOneMinusSourceAlpha},         // BlendSrcAtop
+	{MTLBlendFactorOneMinusDestinationAlpha, MTLBlendFactorSourceAlpha},         // BlendDstAtop
+	{MTLBlendFactorOneMinusDestinationAlpha, MTLBlendFactorOneMinusSourceAlpha}, // BlendXor
+	{MTLBlendFactorOne, MTLBlendFactorOne},                                      // BlendAdd
+	{MTLBlendFactorDestinationColor, MTLBlendFactorOneMinusSourceAlpha},         // BlendMultiply
+	{MTLBlendFactorOne, MTLBlendFactorOneMinusSourceColor},                      // BlendScreen
+};
+
+// pipelines are indexed by whether a texture is sampled, rather than a
+// uniform color drawn, and then by the screen.BlendMode. BlendSrc's
+// pipelines do not enable blending.
+static id<MTLRenderPipelineState> pipelines[2][numBlendModes];
+
+// numWraps is the number of screen.TextureWrap values.
+#define numWraps 3
+
+// addressModes are the address modes of each screen.TextureWrap, in the same
+// order.
+static const MTLSamplerAddressMode addressModes[numWraps] = {
+	MTLSamplerAddressModeClampToEdge,  // WrapClamp
+	MTLSamplerAddressModeRepeat,       // WrapRepeat
+	MTLSamplerAddressModeMirrorRepeat, // WrapMirror
+};
+
+// samplers are indexed by whether a texture is sampled with nearest neighbor,
+// rather than linear, filtering, and then by the screen.TextureWrap.
+static id<MTLSamplerState> samplers[2][numWraps];
+
+// shaderSource is compiled when the driver starts. Each vertex is a
+// position, in normalized device co-ordinates, and a texture co-ordinate,
+// packed into a float4.
+static NSString* const shaderSource = @
+	"#include <metal_stdlib>\n"
+	"using namespace metal;\n"
+	"\n"
+	"struct Vertex {\n"
+	"	float4 position [[position]];\n"
+	"	float2 texCoord;\n"
+	"};\n"
+	"\n"
+	"vertex Vertex vertexShader(uint vid [[vertex_id]], constant float4* vertices [[buffer(0)]]) {\n"
+	"	Vertex out;\n"
+	"	out.position = float4(vertices[vid].xy, 0, 1);\n"
+	"	out.texCoord = vertices[vid].zw;\n"
+	"	return out;\n"
+	"}\n"
+	"\n"
+	"fragment float4 fillShader(Vertex in [[stage_in]], constant float4& color [[buffer(0)]]) {\n"
+	"	return color;\n"
+	"}\n"
+	"\n"
+	"fragment float4 textureShader(Vertex in [[stage_in]], constant float4& color [[buffer(0)]], texture2d<float> tex [[texture(0)]], sampler s [[sampler(0)]]) {\n"
+	"	return tex.sample(s, in.texCoord) * color;\n"
+	"}\n";
+
+id<MTLDevice> mtlDevice() {
+	return device;
+}
+
+int mtlAvailable() {
+	@autoreleasepool {
+		id<MTLDevice> d = MTLCreateSystemDefaultDevice();
+		if (d == nil) {
+			return 0;
+		}
+		[d release];
+		return 1;
+	}
+}
+
+int mtlInit() {
+	@autoreleasepool {
+		device = MTLCreateSystemDefaultDevice();
+		if (device == nil) {
+			return 0;
+		}
+		queue = [device newCommandQueue];
+
+		NSError* err = nil;
+		id<MTLLibrary> library = [device newLibraryWithSource:shaderSource options:nil error:&err];
+		if (library == nil) {
+			NSLog(@"mtldriver: compiling shaders failed: %@", err);
+			return 0;
+		}
+		id<MTLFunction> vertexFunc = [library newFunctionWithName:@"vertexShader"];
+		id<MTLFunction> fragmentFuncs[2] = {
+			[library newFunctionWithName:@"fillShader"],
+			[library newFunctionWithName:@"textureShader"],
+		};
+		int ok = 1;
+		for (int textured = 0; textured < 2 && ok; textured++) {
+			for (int mode = 1; mode < numBlendModes && ok; mode++) {
+				MTLRenderPipelineDescriptor* desc = [[MTLRenderPipelineDescriptor alloc] init];
+				desc.vertexFunction = vertexFunc;
+				desc.fragmentFunction = fragmentFuncs[textured];
+				MTLRenderPipelineColorAttachmentDescriptor* ca = desc.colorAttachments[0];
+				ca.pixelFormat = MTLPixelFormatBGRA8Unorm;
+				if (mode != 2) { // BlendSrc.
+					// Colors and textures are alpha-premultiplied.
+					ca.blendingEnabled = YES;
+					ca.sourceRGBBlendFactor = blendFactors[mode][0];
+					ca.sourceAlphaBlendFactor = blendFactors[mode][0];
+					ca.destinationRGBBlendFactor = blendFactors[mode][1];
+					ca.destinationAlphaBlendFactor = blendFactors[mode][1];
+				}
+				pipelines[textured][mode] = [device newRenderPipelineStateWithDescriptor:desc error:&err];
+				[desc release];
+				if (pipelines[textured][mode] == nil) {
+					NSLog(@"mtldriver: creating render pipeline failed: %@", err);
+					ok = 0;
+				}
+			}
+		}
+		[vertexFunc release];
+		[fragmentFuncs[0] release];
+		[fragmentFuncs[1] release];
+		[library release];
+		if (!ok) {
+			return 0;
+		}
+
+		MTLSamplerDescriptor* sd = [[MTLSamplerDescriptor alloc] init];
+		for (int n = 0; n < 2; n++) {
+			MTLSamplerMinMagFilter f = n ? MTLSamplerMinMagFilterNearest : MTLSamplerMinMagFilterLinear;
+			sd.minFilter = f;
+			sd.magFilter = f;
+			// Textures without mipmaps have a single level, which the mip
+			// filter always picks.
+			sd.mipFilter = n ? MTLSamplerMipFilterNearest : MTLSamplerMipFilterLinear;
+			for (int w = 0; w < numWraps; w++) {
+				sd.sAddressMode = addressModes[w];
+				sd.tAddressMode = addressModes[w];
+				samplers[n][w] = [device newSamplerStateWithDescriptor:sd];
+			}
+		}
+		[sd release];
+		return 1;
+	}
+}
+
+uintptr_t mtlNewTexture(int width, int height, int mipmapped) {
+	@autoreleasepool {
+		MTLTextureDescriptor* desc = [MTLTextureDescriptor
+			texture2DDescriptorWithPixelFormat:MTLPixelFormatBGRA8Unorm
+			width:width
+			height:height
+			mipmapped:(mipmapped != 0)];
+		desc.storageMode = MTLStorageModePrivate;
+		desc.usage = MTLTextureUsageShaderRead | MTLTextureUsageRenderTarget;
+		id<MTLTexture> t = [device newTextureWithDescriptor:desc];
+		if (t == nil) {
+			return 0;
+		}
+
+		// A new texture's contents are undefined. Clear them.
+		id<MTLCommandBuffer> cb = [queue commandBuffer];
+		MTLRenderPassDescriptor* pass = [MTLRenderPassDescriptor renderPassDescriptor];
+		pass.colorAttachments[0].texture = t;
+		pass.colorAttachments[0].loadAction = MTLLoadActionClear;
+		pass.colorAttachments[0].clearColor = MTLClearColorMake(0, 0, 0, 0);
+		pass.colorAttachments[0].storeAction = MTLStoreActionStore;
+		id<MTLRenderCommandEncoder> enc = [cb renderCommandEncoderWithDescriptor:pass];
+		[enc endEncoding];
+		[cb commit];
+		return (uintptr_t)t;
+	}
+}
+
+// compressedPixelFormat returns the pixel format of the given
+// screen.CompressedFormat, or MTLPixelFormatInvalid if the device does not
+// support it. Every Mac GPU supports the BC formats, but Apple silicon ones
+// only from macOS 11, and only Apple silicon ones support ETC2 and ASTC.
+static MTLPixelFormat compressedPixelFormat(int format) {
+	BOOL bc = YES, apple = NO;
+	if (@available(macOS 11.0, *)) {
+		bc = device.supportsBCTextureCompression;
+		apple = [device supportsFamily:MTLGPUFamilyApple2];
+	}
+	switch (format) {
+	case 1: // CompressedBC1
+		return bc ? MTLPixelFormatBC1_RGBA : MTLPixelFormatInvalid;
+	case 2: // CompressedBC3
+		return bc ? MTLPixelFormatBC3_RGBA : MTLPixelFormatInvalid;
+	}
+	if (@available(macOS 11.0, *)) {
+		switch (format) {
+		case 3: // CompressedETC2RGB8
+			return apple ? MTLPixelFormatETC2_RGB8 : MTLPixelFormatInvalid;
+		case 4: // CompressedETC2RGBA8
+			return apple ? MTLPixelFormatEAC_RGBA8 : MTLPixelFormatInvalid;
+		case 5: // CompressedASTC4x4
+			return apple ? MTLPixelFormatASTC_4x4_LDR : MTLPixelFormatInvalid;
+		}
+	}
+	return MTLPixelFormatInvalid;
+}
+
+int mtlSupportsCompressed(int format) {
+	return compressedPixelFormat(format) != MTLPixelFormatInvalid;
+}
+
+uintptr_t mtlNewCompressedTexture(int width, int height, int format) {
+	@autoreleasepool {
+		MTLPixelFormat pf = compressedPixelFormat(format);
+		if (pf == MTLPixelFormatInvalid) {
+			return 0;
+		}
+		MTLTextureDescriptor* desc = [MTLTextureDescriptor
+			texture2DDescriptorWithPixelFormat:pf
+			width:width
+			height:height
+			mipmapped:NO];
+		desc.storageMode = MTLStorageModePrivate;
+		desc.usage = MTLTextureUsageShaderRead;
+		return (uintptr_t)[device newTextureWithDescriptor:desc];
+	}
+}
+
+void mtlReleaseTexture(uintptr_t tex) {
+	[(id<MTLTexture>)tex release];
+}
+
+void mtlUploadTexture(uintptr_t tex, int x, int y, int width, int height, void* pix, int stride, int rows) {
+	@autoreleasepool {
+		// The pixels are copied into a buffer, and then blitted by the GPU,
+		// so that the upload is ordered with respect to any draws.
+		NSUInteger length = (NSUInteger)stride * rows;
+		id<MTLBuffer> buf = [device newBufferWithBytes:pix length:length options:MTLResourceStorageModeShared];
+		id<MTLCommandBuffer> cb = [queue commandBuffer];
+		id<MTLBlitCommandEncoder> enc = [cb blitCommandEncoder];
+		[enc copyFromBuffer:buf
+			sourceOffset:0
+			sourceBytesPerRow:stride
+			sourceBytesPerImage:length
+			sourceSize:MTLSizeMake(width, height, 1)
+			toTexture:(id<MTLTexture>)tex
+			destinationSlice:0
+			destinationLevel:0
+			destinationOrigin:MTLOriginMake(x, y, 0)];
+		[enc endEncoding];
+		[cb commit];
+		[buf release];
+	}
+}
+
+void mtlCopyTexture(uintptr_t dst, uintptr_t src, int width, int height) {
+	@autoreleasepool {
+		id<MTLCommandBuffer> cb = [queue commandBuffer];
+		id<MTLBlitCommandEncoder> enc = [cb blitCommandEncoder];
+		[enc copyFromTexture:(id<MTLTexture>)src
+			sourceSlice:0
+			sourceLevel:0
+			sourceOrigin:MTLOriginMake(0, 0, 0)
+			sourceSize:MTLSizeMake(width, height, 1)
+			toTexture:(id<MTLTexture>)dst
+			destinationSlice:0
+			destinationLevel:0
+			destinationOrigin:MTLOriginMake(0, 0, 0)];
+		[enc endEncoding];
+		[cb commit];
+	}
+}
+
+void mtlDownloadTexture(uintptr_t tex, int x, int y, int width, int height, void* pix, int stride) {
+	@autoreleasepool {
+		NSUInteger rowBytes = 4 * (NSUInteger)width;
+		id<MTLBuffer> buf = [device newBufferWithLength:rowBytes*height options:MTLResourceStorageModeShared];
+		id<MTLCommandBuffer> cb = [queue commandBuffer];
+		id<MTLBlitCommandEncoder> enc = [cb blitCommandEncoder];
+		[enc copyFromTexture:(id<MTLTexture>)tex
+			sourceSlice:0
+			sourceLevel:0
+			sourceOrigin:MTLOriginMake(x, y, 0)
+			sourceSize:MTLSizeMake(width, height, 1)
+			toBuffer:buf
+			destinationOffset:0
+			destinationBytesPerRow:rowBytes
+			destinationBytesPerImage:rowBytes*height];
+		[enc endEncoding];
+		[cb commit];
+		[cb waitUntilCompleted];
+
+		const uint8_t* contents = [buf contents];
+		for (int i = 0; i < height; i++) {
+			memcpy((uint8_t*)pix + (size_t)i*stride, contents + (size_t)i*rowBytes, rowBytes);
+		}
+		[buf release];
+	}
+}
+
+void mtlGenerateMipmaps(uintptr_t t) {
+	@autoreleasepool {
+		id<MTLCommandBuffer> cb = [queue commandBuffer];
+		id<MTLBlitCommandEncoder> enc = [cb blitCommandEncoder];
+		[enc generateMipmapsForTexture:(id<MTLTexture>)t];
+		[enc endEncoding];
+		[cb commit];
+	}
+}
+
+void mtlDrawQuad(uintptr_t dst, uintptr_t src, float* vertices, float* color, int mode, int nearest, int wrap) {
+	@autoreleasepool {
+		id<MTLCommandBuffer> cb = [queue commandBuffer];
+		MTLRenderPassDescriptor* pass = [MTLRenderPassDescriptor renderPassDescriptor];
+		pass.colorAttachments[0].texture = (id<MTLTexture>)dst;
+		pass.colorAttachments[0].loadAction = MTLLoadActionLoad;
+		pass.colorAttachments[0].storeAction = MTLStoreActionStore;
+		id<MTLRenderCommandEncoder> enc = [cb renderCommandEncoderWithDescriptor:pass];
+		[enc setRenderPipelineState:pipelines[src != 0][mode]];
+		[enc setVertexBytes:vertices length:16*sizeof(float) atIndex:0];
+		if (src != 0) {
+			[enc setFragmentTexture:(id<MTLTexture>)src atIndex:0];
+			[enc setFragmentSamplerState:samplers[nearest != 0][wrap] atIndex:0];
+		}
+		[enc setFragmentBytes:color length:4*sizeof(float) atIndex:0];
+		[enc drawPrimitives:MTLPrimitiveTypeTriangleStrip vertexStart:0 vertexCount:4];
+		[enc endEncoding];
+		[cb commit];
+	}
+}
+
+int mtlPresent(uintptr_t view, uintptr_t back, int width, int height) {
+	@autoreleasepool {
+		CAMetalLayer* layer = mtlViewLayer(view);
+		id<CAMetalDrawable> drawable = [layer nextDrawable];
+		if (drawable == nil) {
+			// This can happen if the window is off screen, in which
+			// case there is nothing to update.
+			return 0;
+		}
+		// The drawable's size is updated on the main thread, when the
+		// view is resized, so it may not match the back buffer yet.
+		id<MTLTexture> dst = drawable.texture;
+		int w = MIN(width, (int)dst.width);
+		int h = MIN(height, (int)dst.height);
+
+		id<MTLCommandBuffer> cb = [queue commandBuffer];
+		id<MTLBlitCommandEncoder> enc = [cb blitCommandEncoder];
+		[enc copyFromTexture:(id<MTLTexture>)back
+			sourceSlice:0
+			sourceLevel:0
+			sourceOrigin:MTLOriginMake(0, 0, 0)
+			sourceSize:MTLSizeMake(w, h, 1)
+			toTexture:dst
+			destinationSlice:0
+			destinationLevel:0
+			destinationOrigin:MTLOriginMake(0, 0, 0)];
+		[enc endEncoding];
+		[cb presentDrawable:drawable];
+		[cb commit];
+		return 1;
+	}
+}
+
+void mtlFinish() {
+	@autoreleasepool {
+		id<MTLCommandBuffer> cb = [queue commandBuffer];
+		[cb commit];
+		[cb waitUntilCompleted];
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mtldriver provides a Metal driver for accessing a screen on macOS.
+//
+// Each window is a CAMetalLayer backed NSView. Textures, and each window's
+// back buffer, are MTLTextures, drawn to by render command encoders. Publish
+// blits the back buffer to the layer's next drawable, and presents it.
+//
+// Depth testing is not supported, and NewWindowOptions.DepthBits is ignored.
+package mtldriver // import "golang.org/x/exp/shiny/driver/mtldriver"
+
+import (
+	"golang.org/x/exp/shiny/driver/internal/errscreen"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Main is called by the program's main function to run the graphical
+// application.
+//
+// It calls f on the Screen, possibly in a separate goroutine, as some OS-
+// specific libraries require being on 'the main thread'. It returns when f
+// returns.
+func Main(f func(screen.Screen)) {
+	if err := main(f); err != nil {
+		f(errscreen.Stub(err))
+	}
+}
+
+// Available returns whether the system has a Metal device, and so whether
+// Main can succeed.
+func Available() bool {
+	return available()
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package mtldriver
+
+import (
+	"image"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/exp/shiny/driver/internal/cocoadisplay"
+	"golang.org/x/exp/shiny/driver/internal/pixpool"
+	"golang.org/x/exp/shiny/screen"
+)
+
+var theScreen = &screenImpl{
+	windows: make(map[uintptr]*windowImpl),
+}
+
+type screenImpl struct {
+	textureBytes atomic.Int64
+
+	// pixPool recycles the pixels of released Buffers.
+	pixPool pixpool.Pool
+
+	mu                   sync.Mutex
+	defaultWindowOptions *screen.NewWindowOptions
+	// windows are keyed by their NSView.
+	windows map[uintptr]*windowImpl
+}
+
+func (s *screenImpl) NewBuffer(size image.Point) (screen.Buffer, error) {
+	return &bufferImpl{
+		s:    s,
+		rgba: s.pixPool.NewRGBA(size),
+		size: size,
+	}, nil
+}
+
+func (s *screenImpl) NewTexture(size image.Point) (screen.Texture, error) {
+	return s.NewTextureWithOptions(size, nil)
+}
+
+// NewTextureWithOptions implements screen.Screen. The MTLTexture is BGRA
+// whatever the format, unless it is compressed.
+func (s *screenImpl) NewTextureWithOptions(size image.Point, opts *screen.NewTextureOptions) (screen.Texture, error) {
+	t := &textureImpl{
+		s:      s,
+		size:   size,
+		format: opts.GetFormat(),
+	}
+	if t.format == screen.PixelFormatBGRA8 {
+		t.format = screen.PixelFormatRGBA8
+	}
+	if opts != nil {
+		t.filter, t.mipmap, t.wrap = opts.Filter, opts.Mipmap, opts.Wrap
+		t.compressed = opts.Compressed
+	}
+	var (
+		id  uintptr
+		err error
+	)
+	if t.compressed != screen.CompressedNone {
+		if !supportsCompressed(t.compressed) {
+			return nil, screen.ErrUnsupportedFormat
+		}
+		t.format, t.mipmap = screen.PixelFormatRGBA8, false
+		id, err = newCompressedTexture(size, t.compressed)
+	} else {
+		id, err = newTexture(size, t.mipmap)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.id = id
+	s.textureBytes.Add(t.bytes())
+	return t, nil
+}
+
+// CompressedFormats implements screen.CompressedScreen.
+func (s *screenImpl) CompressedFormats() []screen.CompressedFormat {
+	var f []screen.CompressedFormat
+	for c := screen.CompressedBC1; c <= screen.CompressedASTC4x4; c++ {
+		if supportsCompressed(c) {
+			f = append(f, c)
+		}
+	}
+	return f
+}
+
+func optsSize(opts *screen.NewWindowOptions) (width, height int) {
+	width, height = 1024, 768
+	if opts != nil {
+		if opts.Width > 0 {
+			width = opts.Width
+		}
+		if opts.Height > 0 {
+			height = opts.Height
+		}
+	}
+	return width, height
+}
+
+func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) {
+	s.mu.Lock()
+	opts = opts.WithDefaults(s.defaultWindowOptions)
+	s.mu.Unlock()
+
+	width, height := optsSize(opts)
+	back, err := newTexture(image.Point{width, height}, false)
+	if err != nil {
+		return nil, err
+	}
+	id := newWindow(opts, width, height)
+	w := &windowImpl{
+		s:        s,
+		id:       id,
+		back:     back,
+		backSize: image.Point{width, height},
+		hidden:   opts != nil && opts.Hidden,
+	}
+	if opts != nil {
+		w.Priority = opts.EventPriority
+		w.KeepAllMoves = opts.KeepAllMoves
+		w.KeyEvents = opts.KeyEvents
+		w.Queues = opts.EventQueues
+		w.occlusion.Policy = opts.OccludedPublish
+	}
+
+	s.mu.Lock()
+	s.windows[id] = w
+	s.mu.Unlock()
+
+	w.lifecycler.SendEvent(w, nil)
+
+	// Showing the window sends its first size.Event, even for a hidden
+	// window, which can still be drawn to.
+	showWindow(w, opts)
+	return w, nil
+}
+
+func (s *screenImpl) SetDefaultWindowOptions(opts *screen.NewWindowOptions) {
+	opts = opts.WithDefaults(nil)
+
+	s.mu.Lock()
+	s.defaultWindowOptions = opts
+	s.mu.Unlock()
+}
+
+func (s *screenImpl) TextureMemoryEstimate() int64 {
+	return s.textureBytes.Load()
+}
+
+func (s *screenImpl) AccessibilityPrefs() screen.AccessibilityPrefs {
+	return accessibilityPrefs()
+}
+
+func (s *screenImpl) ColorScheme() screen.ColorScheme {
+	return colorScheme()
+}
+
+func (s *screenImpl) Clipboard() screen.Clipboard {
+	return clipboardImpl{}
+}
+
+func (s *screenImpl) Displays() []screen.Display {
+	return cocoadisplay.Displays()
+}
+
+func (s *screenImpl) NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return cocoadisplay.NewTrayIcon(opts)
+}
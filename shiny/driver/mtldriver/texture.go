@@ -0,0 +1,233 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package mtldriver
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/quad"
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
+	"golang.org/x/exp/shiny/driver/internal/yuv"
+	"golang.org/x/exp/shiny/screen"
+)
+
+type textureImpl struct {
+	s          *screenImpl
+	id         uintptr // An id<MTLTexture>.
+	size       image.Point
+	format     screen.PixelFormat
+	compressed screen.CompressedFormat
+	filter     screen.TextureFilter
+	mipmap     bool
+	wrap       screen.TextureWrap
+
+	// mu guards released.
+	mu       sync.Mutex
+	released bool
+}
+
+func (t *textureImpl) Size() image.Point          { return t.size }
+func (t *textureImpl) Bounds() image.Rectangle    { return image.Rectangle{Max: t.size} }
+func (t *textureImpl) Format() screen.PixelFormat { return t.format }
+
+// bytes returns the estimated memory used by the texture.
+func (t *textureImpl) bytes() int64 {
+	if t.compressed != screen.CompressedNone {
+		return drawer.CompressedBytes(t.compressed, t.size)
+	}
+	return 4 * int64(t.size.X) * int64(t.size.Y)
+}
+
+// Release releases the MTLTexture straight away. Metal command buffers retain
+// the textures that they use, so pending uploads and draws still resolve.
+func (t *textureImpl) Release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return
+	}
+	t.released = true
+	releaseTexture(t.id)
+	t.s.textureBytes.Add(-t.bytes())
+}
+
+// UploadYUV converts src in software.
+//
+// TODO: convert in a Metal shader, as gldriver does in a GL one.
+func (t *textureImpl) UploadYUV(dp image.Point, src *screen.YUVImage, sr image.Rectangle) {
+	yuv.Upload(t.s, t, dp, src, sr)
+}
+
+func (t *textureImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	if t.compressed != screen.CompressedNone {
+		return
+	}
+	if t.format != screen.PixelFormatRGBA8 {
+		// The Buffer's pixels must first be converted to t's format.
+		p, _ := screen.PixelsOf(src.RGBA())
+		t.UploadPixels(dp, p, sr)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return
+	}
+	upload(t.id, t.size, dp, src, sr)
+	t.changed()
+}
+
+// changed is called, while holding t.mu, after t's pixels change. It
+// regenerates t's mipmaps, if it has them.
+func (t *textureImpl) changed() {
+	if t.mipmap {
+		generateMipmaps(t.id)
+	}
+}
+
+// UploadPixels implements screen.PixelUploader.
+//
+// Every MTLTexture is BGRA, so PixelFormatBGRA8 pixels are uploaded to an
+// RGBA8 texture without a copy. Other pixels are converted to the texture's
+// format, and then to BGRA, first.
+//
+// TODO: store Alpha8 and Gray8 textures as MTLPixelFormatR8Unorm, and RGBA64
+// ones as MTLPixelFormatRGBA16Unorm, with matching fragment shaders.
+func (t *textureImpl) UploadPixels(dp image.Point, src *screen.Pixels, sr image.Rectangle) {
+	if t.compressed != screen.CompressedNone {
+		return
+	}
+	originalSRMin := sr.Min
+	sr = sr.Intersect(src.Rect)
+	dp = dp.Add(sr.Min.Sub(originalSRMin))
+	// Unlike image/draw, Metal does not clip to the destination.
+	dr := image.Rectangle{Min: dp, Max: dp.Add(sr.Size())}.Intersect(t.Bounds())
+	if dr.Empty() {
+		return
+	}
+	sr = image.Rectangle{Min: sr.Min.Add(dr.Min.Sub(dp)), Max: sr.Min.Add(dr.Max.Sub(dp))}
+
+	pix, stride := []byte(nil), 0
+	if src.Format == screen.PixelFormatBGRA8 && t.format == screen.PixelFormatRGBA8 {
+		pix, stride = src.Pix[src.PixOffset(sr.Min.X, sr.Min.Y):], src.Stride
+	} else {
+		m := drawer.ConvertPixels(t.format, src, sr)
+		swizzle.BGRA(m.Pix)
+		pix, stride = m.Pix, m.Stride
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return
+	}
+	uploadTexture(t.id, dr, pix, stride)
+	t.changed()
+}
+
+// UploadCompressed implements screen.CompressedUploader.
+func (t *textureImpl) UploadCompressed(dp image.Point, src *screen.CompressedImage, sr image.Rectangle) {
+	if t.compressed == screen.CompressedNone {
+		return
+	}
+	dr, pix := drawer.ClipCompressed(t.size, t.compressed, dp, src, sr)
+	if pix == nil {
+		return
+	}
+	b := t.compressed.BlockSize()
+	rows := (dr.Dy() + b.Y - 1) / b.Y
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return
+	}
+	uploadCompressed(t.id, dr, pix, len(pix)/rows, rows)
+}
+
+func (t *textureImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released || t.compressed != screen.CompressedNone {
+		return
+	}
+	fill(t.id, t.size, dr, src, op)
+	t.changed()
+}
+
+func (t *textureImpl) Download(dp image.Point, sr image.Rectangle) (*image.RGBA, error) {
+	r := sr.Intersect(t.Bounds())
+	m := image.NewRGBA(r.Add(dp.Sub(sr.Min)))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return nil, errTextureReleased
+	}
+	if t.compressed != screen.CompressedNone {
+		return nil, errTextureCompressed
+	}
+	if r.Empty() {
+		return m, nil
+	}
+	downloadTexture(t.id, r, m.Pix, m.Stride)
+	swizzle.BGRA(m.Pix)
+	return m, nil
+}
+
+var (
+	errTextureReleased   = errors.New("mtldriver: texture is released")
+	errTextureCompressed = errors.New("mtldriver: texture is compressed")
+)
+
+// upload implements the screen.Uploader interface's Upload method, onto the
+// MTLTexture dst of the given size.
+func upload(dst uintptr, dstSize image.Point, dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	originalSRMin := sr.Min
+	sr = sr.Intersect(src.Bounds())
+	dp = dp.Add(sr.Min.Sub(originalSRMin))
+	// Unlike image/draw, Metal does not clip to the destination.
+	dr := image.Rectangle{Min: dp, Max: dp.Add(sr.Size())}.Intersect(image.Rectangle{Max: dstSize})
+	if dr.Empty() {
+		return
+	}
+	sr.Min = sr.Min.Add(dr.Min.Sub(dp))
+
+	// Metal textures are BGRA, so the pixels are converted in a copy, which
+	// also lets the caller re-use the Buffer as soon as Upload returns.
+	m := src.RGBA()
+	stride := 4 * dr.Dx()
+	pix := make([]byte, stride*dr.Dy())
+	for y := 0; y < dr.Dy(); y++ {
+		i := m.PixOffset(sr.Min.X, sr.Min.Y+y)
+		copy(pix[y*stride:(y+1)*stride], m.Pix[i:])
+	}
+	swizzle.BGRA(pix)
+	uploadTexture(dst, dr, pix, stride)
+}
+
+// fill implements the screen.Uploader interface's Fill method, onto the
+// MTLTexture dst of the given size.
+func fill(dst uintptr, dstSize image.Point, dr image.Rectangle, src color.Color, op draw.Op) {
+	dr = dr.Intersect(image.Rectangle{Max: dstSize})
+	if dr.Empty() {
+		return
+	}
+	drawQuad(dst, nil, quad.Vertices(quad.Identity, dr, image.Point{}, dstSize), src, op, nil)
+}
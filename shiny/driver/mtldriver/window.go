@@ -0,0 +1,544 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios
+
+package mtldriver
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/capture"
+	"golang.org/x/exp/shiny/driver/internal/cocoadisplay"
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/lifecycler"
+	"golang.org/x/exp/shiny/driver/internal/quad"
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/paint"
+	"golang.org/x/mobile/event/size"
+)
+
+type windowImpl struct {
+	s  *screenImpl
+	id uintptr // The ScreenMetalView, an NSView.
+
+	event.Deque
+	lifecycler lifecycler.State
+
+	// hidden is whether the window is never shown, in which case its back
+	// buffer is never presented.
+	hidden bool
+
+	// mu guards back, backSize, released and colorSpace. If you need to hold both a
+	// windowImpl's mu and a textureImpl's mu, the lock ordering is to lock
+	// the windowImpl's first (and unlock it last).
+	mu sync.Mutex
+	// back is the back buffer, an id<MTLTexture> that the Drawer methods
+	// draw to. Publish blits it to the CAMetalLayer's next drawable.
+	back     uintptr
+	backSize image.Point
+	released bool
+	// colorSpace is the color space set by SetColorSpace.
+	colorSpace screen.ColorSpace
+
+	// pixelsPerPt and scale are the window's scale as of its last size
+	// event, state is its state as of its last screen.WindowStateEvent, and
+	// occluded is whether its occlusion state last said that it was not
+	// visible.
+	// They are only accessed on the main thread.
+	pixelsPerPt float32
+	scale       float64
+	state       screen.WindowState
+	occluded    bool
+
+	imagePool drawer.ImagePool
+	layers    drawer.Layers
+	captures  capture.Hooks
+	timing    frame.Timing
+	occlusion frame.Occlusion
+}
+
+func (w *windowImpl) Release() {
+	w.mu.Lock()
+	released := w.released
+	w.released = true
+	if !released {
+		releaseTexture(w.back)
+		w.back = 0
+	}
+	w.mu.Unlock()
+	if released {
+		return
+	}
+
+	w.imagePool.Release()
+	w.layers.Release()
+	w.StopTimers()
+
+	s := w.s
+	s.mu.Lock()
+	delete(s.windows, w.id)
+	s.mu.Unlock()
+
+	cocoadisplay.ReleaseHotKeys(w)
+	cocoadisplay.ReleaseNotifications(w)
+	closeWindow(w.id)
+}
+
+func (w *windowImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	upload(w.back, w.backSize, dp, src, sr)
+}
+
+func (w *windowImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	fill(w.back, w.backSize, dr, src, op)
+}
+
+// Draw and DrawUniform sample the source texture as its filter and wrap
+// options ask, bilinearly and clamping to its edges by default. Depth testing is not supported, but opts' blend mode and
+// transparency are.
+
+func (w *windowImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	t := src.(*textureImpl)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return
+	}
+	if t.wrap == screen.WrapClamp {
+		sr = sr.Intersect(t.Bounds())
+	}
+	drawQuad(w.back, t, quad.Vertices(src2dst, sr, t.size, w.backSize), nil, op, opts)
+}
+
+func (w *windowImpl) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	drawQuad(w.back, nil, quad.Vertices(src2dst, sr, image.Point{}, w.backSize), src, op, opts)
+}
+
+func (w *windowImpl) Copy(dp image.Point, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Copy(w, dp, src, sr, op, opts)
+}
+
+func (w *windowImpl) Scale(dr image.Rectangle, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Scale(w, dr, src, sr, op, opts)
+}
+
+func (w *windowImpl) DrawImage(dp image.Point, src image.Image, op draw.Op, opts *screen.DrawOptions) {
+	w.imagePool.DrawImage(w.s, w, dp, src, op, opts)
+}
+
+func (w *windowImpl) NewLayer(z int, size image.Point) (screen.Layer, error) {
+	return w.layers.NewLayer(w.s, z, size)
+}
+
+func (w *windowImpl) Publish() screen.PublishResult {
+	if !w.occlusion.Wait() {
+		return screen.PublishResult{Dropped: true}
+	}
+	composited := w.layers.Composite(w)
+
+	w.mu.Lock()
+	if w.released {
+		w.mu.Unlock()
+		return screen.PublishResult{Dropped: true}
+	}
+	// The back buffer is copied, not flipped, to the front, so its contents
+	// are preserved. Compositing any layers overwrites the contents, though.
+	res := screen.PublishResult{BackBufferPreserved: !composited}
+	// Waiting for the layer's next drawable throttles Publish to the
+	// display's refresh rate, so that the drawable is shown at about the
+	// next refresh.
+	if !w.hidden && present(w.id, w.back, w.backSize) {
+		w.timing.Presented(&res, time.Now())
+	} else {
+		res.Dropped = true
+	}
+	var captured *image.RGBA
+	if w.captures.Active() && w.backSize.X > 0 && w.backSize.Y > 0 {
+		captured = image.NewRGBA(image.Rectangle{Max: w.backSize})
+		downloadTexture(w.back, captured.Rect, captured.Pix, captured.Stride)
+		swizzle.BGRA(captured.Pix)
+	}
+	w.mu.Unlock()
+
+	w.captures.Send(captured)
+	return res
+}
+
+func (w *windowImpl) AddCaptureHook(fn func(f screen.CapturedFrame)) (remove func()) {
+	return w.captures.Add(fn)
+}
+
+func (w *windowImpl) RenderFrame(fn func(d screen.Drawer)) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	fn(w)
+	// Command buffers are executed in the order that they are committed,
+	// so waiting for an empty one waits for fn's drawing too.
+	finish()
+	return nil
+}
+
+// NextFrame sends a screen.FrameEvent at the display's next vertical blank.
+func (w *windowImpl) NextFrame() {
+	if !w.isReleased() {
+		cocoadisplay.NextFrame(w)
+	}
+}
+
+var errReleased = errors.New("mtldriver: window is released")
+
+func (w *windowImpl) GLInfo() screen.GLInfo { return screen.GLInfo{} }
+
+func (w *windowImpl) Begin() *screen.Context { return screen.NewContext(w) }
+
+func (w *windowImpl) SetTitle(title string) {
+	if !w.isReleased() {
+		setTitle(w, title)
+	}
+}
+
+func (w *windowImpl) SetIcon(m image.Image) {
+	if !w.isReleased() {
+		setIcon(w, m)
+	}
+}
+
+func (w *windowImpl) SetBadge(label string) {
+	if !w.isReleased() {
+		setBadge(w, label)
+	}
+}
+
+func (w *windowImpl) SetProgress(progress float64) {
+	if !w.isReleased() {
+		setProgress(w, progress)
+	}
+}
+
+func (w *windowImpl) SetSize(width, height int) {
+	if width > 0 && height > 0 && !w.isReleased() {
+		setSize(w, width, height)
+	}
+}
+
+func (w *windowImpl) SetPosition(p image.Point) {
+	if !w.isReleased() {
+		setPosition(w, p)
+	}
+}
+
+func (w *windowImpl) GetGeometry() (image.Point, image.Point) {
+	if w.isReleased() {
+		return image.Point{}, image.Point{}
+	}
+	return geometry(w)
+}
+
+func (w *windowImpl) SetSizeConstraints(min, max image.Point) {
+	if !w.isReleased() {
+		setSizeConstraints(w, min, max)
+	}
+}
+
+func (w *windowImpl) SetAspectRatio(aspect image.Point) {
+	if !w.isReleased() {
+		setAspectRatio(w, aspect)
+	}
+}
+
+func (w *windowImpl) SetTextInputRect(r image.Rectangle) {
+	if !w.isReleased() {
+		setTextInputRect(w, r)
+	}
+}
+
+func (w *windowImpl) SetCursor(c screen.Cursor) {
+	if !w.isReleased() {
+		setCursor(w, c)
+	}
+}
+
+func (w *windowImpl) SetCursorVisible(visible bool) {
+	if !w.isReleased() {
+		setCursorVisible(w, visible)
+	}
+}
+
+func (w *windowImpl) SetPointerCapture(capture bool) {
+	if !w.isReleased() {
+		setPointerCapture(w, capture)
+	}
+}
+
+func (w *windowImpl) StartDrag(data screen.DragData) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return startDrag(w, data)
+}
+
+func (w *windowImpl) StartMove() error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return startMove(w)
+}
+
+func (w *windowImpl) StartResize(edge screen.WindowEdge) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return startResize(w, edge)
+}
+
+func (w *windowImpl) ShowFileDialog(opts *screen.FileDialogOptions) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return showFileDialog(w, opts)
+}
+
+func (w *windowImpl) ColorSpace() screen.ColorSpace {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.colorSpace
+}
+
+func (w *windowImpl) SetColorSpace(cs screen.ColorSpace) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	if err := setColorSpace(w, cs); err != nil {
+		return err
+	}
+	w.colorSpace = cs
+	return nil
+}
+
+func (w *windowImpl) SetMenuBar(bar *screen.Menu) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return setMenuBar(w, bar)
+}
+
+func (w *windowImpl) ShowContextMenu(m *screen.Menu, p image.Point) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return showContextMenu(w, m, p)
+}
+
+func (w *windowImpl) SetAccessTree(root *screen.AccessNode) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return setAccessTree(w, root)
+}
+
+func (w *windowImpl) RegisterHotKey(k key.Code, mods key.Modifiers) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return cocoadisplay.RegisterHotKey(w, k, mods)
+}
+
+func (w *windowImpl) UnregisterHotKey(k key.Code, mods key.Modifiers) {
+	cocoadisplay.UnregisterHotKey(w, k, mods)
+}
+
+func (w *windowImpl) Notify(n *screen.Notification) error {
+	if w.isReleased() {
+		return errReleased
+	}
+	return cocoadisplay.PostNotification(w, n)
+}
+
+func (w *windowImpl) CloseNotification(id int) {
+	cocoadisplay.CloseNotification(w, id)
+}
+
+func (w *windowImpl) Minimize() {
+	if !w.isReleased() {
+		minimize(w)
+	}
+}
+
+func (w *windowImpl) Maximize() {
+	if !w.isReleased() {
+		maximize(w)
+	}
+}
+
+func (w *windowImpl) Restore() {
+	if !w.isReleased() {
+		restore(w)
+	}
+}
+
+func (w *windowImpl) SetFullscreen(mode screen.FullscreenMode) {
+	if !w.isReleased() {
+		setFullscreen(w, mode)
+	}
+}
+
+func (w *windowImpl) SetAlwaysOnTop(onTop bool) {
+	if !w.isReleased() {
+		setAlwaysOnTop(w, onTop)
+	}
+}
+
+func (w *windowImpl) SetOpacity(opacity float32) {
+	if !w.isReleased() {
+		setOpacity(w, opacity)
+	}
+}
+
+func (w *windowImpl) Raise() {
+	if !w.isReleased() {
+		raise(w)
+	}
+}
+
+func (w *windowImpl) Lower() {
+	if !w.isReleased() {
+		lower(w)
+	}
+}
+
+func (w *windowImpl) isReleased() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.released
+}
+
+// resize replaces the back buffer with one of the new size, keeping as much of
+// the old contents as fit, and then sends sz, followed by a paint.Event, as
+// the window's new contents are undefined until it is next published.
+func (w *windowImpl) resize(sz size.Event) {
+	newSize := image.Point{sz.WidthPx, sz.HeightPx}
+	// Metal textures cannot be empty, such as for a window that is
+	// collapsed to its title bar.
+	if newSize.X < 1 {
+		newSize.X = 1
+	}
+	if newSize.Y < 1 {
+		newSize.Y = 1
+	}
+
+	w.mu.Lock()
+	if w.released {
+		w.mu.Unlock()
+		return
+	}
+	if newSize != w.backSize {
+		if back, err := newTexture(newSize, false); err != nil {
+			// Keep drawing to the old back buffer, which present clips
+			// to the drawable's size.
+			log.Print(err)
+		} else {
+			copyTexture(back, w.back, image.Point{
+				X: min(newSize.X, w.backSize.X),
+				Y: min(newSize.Y, w.backSize.Y),
+			})
+			releaseTexture(w.back)
+			w.back, w.backSize = back, newSize
+		}
+	}
+	w.mu.Unlock()
+
+	w.Send(sz)
+	w.Send(paint.Event{External: true})
+}
+
+// sendScale sends a screen.ScaleEvent if ppp or scale differs from that of
+// the window's previous size event, other than for its first size event.
+func (w *windowImpl) sendScale(ppp float32, scale float64) {
+	if ppp == w.pixelsPerPt && scale == w.scale {
+		return
+	}
+	initial := w.pixelsPerPt == 0
+	w.pixelsPerPt, w.scale = ppp, scale
+	if !initial {
+		w.Send(screen.ScaleEvent{PixelsPerPt: ppp, Scale: scale})
+	}
+}
+
+// sendState sends a screen.WindowStateEvent if state differs from the
+// window's previous state.
+func (w *windowImpl) sendState(state screen.WindowState) {
+	if state == w.state {
+		return
+	}
+	w.state = state
+	w.Send(screen.WindowStateEvent{State: state})
+	w.sendVisibility()
+}
+
+// sendVisibility sends a screen.VisibilityEvent if the window's visibility,
+// from its state and its occlusion state, differs from its previous
+// visibility. The occlusion state does not tell whether the window is
+// partially visible.
+func (w *windowImpl) sendVisibility() {
+	v := screen.VisibilityFull
+	switch {
+	case w.state == screen.WindowMinimized:
+		v = screen.VisibilityMinimized
+	case w.occluded:
+		v = screen.VisibilityOccluded
+	}
+	if w.occlusion.Set(v) {
+		w.Send(screen.VisibilityEvent{Visibility: v})
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
@@ -0,0 +1,226 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vncdriver
+
+import (
+	"crypto/des"
+	"encoding/binary"
+	"errors"
+	"image"
+)
+
+// These constants come from RFC 6143.
+const (
+	secNone    = 1
+	secVNCAuth = 2
+
+	// Client to server messages.
+	msgSetPixelFormat           = 0
+	msgSetEncodings             = 2
+	msgFramebufferUpdateRequest = 3
+	msgKeyEvent                 = 4
+	msgPointerEvent             = 5
+	msgClientCutText            = 6
+
+	// Server to client messages.
+	msgFramebufferUpdate = 0
+	msgServerCutText     = 3
+
+	encodingRaw         = 0
+	encodingDesktopSize = -223
+)
+
+// pixelFormat is an RFB PIXEL_FORMAT. Only true color formats are supported.
+type pixelFormat struct {
+	bitsPerPixel uint8
+	depth        uint8
+	bigEndian    bool
+	trueColor    bool
+	redMax       uint16
+	greenMax     uint16
+	blueMax      uint16
+	redShift     uint8
+	greenShift   uint8
+	blueShift    uint8
+}
+
+// defaultPixelFormat is the pixel format that the server starts each
+// connection with. Its pixels have the same layout as an image.RGBA's, so
+// that they can be sent without conversion.
+var defaultPixelFormat = pixelFormat{
+	bitsPerPixel: 32,
+	depth:        24,
+	trueColor:    true,
+	redMax:       0xff,
+	greenMax:     0xff,
+	blueMax:      0xff,
+	redShift:     0,
+	greenShift:   8,
+	blueShift:    16,
+}
+
+var errPixelFormat = errors.New("vncdriver: unsupported pixel format")
+
+func parsePixelFormat(b []byte) (pixelFormat, error) {
+	pf := pixelFormat{
+		bitsPerPixel: b[0],
+		depth:        b[1],
+		bigEndian:    b[2] != 0,
+		trueColor:    b[3] != 0,
+		redMax:       binary.BigEndian.Uint16(b[4:]),
+		greenMax:     binary.BigEndian.Uint16(b[6:]),
+		blueMax:      binary.BigEndian.Uint16(b[8:]),
+		redShift:     b[10],
+		greenShift:   b[11],
+		blueShift:    b[12],
+	}
+	switch pf.bitsPerPixel {
+	case 8, 16, 32:
+	default:
+		return pixelFormat{}, errPixelFormat
+	}
+	// TODO: support color map formats, by sending a SetColorMapEntries
+	// message with a fixed palette.
+	if !pf.trueColor {
+		return pixelFormat{}, errPixelFormat
+	}
+	return pf, nil
+}
+
+func (pf *pixelFormat) append(b []byte) []byte {
+	return append(b,
+		pf.bitsPerPixel, pf.depth, boolByte(pf.bigEndian), boolByte(pf.trueColor),
+		byte(pf.redMax>>8), byte(pf.redMax),
+		byte(pf.greenMax>>8), byte(pf.greenMax),
+		byte(pf.blueMax>>8), byte(pf.blueMax),
+		pf.redShift, pf.greenShift, pf.blueShift,
+		0, 0, 0, // Padding.
+	)
+}
+
+// appendPixels appends the pixels of the r part of m, in pf, to b, as a Raw
+// encoded rectangle's data.
+func (pf *pixelFormat) appendPixels(b []byte, m *image.RGBA, r image.Rectangle) []byte {
+	if *pf == defaultPixelFormat {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			i := m.PixOffset(r.Min.X, y)
+			b = append(b, m.Pix[i:i+4*r.Dx()]...)
+		}
+		return b
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := m.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, i = x+1, i+4 {
+			v := scale(m.Pix[i+0], pf.redMax)<<pf.redShift |
+				scale(m.Pix[i+1], pf.greenMax)<<pf.greenShift |
+				scale(m.Pix[i+2], pf.blueMax)<<pf.blueShift
+			switch {
+			case pf.bitsPerPixel == 8:
+				b = append(b, byte(v))
+			case pf.bitsPerPixel == 16 && pf.bigEndian:
+				b = append(b, byte(v>>8), byte(v))
+			case pf.bitsPerPixel == 16:
+				b = append(b, byte(v), byte(v>>8))
+			case pf.bigEndian:
+				b = append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+			default:
+				b = append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+			}
+		}
+	}
+	return b
+}
+
+// scale scales an 8-bit color channel to one whose maximum is max.
+func scale(c uint8, max uint16) uint32 {
+	return (uint32(c)*uint32(max) + 0x7f) / 0xff
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// appendRect appends a rectangle's header, for the given encoding, to b.
+func appendRect(b []byte, r image.Rectangle, encoding int32) []byte {
+	return append(b,
+		byte(r.Min.X>>8), byte(r.Min.X),
+		byte(r.Min.Y>>8), byte(r.Min.Y),
+		byte(r.Dx()>>8), byte(r.Dx()),
+		byte(r.Dy()>>8), byte(r.Dy()),
+		byte(encoding>>24), byte(encoding>>16), byte(encoding>>8), byte(encoding),
+	)
+}
+
+// maxRects is the most rectangles that a FramebufferUpdate message can have.
+const maxRects = 0xffff
+
+// appendUpdate appends a FramebufferUpdate message to b, of the rects parts
+// of m, Raw encoded in pf. If resize is true, it starts with a DesktopSize
+// pseudo-rectangle of m's size.
+func appendUpdate(b []byte, m *image.RGBA, rects []image.Rectangle, resize bool, pf *pixelFormat) []byte {
+	n := len(rects)
+	if resize {
+		n++
+	}
+	b = append(b, msgFramebufferUpdate, 0, byte(n>>8), byte(n))
+	if resize {
+		b = appendRect(b, image.Rectangle{Max: m.Rect.Size()}, encodingDesktopSize)
+	}
+	for _, r := range rects {
+		b = appendRect(b, r, encodingRaw)
+		b = pf.appendPixels(b, m, r)
+	}
+	return b
+}
+
+// appendCutText appends a ServerCutText message of text to b. RFB's cut text
+// is Latin-1, so other characters are replaced by '?'.
+func appendCutText(b []byte, text string) []byte {
+	latin1 := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r > 0xff {
+			r = '?'
+		}
+		latin1 = append(latin1, byte(r))
+	}
+	n := len(latin1)
+	b = append(b, msgServerCutText, 0, 0, 0, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	return append(b, latin1...)
+}
+
+// decodeLatin1 returns the UTF-8 text of the Latin-1 text b.
+func decodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// vncAuthResponse returns the response to a VNC authentication challenge:
+// the challenge, DES encrypted with the password as the key. Each byte of the
+// key has its bits reversed, as the original VNC implementation's did.
+func vncAuthResponse(password string, challenge []byte) []byte {
+	var k [8]byte
+	copy(k[:], password)
+	for i, c := range k {
+		c = c>>4 | c<<4
+		c = c>>2&0x33 | c<<2&0xcc
+		c = c>>1&0x55 | c<<1&0xaa
+		k[i] = c
+	}
+	block, err := des.NewCipher(k[:])
+	if err != nil {
+		panic(err) // The key is always 8 bytes.
+	}
+	response := make([]byte, len(challenge))
+	for i := 0; i+des.BlockSize <= len(challenge); i += des.BlockSize {
+		block.Encrypt(response[i:], challenge[i:])
+	}
+	return response
+}
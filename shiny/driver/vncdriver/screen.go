@@ -0,0 +1,71 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vncdriver
+
+import (
+	"image"
+
+	"golang.org/x/exp/shiny/driver/headlessdriver"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// screenImpl is a headless Screen whose windows are served to VNC clients.
+type screenImpl struct {
+	screen.Screen
+	srv *server
+}
+
+func newScreenImpl(opts *Options) *screenImpl {
+	s := &screenImpl{
+		Screen: headlessdriver.NewScreen(),
+	}
+	var size image.Point
+	if ds := s.Displays(); len(ds) > 0 {
+		size = ds[0].Bounds.Size()
+	}
+	s.srv = newServer(opts, size, s.Screen.Clipboard())
+	return s
+}
+
+// NewWindow returns a window that is shown to the clients, until another
+// window is created or it is released.
+func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) {
+	hw, err := s.Screen.NewWindow(opts)
+	if err != nil {
+		return nil, err
+	}
+	w := &windowImpl{
+		Window: hw,
+		srv:    s.srv,
+	}
+	s.srv.addWindow(w)
+	return w, nil
+}
+
+// Clipboard returns an in-memory clipboard, private to this Screen, that the
+// clients can cut text to, and that sends them the text written to it.
+func (s *screenImpl) Clipboard() screen.Clipboard {
+	return s.srv
+}
+
+// windowImpl is a headless Window that sends the frames it publishes to the
+// clients.
+type windowImpl struct {
+	screen.Window
+	srv *server
+}
+
+func (w *windowImpl) Release() {
+	w.srv.removeWindow(w)
+	w.Window.Release()
+}
+
+func (w *windowImpl) Publish() screen.PublishResult {
+	r := w.Window.Publish()
+	if m := headlessdriver.Frame(w.Window); m != nil {
+		w.srv.publish(w, m)
+	}
+	return r
+}
@@ -0,0 +1,760 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vncdriver
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net"
+	"sync"
+
+	"golang.org/x/exp/shiny/driver/headlessdriver"
+	"golang.org/x/exp/shiny/driver/internal/x11key"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/mouse"
+)
+
+// tileSize is the width and height of the tiles that the framebuffer is
+// divided into to track its damage. Each published frame is compared with the
+// previous one, tile by tile, and the changed tiles are sent to the clients.
+const tileSize = 32
+
+// maxCutText is the longest cut text accepted from a client.
+const maxCutText = 1 << 20
+
+// server serves a Screen's windows to its VNC clients.
+type server struct {
+	name     string
+	password string
+	// clipboard is the headless Screen's clipboard.
+	clipboard screen.Clipboard
+
+	// mu guards the following fields, and those of each conn that are
+	// documented as such. cond is signalled when any of them changes, for
+	// each conn's writeUpdates goroutine.
+	mu   sync.Mutex
+	cond sync.Cond
+	// windows are the unreleased windows, oldest first. The last is shown.
+	windows []*windowImpl
+	// frame is the framebuffer's contents. It is replaced, never modified,
+	// so that it can be read without holding mu once it has been loaded.
+	frame  *image.RGBA
+	conns  map[*conn]struct{}
+	closed bool
+}
+
+func newServer(opts *Options, size image.Point, clipboard screen.Clipboard) *server {
+	s := &server{
+		name:      "shiny",
+		clipboard: clipboard,
+		frame:     image.NewRGBA(image.Rectangle{Max: size}),
+		conns:     map[*conn]struct{}{},
+	}
+	if opts != nil {
+		if opts.Name != "" {
+			s.name = opts.Name
+		}
+		s.password = opts.Password
+	}
+	s.cond.L = &s.mu
+	return s
+}
+
+func (s *server) serve(l net.Listener) {
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			break
+		}
+		go s.handle(nc)
+	}
+
+	s.mu.Lock()
+	s.closed = true
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.close()
+	}
+}
+
+// window returns the shown window, or nil if there is none.
+func (s *server) window() *windowImpl {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.windows) == 0 {
+		return nil
+	}
+	return s.windows[len(s.windows)-1]
+}
+
+func (s *server) addWindow(w *windowImpl) {
+	_, size := w.GetGeometry()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.windows = append(s.windows, w)
+	s.setFrame(image.NewRGBA(image.Rectangle{Max: size}))
+}
+
+func (s *server) removeWindow(w *windowImpl) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := len(s.windows) - 1
+	for ; i >= 0 && s.windows[i] != w; i-- {
+	}
+	if i < 0 {
+		return
+	}
+	shown := i == len(s.windows)-1
+	s.windows = append(s.windows[:i], s.windows[i+1:]...)
+	if !shown {
+		return
+	}
+	// Show the next newest window, as it was last published, or blank the
+	// framebuffer if there is none.
+	m := image.NewRGBA(s.frame.Rect)
+	if len(s.windows) > 0 {
+		next := s.windows[len(s.windows)-1]
+		if m = headlessdriver.Frame(next.Window); m == nil {
+			_, size := next.GetGeometry()
+			m = image.NewRGBA(image.Rectangle{Max: size})
+		}
+	}
+	s.setFrame(m)
+}
+
+// publish shows m, the frame that w has published, if w is the shown window.
+func (s *server) publish(w *windowImpl, m *image.RGBA) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.windows); n > 0 && s.windows[n-1] == w {
+		s.setFrame(m)
+	}
+}
+
+// setFrame replaces the framebuffer's contents with m, and damages the tiles
+// that differ, or every tile if m is a different size. It must only be called
+// while holding s.mu.
+func (s *server) setFrame(m *image.RGBA) {
+	old := s.frame
+	s.frame = m
+	if old.Rect != m.Rect {
+		for c := range s.conns {
+			c.damageAll(m.Rect.Size())
+		}
+		s.cond.Broadcast()
+		return
+	}
+
+	var damaged []int
+	tx, ty := tilesAcross(m.Rect.Dx()), tilesAcross(m.Rect.Dy())
+	for j := 0; j < ty; j++ {
+		for i := 0; i < tx; i++ {
+			r := image.Rect(i*tileSize, j*tileSize, (i+1)*tileSize, (j+1)*tileSize).Intersect(m.Rect)
+			if !equalPixels(old, m, r) {
+				damaged = append(damaged, j*tx+i)
+			}
+		}
+	}
+	if len(damaged) == 0 {
+		return
+	}
+	for c := range s.conns {
+		for _, i := range damaged {
+			c.damaged[i] = true
+		}
+	}
+	s.cond.Broadcast()
+}
+
+func tilesAcross(n int) int {
+	return (n + tileSize - 1) / tileSize
+}
+
+// equalPixels returns whether the r parts of a and b, which are the same
+// size, have the same pixels.
+func equalPixels(a, b *image.RGBA, r image.Rectangle) bool {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i, j := a.PixOffset(r.Min.X, y), b.PixOffset(r.Min.X, y)
+		n := 4 * r.Dx()
+		if !bytes.Equal(a.Pix[i:i+n], b.Pix[j:j+n]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadText implements screen.Clipboard.
+func (s *server) ReadText() (string, error) {
+	return s.clipboard.ReadText()
+}
+
+// WriteText implements screen.Clipboard, sending text to every client.
+func (s *server) WriteText(text string) error {
+	return s.cut(nil, text)
+}
+
+// cut writes text to the clipboard, and sends it to every client but from.
+func (s *server) cut(from *conn, text string) error {
+	if err := s.clipboard.WriteText(text); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		if c != from {
+			conns = append(conns, c)
+		}
+	}
+	s.mu.Unlock()
+
+	msg := appendCutText(nil, text)
+	for _, c := range conns {
+		if err := c.write(msg); err != nil {
+			c.close()
+		}
+	}
+	return nil
+}
+
+// conn is a client's connection.
+type conn struct {
+	s  *server
+	nc net.Conn
+	br *bufio.Reader
+	// version is the minor version of the protocol: 3, 7 or 8.
+	version int
+
+	// wmu serializes the writes to nc, after the handshake.
+	wmu sync.Mutex
+
+	// These fields are guarded by s.mu. size is the framebuffer size that the
+	// client knows, which only changes if it supports the DesktopSize
+	// pseudo-encoding. damaged are the framebuffer's tiles, by row, that
+	// have changed since they were last sent to the client. requested is
+	// whether the client is waiting for a FramebufferUpdate.
+	pf          pixelFormat
+	desktopSize bool
+	size        image.Point
+	damaged     []bool
+	requested   bool
+	closed      bool
+
+	// These fields are only accessed by the goroutine that reads from nc.
+	buttons uint8
+	pointer image.Point
+	mods    key.Modifiers
+
+	// entered is whether the window was sent a screen.CrossingEvent for the
+	// pointer entering it. The RFB protocol has no such event, so the
+	// pointer enters at the client's first PointerEvent, and leaves when
+	// the client disconnects.
+	entered bool
+
+	// lastPress is the keysym of the last key press, if no key has been
+	// released since. The RFB protocol sends a held key's automatic repeats
+	// as presses without releases.
+	lastPress uint32
+}
+
+func (s *server) handle(nc net.Conn) {
+	c := &conn{
+		s:  s,
+		nc: nc,
+		br: bufio.NewReader(nc),
+		pf: defaultPixelFormat,
+	}
+	shared, err := c.handshake()
+	if err != nil {
+		nc.Close()
+		return
+	}
+
+	// The ServerInit message is written while holding wmu, so that it comes
+	// before any ServerCutText message.
+	c.wmu.Lock()
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		c.wmu.Unlock()
+		nc.Close()
+		return
+	}
+	var others []*conn
+	if !shared {
+		for o := range s.conns {
+			others = append(others, o)
+		}
+	}
+	s.conns[c] = struct{}{}
+	c.damageAll(s.frame.Rect.Size())
+	size := c.size
+	s.mu.Unlock()
+
+	for _, o := range others {
+		o.close()
+	}
+	b := []byte{byte(size.X >> 8), byte(size.X), byte(size.Y >> 8), byte(size.Y)}
+	b = c.pf.append(b)
+	b = append(b, byte(len(s.name)>>24), byte(len(s.name)>>16), byte(len(s.name)>>8), byte(len(s.name)))
+	b = append(b, s.name...)
+	_, err = nc.Write(b)
+	c.wmu.Unlock()
+	if err != nil {
+		c.close()
+		return
+	}
+
+	go c.writeUpdates()
+	if err := c.readMessages(); err != nil {
+		c.close()
+	}
+	if w := s.window(); w != nil && c.entered {
+		w.Send(screen.CrossingEvent{X: float32(c.pointer.X), Y: float32(c.pointer.Y)})
+	}
+}
+
+var errAuth = errors.New("vncdriver: authentication failed")
+
+// handshake negotiates the protocol version and security, and returns whether
+// the client shares the server with other clients.
+func (c *conn) handshake() (shared bool, err error) {
+	if _, err := io.WriteString(c.nc, "RFB 003.008\n"); err != nil {
+		return false, err
+	}
+	var v [12]byte
+	if _, err := io.ReadFull(c.br, v[:]); err != nil {
+		return false, err
+	}
+	var major, minor int
+	if _, err := fmt.Sscanf(string(v[:]), "RFB %03d.%03d\n", &major, &minor); err != nil || major != 3 {
+		return false, fmt.Errorf("vncdriver: unsupported protocol version %q", v[:])
+	}
+	switch {
+	case minor < 7:
+		c.version = 3
+	case minor == 7:
+		c.version = 7
+	default:
+		c.version = 8
+	}
+
+	sec := byte(secNone)
+	if c.s.password != "" {
+		sec = secVNCAuth
+	}
+	if c.version == 3 {
+		// The server decides the security type.
+		if _, err := c.nc.Write([]byte{0, 0, 0, sec}); err != nil {
+			return false, err
+		}
+	} else {
+		if _, err := c.nc.Write([]byte{1, sec}); err != nil {
+			return false, err
+		}
+		var chosen [1]byte
+		if _, err := io.ReadFull(c.br, chosen[:]); err != nil {
+			return false, err
+		}
+		if chosen[0] != sec {
+			return false, c.fail("security type not offered")
+		}
+	}
+
+	if sec == secVNCAuth {
+		challenge := make([]byte, 16)
+		if _, err := rand.Read(challenge); err != nil {
+			return false, err
+		}
+		if _, err := c.nc.Write(challenge); err != nil {
+			return false, err
+		}
+		response := make([]byte, 16)
+		if _, err := io.ReadFull(c.br, response); err != nil {
+			return false, err
+		}
+		if subtle.ConstantTimeCompare(response, vncAuthResponse(c.s.password, challenge)) != 1 {
+			return false, c.fail("authentication failed")
+		}
+	}
+	// Before version 3.8, there is no SecurityResult without authentication.
+	if sec == secVNCAuth || c.version == 8 {
+		if _, err := c.nc.Write([]byte{0, 0, 0, 0}); err != nil {
+			return false, err
+		}
+	}
+
+	var clientInit [1]byte
+	if _, err := io.ReadFull(c.br, clientInit[:]); err != nil {
+		return false, err
+	}
+	return clientInit[0] != 0, nil
+}
+
+// fail writes a failed SecurityResult, with the reason if the protocol version
+// has one, and returns errAuth.
+func (c *conn) fail(reason string) error {
+	b := []byte{0, 0, 0, 1}
+	if c.version == 8 {
+		n := len(reason)
+		b = append(b, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+		b = append(b, reason...)
+	}
+	c.nc.Write(b)
+	return errAuth
+}
+
+func (c *conn) write(b []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.nc.Write(b)
+	return err
+}
+
+func (c *conn) close() {
+	s := c.s
+	s.mu.Lock()
+	closed := c.closed
+	c.closed = true
+	delete(s.conns, c)
+	s.cond.Broadcast()
+	s.mu.Unlock()
+	if !closed {
+		c.nc.Close()
+	}
+}
+
+// damageAll damages every tile of a framebuffer of the given size. If the
+// client supports the DesktopSize pseudo-encoding, the next update tells it
+// the new size. It must only be called while holding s.mu.
+func (c *conn) damageAll(size image.Point) {
+	if c.size == (image.Point{}) {
+		c.size = size
+	}
+	c.damaged = make([]bool, tilesAcross(size.X)*tilesAcross(size.Y))
+	for i := range c.damaged {
+		c.damaged[i] = true
+	}
+}
+
+// damage damages the tiles that overlap r. It must only be called while
+// holding s.mu.
+func (c *conn) damage(r image.Rectangle) {
+	b := c.s.frame.Rect
+	r = r.Intersect(b)
+	if r.Empty() {
+		return
+	}
+	tx := tilesAcross(b.Dx())
+	for j := r.Min.Y / tileSize; j < tilesAcross(r.Max.Y); j++ {
+		for i := r.Min.X / tileSize; i < tilesAcross(r.Max.X); i++ {
+			c.damaged[j*tx+i] = true
+		}
+	}
+}
+
+// updateReady returns whether the client is waiting for an update, and there
+// is something to send it. It must only be called while holding s.mu.
+func (c *conn) updateReady() bool {
+	if !c.requested {
+		return false
+	}
+	if c.desktopSize && c.size != c.s.frame.Rect.Size() {
+		return true
+	}
+	for _, d := range c.damaged {
+		if d {
+			return true
+		}
+	}
+	return false
+}
+
+// takeUpdate returns the damaged parts of m, by rows of tiles, clipped to the
+// client's framebuffer, and whether to tell the client m's size. They are no
+// longer damaged, once taken. It must only be called while holding s.mu.
+func (c *conn) takeUpdate(m *image.RGBA) (rects []image.Rectangle, resize bool) {
+	size := m.Rect.Size()
+	if c.desktopSize && c.size != size {
+		c.size, resize = size, true
+	}
+	fb := m.Rect.Intersect(image.Rectangle{Max: c.size})
+	tx := tilesAcross(size.X)
+	for j := 0; j*tx < len(c.damaged); j++ {
+		row := c.damaged[j*tx : (j+1)*tx]
+		for i := 0; i < tx; {
+			if !row[i] {
+				i++
+				continue
+			}
+			// Merge the run of damaged tiles that starts at i.
+			i0 := i
+			for ; i < tx && row[i]; i++ {
+				row[i] = false
+			}
+			r := image.Rect(i0*tileSize, j*tileSize, i*tileSize, (j+1)*tileSize).Intersect(fb)
+			if !r.Empty() && len(rects) < maxRects {
+				rects = append(rects, r)
+			}
+		}
+	}
+	c.requested = false
+	return rects, resize
+}
+
+// writeUpdates writes a FramebufferUpdate each time the client is ready for
+// one, until the connection is closed.
+func (c *conn) writeUpdates() {
+	s := c.s
+	var b []byte
+	for {
+		s.mu.Lock()
+		for !c.closed && !c.updateReady() {
+			s.cond.Wait()
+		}
+		if c.closed {
+			s.mu.Unlock()
+			return
+		}
+		m := s.frame
+		rects, resize := c.takeUpdate(m)
+		pf := c.pf
+		s.mu.Unlock()
+
+		b = appendUpdate(b[:0], m, rects, resize, &pf)
+		if err := c.write(b); err != nil {
+			c.close()
+			return
+		}
+	}
+}
+
+// readMessages reads the client's messages, until the connection fails or the
+// client sends an invalid message.
+func (c *conn) readMessages() error {
+	s := c.s
+	var b [20]byte
+	for {
+		typ, err := c.br.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case msgSetPixelFormat:
+			if _, err := io.ReadFull(c.br, b[:19]); err != nil {
+				return err
+			}
+			pf, err := parsePixelFormat(b[3:19])
+			if err != nil {
+				return err
+			}
+			s.mu.Lock()
+			c.pf = pf
+			s.mu.Unlock()
+
+		case msgSetEncodings:
+			if _, err := io.ReadFull(c.br, b[:3]); err != nil {
+				return err
+			}
+			encodings := make([]byte, 4*int(binary.BigEndian.Uint16(b[1:])))
+			if _, err := io.ReadFull(c.br, encodings); err != nil {
+				return err
+			}
+			desktopSize := false
+			for i := 0; i < len(encodings); i += 4 {
+				if int32(binary.BigEndian.Uint32(encodings[i:])) == encodingDesktopSize {
+					desktopSize = true
+				}
+			}
+			s.mu.Lock()
+			c.desktopSize = desktopSize
+			s.cond.Broadcast()
+			s.mu.Unlock()
+
+		case msgFramebufferUpdateRequest:
+			if _, err := io.ReadFull(c.br, b[:9]); err != nil {
+				return err
+			}
+			x, y := int(binary.BigEndian.Uint16(b[1:])), int(binary.BigEndian.Uint16(b[3:]))
+			w, h := int(binary.BigEndian.Uint16(b[5:])), int(binary.BigEndian.Uint16(b[7:]))
+			s.mu.Lock()
+			if b[0] == 0 {
+				// A non-incremental request is for the whole region, changed
+				// or not.
+				c.damage(image.Rect(x, y, x+w, y+h))
+			}
+			c.requested = true
+			s.cond.Broadcast()
+			s.mu.Unlock()
+
+		case msgKeyEvent:
+			if _, err := io.ReadFull(c.br, b[:7]); err != nil {
+				return err
+			}
+			c.keyEvent(b[0] != 0, binary.BigEndian.Uint32(b[3:]))
+
+		case msgPointerEvent:
+			if _, err := io.ReadFull(c.br, b[:5]); err != nil {
+				return err
+			}
+			p := image.Point{int(binary.BigEndian.Uint16(b[1:])), int(binary.BigEndian.Uint16(b[3:]))}
+			c.pointerEvent(b[0], p)
+
+		case msgClientCutText:
+			if _, err := io.ReadFull(c.br, b[:7]); err != nil {
+				return err
+			}
+			n := binary.BigEndian.Uint32(b[3:])
+			if n > maxCutText {
+				return errors.New("vncdriver: cut text is too long")
+			}
+			text := make([]byte, n)
+			if _, err := io.ReadFull(c.br, text); err != nil {
+				return err
+			}
+			s.cut(c, decodeLatin1(text))
+
+		default:
+			return fmt.Errorf("vncdriver: unknown message type %d", typ)
+		}
+	}
+}
+
+// keyModifiers are the modifiers of the modifier keys' codes.
+var keyModifiers = map[key.Code]key.Modifiers{
+	key.CodeLeftShift:    key.ModShift,
+	key.CodeRightShift:   key.ModShift,
+	key.CodeLeftControl:  key.ModControl,
+	key.CodeRightControl: key.ModControl,
+	key.CodeLeftAlt:      key.ModAlt,
+	key.CodeRightAlt:     key.ModAlt,
+	key.CodeLeftGUI:      key.ModMeta,
+	key.CodeRightGUI:     key.ModMeta,
+}
+
+func (c *conn) keyEvent(down bool, keysym uint32) {
+	r, code := x11key.KeysymCode(keysym)
+	dir := key.DirRelease
+	if down {
+		dir = key.DirPress
+		if keysym == c.lastPress {
+			dir = key.DirNone
+		}
+		c.lastPress = keysym
+	} else {
+		c.lastPress = 0
+	}
+	if m := keyModifiers[code]; m != 0 {
+		if down {
+			c.mods |= m
+		} else {
+			c.mods &^= m
+		}
+	}
+	if w := c.s.window(); w != nil {
+		// The keysym is that of the key and the modifiers together, so
+		// neither the physical key nor what it types without the modifiers
+		// is known.
+		e := screen.KeyEvent{
+			Event: key.Event{
+				Rune:      r,
+				Code:      code,
+				Modifiers: c.mods,
+				Direction: dir,
+			},
+			Unmodified: -1,
+			Repeat:     dir == key.DirNone,
+		}
+		w.Send(e)
+	}
+}
+
+// pointerButtons are the buttons of the bits of a PointerEvent's button mask.
+var pointerButtons = [...]mouse.Button{
+	mouse.ButtonLeft,
+	mouse.ButtonMiddle,
+	mouse.ButtonRight,
+	mouse.ButtonWheelUp,
+	mouse.ButtonWheelDown,
+	mouse.ButtonWheelLeft,
+	mouse.ButtonWheelRight,
+}
+
+func (c *conn) pointerEvent(mask uint8, p image.Point) {
+	changed, moved := mask^c.buttons, p != c.pointer
+	c.buttons, c.pointer = mask, p
+	w := c.s.window()
+	if w == nil {
+		return
+	}
+
+	x, y := float32(p.X), float32(p.Y)
+	if !c.entered {
+		c.entered = true
+		w.Send(screen.CrossingEvent{Entered: true, X: x, Y: y})
+	}
+	sent := false
+	for i, b := range pointerButtons {
+		bit := uint8(1) << uint(i)
+		if changed&bit == 0 {
+			continue
+		}
+		down := mask&bit != 0
+		if b.IsWheel() {
+			// A wheel step is a press and a release, of which only the
+			// press is sent.
+			if down {
+				w.Send(wheelScrollEvent(x, y, b, c.mods))
+				w.Send(mouse.Event{X: x, Y: y, Button: b, Modifiers: c.mods, Direction: mouse.DirStep})
+				sent = true
+			}
+			continue
+		}
+		dir := mouse.DirRelease
+		if down {
+			dir = mouse.DirPress
+		}
+		w.Send(mouse.Event{X: x, Y: y, Button: b, Modifiers: c.mods, Direction: dir})
+		sent = true
+	}
+	if moved && !sent {
+		w.Send(mouse.Event{X: x, Y: y, Modifiers: c.mods})
+	}
+}
+
+// wheelScrollEvent returns the screen.ScrollEvent of a step of a wheel
+// button.
+func wheelScrollEvent(x, y float32, b mouse.Button, m key.Modifiers) screen.ScrollEvent {
+	e := screen.ScrollEvent{
+		X:         x,
+		Y:         y,
+		Device:    screen.ScrollDeviceWheel,
+		Modifiers: m,
+	}
+	switch b {
+	case mouse.ButtonWheelUp:
+		e.DeltaY = -1
+	case mouse.ButtonWheelDown:
+		e.DeltaY = +1
+	case mouse.ButtonWheelLeft:
+		e.DeltaX = -1
+	case mouse.ButtonWheelRight:
+		e.DeltaX = +1
+	}
+	return e
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vncdriver provides a driver for accessing a screen over the network,
+// by serving it to VNC clients with the Remote Framebuffer (RFB) protocol, as
+// specified by RFC 6143. It needs no display server, so that a program on a
+// headless machine, such as a dashboard or a failing CI job, can be seen and
+// used from elsewhere.
+//
+// Windows are drawn in software, as the headlessdriver's are. The remote
+// framebuffer shows the most recently created unreleased window, and is that
+// window's size. Each call to its Publish method sends the clients the parts
+// of the window that have changed since the previous call, as raw pixels in
+// each client's pixel format. Clients that support the DesktopSize
+// pseudo-encoding are told when the window's size changes. The clients'
+// pointer and key events are sent to the window as mouse, scroll and key
+// events, and text that they cut is written to the Screen's clipboard, as
+// text written to that clipboard is sent to them.
+//
+// TODO: support a compressed encoding, such as ZRLE, for slower networks.
+package vncdriver // import "golang.org/x/exp/shiny/driver/vncdriver"
+
+import (
+	"net"
+	"os"
+
+	"golang.org/x/exp/shiny/driver/internal/errscreen"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// DefaultAddr is the address that Main listens on if the SHINY_VNC_ADDR
+// environment variable is empty. It is the loopback interface, as the RFB
+// protocol is not encrypted, so that a remote client has to connect through
+// a tunnel, such as SSH's port forwarding.
+const DefaultAddr = "localhost:5900"
+
+// Main is called by the program's main function to run the graphical
+// application.
+//
+// It calls f on a Screen that serves the TCP address in the SHINY_VNC_ADDR
+// environment variable, or DefaultAddr, and requires the password, if any,
+// in the SHINY_VNC_PASSWORD environment variable. It returns when f returns.
+func Main(f func(screen.Screen)) {
+	addr := os.Getenv("SHINY_VNC_ADDR")
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		f(errscreen.Stub(err))
+		return
+	}
+	defer l.Close()
+	f(NewScreen(l, &Options{
+		Password: os.Getenv("SHINY_VNC_PASSWORD"),
+	}))
+}
+
+// Options are optional arguments to NewScreen.
+type Options struct {
+	// Name is the desktop name that clients are sent, which they typically
+	// show as their window's title. The default is "shiny".
+	Name string
+
+	// Password is the password that clients have to give, with VNC
+	// authentication, to connect. An empty Password, the default, means that
+	// clients connect without authenticating. Only its first 8 bytes are
+	// used, as VNC authentication ignores the rest.
+	Password string
+}
+
+// NewScreen returns a new Screen, served to the clients that connect to l.
+// It serves l until l is closed, when it closes its clients' connections.
+//
+// The nil *Options means to use the default options.
+func NewScreen(l net.Listener, opts *Options) screen.Screen {
+	s := newScreenImpl(opts)
+	go s.srv.serve(l)
+	return s
+}
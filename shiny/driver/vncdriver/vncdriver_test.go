@@ -0,0 +1,249 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vncdriver
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"net"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/mouse"
+)
+
+// client is a minimal VNC client, that uses the default pixel format.
+type client struct {
+	t  *testing.T
+	nc net.Conn
+	br *bufio.Reader
+}
+
+func newScreen(t *testing.T, opts *Options) (screen.Screen, net.Addr, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	return NewScreen(l, opts), l.Addr(), func() { l.Close() }
+}
+
+func dial(t *testing.T, addr net.Addr) *client {
+	nc, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	return &client{t: t, nc: nc, br: bufio.NewReader(nc)}
+}
+
+func (c *client) read(n int) []byte {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(c.br, b); err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	return b
+}
+
+func (c *client) write(b ...byte) {
+	if _, err := c.nc.Write(b); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+func (c *client) u16() int { return int(binary.BigEndian.Uint16(c.read(2))) }
+func (c *client) u32() int { return int(binary.BigEndian.Uint32(c.read(4))) }
+
+// handshake negotiates version 3.8 of the protocol, and the security type sec,
+// authenticating with password if sec is VNC authentication. It returns the
+// SecurityResult.
+func (c *client) handshake(sec byte, password string) int {
+	if got := string(c.read(12)); got != "RFB 003.008\n" {
+		c.t.Fatalf("server version: got %q", got)
+	}
+	c.write([]byte("RFB 003.008\n")...)
+	n := int(c.read(1)[0])
+	if types := c.read(n); n != 1 || types[0] != sec {
+		c.t.Fatalf("security types: got %v, want [%d]", types, sec)
+	}
+	c.write(sec)
+	if sec == secVNCAuth {
+		c.write(vncAuthResponse(password, c.read(16))...)
+	}
+	return c.u32()
+}
+
+// init sends ClientInit and reads ServerInit, returning the framebuffer's size
+// and the desktop name.
+func (c *client) init() (image.Point, string) {
+	c.write(1)
+	size := image.Point{c.u16(), c.u16()}
+	c.read(16)
+	name := string(c.read(c.u32()))
+	return size, name
+}
+
+func (c *client) requestUpdate(incremental bool, r image.Rectangle) {
+	b := []byte{msgFramebufferUpdateRequest, 0}
+	if incremental {
+		b[1] = 1
+	}
+	b = append(b,
+		byte(r.Min.X>>8), byte(r.Min.X), byte(r.Min.Y>>8), byte(r.Min.Y),
+		byte(r.Dx()>>8), byte(r.Dx()), byte(r.Dy()>>8), byte(r.Dy()))
+	c.write(b...)
+}
+
+// readUpdate reads a FramebufferUpdate of Raw rectangles, drawing them onto m,
+// and returns the rectangles.
+func (c *client) readUpdate(m *image.RGBA) []image.Rectangle {
+	if typ := c.read(2)[0]; typ != msgFramebufferUpdate {
+		c.t.Fatalf("message type: got %d, want %d", typ, msgFramebufferUpdate)
+	}
+	n := c.u16()
+	var rects []image.Rectangle
+	for i := 0; i < n; i++ {
+		x, y, w, h := c.u16(), c.u16(), c.u16(), c.u16()
+		if enc := int32(c.u32()); enc != encodingRaw {
+			c.t.Fatalf("rectangle %d: got encoding %d, want Raw", i, enc)
+		}
+		r := image.Rect(x, y, x+w, y+h)
+		pix := c.read(4 * w * h)
+		for j := 0; j < h; j++ {
+			copy(m.Pix[m.PixOffset(x, y+j):], pix[4*w*j:4*w*(j+1)])
+		}
+		rects = append(rects, r)
+	}
+	return rects
+}
+
+func TestFramebufferUpdate(t *testing.T) {
+	s, addr, closeListener := newScreen(t, &Options{Name: "test"})
+	defer closeListener()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 64, Height: 48, KeyEvents: true})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+
+	c := dial(t, addr)
+	defer c.nc.Close()
+	if result := c.handshake(secNone, ""); result != 0 {
+		t.Fatalf("SecurityResult: got %d, want 0", result)
+	}
+	size, name := c.init()
+	if size != (image.Point{64, 48}) || name != "test" {
+		t.Fatalf("ServerInit: got %v, %q, want (64,48), \"test\"", size, name)
+	}
+
+	red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+	blue := color.RGBA{0x00, 0x00, 0xff, 0xff}
+	w.Fill(image.Rect(0, 0, 64, 48), red, draw.Src)
+	w.Publish()
+	fb := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	c.requestUpdate(false, fb.Rect)
+	c.readUpdate(fb)
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			if got := fb.RGBAAt(x, y); got != red {
+				t.Fatalf("full update: pixel (%d, %d): got %v, want %v", x, y, got, red)
+			}
+		}
+	}
+
+	// Only the damaged tile, clipped to the framebuffer, is sent.
+	w.Fill(image.Rect(40, 40, 44, 44), blue, draw.Src)
+	w.Publish()
+	c.requestUpdate(true, fb.Rect)
+	rects := c.readUpdate(fb)
+	if want := image.Rect(32, 32, 64, 48); len(rects) != 1 || rects[0] != want {
+		t.Fatalf("incremental update: got rectangles %v, want [%v]", rects, want)
+	}
+	if got := fb.RGBAAt(41, 41); got != blue {
+		t.Errorf("incremental update: pixel (41, 41): got %v, want %v", got, blue)
+	}
+	if got := fb.RGBAAt(33, 33); got != red {
+		t.Errorf("incremental update: pixel (33, 33): got %v, want %v", got, red)
+	}
+
+	// Cut text, then a pointer press and a key press. Once the key event
+	// arrives, the cut text has been written to the clipboard.
+	text := "h\xe9llo"
+	c.write(msgClientCutText, 0, 0, 0, 0, 0, 0, byte(len(text)))
+	c.write([]byte(text)...)
+	c.write(msgPointerEvent, 1, 0, 10, 0, 20)
+	c.write(msgKeyEvent, 1, 0, 0, 0, 0, 0, 'A')
+	var gotMouse, gotKey bool
+	for !gotKey {
+		switch e := w.NextEvent().(type) {
+		case mouse.Event:
+			want := mouse.Event{X: 10, Y: 20, Button: mouse.ButtonLeft, Direction: mouse.DirPress}
+			if e != want {
+				t.Errorf("mouse event: got %v, want %v", e, want)
+			}
+			gotMouse = true
+		case screen.KeyEvent:
+			want := key.Event{Rune: 'A', Code: key.CodeA, Direction: key.DirPress}
+			if e.Event != want {
+				t.Errorf("key event: got %v, want %v", e, want)
+			}
+			gotKey = true
+		}
+	}
+	if !gotMouse {
+		t.Error("no mouse event before the key event")
+	}
+
+	// A second press, without a release, is an automatic repeat.
+	c.write(msgKeyEvent, 1, 0, 0, 0, 0, 0, 'A')
+	for gotKey = false; !gotKey; {
+		if e, ok := w.NextEvent().(screen.KeyEvent); ok {
+			want := screen.KeyEvent{
+				Event:      key.Event{Rune: 'A', Code: key.CodeA, Direction: key.DirNone},
+				Unmodified: -1,
+				Repeat:     true,
+				Time:       w.EventTime(),
+			}
+			if e != want {
+				t.Errorf("repeat: got %+v, want %+v", e, want)
+			}
+			gotKey = true
+		}
+	}
+	if got, err := s.Clipboard().ReadText(); err != nil || got != "héllo" {
+		t.Errorf("clipboard: got %q, %v, want %q", got, err, "héllo")
+	}
+}
+
+func TestVNCAuth(t *testing.T) {
+	s, addr, closeListener := newScreen(t, &Options{Password: "secret"})
+	defer closeListener()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 8, Height: 8})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+
+	c := dial(t, addr)
+	defer c.nc.Close()
+	if result := c.handshake(secVNCAuth, "wrong"); result != 1 {
+		t.Fatalf("wrong password: got SecurityResult %d, want 1", result)
+	}
+	if reason := string(c.read(c.u32())); reason == "" {
+		t.Error("wrong password: no reason")
+	}
+
+	c = dial(t, addr)
+	defer c.nc.Close()
+	if result := c.handshake(secVNCAuth, "secret"); result != 0 {
+		t.Fatalf("right password: got SecurityResult %d, want 0", result)
+	}
+	if size, _ := c.init(); size != (image.Point{8, 8}) {
+		t.Errorf("ServerInit: got size %v, want (8,8)", size)
+	}
+}
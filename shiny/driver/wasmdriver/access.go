@@ -0,0 +1,148 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build js,wasm
+
+package wasmdriver
+
+import (
+	"fmt"
+	"strconv"
+	"syscall/js"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// ariaRoles are the ARIA roles of the screen.AccessRoles. A label has no
+// role, as it is only text.
+var ariaRoles = [...]string{
+	screen.AccessGroup:        "group",
+	screen.AccessLabel:        "",
+	screen.AccessButton:       "button",
+	screen.AccessCheckBox:     "checkbox",
+	screen.AccessTextField:    "textbox",
+	screen.AccessTextEditor:   "textbox",
+	screen.AccessImage:        "img",
+	screen.AccessList:         "list",
+	screen.AccessListItem:     "listitem",
+	screen.AccessTable:        "table",
+	screen.AccessColumnHeader: "columnheader",
+	screen.AccessCell:         "cell",
+	screen.AccessScrollArea:   "region",
+	screen.AccessSlider:       "slider",
+}
+
+// SetAccessTree sets the canvas's fallback content, the elements that
+// browsers expose to assistive technologies in place of its pixels, to
+// elements with the ARIA roles and labels of the tree's nodes. The focused
+// node is the canvas's aria-activedescendant, so that the canvas keeps the
+// keyboard focus. Browsers ignore the fallback content's bounds, so the nodes'
+// Bounds are not used.
+func (w *windowImpl) SetAccessTree(root *screen.AccessNode) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return errReleased
+	}
+
+	old, elems := w.accessElems, map[int]js.Value{}
+	focus := ""
+	var children []interface{}
+	if root != nil {
+		children = append(children, w.accessElement(root, old, elems, &focus))
+	}
+	w.canvas.Call("replaceChildren", children...)
+	w.accessElems = elems
+	if focus == "" {
+		w.canvas.Call("removeAttribute", "aria-activedescendant")
+	} else {
+		w.canvas.Call("setAttribute", "aria-activedescendant", focus)
+	}
+	return nil
+}
+
+// accessElement returns the element of n, in elems, reusing those of old,
+// and sets *focus to the ID attribute of the focused element, if any.
+func (w *windowImpl) accessElement(n *screen.AccessNode, old, elems map[int]js.Value, focus *string) js.Value {
+	e, ok := old[n.ID]
+	if !ok {
+		e = w.s.document.Call("createElement", "div")
+		e.Set("id", fmt.Sprintf("shiny-%p-%d", w, n.ID))
+		e.Get("dataset").Set("shinyId", strconv.Itoa(n.ID))
+		// An element is only focusable, for an assistive technology to
+		// focus it, with a tabIndex.
+		e.Set("tabIndex", -1)
+	}
+	elems[n.ID] = e
+
+	setAttr := func(name, value string) {
+		if value == "" {
+			e.Call("removeAttribute", name)
+		} else {
+			e.Call("setAttribute", name, value)
+		}
+	}
+	role := ""
+	if int(n.Role) < len(ariaRoles) {
+		role = ariaRoles[n.Role]
+	}
+	setAttr("role", role)
+	if role == "" {
+		setAttr("aria-label", "")
+	} else {
+		setAttr("aria-label", n.Name)
+	}
+	setAttr("aria-disabled", strconv.FormatBool(n.Disabled))
+	switch n.Role {
+	case screen.AccessCheckBox:
+		setAttr("aria-checked", strconv.FormatBool(n.Value == "true"))
+	case screen.AccessSlider:
+		setAttr("aria-valuetext", n.Value)
+	case screen.AccessTextField, screen.AccessTextEditor:
+		setAttr("aria-multiline", strconv.FormatBool(n.Role == screen.AccessTextEditor))
+	}
+	if n.Focused {
+		*focus = e.Get("id").String()
+	}
+
+	// The text of labels and text fields is their content.
+	children := make([]interface{}, 0, len(n.Children)+1)
+	switch n.Role {
+	case screen.AccessLabel:
+		children = append(children, n.Name)
+	case screen.AccessTextField, screen.AccessTextEditor:
+		children = append(children, n.Value)
+	}
+	for _, c := range n.Children {
+		children = append(children, w.accessElement(c, old, elems, focus))
+	}
+	e.Call("replaceChildren", children...)
+	return e
+}
+
+// addAccessListeners adds the canvas's listeners for the actions of
+// assistive technologies on its fallback content, whose events bubble up to
+// the canvas.
+func (w *windowImpl) addAccessListeners() {
+	w.listen("click", func(e js.Value) {
+		w.sendAccessAction(e, screen.AccessPress)
+	})
+	w.listen("focusin", func(e js.Value) {
+		if w.sendAccessAction(e, screen.AccessFocus) {
+			// The canvas keeps the keyboard focus.
+			w.canvas.Call("focus")
+		}
+	})
+}
+
+// sendAccessAction sends an AccessActionEvent for the fallback element that
+// is e's target, if it is one, and returns whether it is.
+func (w *windowImpl) sendAccessAction(e js.Value, action screen.AccessAction) bool {
+	id, err := strconv.Atoi(e.Get("target").Get("dataset").Get("shinyId").String())
+	if err != nil {
+		return false
+	}
+	w.Send(screen.AccessActionEvent{ID: id, Action: action})
+	return true
+}
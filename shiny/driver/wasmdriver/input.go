@@ -0,0 +1,407 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build js,wasm
+
+package wasmdriver
+
+import (
+	"syscall/js"
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/dnd"
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/touch"
+)
+
+// wheelSteps is how far, in each of a WheelEvent's deltaModes (pixels, lines
+// and pages), a mouse wheel scrolls per click, for the browsers that this
+// package has been tested on.
+var wheelSteps = [3]float64{100, 3, 1}
+
+// domDeltaLine is the WheelEvent deltaMode of deltas in lines.
+const domDeltaLine = 1
+
+// domClock converts the timeStamps of DOM events, in milliseconds since the
+// page loaded.
+var domClock event.Clock
+
+// eventTime returns the time of the DOM event e.
+func eventTime(e js.Value) time.Time {
+	return domClock.Time(time.Duration(e.Get("timeStamp").Float() * float64(time.Millisecond)))
+}
+
+// addListeners adds the canvas's event listeners. The browser calls them one
+// at a time, so they do not need to synchronize with each other.
+func (w *windowImpl) addListeners() {
+	w.listen("mousedown", func(e js.Value) {
+		if w.pen {
+			return
+		}
+		// Prevent the browser from selecting text, as the canvas then does
+		// not get the focus, so give it the focus explicitly.
+		e.Call("preventDefault")
+		w.canvas.Call("focus")
+		if w.isCaptured() && !w.pointerLocked() {
+			// Browsers only lock the pointer in response to a user
+			// gesture, so a capture that SetPointerCapture could not
+			// start, or that the user ended, starts again here.
+			w.canvas.Call("requestPointerLock")
+		}
+		w.sendMouse(e, domMouseButton(e.Get("button").Int()), mouse.DirPress)
+	})
+	w.listen("mouseup", func(e js.Value) {
+		if w.pen {
+			return
+		}
+		w.sendMouse(e, domMouseButton(e.Get("button").Int()), mouse.DirRelease)
+	})
+	w.listen("mousemove", func(e js.Value) {
+		if w.pointerLocked() {
+			// movementX and movementY are in CSS pixels.
+			dpr := devicePixelRatio()
+			w.SendAt(screen.RelativeMouseEvent{
+				DeltaX: float32(e.Get("movementX").Float() * dpr),
+				DeltaY: float32(e.Get("movementY").Float() * dpr),
+			}, eventTime(e))
+			return
+		}
+		if w.pen {
+			return
+		}
+		w.sendMouse(e, mouse.ButtonNone, mouse.DirNone)
+	})
+	w.listen("mouseenter", func(e js.Value) {
+		w.sendCrossing(e, true)
+	})
+	w.listen("mouseleave", func(e js.Value) {
+		w.sendCrossing(e, false)
+	})
+	w.listen("contextmenu", func(e js.Value) {
+		// Right clicks are sent to the window, without a context menu.
+		e.Call("preventDefault")
+	})
+	w.listen("wheel", func(e js.Value) {
+		e.Call("preventDefault")
+		mode := e.Get("deltaMode").Int()
+		if mode < 0 || len(wheelSteps) <= mode {
+			return
+		}
+		dx := e.Get("deltaX").Float() / wheelSteps[mode]
+		dy := e.Get("deltaY").Float() / wheelSteps[mode]
+		// Browsers do not say which device scrolled, but mouse wheels,
+		// unlike touchpads, usually scroll by lines in Firefox. Nor do
+		// they send the phases of a scroll.
+		dev := screen.ScrollDeviceUnknown
+		if mode == domDeltaLine {
+			dev = screen.ScrollDeviceWheel
+		}
+		dpr := devicePixelRatio()
+		w.SendAt(screen.ScrollEvent{
+			X:         float32(e.Get("offsetX").Float() * dpr),
+			Y:         float32(e.Get("offsetY").Float() * dpr),
+			DeltaX:    float32(dx),
+			DeltaY:    float32(dy),
+			Device:    dev,
+			Modifiers: domModifiers(e),
+		}, eventTime(e))
+		// Smooth scrolling, such as from a touchpad, is accumulated until
+		// it adds up to a whole mouse wheel step.
+		w.wheel[0] += dx
+		w.wheel[1] += dy
+		for ; w.wheel[1] >= 1; w.wheel[1]-- {
+			w.sendMouse(e, mouse.ButtonWheelDown, mouse.DirStep)
+		}
+		for ; w.wheel[1] <= -1; w.wheel[1]++ {
+			w.sendMouse(e, mouse.ButtonWheelUp, mouse.DirStep)
+		}
+		for ; w.wheel[0] >= 1; w.wheel[0]-- {
+			w.sendMouse(e, mouse.ButtonWheelRight, mouse.DirStep)
+		}
+		for ; w.wheel[0] <= -1; w.wheel[0]++ {
+			w.sendMouse(e, mouse.ButtonWheelLeft, mouse.DirStep)
+		}
+	})
+
+	// Preventing dragenter's and dragover's default actions accepts the
+	// drop, and preventing drop's stops the browser from opening the data.
+	w.listen("dragenter", func(e js.Value) {
+		e.Call("preventDefault")
+		w.sendDrag(e, screen.DragEnter)
+	})
+	w.listen("dragover", func(e js.Value) {
+		e.Call("preventDefault")
+		e.Get("dataTransfer").Set("dropEffect", "copy")
+		w.sendDrag(e, screen.DragOver)
+	})
+	w.listen("dragleave", func(e js.Value) {
+		w.sendDrag(e, screen.DragLeave)
+	})
+	w.listen("drop", func(e js.Value) {
+		e.Call("preventDefault")
+		w.sendDrag(e, screen.Drop)
+	})
+
+	w.listen("keydown", func(e js.Value) {
+		// Keep the browser's own shortcuts, such as to reload the page,
+		// but not its handling of keys like Tab and Backspace.
+		if !e.Get("ctrlKey").Bool() && !e.Get("metaKey").Bool() {
+			e.Call("preventDefault")
+		}
+		dir := key.DirPress
+		if e.Get("repeat").Bool() {
+			dir = key.DirNone
+		}
+		w.sendKey(e, dir)
+	})
+	w.listen("keyup", func(e js.Value) {
+		w.sendKey(e, key.DirRelease)
+	})
+
+	w.listen("focus", func(e js.Value) {
+		w.lifecycler.SetFocused(true)
+		w.lifecycler.SendEvent(w, nil)
+	})
+	w.listen("fullscreenchange", func(e js.Value) {
+		fullscreen := w.s.document.Get("fullscreenElement").Equal(w.canvas)
+		if fullscreen == w.fullscreen {
+			return
+		}
+		w.fullscreen = fullscreen
+		state := screen.WindowNormal
+		if fullscreen {
+			state = screen.WindowFullscreen
+		}
+		w.Send(screen.WindowStateEvent{State: state})
+	})
+	w.listen("blur", func(e js.Value) {
+		w.lifecycler.SetFocused(false)
+		w.lifecycler.SendEvent(w, nil)
+	})
+
+	// Pens are read from pointer events, which browsers send before the
+	// compatibility mouse events for the same input. Touches are read from
+	// the touch events below.
+	w.listen("pointerdown", func(e js.Value) {
+		if w.isPen(e) {
+			// This also stops the browser from selecting text.
+			e.Call("preventDefault")
+			w.canvas.Call("focus")
+			w.sendPen(e, screen.PenDown)
+		}
+	})
+	w.listen("pointermove", func(e js.Value) {
+		if w.isPen(e) {
+			typ := screen.PenHover
+			if e.Get("buttons").Int() != 0 {
+				typ = screen.PenMove
+			}
+			w.sendPen(e, typ)
+		}
+	})
+	w.listen("pointerup", func(e js.Value) {
+		if w.isPen(e) {
+			w.sendPen(e, screen.PenUp)
+		}
+	})
+	w.listen("pointercancel", func(e js.Value) {
+		if w.isPen(e) {
+			w.sendPen(e, screen.PenUp)
+		}
+	})
+
+	// Preventing the touch events' default actions also prevents the
+	// browser from sending mouse events for them.
+	w.listen("touchstart", func(e js.Value) {
+		e.Call("preventDefault")
+		w.canvas.Call("focus")
+		w.sendTouches(e, touch.TypeBegin)
+	})
+	w.listen("touchmove", func(e js.Value) {
+		e.Call("preventDefault")
+		w.sendTouches(e, touch.TypeMove)
+	})
+	w.listen("touchend", func(e js.Value) {
+		e.Call("preventDefault")
+		w.sendTouches(e, touch.TypeEnd)
+	})
+	w.listen("touchcancel", func(e js.Value) {
+		w.sendTouches(e, touch.TypeEnd)
+	})
+
+	w.addAccessListeners()
+}
+
+// listen adds an event listener to the canvas. It is not passive, so that it
+// can prevent the event's default action, such as scrolling the page.
+func (w *windowImpl) listen(typ string, f func(e js.Value)) {
+	fn := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		f(args[0])
+		return nil
+	})
+	w.canvas.Call("addEventListener", typ, fn, map[string]interface{}{"passive": false})
+	w.listeners = append(w.listeners, listener{typ, fn})
+}
+
+func domMouseButton(b int) mouse.Button {
+	switch b {
+	case 0:
+		return mouse.ButtonLeft
+	case 1:
+		return mouse.ButtonMiddle
+	case 2:
+		return mouse.ButtonRight
+	case 3:
+		return screen.MouseButtonBack
+	case 4:
+		return screen.MouseButtonForward
+	}
+	return mouse.ButtonNone
+}
+
+func domModifiers(e js.Value) (m key.Modifiers) {
+	if e.Get("shiftKey").Bool() {
+		m |= key.ModShift
+	}
+	if e.Get("ctrlKey").Bool() {
+		m |= key.ModControl
+	}
+	if e.Get("altKey").Bool() {
+		m |= key.ModAlt
+	}
+	if e.Get("metaKey").Bool() {
+		m |= key.ModMeta
+	}
+	return m
+}
+
+func (w *windowImpl) sendMouse(e js.Value, b mouse.Button, dir mouse.Direction) {
+	// offsetX and offsetY are in CSS pixels, relative to the canvas.
+	dpr := devicePixelRatio()
+	w.SendAt(mouse.Event{
+		X:         float32(e.Get("offsetX").Float() * dpr),
+		Y:         float32(e.Get("offsetY").Float() * dpr),
+		Button:    b,
+		Modifiers: domModifiers(e),
+		Direction: dir,
+	}, eventTime(e))
+}
+
+func (w *windowImpl) sendCrossing(e js.Value, entered bool) {
+	// offsetX and offsetY are in CSS pixels, relative to the canvas.
+	dpr := devicePixelRatio()
+	w.SendAt(screen.CrossingEvent{
+		Entered: entered,
+		X:       float32(e.Get("offsetX").Float() * dpr),
+		Y:       float32(e.Get("offsetY").Float() * dpr),
+	}, eventTime(e))
+}
+
+// sendDrag sends a screen.DragEvent for a DOM DragEvent. Only the dropped
+// strings, such as text, are read. Browsers do not tell web pages the paths of
+// dropped files.
+//
+// TODO: read the contents of dropped files, with a FileReader.
+func (w *windowImpl) sendDrag(e js.Value, typ screen.DragType) {
+	dt := e.Get("dataTransfer")
+	ev := screen.DragEvent{Type: typ}
+	types := dt.Get("types")
+	for i, n := 0, types.Length(); i < n; i++ {
+		ev.Types = append(ev.Types, types.Index(i).String())
+	}
+	if typ != screen.DragLeave {
+		// offsetX and offsetY are in CSS pixels, relative to the canvas.
+		dpr := devicePixelRatio()
+		ev.X = float32(e.Get("offsetX").Float() * dpr)
+		ev.Y = float32(e.Get("offsetY").Float() * dpr)
+	}
+	if typ == screen.Drop {
+		data := map[string][]byte{}
+		for _, t := range dnd.Wanted(ev.Types) {
+			data[t] = []byte(dt.Call("getData", t).String())
+		}
+		ev.Data = dnd.Decode(data)
+	}
+	w.Send(ev)
+}
+
+func (w *windowImpl) sendKey(e js.Value, dir key.Direction) {
+	// The KeyboardEvent's code names the physical key, and its key is what
+	// the key types in the keyboard layout, with the modifiers. The browser
+	// says nothing of what the key types without them, but only Shift and
+	// Alt change it. The scan code is hidden from web pages.
+	code := keyCode(e.Get("code").String())
+	ev := screen.KeyEvent{
+		Event: key.Event{
+			Rune:      keyRune(e.Get("key").String()),
+			Code:      code,
+			Modifiers: domModifiers(e),
+			Direction: dir,
+		},
+		Physical:   code,
+		Unmodified: -1,
+		Repeat:     dir == key.DirNone,
+	}
+	if !e.Get("shiftKey").Bool() && !e.Get("altKey").Bool() {
+		ev.Unmodified = ev.Rune
+	}
+	at := eventTime(e)
+	w.SendAt(ev, at)
+}
+
+// isPen returns whether the DOM PointerEvent came from a pen, and
+// remembers, for the mouse event listeners, whether it did.
+func (w *windowImpl) isPen(e js.Value) bool {
+	w.pen = e.Get("pointerType").String() == "pen"
+	return w.pen
+}
+
+// DOM PointerEvent buttons bits.
+const (
+	domButtonsBarrel = 2
+	domButtonsEraser = 32
+)
+
+func (w *windowImpl) sendPen(e js.Value, typ screen.PenType) {
+	// offsetX and offsetY are in CSS pixels, relative to the canvas. The
+	// tilts, like screen.PenEvent's, are in degrees, with a positive tiltY
+	// towards the user.
+	dpr := devicePixelRatio()
+	buttons := e.Get("buttons").Int()
+	ev := screen.PenEvent{
+		X:         float32(e.Get("offsetX").Float() * dpr),
+		Y:         float32(e.Get("offsetY").Float() * dpr),
+		Type:      typ,
+		TiltX:     float32(e.Get("tiltX").Float()),
+		TiltY:     float32(e.Get("tiltY").Float()),
+		Eraser:    buttons&domButtonsEraser != 0,
+		Barrel:    buttons&domButtonsBarrel != 0,
+		Modifiers: domModifiers(e),
+	}
+	// Browsers give a pressure of 0.5 for pens that cannot sense it.
+	if typ == screen.PenDown || typ == screen.PenMove {
+		ev.Pressure = float32(e.Get("pressure").Float())
+	}
+	w.SendAt(ev, eventTime(e))
+}
+
+func (w *windowImpl) sendTouches(e js.Value, typ touch.Type) {
+	dpr := devicePixelRatio()
+	r := w.canvas.Call("getBoundingClientRect")
+	left, top := r.Get("left").Float(), r.Get("top").Float()
+	at := eventTime(e)
+	touches := e.Get("changedTouches")
+	for i, n := 0, touches.Length(); i < n; i++ {
+		t := touches.Index(i)
+		w.SendAt(touch.Event{
+			X:        float32((t.Get("clientX").Float() - left) * dpr),
+			Y:        float32((t.Get("clientY").Float() - top) * dpr),
+			Sequence: touch.Sequence(t.Get("identifier").Int()),
+			Type:     typ,
+		}, at)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wasmdriver
+
+import (
+	"testing"
+
+	"golang.org/x/mobile/event/key"
+)
+
+func TestKeyRune(t *testing.T) {
+	testCases := []struct {
+		k    string
+		want rune
+	}{
+		{"a", 'a'},
+		{"A", 'A'},
+		{"é", 'é'},
+		{" ", ' '},
+		{"", -1},
+		{"Enter", -1},
+		{"Shift", -1},
+		{"Dead", -1},
+	}
+	for _, tc := range testCases {
+		if got := keyRune(tc.k); got != tc.want {
+			t.Errorf("keyRune(%q): got %q, want %q", tc.k, got, tc.want)
+		}
+	}
+}
+
+func TestKeyCode(t *testing.T) {
+	testCases := []struct {
+		c    string
+		want key.Code
+	}{
+		{"KeyQ", key.CodeQ},
+		{"Digit0", key.Code0},
+		{"Numpad0", key.CodeKeypad0},
+		{"ShiftRight", key.CodeRightShift},
+		{"F24", key.CodeF24},
+		{"Lang1", key.CodeUnknown},
+	}
+	for _, tc := range testCases {
+		if got := keyCode(tc.c); got != tc.want {
+			t.Errorf("keyCode(%q): got %v, want %v", tc.c, got, tc.want)
+		}
+	}
+}
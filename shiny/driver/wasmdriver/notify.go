@@ -0,0 +1,124 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build js,wasm
+
+package wasmdriver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"syscall/js"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// notification is a shown web Notification, and its event listeners.
+type notification struct {
+	n       js.Value
+	onClick js.Func
+	onClose js.Func
+}
+
+func (n *notification) release() {
+	n.n.Set("onclick", js.Null())
+	n.n.Set("onclose", js.Null())
+	n.onClick.Release()
+	n.onClose.Release()
+}
+
+// Notify shows a web Notification, asking the user for permission first if
+// they have not yet been asked. Web pages' notifications have no buttons, so
+// the notification's Actions are ignored. Notify must not be called from a
+// js.Func, as it waits for the user to answer.
+func (w *windowImpl) Notify(n *screen.Notification) error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+
+	api := js.Global().Get("Notification")
+	if api.Type() != js.TypeFunction {
+		return errors.New("wasmdriver: notifications are not supported")
+	}
+	if api.Get("permission").String() == "default" {
+		if _, err := await(api.Call("requestPermission")); err != nil {
+			return err
+		}
+	}
+	if api.Get("permission").String() != "granted" {
+		return errors.New("wasmdriver: notifications are not permitted")
+	}
+
+	opts := map[string]interface{}{
+		"body": n.Body,
+		// A notification with the tag of another replaces it.
+		"tag": fmt.Sprintf("shiny-%p-%d", w, n.ID),
+	}
+	if n.Image != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, n.Image); err == nil {
+			opts["icon"] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return errReleased
+	}
+	if old := w.notifications[n.ID]; old != nil {
+		old.release()
+	}
+	id := n.ID
+	c := &notification{n: api.New(n.Title, opts)}
+	c.onClick = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		js.Global().Call("focus")
+		c.n.Call("close")
+		w.Send(screen.NotificationEvent{ID: id})
+		return nil
+	})
+	c.onClose = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		w.mu.Lock()
+		if w.notifications[id] == c {
+			delete(w.notifications, id)
+			c.release()
+		}
+		w.mu.Unlock()
+		return nil
+	})
+	c.n.Set("onclick", c.onClick)
+	c.n.Set("onclose", c.onClose)
+	if w.notifications == nil {
+		w.notifications = map[int]*notification{}
+	}
+	w.notifications[id] = c
+	return nil
+}
+
+func (w *windowImpl) CloseNotification(id int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if c := w.notifications[id]; c != nil {
+		delete(w.notifications, id)
+		c.release()
+		c.n.Call("close")
+	}
+}
+
+// closeNotifications closes every shown notification, when w is released.
+func (w *windowImpl) closeNotifications() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for id, c := range w.notifications {
+		delete(w.notifications, id)
+		c.release()
+		c.n.Call("close")
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build js,wasm
+
+package wasmdriver
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/swtexture"
+	"golang.org/x/exp/shiny/screen"
+)
+
+func main(f func(screen.Screen)) error {
+	if js.Global().Get("document").IsUndefined() {
+		return errors.New("wasmdriver: no DOM document, as when not running in a web browser")
+	}
+	f(newScreenImpl())
+	return nil
+}
+
+type screenImpl struct {
+	swtexture.Allocator
+
+	document js.Value
+
+	// accessibilityQueries are the MediaQueryLists for each of the
+	// screen.AccessibilityPrefs.
+	accessibilityQueries [3]js.Value
+
+	// darkQuery is the MediaQueryList for a preferred dark color scheme.
+	darkQuery js.Value
+
+	mu                   sync.Mutex
+	defaultWindowOptions *screen.NewWindowOptions
+	windows              map[*windowImpl]struct{}
+
+	// frames are the windows waiting for a screen.FrameEvent.
+	frames frame.Requests
+
+	clipboard clipboardImpl
+
+	// favicon is the page's icon link, once SetIcon has changed it, and
+	// faviconHref is its href from before then, unless faviconAdded, when
+	// the driver added the link. They are guarded by mu.
+	favicon      js.Value
+	faviconHref  js.Value
+	faviconAdded bool
+}
+
+// accessibilityMediaQueries are the CSS media features that correspond to the
+// screen.AccessibilityPrefs, in the same order as those constants.
+var accessibilityMediaQueries = [3]string{
+	"(prefers-reduced-motion: reduce)",
+	"(prefers-contrast: more)",
+	"(prefers-reduced-transparency: reduce)",
+}
+
+func newScreenImpl() *screenImpl {
+	s := &screenImpl{
+		document: js.Global().Get("document"),
+		windows:  map[*windowImpl]struct{}{},
+	}
+
+	// These listeners live as long as the program, so their js.Funcs are
+	// never released.
+	s.document.Call("addEventListener", "visibilitychange", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		visible := !s.document.Get("hidden").Bool()
+		for _, w := range s.allWindows() {
+			w.lifecycler.SetVisible(visible && !w.hidden)
+			w.lifecycler.SendEvent(w, nil)
+		}
+		return nil
+	}))
+	onAccessibilityChange := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		e := screen.AccessibilityEvent{Prefs: s.AccessibilityPrefs()}
+		for _, w := range s.allWindows() {
+			w.Send(e)
+		}
+		return nil
+	})
+	if matchMedia := js.Global().Get("matchMedia"); matchMedia.Type() == js.TypeFunction {
+		for i, q := range accessibilityMediaQueries {
+			mql := js.Global().Call("matchMedia", q)
+			mql.Call("addEventListener", "change", onAccessibilityChange)
+			s.accessibilityQueries[i] = mql
+		}
+		s.darkQuery = js.Global().Call("matchMedia", "(prefers-color-scheme: dark)")
+		s.darkQuery.Call("addEventListener", "change", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			e := screen.ColorSchemeEvent{Scheme: s.ColorScheme()}
+			for _, w := range s.allWindows() {
+				w.Send(e)
+			}
+			return nil
+		}))
+		s.watchDevicePixelRatio()
+	}
+	// Browsers that support the Window Management API fire a change event
+	// on window.screen when its properties, such as its size, change.
+	if scr := js.Global().Get("screen"); scr.Truthy() && scr.Get("addEventListener").Type() == js.TypeFunction {
+		scr.Call("addEventListener", "change", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			displays := s.Displays()
+			for _, w := range s.allWindows() {
+				w.Send(screen.DisplayEvent{Displays: append([]screen.Display(nil), displays...)})
+			}
+			return nil
+		}))
+	}
+	return s
+}
+
+// watchDevicePixelRatio listens for the next change to the device pixel ratio,
+// such as when the browser is zoomed or moved to another display. There is no
+// event for that, so it matches a media query for the current resolution
+// and, when that stops matching, sends each window its new scale and listens
+// again for the new resolution.
+func (s *screenImpl) watchDevicePixelRatio() {
+	q := fmt.Sprintf("(resolution: %gdppx)", devicePixelRatio())
+	mql := js.Global().Call("matchMedia", q)
+	var fn js.Func
+	fn = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		fn.Release()
+		for _, w := range s.allWindows() {
+			w.rescale()
+		}
+		s.watchDevicePixelRatio()
+		return nil
+	})
+	mql.Call("addEventListener", "change", fn, map[string]interface{}{"once": true})
+}
+
+// requestAnimationFrame is the frame source for the windows' NextFrame
+// methods. The browser calls the callback before it next repaints the page,
+// which it does at the display's refresh rate while the page is visible.
+func (s *screenImpl) requestAnimationFrame() {
+	var fn js.Func
+	fn = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		fn.Release()
+		t := time.Now()
+		// The callback's argument is milliseconds since the time origin.
+		if origin := js.Global().Get("performance").Get("timeOrigin"); origin.Truthy() && len(args) > 0 {
+			t = time.Unix(0, int64((origin.Float()+args[0].Float())*1e6))
+		}
+		if s.frames.Fire(t) {
+			s.requestAnimationFrame()
+		}
+		return nil
+	})
+	js.Global().Call("requestAnimationFrame", fn)
+}
+
+func (s *screenImpl) allWindows() []*windowImpl {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	windows := make([]*windowImpl, 0, len(s.windows))
+	for w := range s.windows {
+		windows = append(windows, w)
+	}
+	return windows
+}
+
+// setFavicon sets the href of the page's icon link, adding one if it has
+// none, or restores the link as it was if href is "".
+func (s *screenImpl) setFavicon(href string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.favicon.IsUndefined() {
+		if href == "" {
+			return
+		}
+		s.favicon = s.document.Call("querySelector", "link[rel~='icon']")
+		s.faviconAdded = s.favicon.IsNull()
+		if s.faviconAdded {
+			s.favicon = s.document.Call("createElement", "link")
+			s.favicon.Set("rel", "icon")
+			s.document.Get("head").Call("appendChild", s.favicon)
+		} else {
+			s.faviconHref = s.favicon.Call("getAttribute", "href")
+		}
+	}
+	switch {
+	case href != "":
+		s.favicon.Set("href", href)
+		return
+	case s.faviconAdded:
+		s.favicon.Call("remove")
+	case s.faviconHref.IsNull():
+		s.favicon.Call("removeAttribute", "href")
+	default:
+		s.favicon.Call("setAttribute", "href", s.faviconHref)
+	}
+	s.favicon = js.Undefined()
+}
+
+func (s *screenImpl) NewTexture(size image.Point) (screen.Texture, error) {
+	return s.NewTextureWithOptions(size, nil)
+}
+
+func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) {
+	s.mu.Lock()
+	opts = opts.WithDefaults(s.defaultWindowOptions)
+	s.mu.Unlock()
+
+	width, height := 1024, 768
+	if opts != nil {
+		if opts.Width > 0 {
+			width = opts.Width
+		}
+		if opts.Height > 0 {
+			height = opts.Height
+		}
+	}
+
+	w := newWindowImpl(s, opts, width, height)
+
+	s.mu.Lock()
+	s.windows[w] = struct{}{}
+	s.mu.Unlock()
+
+	w.lifecycler.SetVisible(!w.hidden && !s.document.Get("hidden").Bool())
+	w.lifecycler.SendEvent(w, nil)
+	w.sendSize(image.Point{width, height})
+	if !w.hidden {
+		w.canvas.Call("focus")
+	}
+	return w, nil
+}
+
+func (s *screenImpl) SetDefaultWindowOptions(opts *screen.NewWindowOptions) {
+	opts = opts.WithDefaults(nil)
+
+	s.mu.Lock()
+	s.defaultWindowOptions = opts
+	s.mu.Unlock()
+}
+
+// AccessibilityPrefs returns the preferences reported by the browser's CSS
+// media features, such as prefers-reduced-motion.
+func (s *screenImpl) AccessibilityPrefs() (p screen.AccessibilityPrefs) {
+	prefs := [3]screen.AccessibilityPrefs{
+		screen.ReduceMotion,
+		screen.HighContrast,
+		screen.ReduceTransparency,
+	}
+	for i, mql := range s.accessibilityQueries {
+		if mql.Truthy() && mql.Get("matches").Bool() {
+			p |= prefs[i]
+		}
+	}
+	return p
+}
+
+// ColorScheme returns the color scheme reported by the browser's
+// prefers-color-scheme CSS media feature.
+func (s *screenImpl) ColorScheme() screen.ColorScheme {
+	if s.darkQuery.Truthy() && s.darkQuery.Get("matches").Bool() {
+		return screen.DarkColorScheme
+	}
+	return screen.LightColorScheme
+}
+
+func (s *screenImpl) Clipboard() screen.Clipboard {
+	return &s.clipboard
+}
+
+// NewTrayIcon returns an error, as a web page has no presence outside of its
+// browser tab.
+func (s *screenImpl) NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return nil, errors.New("wasmdriver: NewTrayIcon is not supported")
+}
+
+// Displays returns the screen that the page is shown on, as window.screen
+// describes it. A page cannot see where that screen is among others, so its
+// Bounds are at the origin, and its DPI and refresh rate are unknown.
+func (s *screenImpl) Displays() []screen.Display {
+	scr := js.Global().Get("screen")
+	if !scr.Truthy() {
+		return nil
+	}
+	dpr := devicePixelRatio()
+	px := func(name string) int {
+		if v := scr.Get(name); v.Type() == js.TypeNumber {
+			return int(v.Float() * dpr)
+		}
+		return 0
+	}
+	d := screen.Display{
+		Bounds:  image.Rect(0, 0, px("width"), px("height")),
+		Scale:   dpr,
+		Primary: true,
+	}
+	// availLeft and availTop are non-standard, but widely supported.
+	x, y := px("availLeft"), px("availTop")
+	d.WorkArea = image.Rect(x, y, x+px("availWidth"), y+px("availHeight")).Intersect(d.Bounds)
+	if d.WorkArea.Empty() {
+		d.WorkArea = d.Bounds
+	}
+	return []screen.Display{d}
+}
+
+// clipboardImpl is the system clipboard, via the asynchronous Clipboard API.
+// Browsers only allow that API in secure contexts, and may ask the user for
+// permission. Without it, as for an insecure page, the clipboard is in-memory
+// and private to this Screen.
+type clipboardImpl struct {
+	mu   sync.Mutex
+	text string
+}
+
+func (c *clipboardImpl) api() js.Value {
+	return js.Global().Get("navigator").Get("clipboard")
+}
+
+func (c *clipboardImpl) ReadText() (string, error) {
+	if api := c.api(); api.Truthy() {
+		v, err := await(api.Call("readText"))
+		if err != nil {
+			return "", err
+		}
+		return v.String(), nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.text, nil
+}
+
+func (c *clipboardImpl) WriteText(text string) error {
+	if api := c.api(); api.Truthy() {
+		_, err := await(api.Call("writeText", text))
+		return err
+	}
+	c.mu.Lock()
+	c.text = text
+	c.mu.Unlock()
+	return nil
+}
+
+// await waits for the JavaScript Promise p to settle, returning its value or
+// its rejection reason. It must not be called from a js.Func, as the Promise
+// cannot settle while a js.Func blocks the browser's event loop.
+func await(p js.Value) (js.Value, error) {
+	type result struct {
+		v   js.Value
+		err error
+	}
+	done := make(chan result, 1)
+	onFulfilled := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		v := js.Undefined()
+		if len(args) > 0 {
+			v = args[0]
+		}
+		done <- result{v: v}
+		return nil
+	})
+	defer onFulfilled.Release()
+	onRejected := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		reason := "unknown error"
+		if len(args) > 0 {
+			reason = args[0].Call("toString").String()
+		}
+		done <- result{err: errors.New("wasmdriver: " + reason)}
+		return nil
+	})
+	defer onRejected.Release()
+
+	p.Call("then", onFulfilled, onRejected)
+	r := <-done
+	return r.v, r.err
+}
@@ -0,0 +1,32 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package wasmdriver provides a driver for accessing a screen from a web
+// browser, for programs built with GOOS=js and GOARCH=wasm.
+//
+// Each window is a canvas element, appended to the document's body. Drawing
+// is done in software, and windows are published by copying their pixels to
+// the canvas as an ImageData. Mouse, keyboard and touch events on the canvas
+// are sent to the window, as are focus changes and changes in the document's
+// visibility.
+//
+// TODO: render with WebGL instead, as the gldriver does with OpenGL.
+package wasmdriver // import "golang.org/x/exp/shiny/driver/wasmdriver"
+
+import (
+	"golang.org/x/exp/shiny/driver/internal/errscreen"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Main is called by the program's main function to run the graphical
+// application.
+//
+// It calls f on the Screen, possibly in a separate goroutine, as some OS-
+// specific libraries require being on 'the main thread'. It returns when f
+// returns.
+func Main(f func(screen.Screen)) {
+	if err := main(f); err != nil {
+		f(errscreen.Stub(err))
+	}
+}
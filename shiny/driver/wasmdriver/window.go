@@ -0,0 +1,857 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build js,wasm
+
+package wasmdriver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strconv"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/capture"
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/lifecycler"
+	"golang.org/x/exp/shiny/driver/internal/swtexture"
+	"golang.org/x/exp/shiny/screen"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/paint"
+	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/geom"
+)
+
+type windowImpl struct {
+	s *screenImpl
+
+	canvas js.Value
+	ctx    js.Value // The canvas's CanvasRenderingContext2D.
+
+	// listeners are the canvas's event listeners, released by Release.
+	listeners []listener
+
+	event.Deque
+	lifecycler lifecycler.State
+
+	// hidden is whether the canvas is never shown.
+	hidden bool
+	// transparent is whether the canvas has an alpha channel. shape, if
+	// non-nil, is the window's shape, to which the canvas is clipped.
+	transparent bool
+	shape       []image.Rectangle
+
+	// These fields are only used by the js.Funcs that handle input events,
+	// which the browser calls one at a time.
+	wheel      [2]float64
+	fullscreen bool
+	// pen is whether the most recent pointer event came from a pen, whose
+	// compatibility mouse events are then ignored.
+	pen bool
+
+	// mu guards back, pixels, imageData, cursor, cursorHidden, captured,
+	// accessElems, notifications and released.
+	// If you need to hold both a windowImpl's mu and a swtexture.Texture's
+	// mu, the lock ordering is to lock the windowImpl's first (and unlock it
+	// last).
+	mu sync.Mutex
+	// back is the back buffer, that the Drawer methods draw to.
+	back *image.RGBA
+	// clip is the clip stack that the Drawer methods draw subject to. Like
+	// back, it is guarded by mu.
+	clip drawer.Clip
+	// pixels is a Uint8ClampedArray the size of back's pixels, and imageData
+	// is an ImageData of them, that Publish puts on the canvas. They are
+	// allocated on the first Publish after each resize.
+	pixels    js.Value
+	imageData js.Value
+	// straight holds a transparent window's pixels, converted from back's
+	// premultiplied alpha to the straight alpha of an ImageData.
+	straight []byte
+	// cursor is the CSS cursor set by SetCursor, or empty for the default.
+	cursor       string
+	cursorHidden bool
+	// captured is whether SetPointerCapture asked for the pointer lock. The
+	// browser may not have granted it, or may have since ended it.
+	captured bool
+	// accessElems are the canvas's fallback elements, set by SetAccessTree,
+	// by their nodes' IDs.
+	accessElems map[int]js.Value
+	// notifications are the shown notifications, posted by Notify, by their
+	// IDs.
+	notifications map[int]*notification
+	released      bool
+
+	imagePool drawer.ImagePool
+	layers    drawer.Layers
+	captures  capture.Hooks
+	timing    frame.Timing
+}
+
+type listener struct {
+	typ string
+	fn  js.Func
+}
+
+func newWindowImpl(s *screenImpl, opts *screen.NewWindowOptions, width, height int) *windowImpl {
+	w := &windowImpl{
+		s:      s,
+		canvas: s.document.Call("createElement", "canvas"),
+		back:   image.NewRGBA(image.Rect(0, 0, width, height)),
+	}
+	if opts != nil {
+		w.Priority = opts.EventPriority
+		w.KeepAllMoves = opts.KeepAllMoves
+		w.KeyEvents = opts.KeyEvents
+		w.Queues = opts.EventQueues
+		w.hidden = opts.Hidden
+		w.clip.Linear = opts.LinearBlending
+		w.transparent = opts.Transparent
+		if len(opts.Shape) > 0 {
+			w.shape = append([]image.Rectangle(nil), opts.Shape...)
+		}
+	}
+
+	w.setCanvasSize(image.Point{width, height})
+	style := w.canvas.Get("style")
+	// The canvas is only focusable, to receive key events, with a tabIndex.
+	w.canvas.Set("tabIndex", 0)
+	style.Set("outline", "none")
+	// Touches are sent to the window, instead of scrolling or zooming.
+	style.Set("touchAction", "none")
+	if opts != nil && opts.Position != nil {
+		w.setPosition(*opts.Position)
+	}
+	if w.hidden {
+		style.Set("display", "none")
+	}
+	if title := opts.GetTitle(); title != "" {
+		w.setTitle(title)
+	}
+	// FixedSize is meaningless, as the user cannot resize a canvas.
+
+	// Unless the window is transparent, the back buffer's alpha is ignored,
+	// as for the other drivers, so the canvas is opaque.
+	w.ctx = w.canvas.Call("getContext", "2d", map[string]interface{}{"alpha": w.transparent})
+	w.addListeners()
+	s.document.Get("body").Call("appendChild", w.canvas)
+	return w
+}
+
+// devicePixelRatio returns the number of canvas pixels per CSS pixel.
+func devicePixelRatio() float64 {
+	if r := js.Global().Get("devicePixelRatio"); r.Truthy() {
+		return r.Float()
+	}
+	return 1
+}
+
+// setCanvasSize sets the canvas's size, in pixels, and its CSS size so that
+// each of its pixels is a device pixel.
+func (w *windowImpl) setCanvasSize(sz image.Point) {
+	dpr := devicePixelRatio()
+	w.canvas.Set("width", sz.X)
+	w.canvas.Set("height", sz.Y)
+	style := w.canvas.Get("style")
+	style.Set("width", fmt.Sprintf("%gpx", float64(sz.X)/dpr))
+	style.Set("height", fmt.Sprintf("%gpx", float64(sz.Y)/dpr))
+	if w.shape != nil {
+		style.Set("clipPath", clipPath(w.shape, dpr))
+	}
+}
+
+// clipPath returns a CSS clip-path of the union of shape, in pixels, for an
+// element with dpr pixels per CSS pixel. Every rectangle's path goes
+// clockwise, so that the nonzero fill rule fills their union. The path also
+// limits where the element receives pointer events.
+func clipPath(shape []image.Rectangle, dpr float64) string {
+	var b bytes.Buffer
+	b.WriteString("path('")
+	for _, r := range shape {
+		if r.Empty() {
+			continue
+		}
+		fmt.Fprintf(&b, "M%g %gH%gV%gH%gZ",
+			float64(r.Min.X)/dpr, float64(r.Min.Y)/dpr,
+			float64(r.Max.X)/dpr, float64(r.Max.Y)/dpr, float64(r.Min.X)/dpr)
+	}
+	b.WriteString("')")
+	return b.String()
+}
+
+func (w *windowImpl) setPosition(p image.Point) {
+	dpr := devicePixelRatio()
+	style := w.canvas.Get("style")
+	style.Set("position", "absolute")
+	style.Set("left", fmt.Sprintf("%gpx", float64(p.X)/dpr))
+	style.Set("top", fmt.Sprintf("%gpx", float64(p.Y)/dpr))
+}
+
+// setTitle labels the canvas for assistive technologies, and titles the
+// document, which is what the browser shows for its tab.
+func (w *windowImpl) setTitle(title string) {
+	w.canvas.Call("setAttribute", "aria-label", title)
+	w.s.document.Set("title", title)
+}
+
+func (w *windowImpl) Release() {
+	w.mu.Lock()
+	released := w.released
+	w.released = true
+	w.mu.Unlock()
+	if released {
+		return
+	}
+
+	w.imagePool.Release()
+	w.layers.Release()
+	w.StopTimers()
+
+	s := w.s
+	s.mu.Lock()
+	delete(s.windows, w)
+	s.mu.Unlock()
+
+	for _, l := range w.listeners {
+		w.canvas.Call("removeEventListener", l.typ, l.fn)
+		l.fn.Release()
+	}
+	w.listeners = nil
+	w.closeNotifications()
+	if w.pointerLocked() {
+		s.document.Call("exitPointerLock")
+	}
+	w.canvas.Call("remove")
+}
+
+func (w *windowImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	swtexture.Upload(w.back, dp, src, sr)
+}
+
+func (w *windowImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.clip.Fill(w.back, dr, src, op)
+}
+
+// Draw and DrawUniform use bilinear sampling, which is exact for
+// transformations that are just translations. Depth testing is not supported,
+// but blend modes and transparency are composited in software.
+
+func (w *windowImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	t := src.(*swtexture.Texture)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	t.Transform(&w.clip, xdraw.ApproxBiLinear, w.back, src2dst, sr, op, opts)
+}
+
+func (w *windowImpl) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.clip.Transform(xdraw.ApproxBiLinear, w.back, src2dst, image.NewUniform(src), sr, op, opts)
+}
+
+func (w *windowImpl) PushClip(r image.Rectangle) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PushClip(r)
+}
+
+func (w *windowImpl) PushClipPath(src2dst f64.Aff3, p *screen.Path) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PushClipPath(src2dst, p)
+}
+
+func (w *windowImpl) PopClip() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PopClip()
+}
+
+func (w *windowImpl) Copy(dp image.Point, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Copy(w, dp, src, sr, op, opts)
+}
+
+func (w *windowImpl) Scale(dr image.Rectangle, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Scale(w, dr, src, sr, op, opts)
+}
+
+func (w *windowImpl) DrawImage(dp image.Point, src image.Image, op draw.Op, opts *screen.DrawOptions) {
+	w.imagePool.DrawImage(w.s, w, dp, src, op, opts)
+}
+
+func (w *windowImpl) NewLayer(z int, size image.Point) (screen.Layer, error) {
+	return w.layers.NewLayer(w.s, z, size)
+}
+
+func (w *windowImpl) Publish() screen.PublishResult {
+	composited := w.layers.Composite(w)
+
+	w.mu.Lock()
+	if w.released {
+		w.mu.Unlock()
+		return screen.PublishResult{Dropped: true}
+	}
+	// The back buffer is copied, not flipped, to the front, so its contents
+	// are preserved. Compositing any layers overwrites the contents, though.
+	res := screen.PublishResult{BackBufferPreserved: !composited}
+	if w.hidden {
+		res.Dropped = true
+	} else {
+		if w.imageData.IsUndefined() {
+			sz := w.back.Rect.Size()
+			w.pixels = js.Global().Get("Uint8ClampedArray").New(len(w.back.Pix))
+			w.imageData = js.Global().Get("ImageData").New(w.pixels, sz.X, sz.Y)
+		}
+		// The back buffer's stride is 4 bytes per pixel, without padding,
+		// as an ImageData's is.
+		if w.transparent {
+			if len(w.straight) != len(w.back.Pix) {
+				w.straight = make([]byte, len(w.back.Pix))
+			}
+			unpremultiply(w.straight, w.back.Pix)
+			js.CopyBytesToJS(w.pixels, w.straight)
+		} else {
+			js.CopyBytesToJS(w.pixels, w.back.Pix)
+		}
+		w.ctx.Call("putImageData", w.imageData, 0, 0)
+		// The browser shows the canvas at its next rendering opportunity.
+		w.timing.Presented(&res, time.Now())
+	}
+	var captured *image.RGBA
+	if w.captures.Active() {
+		captured = capture.Copy(w.back, w.back.Rect)
+	}
+	w.mu.Unlock()
+
+	w.captures.Send(captured)
+	return res
+}
+
+func (w *windowImpl) AddCaptureHook(fn func(f screen.CapturedFrame)) (remove func()) {
+	return w.captures.Add(fn)
+}
+
+// unpremultiply converts the premultiplied RGBA pixels of src to straight
+// alpha, writing them to dst.
+func unpremultiply(dst, src []byte) {
+	for i := 0; i+4 <= len(src); i += 4 {
+		a := uint32(src[i+3])
+		switch a {
+		case 0:
+			dst[i+0], dst[i+1], dst[i+2], dst[i+3] = 0, 0, 0, 0
+		case 0xff:
+			copy(dst[i:i+4], src[i:i+4])
+		default:
+			for j := 0; j < 3; j++ {
+				c := uint32(src[i+j])
+				if c > a {
+					// An invalid premultiplied color saturates.
+					c = a
+				}
+				dst[i+j] = byte((c*0xff + a/2) / a)
+			}
+			dst[i+3] = byte(a)
+		}
+	}
+}
+
+// NextFrame sends a screen.FrameEvent when the browser is next about to repaint
+// the page.
+func (w *windowImpl) NextFrame() {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return
+	}
+	if s := w.s; s.frames.Request(w) {
+		s.requestAnimationFrame()
+	}
+}
+
+func (w *windowImpl) RenderFrame(fn func(d screen.Drawer)) error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+
+	fn(w)
+	return nil
+}
+
+var errReleased = errors.New("wasmdriver: window is released")
+
+func (w *windowImpl) GLInfo() screen.GLInfo { return screen.GLInfo{} }
+
+func (w *windowImpl) Begin() *screen.Context { return screen.NewContext(w) }
+
+func (w *windowImpl) SetTitle(title string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.setTitle(title)
+}
+
+// SetIcon sets the page's favicon, which is shared by its windows, to m, or
+// restores the page's own favicon if m is nil.
+func (w *windowImpl) SetIcon(m image.Image) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	href := ""
+	if m != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, m); err != nil {
+			return
+		}
+		href = "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	w.s.setFavicon(href)
+}
+
+// SetBadge sets the badge of the installed web app, with the Badging API,
+// which is a number if label is one, and otherwise a mark. It does nothing
+// if the browser lacks the API.
+func (w *windowImpl) SetBadge(label string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	nav := js.Global().Get("navigator")
+	if w.released || nav.Get("setAppBadge").Type() != js.TypeFunction {
+		return
+	}
+	switch n, err := strconv.ParseUint(label, 10, 53); {
+	case label == "":
+		nav.Call("clearAppBadge")
+	case err == nil:
+		nav.Call("setAppBadge", float64(n))
+	default:
+		nav.Call("setAppBadge")
+	}
+}
+
+// SetProgress does nothing, as browsers do not show a page's progress.
+func (w *windowImpl) SetProgress(progress float64) {}
+
+// SetSize resizes the canvas and the back buffer, keeping as much of the old
+// contents as fit.
+func (w *windowImpl) SetSize(width, height int) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	sz := image.Point{width, height}
+
+	w.mu.Lock()
+	if w.released || w.back.Rect.Size() == sz {
+		w.mu.Unlock()
+		return
+	}
+	back := image.NewRGBA(image.Rectangle{Max: sz})
+	draw.Draw(back, back.Rect, w.back, image.Point{}, draw.Src)
+	w.back = back
+	w.pixels, w.imageData = js.Undefined(), js.Undefined()
+	w.setCanvasSize(sz)
+	w.mu.Unlock()
+
+	w.sendSize(sz)
+}
+
+// SetPosition positions the canvas absolutely, relative to its containing
+// block, which is typically the document's body.
+func (w *windowImpl) SetPosition(p image.Point) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.setPosition(p)
+}
+
+// GetGeometry returns the canvas's position relative to the document, in
+// device pixels.
+func (w *windowImpl) GetGeometry() (image.Point, image.Point) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return image.Point{}, image.Point{}
+	}
+	dpr := devicePixelRatio()
+	r := w.canvas.Call("getBoundingClientRect")
+	win := js.Global()
+	p := image.Point{
+		X: int((r.Get("left").Float() + win.Get("scrollX").Float()) * dpr),
+		Y: int((r.Get("top").Float() + win.Get("scrollY").Float()) * dpr),
+	}
+	return p, w.back.Rect.Size()
+}
+
+// TODO: focus a hidden textarea at r, and send its composition events as
+// screen.TextEvents.
+func (w *windowImpl) SetTextInputRect(r image.Rectangle) {}
+
+// rescale keeps the canvas's size in pixels after the device pixel ratio
+// changes, so that each of its pixels is still a device pixel, and sends the
+// new scale.
+func (w *windowImpl) rescale() {
+	w.mu.Lock()
+	if w.released {
+		w.mu.Unlock()
+		return
+	}
+	sz := w.back.Rect.Size()
+	w.setCanvasSize(sz)
+	w.mu.Unlock()
+
+	dpr := devicePixelRatio()
+	w.Send(screen.ScaleEvent{
+		PixelsPerPt: pixelsPerPt(dpr),
+		Scale:       dpr,
+	})
+	w.sendSize(sz)
+}
+
+// pixelsPerPt returns the canvas pixels per point for a device pixel ratio. A
+// CSS pixel is 1/96th of an inch, and a point is 1/72nd.
+func pixelsPerPt(dpr float64) float32 {
+	return float32(dpr * 96 / 72)
+}
+
+// cssCursors are the CSS cursor names of the screen.CursorShapes.
+var cssCursors = [...]string{
+	screen.CursorArrow:      "default",
+	screen.CursorIBeam:      "text",
+	screen.CursorCrosshair:  "crosshair",
+	screen.CursorHand:       "pointer",
+	screen.CursorResizeNS:   "ns-resize",
+	screen.CursorResizeEW:   "ew-resize",
+	screen.CursorResizeNWSE: "nwse-resize",
+	screen.CursorResizeNESW: "nesw-resize",
+	screen.CursorResizeAll:  "move",
+	screen.CursorNotAllowed: "not-allowed",
+	screen.CursorWait:       "wait",
+}
+
+// SetCursor sets the canvas's CSS cursor. A custom cursor is a PNG data URL,
+// with the standard arrow as the fallback that CSS requires. Browsers show
+// each of its pixels as a CSS pixel, not a device pixel.
+func (w *windowImpl) SetCursor(c screen.Cursor) {
+	css := "default"
+	if c.HasImage() {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, c.Image); err == nil {
+			css = fmt.Sprintf("url(data:image/png;base64,%s) %d %d, default",
+				base64.StdEncoding.EncodeToString(buf.Bytes()), c.Hotspot.X, c.Hotspot.Y)
+		}
+	} else if c.Shape >= 0 && int(c.Shape) < len(cssCursors) {
+		css = cssCursors[c.Shape]
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.cursor = css
+	w.updateCursor()
+}
+
+func (w *windowImpl) SetCursorVisible(visible bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.cursorHidden = !visible
+	w.updateCursor()
+}
+
+// SetPointerCapture asks the browser for the pointer lock. Browsers only grant
+// it in response to a user gesture, such as a click, so if that fails, it is
+// asked for again when the canvas is next clicked. The browser ends the lock
+// when the user presses Escape.
+func (w *windowImpl) SetPointerCapture(capture bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released || w.captured == capture {
+		return
+	}
+	w.captured = capture
+	if capture {
+		w.canvas.Call("requestPointerLock")
+	} else if w.pointerLocked() {
+		w.s.document.Call("exitPointerLock")
+	}
+}
+
+// StartDrag returns an error, as web pages can only drag data out of the
+// browser from the elements that the user starts to drag, with the DOM's
+// dragstart event.
+func (w *windowImpl) StartDrag(data screen.DragData) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return errReleased
+	}
+	return errors.New("wasmdriver: StartDrag is not supported")
+}
+
+// StartMove returns an error, as a web page cannot move the browser's window.
+func (w *windowImpl) StartMove() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return errReleased
+	}
+	return errors.New("wasmdriver: StartMove is not supported")
+}
+
+// SetSizeConstraints and SetAspectRatio do nothing, as the user cannot resize
+// a canvas.
+func (w *windowImpl) SetSizeConstraints(min, max image.Point) {}
+
+func (w *windowImpl) SetAspectRatio(aspect image.Point) {}
+
+// StartResize returns an error, as a web page cannot resize the browser's
+// window.
+func (w *windowImpl) StartResize(edge screen.WindowEdge) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return errReleased
+	}
+	return errors.New("wasmdriver: StartResize is not supported")
+}
+
+// RegisterHotKey returns an error, as a web page is only sent the key
+// presses made while it has the keyboard focus.
+func (w *windowImpl) RegisterHotKey(k key.Code, mods key.Modifiers) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return errReleased
+	}
+	return errors.New("wasmdriver: RegisterHotKey is not supported")
+}
+
+func (w *windowImpl) UnregisterHotKey(k key.Code, mods key.Modifiers) {}
+
+// Minimize does nothing, as a web page cannot minimize the browser's window.
+func (w *windowImpl) Minimize() {}
+
+// Maximize leaves fullscreen, as a web page cannot maximize the browser's
+// window.
+func (w *windowImpl) Maximize() { w.SetFullscreen(screen.FullscreenNone) }
+
+// Restore leaves fullscreen.
+func (w *windowImpl) Restore() { w.SetFullscreen(screen.FullscreenNone) }
+
+// SetFullscreen asks the browser to show the canvas fullscreen. Browsers only
+// allow that in response to a user gesture, such as a key press or a click.
+// The browser leaves fullscreen when the user presses Escape. There is no
+// exclusive fullscreen, so FullscreenExclusive is the same as
+// FullscreenBorderless.
+func (w *windowImpl) SetFullscreen(mode screen.FullscreenMode) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	doc := w.s.document
+	if mode == screen.FullscreenNone {
+		if doc.Get("fullscreenElement").Equal(w.canvas) {
+			doc.Call("exitFullscreen")
+		}
+		return
+	}
+	if w.canvas.Get("requestFullscreen").Type() == js.TypeFunction {
+		w.canvas.Call("requestFullscreen")
+	}
+}
+
+// SetAlwaysOnTop gives the canvas the greatest CSS z-index, so that it is shown
+// above the page's other elements, or none.
+func (w *windowImpl) SetAlwaysOnTop(onTop bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	zIndex := ""
+	if onTop {
+		zIndex = "2147483647"
+	}
+	w.canvas.Get("style").Set("zIndex", zIndex)
+}
+
+// SetOpacity sets the canvas's CSS opacity.
+func (w *windowImpl) SetOpacity(opacity float32) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	if !(opacity < 1) {
+		w.canvas.Get("style").Set("opacity", "")
+		return
+	}
+	if !(opacity > 0) {
+		opacity = 0
+	}
+	w.canvas.Get("style").Set("opacity", fmt.Sprintf("%g", opacity))
+}
+
+// Raise moves the canvas to the end of the document's body, so that it is
+// drawn above its siblings with the same z-index. Lower moves it to the start.
+func (w *windowImpl) Raise() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.released {
+		w.s.document.Get("body").Call("appendChild", w.canvas)
+	}
+}
+
+func (w *windowImpl) Lower() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.released {
+		body := w.s.document.Get("body")
+		body.Call("insertBefore", w.canvas, body.Get("firstChild"))
+	}
+}
+
+// ShowFileDialog returns an error, as web pages cannot know the paths of the
+// user's files.
+//
+// TODO: show an <input type="file"> element's file chooser, and send the
+// chosen files' contents some other way?
+func (w *windowImpl) ShowFileDialog(opts *screen.FileDialogOptions) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return errReleased
+	}
+	return errors.New("wasmdriver: ShowFileDialog is not supported")
+}
+
+// SetMenuBar and ShowContextMenu return an error, as web pages have no menus
+// of their own.
+func (w *windowImpl) SetMenuBar(bar *screen.Menu) error {
+	return w.errNoMenus()
+}
+
+func (w *windowImpl) ShowContextMenu(m *screen.Menu, p image.Point) error {
+	return w.errNoMenus()
+}
+
+func (w *windowImpl) errNoMenus() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return errReleased
+	}
+	return errors.New("wasmdriver: menus are not supported")
+}
+
+func (w *windowImpl) isCaptured() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.captured
+}
+
+// pointerLocked returns whether the canvas has the browser's pointer lock.
+func (w *windowImpl) pointerLocked() bool {
+	return w.s.document.Get("pointerLockElement").Equal(w.canvas)
+}
+
+// updateCursor sets the canvas's style to show w.cursor, or no cursor if it is
+// hidden. It must be called with w.mu held.
+func (w *windowImpl) updateCursor() {
+	css := w.cursor
+	if w.cursorHidden {
+		css = "none"
+	}
+	w.canvas.Get("style").Set("cursor", css)
+}
+
+// sendSize sends a size.Event, and then a paint.Event, as the window's new
+// contents are undefined until it is next published.
+func (w *windowImpl) sendSize(sz image.Point) {
+	ppp := pixelsPerPt(devicePixelRatio())
+	w.Send(size.Event{
+		WidthPx:     sz.X,
+		HeightPx:    sz.Y,
+		WidthPt:     geom.Pt(float32(sz.X) / ppp),
+		HeightPt:    geom.Pt(float32(sz.Y) / ppp),
+		PixelsPerPt: ppp,
+	})
+	w.Send(paint.Event{External: true})
+}
+
+func (w *windowImpl) ColorSpace() screen.ColorSpace { return screen.ColorSpaceSRGB }
+
+// SetColorSpace only accepts screen.ColorSpaceSRGB, as the canvas's 2D context
+// is created in sRGB.
+func (w *windowImpl) SetColorSpace(cs screen.ColorSpace) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	if cs != screen.ColorSpaceSRGB {
+		return errors.New("wasmdriver: color spaces other than sRGB are not supported")
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"image"
+	"image/draw"
+	"log"
+
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// cursorShapes are the wp_cursor_shape_device_v1 shapes of the
+// screen.CursorShapes.
+var cursorShapes = [...]uint32{
+	screen.CursorArrow:      1,  // default
+	screen.CursorIBeam:      9,  // text
+	screen.CursorCrosshair:  8,  // crosshair
+	screen.CursorHand:       4,  // pointer
+	screen.CursorResizeNS:   27, // ns_resize
+	screen.CursorResizeEW:   26, // ew_resize
+	screen.CursorResizeNWSE: 29, // nwse_resize
+	screen.CursorResizeNESW: 28, // nesw_resize
+	screen.CursorResizeAll:  13, // move
+	screen.CursorNotAllowed: 15, // not_allowed
+	screen.CursorWait:       6,  // wait
+}
+
+func (w *windowImpl) SetCursor(c screen.Cursor) {
+	if c.HasImage() {
+		// Copy the image, as it is shown again each time that the pointer
+		// enters the window.
+		m := image.NewRGBA(c.Image.Rect)
+		draw.Draw(m, m.Rect, c.Image, m.Rect.Min, draw.Src)
+		c.Image = m
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.cursor = c
+	w.updateCursor()
+}
+
+func (w *windowImpl) SetCursorVisible(visible bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released || w.cursorHidden == !visible {
+		return
+	}
+	w.cursorHidden = !visible
+	w.updateCursor()
+}
+
+// updateCursor shows w.cursor, or no cursor if it is hidden or the window has
+// captured the pointer, if the pointer is over the window. Wayland cursors are
+// per pointer, not per surface, so this is also called whenever the pointer
+// enters the window. It must be called with w.mu held.
+func (w *windowImpl) updateCursor() {
+	s := w.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.enterSurface != w.surface {
+		return
+	}
+	c, serial := s.c, s.enterSerial
+	switch {
+	case w.cursorHidden || w.captured:
+		c.request(s.pointer, pointerSetCursor, serial, 0, 0, 0)
+	case w.cursor.HasImage():
+		if err := s.showCursorImage(w.cursor.Image); err != nil {
+			log.Print(err)
+			return
+		}
+		c.request(s.pointer, pointerSetCursor, serial, uint32(s.cursorSurface),
+			uint32(int32(w.cursor.Hotspot.X)), uint32(int32(w.cursor.Hotspot.Y)))
+	case s.cursorShapeDevice != 0:
+		shape := w.cursor.Shape
+		if shape < 0 || int(shape) >= len(cursorShapes) {
+			shape = screen.CursorArrow
+		}
+		c.request(s.cursorShapeDevice, cursorShapeDeviceSetShape, serial, cursorShapes[shape])
+	default:
+		// TODO: draw the shape from the user's cursor theme, as
+		// libwayland-cursor does, for compositors without the cursor-shape
+		// protocol. Until then, the compositor decides what cursor to show.
+	}
+}
+
+// showCursorImage shows m on the cursor surface. The image must have been
+// copied by SetCursor, so that its rows are contiguous. It must be called
+// with s.mu held.
+func (s *screenImpl) showCursorImage(m *image.RGBA) error {
+	b, err := s.newShmBuffer(m.Rect.Size(), shmFormatARGB8888, s.handleCursorRelease)
+	if err != nil {
+		return err
+	}
+	// image.RGBA is already alpha-premultiplied, as ARGB8888 is, so only the
+	// byte order differs.
+	copy(b.data, m.Pix)
+	swizzle.BGRA(b.data)
+	b.busy = true
+
+	c := s.c
+	if s.cursorSurface == 0 {
+		s.cursorSurface = c.newObject(nil)
+		c.request(s.compositor, compositorCreateSurface, uint32(s.cursorSurface))
+	}
+	c.request(s.cursorSurface, surfaceAttach, uint32(b.id), 0, 0)
+	c.request(s.cursorSurface, surfaceDamage, 0, 0, uint32(b.size.X), uint32(b.size.Y))
+	c.request(s.cursorSurface, surfaceCommit)
+
+	// The previous buffer is destroyed once the compositor releases it.
+	if old := s.cursorBuffer; old != nil {
+		if old.busy {
+			old.stale = true
+		} else {
+			old.destroy(c)
+		}
+	}
+	s.cursorBuffer = b
+	return nil
+}
+
+// handleCursorRelease is called, in the readEvents goroutine, when the
+// compositor has finished reading a cursor buffer.
+func (s *screenImpl) handleCursorRelease(b *shmBuffer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if b.stale {
+		b.destroy(s.c)
+		return
+	}
+	b.busy = false
+}
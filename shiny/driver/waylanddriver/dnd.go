@@ -0,0 +1,265 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"syscall"
+
+	"golang.org/x/exp/shiny/driver/internal/dnd"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Drag-and-drop uses the seat's wl_data_device. The compositor announces the
+// data of each drag as a wl_data_offer, listing its MIME types, before the
+// drag enters one of the windows. Once dropped, the data of each type is read
+// from a pipe, written to by the drag's source.
+
+// dropTarget is a drag over one of the windows.
+type dropTarget struct {
+	w     *windowImpl
+	offer objectID
+	types []string
+	// x and y are the drag's most recent position.
+	x, y float32
+}
+
+// initDND gets the seat's wl_data_device, if the compositor has a
+// wl_data_device_manager. It is called by newScreenImpl.
+func (s *screenImpl) initDND() {
+	if s.dataDeviceManager == 0 || s.seat == 0 {
+		return
+	}
+	s.offers = map[objectID][]string{}
+	s.dataDevice = s.c.newObject(s.handleDataDevice)
+	s.c.request(s.dataDeviceManager, dataDeviceManagerGetDataDevice, uint32(s.dataDevice), uint32(s.seat))
+}
+
+func (s *screenImpl) handleDataDevice(opcode uint16, d *decoder) {
+	switch opcode {
+	case dataDeviceEventDataOffer:
+		offer := d.object()
+		if d.err != nil {
+			return
+		}
+		s.offers[offer] = nil
+		s.c.setHandler(offer, func(opcode uint16, d *decoder) {
+			if opcode != dataOfferEventOffer {
+				return
+			}
+			if typ := d.string(); d.err == nil {
+				s.offers[offer] = append(s.offers[offer], typ)
+			}
+		})
+
+	case dataDeviceEventEnter:
+		serial, surface, x, y, offer := d.uint(), d.object(), d.fixed(), d.fixed(), d.object()
+		if d.err != nil {
+			return
+		}
+		s.leaveDrag()
+		w := s.window(surface)
+		if w == nil || offer == 0 {
+			// A drag without an offer has no data.
+			s.destroyOffer(offer)
+			return
+		}
+		s.drag = dropTarget{w: w, offer: offer, types: s.offers[offer], x: x, y: y}
+		m := newMessage(offer, dataOfferAccept)
+		m.putUint(serial)
+		if len(s.drag.types) == 0 {
+			// A null MIME type does not accept the drop.
+			m.putUint(0)
+		} else {
+			m.putString(s.drag.types[0])
+		}
+		s.c.send(m)
+		if s.dataDeviceManagerVersion >= dataDeviceManagerActionsVersion {
+			s.c.request(offer, dataOfferSetActions,
+				dataDeviceManagerDNDActionCopy, dataDeviceManagerDNDActionCopy)
+		}
+		w.Send(screen.DragEvent{Type: screen.DragEnter, X: x, Y: y, Types: s.drag.types})
+
+	case dataDeviceEventLeave:
+		s.leaveDrag()
+
+	case dataDeviceEventMotion:
+		d.uint() // The time.
+		x, y := d.fixed(), d.fixed()
+		if d.err == nil && s.drag.w != nil {
+			s.drag.x, s.drag.y = x, y
+			s.drag.w.Send(screen.DragEvent{Type: screen.DragOver, X: x, Y: y, Types: s.drag.types})
+		}
+
+	case dataDeviceEventDrop:
+		t := s.drag
+		if t.w == nil {
+			return
+		}
+		// The compositor sends a wl_data_device.leave after the drop, which
+		// must not destroy the offer while its data is being received.
+		s.drag = dropTarget{}
+		delete(s.offers, t.offer)
+		// The drag's source may be one of our own windows, whose data is
+		// sent by the readEvents goroutine, so it is received by another
+		// goroutine.
+		go s.receiveDrop(t)
+
+	case dataDeviceEventSelection:
+		// The clipboard is not shared with other applications, so its
+		// offers are not used.
+		//
+		// TODO: read the clipboard's text from the offer.
+		if offer := d.object(); d.err == nil {
+			s.destroyOffer(offer)
+		}
+	}
+}
+
+// leaveDrag ends the drag, if any, over one of the windows, without a drop.
+// It must only be called from the readEvents goroutine.
+func (s *screenImpl) leaveDrag() {
+	t := s.drag
+	if t.w == nil {
+		return
+	}
+	s.drag = dropTarget{}
+	s.destroyOffer(t.offer)
+	t.w.Send(screen.DragEvent{Type: screen.DragLeave, Types: t.types})
+}
+
+// destroyOffer destroys a wl_data_offer, if it is not zero.
+func (s *screenImpl) destroyOffer(offer objectID) {
+	if offer == 0 {
+		return
+	}
+	delete(s.offers, offer)
+	s.c.request(offer, dataOfferDestroy)
+	s.c.forget(offer)
+}
+
+// receiveDrop receives the dropped data of t, and sends the Drop event. It
+// must not be called from the readEvents goroutine.
+func (s *screenImpl) receiveDrop(t dropTarget) {
+	data := map[string][]byte{}
+	for _, typ := range dnd.Wanted(t.types) {
+		b, err := s.receive(t.offer, typ)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		data[typ] = b
+	}
+	t.w.Send(screen.DragEvent{
+		Type:  screen.Drop,
+		X:     t.x,
+		Y:     t.y,
+		Types: t.types,
+		Data:  dnd.Decode(data),
+	})
+	if s.dataDeviceManagerVersion >= dataDeviceManagerActionsVersion {
+		s.c.request(t.offer, dataOfferFinish)
+	}
+	s.c.request(t.offer, dataOfferDestroy)
+	s.c.forget(t.offer)
+}
+
+// receive returns the data, of the given MIME type, of a wl_data_offer.
+func (s *screenImpl) receive(offer objectID, typ string) ([]byte, error) {
+	var p [2]int
+	if err := syscall.Pipe2(p[:], syscall.O_CLOEXEC); err != nil {
+		return nil, fmt.Errorf("waylanddriver: pipe failed: %v", err)
+	}
+	r := os.NewFile(uintptr(p[0]), "wl_data_offer")
+	defer r.Close()
+
+	m := newMessage(offer, dataOfferReceive)
+	m.putString(typ)
+	m.putFD(p[1])
+	err := s.c.send(m)
+	// The drag's source gets its own copy of the write end, and the pipe
+	// reaches its end once the source closes that copy.
+	syscall.Close(p[1])
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("waylanddriver: receiving %s failed: %v", typ, err)
+	}
+	return b, nil
+}
+
+// StartDrag offers data as a wl_data_source, and starts dragging it. The
+// compositor only starts a drag while a mouse button, pressed over the
+// window, is held down.
+func (w *windowImpl) StartDrag(data screen.DragData) error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+
+	s, c := w.s, w.s.c
+	if s.dataDevice == 0 {
+		return errors.New("waylanddriver: compositor has no wl_data_device_manager")
+	}
+	s.mu.Lock()
+	serial, surface := s.buttonSerial, s.buttonSurface
+	s.mu.Unlock()
+	if serial == 0 || surface != w.surface {
+		return errors.New("waylanddriver: no mouse button is held down over the window")
+	}
+
+	types, contents := dnd.Encode(data)
+	var source objectID
+	source = c.newObject(func(opcode uint16, d *decoder) {
+		switch opcode {
+		case dataSourceEventSend:
+			typ, fd := d.string(), d.fd()
+			if fd < 0 {
+				return
+			}
+			// Writing to the pipe blocks until it is read, so it must not
+			// hold up the readEvents goroutine.
+			go func() {
+				f := os.NewFile(uintptr(fd), "wl_data_source")
+				f.Write(contents[typ])
+				f.Close()
+			}()
+		case dataSourceEventCancelled, dataSourceEventDNDFinished:
+			c.request(source, dataSourceDestroy)
+		}
+	})
+
+	var err error
+	check := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+	check(c.request(s.dataDeviceManager, dataDeviceManagerCreateDataSource, uint32(source)))
+	for _, typ := range types {
+		m := newMessage(source, dataSourceOffer)
+		m.putString(typ)
+		check(c.send(m))
+	}
+	if s.dataDeviceManagerVersion >= dataDeviceManagerActionsVersion {
+		check(c.request(source, dataSourceSetActions, dataDeviceManagerDNDActionCopy))
+	}
+	// The drag has no icon surface.
+	check(c.request(s.dataDevice, dataDeviceStartDrag, uint32(source), uint32(w.surface), 0, serial))
+	if err != nil {
+		return fmt.Errorf("waylanddriver: starting a drag failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,471 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"log"
+	"syscall"
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/x11key"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/touch"
+)
+
+// These constants come from /usr/include/linux/input-event-codes.h
+const (
+	btnLeft    = 0x110
+	btnRight   = 0x111
+	btnMiddle  = 0x112
+	btnSide    = 0x113
+	btnExtra   = 0x114
+	btnForward = 0x115
+	btnBack    = 0x116
+)
+
+// wheelStep is how far, in wl_pointer.axis units, a mouse wheel scrolls per
+// click, for the compositors that this package has been tested on.
+const wheelStep = 10
+
+func (s *screenImpl) handleSeat(opcode uint16, d *decoder) {
+	if opcode != seatEventCapabilities {
+		return
+	}
+	caps := d.uint()
+	c := s.c
+	if caps&seatCapabilityPointer != 0 && s.pointer == 0 {
+		s.pointer = c.newObject(s.handlePointer)
+		c.request(s.seat, seatGetPointer, uint32(s.pointer))
+		if s.cursorShapeManager != 0 {
+			device := c.newObject(nil)
+			c.request(s.cursorShapeManager, cursorShapeManagerGetPointer, uint32(device), uint32(s.pointer))
+			s.mu.Lock()
+			s.cursorShapeDevice = device
+			s.mu.Unlock()
+		}
+		if s.relativePointerManager != 0 {
+			s.relativePointer = c.newObject(s.handleRelativePointer)
+			c.request(s.relativePointerManager, relativePointerManagerGetRelativePointer,
+				uint32(s.relativePointer), uint32(s.pointer))
+		}
+	}
+	if caps&seatCapabilityKeyboard != 0 && s.keyboard == 0 {
+		s.keyboard = c.newObject(s.handleKeyboard)
+		c.request(s.seat, seatGetKeyboard, uint32(s.keyboard))
+	}
+	if caps&seatCapabilityTouch != 0 && s.touch == 0 {
+		s.touch = c.newObject(s.handleTouch)
+		c.request(s.seat, seatGetTouch, uint32(s.touch))
+	}
+	// TODO: release the pointer, keyboard and touch if the seat loses them,
+	// and support drawing tablets, with zwp_tablet_manager_v2, sending
+	// screen.PenEvents.
+}
+
+func (s *screenImpl) handlePointer(opcode uint16, d *decoder) {
+	switch opcode {
+	case pointerEventEnter:
+		serial := d.uint()
+		s.pointerSurface = d.object()
+		s.pointerX, s.pointerY = d.fixed(), d.fixed()
+		s.mu.Lock()
+		s.enterSerial, s.enterSurface = serial, s.pointerSurface
+		s.mu.Unlock()
+		if w := s.window(s.pointerSurface); w != nil {
+			w.mu.Lock()
+			w.updateCursor()
+			w.mu.Unlock()
+			// Entering and leaving have no timestamp.
+			w.Send(screen.CrossingEvent{Entered: true, X: s.pointerX, Y: s.pointerY})
+		}
+
+	case pointerEventLeave:
+		if w := s.window(s.pointerSurface); w != nil {
+			w.Send(screen.CrossingEvent{X: s.pointerX, Y: s.pointerY})
+		}
+		s.pointerSurface = 0
+		s.mu.Lock()
+		s.enterSerial, s.enterSurface = 0, 0
+		s.mu.Unlock()
+
+	case pointerEventMotion:
+		s.inputTime = s.clock.Millis(d.uint())
+		s.pointerX, s.pointerY = d.fixed(), d.fixed()
+		if w := s.window(s.pointerSurface); w != nil && !w.isCaptured() {
+			w.handleMouse(s.pointerX, s.pointerY, mouse.ButtonNone, s.modifiers, mouse.DirNone)
+		}
+
+	case pointerEventButton:
+		serial := d.uint()
+		s.inputTime = s.clock.Millis(d.uint())
+		button, state := d.uint(), d.uint()
+		s.mu.Lock()
+		if state != 0 {
+			s.buttonSerial, s.buttonSurface = serial, s.pointerSurface
+		} else {
+			s.buttonSerial, s.buttonSurface = 0, 0
+		}
+		s.mu.Unlock()
+		var b mouse.Button
+		switch button {
+		case btnLeft:
+			b = mouse.ButtonLeft
+		case btnRight:
+			b = mouse.ButtonRight
+		case btnMiddle:
+			b = mouse.ButtonMiddle
+		case btnSide, btnBack:
+			b = screen.MouseButtonBack
+		case btnExtra, btnForward:
+			b = screen.MouseButtonForward
+		default:
+			return
+		}
+		dir := mouse.DirRelease
+		if state != 0 {
+			dir = mouse.DirPress
+		}
+		if w := s.window(s.pointerSurface); w != nil {
+			w.handleMouse(s.pointerX, s.pointerY, b, s.modifiers, dir)
+		}
+
+	case pointerEventAxis:
+		s.inputTime = s.clock.Millis(d.uint())
+		axis, value := d.uint(), d.fixed()
+		if axis > pointerAxisHorizontalScroll {
+			return
+		}
+		s.scroll.delta[axis] += value
+		s.scroll.moved = true
+		if s.seatVersion < pointerFrameVersion {
+			s.sendScroll()
+		}
+
+	case pointerEventFrame:
+		s.sendScroll()
+
+	case pointerEventAxisSource:
+		s.scroll.source, s.scroll.hasSource = d.uint(), true
+
+	case pointerEventAxisStop:
+		s.scroll.stopped = true
+	}
+}
+
+// pendingScroll is the scrolling of a wl_pointer.frame.
+type pendingScroll struct {
+	// delta is the frame's scrolling along each axis, in wl_pointer.axis
+	// units, which are positive down and right.
+	delta     [2]float32
+	moved     bool
+	stopped   bool
+	source    uint32
+	hasSource bool
+}
+
+// sendScroll sends the screen.ScrollEvent, and then the mouse.Events of any
+// whole wheel steps, of the current wl_pointer.frame's scrolling.
+func (s *screenImpl) sendScroll() {
+	sc := s.scroll
+	s.scroll = pendingScroll{}
+	if !sc.moved && !sc.stopped {
+		return
+	}
+	w := s.window(s.pointerSurface)
+	if w == nil || w.inputBlocked() {
+		s.pointerAxis = [2]float32{}
+		s.scrolling = false
+		return
+	}
+
+	e := screen.ScrollEvent{
+		X:         s.pointerX,
+		Y:         s.pointerY,
+		DeltaX:    sc.delta[pointerAxisHorizontalScroll] / wheelStep,
+		DeltaY:    sc.delta[pointerAxisVerticalScroll] / wheelStep,
+		Modifiers: x11key.KeyModifiers(s.modifiers),
+	}
+	if sc.hasSource {
+		switch sc.source {
+		case pointerAxisSourceWheel, pointerAxisSourceWheelTilt:
+			e.Device = screen.ScrollDeviceWheel
+		case pointerAxisSourceFinger, pointerAxisSourceContinuous:
+			e.Device = screen.ScrollDeviceTrackpad
+		}
+	}
+	// Only fingers are said to stop scrolling. Compositors leave kinetic
+	// scrolling to their clients, so there are no momentum phases.
+	if sc.stopped || (sc.hasSource && sc.source == pointerAxisSourceFinger) {
+		switch {
+		case sc.stopped:
+			e.Phase, s.scrolling = screen.ScrollEnd, false
+		case !s.scrolling:
+			e.Phase, s.scrolling = screen.ScrollBegin, true
+		default:
+			e.Phase = screen.ScrollChange
+		}
+	}
+	w.SendAt(e, s.inputTime)
+
+	for axis := range s.pointerAxis {
+		// Positive values scroll down, or right.
+		s.pointerAxis[axis] += sc.delta[axis]
+		for s.pointerAxis[axis] <= -wheelStep || s.pointerAxis[axis] >= wheelStep {
+			var b mouse.Button
+			switch {
+			case axis == pointerAxisVerticalScroll && s.pointerAxis[axis] < 0:
+				b, s.pointerAxis[axis] = mouse.ButtonWheelUp, s.pointerAxis[axis]+wheelStep
+			case axis == pointerAxisVerticalScroll:
+				b, s.pointerAxis[axis] = mouse.ButtonWheelDown, s.pointerAxis[axis]-wheelStep
+			case s.pointerAxis[axis] < 0:
+				b, s.pointerAxis[axis] = mouse.ButtonWheelLeft, s.pointerAxis[axis]+wheelStep
+			default:
+				b, s.pointerAxis[axis] = mouse.ButtonWheelRight, s.pointerAxis[axis]-wheelStep
+			}
+			w.handleMouse(s.pointerX, s.pointerY, b, s.modifiers, mouse.DirStep)
+		}
+	}
+}
+
+// touchPoint is a point touching a window's surface.
+type touchPoint struct {
+	surface objectID
+	x, y    float32
+}
+
+func (s *screenImpl) handleTouch(opcode uint16, d *decoder) {
+	switch opcode {
+	case touchEventDown:
+		d.uint() // The serial.
+		s.inputTime = s.clock.Millis(d.uint())
+		surface, id := d.object(), d.int()
+		p := touchPoint{surface: surface}
+		p.x, p.y = d.fixed(), d.fixed()
+		s.touches[id] = p
+		s.sendTouch(id, p, touch.TypeBegin)
+
+	case touchEventUp:
+		d.uint() // The serial.
+		s.inputTime = s.clock.Millis(d.uint())
+		id := d.int()
+		if p, ok := s.touches[id]; ok {
+			delete(s.touches, id)
+			s.sendTouch(id, p, touch.TypeEnd)
+		}
+
+	case touchEventMotion:
+		s.inputTime = s.clock.Millis(d.uint())
+		id := d.int()
+		p, ok := s.touches[id]
+		if !ok {
+			return
+		}
+		p.x, p.y = d.fixed(), d.fixed()
+		s.touches[id] = p
+		s.sendTouch(id, p, touch.TypeMove)
+
+	case touchEventCancel:
+		// The compositor took over the touches, such as for a gesture of
+		// its own, so they end where they last were.
+		s.inputTime = time.Now()
+		for id, p := range s.touches {
+			delete(s.touches, id)
+			s.sendTouch(id, p, touch.TypeEnd)
+		}
+	}
+}
+
+func (s *screenImpl) sendTouch(id int32, p touchPoint, typ touch.Type) {
+	if w := s.window(p.surface); w != nil && !w.inputBlocked() {
+		w.SendAt(touch.Event{
+			X:        p.x,
+			Y:        p.y,
+			Sequence: touch.Sequence(id),
+			Type:     typ,
+		}, s.inputTime)
+	}
+}
+
+func (s *screenImpl) handleKeyboard(opcode uint16, d *decoder) {
+	switch opcode {
+	case keyboardEventKeymap:
+		format, fd, size := d.uint(), d.fd(), d.uint()
+		if d.err != nil {
+			return
+		}
+		defer syscall.Close(fd)
+		if format != keyboardKeymapFormatXKBV1 {
+			return
+		}
+		// Since version 7 of wl_keyboard, the keymap must be mapped
+		// privately, but that also works for earlier versions.
+		b, err := syscall.Mmap(fd, 0, int(size), syscall.PROT_READ, syscall.MAP_PRIVATE)
+		if err != nil {
+			log.Printf("waylanddriver: mmap of keymap failed: %v", err)
+			return
+		}
+		defer syscall.Munmap(b)
+		// The keymap is NUL terminated.
+		for i, c := range b {
+			if c == 0 {
+				b = b[:i]
+				break
+			}
+		}
+		keysyms, err := parseKeymap(string(b))
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		s.keysyms = *keysyms
+		s.groupNames = groupNames(string(b))
+		s.updateLayout()
+
+	case keyboardEventEnter:
+		d.uint() // The serial.
+		s.keyboardSurface = d.object()
+		// TODO: decode the keys that are already pressed?
+		if w := s.window(s.keyboardSurface); w != nil {
+			w.lifecycler.SetFocused(true)
+			w.lifecycler.SendEvent(w, nil)
+		}
+
+	case keyboardEventLeave:
+		s.stopRepeat()
+		if w := s.window(s.keyboardSurface); w != nil {
+			w.lifecycler.SetFocused(false)
+			w.lifecycler.SendEvent(w, nil)
+		}
+		s.keyboardSurface = 0
+
+	case keyboardEventKey:
+		d.uint() // The serial.
+		s.inputTime = s.clock.Millis(d.uint())
+		k, state := d.uint(), d.uint()
+		w := s.window(s.keyboardSurface)
+		// XKB keycodes, like X11 keycodes, are the Linux evdev keycodes plus
+		// 8.
+		k += 8
+		if w == nil || k > 0xff {
+			return
+		}
+		if state == 0 {
+			if k == s.repeatKey {
+				s.stopRepeat()
+			}
+			w.handleKey(uint8(k), s.modifiers, key.DirRelease)
+			return
+		}
+		if w.inputBlocked() {
+			return
+		}
+		w.handleKey(uint8(k), s.modifiers, key.DirPress)
+		s.startRepeat(w, k)
+
+	case keyboardEventModifiers:
+		d.uint() // The serial.
+		depressed, latched, locked, group := d.uint(), d.uint(), d.uint(), d.uint()
+		// The XKB keymaps that compositors send have the eight core X11
+		// modifiers, in the same order as the X11 masks. As in X11 key
+		// events, the group is in bits 13 and 14.
+		s.modifiers = uint16(depressed|latched|locked)&0xff | uint16(group&3)<<13
+		s.updateLayout()
+
+	case keyboardEventRepeatInfo:
+		s.repeatRate, s.repeatDelay = d.int(), d.int()
+	}
+}
+
+// startRepeat starts repeating the key k, which was just pressed, until it is
+// released. Wayland clients, not the compositor, implement key repeat.
+func (s *screenImpl) startRepeat(w *windowImpl, k uint32) {
+	s.stopRepeat()
+	if s.repeatRate <= 0 || isModifierKey(s.keysyms[k][0]) {
+		return
+	}
+
+	// The repeats use the modifiers from when the key was pressed, and they
+	// are sent from the timer's goroutine, so compute the event here. As on
+	// Windows, a repeat is neither a press nor a release.
+	e := s.keyEvent(uint8(k), s.modifiers, key.DirNone)
+	delay := time.Duration(s.repeatDelay) * time.Millisecond
+	interval := time.Second / time.Duration(s.repeatRate)
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			w.Send(e)
+			t.Reset(interval)
+		}
+	}()
+	s.repeatKey, s.repeatDone = k, done
+}
+
+// keyEvent returns the event for the key k, with the modifiers and group of
+// the state. A key.DirNone event is an automatic repeat.
+func (s *screenImpl) keyEvent(k uint8, state uint16, dir key.Direction) screen.KeyEvent {
+	r, c := s.keysyms.Lookup(k, state)
+	return screen.KeyEvent{
+		Event: key.Event{
+			Rune:      r,
+			Code:      c,
+			Modifiers: x11key.KeyModifiers(state),
+			Direction: dir,
+		},
+		Physical:   x11key.PhysicalCode(k),
+		Scancode:   uint32(k),
+		Unmodified: s.keysyms.Unmodified(k, state),
+		Repeat:     dir == key.DirNone,
+	}
+}
+
+// updateLayout sends a screen.KeyboardLayoutEvent to the windows if the
+// name of the keymap's current group has changed.
+func (s *screenImpl) updateLayout() {
+	g := x11key.Group(s.modifiers)
+	if g >= len(s.groupNames) || s.groupNames[g] == "" || s.groupNames[g] == s.layout {
+		return
+	}
+	first := s.layout == ""
+	s.layout = s.groupNames[g]
+	if first {
+		// The first keymap is the initial layout, not a change.
+		return
+	}
+	s.mu.Lock()
+	windows := make([]*windowImpl, 0, len(s.windows))
+	for _, w := range s.windows {
+		windows = append(windows, w)
+	}
+	s.mu.Unlock()
+	for _, w := range windows {
+		w.Send(screen.KeyboardLayoutEvent{Layout: s.layout})
+	}
+}
+
+func (s *screenImpl) stopRepeat() {
+	if s.repeatDone != nil {
+		close(s.repeatDone)
+	}
+	s.repeatKey, s.repeatDone = 0, nil
+}
+
+// isModifierKey returns whether the keysym is one of the Shift, Control, Alt,
+// Super or similar keys, which do not repeat.
+func isModifierKey(keysym uint32) bool {
+	// These keysyms, from xkShiftL to xkHyperR in X11's keysymdef.h, are
+	// contiguous.
+	return 0xffe1 <= keysym && keysym <= 0xffee
+}
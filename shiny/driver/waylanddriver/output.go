@@ -0,0 +1,189 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"image"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// output is a wl_output global, which is a display.
+type output struct {
+	// name is the global's name in the registry.
+	name    uint32
+	id      objectID
+	version uint32
+
+	// pending holds the properties sent since the last wl_output.done event,
+	// and current holds those as of that event.
+	pending, current screen.Display
+	// makeModel is the "make model" of the output, which is its name for
+	// wl_output versions before 4.
+	makeModel       string
+	widthMM         int32
+	rotated         bool
+	pendingPosition image.Point
+	pendingSize     image.Point
+}
+
+// addOutput binds the wl_output global, with the given registry name and
+// version.
+func (s *screenImpl) addOutput(name, version uint32) {
+	o := &output{
+		name:    name,
+		version: version,
+		pending: screen.Display{Scale: 1},
+	}
+	o.id = s.c.newObject(func(opcode uint16, d *decoder) {
+		s.handleOutput(o, opcode, d)
+	})
+	m := newMessage(s.registry, registryBind)
+	m.putUint(name)
+	m.putString("wl_output")
+	m.putUint(version)
+	m.putObject(o.id)
+	if err := s.c.send(m); err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.outputs = append(s.outputs, o)
+	s.mu.Unlock()
+}
+
+// removeOutput handles a wl_registry.global_remove event for the output with
+// the given registry name, if there is one, such as when a monitor is
+// unplugged.
+func (s *screenImpl) removeOutput(name uint32) {
+	var o *output
+	s.mu.Lock()
+	for i, x := range s.outputs {
+		if x.name == name {
+			o = x
+			s.outputs = append(s.outputs[:i], s.outputs[i+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
+	if o == nil {
+		return
+	}
+	if o.version >= outputReleaseVersion {
+		s.c.request(o.id, outputRelease)
+	}
+	s.sendDisplays()
+}
+
+// handleOutput must only be called from the readEvents goroutine.
+func (s *screenImpl) handleOutput(o *output, opcode uint16, d *decoder) {
+	switch opcode {
+	case outputEventGeometry:
+		x, y, widthMM := d.int(), d.int(), d.int()
+		d.int() // The physical height.
+		d.int() // The subpixel orientation.
+		mk, model := d.string(), d.string()
+		transform := d.int()
+		if d.err != nil {
+			return
+		}
+		o.pendingPosition = image.Point{int(x), int(y)}
+		o.widthMM = widthMM
+		o.makeModel = mk + " " + model
+		// The odd transforms rotate the output by 90 or 270 degrees.
+		o.rotated = transform%2 == 1
+	case outputEventMode:
+		flags, width, height, refresh := d.uint(), d.int(), d.int(), d.int()
+		if d.err != nil || flags&outputModeCurrent == 0 {
+			return
+		}
+		o.pendingSize = image.Point{int(width), int(height)}
+		// The refresh rate is in millihertz.
+		o.pending.RefreshRate = float64(refresh) / 1000
+	case outputEventScale:
+		if f := d.int(); d.err == nil && f > 0 {
+			o.pending.Scale = float64(f)
+		}
+	case outputEventName:
+		if n := d.string(); d.err == nil {
+			o.pending.Name = n
+		}
+	case outputEventDone:
+	default:
+		return
+	}
+	// Before version 2, there is no wl_output.done event, so each event
+	// stands alone.
+	if o.version < outputDoneVersion || opcode == outputEventDone {
+		s.commitOutput(o)
+	}
+}
+
+// commitOutput applies o's pending properties, and tells the windows about
+// the change.
+func (s *screenImpl) commitOutput(o *output) {
+	d := o.pending
+	if d.Name == "" {
+		d.Name = o.makeModel
+	}
+	sz := o.pendingSize
+	if o.rotated {
+		sz.X, sz.Y = sz.Y, sz.X
+	}
+	d.Bounds = image.Rectangle{Min: o.pendingPosition, Max: o.pendingPosition.Add(sz)}
+	// Wayland clients cannot see panels and docks, so the work area is the
+	// whole output.
+	d.WorkArea = d.Bounds
+	d.DPI = 0
+	if o.widthMM > 0 {
+		const mmPerInch = 25.4
+		// The physical width is of the untransformed output.
+		d.DPI = float64(o.pendingSize.X) * mmPerInch / float64(o.widthMM)
+	}
+
+	s.mu.Lock()
+	o.current = d
+	s.mu.Unlock()
+	s.sendDisplays()
+}
+
+// sendDisplays sends a screen.DisplayEvent to every window.
+func (s *screenImpl) sendDisplays() {
+	displays := s.Displays()
+
+	s.mu.Lock()
+	windows := make([]*windowImpl, 0, len(s.windows))
+	for _, w := range s.windows {
+		windows = append(windows, w)
+	}
+	s.mu.Unlock()
+	for _, w := range windows {
+		w.Send(screen.DisplayEvent{Displays: append([]screen.Display(nil), displays...)})
+	}
+}
+
+// Displays returns the compositor's outputs. Wayland has no primary output,
+// so the first one that the compositor advertised is reported as primary.
+//
+// Wayland clients cannot position their windows, so each display's Bounds is
+// only a guide to how the outputs are arranged.
+func (s *screenImpl) Displays() []screen.Display {
+	var displays []screen.Display
+	s.mu.Lock()
+	for _, o := range s.outputs {
+		if o.current.Bounds.Empty() {
+			// The output's properties have yet to arrive.
+			continue
+		}
+		displays = append(displays, o.current)
+	}
+	s.mu.Unlock()
+
+	if len(displays) > 0 {
+		displays[0].Primary = true
+	}
+	return displays
+}
@@ -0,0 +1,63 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// SetPointerCapture locks the pointer in place, with the pointer-constraints
+// protocol, while it is over the window, and sends the motion reported by the
+// relative-pointer protocol. Compositors without those protocols cannot
+// capture the pointer, and only hide the cursor.
+func (w *windowImpl) SetPointerCapture(capture bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released || w.captured == capture {
+		return
+	}
+	w.captured = capture
+
+	s, c := w.s, w.s.c
+	switch {
+	case capture && s.pointerConstraints != 0 && s.pointer != 0:
+		w.lockedPointer = c.newObject(nil)
+		c.request(s.pointerConstraints, pointerConstraintsLockPointer, uint32(w.lockedPointer),
+			uint32(w.surface), uint32(s.pointer), 0, pointerConstraintsLifetimePersistent)
+	case !capture && w.lockedPointer != 0:
+		c.request(w.lockedPointer, lockedPointerDestroy)
+		w.lockedPointer = 0
+	}
+	w.updateCursor()
+}
+
+func (w *windowImpl) isCaptured() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.captured
+}
+
+func (s *screenImpl) handleRelativePointer(opcode uint16, d *decoder) {
+	if opcode != relativePointerEventRelativeMotion {
+		return
+	}
+	// The time, in microseconds, is split into its high and low 32 bits.
+	hi, lo := d.uint(), d.uint()
+	// The accelerated motion, in surface co-ordinates, is followed by the
+	// unaccelerated motion, which is in no particular units.
+	dx, dy := d.fixed(), d.fixed()
+	if d.err != nil {
+		return
+	}
+	t := s.clock.Time(time.Duration(uint64(hi)<<32|uint64(lo)) * time.Microsecond)
+	if w := s.window(s.pointerSurface); w != nil && w.isCaptured() && !w.inputBlocked() {
+		w.SendAt(screen.RelativeMouseEvent{DeltaX: dx, DeltaY: dy}, t)
+	}
+}
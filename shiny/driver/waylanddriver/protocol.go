@@ -0,0 +1,253 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+// These request and event opcodes come from the core Wayland protocol,
+// wayland.xml, from the stable xdg-shell protocol, xdg-shell.xml, from the
+// staging cursor-shape protocol, cursor-shape-v1.xml, and from the unstable
+// relative-pointer-unstable-v1.xml and pointer-constraints-unstable-v1.xml
+// protocols, in the wayland-protocols repository. An interface's opcodes are its requests' (or
+// events') indexes, in the order that the XML file lists them.
+
+// wl_display
+const (
+	displaySync        = 0
+	displayGetRegistry = 1
+
+	displayEventError    = 0
+	displayEventDeleteID = 1
+)
+
+// wl_registry
+const (
+	registryBind = 0
+
+	registryEventGlobal       = 0
+	registryEventGlobalRemove = 1
+)
+
+// wl_callback
+const (
+	callbackEventDone = 0
+)
+
+// wl_compositor and wl_region
+const (
+	compositorCreateSurface = 0
+	compositorCreateRegion  = 1
+
+	regionDestroy = 0
+	regionAdd     = 1
+)
+
+// wl_shm, wl_shm_pool and wl_buffer
+const (
+	shmCreatePool = 0
+
+	shmPoolCreateBuffer = 0
+	shmPoolDestroy      = 1
+
+	bufferDestroy = 0
+
+	bufferEventRelease = 0
+
+	// shmFormatXRGB8888 is a 32-bit format whose bytes, on a little-endian
+	// host, are blue, green, red and an ignored byte. It, and ARGB8888, whose
+	// last byte is alpha, are the two formats that every compositor must
+	// support. ARGB8888's colors are alpha-premultiplied.
+	shmFormatARGB8888 = 0
+	shmFormatXRGB8888 = 1
+)
+
+// wl_surface
+const (
+	surfaceDestroy        = 0
+	surfaceAttach         = 1
+	surfaceDamage         = 2
+	surfaceFrame          = 3
+	surfaceSetInputRegion = 5
+	surfaceCommit         = 6
+	surfaceDamageBuffer   = 9
+
+	// surfaceDamageBufferVersion is the wl_compositor version that introduced
+	// the wl_surface.damage_buffer request.
+	surfaceDamageBufferVersion = 4
+)
+
+// wl_seat, wl_pointer, wl_keyboard and wl_touch
+const (
+	seatGetPointer  = 0
+	seatGetKeyboard = 1
+	seatGetTouch    = 2
+
+	seatEventCapabilities = 0
+
+	seatCapabilityPointer  = 1
+	seatCapabilityKeyboard = 2
+	seatCapabilityTouch    = 4
+
+	pointerSetCursor = 0
+
+	pointerEventEnter      = 0
+	pointerEventLeave      = 1
+	pointerEventMotion     = 2
+	pointerEventButton     = 3
+	pointerEventAxis       = 4
+	pointerEventFrame      = 5
+	pointerEventAxisSource = 6
+	pointerEventAxisStop   = 7
+
+	pointerAxisVerticalScroll   = 0
+	pointerAxisHorizontalScroll = 1
+
+	pointerAxisSourceWheel      = 0
+	pointerAxisSourceFinger     = 1
+	pointerAxisSourceContinuous = 2
+	pointerAxisSourceWheelTilt  = 3
+
+	// pointerFrameVersion is the wl_seat version that introduced the
+	// wl_pointer.frame, axis_source and axis_stop events.
+	pointerFrameVersion = 5
+
+	keyboardEventKeymap     = 0
+	keyboardEventEnter      = 1
+	keyboardEventLeave      = 2
+	keyboardEventKey        = 3
+	keyboardEventModifiers  = 4
+	keyboardEventRepeatInfo = 5
+
+	keyboardKeymapFormatXKBV1 = 1
+
+	touchEventDown   = 0
+	touchEventUp     = 1
+	touchEventMotion = 2
+	touchEventCancel = 4
+)
+
+// wl_output
+const (
+	outputRelease = 0
+
+	outputEventGeometry = 0
+	outputEventMode     = 1
+	outputEventDone     = 2
+	outputEventScale    = 3
+	outputEventName     = 4
+
+	outputModeCurrent = 1
+
+	// outputDoneVersion and outputReleaseVersion are the wl_output versions
+	// that introduced the wl_output.done event and the wl_output.release
+	// request.
+	outputDoneVersion    = 2
+	outputReleaseVersion = 3
+)
+
+// wl_data_device_manager, wl_data_device, wl_data_source and wl_data_offer
+const (
+	dataDeviceManagerCreateDataSource = 0
+	dataDeviceManagerGetDataDevice    = 1
+
+	dataDeviceManagerDNDActionCopy = 1
+
+	// dataDeviceManagerActionsVersion is the wl_data_device_manager version
+	// that introduced drag-and-drop actions and the wl_data_offer.finish
+	// request.
+	dataDeviceManagerActionsVersion = 3
+
+	dataDeviceStartDrag = 0
+
+	dataDeviceEventDataOffer = 0
+	dataDeviceEventEnter     = 1
+	dataDeviceEventLeave     = 2
+	dataDeviceEventMotion    = 3
+	dataDeviceEventDrop      = 4
+	dataDeviceEventSelection = 5
+
+	dataSourceOffer      = 0
+	dataSourceDestroy    = 1
+	dataSourceSetActions = 2
+
+	dataSourceEventSend        = 1
+	dataSourceEventCancelled   = 2
+	dataSourceEventDNDFinished = 4
+
+	dataOfferAccept     = 0
+	dataOfferReceive    = 1
+	dataOfferDestroy    = 2
+	dataOfferFinish     = 3
+	dataOfferSetActions = 4
+
+	dataOfferEventOffer = 0
+)
+
+// xdg_wm_base, xdg_surface and xdg_toplevel
+const (
+	wmBaseGetXDGSurface = 2
+	wmBasePong          = 3
+
+	wmBaseEventPing = 0
+
+	xdgSurfaceDestroy      = 0
+	xdgSurfaceGetToplevel  = 1
+	xdgSurfaceAckConfigure = 4
+
+	xdgSurfaceEventConfigure = 0
+
+	toplevelDestroy         = 0
+	toplevelSetParent       = 1
+	toplevelSetTitle        = 2
+	toplevelMove            = 5
+	toplevelResize          = 6
+	toplevelSetMaxSize      = 7
+	toplevelSetMinSize      = 8
+	toplevelSetMaximized    = 9
+	toplevelUnsetMaximized  = 10
+	toplevelSetFullscreen   = 11
+	toplevelUnsetFullscreen = 12
+	toplevelSetMinimized    = 13
+
+	toplevelEventConfigure = 0
+	toplevelEventClose     = 1
+
+	toplevelStateMaximized  = 1
+	toplevelStateFullscreen = 2
+
+	toplevelResizeEdgeTop         = 1
+	toplevelResizeEdgeBottom      = 2
+	toplevelResizeEdgeLeft        = 4
+	toplevelResizeEdgeTopLeft     = 5
+	toplevelResizeEdgeBottomLeft  = 6
+	toplevelResizeEdgeRight       = 8
+	toplevelResizeEdgeTopRight    = 9
+	toplevelResizeEdgeBottomRight = 10
+)
+
+// wp_cursor_shape_manager_v1 and wp_cursor_shape_device_v1
+const (
+	cursorShapeManagerGetPointer = 1
+
+	cursorShapeDeviceSetShape = 1
+)
+
+// zwp_relative_pointer_manager_v1 and zwp_relative_pointer_v1
+const (
+	relativePointerManagerGetRelativePointer = 1
+
+	relativePointerEventRelativeMotion = 0
+)
+
+// zwp_pointer_constraints_v1 and zwp_locked_pointer_v1
+const (
+	pointerConstraintsLockPointer = 1
+
+	// pointerConstraintsLifetimePersistent means that a lock, deactivated
+	// when the pointer leaves the surface, is reactivated when it returns.
+	pointerConstraintsLifetimePersistent = 2
+
+	lockedPointerDestroy = 0
+)
@@ -0,0 +1,463 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/swtexture"
+	"golang.org/x/exp/shiny/driver/internal/x11key"
+	"golang.org/x/exp/shiny/screen"
+)
+
+type screenImpl struct {
+	swtexture.Allocator
+
+	c *conn
+
+	// These global objects are bound by newScreenImpl, in the readEvents
+	// goroutine, and are immutable afterwards.
+	registry          objectID
+	compositor        objectID
+	compositorVersion uint32
+	shm               objectID
+	wmBase            objectID
+	seat              objectID
+	seatVersion       uint32
+	// cursorShapeManager is the wp_cursor_shape_manager_v1, or zero if the
+	// compositor does not have one. Likewise for the
+	// zwp_relative_pointer_manager_v1 and the zwp_pointer_constraints_v1.
+	cursorShapeManager     objectID
+	relativePointerManager objectID
+	pointerConstraints     objectID
+	// dataDeviceManager is the wl_data_device_manager, or zero, and
+	// dataDevice is the seat's wl_data_device, got by newScreenImpl.
+	dataDeviceManager        objectID
+	dataDeviceManagerVersion uint32
+	dataDevice               objectID
+
+	// This next group of variables are mutable, but are only modified in the
+	// readEvents goroutine.
+	pointer         objectID
+	relativePointer objectID
+	keyboard        objectID
+	touch           objectID
+	pointerSurface  objectID
+	pointerX        float32
+	pointerY        float32
+	// pointerAxis accumulates smooth scrolling, such as from a touchpad,
+	// until it adds up to a whole mouse wheel step.
+	pointerAxis [2]float32
+	// scroll is the scrolling of the current wl_pointer.frame, sent as a
+	// screen.ScrollEvent at the end of the frame. scrolling is whether a
+	// scroll gesture of fingers on a touchpad has begun and not yet ended.
+	scroll          pendingScroll
+	scrolling       bool
+	keyboardSurface objectID
+	keysyms         x11key.KeysymTable
+	// modifiers are the X11 modifier masks, and the XKB group, of the
+	// keyboard. groupNames are the names of the keymap's groups, and layout
+	// is the name of the group that the windows were last told of.
+	modifiers   uint16
+	groupNames  []string
+	layout      string
+	repeatRate  int32
+	repeatDelay int32
+	// repeatKey is the key, if any, that repeats while it is held down.
+	// Closing repeatDone stops the repeats.
+	repeatKey  uint32
+	repeatDone chan struct{}
+	// touches are the points, keyed by their wl_touch IDs, that are
+	// touching the windows.
+	touches map[int32]touchPoint
+	// clock converts the times of input events, and inputTime is the time
+	// of the input event being handled.
+	clock     event.Clock
+	inputTime time.Time
+	// offers are the MIME types of each wl_data_offer. drag is the drag, if
+	// any, over one of the windows.
+	offers map[objectID][]string
+	drag   dropTarget
+
+	mu                   sync.Mutex
+	defaultWindowOptions *screen.NewWindowOptions
+	windows              map[objectID]*windowImpl
+	// outputs are in the order that the compositor advertised them. They
+	// are only added or removed in the readEvents goroutine.
+	outputs []*output
+	// enterSerial and enterSurface are the serial and the surface of the
+	// most recent wl_pointer.enter event, or zero after the pointer leaves.
+	// The cursor can only be set with that event's serial, while the
+	// pointer is over the surface.
+	enterSerial  uint32
+	enterSurface objectID
+	// buttonSerial and buttonSurface are the serial and the surface of the
+	// most recent wl_pointer.button press, or zero after its release. A drag
+	// can only be started with that event's serial.
+	buttonSerial  uint32
+	buttonSurface objectID
+	// cursorShapeDevice is the pointer's wp_cursor_shape_device_v1, or zero.
+	// cursorSurface and cursorBuffer show the image of a custom cursor.
+	cursorShapeDevice objectID
+	cursorSurface     objectID
+	cursorBuffer      *shmBuffer
+
+	// frames are the windows, hidden or not yet configured, waiting for a
+	// screen.FrameEvent that the compositor will never send them.
+	frames frame.Requests
+
+	clipboard clipboardImpl
+}
+
+func newScreenImpl(c *conn) (*screenImpl, error) {
+	s := &screenImpl{
+		c:       c,
+		windows: map[objectID]*windowImpl{},
+		touches: map[int32]touchPoint{},
+		// These are the defaults, in key repeats per second and in
+		// milliseconds, if the compositor does not send a
+		// wl_keyboard.repeat_info event.
+		repeatRate:  25,
+		repeatDelay: 600,
+	}
+	c.setHandler(displayID, s.handleDisplay)
+	go s.run()
+
+	s.registry = c.newObject(s.handleRegistry)
+	if err := c.request(displayID, displayGetRegistry, uint32(s.registry)); err != nil {
+		return nil, err
+	}
+	// The first roundtrip receives the globals, which handleRegistry binds.
+	// The second receives the events, such as the seat's capabilities, sent
+	// in response to binding them.
+	for i := 0; i < 2; i++ {
+		if err := c.roundtrip(); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case s.compositor == 0:
+		return nil, errors.New("waylanddriver: compositor has no wl_compositor")
+	case s.shm == 0:
+		return nil, errors.New("waylanddriver: compositor has no wl_shm")
+	case s.wmBase == 0:
+		return nil, errors.New("waylanddriver: compositor has no xdg_wm_base")
+	}
+	s.initDND()
+	return s, nil
+}
+
+func (s *screenImpl) run() {
+	err := s.c.readEvents()
+	log.Print(err)
+
+	// The connection is gone, and so are the windows.
+	s.mu.Lock()
+	windows := make([]*windowImpl, 0, len(s.windows))
+	for _, w := range s.windows {
+		windows = append(windows, w)
+	}
+	s.mu.Unlock()
+	for _, w := range windows {
+		w.lifecycler.SetDead(true)
+		w.lifecycler.SendEvent(w, nil)
+	}
+}
+
+func (s *screenImpl) handleDisplay(opcode uint16, d *decoder) {
+	switch opcode {
+	case displayEventError:
+		id, code, msg := d.object(), d.uint(), d.string()
+		// The compositor closes the connection after a protocol error, so
+		// there is nothing else to do here.
+		log.Printf("waylanddriver: protocol error on object %d: code %d: %s", id, code, msg)
+	case displayEventDeleteID:
+		s.c.deleteID(objectID(d.uint()))
+	}
+}
+
+// globalVersions are the highest versions of each global interface that this
+// package knows how to use.
+var globalVersions = map[string]uint32{
+	"wl_compositor": 4,
+	"wl_output":     4,
+	"wl_shm":        1,
+	"wl_seat":       5,
+	"xdg_wm_base":   1,
+
+	"wp_cursor_shape_manager_v1":      1,
+	"zwp_relative_pointer_manager_v1": 1,
+	"zwp_pointer_constraints_v1":      1,
+	"wl_data_device_manager":          3,
+}
+
+func (s *screenImpl) handleRegistry(opcode uint16, d *decoder) {
+	if opcode == registryEventGlobalRemove {
+		// TODO: handle other globals being removed, such as a seat.
+		if name := d.uint(); d.err == nil {
+			s.removeOutput(name)
+		}
+		return
+	}
+	if opcode != registryEventGlobal {
+		return
+	}
+	name, iface, version := d.uint(), d.string(), d.uint()
+	if d.err != nil {
+		return
+	}
+	maxVersion, ok := globalVersions[iface]
+	if !ok {
+		return
+	}
+	if version > maxVersion {
+		version = maxVersion
+	}
+	if iface == "wl_output" {
+		// There can be many outputs, and each has its own state.
+		s.addOutput(name, version)
+		return
+	}
+
+	var h handler
+	switch iface {
+	case "wl_compositor":
+		if s.compositor != 0 {
+			return
+		}
+	case "wl_shm":
+		if s.shm != 0 {
+			return
+		}
+	case "wl_seat":
+		// TODO: support multiple seats.
+		if s.seat != 0 {
+			return
+		}
+		h = s.handleSeat
+	case "xdg_wm_base":
+		if s.wmBase != 0 {
+			return
+		}
+		h = s.handleWMBase
+	case "wp_cursor_shape_manager_v1":
+		if s.cursorShapeManager != 0 {
+			return
+		}
+	case "zwp_relative_pointer_manager_v1":
+		if s.relativePointerManager != 0 {
+			return
+		}
+	case "zwp_pointer_constraints_v1":
+		if s.pointerConstraints != 0 {
+			return
+		}
+	case "wl_data_device_manager":
+		if s.dataDeviceManager != 0 {
+			return
+		}
+	}
+
+	id := s.c.newObject(h)
+	m := newMessage(s.registry, registryBind)
+	m.putUint(name)
+	// The new_id argument is untyped, so it is preceded by the interface
+	// name and version.
+	m.putString(iface)
+	m.putUint(version)
+	m.putObject(id)
+	if err := s.c.send(m); err != nil {
+		return
+	}
+
+	switch iface {
+	case "wl_compositor":
+		s.compositor, s.compositorVersion = id, version
+	case "wl_shm":
+		s.shm = id
+	case "wl_seat":
+		s.seat, s.seatVersion = id, version
+	case "xdg_wm_base":
+		s.wmBase = id
+	case "wp_cursor_shape_manager_v1":
+		s.cursorShapeManager = id
+	case "zwp_relative_pointer_manager_v1":
+		s.relativePointerManager = id
+	case "zwp_pointer_constraints_v1":
+		s.pointerConstraints = id
+	case "wl_data_device_manager":
+		s.dataDeviceManager, s.dataDeviceManagerVersion = id, version
+	}
+}
+
+func (s *screenImpl) handleWMBase(opcode uint16, d *decoder) {
+	if opcode == wmBaseEventPing {
+		// The compositor pings to check that the client is still responsive.
+		s.c.request(s.wmBase, wmBasePong, d.uint())
+	}
+}
+
+func (s *screenImpl) NewTexture(size image.Point) (screen.Texture, error) {
+	return s.NewTextureWithOptions(size, nil)
+}
+
+func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) {
+	s.mu.Lock()
+	opts = opts.WithDefaults(s.defaultWindowOptions)
+	s.mu.Unlock()
+
+	width, height := 1024, 768
+	if opts != nil {
+		if opts.Width > 0 {
+			width = opts.Width
+		}
+		if opts.Height > 0 {
+			height = opts.Height
+		}
+		// Wayland clients cannot position their own windows, so
+		// opts.Position is ignored.
+	}
+
+	w := &windowImpl{
+		s:    s,
+		back: image.NewRGBA(image.Rect(0, 0, width, height)),
+	}
+	if opts != nil {
+		w.fixedSize = opts.FixedSize
+		w.interceptClose = opts.InterceptClose
+		w.hidden = opts.Hidden
+		w.Priority = opts.EventPriority
+		w.KeepAllMoves = opts.KeepAllMoves
+		w.KeyEvents = opts.KeyEvents
+		w.Queues = opts.EventQueues
+		w.clip.Linear = opts.LinearBlending
+		if len(opts.Shape) > 0 {
+			w.shape = append([]image.Rectangle(nil), opts.Shape...)
+		}
+		w.transparent = opts.Transparent || w.shape != nil
+	}
+	var owner *windowImpl
+	if opts != nil {
+		owner, _ = opts.Owner.(*windowImpl)
+	}
+	if owner != nil && opts.Modal {
+		w.modalOwner = owner
+		atomic.AddInt32(&owner.modalChildren, 1)
+	}
+
+	c := s.c
+	w.surface = c.newObject(nil)
+	w.xdgSurface = c.newObject(w.handleXDGSurface)
+	w.toplevel = c.newObject(w.handleToplevel)
+
+	// Register the window before creating its surface, so that the
+	// readEvents goroutine can find it.
+	s.mu.Lock()
+	s.windows[w.surface] = w
+	s.mu.Unlock()
+
+	var err error
+	check := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+	check(c.request(s.compositor, compositorCreateSurface, uint32(w.surface)))
+	check(c.request(s.wmBase, wmBaseGetXDGSurface, uint32(w.xdgSurface), uint32(w.surface)))
+	check(c.request(w.xdgSurface, xdgSurfaceGetToplevel, uint32(w.toplevel)))
+	if owner != nil {
+		check(c.request(w.toplevel, toplevelSetParent, uint32(owner.toplevel)))
+	}
+	check(w.setTitle(opts.GetTitle()))
+	if w.shape != nil {
+		check(w.setInputRegion())
+	}
+	if w.fixedSize {
+		check(w.setSizeHints(width, height))
+	}
+	// The initial commit, without a buffer, asks the compositor to
+	// configure the window. The window is not mapped, or shown, until it is
+	// first published after that.
+	check(c.request(w.surface, surfaceCommit))
+	if err != nil {
+		w.Release()
+		return nil, fmt.Errorf("waylanddriver: creating window failed: %v", err)
+	}
+	return w, nil
+}
+
+func (s *screenImpl) SetDefaultWindowOptions(opts *screen.NewWindowOptions) {
+	opts = opts.WithDefaults(nil)
+
+	s.mu.Lock()
+	s.defaultWindowOptions = opts
+	s.mu.Unlock()
+}
+
+// AccessibilityPrefs returns zero.
+//
+// TODO: read the preferences from the XDG desktop portal's Settings
+// interface, over D-Bus.
+func (s *screenImpl) AccessibilityPrefs() screen.AccessibilityPrefs {
+	return 0
+}
+
+// ColorScheme returns screen.LightColorScheme.
+//
+// TODO: read the color-scheme setting from the XDG desktop portal's Settings
+// interface, over D-Bus, as for AccessibilityPrefs.
+func (s *screenImpl) ColorScheme() screen.ColorScheme {
+	return screen.LightColorScheme
+}
+
+// Clipboard returns an in-memory clipboard, private to this Screen.
+//
+// TODO: share the clipboard with other applications, via the
+// wl_data_device_manager interface.
+func (s *screenImpl) Clipboard() screen.Clipboard {
+	return &s.clipboard
+}
+
+// NewTrayIcon returns an error, as Wayland has no system tray protocol of its
+// own.
+//
+// TODO: implement the StatusNotifierItem interface, over D-Bus.
+func (s *screenImpl) NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return nil, errors.New("waylanddriver: tray icons are not implemented")
+}
+
+func (s *screenImpl) window(surface objectID) *windowImpl {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.windows[surface]
+}
+
+type clipboardImpl struct {
+	mu   sync.Mutex
+	text string
+}
+
+func (c *clipboardImpl) ReadText() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.text, nil
+}
+
+func (c *clipboardImpl) WriteText(text string) error {
+	c.mu.Lock()
+	c.text = text
+	c.mu.Unlock()
+	return nil
+}
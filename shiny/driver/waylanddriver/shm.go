@@ -0,0 +1,98 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"syscall"
+)
+
+// shmBuffer is a wl_buffer whose pixels are in memory shared with the
+// compositor.
+type shmBuffer struct {
+	id   objectID
+	size image.Point
+	// data holds the pixels, in a 32-bit format such as shmFormatXRGB8888,
+	// with a stride of 4*size.X bytes.
+	data []byte
+
+	// busy is whether the compositor is reading the buffer, from when it is
+	// attached to a surface and committed until the compositor releases it.
+	// The client must not modify the buffer's pixels while it is busy.
+	busy bool
+	// stale is whether the buffer is no longer the window's size, and should
+	// be destroyed when the compositor releases it.
+	stale bool
+}
+
+// newShmBuffer returns a new buffer of the given size and format. onRelease is
+// called, in the readEvents goroutine, when the compositor releases the
+// buffer.
+func (s *screenImpl) newShmBuffer(size image.Point, format uint32, onRelease func(b *shmBuffer)) (*shmBuffer, error) {
+	stride := 4 * size.X
+	n := stride * size.Y
+
+	// The buffer's memory is a temporary file, in the XDG_RUNTIME_DIR
+	// directory, which is typically a tmpfs, and so never written to disk.
+	// The file is removed straight away, and it lives on only as long as the
+	// file descriptors and mappings do.
+	f, err := ioutil.TempFile(os.Getenv("XDG_RUNTIME_DIR"), "shiny-shm-")
+	if err != nil {
+		return nil, fmt.Errorf("waylanddriver: creating shared memory failed: %v", err)
+	}
+	os.Remove(f.Name())
+	defer f.Close()
+	if err := f.Truncate(int64(n)); err != nil {
+		return nil, fmt.Errorf("waylanddriver: creating shared memory failed: %v", err)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, n, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("waylanddriver: mmap failed: %v", err)
+	}
+
+	b := &shmBuffer{
+		size: size,
+		data: data,
+	}
+	c := s.c
+	pool := c.newObject(nil)
+	b.id = c.newObject(func(opcode uint16, d *decoder) {
+		if opcode == bufferEventRelease {
+			onRelease(b)
+		}
+	})
+
+	// The compositor receives its own copy of the file descriptor, so that f
+	// can be closed as soon as the wl_shm_pool is created. Likewise, the
+	// wl_buffer keeps its memory alive after the pool is destroyed.
+	m := newMessage(s.shm, shmCreatePool)
+	m.putObject(pool)
+	m.putFD(int(f.Fd()))
+	m.putInt(int32(n))
+	err = c.send(m)
+	if err == nil {
+		err = c.request(pool, shmPoolCreateBuffer, uint32(b.id), 0,
+			uint32(size.X), uint32(size.Y), uint32(stride), format)
+	}
+	if err == nil {
+		err = c.request(pool, shmPoolDestroy)
+	}
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *shmBuffer) destroy(c *conn) {
+	c.request(b.id, bufferDestroy)
+	syscall.Munmap(b.data)
+	b.data = nil
+}
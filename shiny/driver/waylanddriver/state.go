@@ -0,0 +1,196 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Minimize asks the compositor to minimize the window. The xdg-shell protocol
+// does not report whether a window is minimized, so no WindowStateEvent is
+// sent for it.
+func (w *windowImpl) Minimize() {
+	w.toplevelRequest(toplevelSetMinimized)
+}
+
+func (w *windowImpl) Maximize() {
+	w.toplevelRequest(toplevelUnsetFullscreen)
+	w.toplevelRequest(toplevelSetMaximized)
+}
+
+// Restore un-maximizes the window and leaves fullscreen. A minimized window
+// cannot be restored by its client: the compositor restores it when the user
+// activates it.
+func (w *windowImpl) Restore() {
+	w.toplevelRequest(toplevelUnsetFullscreen)
+	w.toplevelRequest(toplevelUnsetMaximized)
+}
+
+// SetFullscreen asks the compositor to make the window fullscreen, on the
+// output of its choice. Wayland compositors decide for themselves whether to
+// scan out a fullscreen surface directly, so FullscreenExclusive is the same
+// as FullscreenBorderless.
+func (w *windowImpl) SetFullscreen(mode screen.FullscreenMode) {
+	if mode == screen.FullscreenNone {
+		w.toplevelRequest(toplevelUnsetFullscreen)
+		return
+	}
+	// A null wl_output lets the compositor choose the output.
+	w.toplevelRequest(toplevelSetFullscreen, 0)
+}
+
+// SetAlwaysOnTop, SetOpacity, Raise and Lower do nothing, as xdg-shell leaves
+// the stacking and opacity of windows to the compositor.
+func (w *windowImpl) SetAlwaysOnTop(onTop bool)  {}
+func (w *windowImpl) SetOpacity(opacity float32) {}
+func (w *windowImpl) Raise()                     {}
+func (w *windowImpl) Lower()                     {}
+
+// toplevelResizeEdges are the xdg_toplevel.resize edges, indexed by
+// screen.WindowEdge.
+var toplevelResizeEdges = [...]uint32{
+	screen.WindowEdgeTop:         toplevelResizeEdgeTop,
+	screen.WindowEdgeBottom:      toplevelResizeEdgeBottom,
+	screen.WindowEdgeLeft:        toplevelResizeEdgeLeft,
+	screen.WindowEdgeRight:       toplevelResizeEdgeRight,
+	screen.WindowEdgeTopLeft:     toplevelResizeEdgeTopLeft,
+	screen.WindowEdgeTopRight:    toplevelResizeEdgeTopRight,
+	screen.WindowEdgeBottomLeft:  toplevelResizeEdgeBottomLeft,
+	screen.WindowEdgeBottomRight: toplevelResizeEdgeBottomRight,
+}
+
+// StartMove asks the compositor to move the window. Like StartDrag, it needs
+// the serial of a mouse button press over the window that is still held down.
+func (w *windowImpl) StartMove() error {
+	return w.startMoveResize(toplevelMove)
+}
+
+func (w *windowImpl) StartResize(edge screen.WindowEdge) error {
+	if int(edge) >= len(toplevelResizeEdges) {
+		return fmt.Errorf("waylanddriver: StartResize with invalid edge %d", edge)
+	}
+	return w.startMoveResize(toplevelResize, toplevelResizeEdges[edge])
+}
+
+// SetSizeConstraints asks the compositor to keep the window's size within
+// min and max, in which the xdg-shell protocol, too, means a zero width or
+// height to be unconstrained. Like a new size, the constraints take effect
+// when the window is next published.
+func (w *windowImpl) SetSizeConstraints(min, max image.Point) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released || w.fixedSize {
+		return
+	}
+	c := w.s.c
+	c.request(w.toplevel, toplevelSetMinSize, nonNegative(min.X), nonNegative(min.Y))
+	c.request(w.toplevel, toplevelSetMaxSize, nonNegative(max.X), nonNegative(max.Y))
+}
+
+func nonNegative(x int) uint32 {
+	if x < 0 {
+		return 0
+	}
+	return uint32(x)
+}
+
+// SetAspectRatio makes the window, while it is neither maximized nor
+// fullscreen, take the largest size of that aspect ratio that fits the size
+// that each xdg_toplevel.configure event proposes. See fitAspect.
+func (w *windowImpl) SetAspectRatio(aspect image.Point) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released || w.fixedSize {
+		return
+	}
+	if aspect.X <= 0 || aspect.Y <= 0 {
+		aspect = image.Point{}
+	}
+	w.aspect = aspect
+}
+
+// fitAspect returns the largest size, no larger than sz, whose ratio of width
+// to height is that of aspect, or sz if aspect is zero. The xdg-shell
+// protocol lets a client choose a smaller size than a normal window is
+// configured to, which is what an interactive resize proposes.
+func fitAspect(sz, aspect image.Point) image.Point {
+	if aspect == (image.Point{}) {
+		return sz
+	}
+	if sz.X*aspect.Y > sz.Y*aspect.X {
+		sz.X = sz.Y * aspect.X / aspect.Y
+	} else {
+		sz.Y = sz.X * aspect.Y / aspect.X
+	}
+	if sz.X < 1 {
+		sz.X = 1
+	}
+	if sz.Y < 1 {
+		sz.Y = 1
+	}
+	return sz
+}
+
+func (w *windowImpl) startMoveResize(opcode uint16, args ...uint32) error {
+	s := w.s
+	s.mu.Lock()
+	serial, surface := s.buttonSerial, s.buttonSurface
+	s.mu.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return errReleased
+	}
+	if s.seat == 0 || serial == 0 || surface != w.surface {
+		return errors.New("waylanddriver: no mouse button is held down over the window")
+	}
+	args = append([]uint32{uint32(s.seat), serial}, args...)
+	if err := s.c.request(w.toplevel, opcode, args...); err != nil {
+		return fmt.Errorf("waylanddriver: starting a move or resize failed: %v", err)
+	}
+	return nil
+}
+
+func (w *windowImpl) toplevelRequest(opcode uint16, args ...uint32) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return
+	}
+	w.s.c.request(w.toplevel, opcode, args...)
+}
+
+// handleStates sends a WindowStateEvent if the states array, of an
+// xdg_toplevel.configure event, changed the window's state. Like
+// handleToplevel, it must only be called from the readEvents goroutine.
+func (w *windowImpl) handleStates(states []byte) {
+	var maximized, fullscreen bool
+	for i := 0; i+4 <= len(states); i += 4 {
+		switch byteOrder.Uint32(states[i:]) {
+		case toplevelStateMaximized:
+			maximized = true
+		case toplevelStateFullscreen:
+			fullscreen = true
+		}
+	}
+	state := screen.WindowNormal
+	switch {
+	case fullscreen:
+		state = screen.WindowFullscreen
+	case maximized:
+		state = screen.WindowMaximized
+	}
+	if state != w.state {
+		w.state = state
+		w.Send(screen.WindowStateEvent{State: state})
+	}
+}
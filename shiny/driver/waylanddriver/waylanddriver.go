@@ -0,0 +1,57 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+// Package waylanddriver provides the Wayland driver for accessing a screen.
+//
+// It speaks the Wayland wire protocol directly, without cgo or libwayland,
+// and so needs no C libraries at build or run time. The compositor must
+// support the core wl_compositor, wl_shm and wl_seat interfaces, and the
+// stable xdg-shell protocol's xdg_wm_base interface.
+//
+// Drawing is done in software, and windows are presented by sharing memory,
+// a wl_shm buffer, with the compositor.
+//
+// TODO: render with EGL and OpenGL ES instead, via the linux-dmabuf protocol
+// or wl_egl_window, as the gldriver does on X11.
+package waylanddriver // import "golang.org/x/exp/shiny/driver/waylanddriver"
+
+import (
+	"golang.org/x/exp/shiny/driver/internal/errscreen"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Main is called by the program's main function to run the graphical
+// application.
+//
+// It calls f on the Screen, possibly in a separate goroutine, as some OS-
+// specific libraries require being on 'the main thread'. It returns when f
+// returns.
+func Main(f func(screen.Screen)) {
+	if err := main(f); err != nil {
+		f(errscreen.Stub(err))
+	}
+}
+
+func main(f func(screen.Screen)) (retErr error) {
+	c, err := dial()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			c.close()
+		}
+	}()
+
+	s, err := newScreenImpl(c)
+	if err != nil {
+		return err
+	}
+	f(s)
+	// TODO: tear down the s.run goroutine? It's probably not worth the
+	// complexity of doing it cleanly, if the app is about to exit anyway.
+	return nil
+}
@@ -0,0 +1,758 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/exp/shiny/driver/internal/capture"
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/filedialog"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/lifecycler"
+	"golang.org/x/exp/shiny/driver/internal/notify"
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
+	"golang.org/x/exp/shiny/driver/internal/swtexture"
+	"golang.org/x/exp/shiny/driver/internal/x11key"
+	"golang.org/x/exp/shiny/screen"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/paint"
+	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/geom"
+)
+
+type windowImpl struct {
+	s *screenImpl
+
+	surface    objectID
+	xdgSurface objectID
+	toplevel   objectID
+
+	event.Deque
+	lifecycler lifecycler.State
+
+	// fixedSize is whether the compositor was asked to prevent the user from
+	// resizing the window.
+	fixedSize bool
+	// interceptClose is whether an xdg_toplevel.close event sends a
+	// CloseRequestEvent, instead of killing the window.
+	interceptClose bool
+	// hidden is whether the window is never shown. A Wayland surface is
+	// only mapped once a buffer is attached to it, so a hidden window's
+	// buffers are never attached.
+	hidden bool
+	// state is the window's state, as of the most recent
+	// xdg_toplevel.configure event. It is only accessed by the readEvents
+	// goroutine.
+	state screen.WindowState
+	// modalOwner is the owner whose input the window, created with
+	// NewWindowOptions.Modal, blocks, or nil. modalChildren is how many such
+	// windows block the window's input, and is accessed atomically.
+	modalOwner    *windowImpl
+	modalChildren int32
+	// transparent is whether the window's buffers have an alpha channel.
+	// Shaped windows have one too, so that they are transparent outside
+	// their shape.
+	transparent bool
+	// shape is the window's shape, or nil if it is unshaped. Pixels outside
+	// it are not sent to the compositor.
+	shape []image.Rectangle
+
+	// mu guards back, configureSize, configured, aspect, buffers,
+	// frameRequested, cursor, cursorHidden, captured, lockedPointer and
+	// released. If you need to hold both a windowImpl's mu and a
+	// swtexture.Texture's or the screenImpl's mu, the lock ordering is to
+	// lock the windowImpl's first (and unlock it last).
+	mu sync.Mutex
+	// back is the back buffer, that the Drawer methods draw to.
+	back *image.RGBA
+	// clip is the clip stack that the Drawer methods draw subject to. Like
+	// back, it is guarded by mu.
+	clip drawer.Clip
+	// configureSize is the size from the most recent xdg_toplevel.configure
+	// event, which takes effect at the next xdg_surface.configure event. A
+	// zero width or height means that the client chooses its own size.
+	configureSize image.Point
+	// configured is whether the compositor has configured the window, after
+	// which buffers may be attached to its surface.
+	configured bool
+	// aspect is the aspect ratio set by SetAspectRatio, or zero.
+	aspect image.Point
+	// buffers are the wl_buffers of the back buffer's size, in which
+	// published frames are sent to the compositor.
+	buffers []*shmBuffer
+	// frameRequested is whether a wl_surface.frame callback is pending.
+	frameRequested bool
+	cursor         screen.Cursor
+	cursorHidden   bool
+	// captured is whether the window has captured the pointer. The
+	// zwp_locked_pointer_v1, if any, holds the pointer still while it is
+	// over the window.
+	captured      bool
+	lockedPointer objectID
+	released      bool
+
+	imagePool drawer.ImagePool
+	layers    drawer.Layers
+	captures  capture.Hooks
+	timing    frame.Timing
+}
+
+func (w *windowImpl) Release() {
+	w.mu.Lock()
+	released := w.released
+	w.released = true
+	buffers := w.buffers
+	w.buffers = nil
+	lockedPointer := w.lockedPointer
+	w.lockedPointer = 0
+	w.mu.Unlock()
+	if released {
+		return
+	}
+
+	notify.Release(w)
+	w.imagePool.Release()
+	w.layers.Release()
+	w.StopTimers()
+	if w.modalOwner != nil {
+		atomic.AddInt32(&w.modalOwner.modalChildren, -1)
+	}
+
+	s := w.s
+	s.mu.Lock()
+	delete(s.windows, w.surface)
+	s.mu.Unlock()
+
+	// The xdg-shell protocol requires the role objects to be destroyed
+	// before the wl_surface.
+	c := s.c
+	if lockedPointer != 0 {
+		c.request(lockedPointer, lockedPointerDestroy)
+	}
+	c.request(w.toplevel, toplevelDestroy)
+	c.request(w.xdgSurface, xdgSurfaceDestroy)
+	c.request(w.surface, surfaceDestroy)
+	for _, b := range buffers {
+		b.destroy(c)
+	}
+}
+
+func (w *windowImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	swtexture.Upload(w.back, dp, src, sr)
+}
+
+func (w *windowImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.clip.Fill(w.back, dr, src, op)
+}
+
+// Draw and DrawUniform use bilinear sampling, which is exact for
+// transformations that are just translations. Depth testing is not supported,
+// but blend modes and transparency are composited in software.
+
+func (w *windowImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	t := src.(*swtexture.Texture)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	t.Transform(&w.clip, xdraw.ApproxBiLinear, w.back, src2dst, sr, op, opts)
+}
+
+func (w *windowImpl) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.clip.Transform(xdraw.ApproxBiLinear, w.back, src2dst, image.NewUniform(src), sr, op, opts)
+}
+
+func (w *windowImpl) PushClip(r image.Rectangle) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PushClip(r)
+}
+
+func (w *windowImpl) PushClipPath(src2dst f64.Aff3, p *screen.Path) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PushClipPath(src2dst, p)
+}
+
+func (w *windowImpl) PopClip() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clip.PopClip()
+}
+
+func (w *windowImpl) Copy(dp image.Point, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Copy(w, dp, src, sr, op, opts)
+}
+
+func (w *windowImpl) Scale(dr image.Rectangle, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	drawer.Scale(w, dr, src, sr, op, opts)
+}
+
+func (w *windowImpl) DrawImage(dp image.Point, src image.Image, op draw.Op, opts *screen.DrawOptions) {
+	w.imagePool.DrawImage(w.s, w, dp, src, op, opts)
+}
+
+func (w *windowImpl) NewLayer(z int, size image.Point) (screen.Layer, error) {
+	return w.layers.NewLayer(w.s, z, size)
+}
+
+func (w *windowImpl) Publish() screen.PublishResult {
+	composited := w.layers.Composite(w)
+
+	w.mu.Lock()
+	if w.released {
+		w.mu.Unlock()
+		return screen.PublishResult{Dropped: true}
+	}
+	// The back buffer is copied, not flipped, to the front, so its contents
+	// are preserved. Compositing any layers overwrites the contents, though.
+	res := screen.PublishResult{BackBufferPreserved: !composited}
+	// Buffers must not be attached before the window is first configured.
+	// Until then, there is nothing on screen to update.
+	if w.configured && !w.hidden && w.present() {
+		w.timing.Presented(&res, time.Now())
+	} else {
+		res.Dropped = true
+	}
+	var captured *image.RGBA
+	if w.captures.Active() {
+		captured = capture.Copy(w.back, w.back.Rect)
+	}
+	w.mu.Unlock()
+
+	w.captures.Send(captured)
+	return res
+}
+
+func (w *windowImpl) AddCaptureHook(fn func(f screen.CapturedFrame)) (remove func()) {
+	return w.captures.Add(fn)
+}
+
+// present copies the back buffer to a wl_buffer, and shows that buffer on the
+// window's surface, reporting whether it could. It must be called with w.mu
+// held.
+//
+// TODO: throttle Publish to the compositor's frame rate with
+// wl_surface.frame callbacks, instead of allocating more buffers when the
+// compositor is slow to release them.
+func (w *windowImpl) present() bool {
+	var b *shmBuffer
+	for _, x := range w.buffers {
+		if !x.busy {
+			b = x
+			break
+		}
+	}
+	if b == nil {
+		var err error
+		format := uint32(shmFormatXRGB8888)
+		if w.transparent {
+			format = shmFormatARGB8888
+		}
+		b, err = w.s.newShmBuffer(w.back.Rect.Size(), format, w.handleRelease)
+		if err != nil {
+			log.Print(err)
+			return false
+		}
+		w.buffers = append(w.buffers, b)
+	}
+	// The back buffer's stride is also 4 bytes per pixel, without padding.
+	if w.shape == nil {
+		copy(b.data, w.back.Pix)
+	} else {
+		copyShaped(b.data, w.back, w.shape)
+	}
+	swizzle.BGRA(b.data)
+	b.busy = true
+
+	c := w.s.c
+	width, height := uint32(b.size.X), uint32(b.size.Y)
+	c.request(w.surface, surfaceAttach, uint32(b.id), 0, 0)
+	if w.s.compositorVersion >= surfaceDamageBufferVersion {
+		c.request(w.surface, surfaceDamageBuffer, 0, 0, width, height)
+	} else {
+		// The buffer scale is 1, so surface and buffer co-ordinates are the
+		// same.
+		c.request(w.surface, surfaceDamage, 0, 0, width, height)
+	}
+	c.request(w.surface, surfaceCommit)
+	return true
+}
+
+// copyShaped copies the pixels of src inside shape to dst, whose stride is 4
+// bytes per pixel, and sets the pixels outside it to transparent black.
+func copyShaped(dst []byte, src *image.RGBA, shape []image.Rectangle) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	stride := 4 * src.Rect.Dx()
+	for _, r := range shape {
+		r = r.Add(src.Rect.Min).Intersect(src.Rect)
+		if r.Empty() {
+			continue
+		}
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			i := src.PixOffset(r.Min.X, y)
+			j := (y-src.Rect.Min.Y)*stride + 4*(r.Min.X-src.Rect.Min.X)
+			copy(dst[j:j+4*r.Dx()], src.Pix[i:])
+		}
+	}
+}
+
+// setInputRegion limits the window's input to its shape.
+func (w *windowImpl) setInputRegion() error {
+	c := w.s.c
+	region := c.newObject(nil)
+	if err := c.request(w.s.compositor, compositorCreateRegion, uint32(region)); err != nil {
+		return err
+	}
+	for _, r := range w.shape {
+		if r.Empty() {
+			continue
+		}
+		if err := c.request(region, regionAdd,
+			uint32(int32(r.Min.X)), uint32(int32(r.Min.Y)), uint32(r.Dx()), uint32(r.Dy())); err != nil {
+			return err
+		}
+	}
+	if err := c.request(w.surface, surfaceSetInputRegion, uint32(region)); err != nil {
+		return err
+	}
+	return c.request(region, regionDestroy)
+}
+
+// handleRelease is called, in the readEvents goroutine, when the compositor
+// has finished reading b.
+func (w *windowImpl) handleRelease(b *shmBuffer) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		// Release has already destroyed b.
+		return
+	}
+	if b.stale {
+		b.destroy(w.s.c)
+		return
+	}
+	b.busy = false
+}
+
+// NextFrame requests a wl_surface.frame callback, which the compositor sends
+// when it is a good time to draw a new frame, such as at the output's next
+// vertical blank. The compositor never draws a hidden or not yet configured
+// window, so those windows' frames are paced by a clock instead.
+func (w *windowImpl) NextFrame() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released || w.frameRequested {
+		return
+	}
+	if !w.configured || w.hidden {
+		if s := w.s; s.frames.Request(w) {
+			go s.frames.Tick(frame.Interval(0))
+		}
+		return
+	}
+	w.frameRequested = true
+	c := w.s.c
+	cb := c.newObject(func(opcode uint16, d *decoder) {
+		if opcode != callbackEventDone {
+			return
+		}
+		// The event's timestamp has an undefined base, so is not comparable
+		// with time.Now.
+		w.mu.Lock()
+		w.frameRequested = false
+		w.mu.Unlock()
+		w.Send(screen.FrameEvent{Time: time.Now()})
+	})
+	// A commit is needed for the request to take effect. Committing without
+	// attaching a new buffer leaves the surface's contents unchanged.
+	c.request(w.surface, surfaceFrame, uint32(cb))
+	c.request(w.surface, surfaceCommit)
+}
+
+func (w *windowImpl) RenderFrame(fn func(d screen.Drawer)) error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+
+	fn(w)
+	return nil
+}
+
+var errReleased = errors.New("waylanddriver: window is released")
+
+func (w *windowImpl) GLInfo() screen.GLInfo { return screen.GLInfo{} }
+
+func (w *windowImpl) Begin() *screen.Context { return screen.NewContext(w) }
+
+func (w *windowImpl) SetTitle(title string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.setTitle(title)
+}
+
+// maxTitleLen is the longest title, in bytes, that fits in an
+// xdg_toplevel.set_title request.
+const maxTitleLen = maxMessageSize - headerSize - 8
+
+func (w *windowImpl) setTitle(title string) error {
+	// The title is sent as a NUL terminated string.
+	for i := 0; i < len(title); i++ {
+		if title[i] == 0 {
+			title = title[:i]
+			break
+		}
+	}
+	if len(title) > maxTitleLen {
+		n := maxTitleLen
+		for n > 0 && !utf8.RuneStart(title[n]) {
+			n--
+		}
+		title = title[:n]
+	}
+	m := newMessage(w.toplevel, toplevelSetTitle)
+	m.putString(title)
+	return w.s.c.send(m)
+}
+
+// setSizeHints asks the compositor to keep the window at the given size.
+func (w *windowImpl) setSizeHints(width, height int) error {
+	c := w.s.c
+	if err := c.request(w.toplevel, toplevelSetMinSize, uint32(width), uint32(height)); err != nil {
+		return err
+	}
+	return c.request(w.toplevel, toplevelSetMaxSize, uint32(width), uint32(height))
+}
+
+// SetIcon does nothing, as the compositor finds a window's icon in the
+// desktop entry of its application.
+//
+// TODO: implement the xdg-toplevel-icon-v1 protocol.
+func (w *windowImpl) SetIcon(m image.Image) {}
+
+// SetBadge and SetProgress do nothing, as Wayland has no taskbar of its own.
+func (w *windowImpl) SetBadge(label string) {}
+
+func (w *windowImpl) SetProgress(progress float64) {}
+
+// SetSize resizes the back buffer, keeping as much of the old contents as
+// fit. Wayland clients choose their own size, so the compositor is told of
+// the new size when the window is next published. It may still send a
+// different size, such as when the window is maximized or tiled.
+func (w *windowImpl) SetSize(width, height int) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	w.mu.Lock()
+	if w.released {
+		w.mu.Unlock()
+		return
+	}
+	// A fixed size window's minimum and maximum size must change too, or the
+	// compositor may refuse the new size.
+	if w.fixedSize {
+		w.setSizeHints(width, height)
+	}
+	resized := w.resize(image.Point{width, height})
+	w.mu.Unlock()
+
+	if resized {
+		w.sendSize(image.Point{width, height})
+	}
+}
+
+// resize replaces the back buffer with one of the new size, keeping as much of
+// the old contents as fit, and reports whether the size changed. It must be
+// called with w.mu held.
+func (w *windowImpl) resize(sz image.Point) bool {
+	if w.back.Rect.Size() == sz {
+		return false
+	}
+	back := image.NewRGBA(image.Rectangle{Max: sz})
+	draw.Draw(back, back.Rect, w.back, image.Point{}, draw.Src)
+	w.back = back
+
+	// The buffers are the wrong size now. Those that the compositor is still
+	// reading are destroyed when it releases them.
+	for _, b := range w.buffers {
+		if b.busy {
+			b.stale = true
+		} else {
+			b.destroy(w.s.c)
+		}
+	}
+	w.buffers = nil
+	return true
+}
+
+// SetPosition does nothing, as Wayland clients cannot position their own
+// windows.
+func (w *windowImpl) SetPosition(p image.Point) {}
+
+// GetGeometry returns a zero position, as Wayland does not tell clients where
+// their windows are.
+func (w *windowImpl) GetGeometry() (image.Point, image.Point) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return image.Point{}, image.Point{}
+	}
+	return image.Point{}, w.back.Rect.Size()
+}
+
+// TODO: implement the text-input-unstable-v3 protocol, and send
+// screen.TextEvents.
+func (w *windowImpl) SetTextInputRect(r image.Rectangle) {}
+
+// ShowFileDialog runs zenity, as Wayland has no file dialogs of its own. The
+// dialog is a window of its own, not owned by w, as zenity can only be made
+// transient for an X11 window.
+//
+// TODO: export the toplevel with the xdg-foreign protocol, and pass its handle
+// to the XDG desktop portal's FileChooser.
+func (w *windowImpl) ShowFileDialog(opts *screen.FileDialogOptions) error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+	return filedialog.Start(w, opts, 0)
+}
+
+// Notify runs notify-send, as Wayland has no notifications of its own.
+//
+// TODO: use the XDG desktop portal's Notification interface, which sandboxed
+// applications can call.
+func (w *windowImpl) Notify(n *screen.Notification) error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+	return notify.Post(w, n)
+}
+
+func (w *windowImpl) CloseNotification(id int) {
+	notify.Close(w, id)
+}
+
+// SetMenuBar and ShowContextMenu return an error, as Wayland has no menus of
+// its own. Applications draw their own menus, in xdg_popup surfaces.
+//
+// TODO: implement menus as xdg_popups, drawn by the driver?
+func (w *windowImpl) SetMenuBar(bar *screen.Menu) error {
+	return w.errNoMenus()
+}
+
+func (w *windowImpl) ShowContextMenu(m *screen.Menu, p image.Point) error {
+	return w.errNoMenus()
+}
+
+func (w *windowImpl) errNoMenus() error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+	return errors.New("waylanddriver: menus are not supported")
+}
+
+// SetAccessTree returns an error, as Wayland has no accessibility API of its
+// own.
+//
+// TODO: implement AT-SPI, over D-Bus.
+func (w *windowImpl) SetAccessTree(root *screen.AccessNode) error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+	return errors.New("waylanddriver: accessibility is not implemented")
+}
+
+// RegisterHotKey returns an error, as Wayland lets no client grab a key
+// while one of another client's surfaces has the keyboard focus.
+//
+// TODO: implement the XDG desktop portal's GlobalShortcuts interface, over
+// D-Bus.
+func (w *windowImpl) RegisterHotKey(k key.Code, mods key.Modifiers) error {
+	w.mu.Lock()
+	released := w.released
+	w.mu.Unlock()
+	if released {
+		return errReleased
+	}
+	return errors.New("waylanddriver: hot keys are not implemented")
+}
+
+func (w *windowImpl) UnregisterHotKey(k key.Code, mods key.Modifiers) {}
+
+func (w *windowImpl) handleXDGSurface(opcode uint16, d *decoder) {
+	if opcode != xdgSurfaceEventConfigure {
+		return
+	}
+	serial := d.uint()
+
+	w.mu.Lock()
+	if w.released {
+		w.mu.Unlock()
+		return
+	}
+	w.s.c.request(w.xdgSurface, xdgSurfaceAckConfigure, serial)
+	first := !w.configured
+	w.configured = true
+	resized := false
+	if sz := w.configureSize; sz.X > 0 && sz.Y > 0 && !w.fixedSize {
+		// handleXDGSurface, like handleStates, is only called from the
+		// readEvents goroutine, so it may read w.state.
+		if w.state == screen.WindowNormal {
+			sz = fitAspect(sz, w.aspect)
+		}
+		resized = w.resize(sz)
+	}
+	sz := w.back.Rect.Size()
+	w.mu.Unlock()
+
+	if first {
+		w.lifecycler.SetVisible(!w.hidden)
+		w.lifecycler.SendEvent(w, nil)
+	}
+	if first || resized {
+		w.sendSize(sz)
+	}
+}
+
+func (w *windowImpl) handleToplevel(opcode uint16, d *decoder) {
+	switch opcode {
+	case toplevelEventConfigure:
+		width, height, states := d.int(), d.int(), d.array()
+		if d.err != nil {
+			return
+		}
+		w.mu.Lock()
+		w.configureSize = image.Point{int(width), int(height)}
+		w.mu.Unlock()
+		w.handleStates(states)
+	case toplevelEventClose:
+		if w.interceptClose {
+			w.Send(screen.CloseRequestEvent{})
+			return
+		}
+		w.lifecycler.SetDead(true)
+		w.lifecycler.SendEvent(w, nil)
+	}
+}
+
+// sendSize sends a size.Event, and then a paint.Event, as the window's new
+// contents are undefined until it is next published.
+//
+// TODO: support HiDPI outputs, via wl_surface.set_buffer_scale.
+func (w *windowImpl) sendSize(sz image.Point) {
+	w.Send(size.Event{
+		WidthPx:     sz.X,
+		HeightPx:    sz.Y,
+		WidthPt:     geom.Pt(sz.X),
+		HeightPt:    geom.Pt(sz.Y),
+		PixelsPerPt: 1,
+	})
+	w.Send(paint.Event{External: true})
+}
+
+// inputBlocked returns whether the window is not sent key, mouse and touch
+// events, because a modal window that it owns is open.
+func (w *windowImpl) inputBlocked() bool {
+	return atomic.LoadInt32(&w.modalChildren) > 0
+}
+
+func (w *windowImpl) handleKey(detail uint8, state uint16, dir key.Direction) {
+	if w.inputBlocked() {
+		return
+	}
+	e := w.s.keyEvent(detail, state, dir)
+	w.SendAt(e, w.s.inputTime)
+}
+
+func (w *windowImpl) handleMouse(x, y float32, b mouse.Button, state uint16, dir mouse.Direction) {
+	if w.inputBlocked() {
+		return
+	}
+	w.SendAt(mouse.Event{
+		X:         x,
+		Y:         y,
+		Button:    b,
+		Modifiers: x11key.KeyModifiers(state),
+		Direction: dir,
+	}, w.s.inputTime)
+}
+
+func (w *windowImpl) ColorSpace() screen.ColorSpace { return screen.ColorSpaceSRGB }
+
+// SetColorSpace only accepts screen.ColorSpaceSRGB, as the Wayland color
+// management protocol is not implemented.
+func (w *windowImpl) SetColorSpace(cs screen.ColorSpace) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return errReleased
+	}
+	if cs != screen.ColorSpaceSRGB {
+		return errors.New("waylanddriver: color spaces other than sRGB are not supported")
+	}
+	return nil
+}
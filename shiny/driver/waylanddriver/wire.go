@@ -0,0 +1,374 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+// This file implements the Wayland wire protocol, as specified at
+// https://wayland.freedesktop.org/docs/html/ch04.html#sect-Protocol-Wire-Format
+//
+// Each message, whether a request (from client to compositor) or an event
+// (from compositor to client), is a sequence of 32-bit words in the host's
+// byte order. The first word is the ID of the object that the message is to
+// or from. The second word is the message's size in bytes, in its upper 16
+// bits, and its opcode, in its lower 16 bits. The remaining words are the
+// arguments. File descriptor arguments are not part of the message itself,
+// but are passed alongside it as ancillary data on the Unix socket.
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// byteOrder is the host's byte order.
+var byteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+const (
+	// headerSize is the size, in bytes, of a message's header.
+	headerSize = 8
+	// maxMessageSize is the largest message that libwayland, the reference
+	// implementation, will send or receive.
+	maxMessageSize = 4096
+	// maxFDs is the most file descriptors that libwayland will send with any
+	// one sendmsg call.
+	maxFDs = 28
+)
+
+// objectID identifies a protocol object. 1 is the wl_display, and 0 means no
+// object.
+type objectID uint32
+
+const displayID objectID = 1
+
+// handler handles an event from the compositor to an object.
+type handler func(opcode uint16, d *decoder)
+
+// message is a request, from the client to the compositor.
+type message struct {
+	b   []byte
+	fds []int
+}
+
+func newMessage(id objectID, opcode uint16) *message {
+	m := &message{b: make([]byte, headerSize, 64)}
+	byteOrder.PutUint32(m.b[0:], uint32(id))
+	byteOrder.PutUint32(m.b[4:], uint32(opcode))
+	return m
+}
+
+func (m *message) putUint(u uint32) {
+	var b [4]byte
+	byteOrder.PutUint32(b[:], u)
+	m.b = append(m.b, b[:]...)
+}
+
+func (m *message) putInt(i int32) { m.putUint(uint32(i)) }
+
+func (m *message) putObject(id objectID) { m.putUint(uint32(id)) }
+
+// putString appends s, which must not contain a NUL byte. The string is sent
+// with its length, including a NUL terminator, and padded to a whole number
+// of words.
+func (m *message) putString(s string) {
+	m.putUint(uint32(len(s) + 1))
+	m.b = append(m.b, s...)
+	m.b = append(m.b, 0)
+	m.pad()
+}
+
+func (m *message) putFD(fd int) { m.fds = append(m.fds, fd) }
+
+func (m *message) pad() {
+	for len(m.b)%4 != 0 {
+		m.b = append(m.b, 0)
+	}
+}
+
+// decoder decodes an event's arguments. Decoding a malformed event yields zero
+// values, and sets err.
+type decoder struct {
+	c   *conn
+	b   []byte
+	err error
+}
+
+func (d *decoder) uint() uint32 {
+	if len(d.b) < 4 {
+		d.err = errShortMessage
+		return 0
+	}
+	u := byteOrder.Uint32(d.b)
+	d.b = d.b[4:]
+	return u
+}
+
+func (d *decoder) int() int32 { return int32(d.uint()) }
+
+func (d *decoder) object() objectID { return objectID(d.uint()) }
+
+// fixed decodes a signed 24.8 fixed point number.
+func (d *decoder) fixed() float32 { return float32(d.int()) / 256 }
+
+func (d *decoder) array() []byte {
+	n := int(d.uint())
+	padded := (n + 3) &^ 3
+	if n < 0 || padded > len(d.b) {
+		d.err = errShortMessage
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[padded:]
+	return b
+}
+
+func (d *decoder) string() string {
+	b := d.array()
+	if len(b) == 0 {
+		return ""
+	}
+	// Strip the NUL terminator.
+	return string(b[:len(b)-1])
+}
+
+// fd returns the next file descriptor received from the compositor. The
+// caller is responsible for closing it.
+func (d *decoder) fd() int {
+	if len(d.c.inFDs) == 0 {
+		d.err = errors.New("waylanddriver: missing file descriptor")
+		return -1
+	}
+	fd := d.c.inFDs[0]
+	d.c.inFDs = d.c.inFDs[1:]
+	return fd
+}
+
+var errShortMessage = errors.New("waylanddriver: short message")
+
+// conn is a connection to a Wayland compositor.
+type conn struct {
+	uc *net.UnixConn
+
+	// mu guards writing to uc, nextID, freeIDs and handlers.
+	mu       sync.Mutex
+	nextID   objectID
+	freeIDs  []objectID
+	handlers map[objectID]handler
+
+	// in and inFDs are the bytes and file descriptors received but not yet
+	// decoded. They are only accessed by the readEvents goroutine.
+	in    []byte
+	inFDs []int
+
+	// done is closed when readEvents returns.
+	done chan struct{}
+}
+
+// dial connects to the compositor named by the WAYLAND_DISPLAY environment
+// variable, which is either an absolute path or relative to the
+// XDG_RUNTIME_DIR directory.
+func dial() (*conn, error) {
+	name := os.Getenv("WAYLAND_DISPLAY")
+	if name == "" {
+		name = "wayland-0"
+	}
+	if !filepath.IsAbs(name) {
+		dir := os.Getenv("XDG_RUNTIME_DIR")
+		if dir == "" {
+			return nil, errors.New("waylanddriver: XDG_RUNTIME_DIR is not set")
+		}
+		name = filepath.Join(dir, name)
+	}
+	uc, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: name, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("waylanddriver: connecting to %s failed: %v", name, err)
+	}
+	return &conn{
+		uc:       uc,
+		nextID:   displayID + 1,
+		handlers: map[objectID]handler{},
+		done:     make(chan struct{}),
+	}, nil
+}
+
+func (c *conn) close() error { return c.uc.Close() }
+
+// newObject allocates an ID for a new object, whose events are handled by h.
+// h may be nil, if the object has no events or they are all ignored.
+func (c *conn) newObject(h handler) objectID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var id objectID
+	if n := len(c.freeIDs); n > 0 {
+		id, c.freeIDs = c.freeIDs[n-1], c.freeIDs[:n-1]
+	} else {
+		id = c.nextID
+		c.nextID++
+	}
+	c.handlers[id] = h
+	return id
+}
+
+// setHandler replaces the handler for id's events.
+func (c *conn) setHandler(id objectID, h handler) {
+	c.mu.Lock()
+	c.handlers[id] = h
+	c.mu.Unlock()
+}
+
+// deleteID is called when the compositor acknowledges that an object has been
+// destroyed, after which its ID can be re-used.
+func (c *conn) deleteID(id objectID) {
+	c.mu.Lock()
+	if _, ok := c.handlers[id]; ok {
+		delete(c.handlers, id)
+		c.freeIDs = append(c.freeIDs, id)
+	}
+	c.mu.Unlock()
+}
+
+// forget forgets a compositor-allocated object, such as a wl_data_offer,
+// after the client destroys it. Unlike for the client's own objects, the
+// compositor does not acknowledge that with a delete_id event.
+func (c *conn) forget(id objectID) {
+	c.mu.Lock()
+	delete(c.handlers, id)
+	c.mu.Unlock()
+}
+
+// send sends the request m. Requests are sent, and therefore processed by the
+// compositor, in the order that send is called.
+func (c *conn) send(m *message) error {
+	if len(m.b) > maxMessageSize {
+		return fmt.Errorf("waylanddriver: request too large (%d bytes)", len(m.b))
+	}
+	if len(m.fds) > maxFDs {
+		return fmt.Errorf("waylanddriver: request has too many file descriptors (%d)", len(m.fds))
+	}
+	byteOrder.PutUint32(m.b[4:], uint32(len(m.b))<<16|byteOrder.Uint32(m.b[4:])&0xffff)
+	var oob []byte
+	if len(m.fds) > 0 {
+		oob = syscall.UnixRights(m.fds...)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, _, err := c.uc.WriteMsgUnix(m.b, oob, nil); err != nil {
+		return fmt.Errorf("waylanddriver: write failed: %v", err)
+	}
+	return nil
+}
+
+// request sends a request whose arguments are all uint, int, object or new_id
+// values. Int arguments are passed as their two's complement bits.
+func (c *conn) request(id objectID, opcode uint16, args ...uint32) error {
+	m := newMessage(id, opcode)
+	for _, a := range args {
+		m.putUint(a)
+	}
+	return c.send(m)
+}
+
+// roundtrip waits until the compositor has processed every request sent so
+// far, and the events that they caused have been dispatched. It must not be
+// called from the readEvents goroutine.
+func (c *conn) roundtrip() error {
+	done := make(chan struct{})
+	cb := c.newObject(func(opcode uint16, d *decoder) {
+		if opcode == callbackEventDone {
+			close(done)
+		}
+	})
+	if err := c.request(displayID, displaySync, uint32(cb)); err != nil {
+		return err
+	}
+	select {
+	case <-done:
+		return nil
+	case <-c.done:
+		return errors.New("waylanddriver: connection closed")
+	}
+}
+
+// readEvents reads events from the compositor, and dispatches them to their
+// objects' handlers, until the connection fails.
+func (c *conn) readEvents() error {
+	defer close(c.done)
+
+	buf := make([]byte, maxMessageSize)
+	oob := make([]byte, syscall.CmsgSpace(4*maxFDs))
+	for {
+		n, oobn, _, _, err := c.uc.ReadMsgUnix(buf, oob)
+		if err != nil {
+			return fmt.Errorf("waylanddriver: read failed: %v", err)
+		}
+		if oobn > 0 {
+			if err := c.receiveFDs(oob[:oobn]); err != nil {
+				return err
+			}
+		}
+		c.in = append(c.in, buf[:n]...)
+		if err := c.dispatch(); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *conn) receiveFDs(oob []byte) error {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return fmt.Errorf("waylanddriver: parsing control message failed: %v", err)
+	}
+	for i := range msgs {
+		fds, err := syscall.ParseUnixRights(&msgs[i])
+		if err != nil {
+			return fmt.Errorf("waylanddriver: parsing file descriptors failed: %v", err)
+		}
+		c.inFDs = append(c.inFDs, fds...)
+	}
+	return nil
+}
+
+// dispatch dispatches every complete event in c.in, leaving any partial event
+// for the next read.
+func (c *conn) dispatch() error {
+	for len(c.in) >= headerSize {
+		id := objectID(byteOrder.Uint32(c.in[0:]))
+		sizeOpcode := byteOrder.Uint32(c.in[4:])
+		n := int(sizeOpcode >> 16)
+		if n < headerSize || n%4 != 0 {
+			return fmt.Errorf("waylanddriver: invalid event size %d", n)
+		}
+		if len(c.in) < n {
+			break
+		}
+
+		c.mu.Lock()
+		h := c.handlers[id]
+		c.mu.Unlock()
+		if h != nil {
+			d := &decoder{c: c, b: c.in[headerSize:n]}
+			h(uint16(sizeOpcode), d)
+		}
+		c.in = c.in[n:]
+	}
+	// Move any partial event to the start of the slice, so that c.in does
+	// not grow without bound.
+	c.in = append(c.in[:0], c.in...)
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// socketPair returns a client conn and the raw other end of its socket, which
+// plays the compositor.
+func socketPair(t *testing.T) (*conn, *net.UnixConn) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("Socketpair: %v", err)
+	}
+	var ucs [2]*net.UnixConn
+	for i, fd := range fds {
+		f := os.NewFile(uintptr(fd), "socketpair")
+		fc, err := net.FileConn(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("FileConn: %v", err)
+		}
+		ucs[i] = fc.(*net.UnixConn)
+	}
+	c := &conn{
+		uc:       ucs[0],
+		nextID:   displayID + 1,
+		handlers: map[objectID]handler{},
+		done:     make(chan struct{}),
+	}
+	return c, ucs[1]
+}
+
+// words returns the given words, in the host's byte order.
+func words(us ...uint32) []byte {
+	b := make([]byte, 4*len(us))
+	for i, u := range us {
+		byteOrder.PutUint32(b[4*i:], u)
+	}
+	return b
+}
+
+func TestRequest(t *testing.T) {
+	c, server := socketPair(t)
+	defer c.close()
+	defer server.Close()
+
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	m := newMessage(7, 3)
+	m.putUint(0x01020304)
+	m.putString("hello")
+	m.putFD(int(f.Fd()))
+	m.putInt(-2)
+	if err := c.send(m); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	buf := make([]byte, maxMessageSize)
+	oob := make([]byte, syscall.CmsgSpace(4*maxFDs))
+	n, oobn, _, _, err := server.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatalf("ReadMsgUnix: %v", err)
+	}
+	want := words(
+		7,          // The object ID.
+		28<<16|3,   // The size and opcode.
+		0x01020304, // The uint.
+		6,          // The string's length, including the NUL terminator.
+	)
+	want = append(want, "hello\x00\x00\x00"...)
+	want = append(want, words(0xfffffffe)...)
+	if got := buf[:n]; !bytes.Equal(got, want) {
+		t.Errorf("request:\ngot  % x\nwant % x", got, want)
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("ParseSocketControlMessage: got %d messages, %v", len(msgs), err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("ParseUnixRights: got %d fds, %v", len(fds), err)
+	}
+	syscall.Close(fds[0])
+}
+
+func TestDispatch(t *testing.T) {
+	c, server := socketPair(t)
+	defer c.close()
+
+	type event struct {
+		opcode uint16
+		i      int32
+		s      string
+		f      float32
+		err    error
+	}
+	events := make(chan event, 2)
+	id := c.newObject(func(opcode uint16, d *decoder) {
+		e := event{opcode: opcode}
+		e.i, e.s, e.f = d.int(), d.string(), d.fixed()
+		e.err = d.err
+		events <- e
+	})
+	go c.readEvents()
+
+	// Send two events, the first of them split across two writes. The
+	// second event is too short for its arguments.
+	b := words(uint32(id), 24<<16|5, 0xffffffff, 3)
+	b = append(b, "ab\x00\x00"...)
+	b = append(b, words(3<<8|0x80)...)
+	b = append(b, words(uint32(id), 12<<16|6, 9)...)
+	if _, err := server.Write(b[:10]); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := server.Write(b[10:]); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if got, want := <-events, (event{5, -1, "ab", 3.5, nil}); got != want {
+		t.Errorf("first event: got %+v, want %+v", got, want)
+	}
+	if got := <-events; got.opcode != 6 || got.i != 9 || got.err != errShortMessage {
+		t.Errorf("second event: got %+v, want opcode 6, int 9 and errShortMessage", got)
+	}
+
+	server.Close()
+	<-c.done
+}
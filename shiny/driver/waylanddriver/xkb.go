@@ -0,0 +1,333 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/shiny/driver/internal/x11key"
+)
+
+// parseKeymap parses the parts of an XKB keymap, in the text format sent by
+// wl_keyboard.keymap events, that are needed to look up keys' runes and
+// codes: the xkb_keycodes section, which names each keycode, and the first
+// two groups, or layouts, of the xkb_symbols section, which gives each named
+// key's unshifted and shifted keysyms in each group. The xkb_types and
+// xkb_compatibility sections are ignored.
+//
+// TODO: support more than two groups, and key types other than the one or two
+// level ones, such as the num-pad's KEYPAD type.
+func parseKeymap(s string) (*x11key.KeysymTable, error) {
+	keycodes, ok := section(s, "xkb_keycodes")
+	if !ok {
+		return nil, errors.New("waylanddriver: keymap has no xkb_keycodes section")
+	}
+	symbols, ok := section(s, "xkb_symbols")
+	if !ok {
+		return nil, errors.New("waylanddriver: keymap has no xkb_symbols section")
+	}
+
+	// Each statement in the xkb_keycodes section is like "<AE01> = 10;" or
+	// "alias <AC12> = <BKSL>;".
+	codes := map[string]uint8{}
+	aliases := map[string]string{}
+	for _, stmt := range strings.Split(keycodes, ";") {
+		lhs, rhs, ok := cut(stmt, "=")
+		if !ok {
+			continue
+		}
+		lhs, rhs = strings.TrimSpace(lhs), strings.TrimSpace(rhs)
+		if strings.HasPrefix(lhs, "alias") {
+			aliases[strings.TrimSpace(lhs[len("alias"):])] = rhs
+			continue
+		}
+		if !strings.HasPrefix(lhs, "<") {
+			continue
+		}
+		if n, err := strconv.ParseUint(rhs, 10, 8); err == nil {
+			codes[lhs] = uint8(n)
+		}
+	}
+	for alias, name := range aliases {
+		if n, ok := codes[name]; ok {
+			codes[alias] = n
+		}
+	}
+
+	// Each key in the xkb_symbols section is like "key <AE01> { [ 1, exclam ]
+	// };", although the lists of keysyms may be among other fields, such as
+	// "type= "ALPHABETIC", symbols[Group1]= [ a, A ], symbols[Group2]= [ b,
+	// B ]".
+	t := new(x11key.KeysymTable)
+	for rest := symbols; ; {
+		i := strings.Index(rest, "key <")
+		if i < 0 {
+			break
+		}
+		rest = rest[i+len("key "):]
+		j := strings.IndexByte(rest, '>')
+		if j < 0 {
+			break
+		}
+		name := rest[:j+1]
+		rest = rest[j+1:]
+		body, ok := braces(rest)
+		if !ok {
+			break
+		}
+		rest = rest[len(body):]
+
+		code, ok := codes[name]
+		if !ok {
+			continue
+		}
+		for group, syms := range groupSymbols(body) {
+			for level, sym := range syms {
+				if level >= 2 {
+					break
+				}
+				t[code][2*group+level] = keysym(sym)
+			}
+		}
+	}
+	return t, nil
+}
+
+// groupSymbols returns the names of the keysyms of the first two groups of a
+// key, from the body of its statement in the xkb_symbols section. The body
+// lists them either in fields, like "symbols[Group2]= [ b, B ]", or, if it
+// has no other fields, in group order, like "[ a, A ], [ b, B ]".
+func groupSymbols(body string) (groups [2][]string) {
+	for group := 0; ; group++ {
+		if k := strings.Index(body, "symbols[Group"); k >= 0 {
+			body = body[k+len("symbols[Group"):]
+			k := strings.IndexByte(body, ']')
+			if k < 0 {
+				break
+			}
+			n, err := strconv.Atoi(body[:k])
+			if err != nil {
+				break
+			}
+			group, body = n-1, body[k+1:]
+		} else if strings.Contains(body, "=") {
+			break
+		}
+		k0 := strings.IndexByte(body, '[')
+		k1 := strings.IndexByte(body, ']')
+		if k0 < 0 || k1 < k0 {
+			break
+		}
+		if 0 <= group && group < len(groups) {
+			for _, sym := range strings.Split(body[k0+1:k1], ",") {
+				groups[group] = append(groups[group], strings.TrimSpace(sym))
+			}
+		}
+		body = body[k1+1:]
+	}
+	return groups
+}
+
+// groupNames returns the names of the groups of an XKB keymap, such as
+// "French", from the statements like "name[Group1]="French";" in its
+// xkb_symbols section. A group without a name has an empty one.
+func groupNames(s string) []string {
+	symbols, _ := section(s, "xkb_symbols")
+	var names []string
+	for _, stmt := range strings.Split(symbols, ";") {
+		lhs, rhs, ok := cut(stmt, "=")
+		if !ok {
+			continue
+		}
+		lhs = strings.TrimSpace(lhs)
+		if !strings.HasPrefix(lhs, "name[Group") || !strings.HasSuffix(lhs, "]") {
+			continue
+		}
+		n, err := strconv.Atoi(lhs[len("name[Group") : len(lhs)-1])
+		if err != nil || n < 1 || n > 4 {
+			continue
+		}
+		name, err := strconv.Unquote(strings.TrimSpace(rhs))
+		if err != nil {
+			continue
+		}
+		for len(names) < n {
+			names = append(names, "")
+		}
+		names[n-1] = name
+	}
+	return names
+}
+
+// section returns the contents, between the braces, of the named section of
+// the keymap s.
+func section(s, name string) (string, bool) {
+	i := strings.Index(s, name)
+	if i < 0 {
+		return "", false
+	}
+	s = s[i+len(name):]
+	b, ok := braces(s)
+	if !ok {
+		return "", false
+	}
+	// Strip the outermost braces.
+	return b[strings.IndexByte(b, '{')+1 : len(b)-1], true
+}
+
+// braces returns the prefix of s up to and including the closing brace that
+// matches the first opening brace. Braces inside double-quoted strings are
+// ignored.
+func braces(s string) (string, bool) {
+	depth, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return s[:i+1], true
+			}
+			if depth < 0 {
+				return "", false
+			}
+		}
+	}
+	return "", false
+}
+
+// cut slices s around the first instance of sep.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// keysym returns the keysym with the given name, or zero if the name is not
+// known. As with X11, zero means no keysym.
+func keysym(name string) uint32 {
+	if k, ok := keysymsByName[name]; ok {
+		return k
+	}
+	if len(name) == 1 && '!' <= name[0] && name[0] <= '~' {
+		// Latin letters and digits are named by themselves.
+		return uint32(name[0])
+	}
+	if strings.HasPrefix(name, "0x") {
+		if k, err := strconv.ParseUint(name[2:], 16, 32); err == nil {
+			return uint32(k)
+		}
+	}
+	if strings.HasPrefix(name, "U") && len(name) >= 5 {
+		// Keysyms for Unicode code points, other than Latin-1 ones, are
+		// 0x01000000 plus the code point.
+		if r, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			if r < 0x100 {
+				return uint32(r)
+			}
+			return 0x01000000 + uint32(r)
+		}
+	}
+	return 0
+}
+
+// keysymsByName maps the names of ASCII punctuation keysyms, and of the
+// non-Unicode keysyms that the x11key package knows, to the keysyms. The
+// names and values come from /usr/include/X11/{keysymdef,XF86keysym}.h
+//
+// TODO: add the many other named keysyms, such as "adiaeresis" and
+// "Cyrillic_a", when the x11key package can look up their runes.
+var keysymsByName = map[string]uint32{
+	"NoSymbol":     0x0000,
+	"space":        0x0020,
+	"exclam":       0x0021,
+	"quotedbl":     0x0022,
+	"numbersign":   0x0023,
+	"dollar":       0x0024,
+	"percent":      0x0025,
+	"ampersand":    0x0026,
+	"apostrophe":   0x0027,
+	"parenleft":    0x0028,
+	"parenright":   0x0029,
+	"asterisk":     0x002a,
+	"plus":         0x002b,
+	"comma":        0x002c,
+	"minus":        0x002d,
+	"period":       0x002e,
+	"slash":        0x002f,
+	"colon":        0x003a,
+	"semicolon":    0x003b,
+	"less":         0x003c,
+	"equal":        0x003d,
+	"greater":      0x003e,
+	"question":     0x003f,
+	"at":           0x0040,
+	"bracketleft":  0x005b,
+	"backslash":    0x005c,
+	"bracketright": 0x005d,
+	"asciicircum":  0x005e,
+	"underscore":   0x005f,
+	"grave":        0x0060,
+	"braceleft":    0x007b,
+	"bar":          0x007c,
+	"braceright":   0x007d,
+	"asciitilde":   0x007e,
+
+	"ISO_Left_Tab": 0xfe20,
+	"BackSpace":    0xff08,
+	"Tab":          0xff09,
+	"Return":       0xff0d,
+	"Escape":       0xff1b,
+	"Multi_key":    0xff20,
+	"Home":         0xff50,
+	"Left":         0xff51,
+	"Up":           0xff52,
+	"Right":        0xff53,
+	"Down":         0xff54,
+	"Prior":        0xff55,
+	"Page_Up":      0xff55,
+	"Next":         0xff56,
+	"Page_Down":    0xff56,
+	"End":          0xff57,
+	"Insert":       0xff63,
+	"Menu":         0xff67,
+	"F1":           0xffbe,
+	"F2":           0xffbf,
+	"F3":           0xffc0,
+	"F4":           0xffc1,
+	"F5":           0xffc2,
+	"F6":           0xffc3,
+	"F7":           0xffc4,
+	"F8":           0xffc5,
+	"F9":           0xffc6,
+	"F10":          0xffc7,
+	"F11":          0xffc8,
+	"F12":          0xffc9,
+	"Shift_L":      0xffe1,
+	"Shift_R":      0xffe2,
+	"Control_L":    0xffe3,
+	"Control_R":    0xffe4,
+	"Caps_Lock":    0xffe5,
+	"Meta_L":       0xffe7,
+	"Meta_R":       0xffe8,
+	"Alt_L":        0xffe9,
+	"Alt_R":        0xffea,
+	"Super_L":      0xffeb,
+	"Super_R":      0xffec,
+	"Delete":       0xffff,
+
+	"XF86AudioLowerVolume": 0x1008ff11,
+	"XF86AudioMute":        0x1008ff12,
+	"XF86AudioRaiseVolume": 0x1008ff13,
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android
+
+package waylanddriver
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/mobile/event/key"
+)
+
+// testKeymap is an excerpt of a keymap, for a French AZERTY layout, as sent
+// by a compositor that uses libxkbcommon.
+const testKeymap = `xkb_keymap {
+xkb_keycodes "evdev+aliases(azerty)" {
+	minimum = 8;
+	maximum = 255;
+	<ESC>                = 9;
+	<AE01>               = 10;
+	<RTRN>               = 36;
+	<LFSH>               = 50;
+	<AB01>               = 52;
+	<AD01>               = 24;
+	<TLDE>               = 49;
+	indicator 1 = "Caps Lock";
+	alias <AC12>         = <BKSL>;
+	<BKSL>               = 51;
+};
+
+xkb_types "complete" {
+	virtual_modifiers NumLock,Alt;
+	type "ALPHABETIC" {
+		modifiers= Shift+Lock;
+		map[Shift]= Level2;
+		level_name[Level1]= "Base";
+	};
+};
+
+xkb_symbols "pc+fr+inet(evdev)" {
+	name[Group1]="French";
+
+	key <ESC>                {	[          Escape ] };
+	key <AE01>               {	[       ampersand,               1,             onesuperior,              exclamdown ] };
+	key <AD01>               {
+		type= "ALPHABETIC",
+		symbols[Group1]= [               a,               A ]
+	};
+	key <AB01>               {
+		type= "ALPHABETIC",
+		symbols[Group1]= [               w,               W ],
+		symbols[Group2]= [               z,               Z ]
+	};
+	key <TLDE>               {	[     twosuperior,      asciitilde ] };
+	key <AC12>               {	[        asterisk,              mu ] };
+	key <RTRN>               {	[          Return ] };
+	key <LFSH>               {	[         Shift_L ] };
+	modifier_map Shift { <LFSH> };
+};
+};
+`
+
+func TestParseKeymap(t *testing.T) {
+	kt, err := parseKeymap(testKeymap)
+	if err != nil {
+		t.Fatalf("parseKeymap: %v", err)
+	}
+	testCases := []struct {
+		keycode uint8
+		want    [4]uint32
+	}{
+		{9, [4]uint32{0xff1b, 0}},
+		// The third and fourth levels are not those of the second group.
+		{10, [4]uint32{'&', '1'}},
+		{24, [4]uint32{'a', 'A'}},
+		{52, [4]uint32{'w', 'W', 'z', 'Z'}},
+		// twosuperior is not a keysym name that this package knows.
+		{49, [4]uint32{0, '~'}},
+		// The symbols are for the alias of keycode 51.
+		{51, [4]uint32{'*', 0}},
+		{36, [4]uint32{0xff0d, 0}},
+		{50, [4]uint32{0xffe1, 0}},
+		{53, [4]uint32{0, 0}},
+	}
+	for _, tc := range testCases {
+		if got := kt[tc.keycode]; got != tc.want {
+			t.Errorf("keycode %d: got %#x, want %#x", tc.keycode, got, tc.want)
+		}
+	}
+
+	// The AZERTY layout's 'a' key is where the QWERTY layout's 'q' key is.
+	if r, c := kt.Lookup(24, 0); r != 'a' || c != key.CodeA {
+		t.Errorf("Lookup: got %q, %v, want 'a', %v", r, c, key.CodeA)
+	}
+
+	if got := groupNames(testKeymap); len(got) != 1 || got[0] != "French" {
+		t.Errorf("groupNames: got %q, want [\"French\"]", got)
+	}
+}
+
+func TestGroupSymbols(t *testing.T) {
+	testCases := []struct {
+		body string
+		want string
+	}{
+		{"{ [ a, A ] }", "[[a A] []]"},
+		{"{ [ q, Q ], [ Cyrillic_shorti, Cyrillic_SHORTI ] }", "[[q Q] [Cyrillic_shorti Cyrillic_SHORTI]]"},
+		{`{ type= "ALPHABETIC", symbols[Group2]= [ z, Z ] }`, "[[] [z Z]]"},
+		{`{ symbols[Group1]= [ w ], actions[Group1]= [ NoAction() ] }`, "[[w] []]"},
+	}
+	for _, tc := range testCases {
+		if got := fmt.Sprint(groupSymbols(tc.body)); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.body, got, tc.want)
+		}
+	}
+}
+
+func TestParseKeymapErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"xkb_keymap { xkb_keycodes { <AE01> = 10; }; };",
+		"xkb_keymap { xkb_keycodes { <AE01> = 10; }; xkb_symbols { key <AE01> { [ 1 ] };",
+	} {
+		if _, err := parseKeymap(s); err == nil {
+			t.Errorf("%q: got nil error, want non-nil", s)
+		}
+	}
+}
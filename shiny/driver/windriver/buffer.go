@@ -48,7 +48,7 @@ func (b *bufferImpl) preUpload() {
 		panic("windriver: Buffer.Upload called after Buffer.Release")
 	}
 	if b.nUpload == 0 {
-		swizzle.BGRA(b.buf)
+		b.swizzleAll()
 	}
 	b.nUpload++
 }
@@ -65,10 +65,17 @@ func (b *bufferImpl) postUpload() {
 	if b.released {
 		go b.cleanUp()
 	} else {
-		swizzle.BGRA(b.buf)
+		b.swizzleAll()
 	}
 }
 
+// swizzleAll swaps the R and B channels of all of b's pixels, in bands of rows
+// on multiple CPUs, for large buffers.
+func (b *bufferImpl) swizzleAll() {
+	stride := b.rgba.Stride
+	swizzle.Parallel(b.buf, stride, b.buf, stride, 4*b.size.X, b.size.Y, swizzle.CopyBGRA)
+}
+
 func (b *bufferImpl) Release() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -97,5 +104,5 @@ func (b *bufferImpl) blitToDC(dc syscall.Handle, dp image.Point, sr image.Rectan
 	defer b.postUpload()
 
 	dr := sr.Add(dp.Sub(sr.Min))
-	return copyBitmapToDC(dc, dr, b.hbitmap, sr, draw.Src)
+	return copyBitmapToDC(dc, dr, b.hbitmap, sr, draw.Src, 0xffff)
 }
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"image"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"unsafe"
 
@@ -22,8 +23,11 @@ var theScreen = &screenImpl{
 }
 
 type screenImpl struct {
-	mu      sync.Mutex
-	windows map[syscall.Handle]*windowImpl
+	textureBytes atomic.Int64
+
+	mu                   sync.Mutex
+	windows              map[syscall.Handle]*windowImpl
+	defaultWindowOptions *screen.NewWindowOptions
 }
 
 func (*screenImpl) NewBuffer(size image.Point) (screen.Buffer, error) {
@@ -58,14 +62,49 @@ func (*screenImpl) NewBuffer(size image.Point) (screen.Buffer, error) {
 }
 
 func (*screenImpl) NewTexture(size image.Point) (screen.Texture, error) {
-	return newTexture(size)
+	return newTexture(size, screen.PixelFormatRGBA8)
+}
+
+// NewTextureWithOptions implements screen.Screen. Only the format option is
+// honored, as GDI's StretchBlt and AlphaBlend neither choose their filter
+// nor tile.
+//
+// TODO: tile repeated textures in drawWindow, and use SetStretchBltMode for
+// the filter where AlphaBlend is not needed.
+func (*screenImpl) NewTextureWithOptions(size image.Point, opts *screen.NewTextureOptions) (screen.Texture, error) {
+	if opts != nil && opts.Compressed != screen.CompressedNone {
+		return nil, screen.ErrUnsupportedFormat
+	}
+	return newTexture(size, opts.GetFormat())
+}
+
+func (s *screenImpl) TextureMemoryEstimate() int64 {
+	return s.textureBytes.Load()
 }
 
 func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) {
+	s.mu.Lock()
+	opts = opts.WithDefaults(s.defaultWindowOptions)
+	s.mu.Unlock()
+
 	w := &windowImpl{}
+	if opts != nil {
+		w.Priority = opts.EventPriority
+		w.KeepAllMoves = opts.KeepAllMoves
+		w.KeyEvents = opts.KeyEvents
+		w.Queues = opts.EventQueues
+		w.transparent = opts.Transparent
+		w.occlusion.Policy = opts.OccludedPublish
+	}
 
+	var owner syscall.Handle
+	if opts != nil {
+		if o, ok := opts.Owner.(*windowImpl); ok {
+			owner = o.hwnd
+		}
+	}
 	var err error
-	w.hwnd, err = win32.NewWindow(opts)
+	w.hwnd, err = win32.NewWindow(opts, owner)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +117,40 @@ func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, er
 	if err != nil {
 		return nil, err
 	}
+	if opts != nil && len(opts.Shape) > 0 {
+		if err := win32.SetShape(w.hwnd, opts.Shape); err != nil {
+			return nil, err
+		}
+	}
 
-	win32.Show(w.hwnd)
+	win32.Show(w.hwnd, opts)
 	return w, nil
 }
+
+func (s *screenImpl) SetDefaultWindowOptions(opts *screen.NewWindowOptions) {
+	opts = opts.WithDefaults(nil)
+
+	s.mu.Lock()
+	s.defaultWindowOptions = opts
+	s.mu.Unlock()
+}
+
+func (*screenImpl) AccessibilityPrefs() screen.AccessibilityPrefs {
+	return win32.AccessibilityPrefs()
+}
+
+func (*screenImpl) ColorScheme() screen.ColorScheme {
+	return win32.ColorScheme()
+}
+
+func (*screenImpl) Clipboard() screen.Clipboard {
+	return win32.Clipboard{}
+}
+
+func (*screenImpl) Displays() []screen.Display {
+	return win32.Displays()
+}
+
+func (*screenImpl) NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	return win32.NewTrayIcon(opts)
+}
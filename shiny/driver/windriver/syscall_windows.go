@@ -19,6 +19,11 @@ type _POINT struct {
 	Y int32
 }
 
+type _SIZE struct {
+	CX int32
+	CY int32
+}
+
 type _RECT struct {
 	Left   int32
 	Top    int32
@@ -120,6 +125,8 @@ const (
 
 	_SHADEBLENDCAPS = 120
 	_SB_NONE        = 0
+
+	_ULW_ALPHA = 0x00000002
 )
 
 const (
@@ -165,3 +172,6 @@ func _HIWORD(l uintptr) uint16 {
 //sys	_SetWorldTransform(dc syscall.Handle, x *_XFORM) (err error) = gdi32.SetWorldTransform
 //sys	_StretchBlt(dcdest syscall.Handle, xdest int32, ydest int32, wdest int32, hdest int32, dcsrc syscall.Handle, xsrc int32, ysrc int32, wsrc int32, hsrc int32, rop uint32) (err error) = gdi32.StretchBlt
 //sys	_GetDeviceCaps(dc syscall.Handle, index int32) (ret int32) = gdi32.GetDeviceCaps
+//sys	_GetDIBits(dc syscall.Handle, bitmap syscall.Handle, startScan uint32, scanLines uint32, bits *byte, bmi *_BITMAPINFO, usage uint32) (lines int32, err error) = gdi32.GetDIBits
+//sys	_UpdateLayeredWindow(hwnd syscall.Handle, dcdest syscall.Handle, ptdest *_POINT, size *_SIZE, dcsrc syscall.Handle, ptsrc *_POINT, key _COLORREF, blend *_BLENDFUNCTION, flags uint32) (err error) = user32.UpdateLayeredWindow
+//sys	_SetDIBitsToDevice(dc syscall.Handle, xdest int32, ydest int32, width uint32, height uint32, xsrc int32, ysrc int32, startScan uint32, scanLines uint32, bits *byte, bmi *_BITMAPINFO, usage uint32) (lines int32, err error) = gdi32.SetDIBitsToDevice
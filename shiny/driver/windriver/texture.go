@@ -15,12 +15,16 @@ import (
 	"syscall"
 	"unsafe"
 
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
 	"golang.org/x/exp/shiny/driver/internal/win32"
+	"golang.org/x/exp/shiny/driver/internal/yuv"
 	"golang.org/x/exp/shiny/screen"
 )
 
 type textureImpl struct {
 	size   image.Point
+	format screen.PixelFormat
 	dc     syscall.Handle
 	bitmap syscall.Handle
 
@@ -37,19 +41,30 @@ type handleCreateTextureParams struct {
 
 var msgCreateTexture = win32.AddScreenMsg(handleCreateTexture)
 
-func newTexture(size image.Point) (screen.Texture, error) {
+// newTexture returns a new texture. Its bitmap is 32-bit BGRA whatever the
+// format, as GDI blits between bitmaps of the screen's format.
+func newTexture(size image.Point, format screen.PixelFormat) (screen.Texture, error) {
+	if format == screen.PixelFormatBGRA8 {
+		format = screen.PixelFormatRGBA8
+	}
 	p := handleCreateTextureParams{size: size}
 	win32.SendScreenMessage(msgCreateTexture, 0, uintptr(unsafe.Pointer(&p)))
 	if p.err != nil {
 		return nil, p.err
 	}
-	return &textureImpl{
+	t := &textureImpl{
 		size:   size,
+		format: format,
 		dc:     p.dc,
 		bitmap: p.bitmap,
-	}, nil
+	}
+	theScreen.textureBytes.Add(t.bytes())
+	return t, nil
 }
 
+// bytes returns the estimated memory used by the texture's bitmap.
+func (t *textureImpl) bytes() int64 { return 4 * int64(t.size.X) * int64(t.size.Y) }
+
 func handleCreateTexture(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) {
 	// This code needs to run on Windows message pump thread.
 	// Firstly, it calls GetDC(nil) and, according to Windows documentation
@@ -61,7 +76,7 @@ func handleCreateTexture(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintpt
 	// When this thread is destroyed, the HDC is no longer valid. ...
 	// So making Windows message pump thread own returned HDC makes DC
 	// live as long as we want to.
-	p := (*handleCreateTextureParams)(unsafe.Pointer(lParam))
+	p := (*handleCreateTextureParams)(win32.Pointer(lParam))
 
 	screenDC, err := win32.GetDC(0)
 	if err != nil {
@@ -89,9 +104,55 @@ func (t *textureImpl) Bounds() image.Rectangle {
 	return image.Rectangle{Max: t.size}
 }
 
+func (t *textureImpl) Format() screen.PixelFormat {
+	return t.format
+}
+
+func (t *textureImpl) Download(dp image.Point, sr image.Rectangle) (*image.RGBA, error) {
+	r := sr.Intersect(t.Bounds())
+	m := image.NewRGBA(r.Add(dp.Sub(sr.Min)))
+	if r.Empty() {
+		return m, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.released {
+		return nil, errors.New("windriver: Texture.Download called after Texture.Release")
+	}
+
+	// GetDIBits requires that t.bitmap is not selected into a device
+	// context, which it is not, other than during t.update. A negative
+	// height asks for the rows top-down.
+	bi := _BITMAPINFO{
+		Header: _BITMAPINFOHEADER{
+			Size:        uint32(unsafe.Sizeof(_BITMAPINFOHEADER{})),
+			Width:       int32(t.size.X),
+			Height:      -int32(t.size.Y),
+			Planes:      1,
+			BitCount:    32,
+			Compression: _BI_RGB,
+		},
+	}
+	stride := 4 * t.size.X
+	pix := make([]byte, stride*t.size.Y)
+	if _, err := _GetDIBits(t.dc, t.bitmap, 0, uint32(t.size.Y), &pix[0], &bi, _DIB_RGB_COLORS); err != nil {
+		return nil, err
+	}
+	swizzle.BGRA(pix)
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := (y - r.Min.Y) * m.Stride
+		j := y*stride + 4*r.Min.X
+		copy(m.Pix[i:i+4*r.Dx()], pix[j:])
+	}
+	return m, nil
+}
+
 func (t *textureImpl) Fill(r image.Rectangle, c color.Color, op draw.Op) {
 	err := t.update(func(dc syscall.Handle) error {
-		return fill(dc, r, c, op)
+		return fill(dc, r, c, op, false)
 	})
 	if err != nil {
 		panic(err) // TODO handle error
@@ -112,6 +173,7 @@ func (t *textureImpl) release() error {
 		return nil
 	}
 	t.released = true
+	theScreen.textureBytes.Add(-t.bytes())
 
 	err := _DeleteObject(t.bitmap)
 	if err != nil {
@@ -124,7 +186,18 @@ func (t *textureImpl) Size() image.Point {
 	return t.size
 }
 
+// UploadYUV converts src in software, as GDI bitmaps have no YUV formats.
+func (t *textureImpl) UploadYUV(dp image.Point, src *screen.YUVImage, sr image.Rectangle) {
+	yuv.Upload(theScreen, t, dp, src, sr)
+}
+
 func (t *textureImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	if t.format != screen.PixelFormatRGBA8 {
+		// The Buffer's pixels must first be converted to t's format.
+		p, _ := screen.PixelsOf(src.RGBA())
+		t.UploadPixels(dp, p, sr)
+		return
+	}
 	err := t.update(func(dc syscall.Handle) error {
 		return src.(*bufferImpl).blitToDC(dc, dp, sr)
 	})
@@ -133,6 +206,54 @@ func (t *textureImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectang
 	}
 }
 
+// UploadPixels implements screen.PixelUploader.
+//
+// PixelFormatBGRA8 pixels are GDI's native byte order, so they are set on an
+// RGBA8 texture's bitmap without swizzling. Other pixels are converted to the
+// texture's format, and then to BGRA, first.
+func (t *textureImpl) UploadPixels(dp image.Point, src *screen.Pixels, sr image.Rectangle) {
+	originalSRMin := sr.Min
+	sr = sr.Intersect(src.Rect)
+	dp = dp.Add(sr.Min.Sub(originalSRMin))
+	src2dst := dp.Sub(sr.Min)
+	dr := sr.Add(src2dst).Intersect(t.Bounds())
+	if dr.Empty() {
+		return
+	}
+	sr = dr.Sub(src2dst)
+
+	var pix []byte
+	if src.Format == screen.PixelFormatBGRA8 && t.format == screen.PixelFormatRGBA8 {
+		pix = make([]byte, 4*sr.Dx()*sr.Dy())
+		for y := 0; y < sr.Dy(); y++ {
+			copy(pix[4*sr.Dx()*y:][:4*sr.Dx()], src.Pix[src.PixOffset(sr.Min.X, sr.Min.Y+y):])
+		}
+	} else {
+		pix = drawer.ConvertPixels(t.format, src, sr).Pix
+		swizzle.BGRA(pix)
+	}
+
+	err := t.update(func(dc syscall.Handle) error {
+		// A negative height means that the rows are top-down.
+		bi := _BITMAPINFO{
+			Header: _BITMAPINFOHEADER{
+				Size:        uint32(unsafe.Sizeof(_BITMAPINFOHEADER{})),
+				Width:       int32(dr.Dx()),
+				Height:      -int32(dr.Dy()),
+				Planes:      1,
+				BitCount:    32,
+				Compression: _BI_RGB,
+			},
+		}
+		_, err := _SetDIBitsToDevice(dc, int32(dr.Min.X), int32(dr.Min.Y), uint32(dr.Dx()), uint32(dr.Dy()),
+			0, 0, 0, uint32(dr.Dy()), &pix[0], &bi, _DIB_RGB_COLORS)
+		return err
+	})
+	if err != nil {
+		panic(err) // TODO handle error
+	}
+}
+
 // update prepares texture t for update and executes f over texture device
 // context dc in a safe manner.
 func (t *textureImpl) update(f func(dc syscall.Handle) error) (retErr error) {
@@ -9,16 +9,21 @@ package windriver
 // TODO: implement a back buffer.
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	"math"
+	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 
+	"golang.org/x/exp/shiny/driver/internal/capture"
 	"golang.org/x/exp/shiny/driver/internal/drawer"
 	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/frame"
 	"golang.org/x/exp/shiny/driver/internal/win32"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/image/math/f64"
@@ -27,6 +32,7 @@ import (
 	"golang.org/x/mobile/event/mouse"
 	"golang.org/x/mobile/event/paint"
 	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/event/touch"
 )
 
 type windowImpl struct {
@@ -36,9 +42,48 @@ type windowImpl struct {
 
 	sz             size.Event
 	lifecycleStage lifecycle.Stage
+
+	imagePool drawer.ImagePool
+	layers    drawer.Layers
+	captures  capture.Hooks
+	timing    frame.Timing
+	occlusion frame.Occlusion
+
+	// mu protects released, which is whether Release has been called. It is
+	// held for reading while executing a cmd, so that a concurrent Release
+	// waits for any in-flight cmd to finish before destroying the window.
+	mu       sync.RWMutex
+	released bool
+
+	// transparent is whether the window is a layered window, with per-pixel
+	// alpha. Its Drawer methods then draw on back, a bitmap the size of the
+	// client area that is selected into backDC, and Publish shows back with
+	// UpdateLayeredWindow, with a constant alpha of 255 minus
+	// transparency, as set by SetOpacity. back, backDC, backSize and
+	// transparency are only used on the Windows message pump thread.
+	transparent  bool
+	back         syscall.Handle
+	backDC       syscall.Handle
+	backSize     image.Point
+	transparency byte
 }
 
 func (w *windowImpl) Release() {
+	w.mu.Lock()
+	released := w.released
+	w.released = true
+	w.mu.Unlock()
+	if released {
+		return
+	}
+
+	w.imagePool.Release()
+	w.layers.Release()
+	w.StopTimers()
+	if w.transparent {
+		// execCmd does nothing now that the window is released.
+		win32.SendMessage(w.hwnd, msgCmd, 0, uintptr(unsafe.Pointer(&cmd{id: cmdRelease, w: w})))
+	}
 	win32.Release(w.hwnd)
 }
 
@@ -73,7 +118,8 @@ func (w *windowImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectang
 		src2dst: src2dst,
 		texture: src.(*textureImpl).bitmap,
 		sr:      sr,
-		op:      op,
+		op:      gdiOp(op, opts),
+		alpha:   opts.GetAlpha(),
 	})
 }
 
@@ -85,13 +131,25 @@ func (w *windowImpl) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rec
 	w.execCmd(&cmd{
 		id:      cmdDrawUniform,
 		src2dst: src2dst,
-		color:   src,
+		color:   drawer.ScaleColor(src, opts.GetAlpha()),
 		sr:      sr,
-		op:      op,
+		op:      gdiOp(op, opts),
+		alpha:   0xffff,
 	})
 }
 
-func drawWindow(dc syscall.Handle, src2dst f64.Aff3, src interface{}, sr image.Rectangle, op draw.Op) (retErr error) {
+// gdiOp returns the draw.Op that GDI draws with, for the draw.Op op and the
+// options opts. GDI only has equivalents of screen.BlendSrc and
+// screen.BlendSrcOver, so that other blend modes, and BlendSrc with
+// transparency, are drawn as BlendSrcOver.
+func gdiOp(op draw.Op, opts *screen.DrawOptions) draw.Op {
+	if opts.GetBlend(op) == screen.BlendSrc && opts.GetAlpha() == 0xffff {
+		return draw.Src
+	}
+	return draw.Over
+}
+
+func drawWindow(dc syscall.Handle, src2dst f64.Aff3, src interface{}, sr image.Rectangle, op draw.Op, alpha uint16, withAlpha bool) (retErr error) {
 	var dr image.Rectangle
 	if src2dst[1] != 0 || src2dst[3] != 0 {
 		// general drawing
@@ -150,9 +208,9 @@ func drawWindow(dc syscall.Handle, src2dst f64.Aff3, src interface{}, sr image.R
 	}
 	switch s := src.(type) {
 	case syscall.Handle:
-		return copyBitmapToDC(dc, dr, s, sr, op)
+		return copyBitmapToDC(dc, dr, s, sr, op, alpha)
 	case color.Color:
-		return fill(dc, dr, s, op)
+		return fill(dc, dr, s, op, withAlpha)
 	}
 	return fmt.Errorf("unsupported type %T", src)
 }
@@ -165,24 +223,423 @@ func (w *windowImpl) Scale(dr image.Rectangle, src screen.Texture, sr image.Rect
 	drawer.Scale(w, dr, src, sr, op, opts)
 }
 
+func (w *windowImpl) DrawImage(dp image.Point, src image.Image, op draw.Op, opts *screen.DrawOptions) {
+	w.imagePool.DrawImage(theScreen, w, dp, src, op, opts)
+}
+
+func (w *windowImpl) NewLayer(z int, size image.Point) (screen.Layer, error) {
+	return w.layers.NewLayer(theScreen, z, size)
+}
+
 func (w *windowImpl) Publish() screen.PublishResult {
 	// TODO
-	return screen.PublishResult{}
+
+	composited := w.layers.Composite(w)
+
+	w.mu.RLock()
+	released := w.released
+	w.mu.RUnlock()
+	if released {
+		return screen.PublishResult{Dropped: true}
+	}
+	if !w.occlusion.Wait() {
+		return screen.PublishResult{BackBufferPreserved: !composited, Dropped: true}
+	}
+
+	if w.transparent {
+		w.execCmd(&cmd{id: cmdPublish})
+	}
+	// There is no back buffer (see the TODO above), so drawing happens on the
+	// window's device context directly, and its contents are preserved. Any
+	// invalidated contents will result in a WM_PAINT message and hence an
+	// external paint event. A transparent window's bitmap is likewise
+	// preserved. Compositing any layers overwrites the contents, though.
+	res := screen.PublishResult{BackBufferPreserved: !composited}
+	// The drawing has been done, but the Desktop Window Manager shows it at
+	// its next composition.
+	w.timing.Presented(&res, time.Now())
+
+	if w.captures.Active() {
+		c := &cmd{id: cmdCapture}
+		w.execCmd(c)
+		w.captures.Send(c.m)
+	}
+	return res
+}
+
+func (w *windowImpl) AddCaptureHook(fn func(f screen.CapturedFrame)) (remove func()) {
+	return w.captures.Add(fn)
+}
+
+// NextFrame sends a screen.FrameEvent when the Desktop Window Manager next
+// composes the window.
+func (w *windowImpl) NextFrame() {
+	w.mu.RLock()
+	released := w.released
+	w.mu.RUnlock()
+	if !released {
+		win32.NextFrame(w)
+	}
+}
+
+func (w *windowImpl) RenderFrame(fn func(d screen.Drawer)) error {
+	w.mu.RLock()
+	released := w.released
+	w.mu.RUnlock()
+	if released {
+		return errReleased
+	}
+
+	// Each draw is executed synchronously by execCmd, so the drawing is
+	// finished once fn returns. As with Publish, there is no back buffer (see
+	// the TODO above), so the drawing happens on the window directly.
+	fn(w)
+	if w.transparent {
+		w.execCmd(&cmd{id: cmdPublish})
+	}
+
+	w.mu.RLock()
+	released = w.released
+	w.mu.RUnlock()
+	if released {
+		return errReleased
+	}
+	return nil
+}
+
+var errReleased = errors.New("windriver: window is released")
+
+func (w *windowImpl) Begin() *screen.Context { return screen.NewContext(w) }
+
+func (w *windowImpl) GLInfo() screen.GLInfo { return screen.GLInfo{} }
+
+func (w *windowImpl) SetTitle(title string) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetTitle(w.hwnd, title)
+	}
+}
+
+func (w *windowImpl) SetIcon(m image.Image) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetIcon(w.hwnd, m)
+	}
+}
+
+func (w *windowImpl) SetBadge(label string) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetBadge(w.hwnd, label)
+	}
+}
+
+func (w *windowImpl) SetProgress(progress float64) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetProgress(w.hwnd, progress)
+	}
+}
+
+func (w *windowImpl) SetSize(width, height int) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released && width > 0 && height > 0 {
+		win32.SetSize(w.hwnd, width, height)
+	}
+}
+
+func (w *windowImpl) SetPosition(p image.Point) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetPosition(w.hwnd, p)
+	}
+}
+
+func (w *windowImpl) GetGeometry() (image.Point, image.Point) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return image.Point{}, image.Point{}
+	}
+	pos, sz, _ := win32.Geometry(w.hwnd)
+	return pos, sz
+}
+
+func (w *windowImpl) SetSizeConstraints(min, max image.Point) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetSizeConstraints(w.hwnd, min, max)
+	}
+}
+
+func (w *windowImpl) SetAspectRatio(aspect image.Point) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetAspectRatio(w.hwnd, aspect)
+	}
+}
+
+func (w *windowImpl) SetTextInputRect(r image.Rectangle) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetTextInputRect(w.hwnd, r)
+	}
+}
+
+func (w *windowImpl) SetCursor(c screen.Cursor) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetCursor(w.hwnd, c)
+	}
+}
+
+func (w *windowImpl) SetCursorVisible(visible bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetCursorVisible(w.hwnd, visible)
+	}
+}
+
+func (w *windowImpl) SetPointerCapture(capture bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetPointerCapture(w.hwnd, capture)
+	}
+}
+
+func (w *windowImpl) StartDrag(data screen.DragData) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.StartDrag(w.hwnd, data)
+}
+
+func (w *windowImpl) StartMove() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	win32.StartMove(w.hwnd)
+	return nil
+}
+
+func (w *windowImpl) StartResize(edge screen.WindowEdge) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	win32.StartResize(w.hwnd, edge)
+	return nil
+}
+
+func (w *windowImpl) ShowFileDialog(opts *screen.FileDialogOptions) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.ShowFileDialog(w.hwnd, opts)
+}
+
+func (w *windowImpl) SetMenuBar(bar *screen.Menu) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.SetMenuBar(w.hwnd, bar)
+}
+
+func (w *windowImpl) ShowContextMenu(m *screen.Menu, p image.Point) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.ShowContextMenu(w.hwnd, m, p)
+}
+
+func (w *windowImpl) SetAccessTree(root *screen.AccessNode) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.SetAccessTree(w.hwnd, root)
+}
+
+func (w *windowImpl) RegisterHotKey(k key.Code, mods key.Modifiers) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.RegisterHotKey(w.hwnd, k, mods)
+}
+
+func (w *windowImpl) UnregisterHotKey(k key.Code, mods key.Modifiers) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.UnregisterHotKey(w.hwnd, k, mods)
+	}
+}
+
+func (w *windowImpl) Notify(n *screen.Notification) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	return win32.Notify(w.hwnd, n)
+}
+
+func (w *windowImpl) CloseNotification(id int) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.CloseNotification(w.hwnd, id)
+	}
+}
+
+func (w *windowImpl) Minimize() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.Minimize(w.hwnd)
+	}
+}
+
+func (w *windowImpl) Maximize() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.Maximize(w.hwnd)
+	}
+}
+
+func (w *windowImpl) Restore() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.Restore(w.hwnd)
+	}
+}
+
+func (w *windowImpl) SetFullscreen(mode screen.FullscreenMode) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetFullscreen(w.hwnd, mode)
+	}
+}
+
+func (w *windowImpl) SetAlwaysOnTop(onTop bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetAlwaysOnTop(w.hwnd, onTop)
+	}
+}
+
+// SetOpacity shows a transparent window with a constant alpha, as well as its
+// per-pixel alpha, and makes any other window a layered window with a
+// constant alpha.
+func (w *windowImpl) SetOpacity(opacity float32) {
+	alpha := win32.Opacity(opacity)
+	if w.transparent {
+		w.execCmd(&cmd{id: cmdSetOpacity, alpha: uint16(alpha)})
+		return
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.SetOpacity(w.hwnd, alpha)
+	}
+}
+
+func (w *windowImpl) Raise() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.Raise(w.hwnd)
+	}
+}
+
+func (w *windowImpl) Lower() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.released {
+		win32.Lower(w.hwnd)
+	}
 }
 
 func init() {
-	send := func(hwnd syscall.Handle, e interface{}) {
+	sendAt := func(hwnd syscall.Handle, e interface{}, t time.Time) {
 		theScreen.mu.Lock()
 		w := theScreen.windows[hwnd]
 		theScreen.mu.Unlock()
 
-		w.Send(e)
+		if w == nil {
+			return // The window was released while a menu or dialog was open.
+		}
+		w.SendAt(e, t)
 	}
-	win32.MouseEvent = func(hwnd syscall.Handle, e mouse.Event) { send(hwnd, e) }
+	send := func(hwnd syscall.Handle, e interface{}) { sendAt(hwnd, e, time.Now()) }
+	// sendInput sends an input event at the time of its message.
+	sendInput := func(hwnd syscall.Handle, e interface{}) { sendAt(hwnd, e, win32.MessageTime()) }
+	win32.MouseEvent = func(hwnd syscall.Handle, e mouse.Event) { sendInput(hwnd, e) }
 	win32.PaintEvent = func(hwnd syscall.Handle, e paint.Event) { send(hwnd, e) }
-	win32.KeyEvent = func(hwnd syscall.Handle, e key.Event) { send(hwnd, e) }
+	win32.KeyEvent = func(hwnd syscall.Handle, e screen.KeyEvent) {
+		sendInput(hwnd, e)
+	}
 	win32.LifecycleEvent = lifecycleEvent
 	win32.SizeEvent = sizeEvent
+	win32.AccessibilityEvent = func(hwnd syscall.Handle, e screen.AccessibilityEvent) { send(hwnd, e) }
+	win32.ColorSchemeEvent = func(hwnd syscall.Handle, e screen.ColorSchemeEvent) { send(hwnd, e) }
+	win32.DisplayEvent = func(hwnd syscall.Handle, e screen.DisplayEvent) { send(hwnd, e) }
+	win32.ScaleEvent = func(hwnd syscall.Handle, e screen.ScaleEvent) { send(hwnd, e) }
+	win32.TextEvent = func(hwnd syscall.Handle, e screen.TextEvent) { sendInput(hwnd, e) }
+	win32.RelativeMouseEvent = func(hwnd syscall.Handle, e screen.RelativeMouseEvent) { sendInput(hwnd, e) }
+	win32.DragEvent = func(hwnd syscall.Handle, e screen.DragEvent) { send(hwnd, e) }
+	win32.WindowStateEvent = func(hwnd syscall.Handle, e screen.WindowStateEvent) { send(hwnd, e) }
+	win32.VisibilityEvent = func(hwnd syscall.Handle, e screen.VisibilityEvent) {
+		theScreen.mu.Lock()
+		w := theScreen.windows[hwnd]
+		theScreen.mu.Unlock()
+		if w != nil {
+			w.occlusion.Set(e.Visibility)
+		}
+		send(hwnd, e)
+	}
+	win32.CloseRequestEvent = func(hwnd syscall.Handle, e screen.CloseRequestEvent) { send(hwnd, e) }
+	win32.FileDialogEvent = func(hwnd syscall.Handle, e screen.FileDialogEvent) { send(hwnd, e) }
+	win32.MenuEvent = func(hwnd syscall.Handle, e screen.MenuEvent) { send(hwnd, e) }
+	win32.AccessActionEvent = func(hwnd syscall.Handle, e screen.AccessActionEvent) { send(hwnd, e) }
+	win32.ScrollEvent = func(hwnd syscall.Handle, e screen.ScrollEvent) { sendInput(hwnd, e) }
+	win32.TouchEvent = func(hwnd syscall.Handle, e touch.Event) { sendInput(hwnd, e) }
+	win32.PenEvent = func(hwnd syscall.Handle, e screen.PenEvent) { sendInput(hwnd, e) }
+	win32.CrossingEvent = func(hwnd syscall.Handle, e screen.CrossingEvent) { sendInput(hwnd, e) }
+	win32.FocusEvent = func(hwnd syscall.Handle, e screen.FocusEvent) { send(hwnd, e) }
+	win32.HotKeyEvent = func(hwnd syscall.Handle, e screen.HotKeyEvent) { send(hwnd, e) }
+	win32.KeyboardLayoutEvent = func(hwnd syscall.Handle, e screen.KeyboardLayoutEvent) { send(hwnd, e) }
+	win32.NotificationEvent = func(hwnd syscall.Handle, e screen.NotificationEvent) { send(hwnd, e) }
 }
 
 func lifecycleEvent(hwnd syscall.Handle, to lifecycle.Stage) {
@@ -218,6 +675,7 @@ func sizeEvent(hwnd syscall.Handle, e size.Event) {
 type cmd struct {
 	id  int
 	err error
+	w   *windowImpl
 
 	src2dst f64.Aff3
 	sr      image.Rectangle
@@ -225,8 +683,12 @@ type cmd struct {
 	dr      image.Rectangle
 	color   color.Color
 	op      draw.Op
+	alpha   uint16
 	texture syscall.Handle
 	buffer  *bufferImpl
+	// m is the frame read back by cmdCapture, or nil if the client area is
+	// empty.
+	m *image.RGBA
 }
 
 const (
@@ -234,11 +696,23 @@ const (
 	cmdFill
 	cmdUpload
 	cmdDrawUniform
+	cmdPublish
+	cmdRelease
+	cmdSetOpacity
+	cmdCapture
 )
 
 var msgCmd = win32.AddWindowMsg(handleCmd)
 
+// execCmd executes c on the Windows message pump thread. It does nothing if
+// the window has been released.
 func (w *windowImpl) execCmd(c *cmd) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	c.w = w
 	win32.SendMessage(w.hwnd, msgCmd, 0, uintptr(unsafe.Pointer(c)))
 	if c.err != nil {
 		panic(fmt.Sprintf("execCmd faild for cmd.id=%d: %v", c.id, c.err)) // TODO handle errors
@@ -246,28 +720,129 @@ func (w *windowImpl) execCmd(c *cmd) {
 }
 
 func handleCmd(hwnd syscall.Handle, uMsg uint32, wParam, lParam uintptr) {
-	c := (*cmd)(unsafe.Pointer(lParam))
+	c := (*cmd)(win32.Pointer(lParam))
+	w := c.w
 
-	dc, err := win32.GetDC(hwnd)
-	if err != nil {
-		c.err = err
+	switch c.id {
+	case cmdPublish:
+		c.err = w.updateLayered()
+		return
+	case cmdRelease:
+		w.releaseBack()
+		return
+	case cmdSetOpacity:
+		w.transparency = 255 - byte(c.alpha)
+		c.err = w.updateLayered()
 		return
 	}
-	defer win32.ReleaseDC(hwnd, dc)
+
+	var dc syscall.Handle
+	if w.transparent {
+		dc, c.err = w.backBuffer()
+		if c.err != nil || dc == 0 {
+			return
+		}
+	} else {
+		var err error
+		dc, err = win32.GetDC(hwnd)
+		if err != nil {
+			c.err = err
+			return
+		}
+		defer win32.ReleaseDC(hwnd, dc)
+	}
 
 	switch c.id {
 	case cmdDraw:
-		c.err = drawWindow(dc, c.src2dst, c.texture, c.sr, c.op)
+		c.err = drawWindow(dc, c.src2dst, c.texture, c.sr, c.op, c.alpha, w.transparent)
 	case cmdDrawUniform:
-		c.err = drawWindow(dc, c.src2dst, c.color, c.sr, c.op)
+		c.err = drawWindow(dc, c.src2dst, c.color, c.sr, c.op, c.alpha, w.transparent)
 	case cmdFill:
-		c.err = fill(dc, c.dr, c.color, c.op)
+		c.err = fill(dc, c.dr, c.color, c.op, w.transparent)
 	case cmdUpload:
 		// TODO: adjust if dp is outside dst bounds, or sr is outside buffer bounds.
 		dr := c.sr.Add(c.dp.Sub(c.sr.Min))
-		c.err = copyBitmapToDC(dc, dr, c.buffer.hbitmap, c.sr, draw.Src)
+		c.err = copyBitmapToDC(dc, dr, c.buffer.hbitmap, c.sr, draw.Src, 0xffff)
+	case cmdCapture:
+		var sz image.Point
+		if _, sz, c.err = win32.Geometry(hwnd); c.err == nil && sz.X > 0 && sz.Y > 0 {
+			c.m, c.err = readDC(dc, sz, w.transparent)
+		}
 	default:
 		c.err = fmt.Errorf("unknown command id=%d", c.id)
 	}
 	return
 }
+
+// backBuffer returns the device context of a transparent window's bitmap,
+// first re-creating the bitmap if the client area has changed size, or zero
+// if the client area is empty. A re-created bitmap is transparent black.
+func (w *windowImpl) backBuffer() (syscall.Handle, error) {
+	_, sz, err := win32.Geometry(w.hwnd)
+	if err != nil {
+		return 0, err
+	}
+	if sz.X <= 0 || sz.Y <= 0 {
+		return 0, nil
+	}
+	if w.back != 0 && sz == w.backSize {
+		return w.backDC, nil
+	}
+	if w.backDC == 0 {
+		if w.backDC, err = _CreateCompatibleDC(0); err != nil {
+			return 0, err
+		}
+	}
+	back, _, err := mkbitmap(sz)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := _SelectObject(w.backDC, back); err != nil {
+		_DeleteObject(back)
+		return 0, err
+	}
+	if w.back != 0 {
+		_DeleteObject(w.back)
+	}
+	w.back, w.backSize = back, sz
+	return w.backDC, nil
+}
+
+// updateLayered shows a transparent window's bitmap on screen.
+func (w *windowImpl) updateLayered() error {
+	if w.back == 0 {
+		return nil
+	}
+	size := _SIZE{CX: int32(w.backSize.X), CY: int32(w.backSize.Y)}
+	bf := blendOverFunc
+	bf.SourceConstantAlpha = 255 - w.transparency
+	return _UpdateLayeredWindow(w.hwnd, 0, nil, &size, w.backDC, &_POINT{}, 0, &bf, _ULW_ALPHA)
+}
+
+func (w *windowImpl) releaseBack() {
+	if w.backDC != 0 {
+		_DeleteDC(w.backDC)
+		w.backDC = 0
+	}
+	if w.back != 0 {
+		_DeleteObject(w.back)
+		w.back = 0
+	}
+}
+
+func (w *windowImpl) ColorSpace() screen.ColorSpace { return screen.ColorSpaceSRGB }
+
+// SetColorSpace only accepts screen.ColorSpaceSRGB, as GDI has no way to tag a
+// window with another color space.
+func (w *windowImpl) SetColorSpace(cs screen.ColorSpace) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.released {
+		return errReleased
+	}
+	if cs != screen.ColorSpaceSRGB {
+		return errors.New("windriver: color spaces other than sRGB are not supported")
+	}
+	return nil
+}
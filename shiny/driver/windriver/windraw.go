@@ -13,6 +13,8 @@ import (
 	"image/draw"
 	"syscall"
 	"unsafe"
+
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
 )
 
 func mkbitmap(size image.Point) (syscall.Handle, *byte, error) {
@@ -39,11 +41,13 @@ func mkbitmap(size image.Point) (syscall.Handle, *byte, error) {
 var blendOverFunc = _BLENDFUNCTION{
 	BlendOp:             _AC_SRC_OVER,
 	BlendFlags:          0,
-	SourceConstantAlpha: 255,           // only use per-pixel alphas
+	SourceConstantAlpha: 255,           // by default, only use per-pixel alphas
 	AlphaFormat:         _AC_SRC_ALPHA, // premultiplied
 }
 
-func copyBitmapToDC(dc syscall.Handle, dr image.Rectangle, src syscall.Handle, sr image.Rectangle, op draw.Op) (retErr error) {
+// copyBitmapToDC copies the sr rectangle of the bitmap src to the dr rectangle
+// of dc. For draw.Over, src's alpha is additionally scaled by alpha / 0xffff.
+func copyBitmapToDC(dc syscall.Handle, dr image.Rectangle, src syscall.Handle, sr image.Rectangle, op draw.Op, alpha uint16) (retErr error) {
 	memdc, err := _CreateCompatibleDC(dc)
 	if err != nil {
 		return err
@@ -73,21 +77,26 @@ func copyBitmapToDC(dc syscall.Handle, dr image.Rectangle, src syscall.Handle, s
 		return _StretchBlt(dc, int32(dr.Min.X), int32(dr.Min.Y), int32(dr.Dx()), int32(dr.Dy()),
 			memdc, int32(sr.Min.X), int32(sr.Min.Y), int32(sr.Dx()), int32(sr.Dy()), _SRCCOPY)
 	case draw.Over:
+		bf := blendOverFunc
+		bf.SourceConstantAlpha = byte(alpha >> 8)
 		return _AlphaBlend(dc, int32(dr.Min.X), int32(dr.Min.Y), int32(dr.Dx()), int32(dr.Dy()),
-			memdc, int32(sr.Min.X), int32(sr.Min.Y), int32(sr.Dx()), int32(sr.Dy()), blendOverFunc.ToUintptr())
+			memdc, int32(sr.Min.X), int32(sr.Min.Y), int32(sr.Dx()), int32(sr.Dy()), bf.ToUintptr())
 	default:
 		return fmt.Errorf("windriver: invalid draw operation %v", op)
 	}
 }
 
-func fill(dc syscall.Handle, dr image.Rectangle, c color.Color, op draw.Op) error {
+// fill fills the dr rectangle of dc with c. withAlpha is whether dc's alpha
+// channel is meaningful, as it is for a transparent window's back buffer, in
+// which case draw.Src also sets the alpha, which FillRect does not.
+func fill(dc syscall.Handle, dr image.Rectangle, c color.Color, op draw.Op, withAlpha bool) error {
 	r, g, b, a := c.RGBA()
 	r >>= 8
 	g >>= 8
 	b >>= 8
 	a >>= 8
 
-	if op == draw.Src {
+	if op == draw.Src && !withAlpha {
 		color := _RGB(byte(r), byte(g), byte(b))
 		brush, err := _CreateSolidBrush(color)
 		if err != nil {
@@ -118,5 +127,57 @@ func fill(dc syscall.Handle, dr image.Rectangle, c color.Color, op draw.Op) erro
 	color := _COLORREF((a << 24) | (r << 16) | (g << 8) | b)
 	*(*_COLORREF)(unsafe.Pointer(bitvalues)) = color
 
-	return copyBitmapToDC(dc, dr, bitmap, sr, draw.Over)
+	return copyBitmapToDC(dc, dr, bitmap, sr, op, 0xffff)
+}
+
+// readDC reads back the top left sz pixels of dc. withAlpha is as for fill:
+// if it is false, the pixels read are opaque.
+func readDC(dc syscall.Handle, sz image.Point, withAlpha bool) (*image.RGBA, error) {
+	memdc, err := _CreateCompatibleDC(dc)
+	if err != nil {
+		return nil, err
+	}
+	defer _DeleteDC(memdc)
+
+	bitmap, _, err := mkbitmap(sz)
+	if err != nil {
+		return nil, err
+	}
+	defer _DeleteObject(bitmap)
+
+	prev, err := _SelectObject(memdc, bitmap)
+	if err != nil {
+		return nil, err
+	}
+	err = _BitBlt(memdc, 0, 0, int32(sz.X), int32(sz.Y), dc, 0, 0, _SRCCOPY)
+	// GetDIBits requires that bitmap is not selected into a device context.
+	if _, err2 := _SelectObject(memdc, prev); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A negative height asks for the rows top-down.
+	bi := _BITMAPINFO{
+		Header: _BITMAPINFOHEADER{
+			Size:        uint32(unsafe.Sizeof(_BITMAPINFOHEADER{})),
+			Width:       int32(sz.X),
+			Height:      -int32(sz.Y),
+			Planes:      1,
+			BitCount:    32,
+			Compression: _BI_RGB,
+		},
+	}
+	m := image.NewRGBA(image.Rectangle{Max: sz})
+	if _, err := _GetDIBits(memdc, bitmap, 0, uint32(sz.Y), &m.Pix[0], &bi, _DIB_RGB_COLORS); err != nil {
+		return nil, err
+	}
+	swizzle.BGRA(m.Pix)
+	if !withAlpha {
+		for i := 3; i < len(m.Pix); i += 4 {
+			m.Pix[i] = 0xff
+		}
+	}
+	return m, nil
 }
@@ -31,6 +31,9 @@ var (
 	procSetWorldTransform      = modgdi32.NewProc("SetWorldTransform")
 	procStretchBlt             = modgdi32.NewProc("StretchBlt")
 	procGetDeviceCaps          = modgdi32.NewProc("GetDeviceCaps")
+	procGetDIBits              = modgdi32.NewProc("GetDIBits")
+	procUpdateLayeredWindow    = moduser32.NewProc("UpdateLayeredWindow")
+	procSetDIBitsToDevice      = modgdi32.NewProc("SetDIBitsToDevice")
 )
 
 func _AlphaBlend(dcdest syscall.Handle, xoriginDest int32, yoriginDest int32, wDest int32, hDest int32, dcsrc syscall.Handle, xoriginSrc int32, yoriginSrc int32, wsrc int32, hsrc int32, ftn uintptr) (err error) {
@@ -212,3 +215,41 @@ func _GetDeviceCaps(dc syscall.Handle, index int32) (ret int32) {
 	ret = int32(r0)
 	return
 }
+
+func _GetDIBits(dc syscall.Handle, bitmap syscall.Handle, startScan uint32, scanLines uint32, bits *byte, bmi *_BITMAPINFO, usage uint32) (lines int32, err error) {
+	r0, _, e1 := syscall.Syscall9(procGetDIBits.Addr(), 7, uintptr(dc), uintptr(bitmap), uintptr(startScan), uintptr(scanLines), uintptr(unsafe.Pointer(bits)), uintptr(unsafe.Pointer(bmi)), uintptr(usage), 0, 0)
+	lines = int32(r0)
+	if lines == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _UpdateLayeredWindow(hwnd syscall.Handle, dcdest syscall.Handle, ptdest *_POINT, size *_SIZE, dcsrc syscall.Handle, ptsrc *_POINT, key _COLORREF, blend *_BLENDFUNCTION, flags uint32) (err error) {
+	r1, _, e1 := syscall.Syscall9(procUpdateLayeredWindow.Addr(), 9, uintptr(hwnd), uintptr(dcdest), uintptr(unsafe.Pointer(ptdest)), uintptr(unsafe.Pointer(size)), uintptr(dcsrc), uintptr(unsafe.Pointer(ptsrc)), uintptr(key), uintptr(unsafe.Pointer(blend)), uintptr(flags))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _SetDIBitsToDevice(dc syscall.Handle, xdest int32, ydest int32, width uint32, height uint32, xsrc int32, ysrc int32, startScan uint32, scanLines uint32, bits *byte, bmi *_BITMAPINFO, usage uint32) (lines int32, err error) {
+	r0, _, e1 := syscall.Syscall12(procSetDIBitsToDevice.Addr(), 12, uintptr(dc), uintptr(xdest), uintptr(ydest), uintptr(width), uintptr(height), uintptr(xsrc), uintptr(ysrc), uintptr(startScan), uintptr(scanLines), uintptr(unsafe.Pointer(bits)), uintptr(unsafe.Pointer(bmi)), uintptr(usage))
+	lines = int32(r0)
+	if lines == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
@@ -29,8 +29,12 @@ type bufferImpl struct {
 	size image.Point
 	xs   shm.Seg
 
-	mu        sync.Mutex
-	nUpload   uint32
+	mu      sync.Mutex
+	nUpload uint32
+	// swizzled is the part of buf that is in BGRA order, while nUpload is
+	// non-zero. Only the rectangles being uploaded are swizzled, so that the
+	// cost of an upload is proportional to its size, not to the Buffer's.
+	swizzled  image.Rectangle
 	released  bool
 	cleanedUp bool
 }
@@ -40,7 +44,7 @@ func (b *bufferImpl) Size() image.Point       { return b.size }
 func (b *bufferImpl) Bounds() image.Rectangle { return image.Rectangle{Max: b.size} }
 func (b *bufferImpl) RGBA() *image.RGBA       { return &b.rgba }
 
-func (b *bufferImpl) preUpload() {
+func (b *bufferImpl) preUpload(sr image.Rectangle) {
 	// Check that the program hasn't tried to modify the rgba field via the
 	// pointer returned by the bufferImpl.RGBA method. This check doesn't catch
 	// 100% of all cases; it simply tries to detect some invalid uses of a
@@ -57,7 +61,14 @@ func (b *bufferImpl) preUpload() {
 		panic("x11driver: Buffer.Upload called after Buffer.Release")
 	}
 	if b.nUpload == 0 {
-		swizzle.BGRA(b.buf)
+		b.swizzleExcept(sr, image.Rectangle{})
+		b.swizzled = sr
+	} else if !sr.In(b.swizzled) {
+		// Another upload is in progress. Grow the swizzled region, without
+		// swizzling its existing pixels back to RGBA order.
+		u := b.swizzled.Union(sr)
+		b.swizzleExcept(u, b.swizzled)
+		b.swizzled = u
 	}
 	b.nUpload++
 }
@@ -74,8 +85,32 @@ func (b *bufferImpl) postUpload() {
 	if b.released {
 		go b.cleanUp()
 	} else {
-		swizzle.BGRA(b.buf)
+		b.swizzleExcept(b.swizzled, image.Rectangle{})
 	}
+	b.swizzled = image.Rectangle{}
+}
+
+// swizzleExcept swaps the R and B channels of the pixels in r that are not in
+// except, which must be empty or be contained by r.
+func (b *bufferImpl) swizzleExcept(r, except image.Rectangle) {
+	if except.Empty() {
+		b.swizzleRect(r)
+		return
+	}
+	b.swizzleRect(image.Rect(r.Min.X, r.Min.Y, r.Max.X, except.Min.Y))
+	b.swizzleRect(image.Rect(r.Min.X, except.Min.Y, except.Min.X, except.Max.Y))
+	b.swizzleRect(image.Rect(except.Max.X, except.Min.Y, r.Max.X, except.Max.Y))
+	b.swizzleRect(image.Rect(r.Min.X, except.Max.Y, r.Max.X, r.Max.Y))
+}
+
+// swizzleRect swaps the R and B channels of the pixels in r, in bands of rows
+// on multiple CPUs, for large rectangles.
+func (b *bufferImpl) swizzleRect(r image.Rectangle) {
+	if r.Empty() {
+		return
+	}
+	i, stride := b.rgba.PixOffset(r.Min.X, r.Min.Y), b.rgba.Stride
+	swizzle.Parallel(b.buf[i:], stride, b.buf[i:], stride, 4*r.Dx(), r.Dy(), swizzle.CopyBGRA)
 }
 
 func (b *bufferImpl) Release() {
@@ -97,6 +132,12 @@ func (b *bufferImpl) cleanUp() {
 	b.cleanedUp = true
 	b.mu.Unlock()
 
+	if b.addr == nil && !b.degenerate() {
+		// The Buffer is in Go memory.
+		b.s.pixPool.Put(b.buf)
+		return
+	}
+
 	b.s.mu.Lock()
 	delete(b.s.buffers, b.xs)
 	b.s.mu.Unlock()
@@ -104,12 +145,67 @@ func (b *bufferImpl) cleanUp() {
 	if b.degenerate() {
 		return
 	}
-	shm.Detach(b.s.xc, b.xs)
-	if err := shmClose(b.addr); err != nil {
+	b.s.recycleShm(shmSegment{xs: b.xs, addr: b.addr, buf: b.buf})
+}
+
+// shmSegment is a shared memory segment, attached to the X11 server, that
+// backs a Buffer.
+type shmSegment struct {
+	xs   shm.Seg
+	addr unsafe.Pointer
+	buf  []byte
+}
+
+func (seg shmSegment) close(xc *xgb.Conn) {
+	shm.Detach(xc, seg.xs)
+	if err := shmClose(seg.addr); err != nil {
 		log.Printf("x11driver: shmClose: %v", err)
 	}
 }
 
+// reuseShm returns the most recently released segment of length n, zeroed,
+// if there is one. Re-using segments saves a shmget and shmat, and a round
+// trip to the X11 server, for programs that allocate and release a Buffer
+// every frame.
+func (s *screenImpl) reuseShm(n int) (shmSegment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.freeShm) - 1; i >= 0; i-- {
+		seg := s.freeShm[i]
+		if len(seg.buf) != n {
+			continue
+		}
+		s.freeShm = append(s.freeShm[:i], s.freeShm[i+1:]...)
+		s.freeShmSize -= n
+		for j := range seg.buf {
+			seg.buf[j] = 0
+		}
+		return seg, true
+	}
+	return shmSegment{}, false
+}
+
+// recycleShm keeps seg for re-use by reuseShm, closing the least recently
+// released segments if their total size would exceed maxFreeShmSize.
+func (s *screenImpl) recycleShm(seg shmSegment) {
+	s.mu.Lock()
+	s.freeShm = append(s.freeShm, seg)
+	s.freeShmSize += len(seg.buf)
+	n := 0
+	for s.freeShmSize > maxFreeShmSize {
+		s.freeShmSize -= len(s.freeShm[n].buf)
+		n++
+	}
+	evicted := append([]shmSegment(nil), s.freeShm[:n]...)
+	s.freeShm = append(s.freeShm[:0], s.freeShm[n:]...)
+	s.mu.Unlock()
+
+	for _, e := range evicted {
+		e.close(s.xc)
+	}
+}
+
 func (b *bufferImpl) upload(xd xproto.Drawable, xg xproto.Gcontext, depth uint8, dp image.Point, sr image.Rectangle) {
 	originalSRMin := sr.Min
 	sr = sr.Intersect(b.Bounds())
@@ -117,7 +213,14 @@ func (b *bufferImpl) upload(xd xproto.Drawable, xg xproto.Gcontext, depth uint8,
 		return
 	}
 	dp = dp.Add(sr.Min.Sub(originalSRMin))
-	b.preUpload()
+	b.preUpload(sr)
+
+	if b.addr == nil {
+		dr := image.Rectangle{Min: dp, Max: dp.Add(sr.Size())}
+		putImage(b.s.xc, xd, xg, depth, dr, b.buf[b.rgba.PixOffset(sr.Min.X, sr.Min.Y):], b.rgba.Stride)
+		b.postUpload()
+		return
+	}
 
 	b.s.mu.Lock()
 	b.s.nPendingUploads++
@@ -146,6 +249,34 @@ func (b *bufferImpl) upload(xd xproto.Drawable, xg xproto.Gcontext, depth uint8,
 	b.postUpload()
 }
 
+// putImage sends the BGRA pixels of dr, whose rows start every stride bytes
+// of pix, to xd. Unlike a shared memory Buffer's upload, it copies the pixels
+// into as many X11 requests as they need.
+func putImage(xc *xgb.Conn, xd xproto.Drawable, xg xproto.Gcontext, depth uint8, dr image.Rectangle, pix []byte, stride int) {
+	rowBytes := 4 * dr.Dx()
+	maxRows := (4*int(xproto.Setup(xc).MaximumRequestLength) - 24) / rowBytes
+	if maxRows < 1 {
+		// TODO: split rows that are too wide for a single request.
+		return
+	}
+	if maxRows > dr.Dy() {
+		maxRows = dr.Dy()
+	}
+	buf := make([]byte, 0, rowBytes*maxRows)
+	for y := 0; y < dr.Dy(); y += maxRows {
+		n := maxRows
+		if n > dr.Dy()-y {
+			n = dr.Dy() - y
+		}
+		buf = buf[:0]
+		for j := y; j < y+n; j++ {
+			buf = append(buf, pix[j*stride:][:rowBytes]...)
+		}
+		xproto.PutImage(xc, xproto.ImageFormatZPixmap, xd, xg,
+			uint16(dr.Dx()), uint16(n), int16(dr.Min.X), int16(dr.Min.Y+y), 0, depth, buf)
+	}
+}
+
 func fill(xc *xgb.Conn, xp render.Picture, dr image.Rectangle, src color.Color, op draw.Op) {
 	r, g, b, a := src.RGBA()
 	c := render.Color{
@@ -0,0 +1,47 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPartialSwizzle(t *testing.T) {
+	size := image.Point{4, 3}
+	b := &bufferImpl{size: size}
+	b.rgba = *image.NewRGBA(image.Rectangle{Max: size})
+	b.buf = b.rgba.Pix
+	for i := 0; i < len(b.buf); i += 4 {
+		b.buf[i+0], b.buf[i+2] = 1, 2
+	}
+	swizzled := func(x, y int) bool {
+		return b.buf[b.rgba.PixOffset(x, y)] == 2
+	}
+
+	// Two overlapping uploads are in flight at once.
+	r0 := image.Rect(1, 0, 3, 2)
+	r1 := image.Rect(2, 1, 4, 3)
+	b.preUpload(r0)
+	b.preUpload(r1)
+	u := r0.Union(r1)
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			if got, want := swizzled(x, y), image.Pt(x, y).In(u); got != want {
+				t.Errorf("during uploads, (%d, %d): got swizzled %t, want %t", x, y, got, want)
+			}
+		}
+	}
+
+	b.postUpload()
+	b.postUpload()
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			if swizzled(x, y) {
+				t.Errorf("after uploads, (%d, %d): got swizzled, want not", x, y)
+			}
+		}
+	}
+}
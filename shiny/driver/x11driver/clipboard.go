@@ -0,0 +1,356 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// The X11 clipboard is the CLIPBOARD selection, as specified by the ICCCM at
+// https://www.x.org/releases/X11R7.6/doc/xorg-docs/specs/ICCCM/icccm.html#use_of_selection_atoms
+//
+// Text that is too large for a single request is transferred incrementally,
+// using the INCR protocol.
+
+// clipboardTimeout is how long ReadText waits for the selection owner to
+// respond, before giving up.
+const clipboardTimeout = 2 * time.Second
+
+var errClipboardTimeout = errors.New("x11driver: timed out reading the clipboard")
+
+type clipboardImpl struct {
+	s *screenImpl
+
+	// xw is an unmapped window that owns the selection while we hold the
+	// clipboard, and that receives the clipboard's contents when reading it.
+	xw xproto.Window
+
+	atomClipboard xproto.Atom
+	atomIncr      xproto.Atom
+	atomTargets   xproto.Atom
+	atomText      xproto.Atom
+	// atomProperty names the property of xw that selection owners write
+	// the clipboard's contents to.
+	atomProperty xproto.Atom
+
+	// maxChunk is the largest number of bytes that can be sent in a single
+	// ChangeProperty request.
+	maxChunk int
+
+	// readMu serializes calls to convert, as each uses the same property.
+	readMu sync.Mutex
+	// notifyc and propertyc are sent SelectionNotify events and PropertyNotify
+	// (new value) events for xw by the screenImpl.run goroutine, while a
+	// convert call is in progress.
+	notifyc   chan xproto.SelectionNotifyEvent
+	propertyc chan xproto.PropertyNotifyEvent
+
+	mu    sync.Mutex
+	owned bool
+	text  string
+
+	// incrs holds the in-progress INCR transfers to other clients. It is
+	// only accessed by the screenImpl.run goroutine.
+	incrs map[incrKey]*incrTransfer
+}
+
+type incrKey struct {
+	requestor xproto.Window
+	property  xproto.Atom
+}
+
+type incrTransfer struct {
+	typ  xproto.Atom
+	data []byte
+}
+
+func (s *screenImpl) initClipboard() (err error) {
+	c := &s.clipboard
+	c.s = s
+	c.notifyc = make(chan xproto.SelectionNotifyEvent, 1)
+	c.propertyc = make(chan xproto.PropertyNotifyEvent, 1)
+	c.incrs = map[incrKey]*incrTransfer{}
+
+	// A ChangeProperty request has a 24 byte header, and the maximum request
+	// length is in units of 4 bytes.
+	c.maxChunk = 4*int(xproto.Setup(s.xc).MaximumRequestLength) - 24
+	if c.maxChunk > 1<<16 {
+		c.maxChunk = 1 << 16
+	}
+
+	if c.atomClipboard, err = s.internAtom("CLIPBOARD"); err != nil {
+		return err
+	}
+	if c.atomIncr, err = s.internAtom("INCR"); err != nil {
+		return err
+	}
+	if c.atomTargets, err = s.internAtom("TARGETS"); err != nil {
+		return err
+	}
+	if c.atomText, err = s.internAtom("TEXT"); err != nil {
+		return err
+	}
+	if c.atomProperty, err = s.internAtom("_SHINY_CLIPBOARD"); err != nil {
+		return err
+	}
+
+	c.xw, err = xproto.NewWindowId(s.xc)
+	if err != nil {
+		return fmt.Errorf("x11driver: xproto.NewWindowId failed: %v", err)
+	}
+	xproto.CreateWindow(s.xc, s.xsi.RootDepth, c.xw, s.xsi.Root,
+		0, 0, 1, 1, 0,
+		xproto.WindowClassInputOutput, s.xsi.RootVisual,
+		xproto.CwEventMask,
+		[]uint32{xproto.EventMaskPropertyChange},
+	)
+	return nil
+}
+
+func (c *clipboardImpl) ReadText() (string, error) {
+	c.mu.Lock()
+	owned, text := c.owned, c.text
+	c.mu.Unlock()
+	if owned {
+		return text, nil
+	}
+
+	b, err := c.convert(c.atomClipboard, c.s.atomUTF8String, xproto.TimeCurrentTime)
+	return string(b), err
+}
+
+// convert asks the owner of the selection to convert it to the target type,
+// and returns the converted data. It returns nil data and a nil error if
+// there is no owner, or if the owner cannot convert to that target.
+func (c *clipboardImpl) convert(selection, target xproto.Atom, t xproto.Timestamp) ([]byte, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	// Discard any events left over from an earlier call that timed out.
+	select {
+	case <-c.notifyc:
+	default:
+	}
+	select {
+	case <-c.propertyc:
+	default:
+	}
+
+	xproto.ConvertSelection(c.s.xc, c.xw, selection, target, c.atomProperty, t)
+	var ev xproto.SelectionNotifyEvent
+	select {
+	case ev = <-c.notifyc:
+	case <-time.After(clipboardTimeout):
+		return nil, errClipboardTimeout
+	}
+	if ev.Property == xproto.AtomNone {
+		// There is no selection owner, or it cannot convert to the target.
+		return nil, nil
+	}
+
+	r, err := c.getProperty()
+	if err != nil {
+		return nil, err
+	}
+	if r.Type != c.atomIncr {
+		return r.Value, nil
+	}
+
+	// Deleting the INCR property, which getProperty did, starts the
+	// transfer. The owner then writes each chunk of data to the property,
+	// waiting for us to delete it before writing the next one, and finishes
+	// with a zero-length chunk.
+	var b []byte
+	for {
+		select {
+		case <-c.propertyc:
+		case <-time.After(clipboardTimeout):
+			return nil, errClipboardTimeout
+		}
+		r, err := c.getProperty()
+		if err != nil {
+			return nil, err
+		}
+		if len(r.Value) == 0 {
+			return b, nil
+		}
+		b = append(b, r.Value...)
+	}
+}
+
+// getProperty reads and deletes the c.atomProperty property of c.xw.
+func (c *clipboardImpl) getProperty() (*xproto.GetPropertyReply, error) {
+	// The long length is in units of 4 bytes.
+	const longLength = 1 << 28
+	r, err := xproto.GetProperty(c.s.xc, true, c.xw, c.atomProperty,
+		xproto.GetPropertyTypeAny, 0, longLength).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("x11driver: xproto.GetProperty failed: %v", err)
+	}
+	return r, nil
+}
+
+func (c *clipboardImpl) WriteText(text string) error {
+	c.mu.Lock()
+	c.owned, c.text = true, text
+	c.mu.Unlock()
+
+	xproto.SetSelectionOwner(c.s.xc, c.xw, c.atomClipboard, xproto.TimeCurrentTime)
+	r, err := xproto.GetSelectionOwner(c.s.xc, c.atomClipboard).Reply()
+	if err != nil {
+		return fmt.Errorf("x11driver: xproto.GetSelectionOwner failed: %v", err)
+	}
+	if r.Owner != c.xw {
+		c.mu.Lock()
+		c.owned, c.text = false, ""
+		c.mu.Unlock()
+		return errors.New("x11driver: could not take ownership of the clipboard")
+	}
+	return nil
+}
+
+// handleSelectionClear must only be called from the screenImpl.run goroutine.
+func (c *clipboardImpl) handleSelectionClear(ev xproto.SelectionClearEvent) {
+	if ev.Owner != c.xw || ev.Selection != c.atomClipboard {
+		return
+	}
+	c.mu.Lock()
+	c.owned, c.text = false, ""
+	c.mu.Unlock()
+}
+
+// handleSelectionNotify must only be called from the screenImpl.run
+// goroutine.
+func (c *clipboardImpl) handleSelectionNotify(ev xproto.SelectionNotifyEvent) {
+	// Any selection converted for xw, such as a drag-and-drop's XdndSelection
+	// as well as the CLIPBOARD, is for the convert call in progress.
+	if ev.Requestor != c.xw {
+		return
+	}
+	select {
+	case c.notifyc <- ev:
+	default:
+	}
+}
+
+// handleSelectionRequest must only be called from the screenImpl.run
+// goroutine.
+func (c *clipboardImpl) handleSelectionRequest(ev xproto.SelectionRequestEvent) {
+	property := ev.Property
+	if property == xproto.AtomNone {
+		// Obsolete clients use the target as the property.
+		property = ev.Target
+	}
+
+	c.mu.Lock()
+	owned, text := c.owned, c.text
+	c.mu.Unlock()
+
+	switch {
+	case !owned || ev.Owner != c.xw || ev.Selection != c.atomClipboard:
+		property = xproto.AtomNone
+
+	case ev.Target == c.atomTargets:
+		c.s.setProperty(ev.Requestor, property,
+			c.atomTargets, c.s.atomUTF8String, c.atomText, xproto.AtomString)
+
+	case ev.Target == c.s.atomUTF8String || ev.Target == c.atomText || ev.Target == xproto.AtomString:
+		typ, data := c.s.atomUTF8String, []byte(text)
+		if ev.Target == xproto.AtomString {
+			typ, data = xproto.AtomString, latin1(text)
+		}
+		c.sendData(ev.Requestor, property, typ, data)
+
+	default:
+		property = xproto.AtomNone
+	}
+	c.notify(ev, property)
+}
+
+// sendData writes data, of the given type, to the requestor's property, in
+// reply to a SelectionRequest. It must only be called from the
+// screenImpl.run goroutine.
+func (c *clipboardImpl) sendData(requestor xproto.Window, property, typ xproto.Atom, data []byte) {
+	xc := c.s.xc
+	if len(data) <= c.maxChunk {
+		xproto.ChangeProperty(xc, xproto.PropModeReplace, requestor, property, typ, 8, uint32(len(data)), data)
+		return
+	}
+	// Start an INCR transfer. handlePropertyNotify sends the data when the
+	// requestor deletes the INCR property.
+	xproto.ChangeWindowAttributes(xc, requestor, xproto.CwEventMask,
+		[]uint32{xproto.EventMaskPropertyChange})
+	n := uint32(len(data))
+	xproto.ChangeProperty(xc, xproto.PropModeReplace, requestor, property, c.atomIncr, 32, 1,
+		[]byte{uint8(n >> 0), uint8(n >> 8), uint8(n >> 16), uint8(n >> 24)})
+	c.incrs[incrKey{requestor, property}] = &incrTransfer{
+		typ:  typ,
+		data: data,
+	}
+}
+
+// notify tells the requestor of a SelectionRequest that the selection has
+// been written to property, or that it could not be converted if property
+// is None.
+func (c *clipboardImpl) notify(ev xproto.SelectionRequestEvent, property xproto.Atom) {
+	notify := xproto.SelectionNotifyEvent{
+		Time:      ev.Time,
+		Requestor: ev.Requestor,
+		Selection: ev.Selection,
+		Target:    ev.Target,
+		Property:  property,
+	}
+	xproto.SendEvent(c.s.xc, false, ev.Requestor, 0, string(notify.Bytes()))
+}
+
+// handlePropertyNotify must only be called from the screenImpl.run goroutine.
+func (c *clipboardImpl) handlePropertyNotify(ev xproto.PropertyNotifyEvent) {
+	if ev.Window == c.xw {
+		if ev.Atom == c.atomProperty && ev.State == xproto.PropertyNewValue {
+			select {
+			case c.propertyc <- ev:
+			default:
+			}
+		}
+		return
+	}
+
+	if ev.State != xproto.PropertyDelete {
+		return
+	}
+	k := incrKey{ev.Window, ev.Atom}
+	t := c.incrs[k]
+	if t == nil {
+		return
+	}
+	// Send the next chunk. An empty chunk ends the transfer.
+	n := len(t.data)
+	if n > c.maxChunk {
+		n = c.maxChunk
+	}
+	xproto.ChangeProperty(c.s.xc, xproto.PropModeReplace, ev.Window, ev.Atom, t.typ, 8, uint32(n), t.data[:n])
+	if n == 0 {
+		delete(c.incrs, k)
+	}
+	t.data = t.data[n:]
+}
+
+// latin1 returns s encoded as ISO Latin-1, the encoding of the STRING target,
+// replacing those runes that cannot be encoded with '?'.
+func latin1(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			r = '?'
+		}
+		b = append(b, byte(r))
+	}
+	return b
+}
@@ -0,0 +1,37 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestLatin1(t *testing.T) {
+	if got, want := string(latin1("café 日本")), "caf\xe9 ??"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestClipboardText(t *testing.T) {
+	Main(func(s screen.Screen) {
+		if _, ok := s.(*screenImpl); !ok {
+			t.Skip("no X11 connection")
+		}
+		c := s.Clipboard()
+		const want = "Hello, 世界"
+		if err := c.WriteText(want); err != nil {
+			t.Fatalf("WriteText: %v", err)
+		}
+		got, err := c.ReadText()
+		if err != nil {
+			t.Fatalf("ReadText: %v", err)
+		}
+		if got != want {
+			t.Errorf("ReadText: got %q, want %q", got, want)
+		}
+	})
+}
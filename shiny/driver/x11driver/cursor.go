@@ -0,0 +1,212 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"log"
+
+	"github.com/BurntSushi/xgb/render"
+	"github.com/BurntSushi/xgb/xproto"
+
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// cursorGlyphs are the glyphs, in the X11 "cursor" font, of the
+// screen.CursorShapes. The font has no diagonal resize or not-allowed
+// cursors, so the nearest equivalents are used.
+//
+// TODO: load the user's Xcursor theme, as Xlib does, instead of the cursor
+// font's old-fashioned cursors.
+var cursorGlyphs = [...]uint16{
+	screen.CursorArrow:      68,  // XC_left_ptr
+	screen.CursorIBeam:      152, // XC_xterm
+	screen.CursorCrosshair:  34,  // XC_crosshair
+	screen.CursorHand:       60,  // XC_hand2
+	screen.CursorResizeNS:   116, // XC_sb_v_double_arrow
+	screen.CursorResizeEW:   108, // XC_sb_h_double_arrow
+	screen.CursorResizeNWSE: 14,  // XC_bottom_right_corner
+	screen.CursorResizeNESW: 12,  // XC_bottom_left_corner
+	screen.CursorResizeAll:  52,  // XC_fleur
+	screen.CursorNotAllowed: 24,  // XC_circle
+	screen.CursorWait:       150, // XC_watch
+}
+
+// maxCursorSize is the largest width or height of a custom cursor. Larger
+// images would not fit in a single PutImage request.
+const maxCursorSize = 128
+
+// shapeCursor returns the X11 cursor for a standard shape, creating and
+// caching it on first use. The arrow is the X11 cursor None, which inherits
+// the root window's cursor, as that is the desktop's usual, and possibly
+// themed, arrow.
+func (s *screenImpl) shapeCursor(shape screen.CursorShape) (xproto.Cursor, error) {
+	if shape <= screen.CursorArrow || int(shape) >= len(cursorGlyphs) {
+		return xproto.CursorNone, nil
+	}
+
+	s.cursorMu.Lock()
+	defer s.cursorMu.Unlock()
+
+	if c, ok := s.cursors[shape]; ok {
+		return c, nil
+	}
+	if s.cursorFont == 0 {
+		f, err := xproto.NewFontId(s.xc)
+		if err != nil {
+			return 0, fmt.Errorf("x11driver: xproto.NewFontId failed: %v", err)
+		}
+		if err := xproto.OpenFontChecked(s.xc, f, uint16(len("cursor")), "cursor").Check(); err != nil {
+			return 0, fmt.Errorf("x11driver: xproto.OpenFont failed: %v", err)
+		}
+		s.cursorFont = f
+	}
+	c, err := xproto.NewCursorId(s.xc)
+	if err != nil {
+		return 0, fmt.Errorf("x11driver: xproto.NewCursorId failed: %v", err)
+	}
+	// Each glyph's mask is the glyph after it. The cursor is black, outlined
+	// in white.
+	g := cursorGlyphs[shape]
+	xproto.CreateGlyphCursor(s.xc, c, s.cursorFont, s.cursorFont, g, g+1,
+		0, 0, 0, 0xffff, 0xffff, 0xffff)
+	if s.cursors == nil {
+		s.cursors = map[screen.CursorShape]xproto.Cursor{}
+	}
+	s.cursors[shape] = c
+	return c, nil
+}
+
+// blankCursor returns an invisible X11 cursor, creating and caching it on
+// first use.
+func (s *screenImpl) blankCursor() (xproto.Cursor, error) {
+	s.cursorMu.Lock()
+	defer s.cursorMu.Unlock()
+
+	if s.cursorBlank == 0 {
+		c, err := s.newImageCursor(image.NewRGBA(image.Rect(0, 0, 1, 1)), image.Point{})
+		if err != nil {
+			return 0, err
+		}
+		s.cursorBlank = c
+	}
+	return s.cursorBlank, nil
+}
+
+// newImageCursor returns a new X11 cursor, which the caller must free, showing
+// m. It uses the RENDER extension, for the cursor's alpha channel.
+func (s *screenImpl) newImageCursor(m *image.RGBA, hotspot image.Point) (xproto.Cursor, error) {
+	b := m.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width > maxCursorSize || height > maxCursorSize {
+		return 0, fmt.Errorf("x11driver: cursor image is %dx%d, larger than %dx%d",
+			width, height, maxCursorSize, maxCursorSize)
+	}
+
+	xm, err := xproto.NewPixmapId(s.xc)
+	if err != nil {
+		return 0, fmt.Errorf("x11driver: xproto.NewPixmapId failed: %v", err)
+	}
+	xp, err := render.NewPictureId(s.xc)
+	if err != nil {
+		return 0, fmt.Errorf("x11driver: render.NewPictureId failed: %v", err)
+	}
+	c, err := xproto.NewCursorId(s.xc)
+	if err != nil {
+		return 0, fmt.Errorf("x11driver: xproto.NewCursorId failed: %v", err)
+	}
+
+	// image.RGBA is already alpha-premultiplied, as RENDER requires, so
+	// only the byte order differs.
+	data := make([]byte, 4*width*height)
+	for y := 0; y < height; y++ {
+		i := m.PixOffset(b.Min.X, b.Min.Y+y)
+		row := data[4*width*y : 4*width*(y+1)]
+		copy(row, m.Pix[i:i+4*width])
+		swizzle.BGRA(row)
+	}
+
+	xproto.CreatePixmap(s.xc, 32, xm, xproto.Drawable(s.window32), uint16(width), uint16(height))
+	xproto.PutImage(s.xc, xproto.ImageFormatZPixmap, xproto.Drawable(xm), s.gcontext32,
+		uint16(width), uint16(height), 0, 0, 0, 32, data)
+	render.CreatePicture(s.xc, xp, xproto.Drawable(xm), s.pictformat32, 0, nil)
+	render.CreateCursor(s.xc, c, xp, uint16(hotspot.X), uint16(hotspot.Y))
+	// The cursor keeps its own copy of the image.
+	render.FreePicture(s.xc, xp)
+	xproto.FreePixmap(s.xc, xm)
+	return c, nil
+}
+
+func (w *windowImpl) SetCursor(c screen.Cursor) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released {
+		return
+	}
+	w.cursor = c
+	if c.HasImage() {
+		// Copy the image, as it is uploaded again each time that the cursor
+		// is shown after being hidden.
+		m := image.NewRGBA(c.Image.Rect)
+		draw.Draw(m, m.Rect, c.Image, m.Rect.Min, draw.Src)
+		w.cursor.Image = m
+	}
+	w.updateCursor()
+}
+
+func (w *windowImpl) SetCursorVisible(visible bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released || w.cursorHidden == !visible {
+		return
+	}
+	w.cursorHidden = !visible
+	w.updateCursor()
+}
+
+// updateCursor shows w.cursor, or a blank cursor if it is hidden. It must be
+// called with w.mu held.
+func (w *windowImpl) updateCursor() {
+	s := w.s
+	var (
+		c     xproto.Cursor
+		owned bool
+		err   error
+	)
+	switch {
+	case w.cursorHidden:
+		c, err = s.blankCursor()
+	case w.cursor.HasImage():
+		owned = true
+		c, err = s.newImageCursor(w.cursor.Image, w.cursor.Hotspot)
+	default:
+		c, err = s.shapeCursor(w.cursor.Shape)
+	}
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	xproto.ChangeWindowAttributes(s.xc, w.xw, xproto.CwCursor, []uint32{uint32(c)})
+	// The window keeps the cursor until it is changed, so freeing the old
+	// cursor does not stop it being shown.
+	w.freeCursor()
+	if owned {
+		w.ownedCursor = c
+	}
+}
+
+// freeCursor frees the window's cursor, if the window created it rather than
+// sharing it with other windows. It must be called with w.mu held.
+func (w *windowImpl) freeCursor() {
+	if w.ownedCursor != 0 {
+		xproto.FreeCursor(w.s.xc, w.ownedCursor)
+		w.ownedCursor = 0
+	}
+}
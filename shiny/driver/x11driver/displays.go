@@ -0,0 +1,250 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"image"
+	"log"
+	"time"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/BurntSushi/xgb/xproto"
+
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// initDisplays enables the RandR extension, version 1.3 or later, if the X
+// server has it, and finds the connected displays. Without RandR, the root
+// window's screen is reported as a single display.
+func (s *screenImpl) initDisplays() {
+	if err := randr.Init(s.xc); err == nil {
+		r, err := randr.QueryVersion(s.xc, 1, 3).Reply()
+		if err == nil && (r.MajorVersion > 1 || r.MinorVersion >= 3) {
+			s.randr = true
+			randr.SelectInput(s.xc, s.xsi.Root,
+				randr.NotifyMaskScreenChange|randr.NotifyMaskCrtcChange|randr.NotifyMaskOutputChange)
+		}
+	}
+	s.displays = s.queryDisplays()
+}
+
+// handleDisplayChange must only be called from the screenImpl.run goroutine.
+func (s *screenImpl) handleDisplayChange() {
+	displays := s.queryDisplays()
+
+	s.mu.Lock()
+	s.displays = displays
+	windows := make([]*windowImpl, 0, len(s.windows))
+	for _, w := range s.windows {
+		windows = append(windows, w)
+	}
+	s.mu.Unlock()
+
+	for _, w := range windows {
+		w.Send(screen.DisplayEvent{Displays: append([]screen.Display(nil), displays...)})
+		// The window's display may have a new resolution.
+		if w.updateScale() {
+			w.sendSize()
+		}
+	}
+}
+
+// queryDisplays returns the connected displays, primary first. Each of
+// RandR's CRTCs, which scan out a part of the root window, is a display,
+// named after its first output.
+func (s *screenImpl) queryDisplays() []screen.Display {
+	workArea := s.workArea()
+	scale := float64(s.pixelsPerPt) * ptPerInch / defaultDPI
+	if !s.xftDPI {
+		scale = 1
+	}
+
+	if !s.randr {
+		bounds := image.Rect(0, 0, int(s.xsi.WidthInPixels), int(s.xsi.HeightInPixels))
+		return []screen.Display{{
+			Bounds:   bounds,
+			WorkArea: intersectOrBounds(workArea, bounds),
+			DPI:      dpi(int(s.xsi.WidthInPixels), uint32(s.xsi.WidthInMillimeters)),
+			Scale:    scale,
+			Primary:  true,
+		}}
+	}
+
+	res, err := randr.GetScreenResourcesCurrent(s.xc, s.xsi.Root).Reply()
+	if err != nil {
+		log.Printf("x11driver: randr.GetScreenResourcesCurrent failed: %v", err)
+		return nil
+	}
+	var primary randr.Output
+	if r, err := randr.GetOutputPrimary(s.xc, s.xsi.Root).Reply(); err == nil {
+		primary = r.Output
+	}
+
+	var displays []screen.Display
+	for _, crtc := range res.Crtcs {
+		c, err := randr.GetCrtcInfo(s.xc, crtc, res.ConfigTimestamp).Reply()
+		if err != nil {
+			log.Printf("x11driver: randr.GetCrtcInfo failed: %v", err)
+			continue
+		}
+		if c.Mode == 0 || len(c.Outputs) == 0 {
+			// The CRTC is disabled.
+			continue
+		}
+		bounds := image.Rect(int(c.X), int(c.Y), int(c.X)+int(c.Width), int(c.Y)+int(c.Height))
+		d := screen.Display{
+			Bounds:   bounds,
+			WorkArea: intersectOrBounds(workArea, bounds),
+			Scale:    scale,
+		}
+		for _, m := range res.Modes {
+			if randr.Mode(m.Id) == c.Mode {
+				d.RefreshRate = refreshRate(m)
+				break
+			}
+		}
+		for _, o := range c.Outputs {
+			if o == primary {
+				d.Primary = true
+			}
+		}
+		if o, err := randr.GetOutputInfo(s.xc, c.Outputs[0], res.ConfigTimestamp).Reply(); err == nil {
+			d.Name = string(o.Name)
+			// The physical size is of the unrotated output.
+			width := int(c.Width)
+			if c.Rotation&(randr.RotationRotate90|randr.RotationRotate270) != 0 {
+				width = int(c.Height)
+			}
+			d.DPI = dpi(width, o.MmWidth)
+		}
+		if d.Primary {
+			displays = append([]screen.Display{d}, displays...)
+		} else {
+			displays = append(displays, d)
+		}
+	}
+	if len(displays) != 0 && !displays[0].Primary {
+		// There is no primary output set, so make the display at the origin
+		// primary, as X11 window managers usually do.
+		for i, d := range displays {
+			if d.Bounds.Min == (image.Point{}) {
+				displays[0], displays[i] = displays[i], displays[0]
+				break
+			}
+		}
+		displays[0].Primary = true
+	}
+	return displays
+}
+
+// workArea returns the first rectangle of the root window's _NET_WORKAREA
+// property, set by EWMH window managers, or an empty rectangle if it is not
+// set.
+//
+// TODO: use the rectangle for the _NET_CURRENT_DESKTOP, and notice when the
+// property changes.
+func (s *screenImpl) workArea() image.Rectangle {
+	r, err := xproto.GetProperty(s.xc, false, s.xsi.Root, s.atomNETWorkArea,
+		xproto.AtomCardinal, 0, 4).Reply()
+	if err != nil || r.Format != 32 || len(r.Value) < 16 {
+		return image.Rectangle{}
+	}
+	v := func(i int) int {
+		return int(int32(xgb.Get32(r.Value[4*i:])))
+	}
+	return image.Rect(v(0), v(1), v(0)+v(2), v(1)+v(3))
+}
+
+// windowScale returns the pixels per point and the scale factor for a window
+// centered on p, in root window co-ordinates. An "Xft/DPI" setting applies to
+// every display. Otherwise, the scale is that of the physical resolution of
+// the display containing p, if known, or else of the whole X11 screen.
+func (s *screenImpl) windowScale(p image.Point) (ppp float32, scale float64) {
+	if s.xftDPI {
+		return s.pixelsPerPt, float64(s.pixelsPerPt) * ptPerInch / defaultDPI
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.displays {
+		if p.In(d.Bounds) && d.DPI > 0 {
+			return float32(d.DPI / ptPerInch), 1
+		}
+	}
+	return s.pixelsPerPt, 1
+}
+
+// uniformDPI returns whether every display has the same physical resolution,
+// so that a window's scale does not depend on which display it is on.
+func (s *screenImpl) uniformDPI() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.displays {
+		if d.DPI != s.displays[0].DPI {
+			return false
+		}
+	}
+	return true
+}
+
+// frameInterval returns the time between frames of the primary display, at
+// its RandR refresh rate.
+func (s *screenImpl) frameInterval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.displays) == 0 {
+		return frame.Interval(0)
+	}
+	return frame.Interval(s.displays[0].RefreshRate)
+}
+
+func (s *screenImpl) Displays() []screen.Display {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]screen.Display(nil), s.displays...)
+}
+
+// intersectOrBounds returns the intersection of the work area r with a
+// display's bounds, or the bounds if they do not intersect.
+func intersectOrBounds(r, bounds image.Rectangle) image.Rectangle {
+	if r = r.Intersect(bounds); r.Empty() {
+		return bounds
+	}
+	return r
+}
+
+const (
+	mmPerInch = 25.4
+	ptPerInch = 72
+
+	// defaultDPI is the resolution at which the "Xft/DPI" setting means an
+	// unscaled display.
+	defaultDPI = 96
+)
+
+// dpi returns the pixels per inch of a display that is pixels wide and mm
+// millimeters wide, or zero if its physical width is unknown.
+func dpi(pixels int, mm uint32) float64 {
+	if mm == 0 {
+		return 0
+	}
+	return float64(pixels) * mmPerInch / float64(mm)
+}
+
+// refreshRate returns the vertical refresh rate, in hertz, of a RandR mode.
+func refreshRate(m randr.ModeInfo) float64 {
+	if m.Htotal == 0 || m.Vtotal == 0 {
+		return 0
+	}
+	vtotal := float64(m.Vtotal)
+	if m.ModeFlags&randr.ModeFlagDoubleScan != 0 {
+		vtotal *= 2
+	}
+	if m.ModeFlags&randr.ModeFlagInterlace != 0 {
+		vtotal /= 2
+	}
+	return float64(m.DotClock) / (float64(m.Htotal) * vtotal)
+}
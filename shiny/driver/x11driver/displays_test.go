@@ -0,0 +1,50 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"math"
+	"testing"
+
+	"github.com/BurntSushi/xgb/randr"
+)
+
+func TestRefreshRate(t *testing.T) {
+	testCases := []struct {
+		desc string
+		m    randr.ModeInfo
+		want float64
+	}{{
+		desc: "1920x1080 60Hz",
+		m:    randr.ModeInfo{DotClock: 148500000, Htotal: 2200, Vtotal: 1125},
+		want: 60,
+	}, {
+		desc: "1920x1080 60Hz interlaced",
+		m:    randr.ModeInfo{DotClock: 74250000, Htotal: 2200, Vtotal: 1125, ModeFlags: randr.ModeFlagInterlace},
+		want: 60,
+	}, {
+		desc: "320x240 60Hz double scanned",
+		m:    randr.ModeInfo{DotClock: 12587500, Htotal: 400, Vtotal: 262, ModeFlags: randr.ModeFlagDoubleScan},
+		want: 60.05,
+	}, {
+		desc: "no timings",
+		m:    randr.ModeInfo{DotClock: 148500000},
+		want: 0,
+	}}
+	for _, tc := range testCases {
+		if got := refreshRate(tc.m); math.Abs(got-tc.want) > 0.1 {
+			t.Errorf("%s: got %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestDPI(t *testing.T) {
+	if got, want := dpi(3840, 597), 163.4; math.Abs(got-want) > 0.1 {
+		t.Errorf("3840 pixels in 597mm: got %v, want %v", got, want)
+	}
+	if got := dpi(1024, 0); got != 0 {
+		t.Errorf("unknown width: got %v, want 0", got)
+	}
+}
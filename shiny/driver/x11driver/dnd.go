@@ -0,0 +1,485 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+
+	"golang.org/x/exp/shiny/driver/internal/dnd"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Drag-and-drop is the XDND protocol, specified at
+// https://www.freedesktop.org/wiki/Specifications/XDND/
+//
+// The drag source and the drop target send each other client messages, and
+// the dropped data is the XdndSelection, converted to each of the dragged
+// MIME types, whose atoms are named after the types.
+
+// xdndVersion is the version of the XDND protocol that this package speaks.
+const xdndVersion = 5
+
+type dndImpl struct {
+	s *screenImpl
+
+	atomAware      xproto.Atom
+	atomEnter      xproto.Atom
+	atomPosition   xproto.Atom
+	atomStatus     xproto.Atom
+	atomLeave      xproto.Atom
+	atomDrop       xproto.Atom
+	atomFinished   xproto.Atom
+	atomSelection  xproto.Atom
+	atomTypeList   xproto.Atom
+	atomActionCopy xproto.Atom
+
+	// This next group of variables, for drops on our windows, are only
+	// accessed by the screenImpl.run goroutine. target is the window that a
+	// drag, from the source window, is over. entered is whether target has
+	// been sent a DragEnter event, and x and y are the drag's position in it.
+	target    *windowImpl
+	source    xproto.Window
+	types     []string
+	typeAtoms map[string]xproto.Atom
+	entered   bool
+	x, y      float32
+	atomNames map[xproto.Atom]string
+
+	// mu guards drag, which is the most recent drag from our windows, or
+	// nil. Its data is kept after it is dropped, so that the drop target can
+	// convert the XdndSelection.
+	mu   sync.Mutex
+	drag *dragSource
+}
+
+// dragSource is a drag from one of our windows, started by StartDrag.
+type dragSource struct {
+	w     *windowImpl
+	types []xproto.Atom
+	data  map[xproto.Atom][]byte
+
+	// These fields are only accessed by the screenImpl.run goroutine.
+	// active is whether the pointer is still dragging. target is the
+	// XdndAware window under the pointer, or zero, and accepted is whether
+	// it has said that it accepts the drop. waiting is whether an
+	// XdndPosition has been sent to it without an XdndStatus reply yet, in
+	// which case pending, if non-nil, is the next position to send.
+	active   bool
+	target   xproto.Window
+	accepted bool
+	waiting  bool
+	pending  []uint32
+}
+
+func (s *screenImpl) initDND() (err error) {
+	d := &s.dnd
+	d.s = s
+	d.atomNames = map[xproto.Atom]string{}
+	for _, a := range []struct {
+		atom *xproto.Atom
+		name string
+	}{
+		{&d.atomAware, "XdndAware"},
+		{&d.atomEnter, "XdndEnter"},
+		{&d.atomPosition, "XdndPosition"},
+		{&d.atomStatus, "XdndStatus"},
+		{&d.atomLeave, "XdndLeave"},
+		{&d.atomDrop, "XdndDrop"},
+		{&d.atomFinished, "XdndFinished"},
+		{&d.atomSelection, "XdndSelection"},
+		{&d.atomTypeList, "XdndTypeList"},
+		{&d.atomActionCopy, "XdndActionCopy"},
+	} {
+		if *a.atom, err = s.internAtom(a.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setAware marks xw as a drop target.
+func (d *dndImpl) setAware(xw xproto.Window) {
+	xproto.ChangeProperty(d.s.xc, xproto.PropModeReplace, xw, d.atomAware, xproto.AtomAtom, 32, 1,
+		[]byte{xdndVersion, 0, 0, 0})
+}
+
+// handleClientMessage handles the XDND client messages, returning false for
+// any other client message. It must only be called from the screenImpl.run
+// goroutine.
+func (d *dndImpl) handleClientMessage(ev xproto.ClientMessageEvent) bool {
+	if ev.Format != 32 {
+		return false
+	}
+	data := ev.Data.Data32
+	switch ev.Type {
+	case d.atomEnter:
+		d.handleEnter(ev.Window, xproto.Window(data[0]), data)
+	case d.atomPosition:
+		if xproto.Window(data[0]) == d.source && d.target != nil {
+			d.handlePosition(int16(data[2]>>16), int16(data[2]))
+		}
+	case d.atomLeave:
+		if xproto.Window(data[0]) == d.source {
+			d.leave()
+		}
+	case d.atomDrop:
+		d.handleDrop(xproto.Window(data[0]), xproto.Timestamp(data[2]))
+	case d.atomStatus:
+		d.handleStatus(xproto.Window(data[0]), data[1]&1 != 0)
+	case d.atomFinished:
+		// Nothing else needs doing, as the data is kept until the next
+		// drag.
+	default:
+		return false
+	}
+	return true
+}
+
+func (d *dndImpl) handleEnter(xw, source xproto.Window, data []uint32) {
+	d.leave()
+	w := d.s.findWindow(xw)
+	if w == nil {
+		return
+	}
+
+	atoms := []xproto.Atom{}
+	if data[1]&1 != 0 {
+		// There are more than three types, so they are listed in the
+		// source's XdndTypeList property.
+		r, err := xproto.GetProperty(d.s.xc, false, source, d.atomTypeList, xproto.AtomAtom, 0, 1<<16).Reply()
+		if err != nil {
+			log.Printf("x11driver: xproto.GetProperty failed: %v", err)
+			return
+		}
+		for b := r.Value; len(b) >= 4; b = b[4:] {
+			atoms = append(atoms, xproto.Atom(xgb.Get32(b)))
+		}
+	} else {
+		for _, a := range data[2:5] {
+			if a != 0 {
+				atoms = append(atoms, xproto.Atom(a))
+			}
+		}
+	}
+
+	d.target, d.source = w, source
+	d.types, d.typeAtoms = nil, map[string]xproto.Atom{}
+	for _, a := range atoms {
+		name := d.atomName(a)
+		if name == "" {
+			continue
+		}
+		d.types = append(d.types, name)
+		d.typeAtoms[name] = a
+	}
+}
+
+func (d *dndImpl) handlePosition(rootX, rootY int16) {
+	w := d.target
+	r, err := xproto.TranslateCoordinates(d.s.xc, d.s.xsi.Root, w.xw, rootX, rootY).Reply()
+	if err != nil {
+		log.Printf("x11driver: xproto.TranslateCoordinates failed: %v", err)
+		return
+	}
+	d.x, d.y = float32(r.DstX), float32(r.DstY)
+	typ := screen.DragOver
+	if !d.entered {
+		typ, d.entered = screen.DragEnter, true
+	}
+	w.Send(screen.DragEvent{
+		Type:  typ,
+		X:     d.x,
+		Y:     d.y,
+		Types: append([]string(nil), d.types...),
+	})
+	// Accept the drop, as a copy, and ask for a position message whenever
+	// the pointer moves.
+	d.s.sendClientMessage(d.source, d.atomStatus, uint32(w.xw), 1|2, 0, 0, uint32(d.atomActionCopy))
+}
+
+// leave ends the drag over d.target, if any, sending it a DragLeave event
+// if it was sent a DragEnter.
+func (d *dndImpl) leave() {
+	if d.target != nil && d.entered {
+		d.target.Send(screen.DragEvent{
+			Type:  screen.DragLeave,
+			Types: append([]string(nil), d.types...),
+		})
+	}
+	d.target, d.source, d.entered = nil, 0, false
+}
+
+func (d *dndImpl) handleDrop(source xproto.Window, t xproto.Timestamp) {
+	w := d.target
+	if w == nil || source != d.source {
+		// We are not the target of a drag from that source.
+		return
+	}
+	x, y, types, typeAtoms := d.x, d.y, d.types, d.typeAtoms
+	d.target, d.source, d.entered = nil, 0, false
+
+	// Convert the selection in a separate goroutine, as the SelectionNotify
+	// events are received by this one.
+	go func() {
+		received := map[string][]byte{}
+		for _, typ := range dnd.Wanted(types) {
+			b, err := d.s.clipboard.convert(d.atomSelection, typeAtoms[typ], t)
+			if err != nil {
+				log.Print(err)
+				continue
+			}
+			if b != nil {
+				received[typ] = b
+			}
+		}
+		w.Send(screen.DragEvent{
+			Type:  screen.Drop,
+			X:     x,
+			Y:     y,
+			Types: types,
+			Data:  dnd.Decode(received),
+		})
+		d.s.sendClientMessage(source, d.atomFinished, uint32(w.xw), 1, uint32(d.atomActionCopy))
+	}()
+}
+
+// atomName returns the name of a, or an empty string if it has none. It must
+// only be called from the screenImpl.run goroutine.
+func (d *dndImpl) atomName(a xproto.Atom) string {
+	if name, ok := d.atomNames[a]; ok {
+		return name
+	}
+	r, err := xproto.GetAtomName(d.s.xc, a).Reply()
+	if err != nil {
+		log.Printf("x11driver: xproto.GetAtomName failed: %v", err)
+		return ""
+	}
+	d.atomNames[a] = r.Name
+	return r.Name
+}
+
+func (w *windowImpl) StartDrag(data screen.DragData) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.released {
+		return errors.New("x11driver: StartDrag on a released window")
+	}
+	s := w.s
+	names, byName := dnd.Encode(data)
+	if data.Text != "" {
+		// Older X11 clients only know UTF8_STRING for text.
+		names = append(names, "UTF8_STRING")
+		byName["UTF8_STRING"] = []byte(data.Text)
+	}
+	if len(names) == 0 {
+		return errors.New("x11driver: StartDrag with no data")
+	}
+	ds := &dragSource{
+		w:      w,
+		data:   map[xproto.Atom][]byte{},
+		active: true,
+	}
+	for _, name := range names {
+		a, err := s.internAtom(name)
+		if err != nil {
+			return err
+		}
+		ds.types = append(ds.types, a)
+		ds.data[a] = byName[name]
+	}
+
+	s.setProperty(w.xw, s.dnd.atomTypeList, ds.types...)
+	xproto.SetSelectionOwner(s.xc, w.xw, s.dnd.atomSelection, xproto.TimeCurrentTime)
+	// The button press has already grabbed the pointer, implicitly, for this
+	// window, but for button presses and releases only.
+	r, err := xproto.GrabPointer(s.xc, false, w.xw,
+		xproto.EventMaskButtonRelease|xproto.EventMaskPointerMotion,
+		xproto.GrabModeAsync, xproto.GrabModeAsync, xproto.WindowNone, xproto.CursorNone,
+		xproto.TimeCurrentTime).Reply()
+	if err != nil {
+		return fmt.Errorf("x11driver: xproto.GrabPointer failed: %v", err)
+	}
+	if r.Status != xproto.GrabStatusSuccess {
+		return fmt.Errorf("x11driver: xproto.GrabPointer failed: status %d", r.Status)
+	}
+
+	s.dnd.mu.Lock()
+	s.dnd.drag = ds
+	s.dnd.mu.Unlock()
+	return nil
+}
+
+// activeDrag returns the drag from xw, if the pointer is still dragging it.
+func (d *dndImpl) activeDrag(xw xproto.Window) *dragSource {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.drag == nil || !d.drag.active || d.drag.w.xw != xw {
+		return nil
+	}
+	return d.drag
+}
+
+// handleDragMotion handles the pointer moving, to root window co-ordinates,
+// during a drag from xw. It returns false if there is no such drag. It must
+// only be called from the screenImpl.run goroutine.
+func (d *dndImpl) handleDragMotion(xw xproto.Window, rootX, rootY int16, t xproto.Timestamp) bool {
+	ds := d.activeDrag(xw)
+	if ds == nil {
+		return false
+	}
+	target, version := d.findTarget(rootX, rootY)
+	if target != ds.target {
+		if ds.target != 0 {
+			d.s.sendClientMessage(ds.target, d.atomLeave, uint32(xw), 0)
+		}
+		ds.target = target
+		ds.accepted, ds.waiting, ds.pending = false, false, nil
+		if target != 0 {
+			flags := version << 24
+			if len(ds.types) > 3 {
+				flags |= 1
+			}
+			var types [3]uint32
+			for i := 0; i < len(types) && i < len(ds.types); i++ {
+				types[i] = uint32(ds.types[i])
+			}
+			d.s.sendClientMessage(target, d.atomEnter, uint32(xw), flags, types[0], types[1], types[2])
+		}
+	}
+	if target == 0 {
+		return true
+	}
+	position := []uint32{uint32(xw), 0, uint32(uint16(rootX))<<16 | uint32(uint16(rootY)), uint32(t), uint32(d.atomActionCopy)}
+	if ds.waiting {
+		// The target has not yet replied to the previous position, so only
+		// the most recent position is sent after it does.
+		ds.pending = position
+		return true
+	}
+	ds.waiting = true
+	d.s.sendClientMessage(target, d.atomPosition, position...)
+	return true
+}
+
+// handleDragRelease handles the mouse button being released, ending any drag
+// from xw. It must only be called from the screenImpl.run goroutine.
+func (d *dndImpl) handleDragRelease(xw xproto.Window, t xproto.Timestamp) {
+	ds := d.activeDrag(xw)
+	if ds == nil {
+		return
+	}
+	xproto.UngrabPointer(d.s.xc, t)
+	d.mu.Lock()
+	ds.active = false
+	d.mu.Unlock()
+
+	switch {
+	case ds.target != 0 && ds.accepted:
+		d.s.sendClientMessage(ds.target, d.atomDrop, uint32(xw), 0, uint32(t))
+	case ds.target != 0:
+		d.s.sendClientMessage(ds.target, d.atomLeave, uint32(xw), 0)
+	}
+}
+
+func (d *dndImpl) handleStatus(target xproto.Window, accepted bool) {
+	d.mu.Lock()
+	ds := d.drag
+	d.mu.Unlock()
+	if ds == nil || !ds.active || ds.target != target {
+		return
+	}
+	ds.accepted, ds.waiting = accepted, false
+	if p := ds.pending; p != nil {
+		ds.pending, ds.waiting = nil, true
+		d.s.sendClientMessage(target, d.atomPosition, p...)
+	}
+}
+
+// findTarget returns the XdndAware window at a point, in root window
+// co-ordinates, and its XDND version, or zero if there is none. The window
+// manager's frames are not usually XdndAware, so the search descends from the
+// root window through the windows under the point.
+//
+// TODO: support the XdndProxy property.
+func (d *dndImpl) findTarget(x, y int16) (xproto.Window, uint32) {
+	root := d.s.xsi.Root
+	xw := root
+	// Limit the depth, in case of a misbehaving client.
+	for depth := 0; depth < 8; depth++ {
+		r, err := xproto.TranslateCoordinates(d.s.xc, root, xw, x, y).Reply()
+		if err != nil || r.Child == xproto.WindowNone {
+			return 0, 0
+		}
+		xw = r.Child
+		p, err := xproto.GetProperty(d.s.xc, false, xw, d.atomAware, xproto.AtomAtom, 0, 1).Reply()
+		if err == nil && p.Format == 32 && len(p.Value) >= 4 {
+			version := xgb.Get32(p.Value)
+			if version > xdndVersion {
+				version = xdndVersion
+			}
+			return xw, version
+		}
+	}
+	return 0, 0
+}
+
+// handleSelectionRequest converts the XdndSelection, for a drop target. It
+// must only be called from the screenImpl.run goroutine.
+func (d *dndImpl) handleSelectionRequest(ev xproto.SelectionRequestEvent) {
+	c := &d.s.clipboard
+	property := ev.Property
+	if property == xproto.AtomNone {
+		property = ev.Target
+	}
+
+	d.mu.Lock()
+	ds := d.drag
+	d.mu.Unlock()
+
+	switch {
+	case ds == nil || ds.w.xw != ev.Owner:
+		property = xproto.AtomNone
+	case ev.Target == c.atomTargets:
+		d.s.setProperty(ev.Requestor, property, append([]xproto.Atom{c.atomTargets}, ds.types...)...)
+	default:
+		data, ok := ds.data[ev.Target]
+		if !ok {
+			property = xproto.AtomNone
+			break
+		}
+		c.sendData(ev.Requestor, property, ev.Target, data)
+	}
+	c.notify(ev, property)
+}
+
+// handleSelectionClear forgets the most recent drag's data, once another
+// client owns the XdndSelection.
+func (d *dndImpl) handleSelectionClear(ev xproto.SelectionClearEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.drag != nil && !d.drag.active && d.drag.w.xw == ev.Owner {
+		d.drag = nil
+	}
+}
+
+// sendClientMessage sends a client message, of 32-bit data, to xw.
+func (s *screenImpl) sendClientMessage(xw xproto.Window, typ xproto.Atom, data ...uint32) {
+	var d [5]uint32
+	copy(d[:], data)
+	ev := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: xw,
+		Type:   typ,
+		Data:   xproto.ClientMessageDataUnionData32New(d[:]),
+	}
+	xproto.SendEvent(s.xc, false, xw, xproto.EventMaskNoEvent, string(ev.Bytes()))
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/xgb/xproto"
+
+	"golang.org/x/exp/shiny/driver/internal/hotkey"
+	"golang.org/x/exp/shiny/driver/internal/x11key"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+)
+
+// Hot keys are passive key grabs on the root window, so the X server sends
+// their key presses to the root window, rather than to the focused window.
+// The screenImpl's hotKeys table says which window registered each of them.
+
+// RegisterHotKey grabs the hot key. The X server reports a grab of it by
+// another client as an access error.
+func (w *windowImpl) RegisterHotKey(k key.Code, mods key.Modifiers) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return errReleased
+	}
+	keycode := w.s.keysyms.Keycode(k)
+	if keycode == 0 {
+		return fmt.Errorf("x11driver: no key has the code %v", k)
+	}
+	return w.s.hotKeys.Register(w, hotkey.Key{Code: k, Modifiers: mods}, func() error {
+		return w.s.grabKey(xproto.Keycode(keycode), x11key.ModifierState(mods))
+	})
+}
+
+func (w *windowImpl) UnregisterHotKey(k key.Code, mods key.Modifiers) {
+	w.s.hotKeys.Unregister(w, hotkey.Key{Code: k, Modifiers: mods}, func() {
+		w.s.ungrabKey(hotkey.Key{Code: k, Modifiers: mods})
+	})
+}
+
+// releaseHotKeys unregisters w's hot keys, when it is released.
+func (w *windowImpl) releaseHotKeys() {
+	w.s.hotKeys.Release(w, w.s.ungrabKey)
+}
+
+// grabKey grabs the key with the modifiers, whichever locks are on.
+func (s *screenImpl) grabKey(keycode xproto.Keycode, state uint16) error {
+	for i, lock := range x11key.LockMasks {
+		err := xproto.GrabKeyChecked(s.xc, false, s.xsi.Root, state|lock, keycode,
+			xproto.GrabModeAsync, xproto.GrabModeAsync).Check()
+		if err == nil {
+			continue
+		}
+		for _, lock := range x11key.LockMasks[:i] {
+			xproto.UngrabKey(s.xc, keycode, s.xsi.Root, state|lock)
+		}
+		if _, ok := err.(xproto.AccessError); ok {
+			return screen.ErrHotKeyInUse
+		}
+		return fmt.Errorf("x11driver: GrabKey failed: %v", err)
+	}
+	return nil
+}
+
+func (s *screenImpl) ungrabKey(k hotkey.Key) {
+	keycode, state := xproto.Keycode(s.keysyms.Keycode(k.Code)), x11key.ModifierState(k.Modifiers)
+	for _, lock := range x11key.LockMasks {
+		xproto.UngrabKey(s.xc, keycode, s.xsi.Root, state|lock)
+	}
+}
+
+// handleHotKey sends the HotKeyEvent, if any, for a key press on the root
+// window.
+func (s *screenImpl) handleHotKey(detail xproto.Keycode, state uint16) {
+	_, c := s.keysyms.Lookup(uint8(detail), state)
+	s.hotKeys.Send(hotkey.Key{Code: c, Modifiers: x11key.KeyModifiers(state)})
+}
@@ -0,0 +1,103 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"log"
+
+	"github.com/BurntSushi/xgb/xproto"
+
+	"golang.org/x/exp/shiny/driver/internal/x11key"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+)
+
+// The keyboard layout is the one that the XKB rules names, in the root
+// window's _XKB_RULES_NAMES property, give for the XKB group in the state of
+// each key event. A screen.KeyboardLayoutEvent is sent to the windows when the
+// property changes, or before the first key event in a new group.
+
+// initKeyboardLayout reads the XKB rules names, and watches the root window
+// for changes to them.
+func (s *screenImpl) initKeyboardLayout() (err error) {
+	s.atomXKBRulesNames, err = s.internAtom("_XKB_RULES_NAMES")
+	if err != nil {
+		return err
+	}
+	xproto.ChangeWindowAttributes(s.xc, s.xsi.Root, xproto.CwEventMask,
+		[]uint32{xproto.EventMaskPropertyChange})
+	s.rulesNames = s.readRulesNames()
+	s.layout = s.rulesNames.LayoutName(0)
+	return nil
+}
+
+// readRulesNames returns the value of the root window's _XKB_RULES_NAMES
+// property. It is zero if the X server lacks the XKB extension.
+func (s *screenImpl) readRulesNames() x11key.RulesNames {
+	r, err := xproto.GetProperty(s.xc, false, s.xsi.Root, s.atomXKBRulesNames,
+		xproto.AtomString, 0, 1<<10).Reply()
+	if err != nil {
+		log.Printf("x11driver: xproto.GetProperty failed: %v", err)
+		return x11key.RulesNames{}
+	}
+	return x11key.ParseRulesNames(r.Value)
+}
+
+// physicalCode returns the code of the physical key whose keycode is detail.
+// Only X servers whose XKB rules are "evdev" have evdev keycodes.
+//
+// physicalCode must only be called from the screenImpl.run goroutine.
+func (s *screenImpl) physicalCode(detail xproto.Keycode) key.Code {
+	if s.rulesNames.Rules != "evdev" {
+		return key.CodeUnknown
+	}
+	return x11key.PhysicalCode(uint8(detail))
+}
+
+// handleMappingNotify re-reads a changed keyboard mapping.
+//
+// handleMappingNotify must only be called from the screenImpl.run goroutine.
+func (s *screenImpl) handleMappingNotify(ev xproto.MappingNotifyEvent) {
+	if ev.Request != xproto.MappingKeyboard {
+		return
+	}
+	if err := s.initKeyboardMapping(); err != nil {
+		log.Print(err)
+	}
+	s.handleRulesNamesChange()
+}
+
+// handleRulesNamesChange re-reads the XKB rules names.
+//
+// handleRulesNamesChange must only be called from the screenImpl.run
+// goroutine.
+func (s *screenImpl) handleRulesNamesChange() {
+	s.rulesNames = s.readRulesNames()
+	s.updateLayout(s.group)
+}
+
+// updateLayout sends a screen.KeyboardLayoutEvent to the windows if the
+// layout of the XKB group differs from the last one that they were sent.
+//
+// updateLayout must only be called from the screenImpl.run goroutine.
+func (s *screenImpl) updateLayout(group int) {
+	s.group = group
+	name := s.rulesNames.LayoutName(group)
+	if name == s.layout || name == "" {
+		return
+	}
+	s.layout = name
+
+	s.mu.Lock()
+	windows := make([]*windowImpl, 0, len(s.windows))
+	for _, w := range s.windows {
+		windows = append(windows, w)
+	}
+	s.mu.Unlock()
+
+	for _, w := range windows {
+		w.Send(screen.KeyboardLayoutEvent{Layout: name})
+	}
+}
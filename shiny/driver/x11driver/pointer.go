@@ -0,0 +1,89 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"image"
+	"log"
+	"time"
+
+	"github.com/BurntSushi/xgb/xproto"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// SetPointerCapture grabs the pointer, confining it to the window, and warps
+// it back to the window's center after each motion, so that the motion's
+// distance from the center is its relative motion.
+//
+// TODO: use XInput2's raw motion events, which are neither accelerated nor
+// stopped by the edges of the screen, once xgb supports the XInputExtension.
+func (w *windowImpl) SetPointerCapture(capture bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.released || w.captured == capture {
+		return
+	}
+	s := w.s
+	if !capture {
+		xproto.UngrabPointer(s.xc, xproto.TimeCurrentTime)
+		w.captured = false
+		return
+	}
+
+	g, err := xproto.GetGeometry(s.xc, xproto.Drawable(w.xw)).Reply()
+	if err != nil {
+		log.Printf("x11driver: xproto.GetGeometry failed: %v", err)
+		return
+	}
+	blank, err := s.blankCursor()
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	r, err := xproto.GrabPointer(s.xc, false, w.xw,
+		xproto.EventMaskButtonPress|xproto.EventMaskButtonRelease|xproto.EventMaskPointerMotion,
+		xproto.GrabModeAsync, xproto.GrabModeAsync, w.xw, blank, xproto.TimeCurrentTime).Reply()
+	if err != nil {
+		log.Printf("x11driver: xproto.GrabPointer failed: %v", err)
+		return
+	}
+	if r.Status != xproto.GrabStatusSuccess {
+		// Another client, such as the window manager, has grabbed the
+		// pointer, or the window is not viewable.
+		log.Printf("x11driver: xproto.GrabPointer failed: status %d", r.Status)
+		return
+	}
+	w.captured = true
+	w.captureCenter = image.Point{int(g.Width) / 2, int(g.Height) / 2}
+	w.warpToCenter()
+}
+
+// handleCapturedMotion sends a screen.RelativeMouseEvent, and reports true, if
+// the window has captured the pointer, which has moved to (x, y) at time t. It
+// must only be called from the screenImpl.run goroutine.
+func (w *windowImpl) handleCapturedMotion(x, y int16, t time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.captured {
+		return false
+	}
+	dx, dy := int(x)-w.captureCenter.X, int(y)-w.captureCenter.Y
+	// The warp back to the center also generates a motion event, with no
+	// relative motion.
+	if dx != 0 || dy != 0 {
+		w.SendAt(screen.RelativeMouseEvent{DeltaX: float32(dx), DeltaY: float32(dy)}, t)
+		w.warpToCenter()
+	}
+	return true
+}
+
+// warpToCenter must be called with w.mu held.
+func (w *windowImpl) warpToCenter() {
+	xproto.WarpPointer(w.s.xc, xproto.WindowNone, w.xw, 0, 0, 0, 0,
+		int16(w.captureCenter.X), int16(w.captureCenter.Y))
+}
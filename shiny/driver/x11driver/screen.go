@@ -11,12 +11,20 @@ import (
 	"image/draw"
 	"log"
 	"sync"
+	"sync/atomic"
 
 	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/randr"
 	"github.com/BurntSushi/xgb/render"
+	"github.com/BurntSushi/xgb/shape"
 	"github.com/BurntSushi/xgb/shm"
 	"github.com/BurntSushi/xgb/xproto"
 
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/hotkey"
+	"golang.org/x/exp/shiny/driver/internal/pixpool"
 	"golang.org/x/exp/shiny/driver/internal/x11key"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/image/math/f64"
@@ -29,25 +37,78 @@ import (
 // it's not obvious how to interrupt it to service a NewWindow request.
 
 type screenImpl struct {
+	textureBytes atomic.Int64 // Of non-degenerate textures.
+
 	xc      *xgb.Conn
 	xsi     *xproto.ScreenInfo
 	keysyms x11key.KeysymTable
 
-	atomNETWMName      xproto.Atom
-	atomUTF8String     xproto.Atom
-	atomWMDeleteWindow xproto.Atom
-	atomWMProtocols    xproto.Atom
-	atomWMTakeFocus    xproto.Atom
-
+	atomMotifWMHints            xproto.Atom
+	atomNETFrameExtents         xproto.Atom
+	atomNETWMBypassCompositor   xproto.Atom
+	atomNETWMIcon               xproto.Atom
+	atomNETWMMoveResize         xproto.Atom
+	atomNETWMName               xproto.Atom
+	atomNETWMState              xproto.Atom
+	atomNETWMStateAbove         xproto.Atom
+	atomNETWMStateFullscreen    xproto.Atom
+	atomNETWMStateHidden        xproto.Atom
+	atomNETWMStateMaximizedHorz xproto.Atom
+	atomNETWMStateMaximizedVert xproto.Atom
+	atomNETWMStateModal         xproto.Atom
+	atomNETWMWindowOpacity      xproto.Atom
+	atomNETWorkArea             xproto.Atom
+	atomUTF8String              xproto.Atom
+	atomWMChangeState           xproto.Atom
+	atomWMDeleteWindow          xproto.Atom
+	atomWMProtocols             xproto.Atom
+	atomWMTakeFocus             xproto.Atom
+
+	atomXSettingsSettings xproto.Atom
+	xsettingsOwner        xproto.Window
+
+	// rulesNames are the XKB rules names of the keyboard mapping in keysyms,
+	// group is the XKB group of the last key event, and layout is the name
+	// of the keyboard layout that the windows were last told of. They are
+	// only accessed in the screenImpl.run goroutine, after newScreenImpl
+	// returns.
+	atomXKBRulesNames xproto.Atom
+	rulesNames        x11key.RulesNames
+	group             int
+	layout            string
+
+	atomNETSystemTray       xproto.Atom
+	atomNETSystemTrayOpcode xproto.Atom
+	atomNETSystemTrayVisual xproto.Atom
+	atomXEmbedInfo          xproto.Atom
+
+	clipboard clipboardImpl
+	dnd       dndImpl
+	hotKeys   hotkey.Table
+	clock     event.Clock // Converts the times of input events.
+
+	// pixelsPerPt and xftDPI are mutable, but are only modified in the
+	// screenImpl.run goroutine, after newScreenImpl returns. xftDPI is
+	// whether pixelsPerPt comes from the "Xft/DPI" setting, rather than the
+	// physical screen resolution.
 	pixelsPerPt  float32
+	xftDPI       bool
+	randr        bool
 	pictformat24 render.Pictformat
 	pictformat32 render.Pictformat
 
 	// window32 and its related X11 resources is an unmapped window so that we
 	// have a depth-32 window to create depth-32 pixmaps from, i.e. pixmaps
 	// with an alpha channel. The root window isn't guaranteed to be depth-32.
+	// visual32 and colormap32 are also those of Transparent windows.
 	gcontext32 xproto.Gcontext
 	window32   xproto.Window
+	visual32   xproto.Visualid
+	colormap32 xproto.Colormap
+
+	// shapeExt is whether the X server has the X Nonrectangular Window Shape
+	// extension, for windows with a Shape.
+	shapeExt bool
 
 	// opaqueP is a fully opaque, solid fill picture.
 	opaqueP render.Picture
@@ -56,40 +117,84 @@ type screenImpl struct {
 	uniformC  render.Color
 	uniformP  render.Picture
 
-	mu              sync.Mutex
-	buffers         map[shm.Seg]*bufferImpl
-	uploads         map[uint16]chan struct{}
-	windows         map[xproto.Window]*windowImpl
-	nPendingUploads int
-	completionKeys  []uint16
+	// alphaP is a solid fill picture whose alpha is alphaA. It is the mask
+	// for drawing textures with transparency.
+	alphaMu sync.Mutex
+	alphaA  uint16
+	alphaP  render.Picture
+
+	// cursorMu guards the standard cursors, which are created on first use
+	// and shared by every window.
+	cursorMu    sync.Mutex
+	cursorFont  xproto.Font
+	cursorBlank xproto.Cursor
+	cursors     map[screen.CursorShape]xproto.Cursor
+
+	// useShm is whether Buffers are shared memory segments, attached to the
+	// X11 server, that it reads pixels from. Otherwise, they are in Go memory,
+	// recycled by pixPool, and their pixels are sent over the connection.
+	useShm  bool
+	pixPool pixpool.Pool
+
+	mu                   sync.Mutex
+	accessibilityPrefs   screen.AccessibilityPrefs
+	colorScheme          screen.ColorScheme
+	defaultWindowOptions *screen.NewWindowOptions
+	displays             []screen.Display
+	buffers              map[shm.Seg]*bufferImpl
+	freeShm              []shmSegment
+	freeShmSize          int
+	uploads              map[uint16]chan struct{}
+	windows              map[xproto.Window]*windowImpl
+	trays                map[xproto.Window]*trayImpl
+	nPendingUploads      int
+	completionKeys       []uint16
+
+	// frames are the windows waiting for a screen.FrameEvent.
+	frames frame.Requests
 }
 
-func newScreenImpl(xc *xgb.Conn) (*screenImpl, error) {
+func newScreenImpl(xc *xgb.Conn, useShm bool) (*screenImpl, error) {
 	s := &screenImpl{
 		xc:      xc,
 		xsi:     xproto.Setup(xc).DefaultScreen(xc),
 		buffers: map[shm.Seg]*bufferImpl{},
 		uploads: map[uint16]chan struct{}{},
 		windows: map[xproto.Window]*windowImpl{},
+		trays:   map[xproto.Window]*trayImpl{},
 	}
+	s.useShm = useShm && s.shmWorks()
 	if err := s.initAtoms(); err != nil {
 		return nil, err
 	}
 	if err := s.initKeyboardMapping(); err != nil {
 		return nil, err
 	}
-	const (
-		mmPerInch = 25.4
-		ptPerInch = 72
-	)
+	if err := s.initKeyboardLayout(); err != nil {
+		return nil, err
+	}
 	pixelsPerMM := float32(s.xsi.WidthInPixels) / float32(s.xsi.WidthInMillimeters)
 	s.pixelsPerPt = pixelsPerMM * mmPerInch / ptPerInch
+	if err := s.initXSettings(); err != nil {
+		return nil, err
+	}
+	s.initDisplays()
 	if err := s.initPictformats(); err != nil {
 		return nil, err
 	}
 	if err := s.initWindow32(); err != nil {
 		return nil, err
 	}
+	s.shapeExt = shape.Init(s.xc) == nil
+	if err := s.initClipboard(); err != nil {
+		return nil, err
+	}
+	if err := s.initDND(); err != nil {
+		return nil, err
+	}
+	if err := s.initTray(); err != nil {
+		return nil, err
+	}
 
 	var err error
 	s.opaqueP, err = render.NewPictureId(xc)
@@ -100,6 +205,10 @@ func newScreenImpl(xc *xgb.Conn) (*screenImpl, error) {
 	if err != nil {
 		return nil, fmt.Errorf("x11driver: xproto.NewPictureId failed: %v", err)
 	}
+	s.alphaP, err = render.NewPictureId(xc)
+	if err != nil {
+		return nil, fmt.Errorf("x11driver: xproto.NewPictureId failed: %v", err)
+	}
 	render.CreateSolidFill(s.xc, s.opaqueP, render.Color{
 		Red:   0xffff,
 		Green: 0xffff,
@@ -107,14 +216,22 @@ func newScreenImpl(xc *xgb.Conn) (*screenImpl, error) {
 		Alpha: 0xffff,
 	})
 	render.CreateSolidFill(s.xc, s.uniformP, render.Color{})
+	s.alphaA = 0xffff
+	render.CreateSolidFill(s.xc, s.alphaP, render.Color{Alpha: s.alphaA})
 
 	go s.run()
 	return s, nil
 }
 
 func (s *screenImpl) run() {
+	// pending is an event that was polled for, but not yet handled.
+	var pending xgb.Event
 	for {
-		ev, err := s.xc.WaitForEvent()
+		ev, err := pending, error(nil)
+		pending = nil
+		if ev == nil {
+			ev, err = s.xc.WaitForEvent()
+		}
 		if err != nil {
 			log.Printf("x11driver: xproto.WaitForEvent: %v", err)
 			continue
@@ -125,6 +242,7 @@ func (s *screenImpl) run() {
 		case xproto.DestroyNotifyEvent:
 			s.mu.Lock()
 			delete(s.windows, ev.Window)
+			delete(s.trays, ev.Window)
 			s.mu.Unlock()
 
 		case shm.CompletionEvent:
@@ -134,24 +252,38 @@ func (s *screenImpl) run() {
 			s.mu.Unlock()
 
 		case xproto.ClientMessageEvent:
+			if s.dnd.handleClientMessage(ev) {
+				break
+			}
 			if ev.Type != s.atomWMProtocols || ev.Format != 32 {
 				break
 			}
 			switch xproto.Atom(ev.Data.Data32[0]) {
 			case s.atomWMDeleteWindow:
-				if w := s.findWindow(ev.Window); w != nil {
+				if w := s.findWindow(ev.Window); w == nil {
+					noWindowFound = true
+				} else if w.interceptClose {
+					w.Send(screen.CloseRequestEvent{})
+				} else {
 					w.lifecycler.SetDead(true)
 					w.lifecycler.SendEvent(w, nil)
-				} else {
-					noWindowFound = true
 				}
 			case s.atomWMTakeFocus:
 				xproto.SetInputFocus(s.xc, xproto.InputFocusParent, ev.Window, xproto.Timestamp(ev.Data.Data32[1]))
 			}
 
+		case xproto.VisibilityNotifyEvent:
+			if w := s.findWindow(ev.Window); w != nil {
+				w.handleVisibilityNotify(ev)
+			} else {
+				noWindowFound = true
+			}
+
 		case xproto.ConfigureNotifyEvent:
 			if w := s.findWindow(ev.Window); w != nil {
 				w.handleConfigureNotify(ev)
+			} else if t := s.findTray(ev.Window); t != nil {
+				t.handleConfigureNotify(ev)
 			} else {
 				noWindowFound = true
 			}
@@ -167,6 +299,24 @@ func (s *screenImpl) run() {
 				if ev.Count == 0 {
 					w.handleExpose()
 				}
+			} else if t := s.findTray(ev.Window); t != nil {
+				if ev.Count == 0 {
+					t.handleExpose()
+				}
+			} else {
+				noWindowFound = true
+			}
+
+		case xproto.EnterNotifyEvent:
+			if w := s.findWindow(ev.Event); w != nil {
+				w.handleCrossing(ev, true)
+			} else {
+				noWindowFound = true
+			}
+
+		case xproto.LeaveNotifyEvent:
+			if w := s.findWindow(ev.Event); w != nil {
+				w.handleCrossing(xproto.EnterNotifyEvent(ev), false)
 			} else {
 				noWindowFound = true
 			}
@@ -189,38 +339,103 @@ func (s *screenImpl) run() {
 
 		case xproto.KeyPressEvent:
 			if w := s.findWindow(ev.Event); w != nil {
-				w.handleKey(ev.Detail, ev.State, key.DirPress)
+				w.handleKey(ev.Detail, ev.State, key.DirPress, ev.Time)
+			} else if ev.Event == s.xsi.Root {
+				s.handleHotKey(ev.Detail, ev.State)
 			} else {
 				noWindowFound = true
 			}
 
 		case xproto.KeyReleaseEvent:
-			if w := s.findWindow(ev.Event); w != nil {
-				w.handleKey(ev.Detail, ev.State, key.DirRelease)
-			} else {
-				noWindowFound = true
+			w := s.findWindow(ev.Event)
+			if w == nil {
+				if ev.Event != s.xsi.Root {
+					noWindowFound = true
+				}
+				break
+			}
+			// The X server sends each automatic repeat of a held key as a
+			// release and a press with the same time. Only XKB's detectable
+			// auto-repeat, which xgb lacks, would send just the presses.
+			pending, err = s.xc.PollForEvent()
+			if err != nil {
+				log.Printf("x11driver: xproto.PollForEvent: %v", err)
 			}
+			if p, ok := pending.(xproto.KeyPressEvent); ok && p.Event == ev.Event && p.Detail == ev.Detail && p.Time == ev.Time {
+				pending = nil
+				w.handleKey(p.Detail, p.State, key.DirNone, p.Time)
+				break
+			}
+			w.handleKey(ev.Detail, ev.State, key.DirRelease, ev.Time)
+
+		case xproto.MappingNotifyEvent:
+			s.handleMappingNotify(ev)
 
 		case xproto.ButtonPressEvent:
+			// TODO: send touch.Events and screen.PenEvents, from XInput2's
+			// touch and tablet device events, once xgb supports the
+			// XInputExtension. Until then, the X server sends touches and
+			// pens as core pointer events.
 			if w := s.findWindow(ev.Event); w != nil {
-				w.handleMouse(ev.EventX, ev.EventY, ev.Detail, ev.State, mouse.DirPress)
-			} else {
+				w.handleMouse(ev.EventX, ev.EventY, ev.Detail, ev.State, mouse.DirPress, ev.Time)
+			} else if s.findTray(ev.Event) == nil {
 				noWindowFound = true
 			}
 
 		case xproto.ButtonReleaseEvent:
+			// The release that ends a drag is also sent to the window, as
+			// the window was sent the press.
+			s.dnd.handleDragRelease(ev.Event, ev.Time)
 			if w := s.findWindow(ev.Event); w != nil {
-				w.handleMouse(ev.EventX, ev.EventY, ev.Detail, ev.State, mouse.DirRelease)
+				w.handleMouse(ev.EventX, ev.EventY, ev.Detail, ev.State, mouse.DirRelease, ev.Time)
+			} else if t := s.findTray(ev.Event); t != nil {
+				t.handleButtonRelease(ev.Detail)
 			} else {
 				noWindowFound = true
 			}
 
 		case xproto.MotionNotifyEvent:
+			if s.dnd.handleDragMotion(ev.Event, ev.RootX, ev.RootY, ev.Time) {
+				break
+			}
 			if w := s.findWindow(ev.Event); w != nil {
-				w.handleMouse(ev.EventX, ev.EventY, 0, ev.State, mouse.DirNone)
+				w.handleMouse(ev.EventX, ev.EventY, 0, ev.State, mouse.DirNone, ev.Time)
 			} else {
 				noWindowFound = true
 			}
+
+		case xproto.PropertyNotifyEvent:
+			if ev.Window == s.xsettingsOwner && ev.Atom == s.atomXSettingsSettings {
+				s.handleXSettingsChange()
+			} else if ev.Window == s.xsi.Root && ev.Atom == s.atomXKBRulesNames {
+				s.handleRulesNamesChange()
+			} else if ev.Atom == s.atomNETWMState {
+				if w := s.findWindow(ev.Window); w != nil {
+					w.handleWMStateChange()
+				}
+			} else {
+				s.clipboard.handlePropertyNotify(ev)
+			}
+
+		case randr.ScreenChangeNotifyEvent, randr.NotifyEvent:
+			s.handleDisplayChange()
+
+		case xproto.SelectionClearEvent:
+			if ev.Selection == s.dnd.atomSelection {
+				s.dnd.handleSelectionClear(ev)
+			} else {
+				s.clipboard.handleSelectionClear(ev)
+			}
+
+		case xproto.SelectionNotifyEvent:
+			s.clipboard.handleSelectionNotify(ev)
+
+		case xproto.SelectionRequestEvent:
+			if ev.Selection == s.dnd.atomSelection {
+				s.dnd.handleSelectionRequest(ev)
+			} else {
+				s.clipboard.handleSelectionRequest(ev)
+			}
 		}
 
 		if noWindowFound {
@@ -265,12 +480,35 @@ func (s *screenImpl) handleCompletions() {
 const (
 	maxShmSide = 0x00007fff // 32,767 pixels.
 	maxShmSize = 0x10000000 // 268,435,456 bytes.
+
+	// maxFreeShmSize is the maximum total size of the shared memory
+	// segments of released Buffers that are kept for re-use.
+	maxFreeShmSize = 0x04000000 // 67,108,864 bytes.
 )
 
-func (s *screenImpl) NewBuffer(size image.Point) (retBuf screen.Buffer, retErr error) {
-	// TODO: detect if the X11 server or connection cannot support SHM pixmaps,
-	// and fall back to regular pixmaps.
+// shmWorks returns whether the X11 server can attach this process's shared
+// memory segments. It cannot if it is on another machine, even if it
+// supports MIT-SHM.
+func (s *screenImpl) shmWorks() bool {
+	xs, err := shm.NewSegId(s.xc)
+	if err != nil {
+		return false
+	}
+	shmid, addr, err := shmOpen(1)
+	if err != nil {
+		return false
+	}
+	defer shmClose(addr)
+
+	const readOnly = false
+	if err := shm.AttachChecked(s.xc, xs, uint32(shmid), readOnly).Check(); err != nil {
+		return false
+	}
+	shm.Detach(s.xc, xs)
+	return true
+}
 
+func (s *screenImpl) NewBuffer(size image.Point) (retBuf screen.Buffer, retErr error) {
 	w, h := int64(size.X), int64(size.Y)
 	if w < 0 || maxShmSide < w || h < 0 || maxShmSide < h || maxShmSize < 4*w*h {
 		return nil, fmt.Errorf("x11driver: invalid buffer size %v", size)
@@ -288,6 +526,15 @@ func (s *screenImpl) NewBuffer(size image.Point) (retBuf screen.Buffer, retErr e
 	if size.X == 0 || size.Y == 0 {
 		// No-op, but we can't take the else path because the minimum shmget
 		// size is 1.
+	} else if !s.useShm {
+		b.buf = s.pixPool.Get(4 * size.X * size.Y)
+		b.rgba.Pix = b.buf
+		return b, nil
+	} else if seg, ok := s.reuseShm(4 * size.X * size.Y); ok {
+		b.buf = seg.buf
+		b.rgba.Pix = b.buf
+		b.addr = seg.addr
+		b.xs = seg.xs
 	} else {
 		xs, err := shm.NewSegId(s.xc)
 		if err != nil {
@@ -324,14 +571,38 @@ func (s *screenImpl) NewBuffer(size image.Point) (retBuf screen.Buffer, retErr e
 }
 
 func (s *screenImpl) NewTexture(size image.Point) (screen.Texture, error) {
+	return s.NewTextureWithOptions(size, nil)
+}
+
+// NewTextureWithOptions implements screen.Screen. The texture's pixmap is
+// 32-bit BGRA whatever the format, as X11/Render composites from and onto a
+// single picture format. Its picture's filter and repeat attributes implement
+// the filter and wrap options. Mipmaps are not kept.
+func (s *screenImpl) NewTextureWithOptions(size image.Point, opts *screen.NewTextureOptions) (screen.Texture, error) {
 	w, h := int64(size.X), int64(size.Y)
 	if w < 0 || maxShmSide < w || h < 0 || maxShmSide < h || maxShmSize < 4*w*h {
 		return nil, fmt.Errorf("x11driver: invalid texture size %v", size)
 	}
+	if opts != nil && opts.Compressed != screen.CompressedNone {
+		return nil, screen.ErrUnsupportedFormat
+	}
+	format := opts.GetFormat()
+	if format == screen.PixelFormatBGRA8 {
+		format = screen.PixelFormatRGBA8
+	}
+	filter, wrap := "bilinear", screen.WrapClamp
+	if opts != nil {
+		if opts.Filter == screen.FilterNearest {
+			filter = "nearest"
+		}
+		wrap = opts.Wrap
+	}
 	if w == 0 || h == 0 {
 		return &textureImpl{
-			s:    s,
-			size: size,
+			s:      s,
+			size:   size,
+			format: format,
+			wrap:   wrap,
 		}, nil
 	}
 
@@ -344,24 +615,70 @@ func (s *screenImpl) NewTexture(size image.Point) (screen.Texture, error) {
 		return nil, fmt.Errorf("x11driver: xproto.NewPictureId failed: %v", err)
 	}
 	xproto.CreatePixmap(s.xc, textureDepth, xm, xproto.Drawable(s.window32), uint16(w), uint16(h))
-	render.CreatePicture(s.xc, xp, xproto.Drawable(xm), s.pictformat32, render.CpRepeat, []uint32{render.RepeatPad})
-	render.SetPictureFilter(s.xc, xp, uint16(len("bilinear")), "bilinear", nil)
+	render.CreatePicture(s.xc, xp, xproto.Drawable(xm), s.pictformat32, render.CpRepeat, []uint32{repeats[wrap]})
+	render.SetPictureFilter(s.xc, xp, uint16(len(filter)), filter, nil)
 	// The X11 server doesn't zero-initialize the pixmap. We do it ourselves.
 	render.FillRectangles(s.xc, render.PictOpSrc, xp, render.Color{}, []xproto.Rectangle{{
 		Width:  uint16(w),
 		Height: uint16(h),
 	}})
 
-	return &textureImpl{
-		s:    s,
-		size: size,
-		xm:   xm,
-		xp:   xp,
-	}, nil
+	t := &textureImpl{
+		s:      s,
+		size:   size,
+		format: format,
+		wrap:   wrap,
+		xm:     xm,
+		xp:     xp,
+	}
+	s.textureBytes.Add(t.bytes())
+	return t, nil
+}
+
+// repeats are the X11/Render repeat attributes of each screen.TextureWrap.
+// Clamped textures are padded, so that bilinear filtering near their edges
+// does not fade to transparent.
+var repeats = [...]uint32{
+	screen.WrapClamp:  render.RepeatPad,
+	screen.WrapRepeat: render.RepeatNormal,
+	screen.WrapMirror: render.RepeatReflect,
+}
+
+func (s *screenImpl) TextureMemoryEstimate() int64 {
+	return s.textureBytes.Load()
+}
+
+func (s *screenImpl) AccessibilityPrefs() screen.AccessibilityPrefs {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accessibilityPrefs
+}
+
+func (s *screenImpl) ColorScheme() screen.ColorScheme {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.colorScheme
+}
+
+func (s *screenImpl) Clipboard() screen.Clipboard {
+	return &s.clipboard
+}
+
+func (s *screenImpl) SetDefaultWindowOptions(opts *screen.NewWindowOptions) {
+	opts = opts.WithDefaults(nil)
+
+	s.mu.Lock()
+	s.defaultWindowOptions = opts
+	s.mu.Unlock()
 }
 
 func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) {
+	s.mu.Lock()
+	opts = opts.WithDefaults(s.defaultWindowOptions)
+	s.mu.Unlock()
+
 	width, height := 1024, 768
+	x, y := 0, 0
 	if opts != nil {
 		if opts.Width > 0 {
 			width = opts.Width
@@ -369,6 +686,9 @@ func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, er
 		if opts.Height > 0 {
 			height = opts.Height
 		}
+		if opts.Position != nil {
+			x, y = opts.Position.X, opts.Position.Y
+		}
 	}
 
 	xw, err := xproto.NewWindowId(s.xc)
@@ -383,10 +703,14 @@ func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, er
 	if err != nil {
 		return nil, fmt.Errorf("x11driver: render.NewPictureId failed: %v", err)
 	}
+	depth, visual := s.xsi.RootDepth, s.xsi.RootVisual
+	if opts != nil && opts.Transparent {
+		depth, visual = 32, s.visual32
+	}
 	pictformat := render.Pictformat(0)
-	switch s.xsi.RootDepth {
+	switch depth {
 	default:
-		return nil, fmt.Errorf("x11driver: unsupported root depth %d", s.xsi.RootDepth)
+		return nil, fmt.Errorf("x11driver: unsupported root depth %d", depth)
 	case 24:
 		pictformat = s.pictformat24
 	case 32:
@@ -398,8 +722,26 @@ func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, er
 		xw:      xw,
 		xg:      xg,
 		xp:      xp,
+		depth:   depth,
 		xevents: make(chan xgb.Event),
 	}
+	if opts != nil {
+		w.Priority = opts.EventPriority
+		w.KeepAllMoves = opts.KeepAllMoves
+		w.KeyEvents = opts.KeyEvents
+		w.Queues = opts.EventQueues
+		w.fixedSize = opts.FixedSize
+		w.interceptClose = opts.InterceptClose
+		w.occlusion.Policy = opts.OccludedPublish
+	}
+	var owner *windowImpl
+	if opts != nil {
+		owner, _ = opts.Owner.(*windowImpl)
+	}
+	if owner != nil && opts.Modal {
+		w.modalOwner = owner
+		atomic.AddInt32(&owner.modalChildren, 1)
+	}
 
 	s.mu.Lock()
 	s.windows[xw] = w
@@ -407,42 +749,134 @@ func (s *screenImpl) NewWindow(opts *screen.NewWindowOptions) (screen.Window, er
 
 	w.lifecycler.SendEvent(w, nil)
 
-	xproto.CreateWindow(s.xc, s.xsi.RootDepth, xw, s.xsi.Root,
-		0, 0, uint16(width), uint16(height), 0,
-		xproto.WindowClassInputOutput, s.xsi.RootVisual,
-		xproto.CwEventMask,
-		[]uint32{0 |
-			xproto.EventMaskKeyPress |
-			xproto.EventMaskKeyRelease |
-			xproto.EventMaskButtonPress |
-			xproto.EventMaskButtonRelease |
-			xproto.EventMaskPointerMotion |
-			xproto.EventMaskExposure |
-			xproto.EventMaskStructureNotify |
-			xproto.EventMaskFocusChange,
-		},
-	)
+	valueMask, values := uint32(xproto.CwEventMask), []uint32{0 |
+		xproto.EventMaskKeyPress |
+		xproto.EventMaskKeyRelease |
+		xproto.EventMaskButtonPress |
+		xproto.EventMaskButtonRelease |
+		xproto.EventMaskPointerMotion |
+		xproto.EventMaskEnterWindow |
+		xproto.EventMaskLeaveWindow |
+		xproto.EventMaskExposure |
+		xproto.EventMaskStructureNotify |
+		xproto.EventMaskFocusChange |
+		xproto.EventMaskPropertyChange |
+		xproto.EventMaskVisibilityChange,
+	}
+	if visual != s.xsi.RootVisual {
+		// A window whose visual differs from its parent's needs its own
+		// colormap and border pixel. A zero background pixel is transparent
+		// black. The values are in the order of their bits in the mask.
+		valueMask |= xproto.CwBackPixel | xproto.CwBorderPixel | xproto.CwColormap
+		values = []uint32{0, 0, values[0], uint32(s.colormap32)}
+	}
+	xproto.CreateWindow(s.xc, depth, xw, s.xsi.Root,
+		int16(x), int16(y), uint16(width), uint16(height), 0,
+		xproto.WindowClassInputOutput, visual, valueMask, values)
+	if opts != nil && len(opts.Shape) > 0 && s.shapeExt {
+		shape.Rectangles(s.xc, shape.SoSet, shape.SkBounding, xproto.ClipOrderingUnsorted,
+			xw, 0, 0, shapeRectangles(opts.Shape))
+	}
 	s.setProperty(xw, s.atomWMProtocols, s.atomWMDeleteWindow, s.atomWMTakeFocus)
+	s.dnd.setAware(xw)
+	w.requestedSize = image.Point{width, height}
+	w.setSizeHints(opts)
+	if opts != nil && opts.Undecorated {
+		s.setUndecorated(xw)
+	}
+	if owner != nil {
+		// A transient window is kept above, and minimized with, its owner.
+		// Before the window is mapped, its _NET_WM_STATE can be set
+		// directly, instead of by asking the window manager.
+		b := make([]byte, 4)
+		xgb.Put32(b, uint32(owner.xw))
+		xproto.ChangeProperty(s.xc, xproto.PropModeReplace, xw, xproto.AtomWmTransientFor, xproto.AtomWindow, 32, 1, b)
+		if opts.Modal {
+			s.setProperty(xw, s.atomNETWMState, s.atomNETWMStateModal)
+		}
+	}
 
 	title := []byte(opts.GetTitle())
 	xproto.ChangeProperty(s.xc, xproto.PropModeReplace, xw, s.atomNETWMName, s.atomUTF8String, 8, uint32(len(title)), title)
 
 	xproto.CreateGC(s.xc, xg, xproto.Drawable(xw), 0, nil)
 	render.CreatePicture(s.xc, xp, xproto.Drawable(xw), pictformat, 0, nil)
-	xproto.MapWindow(s.xc, xw)
+	if opts == nil || !opts.Hidden {
+		xproto.MapWindow(s.xc, xw)
+	}
 
 	return w, nil
 }
 
 func (s *screenImpl) initAtoms() (err error) {
+	s.atomMotifWMHints, err = s.internAtom("_MOTIF_WM_HINTS")
+	if err != nil {
+		return err
+	}
+	s.atomNETFrameExtents, err = s.internAtom("_NET_FRAME_EXTENTS")
+	if err != nil {
+		return err
+	}
+	s.atomNETWMBypassCompositor, err = s.internAtom("_NET_WM_BYPASS_COMPOSITOR")
+	if err != nil {
+		return err
+	}
+	s.atomNETWMIcon, err = s.internAtom("_NET_WM_ICON")
+	if err != nil {
+		return err
+	}
+	s.atomNETWMMoveResize, err = s.internAtom("_NET_WM_MOVERESIZE")
+	if err != nil {
+		return err
+	}
 	s.atomNETWMName, err = s.internAtom("_NET_WM_NAME")
 	if err != nil {
 		return err
 	}
+	s.atomNETWMState, err = s.internAtom("_NET_WM_STATE")
+	if err != nil {
+		return err
+	}
+	s.atomNETWMStateAbove, err = s.internAtom("_NET_WM_STATE_ABOVE")
+	if err != nil {
+		return err
+	}
+	s.atomNETWMStateFullscreen, err = s.internAtom("_NET_WM_STATE_FULLSCREEN")
+	if err != nil {
+		return err
+	}
+	s.atomNETWMStateHidden, err = s.internAtom("_NET_WM_STATE_HIDDEN")
+	if err != nil {
+		return err
+	}
+	s.atomNETWMStateMaximizedHorz, err = s.internAtom("_NET_WM_STATE_MAXIMIZED_HORZ")
+	if err != nil {
+		return err
+	}
+	s.atomNETWMStateMaximizedVert, err = s.internAtom("_NET_WM_STATE_MAXIMIZED_VERT")
+	if err != nil {
+		return err
+	}
+	s.atomNETWMStateModal, err = s.internAtom("_NET_WM_STATE_MODAL")
+	if err != nil {
+		return err
+	}
+	s.atomNETWMWindowOpacity, err = s.internAtom("_NET_WM_WINDOW_OPACITY")
+	if err != nil {
+		return err
+	}
+	s.atomNETWorkArea, err = s.internAtom("_NET_WORKAREA")
+	if err != nil {
+		return err
+	}
 	s.atomUTF8String, err = s.internAtom("UTF8_STRING")
 	if err != nil {
 		return err
 	}
+	s.atomWMChangeState, err = s.internAtom("WM_CHANGE_STATE")
+	if err != nil {
+		return err
+	}
 	s.atomWMDeleteWindow, err = s.internAtom("WM_DELETE_WINDOW")
 	if err != nil {
 		return err
@@ -480,8 +914,14 @@ func (s *screenImpl) initKeyboardMapping() error {
 		return fmt.Errorf("x11driver: too few keysyms per keycode: %d", n)
 	}
 	for i := keyLo; i <= keyHi; i++ {
-		s.keysyms[i][0] = uint32(km.Keysyms[(i-keyLo)*n+0])
-		s.keysyms[i][1] = uint32(km.Keysyms[(i-keyLo)*n+1])
+		// The keysyms of the second group, if any, are in the third and
+		// fourth columns.
+		for j := range s.keysyms[i] {
+			s.keysyms[i][j] = 0
+			if j < n {
+				s.keysyms[i][j] = uint32(km.Keysyms[(i-keyLo)*n+j])
+			}
+		}
 	}
 	return nil
 }
@@ -526,17 +966,17 @@ func findPictformat(fs []render.Pictforminfo, depth byte) (render.Pictformat, er
 	return 0, fmt.Errorf("x11driver: no matching Pictformat for depth %d", depth)
 }
 
-func (s *screenImpl) initWindow32() error {
-	visualid, err := findVisual(s.xsi, 32)
+func (s *screenImpl) initWindow32() (err error) {
+	s.visual32, err = findVisual(s.xsi, 32)
 	if err != nil {
 		return err
 	}
-	colormap, err := xproto.NewColormapId(s.xc)
+	s.colormap32, err = xproto.NewColormapId(s.xc)
 	if err != nil {
 		return fmt.Errorf("x11driver: xproto.NewColormapId failed: %v", err)
 	}
 	if err := xproto.CreateColormapChecked(
-		s.xc, xproto.ColormapAllocNone, colormap, s.xsi.Root, visualid).Check(); err != nil {
+		s.xc, xproto.ColormapAllocNone, s.colormap32, s.xsi.Root, s.visual32).Check(); err != nil {
 		return fmt.Errorf("x11driver: xproto.CreateColormap failed: %v", err)
 	}
 	s.window32, err = xproto.NewWindowId(s.xc)
@@ -550,11 +990,11 @@ func (s *screenImpl) initWindow32() error {
 	const depth = 32
 	xproto.CreateWindow(s.xc, depth, s.window32, s.xsi.Root,
 		0, 0, 1, 1, 0,
-		xproto.WindowClassInputOutput, visualid,
+		xproto.WindowClassInputOutput, s.visual32,
 		// The CwBorderPixel attribute seems necessary for depth == 32. See
 		// http://stackoverflow.com/questions/3645632/how-to-create-a-window-with-a-bit-depth-of-32
 		xproto.CwBorderPixel|xproto.CwColormap,
-		[]uint32{0, uint32(colormap)},
+		[]uint32{0, uint32(s.colormap32)},
 	)
 	xproto.CreateGC(s.xc, s.gcontext32, xproto.Drawable(s.window32), 0, nil)
 	return nil
@@ -574,6 +1014,37 @@ func findVisual(xsi *xproto.ScreenInfo, depth byte) (xproto.Visualid, error) {
 	return 0, fmt.Errorf("x11driver: no matching Visualid")
 }
 
+// shapeRectangles converts a window's shape to X11 rectangles, clamped to
+// what X11 can represent. Empty rectangles are omitted.
+func shapeRectangles(rs []image.Rectangle) []xproto.Rectangle {
+	clamp := func(x, lo, hi int) int {
+		if x < lo {
+			return lo
+		}
+		if x > hi {
+			return hi
+		}
+		return x
+	}
+	xrs := make([]xproto.Rectangle, 0, len(rs))
+	for _, r := range rs {
+		x0 := clamp(r.Min.X, -0x8000, 0x7fff)
+		y0 := clamp(r.Min.Y, -0x8000, 0x7fff)
+		x1 := clamp(r.Max.X, x0, x0+0xffff)
+		y1 := clamp(r.Max.Y, y0, y0+0xffff)
+		if x0 >= x1 || y0 >= y1 {
+			continue
+		}
+		xrs = append(xrs, xproto.Rectangle{
+			X:      int16(x0),
+			Y:      int16(y0),
+			Width:  uint16(x1 - x0),
+			Height: uint16(y1 - y0),
+		})
+	}
+	return xrs
+}
+
 func (s *screenImpl) setProperty(xw xproto.Window, prop xproto.Atom, values ...xproto.Atom) {
 	b := make([]byte, len(values)*4)
 	for i, v := range values {
@@ -585,6 +1056,89 @@ func (s *screenImpl) setProperty(xw xproto.Window, prop xproto.Atom, values ...x
 	xproto.ChangeProperty(s.xc, xproto.PropModeReplace, xw, prop, xproto.AtomAtom, 32, uint32(len(values)), b)
 }
 
+// sizeConstraints are a window's SetSizeConstraints and SetAspectRatio
+// arguments.
+type sizeConstraints struct {
+	min, max, aspect image.Point
+}
+
+// setSizeHints sets the ICCCM WM_NORMAL_HINTS property, which tells the window
+// manager whether the window was explicitly positioned and how it may be
+// resized. That property is an array of 18 CARD32 values, of which the first
+// is a bit mask of which of the others are meaningful. A minimum or maximum
+// size constrains both dimensions, so an unconstrained dimension's minimum is
+// 1 and its maximum is the largest that X11 allows.
+func (s *screenImpl) setSizeHints(xw xproto.Window, size image.Point, fixedSize bool, c sizeConstraints, opts *screen.NewWindowOptions) {
+	const (
+		usPosition = 1 << 0
+		usSize     = 1 << 1
+		pMinSize   = 1 << 4
+		pMaxSize   = 1 << 5
+		pAspect    = 1 << 7
+
+		maxInt32 = 1<<31 - 1
+	)
+	var hints [18]uint32
+	hints[0] = usSize
+	hints[3], hints[4] = uint32(size.X), uint32(size.Y)
+	if opts != nil {
+		if p := opts.Position; p != nil {
+			hints[0] |= usPosition
+			hints[1], hints[2] = uint32(int32(p.X)), uint32(int32(p.Y))
+		}
+	}
+	if fixedSize {
+		hints[0] |= pMinSize | pMaxSize
+		hints[5], hints[6] = uint32(size.X), uint32(size.Y)
+		hints[7], hints[8] = uint32(size.X), uint32(size.Y)
+	} else {
+		if c.min.X > 0 || c.min.Y > 0 {
+			hints[0] |= pMinSize
+			hints[5], hints[6] = positive(c.min.X, 1), positive(c.min.Y, 1)
+		}
+		if c.max.X > 0 || c.max.Y > 0 {
+			hints[0] |= pMaxSize
+			hints[7], hints[8] = positive(c.max.X, maxInt32), positive(c.max.Y, maxInt32)
+		}
+		if c.aspect.X > 0 && c.aspect.Y > 0 {
+			hints[0] |= pAspect
+			hints[11], hints[12] = uint32(c.aspect.X), uint32(c.aspect.Y)
+			hints[13], hints[14] = uint32(c.aspect.X), uint32(c.aspect.Y)
+		}
+	}
+	b := make([]byte, 4*len(hints))
+	for i, v := range hints {
+		b[4*i+0] = uint8(v >> 0)
+		b[4*i+1] = uint8(v >> 8)
+		b[4*i+2] = uint8(v >> 16)
+		b[4*i+3] = uint8(v >> 24)
+	}
+	xproto.ChangeProperty(s.xc, xproto.PropModeReplace, xw, xproto.AtomWmNormalHints, xproto.AtomWmSizeHints, 32, uint32(len(hints)), b)
+}
+
+// positive returns x, or otherwise if x is not positive.
+func positive(x int, otherwise uint32) uint32 {
+	if x <= 0 {
+		return otherwise
+	}
+	return uint32(x)
+}
+
+// setUndecorated sets the _MOTIF_WM_HINTS property, which most window
+// managers honor, to ask for a window without a frame. That property is an
+// array of 5 CARD32 values, of which the first is a bit mask of which of the
+// others are meaningful, and the third is which decorations to draw.
+func (s *screenImpl) setUndecorated(xw xproto.Window) {
+	const mwmHintsDecorations = 1 << 1
+	var hints [5]uint32
+	hints[0] = mwmHintsDecorations
+	b := make([]byte, 4*len(hints))
+	for i, v := range hints {
+		xgb.Put32(b[4*i:], v)
+	}
+	xproto.ChangeProperty(s.xc, xproto.PropModeReplace, xw, s.atomMotifWMHints, s.atomMotifWMHints, 32, uint32(len(hints)), b)
+}
+
 func (s *screenImpl) drawUniform(xp render.Picture, src2dst *f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
 	if sr.Empty() {
 		return
@@ -595,7 +1149,7 @@ func (s *screenImpl) drawUniform(xp render.Picture, src2dst *f64.Aff3, src color
 		return
 	}
 
-	r, g, b, a := src.RGBA()
+	r, g, b, a := drawer.ScaleColor(src, opts.GetAlpha()).RGBA()
 	c := render.Color{
 		Red:   uint16(r),
 		Green: uint16(g),
@@ -613,10 +1167,16 @@ func (s *screenImpl) drawUniform(xp render.Picture, src2dst *f64.Aff3, src color
 		render.CreateSolidFill(s.xc, s.uniformP, c)
 	}
 
-	if op == draw.Src {
-		// We implement draw.Src as render.PictOpOutReverse followed by
-		// render.PictOpOver, for the same reason as in textureImpl.draw.
-		render.TriFan(s.xc, render.PictOpOutReverse, s.opaqueP, xp, 0, 0, 0, points[:])
+	trifan(s, opts.GetBlend(op), s.uniformP, xp, points[:])
+}
+
+// alphaMask returns s.alphaP, after setting its alpha to a. It must only be
+// called while holding s.alphaMu.
+func (s *screenImpl) alphaMask(a uint16) render.Picture {
+	if s.alphaA != a {
+		s.alphaA = a
+		render.FreePicture(s.xc, s.alphaP)
+		render.CreateSolidFill(s.xc, s.alphaP, render.Color{Alpha: a})
 	}
-	render.TriFan(s.xc, render.PictOpOver, s.uniformP, xp, 0, 0, 0, points[:])
+	return s.alphaP
 }
@@ -0,0 +1,294 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// The window's state is kept by the window manager, in the EWMH _NET_WM_STATE
+// property. Clients ask the window manager to change it, with client
+// messages sent to the root window, rather than changing it themselves.
+//
+// See https://specifications.freedesktop.org/wm-spec/latest/
+
+// _NET_WM_STATE client message actions.
+const (
+	netWMStateRemove = 0
+	netWMStateAdd    = 1
+)
+
+// iconicState is the ICCCM WM_STATE of a minimized window.
+const iconicState = 3
+
+func (w *windowImpl) Minimize() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	w.s.sendRootMessage(w.xw, w.s.atomWMChangeState, iconicState)
+}
+
+func (w *windowImpl) Maximize() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	s := w.s
+	s.sendRootMessage(w.xw, s.atomNETWMState, netWMStateRemove, uint32(s.atomNETWMStateFullscreen))
+	s.sendRootMessage(w.xw, s.atomNETWMState, netWMStateAdd,
+		uint32(s.atomNETWMStateMaximizedHorz), uint32(s.atomNETWMStateMaximizedVert))
+	// As for Restore, mapping the window un-minimizes it.
+	xproto.MapWindow(s.xc, w.xw)
+}
+
+func (w *windowImpl) Restore() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	s := w.s
+	s.sendRootMessage(w.xw, s.atomNETWMState, netWMStateRemove, uint32(s.atomNETWMStateFullscreen))
+	s.sendRootMessage(w.xw, s.atomNETWMState, netWMStateRemove,
+		uint32(s.atomNETWMStateMaximizedHorz), uint32(s.atomNETWMStateMaximizedVert))
+	// Mapping a minimized window restores it, as ICCCM section 4.1.4 says.
+	xproto.MapWindow(s.xc, w.xw)
+}
+
+// SetFullscreen asks the window manager for a fullscreen window, which also
+// leaves the window's _NET_WM_STATE_MAXIMIZED states as they were, to return
+// to after fullscreen. For FullscreenExclusive, it also sets the
+// _NET_WM_BYPASS_COMPOSITOR property, which asks a compositing window manager
+// to stop compositing while the window is fullscreen.
+func (w *windowImpl) SetFullscreen(mode screen.FullscreenMode) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	s := w.s
+	if mode == screen.FullscreenExclusive {
+		b := make([]byte, 4)
+		xgb.Put32(b, 1) // 1 means to disable compositing.
+		xproto.ChangeProperty(s.xc, xproto.PropModeReplace, w.xw, s.atomNETWMBypassCompositor,
+			xproto.AtomCardinal, 32, 1, b)
+	} else {
+		xproto.DeleteProperty(s.xc, w.xw, s.atomNETWMBypassCompositor)
+	}
+	action := uint32(netWMStateAdd)
+	if mode == screen.FullscreenNone {
+		action = netWMStateRemove
+	}
+	s.sendRootMessage(w.xw, s.atomNETWMState, action, uint32(s.atomNETWMStateFullscreen))
+}
+
+func (w *windowImpl) SetAlwaysOnTop(onTop bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	action := uint32(netWMStateAdd)
+	if !onTop {
+		action = netWMStateRemove
+	}
+	w.s.sendRootMessage(w.xw, w.s.atomNETWMState, action, uint32(w.s.atomNETWMStateAbove))
+}
+
+// SetOpacity sets the _NET_WM_WINDOW_OPACITY property, a CARDINAL where
+// 0xffffffff is opaque, which compositing managers read from the window, or
+// which window managers copy to the window's frame. An opaque window has no
+// such property.
+func (w *windowImpl) SetOpacity(opacity float32) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	s := w.s
+	if !(opacity < 1) {
+		xproto.DeleteProperty(s.xc, w.xw, s.atomNETWMWindowOpacity)
+		return
+	}
+	if !(opacity > 0) {
+		opacity = 0
+	}
+	b := make([]byte, 4)
+	xgb.Put32(b, uint32(float64(opacity)*0xffffffff))
+	xproto.ChangeProperty(s.xc, xproto.PropModeReplace, w.xw, s.atomNETWMWindowOpacity,
+		xproto.AtomCardinal, 32, 1, b)
+}
+
+// Raise and Lower restack the window. A window manager redirects the request,
+// and applies it to the window's frame.
+func (w *windowImpl) Raise() { w.restack(xproto.StackModeAbove) }
+
+func (w *windowImpl) Lower() { w.restack(xproto.StackModeBelow) }
+
+func (w *windowImpl) restack(mode uint32) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	xproto.ConfigureWindow(w.s.xc, w.xw, xproto.ConfigWindowStackMode, []uint32{mode})
+}
+
+// netWMMoveResizeDirections are the _NET_WM_MOVERESIZE directions, indexed by
+// screen.WindowEdge, and netWMMoveResizeMove is the direction that moves the
+// window instead.
+var netWMMoveResizeDirections = [...]uint32{
+	screen.WindowEdgeTop:         1,
+	screen.WindowEdgeBottom:      5,
+	screen.WindowEdgeLeft:        7,
+	screen.WindowEdgeRight:       3,
+	screen.WindowEdgeTopLeft:     0,
+	screen.WindowEdgeTopRight:    2,
+	screen.WindowEdgeBottomLeft:  6,
+	screen.WindowEdgeBottomRight: 4,
+}
+
+const netWMMoveResizeMove = 8
+
+func (w *windowImpl) StartMove() error {
+	return w.startMoveResize("StartMove", netWMMoveResizeMove)
+}
+
+func (w *windowImpl) StartResize(edge screen.WindowEdge) error {
+	if int(edge) >= len(netWMMoveResizeDirections) {
+		return fmt.Errorf("x11driver: StartResize with invalid edge %d", edge)
+	}
+	return w.startMoveResize("StartResize", netWMMoveResizeDirections[edge])
+}
+
+// startMoveResize asks the window manager, with a _NET_WM_MOVERESIZE client
+// message, to move or resize the window interactively, following the pointer
+// until the button that is held down is released.
+func (w *windowImpl) startMoveResize(method string, direction uint32) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.released {
+		return fmt.Errorf("x11driver: %s on a released window", method)
+	}
+	s := w.s
+	r, err := xproto.QueryPointer(s.xc, w.xw).Reply()
+	if err != nil {
+		return fmt.Errorf("x11driver: xproto.QueryPointer failed: %v", err)
+	}
+	button := uint32(0)
+	switch {
+	case r.Mask&xproto.KeyButMaskButton1 != 0:
+		button = 1
+	case r.Mask&xproto.KeyButMaskButton2 != 0:
+		button = 2
+	case r.Mask&xproto.KeyButMaskButton3 != 0:
+		button = 3
+	}
+	// The button press has implicitly grabbed the pointer for this window,
+	// and the window manager cannot grab it while this window has it.
+	xproto.UngrabPointer(s.xc, xproto.TimeCurrentTime)
+	s.sendRootMessage(w.xw, s.atomNETWMMoveResize,
+		uint32(int32(r.RootX)), uint32(int32(r.RootY)), direction, button,
+		1, // The source indication, 1 for a normal application.
+	)
+	return nil
+}
+
+// sendRootMessage sends a client message, about xw, to the root window, where
+// the window manager receives it.
+func (s *screenImpl) sendRootMessage(xw xproto.Window, typ xproto.Atom, data ...uint32) {
+	var d [5]uint32
+	copy(d[:], data)
+	if typ == s.atomNETWMState {
+		d[3] = 1 // The source indication, 1 for a normal application.
+	}
+	ev := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: xw,
+		Type:   typ,
+		Data:   xproto.ClientMessageDataUnionData32New(d[:]),
+	}
+	xproto.SendEvent(s.xc, false, s.xsi.Root,
+		xproto.EventMaskSubstructureNotify|xproto.EventMaskSubstructureRedirect, string(ev.Bytes()))
+}
+
+// handleWMStateChange reads the window's _NET_WM_STATE property, after the
+// window manager changed it, and sends a screen.WindowStateEvent if the
+// window's state changed. Like handleConfigureNotify, it must only be called
+// from the screenImpl.run goroutine.
+func (w *windowImpl) handleWMStateChange() {
+	s := w.s
+	r, err := xproto.GetProperty(s.xc, false, w.xw, s.atomNETWMState, xproto.AtomAtom, 0, 64).Reply()
+	if err != nil {
+		log.Printf("x11driver: xproto.GetProperty failed: %v", err)
+		return
+	}
+	var hidden, fullscreen, maxHorz, maxVert bool
+	if r.Format == 32 {
+		for i := 0; i+4 <= len(r.Value); i += 4 {
+			switch xproto.Atom(xgb.Get32(r.Value[i:])) {
+			case s.atomNETWMStateHidden:
+				hidden = true
+			case s.atomNETWMStateFullscreen:
+				fullscreen = true
+			case s.atomNETWMStateMaximizedHorz:
+				maxHorz = true
+			case s.atomNETWMStateMaximizedVert:
+				maxVert = true
+			}
+		}
+	}
+	state := screen.WindowNormal
+	switch {
+	case hidden:
+		state = screen.WindowMinimized
+	case fullscreen:
+		state = screen.WindowFullscreen
+	case maxHorz && maxVert:
+		state = screen.WindowMaximized
+	}
+	if state != w.state {
+		w.state = state
+		w.Send(screen.WindowStateEvent{State: state})
+		w.updateVisibility()
+	}
+}
+
+// handleVisibilityNotify records how much of the window the X server says is
+// visible, and sends a screen.VisibilityEvent if that changed. Like
+// handleWMStateChange, it must only be called from the screenImpl.run
+// goroutine.
+func (w *windowImpl) handleVisibilityNotify(ev xproto.VisibilityNotifyEvent) {
+	w.xvisibility = ev.State
+	w.updateVisibility()
+}
+
+// updateVisibility sends a screen.VisibilityEvent if the window's visibility,
+// from its state and its last VisibilityNotify event, changed. An unmapped
+// window, such as a minimized one, is sent no VisibilityNotify events.
+func (w *windowImpl) updateVisibility() {
+	v := screen.VisibilityFull
+	switch {
+	case w.state == screen.WindowMinimized:
+		v = screen.VisibilityMinimized
+	case w.xvisibility == xproto.VisibilityFullyObscured:
+		v = screen.VisibilityOccluded
+	case w.xvisibility == xproto.VisibilityPartiallyObscured:
+		v = screen.VisibilityPartial
+	}
+	if w.occlusion.Set(v) {
+		w.Send(screen.VisibilityEvent{Visibility: v})
+	}
+}
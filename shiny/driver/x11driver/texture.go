@@ -5,6 +5,8 @@
 package x11driver
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
@@ -14,6 +16,9 @@ import (
 	"github.com/BurntSushi/xgb/render"
 	"github.com/BurntSushi/xgb/xproto"
 
+	"golang.org/x/exp/shiny/driver/internal/drawer"
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
+	"golang.org/x/exp/shiny/driver/internal/yuv"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/image/math/f64"
 )
@@ -23,9 +28,11 @@ const textureDepth = 32
 type textureImpl struct {
 	s *screenImpl
 
-	size image.Point
-	xm   xproto.Pixmap
-	xp   render.Picture
+	size   image.Point
+	format screen.PixelFormat
+	wrap   screen.TextureWrap
+	xm     xproto.Pixmap
+	xp     render.Picture
 
 	// renderMu is a mutex that enforces the atomicity of methods like
 	// Window.Draw that are conceptually one operation but are implemented by
@@ -38,9 +45,13 @@ type textureImpl struct {
 	released   bool
 }
 
-func (t *textureImpl) degenerate() bool        { return t.size.X == 0 || t.size.Y == 0 }
-func (t *textureImpl) Size() image.Point       { return t.size }
-func (t *textureImpl) Bounds() image.Rectangle { return image.Rectangle{Max: t.size} }
+func (t *textureImpl) degenerate() bool           { return t.size.X == 0 || t.size.Y == 0 }
+func (t *textureImpl) Size() image.Point          { return t.size }
+func (t *textureImpl) Bounds() image.Rectangle    { return image.Rectangle{Max: t.size} }
+func (t *textureImpl) Format() screen.PixelFormat { return t.format }
+
+// bytes returns the estimated memory used by the texture's pixmap.
+func (t *textureImpl) bytes() int64 { return 4 * int64(t.size.X) * int64(t.size.Y) }
 
 func (t *textureImpl) Release() {
 	t.releasedMu.Lock()
@@ -51,17 +62,67 @@ func (t *textureImpl) Release() {
 	if released || t.degenerate() {
 		return
 	}
+	t.s.textureBytes.Add(-t.bytes())
 	render.FreePicture(t.s.xc, t.xp)
 	xproto.FreePixmap(t.s.xc, t.xm)
 }
 
+// UploadYUV converts src in software, as X11 Render has no YUV picture
+// formats.
+func (t *textureImpl) UploadYUV(dp image.Point, src *screen.YUVImage, sr image.Rectangle) {
+	yuv.Upload(t.s, t, dp, src, sr)
+}
+
 func (t *textureImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
 	if t.degenerate() {
 		return
 	}
+	// Clip to the destination, so that only the pixels that can change are
+	// swizzled and sent.
+	src2dst := dp.Sub(sr.Min)
+	dr := sr.Add(src2dst).Intersect(t.Bounds())
+	if dr.Empty() {
+		return
+	}
+	dp, sr = dr.Min, dr.Sub(src2dst)
+	if t.format != screen.PixelFormatRGBA8 {
+		// The Buffer's pixels must first be converted to t's format.
+		p, _ := screen.PixelsOf(src.RGBA())
+		t.UploadPixels(dp, p, sr)
+		return
+	}
 	src.(*bufferImpl).upload(xproto.Drawable(t.xm), t.s.gcontext32, textureDepth, dp, sr)
 }
 
+// UploadPixels implements screen.PixelUploader.
+//
+// Every texture's pixmap holds little-endian BGRA pixels, whatever the
+// texture's format, so PixelFormatBGRA8 pixels are sent to an RGBA8 texture
+// as is. Other pixels are converted to the texture's format, and then to
+// BGRA, before they are sent.
+func (t *textureImpl) UploadPixels(dp image.Point, src *screen.Pixels, sr image.Rectangle) {
+	if t.degenerate() {
+		return
+	}
+	originalSRMin := sr.Min
+	sr = sr.Intersect(src.Rect)
+	dp = dp.Add(sr.Min.Sub(originalSRMin))
+	src2dst := dp.Sub(sr.Min)
+	dr := sr.Add(src2dst).Intersect(t.Bounds())
+	if dr.Empty() {
+		return
+	}
+	sr = dr.Sub(src2dst)
+
+	if src.Format == screen.PixelFormatBGRA8 && t.format == screen.PixelFormatRGBA8 {
+		putImage(t.s.xc, xproto.Drawable(t.xm), t.s.gcontext32, textureDepth, dr, src.Pix[src.PixOffset(sr.Min.X, sr.Min.Y):], src.Stride)
+		return
+	}
+	rgba := drawer.ConvertPixels(t.format, src, sr)
+	swizzle.BGRA(rgba.Pix)
+	putImage(t.s.xc, xproto.Drawable(t.xm), t.s.gcontext32, textureDepth, dr, rgba.Pix, rgba.Stride)
+}
+
 func (t *textureImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
 	if t.degenerate() {
 		return
@@ -69,6 +130,35 @@ func (t *textureImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
 	fill(t.s.xc, t.xp, dr, src, op)
 }
 
+func (t *textureImpl) Download(dp image.Point, sr image.Rectangle) (*image.RGBA, error) {
+	r := sr.Intersect(t.Bounds())
+	m := image.NewRGBA(r.Add(dp.Sub(sr.Min)))
+
+	t.releasedMu.Lock()
+	defer t.releasedMu.Unlock()
+
+	if t.released {
+		return nil, errTextureReleased
+	}
+	if r.Empty() {
+		return m, nil
+	}
+	reply, err := xproto.GetImage(t.s.xc, xproto.ImageFormatZPixmap, xproto.Drawable(t.xm),
+		int16(r.Min.X), int16(r.Min.Y), uint16(r.Dx()), uint16(r.Dy()), 0xffffffff).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("x11driver: xproto.GetImage failed: %v", err)
+	}
+	if len(reply.Data) != len(m.Pix) {
+		return nil, fmt.Errorf("x11driver: xproto.GetImage returned %d bytes, want %d", len(reply.Data), len(m.Pix))
+	}
+	// The pixmap's pixels are little-endian BGRA, as for findPictformat.
+	copy(m.Pix, reply.Data)
+	swizzle.BGRA(m.Pix)
+	return m, nil
+}
+
+var errTextureReleased = errors.New("x11driver: texture is released")
+
 // f64ToFixed converts from float64 to X11/Render's 16.16 fixed point.
 func f64ToFixed(x float64) render.Fixed {
 	return render.Fixed(x * 65536)
@@ -87,8 +177,10 @@ func inv(x *f64.Aff3) f64.Aff3 {
 }
 
 func (t *textureImpl) draw(xp render.Picture, src2dst *f64.Aff3, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
-	sr = sr.Intersect(t.Bounds())
-	if sr.Empty() {
+	if t.wrap == screen.WrapClamp {
+		sr = sr.Intersect(t.Bounds())
+	}
+	if sr.Empty() || t.degenerate() {
 		return
 	}
 
@@ -123,7 +215,13 @@ func (t *textureImpl) draw(xp render.Picture, src2dst *f64.Aff3, sr image.Rectan
 			0, f64ToFixed(1 / src2dst[4]), 0,
 			0, 0, 1 << 16,
 		})
-		render.Composite(t.s.xc, renderOp(op), t.xp, 0, xp,
+		mask := render.Picture(0)
+		if a := opts.GetAlpha(); a != 0xffff {
+			t.s.alphaMu.Lock()
+			defer t.s.alphaMu.Unlock()
+			mask = t.s.alphaMask(a)
+		}
+		render.Composite(t.s.xc, pictOps[opts.GetBlend(op)], t.xp, mask, xp,
 			int16(sr.Min.X), int16(sr.Min.Y), // SrcX, SrcY,
 			0, 0, // MaskX, MaskY,
 			int16(dXMin), int16(dYMin), // DstX, DstY,
@@ -141,8 +239,16 @@ func (t *textureImpl) draw(xp render.Picture, src2dst *f64.Aff3, sr image.Rectan
 		0, 0, 1 << 16,
 	})
 
+	// TODO: support transparency here, where render.TriFan has no mask.
 	points := trifanPoints(src2dst, sr)
-	if op == draw.Src {
+	trifan(t.s, opts.GetBlend(op), t.xp, xp, points[:])
+}
+
+// trifan composites src onto dst, within the quad given by points, with
+// the blend mode.
+func trifan(s *screenImpl, mode screen.BlendMode, src, dst render.Picture, points []render.Pointfix) {
+	switch mode {
+	case screen.BlendSrc:
 		// render.TriFan visits every dst-space pixel in the axis-aligned
 		// bounding box (AABB) containing the transformation of the sr
 		// rectangle in src-space to a quad in dst-space.
@@ -167,9 +273,17 @@ func (t *textureImpl) draw(xp render.Picture, src2dst *f64.Aff3, sr image.Rectan
 		// What X11/Render calls PictOpOutReverse is also known as dst-out. See
 		// http://www.w3.org/TR/SVGCompositing/examples/compop-porterduff-examples.png
 		// for a visualization.
-		render.TriFan(t.s.xc, render.PictOpOutReverse, t.s.opaqueP, xp, 0, 0, 0, points[:])
+		render.TriFan(s.xc, render.PictOpOutReverse, s.opaqueP, dst, 0, 0, 0, points)
+		render.TriFan(s.xc, render.PictOpOver, src, dst, 0, 0, 0, points)
+	case screen.BlendClear:
+		// Similarly, BlendClear is just the first of those calls.
+		render.TriFan(s.xc, render.PictOpOutReverse, s.opaqueP, dst, 0, 0, 0, points)
+	default:
+		// TODO: the other modes that change dst where src is transparent,
+		// such as BlendSrcIn, also change the pixels outside the quad but
+		// inside its AABB.
+		render.TriFan(s.xc, pictOps[mode], src, dst, 0, 0, 0, points)
 	}
-	render.TriFan(t.s.xc, render.PictOpOver, t.xp, xp, 0, 0, 0, points[:])
 }
 
 func trifanPoints(src2dst *f64.Aff3, sr image.Rectangle) [4]render.Pointfix {
@@ -192,6 +306,25 @@ func trifanPoints(src2dst *f64.Aff3, sr image.Rectangle) [4]render.Pointfix {
 	}}
 }
 
+// pictOps are the X11/Render operators of each screen.BlendMode.
+var pictOps = [screen.NumBlendModes]byte{
+	screen.BlendClear:    render.PictOpClear,
+	screen.BlendSrc:      render.PictOpSrc,
+	screen.BlendDst:      render.PictOpDst,
+	screen.BlendSrcOver:  render.PictOpOver,
+	screen.BlendDstOver:  render.PictOpOverReverse,
+	screen.BlendSrcIn:    render.PictOpIn,
+	screen.BlendDstIn:    render.PictOpInReverse,
+	screen.BlendSrcOut:   render.PictOpOut,
+	screen.BlendDstOut:   render.PictOpOutReverse,
+	screen.BlendSrcAtop:  render.PictOpAtop,
+	screen.BlendDstAtop:  render.PictOpAtopReverse,
+	screen.BlendXor:      render.PictOpXor,
+	screen.BlendAdd:      render.PictOpAdd,
+	screen.BlendMultiply: render.PictOpMultiply,
+	screen.BlendScreen:   render.PictOpScreen,
+}
+
 func renderOp(op draw.Op) byte {
 	if op == draw.Src {
 		return render.PictOpSrc
@@ -0,0 +1,322 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/render"
+	"github.com/BurntSushi/xgb/xproto"
+
+	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/icon"
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/mouse"
+)
+
+// Tray icons are small windows, embedded in the system tray by the XEmbed
+// system tray protocol, specified at
+// https://specifications.freedesktop.org/systemtray-spec/
+//
+// The system tray is the owner of the _NET_SYSTEM_TRAY_Sn selection, which is
+// asked to embed a window by a client message. The window's background is
+// that of the tray, or transparent if the tray says that its icons should
+// have a 32-bit visual, and the icon is composited over it.
+//
+// TODO: listen for MANAGER client messages on the root window, so that the
+// icons are embedded again in a system tray that restarts.
+
+const (
+	systemTrayRequestDock = 0
+	xembedMapped          = 1 << 0
+
+	// trayIconSize is the size, in pixels, of a tray icon's window until
+	// the system tray resizes it.
+	trayIconSize = 22
+)
+
+var errTrayReleased = errors.New("x11driver: tray icon is released")
+
+type trayImpl struct {
+	event.Deque
+
+	s  *screenImpl
+	xw xproto.Window
+	xp render.Picture
+
+	mu       sync.Mutex
+	released bool
+	icon     *image.RGBA
+	size     image.Point
+
+	// iconM and iconP, if non-zero, are icon scaled to fit iconSize pixels.
+	iconM    xproto.Pixmap
+	iconP    render.Picture
+	iconSize int
+}
+
+func (s *screenImpl) initTray() (err error) {
+	s.atomNETSystemTray, err = s.internAtom(fmt.Sprintf("_NET_SYSTEM_TRAY_S%d", s.xc.DefaultScreen))
+	if err != nil {
+		return err
+	}
+	s.atomNETSystemTrayOpcode, err = s.internAtom("_NET_SYSTEM_TRAY_OPCODE")
+	if err != nil {
+		return err
+	}
+	s.atomNETSystemTrayVisual, err = s.internAtom("_NET_SYSTEM_TRAY_VISUAL")
+	if err != nil {
+		return err
+	}
+	s.atomXEmbedInfo, err = s.internAtom("_XEMBED_INFO")
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *screenImpl) NewTrayIcon(opts *screen.NewTrayIconOptions) (screen.TrayIcon, error) {
+	if opts == nil {
+		opts = &screen.NewTrayIconOptions{}
+	}
+	if opts.Menu != nil {
+		return nil, errors.New("x11driver: tray icon menus are not supported")
+	}
+	r, err := xproto.GetSelectionOwner(s.xc, s.atomNETSystemTray).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("x11driver: xproto.GetSelectionOwner failed: %v", err)
+	}
+	if r.Owner == xproto.WindowNone {
+		return nil, errors.New("x11driver: there is no system tray")
+	}
+	owner := r.Owner
+
+	xw, err := xproto.NewWindowId(s.xc)
+	if err != nil {
+		return nil, fmt.Errorf("x11driver: xproto.NewWindowId failed: %v", err)
+	}
+	xp, err := render.NewPictureId(s.xc)
+	if err != nil {
+		return nil, fmt.Errorf("x11driver: render.NewPictureId failed: %v", err)
+	}
+	depth, visual, pictformat := s.xsi.RootDepth, s.xsi.RootVisual, s.pictformat24
+	if s.trayVisual(owner) == s.visual32 {
+		depth, visual, pictformat = 32, s.visual32, s.pictformat32
+	}
+	if depth != 24 && depth != 32 {
+		return nil, fmt.Errorf("x11driver: unsupported root depth %d", depth)
+	}
+
+	t := &trayImpl{
+		s:    s,
+		xw:   xw,
+		xp:   xp,
+		size: image.Point{trayIconSize, trayIconSize},
+	}
+	t.setIcon(opts.Icon)
+
+	s.mu.Lock()
+	s.trays[xw] = t
+	s.mu.Unlock()
+
+	// The values are in the order of their bits in the mask.
+	eventMask := uint32(xproto.EventMaskButtonPress |
+		xproto.EventMaskButtonRelease |
+		xproto.EventMaskExposure |
+		xproto.EventMaskStructureNotify)
+	if visual == s.xsi.RootVisual {
+		xproto.CreateWindow(s.xc, depth, xw, s.xsi.Root,
+			0, 0, trayIconSize, trayIconSize, 0,
+			xproto.WindowClassInputOutput, visual,
+			xproto.CwBackPixmap|xproto.CwEventMask,
+			[]uint32{xproto.BackPixmapParentRelative, eventMask})
+	} else {
+		xproto.CreateWindow(s.xc, depth, xw, s.xsi.Root,
+			0, 0, trayIconSize, trayIconSize, 0,
+			xproto.WindowClassInputOutput, visual,
+			xproto.CwBackPixel|xproto.CwBorderPixel|xproto.CwEventMask|xproto.CwColormap,
+			[]uint32{0, 0, eventMask, uint32(s.colormap32)})
+	}
+	render.CreatePicture(s.xc, xp, xproto.Drawable(xw), pictformat, 0, nil)
+	xproto.ChangeProperty(s.xc, xproto.PropModeReplace, xw, s.atomXEmbedInfo, s.atomXEmbedInfo, 32, 2,
+		[]byte{0, 0, 0, 0, xembedMapped, 0, 0, 0})
+	t.setTooltip(opts.Tooltip)
+	s.sendClientMessage(owner, s.atomNETSystemTrayOpcode, xproto.TimeCurrentTime, systemTrayRequestDock, uint32(xw))
+	return t, nil
+}
+
+// trayVisual returns the visual that the system tray, owner, says that its
+// icons should have, or zero if it does not say.
+func (s *screenImpl) trayVisual(owner xproto.Window) xproto.Visualid {
+	r, err := xproto.GetProperty(s.xc, false, owner, s.atomNETSystemTrayVisual,
+		xproto.AtomVisualid, 0, 1).Reply()
+	if err != nil || r.Format != 32 || len(r.Value) < 4 {
+		return 0
+	}
+	return xproto.Visualid(xgb.Get32(r.Value))
+}
+
+func (s *screenImpl) findTray(key xproto.Window) *trayImpl {
+	s.mu.Lock()
+	t := s.trays[key]
+	s.mu.Unlock()
+	return t
+}
+
+func (t *trayImpl) Release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.released {
+		return
+	}
+	t.released = true
+	t.freeIcon()
+	render.FreePicture(t.s.xc, t.xp)
+	xproto.DestroyWindow(t.s.xc, t.xw)
+
+	t.s.mu.Lock()
+	delete(t.s.trays, t.xw)
+	t.s.mu.Unlock()
+}
+
+func (t *trayImpl) SetIcon(m image.Image) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.released {
+		return
+	}
+	t.setIcon(m)
+	t.paint()
+}
+
+// SetTooltip sets the window's _NET_WM_NAME property, which system trays show
+// as the icon's tooltip.
+func (t *trayImpl) SetTooltip(tooltip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.released {
+		t.setTooltip(tooltip)
+	}
+}
+
+// SetMenu returns an error, as system trays do not show the menus of XEmbed
+// icons.
+//
+// TODO: implement the StatusNotifierItem and com.canonical.dbusmenu D-Bus
+// interfaces, which do have menus.
+func (t *trayImpl) SetMenu(m *screen.Menu) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.released {
+		return errTrayReleased
+	}
+	return errors.New("x11driver: tray icon menus are not supported")
+}
+
+func (t *trayImpl) setIcon(m image.Image) {
+	t.freeIcon()
+	t.icon = nil
+	if m != nil && !m.Bounds().Empty() {
+		// The icon is scaled when it is painted, to fit the window.
+		b := m.Bounds()
+		size := b.Dx()
+		if b.Dy() > size {
+			size = b.Dy()
+		}
+		t.icon = icon.Scale(m, size)
+	}
+}
+
+func (t *trayImpl) setTooltip(tooltip string) {
+	b := []byte(tooltip)
+	xproto.ChangeProperty(t.s.xc, xproto.PropModeReplace, t.xw, t.s.atomNETWMName, t.s.atomUTF8String, 8, uint32(len(b)), b)
+}
+
+func (t *trayImpl) freeIcon() {
+	if t.iconP != 0 {
+		render.FreePicture(t.s.xc, t.iconP)
+		xproto.FreePixmap(t.s.xc, t.iconM)
+		t.iconM, t.iconP, t.iconSize = 0, 0, 0
+	}
+}
+
+// paint clears the window to its background and composites the icon, centered,
+// over it. It must only be called while holding t.mu.
+func (t *trayImpl) paint() {
+	xproto.ClearArea(t.s.xc, false, t.xw, 0, 0, 0, 0)
+	if t.icon == nil {
+		return
+	}
+	size := t.size.X
+	if t.size.Y < size {
+		size = t.size.Y
+	}
+	if size <= 0 {
+		return
+	}
+	if t.iconSize != size {
+		t.freeIcon()
+		if err := t.uploadIcon(size); err != nil {
+			return
+		}
+	}
+	render.Composite(t.s.xc, render.PictOpOver, t.iconP, 0, t.xp, 0, 0, 0, 0,
+		int16((t.size.X-size)/2), int16((t.size.Y-size)/2), uint16(size), uint16(size))
+}
+
+// uploadIcon creates iconM and iconP, holding the icon scaled to fit size by
+// size pixels.
+func (t *trayImpl) uploadIcon(size int) error {
+	s := t.s
+	xm, err := xproto.NewPixmapId(s.xc)
+	if err != nil {
+		return err
+	}
+	xp, err := render.NewPictureId(s.xc)
+	if err != nil {
+		return err
+	}
+	// image.RGBA is already alpha-premultiplied, as RENDER requires, so only
+	// the byte order differs.
+	m := icon.Scale(t.icon, size)
+	data := make([]byte, len(m.Pix))
+	copy(data, m.Pix)
+	swizzle.BGRA(data)
+
+	xproto.CreatePixmap(s.xc, 32, xm, xproto.Drawable(s.window32), uint16(size), uint16(size))
+	xproto.PutImage(s.xc, xproto.ImageFormatZPixmap, xproto.Drawable(xm), s.gcontext32,
+		uint16(size), uint16(size), 0, 0, 0, 32, data)
+	render.CreatePicture(s.xc, xp, xproto.Drawable(xm), s.pictformat32, 0, nil)
+	t.iconM, t.iconP, t.iconSize = xm, xp, size
+	return nil
+}
+
+func (t *trayImpl) handleConfigureNotify(ev xproto.ConfigureNotifyEvent) {
+	t.mu.Lock()
+	t.size = image.Point{int(ev.Width), int(ev.Height)}
+	t.mu.Unlock()
+}
+
+func (t *trayImpl) handleExpose() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.released {
+		t.paint()
+	}
+}
+
+// handleButtonRelease sends a TrayEvent for a click of the left, middle or
+// right button, whose X11 buttons are 1, 2 and 3.
+func (t *trayImpl) handleButtonRelease(b xproto.Button) {
+	switch mouse.Button(b) {
+	case mouse.ButtonLeft, mouse.ButtonMiddle, mouse.ButtonRight:
+		t.Send(screen.TrayEvent{Button: mouse.Button(b)})
+	}
+}
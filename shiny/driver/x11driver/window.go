@@ -7,18 +7,27 @@ package x11driver
 // TODO: implement a back buffer.
 
 import (
+	"errors"
 	"image"
 	"image/color"
 	"image/draw"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/BurntSushi/xgb"
 	"github.com/BurntSushi/xgb/render"
 	"github.com/BurntSushi/xgb/xproto"
 
+	"golang.org/x/exp/shiny/driver/internal/capture"
 	"golang.org/x/exp/shiny/driver/internal/drawer"
 	"golang.org/x/exp/shiny/driver/internal/event"
+	"golang.org/x/exp/shiny/driver/internal/filedialog"
+	"golang.org/x/exp/shiny/driver/internal/frame"
+	"golang.org/x/exp/shiny/driver/internal/icon"
 	"golang.org/x/exp/shiny/driver/internal/lifecycler"
+	"golang.org/x/exp/shiny/driver/internal/notify"
+	"golang.org/x/exp/shiny/driver/internal/swizzle"
 	"golang.org/x/exp/shiny/driver/internal/x11key"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/image/math/f64"
@@ -35,24 +44,76 @@ type windowImpl struct {
 	xw xproto.Window
 	xg xproto.Gcontext
 	xp render.Picture
+	// depth is the window's depth: 32 for a Transparent window, and the root
+	// window's depth otherwise.
+	depth uint8
 
 	event.Deque
 	xevents chan xgb.Event
 
+	// fixedSize is whether the window manager was asked to prevent the user
+	// from resizing the window.
+	fixedSize bool
+	// interceptClose is whether a WM_DELETE_WINDOW message sends a
+	// CloseRequestEvent, instead of killing the window.
+	interceptClose bool
+	// modalOwner is the owner whose input the window, created with
+	// NewWindowOptions.Modal, blocks, or nil. modalChildren is how many such
+	// windows block the window's input, and is accessed atomically.
+	modalOwner    *windowImpl
+	modalChildren int32
+
 	// This next group of variables are mutable, but are only modified in the
 	// screenImpl.run goroutine.
 	width, height int
+	// pixelsPerPt and scale are those of the display that the window is on.
+	pixelsPerPt float32
+	scale       float64
+	// state is the window's state, as the window manager last reported it.
+	state screen.WindowState
+	// xvisibility is the State of the window's last VisibilityNotify event.
+	xvisibility byte
 
 	lifecycler lifecycler.State
 
-	mu       sync.Mutex
+	imagePool drawer.ImagePool
+	layers    drawer.Layers
+	captures  capture.Hooks
+	timing    frame.Timing
+	occlusion frame.Occlusion
+
+	// mu protects released and the cursor and capture fields. It is held for
+	// reading by methods that draw to the window, so that a concurrent Release
+	// waits until they are done before destroying the window, and so that
+	// they do nothing afterwards.
+	mu       sync.RWMutex
 	released bool
+	// cursor is the cursor set by SetCursor, shown unless cursorHidden.
+	// ownedCursor, if non-zero, is the X11 cursor created for the window's
+	// custom cursor image.
+	cursor       screen.Cursor
+	cursorHidden bool
+	ownedCursor  xproto.Cursor
+	// captured is whether the window has grabbed the pointer, for
+	// SetPointerCapture. captureCenter is where, in window co-ordinates, the
+	// pointer is held.
+	captured      bool
+	captureCenter image.Point
+	// requestedSize is the size last asked for by NewWindow or SetSize, and
+	// constraints are those set by SetSizeConstraints and SetAspectRatio.
+	// The WM_NORMAL_HINTS property is set from both, and they are only
+	// modified while holding mu for writing.
+	requestedSize image.Point
+	constraints   sizeConstraints
 }
 
 func (w *windowImpl) Release() {
 	w.mu.Lock()
 	released := w.released
 	w.released = true
+	if !released {
+		w.freeCursor()
+	}
 	w.mu.Unlock()
 
 	// TODO: call w.lifecycler.SetDead and w.lifecycler.SendEvent, a la
@@ -61,24 +122,59 @@ func (w *windowImpl) Release() {
 	if released {
 		return
 	}
+	w.releaseHotKeys()
+	notify.Release(w)
+	w.imagePool.Release()
+	w.layers.Release()
+	w.StopTimers()
 	render.FreePicture(w.s.xc, w.xp)
 	xproto.FreeGC(w.s.xc, w.xg)
 	xproto.DestroyWindow(w.s.xc, w.xw)
+	if w.modalOwner != nil {
+		atomic.AddInt32(&w.modalOwner.modalChildren, -1)
+	}
+}
+
+// inputBlocked returns whether the window is not sent key and mouse events,
+// because a modal window that it owns is open. The window manager may still
+// let the user focus the window, and it is still painted.
+func (w *windowImpl) inputBlocked() bool {
+	return atomic.LoadInt32(&w.modalChildren) > 0
 }
 
 func (w *windowImpl) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
-	src.(*bufferImpl).upload(xproto.Drawable(w.xw), w.xg, w.s.xsi.RootDepth, dp, sr)
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	src.(*bufferImpl).upload(xproto.Drawable(w.xw), w.xg, w.depth, dp, sr)
 }
 
 func (w *windowImpl) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
 	fill(w.s.xc, w.xp, dr, src, op)
 }
 
 func (w *windowImpl) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
 	w.s.drawUniform(w.xp, &src2dst, src, sr, op, opts)
 }
 
 func (w *windowImpl) Draw(src2dst f64.Aff3, src screen.Texture, sr image.Rectangle, op draw.Op, opts *screen.DrawOptions) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
 	src.(*textureImpl).draw(w.xp, &src2dst, sr, op, opts)
 }
 
@@ -90,10 +186,27 @@ func (w *windowImpl) Scale(dr image.Rectangle, src screen.Texture, sr image.Rect
 	drawer.Scale(w, dr, src, sr, op, opts)
 }
 
+func (w *windowImpl) DrawImage(dp image.Point, src image.Image, op draw.Op, opts *screen.DrawOptions) {
+	w.imagePool.DrawImage(w.s, w, dp, src, op, opts)
+}
+
+func (w *windowImpl) NewLayer(z int, size image.Point) (screen.Layer, error) {
+	return w.layers.NewLayer(w.s, z, size)
+}
+
 func (w *windowImpl) Publish() screen.PublishResult {
 	// TODO: implement a back buffer, and copy or flip that here to the front
 	// buffer.
 
+	composited := w.layers.Composite(w)
+
+	w.mu.RLock()
+	released := w.released
+	w.mu.RUnlock()
+	if released {
+		return screen.PublishResult{Dropped: true}
+	}
+
 	// This sync isn't needed to flush the outgoing X11 requests. Instead, it
 	// acts as a form of flow control. Outgoing requests can be quite small on
 	// the wire, e.g. draw this texture ID (an integer) to this rectangle (four
@@ -102,8 +215,290 @@ func (w *windowImpl) Publish() screen.PublishResult {
 	// client could easily end up sending work at a faster rate than the X11
 	// server can serve.
 	w.s.xc.Sync()
+	if !w.occlusion.Wait() {
+		return screen.PublishResult{BackBufferPreserved: !composited, Dropped: true}
+	}
+
+	// There is no back buffer (see the TODO above), so drawing happens on the
+	// front buffer directly, and its contents are preserved. Any contents lost
+	// by the X11 server, such as when the window is obscured, will result in
+	// an external paint event. Compositing any layers overwrites the contents,
+	// though.
+	res := screen.PublishResult{BackBufferPreserved: !composited}
+	// The drawing has been done once the sync returns, but a compositing
+	// manager, if any, may show it later.
+	w.timing.Presented(&res, time.Now())
+
+	if w.captures.Active() {
+		w.captures.Send(w.readFrame())
+	}
+	return res
+}
+
+func (w *windowImpl) AddCaptureHook(fn func(f screen.CapturedFrame)) (remove func()) {
+	return w.captures.Add(fn)
+}
+
+// readFrame reads the window's contents back from the X11 server, for its
+// capture hooks. It returns nil if they cannot be read, such as while the
+// window is minimized, when the server does not keep them.
+func (w *windowImpl) readFrame() *image.RGBA {
+	xc := w.s.xc
+	g, err := xproto.GetGeometry(xc, xproto.Drawable(w.xw)).Reply()
+	if err != nil || g.Width == 0 || g.Height == 0 {
+		return nil
+	}
+	reply, err := xproto.GetImage(xc, xproto.ImageFormatZPixmap, xproto.Drawable(w.xw),
+		0, 0, g.Width, g.Height, 0xffffffff).Reply()
+	if err != nil {
+		return nil
+	}
+	m := image.NewRGBA(image.Rect(0, 0, int(g.Width), int(g.Height)))
+	if len(reply.Data) != len(m.Pix) {
+		// The window's visual does not have 32 bits per pixel.
+		return nil
+	}
+	// The window's pixels are little-endian BGRA, as for findPictformat.
+	// Unless the window is Transparent, the alpha byte is padding.
+	copy(m.Pix, reply.Data)
+	swizzle.BGRA(m.Pix)
+	if w.depth != 32 {
+		for i := 3; i < len(m.Pix); i += 4 {
+			m.Pix[i] = 0xff
+		}
+	}
+	return m
+}
+
+func (w *windowImpl) RenderFrame(fn func(d screen.Drawer)) error {
+	w.mu.RLock()
+	released := w.released
+	w.mu.RUnlock()
+	if released {
+		return errReleased
+	}
+
+	fn(w)
+
+	// As with Publish, there is no back buffer, so the drawing happens on the
+	// front buffer. Unlike Publish, any Layers are not composited.
+	w.mu.RLock()
+	released = w.released
+	w.mu.RUnlock()
+	if released {
+		return errReleased
+	}
+	w.s.xc.Sync()
+	return nil
+}
+
+// NextFrame sends a screen.FrameEvent at the next tick of a clock running at
+// the primary display's refresh rate.
+//
+// TODO: use the Present extension's PresentCompleteNotify events, to wait for
+// the window's display's actual vertical blank, once the xgb package supports
+// that extension and the X Generic Events that it sends.
+func (w *windowImpl) NextFrame() {
+	w.mu.RLock()
+	released := w.released
+	w.mu.RUnlock()
+	if released {
+		return
+	}
+	if s := w.s; s.frames.Request(w) {
+		go s.frames.Tick(s.frameInterval())
+	}
+}
+
+var errReleased = errors.New("x11driver: window is released")
+
+func (w *windowImpl) Begin() *screen.Context { return screen.NewContext(w) }
+
+func (w *windowImpl) GLInfo() screen.GLInfo { return screen.GLInfo{} }
+
+func (w *windowImpl) SetTitle(title string) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	b := []byte(title)
+	xproto.ChangeProperty(w.s.xc, xproto.PropModeReplace, w.xw, w.s.atomNETWMName, w.s.atomUTF8String, 8, uint32(len(b)), b)
+}
+
+// SetIcon sets the window's _NET_WM_ICON property, which holds the icon at
+// several sizes, for the window manager to choose from.
+func (w *windowImpl) SetIcon(m image.Image) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	if m == nil {
+		xproto.DeleteProperty(w.s.xc, w.xw, w.s.atomNETWMIcon)
+		return
+	}
+	data := icon.NetWMIcon(m)
+	b := make([]byte, 4*len(data))
+	for i, v := range data {
+		xgb.Put32(b[4*i:], v)
+	}
+	xproto.ChangeProperty(w.s.xc, xproto.PropModeReplace, w.xw, w.s.atomNETWMIcon,
+		xproto.AtomCardinal, 32, uint32(len(data)), b)
+}
+
+// SetBadge and SetProgress do nothing, as X11 window managers have no badges
+// or progress of their own.
+//
+// TODO: implement the com.canonical.Unity.LauncherEntry D-Bus interface,
+// which some docks and taskbars support.
+func (w *windowImpl) SetBadge(label string) {}
+
+func (w *windowImpl) SetProgress(progress float64) {}
+
+func (w *windowImpl) SetSize(width, height int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released || width <= 0 || height <= 0 {
+		return
+	}
+	// A fixed size window's minimum and maximum size hints must change too,
+	// or the window manager may refuse the new size.
+	w.requestedSize = image.Point{width, height}
+	w.setSizeHints(nil)
+	xproto.ConfigureWindow(w.s.xc, w.xw, xproto.ConfigWindowWidth|xproto.ConfigWindowHeight,
+		[]uint32{uint32(width), uint32(height)})
+}
+
+func (w *windowImpl) SetSizeConstraints(min, max image.Point) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return
+	}
+	w.constraints.min, w.constraints.max = min, max
+	w.setSizeHints(nil)
+}
+
+func (w *windowImpl) SetAspectRatio(aspect image.Point) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return
+	}
+	w.constraints.aspect = aspect
+	w.setSizeHints(nil)
+}
+
+// setSizeHints sets the window's WM_NORMAL_HINTS property from its requested
+// size and constraints, and from opts, which is nil unless the window is
+// being created. It must only be called while holding w.mu for writing.
+func (w *windowImpl) setSizeHints(opts *screen.NewWindowOptions) {
+	w.s.setSizeHints(w.xw, w.requestedSize, w.fixedSize, w.constraints, opts)
+}
+
+func (w *windowImpl) SetPosition(p image.Point) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return
+	}
+	// With the default NorthWest window gravity, the window manager places
+	// the top-left corner of the window's frame at the requested position.
+	xproto.ConfigureWindow(w.s.xc, w.xw, xproto.ConfigWindowX|xproto.ConfigWindowY,
+		[]uint32{uint32(int32(p.X)), uint32(int32(p.Y))})
+}
+
+func (w *windowImpl) GetGeometry() (image.Point, image.Point) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.released {
+		return image.Point{}, image.Point{}
+	}
+	xc := w.s.xc
+	g, err := xproto.GetGeometry(xc, xproto.Drawable(w.xw)).Reply()
+	if err != nil {
+		return image.Point{}, image.Point{}
+	}
+	// The window's own position is relative to its parent, which is typically
+	// the window manager's frame, so translate its origin to root window
+	// co-ordinates.
+	t, err := xproto.TranslateCoordinates(xc, w.xw, w.s.xsi.Root, 0, 0).Reply()
+	if err != nil {
+		return image.Point{}, image.Point{}
+	}
+	pos := image.Point{int(t.DstX), int(t.DstY)}
+	// The _NET_FRAME_EXTENTS property, if the window manager sets it, holds
+	// the widths of the frame's left, right, top and bottom borders.
+	r, err := xproto.GetProperty(xc, false, w.xw, w.s.atomNETFrameExtents, xproto.AtomCardinal, 0, 4).Reply()
+	if err == nil && r.Format == 32 && len(r.Value) == 16 {
+		pos.X -= int(xgb.Get32(r.Value[0:]))
+		pos.Y -= int(xgb.Get32(r.Value[8:]))
+	}
+	return pos, image.Point{int(g.Width), int(g.Height)}
+}
+
+// TODO: implement the client side of the XIM protocol, which, unlike Xlib,
+// the xgb package does not provide, and send screen.TextEvents.
+func (w *windowImpl) SetTextInputRect(r image.Rectangle) {}
+
+// ShowFileDialog runs zenity, as X11 has no file dialogs of its own.
+func (w *windowImpl) ShowFileDialog(opts *screen.FileDialogOptions) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.released {
+		return errReleased
+	}
+	return filedialog.Start(w, opts, uint32(w.xw))
+}
+
+// Notify runs notify-send, as X11 has no notifications of its own.
+func (w *windowImpl) Notify(n *screen.Notification) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.released {
+		return errReleased
+	}
+	return notify.Post(w, n)
+}
+
+func (w *windowImpl) CloseNotification(id int) {
+	notify.Close(w, id)
+}
+
+// SetMenuBar and ShowContextMenu return an error, as X11 has no menus of its
+// own. Applications draw their own menus, as toolkits such as GTK do.
+func (w *windowImpl) SetMenuBar(bar *screen.Menu) error {
+	return w.errNoMenus()
+}
+
+func (w *windowImpl) ShowContextMenu(m *screen.Menu, p image.Point) error {
+	return w.errNoMenus()
+}
+
+func (w *windowImpl) errNoMenus() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.released {
+		return errReleased
+	}
+	return errors.New("x11driver: menus are not supported")
+}
+
+// SetAccessTree returns an error, as X11 has no accessibility API of its own.
+//
+// TODO: implement AT-SPI, over D-Bus.
+func (w *windowImpl) SetAccessTree(root *screen.AccessNode) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 
-	return screen.PublishResult{}
+	if w.released {
+		return errReleased
+	}
+	return errors.New("x11driver: accessibility is not implemented")
 }
 
 func (w *windowImpl) handleConfigureNotify(ev xproto.ConfigureNotifyEvent) {
@@ -113,34 +508,92 @@ func (w *windowImpl) handleConfigureNotify(ev xproto.ConfigureNotifyEvent) {
 	w.lifecycler.SendEvent(w, nil)
 
 	newWidth, newHeight := int(ev.Width), int(ev.Height)
-	if w.width == newWidth && w.height == newHeight {
-		return
-	}
+	resized := w.width != newWidth || w.height != newHeight
 	w.width, w.height = newWidth, newHeight
+	// The window may have moved to a display with a different resolution.
+	if rescaled := w.updateScale(); resized || rescaled {
+		w.sendSize()
+	}
+}
+
+// updateScale sets the window's scale to that of the display that it is on,
+// sending a screen.ScaleEvent if it changed, and returns whether it changed.
+// Like handleConfigureNotify, it must only be called from the screenImpl.run
+// goroutine.
+func (w *windowImpl) updateScale() bool {
+	var center image.Point
+	if !w.s.uniformDPI() {
+		// Finding the window's position needs a round trip, so is only
+		// done when it matters.
+		t, err := xproto.TranslateCoordinates(w.s.xc, w.xw, w.s.xsi.Root,
+			int16(w.width/2), int16(w.height/2)).Reply()
+		if err == nil {
+			center = image.Point{int(t.DstX), int(t.DstY)}
+		}
+	}
+	ppp, scale := w.s.windowScale(center)
+	if ppp == w.pixelsPerPt && scale == w.scale {
+		return false
+	}
+	initial := w.pixelsPerPt == 0
+	w.pixelsPerPt, w.scale = ppp, scale
+	if !initial {
+		w.Send(screen.ScaleEvent{PixelsPerPt: ppp, Scale: scale})
+	}
+	return !initial
+}
+
+// sendSize sends a size.Event for the window's current size and scale. Like
+// handleConfigureNotify, it must only be called from the screenImpl.run
+// goroutine.
+func (w *windowImpl) sendSize() {
+	if w.pixelsPerPt == 0 {
+		w.updateScale()
+	}
+	ppp := w.pixelsPerPt
 	w.Send(size.Event{
-		WidthPx:     newWidth,
-		HeightPx:    newHeight,
-		WidthPt:     geom.Pt(newWidth),
-		HeightPt:    geom.Pt(newHeight),
-		PixelsPerPt: w.s.pixelsPerPt,
+		WidthPx:     w.width,
+		HeightPx:    w.height,
+		WidthPt:     geom.Pt(float32(w.width) / ppp),
+		HeightPt:    geom.Pt(float32(w.height) / ppp),
+		PixelsPerPt: ppp,
 	})
 }
 
 func (w *windowImpl) handleExpose() {
-	w.Send(paint.Event{})
+	w.Send(paint.Event{External: true})
 }
 
-func (w *windowImpl) handleKey(detail xproto.Keycode, state uint16, dir key.Direction) {
+// handleKey sends a key event, whose Direction is key.DirNone for an
+// automatic repeat.
+func (w *windowImpl) handleKey(detail xproto.Keycode, state uint16, dir key.Direction, t xproto.Timestamp) {
+	if g := x11key.Group(state); g != w.s.group {
+		w.s.updateLayout(g)
+	}
+	if w.inputBlocked() {
+		return
+	}
 	r, c := w.s.keysyms.Lookup(uint8(detail), state)
-	w.Send(key.Event{
-		Rune:      r,
-		Code:      c,
-		Modifiers: x11key.KeyModifiers(state),
-		Direction: dir,
-	})
+	e := screen.KeyEvent{
+		Event: key.Event{
+			Rune:      r,
+			Code:      c,
+			Modifiers: x11key.KeyModifiers(state),
+			Direction: dir,
+		},
+		Physical:   w.s.physicalCode(detail),
+		Scancode:   uint32(detail),
+		Unmodified: w.s.keysyms.Unmodified(uint8(detail), state),
+		Repeat:     dir == key.DirNone,
+	}
+	at := w.s.clock.Millis(uint32(t))
+	w.SendAt(e, at)
 }
 
-func (w *windowImpl) handleMouse(x, y int16, b xproto.Button, state uint16, dir mouse.Direction) {
+func (w *windowImpl) handleMouse(x, y int16, b xproto.Button, state uint16, dir mouse.Direction, t xproto.Timestamp) {
+	if w.inputBlocked() {
+		return
+	}
 	// TODO: should a mouse.Event have a separate MouseModifiers field, for
 	// which buttons are pressed during a mouse move?
 	btn := mouse.Button(b)
@@ -154,17 +607,79 @@ func (w *windowImpl) handleMouse(x, y int16, b xproto.Button, state uint16, dir
 	case 7:
 		btn = mouse.ButtonWheelRight
 	}
+	// Buttons 8 and 9, typically the back and forward side buttons, are
+	// screen.MouseButtonBack and screen.MouseButtonForward. They, and any
+	// higher numbered buttons, are reported unchanged.
+	at := w.s.clock.Millis(uint32(t))
+	if dir == mouse.DirNone && w.handleCapturedMotion(x, y, at) {
+		return
+	}
 	if btn.IsWheel() {
 		if dir != mouse.DirPress {
 			return
 		}
 		dir = mouse.DirStep
+		// TODO: send smooth scrolling, from XInput2's scroll valuators,
+		// once xgb supports the XInputExtension.
+		w.SendAt(wheelScrollEvent(float32(x), float32(y), btn, x11key.KeyModifiers(state)), at)
 	}
-	w.Send(mouse.Event{
+	w.SendAt(mouse.Event{
 		X:         float32(x),
 		Y:         float32(y),
 		Button:    btn,
 		Modifiers: x11key.KeyModifiers(state),
 		Direction: dir,
-	})
+	}, at)
+}
+
+func (w *windowImpl) handleCrossing(ev xproto.EnterNotifyEvent, entered bool) {
+	// Crossings due to grabs, and into or out of child windows, do not move
+	// the pointer into or out of the window.
+	if ev.Mode != xproto.NotifyModeNormal || ev.Detail == xproto.NotifyDetailInferior {
+		return
+	}
+	w.SendAt(screen.CrossingEvent{
+		Entered: entered,
+		X:       float32(ev.EventX),
+		Y:       float32(ev.EventY),
+	}, w.s.clock.Millis(uint32(ev.Time)))
+}
+
+// wheelScrollEvent returns the screen.ScrollEvent of a step of a wheel
+// button.
+func wheelScrollEvent(x, y float32, b mouse.Button, m key.Modifiers) screen.ScrollEvent {
+	e := screen.ScrollEvent{
+		X:         x,
+		Y:         y,
+		Device:    screen.ScrollDeviceWheel,
+		Modifiers: m,
+	}
+	switch b {
+	case mouse.ButtonWheelUp:
+		e.DeltaY = -1
+	case mouse.ButtonWheelDown:
+		e.DeltaY = +1
+	case mouse.ButtonWheelLeft:
+		e.DeltaX = -1
+	case mouse.ButtonWheelRight:
+		e.DeltaX = +1
+	}
+	return e
+}
+
+func (w *windowImpl) ColorSpace() screen.ColorSpace { return screen.ColorSpaceSRGB }
+
+// SetColorSpace only accepts screen.ColorSpaceSRGB, as X11 has no way to tag a
+// window with another color space.
+func (w *windowImpl) SetColorSpace(cs screen.ColorSpace) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.released {
+		return errReleased
+	}
+	if cs != screen.ColorSpaceSRGB {
+		return errors.New("x11driver: color spaces other than sRGB are not supported")
+	}
+	return nil
 }
@@ -0,0 +1,128 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/BurntSushi/xgb/xproto"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestReleaseRacesWithDraws(t *testing.T) {
+	Main(func(s screen.Screen) {
+		w, err := s.NewWindow(&screen.NewWindowOptions{Width: 64, Height: 64})
+		if err != nil {
+			t.Skipf("NewWindow: %v", err)
+		}
+		tex, err := s.NewTexture(image.Point{16, 16})
+		if err != nil {
+			w.Release()
+			t.Fatalf("NewTexture: %v", err)
+		}
+		defer tex.Release()
+
+		const n = 8
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				for j := 0; j < 100; j++ {
+					w.Fill(image.Rect(0, 0, 16, 16), color.Black, draw.Src)
+					w.Copy(image.Point{j % 48, j % 48}, tex, tex.Bounds(), draw.Over, nil)
+					w.Publish()
+				}
+			}()
+		}
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				w.Release()
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		// All calls after Release should be no-ops.
+		w.Release()
+		w.Fill(image.Rect(0, 0, 16, 16), color.White, draw.Src)
+		if got := w.Publish(); got.BackBufferPreserved {
+			t.Errorf("Publish after Release: got BackBufferPreserved, want not")
+		}
+	})
+}
+
+func TestTextureMemoryEstimate(t *testing.T) {
+	Main(func(s screen.Screen) {
+		if _, ok := s.(*screenImpl); !ok {
+			t.Skip("no X11 connection")
+		}
+		before := s.TextureMemoryEstimate()
+		tex, err := s.NewTexture(image.Point{16, 8})
+		if err != nil {
+			t.Fatalf("NewTexture: %v", err)
+		}
+		if got, want := s.TextureMemoryEstimate()-before, int64(4*16*8); got != want {
+			t.Errorf("after NewTexture: got %d more bytes, want %d", got, want)
+		}
+		tex.Release()
+		tex.Release()
+		if got, want := s.TextureMemoryEstimate(), before; got != want {
+			t.Errorf("after Release: got %d bytes, want %d", got, want)
+		}
+	})
+}
+
+func TestTextureDownload(t *testing.T) {
+	Main(func(s screen.Screen) {
+		if _, ok := s.(*screenImpl); !ok {
+			t.Skip("no X11 connection")
+		}
+		tex, err := s.NewTexture(image.Point{4, 4})
+		if err != nil {
+			t.Fatalf("NewTexture: %v", err)
+		}
+		defer tex.Release()
+		red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+		tex.Fill(tex.Bounds(), red, draw.Src)
+
+		m, err := tex.Download(image.Point{}, image.Rect(1, 1, 3, 3))
+		if err != nil {
+			t.Fatalf("Download: %v", err)
+		}
+		if got, want := m.Bounds(), image.Rect(0, 0, 2, 2); got != want {
+			t.Fatalf("bounds: got %v, want %v", got, want)
+		}
+		if got := m.RGBAAt(1, 1); got != red {
+			t.Errorf("pixel: got %v, want %v", got, red)
+		}
+	})
+}
+
+func TestShapeRectangles(t *testing.T) {
+	got := shapeRectangles([]image.Rectangle{
+		image.Rect(0, 0, 10, 20),
+		image.Rect(5, 5, 5, 50),
+		image.Rect(-40000, 1, 40000, 2),
+	})
+	want := []xproto.Rectangle{
+		{X: 0, Y: 0, Width: 10, Height: 20},
+		{X: -0x8000, Y: 1, Width: 0xffff, Height: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -47,11 +47,11 @@ func main(f func(screen.Screen)) (retErr error) {
 	if err := render.Init(xc); err != nil {
 		return fmt.Errorf("x11driver: render.Init failed: %v", err)
 	}
-	if err := shm.Init(xc); err != nil {
-		return fmt.Errorf("x11driver: shm.Init failed: %v", err)
-	}
+	// MIT-SHM is optional. Without it, Buffers are uploaded by core protocol
+	// requests.
+	useShm := shm.Init(xc) == nil
 
-	s, err := newScreenImpl(xc)
+	s, err := newScreenImpl(xc, useShm)
 	if err != nil {
 		return err
 	}
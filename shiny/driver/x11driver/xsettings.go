@@ -0,0 +1,222 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/BurntSushi/xgb/xproto"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// The XSETTINGS protocol is how desktop environments such as GNOME and XFCE
+// publish user preferences to X11 clients. It is specified at
+// https://specifications.freedesktop.org/xsettings-spec/xsettings-spec-0.5.html
+
+// initXSettings finds the XSETTINGS manager, if there is one, and reads its
+// settings. It is not an error for there to be no manager, in which case the
+// default (zero) accessibility preferences and the physical screen resolution
+// are used.
+//
+// TODO: listen for MANAGER client messages on the root window, so that we
+// notice a settings manager that starts (or restarts) after we do.
+func (s *screenImpl) initXSettings() error {
+	selection, err := s.internAtom(fmt.Sprintf("_XSETTINGS_S%d", s.xc.DefaultScreen))
+	if err != nil {
+		return err
+	}
+	s.atomXSettingsSettings, err = s.internAtom("_XSETTINGS_SETTINGS")
+	if err != nil {
+		return err
+	}
+
+	r, err := xproto.GetSelectionOwner(s.xc, selection).Reply()
+	if err != nil {
+		return fmt.Errorf("x11driver: xproto.GetSelectionOwner failed: %v", err)
+	}
+	if r.Owner == xproto.WindowNone {
+		return nil
+	}
+	s.xsettingsOwner = r.Owner
+	xproto.ChangeWindowAttributes(s.xc, s.xsettingsOwner, xproto.CwEventMask,
+		[]uint32{xproto.EventMaskPropertyChange})
+	if x := s.readXSettings(); x != nil {
+		s.accessibilityPrefs = x.accessibilityPrefs()
+		s.colorScheme = x.colorScheme()
+		if ppp, ok := x.pixelsPerPt(); ok {
+			s.pixelsPerPt = ppp
+			s.xftDPI = true
+		}
+	}
+	return nil
+}
+
+// readXSettings returns the settings held by the XSETTINGS manager's
+// _XSETTINGS_SETTINGS property, or nil if they could not be read.
+func (s *screenImpl) readXSettings() *xsettings {
+	r, err := xproto.GetProperty(s.xc, false, s.xsettingsOwner, s.atomXSettingsSettings,
+		xproto.GetPropertyTypeAny, 0, 1<<16).Reply()
+	if err != nil {
+		log.Printf("x11driver: xproto.GetProperty failed: %v", err)
+		return nil
+	}
+	x, err := parseXSettings(r.Value)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+	return x
+}
+
+// handleXSettingsChange must only be called from the screenImpl.run goroutine.
+func (s *screenImpl) handleXSettingsChange() {
+	x := s.readXSettings()
+	if x == nil {
+		return
+	}
+	prefs, scheme := x.accessibilityPrefs(), x.colorScheme()
+	ppp, ok := x.pixelsPerPt()
+	if !ok {
+		ppp = s.pixelsPerPt
+	}
+	rescaled := s.pixelsPerPt != ppp
+	s.pixelsPerPt = ppp
+	s.xftDPI = s.xftDPI || ok
+
+	s.mu.Lock()
+	prefsChanged := s.accessibilityPrefs != prefs
+	s.accessibilityPrefs = prefs
+	schemeChanged := s.colorScheme != scheme
+	s.colorScheme = scheme
+	windows := make([]*windowImpl, 0, len(s.windows))
+	for _, w := range s.windows {
+		windows = append(windows, w)
+	}
+	s.mu.Unlock()
+
+	for _, w := range windows {
+		if prefsChanged {
+			w.Send(screen.AccessibilityEvent{Prefs: prefs})
+		}
+		if schemeChanged {
+			w.Send(screen.ColorSchemeEvent{Scheme: scheme})
+		}
+		if rescaled && w.updateScale() {
+			w.sendSize()
+		}
+	}
+	if rescaled {
+		s.handleDisplayChange()
+	}
+}
+
+// xsettings holds the integer and string valued settings of an XSETTINGS
+// manager. Color valued settings are ignored.
+type xsettings struct {
+	ints map[string]int32
+	strs map[string]string
+}
+
+func (x *xsettings) accessibilityPrefs() (p screen.AccessibilityPrefs) {
+	if v, ok := x.ints["Gtk/EnableAnimations"]; ok && v == 0 {
+		p |= screen.ReduceMotion
+	}
+	if strings.Contains(x.strs["Net/ThemeName"], "HighContrast") {
+		p |= screen.HighContrast
+	}
+	return p
+}
+
+// colorScheme returns the color scheme implied by the "Net/ThemeName"
+// setting. There is no XSETTINGS setting for a preferred color scheme, but
+// dark themes, such as "Adwaita-dark", are conventionally named so.
+func (x *xsettings) colorScheme() screen.ColorScheme {
+	if strings.Contains(strings.ToLower(x.strs["Net/ThemeName"]), "dark") {
+		return screen.DarkColorScheme
+	}
+	return screen.LightColorScheme
+}
+
+// pixelsPerPt returns the scale implied by the "Xft/DPI" setting, which is how
+// desktop environments expose the user's chosen, possibly fractional, scaling
+// factor. Its value is the resolution in dots per inch, multiplied by 1024, or
+// -1 for the default resolution.
+func (x *xsettings) pixelsPerPt() (ppp float32, ok bool) {
+	const ptPerInch = 72
+	v, ok := x.ints["Xft/DPI"]
+	if !ok || v <= 0 {
+		return 0, false
+	}
+	return float32(v) / 1024 / ptPerInch, true
+}
+
+func parseXSettings(b []byte) (*xsettings, error) {
+	errShort := fmt.Errorf("x11driver: XSETTINGS data is too short")
+
+	if len(b) < 12 {
+		return nil, errShort
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if b[0] != 0 {
+		order = binary.BigEndian
+	}
+	n := order.Uint32(b[8:])
+	b = b[12:]
+
+	x := &xsettings{
+		ints: map[string]int32{},
+		strs: map[string]string{},
+	}
+	for ; n > 0; n-- {
+		if len(b) < 4 {
+			return nil, errShort
+		}
+		typ, nameLen := b[0], int(order.Uint16(b[2:]))
+		b = b[4:]
+		// The name is padded to a multiple of 4 bytes, and is followed by
+		// the 4 byte serial number of the setting's last change.
+		if len(b) < pad4(nameLen)+4 {
+			return nil, errShort
+		}
+		name := string(b[:nameLen])
+		b = b[pad4(nameLen)+4:]
+
+		switch typ {
+		case 0: // Integer.
+			if len(b) < 4 {
+				return nil, errShort
+			}
+			x.ints[name] = int32(order.Uint32(b))
+			b = b[4:]
+		case 1: // String.
+			if len(b) < 4 {
+				return nil, errShort
+			}
+			valueLen := order.Uint32(b)
+			b = b[4:]
+			if uint64(len(b)) < (uint64(valueLen)+3)&^3 {
+				return nil, errShort
+			}
+			x.strs[name] = string(b[:valueLen])
+			b = b[pad4(int(valueLen)):]
+		case 2: // Color.
+			if len(b) < 8 {
+				return nil, errShort
+			}
+			b = b[8:]
+		default:
+			return nil, fmt.Errorf("x11driver: unknown XSETTINGS setting type %d", typ)
+		}
+	}
+	return x, nil
+}
+
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
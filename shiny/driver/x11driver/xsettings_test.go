@@ -0,0 +1,115 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x11driver
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+type xsetting struct {
+	typ   byte
+	name  string
+	value interface{}
+}
+
+func encodeXSettings(order binary.ByteOrder, settings []xsetting) []byte {
+	b := make([]byte, 12)
+	if order == binary.BigEndian {
+		b[0] = 1
+	}
+	order.PutUint32(b[4:], 1)
+	order.PutUint32(b[8:], uint32(len(settings)))
+
+	u32 := func(v uint32) {
+		var x [4]byte
+		order.PutUint32(x[:], v)
+		b = append(b, x[:]...)
+	}
+	str := func(s string) {
+		b = append(b, s...)
+		b = append(b, make([]byte, pad4(len(s))-len(s))...)
+	}
+	for _, s := range settings {
+		b = append(b, s.typ, 0, 0, 0)
+		order.PutUint16(b[len(b)-2:], uint16(len(s.name)))
+		str(s.name)
+		u32(0) // Last change serial.
+		switch v := s.value.(type) {
+		case int32:
+			u32(uint32(v))
+		case string:
+			u32(uint32(len(v)))
+			str(v)
+		case [4]uint16:
+			for _, c := range v {
+				var x [2]byte
+				order.PutUint16(x[:], c)
+				b = append(b, x[:]...)
+			}
+		}
+	}
+	return b
+}
+
+func TestParseXSettings(t *testing.T) {
+	settings := []xsetting{
+		{0, "Gtk/EnableAnimations", int32(0)},
+		{2, "Gtk/Color", [4]uint16{1, 2, 3, 4}},
+		{1, "Net/ThemeName", "HighContrast"},
+		{0, "Net/DoubleClickTime", int32(400)},
+		{0, "Xft/DPI", int32(144 * 1024)},
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		b := encodeXSettings(order, settings)
+		x, err := parseXSettings(b)
+		if err != nil {
+			t.Errorf("%v: parseXSettings: %v", order, err)
+			continue
+		}
+		if got, want := x.ints["Net/DoubleClickTime"], int32(400); got != want {
+			t.Errorf("%v: Net/DoubleClickTime: got %d, want %d", order, got, want)
+		}
+		if got, want := x.strs["Net/ThemeName"], "HighContrast"; got != want {
+			t.Errorf("%v: Net/ThemeName: got %q, want %q", order, got, want)
+		}
+		if got, want := x.accessibilityPrefs(), screen.ReduceMotion|screen.HighContrast; got != want {
+			t.Errorf("%v: accessibilityPrefs: got %#x, want %#x", order, got, want)
+		}
+		if got := x.colorScheme(); got != screen.LightColorScheme {
+			t.Errorf("%v: colorScheme: got %v, want light", order, got)
+		}
+		if ppp, ok := x.pixelsPerPt(); !ok || ppp != 2 {
+			t.Errorf("%v: pixelsPerPt: got %v, %t, want 2, true", order, ppp, ok)
+		}
+
+		for i := range b {
+			if _, err := parseXSettings(b[:i]); err == nil {
+				t.Errorf("%v: parseXSettings(b[:%d]): got nil error, want non-nil", order, i)
+				break
+			}
+		}
+	}
+}
+
+func TestXSettingsColorScheme(t *testing.T) {
+	testCases := []struct {
+		themeName string
+		want      screen.ColorScheme
+	}{
+		{"", screen.LightColorScheme},
+		{"Adwaita", screen.LightColorScheme},
+		{"Adwaita-dark", screen.DarkColorScheme},
+		{"Breeze Dark", screen.DarkColorScheme},
+	}
+	for _, tc := range testCases {
+		x := &xsettings{strs: map[string]string{"Net/ThemeName": tc.themeName}}
+		if got := x.colorScheme(); got != tc.want {
+			t.Errorf("%q: got %v, want %v", tc.themeName, got, tc.want)
+		}
+	}
+}
@@ -87,6 +87,7 @@ type Button struct {
 	node.LeafEmbed
 	icon    []byte
 	onClick func()
+	hovered bool
 	z       iconvg.Rasterizer
 }
 
@@ -106,12 +107,18 @@ func (w *Button) Measure(t *theme.Theme, widthHint, heightHint int) {
 
 func (w *Button) PaintBase(ctx *node.PaintBaseContext, origin image.Point) error {
 	w.Marks.UnmarkNeedsPaintBase()
+	if w.hovered {
+		draw.Draw(ctx.Dst, w.Rect.Add(origin), ctx.Theme.GetPalette().Light(), image.Point{}, draw.Src)
+	}
 	w.z.SetDstImage(ctx.Dst, w.Rect.Add(origin), draw.Over)
 	return iconvg.Decode(&w.z, w.icon, nil)
 }
 
 func (w *Button) OnInputEvent(e interface{}, origin image.Point) node.EventHandled {
 	switch e := e.(type) {
+	case node.HoverEvent:
+		w.hovered = e.Hovered
+		w.Mark(node.MarkNeedsPaintBase)
 	case gesture.Event:
 		if e.Type != gesture.TypeTap {
 			break
@@ -9,9 +9,14 @@
 // -tags=example" to install it.
 
 // Widgetgallery exhibits the shiny/widget package's widget set.
+//
+// Pressing Control-Shift-I shows or hides the inspector overlay, which
+// outlines each widget and lists the widget tree. The -inspect flag shows it
+// from the start.
 package main
 
 import (
+	"flag"
 	"image"
 	"image/color"
 	"image/draw"
@@ -20,10 +25,14 @@ import (
 	"golang.org/x/exp/shiny/driver"
 	"golang.org/x/exp/shiny/gesture"
 	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/unit"
 	"golang.org/x/exp/shiny/widget"
 	"golang.org/x/exp/shiny/widget/node"
+	"golang.org/x/exp/shiny/widget/theme"
 )
 
+var inspect = flag.Bool("inspect", false, "show the inspector overlay")
+
 var uniforms = [...]*image.Uniform{
 	image.NewUniform(color.RGBA{0xbf, 0x00, 0x00, 0xff}),
 	image.NewUniform(color.RGBA{0x9f, 0x9f, 0x00, 0xff}),
@@ -68,13 +77,31 @@ func (w *custom) PaintBase(ctx *node.PaintBaseContext, origin image.Point) error
 
 func main() {
 	log.SetFlags(0)
+	flag.Parse()
 	driver.Main(func(s screen.Screen) {
-		// TODO: create a bunch of standard widgets: buttons, labels, etc.
-		w := widget.NewSheet(newCustom())
+		// TODO: add buttons, once package widget has them.
+		stretch := widget.FlowLayoutData{ExpandAcross: true}
+		w := widget.NewSheet(widget.NewUniform(theme.Background,
+			widget.NewPadder(widget.AxisBoth, unit.Ems(1), widget.NewFlow(widget.AxisVertical,
+				widget.NewLabel("Label"),
+				widget.WithLayoutData(widget.NewTextField("TextField"), stretch),
+				widget.NewPadder(widget.AxisVertical, unit.Ems(0.5),
+					widget.NewText("Text wraps its lines to fit the width that it is laid out at."),
+				),
+				widget.NewFlow(widget.AxisHorizontal,
+					widget.NewSizer(unit.Ems(4), unit.Ems(4), newCustom()),
+					widget.NewPadder(widget.AxisHorizontal, unit.Ems(0.5),
+						widget.NewLabel("A custom widget: tap it to change its color."),
+					),
+				),
+			)),
+		))
 		if err := widget.RunWindow(s, w, &widget.RunWindowOptions{
 			NewWindowOptions: screen.NewWindowOptions{
 				Title: "WidgetGallery Shiny Example",
 			},
+			Inspect:      *inspect,
+			InspectorKey: true,
 		}); err != nil {
 			log.Fatal(err)
 		}
@@ -0,0 +1,394 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gamepad provides the button and axis events of game controllers,
+// such as gamepads and joysticks, and the events of their being connected
+// and disconnected.
+//
+// The controllers are read with XInput on Windows, evdev on Linux and the
+// Game Controller framework on macOS. Elsewhere, there are none.
+package gamepad // import "golang.org/x/exp/shiny/gamepad"
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// TODO: DirectInput, for older Windows controllers that lack XInput.
+//
+// TODO: rumble.
+
+// Button is a gamepad button. They are named for their positions, as on an
+// Xbox controller, whose face buttons are A, B, X and Y.
+type Button uint8
+
+const (
+	ButtonSouth Button = iota // A.
+	ButtonEast                // B.
+	ButtonWest                // X.
+	ButtonNorth               // Y.
+	ButtonLeftShoulder
+	ButtonRightShoulder
+	ButtonBack
+	ButtonStart
+	ButtonGuide
+	ButtonLeftStick
+	ButtonRightStick
+	ButtonDPadUp
+	ButtonDPadDown
+	ButtonDPadLeft
+	ButtonDPadRight
+
+	numButtons = iota
+)
+
+func (b Button) String() string {
+	switch b {
+	case ButtonSouth:
+		return "ButtonSouth"
+	case ButtonEast:
+		return "ButtonEast"
+	case ButtonWest:
+		return "ButtonWest"
+	case ButtonNorth:
+		return "ButtonNorth"
+	case ButtonLeftShoulder:
+		return "ButtonLeftShoulder"
+	case ButtonRightShoulder:
+		return "ButtonRightShoulder"
+	case ButtonBack:
+		return "ButtonBack"
+	case ButtonStart:
+		return "ButtonStart"
+	case ButtonGuide:
+		return "ButtonGuide"
+	case ButtonLeftStick:
+		return "ButtonLeftStick"
+	case ButtonRightStick:
+		return "ButtonRightStick"
+	case ButtonDPadUp:
+		return "ButtonDPadUp"
+	case ButtonDPadDown:
+		return "ButtonDPadDown"
+	case ButtonDPadLeft:
+		return "ButtonDPadLeft"
+	case ButtonDPadRight:
+		return "ButtonDPadRight"
+	}
+	return fmt.Sprintf("gamepad.Button(%d)", b)
+}
+
+// Axis is a gamepad axis. The stick axes range from -1 to 1, with positive Y
+// being down, as for window coordinates. The trigger axes range from 0,
+// released, to 1, fully pressed.
+type Axis uint8
+
+const (
+	AxisLeftX Axis = iota
+	AxisLeftY
+	AxisRightX
+	AxisRightY
+	AxisLeftTrigger
+	AxisRightTrigger
+
+	numAxes = iota
+)
+
+func (a Axis) String() string {
+	switch a {
+	case AxisLeftX:
+		return "AxisLeftX"
+	case AxisLeftY:
+		return "AxisLeftY"
+	case AxisRightX:
+		return "AxisRightX"
+	case AxisRightY:
+		return "AxisRightY"
+	case AxisLeftTrigger:
+		return "AxisLeftTrigger"
+	case AxisRightTrigger:
+		return "AxisRightTrigger"
+	}
+	return fmt.Sprintf("gamepad.Axis(%d)", a)
+}
+
+// Gamepad is a connected game controller.
+type Gamepad struct {
+	// ID identifies the gamepad while it is connected. IDs are not reused,
+	// so a gamepad that is disconnected and reconnected has a new ID.
+	ID int
+
+	// Name is the controller's name, as the platform reports it, such as
+	// "Xbox Wireless Controller".
+	Name string
+}
+
+// ConnectEvent is sent when a gamepad is connected or disconnected. When it
+// is connected, its buttons are released and its axes are 0, until button
+// and axis events say otherwise.
+type ConnectEvent struct {
+	Gamepad   Gamepad
+	Connected bool
+}
+
+// ButtonEvent is sent when a gamepad button is pressed or released.
+type ButtonEvent struct {
+	// ID is the Gamepad.ID of the gamepad.
+	ID      int
+	Button  Button
+	Pressed bool
+}
+
+// AxisEvent is sent when a gamepad axis moves.
+type AxisEvent struct {
+	// ID is the Gamepad.ID of the gamepad.
+	ID    int
+	Axis  Axis
+	Value float32
+}
+
+const (
+	// pollInterval is how often the gamepads are polled.
+	pollInterval = time.Second / 60
+
+	// deadZone is how far a stick must be pushed from its center before it
+	// is not 0, as sticks do not quite return to their centers, and their
+	// readings are noisy.
+	deadZone = 0.1
+)
+
+// state is a gamepad's buttons, of which bit i is Button(i), and axes.
+type state struct {
+	buttons uint32
+	axes    [numAxes]float32
+}
+
+// device is a gamepad as polled by a backend.
+type device struct {
+	// key identifies the device to the backend, such as its path.
+	key   string
+	name  string
+	state state
+}
+
+// backend polls the gamepads of a platform.
+type backend interface {
+	// poll returns the connected gamepads and their states.
+	poll() []device
+	close()
+}
+
+// newBackend is the platform's backend.
+var newBackend func() backend = newPlatformBackend
+
+// watchers holds the EventDeques passed to Watch. The gamepads are polled
+// while there are any.
+var watchers struct {
+	mu      sync.Mutex
+	deqs    map[*watcher]struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+	// gamepads are the connected gamepads, keyed by device key.
+	gamepads map[string]*gamepad
+	nextID   int
+}
+
+type watcher struct {
+	deq screen.EventDeque
+}
+
+type gamepad struct {
+	Gamepad
+	state state
+}
+
+// Gamepads returns the connected gamepads, while there is a Watch. Without
+// one, there are none.
+func Gamepads() []Gamepad {
+	watchers.mu.Lock()
+	defer watchers.mu.Unlock()
+
+	gs := make([]Gamepad, 0, len(watchers.gamepads))
+	for _, g := range watchers.gamepads {
+		gs = append(gs, g.Gamepad)
+	}
+	sortGamepads(gs)
+	return gs
+}
+
+// Watch sends the gamepads' events to deq, such as a screen.Window, until
+// stop is called. It first sends a ConnectEvent for each gamepad that is
+// already connected, followed by the events of its buttons that are pressed
+// and its axes that are not 0. There may be several Watches at once, which
+// share the polling of the gamepads.
+func Watch(deq screen.EventDeque) (stop func()) {
+	w := &watcher{deq}
+
+	watchers.mu.Lock()
+	defer watchers.mu.Unlock()
+
+	gs := make([]*gamepad, 0, len(watchers.gamepads))
+	for _, g := range watchers.gamepads {
+		gs = append(gs, g)
+	}
+	sortByID(gs)
+	for _, g := range gs {
+		deq.Send(ConnectEvent{Gamepad: g.Gamepad, Connected: true})
+		sendChanges(deq, g.ID, state{}, g.state)
+	}
+
+	if watchers.deqs == nil {
+		watchers.deqs = map[*watcher]struct{}{}
+		watchers.gamepads = map[string]*gamepad{}
+	}
+	watchers.deqs[w] = struct{}{}
+	if len(watchers.deqs) == 1 {
+		watchers.stop = make(chan struct{})
+		watchers.stopped = make(chan struct{})
+		go run(newBackend(), watchers.stop, watchers.stopped)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { unwatch(w) })
+	}
+}
+
+func unwatch(w *watcher) {
+	watchers.mu.Lock()
+	delete(watchers.deqs, w)
+	if len(watchers.deqs) != 0 {
+		watchers.mu.Unlock()
+		return
+	}
+	// Without a Watch, there are no gamepads, until the next one.
+	stop, stopped := watchers.stop, watchers.stopped
+	watchers.stop, watchers.stopped = nil, nil
+	watchers.gamepads = map[string]*gamepad{}
+	watchers.mu.Unlock()
+
+	close(stop)
+	<-stopped
+}
+
+// run polls b until stop is closed.
+func run(b backend, stop, stopped chan struct{}) {
+	defer close(stopped)
+	defer b.close()
+
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+	for {
+		update(stop, b.poll())
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// update sends the events of the changes since the previous poll, unless
+// stop is closed.
+func update(stop chan struct{}, devices []device) {
+	watchers.mu.Lock()
+	defer watchers.mu.Unlock()
+
+	// A poll that finishes after unwatch, which may have been followed by
+	// another Watch, and run, is stale.
+	if watchers.stop != stop {
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, d := range devices {
+		seen[d.key] = true
+		d.state = applyDeadZone(d.state)
+		g := watchers.gamepads[d.key]
+		if g == nil {
+			watchers.nextID++
+			g = &gamepad{Gamepad: Gamepad{ID: watchers.nextID, Name: d.name}}
+			watchers.gamepads[d.key] = g
+			broadcast(ConnectEvent{Gamepad: g.Gamepad, Connected: true})
+		}
+		for w := range watchers.deqs {
+			sendChanges(w.deq, g.ID, g.state, d.state)
+		}
+		g.state = d.state
+	}
+	var gone []*gamepad
+	for k, g := range watchers.gamepads {
+		if !seen[k] {
+			gone = append(gone, g)
+			delete(watchers.gamepads, k)
+		}
+	}
+	sortByID(gone)
+	for _, g := range gone {
+		broadcast(ConnectEvent{Gamepad: g.Gamepad, Connected: false})
+	}
+}
+
+func broadcast(e interface{}) {
+	for w := range watchers.deqs {
+		w.deq.Send(e)
+	}
+}
+
+// sendChanges sends the events of the changes from old to new.
+func sendChanges(deq screen.EventDeque, id int, old, new state) {
+	for b := Button(0); b < numButtons; b++ {
+		bit := uint32(1) << b
+		if old.buttons&bit != new.buttons&bit {
+			deq.Send(ButtonEvent{ID: id, Button: b, Pressed: new.buttons&bit != 0})
+		}
+	}
+	for a := Axis(0); a < numAxes; a++ {
+		if old.axes[a] != new.axes[a] {
+			deq.Send(AxisEvent{ID: id, Axis: a, Value: new.axes[a]})
+		}
+	}
+}
+
+// applyDeadZone clamps s's axes to their ranges, and zeroes its sticks' axes
+// that are within the dead zone.
+func applyDeadZone(s state) state {
+	for a := Axis(0); a < numAxes; a++ {
+		v := s.axes[a]
+		lo := float32(-1)
+		if a == AxisLeftTrigger || a == AxisRightTrigger {
+			lo = 0
+		} else if -deadZone < v && v < deadZone {
+			v = 0
+		}
+		if v < lo {
+			v = lo
+		} else if v > 1 {
+			v = 1
+		}
+		s.axes[a] = v
+	}
+	return s
+}
+
+// The gamepads are few, so they are sorted by insertion sort.
+
+func sortGamepads(gs []Gamepad) {
+	for i := 1; i < len(gs); i++ {
+		for j := i; j > 0 && gs[j].ID < gs[j-1].ID; j-- {
+			gs[j], gs[j-1] = gs[j-1], gs[j]
+		}
+	}
+}
+
+func sortByID(gs []*gamepad) {
+	for i := 1; i < len(gs); i++ {
+		for j := i; j > 0 && gs[j].ID < gs[j-1].ID; j-- {
+			gs[j], gs[j-1] = gs[j-1], gs[j]
+		}
+	}
+}
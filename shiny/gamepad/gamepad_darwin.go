@@ -0,0 +1,149 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin,!ios,cgo
+
+package gamepad
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Foundation -framework GameController
+
+#import <Foundation/Foundation.h>
+#import <GameController/GameController.h>
+#include <stdint.h>
+#include <string.h>
+
+// The buttons and axes, in the same order as those of package gamepad.
+enum {
+	buttonSouth,
+	buttonEast,
+	buttonWest,
+	buttonNorth,
+	buttonLeftShoulder,
+	buttonRightShoulder,
+	buttonBack,
+	buttonStart,
+	buttonGuide,
+	buttonLeftStick,
+	buttonRightStick,
+	buttonDPadUp,
+	buttonDPadDown,
+	buttonDPadLeft,
+	buttonDPadRight,
+};
+
+enum {
+	axisLeftX,
+	axisLeftY,
+	axisRightX,
+	axisRightY,
+	axisLeftTrigger,
+	axisRightTrigger,
+	numAxes,
+};
+
+typedef struct gamepadState {
+	uintptr_t id;
+	char name[128];
+	uint32_t buttons;
+	float axes[numAxes];
+} gamepadState;
+
+static void setButton(gamepadState* s, int button, GCControllerButtonInput* input) {
+	if (input != nil && input.pressed) {
+		s->buttons |= 1 << button;
+	}
+}
+
+// pollGamepads fills in the states of up to n of the connected controllers
+// that have an extended gamepad profile, returning how many it filled in.
+static int pollGamepads(gamepadState* states, int n) {
+	int count = 0;
+	@autoreleasepool {
+		for (GCController* c in [GCController controllers]) {
+			GCExtendedGamepad* g = c.extendedGamepad;
+			if (g == nil) {
+				continue;
+			}
+			if (count == n) {
+				break;
+			}
+			gamepadState* s = &states[count++];
+			memset(s, 0, sizeof(*s));
+			s->id = (uintptr_t)c;
+			NSString* name = c.vendorName;
+			if (name != nil) {
+				strlcpy(s->name, name.UTF8String, sizeof(s->name));
+			}
+
+			setButton(s, buttonSouth, g.buttonA);
+			setButton(s, buttonEast, g.buttonB);
+			setButton(s, buttonWest, g.buttonX);
+			setButton(s, buttonNorth, g.buttonY);
+			setButton(s, buttonLeftShoulder, g.leftShoulder);
+			setButton(s, buttonRightShoulder, g.rightShoulder);
+			if (@available(macOS 10.15, *)) {
+				setButton(s, buttonBack, g.buttonOptions);
+				setButton(s, buttonStart, g.buttonMenu);
+			}
+			if (@available(macOS 11.0, *)) {
+				setButton(s, buttonGuide, g.buttonHome);
+			}
+			if (@available(macOS 10.14.1, *)) {
+				setButton(s, buttonLeftStick, g.leftThumbstickButton);
+				setButton(s, buttonRightStick, g.rightThumbstickButton);
+			}
+			setButton(s, buttonDPadUp, g.dpad.up);
+			setButton(s, buttonDPadDown, g.dpad.down);
+			setButton(s, buttonDPadLeft, g.dpad.left);
+			setButton(s, buttonDPadRight, g.dpad.right);
+
+			// The Game Controller framework's positive Y is up.
+			s->axes[axisLeftX] = g.leftThumbstick.xAxis.value;
+			s->axes[axisLeftY] = -g.leftThumbstick.yAxis.value;
+			s->axes[axisRightX] = g.rightThumbstick.xAxis.value;
+			s->axes[axisRightY] = -g.rightThumbstick.yAxis.value;
+			s->axes[axisLeftTrigger] = g.leftTrigger.value;
+			s->axes[axisRightTrigger] = g.rightTrigger.value;
+		}
+	}
+	return count;
+}
+*/
+import "C"
+
+import "fmt"
+
+// The Game Controller framework finds controllers, and updates their
+// values, on the main thread's run loop, which the Cocoa drivers run. Since
+// macOS 11, only the frontmost application sees a controller's input.
+
+// maxGamepads is the most gamepads that are polled.
+const maxGamepads = 16
+
+type gcBackend struct {
+	states [maxGamepads]C.gamepadState
+}
+
+func newPlatformBackend() backend { return &gcBackend{} }
+
+func (b *gcBackend) poll() []device {
+	n := int(C.pollGamepads(&b.states[0], maxGamepads))
+	ds := make([]device, n)
+	for i := range ds {
+		s := &b.states[i]
+		ds[i] = device{
+			key:  fmt.Sprint(uintptr(s.id)),
+			name: C.GoString(&s.name[0]),
+		}
+		ds[i].state.buttons = uint32(s.buttons)
+		for a := range ds[i].state.axes {
+			ds[i].state.axes[a] = float32(s.axes[a])
+		}
+	}
+	return ds
+}
+
+func (b *gcBackend) close() {}
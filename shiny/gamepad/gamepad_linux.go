@@ -0,0 +1,302 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gamepad
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// The gamepads are the evdev devices, /dev/input/event*, that have gamepad or
+// joystick buttons. Reading them usually needs the user to be in the input
+// group, or a udev rule granting access. Devices that cannot be opened are
+// ignored.
+//
+// See https://www.kernel.org/doc/html/latest/input/gamepad.html
+
+// rescanInterval is how often /dev/input is rescanned for new devices.
+const rescanInterval = time.Second
+
+// Event types and codes, from linux/input-event-codes.h.
+const (
+	evKey = 0x01
+	evAbs = 0x03
+
+	btnJoystick = 0x120
+	btnGamepad  = 0x130
+	keyMax      = 0x2ff
+
+	absX     = 0x00
+	absY     = 0x01
+	absZ     = 0x02
+	absRX    = 0x03
+	absRY    = 0x04
+	absRZ    = 0x05
+	absHat0X = 0x10
+	absHat0Y = 0x11
+	absMax   = 0x3f
+)
+
+// evdevButtons are the Buttons of the evdev key codes.
+var evdevButtons = map[uint16]Button{
+	0x130: ButtonSouth,         // BTN_SOUTH.
+	0x131: ButtonEast,          // BTN_EAST.
+	0x133: ButtonNorth,         // BTN_NORTH.
+	0x134: ButtonWest,          // BTN_WEST.
+	0x136: ButtonLeftShoulder,  // BTN_TL.
+	0x137: ButtonRightShoulder, // BTN_TR.
+	0x13a: ButtonBack,          // BTN_SELECT.
+	0x13b: ButtonStart,         // BTN_START.
+	0x13c: ButtonGuide,         // BTN_MODE.
+	0x13d: ButtonLeftStick,     // BTN_THUMBL.
+	0x13e: ButtonRightStick,    // BTN_THUMBR.
+	0x220: ButtonDPadUp,        // BTN_DPAD_UP.
+	0x221: ButtonDPadDown,      // BTN_DPAD_DOWN.
+	0x222: ButtonDPadLeft,      // BTN_DPAD_LEFT.
+	0x223: ButtonDPadRight,     // BTN_DPAD_RIGHT.
+}
+
+// evdevAxes are the Axes of the evdev absolute axis codes.
+var evdevAxes = map[uint16]Axis{
+	absX:  AxisLeftX,
+	absY:  AxisLeftY,
+	absRX: AxisRightX,
+	absRY: AxisRightY,
+	absZ:  AxisLeftTrigger,
+	absRZ: AxisRightTrigger,
+}
+
+// inputEvent is a struct input_event.
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// inputAbsinfo is a struct input_absinfo.
+type inputAbsinfo struct {
+	Value      int32
+	Minimum    int32
+	Maximum    int32
+	Fuzz       int32
+	Flat       int32
+	Resolution int32
+}
+
+// The evdev ioctls, from linux/input.h.
+func eviocgname(n uintptr) uintptr    { return ioc(2, 0x06, n) }
+func eviocgbit(ev, n uintptr) uintptr { return ioc(2, 0x20+ev, n) }
+func eviocgabs(abs uintptr) uintptr {
+	return ioc(2, 0x40+abs, unsafe.Sizeof(inputAbsinfo{}))
+}
+
+// ioc is the _IOC macro, for the 'E' ioctls.
+func ioc(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | 'E'<<8 | nr
+}
+
+func ioctl(fd int, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+type evdevDevice struct {
+	fd    int
+	name  string
+	state state
+	// absInfo is the range of each absolute axis of the device.
+	absInfo map[uint16]inputAbsinfo
+}
+
+type evdevBackend struct {
+	devices map[string]*evdevDevice
+	// failed holds the devices that are not gamepads, so that they are not
+	// tried again at every rescan.
+	failed     map[string]bool
+	lastRescan time.Time
+}
+
+func newPlatformBackend() backend {
+	return &evdevBackend{
+		devices: map[string]*evdevDevice{},
+		failed:  map[string]bool{},
+	}
+}
+
+func (b *evdevBackend) poll() []device {
+	if time.Since(b.lastRescan) >= rescanInterval {
+		b.lastRescan = time.Now()
+		b.rescan()
+	}
+	ds := make([]device, 0, len(b.devices))
+	for path, d := range b.devices {
+		if !d.read() {
+			syscall.Close(d.fd)
+			delete(b.devices, path)
+			continue
+		}
+		ds = append(ds, device{key: path, name: d.name, state: d.state})
+	}
+	return ds
+}
+
+func (b *evdevBackend) close() {
+	for path, d := range b.devices {
+		syscall.Close(d.fd)
+		delete(b.devices, path)
+	}
+}
+
+// rescan opens the new gamepads in /dev/input.
+func (b *evdevBackend) rescan() {
+	paths, _ := filepath.Glob("/dev/input/event*")
+	exists := map[string]bool{}
+	for _, path := range paths {
+		exists[path] = true
+		if b.devices[path] != nil || b.failed[path] {
+			continue
+		}
+		d, err := openEvdev(path)
+		if err != nil {
+			// A device that could not be opened may become openable, such
+			// as when udev grants access just after creating it, so only
+			// non-gamepads are remembered.
+			if err == errNotGamepad {
+				b.failed[path] = true
+			}
+			continue
+		}
+		b.devices[path] = d
+	}
+	// Device nodes are reused, so forget those that were removed.
+	for path := range b.failed {
+		if !exists[path] {
+			delete(b.failed, path)
+		}
+	}
+}
+
+var errNotGamepad = errors.New("gamepad: not a gamepad")
+
+func openEvdev(path string) (*evdevDevice, error) {
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newEvdevDevice(fd)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return d, nil
+}
+
+func newEvdevDevice(fd int) (*evdevDevice, error) {
+	var keys [keyMax/8 + 1]byte
+	if err := ioctl(fd, eviocgbit(evKey, uintptr(len(keys))), unsafe.Pointer(&keys[0])); err != nil {
+		return nil, errNotGamepad
+	}
+	if !hasBit(keys[:], btnGamepad) && !hasBit(keys[:], btnJoystick) {
+		return nil, errNotGamepad
+	}
+
+	var name [256]byte
+	ioctl(fd, eviocgname(uintptr(len(name))), unsafe.Pointer(&name[0]))
+	d := &evdevDevice{
+		fd:      fd,
+		name:    strings.TrimRight(string(name[:]), "\x00"),
+		absInfo: map[uint16]inputAbsinfo{},
+	}
+
+	var abs [absMax/8 + 1]byte
+	ioctl(fd, eviocgbit(evAbs, uintptr(len(abs))), unsafe.Pointer(&abs[0]))
+	for code := uint16(0); code <= absMax; code++ {
+		if !hasBit(abs[:], int(code)) {
+			continue
+		}
+		var info inputAbsinfo
+		if ioctl(fd, eviocgabs(uintptr(code)), unsafe.Pointer(&info)) == nil && info.Maximum > info.Minimum {
+			d.absInfo[code] = info
+			d.setAbs(code, info.Value)
+		}
+	}
+	return d, nil
+}
+
+func hasBit(bits []byte, i int) bool {
+	return bits[i/8]&(1<<uint(i%8)) != 0
+}
+
+// read reads the device's pending events, returning false if the device was
+// removed.
+func (d *evdevDevice) read() bool {
+	var buf [64]inputEvent
+	b := (*[unsafe.Sizeof(buf)]byte)(unsafe.Pointer(&buf))[:]
+	size := int(unsafe.Sizeof(buf[0]))
+	for {
+		n, err := syscall.Read(d.fd, b)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			return true
+		}
+		if err != nil || n <= 0 {
+			return false
+		}
+		for _, e := range buf[:n/size] {
+			switch e.Type {
+			case evKey:
+				if button, ok := evdevButtons[e.Code]; ok {
+					d.setButton(button, e.Value != 0)
+				}
+			case evAbs:
+				d.setAbs(e.Code, e.Value)
+			}
+		}
+	}
+}
+
+func (d *evdevDevice) setButton(b Button, pressed bool) {
+	if pressed {
+		d.state.buttons |= 1 << b
+	} else {
+		d.state.buttons &^= 1 << b
+	}
+}
+
+// setAbs sets the axis, or the d-pad buttons of the hat, of an absolute axis
+// code to v.
+func (d *evdevDevice) setAbs(code uint16, v int32) {
+	info, ok := d.absInfo[code]
+	if !ok {
+		return
+	}
+	switch code {
+	case absHat0X:
+		d.setButton(ButtonDPadLeft, v < 0)
+		d.setButton(ButtonDPadRight, v > 0)
+		return
+	case absHat0Y:
+		d.setButton(ButtonDPadUp, v < 0)
+		d.setButton(ButtonDPadDown, v > 0)
+		return
+	}
+	a, ok := evdevAxes[code]
+	if !ok {
+		return
+	}
+	// Scale to 0 to 1, and, for sticks, to -1 to 1.
+	f := float32(v-info.Minimum) / float32(info.Maximum-info.Minimum)
+	if a != AxisLeftTrigger && a != AxisRightTrigger {
+		f = 2*f - 1
+	}
+	d.state.axes[a] = f
+}
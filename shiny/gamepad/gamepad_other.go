@@ -0,0 +1,15 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux,!windows,!darwin ios darwin,!cgo
+
+package gamepad
+
+type noBackend struct{}
+
+// newPlatformBackend returns a backend with no gamepads.
+func newPlatformBackend() backend { return noBackend{} }
+
+func (noBackend) poll() []device { return nil }
+func (noBackend) close()         {}
@@ -0,0 +1,128 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gamepad
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// recordingDeque is a screen.EventDeque that records the events sent to it.
+type recordingDeque struct {
+	mu     sync.Mutex
+	events []interface{}
+}
+
+func (d *recordingDeque) Send(e interface{}) {
+	d.mu.Lock()
+	d.events = append(d.events, e)
+	d.mu.Unlock()
+}
+
+func (d *recordingDeque) SendFirst(e interface{}) { d.Send(e) }
+func (d *recordingDeque) NextEvent() interface{}  { panic("unimplemented") }
+
+// take returns, and forgets, the events sent so far.
+func (d *recordingDeque) take() []interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	es := d.events
+	d.events = nil
+	return es
+}
+
+// fakeBackend is a backend whose devices are set by the tests.
+type fakeBackend struct {
+	mu      sync.Mutex
+	devices []device
+}
+
+func (b *fakeBackend) poll() []device {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.devices
+}
+
+func (b *fakeBackend) close() {}
+
+func TestUpdate(t *testing.T) {
+	fb := &fakeBackend{}
+	defer func(f func() backend) { newBackend = f }(newBackend)
+	newBackend = func() backend { return fb }
+
+	var deq recordingDeque
+	stop := Watch(&deq)
+	defer stop()
+	watchers.mu.Lock()
+	s := watchers.stop
+	watchers.mu.Unlock()
+
+	// set sets the devices, and updates the gamepads immediately, rather
+	// than waiting for the next poll, which then sees no changes.
+	set := func(ds ...device) {
+		fb.mu.Lock()
+		fb.devices = ds
+		fb.mu.Unlock()
+		update(s, ds)
+	}
+
+	pressed := state{buttons: 1<<ButtonSouth | 1<<ButtonDPadUp}
+	pressed.axes[AxisLeftX] = -0.5
+	pressed.axes[AxisLeftY] = 0.05 // Within the dead zone.
+	pressed.axes[AxisRightTrigger] = 1.5
+
+	set(device{key: "a", name: "Pad", state: pressed})
+	id := 0
+	if gs := Gamepads(); len(gs) != 1 || gs[0].Name != "Pad" {
+		t.Fatalf("Gamepads: got %+v, want one named Pad", gs)
+	} else {
+		id = gs[0].ID
+	}
+	want := []interface{}{
+		ConnectEvent{Gamepad: Gamepad{ID: id, Name: "Pad"}, Connected: true},
+		ButtonEvent{ID: id, Button: ButtonSouth, Pressed: true},
+		ButtonEvent{ID: id, Button: ButtonDPadUp, Pressed: true},
+		AxisEvent{ID: id, Axis: AxisLeftX, Value: -0.5},
+		AxisEvent{ID: id, Axis: AxisRightTrigger, Value: 1},
+	}
+	if got := deq.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("connect:\ngot  %v\nwant %v", got, want)
+	}
+
+	// A second Watch is sent the gamepad's current state.
+	var deq2 recordingDeque
+	stop2 := Watch(&deq2)
+	if got := deq2.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("second Watch:\ngot  %v\nwant %v", got, want)
+	}
+	stop2()
+
+	released := pressed
+	released.buttons &^= 1 << ButtonSouth
+	set(device{key: "a", name: "Pad", state: released})
+	want = []interface{}{
+		ButtonEvent{ID: id, Button: ButtonSouth, Pressed: false},
+	}
+	if got := deq.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("release:\ngot  %v\nwant %v", got, want)
+	}
+
+	set(device{key: "b", name: "Stick"})
+	want = []interface{}{
+		ConnectEvent{Gamepad: Gamepad{ID: id + 1, Name: "Stick"}, Connected: true},
+		ConnectEvent{Gamepad: Gamepad{ID: id, Name: "Pad"}, Connected: false},
+	}
+	if got := deq.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("disconnect:\ngot  %v\nwant %v", got, want)
+	}
+
+	stop()
+	// A poll that finishes after stop is ignored.
+	update(s, []device{{key: "c", name: "Stale"}})
+	if gs := Gamepads(); len(gs) != 0 {
+		t.Errorf("after stop: got gamepads %+v, want none", gs)
+	}
+}
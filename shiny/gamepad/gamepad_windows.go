@@ -0,0 +1,127 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package gamepad
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// XInput has four user indexes, or slots, for controllers. Polling an empty
+// slot is slow, so empty slots are polled less often.
+const (
+	xinputUsers = 4
+
+	emptySlotInterval = time.Second
+)
+
+const _ERROR_SUCCESS = 0
+
+type _XINPUT_GAMEPAD struct {
+	Buttons      uint16
+	LeftTrigger  uint8
+	RightTrigger uint8
+	ThumbLX      int16
+	ThumbLY      int16
+	ThumbRX      int16
+	ThumbRY      int16
+}
+
+type _XINPUT_STATE struct {
+	PacketNumber uint32
+	Gamepad      _XINPUT_GAMEPAD
+}
+
+// xinputButtons are the XINPUT_GAMEPAD button bits of the Buttons.
+var xinputButtons = [...]struct {
+	bit    uint16
+	button Button
+}{
+	{0x0001, ButtonDPadUp},
+	{0x0002, ButtonDPadDown},
+	{0x0004, ButtonDPadLeft},
+	{0x0008, ButtonDPadRight},
+	{0x0010, ButtonStart},
+	{0x0020, ButtonBack},
+	{0x0040, ButtonLeftStick},
+	{0x0080, ButtonRightStick},
+	{0x0100, ButtonLeftShoulder},
+	{0x0200, ButtonRightShoulder},
+	{0x1000, ButtonSouth},
+	{0x2000, ButtonEast},
+	{0x4000, ButtonWest},
+	{0x8000, ButtonNorth},
+}
+
+// procXInputGetState is XInputGetState, from the newest XInput DLL there
+// is, or nil if there is none.
+var procXInputGetState = func() *syscall.LazyProc {
+	// xinput1_4.dll comes with Windows 8 and later, and xinput9_1_0.dll with
+	// Windows Vista and later.
+	for _, name := range []string{"xinput1_4.dll", "xinput1_3.dll", "xinput9_1_0.dll"} {
+		p := syscall.NewLazyDLL(name).NewProc("XInputGetState")
+		if p.Find() == nil {
+			return p
+		}
+	}
+	return nil
+}()
+
+type xinputBackend struct {
+	connected [xinputUsers]bool
+	lastEmpty time.Time
+}
+
+func newPlatformBackend() backend { return &xinputBackend{} }
+
+func (b *xinputBackend) poll() []device {
+	if procXInputGetState == nil {
+		return nil
+	}
+	pollEmpty := time.Since(b.lastEmpty) >= emptySlotInterval
+	if pollEmpty {
+		b.lastEmpty = time.Now()
+	}
+	var ds []device
+	for i := 0; i < xinputUsers; i++ {
+		if !b.connected[i] && !pollEmpty {
+			continue
+		}
+		var s _XINPUT_STATE
+		r, _, _ := procXInputGetState.Call(uintptr(i), uintptr(unsafe.Pointer(&s)))
+		b.connected[i] = r == _ERROR_SUCCESS
+		if !b.connected[i] {
+			continue
+		}
+		ds = append(ds, device{
+			key:   fmt.Sprint(i),
+			name:  fmt.Sprintf("XInput Controller %d", i+1),
+			state: xinputState(&s.Gamepad),
+		})
+	}
+	return ds
+}
+
+func (b *xinputBackend) close() {}
+
+func xinputState(g *_XINPUT_GAMEPAD) (s state) {
+	for _, x := range xinputButtons {
+		if g.Buttons&x.bit != 0 {
+			s.buttons |= 1 << x.button
+		}
+	}
+	// XInput's positive Y is up.
+	s.axes[AxisLeftX] = float32(g.ThumbLX) / 32767
+	s.axes[AxisLeftY] = -float32(g.ThumbLY) / 32767
+	s.axes[AxisRightX] = float32(g.ThumbRX) / 32767
+	s.axes[AxisRightY] = -float32(g.ThumbRY) / 32767
+	s.axes[AxisLeftTrigger] = float32(g.LeftTrigger) / 255
+	s.axes[AxisRightTrigger] = float32(g.RightTrigger) / 255
+	return s
+}
@@ -2,30 +2,99 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package gesture provides gesture events such as long presses and drags.
-// These are higher level than underlying mouse and touch events.
+// Package gesture provides gesture events such as long presses, drags,
+// flings, pinches and rotations. These are higher level than underlying mouse
+// and touch events.
 package gesture
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/touch"
 )
 
-// TODO: handle touch events, not just mouse events.
-//
-// TODO: multi-button / multi-touch gestures such as pinch, rotate and tilt?
+// TODO: gestures of three or more touches, such as tilt?
 
 const (
 	// TODO: use a resolution-independent unit such as DIPs or Millimetres?
-	dragThreshold = 10 // Pixels.
+	dragThreshold  = 10 // Pixels.
+	pinchThreshold = 10 // Pixels.
+	flingThreshold = 50 // Pixels per second.
+
+	rotateThreshold = 0.1 // Radians, or about 6 degrees.
 
 	doublePressThreshold = 300 * time.Millisecond
 	longPressThreshold   = 500 * time.Millisecond
 )
 
+// Thresholds are how far, how fast or how long the mouse or touches must
+// move or be held for an EventFilter to recognize a gesture. A zero field
+// means to use the default value.
+type Thresholds struct {
+	// Drag is how far, in pixels, a press must move to be a drag.
+	Drag float32
+	// Pinch is how much, in pixels, the distance between two touches must
+	// change to be a pinch.
+	Pinch float32
+	// Rotate is how far, in radians, two touches must turn to be a rotation.
+	Rotate float32
+	// Fling is how fast, in pixels per second, a drag must be moving when it
+	// ends to be a fling.
+	Fling float32
+
+	// DoublePress is how soon after a release the next press must be to be
+	// a double press.
+	DoublePress time.Duration
+	// LongPress is how long a press must be held to be a long press.
+	LongPress time.Duration
+}
+
+func (t *Thresholds) drag() float32 {
+	if t.Drag > 0 {
+		return t.Drag
+	}
+	return dragThreshold
+}
+
+func (t *Thresholds) pinch() float32 {
+	if t.Pinch > 0 {
+		return t.Pinch
+	}
+	return pinchThreshold
+}
+
+func (t *Thresholds) rotate() float32 {
+	if t.Rotate > 0 {
+		return t.Rotate
+	}
+	return rotateThreshold
+}
+
+func (t *Thresholds) fling() float32 {
+	if t.Fling > 0 {
+		return t.Fling
+	}
+	return flingThreshold
+}
+
+func (t *Thresholds) doublePress() time.Duration {
+	if t.DoublePress > 0 {
+		return t.DoublePress
+	}
+	return doublePressThreshold
+}
+
+func (t *Thresholds) longPress() time.Duration {
+	if t.LongPress > 0 {
+		return t.LongPress
+	}
+	return longPressThreshold
+}
+
 // Type describes the type of a touch event.
 type Type uint8
 
@@ -36,9 +105,10 @@ const (
 	TypeEnd   Type = 1
 
 	// TypeIsXxx is when the gesture is recognized as a long press, double
-	// press or drag. For example, a mouse button press won't generate a
-	// TypeIsLongPress immediately, but if a threshold duration passes without
-	// the corresponding mouse button release, a TypeIsLongPress event is sent.
+	// press, drag, pinch or rotation. For example, a mouse button press
+	// won't generate a TypeIsLongPress immediately, but if a threshold
+	// duration passes without the corresponding mouse button release, a
+	// TypeIsLongPress event is sent.
 	//
 	// Once a TypeIsXxx event is sent, the corresponding Event.Xxx bool field
 	// is set for this and subsequent events. For example, a TypeTap event by
@@ -48,7 +118,8 @@ const (
 	// TypeEnd is seen before TypeIsDoublePress, or equivalently, if the
 	// TypeEnd event's DoublePress field is false, the gesture is a single tap.
 	//
-	// These attributes aren't exclusive. A long press drag is perfectly valid.
+	// These attributes aren't exclusive. A long press drag is perfectly
+	// valid, as is a pinch that also rotates.
 	//
 	// The uncommon "double press" instead of "double tap" terminology is
 	// because, in this package, taps are associated with button releases, not
@@ -56,15 +127,24 @@ const (
 	TypeIsLongPress   Type = 10
 	TypeIsDoublePress Type = 11
 	TypeIsDrag        Type = 12
+	TypeIsPinch       Type = 13
+	TypeIsRotate      Type = 14
 
 	// TypeTap and TypeDrag are tap and drag events.
 	//
-	// For 'flinging' drags, to simulate inertia, look to the Velocity field of
-	// the TypeEnd event.
+	// A drag that is still moving fast when it ends is a fling, which can
+	// be continued, to simulate inertia, in the direction, and at the speed,
+	// of the Velocity field of its TypeFling event. That event is sent just
+	// before the gesture's TypeEnd event.
 	//
-	// TODO: implement velocity.
-	TypeTap  Type = 20
-	TypeDrag Type = 21
+	// TypePinch and TypeRotate are sent as two touches move, once they are
+	// recognized as a pinch or rotation. Their Scale, Rotation and Focus
+	// fields say how the touches have moved.
+	TypeTap    Type = 20
+	TypeDrag   Type = 21
+	TypeFling  Type = 22
+	TypePinch  Type = 23
+	TypeRotate Type = 24
 
 	// All internal types are >= typeInternal.
 	typeInternal Type = 100
@@ -94,10 +174,20 @@ func (t Type) String() string {
 		return "IsDoublePress"
 	case TypeIsDrag:
 		return "IsDrag"
+	case TypeIsPinch:
+		return "IsPinch"
+	case TypeIsRotate:
+		return "IsRotate"
 	case TypeTap:
 		return "Tap"
 	case TypeDrag:
 		return "Drag"
+	case TypeFling:
+		return "Fling"
+	case TypePinch:
+		return "Pinch"
+	case TypeRotate:
+		return "Rotate"
 	default:
 		return fmt.Sprintf("gesture.Type(%d)", t)
 	}
@@ -113,8 +203,8 @@ type Event struct {
 	// Type is the gesture type.
 	Type Type
 
-	// Drag, LongPress and DoublePress are set when the gesture is recognized as
-	// a drag, etc.
+	// Drag, LongPress, DoublePress, Pinch and Rotate are set when the
+	// gesture is recognized as a drag, etc.
 	//
 	// Note that these status fields can be lost during a gesture's events over
 	// time: LongPress can be set for the first press of a double press, but
@@ -122,18 +212,36 @@ type Event struct {
 	Drag        bool
 	LongPress   bool
 	DoublePress bool
+	Pinch       bool
+	Rotate      bool
 
 	// InitialPos is the initial position of the button press or touch that
-	// started this gesture.
+	// started this gesture. For a gesture of two touches, it is their
+	// midpoint when the second touch began.
 	InitialPos Point
 
-	// CurrentPos is the current position of the button or touch event.
+	// CurrentPos is the current position of the button or touch event. For a
+	// gesture of two touches, it is their midpoint.
 	CurrentPos Point
 
-	// TODO: a "Velocity Point" field. See
-	//	- frameworks/native/libs/input/VelocityTracker.cpp in AOSP, or
-	//	- https://chromium.googlesource.com/chromium/src/+/master/ui/events/gesture_detection/velocity_tracker.cc in Chromium,
-	// for some velocity tracking implementations.
+	// Velocity is how fast, in pixels per second, the button or touch is
+	// moving, estimated from its most recent positions.
+	Velocity Point
+
+	// Scale, Rotation and Focus are set for a gesture of two touches.
+	//
+	// Scale is the distance between the touches, as a fraction of their
+	// distance when the second touch began. It is 1 for other gestures.
+	//
+	// Rotation is how far, in radians, the line between the touches has
+	// turned since the second touch began. Positive rotations, like the
+	// angles of screen coordinates, whose Y axis points down, are clockwise.
+	//
+	// Focus is the point, in pixels, that a pinch or rotation is about: the
+	// midpoint of the touches.
+	Scale    float32
+	Rotation float32
+	Focus    Point
 
 	// Time is the event's time.
 	Time time.Time
@@ -154,15 +262,58 @@ type internalEvent struct {
 	pressCounter uint32
 }
 
+// Recognizer recognizes gestures in lower level events. Its Filter method,
+// like an EventFilter's, returns the event, a different event, or nil to
+// consume the event, and can send the gestures that it recognizes to an
+// EventDeque.
+//
+// Apps can recognize their own gestures by implementing Recognizer, and run
+// them alongside an EventFilter in a Pipeline.
+type Recognizer interface {
+	Filter(e interface{}) interface{}
+}
+
+// Pipeline is a sequence of Recognizers. Each event is filtered by each
+// Recognizer in turn, and the event that the last returns, or nil if any
+// consumed it, is returned. For example, an app's recognizer that comes
+// before an EventFilter can consume touches that the EventFilter would
+// otherwise see.
+type Pipeline []Recognizer
+
+// Filter filters the event through each of the Recognizers.
+func (p Pipeline) Filter(e interface{}) interface{} {
+	for _, r := range p {
+		if e = r.Filter(e); e == nil {
+			return nil
+		}
+	}
+	return e
+}
+
+// now is time.Now, replaced by tests.
+var now = time.Now
+
 // EventFilter generates gesture events from lower level mouse and touch
 // events.
+//
+// A single touch is treated like a press of the left mouse button. When a
+// second touch begins, the gesture, if it started with the first touch,
+// becomes one of two touches, which can be recognized as a pinch or a
+// rotation, and which ends when either touch ends. Any further touches are
+// ignored.
 type EventFilter struct {
 	EventDeque screen.EventDeque
 
+	// Thresholds, if non-zero, are how far, how fast or how long the mouse
+	// or touches must move or be held to be a gesture.
+	Thresholds Thresholds
+
 	inProgress  bool
 	drag        bool
 	longPress   bool
 	doublePress bool
+	pinch       bool
+	rotate      bool
 
 	// initialPos is the initial position of the button press or touch that
 	// started this gesture.
@@ -174,6 +325,29 @@ type EventFilter struct {
 
 	// pressCounter is incremented on every button press and release.
 	pressCounter uint32
+
+	// velocity tracks the press's, or the touches' midpoint's, recent
+	// positions, to estimate how fast it is moving.
+	velocity velocityTracker
+
+	// touches are the positions of the current touches. pressTouch is the
+	// touch that is pressing pressButton, if touchPressed is set, rather
+	// than the mouse. The touches that are pressed after the first are
+	// ignored, except for the second, twoTouch, while twoTouches is set.
+	touches      map[touch.Sequence]Point
+	pressTouch   touch.Sequence
+	touchPressed bool
+	twoTouch     touch.Sequence
+	twoTouches   bool
+	// scale, rotation and focus are those of the two touches. startDist,
+	// startAngle and angle are the distance and angle between them, when
+	// the second began and most recently.
+	scale      float32
+	rotation   float32
+	focus      Point
+	startDist  float32
+	startAngle float32
+	angle      float32
 }
 
 func (f *EventFilter) sendFirst(t Type, x, y float32, now time.Time) {
@@ -187,19 +361,34 @@ func (f *EventFilter) sendFirst(t Type, x, y float32, now time.Time) {
 		})
 		return
 	}
-	f.EventDeque.SendFirst(Event{
+	f.EventDeque.SendFirst(f.event(t, x, y, now))
+}
+
+// event returns the gesture event of type t, at x and y, of the gesture in
+// progress.
+func (f *EventFilter) event(t Type, x, y float32, now time.Time) Event {
+	e := Event{
 		Type:        t,
 		Drag:        f.drag,
 		LongPress:   f.longPress,
 		DoublePress: f.doublePress,
+		Pinch:       f.pinch,
+		Rotate:      f.rotate,
 		InitialPos:  f.initialPos,
 		CurrentPos: Point{
 			X: x,
 			Y: y,
 		},
-		// TODO: Velocity.
-		Time: now,
-	})
+		Velocity: f.velocity.velocity(now),
+		Scale:    1,
+		Time:     now,
+	}
+	if f.twoTouches {
+		e.Scale = f.scale
+		e.Rotation = f.rotation
+		e.Focus = f.focus
+	}
+	return e
 }
 
 func (f *EventFilter) sendAfter(e internalEvent, sleep time.Duration) {
@@ -213,8 +402,13 @@ func (f *EventFilter) end(x, y float32, now time.Time) {
 	f.drag = false
 	f.longPress = false
 	f.doublePress = false
+	f.pinch = false
+	f.rotate = false
 	f.initialPos = Point{}
 	f.pressButton = mouse.ButtonNone
+	f.touchPressed = false
+	f.twoTouches = false
+	f.velocity.reset()
 }
 
 // Filter filters the event. It can return e, a different event, or nil to
@@ -227,12 +421,12 @@ func (f *EventFilter) Filter(e interface{}) interface{} {
 			break
 		}
 
-		now := time.Now()
+		now := now()
 
 		switch e.typ {
 		case typeDoublePressSchedule:
 			e.typ = typeDoublePressResolve
-			go f.sendAfter(e, doublePressThreshold)
+			go f.sendAfter(e, f.Thresholds.doublePress())
 
 		case typeDoublePressResolve:
 			if e.pressCounter == f.pressCounter {
@@ -242,7 +436,7 @@ func (f *EventFilter) Filter(e interface{}) interface{} {
 
 		case typeLongPressSchedule:
 			e.typ = typeLongPressResolve
-			go f.sendAfter(e, longPressThreshold)
+			go f.sendAfter(e, f.Thresholds.longPress())
 
 		case typeLongPressResolve:
 			if e.pressCounter == f.pressCounter && !f.drag {
@@ -253,67 +447,235 @@ func (f *EventFilter) Filter(e interface{}) interface{} {
 		return nil
 
 	case mouse.Event:
-		now := time.Now()
+		now := f.eventTime()
 
 		switch e.Direction {
 		case mouse.DirNone:
-			if f.pressButton == mouse.ButtonNone {
-				break
-			}
-			startDrag := false
-			if !f.drag &&
-				(abs(e.X-f.initialPos.X) > dragThreshold || abs(e.Y-f.initialPos.Y) > dragThreshold) {
-				f.drag = true
-				startDrag = true
-			}
-			if f.drag {
-				f.sendFirst(TypeDrag, e.X, e.Y, now)
-			}
-			if startDrag {
-				f.sendFirst(TypeIsDrag, e.X, e.Y, now)
-			}
-
+			f.move(e.X, e.Y, now)
 		case mouse.DirPress:
-			if f.pressButton != mouse.ButtonNone {
-				break
-			}
+			f.press(e.Button, e.X, e.Y, now)
+		case mouse.DirRelease:
+			f.release(e.Button, e.X, e.Y, now)
+		}
 
-			oldInProgress := f.inProgress
-			oldDoublePress := f.doublePress
+	case touch.Event:
+		f.filterTouch(e, f.eventTime())
+	}
+	return e
+}
 
-			f.drag = false
-			f.longPress = false
-			f.doublePress = f.inProgress
-			f.initialPos = Point{e.X, e.Y}
-			f.pressButton = e.Button
-			f.pressCounter++
+// eventTime returns the time of the mouse or touch event being filtered. It is
+// the time that the platform reported, which velocities are estimated from
+// more precisely, if the EventDeque is a screen.Window, or otherwise now.
+func (f *EventFilter) eventTime() time.Time {
+	if w, ok := f.EventDeque.(interface{ EventTime() time.Time }); ok {
+		if t := w.EventTime(); !t.IsZero() {
+			return t
+		}
+	}
+	return now()
+}
+
+func (f *EventFilter) move(x, y float32, now time.Time) {
+	if f.pressButton == mouse.ButtonNone {
+		return
+	}
+	f.velocity.add(x, y, now)
+	startDrag := false
+	if drag := f.Thresholds.drag(); !f.drag &&
+		(abs(x-f.initialPos.X) > drag || abs(y-f.initialPos.Y) > drag) {
+		f.drag = true
+		startDrag = true
+	}
+	if f.drag {
+		f.sendFirst(TypeDrag, x, y, now)
+	}
+	if startDrag {
+		f.sendFirst(TypeIsDrag, x, y, now)
+	}
+}
+
+func (f *EventFilter) press(b mouse.Button, x, y float32, now time.Time) {
+	if f.pressButton != mouse.ButtonNone {
+		return
+	}
+
+	oldInProgress := f.inProgress
+	oldDoublePress := f.doublePress
+
+	f.drag = false
+	f.longPress = false
+	f.doublePress = f.inProgress
+	f.initialPos = Point{x, y}
+	f.pressButton = b
+	f.pressCounter++
+	f.velocity.reset()
+	f.velocity.add(x, y, now)
+
+	f.inProgress = true
+
+	f.sendFirst(typeLongPressSchedule, x, y, now)
+	if !oldDoublePress && f.doublePress {
+		f.sendFirst(TypeIsDoublePress, x, y, now)
+	}
+	if !oldInProgress {
+		f.sendFirst(TypeStart, x, y, now)
+	}
+}
 
-			f.inProgress = true
+func (f *EventFilter) release(b mouse.Button, x, y float32, now time.Time) {
+	if f.pressButton != b {
+		return
+	}
+	f.pressButton = mouse.ButtonNone
+	f.touchPressed = false
+	f.pressCounter++
 
-			f.sendFirst(typeLongPressSchedule, e.X, e.Y, now)
-			if !oldDoublePress && f.doublePress {
-				f.sendFirst(TypeIsDoublePress, e.X, e.Y, now)
+	if f.drag {
+		f.velocity.add(x, y, now)
+		f.endDrag(x, y, now)
+		return
+	}
+	f.sendFirst(typeDoublePressSchedule, x, y, now)
+	f.sendFirst(TypeTap, x, y, now)
+}
+
+// endDrag ends a drag, or a gesture of two touches, that has been released
+// at x and y, sending a TypeFling event first if it was moving fast enough. A
+// pinch or rotation can be flung too, by the moving midpoint of its touches.
+func (f *EventFilter) endDrag(x, y float32, now time.Time) {
+	fling := f.event(TypeFling, x, y, now)
+	f.end(x, y, now)
+	v := fling.Velocity
+	if (fling.Drag || fling.Pinch || fling.Rotate) && math.Hypot(float64(v.X), float64(v.Y)) >= float64(f.Thresholds.fling()) {
+		f.EventDeque.SendFirst(fling)
+	}
+}
+
+func (f *EventFilter) filterTouch(e touch.Event, now time.Time) {
+	p := Point{e.X, e.Y}
+	switch e.Type {
+	case touch.TypeBegin:
+		if f.touches == nil {
+			f.touches = map[touch.Sequence]Point{}
+		}
+		f.touches[e.Sequence] = p
+		switch len(f.touches) {
+		case 1:
+			if f.pressButton == mouse.ButtonNone {
+				f.press(mouse.ButtonLeft, e.X, e.Y, now)
+				f.pressTouch, f.touchPressed = e.Sequence, true
 			}
-			if !oldInProgress {
-				f.sendFirst(TypeStart, e.X, e.Y, now)
+		case 2:
+			if f.touchPressed {
+				f.beginTwoTouches(e.Sequence, now)
 			}
+		}
 
-		case mouse.DirRelease:
-			if f.pressButton != e.Button {
-				break
-			}
+	case touch.TypeMove:
+		if _, ok := f.touches[e.Sequence]; !ok {
+			return
+		}
+		f.touches[e.Sequence] = p
+		switch {
+		case f.twoTouches && (e.Sequence == f.pressTouch || e.Sequence == f.twoTouch):
+			f.moveTwoTouches(now)
+		case !f.twoTouches && f.touchPressed && e.Sequence == f.pressTouch:
+			f.move(e.X, e.Y, now)
+		}
+
+	case touch.TypeEnd:
+		if _, ok := f.touches[e.Sequence]; !ok {
+			return
+		}
+		delete(f.touches, e.Sequence)
+		switch {
+		case f.twoTouches && (e.Sequence == f.pressTouch || e.Sequence == f.twoTouch):
+			// The gesture ends when either touch ends. The other is then
+			// ignored, until it ends too.
 			f.pressButton = mouse.ButtonNone
+			f.touchPressed = false
 			f.pressCounter++
-
-			if f.drag {
-				f.end(e.X, e.Y, now)
-				break
-			}
-			f.sendFirst(typeDoublePressSchedule, e.X, e.Y, now)
-			f.sendFirst(TypeTap, e.X, e.Y, now)
+			f.endDrag(f.focus.X, f.focus.Y, now)
+		case !f.twoTouches && f.touchPressed && e.Sequence == f.pressTouch:
+			f.release(mouse.ButtonLeft, e.X, e.Y, now)
 		}
 	}
-	return e
+}
+
+// twoTouchPositions returns the positions of the two touches.
+func (f *EventFilter) twoTouchPositions() (a, b Point) {
+	return f.touches[f.pressTouch], f.touches[f.twoTouch]
+}
+
+func (f *EventFilter) beginTwoTouches(second touch.Sequence, now time.Time) {
+	f.twoTouch, f.twoTouches = second, true
+	a, b := f.twoTouchPositions()
+	f.startDist = distance(a, b)
+	f.startAngle = angle(a, b)
+	f.angle = f.startAngle
+	f.scale, f.rotation = 1, 0
+	f.focus = midpoint(a, b)
+	// The press is no longer a tap, nor a long press, of the first touch.
+	f.pressCounter++
+	f.initialPos = f.focus
+	f.velocity.reset()
+	f.velocity.add(f.focus.X, f.focus.Y, now)
+}
+
+func (f *EventFilter) moveTwoTouches(now time.Time) {
+	a, b := f.twoTouchPositions()
+	f.focus = midpoint(a, b)
+	f.velocity.add(f.focus.X, f.focus.Y, now)
+	dist := distance(a, b)
+	if f.startDist > 0 {
+		f.scale = dist / f.startDist
+	}
+	// The angle wraps around, so accumulate its changes, each of which is
+	// less than half a turn.
+	newAngle := angle(a, b)
+	delta := newAngle - f.angle
+	for delta > math.Pi {
+		delta -= 2 * math.Pi
+	}
+	for delta < -math.Pi {
+		delta += 2 * math.Pi
+	}
+	f.angle = newAngle
+	f.rotation += delta
+
+	startPinch := !f.pinch && abs(dist-f.startDist) > f.Thresholds.pinch()
+	startRotate := !f.rotate && abs(f.rotation) > f.Thresholds.rotate()
+	f.pinch = f.pinch || startPinch
+	f.rotate = f.rotate || startRotate
+
+	// SendFirst puts each event before those sent earlier, so the events
+	// are sent in the reverse of the order that they are received.
+	x, y := f.focus.X, f.focus.Y
+	if f.rotate {
+		f.sendFirst(TypeRotate, x, y, now)
+	}
+	if f.pinch {
+		f.sendFirst(TypePinch, x, y, now)
+	}
+	if startRotate {
+		f.sendFirst(TypeIsRotate, x, y, now)
+	}
+	if startPinch {
+		f.sendFirst(TypeIsPinch, x, y, now)
+	}
+}
+
+func distance(a, b Point) float32 {
+	return float32(math.Hypot(float64(b.X-a.X), float64(b.Y-a.Y)))
+}
+
+func angle(a, b Point) float32 {
+	return float32(math.Atan2(float64(b.Y-a.Y), float64(b.X-a.X)))
+}
+
+func midpoint(a, b Point) Point {
+	return Point{(a.X + b.X) / 2, (a.Y + b.Y) / 2}
 }
 
 func abs(x float32) float32 {
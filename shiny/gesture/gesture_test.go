@@ -0,0 +1,241 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gesture
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/touch"
+)
+
+// testDeque is a screen.EventDeque. The EventFilter's timers may send to it
+// from other goroutines.
+type testDeque struct {
+	mu     sync.Mutex
+	events []interface{}
+}
+
+func (q *testDeque) Send(e interface{}) {
+	q.mu.Lock()
+	q.events = append(q.events, e)
+	q.mu.Unlock()
+}
+
+func (q *testDeque) SendFirst(e interface{}) {
+	q.mu.Lock()
+	q.events = append([]interface{}{e}, q.events...)
+	q.mu.Unlock()
+}
+
+func (q *testDeque) NextEvent() interface{} { panic("unimplemented") }
+
+func (q *testDeque) pop() (interface{}, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.events) == 0 {
+		return nil, false
+	}
+	e := q.events[0]
+	q.events = q.events[1:]
+	return e, true
+}
+
+// tester runs an EventFilter's event loop, with a fake clock.
+type tester struct {
+	q testDeque
+	f *EventFilter
+	t time.Time
+}
+
+func newTester(t *testing.T) *tester {
+	tt := &tester{t: time.Unix(1e9, 0)}
+	tt.f = &EventFilter{
+		EventDeque: &tt.q,
+		// The long press timers never fire during the tests.
+		Thresholds: Thresholds{LongPress: time.Hour},
+	}
+	old := now
+	now = func() time.Time { return tt.t }
+	t.Cleanup(func() { now = old })
+	return tt
+}
+
+// send sends e, after advancing the clock by dt, and returns the gesture
+// events that the EventFilter sends, in the order they are received.
+func (tt *tester) send(dt time.Duration, e interface{}) (es []Event) {
+	tt.t = tt.t.Add(dt)
+	tt.q.Send(e)
+	for {
+		e, ok := tt.q.pop()
+		if !ok {
+			return es
+		}
+		if ge, ok := tt.f.Filter(e).(Event); ok {
+			es = append(es, ge)
+		}
+	}
+}
+
+func types(es []Event) []Type {
+	ts := make([]Type, len(es))
+	for i, e := range es {
+		ts[i] = e.Type
+	}
+	return ts
+}
+
+func TestVelocityTracker(t *testing.T) {
+	var v velocityTracker
+	t0 := time.Unix(1e9, 0)
+	// A point that moved slowly, long ago, and then at 1000 pixels per
+	// second right and 500 up.
+	v.add(0, 0, t0)
+	v.add(1, 0, t0.Add(50*time.Millisecond))
+	for i := 0; i <= 8; i++ {
+		d := time.Duration(i) * 10 * time.Millisecond
+		v.add(1+float32(i)*10, -float32(i)*5, t0.Add(time.Second+d))
+	}
+	got := v.velocity(t0.Add(time.Second + 80*time.Millisecond))
+	if math.Abs(float64(got.X-1000)) > 1 || math.Abs(float64(got.Y+500)) > 1 {
+		t.Errorf("velocity: got %v, want {1000 -500}", got)
+	}
+	// After the point stops, its old positions are forgotten.
+	if got := v.velocity(t0.Add(2 * time.Second)); got != (Point{}) {
+		t.Errorf("velocity after stopping: got %v, want zero", got)
+	}
+}
+
+func TestTap(t *testing.T) {
+	tt := newTester(t)
+	got := types(tt.send(0, mouse.Event{X: 5, Y: 5, Button: mouse.ButtonLeft, Direction: mouse.DirPress}))
+	want := []Type{TypeStart}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("press: got %v, want %v", got, want)
+	}
+	got = types(tt.send(0, mouse.Event{X: 6, Y: 5, Button: mouse.ButtonLeft, Direction: mouse.DirRelease}))
+	want = []Type{TypeTap}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("release: got %v, want %v", got, want)
+	}
+}
+
+func TestFling(t *testing.T) {
+	tt := newTester(t)
+	tt.send(0, touch.Event{X: 10, Y: 10, Sequence: 1, Type: touch.TypeBegin})
+	var got []Type
+	for i := 1; i <= 5; i++ {
+		got = append(got, types(tt.send(10*time.Millisecond, touch.Event{
+			X: 10 + float32(i)*20, Y: 10, Sequence: 1, Type: touch.TypeMove,
+		}))...)
+	}
+	want := []Type{TypeIsDrag, TypeDrag, TypeDrag, TypeDrag, TypeDrag, TypeDrag}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("moves: got %v, want %v", got, want)
+	}
+	es := tt.send(10*time.Millisecond, touch.Event{X: 130, Y: 10, Sequence: 1, Type: touch.TypeEnd})
+	if got, want := types(es), []Type{TypeFling, TypeEnd}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("end: got %v, want %v", got, want)
+	}
+	if v := es[0].Velocity; math.Abs(float64(v.X-2000)) > 1 || v.Y != 0 {
+		t.Errorf("fling velocity: got %v, want {2000 0}", v)
+	}
+
+	// A drag that stops before it ends is not a fling.
+	tt.send(time.Second, touch.Event{X: 10, Y: 10, Sequence: 2, Type: touch.TypeBegin})
+	tt.send(10*time.Millisecond, touch.Event{X: 50, Y: 10, Sequence: 2, Type: touch.TypeMove})
+	es = tt.send(time.Second, touch.Event{X: 50, Y: 10, Sequence: 2, Type: touch.TypeEnd})
+	if got, want := types(es), []Type{TypeEnd}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stopped end: got %v, want %v", got, want)
+	}
+}
+
+func TestPinchRotate(t *testing.T) {
+	tt := newTester(t)
+	tt.send(0, touch.Event{X: 100, Y: 100, Sequence: 1, Type: touch.TypeBegin})
+	tt.send(0, touch.Event{X: 200, Y: 100, Sequence: 2, Type: touch.TypeBegin})
+
+	// Spreading the touches apart is a pinch.
+	es := tt.send(10*time.Millisecond, touch.Event{X: 300, Y: 100, Sequence: 2, Type: touch.TypeMove})
+	if got, want := types(es), []Type{TypeIsPinch, TypePinch}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pinch: got %v, want %v", got, want)
+	}
+	e := es[1]
+	if e.Scale != 2 || e.Rotation != 0 || e.Focus != (Point{200, 100}) || e.InitialPos != (Point{150, 100}) {
+		t.Errorf("pinch: got scale %v, rotation %v, focus %v, initial %v, want 2, 0, {200 100}, {150 100}",
+			e.Scale, e.Rotation, e.Focus, e.InitialPos)
+	}
+
+	// Moving the second touch below the first turns them clockwise, by a
+	// quarter turn, keeping their distance.
+	es = tt.send(10*time.Millisecond, touch.Event{X: 100, Y: 300, Sequence: 2, Type: touch.TypeMove})
+	if got, want := types(es), []Type{TypeIsRotate, TypePinch, TypeRotate}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("rotate: got %v, want %v", got, want)
+	}
+	e = es[2]
+	if e.Scale != 2 || math.Abs(float64(e.Rotation-math.Pi/2)) > 1e-6 || !e.Pinch || !e.Rotate {
+		t.Errorf("rotate: got scale %v, rotation %v, pinch %t, rotate %t, want 2, π/2, true, true",
+			e.Scale, e.Rotation, e.Pinch, e.Rotate)
+	}
+
+	// Turning on past the half turn keeps adding up.
+	tt.send(10*time.Millisecond, touch.Event{X: -100, Y: 100, Sequence: 2, Type: touch.TypeMove})
+	es = tt.send(10*time.Millisecond, touch.Event{X: 100, Y: -100, Sequence: 2, Type: touch.TypeMove})
+	if e := es[len(es)-1]; math.Abs(float64(e.Rotation-3*math.Pi/2)) > 1e-6 {
+		t.Errorf("rotate past a half turn: got %v, want 3π/2", e.Rotation)
+	}
+
+	// The gesture ends when either touch ends, and the other is then
+	// ignored.
+	es = tt.send(time.Second, touch.Event{X: 100, Y: 100, Sequence: 1, Type: touch.TypeEnd})
+	if got, want := types(es), []Type{TypeEnd}; !reflect.DeepEqual(got, want) {
+		t.Errorf("end: got %v, want %v", got, want)
+	}
+	es = tt.send(0, touch.Event{X: 200, Y: 200, Sequence: 2, Type: touch.TypeMove})
+	es = append(es, tt.send(0, touch.Event{X: 200, Y: 200, Sequence: 2, Type: touch.TypeEnd})...)
+	if len(es) != 0 {
+		t.Errorf("remaining touch: got %v, want none", types(es))
+	}
+}
+
+// rightClickRecognizer is an app's Recognizer, that consumes right clicks.
+type rightClickRecognizer struct {
+	clicks int
+}
+
+func (r *rightClickRecognizer) Filter(e interface{}) interface{} {
+	if e, ok := e.(mouse.Event); ok && e.Button == mouse.ButtonRight {
+		if e.Direction == mouse.DirRelease {
+			r.clicks++
+		}
+		return nil
+	}
+	return e
+}
+
+func TestPipeline(t *testing.T) {
+	tt := newTester(t)
+	r := &rightClickRecognizer{}
+	p := Pipeline{r, tt.f}
+	for _, dir := range []mouse.Direction{mouse.DirPress, mouse.DirRelease} {
+		if got := p.Filter(mouse.Event{Button: mouse.ButtonRight, Direction: dir}); got != nil {
+			t.Errorf("right %v: got %v, want nil", dir, got)
+		}
+	}
+	if r.clicks != 1 {
+		t.Errorf("clicks: got %d, want 1", r.clicks)
+	}
+	if _, ok := tt.q.pop(); ok {
+		t.Errorf("EventFilter saw the right click")
+	}
+	e := mouse.Event{Button: mouse.ButtonLeft, Direction: mouse.DirPress}
+	if got := p.Filter(e); got != e {
+		t.Errorf("left press: got %v, want %v", got, e)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gesture
+
+import (
+	"time"
+)
+
+const (
+	// velocityHorizon is how old the positions that velocities are
+	// estimated from can be. Older positions say little about how fast
+	// something is moving now, such as after it stopped moving.
+	velocityHorizon = 100 * time.Millisecond
+
+	// velocitySamples is how many of the most recent positions are kept.
+	velocitySamples = 20
+)
+
+type velocitySample struct {
+	x, y float32
+	t    time.Time
+}
+
+// velocityTracker estimates the velocity of a moving point, from the least
+// squares line through its recent positions. This is what AOSP's
+// frameworks/native/libs/input/VelocityTracker.cpp calls its LSQ1 strategy.
+type velocityTracker struct {
+	// samples is a ring buffer of the n most recent positions, the newest
+	// of which is at samples[i].
+	samples [velocitySamples]velocitySample
+	i, n    int
+}
+
+func (v *velocityTracker) reset() {
+	v.i, v.n = 0, 0
+}
+
+func (v *velocityTracker) add(x, y float32, t time.Time) {
+	if v.n > 0 {
+		v.i = (v.i + 1) % velocitySamples
+	}
+	v.samples[v.i] = velocitySample{x, y, t}
+	if v.n < velocitySamples {
+		v.n++
+	}
+}
+
+// velocity returns the estimated velocity, in pixels per second, as of now,
+// or zero if there are too few recent positions to estimate it.
+func (v *velocityTracker) velocity(now time.Time) Point {
+	// The times are in seconds, relative to now, so that they are small
+	// enough to keep their precision as float64s.
+	var ts, xs, ys [velocitySamples]float64
+	m := 0
+	for j := 0; j < v.n; j++ {
+		s := &v.samples[(v.i-j+velocitySamples)%velocitySamples]
+		age := now.Sub(s.t)
+		if age > velocityHorizon {
+			break
+		}
+		ts[m], xs[m], ys[m] = -age.Seconds(), float64(s.x), float64(s.y)
+		m++
+	}
+	if m < 2 {
+		return Point{}
+	}
+
+	var meanT, meanX, meanY float64
+	for j := 0; j < m; j++ {
+		meanT += ts[j]
+		meanX += xs[j]
+		meanY += ys[j]
+	}
+	meanT /= float64(m)
+	meanX /= float64(m)
+	meanY /= float64(m)
+
+	var stt, stx, sty float64
+	for j := 0; j < m; j++ {
+		dt := ts[j] - meanT
+		stt += dt * dt
+		stx += dt * (xs[j] - meanX)
+		sty += dt * (ys[j] - meanY)
+	}
+	if stt == 0 {
+		// All of the positions are at the same time.
+		return Point{}
+	}
+	return Point{float32(stx / stt), float32(sty / stt)}
+}
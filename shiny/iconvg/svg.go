@@ -0,0 +1,263 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iconvg
+
+import (
+	"errors"
+	"strconv"
+)
+
+var errInvalidSVGPathData = errors.New("iconvg: invalid SVG path data")
+
+// SVGPath encodes a path whose geometry is the SVG path data d, the value of
+// an SVG path element's "d" attribute, such as "M 10 10 h 20 v 20 z". It is
+// like calling StartPath, then the drawing methods that correspond to d's
+// commands, then ClosePathEndPath. The co-ordinates of d are in the same
+// co-ordinate space as the Metadata's ViewBox, so that an SVG graphic whose
+// viewBox is that ViewBox converts without any further transformation. An
+// arc's x-axis rotation is in degrees, as for SVG, not in turns, as for the
+// AbsArcTo and RelArcTo methods.
+//
+// As every IconVG path is filled, an SVG subpath that is not explicitly
+// closed is implicitly closed, just as SVG fills it.
+//
+// An SVG graphic can be converted to IconVG by calling Reset with the
+// graphic's view box, then, for each path, setting a color register with
+// SetCReg or a gradient with SetLinearGradient and friends, and calling
+// SVGPath with that path's data.
+func (e *Encoder) SVGPath(adj uint8, d string) error {
+	p := svgParser{s: d}
+	var (
+		op           byte
+		started      bool
+		closed       bool
+		start, pen   [2]float32
+		args         [7]float32
+		flag0, flag1 bool
+	)
+	for {
+		p.skipSpace()
+		if p.i == len(p.s) {
+			break
+		}
+		if c := p.s[p.i]; isSVGCommand(c) {
+			op = c
+			p.i++
+		} else if op == 0 || op == 'Z' || op == 'z' {
+			return errInvalidSVGPathData
+		} else if op == 'M' {
+			// Co-ordinates after a moveto are implicit linetos.
+			op = 'L'
+		} else if op == 'm' {
+			op = 'l'
+		}
+
+		nArgs, iFlags := svgNArgs(op), -1
+		if op == 'A' || op == 'a' {
+			iFlags = 3
+		}
+		for i := 0; i < nArgs; i++ {
+			p.skipSpace()
+			if iFlags >= 0 && (i == iFlags || i == iFlags+1) {
+				f, ok := p.flag()
+				if !ok {
+					return errInvalidSVGPathData
+				}
+				if i == iFlags {
+					flag0 = f
+				} else {
+					flag1 = f
+				}
+				continue
+			}
+			f, ok := p.number()
+			if !ok {
+				return errInvalidSVGPathData
+			}
+			args[i] = f
+		}
+
+		relative := 'a' <= op && op <= 'z'
+		if op == 'Z' || op == 'z' {
+			closed, pen = true, start
+			continue
+		}
+		if op == 'M' || op == 'm' {
+			x, y := args[0], args[1]
+			if relative {
+				x, y = pen[0]+x, pen[1]+y
+			}
+			if !started {
+				started = true
+				e.StartPath(adj, x, y)
+			} else {
+				e.ClosePathAbsMoveTo(x, y)
+			}
+			closed, start, pen = false, [2]float32{x, y}, [2]float32{x, y}
+			continue
+		}
+		if !started {
+			return errInvalidSVGPathData
+		}
+		if closed {
+			// SVG continues from the start of the closed subpath.
+			e.ClosePathAbsMoveTo(start[0], start[1])
+			closed = false
+		}
+
+		// Track the pen, the end point of each command.
+		switch op {
+		case 'H':
+			pen[0] = args[0]
+		case 'h':
+			pen[0] += args[0]
+		case 'V':
+			pen[1] = args[0]
+		case 'v':
+			pen[1] += args[0]
+		default:
+			x, y := args[nArgs-2], args[nArgs-1]
+			if relative {
+				pen[0], pen[1] = pen[0]+x, pen[1]+y
+			} else {
+				pen[0], pen[1] = x, y
+			}
+		}
+
+		switch op {
+		case 'L':
+			e.AbsLineTo(args[0], args[1])
+		case 'l':
+			e.RelLineTo(args[0], args[1])
+		case 'H':
+			e.AbsHLineTo(args[0])
+		case 'h':
+			e.RelHLineTo(args[0])
+		case 'V':
+			e.AbsVLineTo(args[0])
+		case 'v':
+			e.RelVLineTo(args[0])
+		case 'T':
+			e.AbsSmoothQuadTo(args[0], args[1])
+		case 't':
+			e.RelSmoothQuadTo(args[0], args[1])
+		case 'Q':
+			e.AbsQuadTo(args[0], args[1], args[2], args[3])
+		case 'q':
+			e.RelQuadTo(args[0], args[1], args[2], args[3])
+		case 'S':
+			e.AbsSmoothCubeTo(args[0], args[1], args[2], args[3])
+		case 's':
+			e.RelSmoothCubeTo(args[0], args[1], args[2], args[3])
+		case 'C':
+			e.AbsCubeTo(args[0], args[1], args[2], args[3], args[4], args[5])
+		case 'c':
+			e.RelCubeTo(args[0], args[1], args[2], args[3], args[4], args[5])
+		case 'A':
+			e.AbsArcTo(args[0], args[1], args[2]/360, flag0, flag1, args[5], args[6])
+		case 'a':
+			e.RelArcTo(args[0], args[1], args[2]/360, flag0, flag1, args[5], args[6])
+		}
+	}
+	if !started {
+		return errInvalidSVGPathData
+	}
+	e.ClosePathEndPath()
+	return nil
+}
+
+func isSVGCommand(c byte) bool {
+	return svgNArgs(c) >= 0
+}
+
+// svgNArgs returns the number of arguments of the SVG path command c, or -1
+// if c is not a command.
+func svgNArgs(c byte) int {
+	switch c {
+	case 'Z', 'z':
+		return 0
+	case 'H', 'h', 'V', 'v':
+		return 1
+	case 'M', 'm', 'L', 'l', 'T', 't':
+		return 2
+	case 'Q', 'q', 'S', 's':
+		return 4
+	case 'C', 'c':
+		return 6
+	case 'A', 'a':
+		return 7
+	}
+	return -1
+}
+
+// svgParser scans the numbers and flags of SVG path data.
+type svgParser struct {
+	s string
+	i int
+}
+
+// skipSpace skips white space and at most one comma.
+func (p *svgParser) skipSpace() {
+	comma := false
+	for ; p.i < len(p.s); p.i++ {
+		switch p.s[p.i] {
+		case ' ', '\t', '\n', '\r', '\f':
+		case ',':
+			if comma {
+				return
+			}
+			comma = true
+		default:
+			return
+		}
+	}
+}
+
+// number scans a number, such as "-1.5e3". Numbers need not be separated if
+// that is unambiguous, as in "1-2" or "0.5.5".
+func (p *svgParser) number() (float32, bool) {
+	i, s := p.i, p.s
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits, dot := 0, false
+	for ; i < len(s); i++ {
+		if c := s[i]; '0' <= c && c <= '9' {
+			digits++
+		} else if c == '.' && !dot {
+			dot = true
+		} else {
+			break
+		}
+	}
+	if digits == 0 {
+		return 0, false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && '0' <= s[j] && s[j] <= '9' {
+			for i = j; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+			}
+		}
+	}
+	f, err := strconv.ParseFloat(s[p.i:i], 32)
+	if err != nil {
+		return 0, false
+	}
+	p.i = i
+	return float32(f), true
+}
+
+// flag scans an arc flag, a single "0" or "1".
+func (p *svgParser) flag() (bool, bool) {
+	if p.i == len(p.s) || (p.s[p.i] != '0' && p.s[p.i] != '1') {
+		return false, false
+	}
+	p.i++
+	return p.s[p.i-1] == '1', true
+}
@@ -0,0 +1,66 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iconvg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSVGPath(t *testing.T) {
+	var got Encoder
+	err := got.SVGPath(0, "M-8-8 h16, v16 H-8 z m4 4 l4,0 0 4a2 2 90 01-4 0z M1e1,2.5.5-1 Q 1 2 3 4 T 5 6 C1 2 3 4 5 6 S 1 2 3 4")
+	if err != nil {
+		t.Fatalf("SVGPath: %v", err)
+	}
+	gotBytes, err := got.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+
+	var want Encoder
+	want.StartPath(0, -8, -8)
+	want.RelHLineTo(16)
+	want.RelVLineTo(16)
+	want.AbsHLineTo(-8)
+	// The "m" is relative to the start of the closed subpath.
+	want.ClosePathAbsMoveTo(-4, -4)
+	want.RelLineTo(4, 0)
+	want.RelLineTo(0, 4)
+	want.RelArcTo(2, 2, 0.25, false, true, -4, 0)
+	want.ClosePathAbsMoveTo(10, 2.5)
+	// The implicit lineto after a moveto.
+	want.AbsLineTo(0.5, -1)
+	want.AbsQuadTo(1, 2, 3, 4)
+	want.AbsSmoothQuadTo(5, 6)
+	want.AbsCubeTo(1, 2, 3, 4, 5, 6)
+	want.AbsSmoothCubeTo(1, 2, 3, 4)
+	want.ClosePathEndPath()
+	wantBytes, err := want.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+
+	if !bytes.Equal(gotBytes, wantBytes) {
+		t.Errorf("got  % x\nwant % x", gotBytes, wantBytes)
+	}
+}
+
+func TestSVGPathInvalid(t *testing.T) {
+	testCases := []string{
+		"",
+		"L 1 2",
+		"M 1",
+		"M 1 2 z 3 4",
+		"M 1 2 A 1 1 0 2 0 3 4",
+		"M 1 2 X",
+	}
+	for _, tc := range testCases {
+		var e Encoder
+		if err := e.SVGPath(0, tc); err == nil {
+			t.Errorf("%q: got nil error, want non-nil", tc)
+		}
+	}
+}
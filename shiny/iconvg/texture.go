@@ -0,0 +1,43 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iconvg
+
+import (
+	"image"
+	"image/draw"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// NewTexture returns a new Texture of s that holds the IconVG graphic src,
+// rasterized at size, in pixels.
+//
+// The size should be that at which the Texture is drawn, in the screen's
+// pixels, such as a theme's Pixels of the icon's size in device-independent
+// units. An IconVG graphic is scalable, so rasterizing it at the destination
+// scale instead of scaling a Texture rasterized at another size keeps its
+// edges crisp on high-DPI screens, and lets its levels of detail apply.
+func NewTexture(s screen.Screen, src []byte, size image.Point, opts *DecodeOptions) (screen.Texture, error) {
+	buf, err := s.NewBuffer(size)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Release()
+
+	m := buf.RGBA()
+	draw.Draw(m, m.Bounds(), image.Transparent, image.Point{}, draw.Src)
+	var z Rasterizer
+	z.SetDstImage(m, m.Bounds(), draw.Over)
+	if err := Decode(&z, src, opts); err != nil {
+		return nil, err
+	}
+
+	tex, err := s.NewTexture(size)
+	if err != nil {
+		return nil, err
+	}
+	tex.Upload(image.Point{}, buf, buf.Bounds())
+	return tex, nil
+}
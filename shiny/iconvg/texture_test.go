@@ -0,0 +1,52 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iconvg
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/exp/shiny/driver/headlessdriver"
+)
+
+func TestNewTexture(t *testing.T) {
+	// The left half of the view box is filled in the default palette's
+	// first color, opaque black.
+	var e Encoder
+	if err := e.SVGPath(0, "M-32-32 H0 V32 H-32 z"); err != nil {
+		t.Fatalf("SVGPath: %v", err)
+	}
+	src, err := e.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+
+	// Rasterized at 4 times the size, the edge is still sharp.
+	for _, size := range []image.Point{{8, 8}, {32, 32}} {
+		tex, err := NewTexture(headlessdriver.NewScreen(), src, size, nil)
+		if err != nil {
+			t.Fatalf("size %v: NewTexture: %v", size, err)
+		}
+		if got := tex.Size(); got != size {
+			t.Errorf("size %v: got size %v", size, got)
+		}
+		m, err := tex.Download(image.Point{}, tex.Bounds())
+		if err != nil {
+			t.Fatalf("size %v: Download: %v", size, err)
+		}
+		for y := 0; y < size.Y; y++ {
+			for x := 0; x < size.X; x++ {
+				want := uint8(0)
+				if x < size.X/2 {
+					want = 0xff
+				}
+				if got := m.RGBAAt(x, y).A; got != want {
+					t.Errorf("size %v: (%d, %d): got alpha %#02x, want %#02x", size, x, y, got, want)
+				}
+			}
+		}
+		tex.Release()
+	}
+}
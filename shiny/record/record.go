@@ -0,0 +1,324 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package record records the events of a screen.Window, with the times that
+// they happened, and replays them into a window, such as one of the
+// headlessdriver's, so that UI interactions can be captured once and turned
+// into regression tests.
+//
+// A recording is a sequence of entries, encoded one per line as a JSON
+// object that holds the entry's time, its event's registered type name, and
+// the event itself:
+//
+//	{"time":1500000000,"type":"mouse.Event","event":{"X":10,"Y":20,...}}
+//
+// Events of unregistered types, such as a driver's or package's internal
+// events, are not recorded.
+package record // import "golang.org/x/exp/shiny/record"
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/lifecycle"
+	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/paint"
+	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/event/touch"
+)
+
+// TODO: record the window's published frames, so that a replay can be
+// checked against them?
+
+// Entry is a recorded event.
+type Entry struct {
+	// Time is when the event happened, as the window's EventTime reported
+	// it, relative to the start of the recording. It is negative for an
+	// event that happened before the recording started, but was returned
+	// by NextEvent after.
+	Time time.Duration
+
+	// Event is the event, whose type is registered.
+	Event interface{}
+}
+
+var (
+	registryMu sync.RWMutex
+	nameToType = map[string]reflect.Type{}
+	typeToName = map[reflect.Type]string{}
+)
+
+// Register records the type of e, an event value, under the given name, so
+// that events of that type can be recorded and replayed. Like
+// encoding/gob.RegisterName, it panics if the type or the name is already
+// registered.
+//
+// The key, lifecycle, mouse, paint, size and touch events, and those of the
+// screen package, are already registered, except for screen.UploadEvent,
+// whose Buffer cannot be recreated on replay. Other packages' events, such as
+// the gesture and gamepad packages', must be registered before they can be
+// recorded. The type should be encodable by encoding/json.
+func Register(name string, e interface{}) {
+	t := reflect.TypeOf(e)
+	if t == nil {
+		panic("record: Register of nil event")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := nameToType[name]; ok {
+		panic(fmt.Sprintf("record: registering duplicate name %q", name))
+	}
+	if _, ok := typeToName[t]; ok {
+		panic(fmt.Sprintf("record: registering duplicate type %v", t))
+	}
+	nameToType[name] = t
+	typeToName[t] = name
+}
+
+func init() {
+	Register("key.Event", key.Event{})
+	Register("lifecycle.Event", lifecycle.Event{})
+	Register("mouse.Event", mouse.Event{})
+	Register("paint.Event", paint.Event{})
+	Register("size.Event", size.Event{})
+	Register("touch.Event", touch.Event{})
+
+	Register("screen.AccessActionEvent", screen.AccessActionEvent{})
+	Register("screen.AccessibilityEvent", screen.AccessibilityEvent{})
+	Register("screen.CloseRequestEvent", screen.CloseRequestEvent{})
+	Register("screen.ColorSchemeEvent", screen.ColorSchemeEvent{})
+	Register("screen.CrossingEvent", screen.CrossingEvent{})
+	Register("screen.DeviceLostEvent", screen.DeviceLostEvent{})
+	Register("screen.DisplayEvent", screen.DisplayEvent{})
+	Register("screen.DragEvent", screen.DragEvent{})
+	Register("screen.FileDialogEvent", screen.FileDialogEvent{})
+	Register("screen.FocusEvent", screen.FocusEvent{})
+	Register("screen.FrameEvent", screen.FrameEvent{})
+	Register("screen.HotKeyEvent", screen.HotKeyEvent{})
+	Register("screen.KeyEvent", screen.KeyEvent{})
+	Register("screen.KeyboardLayoutEvent", screen.KeyboardLayoutEvent{})
+	Register("screen.MenuEvent", screen.MenuEvent{})
+	Register("screen.NotificationEvent", screen.NotificationEvent{})
+	Register("screen.PenEvent", screen.PenEvent{})
+	Register("screen.RelativeMouseEvent", screen.RelativeMouseEvent{})
+	Register("screen.ScaleEvent", screen.ScaleEvent{})
+	Register("screen.ScrollEvent", screen.ScrollEvent{})
+	Register("screen.TextEvent", screen.TextEvent{})
+	Register("screen.TrayEvent", screen.TrayEvent{})
+	Register("screen.VisibilityEvent", screen.VisibilityEvent{})
+	Register("screen.WindowStateEvent", screen.WindowStateEvent{})
+}
+
+// fileDialogEvent is the encoding of a screen.FileDialogEvent, whose Err
+// cannot be encoded as is. Replaying it gives an Err with the same message,
+// but not the same value.
+type fileDialogEvent struct {
+	ID    int
+	Kind  screen.FileDialogKind
+	Paths []string
+	Err   string `json:",omitempty"`
+}
+
+// wireEntry is the encoding of an Entry.
+type wireEntry struct {
+	Time  time.Duration   `json:"time"`
+	Type  string          `json:"type"`
+	Event json.RawMessage `json:"event"`
+}
+
+// ErrUnregistered is returned by Encoder.Encode for an event of an
+// unregistered type.
+var ErrUnregistered = errors.New("record: unregistered event type")
+
+// Encoder writes entries to an output stream.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes e, followed by a newline.
+func (c *Encoder) Encode(e Entry) error {
+	registryMu.RLock()
+	name, ok := typeToName[reflect.TypeOf(e.Event)]
+	registryMu.RUnlock()
+	if !ok {
+		return ErrUnregistered
+	}
+
+	v := e.Event
+	switch x := v.(type) {
+	case lifecycle.Event:
+		// The DrawContext, such as a gl.Context, is only valid for the
+		// recorded session.
+		x.DrawContext = nil
+		v = x
+	case screen.FileDialogEvent:
+		y := fileDialogEvent{ID: x.ID, Kind: x.Kind, Paths: x.Paths}
+		if x.Err != nil {
+			y.Err = x.Err.Error()
+		}
+		v = y
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b, err = json.Marshal(wireEntry{Time: e.Time, Type: name, Event: b})
+	if err != nil {
+		return err
+	}
+	_, err = c.w.Write(append(b, '\n'))
+	return err
+}
+
+// Decoder reads entries from an input stream.
+type Decoder struct {
+	d *json.Decoder
+}
+
+// NewDecoder returns a new Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{d: json.NewDecoder(bufio.NewReader(r))}
+}
+
+// Decode returns the next entry. It returns io.EOF at the end of the input.
+func (c *Decoder) Decode() (Entry, error) {
+	var w wireEntry
+	if err := c.d.Decode(&w); err != nil {
+		return Entry{}, err
+	}
+	registryMu.RLock()
+	t, ok := nameToType[w.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return Entry{}, fmt.Errorf("record: unregistered event type %q", w.Type)
+	}
+
+	if t == reflect.TypeOf(screen.FileDialogEvent{}) {
+		var y fileDialogEvent
+		if err := json.Unmarshal(w.Event, &y); err != nil {
+			return Entry{}, err
+		}
+		x := screen.FileDialogEvent{ID: y.ID, Kind: y.Kind, Paths: y.Paths}
+		if y.Err != "" {
+			x.Err = errors.New(y.Err)
+		}
+		return Entry{Time: w.Time, Event: x}, nil
+	}
+	p := reflect.New(t)
+	if err := json.Unmarshal(w.Event, p.Interface()); err != nil {
+		return Entry{}, err
+	}
+	return Entry{Time: w.Time, Event: p.Elem().Interface()}, nil
+}
+
+// ReadAll returns all of the entries that r holds.
+func ReadAll(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	d := NewDecoder(r)
+	for {
+		e, err := d.Decode()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, e)
+	}
+}
+
+// Recorder records a window's events.
+type Recorder struct {
+	w      screen.Window
+	start  time.Time
+	remove func()
+
+	mu   sync.Mutex
+	enc  *Encoder
+	err  error
+	done bool
+}
+
+// Start starts recording the events of w to dst, until Stop is called.
+//
+// The events are recorded by an event filter, as they are returned by w's
+// NextEvent, so the filters added before it may change or consume them, and
+// those added after it do not. To record the events as the driver sent them,
+// start recording before adding any other filter.
+func Start(w screen.Window, dst io.Writer) *Recorder {
+	r := &Recorder{
+		w:     w,
+		start: time.Now(),
+		enc:   NewEncoder(dst),
+	}
+	r.remove = w.AddEventFilter(r.filter)
+	return r
+}
+
+func (r *Recorder) filter(e interface{}) interface{} {
+	t := r.w.EventTime().Sub(r.start)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done || r.err != nil {
+		return e
+	}
+	if err := r.enc.Encode(Entry{Time: t, Event: e}); err != nil && err != ErrUnregistered {
+		r.err = err
+	}
+	return e
+}
+
+// Stop stops recording, returning the first error, if any, that writing the
+// recording returned.
+func (r *Recorder) Stop() error {
+	r.remove()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done = true
+	return r.err
+}
+
+// ReplayOptions are optional arguments to Replay.
+type ReplayOptions struct {
+	// Speed is how fast to replay the entries, relative to how fast they
+	// were recorded, so that 1 replays them in real time and 2 at double
+	// speed. Zero, the default, sends every entry at once, without waiting,
+	// which is what deterministic tests usually want.
+	Speed float64
+}
+
+// Replay sends the events of entries, in order, to dst, which is typically a
+// Window. Replay only sends the events. It does not change the window, so that
+// replaying a size.Event, for example, does not resize it.
+//
+// The nil *ReplayOptions means to use the default options.
+func Replay(dst screen.EventDeque, entries []Entry, opts *ReplayOptions) {
+	var speed float64
+	if opts != nil {
+		speed = opts.Speed
+	}
+	start := time.Now()
+	for _, e := range entries {
+		if speed > 0 {
+			at := start.Add(time.Duration(float64(e.Time) / speed))
+			if d := at.Sub(time.Now()); d > 0 {
+				time.Sleep(d)
+			}
+		}
+		dst.Send(e.Event)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package record
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/shiny/driver/headlessdriver"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/lifecycle"
+	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/size"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	entries := []Entry{
+		{Time: 0, Event: lifecycle.Event{To: lifecycle.StageFocused}},
+		{Time: 5 * time.Millisecond, Event: size.Event{WidthPx: 32, HeightPx: 16, PixelsPerPt: 2}},
+		{Time: 10 * time.Millisecond, Event: mouse.Event{X: 1.5, Y: 2, Button: mouse.ButtonLeft, Direction: mouse.DirPress}},
+		{Time: 15 * time.Millisecond, Event: key.Event{Rune: 'a', Code: key.CodeA, Direction: key.DirPress}},
+		{Time: 20 * time.Millisecond, Event: screen.DragEvent{Type: screen.Drop, Data: screen.DragData{Files: []string{"/tmp/x"}}}},
+		{Time: 25 * time.Millisecond, Event: screen.FileDialogEvent{ID: 2, Err: errors.New("boom")}},
+		{Time: 30 * time.Millisecond, Event: screen.KeyEvent{Event: key.Event{Rune: 'a', Code: key.CodeQ, Direction: key.DirPress}, Physical: key.CodeQ, Scancode: 24, Unmodified: 'a'}},
+	}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			t.Fatalf("Encode(%#v): %v", e, err)
+		}
+	}
+	if err := enc.Encode(Entry{Event: struct{}{}}); err != ErrUnregistered {
+		t.Errorf("Encode of an unregistered event: got %v, want ErrUnregistered", err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != len(entries) {
+		t.Errorf("got %d lines, want %d", n, len(entries))
+	}
+
+	got, err := ReadAll(&buf)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("got %d entries, want %d", len(got), len(entries))
+	}
+	for i := range got {
+		g, w := got[i], entries[i]
+		if fd, ok := w.Event.(screen.FileDialogEvent); ok {
+			gd, ok := g.Event.(screen.FileDialogEvent)
+			if !ok || gd.ID != fd.ID || gd.Err == nil || gd.Err.Error() != fd.Err.Error() {
+				t.Errorf("entry %d: got %#v, want %#v", i, g.Event, w.Event)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(g, w) {
+			t.Errorf("entry %d: got %#v, want %#v", i, g, w)
+		}
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	s := headlessdriver.NewScreen()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 32, Height: 16})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+
+	var buf bytes.Buffer
+	r := Start(w, &buf)
+	w.Send(mouse.Event{X: 3, Y: 4, Button: mouse.ButtonLeft, Direction: mouse.DirPress})
+	w.Send(struct{}{})
+	w.Send(key.Event{Rune: 'q', Code: key.CodeQ, Direction: key.DirRelease})
+	var (
+		want      []interface{}
+		wantTimes []time.Duration
+	)
+	for i := 0; i < 7; i++ {
+		e := w.NextEvent()
+		if _, ok := e.(struct{}); !ok {
+			want = append(want, e)
+			wantTimes = append(wantTimes, w.EventTime().Sub(r.start))
+		}
+	}
+	if err := r.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	w.Send(mouse.Event{X: 5})
+	w.NextEvent()
+
+	entries, err := ReadAll(&buf)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i := range entries {
+		if entries[i].Time != wantTimes[i] {
+			t.Errorf("entry %d: time %v, want the event's time %v", i, entries[i].Time, wantTimes[i])
+		}
+		if i > 0 && entries[i].Time < entries[i-1].Time {
+			t.Errorf("entry %d: time %v is before entry %d's %v", i, entries[i].Time, i-1, entries[i-1].Time)
+		}
+	}
+
+	w2, err := s.NewWindow(&screen.NewWindowOptions{Width: 32, Height: 16})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w2.Release()
+	// Skip w2's own initial lifecycle, focus, size and paint events.
+	for i := 0; i < 4; i++ {
+		w2.NextEvent()
+	}
+	Replay(w2, entries, nil)
+	for i, wantE := range want {
+		if got := w2.NextEvent(); !reflect.DeepEqual(got, wantE) {
+			t.Errorf("replayed event %d: got %#v, want %#v", i, got, wantE)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package screen
+
+import (
+	"image"
+	"image/draw"
+
+	"golang.org/x/image/math/f64"
+)
+
+// Quad is one of the sub-Textures drawn by DrawBatch. Its fields are the
+// corresponding arguments of the Draw method of the Drawer interface.
+type Quad struct {
+	Src2Dst f64.Aff3
+	SR      image.Rectangle
+}
+
+// BatchDrawer is implemented by Drawers, such as GPU-backed Windows, that can
+// draw many sub-Textures of one Texture at a cost much lower than that of as
+// many Draw calls, such as by issuing a single GPU draw call.
+type BatchDrawer interface {
+	// DrawBatch draws each of quads, in order, as if by calling Draw with
+	// src, that quad's Src2Dst and SR fields, op and opts.
+	//
+	// The quads slice may be re-used once DrawBatch returns.
+	DrawBatch(src Texture, quads []Quad, op draw.Op, opts *DrawOptions)
+}
+
+// DrawBatch draws each of quads, in order, as if by calling Draw with src,
+// that quad's Src2Dst and SR fields, op and opts.
+//
+// If d implements BatchDrawer, its DrawBatch method is called. Otherwise,
+// d's Draw method is called once per quad.
+func DrawBatch(d Drawer, src Texture, quads []Quad, op draw.Op, opts *DrawOptions) {
+	if bd, ok := d.(BatchDrawer); ok {
+		bd.DrawBatch(src, quads, op, opts)
+		return
+	}
+	for _, q := range quads {
+		d.Draw(q.Src2Dst, src, q.SR, op, opts)
+	}
+}
@@ -0,0 +1,20 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package screen
+
+// ColorSpace is a color space: the colors of the red, green and blue
+// primaries and of the white point that a pixel's channels are relative to.
+// Every ColorSpace has the sRGB transfer function, so that pixels are
+// sRGB-encoded whatever their ColorSpace.
+type ColorSpace uint8
+
+const (
+	// ColorSpaceSRGB is sRGB, the color space of most displays and images,
+	// and of every Window unless set otherwise.
+	ColorSpaceSRGB ColorSpace = iota
+	// ColorSpaceDisplayP3 has the wider DCI-P3 primaries, and the D65 white
+	// point, of Apple's wide gamut displays.
+	ColorSpaceDisplayP3
+)
@@ -0,0 +1,116 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package screen
+
+import (
+	"errors"
+	"image"
+)
+
+// ErrUnsupportedFormat is returned by NewTextureWithOptions when the Screen
+// cannot create Textures of the requested format.
+var ErrUnsupportedFormat = errors.New("screen: unsupported texture format")
+
+// CompressedFormat is the block compression of a compressed Texture's
+// pixels, which the GPU decodes as it samples them. Every format encodes
+// blocks of 4x4 pixels, in 8 or 16 bytes, compared to the 64 bytes of those
+// pixels in PixelFormatRGBA8. Like every Texture's pixels, the encoded colors
+// are alpha-premultiplied.
+//
+// Desktop GPUs typically support the BC formats, and mobile ones, including
+// Apple silicon, the ETC2 and ASTC formats. A CompressedScreen's
+// CompressedFormats method reports which are supported.
+type CompressedFormat uint8
+
+const (
+	// CompressedNone means that a Texture is not compressed.
+	CompressedNone CompressedFormat = iota
+	// CompressedBC1 is BC1, also known as DXT1: opaque RGB, or RGB with 1
+	// bit of alpha, in 8 bytes per block.
+	CompressedBC1
+	// CompressedBC3 is BC3, also known as DXT5: RGBA in 16 bytes per block.
+	CompressedBC3
+	// CompressedETC2RGB8 is ETC2: opaque RGB in 8 bytes per block. It can
+	// also decode ETC1 data.
+	CompressedETC2RGB8
+	// CompressedETC2RGBA8 is ETC2 with EAC alpha: RGBA in 16 bytes per
+	// block.
+	CompressedETC2RGBA8
+	// CompressedASTC4x4 is ASTC, with the low dynamic range profile and 4x4
+	// blocks: RGBA in 16 bytes per block.
+	CompressedASTC4x4
+)
+
+// BlockSize returns the size, in pixels, of the blocks of f. It is (1, 1)
+// for CompressedNone.
+func (f CompressedFormat) BlockSize() image.Point {
+	if f == CompressedNone {
+		return image.Point{1, 1}
+	}
+	return image.Point{4, 4}
+}
+
+// BlockBytes returns the number of bytes per block of f. It is zero for
+// CompressedNone.
+func (f CompressedFormat) BlockBytes() int {
+	switch f {
+	case CompressedBC1, CompressedETC2RGB8:
+		return 8
+	case CompressedBC3, CompressedETC2RGBA8, CompressedASTC4x4:
+		return 16
+	}
+	return 0
+}
+
+// CompressedImage is a rectangle of block-compressed pixels, such as a mipmap
+// level loaded from a KTX or DDS file. Its blocks are in rows, each row of
+// blocks starting Stride bytes after the previous one. The block whose top
+// left pixel is (x, y) starts at Pix[PixOffset(x, y)]. Blocks at the right
+// and bottom edges may extend beyond Rect.
+type CompressedImage struct {
+	Format CompressedFormat
+	Pix    []byte
+	Stride int
+	Rect   image.Rectangle
+}
+
+// Bounds returns the bounds of the image.
+func (m *CompressedImage) Bounds() image.Rectangle {
+	return m.Rect
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds
+// to the block that contains the pixel at (x, y).
+func (m *CompressedImage) PixOffset(x, y int) int {
+	b := m.Format.BlockSize()
+	return (y-m.Rect.Min.Y)/b.Y*m.Stride + (x-m.Rect.Min.X)/b.X*m.Format.BlockBytes()
+}
+
+// CompressedScreen is implemented by Screens that can create compressed
+// Textures, by setting NewTextureOptions.Compressed. The gldriver, the
+// mtldriver and the d3ddriver implement it.
+type CompressedScreen interface {
+	// CompressedFormats returns the CompressedFormats of the Textures that
+	// the Screen can create, which depend on the GPU. It may be empty.
+	CompressedFormats() []CompressedFormat
+}
+
+// CompressedUploader is implemented by the Textures of CompressedScreens. A
+// compressed Texture's pixels can only be set by UploadCompressed: its other
+// upload methods, such as Upload and Fill, do nothing, it cannot be drawn on,
+// and its Download method returns an error. Conversely, UploadCompressed does
+// nothing to an uncompressed Texture.
+type CompressedUploader interface {
+	// UploadCompressed uploads the sub-CompressedImage defined by src and sr
+	// to the destination (the method receiver), such that sr.Min in
+	// src-space aligns with dp in dst-space. The destination's contents are
+	// overwritten; the draw operator is implicitly draw.Src.
+	//
+	// Whole blocks are uploaded, without decoding them, and so src.Format
+	// must match the Texture's, and, after clipping, the uploaded rectangle
+	// must be aligned to blocks, in both src and dst, except where it meets
+	// the Texture's right or bottom edge. Other uploads are ignored.
+	UploadCompressed(dp image.Point, src *CompressedImage, sr image.Rectangle)
+}
@@ -0,0 +1,313 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package screen
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/math/f64"
+)
+
+// Context is an immediate-mode drawing context for a Window, obtained by
+// calling the Window's Begin method. It holds a current color, line width,
+// transform and clip stack, and draws using the Window's Drawer methods, so
+// that simple visualizations need not manage transformation matrices by hand.
+//
+// If the Window implements Clipper, clipping is delegated to it, and is
+// exact for any transform.
+//
+// A Context's methods draw using the draw.Over operator. A Context is not
+// safe for concurrent use, and should not be used after calling End.
+type Context struct {
+	w       Window
+	clipper Clipper
+
+	color     color.Color
+	lineWidth int
+	// transform maps from user space, the co-ordinates passed to methods like
+	// Rect, to Window-space.
+	transform f64.Aff3
+	// transforms is the stack of transforms saved by PushTransform.
+	transforms []f64.Aff3
+	// clips is the clip stack, in Window-space. Each element is already
+	// intersected with the one below it. When clipper is non-nil, it only
+	// counts the regions pushed onto the clipper.
+	clips []image.Rectangle
+}
+
+// NewContext returns a new Context that draws to w. Its initial color is
+// opaque black, its initial line width is 1, its initial transform is the
+// identity and its clip stack is empty.
+//
+// Drivers implement the Window.Begin method by calling NewContext. Other
+// code should call Begin instead.
+func NewContext(w Window) *Context {
+	clipper, _ := w.(Clipper)
+	return &Context{
+		w:         w,
+		clipper:   clipper,
+		color:     color.Black,
+		lineWidth: 1,
+		transform: f64.Aff3{
+			1, 0, 0,
+			0, 1, 0,
+		},
+	}
+}
+
+// SetColor sets the color used by subsequent calls to Rect and Line.
+func (c *Context) SetColor(col color.Color) {
+	c.color = col
+}
+
+// SetLineWidth sets the width, in pixels, of subsequent calls to Line.
+func (c *Context) SetLineWidth(width int) {
+	c.lineWidth = width
+}
+
+// Translate moves the origin of user space by (dx, dy), in user space.
+func (c *Context) Translate(dx, dy float64) {
+	c.transform = mul(c.transform, f64.Aff3{
+		1, 0, dx,
+		0, 1, dy,
+	})
+}
+
+// Rotate rotates user space clockwise about its origin by the given angle,
+// in radians. The Y axis points down, so that a positive angle is clockwise
+// on screen.
+func (c *Context) Rotate(radians float64) {
+	sin, cos := math.Sincos(radians)
+	c.transform = mul(c.transform, f64.Aff3{
+		snap(cos), snap(-sin), 0,
+		snap(sin), snap(cos), 0,
+	})
+}
+
+// PushTransform pushes a copy of the current transform onto the transform
+// stack, so that a later PopTransform can restore it.
+func (c *Context) PushTransform() {
+	c.transforms = append(c.transforms, c.transform)
+}
+
+// PopTransform restores the transform most recently pushed by PushTransform.
+// It does nothing if the transform stack is empty.
+func (c *Context) PopTransform() {
+	if n := len(c.transforms); n > 0 {
+		c.transform = c.transforms[n-1]
+		c.transforms = c.transforms[:n-1]
+	}
+}
+
+// PushClip pushes a clip rectangle, in Window-space, onto the clip stack.
+// Subsequent drawing is clipped to the intersection of r and the enclosing
+// clip rectangles, until the matching PopClip.
+//
+// Unless the Window is a Clipper, clipping is exact, to the nearest pixel,
+// only when the current transform is axis-aligned: only rotations by
+// multiples of 90 degrees keep it so. Otherwise, a shape is drawn only if its
+// Window-space bounds lie entirely within the clip rectangle.
+func (c *Context) PushClip(r image.Rectangle) {
+	if c.clipper != nil {
+		c.clipper.PushClip(r)
+	}
+	c.pushClip(r)
+}
+
+// PushClipPath pushes the Path p, in user space, onto the clip stack.
+// Subsequent drawing is clipped to the intersection of p and the enclosing
+// clip regions, until the matching PopClip.
+//
+// Unless the Window is a Clipper, the clip is only p's Window-space bounding
+// rectangle.
+func (c *Context) PushClipPath(p *Path) {
+	if c.clipper != nil {
+		c.clipper.PushClipPath(c.transform, p)
+		c.pushClip(image.Rectangle{})
+		return
+	}
+	minX, minY := math.Inf(+1), math.Inf(+1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	p.Flatten(c.transform, 0, func(contour []f64.Vec2) {
+		for _, v := range contour {
+			minX, maxX = math.Min(minX, v[0]), math.Max(maxX, v[0])
+			minY, maxY = math.Min(minY, v[1]), math.Max(maxY, v[1])
+		}
+	})
+	var r image.Rectangle
+	if minX <= maxX {
+		r = image.Rect(
+			int(math.Floor(minX)), int(math.Floor(minY)),
+			int(math.Ceil(maxX)), int(math.Ceil(maxY)),
+		)
+	}
+	c.pushClip(r)
+}
+
+func (c *Context) pushClip(r image.Rectangle) {
+	if n := len(c.clips); n > 0 {
+		r = r.Intersect(c.clips[n-1])
+	}
+	c.clips = append(c.clips, r)
+}
+
+// PopClip pops the clip region most recently pushed by PushClip or
+// PushClipPath. It does nothing if the clip stack is empty.
+func (c *Context) PopClip() {
+	if n := len(c.clips); n > 0 {
+		c.clips = c.clips[:n-1]
+		if c.clipper != nil {
+			c.clipper.PopClip()
+		}
+	}
+}
+
+// Rect fills the rectangle r, in user space, with the current color.
+func (c *Context) Rect(r image.Rectangle) {
+	c.draw(c.transform, r, func(src2dst f64.Aff3, sr image.Rectangle) {
+		c.w.DrawUniform(src2dst, c.color, sr, draw.Over, nil)
+	})
+}
+
+// Image draws the texture t such that its top-left pixel is at p, in user
+// space.
+func (c *Context) Image(p image.Point, t Texture) {
+	src2dst := mul(c.transform, f64.Aff3{
+		1, 0, float64(p.X),
+		0, 1, float64(p.Y),
+	})
+	c.draw(src2dst, t.Bounds(), func(src2dst f64.Aff3, sr image.Rectangle) {
+		c.w.Draw(src2dst, t, sr, draw.Over, nil)
+	})
+}
+
+// Line draws a line from p0 to p1, in user space, with the current color and
+// line width.
+func (c *Context) Line(p0, p1 image.Point) {
+	dx, dy := float64(p1.X-p0.X), float64(p1.Y-p0.Y)
+	hypot := math.Hypot(dx, dy)
+	if hypot == 0 || c.lineWidth <= 0 {
+		return
+	}
+	// The line is a length by lineWidth rectangle, centered on the X axis
+	// and rotated to point from p0 to p1.
+	length := int(math.Ceil(hypot))
+	sin, cos := dy/hypot, dx/hypot
+	src2dst := mul(c.transform, f64.Aff3{
+		cos, -sin, float64(p0.X),
+		sin, cos, float64(p0.Y),
+	})
+	half := c.lineWidth / 2
+	sr := image.Rect(0, -half, length, c.lineWidth-half)
+	c.draw(src2dst, sr, func(src2dst f64.Aff3, sr image.Rectangle) {
+		c.w.DrawUniform(src2dst, c.color, sr, draw.Over, nil)
+	})
+}
+
+// End pops any clip regions still on the clip stack, then publishes the
+// Window and returns the result. The Context should not be used afterwards.
+func (c *Context) End() PublishResult {
+	for len(c.clips) > 0 {
+		c.PopClip()
+	}
+	return c.w.Publish()
+}
+
+// draw calls fn to draw the src-space rectangle sr, transformed to
+// Window-space by src2dst, subject to the current clip.
+func (c *Context) draw(src2dst f64.Aff3, sr image.Rectangle, fn func(src2dst f64.Aff3, sr image.Rectangle)) {
+	if sr.Empty() {
+		return
+	}
+	n := len(c.clips)
+	if n == 0 || c.clipper != nil {
+		fn(src2dst, sr)
+		return
+	}
+	clip := c.clips[n-1]
+	if clip.Empty() {
+		return
+	}
+	if !axisAligned(src2dst) {
+		if transformRect(src2dst, sr).In(clip) {
+			fn(src2dst, sr)
+		}
+		return
+	}
+	sr = sr.Intersect(transformRect(invert(src2dst), clip))
+	if !sr.Empty() {
+		fn(src2dst, sr)
+	}
+}
+
+// mul returns the affine transformation that applies b and then a.
+func mul(a, b f64.Aff3) f64.Aff3 {
+	return f64.Aff3{
+		a[0]*b[0] + a[1]*b[3],
+		a[0]*b[1] + a[1]*b[4],
+		a[0]*b[2] + a[1]*b[5] + a[2],
+		a[3]*b[0] + a[4]*b[3],
+		a[3]*b[1] + a[4]*b[4],
+		a[3]*b[2] + a[4]*b[5] + a[5],
+	}
+}
+
+// invert returns the inverse of the affine transformation a, which must be
+// invertible.
+func invert(a f64.Aff3) f64.Aff3 {
+	det := a[0]*a[4] - a[1]*a[3]
+	return f64.Aff3{
+		+a[4] / det,
+		-a[1] / det,
+		(a[1]*a[5] - a[2]*a[4]) / det,
+		-a[3] / det,
+		+a[0] / det,
+		(a[2]*a[3] - a[0]*a[5]) / det,
+	}
+}
+
+// axisAligned returns whether a maps axis-aligned rectangles to axis-aligned
+// rectangles.
+func axisAligned(a f64.Aff3) bool {
+	return (a[1] == 0 && a[3] == 0) || (a[0] == 0 && a[4] == 0)
+}
+
+// transformRect returns the smallest rectangle that contains r transformed by
+// a.
+func transformRect(a f64.Aff3, r image.Rectangle) image.Rectangle {
+	minX, minY := math.Inf(+1), math.Inf(+1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range [4]image.Point{
+		r.Min,
+		{r.Max.X, r.Min.Y},
+		{r.Min.X, r.Max.Y},
+		r.Max,
+	} {
+		x := a[0]*float64(p.X) + a[1]*float64(p.Y) + a[2]
+		y := a[3]*float64(p.X) + a[4]*float64(p.Y) + a[5]
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	return image.Rect(
+		int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX)), int(math.Ceil(maxY)),
+	)
+}
+
+// snap rounds x to -1, 0 or +1 if it is very close to one of them, so that
+// rotating by a multiple of 90 degrees gives an exactly axis-aligned
+// transform, despite floating point error.
+func snap(x float64) float64 {
+	const epsilon = 1e-12
+	for _, v := range [3]float64{-1, 0, +1} {
+		if math.Abs(x-v) < epsilon {
+			return v
+		}
+	}
+	return x
+}
@@ -0,0 +1,149 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package screen
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+)
+
+// recordingWindow is a Window that records the Window-space bounds of its
+// DrawUniform calls. Its other methods are unimplemented.
+type recordingWindow struct {
+	Window
+	drawn     []image.Rectangle
+	published int
+}
+
+func (w *recordingWindow) DrawUniform(src2dst f64.Aff3, src color.Color, sr image.Rectangle, op draw.Op, opts *DrawOptions) {
+	w.drawn = append(w.drawn, transformRect(src2dst, sr))
+}
+
+func (w *recordingWindow) Publish() PublishResult {
+	w.published++
+	return PublishResult{}
+}
+
+func TestContext(t *testing.T) {
+	w := &recordingWindow{}
+	c := NewContext(w)
+
+	c.Translate(10, 20)
+	c.Rect(image.Rect(0, 0, 5, 5))
+
+	c.PushClip(image.Rect(12, 0, 100, 23))
+	c.Rect(image.Rect(0, 0, 5, 5))
+	c.PushClip(image.Rect(0, 0, 13, 100))
+	c.Rect(image.Rect(0, 0, 5, 5))
+	c.PopClip()
+
+	// Rotating by 90 degrees keeps the transform axis-aligned, so that
+	// clipping is still exact.
+	c.Rotate(math.Pi / 2)
+	c.Rect(image.Rect(0, -5, 5, 0))
+
+	// Rotating by 45 degrees does not, so that a partially clipped rectangle
+	// is not drawn at all.
+	c.Rotate(-math.Pi / 4)
+	c.Rect(image.Rect(0, 0, 5, 5))
+	c.PopClip()
+	c.Rect(image.Rect(0, 0, 5, 5))
+
+	if c.End(); w.published != 1 {
+		t.Errorf("published: got %d, want 1", w.published)
+	}
+
+	want := []image.Rectangle{
+		image.Rect(10, 20, 15, 25),
+		image.Rect(12, 20, 15, 23),
+		image.Rect(12, 20, 13, 23),
+		image.Rect(12, 20, 15, 23),
+		image.Rect(6, 20, 14, 28),
+	}
+	if len(w.drawn) != len(want) {
+		t.Fatalf("drawn: got %v, want %v", w.drawn, want)
+	}
+	for i, got := range w.drawn {
+		if got != want[i] {
+			t.Errorf("drawn[%d]: got %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+// clippingWindow is a recordingWindow that is also a Clipper, recording its
+// clip stack operations.
+type clippingWindow struct {
+	recordingWindow
+	clipOps []string
+}
+
+func (w *clippingWindow) PushClip(r image.Rectangle) {
+	w.clipOps = append(w.clipOps, "push "+r.String())
+}
+
+func (w *clippingWindow) PushClipPath(src2dst f64.Aff3, p *Path) {
+	w.clipOps = append(w.clipOps, "pushPath")
+}
+
+func (w *clippingWindow) PopClip() {
+	w.clipOps = append(w.clipOps, "pop")
+}
+
+func TestContextClipper(t *testing.T) {
+	w := &clippingWindow{}
+	c := NewContext(w)
+
+	c.PushTransform()
+	c.Translate(10, 20)
+	c.PushClip(image.Rect(12, 0, 100, 23))
+	p := &Path{}
+	p.Ellipse(0, 0, 5, 5)
+	c.PushClipPath(p)
+	// The Clipper clips, so that a rotated rectangle is drawn regardless.
+	c.Rotate(-math.Pi / 4)
+	c.Rect(image.Rect(0, 0, 5, 5))
+	c.PopClip()
+	c.PopTransform()
+	c.Rect(image.Rect(0, 0, 5, 5))
+	c.End()
+
+	if len(w.drawn) != 2 {
+		t.Fatalf("drawn: got %v, want 2 rectangles", w.drawn)
+	}
+	if got, want := w.drawn[1], image.Rect(0, 0, 5, 5); got != want {
+		t.Errorf("drawn[1]: got %v, want %v", got, want)
+	}
+	want := []string{"push (12,0)-(100,23)", "pushPath", "pop", "pop"}
+	if len(w.clipOps) != len(want) {
+		t.Fatalf("clipOps: got %q, want %q", w.clipOps, want)
+	}
+	for i, got := range w.clipOps {
+		if got != want[i] {
+			t.Errorf("clipOps[%d]: got %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestContextClipPathBounds(t *testing.T) {
+	w := &recordingWindow{}
+	c := NewContext(w)
+
+	p := &Path{}
+	p.Rect(0, 0, 10, 10)
+	c.Translate(5, 5)
+	c.PushClipPath(p)
+	c.Rect(image.Rect(-5, -5, 5, 5))
+	c.End()
+
+	want := []image.Rectangle{image.Rect(5, 5, 10, 10)}
+	if len(w.drawn) != len(want) || w.drawn[0] != want[0] {
+		t.Errorf("drawn: got %v, want %v", w.drawn, want)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package screen
+
+import (
+	"image"
+)
+
+// PixelFormat is the memory layout of a pixel, in a Texture or in Pixels.
+type PixelFormat uint8
+
+const (
+	// PixelFormatRGBA8 is 8 bits each of alpha-premultiplied red, green, blue
+	// and alpha, as for an *image.RGBA. It is the format of Textures returned
+	// by NewTexture.
+	PixelFormatRGBA8 PixelFormat = iota
+	// PixelFormatBGRA8 is like PixelFormatRGBA8, but with the red and blue
+	// bytes swapped. It is the native byte order of Windows and most X11
+	// servers, where uploading it avoids swizzling every pixel. As a Texture
+	// format, it is equivalent to PixelFormatRGBA8.
+	PixelFormatBGRA8
+	// PixelFormatAlpha8 is 8 bits of alpha, as for an *image.Alpha. Like an
+	// *image.Alpha, an Alpha8 Texture draws as white whose opacity is that
+	// alpha, which suits glyph atlases and masks.
+	PixelFormatAlpha8
+	// PixelFormatGray8 is 8 bits of opaque gray, as for an *image.Gray.
+	PixelFormatGray8
+	// PixelFormatRGBA64 is 16 big-endian bits each of alpha-premultiplied
+	// red, green, blue and alpha, as for an *image.RGBA64, such as for high
+	// dynamic range content. Drivers may store such Textures at a lower
+	// precision, but never lower than PixelFormatRGBA8.
+	PixelFormatRGBA64
+)
+
+// BytesPerPixel returns the number of bytes per pixel of f.
+func (f PixelFormat) BytesPerPixel() int {
+	switch f {
+	case PixelFormatAlpha8, PixelFormatGray8:
+		return 1
+	case PixelFormatRGBA64:
+		return 8
+	}
+	return 4
+}
+
+// Pixels is a rectangle of pixels in a given PixelFormat. The pixel at (x, y)
+// starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*Format.BytesPerPixel()].
+type Pixels struct {
+	Format PixelFormat
+	Pix    []byte
+	Stride int
+	Rect   image.Rectangle
+}
+
+// NewPixels returns new, zeroed, Pixels with the given format and bounds.
+func NewPixels(format PixelFormat, r image.Rectangle) *Pixels {
+	stride := r.Dx() * format.BytesPerPixel()
+	return &Pixels{
+		Format: format,
+		Pix:    make([]byte, stride*r.Dy()),
+		Stride: stride,
+		Rect:   r,
+	}
+}
+
+// PixelsOf returns Pixels that share m's pixel data, if m is an
+// *image.Alpha, *image.Gray, *image.RGBA or *image.RGBA64. Otherwise, it
+// returns nil and false.
+func PixelsOf(m image.Image) (*Pixels, bool) {
+	switch m := m.(type) {
+	case *image.Alpha:
+		return &Pixels{PixelFormatAlpha8, m.Pix, m.Stride, m.Rect}, true
+	case *image.Gray:
+		return &Pixels{PixelFormatGray8, m.Pix, m.Stride, m.Rect}, true
+	case *image.RGBA:
+		return &Pixels{PixelFormatRGBA8, m.Pix, m.Stride, m.Rect}, true
+	case *image.RGBA64:
+		return &Pixels{PixelFormatRGBA64, m.Pix, m.Stride, m.Rect}, true
+	}
+	return nil, false
+}
+
+// Bounds returns the bounds of the pixels.
+func (p *Pixels) Bounds() image.Rectangle {
+	return p.Rect
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds
+// to the pixel at (x, y).
+func (p *Pixels) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*p.Format.BytesPerPixel()
+}
+
+// PixelUploader is implemented by Textures that can upload Pixels of any
+// PixelFormat, converting them to the Texture's own format as they do so.
+// The Textures returned by this module's drivers implement it.
+type PixelUploader interface {
+	// UploadPixels uploads the sub-Pixels defined by src and sr to the
+	// destination (the method receiver), such that sr.Min in src-space
+	// aligns with dp in dst-space. The destination's contents are
+	// overwritten; the draw operator is implicitly draw.Src.
+	//
+	// A src whose format matches the Texture's, or is PixelFormatBGRA8 on
+	// a driver whose native format that is, is uploaded without converting
+	// each pixel.
+	UploadPixels(dp image.Point, src *Pixels, sr image.Rectangle)
+}
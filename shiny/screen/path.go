@@ -0,0 +1,297 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package screen
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/vector"
+)
+
+// PathOp is the operation of a PathSegment.
+type PathOp uint8
+
+const (
+	// PathMoveTo starts a new contour at Points[0].
+	PathMoveTo PathOp = iota
+	// PathLineTo adds a straight line to Points[0].
+	PathLineTo
+	// PathQuadTo adds a quadratic Bézier curve, with control point
+	// Points[0], to Points[1].
+	PathQuadTo
+	// PathCubeTo adds a cubic Bézier curve, with control points Points[0]
+	// and Points[1], to Points[2].
+	PathCubeTo
+	// PathClose closes the current contour.
+	PathClose
+)
+
+// PathSegment is a single operation of a Path. Only the leading elements of
+// Points that are used by Op are meaningful.
+type PathSegment struct {
+	Op     PathOp
+	Points [3]f64.Vec2
+}
+
+// Path is a vector shape made of contours of straight lines and quadratic and
+// cubic Bézier curves. The zero value is an empty path.
+//
+// A Path is filled using the non-zero winding rule. Contours are implicitly
+// closed when filling.
+type Path struct {
+	Segments []PathSegment
+}
+
+// MoveTo starts a new contour at (x, y).
+func (p *Path) MoveTo(x, y float64) {
+	p.Segments = append(p.Segments, PathSegment{
+		Op:     PathMoveTo,
+		Points: [3]f64.Vec2{{x, y}},
+	})
+}
+
+// LineTo adds a straight line from the current point to (x, y).
+func (p *Path) LineTo(x, y float64) {
+	p.Segments = append(p.Segments, PathSegment{
+		Op:     PathLineTo,
+		Points: [3]f64.Vec2{{x, y}},
+	})
+}
+
+// QuadTo adds a quadratic Bézier curve from the current point, with control
+// point (cx, cy), to (x, y).
+func (p *Path) QuadTo(cx, cy, x, y float64) {
+	p.Segments = append(p.Segments, PathSegment{
+		Op:     PathQuadTo,
+		Points: [3]f64.Vec2{{cx, cy}, {x, y}},
+	})
+}
+
+// CubeTo adds a cubic Bézier curve from the current point, with control
+// points (c0x, c0y) and (c1x, c1y), to (x, y).
+func (p *Path) CubeTo(c0x, c0y, c1x, c1y, x, y float64) {
+	p.Segments = append(p.Segments, PathSegment{
+		Op:     PathCubeTo,
+		Points: [3]f64.Vec2{{c0x, c0y}, {c1x, c1y}, {x, y}},
+	})
+}
+
+// Close closes the current contour.
+func (p *Path) Close() {
+	p.Segments = append(p.Segments, PathSegment{Op: PathClose})
+}
+
+// Rect adds a closed rectangular contour.
+func (p *Path) Rect(minX, minY, maxX, maxY float64) {
+	p.MoveTo(minX, minY)
+	p.LineTo(maxX, minY)
+	p.LineTo(maxX, maxY)
+	p.LineTo(minX, maxY)
+	p.Close()
+}
+
+// kappa is the distance, relative to the radius, of a cubic Bézier curve's
+// control points from its end points, when approximating a quarter circle.
+const kappa = 0.5522847498307936 // 4 * (math.Sqrt2 - 1) / 3.
+
+// RoundedRect adds a closed rectangular contour whose corners are rounded
+// with the given radius. The radius is clamped to half of the smaller side.
+func (p *Path) RoundedRect(minX, minY, maxX, maxY, radius float64) {
+	radius = math.Min(radius, math.Min(maxX-minX, maxY-minY)/2)
+	if radius <= 0 {
+		p.Rect(minX, minY, maxX, maxY)
+		return
+	}
+	k := radius * kappa
+	p.MoveTo(minX+radius, minY)
+	p.LineTo(maxX-radius, minY)
+	p.CubeTo(maxX-radius+k, minY, maxX, minY+radius-k, maxX, minY+radius)
+	p.LineTo(maxX, maxY-radius)
+	p.CubeTo(maxX, maxY-radius+k, maxX-radius+k, maxY, maxX-radius, maxY)
+	p.LineTo(minX+radius, maxY)
+	p.CubeTo(minX+radius-k, maxY, minX, maxY-radius+k, minX, maxY-radius)
+	p.LineTo(minX, minY+radius)
+	p.CubeTo(minX, minY+radius-k, minX+radius-k, minY, minX+radius, minY)
+	p.Close()
+}
+
+// Ellipse adds a closed elliptical contour centered on (cx, cy) with the
+// radii rx and ry. A circle has equal radii.
+func (p *Path) Ellipse(cx, cy, rx, ry float64) {
+	kx, ky := rx*kappa, ry*kappa
+	p.MoveTo(cx+rx, cy)
+	p.CubeTo(cx+rx, cy+ky, cx+kx, cy+ry, cx, cy+ry)
+	p.CubeTo(cx-kx, cy+ry, cx-rx, cy+ky, cx-rx, cy)
+	p.CubeTo(cx-rx, cy-ky, cx-kx, cy-ry, cx, cy-ry)
+	p.CubeTo(cx+kx, cy-ry, cx+rx, cy-ky, cx+rx, cy)
+	p.Close()
+}
+
+// Flatten transforms p by src2dst and approximates its curves by straight
+// lines that deviate from them by no more than tolerance, in dst space. It
+// calls f once per contour with that contour's vertices, which are only valid
+// during the call. Contours with fewer than three vertices are skipped.
+//
+// Flatten is intended for use by PathDrawer implementations.
+func (p *Path) Flatten(src2dst f64.Aff3, tolerance float64, f func(contour []f64.Vec2)) {
+	if tolerance <= 0 {
+		tolerance = 0.25
+	}
+	var (
+		contour []f64.Vec2
+		start   f64.Vec2
+		pen     f64.Vec2
+	)
+	flush := func() {
+		if len(contour) >= 3 {
+			f(contour)
+		}
+		contour = contour[:0]
+	}
+	for _, s := range p.Segments {
+		var q [3]f64.Vec2
+		for i := range q {
+			q[i] = transform(src2dst, s.Points[i])
+		}
+		switch s.Op {
+		case PathMoveTo:
+			flush()
+			start, pen = q[0], q[0]
+			contour = append(contour, pen)
+		case PathLineTo:
+			pen = q[0]
+			contour = append(contour, pen)
+		case PathQuadTo:
+			n := segments(tolerance, pen, q[0], q[1])
+			for i := 1; i <= n; i++ {
+				t := float64(i) / float64(n)
+				u := 1 - t
+				contour = append(contour, f64.Vec2{
+					u*u*pen[0] + 2*u*t*q[0][0] + t*t*q[1][0],
+					u*u*pen[1] + 2*u*t*q[0][1] + t*t*q[1][1],
+				})
+			}
+			pen = q[1]
+		case PathCubeTo:
+			n := segments(tolerance, pen, q[0], q[1], q[2])
+			for i := 1; i <= n; i++ {
+				t := float64(i) / float64(n)
+				u := 1 - t
+				contour = append(contour, f64.Vec2{
+					u*u*u*pen[0] + 3*u*u*t*q[0][0] + 3*u*t*t*q[1][0] + t*t*t*q[2][0],
+					u*u*u*pen[1] + 3*u*u*t*q[0][1] + 3*u*t*t*q[1][1] + t*t*t*q[2][1],
+				})
+			}
+			pen = q[2]
+		case PathClose:
+			flush()
+			pen = start
+			contour = append(contour, pen)
+		}
+	}
+	flush()
+}
+
+func transform(a f64.Aff3, p f64.Vec2) f64.Vec2 {
+	return f64.Vec2{
+		a[0]*p[0] + a[1]*p[1] + a[2],
+		a[3]*p[0] + a[4]*p[1] + a[5],
+	}
+}
+
+// segments returns how many straight lines approximate the Bézier curve with
+// the given control polygon to within tolerance. A chord of length 1/n
+// deviates from the curve by at most 1/(8*n*n) times the curve's maximum
+// second derivative, which is bounded by its control polygon's largest second
+// difference multiplied by degree*(degree-1).
+func segments(tolerance float64, pts ...f64.Vec2) int {
+	d := 0.0
+	for i := 2; i < len(pts); i++ {
+		dx := pts[i][0] - 2*pts[i-1][0] + pts[i-2][0]
+		dy := pts[i][1] - 2*pts[i-1][1] + pts[i-2][1]
+		d = math.Max(d, math.Hypot(dx, dy))
+	}
+	degree := len(pts) - 1
+	d *= float64(degree * (degree - 1))
+	n := int(math.Ceil(math.Sqrt(d / (8 * tolerance))))
+	if n < 1 {
+		n = 1
+	} else if n > 256 {
+		n = 256
+	}
+	return n
+}
+
+// PathDrawer is implemented by Drawers, such as GPU-backed Windows, that can
+// fill a Path directly, with anti-aliasing.
+type PathDrawer interface {
+	// FillPath fills the Path p, transformed by src2dst, with the uniform
+	// color src, using the draw.Over operator.
+	FillPath(src2dst f64.Aff3, p *Path, src color.Color)
+}
+
+// FillPath fills the Path p, transformed by src2dst, with the uniform color
+// src, using the draw.Over operator.
+//
+// If d implements PathDrawer, its FillPath method is called. Otherwise, the
+// path is rasterized into a Buffer obtained from s, uploaded to a Texture and
+// drawn via d's Copy method.
+func FillPath(s Screen, d Drawer, src2dst f64.Aff3, p *Path, src color.Color) error {
+	if pd, ok := d.(PathDrawer); ok {
+		pd.FillPath(src2dst, p, src)
+		return nil
+	}
+
+	var contours [][]f64.Vec2
+	minX, minY := math.Inf(+1), math.Inf(+1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	p.Flatten(src2dst, 0, func(c []f64.Vec2) {
+		contours = append(contours, append([]f64.Vec2(nil), c...))
+		for _, v := range c {
+			minX, minY = math.Min(minX, v[0]), math.Min(minY, v[1])
+			maxX, maxY = math.Max(maxX, v[0]), math.Max(maxY, v[1])
+		}
+	})
+	if len(contours) == 0 {
+		return nil
+	}
+	r := image.Rect(
+		int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX)), int(math.Ceil(maxY)),
+	)
+	if r.Empty() {
+		return nil
+	}
+
+	z := vector.NewRasterizer(r.Dx(), r.Dy())
+	ox, oy := float64(r.Min.X), float64(r.Min.Y)
+	for _, c := range contours {
+		z.MoveTo(float32(c[0][0]-ox), float32(c[0][1]-oy))
+		for _, v := range c[1:] {
+			z.LineTo(float32(v[0]-ox), float32(v[1]-oy))
+		}
+		z.ClosePath()
+	}
+
+	b, err := s.NewBuffer(r.Size())
+	if err != nil {
+		return err
+	}
+	defer b.Release()
+	z.Draw(b.RGBA(), b.Bounds(), image.NewUniform(src), image.Point{})
+
+	t, err := s.NewTexture(r.Size())
+	if err != nil {
+		return err
+	}
+	defer t.Release()
+	t.Upload(image.Point{}, b, b.Bounds())
+	d.Copy(r.Min, t, t.Bounds(), draw.Over, nil)
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package screen
+
+import (
+	"math"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+)
+
+func TestPathFlatten(t *testing.T) {
+	const tolerance = 0.25
+
+	p := &Path{}
+	p.Ellipse(0, 0, 10, 10)
+	p.Rect(0, 0, 1, 1)
+
+	// src2dst scales by 2 and translates by (5, 7), so that the circle has
+	// radius 20 in dst space.
+	var contours [][]f64.Vec2
+	p.Flatten(f64.Aff3{2, 0, 5, 0, 2, 7}, tolerance, func(c []f64.Vec2) {
+		contours = append(contours, append([]f64.Vec2(nil), c...))
+	})
+	if len(contours) != 2 {
+		t.Fatalf("got %d contours, want 2", len(contours))
+	}
+
+	circle := contours[0]
+	if len(circle) < 8 {
+		t.Errorf("circle: got %d vertices, want at least 8", len(circle))
+	}
+	for i, v := range circle {
+		if r := math.Hypot(v[0]-5, v[1]-7); math.Abs(r-20) > 0.05 {
+			t.Errorf("circle vertex %d: %v is at radius %g, want 20", i, v, r)
+		}
+		w := circle[(i+1)%len(circle)]
+		mid := math.Hypot((v[0]+w[0])/2-5, (v[1]+w[1])/2-7)
+		if d := 20 - mid; d > tolerance {
+			t.Errorf("circle edge %d: deviates by %g, want at most %g", i, d, tolerance)
+		}
+	}
+
+	want := []f64.Vec2{{5, 7}, {7, 7}, {7, 9}, {5, 9}}
+	if got := contours[1]; len(got) != len(want) {
+		t.Errorf("rect: got %v, want %v", got, want)
+	} else {
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("rect: got %v, want %v", got, want)
+				break
+			}
+		}
+	}
+}
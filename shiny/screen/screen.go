@@ -55,12 +55,16 @@
 package screen // import "golang.org/x/exp/shiny/screen"
 
 import (
+	"errors"
 	"image"
 	"image/color"
 	"image/draw"
+	"time"
 	"unicode/utf8"
 
 	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/mouse"
 )
 
 // TODO: specify image format (Alpha or Gray, not just RGBA) for NewBuffer
@@ -71,15 +75,904 @@ type Screen interface {
 	// NewBuffer returns a new Buffer for this screen.
 	NewBuffer(size image.Point) (Buffer, error)
 
-	// NewTexture returns a new Texture for this screen.
+	// NewTexture returns a new Texture for this screen. It is equivalent to
+	// NewTextureWithOptions(size, nil).
 	NewTexture(size image.Point) (Texture, error)
 
+	// NewTextureWithOptions returns a new Texture for this screen with the
+	// given pixel format and sampling options. Uploading a Buffer to it
+	// converts the Buffer's RGBA pixels to that format.
+	//
+	// A nil opts is valid and means to use the default option values.
+	NewTextureWithOptions(size image.Point, opts *NewTextureOptions) (Texture, error)
+
 	// NewWindow returns a new Window for this screen.
 	//
 	// A nil opts is valid and means to use the default option values.
 	NewWindow(opts *NewWindowOptions) (Window, error)
+
+	// SetDefaultWindowOptions sets the default option values used by
+	// subsequent calls to NewWindow. Any zero valued field of the opts passed
+	// to NewWindow, such as an empty Title, inherits the corresponding field
+	// of these defaults. Non-zero fields override the defaults. See the
+	// NewWindowOptions.WithDefaults method.
+	//
+	// A nil opts is valid and means to clear the defaults. The opts value is
+	// copied, so later changes to it do not affect the defaults.
+	SetDefaultWindowOptions(opts *NewWindowOptions)
+
+	// TextureMemoryEstimate returns an estimate, in bytes, of the memory used
+	// by this screen's Textures that have not yet been released. It is useful
+	// for budgeting assets and for detecting leaked Textures.
+	//
+	// The estimate assumes 4 bytes per pixel. It is approximate: it does not
+	// account for any padding, compression or mipmaps that the GPU driver or
+	// X11 server may add, or for memory used by Buffers or Windows.
+	TextureMemoryEstimate() int64
+
+	// AccessibilityPrefs returns the user's accessibility preferences, as
+	// configured in the operating system. Drivers return zero, meaning no
+	// preferences, for those that the platform does not expose.
+	//
+	// Windows are sent an AccessibilityEvent when these preferences change.
+	AccessibilityPrefs() AccessibilityPrefs
+
+	// ColorScheme returns the user's preferred color scheme, such as a dark
+	// one, as configured in the operating system. Drivers return
+	// LightColorScheme if the platform does not expose a preference.
+	//
+	// Windows are sent a ColorSchemeEvent when this preference changes.
+	ColorScheme() ColorScheme
+
+	// Clipboard returns the system clipboard, which is shared with other
+	// applications.
+	Clipboard() Clipboard
+
+	// Displays returns the connected displays, such as monitors, with the
+	// primary display first. It returns nil if the driver cannot enumerate
+	// them.
+	//
+	// Windows are sent a DisplayEvent when displays are connected or
+	// disconnected, or their properties change.
+	Displays() []Display
+
+	// NewTrayIcon returns a new TrayIcon, shown in the system tray until it
+	// is released. It returns an error if the platform has no system tray,
+	// as for the waylanddriver and the wasmdriver.
+	//
+	// A nil opts is valid and means to use the default option values.
+	NewTrayIcon(opts *NewTrayIconOptions) (TrayIcon, error)
+}
+
+// Display is a monitor, or other output device, that windows can be shown on.
+type Display struct {
+	// Name identifies the display, such as "HDMI-1" or "DELL U2715H". Its
+	// format depends on the platform, and it may be empty.
+	Name string
+
+	// Bounds is the display's position and size, in the same screen pixels
+	// as NewWindowOptions.Position and Window.SetPosition. To open a window
+	// on a particular display, position it within that display's Bounds.
+	Bounds image.Rectangle
+
+	// WorkArea is the part of Bounds that windows can occupy without being
+	// obscured, excluding task bars, docks and menu bars. It is the same as
+	// Bounds if the driver does not know.
+	WorkArea image.Rectangle
+
+	// DPI is the display's physical pixel density, in pixels per inch, as
+	// calculated from the physical size that it reports. It is zero if the
+	// physical size is unknown.
+	DPI float64
+
+	// Scale is the factor by which the operating system scales user
+	// interfaces on the display, such as 2 for a Retina display on macOS or
+	// 1.5 for "150%" on Windows. It is 1 if the display is not scaled.
+	//
+	// Where DPI describes the hardware, Scale is the user's choice, and is
+	// usually the better guide to how large to draw content.
+	Scale float64
+
+	// RefreshRate is the display's refresh rate, in hertz, or zero if it is
+	// unknown.
+	RefreshRate float64
+
+	// Primary is whether the display is the primary one, which typically has
+	// the task bar or menu bar, and where new windows open by default.
+	Primary bool
+
+	// ColorSpace is the widest color space that the display can show. It is
+	// ColorSpaceSRGB if the driver does not know, as for every driver but
+	// the gldriver and mtldriver on macOS.
+	ColorSpace ColorSpace
+}
+
+// DisplayEvent is sent to a Window's EventDeque when displays are connected
+// or disconnected, or their properties, such as their bounds or scale,
+// change.
+type DisplayEvent struct {
+	// Displays are the connected displays, as returned by Screen.Displays.
+	Displays []Display
+}
+
+// ScaleEvent is sent to a Window's EventDeque when the window's scale
+// changes, such as when it moves between a Retina and a non-Retina display, or
+// when the user changes the display's scale factor. It is followed by a
+// size.Event whose PixelsPerPt is the new scale.
+//
+// Windows are not sent a ScaleEvent for their initial scale, which is given
+// by their first size.Event.
+type ScaleEvent struct {
+	// PixelsPerPt is the new number of pixels per point, as for the
+	// size.Event that follows.
+	PixelsPerPt float32
+
+	// Scale is the new scale factor of the display that the window is on,
+	// as for Display.Scale.
+	Scale float64
+}
+
+// FrameEvent is sent to a Window's EventDeque, after a call to the Window's
+// NextFrame method, when the display is ready for a new frame.
+type FrameEvent struct {
+	// Time is when the display became ready for the frame, such as the time
+	// of its vertical blank, if the driver knows it. Otherwise, it is when
+	// the event was sent.
+	Time time.Time
+}
+
+// CapturedFrame is a copy of a frame that a Window published, as passed to
+// the window's capture hooks. See Window.AddCaptureHook.
+type CapturedFrame struct {
+	// Image is the frame's pixels, composited with the window's Layers. Its
+	// bounds are the window's size, in pixels, at the time, with the
+	// origin at the top left. The hook that it is passed to owns it, and
+	// may keep it.
+	Image *image.RGBA
+
+	// Time is when the frame was published.
+	Time time.Time
+}
+
+// DeviceLostEvent is sent to a Window's EventDeque when the GPU context that
+// renders the window has been lost, such as after a graphics driver reset or
+// update, and the driver has recovered.
+//
+// Every Texture, and every Layer, of the Screen remains valid, with the same
+// size, format and options, but its pixels are undefined, as are the
+// window's. The app should re-upload its Textures' contents, and redraw its
+// Layers and the window. Every Window of the Screen is sent a
+// DeviceLostEvent, no later than its next Publish.
+//
+// Only the gldriver sends DeviceLostEvents, and only on platforms where EGL
+// reports context loss: Windows and X11.
+type DeviceLostEvent struct{}
+
+// RelativeMouseEvent is sent to a Window's EventDeque, instead of a
+// mouse.Event, when the mouse moves while the window has captured the
+// pointer. See Window.SetPointerCapture.
+type RelativeMouseEvent struct {
+	// DeltaX and DeltaY are how far the mouse moved, in pixels, since the
+	// previous RelativeMouseEvent. Unlike a mouse.Event's position, they
+	// are not limited by the edges of the window or the screen. Positive
+	// DeltaY is downwards.
+	DeltaX, DeltaY float32
+
+	// Time is when the platform says that the event happened, as
+	// Window.EventTime also reports.
+	Time time.Time
+}
+
+// CrossingEvent is sent to a Window's EventDeque when the pointer enters or
+// leaves the window. While the pointer is in the window, its motion is sent
+// as mouse.Events, so a program that shows which part of the window the
+// pointer is over, such as by highlighting a button, should stop doing so
+// when the pointer leaves.
+//
+// While a mouse button is held down, the window that it was pressed in
+// typically keeps receiving the pointer's motion, even outside of the
+// window, and the pointer may not be reported to leave until the button is
+// released. The mobiledriver, whose windows have touches but no pointer,
+// does not send CrossingEvents.
+type CrossingEvent struct {
+	// Entered is whether the pointer entered, rather than left, the window.
+	Entered bool
+
+	// X and Y are the pointer's position, in pixels, when it crossed the
+	// window's edge.
+	X, Y float32
+
+	// Time is when the platform says that the event happened, as
+	// Window.EventTime also reports.
+	Time time.Time
+}
+
+// FocusEvent is sent to a Window's EventDeque when the window gains or loses
+// the keyboard focus. It accompanies the lifecycle.Event whose To or From
+// stage is lifecycle.StageFocused, for programs that only need to know
+// whether they have the focus, such as to show or hide a text caret, and not
+// whether they are visible.
+type FocusEvent struct {
+	// Focused is whether the window gained, rather than lost, the focus.
+	Focused bool
+}
+
+// ScrollDevice is the kind of device that sent a ScrollEvent.
+type ScrollDevice uint8
+
+const (
+	// ScrollDeviceUnknown is a device that the platform does not tell
+	// apart.
+	ScrollDeviceUnknown ScrollDevice = iota
+	// ScrollDeviceWheel is a mouse wheel, which scrolls in whole steps.
+	ScrollDeviceWheel
+	// ScrollDeviceTrackpad is a trackpad, or another device, such as a
+	// touch-sensitive mouse, that scrolls smoothly.
+	ScrollDeviceTrackpad
+)
+
+// ScrollPhase is the phase, within a scroll gesture, of a ScrollEvent.
+type ScrollPhase uint8
+
+const (
+	// ScrollPhaseNone is a scroll that is not part of a gesture, such as a
+	// click of a mouse wheel, or whose platform does not say.
+	ScrollPhaseNone ScrollPhase = iota
+	// ScrollBegin is the first scroll of a gesture, when the user's
+	// fingers start to move on a trackpad.
+	ScrollBegin
+	// ScrollChange is a scroll during a gesture.
+	ScrollChange
+	// ScrollEnd is the end of a gesture, when the user lifts their fingers.
+	// It may be followed by ScrollMomentum events.
+	ScrollEnd
+	// ScrollMomentum is a scroll that the platform sends after a gesture
+	// ends, to continue it with kinetic scrolling that slows down by
+	// itself. Programs that implement kinetic scrolling themselves should
+	// ignore the deltas of these events, as they would otherwise scroll
+	// twice.
+	ScrollMomentum
+	// ScrollMomentumEnd is the end of kinetic scrolling, either because it
+	// slowed to a stop or because the user touched the trackpad again.
+	ScrollMomentumEnd
+)
+
+// ScrollEvent is sent to a Window's EventDeque when the user scrolls, such
+// as with a mouse wheel or by moving two fingers on a trackpad.
+//
+// For programs that do not handle ScrollEvents, the drivers also send each
+// whole step of scrolling, after the ScrollEvent, as a mouse.Event whose
+// Button is mouse.ButtonWheelUp, ButtonWheelDown, ButtonWheelLeft or
+// ButtonWheelRight. Programs that handle ScrollEvents should ignore those.
+type ScrollEvent struct {
+	// X and Y are the pointer's position, in pixels.
+	X, Y float32
+
+	// DeltaX and DeltaY are how far to scroll, in steps, where a step is
+	// how far one click of a typical mouse wheel scrolls, such as three
+	// lines of text. Smooth scrolling has fractional steps. Positive DeltaY
+	// scrolls down, towards the end of a document, and positive DeltaX
+	// scrolls right. Platforms that reverse the direction of scrolling,
+	// such as for "natural" scrolling, have already done so.
+	DeltaX, DeltaY float32
+
+	Device ScrollDevice
+	Phase  ScrollPhase
+
+	Modifiers key.Modifiers
+
+	// Time is when the platform says that the event happened, as
+	// Window.EventTime also reports.
+	Time time.Time
+}
+
+// Touch screens send a golang.org/x/mobile/event/touch.Event for each finger,
+// whose Sequence tells the fingers apart, rather than a mouse.Event.
+
+// PenType is the type of a PenEvent.
+type PenType uint8
+
+const (
+	// PenHover is the pen moving above the surface, close enough to be
+	// sensed, without touching it.
+	PenHover PenType = iota
+	// PenDown is the pen touching the surface.
+	PenDown
+	// PenMove is the pen moving while touching the surface.
+	PenMove
+	// PenUp is the pen leaving the surface.
+	PenUp
+)
+
+// PenEvent is sent to a Window's EventDeque, instead of a mouse.Event, when a
+// pen, or stylus, on a drawing tablet or pen display, moves over the window,
+// or touches or leaves its surface. Platforms that cannot tell pens from
+// mice send mouse.Events instead.
+type PenEvent struct {
+	// X and Y are the pen's position, in pixels.
+	X, Y float32
+
+	Type PenType
+
+	// Pressure is how hard the pen presses on the surface, from 0 to 1. It
+	// is 0 while the pen hovers, and 0.5 for a pen that cannot sense
+	// pressure while it touches the surface.
+	Pressure float32
+
+	// TiltX and TiltY are the pen's angles, in degrees from -90 to 90,
+	// between the surface's normal and the pen, in the planes of the X and
+	// Y axes. Positive TiltX is to the right, and positive TiltY is
+	// downwards. They are 0 for a pen that cannot sense tilt.
+	TiltX, TiltY float32
+
+	// Eraser is whether the pen is erasing, such as with its eraser end.
+	Eraser bool
+
+	// Barrel is whether the pen's barrel button is pressed.
+	Barrel bool
+
+	Modifiers key.Modifiers
+
+	// Time is when the platform says that the event happened, as
+	// Window.EventTime also reports.
+	Time time.Time
 }
 
+// WindowState is whether a window is minimized, maximized or fullscreen.
+type WindowState uint8
+
+const (
+	// WindowNormal is a window that is neither minimized, maximized nor
+	// fullscreen.
+	WindowNormal WindowState = iota
+	// WindowMinimized is a window that is minimized, or iconified, and so
+	// not shown.
+	WindowMinimized
+	// WindowMaximized is a window that fills the work area of its display,
+	// such as by the user clicking its frame's maximize button.
+	WindowMaximized
+	// WindowFullscreen is a window that covers its whole display, without
+	// a frame.
+	WindowFullscreen
+)
+
+// FullscreenMode is how a window covers its display, as set by
+// Window.SetFullscreen.
+type FullscreenMode uint8
+
+const (
+	// FullscreenNone is not fullscreen.
+	FullscreenNone FullscreenMode = iota
+	// FullscreenBorderless is a window, without a frame, that covers its
+	// display, above any task bars, docks or menu bars. It is composited
+	// with the desktop like any other window, so switching to other
+	// applications is quick.
+	FullscreenBorderless
+	// FullscreenExclusive is fullscreen that gives the window its display to
+	// itself, bypassing the desktop's compositor, for lower latency. Drivers
+	// that cannot do so treat it as FullscreenBorderless.
+	FullscreenExclusive
+)
+
+// WindowEdge is an edge or corner of a window, by which Window.StartResize
+// resizes it.
+type WindowEdge uint8
+
+const (
+	WindowEdgeTop WindowEdge = iota
+	WindowEdgeBottom
+	WindowEdgeLeft
+	WindowEdgeRight
+	WindowEdgeTopLeft
+	WindowEdgeTopRight
+	WindowEdgeBottomLeft
+	WindowEdgeBottomRight
+)
+
+// WindowStateEvent is sent to a Window's EventDeque when the window's state
+// changes, whether by the Window's Minimize, Maximize, Restore and
+// SetFullscreen methods or by the user. It is usually followed by a
+// size.Event.
+//
+// Windows are not sent a WindowStateEvent for their initial state, which is
+// WindowNormal.
+type WindowStateEvent struct {
+	State WindowState
+}
+
+// Visibility is how much of a window is seen on screen.
+type Visibility uint8
+
+const (
+	// VisibilityFull means that the window is not known to be covered.
+	VisibilityFull Visibility = iota
+	// VisibilityPartial means that other windows cover part of the window.
+	VisibilityPartial
+	// VisibilityOccluded means that none of the window is seen, such as
+	// when other windows cover all of it, or it is on another virtual
+	// desktop.
+	VisibilityOccluded
+	// VisibilityMinimized means that the window is minimized.
+	VisibilityMinimized
+)
+
+// VisibilityEvent is sent to a Window's EventDeque when how much of the
+// window is seen on screen changes. An app can stop animating, or draw less
+// often, while its window is occluded or minimized, or can have the driver
+// throttle its Publish calls instead, with NewWindowOptions.OccludedPublish.
+//
+// Windows are not sent a VisibilityEvent for their initial visibility, which
+// is VisibilityFull. A minimized window is also sent a WindowStateEvent.
+//
+// The x11driver and the gldriver on X11 report the X server's
+// VisibilityNotify events, but a compositing manager, which draws every
+// window offscreen, leaves every unminimized window fully visible. The
+// mtldriver and the gldriver on macOS report the window's occlusion state,
+// which does not tell partial visibility apart from full visibility. The
+// windriver, d3ddriver and the gldriver on Windows report a window as
+// occluded when the Desktop Window Manager cloaks it, such as when it is on
+// another virtual desktop, but not when other windows cover it. Other
+// drivers only send VisibilityEvents, if at all, for minimized windows.
+type VisibilityEvent struct {
+	Visibility Visibility
+}
+
+// CloseRequestEvent is sent to a Window's EventDeque when the user asks to
+// close the window, such as by clicking its close button, if the window was
+// created with NewWindowOptions.InterceptClose. The window stays open, and
+// its lifecycle stage is unchanged. To allow the close, such as after asking
+// the user whether to save their changes, call the Window's Release method,
+// which closes it. To veto it, do nothing.
+type CloseRequestEvent struct{}
+
+// FileDialogKind is what a file dialog, shown by Window.ShowFileDialog,
+// chooses.
+type FileDialogKind uint8
+
+const (
+	// FileDialogOpen chooses existing files, to open.
+	FileDialogOpen FileDialogKind = iota
+	// FileDialogSave chooses a file name, to save to. The file may or may
+	// not exist, and platforms typically ask the user before choosing an
+	// existing file.
+	FileDialogSave
+	// FileDialogDirectory chooses existing directories.
+	FileDialogDirectory
+)
+
+// FileFilter is a named group of file name patterns, such as "Images" for
+// "*.png" and "*.jpg", that a file dialog can restrict its files to.
+type FileFilter struct {
+	Name string
+	// Patterns are the file names to show, as for path.Match. Platforms
+	// differ in the patterns they support, and "*.ext" is the most portable.
+	Patterns []string
+}
+
+// FileDialogOptions are optional arguments to Window.ShowFileDialog.
+type FileDialogOptions struct {
+	Kind FileDialogKind
+
+	// ID is copied to the dialog's FileDialogEvent, to tell the results of
+	// several dialogs apart.
+	ID int
+
+	// Title is the dialog's title. If empty, the platform chooses one.
+	Title string
+
+	// Directory is the directory that the dialog starts in. If empty, the
+	// platform chooses, typically the directory most recently chosen from.
+	Directory string
+
+	// FileName is the file name that a FileDialogSave dialog initially
+	// suggests. Other kinds of dialog ignore it.
+	FileName string
+
+	// Filters are the groups of files that the user can choose to show, the
+	// first of which is initially shown. If empty, every file is shown.
+	// FileDialogDirectory dialogs ignore them. Platforms whose dialogs have
+	// no choice of filters show the files matching any of them.
+	Filters []FileFilter
+
+	// Multiple is whether the user can choose more than one file or
+	// directory. FileDialogSave dialogs ignore it.
+	Multiple bool
+}
+
+// FileDialogEvent is sent to a Window's EventDeque when a file dialog, shown
+// by its ShowFileDialog method, is closed.
+type FileDialogEvent struct {
+	// ID and Kind are those of the dialog's FileDialogOptions.
+	ID   int
+	Kind FileDialogKind
+
+	// Paths are the absolute paths that the user chose. It is empty if the
+	// user cancelled the dialog, or if Err is non-nil.
+	Paths []string
+
+	// Err is any error that happened after the dialog was shown.
+	Err error
+}
+
+// Menu is a menu of items, for the window's menu bar or a context menu. The
+// items of a menu bar are its menus, each of which is an item with a Submenu.
+type Menu struct {
+	// Title is the menu's title, shown by the item that opens it. Menus
+	// opened by no item, such as context menus, ignore it.
+	Title string
+	Items []MenuItem
+}
+
+// MenuItem is one of the items of a Menu.
+type MenuItem struct {
+	// ID is copied to the MenuEvent sent when the item is chosen, to tell the
+	// items apart. Items with submenus, and separators, cannot be chosen.
+	ID int
+
+	// Title is the text shown for the item. The platform may show some menu
+	// items, such as the application menu's on macOS, under titles of its
+	// own.
+	Title string
+
+	// Separator is whether the item is a line between groups of items,
+	// rather than an item that can be chosen. Its other fields are ignored.
+	Separator bool
+
+	// Submenu, if non-nil, is the menu that the item opens.
+	Submenu *Menu
+
+	// Key and Modifiers are the item's keyboard shortcut, such as
+	// key.CodeS and key.ModControl for saving. A zero Key means no
+	// shortcut. Pressing the shortcut sends the item's MenuEvent instead of
+	// key.Events. On macOS, key.ModMeta is the Command key, and shortcuts
+	// conventionally use it instead of key.ModControl.
+	Key       key.Code
+	Modifiers key.Modifiers
+
+	// Disabled is whether the item is shown, dimmed, without being able to
+	// be chosen.
+	Disabled bool
+
+	// Checked is whether the item is shown with a check mark.
+	Checked bool
+}
+
+// MenuEvent is sent to a Window's EventDeque when the user chooses an item of
+// its menu bar or of a context menu, shown by its ShowContextMenu method. It
+// is also sent to a TrayIcon's EventDeque for the items of its menu.
+type MenuEvent struct {
+	// ID is that of the chosen MenuItem.
+	ID int
+}
+
+// HotKeyEvent is sent to a Window's EventDeque when the user presses a hot key
+// that the window registered, with its RegisterHotKey method, whether or not
+// the window has the keyboard focus. Some platforms send it repeatedly while
+// the key is held down.
+type HotKeyEvent struct {
+	Key       key.Code
+	Modifiers key.Modifiers
+}
+
+// ErrHotKeyInUse is returned by Window.RegisterHotKey when the hot key is
+// already registered, by another application or by another window.
+var ErrHotKeyInUse = errors.New("screen: hot key is already registered")
+
+// Notification is a desktop notification, posted by a Window's Notify method.
+type Notification struct {
+	// ID identifies the notification, in its NotificationEvents and to
+	// Window.CloseNotification.
+	ID int
+
+	Title string
+	Body  string
+
+	// Image, if non-nil, is shown beside the title and body.
+	Image image.Image
+
+	// Actions are the notification's buttons. Their IDs should be non-zero,
+	// as the zero Action of a NotificationEvent means the notification
+	// itself. Windows and web browsers show no buttons.
+	Actions []NotificationAction
+}
+
+// NotificationAction is a button of a Notification.
+type NotificationAction struct {
+	ID    int
+	Title string
+}
+
+// NotificationEvent is sent to a Window's EventDeque when the user activates a
+// notification that it posted, by clicking on it or on one of its buttons.
+type NotificationEvent struct {
+	// ID is that of the Notification.
+	ID int
+
+	// Action is the ID of the chosen NotificationAction, or zero if the user
+	// clicked on the notification itself.
+	Action int
+}
+
+// DragEvent is sent to a Window's EventDeque when data, such as files or text
+// from another application, is dragged over the window or dropped on it.
+//
+// A drag entering the window sends a DragEnter event, then a DragOver event
+// each time that the pointer moves, and then either a DragLeave event, if the
+// drag leaves the window or is cancelled, or a Drop event. Windows accept
+// every drop, as a copy of the data.
+type DragEvent struct {
+	Type DragType
+
+	// X and Y are the pointer's position, in window-space pixels. They are
+	// zero for a DragLeave event.
+	X, Y float32
+
+	// Types are the MIME types of the dragged data, such as "text/uri-list"
+	// for files and "text/plain;charset=utf-8" for text, in the order that
+	// the drag source offered them.
+	Types []string
+
+	// Data is the dropped data. It is only set for a Drop event, as most
+	// platforms only transfer the data once it is dropped.
+	Data DragData
+}
+
+// DragType is the type of a DragEvent.
+type DragType uint8
+
+const (
+	DragEnter DragType = iota
+	DragOver
+	DragLeave
+	Drop
+)
+
+// DragData is data that is dragged into or out of a window.
+type DragData struct {
+	// Files are the absolute paths of dragged files.
+	Files []string
+
+	// Text is dragged text, or empty if there is none.
+	Text string
+
+	// Other is any other dragged data, keyed by MIME type, such as
+	// "image/png".
+	Other map[string][]byte
+}
+
+// Clipboard is a system clipboard. Its methods are safe for concurrent use.
+//
+// TODO: support images and other MIME types, not just text.
+type Clipboard interface {
+	// ReadText returns the clipboard's contents, as text. It returns an empty
+	// string and a nil error if the clipboard is empty or does not hold
+	// text.
+	ReadText() (string, error)
+
+	// WriteText replaces the clipboard's contents with the given text.
+	WriteText(text string) error
+}
+
+// AccessibilityPrefs is a set of accessibility preferences.
+type AccessibilityPrefs uint32
+
+const (
+	// ReduceMotion means that animations should be minimized or disabled.
+	ReduceMotion AccessibilityPrefs = 1 << iota
+	// HighContrast means that colors should have high contrast, such as
+	// when the user has chosen a high contrast theme.
+	HighContrast
+	// ReduceTransparency means that translucent surfaces should be drawn
+	// opaquely.
+	ReduceTransparency
+)
+
+// AccessibilityEvent is sent to a Window's EventDeque when the user's
+// accessibility preferences change.
+type AccessibilityEvent struct {
+	Prefs AccessibilityPrefs
+}
+
+// AccessRole is the kind of user interface element that an AccessNode is,
+// such as a button, for assistive technologies.
+type AccessRole uint8
+
+const (
+	// AccessGroup is a node that only groups its children, such as a
+	// container widget's.
+	AccessGroup        AccessRole = iota
+	AccessLabel                   // Text that cannot be edited.
+	AccessButton                  // Something that does something when pressed.
+	AccessCheckBox                // Something that is checked or not, as its Value says.
+	AccessTextField               // One line of text that can be edited.
+	AccessTextEditor              // Lines of text that can be edited.
+	AccessImage                   // A picture, described by its Name.
+	AccessList                    // A list of AccessListItems.
+	AccessListItem                // An item of an AccessList.
+	AccessTable                   // A table of AccessColumnHeaders and AccessCells.
+	AccessColumnHeader            // The header of a table's column.
+	AccessCell                    // A cell of a table.
+	AccessScrollArea              // A view that scrolls its children.
+	AccessSlider                  // A value in a range, as its Value says.
+)
+
+// AccessNode describes a user interface element, such as a widget, to the
+// platform's assistive technologies, such as screen readers. The nodes of a
+// window form a tree, whose root is the window's content area.
+type AccessNode struct {
+	// ID tells the nodes apart, in AccessActionEvents. It should be unique
+	// within the tree, and should be the same for the same element each time
+	// that the tree is set, so that, for example, a screen reader keeps its
+	// place.
+	ID int
+
+	Role AccessRole
+
+	// Name is what the element is called, such as a button's text, and Value
+	// is its content, such as a text field's text.
+	Name, Value string
+
+	// Bounds is where the element is, in window-space pixels.
+	Bounds image.Rectangle
+
+	// Focused is whether the element has the keyboard focus. Disabled is
+	// whether it is shown, dimmed, without being able to be used.
+	Focused, Disabled bool
+
+	Children []*AccessNode
+}
+
+// AccessAction is what an assistive technology asks an element to do.
+type AccessAction uint8
+
+const (
+	// AccessPress asks that the element be activated, as if the user tapped
+	// on it.
+	AccessPress AccessAction = iota
+	// AccessFocus asks that the element be given the keyboard focus.
+	AccessFocus
+)
+
+// AccessActionEvent is sent to a Window's EventDeque when an assistive
+// technology asks for an action on a node of the tree set by its
+// SetAccessTree method.
+type AccessActionEvent struct {
+	// ID is that of the AccessNode.
+	ID     int
+	Action AccessAction
+}
+
+// ColorScheme is a preferred color scheme, for an application's colors.
+type ColorScheme uint8
+
+const (
+	// LightColorScheme means dark text on a light background.
+	LightColorScheme ColorScheme = iota
+	// DarkColorScheme means light text on a dark background, such as when
+	// the user has switched on macOS's Dark Mode, or chosen Windows's dark
+	// app mode.
+	DarkColorScheme
+)
+
+// ColorSchemeEvent is sent to a Window's EventDeque when the user's preferred
+// color scheme changes.
+type ColorSchemeEvent struct {
+	Scheme ColorScheme
+}
+
+// TextEvent is sent to a Window's EventDeque when an input method, such as
+// those used to enter Chinese, Japanese or Korean text, composes or commits
+// text.
+//
+// Text typed without an input method is not sent as TextEvents. Instead, each
+// rune is the Rune field of the key.Event for its key press.
+type TextEvent struct {
+	// Preedit is the text that the input method is composing, and has not yet
+	// committed. It replaces the Preedit text of any earlier TextEvent. An
+	// empty Preedit means that the composition has ended or been cancelled.
+	//
+	// Some input methods draw the text being composed themselves, in which
+	// case the driver never sends a non-empty Preedit.
+	Preedit string
+
+	// PreeditCursor is the position of the input method's cursor, as a byte
+	// offset into Preedit.
+	PreeditCursor int
+
+	// Commit is the text, if any, that the input method has finished
+	// composing, to be inserted at the text cursor.
+	Commit string
+
+	// Time is when the platform says that the event happened, as
+	// Window.EventTime also reports.
+	Time time.Time
+}
+
+// KeyEvent is sent to a Window's EventDeque when a key is pressed, released or
+// repeated, with more about the key than a key.Event holds: where it is on
+// the keyboard, independent of the keyboard layout, and what it types in the
+// current layout, independent of the modifiers.
+//
+// KeyEvents are only sent to windows created with NewWindowOptions.KeyEvents
+// set, instead of the key.Events that are otherwise sent. The mobiledriver
+// does not send KeyEvents.
+type KeyEvent struct {
+	// Event is the key.Event. Its Rune is what the key types in the current
+	// keyboard layout, with the modifiers that are held. Its Code is as for
+	// the driver's key.Events, which, depending on the platform, is either
+	// the physical key or the key that types that rune on a US keyboard.
+	key.Event
+
+	// Physical is the code of the physical key, wherever the current layout
+	// puts the characters, such as key.CodeQ for the key to the right of Tab,
+	// whether it types 'q', 'a' on a French AZERTY layout, or an apostrophe
+	// on a Dvorak layout. It is key.CodeUnknown if the platform does not
+	// say, as for the VNC protocol.
+	Physical key.Code
+
+	// Scancode is the platform's code for the physical key: an X11 keycode,
+	// a Windows scan code, with 0xe000 added for an extended key, or a macOS
+	// virtual key code. It is zero if the platform does not say, as in a
+	// browser.
+	Scancode uint32
+
+	// Unmodified is what the key types in the current keyboard layout when
+	// no modifier keys are held, such as 'a' for the key to the right of Tab
+	// on an AZERTY layout, even with Control held. Keyboard shortcuts, such as
+	// Control+Z, which are labelled for the characters that their keys type,
+	// should match it rather than Rune or Code. It is -1 if the key does not
+	// type a character, such as an arrow key, or if the platform does not
+	// say.
+	Unmodified rune
+
+	// Repeat is whether the event is an automatic repeat of a key that is
+	// being held down. The Event's Direction is key.DirNone for a repeat, and
+	// key.DirPress only for the first press.
+	Repeat bool
+
+	// Time is when the platform says that the event happened, as
+	// Window.EventTime also reports.
+	Time time.Time
+}
+
+// KeyboardLayoutEvent is sent to a Window's EventDeque when the user switches
+// keyboard layouts, such as from a US English to a French layout, which
+// changes the Rune and Unmodified fields of the KeyEvents that follow.
+//
+// The x11driver, gldriver, mtldriver, waylanddriver and windriver send
+// KeyboardLayoutEvents. Browsers do not tell web pages about keyboard layouts.
+type KeyboardLayoutEvent struct {
+	// Layout is the platform's name for the new layout: an XKB layout, such
+	// as "fr" or "us(dvorak)", on X11, the name of the layout's XKB group,
+	// such as "French", on Wayland, a keyboard layout identifier, such as
+	// "0000040C", on Windows, or an input source identifier, such as
+	// "com.apple.keylayout.French", on macOS.
+	Layout string
+}
+
+// Mouse buttons, in addition to those defined by the
+// golang.org/x/mobile/event/mouse package, that drivers report in a
+// mouse.Event's Button field.
+//
+// Every driver numbers mouse buttons consistently. ButtonLeft, ButtonMiddle
+// and ButtonRight are 1, 2 and 3. The wheel "buttons" are negative, and
+// horizontal scrolling, such as by tilting the wheel, is reported as
+// ButtonWheelLeft or ButtonWheelRight. The side buttons commonly used to
+// navigate back and forward are MouseButtonBack (8) and MouseButtonForward
+// (9), and any further buttons are numbered from 10 upwards.
+//
+// The native button numbers map to these as follows:
+//
+//	X11:     1, 2 and 3 are left, middle and right, 4 to 7 are the wheel
+//	         and 8 and above are unchanged.
+//	Windows: XBUTTON1 and XBUTTON2 are back and forward.
+//	Cocoa:   0, 1 and 2 are left, right and middle, 3 and 4 are back and
+//	         forward, and 5 and above are 10 and above.
+const (
+	MouseButtonBack    mouse.Button = 8
+	MouseButtonForward mouse.Button = 9
+)
+
 // TODO: rename Buffer to Image, to be less confusing with a Window's back and
 // front buffers.
 
@@ -164,13 +1057,94 @@ type Texture interface {
 	// image.Rectangle{Max: t.Size()}.
 	Bounds() image.Rectangle
 
+	// Format returns the format of the Texture's pixels. A Texture created
+	// with PixelFormatBGRA8 reports PixelFormatRGBA8.
+	Format() PixelFormat
+
 	Uploader
 
+	// Download returns a copy of the Texture's pixels within sr, such as for
+	// taking a screenshot or for verifying rendered output in a test. The
+	// returned image's bounds are sr, clipped to the Texture's bounds, and
+	// then translated so that sr.Min is at dp.
+	//
+	// Download waits for any pending uploads and fills to resolve. It returns
+	// an error if the Texture has been released.
+	Download(dp image.Point, sr image.Rectangle) (*image.RGBA, error)
+
 	// TODO: also implement Drawer? If so, merge the Uploader and Drawer
 	// interfaces??
 }
 
-// EventDeque is an infinitely buffered double-ended queue of events.
+// EventFilter is a filter of a Window's events, added by its AddEventFilter
+// method. It returns the event to pass on to the next filter, or to the
+// application if it is the last one, which can be e itself or another event,
+// or nil to consume e.
+type EventFilter func(e interface{}) interface{}
+
+// OverflowPolicy is what happens to an event that is sent to a full event
+// queue.
+type OverflowPolicy uint8
+
+const (
+	// OverflowDropOldest drops the queue's oldest pending event, to make
+	// room for the sent event.
+	OverflowDropOldest OverflowPolicy = iota
+
+	// OverflowCoalesce merges the sent event into the queue's newest pending
+	// event, if they are both mouse motion events, as NewWindowOptions'
+	// KeepAllMoves describes, and otherwise drops the oldest pending event.
+	OverflowCoalesce
+
+	// OverflowBlock blocks the sender until the app takes an event from the
+	// queue. This stalls the driver, and so the window's user interface,
+	// while the queue is full. It deadlocks if the goroutine that calls
+	// NextEvent, or an event filter, sends to the full queue.
+	OverflowBlock
+)
+
+// EventQueueOptions bound a window's queue of pending events of one priority.
+type EventQueueOptions struct {
+	// Capacity is how many pending events the queue holds before it
+	// overflows. Zero means that it is unbounded.
+	Capacity int
+
+	// Overflow is what happens to an event sent while the queue is full.
+	Overflow OverflowPolicy
+}
+
+// EventQueueStats are the statistics of a window's queue of pending events of
+// one priority.
+type EventQueueStats struct {
+	Priority int
+
+	// Pending is how many events the queue holds, and MaxPending the most
+	// that it has held at once.
+	Pending    int
+	MaxPending int
+
+	// Dropped is how many events the queue dropped as it overflowed, and
+	// Coalesced how many were merged into another event.
+	Dropped   uint64
+	Coalesced uint64
+}
+
+// Timer is a scheduled sending of an event, returned by Window.SendLater.
+type Timer interface {
+	// Stop stops the Timer's sends that are still to come. It reports
+	// whether there were any, as it is false if the Timer was already
+	// stopped, or has sent its only event.
+	Stop() bool
+
+	// Reset schedules the Timer's next send for the time at, keeping its
+	// event and interval, even if the Timer was stopped or has sent its only
+	// event, unless its window has been released. It reports whether the
+	// Timer had sends still to come, as Stop does.
+	Reset(at time.Time) bool
+}
+
+// EventDeque is a buffered double-ended queue of events. A Window's buffers
+// events without limit, unless NewWindowOptions.EventQueues bound it.
 type EventDeque interface {
 	// Send adds an event to the end of the deque. They are returned by
 	// NextEvent in FIFO order.
@@ -204,19 +1178,506 @@ type EventDeque interface {
 type Window interface {
 	// Release closes the window.
 	//
-	// The behavior of the Window after Release, whether calling its methods or
-	// passing it as an argument, is undefined.
+	// Release is idempotent, and may be called from any goroutine, even while
+	// other goroutines are drawing to the window. After Release, calls to the
+	// Window's Uploader, Drawer and Publish methods are no-ops. The behavior
+	// of the Window after Release, whether calling its other methods or
+	// passing it as an argument, is otherwise undefined.
 	Release()
 
 	EventDeque
 
+	// AddEventFilter adds f to the end of the window's chain of event
+	// filters, through which each event passes before NextEvent returns it,
+	// and returns a function that removes f. A filter can observe events,
+	// such as for a debugging overlay, transform them, such as to remap keys,
+	// or consume them, such as while an automation layer is in control. It
+	// can also Send events, such as gestures that it recognizes.
+	//
+	// Filters are called, in the order they were added, on the goroutine
+	// that calls NextEvent. Adding and removing filters is safe from any
+	// goroutine, and takes effect from the next event.
+	AddEventFilter(f EventFilter) (remove func())
+
+	// EventTime returns the time of the event that NextEvent most recently
+	// returned, or that the filters are filtering. For input events, it is
+	// the time that the platform reported for the native event, which is
+	// more precise, such as for estimating velocities, than when NextEvent
+	// returned it. Otherwise, it is when the event was sent. Like the
+	// filters, it should only be called on the goroutine that calls
+	// NextEvent.
+	//
+	// This package's input events, such as KeyEvent and ScrollEvent, also
+	// hold that time in their Time field. EventTime is how to get the time
+	// of the key, mouse and touch events, which have no such field.
+	EventTime() time.Time
+
+	// EventQueueStats returns the statistics of the queues of the window's
+	// pending events, one for each priority of the events sent so far, in
+	// decreasing order of priority, so that apps can tell when they are
+	// falling behind. The events sent by SendFirst are not counted.
+	EventQueueStats() []EventQueueStats
+
 	Uploader
 
 	Drawer
 
-	// Publish flushes any pending Upload and Draw calls to the window, and
-	// swaps the back buffer to the front.
+	// DrawImage draws src on the window, such that src.Bounds().Min in
+	// src-space aligns with dp in dst-space. Any image.Image is accepted, and
+	// converted as necessary.
+	//
+	// DrawImage is a convenience for drawing one-off images: the driver
+	// manages a transient Texture on the caller's behalf. It is less efficient
+	// than uploading to a Buffer and Texture once and re-using that Texture,
+	// for an image that is drawn repeatedly.
+	DrawImage(dp image.Point, src image.Image, op draw.Op, opts *DrawOptions)
+
+	// NewLayer returns a new Layer of the given size for this window. Its
+	// initial contents are transparent and its initial position is (0, 0).
+	NewLayer(z int, size image.Point) (Layer, error)
+
+	// Publish flushes any pending Upload and Draw calls to the window,
+	// composites the window's Layers, and swaps the back buffer to the front.
 	Publish() PublishResult
+
+	// AddCaptureHook adds fn to the end of the window's capture hooks, which
+	// are called with a copy of each frame that Publish shows, and returns a
+	// function that removes fn. This is for taking screenshots, by removing
+	// the hook after its first frame, for recording screencasts, and for
+	// comparing frames against golden images in tests.
+	//
+	// Reading a frame back is expensive on some drivers, such as those that
+	// render on the GPU, so a window only does so while it has capture
+	// hooks. The hooks are called, in the order they were added, on the
+	// goroutine that calls Publish, before it returns, so a slow hook, such
+	// as one encoding video, should pass its frames to another goroutine.
+	// The x11driver and windriver have no back buffer, so they read each
+	// frame back from the window on screen, and the parts of it covered by
+	// other windows may be missing. Nothing is captured once the window has
+	// been released.
+	AddCaptureHook(fn func(f CapturedFrame)) (remove func())
+
+	// RenderFrame calls fn to draw to the window's back buffer, and then waits
+	// for that drawing to finish. Unlike Publish, it does not composite the
+	// window's Layers or swap the back buffer to the front, so nothing is
+	// shown on screen. This is useful for warming up a driver's caches, or for
+	// capturing a frame that is never displayed.
+	//
+	// The Drawer passed to fn is only valid during the call to fn. RenderFrame
+	// returns an error if the window has been released.
+	RenderFrame(fn func(d Drawer)) error
+
+	// NextFrame asks for a FrameEvent to be sent to the window's EventDeque
+	// when the display is next ready for a new frame, typically at its next
+	// vertical blank. Drawing, publishing and calling NextFrame again in
+	// response to each FrameEvent paces an animation to the display's refresh
+	// rate, without tearing or publishing more frames than can be shown.
+	//
+	// At most one FrameEvent is sent for the calls to NextFrame made before
+	// it. NextFrame does nothing if the window has been released.
+	NextFrame()
+
+	// SendLater sends event to the window's EventDeque at the time at, and
+	// then, if every is positive, every interval after that, until the
+	// returned Timer is stopped or the window is released. Animations,
+	// blinking cursors and tooltips can use it instead of each needing a
+	// goroutine that sleeps and calls Send. EventTime reports when each
+	// event was due.
+	//
+	// The window's Timers share one wakeup, and events that fall due
+	// together are sent together. While the window is sent FrameEvents, an
+	// event due within half a frame of one is sent just before it, so that
+	// the app wakes once for both, and draws the event's effect in that
+	// frame. A repeating Timer that falls behind skips the sends it missed.
+	SendLater(event interface{}, at time.Time, every time.Duration) Timer
+
+	// GLInfo describes the OpenGL implementation that renders the window. It
+	// is the zero value if the window is not rendered by OpenGL, as for the
+	// x11driver, windriver, waylanddriver, mtldriver, wasmdriver and
+	// headlessdriver, or if the window has been released.
+	GLInfo() GLInfo
+
+	// Begin returns a new immediate-mode drawing Context for the window. The
+	// Context's End method publishes the window.
+	Begin() *Context
+
+	// SetTitle sets the window's title. It does nothing if the window has
+	// been released.
+	SetTitle(title string)
+
+	// SetIcon sets the icon shown for the window by the taskbar, window
+	// switcher and title bar, or restores the default icon, for a nil icon.
+	// The platform scales the icon as it needs, so it should be square, and
+	// large, such as 256 by 256 pixels. On macOS, where windows have no
+	// icons, it sets the application's dock icon. SetIcon does nothing if
+	// the window has been released.
+	SetIcon(icon image.Image)
+
+	// SetBadge shows a short label, such as a count of unread messages, on
+	// the window's taskbar button, or removes it, for an empty label. On
+	// macOS, it shows the label on the application's dock icon. Platforms
+	// that cannot show text may show a mark instead, and some have no badges
+	// at all. SetBadge does nothing if the window has been released.
+	SetBadge(label string)
+
+	// SetProgress shows the progress of a long operation, from 0 to 1, on
+	// the window's taskbar button, or removes it, for a negative progress.
+	// On macOS, it shows the progress on the application's dock icon. Some
+	// platforms have no such progress. SetProgress does nothing if the
+	// window has been released.
+	SetProgress(progress float64)
+
+	// SetSize requests that the window's content area be resized to width by
+	// height pixels. The window is sent a size.Event when the new size takes
+	// effect. The operating system or window manager may constrain or ignore
+	// the request. SetSize does nothing if the window has been released.
+	SetSize(width, height int)
+
+	// SetPosition requests that the window be moved so that the top-left
+	// corner of its frame is at p, in screen pixels, as for
+	// NewWindowOptions.Position. The operating system or window manager may
+	// constrain or ignore the request. SetPosition does nothing if the window
+	// has been released.
+	SetPosition(p image.Point)
+
+	// GetGeometry returns the window's position, as for SetPosition, and the
+	// size of its content area, as for SetSize. It may not yet reflect recent
+	// calls to SetSize and SetPosition, which some platforms apply
+	// asynchronously. It returns zero values if the window has been released.
+	GetGeometry() (position, size image.Point)
+
+	// SetSizeConstraints sets the smallest and largest sizes, in pixels,
+	// that the user can resize the window's content area to. A zero width
+	// or height, in min or max, leaves that dimension unconstrained, so
+	// that SetSizeConstraints(image.Point{}, image.Point{}) removes the
+	// constraints. A fullscreen window ignores them, and they are ignored
+	// for a window created with NewWindowOptions.FixedSize, which the user
+	// cannot resize at all.
+	//
+	// SetSizeConstraints and SetAspectRatio do nothing if the window has
+	// been released, or on the wasmdriver, as the user cannot resize a
+	// canvas. The window manager may ignore them, such as while the window
+	// is maximized.
+	SetSizeConstraints(min, max image.Point)
+
+	// SetAspectRatio keeps the ratio of the width to the height of the
+	// window's content area, while the user resizes it, at that of aspect,
+	// such as image.Point{16, 9}. A zero or negative aspect removes the
+	// constraint. Wayland compositors cannot constrain the aspect ratio, so
+	// the waylanddriver instead picks the largest size of that aspect
+	// ratio that fits the size that the compositor proposes.
+	SetAspectRatio(aspect image.Point)
+
+	// SetTextInputRect tells the window's input method, if any, where the
+	// text cursor is, in window-space pixels, so that the input method can
+	// place its composition and candidate windows next to it. It does
+	// nothing if the window has been released.
+	//
+	// Input methods send the composed text as TextEvents. The x11driver,
+	// waylanddriver, wasmdriver and mobiledriver do not yet support input
+	// methods, and ignore SetTextInputRect.
+	SetTextInputRect(r image.Rectangle)
+
+	// SetCursor sets the mouse cursor's appearance while it is over the
+	// window's content area. A new window has the CursorArrow shape. It does
+	// nothing if the window has been released.
+	SetCursor(c Cursor)
+
+	// SetCursorVisible shows or hides the mouse cursor while it is over the
+	// window's content area. Hiding the cursor does not forget the Cursor
+	// set by SetCursor, which is shown again after SetCursorVisible(true). It
+	// does nothing if the window has been released.
+	SetCursorVisible(visible bool)
+
+	// SetPointerCapture captures or releases the mouse pointer. While the
+	// window has captured it, the pointer is hidden and held in place, and
+	// moving the mouse sends RelativeMouseEvents instead of mouse.Events.
+	// Mouse buttons and wheels still send mouse.Events. This is for
+	// controls, such as a 3D camera's, that turn the mouse's motion into
+	// something other than a pointer's position.
+	//
+	// Depending on the platform, the window losing the keyboard focus may end
+	// the capture, so a window should capture the pointer again when it
+	// regains the focus. SetPointerCapture does nothing if the window has
+	// been released.
+	SetPointerCapture(capture bool)
+
+	// StartDrag starts dragging data out of the window, so that it can be
+	// dropped on another window or application, as a copy. It should be
+	// called while a mouse button is held down, typically in response to the
+	// mouse.Event that pressed it, and the drag follows the pointer until the
+	// button is released. StartDrag does not wait for the drag to finish.
+	//
+	// StartDrag returns an error if the platform cannot start a drag, or if
+	// the window has been released.
+	StartDrag(data DragData) error
+
+	// StartMove starts moving the window interactively, as if the user had
+	// dragged its title bar, so that a window drawing its own title bar,
+	// such as an Undecorated one, can still be moved by the platform's
+	// window manager, with its snapping to screen edges and the like. It
+	// should be called while the left mouse button is held down, typically
+	// in response to the mouse.Event that pressed it, and the window follows
+	// the pointer until the button is released. StartMove does not wait for
+	// the move to finish.
+	//
+	// StartMove returns an error if the platform cannot move the window, or
+	// if the window has been released.
+	StartMove() error
+
+	// StartResize is like StartMove, but resizes the window by the given
+	// edge or corner, as if the user had dragged that part of its frame.
+	StartResize(edge WindowEdge) error
+
+	// ShowFileDialog shows the platform's dialog for choosing files to open,
+	// a file to save to, or directories, owned by the window. A nil opts
+	// means to choose one file to open. ShowFileDialog does not wait for the
+	// user to choose: the dialog's result is sent to the window's EventDeque
+	// as a FileDialogEvent.
+	//
+	// ShowFileDialog returns an error if the platform cannot show the
+	// dialog, or if the window has been released.
+	ShowFileDialog(opts *FileDialogOptions) error
+
+	// SetMenuBar sets the window's menu bar, whose items are its menus, or
+	// removes it, for a nil bar. The window keeps no reference to bar, so
+	// changing the menus, such as to disable an item, means setting the menu
+	// bar again. On macOS, whose menu bar is the application's, it is shown
+	// while the window is the key window, after the application's own menu.
+	//
+	// SetMenuBar returns an error if the platform has no menu bars, or if the
+	// window has been released.
+	SetMenuBar(bar *Menu) error
+
+	// ShowContextMenu pops up a menu at p, in window-space pixels. It does not
+	// wait for the user to choose: the chosen item, if any, is sent to the
+	// window's EventDeque as a MenuEvent.
+	//
+	// ShowContextMenu returns an error if the platform has no menus of its
+	// own, or if the window has been released.
+	ShowContextMenu(m *Menu, p image.Point) error
+
+	// SetAccessTree describes the window's contents to the platform's
+	// assistive technologies, such as screen readers, as a tree of nodes
+	// whose root is the window's content area, or removes the description,
+	// for a nil root. The window keeps no reference to root, so changing the
+	// contents means setting the tree again. Actions that assistive
+	// technologies ask for are sent to the window's EventDeque as
+	// AccessActionEvents.
+	//
+	// SetAccessTree returns an error if the driver does not support the
+	// platform's accessibility API, or if the window has been released.
+	SetAccessTree(root *AccessNode) error
+
+	// RegisterHotKey registers a system-wide keyboard shortcut, the key k
+	// pressed with exactly the modifiers mods, such as key.CodeSpacebar and
+	// key.ModAlt for a launcher: pressing it sends a HotKeyEvent to the
+	// window's EventDeque, instead of key.Events to whichever application
+	// has the keyboard focus. On macOS, key.ModMeta is the Command key, and
+	// on Windows it is the Windows key. Registering a hot key that the
+	// window has already registered does nothing.
+	//
+	// RegisterHotKey returns ErrHotKeyInUse if another application or
+	// another window has registered the hot key. It returns another error if
+	// no key on the keyboard has the code k, if the platform has no global
+	// shortcuts, as for Wayland compositors and web browsers, or if the
+	// window has been released. Releasing the window unregisters its hot
+	// keys.
+	RegisterHotKey(k key.Code, mods key.Modifiers) error
+
+	// UnregisterHotKey unregisters a hot key that the window registered. It
+	// does nothing if the window did not register it.
+	UnregisterHotKey(k key.Code, mods key.Modifiers)
+
+	// Notify posts a desktop notification, shown by the platform outside of
+	// the window, such as in a notification center, whose activation is
+	// sent to the window's EventDeque as a NotificationEvent. Posting a
+	// notification with the ID of one that the window posted, and that is
+	// still shown, replaces it. Notify does not wait for the notification to
+	// be shown.
+	//
+	// Notify returns an error if the platform has no notifications, if the
+	// user has not allowed the application to post them, or if the window
+	// has been released.
+	Notify(n *Notification) error
+
+	// CloseNotification removes a notification that the window posted, with
+	// the given ID, if it is still shown.
+	CloseNotification(id int)
+
+	// Minimize requests that the window be minimized, or iconified.
+	//
+	// Minimize, Maximize, Restore and SetFullscreen send the window a
+	// WindowStateEvent when its state changes. The operating system or
+	// window manager may ignore the request. They do nothing if the window
+	// has been released.
+	Minimize()
+
+	// Maximize requests that the window be maximized, filling the work area
+	// of its display.
+	Maximize()
+
+	// Restore requests that the window be restored from being minimized,
+	// maximized or fullscreen, to the WindowNormal state and its earlier
+	// size and position.
+	Restore()
+
+	// SetFullscreen requests that the window enter fullscreen, in the given
+	// mode, or leave it, for FullscreenNone. Leaving fullscreen restores the
+	// window's earlier state, such as being maximized, as well as its earlier
+	// size and position.
+	SetFullscreen(mode FullscreenMode)
+
+	// SetAlwaysOnTop requests that the window be kept above other windows
+	// that are not always on top, even while it does not have the keyboard
+	// focus, as for tool palettes and overlays, or not.
+	//
+	// SetAlwaysOnTop, SetOpacity, Raise and Lower do nothing if the window
+	// has been released, and the operating system or window manager may
+	// ignore them. Wayland has no protocol for them, so that they do
+	// nothing on the waylanddriver.
+	SetAlwaysOnTop(onTop bool)
+
+	// SetOpacity sets the opacity of the whole window, including its
+	// frame, from 0, for fully transparent, to 1, for opaque, which is a
+	// new window's opacity. Other values are clamped to that range. The
+	// window's opacity multiplies the alpha of a Transparent window's
+	// pixels. Even a fully transparent window still receives mouse events.
+	// On X11, opacity depends on a compositing manager that honors the
+	// _NET_WM_WINDOW_OPACITY property.
+	SetOpacity(opacity float32)
+
+	// Raise requests that the window be moved above the other windows at
+	// its level, without giving it the keyboard focus.
+	Raise()
+
+	// Lower requests that the window be moved below the other windows at
+	// its level.
+	Lower()
+
+	// ColorSpace returns the color space that the window's pixels are in, as
+	// set by SetColorSpace. It is ColorSpaceSRGB for a new window.
+	ColorSpace() ColorSpace
+
+	// SetColorSpace tags the window's pixels as being in the color space cs,
+	// so that the operating system converts them to the color space of the
+	// display that shows them. Content with colors outside of sRGB, such as
+	// photographs taken in Display P3, is only shown faithfully on a wide
+	// gamut display, such as one whose Display.ColorSpace is
+	// ColorSpaceDisplayP3, by a window tagged with that color space.
+	//
+	// Every driver accepts ColorSpaceSRGB. Only the gldriver and mtldriver on
+	// macOS, and the headlessdriver, which has no display to convert to,
+	// accept other color spaces. SetColorSpace returns an error for them on
+	// other drivers, or if the window has been released.
+	SetColorSpace(cs ColorSpace) error
+}
+
+// CursorShape is one of the operating system's standard mouse cursors.
+type CursorShape int
+
+const (
+	CursorArrow      CursorShape = iota // The default cursor.
+	CursorIBeam                         // Text, that can be selected.
+	CursorCrosshair                     // Precise selection, such as of pixels.
+	CursorHand                          // A link, or something else to click.
+	CursorResizeNS                      // Resizing vertically.
+	CursorResizeEW                      // Resizing horizontally.
+	CursorResizeNWSE                    // Resizing from the top left or bottom right.
+	CursorResizeNESW                    // Resizing from the top right or bottom left.
+	CursorResizeAll                     // Moving, in any direction.
+	CursorNotAllowed                    // An action that is not allowed.
+	CursorWait                          // The program is busy.
+)
+
+// Cursor is the appearance of the mouse cursor.
+type Cursor struct {
+	// Image, if non-nil and non-empty, is a custom cursor. Its pixels are
+	// shown at the display's resolution, like the pixels of a window. Its
+	// alpha channel is the cursor's transparency. Operating systems may
+	// limit the size of cursors, typically to 32x32 or larger.
+	//
+	// The image is copied, so it may be modified after SetCursor returns.
+	Image *image.RGBA
+
+	// Hotspot is the point in Image, relative to Image.Bounds().Min, that is
+	// the mouse's position.
+	Hotspot image.Point
+
+	// Shape is the standard cursor to show when there is no Image.
+	Shape CursorShape
+}
+
+// HasImage returns whether c is a custom cursor, rather than a standard
+// shape.
+func (c Cursor) HasImage() bool {
+	return c.Image != nil && !c.Image.Rect.Empty()
+}
+
+// GLInfo describes an OpenGL implementation.
+type GLInfo struct {
+	// Vendor, Renderer and Version are the GL_VENDOR, GL_RENDERER and
+	// GL_VERSION strings. The Renderer typically names the GPU.
+	Vendor, Renderer, Version string
+
+	// Samples is the number of samples per pixel of the window's back
+	// buffer, as requested by NewWindowOptions.Samples. It is zero or one if
+	// the window is not multisampled.
+	Samples int
+}
+
+// Layer is part of a Window's contents that is retained by the driver between
+// calls to Publish. Each Publish composites every Layer, in Z order, over what
+// has otherwise been drawn on the window since the previous Publish.
+//
+// A Layer's contents are only modified by uploading to, filling or drawing on
+// it, so that re-painting a window whose Layers have not changed is cheap: an
+// unchanged Layer is composited from its existing pixels, typically with a
+// single textured quad, however expensive it was to draw. Scrolling or
+// animating such content only needs a new transform or transparency. A Layer
+// is not a Texture, and cannot be passed as the src of a Drawer's methods.
+//
+// Drawing on a Layer uses Layer-space coordinates, in which the Layer's
+// top-left pixel is at (0, 0). Drivers draw on a Layer as they draw on a
+// Window, such as with a GPU Framebuffer, except that depth testing is not
+// supported. On drivers whose Textures cannot be drawn on, drawing on a Layer
+// is emulated in software, which is correct but slow.
+type Layer interface {
+	// Release releases the Layer's resources and removes it from its Window.
+	//
+	// The behavior of the Layer after Release, whether calling its methods or
+	// passing it as an argument, is undefined.
+	Release()
+
+	// Size returns the size of the Layer's image.
+	Size() image.Point
+
+	// Bounds returns the bounds of the Layer's image. It is equal to
+	// image.Rectangle{Max: l.Size()}.
+	Bounds() image.Rectangle
+
+	Uploader
+	Drawer
+
+	// Z returns the Layer's compositing order. Layers with a higher Z are
+	// composited over those with a lower Z. Layers with equal Z are
+	// composited in the order that they were created.
+	Z() int
+
+	// Move sets where, in Window-space, the Layer's top-left pixel is
+	// composited. It is equivalent to calling SetTransform with a
+	// translation by dp.
+	Move(dp image.Point)
+
+	// SetTransform sets the transformation from Layer-space to Window-space
+	// with which the Layer is composited. The initial transform is the
+	// identity.
+	SetTransform(src2dst f64.Aff3)
+
+	// SetTransparency sets the transparency with which the Layer is
+	// composited, as for DrawOptions.Transparency. The initial transparency
+	// is zero, meaning opaque.
+	SetTransparency(transparency uint16)
 }
 
 // PublishResult is the result of an Window.Publish call.
@@ -224,6 +1685,24 @@ type PublishResult struct {
 	// BackBufferPreserved is whether the contents of the back buffer was
 	// preserved. If false, the contents are undefined.
 	BackBufferPreserved bool
+
+	// PresentTime is when the frame was presented: handed to the display,
+	// compositor or, for the headlessdriver, the window's front buffer. For
+	// drivers that wait for the display before swapping buffers, such as
+	// the gldriver, mtldriver and d3ddriver, it is close to when the frame
+	// was first shown. It is the zero Time if the frame was dropped.
+	PresentTime time.Time
+
+	// Interval is the time between the PresentTimes of the frame and of the
+	// previous frame that the window presented, or zero if there was none.
+	// An Interval of about one over the display's RefreshRate means that
+	// the animation is keeping up. A longer one means that frames were
+	// missed, such as when an app takes too long to draw.
+	Interval time.Duration
+
+	// Dropped is whether the frame was not presented, such as because the
+	// window is hidden, or has been released.
+	Dropped bool
 }
 
 // NewWindowOptions are optional arguments to NewWindow.
@@ -236,9 +1715,269 @@ type NewWindowOptions struct {
 	// Title specifies the window title.
 	Title string
 
+	// Position, if non-nil, specifies the screen position, in pixels, of the
+	// top-left corner of the new window's frame. If nil, the operating system
+	// or window manager chooses the position. Either may still override an
+	// explicit position.
+	Position *image.Point
+
+	// FixedSize is whether the user should be prevented from resizing the
+	// new window.
+	FixedSize bool
+
+	// Hidden is whether the new window should not be shown on screen. A
+	// hidden window can still be drawn to, for example by RenderFrame.
+	Hidden bool
+
+	// DepthBits, if non-zero, requests that the window have a depth buffer of
+	// at least that many bits per pixel. Drivers may provide fewer bits than
+	// requested, or none at all, in which case DrawOptions.Depth is ignored.
+	// The gldriver provides 16 bits. The x11driver, windriver, waylanddriver,
+	// mtldriver, wasmdriver and headlessdriver provide none.
+	DepthBits int
+
+	// Samples, if greater than one, requests that the window be multisampled
+	// with that many samples per pixel, which anti-aliases the edges of
+	// what is drawn, such as rotated Textures, at the cost of more memory and
+	// fill rate. Drivers may provide fewer samples than requested, or none at
+	// all, and Window.GLInfo reports how many were actually provided. The
+	// gldriver chooses a multisampled EGL config on Windows and X11, and a
+	// multisampled pixel format on macOS, falling back to a single sample if
+	// there is none. Other drivers ignore this field.
+	Samples int
+
+	// GLErrorPolicy is what to do when an OpenGL error is detected at the end
+	// of a frame, when the window is published. Only the gldriver uses
+	// OpenGL, and other drivers ignore this field.
+	GLErrorPolicy GLErrorPolicy
+
+	// WarmUp is whether to also warm up the GPU pipeline state that common
+	// drawing uses, when the window is created, by drawing nothing with it,
+	// as many OpenGL implementations otherwise only finish compiling a
+	// shader program, for a given blending mode, on its first draw. The
+	// gldriver always compiles its shader programs when the window is
+	// created, regardless. Other drivers ignore this field.
+	WarmUp bool
+
+	// OccludedPublish is what the new window's Publish method does while the
+	// window is occluded or minimized, as its last VisibilityEvent said, so
+	// that an app that redraws continuously does not use the CPU and GPU
+	// for frames that are not seen. Some platforms, such as macOS, do not
+	// otherwise throttle an occluded window's frames to the display's
+	// refresh rate, so that such an app draws as fast as it can.
+	//
+	// The x11driver and windriver draw directly to the window, so that a
+	// dropped frame has already been drawn. Drivers that send no
+	// VisibilityEvents never throttle.
+	OccludedPublish OccludedPublishPolicy
+
+	// PreferredGPU is which GPU, on systems with more than one, should render
+	// the window. It is a best-effort hint, and whether it is honored depends
+	// on the platform and the driver. Window.GLInfo reports which GPU was
+	// actually chosen.
+	//
+	// The gldriver on Windows passes it to ANGLE via the
+	// EGL_ANGLE_power_preference extension, if available. The gldriver on
+	// macOS requests the discrete GPU for GPUHighPerformance, by disallowing
+	// offline renderers. The gldriver on X11 creates its GL context before any
+	// window, and so ignores this field; set the DRI_PRIME environment
+	// variable before starting the program instead. The mtldriver similarly
+	// creates its Metal device before any window, and ignores this field.
+	// Other drivers do not use the GPU, and ignore this field.
+	PreferredGPU GPUPreference
+
+	// EventPriority, if non-nil, returns the priority of each event sent to
+	// the window. Its NextEvent method returns pending events of higher
+	// priority before those of lower priority, even if they were sent later,
+	// so that, for example, a key or lifecycle event need not wait behind a
+	// backlog of mouse motion or paint events. Events of equal priority are
+	// returned in the order that they were sent, and events sent by SendFirst
+	// are returned before all others.
+	//
+	// If nil, every event has the same priority. The function may be called
+	// concurrently from multiple goroutines.
+	EventPriority func(event interface{}) int
+
+	// KeepAllMoves is whether every mouse motion event is sent to the window.
+	// Otherwise, as by default, a mouse.Event without a Direction, or a
+	// RelativeMouseEvent, replaces the window's last pending event if that
+	// is a like event, keeping the latest position, or the sum of the
+	// movements, so that fast motion does not flood the app with events.
+	// Apps that need every sample, such as for handwriting recognition, should
+	// set it.
+	KeepAllMoves bool
+
+	// EventQueues, keyed by event priority, as returned by EventPriority,
+	// bound the queues of the window's pending events of each priority. The
+	// queues of priorities without an entry, as by default, are unbounded,
+	// so that a stalled app's pending events grow without limit.
+	EventQueues map[int]EventQueueOptions
+
+	// InterceptClose is whether the user's requests to close the new window
+	// are sent as CloseRequestEvents, leaving the app to decide whether to
+	// close it. Otherwise, such a request sends a lifecycle.Event whose To
+	// stage is lifecycle.StageDead, and the app is expected to release the
+	// window.
+	//
+	// Only the user's requests are intercepted. The window still dies, as
+	// a lifecycle.Event to lifecycle.StageDead, when the operating system
+	// ends the app, such as when the user logs out, or when the connection
+	// to the display is lost. Web browsers close pages without asking, so
+	// the wasmdriver never sends a CloseRequestEvent.
+	InterceptClose bool
+
+	// LinearBlending is whether drawing on the new window blends and
+	// anti-aliases in linear light, instead of on sRGB-encoded values. That
+	// makes anti-aliased edges, translucency and gradients between colors
+	// look physically correct, without the darkened fringes of blending in
+	// gamma space. Pixels, colors and Textures are still sRGB-encoded, and
+	// drawing without blending, such as with draw.Src, gives the same
+	// result as otherwise. Only drawing on the window itself, including
+	// the compositing of its Layers, is affected: drawing on Textures and
+	// Layers still blends sRGB-encoded values.
+	//
+	// The gldriver renders to an sRGB-encoded back buffer and has the GPU
+	// convert to and from linear light, if the platform's OpenGL can: with
+	// the EGL_KHR_gl_colorspace extension on Windows and X11, and always on
+	// macOS. The headlessdriver, waylanddriver and wasmdriver composite in
+	// linear light in software, which is slower. Other drivers ignore this
+	// field.
+	LinearBlending bool
+
+	// Transparent is whether the new window has per-pixel alpha, so that
+	// whatever is behind it shows through where its pixels are translucent
+	// or transparent, as for overlays and splash screens. Otherwise, the
+	// window is opaque and its pixels' alpha is ignored. Pixels are
+	// alpha-premultiplied, like those of an image.RGBA, and a transparent
+	// window's pixels are initially transparent black. On X11, whatever is
+	// behind the window only shows through if a compositing manager is
+	// running.
+	//
+	// The x11driver uses a 32-bit ARGB visual, the windriver a layered
+	// window, and the mtldriver and the gldriver on macOS a window with a
+	// clear background. The waylanddriver and wasmdriver use buffers and
+	// canvases with an alpha channel. The gldriver on Windows and X11 shares
+	// one OpenGL configuration between all windows, and ignores this field,
+	// as does the headlessdriver.
+	Transparent bool
+
+	// Shape, if non-empty, is the shape of the new window: the union of
+	// these rectangles, in pixels, relative to the top-left of the window's
+	// content area. Only the parts of the window inside its shape are
+	// drawn and receive mouse events; the window is absent elsewhere, and
+	// whatever is behind it, such as another window, is seen and clicked
+	// instead. The window's size, and the co-ordinates of what is drawn on
+	// it, are unaffected.
+	//
+	// The x11driver and the gldriver on X11 use the X Nonrectangular Window
+	// Shape extension, and the windriver and the gldriver on Windows a
+	// window region. The waylanddriver sets the surface's input region and
+	// draws transparent pixels outside the shape, and the wasmdriver clips
+	// the canvas with a CSS clip-path. macOS has no window shapes, so that
+	// the mtldriver and the gldriver there ignore this field, as does the
+	// headlessdriver; on macOS, a Transparent window is already absent
+	// where its pixels are fully transparent.
+	Shape []image.Rectangle
+
+	// Undecorated is whether the new window has no frame: no title bar,
+	// no borders and no buttons to close, minimize or maximize it. The app
+	// is expected to draw its own, and to call the window's StartMove and
+	// StartResize methods when the user drags them, so that the window
+	// still moves and resizes as other windows do. The window can still be
+	// minimized, maximized and made fullscreen.
+	//
+	// The x11driver and the gldriver on X11 set the _MOTIF_WM_HINTS
+	// property, which most, but not all, window managers honor. The
+	// windriver and the gldriver on Windows make a popup window whose
+	// sizing border has no width. On macOS, the window's content covers
+	// its title bar, which is transparent and whose buttons are hidden.
+	// The waylanddriver's windows never have a frame, as it does not use a
+	// decoration protocol, and the wasmdriver and headlessdriver ignore
+	// this field.
+	Undecorated bool
+
+	// KeyEvents is whether key presses, releases and repeats are sent to the
+	// new window as KeyEvents, which say more about the key, instead of as
+	// key.Events. The mobiledriver ignores this field.
+	KeyEvents bool
+
+	// Owner, if non-nil, is the window that owns the new window, such as
+	// the app's main window for a dialog. An owned window stays above its
+	// owner, is minimized and restored with it, and has no taskbar entry of
+	// its own. Owner must be a Window made by the same Screen, and an owned
+	// window should be released before its owner.
+	//
+	// The x11driver and the gldriver on X11 set the WM_TRANSIENT_FOR
+	// property, which window managers honor. The windriver, d3ddriver and
+	// the gldriver on Windows make an owned window. The mtldriver and the
+	// gldriver on macOS add the window as a child window of its owner. The
+	// waylanddriver sets the xdg_toplevel's parent. Other drivers ignore
+	// this field.
+	Owner Window
+
+	// Modal is whether the new window, which must have an Owner, blocks the
+	// owner's input until the new window is released, as for a dialog that
+	// must be answered before the app continues. The owner is sent no key
+	// or mouse events meanwhile, though it is still painted and can still
+	// be drawn to.
+	//
+	// The x11driver and the gldriver on X11 also set the window's
+	// _NET_WM_STATE_MODAL state, so that the window manager keeps the
+	// owner from being focused, and the windriver, d3ddriver and the
+	// gldriver on Windows disable the owner window. On macOS, the window is
+	// shown as a sheet, attached to its owner's title bar, and its
+	// Position is ignored.
+	Modal bool
+
 	// TODO: fullscreen, icon, cursorHidden?
 }
 
+// GLErrorPolicy is a policy for OpenGL errors, such as running out of memory
+// part way through a frame.
+type GLErrorPolicy uint8
+
+const (
+	// GLErrorIgnore means to ignore any errors. The OpenGL error flags are not
+	// checked, which avoids the cost of doing so for every frame.
+	GLErrorIgnore GLErrorPolicy = iota
+	// GLErrorReset means to log the error and reset the OpenGL state that
+	// the driver relies on, such as bound buffers and textures, and the
+	// blending, scissor and depth tests, so that one bad frame does not
+	// corrupt subsequent frames.
+	GLErrorReset
+	// GLErrorPanic means to panic, which can help when debugging.
+	GLErrorPanic
+)
+
+// OccludedPublishPolicy is a policy for publishing the frames of an occluded or
+// minimized window.
+type OccludedPublishPolicy uint8
+
+const (
+	// OccludedPublishNormal means to publish every frame as usual.
+	OccludedPublishNormal OccludedPublishPolicy = iota
+	// OccludedPublishThrottle means that Publish waits, if need be, so that
+	// the window presents at most one frame a second. The wait ends early
+	// if the window becomes visible.
+	OccludedPublishThrottle
+	// OccludedPublishSuppress means that Publish drops every frame, without
+	// presenting it, and returns at once. An app that redraws continuously
+	// should wait for a VisibilityEvent instead.
+	OccludedPublishSuppress
+)
+
+// GPUPreference is a preference for which GPU to use.
+type GPUPreference uint8
+
+const (
+	// GPUDefault means to use the system's default GPU.
+	GPUDefault GPUPreference = iota
+	// GPUHighPerformance prefers a discrete, high performance GPU.
+	GPUHighPerformance
+	// GPULowPower prefers an integrated, power efficient GPU.
+	GPULowPower
+)
+
 // GetTitle returns a sanitized form of o.Title. In particular, its length will
 // not exceed 4096, and it may be further truncated so that it is valid UTF-8
 // and will not contain the NUL byte.
@@ -251,6 +1990,118 @@ func (o *NewWindowOptions) GetTitle() string {
 	return sanitizeUTF8(o.Title, 4096)
 }
 
+// WithDefaults returns the options o, with every zero valued field replaced
+// by the corresponding field of defaults. Non-zero fields of o take
+// precedence.
+//
+// o and defaults may be nil. The result is nil if both are nil, and otherwise
+// is a new value that does not alias either argument: its Position, Shape and
+// EventQueues are copies. Its Owner and EventPriority still refer to the same
+// Window and function.
+func (o *NewWindowOptions) WithDefaults(defaults *NewWindowOptions) *NewWindowOptions {
+	if o == nil && defaults == nil {
+		return nil
+	}
+	var ret NewWindowOptions
+	if o != nil {
+		ret = *o
+	}
+	if defaults == nil {
+		ret.unalias()
+		return &ret
+	}
+	if ret.Width == 0 {
+		ret.Width = defaults.Width
+	}
+	if ret.Height == 0 {
+		ret.Height = defaults.Height
+	}
+	if ret.Title == "" {
+		ret.Title = defaults.Title
+	}
+	if ret.Position == nil {
+		ret.Position = defaults.Position
+	}
+	if !ret.FixedSize {
+		ret.FixedSize = defaults.FixedSize
+	}
+	if !ret.Hidden {
+		ret.Hidden = defaults.Hidden
+	}
+	if ret.DepthBits == 0 {
+		ret.DepthBits = defaults.DepthBits
+	}
+	if ret.Samples == 0 {
+		ret.Samples = defaults.Samples
+	}
+	if ret.GLErrorPolicy == GLErrorIgnore {
+		ret.GLErrorPolicy = defaults.GLErrorPolicy
+	}
+	if !ret.WarmUp {
+		ret.WarmUp = defaults.WarmUp
+	}
+	if ret.OccludedPublish == OccludedPublishNormal {
+		ret.OccludedPublish = defaults.OccludedPublish
+	}
+	if ret.PreferredGPU == GPUDefault {
+		ret.PreferredGPU = defaults.PreferredGPU
+	}
+	if ret.EventPriority == nil {
+		ret.EventPriority = defaults.EventPriority
+	}
+	if !ret.KeepAllMoves {
+		ret.KeepAllMoves = defaults.KeepAllMoves
+	}
+	if ret.EventQueues == nil {
+		ret.EventQueues = defaults.EventQueues
+	}
+	if !ret.InterceptClose {
+		ret.InterceptClose = defaults.InterceptClose
+	}
+	if !ret.LinearBlending {
+		ret.LinearBlending = defaults.LinearBlending
+	}
+	if !ret.Transparent {
+		ret.Transparent = defaults.Transparent
+	}
+	if len(ret.Shape) == 0 {
+		ret.Shape = defaults.Shape
+	}
+	if !ret.Undecorated {
+		ret.Undecorated = defaults.Undecorated
+	}
+	if !ret.KeyEvents {
+		ret.KeyEvents = defaults.KeyEvents
+	}
+	if ret.Owner == nil {
+		ret.Owner = defaults.Owner
+	}
+	if !ret.Modal {
+		ret.Modal = defaults.Modal
+	}
+	ret.unalias()
+	return &ret
+}
+
+// unalias replaces o's Position, Shape and EventQueues with copies, so that o
+// does not alias the options that they were copied from.
+func (o *NewWindowOptions) unalias() {
+	if o.Position != nil {
+		p := *o.Position
+		o.Position = &p
+	}
+	if o.Shape != nil {
+		o.Shape = append([]image.Rectangle(nil), o.Shape...)
+	}
+	if o.EventQueues != nil {
+		m := make(map[int]EventQueueOptions, len(o.EventQueues))
+		for k, v := range o.EventQueues {
+			m[k] = v
+		}
+		o.EventQueues = m
+	}
+}
+
 func sanitizeUTF8(s string, n int) string {
 	if n < len(s) {
 		s = s[:n]
@@ -266,6 +2117,62 @@ func sanitizeUTF8(s string, n int) string {
 	return s[:i]
 }
 
+// TrayIcon is an icon in the system tray, such as the notification area of the
+// Windows taskbar, or the status area of the macOS menu bar, that gives an
+// application a presence without an open window. On X11, it is embedded in
+// the tray of the desktop environment's panel, if it has one, by the XEmbed
+// system tray protocol.
+//
+// Its EventDeque is sent a TrayEvent when the user clicks it, and a MenuEvent
+// when the user chooses an item of its menu.
+type TrayIcon interface {
+	// Release removes the icon from the tray.
+	//
+	// Release is idempotent, and may be called from any goroutine. The
+	// behavior of the TrayIcon after Release, whether calling its other
+	// methods or passing it as an argument, is otherwise undefined.
+	Release()
+
+	EventDeque
+
+	// SetIcon sets the image shown in the tray, scaled to the size of the
+	// tray's icons, which are usually square and small, such as 16x16
+	// pixels on Windows. A nil m shows a blank icon.
+	SetIcon(m image.Image)
+
+	// SetTooltip sets the text shown when the pointer hovers over the icon.
+	// Trays that embed the icon by the XEmbed protocol show, if anything,
+	// the icon window's name.
+	SetTooltip(tooltip string)
+
+	// SetMenu sets the menu shown when the icon is clicked, or removes it,
+	// for a nil m. On Windows, the menu is shown for the right mouse button,
+	// and the left still sends TrayEvents. On macOS, it is shown for every
+	// button. The items' keyboard shortcuts are shown, if at all, without
+	// taking effect.
+	//
+	// SetMenu returns an error if the platform cannot show a tray icon's
+	// menu, as on X11, whose applications can show a window of their own in
+	// response to a TrayEvent instead.
+	SetMenu(m *Menu) error
+}
+
+// NewTrayIconOptions are optional arguments to NewTrayIcon.
+type NewTrayIconOptions struct {
+	// Icon, Tooltip and Menu are the TrayIcon's initial image, tooltip and
+	// menu, as for its SetIcon, SetTooltip and SetMenu methods.
+	Icon    image.Image
+	Tooltip string
+	Menu    *Menu
+}
+
+// TrayEvent is sent to a TrayIcon's EventDeque when the user clicks it,
+// unless the click shows its menu.
+type TrayEvent struct {
+	// Button is the mouse button that was clicked.
+	Button mouse.Button
+}
+
 // Uploader is something you can upload a Buffer to.
 type Uploader interface {
 	// Upload uploads the sub-Buffer defined by src and sr to the destination
@@ -273,6 +2180,11 @@ type Uploader interface {
 	// dst-space. The destination's contents are overwritten; the draw operator
 	// is implicitly draw.Src.
 	//
+	// Only the pixels in sr are uploaded. To update a small part of a large
+	// Texture, upload just the changed (dirty) rectangle: the cost of an
+	// Upload is proportional to the size of sr, not of the Buffer or the
+	// destination.
+	//
 	// It is valid to upload a Buffer while another upload of the same Buffer
 	// is in progress, but a Buffer's image.RGBA pixel contents should not be
 	// accessed while it is uploading. A Buffer is re-usable, in that its pixel
@@ -340,6 +2252,27 @@ type Drawer interface {
 	Scale(dr image.Rectangle, src Texture, sr image.Rectangle, op draw.Op, opts *DrawOptions)
 }
 
+// Clipper is implemented by Drawers that can clip their drawing, such as with
+// a scissor rectangle or a stencil buffer, without intermediate Textures.
+//
+// A Clipper has a stack of clip regions. Drawing with the Fill, Draw,
+// DrawUniform, Copy and Scale methods, and FillPath if the Clipper is also a
+// PathDrawer, only affects pixels inside every region on the stack. Upload
+// is not drawing, and may ignore the stack. Every push should be matched by
+// a PopClip before the next Publish.
+type Clipper interface {
+	// PushClip pushes the rectangle r, in dst-space, onto the clip stack.
+	PushClip(r image.Rectangle)
+
+	// PushClipPath pushes the Path p, transformed by src2dst, onto the clip
+	// stack. Its edges may or may not be anti-aliased.
+	PushClipPath(src2dst f64.Aff3, p *Path)
+
+	// PopClip pops the region most recently pushed onto the clip stack. It
+	// does nothing if the stack is empty.
+	PopClip()
+}
+
 // These draw.Op constants are provided so that users of this package don't
 // have to explicitly import "image/draw".
 const (
@@ -349,6 +2282,97 @@ const (
 
 // DrawOptions are optional arguments to Draw.
 type DrawOptions struct {
-	// TODO: transparency in [0x0000, 0xffff]?
+	// Depth is the depth, in the range [0, 1], of the drawn pixels. Lower
+	// values are nearer. It applies only when drawing on a Window that has a
+	// depth buffer (see NewWindowOptions.DepthBits).
+	//
+	// On such a Window, each draw with non-nil DrawOptions is depth tested:
+	// a pixel is drawn only if its Depth is no greater than that of what was
+	// already drawn there, since the previous Publish. Unlike the default
+	// painter's algorithm, the order of such draws then matters only for equal
+	// Depths. Draws with nil DrawOptions, Fill and Upload calls are not depth
+	// tested, and do not affect the depth buffer.
+	Depth float32
+
+	// Blend, if non-zero, is the blend mode, overriding the draw.Op argument.
+	Blend BlendMode
+
+	// Transparency scales the source's alpha, and hence its
+	// alpha-premultiplied color, by (0xffff - Transparency) / 0xffff. The
+	// zero value means fully opaque.
+	Transparency uint16
+
 	// TODO: scaler (nearest neighbor vs linear)?
 }
+
+// GetBlend returns the blend mode for a draw with the draw.Op op and the
+// options o: o.Blend if it is non-zero, otherwise BlendSrc or BlendSrcOver.
+//
+// o may be nil, in which case the mode is derived from op.
+func (o *DrawOptions) GetBlend(op draw.Op) BlendMode {
+	if o != nil && o.Blend != BlendDefault {
+		return o.Blend
+	}
+	if op == draw.Src {
+		return BlendSrc
+	}
+	return BlendSrcOver
+}
+
+// GetAlpha returns the factor, in the range [0, 0xffff], by which a draw with
+// the options o scales its alpha-premultiplied source.
+//
+// o may be nil, in which case 0xffff is returned.
+func (o *DrawOptions) GetAlpha() uint16 {
+	if o == nil {
+		return 0xffff
+	}
+	return 0xffff - o.Transparency
+}
+
+// BlendMode is how a draw combines its source and destination pixels.
+//
+// Each mode is defined, for alpha-premultiplied colors, as
+//
+//	result = src*Fs + dst*Fd
+//
+// where the factors Fs and Fd are given for each mode below, in terms of the
+// source and destination alphas, Sa and Da, and colors, Sc and Dc. For the
+// alpha channel, Sc and Dc are Sa and Da. Pixels outside of the drawn quad
+// are unaffected, even for modes like BlendSrcIn that Porter and Duff define
+// to clear them.
+type BlendMode uint8
+
+const (
+	// BlendDefault means to use the draw.Op argument: BlendSrc for draw.Src
+	// and BlendSrcOver for draw.Over.
+	BlendDefault BlendMode = iota
+
+	// The Porter-Duff operators.
+	BlendClear   // Fs = 0,      Fd = 0
+	BlendSrc     // Fs = 1,      Fd = 0
+	BlendDst     // Fs = 0,      Fd = 1
+	BlendSrcOver // Fs = 1,      Fd = 1 - Sa
+	BlendDstOver // Fs = 1 - Da, Fd = 1
+	BlendSrcIn   // Fs = Da,     Fd = 0
+	BlendDstIn   // Fs = 0,      Fd = Sa
+	BlendSrcOut  // Fs = 1 - Da, Fd = 0
+	BlendDstOut  // Fs = 0,      Fd = 1 - Sa
+	BlendSrcAtop // Fs = Da,     Fd = 1 - Sa
+	BlendDstAtop // Fs = 1 - Da, Fd = Sa
+	BlendXor     // Fs = 1 - Da, Fd = 1 - Sa
+
+	// BlendAdd sums the source and destination, clamping the result.
+	BlendAdd // Fs = 1, Fd = 1
+
+	// BlendMultiply darkens the destination by multiplying it by the source.
+	// It matches the W3C multiply mode when the destination is opaque.
+	BlendMultiply // Fs = Dc, Fd = 1 - Sa
+
+	// BlendScreen lightens the destination by multiplying its inverse by the
+	// source's inverse.
+	BlendScreen // Fs = 1, Fd = 1 - Sc
+
+	// NumBlendModes is the number of blend modes, including BlendDefault.
+	NumBlendModes int = iota
+)
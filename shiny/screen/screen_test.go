@@ -5,6 +5,8 @@
 package screen
 
 import (
+	"image"
+	"reflect"
 	"testing"
 )
 
@@ -51,3 +53,43 @@ func TestSanitizeUTF8(t *testing.T) {
 		}
 	}
 }
+
+func TestWithDefaults(t *testing.T) {
+	if got := (*NewWindowOptions)(nil).WithDefaults(nil); got != nil {
+		t.Errorf("nil.WithDefaults(nil): got %v, want nil", got)
+	}
+
+	defaults := &NewWindowOptions{Width: 640, Height: 480, Title: "default", FixedSize: true, DepthBits: 16}
+	got := (*NewWindowOptions)(nil).WithDefaults(defaults)
+	if got == defaults || !reflect.DeepEqual(got, defaults) {
+		t.Errorf("nil.WithDefaults: got %+v, want a copy of %+v", got, defaults)
+	}
+
+	o := &NewWindowOptions{Width: 100, Title: "explicit"}
+	got = o.WithDefaults(defaults)
+	if want := (NewWindowOptions{Width: 100, Height: 480, Title: "explicit", FixedSize: true, DepthBits: 16}); !reflect.DeepEqual(*got, want) {
+		t.Errorf("WithDefaults: got %+v, want %+v", *got, want)
+	}
+	if o.Height != 0 {
+		t.Errorf("WithDefaults modified its receiver: %+v", *o)
+	}
+
+	defaults = &NewWindowOptions{
+		Position:    &image.Point{X: 10, Y: 20},
+		Shape:       []image.Rectangle{image.Rect(0, 0, 10, 10)},
+		EventQueues: map[int]EventQueueOptions{1: {Capacity: 8}},
+	}
+	for _, got := range []*NewWindowOptions{
+		(*NewWindowOptions)(nil).WithDefaults(defaults),
+		defaults.WithDefaults(nil),
+		o.WithDefaults(defaults),
+	} {
+		if got.Position == defaults.Position || &got.Shape[0] == &defaults.Shape[0] {
+			t.Errorf("WithDefaults: got %+v, which aliases %+v", *got, *defaults)
+		}
+		got.EventQueues[2] = EventQueueOptions{}
+		if len(defaults.EventQueues) != 1 {
+			t.Errorf("WithDefaults: got EventQueues that alias the defaults'")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package screen
+
+import (
+	"errors"
+	"image"
+)
+
+// ErrTextureTooLarge is returned by NewTextureWithOptions when the Screen
+// cannot create a Texture of the requested size.
+var ErrTextureTooLarge = errors.New("screen: texture is too large")
+
+// MaxTextureSizer is implemented by Screens whose GPU limits the width and
+// height of its textures. The gldriver implements it.
+type MaxTextureSizer interface {
+	// MaxTextureSize returns the largest width and height, in pixels, of a
+	// GPU texture. It is zero if the Screen cannot tell.
+	//
+	// Drivers may make a larger Texture of several GPU textures, such as for
+	// scrolling around a large map. Such a Texture is clamped, whatever its
+	// NewTextureOptions.Wrap, and drawing it scaled or transformed may show
+	// seams where the GPU textures meet. A driver that cannot make one
+	// returns ErrTextureTooLarge.
+	MaxTextureSize() int
+}
+
+// AsyncUploader is implemented by Textures that can upload a Buffer without
+// blocking the caller until the upload is done. The gldriver's Textures
+// implement it.
+type AsyncUploader interface {
+	// UploadAsync starts to upload the sub-Buffer defined by src and sr to
+	// the destination (the method receiver), as Upload does, and returns
+	// without waiting for the pixels to be copied. When the upload is done, q,
+	// if non-nil, is sent an UploadEvent whose ID is id.
+	//
+	// Until then, src's pixel contents should not be accessed, but src can be
+	// released, which then happens after the upload. Drawing the destination
+	// before then may draw its previous or its new pixels. Asynchronous
+	// uploads are done in the order that they were started.
+	UploadAsync(dp image.Point, src Buffer, sr image.Rectangle, q EventDeque, id int)
+}
+
+// UploadEvent is sent to an EventDeque when an upload, started by an
+// AsyncUploader's UploadAsync method, is done.
+type UploadEvent struct {
+	// ID is the id passed to UploadAsync, to tell the uploads apart.
+	ID int
+
+	// Buffer is the uploaded Buffer, which can now be modified or uploaded
+	// again.
+	Buffer Buffer
+}
+
+// TextureFilter is how a Texture's pixels are sampled when it is drawn
+// scaled or transformed.
+type TextureFilter uint8
+
+const (
+	// FilterDefault is the driver's choice, which is FilterLinear for every
+	// driver but the headless one, whose output must be exact.
+	FilterDefault TextureFilter = iota
+	// FilterLinear interpolates between the nearest pixels, which suits
+	// photographs and other smooth images.
+	FilterLinear
+	// FilterNearest takes the nearest pixel, which scales pixel art crisply.
+	FilterNearest
+)
+
+// TextureWrap is how a Texture is sampled outside of its bounds.
+type TextureWrap uint8
+
+const (
+	// WrapClamp, the default, clips the source rectangle of each draw to the
+	// Texture's bounds. Samples near the Texture's edges repeat the edge
+	// pixels.
+	WrapClamp TextureWrap = iota
+	// WrapRepeat tiles the Texture, so that a draw whose source rectangle
+	// is larger than the Texture repeats it, such as for a tiled background.
+	WrapRepeat
+	// WrapMirror is like WrapRepeat, but every other tile is mirrored, so
+	// that adjacent tiles meet seamlessly.
+	WrapMirror
+)
+
+// NewTextureOptions are optional arguments to NewTextureWithOptions.
+type NewTextureOptions struct {
+	// Format is the format of the Texture's pixels.
+	Format PixelFormat
+
+	// Filter is how the Texture is sampled when it is drawn scaled.
+	Filter TextureFilter
+
+	// Mipmap is whether to keep mipmaps of the Texture, that is,
+	// progressively halved copies of it, which make it smoother when it is
+	// drawn at less than half of its size. They are regenerated after every
+	// change to the Texture's pixels, which costs a third more memory and
+	// some time, so they suit Textures that seldom change. Drivers that
+	// cannot keep mipmaps ignore Mipmap.
+	Mipmap bool
+
+	// Wrap is how the Texture is sampled outside of its bounds. Drivers
+	// that cannot repeat a Texture clamp it instead. The OpenGL driver
+	// cannot repeat, or keep mipmaps of, a Texture whose sides are not
+	// powers of two on an OpenGL ES 2 context.
+	Wrap TextureWrap
+
+	// Compressed is the block compression of the Texture's pixels. If it is
+	// not CompressedNone, Format and Mipmap are ignored, the Texture reports
+	// PixelFormatRGBA8, and its pixels, undefined until then, are set by its
+	// UploadCompressed method, as CompressedUploader describes.
+	// NewTextureWithOptions returns ErrUnsupportedFormat if the Screen is
+	// not a CompressedScreen whose CompressedFormats include Compressed.
+	Compressed CompressedFormat
+}
+
+// GetFormat returns o.Format, or PixelFormatRGBA8 if o is nil.
+func (o *NewTextureOptions) GetFormat() PixelFormat {
+	if o == nil {
+		return PixelFormatRGBA8
+	}
+	return o.Format
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package screen
+
+import (
+	"image"
+)
+
+// YUVFormat is the memory layout of a YUVImage's planes.
+type YUVFormat uint8
+
+const (
+	// YUVI420 has separate U and V planes, each subsampled by 2 both
+	// horizontally and vertically.
+	YUVI420 YUVFormat = iota
+	// YUVNV12 has a single chroma plane, held in U, of interleaved U and V
+	// samples, subsampled by 2 both horizontally and vertically. V is unused.
+	YUVNV12
+)
+
+// YUVColorSpace is the matrix that converts a YUVImage's samples to RGB.
+type YUVColorSpace uint8
+
+const (
+	// YUVBT601 is ITU-R BT.601, used by standard definition video.
+	YUVBT601 YUVColorSpace = iota
+	// YUVBT709 is ITU-R BT.709, used by high definition video.
+	YUVBT709
+)
+
+// YUVImage is a planar YUV image, such as a frame decoded by a video codec.
+//
+// The luma sample of the pixel at (x, y) is at
+// Y[(y-Rect.Min.Y)*YStride+(x-Rect.Min.X)]. Its chroma samples, shared with
+// its neighbors in a 2x2 block, are at U[cy*UVStride+cx] and V[cy*UVStride+cx]
+// for I420, or at U[cy*UVStride+2*cx] and U[cy*UVStride+2*cx+1] for NV12,
+// where cx = (x-Rect.Min.X)/2 and cy = (y-Rect.Min.Y)/2.
+type YUVImage struct {
+	Format     YUVFormat
+	ColorSpace YUVColorSpace
+	// FullRange is whether the samples use the full range of [0, 255]. If
+	// false, they use video range, where luma is in [16, 235] and chroma in
+	// [16, 240].
+	FullRange bool
+
+	Y, U, V           []byte
+	YStride, UVStride int
+	Rect              image.Rectangle
+}
+
+// Bounds returns the bounds of the image.
+func (m *YUVImage) Bounds() image.Rectangle {
+	return m.Rect
+}
+
+// YUVUploader is implemented by Textures that can upload YUV images,
+// converting them to RGBA as they do so. The Textures returned by this
+// module's drivers implement it: GPU-backed drivers convert in a fragment
+// shader, and the others in SIMD-optimized software.
+//
+// Uploading a YUVImage is cheaper than converting it to a Buffer and
+// uploading that, as converted pixels are not written to memory twice.
+type YUVUploader interface {
+	// UploadYUV uploads the sub-YUVImage defined by src and sr to the
+	// destination (the method receiver), such that sr.Min in src-space
+	// aligns with dp in dst-space. The destination's contents are
+	// overwritten; the draw operator is implicitly draw.Src.
+	//
+	// It is valid to upload a YUVImage while another upload of the same
+	// YUVImage is in progress, but a YUVImage's planes should not be
+	// modified until UploadYUV returns.
+	UploadYUV(dp image.Point, src *YUVImage, sr image.Rectangle)
+}
@@ -0,0 +1,399 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package glyphcache draws text with glyphs rasterized once and cached in a
+// texture atlas.
+//
+// Drawing text with a font.Drawer rasterizes every glyph, on the CPU, into a
+// Buffer that is then uploaded in full. A Cache instead rasterizes each
+// distinct glyph, at each subpixel position and in each color, once, and
+// draws text as a batch of quads of the atlas's Textures, which GPU-backed
+// drivers draw with a single draw call per atlas page.
+package glyphcache // import "golang.org/x/exp/shiny/text/glyphcache"
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+
+	"golang.org/x/exp/shiny/atlas"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/text/shape"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+)
+
+// LCD is the order of an LCD panel's subpixels, for subpixel anti-aliasing.
+type LCD uint8
+
+const (
+	// LCDNone means grayscale anti-aliasing.
+	LCDNone LCD = iota
+	// LCDRGB means horizontal red, green and blue subpixels, from the left.
+	LCDRGB
+	// LCDBGR means horizontal blue, green and red subpixels, from the left.
+	LCDBGR
+)
+
+// Options are optional arguments to New.
+type Options struct {
+	// SubpixelPositions is the number of horizontal positions within each
+	// pixel that glyphs are rasterized at, so that text is spaced as per the
+	// faces' fractional advances instead of being rounded to whole pixels.
+	// Each position of a glyph is cached separately. The zero value means 1:
+	// whole pixels only. Values above 64 are treated as 64.
+	SubpixelPositions int
+
+	// LCD is the subpixel order for subpixel anti-aliasing. Each subpixel's
+	// coverage is that of a whole pixel centered on that subpixel, which is a
+	// three-tap box filter that limits color fringes. Such glyphs are drawn
+	// in two passes, with the screen.BlendMultiply and screen.BlendAdd modes,
+	// so that each color channel is blended with its own coverage.
+	LCD LCD
+
+	// Atlas are the options of the Cache's atlas.
+	Atlas atlas.Options
+}
+
+// Cache is a cache of rasterized glyphs.
+//
+// Its methods are safe for concurrent use, but the font.Faces passed to them
+// should not be used concurrently by anything else.
+type Cache struct {
+	s      screen.Screen
+	atlas  *atlas.Atlas
+	phases int
+	lcd    LCD
+
+	mu     sync.Mutex
+	glyphs map[key]*glyph
+	// sweepAt is the number of glyphs at which to forget those whose Regions
+	// the atlas has evicted.
+	sweepAt int
+	buf     screen.Buffer
+	// quads and covers are scratch batches of the glyphs to draw and, for LCD
+	// glyphs, of their coverage.
+	quads  []screen.Quad
+	covers []screen.Quad
+}
+
+// key identifies a rasterized glyph.
+type key struct {
+	face  font.Face
+	r     rune
+	phase int
+	color color.RGBA64
+}
+
+// glyph is a rasterized glyph.
+type glyph struct {
+	// region holds the glyph's pixels. It is nil for a glyph without pixels,
+	// such as a space. For LCD glyphs, its left half holds the coverage and
+	// its right half holds the color.
+	region *atlas.Region
+	// rect is the glyph's bounds, relative to its whole-pixel dot.
+	rect image.Rectangle
+}
+
+// New returns a new Cache whose atlas is allocated by s. A nil opts is valid
+// and means to use the default option values.
+func New(s screen.Screen, opts *Options) *Cache {
+	c := &Cache{
+		s:       s,
+		phases:  1,
+		glyphs:  map[key]*glyph{},
+		sweepAt: 256,
+	}
+	var aopts *atlas.Options
+	if opts != nil {
+		if opts.SubpixelPositions > 64 {
+			c.phases = 64
+		} else if opts.SubpixelPositions > 1 {
+			c.phases = opts.SubpixelPositions
+		}
+		c.lcd = opts.LCD
+		aopts = &opts.Atlas
+	}
+	c.atlas = atlas.New(s, aopts)
+	return c
+}
+
+// Release releases the Cache's atlas and other resources.
+func (c *Cache) Release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.atlas.Release()
+	if c.buf != nil {
+		c.buf.Release()
+		c.buf = nil
+	}
+	c.glyphs = map[key]*glyph{}
+}
+
+// glyphAt is a glyph to draw, at a dot.
+type glyphAt struct {
+	face font.Face
+	r    rune
+	dot  fixed.Point26_6
+}
+
+// DrawString draws s with face to dst, in the color src, starting at dot, in
+// the same way as a font.Drawer's DrawString method, and returns the advanced
+// dot. src2dst maps from the dot's co-ordinate space to dst-space, such as a
+// node.PaintContext's Src2Dst. For sharp glyphs, it should be a translation.
+func (c *Cache) DrawString(dst screen.Drawer, src2dst f64.Aff3, dot fixed.Point26_6, face font.Face, s string, src color.Color) fixed.Point26_6 {
+	gs := make([]glyphAt, 0, len(s))
+	prev := rune(-1)
+	for _, r := range s {
+		if prev >= 0 {
+			dot.X += face.Kern(prev, r)
+		}
+		gs = append(gs, glyphAt{face, r, dot})
+		a, _ := face.GlyphAdvance(r)
+		dot.X += a
+		prev = r
+	}
+	c.draw(dst, src2dst, gs, src)
+	return dot
+}
+
+// DrawLine draws the shaped line l to dst, in the color src, starting at dot,
+// and returns the advanced dot. src2dst is as for DrawString.
+func (c *Cache) DrawLine(dst screen.Drawer, src2dst f64.Aff3, dot fixed.Point26_6, l *shape.Line, src color.Color) fixed.Point26_6 {
+	gs := make([]glyphAt, 0, len(l.Glyphs))
+	for _, g := range l.Glyphs {
+		gs = append(gs, glyphAt{g.Face, g.Rune, dot.Add(g.Offset)})
+		dot.X += g.Advance
+	}
+	c.draw(dst, src2dst, gs, src)
+	return dot
+}
+
+func (c *Cache) draw(dst screen.Drawer, src2dst f64.Aff3, gs []glyphAt, src color.Color) {
+	col := color.RGBA64Model.Convert(src).(color.RGBA64)
+	if col.A == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var tex screen.Texture
+	for _, g := range gs {
+		x, phase := c.position(g.dot.X)
+		y := g.dot.Y.Round()
+		k := key{g.face, g.r, phase, col}
+		e := c.glyphs[k]
+		if e != nil && e.region == nil {
+			continue
+		}
+
+		var (
+			t  screen.Texture
+			q  screen.Quad
+			ok bool
+		)
+		if e != nil {
+			t, q, ok = e.region.Quad(glyphTransform(src2dst, x, y, e))
+		}
+		if !ok {
+			// Rasterizing can evict the Regions of the glyphs already
+			// batched, so draw those first.
+			c.flush(dst, tex)
+			if e = c.rasterize(k); e.region == nil {
+				continue
+			}
+			if t, q, ok = e.region.Quad(glyphTransform(src2dst, x, y, e)); !ok {
+				continue
+			}
+		}
+		if t != tex {
+			c.flush(dst, tex)
+			tex = t
+		}
+		if c.lcd == LCDNone {
+			c.quads = append(c.quads, q)
+			continue
+		}
+		// The left half of the Region is the coverage, and the right half is
+		// the color, both drawn at the same place.
+		w := e.rect.Dx()
+		cover, fill := q, q
+		cover.SR.Max.X -= w
+		fill.SR.Min.X += w
+		translate(&fill.Src2Dst, float64(-w), 0)
+		c.covers = append(c.covers, cover)
+		c.quads = append(c.quads, fill)
+	}
+	c.flush(dst, tex)
+}
+
+var (
+	multiply = &screen.DrawOptions{Blend: screen.BlendMultiply}
+	add      = &screen.DrawOptions{Blend: screen.BlendAdd}
+)
+
+// flush draws the batched glyphs, which are all on tex. It must only be called
+// while holding c.mu.
+func (c *Cache) flush(dst screen.Drawer, tex screen.Texture) {
+	if len(c.quads) == 0 {
+		return
+	}
+	if c.lcd == LCDNone {
+		screen.DrawBatch(dst, tex, c.quads, draw.Over, nil)
+	} else {
+		screen.DrawBatch(dst, tex, c.covers, draw.Over, multiply)
+		screen.DrawBatch(dst, tex, c.quads, draw.Over, add)
+	}
+	c.quads = c.quads[:0]
+	c.covers = c.covers[:0]
+}
+
+// position returns the whole pixel and the subpixel phase to draw a glyph at
+// for the x co-ordinate of its dot: x rounded to the nearest of c.phases
+// positions per pixel.
+func (c *Cache) position(x fixed.Int26_6) (pixel, phase int) {
+	if c.phases == 1 {
+		return x.Round(), 0
+	}
+	q := (int(x)*c.phases + 32) >> 6
+	pixel = q / c.phases
+	if q%c.phases < 0 {
+		pixel--
+	}
+	return pixel, q - pixel*c.phases
+}
+
+// rasterize rasterizes and caches the glyph k. It must only be called while
+// holding c.mu.
+func (c *Cache) rasterize(k key) *glyph {
+	if len(c.glyphs) >= c.sweepAt {
+		for k, e := range c.glyphs {
+			if e.region != nil && !e.region.Valid() {
+				delete(c.glyphs, k)
+			}
+		}
+		c.sweepAt = 2 * len(c.glyphs)
+		if c.sweepAt < 256 {
+			c.sweepAt = 256
+		}
+	}
+
+	e := &glyph{}
+	c.glyphs[k] = e
+	dot := fixed.Point26_6{X: fixed.Int26_6(k.phase * 64 / c.phases)}
+
+	// For LCD glyphs, the red, green and blue coverages are those of the
+	// glyph shifted by a third of a pixel one way, not at all and the other
+	// way. The masks are copied, as a face may re-use its mask buffer.
+	var masks [3]*image.Alpha
+	n, third := 1, fixed.Int26_6(0)
+	if c.lcd != LCDNone {
+		n, third = 3, 21
+	}
+	for i := 0; i < n; i++ {
+		d := dot
+		d.X += third * fixed.Int26_6(1-i)
+		dr, mask, maskp, _, ok := k.face.Glyph(d, k.r)
+		if !ok || dr.Empty() {
+			continue
+		}
+		masks[i] = image.NewAlpha(dr)
+		draw.Draw(masks[i], dr, mask, maskp, draw.Src)
+		e.rect = e.rect.Union(dr)
+	}
+	if e.rect.Empty() {
+		return e
+	}
+	if c.lcd == LCDBGR {
+		masks[0], masks[2] = masks[2], masks[0]
+	}
+
+	size := e.rect.Size()
+	if c.lcd != LCDNone {
+		size.X *= 2
+	}
+	if c.buf != nil {
+		if bs := c.buf.Size(); bs.X < size.X || bs.Y < size.Y {
+			c.buf.Release()
+			c.buf = nil
+		}
+	}
+	if c.buf == nil {
+		bs := size
+		if bs.X < 64 {
+			bs.X = 64
+		}
+		if bs.Y < 64 {
+			bs.Y = 64
+		}
+		var err error
+		if c.buf, err = c.s.NewBuffer(bs); err != nil {
+			return e
+		}
+	}
+	r, err := c.atlas.Alloc(size)
+	if err != nil {
+		return e
+	}
+
+	rgba := c.buf.RGBA()
+	w := e.rect.Dx()
+	channels := [3]uint16{k.color.R, k.color.G, k.color.B}
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < w; x++ {
+			p := image.Point{x, y}.Add(e.rect.Min)
+			if c.lcd == LCDNone {
+				a := coverage(masks[0], p)
+				rgba.SetRGBA(x, y, color.RGBA{
+					scale(k.color.R, a), scale(k.color.G, a), scale(k.color.B, a), scale(k.color.A, a),
+				})
+				continue
+			}
+			var cov, col [3]uint8
+			maxA := uint8(0)
+			for i, m := range masks {
+				a := coverage(m, p)
+				cov[i] = 0xff - scale(k.color.A, a)
+				col[i] = scale(channels[i], a)
+				if s := scale(k.color.A, a); s > maxA {
+					maxA = s
+				}
+			}
+			rgba.SetRGBA(x, y, color.RGBA{cov[0], cov[1], cov[2], 0xff})
+			rgba.SetRGBA(x+w, y, color.RGBA{col[0], col[1], col[2], maxA})
+		}
+	}
+	r.Upload(image.Point{}, c.buf, image.Rectangle{Max: size})
+	e.region = r
+	return e
+}
+
+// coverage returns m's alpha at p, or zero if m is nil.
+func coverage(m *image.Alpha, p image.Point) uint8 {
+	if m == nil {
+		return 0
+	}
+	return m.AlphaAt(p.X, p.Y).A
+}
+
+// scale returns the 16-bit v scaled by the 8-bit a, as an 8-bit value.
+func scale(v uint16, a uint8) uint8 {
+	return uint8(((uint32(v)*uint32(a) + 0x7f) / 0xff) >> 8)
+}
+
+// glyphTransform returns the transform from e's Region-space to dst-space for
+// e drawn with its whole-pixel dot at (x, y).
+func glyphTransform(src2dst f64.Aff3, x, y int, e *glyph) f64.Aff3 {
+	translate(&src2dst, float64(x+e.rect.Min.X), float64(y+e.rect.Min.Y))
+	return src2dst
+}
+
+func translate(a *f64.Aff3, tx, ty float64) {
+	a[2] += a[0]*tx + a[1]*ty
+	a[5] += a[3]*tx + a[4]*ty
+}
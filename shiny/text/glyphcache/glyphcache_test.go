@@ -0,0 +1,162 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glyphcache
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"golang.org/x/exp/shiny/driver/headlessdriver"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/text/shape"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/inconsolata"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+)
+
+// batchCounter is a screen.BatchDrawer that counts its DrawBatch calls.
+type batchCounter struct {
+	screen.Drawer
+	batches, quads int
+}
+
+func (b *batchCounter) DrawBatch(src screen.Texture, quads []screen.Quad, op draw.Op, opts *screen.DrawOptions) {
+	b.batches++
+	b.quads += len(quads)
+	for _, q := range quads {
+		b.Draw(q.Src2Dst, src, q.SR, op, opts)
+	}
+}
+
+var identity = f64.Aff3{1, 0, 0, 0, 1, 0}
+
+func TestDrawString(t *testing.T) {
+	const text = "Hello, gopher"
+	face := inconsolata.Regular8x16
+	red := color.RGBA{0xc0, 0x00, 0x00, 0xff}
+	size := image.Point{8 * len(text), 16}
+
+	// The reference is drawn by a font.Drawer.
+	want := image.NewRGBA(image.Rectangle{Max: size})
+	draw.Draw(want, want.Bounds(), image.White, image.Point{}, draw.Src)
+	d := font.Drawer{Dst: want, Src: image.NewUniform(red), Face: face, Dot: fixed.P(0, 12)}
+	d.DrawString(text)
+
+	for _, lcd := range []LCD{LCDNone, LCDRGB} {
+		s := headlessdriver.NewScreen()
+		w, err := s.NewWindow(&screen.NewWindowOptions{Width: size.X, Height: size.Y})
+		if err != nil {
+			t.Fatalf("lcd=%d: NewWindow: %v", lcd, err)
+		}
+		c := New(s, &Options{LCD: lcd})
+
+		drawer := &batchCounter{Drawer: w}
+		for i := 0; i < 2; i++ {
+			w.Fill(image.Rectangle{Max: size}, color.White, draw.Src)
+			dot := c.DrawString(drawer, identity, fixed.P(0, 12), face, text, red)
+			if dot != d.Dot {
+				t.Errorf("lcd=%d: dot: got %v, want %v", lcd, dot, d.Dot)
+			}
+		}
+		w.Publish()
+
+		// The second draw re-uses the glyphs rasterized by the first.
+		if got, want := len(c.glyphs), len("Helo, gphr"); got != want {
+			t.Errorf("lcd=%d: got %d cached glyphs, want %d", lcd, got, want)
+		}
+		passes := 1
+		if lcd != LCDNone {
+			passes = 2
+		}
+		// The first draw rasterizes, and hence flushes, on each new glyph.
+		if got, want := drawer.quads, 2*passes*len(text); got != want {
+			t.Errorf("lcd=%d: got %d quads, want %d", lcd, got, want)
+		}
+
+		got := headlessdriver.Frame(w)
+		for y := 0; y < size.Y; y++ {
+			for x := 0; x < size.X; x++ {
+				if g, w := got.RGBAAt(x, y), want.RGBAAt(x, y); !near(g, w) {
+					t.Fatalf("lcd=%d: pixel (%d, %d): got %v, want %v", lcd, x, y, g, w)
+				}
+			}
+		}
+		c.Release()
+		w.Release()
+	}
+}
+
+// near returns whether a and b differ by at most 1 in each channel. Cached
+// glyphs are pre-multiplied 8-bit pixels, so their rounding differs slightly
+// from that of a font.Drawer.
+func near(a, b color.RGBA) bool {
+	d := func(x, y uint8) bool { return x-y <= 1 || y-x <= 1 }
+	return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B) && d(a.A, b.A)
+}
+
+func TestBatch(t *testing.T) {
+	s := headlessdriver.NewScreen()
+	w, err := s.NewWindow(&screen.NewWindowOptions{Width: 64, Height: 16})
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	defer w.Release()
+	c := New(s, nil)
+	defer c.Release()
+
+	// Once its glyphs are cached, a string is drawn with a single batch.
+	face := inconsolata.Regular8x16
+	c.DrawString(w, identity, fixed.P(0, 12), face, "abcabc", color.Black)
+	drawer := &batchCounter{Drawer: w}
+	c.DrawString(drawer, identity, fixed.P(0, 12), face, "abcabc", color.Black)
+	if drawer.batches != 1 || drawer.quads != 6 {
+		t.Errorf("got %d batches of %d quads, want 1 batch of 6", drawer.batches, drawer.quads)
+	}
+
+	// A shaped line is drawn in the same way.
+	l := (&shape.Shaper{Faces: []font.Face{face}}).Shape("cab", shape.Auto)
+	drawer = &batchCounter{Drawer: w}
+	if got, want := c.DrawLine(drawer, identity, fixed.P(0, 12), l, color.Black), fixed.P(24, 12); got != want {
+		t.Errorf("DrawLine: dot: got %v, want %v", got, want)
+	}
+	if drawer.batches != 1 || drawer.quads != 3 {
+		t.Errorf("DrawLine: got %d batches of %d quads, want 1 batch of 3", drawer.batches, drawer.quads)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	c := New(headlessdriver.NewScreen(), &Options{SubpixelPositions: 4})
+	defer c.Release()
+	testCases := []struct {
+		x            fixed.Int26_6
+		pixel, phase int
+	}{
+		{0, 0, 0},
+		{7, 0, 0},
+		{8, 0, 1},
+		{16, 0, 1},
+		{32, 0, 2},
+		{40, 0, 3},
+		{56, 1, 0},
+		{64 + 48, 1, 3},
+		{-16, -1, 3},
+		{-64, -1, 0},
+	}
+	for _, tc := range testCases {
+		pixel, phase := c.position(tc.x)
+		if pixel != tc.pixel || phase != tc.phase {
+			t.Errorf("x=%v: got %d, %d, want %d, %d", tc.x, pixel, phase, tc.pixel, tc.phase)
+		}
+	}
+
+	c = New(headlessdriver.NewScreen(), nil)
+	defer c.Release()
+	if pixel, phase := c.position(fixed.I(3) + 40); pixel != 4 || phase != 0 {
+		t.Errorf("whole pixels: got %d, %d, want 4, 0", pixel, phase)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package text
+
+import (
+	"image"
+	"strings"
+
+	"golang.org/x/exp/shiny/text/shape"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// MeasureText returns the advance width of s, and the bounds of the pixels
+// that drawing s would touch, relative to a dot at the origin. Consecutive
+// runes are kerned in the same way as by a font.Drawer's DrawString method.
+//
+// s is measured as a single line: a '\n' is measured like any other rune.
+func MeasureText(face font.Face, s string) (advance fixed.Int26_6, bounds image.Rectangle) {
+	b, advance := font.BoundString(face, s)
+	return advance, image.Rectangle{
+		Min: image.Point{b.Min.X.Floor(), b.Min.Y.Floor()},
+		Max: image.Point{b.Max.X.Ceil(), b.Max.Y.Ceil()},
+	}
+}
+
+// WrapText breaks s into lines no wider than maxWidth, in the same way that a
+// Frame with that face, no fallback faces and that maximum width would lay out
+// s. In particular, s is broken
+// at every '\n', which are not included in the returned lines, and at spaces,
+// which are kept at the end of the line that they end. A single word that is
+// wider than maxWidth is not broken. A non-positive maxWidth means to break
+// only at '\n's.
+//
+// The returned lines are sub-strings of s.
+func WrapText(face font.Face, s string, maxWidth fixed.Int26_6) []string {
+	shaper := &shape.Shaper{Faces: []font.Face{face}}
+	lines := make([]string, 0, 1+strings.Count(s, "\n"))
+	for {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			return wrapParagraph(lines, face, shaper, s, maxWidth)
+		}
+		lines = wrapParagraph(lines, face, shaper, s[:i], maxWidth)
+		s = s[i+1:]
+	}
+}
+
+// wrapParagraph appends the lines of the '\n'-free s to dst. It matches the
+// line breaking algorithm of the layout function.
+func wrapParagraph(dst []string, face font.Face, shaper *shape.Shaper, s string, maxWidth fixed.Int26_6) []string {
+	if maxWidth <= 0 {
+		return append(dst, s)
+	}
+	for {
+		var (
+			n          = len(s)
+			breakPoint = 0
+			prevC      = rune(-1)
+			advance    fixed.Int26_6
+		)
+		for i, c := range s {
+			if prevC >= 0 {
+				advance += face.Kern(prevC, c)
+			}
+			if c == ' ' {
+				breakPoint = i + 1
+			}
+			advance += glyphAdvance(face, shaper, c)
+			if c != ' ' && advance > maxWidth && breakPoint != 0 {
+				n = breakPoint
+				break
+			}
+			prevC = c
+		}
+		dst = append(dst, s[:n])
+		if n == len(s) {
+			return dst
+		}
+		s = s[n:]
+	}
+}
+
+// glyphAdvance returns the advance of r as the layout function measures it:
+// with face or, if face has no glyph for r, as shaper draws it, with a
+// fallback face or as a U+FFFD replacement character.
+func glyphAdvance(face font.Face, shaper *shape.Shaper, r rune) fixed.Int26_6 {
+	a, ok := face.GlyphAdvance(r)
+	if !ok {
+		if a, ok = shaper.GlyphAdvance(r); !ok {
+			a, _ = face.GlyphAdvance('\uFFFD')
+		}
+	}
+	return a
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shape
+
+import (
+	"unicode"
+)
+
+// arabicForms maps an Arabic letter to its presentation forms, which are
+// consecutive runes in the order isolated, final, initial and medial. Letters
+// with two forms join only to the preceding letter, and letters with four
+// forms join on both sides.
+var arabicForms = map[rune]struct {
+	first rune
+	n     int8
+}{
+	0x0621: {0xFE80, 1}, // Hamza.
+	0x0622: {0xFE81, 2}, // Alef with madda above.
+	0x0623: {0xFE83, 2}, // Alef with hamza above.
+	0x0624: {0xFE85, 2}, // Waw with hamza above.
+	0x0625: {0xFE87, 2}, // Alef with hamza below.
+	0x0626: {0xFE89, 4}, // Yeh with hamza above.
+	0x0627: {0xFE8D, 2}, // Alef.
+	0x0628: {0xFE8F, 4}, // Beh.
+	0x0629: {0xFE93, 2}, // Teh marbuta.
+	0x062A: {0xFE95, 4}, // Teh.
+	0x062B: {0xFE99, 4}, // Theh.
+	0x062C: {0xFE9D, 4}, // Jeem.
+	0x062D: {0xFEA1, 4}, // Hah.
+	0x062E: {0xFEA5, 4}, // Khah.
+	0x062F: {0xFEA9, 2}, // Dal.
+	0x0630: {0xFEAB, 2}, // Thal.
+	0x0631: {0xFEAD, 2}, // Reh.
+	0x0632: {0xFEAF, 2}, // Zain.
+	0x0633: {0xFEB1, 4}, // Seen.
+	0x0634: {0xFEB5, 4}, // Sheen.
+	0x0635: {0xFEB9, 4}, // Sad.
+	0x0636: {0xFEBD, 4}, // Dad.
+	0x0637: {0xFEC1, 4}, // Tah.
+	0x0638: {0xFEC5, 4}, // Zah.
+	0x0639: {0xFEC9, 4}, // Ain.
+	0x063A: {0xFECD, 4}, // Ghain.
+	0x0641: {0xFED1, 4}, // Feh.
+	0x0642: {0xFED5, 4}, // Qaf.
+	0x0643: {0xFED9, 4}, // Kaf.
+	0x0644: {0xFEDD, 4}, // Lam.
+	0x0645: {0xFEE1, 4}, // Meem.
+	0x0646: {0xFEE5, 4}, // Noon.
+	0x0647: {0xFEE9, 4}, // Heh.
+	0x0648: {0xFEED, 2}, // Waw.
+	0x0649: {0xFEEF, 2}, // Alef maksura.
+	0x064A: {0xFEF1, 4}, // Yeh.
+	0x0679: {0xFB66, 4}, // Tteh.
+	0x067E: {0xFB56, 4}, // Peh.
+	0x0686: {0xFB7A, 4}, // Tcheh.
+	0x0688: {0xFB88, 2}, // Ddal.
+	0x0691: {0xFB8C, 2}, // Rreh.
+	0x0698: {0xFB8A, 2}, // Jeh.
+	0x06A9: {0xFB8E, 4}, // Keheh.
+	0x06AF: {0xFB92, 4}, // Gaf.
+	0x06BE: {0xFBAA, 4}, // Heh doachashmee.
+	0x06C1: {0xFBA6, 4}, // Heh goal.
+	0x06CC: {0xFBFC, 4}, // Farsi yeh.
+	0x06D2: {0xFBAE, 2}, // Yeh barree.
+}
+
+// lamAlef maps an alef to its isolated lam-alef ligature. The final form is
+// the next rune.
+var lamAlef = map[rune]rune{
+	0x0622: 0xFEF5,
+	0x0623: 0xFEF7,
+	0x0625: 0xFEF9,
+	0x0627: 0xFEFB,
+}
+
+const lam = 0x0644
+
+// joining is an Arabic joining type.
+type joining uint8
+
+const (
+	joinNone        joining = iota // Non-joining.
+	joinRight                      // Joins to the preceding letter only.
+	joinDual                       // Joins on both sides.
+	joinCausing                    // Causes joining, like a tatweel or ZWJ.
+	joinTransparent                // Skipped over, like a combining mark.
+)
+
+func joiningType(r rune) joining {
+	if f, ok := arabicForms[r]; ok {
+		switch f.n {
+		case 2:
+			return joinRight
+		case 4:
+			return joinDual
+		}
+		return joinNone
+	}
+	switch {
+	case r == 0x0640 || r == 0x200D:
+		return joinCausing
+	case unicode.In(r, unicode.Mn, unicode.Me) || r == 0x200B || (unicode.Is(unicode.Cf, r) && r != 0x200C):
+		return joinTransparent
+	case unicode.Is(unicode.Arabic, r) && unicode.IsLetter(r):
+		// Most other Arabic letters join on both sides.
+		return joinDual
+	}
+	return joinNone
+}
+
+// arabicShape is how to draw an Arabic rune, if the face has the glyphs.
+type arabicShape struct {
+	// form is the rune's contextual presentation form, or zero.
+	form rune
+	// lig is, for a lam followed by an alef, their ligature, or zero. The
+	// ligature replaces both runes.
+	lig rune
+}
+
+// joinArabic returns the contextual form of each rune of Arabic text.
+func joinArabic(rs []rune) []arabicShape {
+	types := make([]joining, len(rs))
+	for i, r := range rs {
+		types[i] = joiningType(r)
+	}
+	shapes := make([]arabicShape, len(rs))
+	for i, r := range rs {
+		t := types[i]
+		if t != joinRight && t != joinDual {
+			continue
+		}
+		prevJoins := false
+		for j := i - 1; j >= 0; j-- {
+			if types[j] != joinTransparent {
+				prevJoins = types[j] == joinDual || types[j] == joinCausing
+				break
+			}
+		}
+		nextJoins := false
+		if t == joinDual {
+			for j := i + 1; j < len(rs); j++ {
+				if types[j] != joinTransparent {
+					nextJoins = types[j] != joinNone
+					break
+				}
+			}
+		}
+
+		// The forms are isolated, final, initial and medial.
+		form := 0
+		switch {
+		case prevJoins && nextJoins:
+			form = 3
+		case nextJoins:
+			form = 2
+		case prevJoins:
+			form = 1
+		}
+		if f, ok := arabicForms[r]; ok {
+			shapes[i].form = f.first + rune(form)
+		}
+
+		if r == lam && i+1 < len(rs) {
+			if l, ok := lamAlef[rs[i+1]]; ok {
+				if prevJoins {
+					l++
+				}
+				shapes[i].lig = l
+			}
+		}
+	}
+	return shapes
+}
@@ -0,0 +1,590 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shape
+
+import (
+	"unicode"
+)
+
+// class is a Unicode bidirectional character type.
+type class uint8
+
+const (
+	cL   class = iota // Left-to-right.
+	cR                // Right-to-left.
+	cAL               // Arabic letter.
+	cEN               // European number.
+	cES               // European separator.
+	cET               // European terminator.
+	cAN               // Arabic number.
+	cCS               // Common separator.
+	cNSM              // Non-spacing mark.
+	cBN               // Boundary neutral.
+	cB                // Paragraph separator.
+	cS                // Segment separator.
+	cWS               // White space.
+	cON               // Other neutral.
+	cLRE              // Left-to-right embedding.
+	cLRO              // Left-to-right override.
+	cRLE              // Right-to-left embedding.
+	cRLO              // Right-to-left override.
+	cPDF              // Pop directional format.
+	cLRI              // Left-to-right isolate.
+	cRLI              // Right-to-left isolate.
+	cFSI              // First strong isolate.
+	cPDI              // Pop directional isolate.
+)
+
+// classOf returns r's bidirectional character type.
+//
+// The standard library does not provide the Bidi_Class property, so it is
+// derived from each rune's block and general category. That derivation is
+// exact for the characters that matter in practice: letters, digits,
+// punctuation and the explicit formatting characters.
+func classOf(r rune) class {
+	switch r {
+	case 0x202A:
+		return cLRE
+	case 0x202B:
+		return cRLE
+	case 0x202C:
+		return cPDF
+	case 0x202D:
+		return cLRO
+	case 0x202E:
+		return cRLO
+	case 0x2066:
+		return cLRI
+	case 0x2067:
+		return cRLI
+	case 0x2068:
+		return cFSI
+	case 0x2069:
+		return cPDI
+	case 0x200E:
+		return cL
+	case 0x200F:
+		return cR
+	case 0x061C:
+		return cAL
+	case '\n', '\r', 0x1C, 0x1D, 0x1E, 0x85, 0x2029:
+		return cB
+	case '\t', 0x0B, 0x1F:
+		return cS
+	case ' ', 0x0C, 0x1680, 0x2028, 0x205F, 0x3000:
+		return cWS
+	case '+', '-', 0x207A, 0x207B, 0x208A, 0x208B, 0x2212, 0xFB29, 0xFE62, 0xFE63, 0xFF0B, 0xFF0D:
+		return cES
+	case '#', '$', '%', 0xA2, 0xA3, 0xA4, 0xA5, 0xB0, 0xB1, 0x066A, 0x2030, 0x2031, 0x2032, 0x2033, 0x2034,
+		0x212E, 0x2213, 0xFE5F, 0xFE69, 0xFE6A, 0xFF03, 0xFF04, 0xFF05, 0xFFE0, 0xFFE1, 0xFFE5, 0xFFE6:
+		return cET
+	case ',', '.', '/', ':', 0xA0, 0x060C, 0x202F, 0x2044, 0xFE50, 0xFE52, 0xFE55,
+		0xFF0C, 0xFF0E, 0xFF0F, 0xFF1A:
+		return cCS
+	case 0xB2, 0xB3, 0xB9:
+		return cEN
+	case 0x066B, 0x066C:
+		return cAN
+	}
+	switch {
+	case '0' <= r && r <= '9',
+		0x06F0 <= r && r <= 0x06F9,
+		0x2070 <= r && r <= 0x2079 && r != 0x2071 && r != 0x2072 && r != 0x2073,
+		0x2080 <= r && r <= 0x2089,
+		0x2488 <= r && r <= 0x249B,
+		0xFF10 <= r && r <= 0xFF19,
+		0x1D7CE <= r && r <= 0x1D7FF:
+		return cEN
+	case 0x0660 <= r && r <= 0x0669,
+		0x0600 <= r && r <= 0x0605,
+		r == 0x06DD, r == 0x08E2,
+		0x10E60 <= r && r <= 0x10E7E:
+		return cAN
+	case 0x20A0 <= r && r <= 0x20CF:
+		return cET
+	case 0x2000 <= r && r <= 0x200A:
+		return cWS
+	case r < 0x20, 0x7F <= r && r <= 0x9F,
+		r == 0xAD, 0x200B <= r && r <= 0x200D, 0x2060 <= r && r <= 0x2065,
+		r == 0xFEFF, 0xE0000 <= r && r <= 0xE0FFF:
+		return cBN
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return cNSM
+	case 0x0590 <= r && r <= 0x05FF, 0x07C0 <= r && r <= 0x085F,
+		0xFB1D <= r && r <= 0xFB4F,
+		0x10800 <= r && r <= 0x10CFF, 0x10D40 <= r && r <= 0x10EBF,
+		0x10F00 <= r && r <= 0x10F2F, 0x10F70 <= r && r <= 0x10FFF,
+		0x1E800 <= r && r <= 0x1EDFF, 0x1EF00 <= r && r <= 0x1EFFF:
+		return cR
+	case 0x0600 <= r && r <= 0x07BF, 0x0860 <= r && r <= 0x08FF,
+		0xFB50 <= r && r <= 0xFDCF, 0xFDF0 <= r && r <= 0xFDFF,
+		0xFE70 <= r && r <= 0xFEFF,
+		0x10D00 <= r && r <= 0x10D3F, 0x10EC0 <= r && r <= 0x10EFF,
+		0x10F30 <= r && r <= 0x10F6F, 0x1EC70 <= r && r <= 0x1ECBF,
+		0x1ED00 <= r && r <= 0x1ED4F, 0x1EE00 <= r && r <= 0x1EEFF:
+		if unicode.In(r, unicode.P, unicode.S) && r != 0x061B && r != 0x061F {
+			return cON
+		}
+		return cAL
+	case unicode.In(r, unicode.L, unicode.Mc, unicode.Nd, unicode.Nl):
+		return cL
+	case unicode.Is(unicode.Cf, r):
+		return cBN
+	}
+	return cON
+}
+
+// isIsolate returns whether c is an isolate initiator or terminator.
+func isIsolate(c class) bool {
+	return c == cLRI || c == cRLI || c == cFSI || c == cPDI
+}
+
+// firstStrong returns the class, cL or cR, of the first strong character of
+// cls that is not inside an isolate, as per rule P2 of the Unicode
+// Bidirectional Algorithm. It returns cON if there is no such character.
+func firstStrong(cls []class) class {
+	depth := 0
+	for _, c := range cls {
+		switch c {
+		case cL:
+			if depth == 0 {
+				return cL
+			}
+		case cR, cAL:
+			if depth == 0 {
+				return cR
+			}
+		case cLRI, cRLI, cFSI:
+			depth++
+		case cPDI:
+			if depth > 0 {
+				depth--
+			}
+		case cB:
+			return cON
+		}
+	}
+	return cON
+}
+
+// matchIsolates returns, for each isolate initiator in cls, the index of its
+// matching PDI, or len(cls) if it has none, as per rule BD9. Other elements
+// of the result are -1, except for matched PDIs, which are -2.
+func matchIsolates(cls []class) []int {
+	m := make([]int, len(cls))
+	var stack []int
+	for i, c := range cls {
+		m[i] = -1
+		switch c {
+		case cLRI, cRLI, cFSI:
+			m[i] = len(cls)
+			stack = append(stack, i)
+		case cPDI:
+			if n := len(stack); n > 0 {
+				m[stack[n-1]] = i
+				m[i] = -2
+				stack = stack[:n-1]
+			}
+		case cB:
+			stack = stack[:0]
+		}
+	}
+	return m
+}
+
+// maxDepth is the maximum explicit embedding level.
+const maxDepth = 125
+
+// resolveLevels returns the embedding level of each character of a line, as
+// per rules X1-X10, W1-W7, N1-N2, I1-I2 and L1 of the Unicode Bidirectional
+// Algorithm. base is the paragraph embedding level.
+//
+// Paired brackets (rule N0) are resolved like any other neutral characters.
+func resolveLevels(cls []class, base uint8) []uint8 {
+	n := len(cls)
+	levels := make([]uint8, n)
+	types := make([]class, n)
+	copy(types, cls)
+	match := matchIsolates(cls)
+
+	// Rules X1-X8: explicit embeddings, overrides and isolates.
+	type entry struct {
+		level    uint8
+		override class // cON means no override.
+		isolate  bool
+	}
+	stack := []entry{{level: base, override: cON}}
+	overflowIsolates, overflowEmbeddings, validIsolates := 0, 0, 0
+	for i, c := range cls {
+		top := stack[len(stack)-1]
+		switch c {
+		case cRLE, cLRE, cRLO, cLRO, cRLI, cLRI, cFSI:
+			isolate := c == cRLI || c == cLRI || c == cFSI
+			rtl := c == cRLE || c == cRLO || c == cRLI
+			if c == cFSI {
+				end := match[i]
+				rtl = firstStrong(cls[i+1:end]) == cR
+			}
+			var level uint8
+			if rtl {
+				level = (top.level + 1) | 1
+			} else {
+				level = (top.level + 2) &^ 1
+			}
+			levels[i] = top.level
+			if isolate {
+				if top.override != cON {
+					types[i] = top.override
+				}
+			} else {
+				types[i] = cBN
+			}
+			if level <= maxDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				e := entry{level: level, override: cON, isolate: isolate}
+				switch c {
+				case cRLO:
+					e.override = cR
+				case cLRO:
+					e.override = cL
+				}
+				if isolate {
+					validIsolates++
+				}
+				stack = append(stack, e)
+			} else if isolate {
+				overflowIsolates++
+			} else if overflowIsolates == 0 {
+				overflowEmbeddings++
+			}
+
+		case cPDI:
+			if overflowIsolates > 0 {
+				overflowIsolates--
+			} else if validIsolates > 0 {
+				overflowEmbeddings = 0
+				for !stack[len(stack)-1].isolate {
+					stack = stack[:len(stack)-1]
+				}
+				stack = stack[:len(stack)-1]
+				validIsolates--
+			}
+			top = stack[len(stack)-1]
+			levels[i] = top.level
+			if top.override != cON {
+				types[i] = top.override
+			}
+
+		case cPDF:
+			if overflowIsolates > 0 {
+				// No-op.
+			} else if overflowEmbeddings > 0 {
+				overflowEmbeddings--
+			} else if !top.isolate && len(stack) >= 2 {
+				stack = stack[:len(stack)-1]
+			}
+			levels[i] = top.level
+			types[i] = cBN
+
+		case cB:
+			levels[i] = base
+
+		default:
+			levels[i] = top.level
+			if c != cBN && top.override != cON {
+				types[i] = top.override
+			}
+		}
+	}
+
+	// Rule X9 removes the explicit formatting characters, which now have type
+	// BN, and rule X10 resolves the remaining characters in isolating run
+	// sequences.
+	var kept []int
+	for i, t := range types {
+		if t != cBN {
+			kept = append(kept, i)
+		}
+	}
+	var runs [][]int
+	for k := 0; k < len(kept); {
+		j := k + 1
+		for j < len(kept) && levels[kept[j]] == levels[kept[k]] {
+			j++
+		}
+		runs = append(runs, kept[k:j])
+		k = j
+	}
+	runStartingAt := map[int]int{}
+	for r, run := range runs {
+		runStartingAt[run[0]] = r
+	}
+	for _, run := range runs {
+		if match[run[0]] == -2 {
+			// This run continues the sequence of its matching initiator.
+			continue
+		}
+		seq := append([]int(nil), run...)
+		for {
+			last := seq[len(seq)-1]
+			end := match[last]
+			if end < 0 || end >= n {
+				break
+			}
+			r, ok := runStartingAt[end]
+			if !ok {
+				break
+			}
+			seq = append(seq, runs[r]...)
+		}
+		resolveSequence(cls, types, levels, seq, base, n)
+	}
+
+	// Removed characters take the level of the preceding character.
+	for i, t := range types {
+		if t == cBN {
+			if i > 0 {
+				levels[i] = levels[i-1]
+			} else {
+				levels[i] = base
+			}
+		}
+	}
+
+	// Rule L1: reset segment separators, paragraph separators and trailing
+	// white space to the paragraph level.
+	trailing := true
+	for i := n - 1; i >= 0; i-- {
+		switch c := cls[i]; {
+		case c == cS || c == cB:
+			levels[i] = base
+			trailing = true
+		case trailing && (c == cWS || isIsolate(c) || types[i] == cBN):
+			levels[i] = base
+		default:
+			trailing = false
+		}
+	}
+	return levels
+}
+
+// resolveSequence applies the weak, neutral and implicit rules to the
+// isolating run sequence seq, whose elements are indexes into cls, types and
+// levels.
+func resolveSequence(cls, types []class, levels []uint8, seq []int, base uint8, n int) {
+	level := levels[seq[0]]
+	first, last := seq[0], seq[len(seq)-1]
+
+	prevLevel, nextLevel := base, base
+	for i := first - 1; i >= 0; i-- {
+		if types[i] != cBN {
+			prevLevel = levels[i]
+			break
+		}
+	}
+	if c := cls[last]; c != cLRI && c != cRLI && c != cFSI {
+		for i := last + 1; i < n; i++ {
+			if types[i] != cBN {
+				nextLevel = levels[i]
+				break
+			}
+		}
+	}
+	sos, eos := cL, cL
+	if max8(level, prevLevel)&1 != 0 {
+		sos = cR
+	}
+	if max8(levels[last], nextLevel)&1 != 0 {
+		eos = cR
+	}
+
+	ts := make([]class, len(seq))
+	for k, i := range seq {
+		ts[k] = types[i]
+	}
+
+	// W1: non-spacing marks take the type of the previous character.
+	for k, t := range ts {
+		if t != cNSM {
+			continue
+		}
+		switch {
+		case k == 0:
+			ts[k] = sos
+		case isIsolate(ts[k-1]):
+			ts[k] = cON
+		default:
+			ts[k] = ts[k-1]
+		}
+	}
+
+	// W2: European numbers after an Arabic letter are Arabic numbers.
+	// W3: Arabic letters are right-to-left.
+	strong := sos
+	for k, t := range ts {
+		switch t {
+		case cL, cR, cAL:
+			strong = t
+		case cEN:
+			if strong == cAL {
+				ts[k] = cAN
+			}
+		}
+	}
+	for k, t := range ts {
+		if t == cAL {
+			ts[k] = cR
+		}
+	}
+
+	// W4: a single separator between two numbers of the same type.
+	for k := 1; k+1 < len(ts); k++ {
+		p, t, q := ts[k-1], ts[k], ts[k+1]
+		switch {
+		case t == cES && p == cEN && q == cEN:
+			ts[k] = cEN
+		case t == cCS && p == q && (p == cEN || p == cAN):
+			ts[k] = p
+		}
+	}
+
+	// W5: European terminators adjacent to European numbers.
+	for k := 0; k < len(ts); {
+		if ts[k] != cET {
+			k++
+			continue
+		}
+		j := k
+		for j < len(ts) && ts[j] == cET {
+			j++
+		}
+		if (k > 0 && ts[k-1] == cEN) || (j < len(ts) && ts[j] == cEN) {
+			for ; k < j; k++ {
+				ts[k] = cEN
+			}
+		}
+		k = j
+	}
+
+	// W6: remaining separators and terminators are neutral.
+	// W7: European numbers after a left-to-right character are
+	// left-to-right.
+	strong = sos
+	for k, t := range ts {
+		switch t {
+		case cES, cET, cCS:
+			ts[k] = cON
+		case cL, cR:
+			strong = t
+		case cEN:
+			if strong == cL {
+				ts[k] = cL
+			}
+		}
+	}
+
+	// N1 and N2: sequences of neutrals take the direction of the surrounding
+	// strong text if both sides agree, and the embedding direction otherwise.
+	embedding := cL
+	if level&1 != 0 {
+		embedding = cR
+	}
+	for k := 0; k < len(ts); {
+		if !isNeutral(ts[k]) {
+			k++
+			continue
+		}
+		j := k
+		for j < len(ts) && isNeutral(ts[j]) {
+			j++
+		}
+		before, after := sos, eos
+		if k > 0 {
+			before = strongDirection(ts[k-1])
+		}
+		if j < len(ts) {
+			after = strongDirection(ts[j])
+		}
+		d := embedding
+		if before == after {
+			d = before
+		}
+		for ; k < j; k++ {
+			ts[k] = d
+		}
+	}
+
+	// I1 and I2: implicit levels.
+	for k, i := range seq {
+		l := levels[i]
+		switch t := ts[k]; {
+		case l&1 == 0 && t == cR:
+			levels[i] = l + 1
+		case l&1 == 0 && (t == cAN || t == cEN):
+			levels[i] = l + 2
+		case l&1 != 0 && (t == cL || t == cEN || t == cAN):
+			levels[i] = l + 1
+		}
+	}
+}
+
+func isNeutral(c class) bool {
+	switch c {
+	case cB, cS, cWS, cON, cLRI, cRLI, cFSI, cPDI:
+		return true
+	}
+	return false
+}
+
+// strongDirection returns the direction, cL or cR, that a resolved
+// non-neutral type counts as for rule N1. Numbers count as right-to-left.
+func strongDirection(c class) class {
+	if c == cL {
+		return cL
+	}
+	return cR
+}
+
+func max8(a, b uint8) uint8 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// visualOrder returns the visual (left-to-right) order of elements with the
+// given embedding levels, as per rule L2: from the highest level to the
+// lowest odd level, each maximal sequence at that level or higher is
+// reversed. The i'th element of the result is the index of the element to
+// display i'th.
+func visualOrder(levels []uint8) []int {
+	order := make([]int, len(levels))
+	highest, lowestOdd := uint8(0), uint8(maxDepth+2)
+	for i, l := range levels {
+		order[i] = i
+		if l > highest {
+			highest = l
+		}
+		if l&1 != 0 && l < lowestOdd {
+			lowestOdd = l
+		}
+	}
+	for l := highest; l >= lowestOdd && l > 0; l-- {
+		for i := 0; i < len(order); {
+			if levels[order[i]] < l {
+				i++
+				continue
+			}
+			j := i
+			for j < len(order) && levels[order[j]] >= l {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				order[a], order[b] = order[b], order[a]
+			}
+			i = j
+		}
+	}
+	return order
+}
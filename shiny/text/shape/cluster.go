@@ -0,0 +1,158 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shape
+
+import (
+	"unicode"
+)
+
+const (
+	zwj = 0x200D
+)
+
+// clusterEnd returns the end of the cluster of rs that starts at i. A cluster
+// is a base rune followed by any combining marks, joiners, variation
+// selectors, emoji modifiers and tags, and is drawn with a single face.
+//
+// In Indic scripts, a cluster is a syllable: consonants joined by viramas.
+func clusterEnd(rs []rune, i int, script string) int {
+	r := rs[i]
+	i++
+	switch {
+	case r == '\r':
+		if i < len(rs) && rs[i] == '\n' {
+			i++
+		}
+		return i
+	case isRegionalIndicator(r):
+		// Regional indicators pair up as flags.
+		if i < len(rs) && isRegionalIndicator(rs[i]) {
+			i++
+		}
+	}
+	virama := viramas[script]
+	for i < len(rs) {
+		r := rs[i]
+		switch {
+		case r == zwj:
+			i++
+			// A ZWJ joins emoji into one sequence.
+			if i < len(rs) && unicode.Is(unicode.So, rs[i]) {
+				i++
+			}
+		case extends(r):
+			i++
+		case virama != 0 && rs[i-1] == virama && unicode.Is(unicode.Lo, r):
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// extends returns whether r extends the cluster before it.
+func extends(r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc),
+		r == 0x200C,
+		0xFE00 <= r && r <= 0xFE0F,
+		0x1F3FB <= r && r <= 0x1F3FF, // Emoji skin tone modifiers.
+		0xE0020 <= r && r <= 0xE007F, // Tags.
+		0xE0100 <= r && r <= 0xE01EF: // Variation selectors supplement.
+		return true
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return 0x1F1E6 <= r && r <= 0x1F1FF
+}
+
+// viramas maps an Indic script to its virama, which joins consonants.
+var viramas = map[string]rune{
+	"Devanagari": 0x094D,
+	"Bengali":    0x09CD,
+	"Gurmukhi":   0x0A4D,
+	"Gujarati":   0x0ACD,
+	"Oriya":      0x0B4D,
+	"Tamil":      0x0BCD,
+	"Telugu":     0x0C4D,
+	"Kannada":    0x0CCD,
+	"Malayalam":  0x0D4D,
+	"Sinhala":    0x0DCA,
+}
+
+// preBase are the Indic vowel signs that are written before the syllable
+// that they follow in logical order.
+var preBase = map[rune]bool{
+	0x093F: true, // Devanagari i.
+	0x094E: true, // Devanagari prishthamatra e.
+	0x09BF: true, // Bengali i.
+	0x09C7: true, // Bengali e.
+	0x09C8: true, // Bengali ai.
+	0x0A3F: true, // Gurmukhi i.
+	0x0ABF: true, // Gujarati i.
+	0x0B47: true, // Oriya e.
+	0x0BC6: true, // Tamil e.
+	0x0BC7: true, // Tamil ee.
+	0x0BC8: true, // Tamil ai.
+	0x0D46: true, // Malayalam e.
+	0x0D47: true, // Malayalam ee.
+	0x0D48: true, // Malayalam ai.
+	0x0DD9: true, // Sinhala kombuva.
+	0x0DDB: true, // Sinhala kombu deka.
+}
+
+// splitVowels maps the Indic vowel signs that have a pre-base part to their
+// canonical decompositions.
+var splitVowels = map[rune][]rune{
+	0x09CB: {0x09C7, 0x09BE},
+	0x09CC: {0x09C7, 0x09D7},
+	0x0B48: {0x0B47, 0x0B56},
+	0x0B4B: {0x0B47, 0x0B3E},
+	0x0B4C: {0x0B47, 0x0B57},
+	0x0BCA: {0x0BC6, 0x0BBE},
+	0x0BCB: {0x0BC7, 0x0BBE},
+	0x0BCC: {0x0BC6, 0x0BD7},
+	0x0D4A: {0x0D46, 0x0D3E},
+	0x0D4B: {0x0D47, 0x0D3E},
+	0x0D4C: {0x0D46, 0x0D57},
+	0x0DDA: {0x0DD9, 0x0DCA},
+	0x0DDC: {0x0DD9, 0x0DCF},
+	0x0DDD: {0x0DD9, 0x0DCF, 0x0DCA},
+	0x0DDE: {0x0DD9, 0x0DDF},
+}
+
+// decomposeIndic returns a copy of rs with split vowel signs decomposed, and
+// the corresponding byte offsets. The parts of a split vowel sign share its
+// offset.
+func decomposeIndic(rs []rune, offs []int) ([]rune, []int) {
+	rs2 := make([]rune, 0, len(rs))
+	offs2 := make([]int, 0, len(rs))
+	for i, r := range rs {
+		if d, ok := splitVowels[r]; ok {
+			rs2 = append(rs2, d...)
+			for range d {
+				offs2 = append(offs2, offs[i])
+			}
+			continue
+		}
+		rs2 = append(rs2, r)
+		offs2 = append(offs2, offs[i])
+	}
+	return rs2, offs2
+}
+
+// reorderIndic moves any pre-base vowel sign of a syllable to its start.
+func reorderIndic(syllable []rune) {
+	for i := 1; i < len(syllable); i++ {
+		if r := syllable[i]; preBase[r] {
+			copy(syllable[1:i+1], syllable[:i])
+			syllable[0] = r
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shape
+
+import (
+	"sort"
+	"unicode"
+)
+
+// commonScripts are checked, in order, before the other scripts, so that
+// looking up the script of most text is fast.
+var commonScripts = []string{
+	"Latin", "Common", "Inherited", "Han", "Arabic", "Cyrillic", "Hebrew",
+	"Devanagari", "Hiragana", "Katakana", "Hangul", "Greek", "Thai",
+	"Bengali", "Tamil", "Telugu", "Kannada", "Malayalam", "Gujarati",
+	"Gurmukhi", "Oriya", "Sinhala",
+}
+
+// otherScripts are the names of the other scripts in unicode.Scripts, sorted.
+var otherScripts = func() []string {
+	common := map[string]bool{}
+	for _, s := range commonScripts {
+		common[s] = true
+	}
+	var s []string
+	for name := range unicode.Scripts {
+		if !common[name] {
+			s = append(s, name)
+		}
+	}
+	sort.Strings(s)
+	return s
+}()
+
+// scriptOf returns the name of r's script, or "Common" for an unassigned rune.
+func scriptOf(r rune) string {
+	if r < 0x80 {
+		if 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z' {
+			return "Latin"
+		}
+		return "Common"
+	}
+	for _, s := range commonScripts {
+		if unicode.Is(unicode.Scripts[s], r) {
+			return s
+		}
+	}
+	for _, s := range otherScripts {
+		if unicode.Is(unicode.Scripts[s], r) {
+			return s
+		}
+	}
+	return "Common"
+}
+
+// resolveScripts returns the script of each rune, with Common and Inherited
+// runes taking the script of the preceding rune at the same embedding level
+// or, failing that, of the next rune with a real script.
+func resolveScripts(rs []rune, levels []uint8) []string {
+	scripts := make([]string, len(rs))
+	pending := 0
+	prev := ""
+	for i, r := range rs {
+		if i > 0 && levels[i] != levels[i-1] {
+			pending, prev = i, ""
+		}
+		s := scriptOf(r)
+		if s == "Common" || s == "Inherited" {
+			if prev == "" {
+				scripts[i] = "Common"
+				continue
+			}
+			s = prev
+		} else if prev == "" {
+			for ; pending < i; pending++ {
+				scripts[pending] = s
+			}
+		}
+		scripts[i], prev = s, s
+	}
+	return scripts
+}
@@ -0,0 +1,419 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package shape lays out a line of text as a sequence of positioned glyphs.
+//
+// Shaping a line splits its text into runs of a single script and
+// bidirectional embedding level, orders those runs visually as per the
+// Unicode Bidirectional Algorithm (UAX #9), applies each script's shaping and
+// chooses, for each cluster of runes, the first face in a fallback chain that
+// can draw all of it.
+//
+// A font.Face maps runes to glyphs one at a time, and does not expose a font's
+// OpenType layout tables. Shaping is therefore limited to what Unicode itself
+// can express: Arabic letters are replaced by their contextual presentation
+// forms, including the mandatory lam-alef ligatures, when the face has glyphs
+// for them; Indic pre-base vowel signs are moved before their syllable;
+// combining marks are centered over their base; and emoji sequences are kept
+// together as one cluster. Conjuncts, ligatures and mark attachment that
+// require a font's GSUB and GPOS tables are not applied.
+package shape // import "golang.org/x/exp/shiny/text/shape"
+
+import (
+	"image/draw"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Direction is the base direction of a line or paragraph of text.
+type Direction uint8
+
+const (
+	// Auto means to take the direction from the first strong character, as
+	// per rules P2 and P3 of the Unicode Bidirectional Algorithm, or
+	// LeftToRight if there is no strong character.
+	Auto Direction = iota
+	LeftToRight
+	RightToLeft
+)
+
+// ParagraphDirection returns the base direction of a paragraph of text: the
+// direction of its first strong character, or LeftToRight if it has none.
+func ParagraphDirection(s string) Direction {
+	for _, r := range s {
+		switch classOf(r) {
+		case cL:
+			return LeftToRight
+		case cR, cAL:
+			return RightToLeft
+		}
+	}
+	return LeftToRight
+}
+
+// Glyph is a positioned glyph.
+type Glyph struct {
+	// Face is the font face to draw the glyph with.
+	Face font.Face
+
+	// Rune is the rune to draw with Face. It can differ from the shaped text,
+	// such as for a presentation form, a ligature or a mirrored bracket.
+	Rune rune
+
+	// Cluster is the byte offset, in the shaped text, of the start of the
+	// cluster that the glyph belongs to. Every glyph of a cluster, such as a
+	// base letter and its combining marks, has the same Cluster.
+	Cluster int
+
+	// Advance is how far to move the dot after drawing the glyph, including
+	// any kerning with the next glyph.
+	Advance fixed.Int26_6
+
+	// Offset is added to the dot when drawing the glyph.
+	Offset fixed.Point26_6
+}
+
+// Run is a maximal run of text with the same script and bidirectional
+// embedding level.
+type Run struct {
+	// Start and End are the byte offsets, in the shaped text, of the run.
+	Start, End int
+
+	// Script is the name of the run's script, as a key of unicode.Scripts,
+	// such as "Latin" or "Arabic". Common and Inherited runes take the
+	// script of the text around them.
+	Script string
+
+	// Level is the run's embedding level. Odd levels are right-to-left.
+	Level uint8
+
+	// Glyphs are the run's glyphs, in visual order.
+	Glyphs []Glyph
+}
+
+// Line is a shaped line of text.
+type Line struct {
+	// Glyphs are the line's glyphs, in visual (left-to-right) order.
+	Glyphs []Glyph
+
+	// Runs are the line's runs, in visual order. Each Run's Glyphs is a
+	// sub-slice of the Line's Glyphs.
+	Runs []Run
+
+	// Advance is the total advance width of the line.
+	Advance fixed.Int26_6
+}
+
+// Draw draws the line with d's destination image and source, starting at d's
+// dot, and advances the dot. The glyphs' faces are used instead of d.Face.
+func (l *Line) Draw(d *font.Drawer) {
+	for _, g := range l.Glyphs {
+		dr, mask, maskp, _, ok := g.Face.Glyph(d.Dot.Add(g.Offset), g.Rune)
+		if ok {
+			draw.DrawMask(d.Dst, dr, d.Src, dr.Min, mask, maskp, draw.Over)
+		}
+		d.Dot.X += g.Advance
+	}
+}
+
+// Shaper shapes text with a chain of font faces.
+//
+// A Shaper is not safe for concurrent use, as its faces are not.
+type Shaper struct {
+	// Faces is the font fallback chain, in order of preference. Each cluster
+	// of runes is drawn with the first face that has glyphs for all of it.
+	// If Faces is empty, shaped Lines have no glyphs.
+	Faces []font.Face
+}
+
+// GlyphAdvance returns the advance of r when drawn on its own, by the first
+// face that has a glyph for r. Combining marks and default ignorable runes,
+// such as a zero width joiner, have zero advance. ok is whether any face has
+// a glyph for r.
+func (s *Shaper) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
+	if ignorable(r) {
+		return 0, true
+	}
+	for _, f := range s.Faces {
+		if a, ok := f.GlyphAdvance(r); ok {
+			if isMark(r) {
+				a = 0
+			}
+			return a, true
+		}
+	}
+	return 0, false
+}
+
+// Shape shapes a line of text. dir is the line's base direction. For a line
+// that is part of a longer paragraph, the paragraph's direction should be
+// passed, instead of the Auto value.
+func (s *Shaper) Shape(text string, dir Direction) *Line {
+	rs := make([]rune, 0, len(text))
+	offs := make([]int, 0, len(text)+1)
+	for i, r := range text {
+		rs = append(rs, r)
+		offs = append(offs, i)
+	}
+	offs = append(offs, len(text))
+
+	cls := make([]class, len(rs))
+	for i, r := range rs {
+		cls[i] = classOf(r)
+	}
+	var base uint8
+	switch dir {
+	case Auto:
+		if firstStrong(cls) == cR {
+			base = 1
+		}
+	case RightToLeft:
+		base = 1
+	}
+	levels := resolveLevels(cls, base)
+	scripts := resolveScripts(rs, levels)
+
+	var runs []Run
+	var glyphs [][]Glyph
+	levelsOfRuns := []uint8(nil)
+	for i := 0; i < len(rs); {
+		j := i + 1
+		for j < len(rs) && levels[j] == levels[i] && scripts[j] == scripts[i] {
+			j++
+		}
+		runs = append(runs, Run{
+			Start:  offs[i],
+			End:    offs[j],
+			Script: scripts[i],
+			Level:  levels[i],
+		})
+		glyphs = append(glyphs, s.shapeRun(rs[i:j], offs[i:j], scripts[i], levels[i]&1 != 0))
+		levelsOfRuns = append(levelsOfRuns, levels[i])
+		i = j
+	}
+
+	n := 0
+	for _, g := range glyphs {
+		n += len(g)
+	}
+	l := &Line{
+		Glyphs: make([]Glyph, 0, n),
+		Runs:   make([]Run, 0, len(runs)),
+	}
+	for _, k := range visualOrder(levelsOfRuns) {
+		r := runs[k]
+		start := len(l.Glyphs)
+		l.Glyphs = append(l.Glyphs, glyphs[k]...)
+		r.Glyphs = l.Glyphs[start:len(l.Glyphs):len(l.Glyphs)]
+		l.Runs = append(l.Runs, r)
+	}
+	for _, g := range l.Glyphs {
+		l.Advance += g.Advance
+	}
+	return l
+}
+
+// shapeRun shapes a run of runes with the same script and embedding level,
+// returning its glyphs in visual order. offs holds each rune's byte offset in
+// the shaped text.
+func (s *Shaper) shapeRun(rs []rune, offs []int, script string, rtl bool) []Glyph {
+	if len(s.Faces) == 0 {
+		return nil
+	}
+	indic := viramas[script] != 0
+	if indic {
+		rs, offs = decomposeIndic(rs, offs)
+	}
+	var joins []arabicShape
+	if script == "Arabic" {
+		joins = joinArabic(rs)
+	}
+
+	glyphs := make([]Glyph, 0, len(rs))
+	// clusters holds the index in glyphs of the start of each cluster.
+	clusters := []int(nil)
+	for i := 0; i < len(rs); {
+		j := clusterEnd(rs, i, script)
+		if joins != nil && joins[i].lig != 0 {
+			// A lam and the following alef are one cluster if the face has
+			// their ligature.
+			if k := clusterEnd(rs, j, script); has(s.face(rs[i:k]), joins[i].lig) {
+				j = k
+			}
+		}
+		if indic {
+			reorderIndic(rs[i:j])
+		}
+		face := s.face(rs[i:j])
+		clusters = append(clusters, len(glyphs))
+		hasBase, baseAdvance := false, fixed.Int26_6(0)
+		for k := i; k < j; k++ {
+			r := rs[k]
+			if ignorable(r) {
+				continue
+			}
+			if joins != nil {
+				if a := joins[k]; a.lig != 0 && k+1 < j && has(face, a.lig) {
+					r, k = a.lig, k+1
+				} else if a.form != 0 && has(face, a.form) {
+					r = a.form
+				}
+			}
+			if rtl {
+				r = mirror(r)
+			}
+			f := face
+			a, ok := f.GlyphAdvance(r)
+			if !ok {
+				f, r, a = s.fallback(r)
+			}
+			g := Glyph{
+				Face:    f,
+				Rune:    r,
+				Cluster: offs[i],
+				Advance: a,
+			}
+			if hasBase && isMark(r) {
+				// Center the mark over its base, unless the face has already
+				// positioned it with a zero advance.
+				g.Advance = 0
+				if a != 0 {
+					g.Offset.X = -(baseAdvance + a) / 2
+				}
+			} else {
+				hasBase, baseAdvance = true, a
+			}
+			glyphs = append(glyphs, g)
+		}
+		i = j
+	}
+
+	if rtl {
+		out := make([]Glyph, 0, len(glyphs))
+		for c := len(clusters) - 1; c >= 0; c-- {
+			end := len(glyphs)
+			if c+1 < len(clusters) {
+				end = clusters[c+1]
+			}
+			out = append(out, glyphs[clusters[c]:end]...)
+		}
+		return out
+	}
+
+	// Kern consecutive base glyphs of the same face.
+	prev := -1
+	for i := range glyphs {
+		g := &glyphs[i]
+		if g.Advance == 0 {
+			continue
+		}
+		if prev >= 0 && glyphs[prev].Face == g.Face {
+			glyphs[prev].Advance += g.Face.Kern(glyphs[prev].Rune, g.Rune)
+		}
+		prev = i
+	}
+	return glyphs
+}
+
+// face returns the face to draw a cluster with: the first face that has
+// glyphs for all of the cluster's visible runes or, failing that, the first
+// face that has a glyph for its first visible rune.
+func (s *Shaper) face(cluster []rune) font.Face {
+loop:
+	for _, f := range s.Faces {
+		for _, r := range cluster {
+			if !ignorable(r) && !has(f, r) {
+				continue loop
+			}
+		}
+		return f
+	}
+	for _, r := range cluster {
+		if ignorable(r) {
+			continue
+		}
+		for _, f := range s.Faces {
+			if has(f, r) {
+				return f
+			}
+		}
+		break
+	}
+	return s.Faces[0]
+}
+
+// fallback returns the face, rune and advance to draw r with when the
+// cluster's face has no glyph for it: the first face that has a glyph for r,
+// or else the first face's U+FFFD REPLACEMENT CHARACTER.
+func (s *Shaper) fallback(r rune) (font.Face, rune, fixed.Int26_6) {
+	for _, f := range s.Faces {
+		if a, ok := f.GlyphAdvance(r); ok {
+			return f, r, a
+		}
+	}
+	f := s.Faces[0]
+	if a, ok := f.GlyphAdvance(utf8.RuneError); ok {
+		return f, utf8.RuneError, a
+	}
+	a, _ := f.GlyphAdvance(r)
+	return f, r, a
+}
+
+func has(f font.Face, r rune) bool {
+	_, ok := f.GlyphAdvance(r)
+	return ok
+}
+
+// isMark returns whether r is a non-spacing or enclosing combining mark.
+func isMark(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me)
+}
+
+// ignorable returns whether r is a default ignorable rune, which is not drawn
+// unless supported by the font: joiners, variation selectors, bidirectional
+// formatting characters, tags and other format controls.
+func ignorable(r rune) bool {
+	switch {
+	case r == 0x00AD, r == 0x034F, r == 0x061C, r == 0x180E,
+		0x200B <= r && r <= 0x200F,
+		0x202A <= r && r <= 0x202E,
+		0x2060 <= r && r <= 0x206F,
+		0xFE00 <= r && r <= 0xFE0F,
+		r == 0xFEFF,
+		0xE0000 <= r && r <= 0xE0FFF:
+		return true
+	}
+	return false
+}
+
+// mirror returns the mirrored form of r, for bracket-like runes drawn in a
+// right-to-left run, as per rule L4 of the Unicode Bidirectional Algorithm.
+func mirror(r rune) rune {
+	if m, ok := mirrors[r]; ok {
+		return m
+	}
+	return r
+}
+
+var mirrors = map[rune]rune{
+	'(': ')', ')': '(',
+	'<': '>', '>': '<',
+	'[': ']', ']': '[',
+	'{': '}', '}': '{',
+	'«': '»', '»': '«',
+	'‹': '›', '›': '‹',
+	'⁅': '⁆', '⁆': '⁅',
+	'≤': '≥', '≥': '≤',
+	'〈': '〉', '〉': '〈',
+	'《': '》', '》': '《',
+	'「': '」', '」': '「',
+	'『': '』', '』': '『',
+	'【': '】', '】': '【',
+	'（': '）', '）': '（',
+	'［': '］', '］': '［',
+	'｛': '｝', '｝': '｛',
+}
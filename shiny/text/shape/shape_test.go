@@ -0,0 +1,299 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shape
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// testFace is a font.Face that has 1-pixel-high, adv-wide glyphs for runes.
+type testFace struct {
+	runes string
+	adv   fixed.Int26_6
+}
+
+func (f *testFace) Close() error { return nil }
+
+func (f *testFace) Glyph(dot fixed.Point26_6, r rune) (
+	dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+
+	if !strings.ContainsRune(f.runes, r) {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	x, y := dot.X.Round(), dot.Y.Round()
+	dr = image.Rect(x, y-1, x+f.adv.Round(), y)
+	return dr, image.Opaque, image.Point{}, f.adv, true
+}
+
+func (f *testFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	if !strings.ContainsRune(f.runes, r) {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	return fixed.R(0, -1, f.adv.Round(), 0), f.adv, true
+}
+
+func (f *testFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	if !strings.ContainsRune(f.runes, r) {
+		return 0, false
+	}
+	return f.adv, true
+}
+
+func (f *testFace) Kern(r0, r1 rune) fixed.Int26_6 { return 0 }
+
+func (f *testFace) Metrics() font.Metrics {
+	return font.Metrics{Height: fixed.I(1), Ascent: fixed.I(1)}
+}
+
+func TestLevels(t *testing.T) {
+	const (
+		alef = "א"
+		bet  = "ב"
+		lre  = "\u202A"
+		rli  = "\u2067"
+		pdf  = "\u202C"
+		pdi  = "\u2069"
+	)
+	testCases := []struct {
+		s    string
+		base uint8
+		want []uint8
+	}{
+		{"ab", 0, []uint8{0, 0}},
+		{alef + bet, 0, []uint8{1, 1}},
+		{alef + bet, 1, []uint8{1, 1}},
+		{"a " + alef + bet + " c", 0, []uint8{0, 0, 1, 1, 0, 0}},
+		// Numbers in right-to-left text are at an even level.
+		{alef + " 12", 1, []uint8{1, 1, 2, 2}},
+		// A neutral between a number and right-to-left text is R.
+		{alef + " 12 " + bet, 0, []uint8{1, 1, 2, 2, 1, 1}},
+		// Trailing white space is reset to the paragraph level.
+		{alef + bet + "  ", 0, []uint8{1, 1, 0, 0}},
+		// An embedding raises the level of its contents.
+		{alef + lre + "ab" + pdf + bet, 1, []uint8{1, 1, 2, 2, 2, 1}},
+		// An isolate's contents do not affect the text around it.
+		{"a" + rli + "b" + pdi + "1", 0, []uint8{0, 0, 2, 0, 0}},
+		// A mark takes the level of its base.
+		{alef + "\u05B4", 0, []uint8{1, 1}},
+	}
+	for _, tc := range testCases {
+		var cls []class
+		for _, r := range tc.s {
+			cls = append(cls, classOf(r))
+		}
+		if got := resolveLevels(cls, tc.base); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%+q (base %d): got %v, want %v", tc.s, tc.base, got, tc.want)
+		}
+	}
+}
+
+func TestVisualOrder(t *testing.T) {
+	testCases := []struct {
+		levels []uint8
+		want   []int
+	}{
+		{[]uint8{0, 0, 0}, []int{0, 1, 2}},
+		{[]uint8{1, 1, 1}, []int{2, 1, 0}},
+		{[]uint8{0, 1, 1, 0}, []int{0, 2, 1, 3}},
+		{[]uint8{1, 2, 2, 1}, []int{3, 1, 2, 0}},
+	}
+	for _, tc := range testCases {
+		if got := visualOrder(tc.levels); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: got %v, want %v", tc.levels, got, tc.want)
+		}
+	}
+}
+
+func TestParagraphDirection(t *testing.T) {
+	testCases := []struct {
+		s    string
+		want Direction
+	}{
+		{"", LeftToRight},
+		{"123 abc", LeftToRight},
+		{"123 אbc", RightToLeft},
+		{"(ا)", RightToLeft},
+	}
+	for _, tc := range testCases {
+		if got := ParagraphDirection(tc.s); got != tc.want {
+			t.Errorf("%+q: got %v, want %v", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestScripts(t *testing.T) {
+	rs := []rune("1 abc אב (कि)")
+	got := resolveScripts(rs, make([]uint8, len(rs)))
+	want := []string{
+		"Latin", "Latin", "Latin", "Latin", "Latin", "Latin",
+		"Hebrew", "Hebrew", "Hebrew", "Hebrew",
+		"Devanagari", "Devanagari", "Devanagari",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+// runes returns the runes of l's glyphs.
+func runes(l *Line) string {
+	var b []rune
+	for _, g := range l.Glyphs {
+		b = append(b, g.Rune)
+	}
+	return string(b)
+}
+
+func TestShapeBidi(t *testing.T) {
+	s := &Shaper{Faces: []font.Face{&testFace{
+		runes: "abc ()אבג",
+		adv:   fixed.I(2),
+	}}}
+
+	l := s.Shape("ab אבג c", Auto)
+	if got, want := runes(l), "ab גבא c"; got != want {
+		t.Errorf("LTR: got %+q, want %+q", got, want)
+	}
+	if got, want := len(l.Runs), 3; got != want {
+		t.Fatalf("LTR: got %d runs, want %d", got, want)
+	}
+	if r := l.Runs[1]; r.Script != "Hebrew" || r.Level != 1 || r.Start != 3 || r.End != 9 || len(r.Glyphs) != 3 {
+		t.Errorf("LTR: Hebrew run: got %+v", r)
+	}
+	if got, want := l.Advance, fixed.I(2*len("ab  c")+2*3); got != want {
+		t.Errorf("LTR: advance: got %v, want %v", got, want)
+	}
+
+	// In right-to-left text, brackets are mirrored.
+	l = s.Shape("א(ב)", Auto)
+	if got, want := runes(l), "(ב)א"; got != want {
+		t.Errorf("RTL: got %+q, want %+q", got, want)
+	}
+	if got, want := l.Glyphs[3].Cluster, 0; got != want {
+		t.Errorf("RTL: cluster: got %d, want %d", got, want)
+	}
+}
+
+func TestShapeFallback(t *testing.T) {
+	primary := &testFace{runes: "ab\uFFFD", adv: fixed.I(2)}
+	emoji := &testFace{runes: "a\U0001F44D\U0001F3FD", adv: fixed.I(5)}
+	s := &Shaper{Faces: []font.Face{primary, emoji}}
+
+	// The thumbs up and skin tone modifier are one cluster, drawn with the
+	// emoji face. The variation selector is not drawn. U+00E9 is in neither
+	// face, so it is drawn as the primary face's U+FFFD.
+	l := s.Shape("a\U0001F44D\U0001F3FD\uFE0Fb\u00E9", LeftToRight)
+	type glyph struct {
+		face    font.Face
+		r       rune
+		cluster int
+	}
+	var got []glyph
+	for _, g := range l.Glyphs {
+		got = append(got, glyph{g.Face, g.Rune, g.Cluster})
+	}
+	want := []glyph{
+		{primary, 'a', 0},
+		{emoji, 0x1F44D, 1},
+		{emoji, 0x1F3FD, 1},
+		{primary, 'b', 12},
+		{primary, 0xFFFD, 13},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+
+	if a, ok := s.GlyphAdvance(0x1F44D); a != fixed.I(5) || !ok {
+		t.Errorf("GlyphAdvance(U+1F44D): got %v, %t, want 5:00, true", a, ok)
+	}
+	if a, ok := s.GlyphAdvance(zwj); a != 0 || !ok {
+		t.Errorf("GlyphAdvance(ZWJ): got %v, %t, want 0:00, true", a, ok)
+	}
+	if _, ok := s.GlyphAdvance('z'); ok {
+		t.Errorf("GlyphAdvance('z'): got ok, want !ok")
+	}
+}
+
+func TestShapeArabic(t *testing.T) {
+	const (
+		beh  = "\u0628"
+		lamR = "\u0644"
+		alef = "\u0627"
+	)
+	// beh, lam, alef: the beh joins to the lam, which forms a ligature with
+	// the alef.
+	text := beh + lamR + alef
+	forms := &testFace{runes: "\u0628\u0644\u0627\uFE91\uFEFC", adv: fixed.I(3)}
+	l := (&Shaper{Faces: []font.Face{forms}}).Shape(text, Auto)
+	if got, want := runes(l), "\uFEFC\uFE91"; got != want {
+		t.Errorf("with forms: got %+q, want %+q", got, want)
+	}
+
+	// Without the presentation forms, the nominal letters are drawn.
+	nominal := &testFace{runes: beh + lamR + alef, adv: fixed.I(3)}
+	l = (&Shaper{Faces: []font.Face{nominal}}).Shape(text, Auto)
+	if got, want := runes(l), alef+lamR+beh; got != want {
+		t.Errorf("nominal: got %+q, want %+q", got, want)
+	}
+}
+
+func TestShapeIndic(t *testing.T) {
+	// KA, VIRAMA, SSA, I: the pre-base vowel sign I is drawn before the
+	// syllable.
+	f := &testFace{runes: "क्षि", adv: fixed.I(4)}
+	l := (&Shaper{Faces: []font.Face{f}}).Shape("क्षि", Auto)
+	if got, want := runes(l), "िक्ष"; got != want {
+		t.Errorf("got %+q, want %+q", got, want)
+	}
+	for i, g := range l.Glyphs {
+		if g.Cluster != 0 {
+			t.Errorf("glyph %d: cluster: got %d, want 0", i, g.Cluster)
+		}
+	}
+}
+
+func TestShapeMarks(t *testing.T) {
+	f := &testFace{runes: "e\u0301", adv: fixed.I(4)}
+	l := (&Shaper{Faces: []font.Face{f}}).Shape("e\u0301e", Auto)
+	if got, want := len(l.Glyphs), 3; got != want {
+		t.Fatalf("got %d glyphs, want %d", got, want)
+	}
+	mark := l.Glyphs[1]
+	if mark.Advance != 0 || mark.Offset.X != -fixed.I(4) || mark.Cluster != 0 {
+		t.Errorf("mark: got %+v", mark)
+	}
+	if got, want := l.Advance, fixed.I(8); got != want {
+		t.Errorf("advance: got %v, want %v", got, want)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	narrow := &testFace{runes: "a", adv: fixed.I(1)}
+	wide := &testFace{runes: "b", adv: fixed.I(2)}
+	l := (&Shaper{Faces: []font.Face{narrow, wide}}).Shape("aba", Auto)
+
+	dst := image.NewRGBA(image.Rect(0, 0, 6, 1))
+	d := font.Drawer{
+		Dst: dst,
+		Src: image.NewUniform(color.RGBA{0xff, 0, 0, 0xff}),
+		Dot: fixed.P(1, 1),
+	}
+	l.Draw(&d)
+	if got, want := d.Dot, fixed.P(5, 1); got != want {
+		t.Errorf("dot: got %v, want %v", got, want)
+	}
+	for x := 0; x < 6; x++ {
+		painted := dst.RGBAAt(x, 0).A != 0
+		if want := 1 <= x && x < 5; painted != want {
+			t.Errorf("x=%d: painted: got %t, want %t", x, painted, want)
+		}
+	}
+}
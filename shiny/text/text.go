@@ -40,6 +40,7 @@ import (
 	"io"
 	"unicode/utf8"
 
+	"golang.org/x/exp/shiny/text/shape"
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
@@ -114,6 +115,11 @@ type Frame struct {
 	faceHeight int32
 	face       font.Face
 
+	// fallbackFaces are the faces for runes that face has no glyph for, and
+	// shaper shapes text with face followed by fallbackFaces.
+	fallbackFaces []font.Face
+	shaper        shape.Shaper
+
 	// len is the total length of the Frame's current textual content, in
 	// bytes. It can be smaller then len(text), since that []byte can contain
 	// 'holes' of deleted content.
@@ -163,11 +169,36 @@ func (f *Frame) SetFace(face font.Face) {
 		m := face.Metrics()
 		f.faceHeight = int32(m.Ascent.Ceil() + m.Descent.Ceil())
 	}
+	f.setShaperFaces()
+	if f.len != 0 {
+		f.relayout()
+	}
+}
+
+// SetFallbackFaces sets the font faces, in order of preference, for measuring
+// and shaping runes that the Frame's face has no glyph for, such as emoji or
+// another script's letters.
+//
+// Line heights are still those of the Frame's face.
+func (f *Frame) SetFallbackFaces(faces []font.Face) {
+	if !f.initialized() {
+		f.initialize()
+	}
+	f.fallbackFaces = append(f.fallbackFaces[:0], faces...)
+	f.setShaperFaces()
 	if f.len != 0 {
 		f.relayout()
 	}
 }
 
+func (f *Frame) setShaperFaces() {
+	f.shaper.Faces = f.shaper.Faces[:0]
+	if f.face != nil {
+		f.shaper.Faces = append(f.shaper.Faces, f.face)
+		f.shaper.Faces = append(f.shaper.Faces, f.fallbackFaces...)
+	}
+}
+
 // TODO: should SetMaxWidth take an int number of pixels instead of a
 // fixed.Int26_6 number of sub-pixels? Height returns an int, since it assumes
 // that the text baselines are quantized to the integer pixel grid.
@@ -668,6 +699,20 @@ func (p *Paragraph) Next(f *Frame) *Paragraph {
 	return &f.paragraphs[p.next]
 }
 
+// Direction returns the base direction of this Paragraph: the direction of its
+// first strong character, as per the Unicode Bidirectional Algorithm.
+//
+// f is the Frame that contains the Paragraph.
+func (p *Paragraph) Direction(f *Frame) shape.Direction {
+	var buf []byte
+	for l := p.firstL; l != 0; l = f.lines[l].next {
+		for b := f.lines[l].firstB; b != 0; b = f.boxes[b].next {
+			buf = append(buf, f.boxes[b].Text(f)...)
+		}
+	}
+	return shape.ParagraphDirection(string(buf))
+}
+
 func (p *Paragraph) invalidateCaches() {
 	p.cachedHeightPlus1 = 0
 	p.cachedLineCountPlus1 = 0
@@ -732,6 +777,19 @@ func (l *Line) Next(f *Frame) *Line {
 	return &f.lines[l.next]
 }
 
+// Shape returns the shaped text of this Line, trimmed right of any white space
+// as per Box.TrimmedText, with the Frame's face and fallback faces. dir should
+// be the direction of the Paragraph that contains the Line.
+//
+// f is the Frame that contains the Line.
+func (l *Line) Shape(f *Frame, dir shape.Direction) *shape.Line {
+	var buf []byte
+	for b := l.firstB; b != 0; b = f.boxes[b].next {
+		buf = append(buf, f.boxes[b].TrimmedText(f)...)
+	}
+	return f.shaper.Shape(string(buf), dir)
+}
+
 func (l *Line) invalidateCaches() {
 	l.cachedHeightPlus1 = 0
 }
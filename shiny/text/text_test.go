@@ -17,6 +17,7 @@ import (
 	"testing"
 	"unicode/utf8"
 
+	"golang.org/x/exp/shiny/text/shape"
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
@@ -497,6 +498,100 @@ func TestSetMaxWidth(t *testing.T) {
 	}
 }
 
+// asciiFace is a toyFace that has no glyphs for non-ASCII runes.
+type asciiFace struct{ toyFace }
+
+func (asciiFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	return fixed.I(1), r < utf8.RuneSelf
+}
+
+// wideFace is a toyFace whose glyphs are 4 pixels wide.
+type wideFace struct{ toyFace }
+
+func (wideFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	return fixed.I(4), true
+}
+
+func TestSetFallbackFaces(t *testing.T) {
+	f := new(Frame)
+	f.SetFace(asciiFace{})
+	f.SetFallbackFaces([]font.Face{wideFace{}})
+	f.SetMaxWidth(fixed.I(10))
+	c := f.NewCaret()
+	c.WriteString("abc אב d\n")
+	c.Close()
+	if err := checkInvariants(f); err != nil {
+		t.Fatal(err)
+	}
+
+	// The Hebrew letters are measured with the fallback face, so that they
+	// do not fit on the first Line.
+	p := f.FirstParagraph()
+	if got, want := p.LineCount(f), 2; got != want {
+		t.Fatalf("LineCount: got %d, want %d", got, want)
+	}
+	if got, want := p.Direction(f), shape.LeftToRight; got != want {
+		t.Errorf("Direction: got %v, want %v", got, want)
+	}
+	l := p.FirstLine(f).Next(f).Shape(f, shape.LeftToRight)
+	if got, want := l.Advance, fixed.I(4+4+1+1); got != want {
+		t.Errorf("second Line: advance: got %v, want %v", got, want)
+	}
+	if got, want := len(l.Runs), 2; got != want {
+		t.Fatalf("second Line: got %d runs, want %d", got, want)
+	}
+	if r := l.Runs[0]; r.Script != "Hebrew" || r.Level != 1 || r.Glyphs[0].Face != (wideFace{}) {
+		t.Errorf("second Line: first run: got %+v", r)
+	}
+}
+
+// sparseFace is an asciiFace that also has a 2 pixel wide U+FFFD replacement
+// character, and that kerns every rune after a non-ASCII one by 1 pixel.
+type sparseFace struct{ asciiFace }
+
+func (sparseFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	if r == '\uFFFD' {
+		return fixed.I(2), true
+	}
+	return asciiFace{}.GlyphAdvance(r)
+}
+
+func (sparseFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	if r0 >= utf8.RuneSelf {
+		return fixed.I(1)
+	}
+	return 0
+}
+
+func TestWrapText(t *testing.T) {
+	for _, face := range []font.Face{toyFace{}, sparseFace{}} {
+		for maxWidth := 0; maxWidth < 20; maxWidth++ {
+			f := new(Frame)
+			f.SetFace(face)
+			f.SetMaxWidth(fixed.I(maxWidth))
+			c := f.NewCaret()
+			c.WriteString(iRobot)
+			c.Close()
+
+			var want []string
+			for p := f.FirstParagraph(); p != nil; p = p.Next(f) {
+				for l := p.FirstLine(f); l != nil; l = l.Next(f) {
+					line := ""
+					for b := l.FirstBox(f); b != nil; b = b.Next(f) {
+						line += string(b.Text(f))
+					}
+					want = append(want, strings.TrimSuffix(line, "\n"))
+				}
+			}
+
+			got := WrapText(face, iRobot, fixed.I(maxWidth))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%T: maxWidth=%d:\ngot  %q\nwant %q", face, maxWidth, got, want)
+			}
+		}
+	}
+}
+
 func TestReadRuneAcrossBoxes(t *testing.T) {
 	rng := rand.New(rand.NewSource(1))
 	for i := 0; i < 6; i++ {
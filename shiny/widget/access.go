@@ -0,0 +1,102 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/widget/node"
+)
+
+// accessTree builds the accessibility trees of a widget tree, from the
+// node.Describers in it, giving each node the same ID for as long as it is in
+// the tree.
+type accessTree struct {
+	ids    map[*node.Embed]int
+	nodes  map[int]accessNode
+	nextID int
+
+	// last is the most recently built tree.
+	last *screen.AccessNode
+}
+
+// accessNode is a node of a built tree, and its widget.
+type accessNode struct {
+	n *node.Embed
+	a *screen.AccessNode
+}
+
+// build returns the accessibility tree of the widget tree whose root is root,
+// and whether it differs from the previously built one. The tree's root is a
+// group for the whole window, whose ID is 0.
+func (t *accessTree) build(root node.Node) (a *screen.AccessNode, changed bool) {
+	oldIDs := t.ids
+	t.ids, t.nodes = map[*node.Embed]int{}, map[int]accessNode{}
+	r := root.Wrappee()
+	a = &screen.AccessNode{
+		Role:     screen.AccessGroup,
+		Bounds:   r.Rect,
+		Children: t.children(r, oldIDs, node.Focused(root)),
+	}
+	if d, ok := r.Wrapper.(node.Describer); ok {
+		d.Describe(a)
+		t.nodes[0] = accessNode{r, a}
+	}
+	changed = !accessEqual(a, t.last)
+	t.last = a
+	return a, changed
+}
+
+// children returns the accessibility nodes of n's children, or of their
+// descendants for those that are not node.Describers.
+func (t *accessTree) children(n *node.Embed, oldIDs map[*node.Embed]int, focus node.Node) (ret []*screen.AccessNode) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		d, ok := c.Wrapper.(node.Describer)
+		if !ok {
+			ret = append(ret, t.children(c, oldIDs, focus)...)
+			continue
+		}
+		id, ok := oldIDs[c]
+		if !ok {
+			t.nextID++
+			id = t.nextID
+		}
+		a := &screen.AccessNode{
+			ID:      id,
+			Bounds:  c.Rect.Add(windowOrigin(c)),
+			Focused: focus != nil && focus.Wrappee() == c,
+		}
+		t.ids[c], t.nodes[id] = id, accessNode{c, a}
+		d.Describe(a)
+		a.Children = t.children(c, oldIDs, focus)
+		ret = append(ret, a)
+	}
+	return ret
+}
+
+// node returns the widget, and the accessibility node, whose ID is id in the
+// most recently built tree. They are nil if there are none.
+func (t *accessTree) node(id int) (*node.Embed, *screen.AccessNode) {
+	an := t.nodes[id]
+	return an.n, an.a
+}
+
+// accessEqual returns whether a and b, either of which may be nil, describe
+// the same tree.
+func accessEqual(a, b *screen.AccessNode) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.ID != b.ID || a.Role != b.Role || a.Name != b.Name || a.Value != b.Value ||
+		a.Bounds != b.Bounds || a.Focused != b.Focused || a.Disabled != b.Disabled ||
+		len(a.Children) != len(b.Children) {
+		return false
+	}
+	for i := range a.Children {
+		if !accessEqual(a.Children[i], b.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
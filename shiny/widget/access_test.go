@@ -0,0 +1,74 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/unit"
+	"golang.org/x/exp/shiny/widget/node"
+)
+
+func TestAccessTree(t *testing.T) {
+	l := NewLabel("Name")
+	f := NewTextField("Gopher")
+	p := NewPadder(AxisBoth, unit.Pixels(0), f)
+	root := NewFlow(AxisVertical, l, p)
+	root.Rect = image.Rect(0, 0, 100, 100)
+	l.Rect = image.Rect(0, 0, 100, 20)
+	p.Rect = image.Rect(0, 20, 100, 50)
+	f.Rect = image.Rect(5, 5, 95, 25)
+
+	var access accessTree
+	a, changed := access.build(root)
+	if !changed {
+		t.Errorf("first build: got unchanged, want changed")
+	}
+	// The Flow and Padder are not node.Describers, so the label and text
+	// field are the children of the window's root group.
+	want := &screen.AccessNode{
+		Role:   screen.AccessGroup,
+		Bounds: image.Rect(0, 0, 100, 100),
+		Children: []*screen.AccessNode{{
+			ID:     1,
+			Role:   screen.AccessLabel,
+			Name:   "Name",
+			Bounds: image.Rect(0, 0, 100, 20),
+		}, {
+			ID:     2,
+			Role:   screen.AccessTextField,
+			Value:  "Gopher",
+			Bounds: image.Rect(5, 25, 95, 45),
+		}},
+	}
+	if !accessEqual(a, want) {
+		t.Fatalf("first build: got %v, want %v", a, want)
+	}
+	if _, changed := access.build(root); changed {
+		t.Errorf("second build: got changed, want unchanged")
+	}
+
+	// Nodes keep their IDs, and new nodes get new ones.
+	node.Focus(f)
+	root.Insert(NewLabel("New"), l)
+	a, changed = access.build(root)
+	if !changed {
+		t.Errorf("third build: got unchanged, want changed")
+	}
+	if len(a.Children) != 3 {
+		t.Fatalf("third build: got %d children, want 3", len(a.Children))
+	}
+	if got := a.Children[0]; got.ID != 3 || got.Name != "New" {
+		t.Errorf("third build: got new label %v, want ID 3", got)
+	}
+	if got := a.Children[2]; got.ID != 2 || !got.Focused {
+		t.Errorf("third build: got text field %v, want ID 2, focused", got)
+	}
+	if n, _ := access.node(2); n != &f.Embed {
+		t.Errorf("node(2): got %p, want the text field %p", n, &f.Embed)
+	}
+}
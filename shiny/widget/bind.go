@@ -0,0 +1,139 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"sync"
+
+	"golang.org/x/exp/shiny/widget/node"
+)
+
+// Value is an observable value that widgets' properties can be bound to, so
+// that setting the value updates those properties and marks their widgets as
+// needing to be laid out and painted again. For example:
+//
+//	count := widget.NewValue(0)
+//	label := widget.NewLabel("")
+//	count.Bind(label, 0, func(x interface{}) {
+//		label.Text = fmt.Sprint(x)
+//	})
+//
+// after which count.Set(1), from any goroutine, updates the label.
+//
+// It is safe to call a Value's methods concurrently.
+type Value struct {
+	mu       sync.Mutex
+	x        interface{}
+	bindings []*binding
+}
+
+// binding is a widget property that is bound to a Value.
+type binding struct {
+	n     node.Node
+	marks node.Marks
+	apply func(x interface{})
+}
+
+// NewValue returns a new Value that holds x.
+func NewValue(x interface{}) *Value {
+	return &Value{x: x}
+}
+
+// Get returns the value.
+func (v *Value) Get() interface{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.x
+}
+
+// Set sets the value, and updates the properties that are bound to it.
+//
+// A bound widget that is in the tree of a window run by RunWindow is updated
+// on that window's event loop, after Set returns, so that the widget tree is
+// only ever changed by that loop's goroutine. One that is not, such as one
+// whose tree has not been given to RunWindow yet, is updated before Set
+// returns.
+func (v *Value) Set(x interface{}) {
+	v.mu.Lock()
+	v.x = x
+	bs := append([]*binding(nil), v.bindings...)
+	v.mu.Unlock()
+	if len(bs) == 0 {
+		return
+	}
+
+	windowsMu.Lock()
+	running := len(windows) != 0
+	for _, w := range windows {
+		w.Send(bindingEvent{v})
+	}
+	windowsMu.Unlock()
+	if !running {
+		for _, b := range bs {
+			b.update(x)
+		}
+	}
+}
+
+// Bind binds a property of n to the value: apply, which sets that property,
+// is called with the value now and after every change to it, after which n
+// is marked with marks. A zero marks means to mark n as needing to be
+// measured, laid out and painted again.
+//
+// Bind should be called before n's tree is given to RunWindow, or on its
+// event loop, such as from an event handler. The returned unbind function
+// stops the updates, such as before n is removed from its tree.
+func (v *Value) Bind(n node.Node, marks node.Marks, apply func(x interface{})) (unbind func()) {
+	if marks == 0 {
+		marks = node.MarkNeedsMeasureLayout | node.MarkNeedsPaint | node.MarkNeedsPaintBase
+	}
+	b := &binding{n: n, marks: marks, apply: apply}
+	v.mu.Lock()
+	v.bindings = append(v.bindings, b)
+	x := v.x
+	v.mu.Unlock()
+	b.update(x)
+
+	return func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		for i, c := range v.bindings {
+			if c == b {
+				v.bindings = append(v.bindings[:i], v.bindings[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (b *binding) update(x interface{}) {
+	b.apply(x)
+	b.n.Wrappee().Mark(b.marks)
+}
+
+// bindingEvent is sent to every running window by Value.Set.
+type bindingEvent struct {
+	v *Value
+}
+
+// updateBindings updates those of the value's bindings whose widgets are in
+// the tree whose root is root.
+func updateBindings(root node.Node, v *Value) {
+	v.mu.Lock()
+	x := v.x
+	bs := append([]*binding(nil), v.bindings...)
+	v.mu.Unlock()
+
+	r := root.Wrappee()
+	for _, b := range bs {
+		n := b.n.Wrappee()
+		for n.Parent != nil {
+			n = n.Parent
+		}
+		if n == r {
+			b.update(x)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/exp/shiny/widget/node"
+)
+
+func TestValue(t *testing.T) {
+	v := NewValue(1)
+	l := NewLabel("")
+	root := NewFlow(AxisVertical, l)
+	unbind := v.Bind(l, node.MarkNeedsPaint, func(x interface{}) {
+		l.Text = fmt.Sprint(x)
+	})
+	if got, want := l.Text, "1"; got != want {
+		t.Fatalf("after Bind: got %q, want %q", got, want)
+	}
+
+	// Without a running window, Set updates the label, and marks it and its
+	// ancestors, before it returns.
+	l.Marks, root.Marks = 0, 0
+	v.Set(2)
+	if got, want := l.Text, "2"; got != want {
+		t.Errorf("after Set: got %q, want %q", got, want)
+	}
+	if !l.Marks.NeedsPaint() || !root.Marks.DescendantNeedsPaint() {
+		t.Errorf("after Set: got marks %v, %v, want the label needing paint", l.Marks, root.Marks)
+	}
+
+	// A window's event loop updates only the bindings in its tree.
+	v.mu.Lock()
+	v.x = 3
+	v.mu.Unlock()
+	updateBindings(NewLabel(""), v)
+	if got, want := l.Text, "2"; got != want {
+		t.Errorf("after updating another tree: got %q, want %q", got, want)
+	}
+	updateBindings(root, v)
+	if got, want := l.Text, "3"; got != want {
+		t.Errorf("after updating the label's tree: got %q, want %q", got, want)
+	}
+
+	unbind()
+	v.Set(4)
+	if got, want := l.Text, "3"; got != want {
+		t.Errorf("after unbinding: got %q, want %q", got, want)
+	}
+	if got, want := v.Get(), 4; got != want {
+		t.Errorf("Get: got %v, want %v", got, want)
+	}
+}
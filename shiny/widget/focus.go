@@ -0,0 +1,102 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+	"image/draw"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/widget/node"
+	"golang.org/x/exp/shiny/widget/theme"
+)
+
+// TabGroup is a shell widget that overrides the order in which pressing Tab
+// moves the keyboard focus through its inner widget's descendants. Those that
+// are not in Order, nor descended from a node in Order, are skipped.
+type TabGroup struct {
+	node.ShellEmbed
+
+	// Order is the nodes, or the containers of the nodes, in the order that
+	// Tab visits them.
+	Order []node.Node
+}
+
+// NewTabGroup returns a new TabGroup widget.
+func NewTabGroup(inner node.Node, order ...node.Node) *TabGroup {
+	w := &TabGroup{
+		Order: order,
+	}
+	w.Wrapper = w
+	if inner != nil {
+		w.Insert(inner, nil)
+	}
+	return w
+}
+
+func (w *TabGroup) TabOrder() []node.Node { return w.Order }
+
+// focusRing returns where the focus ring of n is, in window coordinates, and
+// the part of it that is visible, which is clipped by n's ancestors.
+func focusRing(n *node.Embed) (r, clip image.Rectangle) {
+	r = n.Rect.Add(windowOrigin(n))
+	clip = r
+	for p := n.Parent; p != nil; p = p.Parent {
+		clip = clip.Intersect(p.Rect.Add(windowOrigin(p)))
+	}
+	return r, clip
+}
+
+// paintFocusRing paints a focus ring just inside r, clipped to clip.
+func paintFocusRing(w screen.Window, t *theme.Theme, r, clip image.Rectangle) {
+	t = t.Style("FocusRing")
+	c := t.GetPalette().Accent().C
+	d := t.Pixels(t.GetFocusRingWidth()).Ceil()
+	if d > r.Dx()/2 || d > r.Dy()/2 {
+		// The ring would fill n.
+		return
+	}
+	for _, edge := range [...]image.Rectangle{
+		{r.Min, image.Point{r.Max.X, r.Min.Y + d}},
+		{image.Point{r.Min.X, r.Max.Y - d}, r.Max},
+		{image.Point{r.Min.X, r.Min.Y + d}, image.Point{r.Min.X + d, r.Max.Y - d}},
+		{image.Point{r.Max.X - d, r.Min.Y + d}, image.Point{r.Max.X, r.Max.Y - d}},
+	} {
+		if edge = edge.Intersect(clip); !edge.Empty() {
+			w.Fill(edge, c, draw.Over)
+		}
+	}
+}
+
+// scrollIntoView scrolls n's ancestor Scrollers, where needed, so that as much
+// of n as fits is visible.
+func scrollIntoView(n *node.Embed) {
+	// r is where n is, in the coordinate space of the ancestor p's children.
+	r := n.Rect
+	for p := n.Parent; p != nil; p = p.Parent {
+		sc, ok := p.Wrapper.(*Scroller)
+		if !ok {
+			r = r.Add(p.Rect.Min)
+			continue
+		}
+		o, v := sc.Offset(), sc.viewport()
+		if r.Max.X > v.Max.X {
+			o.X += r.Max.X - v.Max.X
+		}
+		if r.Max.Y > v.Max.Y {
+			o.Y += r.Max.Y - v.Max.Y
+		}
+		if r.Min.X < o.X {
+			o.X = r.Min.X
+		}
+		if r.Min.Y < o.Y {
+			o.Y = r.Min.Y
+		}
+		if o != sc.Offset() {
+			sc.ScrollTo(o)
+		}
+		r = r.Sub(sc.Offset()).Add(p.Rect.Min)
+	}
+}
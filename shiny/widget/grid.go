@@ -0,0 +1,375 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+
+	"golang.org/x/exp/shiny/unit"
+	"golang.org/x/exp/shiny/widget/node"
+	"golang.org/x/exp/shiny/widget/theme"
+)
+
+// TrackSizing is how a Grid's row or column, also called a track, is sized.
+type TrackSizing uint8
+
+const (
+	// TrackContent is a track that is as large as the largest of its
+	// children's measured sizes.
+	TrackContent TrackSizing = iota
+
+	// TrackFixed is a track whose size is its Track.Size.
+	TrackFixed
+
+	// TrackWeighted is a track that shares, with the other weighted tracks,
+	// the Grid's space that is left over after its other tracks, in
+	// proportion to its Track.Weight. For example, weighted tracks of
+	// weights 2 and 1 are laid out as two thirds and one third of that
+	// space. The Grid's natural size is the smallest that fits every
+	// weighted track's children.
+	TrackWeighted
+)
+
+// Track is the sizing of a Grid's row or column. The zero value is a
+// TrackContent track.
+type Track struct {
+	Sizing TrackSizing
+
+	// Size is the size of a TrackFixed track.
+	Size unit.Value
+
+	// Weight is the weight of a TrackWeighted track. A non-positive Weight
+	// is equivalent to 1.
+	Weight int
+}
+
+func (tr Track) weight() int {
+	if tr.Weight <= 0 {
+		return 1
+	}
+	return tr.Weight
+}
+
+// FixedTrack returns a TrackFixed track of the given size.
+func FixedTrack(size unit.Value) Track {
+	return Track{Sizing: TrackFixed, Size: size}
+}
+
+// WeightedTrack returns a TrackWeighted track of the given weight.
+func WeightedTrack(weight int) Track {
+	return Track{Sizing: TrackWeighted, Weight: weight}
+}
+
+// Align is how a child is aligned, along an axis, within the space that it is
+// laid out in.
+type Align uint8
+
+const (
+	// AlignAuto is the default alignment, which is the parent's. For the
+	// parent itself, it is AlignStretch.
+	AlignAuto Align = iota
+	// AlignStretch sizes the child to fill that space.
+	AlignStretch
+	// AlignStart, AlignCenter and AlignEnd keep the child's measured size,
+	// if it fits, and align it at the start (the left or top), center or end
+	// of that space.
+	AlignStart
+	AlignCenter
+	AlignEnd
+)
+
+// align returns the offset and length of a child of the given size, aligned
+// by a within space of the given length.
+func align(a Align, space, size int) (offset, length int) {
+	if a == AlignAuto || a == AlignStretch || size >= space {
+		return 0, space
+	}
+	switch a {
+	case AlignCenter:
+		return (space - size) / 2, size
+	case AlignEnd:
+		return space - size, size
+	}
+	return 0, size
+}
+
+// Grid is a container widget that lays out its children in the cells of a
+// grid of rows and columns. A child's cell, and how many rows and columns it
+// spans, is set by its LayoutData being a GridLayoutData. Children without
+// one are placed in the next cell, in row-major order, after the previous
+// child, wrapping at the Grid's number of Columns.
+type Grid struct {
+	node.ContainerEmbed
+
+	// Columns and Rows are the sizings of the Grid's tracks. Columns and rows
+	// beyond those listed, whose cells have children, are TrackContent
+	// tracks.
+	Columns, Rows []Track
+
+	// ColumnGap and RowGap are the space between adjacent columns and rows.
+	ColumnGap, RowGap unit.Value
+
+	// AlignX and AlignY are the default alignments of children within their
+	// cells, horizontally and vertically.
+	AlignX, AlignY Align
+}
+
+// NewGrid returns a new Grid widget with the given columns, containing the
+// given children.
+func NewGrid(columns []Track, children ...node.Node) *Grid {
+	w := &Grid{
+		Columns: columns,
+	}
+	w.Wrapper = w
+	for _, c := range children {
+		w.Insert(c, nil)
+	}
+	return w
+}
+
+// GridLayoutData is the node LayoutData type for a Grid's children.
+type GridLayoutData struct {
+	// Column and Row are the cell that the child is in, or the first of the
+	// cells that it spans, counting from zero.
+	Column, Row int
+
+	// ColumnSpan and RowSpan are how many columns and rows the child spans.
+	// A non-positive span is equivalent to 1.
+	ColumnSpan, RowSpan int
+
+	// AlignX and AlignY are the child's alignments within its cells,
+	// horizontally and vertically. AlignAuto means those of the Grid.
+	AlignX, AlignY Align
+}
+
+// gridSpan is where a child is along one of a Grid's axes: from the start
+// track, spanning span tracks.
+type gridSpan struct {
+	start, span int
+}
+
+// gridCell is where a child is in a Grid.
+type gridCell struct {
+	n      *node.Embed
+	x, y   gridSpan
+	ax, ay Align
+}
+
+// cells returns where the children are, and how many columns and rows there
+// are.
+func (w *Grid) cells() (cells []gridCell, nCols, nRows int) {
+	nCols, nRows = len(w.Columns), len(w.Rows)
+	wrap := len(w.Columns)
+	if wrap == 0 {
+		wrap = 1
+	}
+	col, row := 0, 0
+	for c := w.FirstChild; c != nil; c = c.NextSibling {
+		gc := gridCell{n: c}
+		if d, ok := c.LayoutData.(GridLayoutData); ok {
+			gc.x = gridSpan{d.Column, d.ColumnSpan}
+			gc.y = gridSpan{d.Row, d.RowSpan}
+			gc.ax, gc.ay = d.AlignX, d.AlignY
+		} else {
+			if col >= wrap {
+				col, row = 0, row+1
+			}
+			gc.x = gridSpan{col, 1}
+			gc.y = gridSpan{row, 1}
+		}
+		for _, s := range [...]*gridSpan{&gc.x, &gc.y} {
+			if s.start < 0 {
+				s.start = 0
+			}
+			if s.span <= 0 {
+				s.span = 1
+			}
+		}
+		if gc.ax == AlignAuto {
+			gc.ax = w.AlignX
+		}
+		if gc.ay == AlignAuto {
+			gc.ay = w.AlignY
+		}
+		col, row = gc.x.start+gc.x.span, gc.y.start
+		if n := gc.x.start + gc.x.span; nCols < n {
+			nCols = n
+		}
+		if n := gc.y.start + gc.y.span; nRows < n {
+			nRows = n
+		}
+		cells = append(cells, gc)
+	}
+	return cells, nCols, nRows
+}
+
+// trackSizes returns the sizes of n tracks, whose sizings are tracks, that
+// are separated by gap and are to fit within the given space, or their
+// natural sizes if space is negative. spans and sizes are where the children
+// are, and their measured sizes, along the tracks' axis.
+func trackSizes(t *theme.Theme, tracks []Track, n, gap, space int, spans []gridSpan, sizes []int) []int {
+	track := func(i int) Track {
+		if i < len(tracks) {
+			return tracks[i]
+		}
+		return Track{}
+	}
+
+	// The content size of a track is the largest size of its children, or
+	// its size if it is fixed. Children that span multiple tracks share out
+	// what they need beyond the spanned tracks' content sizes over those
+	// tracks that are not fixed.
+	content := make([]int, n)
+	for i := range content {
+		if tr := track(i); tr.Sizing == TrackFixed {
+			content[i] = t.Pixels(tr.Size).Round()
+		}
+	}
+	for i, s := range spans {
+		if track(s.start).Sizing == TrackFixed {
+			continue
+		}
+		if s.span == 1 && content[s.start] < sizes[i] {
+			content[s.start] = sizes[i]
+		}
+	}
+	for i, s := range spans {
+		if s.span == 1 {
+			continue
+		}
+		need, flexible := sizes[i]-gap*(s.span-1), 0
+		for j := s.start; j < s.start+s.span; j++ {
+			need -= content[j]
+			if track(j).Sizing != TrackFixed {
+				flexible++
+			}
+		}
+		for j := s.start; j < s.start+s.span && need > 0 && flexible > 0; j++ {
+			if track(j).Sizing != TrackFixed {
+				delta := need / flexible
+				content[j] += delta
+				need -= delta
+				flexible--
+			}
+		}
+	}
+
+	ret := make([]int, n)
+	left, totalWeight, natural := space-gap*(n-1), 0, 0
+	for i := range ret {
+		switch tr := track(i); tr.Sizing {
+		case TrackWeighted:
+			totalWeight += tr.weight()
+			continue
+		default:
+			ret[i] = content[i]
+		}
+		left -= ret[i]
+	}
+	if totalWeight == 0 {
+		return ret
+	}
+	if space < 0 {
+		// The weighted tracks' natural size is the smallest for which every
+		// weighted track's share is at least its content size.
+		for i := range ret {
+			if tr := track(i); tr.Sizing == TrackWeighted {
+				weight := tr.weight()
+				if s := (content[i]*totalWeight + weight - 1) / weight; natural < s {
+					natural = s
+				}
+			}
+		}
+		left = natural
+	}
+	if left < 0 {
+		left = 0
+	}
+	for i := range ret {
+		if tr := track(i); tr.Sizing == TrackWeighted {
+			weight := tr.weight()
+			delta := left * weight / totalWeight
+			ret[i] = delta
+			left -= delta
+			totalWeight -= weight
+		}
+	}
+	return ret
+}
+
+// sizes returns the sizes of the Grid's columns and rows, fitting within the
+// given size, or their natural sizes if that size is negative.
+func (w *Grid) sizes(t *theme.Theme, cells []gridCell, nCols, nRows int, size image.Point) (cols, rows []int) {
+	xSpans, ySpans := make([]gridSpan, len(cells)), make([]gridSpan, len(cells))
+	xSizes, ySizes := make([]int, len(cells)), make([]int, len(cells))
+	for i, gc := range cells {
+		xSpans[i], ySpans[i] = gc.x, gc.y
+		xSizes[i], ySizes[i] = gc.n.MeasuredSize.X, gc.n.MeasuredSize.Y
+	}
+	cols = trackSizes(t, w.Columns, nCols, t.Pixels(w.ColumnGap).Round(), size.X, xSpans, xSizes)
+	rows = trackSizes(t, w.Rows, nRows, t.Pixels(w.RowGap).Round(), size.Y, ySpans, ySizes)
+	return cols, rows
+}
+
+// sumTracks returns the total size of tracks separated by gap.
+func sumTracks(tracks []int, gap int) (total int) {
+	for i, s := range tracks {
+		if i > 0 {
+			total += gap
+		}
+		total += s
+	}
+	return total
+}
+
+func (w *Grid) Measure(t *theme.Theme, widthHint, heightHint int) {
+	cells, nCols, nRows := w.cells()
+	colGap := t.Pixels(w.ColumnGap).Round()
+	for _, gc := range cells {
+		// A child whose columns are all fixed is measured with their width
+		// as its hint.
+		hint := 0
+		for j := gc.x.start; j < gc.x.start+gc.x.span; j++ {
+			if j >= len(w.Columns) || w.Columns[j].Sizing != TrackFixed {
+				hint = node.NoHint
+				break
+			}
+			hint += t.Pixels(w.Columns[j].Size).Round()
+		}
+		if hint >= 0 {
+			hint += colGap * (gc.x.span - 1)
+		}
+		gc.n.Wrapper.Measure(t, hint, node.NoHint)
+	}
+	cols, rows := w.sizes(t, cells, nCols, nRows, image.Point{-1, -1})
+	w.MeasuredSize = image.Point{
+		X: sumTracks(cols, colGap),
+		Y: sumTracks(rows, t.Pixels(w.RowGap).Round()),
+	}
+}
+
+func (w *Grid) Layout(t *theme.Theme) {
+	cells, nCols, nRows := w.cells()
+	cols, rows := w.sizes(t, cells, nCols, nRows, w.Rect.Size())
+
+	// xs and ys are the tracks' starts, and a final entry for the end.
+	colGap, rowGap := t.Pixels(w.ColumnGap).Round(), t.Pixels(w.RowGap).Round()
+	xs, ys := make([]int, nCols+1), make([]int, nRows+1)
+	for i, s := range cols {
+		xs[i+1] = xs[i] + s + colGap
+	}
+	for i, s := range rows {
+		ys[i+1] = ys[i] + s + rowGap
+	}
+
+	for _, gc := range cells {
+		x0, x1 := xs[gc.x.start], xs[gc.x.start+gc.x.span]-colGap
+		y0, y1 := ys[gc.y.start], ys[gc.y.start+gc.y.span]-rowGap
+		dx, width := align(gc.ax, x1-x0, gc.n.MeasuredSize.X)
+		dy, height := align(gc.ay, y1-y0, gc.n.MeasuredSize.Y)
+		gc.n.Rect = image.Rect(x0+dx, y0+dy, x0+dx+width, y0+dy+height)
+		gc.n.Wrapper.Layout(t)
+	}
+}
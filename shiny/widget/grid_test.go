@@ -0,0 +1,140 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/exp/shiny/gesture"
+	"golang.org/x/exp/shiny/unit"
+	"golang.org/x/exp/shiny/widget/node"
+)
+
+func newTestSizer(width, height float64) *Sizer {
+	return NewSizer(unit.Pixels(width), unit.Pixels(height), nil)
+}
+
+func TestGrid(t *testing.T) {
+	a := newTestSizer(30, 20)
+	b := newTestSizer(40, 10)
+	c := newTestSizer(30, 10)
+	d := newTestSizer(10, 10)
+	e := WithLayoutData(newTestSizer(120, 30), GridLayoutData{Column: 0, Row: 1, ColumnSpan: 2})
+	f := newTestSizer(10, 10)
+	w := NewGrid([]Track{
+		FixedTrack(unit.Pixels(50)),
+		{},
+		WeightedTrack(2),
+		WeightedTrack(1),
+	}, a, b, c, d, e, f)
+	w.ColumnGap, w.RowGap = unit.Pixels(10), unit.Pixels(5)
+	w.AlignY = AlignCenter
+
+	// The content-sized column is 40 pixels, for b, plus the 20 pixels that
+	// e needs beyond the two columns that it spans. The weighted columns are
+	// 30 and 15 pixels, so that the first is at least as wide as c.
+	w.Measure(nil, node.NoHint, node.NoHint)
+	if got, want := w.MeasuredSize, (image.Point{50 + 60 + 30 + 15 + 3*10, 20 + 5 + 30}); got != want {
+		t.Fatalf("MeasuredSize: got %v, want %v", got, want)
+	}
+
+	// Laid out 60 pixels wider than that, the weighted columns are 70 and 35
+	// pixels.
+	w.Rect = image.Rect(0, 0, 245, 55)
+	w.Layout(nil)
+	testCases := []struct {
+		name string
+		n    node.Node
+		want image.Rectangle
+	}{
+		{"a", a, image.Rect(0, 0, 50, 20)},
+		{"b", b, image.Rect(60, 5, 120, 15)},
+		{"c", c, image.Rect(130, 5, 200, 15)},
+		{"d", d, image.Rect(210, 5, 245, 15)},
+		{"e", e, image.Rect(0, 25, 120, 55)},
+		// f is placed after e, in the next cell.
+		{"f", f, image.Rect(130, 35, 200, 45)},
+	}
+	for _, tc := range testCases {
+		if got := tc.n.Wrappee().Rect; got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// A child's alignment overrides the Grid's.
+	f.LayoutData = GridLayoutData{Column: 3, Row: 1, AlignX: AlignEnd, AlignY: AlignStart}
+	w.Layout(nil)
+	if got, want := f.Rect, image.Rect(235, 25, 245, 35); got != want {
+		t.Errorf("aligned f: got %v, want %v", got, want)
+	}
+}
+
+func TestTable(t *testing.T) {
+	names := []string{"carol", "alice", "bob", "dave"}
+	ages := []int{30, 40, 30, 20}
+	w := NewTable([]TableColumn{{
+		Title: "Name",
+		Less:  func(i, j int) bool { return names[i] < names[j] },
+	}, {
+		Title: "Age",
+		Less:  func(i, j int) bool { return ages[i] < ages[j] },
+	}, {
+		Title: "Notes",
+		Track: WeightedTrack(1),
+	}}, len(names), func(row, col int) node.Node {
+		return NewLabel(names[row])
+	})
+	sorts := 0
+	w.OnSort = func() { sorts++ }
+
+	checkOrder := func(want ...int) {
+		t.Helper()
+		for k, i := range want {
+			if got := w.Row(k); got != i {
+				t.Fatalf("Row(%d): got %d, want %d", k, got, i)
+			}
+			for j, c := range w.cells[i] {
+				if got, want := c.Wrappee().LayoutData, (GridLayoutData{Column: j, Row: k + 1}); got != want {
+					t.Fatalf("row %d column %d LayoutData: got %v, want %v", i, j, got, want)
+				}
+			}
+		}
+	}
+	checkOrder(0, 1, 2, 3)
+
+	// The headers are the Grid's first children.
+	var headers []node.Node
+	for c := w.grid.FirstChild; c != nil && len(headers) < 3; c = c.NextSibling {
+		headers = append(headers, c.Wrapper)
+	}
+	tap := func(col int) node.EventHandled {
+		return headers[col].OnInputEvent(gesture.Event{Type: gesture.TypeTap}, image.Point{})
+	}
+
+	// Sorting is stable, so carol stays before bob.
+	tap(1)
+	checkOrder(3, 0, 2, 1)
+	tap(1)
+	checkOrder(1, 0, 2, 3)
+	if col, descending := w.SortColumn(); col != 1 || !descending {
+		t.Errorf("SortColumn: got %d, %t, want 1, true", col, descending)
+	}
+	tap(0)
+	checkOrder(1, 2, 0, 3)
+	if got := tap(2); got != node.NotHandled {
+		t.Errorf("tapping an unsortable column: got %v, want not handled", got)
+	}
+	if sorts != 3 {
+		t.Errorf("sorts: got %d, want 3", sorts)
+	}
+
+	// Reloading keeps the sort.
+	names[3] = "aaron"
+	w.Reload()
+	checkOrder(3, 1, 2, 0)
+	w.SortBy(-1, false)
+	checkOrder(0, 1, 2, 3)
+}
@@ -0,0 +1,63 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+
+	"golang.org/x/exp/shiny/widget/node"
+)
+
+// hoverPath appends, to path, the nodes that the pointer at p, in window
+// coordinates, hovers over: root, if p is in it, then the last of its
+// children that p is in, and so on. As for input events, later children are
+// drawn over, and have priority over, earlier ones.
+func hoverPath(path []*node.Embed, root *node.Embed, p image.Point) []*node.Embed {
+	n, o := root, windowOrigin(root)
+	for n != nil && p.In(n.Rect.Add(o)) {
+		path = append(path, n)
+		o = o.Add(n.Rect.Min)
+		if sc, ok := n.Wrapper.(*Scroller); ok {
+			o = o.Sub(sc.Offset())
+		}
+		c := n.LastChild
+		for c != nil && !p.In(c.Rect.Add(o)) {
+			c = c.PrevSibling
+		}
+		n = c
+	}
+	return path
+}
+
+// hover sends node.HoverEvents to the nodes of the tree whose root is root
+// that the pointer stops and starts hovering over, when it moves from over
+// the nodes from to over the nodes to, as returned by hoverPath. The nodes
+// that it stops hovering over are sent theirs first, deepest first, then the
+// nodes that it starts hovering over, shallowest first. Nodes that have been
+// removed from the tree are not sent any.
+func hover(root *node.Embed, from, to []*node.Embed) {
+	i := 0
+	for i < len(from) && i < len(to) && from[i] == to[i] {
+		i++
+	}
+	for j := len(from) - 1; j >= i; j-- {
+		if n := from[j]; inTree(n, root) {
+			n.Wrapper.OnInputEvent(node.HoverEvent{}, windowOrigin(n))
+		}
+	}
+	for _, n := range to[i:] {
+		n.Wrapper.OnInputEvent(node.HoverEvent{Hovered: true}, windowOrigin(n))
+	}
+}
+
+// inTree returns whether n is in the tree whose root is root.
+func inTree(n, root *node.Embed) bool {
+	for ; n != nil; n = n.Parent {
+		if n == root {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"fmt"
+	"image"
+	"testing"
+
+	"golang.org/x/exp/shiny/widget/node"
+)
+
+// hoverLeaf is a leaf that records its HoverEvents.
+type hoverLeaf struct {
+	node.LeafEmbed
+	events []bool
+}
+
+func newHoverLeaf(r image.Rectangle) *hoverLeaf {
+	w := &hoverLeaf{}
+	w.Wrapper = w
+	w.Rect = r
+	return w
+}
+
+func (w *hoverLeaf) OnInputEvent(e interface{}, origin image.Point) node.EventHandled {
+	if e, ok := e.(node.HoverEvent); ok {
+		w.events = append(w.events, e.Hovered)
+	}
+	return node.NotHandled
+}
+
+func TestHover(t *testing.T) {
+	// a is on the left, and b and c are in a Scroller on the right, where c
+	// is below b, and only shows when scrolled down.
+	a := newHoverLeaf(image.Rect(0, 0, 50, 100))
+	b := newHoverLeaf(image.Rect(0, 0, 50, 100))
+	c := newHoverLeaf(image.Rect(0, 100, 50, 200))
+	inner := NewFlow(AxisVertical, b, c)
+	inner.Rect = image.Rect(0, 0, 50, 200)
+	sc := NewScroller(AxisVertical, inner)
+	sc.Rect = image.Rect(50, 0, 100, 100)
+	root := NewFlow(AxisHorizontal, a, sc)
+	root.Rect = image.Rect(0, 0, 100, 100)
+
+	var hovered []*node.Embed
+	move := func(p image.Point) {
+		next := hoverPath(nil, &root.Embed, p)
+		hover(&root.Embed, hovered, next)
+		hovered = next
+	}
+	// check checks the events of a, b and c, formatted as by fmt.Sprint.
+	check := func(desc, want string) {
+		t.Helper()
+		if got := fmt.Sprint(a.events, b.events, c.events); got != want {
+			t.Errorf("%s: got events %s, want %s", desc, got, want)
+		}
+		a.events, b.events, c.events = nil, nil, nil
+	}
+
+	move(image.Point{10, 10})
+	check("over a", "[true] [] []")
+	if len(hovered) != 2 {
+		t.Errorf("over a: got %d hovered nodes, want 2", len(hovered))
+	}
+	move(image.Point{20, 20})
+	check("within a", "[] [] []")
+	move(image.Point{60, 90})
+	check("over b", "[false] [true] []")
+
+	// Scrolling moves c under the pointer.
+	sc.ScrollTo(image.Point{0, 50})
+	if got := sc.Offset(); got != (image.Point{0, 50}) {
+		t.Fatalf("Offset: got %v, want (0,50)", got)
+	}
+	move(image.Point{60, 90})
+	check("scrolled", "[] [false] [true]")
+
+	move(image.Point{200, 200})
+	check("outside", "[] [] [false]")
+	if len(hovered) != 0 {
+		t.Errorf("outside: got %d hovered nodes, want 0", len(hovered))
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/widget/node"
+	"golang.org/x/exp/shiny/widget/theme"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/mobile/event/key"
+)
+
+// The colors of the inspector overlay.
+var (
+	// inspectBoundsColor outlines each node.
+	inspectBoundsColor = color.NRGBA{0x00, 0x80, 0xff, 0x80}
+	// inspectResizedColor outlines each node that is laid out at other than
+	// its natural size.
+	inspectResizedColor = color.NRGBA{0xff, 0x80, 0x00, 0xc0}
+	// inspectTargetColor fills the hit-test target.
+	inspectTargetColor = color.NRGBA{0x00, 0x80, 0xff, 0x40}
+	// inspectDamageColor outlines the damaged region.
+	inspectDamageColor = color.NRGBA{0xff, 0x00, 0x00, 0xc0}
+)
+
+// inspectEvent is sent to a window by SetInspector.
+type inspectEvent struct {
+	on bool
+}
+
+// SetInspector shows, or hides, the inspector overlay of the window, run by
+// RunWindow, whose widget tree's root is root. The overlay is a debugging aid
+// for building layouts, drawn over the widgets. It outlines every node's
+// bounds, in orange for those laid out at other than their natural size, and
+// the region that was damaged since the previous paint, in red. It fills the
+// node under the pointer, which is the target of its input events, and lists
+// the node hierarchy in a panel, with each node's bounds, in window
+// coordinates, and where they differ, its natural size. The panel's theme
+// style kind is "Inspector".
+//
+// While the overlay is shown, the whole tree is painted every time, so that
+// the overlay does not linger where a node was not damaged. If
+// RunWindowOptions.InspectorKey is set, pressing Control-Shift-I in the window
+// also shows or hides it.
+//
+// It is safe to call SetInspector concurrently with RunWindow. It does
+// nothing if root is not the root of a running window.
+func SetInspector(root node.Node, on bool) {
+	windowsMu.Lock()
+	w := windows[root]
+	windowsMu.Unlock()
+	if w != nil {
+		w.Send(inspectEvent{on})
+	}
+}
+
+// isInspectorKey returns whether k is the press of Control-Shift-I, which
+// shows or hides the inspector overlay.
+func isInspectorKey(k key.Event) bool {
+	return k.Code == key.CodeI && k.Direction == key.DirPress &&
+		k.Modifiers == key.ModControl|key.ModShift
+}
+
+// inspector is the inspector overlay of a window.
+type inspector struct {
+	on bool
+
+	// target is the hit-test target shown by the most recent paint, and
+	// damage is the region that it repainted, or would have repainted were
+	// the overlay not shown, in window coordinates.
+	target *node.Embed
+	damage image.Rectangle
+
+	// buf holds the pixels of the panel.
+	buf screen.Buffer
+}
+
+// show shows or hides the overlay, marking root as needing paint, to paint it
+// or to paint over it.
+func (in *inspector) show(root node.Node, on bool) {
+	if in.on != on {
+		in.on = on
+		root.Mark(node.MarkNeedsPaint)
+	}
+}
+
+// stale returns whether the overlay is shown, and needs painting again as the
+// pointer moved over another node since its most recent paint. hovered are the
+// nodes that the pointer hovers over, as returned by hoverPath.
+func (in *inspector) stale(hovered []*node.Embed) bool {
+	return in.on && in.target != hitTarget(hovered)
+}
+
+func (in *inspector) release() {
+	if in.buf != nil {
+		in.buf.Release()
+		in.buf = nil
+	}
+}
+
+// hitTarget returns the deepest of the hovered nodes, which is the first to
+// be sent the pointer's input events, or nil if there are none.
+func hitTarget(hovered []*node.Embed) *node.Embed {
+	if len(hovered) == 0 {
+		return nil
+	}
+	return hovered[len(hovered)-1]
+}
+
+// paint paints the overlay over the tree whose root is root, after it is
+// painted and before it is published.
+func (in *inspector) paint(w screen.Window, s screen.Screen, t *theme.Theme, root *node.Embed, hovered []*node.Embed) error {
+	in.target = hitTarget(hovered)
+	rows := inspectRows(nil, root, 0)
+	for _, row := range rows {
+		c := inspectBoundsColor
+		if row.n.Rect.Size() != row.n.MeasuredSize {
+			c = inspectResizedColor
+		}
+		r, clip := focusRing(row.n)
+		paintOutline(w, r, clip, c)
+	}
+	if in.target != nil {
+		_, clip := focusRing(in.target)
+		w.Fill(clip, inspectTargetColor, draw.Over)
+	}
+	if !in.damage.Empty() {
+		paintOutline(w, in.damage, root.Rect, inspectDamageColor)
+	}
+	return in.paintPanel(w, s, t, root.Rect, rows)
+}
+
+// paintPanel paints the panel that lists rows, at the right of bounds, or at
+// its left if the target is under the right.
+func (in *inspector) paintPanel(w screen.Window, s screen.Screen, t *theme.Theme, bounds image.Rectangle, rows []inspectRow) error {
+	t = t.Style("Inspector")
+	opts := t.FaceOptions(theme.Caption)
+	face := t.AcquireFontFace(opts)
+	defer t.ReleaseFontFace(opts, face)
+	m := face.Metrics()
+	ascent, lineHeight := m.Ascent.Ceil(), m.Height.Ceil()
+	pad := t.Pixels(t.GetSpacing()).Ceil()
+
+	// Show as many rows as fit, scrolled so that the target's is in the
+	// middle, if they do not all fit.
+	targetRow := -1
+	for i, row := range rows {
+		if row.n == in.target {
+			targetRow = i
+		}
+	}
+	if n := (bounds.Dy() - 2*pad) / lineHeight; n <= 0 {
+		return nil
+	} else if len(rows) > n {
+		first := targetRow - n/2
+		if first > len(rows)-n {
+			first = len(rows) - n
+		}
+		if first < 0 {
+			first = 0
+		}
+		rows, targetRow = rows[first:first+n], targetRow-first
+	}
+
+	width := fixed.Int26_6(0)
+	for _, row := range rows {
+		if a := font.MeasureString(face, row.String()); a > width {
+			width = a
+		}
+	}
+	size := image.Point{width.Ceil() + 2*pad, len(rows)*lineHeight + 2*pad}
+	if size.X > bounds.Dx() {
+		size.X = bounds.Dx()
+	}
+	if b := in.buf; b == nil || size.X > b.Size().X || size.Y > b.Size().Y {
+		in.release()
+		buf, err := s.NewBuffer(size)
+		if err != nil {
+			return err
+		}
+		in.buf = buf
+	}
+
+	sr := image.Rectangle{Max: size}
+	dst := in.buf.RGBA()
+	pal := t.GetPalette()
+	draw.Draw(dst, sr, pal.Background(), image.Point{}, draw.Src)
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  pal.Foreground(),
+		Face: face,
+	}
+	for i, row := range rows {
+		y := pad + i*lineHeight
+		if i == targetRow {
+			draw.Draw(dst, image.Rect(0, y, size.X, y+lineHeight), pal.Accent(), image.Point{}, draw.Src)
+		}
+		d.Dot = fixed.P(pad, y+ascent)
+		d.DrawString(row.String())
+	}
+
+	dp := image.Point{bounds.Max.X - size.X, bounds.Min.Y}
+	if in.target != nil {
+		r, _ := focusRing(in.target)
+		if r.Overlaps(sr.Add(dp)) {
+			dp.X = bounds.Min.X
+		}
+	}
+	w.Upload(dp, in.buf, sr)
+	return nil
+}
+
+// paintOutline paints a one pixel wide outline just inside r, clipped to clip.
+func paintOutline(w screen.Window, r, clip image.Rectangle, c color.Color) {
+	if r.Empty() {
+		return
+	}
+	for _, edge := range [...]image.Rectangle{
+		{r.Min, image.Point{r.Max.X, r.Min.Y + 1}},
+		{image.Point{r.Min.X, r.Max.Y - 1}, r.Max},
+		{image.Point{r.Min.X, r.Min.Y + 1}, image.Point{r.Min.X + 1, r.Max.Y - 1}},
+		{image.Point{r.Max.X - 1, r.Min.Y + 1}, image.Point{r.Max.X, r.Max.Y - 1}},
+	} {
+		if edge = edge.Intersect(clip); !edge.Empty() {
+			w.Fill(edge, c, draw.Over)
+		}
+	}
+}
+
+// inspectRow is a row of the inspector panel, for the node n, which is depth
+// levels below the root.
+type inspectRow struct {
+	n     *node.Embed
+	depth int
+}
+
+// String returns the row's text: n's type, its bounds in window coordinates
+// and, if it is laid out at other than its natural size, that size.
+func (r inspectRow) String() string {
+	b := r.n.Rect.Add(windowOrigin(r.n))
+	s := fmt.Sprintf("%s%s %v", strings.Repeat("  ", r.depth),
+		strings.TrimPrefix(fmt.Sprintf("%T", r.n.Wrapper), "*"), b)
+	if ms := r.n.MeasuredSize; ms != b.Size() {
+		s += fmt.Sprintf(" natural %dx%d", ms.X, ms.Y)
+	}
+	return s
+}
+
+// inspectRows appends, to rows, the rows for n, which is depth levels below
+// the root, and for its descendants, in depth first order.
+func inspectRows(rows []inspectRow, n *node.Embed, depth int) []inspectRow {
+	rows = append(rows, inspectRow{n, depth})
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		rows = inspectRows(rows, c, depth+1)
+	}
+	return rows
+}
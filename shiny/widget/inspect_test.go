@@ -0,0 +1,63 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/shiny/widget/node"
+)
+
+func TestInspectRows(t *testing.T) {
+	// a is on the left, at its natural size, and b is in a Scroller on the
+	// right, scrolled down, and stretched across it.
+	a := newHoverLeaf(image.Rect(0, 0, 50, 100))
+	a.MeasuredSize = image.Point{50, 100}
+	b := newHoverLeaf(image.Rect(0, 0, 50, 200))
+	b.MeasuredSize = image.Point{30, 200}
+	sc := NewScroller(AxisVertical, b)
+	sc.Rect = image.Rect(50, 0, 100, 100)
+	sc.MeasuredSize = image.Point{50, 100}
+	root := NewFlow(AxisHorizontal, a, sc)
+	root.Rect = image.Rect(0, 0, 100, 100)
+	root.MeasuredSize = image.Point{100, 100}
+	sc.ScrollTo(image.Point{0, 40})
+
+	var got []string
+	for _, row := range inspectRows(nil, &root.Embed, 0) {
+		got = append(got, row.String())
+	}
+	want := []string{
+		"widget.Flow (0,0)-(100,100)",
+		"  widget.hoverLeaf (0,0)-(50,100)",
+		"  widget.Scroller (50,0)-(100,100)",
+		"    widget.hoverLeaf (50,-40)-(100,160) natural 30x200",
+	}
+	if g, w := strings.Join(got, "\n"), strings.Join(want, "\n"); g != w {
+		t.Errorf("got rows\n%s\nwant\n%s", g, w)
+	}
+
+	if got := hitTarget(hoverPath(nil, &root.Embed, image.Point{60, 10})); got != &b.Embed {
+		t.Errorf("hitTarget: got %p, want b (%p)", got, &b.Embed)
+	}
+	if got := hitTarget(nil); got != nil {
+		t.Errorf("hitTarget(nil): got %p, want nil", got)
+	}
+
+	in := inspector{on: true}
+	if !in.stale([]*node.Embed{&root.Embed, &a.Embed}) {
+		t.Errorf("stale: got false for a new target, want true")
+	}
+	in.target = &a.Embed
+	if in.stale([]*node.Embed{&root.Embed, &a.Embed}) {
+		t.Errorf("stale: got true for the same target, want false")
+	}
+	in.on = false
+	if in.stale(nil) {
+		t.Errorf("stale: got true while hidden, want false")
+	}
+}
@@ -7,17 +7,23 @@ package widget
 import (
 	"image"
 
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/text/shape"
 	"golang.org/x/exp/shiny/widget/node"
 	"golang.org/x/exp/shiny/widget/theme"
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
 
-// Label is a leaf widget that holds a text label.
+// Label is a leaf widget that holds a text label. Its theme style kind is
+// "Label".
 type Label struct {
 	node.LeafEmbed
 	Text       string
 	ThemeColor theme.Color
+
+	// TextStyle is the role of the label's text, which determines its size.
+	TextStyle theme.TextStyle
 }
 
 // NewLabel returns a new Label widget.
@@ -29,14 +35,28 @@ func NewLabel(text string) *Label {
 	return w
 }
 
+// shape shapes the label's text with the theme's font face, followed by its
+// fallback faces. The faces are released by calling release.
+func (w *Label) shape(t *theme.Theme) (l *shape.Line, face font.Face, release func()) {
+	opts := t.FaceOptions(w.TextStyle)
+	face = t.AcquireFontFace(opts)
+	fallback := t.AcquireFallbackFontFaces(opts)
+	s := shape.Shaper{Faces: append([]font.Face{face}, fallback...)}
+	return s.Shape(w.Text, shape.Auto), face, func() {
+		t.ReleaseFallbackFontFaces(opts, fallback)
+		t.ReleaseFontFace(opts, face)
+	}
+}
+
 func (w *Label) Measure(t *theme.Theme, widthHint, heightHint int) {
-	face := t.AcquireFontFace(theme.FontFaceOptions{})
-	defer t.ReleaseFontFace(theme.FontFaceOptions{}, face)
+	t = t.Style("Label")
+	l, face, release := w.shape(t)
+	defer release()
 	m := face.Metrics()
 
 	// TODO: padding, to match a Text widget?
 
-	w.MeasuredSize.X = font.MeasureString(face, w.Text).Ceil()
+	w.MeasuredSize.X = l.Advance.Ceil()
 	w.MeasuredSize.Y = m.Ascent.Ceil() + m.Descent.Ceil()
 }
 
@@ -47,8 +67,9 @@ func (w *Label) PaintBase(ctx *node.PaintBaseContext, origin image.Point) error
 		return nil
 	}
 
-	face := ctx.Theme.AcquireFontFace(theme.FontFaceOptions{})
-	defer ctx.Theme.ReleaseFontFace(theme.FontFaceOptions{}, face)
+	t := ctx.Theme.Style("Label")
+	l, face, release := w.shape(t)
+	defer release()
 	m := face.Metrics()
 	ascent := m.Ascent.Ceil()
 
@@ -59,13 +80,17 @@ func (w *Label) PaintBase(ctx *node.PaintBaseContext, origin image.Point) error
 
 	d := font.Drawer{
 		Dst:  dst,
-		Src:  tc.Uniform(ctx.Theme),
+		Src:  tc.Uniform(t),
 		Face: face,
 		Dot: fixed.Point26_6{
 			X: fixed.I(origin.X + w.Rect.Min.X),
 			Y: fixed.I(origin.Y + w.Rect.Min.Y + ascent),
 		},
 	}
-	d.DrawString(w.Text)
+	l.Draw(&d)
 	return nil
 }
+
+func (w *Label) Describe(a *screen.AccessNode) {
+	a.Role, a.Name = screen.AccessLabel, w.Text
+}
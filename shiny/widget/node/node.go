@@ -97,6 +97,12 @@ type Node interface {
 	// Layout lays out this node (and its children), setting the Embed.Rect
 	// fields of each child. This node's Embed.Rect field should have
 	// previously been set during the parent node's layout.
+	//
+	// A node's layout should depend only on its Embed.Rect, its own state and
+	// its children's Embed.MeasuredSize and Embed.LayoutData fields. A node
+	// whose state otherwise changes should be marked with
+	// MarkNeedsMeasureLayout, so that the Relayout function lays it out
+	// again, but need not lay out those parts of the tree that did not change.
 	Layout(t *theme.Theme)
 
 	// Paint paints this node (and its children). Painting is split into two
@@ -133,6 +139,15 @@ type Node interface {
 	// origin is the parent widget's origin with respect to the ctx.Src2Dst
 	// transformation matrix; this node's Embed.Rect.Add(origin) will be its
 	// position and size in pre-transformed coordinate space.
+	//
+	// Painting is driven by damage. A node marked with MarkNeedsPaint or
+	// MarkNeedsPaintBase has changed, and its bounds, or only the part of it
+	// passed to Embed.Invalidate, contribute to the damaged region computed
+	// by the Damage function. Paint is called on those nodes whose bounds
+	// overlap ctx.Damage, and need not be called on any other node, as their
+	// previous rendering is still valid. Implementations that paint children
+	// should skip those children that do not overlap the damage, as per the
+	// PaintContext.Damaged method.
 	Paint(ctx *PaintContext, origin image.Point) error
 
 	// PaintBase paints the base pass of this node (and its children) onto an
@@ -148,16 +163,17 @@ type Node interface {
 	Mark(m Marks)
 
 	// OnChildMarked handles a child being given new marks. By default, marks
-	// are propagated up the node tree towards the root. For example, a child
-	// being marked for needing paint will cause the parent being marked for
-	// needing paint.
+	// are propagated up the node tree towards the root, as per the
+	// Marks.PropagateUp method. For example, a child being marked for needing
+	// paint will cause the parent being marked for having a descendant that
+	// needs paint.
 	OnChildMarked(child Node, newMarks Marks)
 
 	// OnLifecycleEvent propagates a lifecycle event to a node (and its
 	// children).
 	OnLifecycleEvent(e lifecycle.Event)
 
-	// OnInputEvent handles a key, mouse, touch or gesture event.
+	// OnInputEvent handles a key, mouse, scroll, touch or gesture event.
 	//
 	// origin is the parent widget's origin with respect to the event origin;
 	// this node's Embed.Rect.Add(origin) will be its position and size in
@@ -168,6 +184,15 @@ type Node interface {
 
 }
 
+// Describer is implemented by nodes that describe themselves to assistive
+// technologies, such as screen readers. RunWindow sets the ID, Bounds, Focused
+// and Children of the node's screen.AccessNode, and Describe sets the rest,
+// such as its Role and Name. A node that is not a Describer is left out of
+// the accessibility tree, and its children are hoisted into its parent's.
+type Describer interface {
+	Describe(a *screen.AccessNode)
+}
+
 // PaintContext is the context for the Node.Paint method.
 type PaintContext struct {
 	Theme   *theme.Theme
@@ -175,18 +200,120 @@ type PaintContext struct {
 	Drawer  screen.Drawer
 	Src2Dst f64.Aff3
 
-	// TODO: add a clip rectangle?
+	// Damage is the region, in pre-transformed coordinate space, that needs
+	// repainting. Nodes that do not overlap it need not be painted.
+	//
+	// A zero Damage means that the entire node tree needs repainting, such as
+	// for the first paint or when the window's back buffer was not preserved.
+	//
+	// If the Drawer is a screen.Clipper, widget.RunWindow clips painting to
+	// the Damage.
+	Damage image.Rectangle
 
 	// TODO: add the DrawContext from the lifecycle event?
 }
 
+// Damaged returns whether r, in pre-transformed coordinate space, overlaps
+// the damaged region and therefore needs painting.
+func (c *PaintContext) Damaged(r image.Rectangle) bool {
+	return c.Damage == (image.Rectangle{}) || r.Overlaps(c.Damage)
+}
+
+// Damage returns the region that needs repainting for the node n and its
+// descendants: the union of the bounds of every node marked as needing paint
+// or as needing paint base, or of their invalidated parts. The result is in
+// the same coordinate space as origin, which is n's parent widget's origin.
+//
+// It does not look below a node marked as needing paint or paint base, as
+// that node's bounds are presumed to contain those of its descendants. It
+// only looks below a node marked as having a descendant that needs them.
+func Damage(n Node, origin image.Point) image.Rectangle {
+	m := n.Wrappee()
+	if m.Marks.NeedsPaint() || m.Marks.NeedsPaintBase() {
+		if m.damage != (image.Rectangle{}) {
+			return m.damage.Add(origin.Add(m.Rect.Min))
+		}
+		return m.Rect.Add(origin)
+	}
+	d := image.Rectangle{}
+	if m.Marks.DescendantNeedsPaint() || m.Marks.DescendantNeedsPaintBase() {
+		origin = origin.Add(m.Rect.Min)
+		for c := m.FirstChild; c != nil; c = c.NextSibling {
+			d = d.Union(Damage(c.Wrapper, origin))
+		}
+	}
+	return d
+}
+
+// Relayout measures and lays out those parts of the tree rooted at n that are
+// marked as needing it. n's Embed.Rect should already be set, and widthHint
+// and heightHint are as per the Node.Measure method.
+//
+// The whole tree is measured again, as a node's natural size can depend on
+// the hints from its ancestors, but Layout is only called on a node that is
+// marked with MarkNeedsMeasureLayout or one of whose children has a changed
+// natural size, as the rest of the tree would be laid out exactly as before.
+// Every node that is laid out again is marked as needing paint, as its
+// children may have moved.
+func Relayout(n Node, t *theme.Theme, widthHint, heightHint int) {
+	m := n.Wrappee()
+	if !m.Marks.NeedsMeasureLayout() && !m.Marks.DescendantNeedsMeasureLayout() {
+		return
+	}
+	saveMeasuredSizes(m)
+	n.Measure(t, widthHint, heightHint)
+	relayout(m, t)
+}
+
+func saveMeasuredSizes(m *Embed) {
+	m.oldMeasuredSize = m.MeasuredSize
+	for c := m.FirstChild; c != nil; c = c.NextSibling {
+		saveMeasuredSizes(c)
+	}
+}
+
+func relayout(m *Embed, t *theme.Theme) {
+	needsLayout := m.Marks.NeedsMeasureLayout()
+	for c := m.FirstChild; c != nil && !needsLayout; c = c.NextSibling {
+		needsLayout = c.MeasuredSize != c.oldMeasuredSize
+	}
+	if needsLayout {
+		m.Wrapper.Layout(t)
+		unmarkMeasureLayout(m)
+		m.Wrapper.Mark(MarkNeedsPaint)
+		return
+	}
+	m.Marks.UnmarkDescendantNeedsMeasureLayout()
+	for c := m.FirstChild; c != nil; c = c.NextSibling {
+		if c.Marks.NeedsMeasureLayout() || c.Marks.DescendantNeedsMeasureLayout() {
+			relayout(c, t)
+		}
+	}
+}
+
+func unmarkMeasureLayout(m *Embed) {
+	m.Marks.UnmarkNeedsMeasureLayout()
+	m.Marks.UnmarkDescendantNeedsMeasureLayout()
+	for c := m.FirstChild; c != nil; c = c.NextSibling {
+		unmarkMeasureLayout(c)
+	}
+}
+
 // PaintBaseContext is the context for the Node.PaintBase method.
 type PaintBaseContext struct {
 	Theme *theme.Theme
-	Dst   *image.RGBA
 
-	// TODO: add a clip rectangle? Or rely on the RGBA.SubImage method to pass
-	// smaller Dst images?
+	// Dst is the pixel buffer to paint on. It may be a SubImage of a larger
+	// buffer, covering only that buffer's damaged region, in which case
+	// nodes should not paint outside of Dst.Bounds(), and those that do not
+	// overlap it need not be painted.
+	Dst *image.RGBA
+}
+
+// Damaged returns whether r, in ctx.Dst's coordinate space, overlaps ctx.Dst
+// and therefore needs painting.
+func (c *PaintBaseContext) Damaged(r image.Rectangle) bool {
+	return r.Overlaps(c.Dst.Rect)
 }
 
 // LeafEmbed is designed to be embedded in struct types for nodes with no
@@ -205,6 +332,7 @@ func (m *LeafEmbed) Layout(t *theme.Theme) {}
 
 func (m *LeafEmbed) Paint(ctx *PaintContext, origin image.Point) error {
 	m.Marks.UnmarkNeedsPaint()
+	m.Marks.UnmarkDescendantNeedsPaint()
 	return nil
 }
 
@@ -250,22 +378,26 @@ func (m *ShellEmbed) Layout(t *theme.Theme) {
 
 func (m *ShellEmbed) Paint(ctx *PaintContext, origin image.Point) error {
 	m.Marks.UnmarkNeedsPaint()
-	if c := m.FirstChild; c != nil {
-		return c.Wrapper.Paint(ctx, origin.Add(m.Rect.Min))
+	m.Marks.UnmarkDescendantNeedsPaint()
+	origin = origin.Add(m.Rect.Min)
+	if c := m.FirstChild; c != nil && ctx.Damaged(c.Rect.Add(origin)) {
+		return c.Wrapper.Paint(ctx, origin)
 	}
 	return nil
 }
 
 func (m *ShellEmbed) PaintBase(ctx *PaintBaseContext, origin image.Point) error {
 	m.Marks.UnmarkNeedsPaintBase()
-	if c := m.FirstChild; c != nil {
-		return c.Wrapper.PaintBase(ctx, origin.Add(m.Rect.Min))
+	m.Marks.UnmarkDescendantNeedsPaintBase()
+	origin = origin.Add(m.Rect.Min)
+	if c := m.FirstChild; c != nil && ctx.Damaged(c.Rect.Add(origin)) {
+		return c.Wrapper.PaintBase(ctx, origin)
 	}
 	return nil
 }
 
 func (m *ShellEmbed) OnChildMarked(child Node, newMarks Marks) {
-	m.Mark(newMarks)
+	m.Mark(newMarks.PropagateUp())
 }
 
 func (m *ShellEmbed) OnLifecycleEvent(e lifecycle.Event) {
@@ -275,6 +407,10 @@ func (m *ShellEmbed) OnLifecycleEvent(e lifecycle.Event) {
 }
 
 func (m *ShellEmbed) OnInputEvent(e interface{}, origin image.Point) EventHandled {
+	if _, ok := e.(HoverEvent); ok {
+		// The child is sent its own HoverEvents.
+		return NotHandled
+	}
 	if c := m.FirstChild; c != nil {
 		return c.Wrapper.OnInputEvent(e, origin.Add(m.Rect.Min))
 	}
@@ -312,8 +448,12 @@ func (m *ContainerEmbed) Layout(t *theme.Theme) {
 
 func (m *ContainerEmbed) Paint(ctx *PaintContext, origin image.Point) error {
 	m.Marks.UnmarkNeedsPaint()
+	m.Marks.UnmarkDescendantNeedsPaint()
 	origin = origin.Add(m.Rect.Min)
 	for c := m.FirstChild; c != nil; c = c.NextSibling {
+		if !ctx.Damaged(c.Rect.Add(origin)) {
+			continue
+		}
 		if err := c.Wrapper.Paint(ctx, origin); err != nil {
 			return err
 		}
@@ -323,8 +463,12 @@ func (m *ContainerEmbed) Paint(ctx *PaintContext, origin image.Point) error {
 
 func (m *ContainerEmbed) PaintBase(ctx *PaintBaseContext, origin image.Point) error {
 	m.Marks.UnmarkNeedsPaintBase()
+	m.Marks.UnmarkDescendantNeedsPaintBase()
 	origin = origin.Add(m.Rect.Min)
 	for c := m.FirstChild; c != nil; c = c.NextSibling {
+		if !ctx.Damaged(c.Rect.Add(origin)) {
+			continue
+		}
 		if err := c.Wrapper.PaintBase(ctx, origin); err != nil {
 			return err
 		}
@@ -333,7 +477,7 @@ func (m *ContainerEmbed) PaintBase(ctx *PaintBaseContext, origin image.Point) er
 }
 
 func (m *ContainerEmbed) OnChildMarked(child Node, newMarks Marks) {
-	m.Mark(newMarks)
+	m.Mark(newMarks.PropagateUp())
 }
 
 func (m *ContainerEmbed) OnLifecycleEvent(e lifecycle.Event) {
@@ -356,6 +500,14 @@ func (m *ContainerEmbed) OnInputEvent(e interface{}, origin image.Point) EventHa
 			X: int(e.X) - origin.X,
 			Y: int(e.Y) - origin.Y,
 		}
+	case screen.ScrollEvent:
+		p = image.Point{
+			X: int(e.X) - origin.X,
+			Y: int(e.Y) - origin.Y,
+		}
+	case HoverEvent:
+		// The children are sent their own HoverEvents.
+		return NotHandled
 	}
 	// Iterate backwards. Later children have priority over earlier children,
 	// as later ones are usually drawn over earlier ones.
@@ -410,10 +562,176 @@ type Embed struct {
 	// Marks are a bitfield of node state, such as whether it needs measure,
 	// layout or paint.
 	Marks Marks
+
+	// focus is the node with the keyboard focus, if this node is the root of
+	// its tree.
+	focus *Embed
+
+	// damage is the invalidated part of this node, relative to Rect.Min, if
+	// it is marked as needing paint or paint base. A zero damage means all of
+	// it.
+	damage image.Rectangle
+
+	// oldMeasuredSize is MeasuredSize before Relayout measured again.
+	oldMeasuredSize image.Point
 }
 
 func (m *Embed) Wrappee() *Embed { return m }
 
+func (m *Embed) root() *Embed {
+	for m.Parent != nil {
+		m = m.Parent
+	}
+	return m
+}
+
+// FocusEvent is sent to a node's OnInputEvent method when it gains or loses
+// the keyboard focus.
+type FocusEvent struct {
+	// Focused is whether the node gained the focus, rather than lost it.
+	Focused bool
+}
+
+// HoverEvent is sent to a node's OnInputEvent method, by widget.RunWindow,
+// when the pointer starts or stops hovering over it. The pointer hovers over
+// a node while it is over the node's Rect, unless a later sibling, drawn on
+// top of the node, is under it instead. It also hovers over the node's
+// ancestors, each of which is sent its own HoverEvents. The pointer stops
+// hovering over every node when it leaves the window.
+//
+// A node can show that it is hovered over, such as by highlighting a button,
+// to tell the user that it would respond to a press there.
+type HoverEvent struct {
+	// Hovered is whether the pointer started hovering over the node, rather
+	// than stopped.
+	Hovered bool
+}
+
+// Focus gives n the keyboard focus of the node tree that it is in, taking it
+// from any other node in that tree. The node with the focus is sent the input
+// events, such as key events, that are not about a position.
+func Focus(n Node) {
+	m := n.Wrappee()
+	r := m.root()
+	old := Focused(r.Wrapper)
+	if old == n {
+		return
+	}
+	r.focus = m
+	if old != nil {
+		old.OnInputEvent(FocusEvent{}, image.Point{})
+	}
+	n.OnInputEvent(FocusEvent{Focused: true}, image.Point{})
+}
+
+// Unfocus takes the keyboard focus from n, if it has it.
+func Unfocus(n Node) {
+	m := n.Wrappee()
+	r := m.root()
+	if r.focus != m {
+		return
+	}
+	r.focus = nil
+	n.OnInputEvent(FocusEvent{}, image.Point{})
+}
+
+// Focused returns the node with the keyboard focus in the node tree that n is
+// in, or nil if no node has it. A node that is removed from the tree loses the
+// focus, without being sent a FocusEvent.
+func Focused(n Node) Node {
+	r := n.Wrappee().root()
+	if f := r.focus; f != nil && f.root() == r {
+		return f.Wrapper
+	}
+	return nil
+}
+
+// Focuser is implemented by nodes that can take the keyboard focus, such as
+// text fields. Pressing Tab moves the focus from one Focusable node to the
+// next, and Shift-Tab to the previous. A Focuser's descendants are not
+// visited.
+type Focuser interface {
+	// Focusable returns whether the node can take the focus now. A disabled
+	// widget, for example, cannot.
+	Focusable() bool
+}
+
+// TabOrderer is implemented by nodes that override the order in which Tab moves
+// the focus through their descendants. Otherwise, that order is the tree's,
+// depth first, which is usually the order in which those descendants are laid
+// out, left to right and top to bottom.
+type TabOrderer interface {
+	// TabOrder returns the node's descendants in the order that Tab visits
+	// them. A returned node that is not a Focuser is replaced by its own
+	// descendants, in their order. Descendants that are neither returned nor
+	// descended from a returned node are not visited.
+	TabOrder() []Node
+}
+
+// NextFocus returns the Focusable node that pressing Tab, or Shift-Tab if
+// backward is true, moves the keyboard focus of n's tree to: the one after, or
+// before, the focused node, wrapping around, or the first, or last, if no node
+// has the focus. It returns nil if no node in the tree is Focusable.
+func NextFocus(n Node, backward bool) Node {
+	r := n.Wrappee().root()
+	stops := tabStops(r, nil)
+	if len(stops) == 0 {
+		return nil
+	}
+	i := -1
+	if f := Focused(r.Wrapper); f != nil {
+		for j, s := range stops {
+			if s.Wrappee() == f.Wrappee() {
+				i = j
+				break
+			}
+		}
+	}
+	switch {
+	case i < 0 && backward:
+		i = len(stops) - 1
+	case i < 0:
+		i = 0
+	case backward:
+		i = (i + len(stops) - 1) % len(stops)
+	default:
+		i = (i + 1) % len(stops)
+	}
+	return stops[i]
+}
+
+// tabStops appends the Focusable nodes of m's subtree, in Tab order, to stops.
+func tabStops(m *Embed, stops []Node) []Node {
+	if f, ok := m.Wrapper.(Focuser); ok {
+		if f.Focusable() {
+			stops = append(stops, m.Wrapper)
+		}
+		return stops
+	}
+	if o, ok := m.Wrapper.(TabOrderer); ok {
+		for _, n := range o.TabOrder() {
+			if c := n.Wrappee(); c != m && c.descends(m) {
+				stops = tabStops(c, stops)
+			}
+		}
+		return stops
+	}
+	for c := m.FirstChild; c != nil; c = c.NextSibling {
+		stops = tabStops(c, stops)
+	}
+	return stops
+}
+
+// descends returns whether m is a or is a descendant of a.
+func (m *Embed) descends(a *Embed) bool {
+	for ; m != nil; m = m.Parent {
+		if m == a {
+			return true
+		}
+	}
+	return false
+}
+
 // TODO: should insert and remove call Mark(MarkNeedsMeasureLayout | MarkNeedsPaint)?
 
 func (m *Embed) insert(c, nextSibling Node) {
@@ -475,6 +793,30 @@ func (m *Embed) remove(c Node) {
 }
 
 func (m *Embed) Mark(marks Marks) {
+	if marks&(MarkNeedsPaint|MarkNeedsPaintBase) != 0 {
+		m.damage = image.Rectangle{}
+	}
+	m.mark(marks)
+}
+
+// Invalidate marks this node as needing paint and paint base, like Mark does,
+// but only damages the part r of it, in the coordinate space where (0, 0) is
+// its Rect.Min, instead of all of its Rect. For example, a text widget might
+// invalidate only the line being edited.
+func (m *Embed) Invalidate(r image.Rectangle) {
+	r = r.Intersect(m.Rect.Sub(m.Rect.Min))
+	if r.Empty() {
+		return
+	}
+	if !m.Marks.NeedsPaint() && !m.Marks.NeedsPaintBase() {
+		m.damage = r
+	} else if m.damage != (image.Rectangle{}) {
+		m.damage = m.damage.Union(r)
+	}
+	m.mark(MarkNeedsPaint | MarkNeedsPaintBase)
+}
+
+func (m *Embed) mark(marks Marks) {
 	oldMarks := m.Marks
 	m.Marks |= marks
 	changedMarks := m.Marks ^ oldMarks
@@ -491,19 +833,65 @@ const (
 	// MarkNeedsMeasureLayout marks this node as needing Measure and Layout
 	// calls.
 	MarkNeedsMeasureLayout = Marks(1 << 0)
-	// TODO: use this.
 
 	// MarkNeedsPaint marks this node as needing a Paint call.
 	MarkNeedsPaint = Marks(1 << 1)
 
 	// MarkNeedsPaintBase marks this node as needing a PaintBase call.
 	MarkNeedsPaintBase = Marks(1 << 2)
-)
 
-func (m Marks) NeedsMeasureLayout() bool { return m&MarkNeedsMeasureLayout != 0 }
-func (m Marks) NeedsPaint() bool         { return m&MarkNeedsPaint != 0 }
-func (m Marks) NeedsPaintBase() bool     { return m&MarkNeedsPaintBase != 0 }
+	// MarkDescendantNeedsPaint marks this node as having a descendant that
+	// needs a Paint call. The node itself has not changed, so its bounds are
+	// not necessarily damaged, but Paint must still be called on it to reach
+	// that descendant.
+	MarkDescendantNeedsPaint = Marks(1 << 3)
+
+	// MarkDescendantNeedsPaintBase marks this node as having a descendant
+	// that needs a PaintBase call.
+	MarkDescendantNeedsPaintBase = Marks(1 << 4)
+
+	// MarkDescendantNeedsMeasureLayout marks this node as having a
+	// descendant that needs Measure and Layout calls. The node itself need
+	// not be laid out again, unless the descendant's natural size changes
+	// its own.
+	MarkDescendantNeedsMeasureLayout = Marks(1 << 5)
+)
 
-func (m *Marks) UnmarkNeedsMeasureLayout() { *m &^= MarkNeedsMeasureLayout }
-func (m *Marks) UnmarkNeedsPaint()         { *m &^= MarkNeedsPaint }
-func (m *Marks) UnmarkNeedsPaintBase()     { *m &^= MarkNeedsPaintBase }
+func (m Marks) NeedsMeasureLayout() bool           { return m&MarkNeedsMeasureLayout != 0 }
+func (m Marks) NeedsPaint() bool                   { return m&MarkNeedsPaint != 0 }
+func (m Marks) NeedsPaintBase() bool               { return m&MarkNeedsPaintBase != 0 }
+func (m Marks) DescendantNeedsPaint() bool         { return m&MarkDescendantNeedsPaint != 0 }
+func (m Marks) DescendantNeedsPaintBase() bool     { return m&MarkDescendantNeedsPaintBase != 0 }
+func (m Marks) DescendantNeedsMeasureLayout() bool { return m&MarkDescendantNeedsMeasureLayout != 0 }
+
+func (m *Marks) UnmarkNeedsMeasureLayout()           { *m &^= MarkNeedsMeasureLayout }
+func (m *Marks) UnmarkNeedsPaint()                   { *m &^= MarkNeedsPaint }
+func (m *Marks) UnmarkNeedsPaintBase()               { *m &^= MarkNeedsPaintBase }
+func (m *Marks) UnmarkDescendantNeedsPaint()         { *m &^= MarkDescendantNeedsPaint }
+func (m *Marks) UnmarkDescendantNeedsPaintBase()     { *m &^= MarkDescendantNeedsPaintBase }
+func (m *Marks) UnmarkDescendantNeedsMeasureLayout() { *m &^= MarkDescendantNeedsMeasureLayout }
+
+// PropagateUp returns the marks that a parent node should be given when one of
+// its children is given the marks m.
+//
+// A child needing measure and layout, paint or paint base means that the
+// parent has a descendant that needs them, not that the parent itself does:
+// only the child's bounds are damaged, and the child's natural size may not
+// change. A node that caches its descendants' base pass, such as a
+// widget.Sheet, should convert MarkDescendantNeedsPaintBase to
+// MarkDescendantNeedsPaint, as it repaints their base pass in its own Paint.
+func (m Marks) PropagateUp() Marks {
+	if m&MarkNeedsMeasureLayout != 0 {
+		m &^= MarkNeedsMeasureLayout
+		m |= MarkDescendantNeedsMeasureLayout
+	}
+	if m&MarkNeedsPaint != 0 {
+		m &^= MarkNeedsPaint
+		m |= MarkDescendantNeedsPaint
+	}
+	if m&MarkNeedsPaintBase != 0 {
+		m &^= MarkNeedsPaintBase
+		m |= MarkDescendantNeedsPaintBase
+	}
+	return m
+}
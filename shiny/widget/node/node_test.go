@@ -0,0 +1,315 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/exp/shiny/widget/theme"
+)
+
+type testLeaf struct {
+	LeafEmbed
+	painted int
+}
+
+func newTestLeaf(r image.Rectangle) *testLeaf {
+	w := &testLeaf{}
+	w.Wrapper = w
+	w.Rect = r
+	return w
+}
+
+func (w *testLeaf) Paint(ctx *PaintContext, origin image.Point) error {
+	w.painted++
+	return w.LeafEmbed.Paint(ctx, origin)
+}
+
+func (w *testLeaf) Measure(t *theme.Theme, widthHint, heightHint int) {
+	w.MeasuredSize = w.Rect.Size()
+}
+
+type testContainer struct {
+	ContainerEmbed
+	laidOut int
+}
+
+func (w *testContainer) Layout(t *theme.Theme) {
+	w.laidOut++
+	w.ContainerEmbed.Layout(t)
+}
+
+func newTestContainer(r image.Rectangle, children ...Node) *testContainer {
+	w := &testContainer{}
+	w.Wrapper = w
+	w.Rect = r
+	for _, c := range children {
+		w.Insert(c, nil)
+	}
+	return w
+}
+
+func TestMarkPropagation(t *testing.T) {
+	a := newTestLeaf(image.Rect(0, 0, 10, 10))
+	b := newTestLeaf(image.Rect(10, 0, 20, 10))
+	inner := newTestContainer(image.Rect(5, 5, 25, 15), a, b)
+	root := newTestContainer(image.Rect(0, 0, 100, 100), inner)
+
+	b.Mark(MarkNeedsPaint | MarkNeedsMeasureLayout)
+
+	if got, want := b.Marks, MarkNeedsPaint|MarkNeedsMeasureLayout; got != want {
+		t.Errorf("b.Marks: got %#x, want %#x", got, want)
+	}
+	if got, want := a.Marks, Marks(0); got != want {
+		t.Errorf("a.Marks: got %#x, want %#x", got, want)
+	}
+	for _, n := range []*testContainer{inner, root} {
+		if got, want := n.Marks, MarkDescendantNeedsPaint|MarkDescendantNeedsMeasureLayout; got != want {
+			t.Errorf("container Marks: got %#x, want %#x", got, want)
+		}
+	}
+}
+
+func TestDamage(t *testing.T) {
+	a := newTestLeaf(image.Rect(0, 0, 10, 10))
+	b := newTestLeaf(image.Rect(10, 0, 20, 10))
+	c := newTestLeaf(image.Rect(40, 40, 50, 50))
+	inner := newTestContainer(image.Rect(5, 5, 25, 15), a, b)
+	root := newTestContainer(image.Rect(0, 0, 100, 100), inner, c)
+
+	if got := Damage(root, image.Point{}); !got.Empty() {
+		t.Fatalf("initial Damage: got %v, want empty", got)
+	}
+
+	b.Mark(MarkNeedsPaint)
+	if got, want := Damage(root, image.Point{}), image.Rect(15, 5, 25, 15); got != want {
+		t.Fatalf("Damage after marking b: got %v, want %v", got, want)
+	}
+
+	c.Mark(MarkNeedsPaint)
+	if got, want := Damage(root, image.Point{}), image.Rect(15, 5, 50, 50); got != want {
+		t.Fatalf("Damage after marking c: got %v, want %v", got, want)
+	}
+
+	ctx := &PaintContext{Damage: Damage(root, image.Point{})}
+	if err := root.Paint(ctx, image.Point{}); err != nil {
+		t.Fatalf("Paint: %v", err)
+	}
+	if a.painted != 0 || b.painted != 1 || c.painted != 1 {
+		t.Errorf("painted: got a=%d, b=%d, c=%d, want 0, 1, 1", a.painted, b.painted, c.painted)
+	}
+	if got := Damage(root, image.Point{}); !got.Empty() {
+		t.Errorf("Damage after Paint: got %v, want empty", got)
+	}
+
+	// A zero Damage means to paint everything.
+	if err := root.Paint(&PaintContext{}, image.Point{}); err != nil {
+		t.Fatalf("Paint: %v", err)
+	}
+	if a.painted != 1 || b.painted != 2 || c.painted != 2 {
+		t.Errorf("painted: got a=%d, b=%d, c=%d, want 1, 2, 2", a.painted, b.painted, c.painted)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	a := newTestLeaf(image.Rect(0, 0, 10, 10))
+	b := newTestLeaf(image.Rect(10, 0, 20, 10))
+	root := newTestContainer(image.Rect(5, 5, 25, 15), a, b)
+
+	b.Invalidate(image.Rect(2, 3, 4, 5))
+	if got, want := b.Marks, MarkNeedsPaint|MarkNeedsPaintBase; got != want {
+		t.Errorf("b.Marks: got %#x, want %#x", got, want)
+	}
+	if got, want := root.Marks, MarkDescendantNeedsPaint|MarkDescendantNeedsPaintBase; got != want {
+		t.Errorf("root.Marks: got %#x, want %#x", got, want)
+	}
+	if got, want := Damage(root, image.Point{}), image.Rect(17, 8, 19, 10); got != want {
+		t.Fatalf("Damage after invalidating part of b: got %v, want %v", got, want)
+	}
+
+	// Invalidating more of b adds to its damage, clipped to b's bounds.
+	b.Invalidate(image.Rect(6, 7, 50, 50))
+	if got, want := Damage(root, image.Point{}), image.Rect(17, 8, 25, 15); got != want {
+		t.Fatalf("Damage after invalidating more of b: got %v, want %v", got, want)
+	}
+
+	// Marking b damages all of it, even if it is invalidated again.
+	b.Mark(MarkNeedsPaint)
+	b.Invalidate(image.Rect(2, 3, 4, 5))
+	if got, want := Damage(root, image.Point{}), image.Rect(15, 5, 25, 15); got != want {
+		t.Fatalf("Damage after marking b: got %v, want %v", got, want)
+	}
+
+	// Marking a node as only needing paint base also damages it.
+	b.Marks = 0
+	root.Marks = 0
+	a.Mark(MarkNeedsPaintBase)
+	if got, want := Damage(root, image.Point{}), image.Rect(5, 5, 15, 15); got != want {
+		t.Fatalf("Damage after marking a for paint base: got %v, want %v", got, want)
+	}
+}
+
+func TestRelayout(t *testing.T) {
+	a := newTestLeaf(image.Rect(0, 0, 10, 10))
+	b := newTestLeaf(image.Rect(0, 0, 20, 10))
+	c := newTestLeaf(image.Rect(0, 0, 40, 10))
+	inner0 := newTestContainer(image.Rect(0, 0, 10, 10), a)
+	inner1 := newTestContainer(image.Rect(0, 0, 40, 10), b, c)
+	root := newTestContainer(image.Rect(0, 0, 100, 100), inner0, inner1)
+	// ContainerEmbed.Measure sets a container's natural size to that of its
+	// largest child.
+	root.Measure(nil, NoHint, NoHint)
+
+	// Marking a with an unchanged natural size only lays out a again.
+	a.Mark(MarkNeedsMeasureLayout)
+	Relayout(root, nil, NoHint, NoHint)
+	if root.laidOut != 0 || inner0.laidOut != 0 || inner1.laidOut != 0 {
+		t.Errorf("laidOut: got root=%d, inner0=%d, inner1=%d, want 0, 0, 0",
+			root.laidOut, inner0.laidOut, inner1.laidOut)
+	}
+	if got, want := Damage(root, image.Point{}), image.Rect(0, 0, 10, 10); got != want {
+		t.Errorf("Damage after relayout of a: got %v, want %v", got, want)
+	}
+	for _, n := range []*Embed{&a.Embed, &inner0.Embed, &root.Embed} {
+		if m := n.Marks; m.NeedsMeasureLayout() || m.DescendantNeedsMeasureLayout() {
+			t.Errorf("Marks after Relayout: got %#x, want no layout marks", m)
+		}
+	}
+
+	// Growing b lays out b's parent again, but not root, as the natural size
+	// of b's parent is that of c, and so unchanged.
+	root.Paint(&PaintContext{}, image.Point{})
+	b.Rect.Max.X = 30
+	b.Mark(MarkNeedsMeasureLayout)
+	Relayout(root, nil, NoHint, NoHint)
+	if root.laidOut != 0 || inner0.laidOut != 0 || inner1.laidOut != 1 {
+		t.Errorf("laidOut: got root=%d, inner0=%d, inner1=%d, want 0, 0, 1",
+			root.laidOut, inner0.laidOut, inner1.laidOut)
+	}
+	if got, want := Damage(root, image.Point{}), image.Rect(0, 0, 40, 10); got != want {
+		t.Errorf("Damage after relayout of b: got %v, want %v", got, want)
+	}
+
+	// Growing c past a changes the natural size of c's parent, so root is
+	// laid out again, and so are all of its children.
+	c.Rect.Max.X = 50
+	c.Mark(MarkNeedsMeasureLayout)
+	Relayout(root, nil, NoHint, NoHint)
+	if root.laidOut != 1 || inner0.laidOut != 1 || inner1.laidOut != 2 {
+		t.Errorf("laidOut: got root=%d, inner0=%d, inner1=%d, want 1, 1, 2",
+			root.laidOut, inner0.laidOut, inner1.laidOut)
+	}
+
+	// Without any marks, nothing is laid out again.
+	Relayout(root, nil, NoHint, NoHint)
+	if root.laidOut != 1 || inner0.laidOut != 1 || inner1.laidOut != 2 {
+		t.Errorf("laidOut: got root=%d, inner0=%d, inner1=%d, want 1, 1, 2",
+			root.laidOut, inner0.laidOut, inner1.laidOut)
+	}
+}
+
+type testFocusLeaf struct {
+	LeafEmbed
+	events []FocusEvent
+}
+
+func (w *testFocusLeaf) OnInputEvent(e interface{}, origin image.Point) EventHandled {
+	if e, ok := e.(FocusEvent); ok {
+		w.events = append(w.events, e)
+	}
+	return Handled
+}
+
+func TestFocus(t *testing.T) {
+	a, b := &testFocusLeaf{}, &testFocusLeaf{}
+	a.Wrapper, b.Wrapper = a, b
+	inner := newTestContainer(image.Rectangle{}, a)
+	root := newTestContainer(image.Rectangle{}, inner, b)
+
+	if got := Focused(root); got != nil {
+		t.Fatalf("initial Focused: got %v, want nil", got)
+	}
+	Focus(a)
+	Focus(a)
+	if got := Focused(b); got != Node(a) {
+		t.Fatalf("Focused after focusing a: got %v, want a", got)
+	}
+	Focus(b)
+	if len(a.events) != 2 || !a.events[0].Focused || a.events[1].Focused {
+		t.Errorf("a's events: got %v, want gained then lost", a.events)
+	}
+	Unfocus(a)
+	if len(b.events) != 1 || Focused(root) != Node(b) {
+		t.Errorf("b after unfocusing a: got events %v, focused %v, want gained, b", b.events, Focused(root))
+	}
+	Unfocus(b)
+	if len(b.events) != 2 || b.events[1].Focused || Focused(root) != nil {
+		t.Errorf("b after unfocusing b: got events %v, focused %v, want gained then lost, nil", b.events, Focused(root))
+	}
+
+	// A removed node loses the focus.
+	Focus(a)
+	inner.Remove(a)
+	if got := Focused(root); got != nil {
+		t.Errorf("Focused after removing a: got %v, want nil", got)
+	}
+}
+
+type testTabLeaf struct {
+	testFocusLeaf
+	focusable bool
+}
+
+func (w *testTabLeaf) Focusable() bool { return w.focusable }
+
+func newTestTabLeaf(focusable bool) *testTabLeaf {
+	w := &testTabLeaf{focusable: focusable}
+	w.Wrapper = w
+	return w
+}
+
+type testTabOrderer struct {
+	testContainer
+	order []Node
+}
+
+func (w *testTabOrderer) TabOrder() []Node { return w.order }
+
+func TestNextFocus(t *testing.T) {
+	a, b, c, d, e := newTestTabLeaf(true), newTestTabLeaf(false), newTestTabLeaf(true), newTestTabLeaf(true), newTestTabLeaf(true)
+	inner := newTestContainer(image.Rectangle{}, b, c)
+	o := &testTabOrderer{}
+	o.Wrapper = o
+	o.Insert(d, nil)
+	o.Insert(e, nil)
+	o.order = []Node{e, d, a}
+	root := newTestContainer(image.Rectangle{}, a, inner, o)
+
+	// The tree's order is a, c, and then o's order, which skips a, as it is
+	// not o's descendant.
+	want := []Node{a, c, e, d, a}
+	for i, w := range want {
+		got := NextFocus(root, false)
+		if got != w {
+			t.Fatalf("Tab #%d: got %p, want %p", i, got, w)
+		}
+		Focus(got)
+	}
+	for i, w := range []Node{d, e, c, a, d} {
+		got := NextFocus(root, true)
+		if got != w {
+			t.Fatalf("Shift-Tab #%d: got %p, want %p", i, got, w)
+		}
+		Focus(got)
+	}
+
+	for _, n := range []*testTabLeaf{a, c, d, e} {
+		n.focusable = false
+	}
+	if got := NextFocus(root, false); got != nil {
+		t.Errorf("with nothing focusable: got %p, want nil", got)
+	}
+}
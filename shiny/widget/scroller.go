@@ -0,0 +1,397 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"time"
+
+	"golang.org/x/exp/shiny/gesture"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/unit"
+	"golang.org/x/exp/shiny/widget/node"
+	"golang.org/x/exp/shiny/widget/theme"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/event/mouse"
+)
+
+var (
+	// scrollStep is how far one step of a screen.ScrollEvent, such as one
+	// click of a mouse wheel, scrolls: about three lines of text.
+	scrollStep = unit.Ems(3)
+
+	// scrollbarWidth is how thick a Scroller's scroll bars are.
+	scrollbarWidth = unit.DIPs(4)
+)
+
+const (
+	// flingFriction is how quickly a fling slows down. Its velocity decays
+	// by a factor of e every 1/flingFriction seconds, which is about what
+	// iOS does.
+	flingFriction = 2
+
+	// minFlingSpeed is the speed, in pixels per second, below which a fling
+	// stops.
+	minFlingSpeed = 10
+)
+
+// Virtualizer is implemented by a Scroller's child that only has children for
+// the part of it that is visible, such as a VirtualList, so that a very large
+// child need not create all of its descendants.
+type Virtualizer interface {
+	// SetViewport sets the part of the node, relative to its Rect.Min, that
+	// is visible. The Scroller calls SetViewport before laying the node out,
+	// and marks the node as needing measure and layout after scrolling
+	// changes the visible part.
+	SetViewport(r image.Rectangle)
+}
+
+// Scroller is a shell widget that shows part of its inner widget, which can be
+// larger than the Scroller along the Scroller's axis, and scrolls it in
+// response to mouse wheels, trackpads and drags. A drag that is still moving
+// fast when it ends, a fling, keeps scrolling for a while, slowing down by
+// itself.
+//
+// A Scroller is a Sheet, whose pixel buffer clips what its descendants paint
+// to the Scroller's bounds. It shows a scroll bar for each axis along which
+// the inner widget is larger than the Scroller.
+//
+// Input events are offered to the inner widget first, and scroll the Scroller
+// only if the inner widget does not handle them. A scroll that would not move
+// the Scroller, such as when it is already scrolled to the end, is not
+// handled, so that an outer Scroller can scroll instead.
+type Scroller struct {
+	Sheet
+	Axis Axis
+
+	// pos is the scroll position, which, once rounded, is the Sheet's offset.
+	pos f64.Vec2
+
+	// velocity is the velocity of a fling, in pixels per second, which is
+	// zero unless a fling is in progress. lastFling is when pos was last
+	// moved by the fling.
+	velocity  f64.Vec2
+	lastFling time.Time
+
+	// dragFrom is pos when the most recent gesture started.
+	dragFrom f64.Vec2
+
+	// sawScrollEvent is whether a screen.ScrollEvent has been handled, after
+	// which the mouse.Events of mouse wheels are ignored.
+	sawScrollEvent bool
+
+	// step and barWidth are scrollStep and scrollbarWidth in pixels, as per
+	// the theme of the most recent Layout.
+	step, barWidth int
+}
+
+// NewScroller returns a new Scroller widget.
+func NewScroller(a Axis, inner node.Node) *Scroller {
+	w := &Scroller{
+		Axis: a,
+	}
+	w.Wrapper = w
+	if inner != nil {
+		w.Insert(inner, nil)
+	}
+	return w
+}
+
+// Offset returns the position, in the inner widget's coordinate space, of the
+// Scroller's top-left pixel.
+func (w *Scroller) Offset() image.Point { return w.offset }
+
+// ScrollTo scrolls so that the point p, in the inner widget's coordinate
+// space, is at the Scroller's top-left, or as near to it as the inner widget's
+// size allows. It stops any fling.
+func (w *Scroller) ScrollTo(p image.Point) {
+	w.velocity = f64.Vec2{}
+	w.scrollTo(f64.Vec2{float64(p.X), float64(p.Y)})
+}
+
+// maxPos returns the largest scroll position, which is zero along each axis
+// that the Scroller does not scroll along.
+func (w *Scroller) maxPos() (m f64.Vec2) {
+	c := w.FirstChild
+	if c == nil {
+		return m
+	}
+	if w.Axis.Horizontal() && c.Rect.Dx() > w.Rect.Dx() {
+		m[0] = float64(c.Rect.Dx() - w.Rect.Dx())
+	}
+	if w.Axis.Vertical() && c.Rect.Dy() > w.Rect.Dy() {
+		m[1] = float64(c.Rect.Dy() - w.Rect.Dy())
+	}
+	return m
+}
+
+func (w *Scroller) clamp(p f64.Vec2) f64.Vec2 {
+	m := w.maxPos()
+	for i := range p {
+		p[i] = math.Max(0, math.Min(p[i], m[i]))
+	}
+	return p
+}
+
+func (w *Scroller) viewport() image.Rectangle {
+	return image.Rectangle{
+		Min: w.offset,
+		Max: w.offset.Add(w.Rect.Size()),
+	}
+}
+
+// scrollTo scrolls to p, clamped to the inner widget's size, returning whether
+// the scroll position changed.
+func (w *Scroller) scrollTo(p f64.Vec2) bool {
+	p = w.clamp(p)
+	if p == w.pos {
+		return false
+	}
+	w.pos = p
+	o := image.Point{
+		X: int(math.Round(p[0])),
+		Y: int(math.Round(p[1])),
+	}
+	if o == w.offset {
+		return true
+	}
+	w.offset = o
+	w.Mark(node.MarkNeedsPaint)
+	if c := w.FirstChild; c != nil {
+		if v, ok := c.Wrapper.(Virtualizer); ok {
+			v.SetViewport(w.viewport())
+			c.Wrapper.Mark(node.MarkNeedsMeasureLayout)
+		}
+	}
+	return true
+}
+
+// scrollBy scrolls by dx and dy steps, stopping any fling.
+func (w *Scroller) scrollBy(dx, dy float64) node.EventHandled {
+	w.velocity = f64.Vec2{}
+	p := w.pos
+	p[0] += dx * float64(w.step)
+	p[1] += dy * float64(w.step)
+	if w.scrollTo(p) {
+		return node.Handled
+	}
+	return node.NotHandled
+}
+
+// fling starts a fling with the velocity v, in pixels per second.
+func (w *Scroller) fling(v f64.Vec2, now time.Time) {
+	if !w.Axis.Horizontal() {
+		v[0] = 0
+	}
+	if !w.Axis.Vertical() {
+		v[1] = 0
+	}
+	if math.Hypot(v[0], v[1]) < minFlingSpeed {
+		return
+	}
+	w.velocity, w.lastFling = v, now
+	w.Mark(node.MarkNeedsPaint)
+}
+
+// animate moves a fling in progress on to the time now, slowing it down. A
+// fling stops along an axis when it reaches the end of that axis.
+func (w *Scroller) animate(now time.Time) {
+	if w.velocity == (f64.Vec2{}) {
+		return
+	}
+	dt := now.Sub(w.lastFling).Seconds()
+	w.lastFling = now
+	// Integrating a velocity of v·exp(-flingFriction·t) over dt gives the
+	// distance moved.
+	decay := math.Exp(-flingFriction * dt)
+	p := w.pos
+	for i := range p {
+		p[i] += w.velocity[i] * (1 - decay) / flingFriction
+		w.velocity[i] *= decay
+	}
+	w.scrollTo(p)
+
+	m := w.maxPos()
+	for i := range w.velocity {
+		if (w.pos[i] <= 0 && w.velocity[i] < 0) || (w.pos[i] >= m[i] && w.velocity[i] > 0) {
+			w.velocity[i] = 0
+		}
+	}
+	if math.Hypot(w.velocity[0], w.velocity[1]) < minFlingSpeed {
+		w.velocity = f64.Vec2{}
+	}
+}
+
+func (w *Scroller) Measure(t *theme.Theme, widthHint, heightHint int) {
+	c := w.FirstChild
+	if c == nil {
+		w.MeasuredSize = image.Point{}
+		return
+	}
+	// The inner widget is unconstrained along the axes that it scrolls
+	// along.
+	if w.Axis.Horizontal() {
+		widthHint = node.NoHint
+	}
+	if w.Axis.Vertical() {
+		heightHint = node.NoHint
+	}
+	c.Wrapper.Measure(t, widthHint, heightHint)
+	w.MeasuredSize = c.MeasuredSize
+}
+
+func (w *Scroller) Layout(t *theme.Theme) {
+	w.step = t.Pixels(scrollStep).Round()
+	w.barWidth = t.Pixels(scrollbarWidth).Ceil()
+	c := w.FirstChild
+	if c == nil {
+		return
+	}
+	// The inner widget fills the Scroller, and is at its natural size
+	// along the axes that it scrolls along, if that is larger.
+	size := w.Rect.Size()
+	if w.Axis.Horizontal() && size.X < c.MeasuredSize.X {
+		size.X = c.MeasuredSize.X
+	}
+	if w.Axis.Vertical() && size.Y < c.MeasuredSize.Y {
+		size.Y = c.MeasuredSize.Y
+	}
+	c.Rect = image.Rectangle{Max: size}
+
+	// The inner widget may have shrunk, so clamp the scroll position, but do
+	// not call scrollTo, as the inner widget is about to be laid out anyway.
+	w.pos = w.clamp(w.pos)
+	w.offset = image.Point{
+		X: int(math.Round(w.pos[0])),
+		Y: int(math.Round(w.pos[1])),
+	}
+	if v, ok := c.Wrapper.(Virtualizer); ok {
+		v.SetViewport(w.viewport())
+	}
+	c.Wrapper.Layout(t)
+}
+
+func (w *Scroller) Paint(ctx *node.PaintContext, origin image.Point) error {
+	// TODO: after scrolling, copy the part of the buffer that is still
+	// visible, instead of painting all of it again.
+	w.animate(time.Now())
+	if err := w.Sheet.Paint(ctx, origin); err != nil {
+		return err
+	}
+	w.paintScrollbars(ctx, w.Rect.Add(origin))
+	if w.velocity != (f64.Vec2{}) {
+		// Paint again, and so move the fling on, at the next frame.
+		w.Mark(node.MarkNeedsPaint)
+	}
+	return nil
+}
+
+// paintScrollbars paints the scroll bars of the Scroller, whose bounds are r,
+// along its bottom and right edges.
+func (w *Scroller) paintScrollbars(ctx *node.PaintContext, r image.Rectangle) {
+	c := w.FirstChild
+	if c == nil {
+		return
+	}
+	m := w.maxPos()
+	if m[0] > 0 {
+		x0, x1 := scrollbarThumb(r.Dx(), c.Rect.Dx(), w.pos[0]/m[0], w.barWidth)
+		w.paintScrollbar(ctx, image.Rect(r.Min.X+x0, r.Max.Y-w.barWidth, r.Min.X+x1, r.Max.Y))
+	}
+	if m[1] > 0 {
+		y0, y1 := scrollbarThumb(r.Dy(), c.Rect.Dy(), w.pos[1]/m[1], w.barWidth)
+		w.paintScrollbar(ctx, image.Rect(r.Max.X-w.barWidth, r.Min.Y+y0, r.Max.X, r.Min.Y+y1))
+	}
+}
+
+func (w *Scroller) paintScrollbar(ctx *node.PaintContext, r image.Rectangle) {
+	if ctx.Damage != (image.Rectangle{}) {
+		r = r.Intersect(ctx.Damage)
+	}
+	if r.Empty() {
+		return
+	}
+	ctx.Drawer.DrawUniform(ctx.Src2Dst, theme.Dark.Color(ctx.Theme), r, draw.Src, nil)
+}
+
+// scrollbarThumb returns where, along a scroll bar of length n, its thumb
+// starts and ends, for a viewport of length n onto content of length
+// contentN, scrolled frac of the way to its end. The thumb is at least
+// minLen long.
+func scrollbarThumb(n, contentN int, frac float64, minLen int) (start, end int) {
+	thumb := n * n / contentN
+	if thumb < minLen {
+		thumb = minLen
+	}
+	if thumb > n {
+		thumb = n
+	}
+	start = int(math.Round(frac * float64(n-thumb)))
+	return start, start + thumb
+}
+
+func (w *Scroller) OnInputEvent(e interface{}, origin image.Point) node.EventHandled {
+	c := w.FirstChild
+	if c == nil {
+		return node.NotHandled
+	}
+	if _, ok := e.(node.HoverEvent); ok {
+		// The child is sent its own HoverEvents.
+		return node.NotHandled
+	}
+	if e, ok := e.(gesture.Event); ok && e.Type == gesture.TypeStart {
+		// Touching the Scroller stops a fling.
+		w.velocity = f64.Vec2{}
+		w.dragFrom = w.pos
+	}
+	if c.Wrapper.OnInputEvent(e, origin.Add(w.Rect.Min).Sub(w.offset)) == node.Handled {
+		return node.Handled
+	}
+
+	switch e := e.(type) {
+	case screen.ScrollEvent:
+		w.sawScrollEvent = true
+		return w.scrollBy(float64(e.DeltaX), float64(e.DeltaY))
+
+	case mouse.Event:
+		if e.Direction != mouse.DirStep || w.sawScrollEvent {
+			break
+		}
+		switch e.Button {
+		case mouse.ButtonWheelUp:
+			return w.scrollBy(0, -1)
+		case mouse.ButtonWheelDown:
+			return w.scrollBy(0, +1)
+		case mouse.ButtonWheelLeft:
+			return w.scrollBy(-1, 0)
+		case mouse.ButtonWheelRight:
+			return w.scrollBy(+1, 0)
+		}
+
+	case gesture.Event:
+		if !e.Drag || w.maxPos() == (f64.Vec2{}) {
+			break
+		}
+		switch e.Type {
+		case gesture.TypeIsDrag, gesture.TypeDrag:
+			// The inner widget moves with the pointer.
+			p := w.dragFrom
+			p[0] += float64(e.InitialPos.X - e.CurrentPos.X)
+			p[1] += float64(e.InitialPos.Y - e.CurrentPos.Y)
+			w.scrollTo(p)
+			return node.Handled
+		case gesture.TypeFling:
+			w.fling(f64.Vec2{-float64(e.Velocity.X), -float64(e.Velocity.Y)}, time.Now())
+			return node.Handled
+		}
+	}
+	return node.NotHandled
+}
+
+func (w *Scroller) Describe(a *screen.AccessNode) {
+	a.Role = screen.AccessScrollArea
+}
@@ -0,0 +1,170 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+	"math"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/unit"
+	"golang.org/x/exp/shiny/widget/node"
+	"golang.org/x/exp/shiny/widget/theme"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/event/mouse"
+)
+
+func newTestScroller(list *VirtualList) *Scroller {
+	w := NewScroller(AxisVertical, list)
+	w.Rect = image.Rect(0, 0, 200, 100)
+	w.Mark(node.MarkNeedsMeasureLayout)
+	node.Relayout(w, nil, 200, 100)
+	return w
+}
+
+func TestVirtualList(t *testing.T) {
+	created, released := 0, 0
+	list := NewVirtualList(100000, unit.Pixels(10), func(i int) node.Node {
+		created++
+		return WithLayoutData(NewSpace(), i)
+	})
+	list.ReleaseRow = func(i int, n node.Node) {
+		released++
+		if got := n.Wrappee().LayoutData; got != i {
+			t.Errorf("ReleaseRow(%d, n): n is row %v", i, got)
+		}
+	}
+	w := newTestScroller(list)
+
+	if got, want := list.Rect, image.Rect(0, 0, 200, 1000000); got != want {
+		t.Fatalf("list.Rect: got %v, want %v", got, want)
+	}
+	// checkRows checks that the list's rows are rows lo to hi, and where
+	// they are.
+	checkRows := func(lo, hi int) {
+		t.Helper()
+		i := lo
+		for c := list.FirstChild; c != nil; c, i = c.NextSibling, i+1 {
+			if c.LayoutData != i {
+				t.Fatalf("row: got %v, want %d", c.LayoutData, i)
+			}
+			if want := image.Rect(0, 10*i, 200, 10*i+10); c.Rect != want {
+				t.Fatalf("row %d Rect: got %v, want %v", i, c.Rect, want)
+			}
+		}
+		if i != hi {
+			t.Fatalf("rows: got %d to %d, want %d to %d", lo, i, lo, hi)
+		}
+	}
+	// The viewport is 100 pixels, or 10 rows, high, and there are rows for
+	// half of that above and below it.
+	checkRows(0, 15)
+	if created != 15 {
+		t.Errorf("created: got %d, want 15", created)
+	}
+
+	// Scrolling a little keeps most of the rows.
+	w.ScrollTo(image.Point{0, 30})
+	node.Relayout(w, nil, 200, 100)
+	checkRows(0, 18)
+	if created != 18 || released != 0 {
+		t.Errorf("created, released: got %d, %d, want 18, 0", created, released)
+	}
+
+	// Scrolling a long way replaces all of them.
+	w.ScrollTo(image.Point{0, 500000})
+	node.Relayout(w, nil, 200, 100)
+	checkRows(49995, 50015)
+	if created != 38 || released != 18 {
+		t.Errorf("created, released: got %d, %d, want 38, 18", created, released)
+	}
+}
+
+func TestScrollerInput(t *testing.T) {
+	list := NewVirtualList(100, unit.Pixels(10), func(i int) node.Node { return NewSpace() })
+	w := newTestScroller(list)
+	step := theme.Default.Pixels(scrollStep).Round()
+
+	if got := w.OnInputEvent(mouse.Event{Button: mouse.ButtonWheelDown, Direction: mouse.DirStep}, image.Point{}); got != node.Handled {
+		t.Errorf("wheel: got %v, want handled", got)
+	}
+	if got, want := w.Offset(), (image.Point{0, step}); got != want {
+		t.Errorf("offset after wheel: got %v, want %v", got, want)
+	}
+
+	// After a screen.ScrollEvent, the wheels' mouse.Events are ignored.
+	w.OnInputEvent(screen.ScrollEvent{DeltaY: 0.5}, image.Point{})
+	w.OnInputEvent(mouse.Event{Button: mouse.ButtonWheelDown, Direction: mouse.DirStep}, image.Point{})
+	if got, want := w.Offset(), (image.Point{0, step + step/2}); got != want {
+		t.Errorf("offset after ScrollEvent: got %v, want %v", got, want)
+	}
+
+	// Scrolling past the end stops at the end, and scrolling further is not
+	// handled.
+	w.OnInputEvent(screen.ScrollEvent{DeltaY: 1000}, image.Point{})
+	if got, want := w.Offset(), (image.Point{0, 900}); got != want {
+		t.Errorf("offset at the end: got %v, want %v", got, want)
+	}
+	if got := w.OnInputEvent(screen.ScrollEvent{DeltaY: 1}, image.Point{}); got != node.NotHandled {
+		t.Errorf("scroll past the end: got %v, want not handled", got)
+	}
+	// A vertical Scroller does not scroll horizontally.
+	if got := w.OnInputEvent(screen.ScrollEvent{DeltaX: -1}, image.Point{}); got != node.NotHandled {
+		t.Errorf("horizontal scroll: got %v, want not handled", got)
+	}
+}
+
+func TestScrollerFling(t *testing.T) {
+	list := NewVirtualList(100000, unit.Pixels(10), func(i int) node.Node { return NewSpace() })
+	w := newTestScroller(list)
+
+	t0 := time.Unix(1e9, 0)
+	w.fling(f64.Vec2{500, 1000}, t0)
+	w.animate(t0.Add(time.Second))
+	// The fling moved 1000·(1-exp(-2))/2 pixels, and not horizontally.
+	if got, want := w.pos, (f64.Vec2{0, 1000 * (1 - math.Exp(-2)) / 2}); math.Abs(got[1]-want[1]) > 1e-6 || got[0] != 0 {
+		t.Errorf("pos after a second: got %v, want %v", got, want)
+	}
+	// It keeps moving, but slows to a stop.
+	for i := 2; i <= 5 && w.velocity != (f64.Vec2{}); i++ {
+		w.animate(t0.Add(time.Duration(i) * time.Second))
+	}
+	if w.velocity != (f64.Vec2{}) {
+		t.Errorf("velocity after 5 seconds: got %v, want zero", w.velocity)
+	}
+	if got, max := w.pos[1], 1000.0/flingFriction; got >= max {
+		t.Errorf("pos after stopping: got %v, want less than %v", got, max)
+	}
+
+	// A fling stops at the end.
+	w.ScrollTo(image.Point{0, 999850})
+	w.fling(f64.Vec2{0, 1000}, t0)
+	w.animate(t0.Add(time.Second))
+	if got, want := w.Offset(), (image.Point{0, 999900}); got != want || w.velocity != (f64.Vec2{}) {
+		t.Errorf("fling at the end: got offset %v, velocity %v, want %v, zero", got, w.velocity, want)
+	}
+}
+
+func TestScrollIntoView(t *testing.T) {
+	list := NewVirtualList(100, unit.Pixels(10), func(i int) node.Node { return NewSpace() })
+	w := newTestScroller(list)
+
+	// A row below the viewport is scrolled up to its bottom, and one above
+	// it is scrolled down to its top.
+	row := list.FirstChild
+	for i := 0; i < 12; i++ {
+		row = row.NextSibling
+	}
+	scrollIntoView(row)
+	if got, want := w.Offset(), (image.Point{0, 30}); got != want {
+		t.Errorf("offset after scrolling to row 12: got %v, want %v", got, want)
+	}
+	scrollIntoView(list.FirstChild)
+	if got, want := w.Offset(), (image.Point{}); got != want {
+		t.Errorf("offset after scrolling to row 0: got %v, want %v", got, want)
+	}
+}
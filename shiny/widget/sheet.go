@@ -14,8 +14,6 @@ import (
 	"golang.org/x/mobile/event/lifecycle"
 )
 
-// TODO: scrolling.
-
 // Sheet is a shell widget that provides *image.RGBA pixel buffers (analogous
 // to blank sheets of paper) for its descendent widgets to paint on, via their
 // PaintBase methods. Such buffers may be cached and their contents re-used for
@@ -26,11 +24,15 @@ import (
 // more complicated app may have multiple Sheets. For example, consider a text
 // editor consisting of a small header bar and a large text widget. Those two
 // nodes may be backed by two separate Sheets, since scrolling the latter
-// should not scroll the former.
+// should not scroll the former. A Scroller is a Sheet that can scroll.
 type Sheet struct {
 	node.ShellEmbed
 	buf screen.Buffer
 	tex screen.Texture
+
+	// offset is the position, in its child's coordinate space, of the
+	// Sheet's top-left pixel. It is only non-zero for a Scroller.
+	offset image.Point
 }
 
 // NewSheet returns a new Sheet widget.
@@ -55,14 +57,19 @@ func (w *Sheet) release() {
 }
 
 func (w *Sheet) Paint(ctx *node.PaintContext, origin image.Point) (retErr error) {
+	// The Sheet itself needing paint, such as after its child was laid out
+	// again, means that the whole buffer needs repainting.
+	fresh := w.Marks.NeedsPaint() || w.Marks.NeedsPaintBase()
 	w.Marks.UnmarkNeedsPaint()
+	w.Marks.UnmarkNeedsPaintBase()
+	w.Marks.UnmarkDescendantNeedsPaint()
 	c := w.FirstChild
 	if c == nil {
 		w.release()
 		return nil
 	}
 
-	fresh, size := false, w.Rect.Size()
+	size := w.Rect.Size()
 	if w.buf != nil && w.buf.Size() != size {
 		w.release()
 	}
@@ -79,26 +86,55 @@ func (w *Sheet) Paint(ctx *node.PaintContext, origin image.Point) (retErr error)
 		}
 		fresh = true
 	}
-	if fresh || c.Marks.NeedsPaintBase() {
-		c.Wrapper.PaintBase(&node.PaintBaseContext{
-			Theme: ctx.Theme,
-			Dst:   w.buf.RGBA(),
-		}, image.Point{})
+	if fresh || c.Marks&sheetDamageMarks != 0 {
+		// Unless the buffer is fresh, only repaint, and upload, the damaged
+		// part of it. The rest of the buffer and texture is still valid.
+		//
+		// Damage from descendants that only need paint, not paint base, is
+		// repainted too, as they may have moved, such as after a layout.
+		dr, o := w.buf.Bounds(), image.Point{}.Sub(w.offset)
+		if !fresh {
+			dr = node.Damage(c.Wrapper, o).Intersect(dr)
+		}
+		if !dr.Empty() {
+			dst := w.buf.RGBA().SubImage(dr).(*image.RGBA)
+			draw.Draw(dst, dr, image.Transparent, image.Point{}, draw.Src)
+			c.Wrapper.PaintBase(&node.PaintBaseContext{
+				Theme: ctx.Theme,
+				Dst:   dst,
+			}, o)
+			w.tex.Upload(dr.Min, w.buf, dr)
+		}
 	}
 
-	w.tex.Upload(image.Point{}, w.buf, w.buf.Bounds())
+	// Only draw that part of the texture that is damaged, so that we do not
+	// paint over any undamaged nodes that overlap this one.
+	r := w.Rect.Add(origin)
+	sr := w.tex.Bounds()
+	if ctx.Damage != (image.Rectangle{}) {
+		sr = ctx.Damage.Intersect(r).Sub(r.Min)
+	}
 
 	src2dst := ctx.Src2Dst
-	translate(&src2dst,
-		float64(origin.X+w.Rect.Min.X),
-		float64(origin.Y+w.Rect.Min.Y),
-	)
+	translate(&src2dst, float64(r.Min.X), float64(r.Min.Y))
 	// TODO: should draw.Over be configurable?
-	ctx.Drawer.Draw(src2dst, w.tex, w.tex.Bounds(), draw.Over, nil)
-
-	return c.Wrapper.Paint(ctx, origin.Add(w.Rect.Min))
+	ctx.Drawer.Draw(src2dst, w.tex, sr, draw.Over, nil)
+
+	// Clip the child's effects pass to the Sheet's bounds, if the Drawer can.
+	if clipper, ok := ctx.Drawer.(screen.Clipper); ok {
+		p := &screen.Path{}
+		p.Rect(float64(r.Min.X), float64(r.Min.Y), float64(r.Max.X), float64(r.Max.Y))
+		clipper.PushClipPath(ctx.Src2Dst, p)
+		defer clipper.PopClip()
+	}
+	return c.Wrapper.Paint(ctx, r.Min.Sub(w.offset))
 }
 
+// sheetDamageMarks are the marks of a Sheet's child that mean that some of the
+// Sheet's buffer is damaged.
+const sheetDamageMarks = node.MarkNeedsPaint | node.MarkDescendantNeedsPaint |
+	node.MarkNeedsPaintBase | node.MarkDescendantNeedsPaintBase
+
 func translate(a *f64.Aff3, tx, ty float64) {
 	a[2] += a[0]*tx + a[1]*ty
 	a[5] += a[3]*tx + a[4]*ty
@@ -106,6 +142,7 @@ func translate(a *f64.Aff3, tx, ty float64) {
 
 func (w *Sheet) PaintBase(ctx *node.PaintBaseContext, origin image.Point) error {
 	w.Marks.UnmarkNeedsPaintBase()
+	w.Marks.UnmarkDescendantNeedsPaintBase()
 	// Do not recursively call PaintBase on our children. We create our own
 	// buffers, and Sheet.Paint will call PaintBase with our PaintBaseContext
 	// instead of our ancestor's.
@@ -113,9 +150,10 @@ func (w *Sheet) PaintBase(ctx *node.PaintBaseContext, origin image.Point) error
 }
 
 func (w *Sheet) OnChildMarked(child node.Node, newMarks node.Marks) {
-	if newMarks&node.MarkNeedsPaintBase != 0 {
-		newMarks &^= node.MarkNeedsPaintBase
-		newMarks |= node.MarkNeedsPaint
+	newMarks = newMarks.PropagateUp()
+	if newMarks&node.MarkDescendantNeedsPaintBase != 0 {
+		newMarks &^= node.MarkDescendantNeedsPaintBase
+		newMarks |= node.MarkDescendantNeedsPaint
 	}
 	w.Mark(newMarks)
 }
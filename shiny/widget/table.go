@@ -0,0 +1,275 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+	"image/draw"
+	"sort"
+
+	"golang.org/x/exp/shiny/gesture"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/widget/node"
+	"golang.org/x/exp/shiny/widget/theme"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/mobile/event/key"
+)
+
+// TableColumn is a column of a Table.
+type TableColumn struct {
+	// Title is the text of the column's header.
+	Title string
+
+	// Track is the column's sizing.
+	Track Track
+
+	// Less, if non-nil, makes the column sortable. It reports whether row i
+	// sorts before row j by this column, in ascending order.
+	Less func(i, j int) bool
+}
+
+// Table is a shell widget that lays out a number of rows of cells, one cell
+// per column, below a row of column headers. Tapping on the header of a
+// sortable column sorts the rows by that column, and tapping on it again
+// reverses their order. The headers' theme style kind is "TableHeader".
+//
+// After changing Columns, Rows or Cell, call Reload. After changing the data
+// that a column's Less compares, call SortBy to sort the rows again.
+type Table struct {
+	node.ShellEmbed
+
+	Columns []TableColumn
+
+	// Rows is the number of rows, not counting the headers.
+	Rows int
+
+	// Cell returns the node of the cell in the given row and column. It should
+	// not be nil.
+	Cell func(row, col int) node.Node
+
+	// OnSort, if non-nil, is called after the user sorts the rows.
+	OnSort func()
+
+	grid *Grid
+
+	// cells are the cells' nodes, indexed by row and then column.
+	cells [][]node.Node
+
+	// order is the rows, in the order that they are shown.
+	order []int
+
+	// sortColumn is the column that the rows are sorted by, or -1.
+	sortColumn int
+	descending bool
+}
+
+// NewTable returns a new Table widget.
+func NewTable(columns []TableColumn, rows int, cell func(row, col int) node.Node) *Table {
+	w := &Table{
+		Columns:    columns,
+		Rows:       rows,
+		Cell:       cell,
+		grid:       &Grid{},
+		sortColumn: -1,
+	}
+	w.Wrapper = w
+	w.grid.Wrapper = w.grid
+	w.Insert(w.grid, nil)
+	w.Reload()
+	return w
+}
+
+// Reload re-creates the headers and cells.
+func (w *Table) Reload() {
+	g := w.grid
+	for c := g.FirstChild; c != nil; {
+		next := c.NextSibling
+		g.Remove(c.Wrapper)
+		c = next
+	}
+	g.Columns = make([]Track, len(w.Columns))
+	for i, c := range w.Columns {
+		g.Columns[i] = c.Track
+		h := &tableHeader{table: w, col: i}
+		h.Wrapper = h
+		h.LayoutData = GridLayoutData{Column: i}
+		g.Insert(h, nil)
+	}
+	w.cells = make([][]node.Node, w.Rows)
+	for i := range w.cells {
+		w.cells[i] = make([]node.Node, len(w.Columns))
+		for j := range w.cells[i] {
+			c := w.Cell(i, j)
+			w.cells[i][j] = c
+			g.Insert(c, nil)
+		}
+	}
+	if w.sortColumn >= len(w.Columns) {
+		w.sortColumn = -1
+	}
+	w.SortBy(w.sortColumn, w.descending)
+}
+
+// SortColumn returns the column that the rows are sorted by, or -1 if they are
+// unsorted, and whether they are in descending order.
+func (w *Table) SortColumn() (col int, descending bool) {
+	return w.sortColumn, w.descending
+}
+
+// SortBy sorts the rows by the given column, in ascending or descending
+// order. A col of -1, or of a column that is not sortable, unsorts them. The
+// sort is stable, so that rows that are equal by that column stay in their
+// unsorted order.
+func (w *Table) SortBy(col int, descending bool) {
+	if col < 0 || len(w.Columns) <= col || w.Columns[col].Less == nil {
+		col, descending = -1, false
+	}
+	w.sortColumn, w.descending = col, descending
+	w.order = w.order[:0]
+	for i := 0; i < w.Rows; i++ {
+		w.order = append(w.order, i)
+	}
+	if col >= 0 {
+		sort.Stable(&tableOrder{w.order, w.Columns[col].Less, descending})
+	}
+	for k, i := range w.order {
+		for j, c := range w.cells[i] {
+			c.Wrappee().LayoutData = GridLayoutData{Column: j, Row: k + 1}
+		}
+	}
+	w.grid.Mark(node.MarkNeedsMeasureLayout | node.MarkNeedsPaint)
+}
+
+// Row returns the row that is shown k rows below the headers, counting from
+// zero.
+func (w *Table) Row(k int) int {
+	return w.order[k]
+}
+
+// tableOrder sorts a Table's rows.
+type tableOrder struct {
+	rows       []int
+	less       func(i, j int) bool
+	descending bool
+}
+
+func (o *tableOrder) Len() int      { return len(o.rows) }
+func (o *tableOrder) Swap(i, j int) { o.rows[i], o.rows[j] = o.rows[j], o.rows[i] }
+func (o *tableOrder) Less(i, j int) bool {
+	if o.descending {
+		return o.less(o.rows[j], o.rows[i])
+	}
+	return o.less(o.rows[i], o.rows[j])
+}
+
+// tableHeader is a leaf widget that is a Table's column header.
+type tableHeader struct {
+	node.LeafEmbed
+	table *Table
+	col   int
+}
+
+// padding returns the padding around a header's title: half of the theme's
+// spacing.
+func (w *tableHeader) padding(t *theme.Theme) int {
+	return t.Pixels(t.GetSpacing()).Ceil() / 2
+}
+
+func (w *tableHeader) Measure(t *theme.Theme, widthHint, heightHint int) {
+	t = t.Style("TableHeader")
+	face := t.AcquireFontFace(theme.FontFaceOptions{})
+	defer t.ReleaseFontFace(theme.FontFaceOptions{}, face)
+	m := face.Metrics()
+	h := m.Ascent.Ceil() + m.Descent.Ceil()
+	pad := w.padding(t)
+
+	w.MeasuredSize.X = font.MeasureString(face, w.table.Columns[w.col].Title).Ceil() + 2*pad
+	if w.table.Columns[w.col].Less != nil {
+		// Leave room for the sort arrow.
+		w.MeasuredSize.X += h
+	}
+	w.MeasuredSize.Y = h + 2*pad
+}
+
+func (w *tableHeader) PaintBase(ctx *node.PaintBaseContext, origin image.Point) error {
+	w.Marks.UnmarkNeedsPaintBase()
+	r := w.Rect.Add(origin)
+	dst := ctx.Dst.SubImage(r).(*image.RGBA)
+	if dst.Bounds().Empty() {
+		return nil
+	}
+
+	t := ctx.Theme.Style("TableHeader")
+	face := t.AcquireFontFace(theme.FontFaceOptions{})
+	defer t.ReleaseFontFace(theme.FontFaceOptions{}, face)
+	m := face.Metrics()
+	ascent, h := m.Ascent.Ceil(), m.Ascent.Ceil()+m.Descent.Ceil()
+	pad := w.padding(t)
+	pal := t.GetPalette()
+
+	draw.Draw(dst, r, pal.Neutral(), image.Point{}, draw.Src)
+	draw.Draw(dst, image.Rect(r.Min.X, r.Max.Y-1, r.Max.X, r.Max.Y), pal.Dark(), image.Point{}, draw.Src)
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  pal.Foreground(),
+		Face: face,
+		Dot:  fixed.P(r.Min.X+pad, r.Min.Y+pad+ascent),
+	}
+	d.DrawString(w.table.Columns[w.col].Title)
+
+	if col, descending := w.table.SortColumn(); col == w.col {
+		// Draw a triangle, pointing up for ascending and down for descending,
+		// at the right.
+		size := h / 2
+		x0, y0 := r.Max.X-pad-h+h/4, r.Min.Y+pad+h/4
+		for i := 0; i < size; i++ {
+			y := y0 + i
+			if descending {
+				y = y0 + size - 1 - i
+			}
+			half := i / 2
+			draw.Draw(dst, image.Rect(x0+size/2-half, y, x0+size/2+half+1, y+1), pal.Foreground(), image.Point{}, draw.Src)
+		}
+	}
+	return nil
+}
+
+// Focusable returns whether the header's column is sortable, in which case
+// pressing Space or Enter sorts it, as tapping on it does.
+func (w *tableHeader) Focusable() bool { return w.table.Columns[w.col].Less != nil }
+
+func (w *tableHeader) OnInputEvent(e interface{}, origin image.Point) node.EventHandled {
+	if w.table.Columns[w.col].Less == nil {
+		return node.NotHandled
+	}
+	switch e := e.(type) {
+	case gesture.Event:
+		if e.Type != gesture.TypeTap {
+			return node.NotHandled
+		}
+	case key.Event:
+		if e.Direction != key.DirPress || e.Modifiers != 0 ||
+			(e.Code != key.CodeSpacebar && e.Code != key.CodeReturnEnter) {
+			return node.NotHandled
+		}
+	default:
+		return node.NotHandled
+	}
+	col, descending := w.table.SortColumn()
+	w.table.SortBy(w.col, col == w.col && !descending)
+	if w.table.OnSort != nil {
+		w.table.OnSort()
+	}
+	return node.Handled
+}
+
+func (w *Table) Describe(a *screen.AccessNode) {
+	a.Role = screen.AccessTable
+}
+
+func (w *tableHeader) Describe(a *screen.AccessNode) {
+	a.Role, a.Name = screen.AccessColumnHeader, w.table.Columns[w.col].Title
+}
@@ -7,20 +7,34 @@ package widget
 import (
 	"image"
 	"image/draw"
+	"io"
 
+	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/exp/shiny/text"
-	"golang.org/x/exp/shiny/unit"
+	"golang.org/x/exp/shiny/text/glyphcache"
+	"golang.org/x/exp/shiny/text/shape"
 	"golang.org/x/exp/shiny/widget/node"
 	"golang.org/x/exp/shiny/widget/theme"
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
 
-// Text is a leaf widget that holds a text label.
+// Text is a leaf widget that holds a text label. Its theme style kind is
+// "Text".
 type Text struct {
 	node.LeafEmbed
-	frame   text.Frame
-	faceSet bool
+	frame text.Frame
+
+	// GlyphCache, if non-nil, is the cache that the text is drawn with,
+	// during the effects pass, on the Drawer. If nil, the text is drawn on
+	// the base pass's buffer, with a font.Drawer.
+	GlyphCache *glyphcache.Cache
+
+	// face is the frame's face, and fallback its fallback faces, acquired
+	// from faceTheme.
+	face      font.Face
+	fallback  []font.Face
+	faceTheme *theme.Theme
 
 	// TODO: scrolling, although should that be the responsibility of this
 	// widget, the parent widget or something else?
@@ -38,18 +52,25 @@ func NewText(text string) *Text {
 	return w
 }
 
+// setFace sets the frame's face, if it is not already set or if the theme has
+// changed since.
 func (w *Text) setFace(t *theme.Theme) {
-	// TODO: can a theme change at runtime, or can it be set only once, at
-	// start-up?
-	if !w.faceSet {
-		w.faceSet = true
-		// TODO: when is face released? Should we just unconditionally call
-		// SetFace for every Measure, Layout and Paint? How do we avoid
-		// excessive re-calculation of soft returns when re-using the same
-		// logical face (as in "Times New Roman 12pt") even if using different
-		// physical font.Face values (as each Face may have its own caches)?
-		face := t.AcquireFontFace(theme.FontFaceOptions{})
-		w.frame.SetFace(face)
+	if w.face == nil || w.faceTheme != t {
+		// TODO: how do we avoid excessive re-calculation of soft returns when
+		// re-using the same logical face (as in "Times New Roman 12pt") even
+		// if using different physical font.Face values (as each Face may have
+		// its own caches)?
+		if w.face != nil {
+			s := w.faceTheme.Style("Text")
+			s.ReleaseFallbackFontFaces(theme.FontFaceOptions{}, w.fallback)
+			s.ReleaseFontFace(theme.FontFaceOptions{}, w.face)
+		}
+		s := t.Style("Text")
+		w.face = s.AcquireFontFace(theme.FontFaceOptions{})
+		w.fallback = s.AcquireFallbackFontFaces(theme.FontFaceOptions{})
+		w.faceTheme = t
+		w.frame.SetFallbackFaces(w.fallback)
+		w.frame.SetFace(w.face)
 	}
 }
 
@@ -57,7 +78,7 @@ func (w *Text) setFace(t *theme.Theme) {
 // part of the node.Embed type instead of having each widget implement its own?
 
 func (w *Text) padding(t *theme.Theme) int {
-	return t.Pixels(unit.Ems(0.5)).Ceil()
+	return t.Pixels(t.Style("Text").GetSpacing()).Ceil()
 }
 
 func (w *Text) Measure(t *theme.Theme, widthHint, heightHint int) {
@@ -102,51 +123,92 @@ func (w *Text) PaintBase(ctx *node.PaintBaseContext, origin image.Point) error {
 		return nil
 	}
 
-	face := ctx.Theme.AcquireFontFace(theme.FontFaceOptions{})
-	defer ctx.Theme.ReleaseFontFace(theme.FontFaceOptions{}, face)
-	m := face.Metrics()
+	w.setFace(ctx.Theme)
+	pal := ctx.Theme.Style("Text").GetPalette()
+	draw.Draw(dst, dst.Bounds(), pal.Background(), image.Point{}, draw.Src)
+	if w.GlyphCache != nil {
+		return nil
+	}
+
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  pal.Foreground(),
+		Face: w.face,
+	}
+	w.visibleLines(ctx.Theme, origin, dst.Bounds(), func(dot fixed.Point26_6, l *shape.Line) {
+		d.Dot = dot
+		l.Draw(&d)
+	})
+	return nil
+}
+
+func (w *Text) Paint(ctx *node.PaintContext, origin image.Point) error {
+	// TODO: draw an optional border, whose color depends on whether w has the
+	// keyboard focus.
+	if r := w.Rect.Add(origin); w.GlyphCache != nil && ctx.Damaged(r) {
+		// Only draw over the damaged part of w, as the text is drawn with
+		// draw.Over, and the rest of it was not repainted.
+		if ctx.Damage != (image.Rectangle{}) {
+			r = r.Intersect(ctx.Damage)
+			if c, ok := ctx.Drawer.(screen.Clipper); ok {
+				p := &screen.Path{}
+				p.Rect(float64(r.Min.X), float64(r.Min.Y), float64(r.Max.X), float64(r.Max.Y))
+				c.PushClipPath(ctx.Src2Dst, p)
+				defer c.PopClip()
+			}
+		}
+		w.setFace(ctx.Theme)
+		fg := ctx.Theme.Style("Text").GetPalette().Foreground()
+		w.visibleLines(ctx.Theme, origin, r, func(dot fixed.Point26_6, l *shape.Line) {
+			w.GlyphCache.DrawLine(ctx.Drawer, ctx.Src2Dst, dot, l, fg)
+		})
+	}
+	return w.LeafEmbed.Paint(ctx, origin)
+}
+
+// visibleLines calls f with the shaped lines of w's frame that overlap r, and
+// with where to draw them from.
+func (w *Text) visibleLines(t *theme.Theme, origin image.Point, r image.Rectangle, f func(dot fixed.Point26_6, l *shape.Line)) {
+	m := w.face.Metrics()
 	ascent := m.Ascent.Ceil()
 	descent := m.Descent.Ceil()
 	height := m.Height.Ceil()
+	padding := w.padding(t)
 
-	padding := w.padding(ctx.Theme)
-
-	draw.Draw(dst, dst.Bounds(), ctx.Theme.GetPalette().Background(), image.Point{}, draw.Src)
-
-	minDotY := fixed.I(dst.Bounds().Min.Y - descent)
-	maxDotY := fixed.I(dst.Bounds().Max.Y + ascent)
+	minDotY := fixed.I(r.Min.Y - descent)
+	maxDotY := fixed.I(r.Max.Y + ascent)
 
 	x0 := fixed.I(origin.X + w.Rect.Min.X + padding)
-	d := font.Drawer{
-		Dst:  dst,
-		Src:  ctx.Theme.GetPalette().Foreground(),
-		Face: face,
-		Dot: fixed.Point26_6{
-			X: x0,
-			Y: fixed.I(origin.Y + w.Rect.Min.Y + padding + ascent),
-		},
+	x1 := fixed.I(origin.X + w.Rect.Max.X - padding)
+	dot := fixed.Point26_6{
+		X: x0,
+		Y: fixed.I(origin.Y + w.Rect.Min.Y + padding + ascent),
 	}
-	f := &w.frame
-	for p := f.FirstParagraph(); p != nil; p = p.Next(f) {
-		for l := p.FirstLine(f); l != nil; l = l.Next(f) {
-			if d.Dot.Y > minDotY {
-				if d.Dot.Y >= maxDotY {
-					return nil
+	fr := &w.frame
+	for p := fr.FirstParagraph(); p != nil; p = p.Next(fr) {
+		dir := p.Direction(fr)
+		for l := p.FirstLine(fr); l != nil; l = l.Next(fr) {
+			if dot.Y > minDotY {
+				if dot.Y >= maxDotY {
+					return
 				}
-				for b := l.FirstBox(f); b != nil; b = b.Next(f) {
-					d.DrawBytes(b.TrimmedText(f))
-					// TODO: adjust d.Dot.X for any ligatures?
+				sl := l.Shape(fr, dir)
+				// Right-to-left paragraphs are aligned to the right.
+				if dir == shape.RightToLeft {
+					dot.X = x1 - sl.Advance
 				}
-				d.Dot.X = x0
+				f(dot, sl)
+				dot.X = x0
 			}
-			d.Dot.Y += fixed.I(height)
+			dot.Y += fixed.I(height)
 		}
 	}
-	return nil
 }
 
-func (w *Text) Paint(ctx *node.PaintContext, origin image.Point) error {
-	// TODO: draw an optional border, whose color depends on whether w has the
-	// keyboard focus.
-	return w.LeafEmbed.Paint(ctx, origin)
+func (w *Text) Describe(a *screen.AccessNode) {
+	c := w.frame.NewCaret()
+	defer c.Close()
+	b := make([]byte, w.frame.Len())
+	n, _ := io.ReadFull(c, b)
+	a.Role, a.Name = screen.AccessLabel, string(b[:n])
 }
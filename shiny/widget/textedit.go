@@ -0,0 +1,912 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/exp/shiny/gesture"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/text"
+	"golang.org/x/exp/shiny/unit"
+	"golang.org/x/exp/shiny/widget/node"
+	"golang.org/x/exp/shiny/widget/theme"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/mobile/event/key"
+)
+
+var (
+	// shortcutModifier is the modifier key of keyboard shortcuts, such as
+	// for copying and pasting, and wordModifier is that of moving the cursor
+	// by words: Command and Option on macOS, and Control elsewhere.
+	shortcutModifier = key.ModControl
+	wordModifier     = key.ModControl
+
+	// selectionMask is how opaquely the theme's accent color is painted
+	// behind selected text.
+	selectionMask = image.NewUniform(color.Alpha{0x60})
+)
+
+func init() {
+	if runtime.GOOS == "darwin" {
+		shortcutModifier = key.ModMeta
+		wordModifier = key.ModAlt
+	}
+}
+
+// textEdit is an undoable change to a TextEditor's text: the deleted bytes at
+// pos were replaced by the inserted bytes.
+type textEdit struct {
+	pos               int
+	deleted, inserted string
+
+	// cursor and anchor are the selection before the change.
+	cursor, anchor int
+}
+
+// textLine is a line of a TextEditor's laid out text.
+type textLine struct {
+	// start is the byte offset, in the text, of the line's first byte.
+	start int
+	// text is the line's text, including any trailing '\n'.
+	text []byte
+	// y is the line's top, in pixels, relative to the text's top.
+	y int
+}
+
+// visible returns the line's text without any trailing '\n'.
+func (l *textLine) visible() []byte {
+	if n := len(l.text); n > 0 && l.text[n-1] == '\n' {
+		return l.text[:n-1]
+	}
+	return l.text
+}
+
+// TextEditor is a leaf widget that holds text, of one or more lines, that the
+// user can edit after tapping on it to give it the keyboard focus.
+//
+// The user can select text by dragging over it, or double-pressing on a word,
+// and move the cursor with the arrow, Home and End keys, extending the
+// selection if Shift is held down. The usual keyboard shortcuts cut, copy,
+// paste, select all, undo and redo. An input method's composition, before it
+// is committed, is shown underlined at the cursor.
+//
+// Its theme style kind is "TextEditor", or "TextField" for a TextField's.
+type TextEditor struct {
+	node.LeafEmbed
+
+	// Clipboard is the clipboard that text is cut or copied to, and pasted
+	// from. If it is nil, RunWindow sets it to its screen's clipboard.
+	Clipboard screen.Clipboard
+
+	// OnChange, if non-nil, is called after the user changes the text.
+	OnChange func()
+
+	frame text.Frame
+
+	// face is the frame's face, acquired from faceTheme.
+	face      font.Face
+	faceTheme *theme.Theme
+
+	// frameHeight is the frame's height as of the most recent Measure or
+	// Layout. pad is the padding, in pixels, around the text.
+	frameHeight int
+	pad         int
+
+	// singleLine is whether this is a TextField's TextEditor, whose onEnter
+	// is called when the user presses Enter.
+	singleLine bool
+	onEnter    func()
+
+	// cursor and anchor are the ends of the selection, as byte offsets into
+	// the text. The cursor is where the caret is. They are equal when no
+	// text is selected.
+	cursor, anchor int
+
+	// goalX is the x position that moving the cursor up or down a line aims
+	// for, or -1 for the cursor's own x position.
+	goalX int
+
+	// preedit is the text that an input method is composing. It is in the
+	// frame, after the cursor, but is not part of the text until it is
+	// committed. preeditCursor is the input method's cursor, as a byte
+	// offset into preedit.
+	preedit       string
+	preeditCursor int
+
+	undo, redo []textEdit
+
+	// typing is whether the most recent undo entry is of typing, which any
+	// more typing at its end is added to, so that it is undone all at once.
+	typing bool
+
+	focused bool
+
+	// scrollX is how far a single line of text is scrolled to the left, so
+	// that its caret is visible.
+	scrollX int
+}
+
+// NewTextEditor returns a new TextEditor widget.
+func NewTextEditor(text string) *TextEditor {
+	w := &TextEditor{}
+	w.Wrapper = w
+	w.init(text)
+	return w
+}
+
+func (w *TextEditor) init(text string) {
+	w.goalX = -1
+	w.SetText(text)
+}
+
+func (w *TextEditor) textEditor() *TextEditor { return w }
+
+// Text returns the text.
+func (w *TextEditor) Text() string {
+	s := w.read(0, w.frame.Len())
+	if w.preedit != "" {
+		s = s[:w.cursor] + s[w.cursor+len(w.preedit):]
+	}
+	return s
+}
+
+func (w *TextEditor) Focusable() bool { return true }
+
+func (w *TextEditor) Describe(a *screen.AccessNode) {
+	a.Role, a.Value = screen.AccessTextEditor, w.Text()
+	if w.singleLine {
+		a.Role = screen.AccessTextField
+	}
+}
+
+// SetText sets the text, placing the cursor at its end, and forgets what can
+// be undone.
+func (w *TextEditor) SetText(s string) {
+	if w.singleLine {
+		s = singleLineText(s)
+	}
+	w.preedit, w.preeditCursor = "", 0
+	w.rawReplace(0, w.frame.Len(), s)
+	w.cursor, w.anchor = len(s), len(s)
+	w.undo, w.redo, w.typing = nil, nil, false
+	w.changed()
+}
+
+// Selection returns the start and end, as byte offsets into the text, of the
+// selected text. They are equal, at the cursor, if no text is selected.
+func (w *TextEditor) Selection() (start, end int) {
+	if w.cursor < w.anchor {
+		return w.cursor, w.anchor
+	}
+	return w.anchor, w.cursor
+}
+
+// Select selects the text from start to end, with the cursor at end. They
+// are byte offsets into the text, and are clamped to its length.
+func (w *TextEditor) Select(start, end int) {
+	w.setPreedit("", 0)
+	n := w.frame.Len()
+	w.anchor, w.cursor = clampInt(start, 0, n), clampInt(end, 0, n)
+	w.typing, w.goalX = false, -1
+	w.markPaint()
+}
+
+func clampInt(x, lo, hi int) int {
+	if x < lo {
+		return lo
+	}
+	if x > hi {
+		return hi
+	}
+	return x
+}
+
+func singleLineText(s string) string {
+	s = strings.Replace(s, "\r\n", " ", -1)
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// read returns the frame's text from i to j.
+func (w *TextEditor) read(i, j int) string {
+	if i >= j {
+		return ""
+	}
+	c := w.frame.NewCaret()
+	defer c.Close()
+	c.Seek(int64(i), text.SeekSet)
+	b := make([]byte, j-i)
+	n, _ := io.ReadFull(c, b)
+	return string(b[:n])
+}
+
+// rawReplace replaces the frame's text from i to j with s, without recording
+// it for undo.
+func (w *TextEditor) rawReplace(i, j int, s string) {
+	c := w.frame.NewCaret()
+	defer c.Close()
+	c.Seek(int64(i), text.SeekSet)
+	if j > i {
+		c.Delete(text.Forwards, j-i)
+	}
+	c.WriteString(s)
+}
+
+// replace replaces the text from i to j with s, recording it for undo, and
+// places the cursor after s. typing is whether s was typed.
+func (w *TextEditor) replace(i, j int, s string, typing bool) {
+	if w.singleLine {
+		s = singleLineText(s)
+	}
+	e := textEdit{
+		pos:      i,
+		deleted:  w.read(i, j),
+		inserted: s,
+		cursor:   w.cursor,
+		anchor:   w.anchor,
+	}
+	if e.deleted == "" && e.inserted == "" {
+		return
+	}
+	w.rawReplace(i, j, s)
+	if n := len(w.undo); typing && w.typing && e.deleted == "" && w.undo[n-1].pos+len(w.undo[n-1].inserted) == i {
+		w.undo[n-1].inserted += s
+	} else {
+		w.undo = append(w.undo, e)
+	}
+	w.redo = w.redo[:0]
+	w.cursor, w.anchor = i+len(s), i+len(s)
+	w.typing, w.goalX = typing, -1
+	w.edited()
+}
+
+// insert replaces the selection with s.
+func (w *TextEditor) insert(s string, typing bool) {
+	i, j := w.Selection()
+	w.replace(i, j, s, typing)
+}
+
+func (w *TextEditor) undoEdit() {
+	n := len(w.undo)
+	if n == 0 {
+		return
+	}
+	e := w.undo[n-1]
+	w.undo = w.undo[:n-1]
+	w.rawReplace(e.pos, e.pos+len(e.inserted), e.deleted)
+	w.redo = append(w.redo, e)
+	w.cursor, w.anchor = e.cursor, e.anchor
+	w.typing, w.goalX = false, -1
+	w.edited()
+}
+
+func (w *TextEditor) redoEdit() {
+	n := len(w.redo)
+	if n == 0 {
+		return
+	}
+	e := w.redo[n-1]
+	w.redo = w.redo[:n-1]
+	w.rawReplace(e.pos, e.pos+len(e.deleted), e.inserted)
+	w.undo = append(w.undo, e)
+	w.cursor, w.anchor = e.pos+len(e.inserted), e.pos+len(e.inserted)
+	w.typing, w.goalX = false, -1
+	w.edited()
+}
+
+// edited is called after the user changes the text.
+func (w *TextEditor) edited() {
+	w.changed()
+	if w.OnChange != nil {
+		w.OnChange()
+	}
+}
+
+// changed is called after the text changes.
+func (w *TextEditor) changed() {
+	w.markPaint()
+	if !w.singleLine && w.frame.Height() != w.frameHeight {
+		w.Mark(node.MarkNeedsMeasureLayout)
+	}
+}
+
+func (w *TextEditor) markPaint() {
+	w.Mark(node.MarkNeedsPaint | node.MarkNeedsPaintBase)
+}
+
+// setPreedit replaces the input method's composition with s, whose cursor is
+// at the byte offset cursor. Starting to compose replaces the selection.
+func (w *TextEditor) setPreedit(s string, cursor int) {
+	if s == w.preedit && cursor == w.preeditCursor {
+		return
+	}
+	if w.preedit == "" {
+		w.insert("", false)
+	}
+	w.rawReplace(w.cursor, w.cursor+len(w.preedit), s)
+	w.preedit, w.preeditCursor = s, clampInt(cursor, 0, len(s))
+	w.changed()
+}
+
+// moveTo moves the cursor to pos, extending the selection if extend is true
+// or otherwise selecting nothing.
+func (w *TextEditor) moveTo(pos int, extend bool) {
+	w.cursor = pos
+	if !extend {
+		w.anchor = pos
+	}
+	w.typing, w.goalX = false, -1
+	w.markPaint()
+}
+
+func (w *TextEditor) setFace(t *theme.Theme) {
+	if w.face == nil || w.faceTheme != t {
+		if w.face != nil {
+			w.faceTheme.Style(w.styleKind()).ReleaseFontFace(theme.FontFaceOptions{}, w.face)
+		}
+		w.face = t.Style(w.styleKind()).AcquireFontFace(theme.FontFaceOptions{})
+		w.faceTheme = t
+		w.frame.SetFace(w.face)
+	}
+}
+
+// styleKind returns the widget's theme style kind.
+func (w *TextEditor) styleKind() string {
+	if w.singleLine {
+		return "TextField"
+	}
+	return "TextEditor"
+}
+
+func (w *TextEditor) lineHeight() int {
+	if w.face == nil {
+		return 0
+	}
+	m := w.face.Metrics()
+	return m.Ascent.Ceil() + m.Descent.Ceil()
+}
+
+// lines returns the frame's laid out lines.
+//
+// TODO: cache these between edits, for long texts.
+func (w *TextEditor) lines() []textLine {
+	f := &w.frame
+	ls := []textLine(nil)
+	pos, y := 0, 0
+	for p := f.FirstParagraph(); p != nil; p = p.Next(f) {
+		for l := p.FirstLine(f); l != nil; l = l.Next(f) {
+			tl := textLine{start: pos, y: y}
+			for b := l.FirstBox(f); b != nil; b = b.Next(f) {
+				tl.text = append(tl.text, b.Text(f)...)
+			}
+			ls = append(ls, tl)
+			pos += len(tl.text)
+			y += w.lineHeight()
+		}
+	}
+	return ls
+}
+
+// lineIndex returns the index of the line that the cursor is on when it is at
+// pos. A cursor at the end of a wrapped line is at the start of the next.
+func lineIndex(ls []textLine, pos int) int {
+	i := sort.Search(len(ls), func(i int) bool { return ls[i].start > pos }) - 1
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+// lineEnd returns the last position, on the i'th line, of the cursor: before
+// its trailing '\n', or before the space that it was wrapped at.
+func lineEnd(ls []textLine, i int) int {
+	l := &ls[i]
+	end := l.start + len(l.text)
+	if i+1 < len(ls) && len(l.text) > 0 {
+		_, size := utf8.DecodeLastRune(l.text)
+		end -= size
+	}
+	return end
+}
+
+// posX returns the x position, relative to the line's start, of pos on the
+// line l.
+func (w *TextEditor) posX(l *textLine, pos int) int {
+	b := l.visible()
+	if n := pos - l.start; n < len(b) {
+		b = b[:n]
+	}
+	return font.MeasureBytes(w.face, b).Round()
+}
+
+// hitLine returns the position, on the i'th line, that is nearest to x.
+func (w *TextEditor) hitLine(ls []textLine, i, x int) int {
+	l := &ls[i]
+	b := l.visible()
+	fx := fixed.I(x)
+	adv, prev := fixed.Int26_6(0), rune(-1)
+	for k := 0; k < len(b); {
+		r, size := utf8.DecodeRune(b[k:])
+		if prev >= 0 {
+			adv += w.face.Kern(prev, r)
+		}
+		a, _ := w.face.GlyphAdvance(r)
+		if fx < adv+a/2 {
+			return l.start + k
+		}
+		adv, prev, k = adv+a, r, k+size
+	}
+	return lineEnd(ls, i)
+}
+
+// hit returns the position that is nearest to p, relative to the text's
+// top-left.
+func (w *TextEditor) hit(p image.Point) int {
+	if w.face == nil {
+		return w.cursor
+	}
+	ls := w.lines()
+	i := 0
+	if h := w.lineHeight(); h > 0 && p.Y > 0 {
+		i = p.Y / h
+	}
+	if i >= len(ls) {
+		i = len(ls) - 1
+	}
+	return w.hitLine(ls, i, p.X)
+}
+
+// caretRect returns where the caret is, relative to the text's top-left.
+func (w *TextEditor) caretRect() image.Rectangle {
+	if w.face == nil {
+		return image.Rectangle{}
+	}
+	ls := w.lines()
+	pos := w.cursor + w.preeditCursor
+	l := &ls[lineIndex(ls, pos)]
+	x := w.posX(l, pos)
+	return image.Rect(x, l.y, x+1, l.y+w.lineHeight())
+}
+
+// textOrigin returns the text's top-left, relative to w.Rect.Min.
+func (w *TextEditor) textOrigin() image.Point {
+	return image.Point{w.pad - w.scrollX, w.pad}
+}
+
+// textInputRect returns where the caret is, relative to w.Rect.Min, for an
+// input method to show its candidates next to.
+func (w *TextEditor) textInputRect() image.Rectangle {
+	return w.caretRect().Add(w.textOrigin())
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// prevWord returns the start of the word before pos in s, and nextWord
+// returns the end of the word after it.
+func prevWord(s string, pos int) int {
+	for inWord := false; pos > 0; {
+		r, size := utf8.DecodeLastRuneInString(s[:pos])
+		if isWordRune(r) {
+			inWord = true
+		} else if inWord {
+			break
+		}
+		pos -= size
+	}
+	return pos
+}
+
+func nextWord(s string, pos int) int {
+	for inWord := false; pos < len(s); {
+		r, size := utf8.DecodeRuneInString(s[pos:])
+		if isWordRune(r) {
+			inWord = true
+		} else if inWord {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
+
+// prevRune returns the start of the rune before pos, and nextRune returns the
+// end of the rune after it.
+func (w *TextEditor) prevRune(pos int) int {
+	if pos == 0 {
+		return 0
+	}
+	_, size := utf8.DecodeLastRuneInString(w.read(pos-utf8.UTFMax, pos))
+	return pos - size
+}
+
+func (w *TextEditor) nextRune(pos int) int {
+	_, size := utf8.DecodeRuneInString(w.read(pos, pos+utf8.UTFMax))
+	return pos + size
+}
+
+func (w *TextEditor) Measure(t *theme.Theme, widthHint, heightHint int) {
+	w.setFace(t)
+	w.pad = t.Pixels(t.Style(w.styleKind()).GetSpacing()).Ceil()
+	width := widthHint
+	if w.singleLine {
+		if width < 0 {
+			width = t.Pixels(unit.Ems(15)).Ceil()
+		}
+		w.frame.SetMaxWidth(0)
+		w.MeasuredSize = image.Point{width, w.lineHeight() + 2*w.pad}
+		return
+	}
+	if width < 0 {
+		width = t.Pixels(unit.Ems(30)).Ceil()
+	}
+	w.setMaxWidth(width)
+	w.MeasuredSize = image.Point{width, w.frameHeight + 2*w.pad}
+}
+
+func (w *TextEditor) Layout(t *theme.Theme) {
+	w.setFace(t)
+	if !w.singleLine {
+		w.setMaxWidth(w.Rect.Dx())
+	}
+}
+
+func (w *TextEditor) setMaxWidth(width int) {
+	maxWidth := fixed.I(width - 2*w.pad)
+	if maxWidth <= 1 {
+		maxWidth = 1
+	}
+	w.frame.SetMaxWidth(maxWidth)
+	w.frameHeight = w.frame.Height()
+	if h := w.lineHeight(); w.frameHeight < h {
+		w.frameHeight = h
+	}
+}
+
+// scrollToCaret scrolls a single line so that its caret is visible.
+func (w *TextEditor) scrollToCaret() {
+	width := w.Rect.Dx() - 2*w.pad
+	x := w.caretRect().Max.X
+	if x-w.scrollX > width {
+		w.scrollX = x - width
+	}
+	if x-1 < w.scrollX {
+		w.scrollX = x - 1
+	}
+	if w.scrollX < 0 {
+		w.scrollX = 0
+	}
+}
+
+func (w *TextEditor) PaintBase(ctx *node.PaintBaseContext, origin image.Point) error {
+	w.Marks.UnmarkNeedsPaintBase()
+	r := w.Rect.Add(origin)
+	dst := ctx.Dst.SubImage(r).(*image.RGBA)
+	if dst.Bounds().Empty() || w.face == nil {
+		return nil
+	}
+	pal := ctx.Theme.Style(w.styleKind()).GetPalette()
+	draw.Draw(dst, r, pal.Background(), image.Point{}, draw.Src)
+
+	// The border's color shows whether the TextEditor has the focus.
+	border := pal.Dark()
+	if w.focused {
+		border = pal.Accent()
+	}
+	for _, b := range [...]image.Rectangle{
+		{r.Min, image.Point{r.Max.X, r.Min.Y + 1}},
+		{image.Point{r.Min.X, r.Max.Y - 1}, r.Max},
+		{r.Min, image.Point{r.Min.X + 1, r.Max.Y}},
+		{image.Point{r.Max.X - 1, r.Min.Y}, r.Max},
+	} {
+		draw.Draw(dst, b, border, image.Point{}, draw.Src)
+	}
+
+	if w.singleLine {
+		w.scrollToCaret()
+	}
+	// The text is clipped to within the border.
+	dst = dst.SubImage(r.Inset(1)).(*image.RGBA)
+	o := r.Min.Add(w.textOrigin())
+	h := w.lineHeight()
+	ascent := w.face.Metrics().Ascent.Ceil()
+	selStart, selEnd := w.Selection()
+	preStart, preEnd := w.cursor, w.cursor+len(w.preedit)
+	// TODO: shape the text, as the Text widget does, once the caret and the
+	// selection can be placed within bidirectional text.
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  pal.Foreground(),
+		Face: w.face,
+	}
+	for _, l := range w.lines() {
+		y := o.Y + l.y
+		if y+h <= dst.Rect.Min.Y {
+			continue
+		}
+		if y >= dst.Rect.Max.Y {
+			break
+		}
+		b := l.visible()
+		end := l.start + len(l.text)
+
+		if selStart < end && selEnd > l.start && selStart != selEnd {
+			x0, x1 := w.posX(&l, selStart), w.posX(&l, selEnd)
+			if selEnd > l.start+len(b) && len(b) < len(l.text) {
+				// Show that the line's '\n' is selected.
+				x1 += h / 2
+			}
+			sr := image.Rect(o.X+x0, y, o.X+x1, y+h)
+			draw.DrawMask(dst, sr, pal.Accent(), image.Point{}, selectionMask, image.Point{}, draw.Over)
+		}
+
+		d.Dot = fixed.P(o.X, y+ascent)
+		d.DrawBytes(b)
+
+		if preStart < end && preEnd > l.start {
+			x0, x1 := w.posX(&l, preStart), w.posX(&l, preEnd)
+			ur := image.Rect(o.X+x0, y+ascent+1, o.X+x1, y+ascent+2)
+			draw.Draw(dst, ur, pal.Foreground(), image.Point{}, draw.Src)
+		}
+	}
+
+	// TODO: blink the caret.
+	if w.focused {
+		draw.Draw(dst, w.caretRect().Add(o), pal.Foreground(), image.Point{}, draw.Src)
+	}
+	return nil
+}
+
+func (w *TextEditor) OnInputEvent(e interface{}, origin image.Point) node.EventHandled {
+	switch e := e.(type) {
+	case node.FocusEvent:
+		w.focused = e.Focused
+		if !e.Focused {
+			w.setPreedit("", 0)
+		}
+		w.markPaint()
+		return node.Handled
+	case key.Event:
+		return w.onKey(e)
+	case screen.TextEvent:
+		if e.Commit != "" {
+			w.setPreedit("", 0)
+			w.insert(e.Commit, true)
+		}
+		w.setPreedit(e.Preedit, e.PreeditCursor)
+		return node.Handled
+	case gesture.Event:
+		return w.onGesture(e, origin)
+	}
+	return node.NotHandled
+}
+
+func (w *TextEditor) onGesture(e gesture.Event, origin image.Point) node.EventHandled {
+	p := image.Point{int(e.CurrentPos.X), int(e.CurrentPos.Y)}
+	p = p.Sub(origin.Add(w.Rect.Min).Add(w.textOrigin()))
+	switch e.Type {
+	case gesture.TypeStart:
+		node.Focus(w.Wrapper)
+		w.setPreedit("", 0)
+		w.moveTo(w.hit(p), false)
+	case gesture.TypeIsDrag, gesture.TypeDrag:
+		w.moveTo(w.hit(p), true)
+	case gesture.TypeIsDoublePress:
+		s, pos := w.Text(), w.hit(p)
+		w.anchor, w.cursor = prevWord(s, nextWord(s, pos)), nextWord(s, prevWord(s, pos))
+		if w.anchor > pos || w.cursor < pos {
+			// pos is not in a word.
+			w.anchor, w.cursor = pos, pos
+		}
+		w.markPaint()
+	case gesture.TypeTap, gesture.TypeEnd, gesture.TypeIsLongPress, gesture.TypeFling:
+	default:
+		return node.NotHandled
+	}
+	return node.Handled
+}
+
+func (w *TextEditor) onKey(e key.Event) node.EventHandled {
+	if e.Direction == key.DirRelease {
+		return node.NotHandled
+	}
+	if w.preedit != "" {
+		// The input method is composing, so the keys are its.
+		return node.Handled
+	}
+	shift := e.Modifiers&key.ModShift != 0
+	if e.Modifiers&shortcutModifier != 0 && w.onShortcut(e.Code, shift) {
+		return node.Handled
+	}
+
+	byWord := e.Modifiers&wordModifier != 0
+	selStart, selEnd := w.Selection()
+	switch e.Code {
+	case key.CodeLeftArrow:
+		switch {
+		case selStart != selEnd && !shift:
+			w.moveTo(selStart, false)
+		case byWord:
+			w.moveTo(prevWord(w.Text(), w.cursor), shift)
+		default:
+			w.moveTo(w.prevRune(w.cursor), shift)
+		}
+	case key.CodeRightArrow:
+		switch {
+		case selStart != selEnd && !shift:
+			w.moveTo(selEnd, false)
+		case byWord:
+			w.moveTo(nextWord(w.Text(), w.cursor), shift)
+		default:
+			w.moveTo(w.nextRune(w.cursor), shift)
+		}
+	case key.CodeUpArrow, key.CodeDownArrow:
+		if w.singleLine || w.face == nil {
+			return node.NotHandled
+		}
+		d := -1
+		if e.Code == key.CodeDownArrow {
+			d = +1
+		}
+		w.moveLine(d, shift)
+	case key.CodeHome, key.CodeEnd:
+		if w.face == nil {
+			return node.NotHandled
+		}
+		ls := w.lines()
+		i := lineIndex(ls, w.cursor)
+		if e.Code == key.CodeHome {
+			w.moveTo(ls[i].start, shift)
+		} else {
+			w.moveTo(lineEnd(ls, i), shift)
+		}
+	case key.CodeDeleteBackspace:
+		if selStart == selEnd {
+			if byWord {
+				selStart = prevWord(w.Text(), selStart)
+			} else {
+				selStart = w.prevRune(selStart)
+			}
+		}
+		w.replace(selStart, selEnd, "", false)
+	case key.CodeDeleteForward:
+		if selStart == selEnd {
+			if byWord {
+				selEnd = nextWord(w.Text(), selEnd)
+			} else {
+				selEnd = w.nextRune(selEnd)
+			}
+		}
+		w.replace(selStart, selEnd, "", false)
+	case key.CodeReturnEnter:
+		if !w.singleLine {
+			w.insert("\n", true)
+		} else if w.onEnter != nil {
+			w.onEnter()
+		}
+	default:
+		// Control, but not Control and Alt, which is AltGr on some
+		// keyboards, makes a shortcut rather than a rune.
+		m := e.Modifiers
+		if e.Rune < ' ' || e.Rune == 0x7f || m&key.ModMeta != 0 || m&(key.ModControl|key.ModAlt) == key.ModControl {
+			return node.NotHandled
+		}
+		w.insert(string(e.Rune), true)
+	}
+	return node.Handled
+}
+
+// onShortcut handles the keyboard shortcut of the shortcut modifier and c,
+// returning whether it is one of the TextEditor's.
+func (w *TextEditor) onShortcut(c key.Code, shift bool) bool {
+	switch c {
+	case key.CodeA:
+		w.Select(0, w.frame.Len())
+	case key.CodeC:
+		w.copySelection()
+	case key.CodeX:
+		w.copySelection()
+		w.insert("", false)
+	case key.CodeV:
+		w.paste()
+	case key.CodeZ:
+		if shift {
+			w.redoEdit()
+		} else {
+			w.undoEdit()
+		}
+	case key.CodeY:
+		w.redoEdit()
+	case key.CodeHome:
+		w.moveTo(0, shift)
+	case key.CodeEnd:
+		w.moveTo(w.frame.Len(), shift)
+	default:
+		return false
+	}
+	return true
+}
+
+// moveLine moves the cursor d lines down, or up if d is negative, keeping its
+// x position.
+func (w *TextEditor) moveLine(d int, extend bool) {
+	ls := w.lines()
+	i := lineIndex(ls, w.cursor)
+	x := w.goalX
+	if x < 0 {
+		x = w.posX(&ls[i], w.cursor)
+	}
+	pos := 0
+	switch i += d; {
+	case i < 0:
+		pos = 0
+	case i >= len(ls):
+		pos = w.frame.Len()
+	default:
+		pos = w.hitLine(ls, i, x)
+	}
+	w.moveTo(pos, extend)
+	w.goalX = x
+}
+
+func (w *TextEditor) copySelection() {
+	i, j := w.Selection()
+	if i == j || w.Clipboard == nil {
+		return
+	}
+	// TODO: report errors?
+	w.Clipboard.WriteText(w.read(i, j))
+}
+
+func (w *TextEditor) paste() {
+	if w.Clipboard == nil {
+		return
+	}
+	s, err := w.Clipboard.ReadText()
+	if err != nil || s == "" {
+		return
+	}
+	w.insert(strings.Replace(s, "\r\n", "\n", -1), false)
+}
+
+// TextField is a leaf widget that holds a single line of text that the user
+// can edit. It is a TextEditor whose text is not wrapped, but scrolls
+// horizontally, and whose new lines, such as in pasted text, are replaced by
+// spaces.
+type TextField struct {
+	TextEditor
+
+	// OnEnter, if non-nil, is called when the user presses Enter.
+	OnEnter func()
+}
+
+// NewTextField returns a new TextField widget.
+func NewTextField(text string) *TextField {
+	w := &TextField{}
+	w.Wrapper = w
+	w.singleLine = true
+	w.onEnter = func() {
+		if w.OnEnter != nil {
+			w.OnEnter()
+		}
+	}
+	w.init(text)
+	return w
+}
@@ -0,0 +1,215 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/exp/shiny/gesture"
+	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/exp/shiny/widget/node"
+	"golang.org/x/mobile/event/key"
+)
+
+// layoutTestEditor lays out w, whose default theme's font is 8 pixels wide
+// and 16 pixels high, 200 pixels wide.
+func layoutTestEditor(w *TextEditor) {
+	w.Measure(nil, 200, node.NoHint)
+	w.Rect = image.Rectangle{Max: w.MeasuredSize}
+	w.Layout(nil)
+}
+
+func typeText(w *TextEditor, s string) {
+	for _, r := range s {
+		w.OnInputEvent(key.Event{Rune: r, Direction: key.DirPress}, image.Point{})
+	}
+}
+
+func pressKey(w *TextEditor, c key.Code, m key.Modifiers) node.EventHandled {
+	return w.OnInputEvent(key.Event{Rune: -1, Code: c, Modifiers: m, Direction: key.DirPress}, image.Point{})
+}
+
+func TestTextEditorEditing(t *testing.T) {
+	w := NewTextEditor("hello")
+	layoutTestEditor(w)
+
+	changes := 0
+	w.OnChange = func() { changes++ }
+	typeText(w, " world")
+	if got, want := w.Text(), "hello world"; got != want {
+		t.Fatalf("after typing: got %q, want %q", got, want)
+	}
+	if changes != 6 {
+		t.Errorf("changes: got %d, want 6", changes)
+	}
+
+	pressKey(w, key.CodeDeleteBackspace, 0)
+	pressKey(w, key.CodeLeftArrow, wordModifier)
+	pressKey(w, key.CodeLeftArrow, key.ModShift)
+	if got, want := w.Text(), "hello worl"; got != want {
+		t.Fatalf("after backspace: got %q, want %q", got, want)
+	}
+	if i, j := w.Selection(); i != 5 || j != 6 {
+		t.Fatalf("selection: got %d, %d, want 5, 6", i, j)
+	}
+	// Typing replaces the selection.
+	typeText(w, ", ")
+	if got, want := w.Text(), "hello, worl"; got != want {
+		t.Fatalf("after replacing: got %q, want %q", got, want)
+	}
+
+	// The typing is undone all at once, then the backspace, then the first
+	// typing, and redoing redoes them, in order.
+	for _, want := range []string{"hello worl", "hello world", "hello"} {
+		pressKey(w, key.CodeZ, shortcutModifier)
+		if got := w.Text(); got != want {
+			t.Errorf("after undo: got %q, want %q", got, want)
+		}
+	}
+	if got := pressKey(w, key.CodeZ, shortcutModifier); got != node.Handled || w.Text() != "hello" {
+		t.Errorf("undo with nothing to undo: got %v, %q", got, w.Text())
+	}
+	for _, want := range []string{"hello world", "hello worl"} {
+		pressKey(w, key.CodeZ, shortcutModifier|key.ModShift)
+		if got := w.Text(); got != want {
+			t.Errorf("after redo: got %q, want %q", got, want)
+		}
+	}
+	pressKey(w, key.CodeY, shortcutModifier)
+	if got, want := w.Text(), "hello, worl"; got != want {
+		t.Errorf("after the last redo: got %q, want %q", got, want)
+	}
+
+	// A shortcut that is not the editor's is not handled.
+	if got := w.OnInputEvent(key.Event{Rune: 'q', Code: key.CodeQ, Modifiers: key.ModControl | key.ModMeta, Direction: key.DirPress}, image.Point{}); got != node.NotHandled || w.Text() != "hello, worl" {
+		t.Errorf("unknown shortcut: got %v, %q", got, w.Text())
+	}
+}
+
+func TestTextEditorNavigation(t *testing.T) {
+	w := NewTextEditor("ab\ncdef\ng")
+	layoutTestEditor(w)
+	w.Select(1, 1)
+
+	testCases := []struct {
+		code key.Code
+		mods key.Modifiers
+		want int
+	}{
+		{key.CodeDownArrow, 0, 4},
+		{key.CodeEnd, 0, 7},
+		// Moving down keeps the x position that the cursor was at.
+		{key.CodeDownArrow, 0, 9},
+		{key.CodeUpArrow, 0, 7},
+		{key.CodeHome, 0, 3},
+		{key.CodeUpArrow, 0, 0},
+		{key.CodeEnd, shortcutModifier, 9},
+		{key.CodeRightArrow, 0, 9},
+		{key.CodeLeftArrow, 0, 8},
+		{key.CodeLeftArrow, 0, 7},
+	}
+	for _, tc := range testCases {
+		pressKey(w, tc.code, tc.mods)
+		if i, j := w.Selection(); i != tc.want || j != tc.want {
+			t.Fatalf("code %v: got %d, %d, want %d", tc.code, i, j, tc.want)
+		}
+	}
+
+	// Press on the left of the 'e', and drag to the right of the 'a'.
+	press := func(typ gesture.Type, x, y int) {
+		p := image.Point{x*8 + 2, y*16 + 8}.Add(w.textOrigin())
+		w.OnInputEvent(gesture.Event{
+			Type:       typ,
+			CurrentPos: gesture.Point{X: float32(p.X), Y: float32(p.Y)},
+		}, image.Point{})
+	}
+	press(gesture.TypeStart, 2, 1)
+	press(gesture.TypeDrag, 0, 0)
+	press(gesture.TypeDrag, 1, 0)
+	if w.cursor != 1 || w.anchor != 5 {
+		t.Errorf("drag: got cursor %d, anchor %d, want 1, 5", w.cursor, w.anchor)
+	}
+	press(gesture.TypeIsDoublePress, 2, 1)
+	if i, j := w.Selection(); i != 3 || j != 7 {
+		t.Errorf("double press: got %d, %d, want 3, 7", i, j)
+	}
+}
+
+type testClipboard string
+
+func (c *testClipboard) ReadText() (string, error) { return string(*c), nil }
+func (c *testClipboard) WriteText(s string) error  { *c = testClipboard(s); return nil }
+
+func TestTextFieldClipboard(t *testing.T) {
+	w := NewTextField("one\ntwo")
+	layoutTestEditor(&w.TextEditor)
+	clip := new(testClipboard)
+	w.Clipboard = clip
+	entered := false
+	w.OnEnter = func() { entered = true }
+
+	if got, want := w.Text(), "one two"; got != want {
+		t.Fatalf("text: got %q, want %q", got, want)
+	}
+	pressKey(&w.TextEditor, key.CodeA, shortcutModifier)
+	pressKey(&w.TextEditor, key.CodeX, shortcutModifier)
+	if got, want := string(*clip), "one two"; got != want || w.Text() != "" {
+		t.Fatalf("cut: got clipboard %q, text %q, want %q, empty", got, w.Text(), want)
+	}
+
+	*clip = "three\r\nfour"
+	pressKey(&w.TextEditor, key.CodeV, shortcutModifier)
+	if got, want := w.Text(), "three four"; got != want {
+		t.Errorf("paste: got %q, want %q", got, want)
+	}
+	pressKey(&w.TextEditor, key.CodeReturnEnter, 0)
+	if !entered || w.Text() != "three four" {
+		t.Errorf("enter: got entered %t, text %q", entered, w.Text())
+	}
+	// A TextField does not handle moving between lines.
+	if got := pressKey(&w.TextEditor, key.CodeUpArrow, 0); got != node.NotHandled {
+		t.Errorf("up: got %v, want not handled", got)
+	}
+}
+
+func TestTextEditorPreedit(t *testing.T) {
+	w := NewTextEditor("ab")
+	layoutTestEditor(w)
+	w.Select(1, 2)
+
+	send := func(e screen.TextEvent) { w.OnInputEvent(e, image.Point{}) }
+	send(screen.TextEvent{Preedit: "ni", PreeditCursor: 2})
+	if got, want := w.Text(), "a"; got != want {
+		t.Errorf("composing replaces the selection: got %q, want %q", got, want)
+	}
+	if got, want := w.read(0, w.frame.Len()), "ani"; got != want {
+		t.Errorf("frame while composing: got %q, want %q", got, want)
+	}
+	if got, want := w.caretRect().Min.X, 3*8; got != want {
+		t.Errorf("caret while composing: got %d, want %d", got, want)
+	}
+	// Keys are the input method's while it is composing.
+	typeText(w, "x")
+	if got, want := w.Text(), "a"; got != want {
+		t.Errorf("typing while composing: got %q, want %q", got, want)
+	}
+
+	send(screen.TextEvent{Commit: "é", Preedit: "h"})
+	if got, want := w.Text(), "aé"; got != want {
+		t.Errorf("after committing: got %q, want %q", got, want)
+	}
+	send(screen.TextEvent{})
+	if got, want := w.read(0, w.frame.Len()), "aé"; got != want || w.preedit != "" {
+		t.Errorf("after cancelling: got %q, want %q", got, want)
+	}
+
+	// The commit is undone, and then the deleted selection.
+	pressKey(w, key.CodeZ, shortcutModifier)
+	pressKey(w, key.CodeZ, shortcutModifier)
+	if got, want := w.Text(), "ab"; got != want {
+		t.Errorf("after undoing: got %q, want %q", got, want)
+	}
+}
@@ -16,17 +16,41 @@ import (
 )
 
 // FontFaceOptions allows asking for font face variants, such as style (e.g.
-// italic) or weight (e.g. bold).
+// italic), weight (e.g. bold) or size.
 //
 // TODO: include font.Hinting and font.Stretch typed fields?
-//
-// TODO: include font size? If so, directly as "12pt" or indirectly as an enum
-// (Heading1, Heading2, Body, etc)?
 type FontFaceOptions struct {
 	Style  font.Style
 	Weight font.Weight
+
+	// Scale is the font size, relative to the catalog's default size. A zero
+	// value is equivalent to 1. Catalogs whose faces cannot be scaled, such
+	// as those of bitmap fonts, may ignore it.
+	Scale float64
 }
 
+// TextStyle is the role of a piece of text, such as a title, which determines
+// its size, as per a theme's TypeScale.
+type TextStyle uint8
+
+const (
+	// Body is the style of most text, at the catalog's default size.
+	Body TextStyle = iota
+	// Caption is the style of small text, such as annotations.
+	Caption
+	// Subheading, Title and Headline are the styles of progressively larger
+	// headings.
+	Subheading
+	Title
+	Headline
+
+	TextStyleLen = 5
+)
+
+// TypeScale is a theme's font sizes, indexed by TextStyle constants, relative
+// to the font face catalog's default size.
+type TypeScale [TextStyleLen]float64
+
 // FontFaceCatalog provides a theme's font faces.
 //
 // AcquireFontFace returns a font.Face. ReleaseFontFace should be called, with
@@ -43,6 +67,19 @@ type FontFaceCatalog interface {
 	// TODO: add a "Metrics(FontFaceOptions) font.Metrics" method?
 }
 
+// FallbackFontFaceCatalog is a FontFaceCatalog that also provides fallback
+// font faces, for text that the font.Face from AcquireFontFace has no glyphs
+// for, such as emoji or other scripts.
+//
+// AcquireFallbackFontFaces returns the fallback faces in order of preference,
+// not including the primary face. ReleaseFallbackFontFaces should be called,
+// with the same options and faces, once a widget is done with them.
+type FallbackFontFaceCatalog interface {
+	FontFaceCatalog
+	AcquireFallbackFontFaces(FontFaceOptions) []font.Face
+	ReleaseFallbackFontFaces(FontFaceOptions, []font.Face)
+}
+
 // Color is a theme-dependent color, such as "the foreground color". Combining
 // a Color with a Theme results in a color.Color in the sense of the standard
 // library's image/color package. It can also result in an *image.Uniform,
@@ -106,6 +143,12 @@ const (
 // does not provide a DPI value.
 const DefaultDPI = 72.0
 
+// DefaultSpacing is the fallback value of a theme's Spacing.
+var DefaultSpacing = unit.Ems(0.5)
+
+// DefaultFocusRingWidth is the fallback value of a theme's FocusRingWidth.
+var DefaultFocusRingWidth = unit.DIPs(2)
+
 var (
 	// DefaultFontFaceCatalog is a catalog for a basic font face.
 	DefaultFontFaceCatalog FontFaceCatalog = defaultFontFaceCatalog{}
@@ -120,10 +163,34 @@ var (
 		Background: image.Uniform{C: color.RGBA{0xff, 0xff, 0xff, 0xff}}, // Material Design "White".
 	}
 
+	// DarkPalette is a palette for light text on a dark background, such as
+	// for when the user prefers a dark color scheme.
+	DarkPalette = Palette{
+		Light:      image.Uniform{C: color.RGBA{0x42, 0x42, 0x42, 0xff}}, // Material Design "Grey 800".
+		Neutral:    image.Uniform{C: color.RGBA{0x30, 0x30, 0x30, 0xff}}, // Material Design "Grey 850".
+		Dark:       image.Uniform{C: color.RGBA{0x21, 0x21, 0x21, 0xff}}, // Material Design "Grey 900".
+		Accent:     image.Uniform{C: color.RGBA{0x64, 0xb5, 0xf6, 0xff}}, // Material Design "Blue 300".
+		Foreground: image.Uniform{C: color.RGBA{0xff, 0xff, 0xff, 0xff}}, // Material Design "White".
+		Background: image.Uniform{C: color.RGBA{0x12, 0x12, 0x12, 0xff}}, // Material Design dark theme surface.
+	}
+
+	// DefaultTypeScale is the default theme's type scale.
+	DefaultTypeScale = TypeScale{
+		Body:       1,
+		Caption:    0.75,
+		Subheading: 1.25,
+		Title:      1.5,
+		Headline:   2,
+	}
+
 	// Default uses the default DPI, FontFaceCatalog and Palette.
 	//
 	// The nil-valued pointer is a valid receiver for a Theme's methods.
 	Default *Theme
+
+	// DarkTheme uses the default DPI and FontFaceCatalog, and the
+	// DarkPalette.
+	DarkTheme = &Theme{Palette: &DarkPalette}
 )
 
 // Note that a *basicfont.Face such as inconsolata.Regular8x16 is stateless and
@@ -139,7 +206,8 @@ func (defaultFontFaceCatalog) AcquireFontFace(FontFaceOptions) font.Face {
 func (defaultFontFaceCatalog) ReleaseFontFace(FontFaceOptions, font.Face) {}
 
 // Theme is used for measuring, laying out and painting widgets. It consists of
-// a screen DPI resolution, a set of font faces and colors.
+// a screen DPI resolution, a set of font faces and colors, and sizes for text
+// and spacing.
 type Theme struct {
 	// DPI is the screen resolution, in dots (i.e. pixels) per inch.
 	//
@@ -155,6 +223,30 @@ type Theme struct {
 	//
 	// A zero value means to use the DefaultPalette.
 	Palette *Palette
+
+	// TypeScale provides a theme's font sizes.
+	//
+	// A zero value means to use the DefaultTypeScale.
+	TypeScale *TypeScale
+
+	// Spacing is the basic unit of space around and between widgets, such as
+	// the padding around a Text widget's text.
+	//
+	// A zero value means to use the DefaultSpacing.
+	Spacing unit.Value
+
+	// FocusRingWidth is the width of the ring that is painted, in the
+	// palette's Accent color, just inside the widget that the keyboard moved
+	// the focus to. The ring's theme style kind is "FocusRing".
+	//
+	// A zero value means to use the DefaultFocusRingWidth.
+	FocusRingWidth unit.Value
+
+	// Styles are themes for particular kinds of widget, keyed by kind, such
+	// as "Label", that override this theme. A field of a style theme that
+	// has its zero value, and its DPI, are those of this theme. Each widget's
+	// documentation says what kind it is.
+	Styles map[string]*Theme
 }
 
 // GetDPI returns the theme's DPI, or the default DPI if the field value is
@@ -184,6 +276,72 @@ func (t *Theme) GetPalette() *Palette {
 	return &DefaultPalette
 }
 
+// GetTypeScale returns the theme's type scale, or the default type scale if
+// the field value is zero.
+func (t *Theme) GetTypeScale() *TypeScale {
+	if t != nil && t.TypeScale != nil {
+		return t.TypeScale
+	}
+	return &DefaultTypeScale
+}
+
+// GetSpacing returns the theme's spacing, or the default spacing if the field
+// value is zero.
+func (t *Theme) GetSpacing() unit.Value {
+	if t != nil && t.Spacing.F != 0 {
+		return t.Spacing
+	}
+	return DefaultSpacing
+}
+
+// GetFocusRingWidth returns the theme's focus ring width, or the default width
+// if the field value is zero.
+func (t *Theme) GetFocusRingWidth() unit.Value {
+	if t != nil && t.FocusRingWidth.F != 0 {
+		return t.FocusRingWidth
+	}
+	return DefaultFocusRingWidth
+}
+
+// FaceOptions returns the font face options for text of the given style.
+func (t *Theme) FaceOptions(s TextStyle) FontFaceOptions {
+	o := FontFaceOptions{}
+	if s < TextStyleLen {
+		o.Scale = t.GetTypeScale()[s]
+	}
+	if s >= Title {
+		o.Weight = font.WeightBold
+	}
+	return o
+}
+
+// Style returns the theme for widgets of the given kind: this theme,
+// overridden by any of its Styles for that kind.
+func (t *Theme) Style(kind string) *Theme {
+	if t == nil || t.Styles[kind] == nil {
+		return t
+	}
+	s := t.Styles[kind]
+	u := *t
+	u.Styles = nil
+	if s.FontFaceCatalog != nil {
+		u.FontFaceCatalog = s.FontFaceCatalog
+	}
+	if s.Palette != nil {
+		u.Palette = s.Palette
+	}
+	if s.TypeScale != nil {
+		u.TypeScale = s.TypeScale
+	}
+	if s.Spacing.F != 0 {
+		u.Spacing = s.Spacing
+	}
+	if s.FocusRingWidth.F != 0 {
+		u.FocusRingWidth = s.FocusRingWidth
+	}
+	return &u
+}
+
 // AcquireFontFace calls the same method on the result of GetFontFaceCatalog.
 func (t *Theme) AcquireFontFace(o FontFaceOptions) font.Face {
 	return t.GetFontFaceCatalog().AcquireFontFace(o)
@@ -194,6 +352,24 @@ func (t *Theme) ReleaseFontFace(o FontFaceOptions, f font.Face) {
 	t.GetFontFaceCatalog().ReleaseFontFace(o, f)
 }
 
+// AcquireFallbackFontFaces calls the same method on the result of
+// GetFontFaceCatalog, if it is a FallbackFontFaceCatalog, and returns nil
+// otherwise.
+func (t *Theme) AcquireFallbackFontFaces(o FontFaceOptions) []font.Face {
+	if c, ok := t.GetFontFaceCatalog().(FallbackFontFaceCatalog); ok {
+		return c.AcquireFallbackFontFaces(o)
+	}
+	return nil
+}
+
+// ReleaseFallbackFontFaces calls the same method on the result of
+// GetFontFaceCatalog, if it is a FallbackFontFaceCatalog.
+func (t *Theme) ReleaseFallbackFontFaces(o FontFaceOptions, f []font.Face) {
+	if c, ok := t.GetFontFaceCatalog().(FallbackFontFaceCatalog); ok {
+		c.ReleaseFallbackFontFaces(o, f)
+	}
+}
+
 // Pixels implements the unit.Converter interface.
 func (t *Theme) Pixels(v unit.Value) fixed.Int26_6 {
 	c := t.Convert(v, unit.Px)
@@ -8,6 +8,8 @@ import (
 	"testing"
 
 	"golang.org/x/exp/shiny/unit"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/inconsolata"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -40,3 +42,82 @@ func TestThemeIsAUnitConverter(t *testing.T) {
 		}
 	}
 }
+
+func TestThemeStyle(t *testing.T) {
+	big := TypeScale{2, 2, 2, 2, 2}
+	th := &Theme{
+		DPI:     160,
+		Palette: &DarkPalette,
+		Styles: map[string]*Theme{
+			"Label": {
+				DPI:       72,
+				TypeScale: &big,
+			},
+		},
+	}
+
+	// A kind without a style is the theme itself.
+	if got := th.Style("Text"); got != th {
+		t.Errorf("Text: got %p, want the theme %p", got, th)
+	}
+
+	// A style's non-zero fields, other than its DPI, override the theme's.
+	s := th.Style("Label")
+	if got, want := s.GetDPI(), 160.0; got != want {
+		t.Errorf("Label DPI: got %v, want %v", got, want)
+	}
+	if got, want := s.GetPalette(), &DarkPalette; got != want {
+		t.Errorf("Label Palette: got %p, want %p", got, want)
+	}
+	if got, want := s.FaceOptions(Title).Scale, 2.0; got != want {
+		t.Errorf("Label Title scale: got %v, want %v", got, want)
+	}
+	if got, want := s.GetSpacing(), DefaultSpacing; got != want {
+		t.Errorf("Label Spacing: got %v, want %v", got, want)
+	}
+	if s.Styles != nil {
+		t.Errorf("Label Styles: got %v, want nil", s.Styles)
+	}
+
+	// The nil Theme, like the zero Theme, uses the defaults.
+	if got, want := Default.Style("Label"), Default; got != want {
+		t.Errorf("Default Label: got %p, want %p", got, want)
+	}
+	if got, want := Default.FaceOptions(Caption).Scale, DefaultTypeScale[Caption]; got != want {
+		t.Errorf("Default Caption scale: got %v, want %v", got, want)
+	}
+}
+
+// fallbackCatalog is a FallbackFontFaceCatalog that counts its fallback faces'
+// acquisitions and releases.
+type fallbackCatalog struct {
+	defaultFontFaceCatalog
+	acquired, released int
+}
+
+func (c *fallbackCatalog) AcquireFallbackFontFaces(FontFaceOptions) []font.Face {
+	c.acquired++
+	return []font.Face{inconsolata.Bold8x16}
+}
+
+func (c *fallbackCatalog) ReleaseFallbackFontFaces(FontFaceOptions, []font.Face) {
+	c.released++
+}
+
+func TestFallbackFontFaces(t *testing.T) {
+	if got := Default.AcquireFallbackFontFaces(FontFaceOptions{}); got != nil {
+		t.Errorf("Default: got %v, want nil", got)
+	}
+	Default.ReleaseFallbackFontFaces(FontFaceOptions{}, nil)
+
+	c := &fallbackCatalog{}
+	th := &Theme{FontFaceCatalog: c}
+	faces := th.Style("Label").AcquireFallbackFontFaces(FontFaceOptions{})
+	if len(faces) != 1 || faces[0] != inconsolata.Bold8x16 {
+		t.Errorf("got %v, want the catalog's faces", faces)
+	}
+	th.ReleaseFallbackFontFaces(FontFaceOptions{}, faces)
+	if c.acquired != 1 || c.released != 1 {
+		t.Errorf("got %d acquired and %d released, want 1 and 1", c.acquired, c.released)
+	}
+}
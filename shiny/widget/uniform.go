@@ -33,6 +33,7 @@ func NewUniform(c theme.Color, inner node.Node) *Uniform {
 
 func (w *Uniform) PaintBase(ctx *node.PaintBaseContext, origin image.Point) error {
 	w.Marks.UnmarkNeedsPaintBase()
+	w.Marks.UnmarkDescendantNeedsPaintBase()
 	if w.ThemeColor != nil {
 		src := w.ThemeColor.Uniform(ctx.Theme)
 		// TODO: should draw.Src be draw.Over?
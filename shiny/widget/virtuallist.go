@@ -0,0 +1,144 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package widget
+
+import (
+	"image"
+
+	"golang.org/x/exp/shiny/unit"
+	"golang.org/x/exp/shiny/widget/node"
+	"golang.org/x/exp/shiny/widget/theme"
+)
+
+// VirtualList is a container widget that lays out a number of rows, all of the
+// same height, vertically. Unlike a Flow, it only has children for those rows
+// that are visible, or nearly so, as per its viewport. It creates them, and
+// releases them when they are no longer visible, by calling its callbacks,
+// which means that a list of 100,000 rows only needs a few dozen nodes.
+//
+// A VirtualList is designed to be the inner widget of a vertical Scroller,
+// which sets its viewport. Otherwise, every row is visible.
+//
+// After changing Len, mark the VirtualList with node.MarkNeedsMeasureLayout.
+type VirtualList struct {
+	node.ContainerEmbed
+
+	// Len is the number of rows.
+	Len int
+
+	// RowHeight is the height of every row.
+	RowHeight unit.Value
+
+	// NewRow returns a new node for row i. It should not be nil.
+	NewRow func(i int) node.Node
+
+	// ReleaseRow, if not nil, is called with row i's node n after it is
+	// removed from the VirtualList, such as for n to be re-used by NewRow.
+	ReleaseRow func(i int, n node.Node)
+
+	viewport image.Rectangle
+
+	// rows are the children, which are the rows from first onwards.
+	first int
+	rows  []node.Node
+}
+
+// NewVirtualList returns a new VirtualList widget.
+func NewVirtualList(n int, rowHeight unit.Value, newRow func(i int) node.Node) *VirtualList {
+	w := &VirtualList{
+		Len:       n,
+		RowHeight: rowHeight,
+		NewRow:    newRow,
+	}
+	w.Wrapper = w
+	return w
+}
+
+func (w *VirtualList) SetViewport(r image.Rectangle) { w.viewport = r }
+
+func (w *VirtualList) rowHeight(t *theme.Theme) int {
+	if h := t.Pixels(w.RowHeight).Round(); h > 0 {
+		return h
+	}
+	return 1
+}
+
+func (w *VirtualList) Measure(t *theme.Theme, widthHint, heightHint int) {
+	// The rows are not measured, as most of them do not exist, so the
+	// natural width is only the hint.
+	w.MeasuredSize = image.Point{
+		Y: w.Len * w.rowHeight(t),
+	}
+	if widthHint > 0 {
+		w.MeasuredSize.X = widthHint
+	}
+}
+
+func (w *VirtualList) Layout(t *theme.Theme) {
+	rowHeight := w.rowHeight(t)
+	vp := w.viewport
+	if vp.Empty() {
+		vp = w.Rect.Sub(w.Rect.Min)
+	}
+	// Also have rows for half a viewport above and below the viewport, so
+	// that scrolling by less than that does not show missing rows before the
+	// next layout.
+	over := vp.Dy() / 2
+	lo := (vp.Min.Y - over) / rowHeight
+	hi := (vp.Max.Y + over + rowHeight - 1) / rowHeight
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > w.Len {
+		hi = w.Len
+	}
+	if hi < lo {
+		hi = lo
+	}
+
+	// Remove the rows that are no longer visible, keeping the rest, which
+	// are the rows from keptLo onwards.
+	keptLo, kept := lo, w.rows[:0]
+	for j, n := range w.rows {
+		if i := w.first + j; i < lo || hi <= i {
+			w.Remove(n)
+			if w.ReleaseRow != nil {
+				w.ReleaseRow(i, n)
+			}
+			continue
+		}
+		if len(kept) == 0 {
+			keptLo = w.first + j
+		}
+		kept = append(kept, n)
+	}
+
+	// Add the newly visible rows before and after those kept.
+	rows := make([]node.Node, 0, hi-lo)
+	var next node.Node
+	if len(kept) > 0 {
+		next = kept[0]
+	}
+	for i := lo; i < keptLo; i++ {
+		n := w.NewRow(i)
+		w.Insert(n, next)
+		rows = append(rows, n)
+	}
+	rows = append(rows, kept...)
+	for i := keptLo + len(kept); i < hi; i++ {
+		n := w.NewRow(i)
+		w.Insert(n, nil)
+		rows = append(rows, n)
+	}
+	w.first, w.rows = lo, rows
+
+	width := w.Rect.Dx()
+	for j, n := range w.rows {
+		y := (lo + j) * rowHeight
+		n.Measure(t, width, rowHeight)
+		n.Wrappee().Rect = image.Rect(0, y, width, y+rowHeight)
+		n.Layout(t)
+	}
+}
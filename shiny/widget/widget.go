@@ -9,13 +9,16 @@ package widget // import "golang.org/x/exp/shiny/widget"
 
 import (
 	"image"
+	"sync"
 
+	"golang.org/x/exp/shiny/animation"
 	"golang.org/x/exp/shiny/gesture"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/exp/shiny/unit"
 	"golang.org/x/exp/shiny/widget/node"
 	"golang.org/x/exp/shiny/widget/theme"
 	"golang.org/x/image/math/f64"
+	"golang.org/x/mobile/event/key"
 	"golang.org/x/mobile/event/lifecycle"
 	"golang.org/x/mobile/event/mouse"
 	"golang.org/x/mobile/event/paint"
@@ -48,6 +51,25 @@ type RunWindowOptions struct {
 	NewWindowOptions screen.NewWindowOptions
 	Theme            theme.Theme
 
+	// DarkTheme, if non-nil, is used instead of Theme while the screen's
+	// color scheme is dark, so that the window follows the user's system
+	// setting. Typically, it is theme.DarkTheme or a variation on it.
+	DarkTheme *theme.Theme
+
+	// Clock, if non-nil, is ticked with the time of each of the window's
+	// frames, for as long as it has running animations, so that animated
+	// widgets are painted once per frame, in step with the display.
+	Clock *animation.Clock
+
+	// Inspect is whether to show the inspector overlay from the start. See
+	// SetInspector.
+	Inspect bool
+
+	// InspectorKey is whether pressing Control-Shift-I in the window shows
+	// or hides the inspector overlay, unless the focused node handles that
+	// key itself.
+	InspectorKey bool
+
 	// TODO: some mechanism to process, filter and inject events. Perhaps a
 	// screen.EventFilter interface, and note that the zero value in this
 	// RunWindowOptions implicitly includes the gesture.EventFilter?
@@ -57,8 +79,6 @@ type RunWindowOptions struct {
 // like lifecycle events or app-specific)? How does it stop the event loop when
 // the app's work is done?
 
-// TODO: how do widgets signal that they need repaint or relayout?
-
 // TODO: propagate keyboard / mouse / touch events.
 
 // RunWindow creates a new window for s, with the given widget tree, and runs
@@ -67,12 +87,14 @@ type RunWindowOptions struct {
 // A nil opts is valid and means to use the default option values.
 func RunWindow(s screen.Screen, root node.Node, opts *RunWindowOptions) error {
 	var (
-		nwo *screen.NewWindowOptions
-		t   *theme.Theme
+		nwo   *screen.NewWindowOptions
+		t     *theme.Theme
+		clock *animation.Clock
 	)
 	if opts != nil {
 		nwo = &opts.NewWindowOptions
 		t = &opts.Theme
+		clock = opts.Clock
 	}
 	w, err := s.NewWindow(nwo)
 	if err != nil {
@@ -80,6 +102,41 @@ func RunWindow(s screen.Screen, root node.Node, opts *RunWindowOptions) error {
 	}
 	defer w.Release()
 
+	windowsMu.Lock()
+	windows[root] = w
+	windowsMu.Unlock()
+	defer func() {
+		windowsMu.Lock()
+		delete(windows, root)
+		windowsMu.Unlock()
+	}()
+
+	// scheme is the screen's color scheme, and override is the theme set by
+	// SetTheme, if any. chooseTheme sets t to the theme that they select,
+	// keeping t's DPI, and marks the whole tree as needing to be measured,
+	// laid out and painted with it.
+	scheme, override := s.ColorScheme(), (*theme.Theme)(nil)
+	chooseTheme := func() {
+		newT := new(theme.Theme)
+		switch {
+		case override != nil:
+			*newT = *override
+		case opts == nil:
+		case opts.DarkTheme != nil && scheme == screen.DarkColorScheme:
+			*newT = *opts.DarkTheme
+		default:
+			*newT = opts.Theme
+		}
+		if t != nil && t.DPI != 0 {
+			newT.DPI = t.DPI
+		}
+		t = newT
+		markTree(root.Wrappee())
+	}
+	if opts != nil && opts.DarkTheme != nil {
+		chooseTheme()
+	}
+
 	// paintPending batches up multiple NeedsPaint observations so that we
 	// paint only once (which can be relatively expensive) even when there are
 	// multiple input events in the queue, such as from a rapidly moving mouse
@@ -92,6 +149,71 @@ func RunWindow(s screen.Screen, root node.Node, opts *RunWindowOptions) error {
 	// throttle like this, should it be provided at a lower level?
 	paintPending := false
 
+	// framePending is whether painting left some widgets needing paint
+	// again, such as for an animation, and we are waiting for the next
+	// screen.FrameEvent to do so, instead of painting as fast as we can.
+	framePending := false
+
+	// backBufferPreserved is whether the window's contents from the previous
+	// Publish are still valid. If so, an internal paint event only needs to
+	// repaint the damaged region, not the entire widget tree.
+	backBufferPreserved := false
+
+	// hints are the Measure hints for the root node: the window's size.
+	hints := image.Point{}
+
+	// textInputRect is where the focused widget last took text input, for
+	// an input method to show its candidates next to.
+	textInputRect := image.Rectangle{}
+
+	// access builds the accessibility trees that are set after painting, for
+	// as long as the window supports them.
+	access, accessible := accessTree{}, true
+
+	// focusVisible is whether the focused node, if any, was given the focus
+	// by the keyboard, and so has a focus ring, until the next press. ring is
+	// where the most recently painted focus ring is, if any.
+	focusVisible, ring := false, image.Rectangle{}
+
+	// insp is the inspector overlay, shown by SetInspector.
+	insp := inspector{on: opts != nil && opts.Inspect}
+	inspectorKey := opts != nil && opts.InspectorKey
+	defer insp.release()
+
+	// pointer is where the pointer is, while pointerIn, and hovered are the
+	// nodes that it hovers over, from the root down. hoverBuf is re-used
+	// for the next ones.
+	pointer, pointerIn := image.Point{}, false
+	hovered, hoverBuf := []*node.Embed(nil), []*node.Embed(nil)
+	updateHover := func() {
+		hoverBuf = hoverBuf[:0]
+		if pointerIn {
+			hoverBuf = hoverPath(hoverBuf, root.Wrappee(), pointer)
+		}
+		hover(root.Wrappee(), hovered, hoverBuf)
+		hovered, hoverBuf = hoverBuf, hovered
+	}
+	movePointer := func(x, y float32) {
+		pointer, pointerIn = image.Point{int(x), int(y)}, true
+		updateHover()
+	}
+
+	onGesture := func(e gesture.Event) {
+		if e.Type == gesture.TypeStart && focusVisible {
+			focusVisible = false
+			root.Mark(node.MarkNeedsPaint)
+		}
+		// Pressing outside of the focused node takes away its focus,
+		// unless the press gives another node the focus.
+		if f := node.Focused(root); f != nil && e.Type == gesture.TypeStart {
+			p := image.Point{int(e.CurrentPos.X), int(e.CurrentPos.Y)}
+			if fw := f.Wrappee(); !p.In(fw.Rect.Add(windowOrigin(fw))) {
+				node.Unfocus(f)
+			}
+		}
+		root.OnInputEvent(e, image.Point{})
+	}
+
 	gef := gesture.EventFilter{EventDeque: w}
 	for {
 		e := w.NextEvent()
@@ -107,10 +229,57 @@ func RunWindow(s screen.Screen, root node.Node, opts *RunWindowOptions) error {
 				return nil
 			}
 
-		case gesture.Event, mouse.Event:
+		case gesture.Event:
+			onGesture(e)
+
+		case mouse.Event:
+			movePointer(e.X, e.Y)
 			root.OnInputEvent(e, image.Point{})
 
+		case screen.ScrollEvent:
+			movePointer(e.X, e.Y)
+			root.OnInputEvent(e, image.Point{})
+
+		case screen.CrossingEvent:
+			if e.Entered {
+				movePointer(e.X, e.Y)
+			} else {
+				pointerIn = false
+				updateHover()
+			}
+
+		case key.Event, screen.TextEvent:
+			// Key and text events go to the focused node, if any. A Tab key
+			// that it does not handle moves the focus, and, if enabled, a
+			// Control-Shift-I shows or hides the inspector overlay.
+			if f := node.Focused(root); f != nil {
+				if ti, ok := f.(textInputter); ok && ti.textEditor().Clipboard == nil {
+					ti.textEditor().Clipboard = s.Clipboard()
+				}
+				if f.OnInputEvent(e, windowOrigin(f.Wrappee())) == node.Handled {
+					break
+				}
+			}
+			if k, ok := e.(key.Event); ok && inspectorKey && isInspectorKey(k) {
+				insp.show(root, !insp.on)
+				break
+			}
+			if k, ok := e.(key.Event); ok && k.Code == key.CodeTab && k.Direction != key.DirRelease &&
+				k.Modifiers&^key.ModShift == 0 {
+				if n := node.NextFocus(root, k.Modifiers&key.ModShift != 0); n != nil {
+					node.Focus(n)
+					scrollIntoView(n.Wrappee())
+					focusVisible = true
+					root.Mark(node.MarkNeedsPaint)
+				}
+			}
+
 		case paint.Event:
+			node.Relayout(root, t, hints.X, hints.Y)
+			if pointerIn {
+				// The layout may have moved the nodes under the pointer.
+				updateHover()
+			}
 			ctx := &node.PaintContext{
 				Theme:  t,
 				Screen: s,
@@ -120,11 +289,116 @@ func RunWindow(s screen.Screen, root node.Node, opts *RunWindowOptions) error {
 					0, 1, 0,
 				},
 			}
+			paintPending = false
+			var newRing, ringClip image.Rectangle
+			if f := node.Focused(root); f != nil && focusVisible {
+				newRing, ringClip = focusRing(f.Wrappee())
+			}
+			// TODO: pass the damaged region to Publish, so that drivers can
+			// copy only that to the screen, or tell the compositor about it.
+			insp.damage = root.Wrappee().Rect
+			if !e.External && backBufferPreserved && newRing == ring {
+				// Leave ctx.Damage as the zero value, meaning the entire
+				// tree, unless the previous frame can be re-used. It cannot
+				// if the focus ring moved, as it is painted over the tree.
+				ctx.Damage = node.Damage(root, image.Point{})
+				if ctx.Damage.Empty() && !insp.stale(hovered) {
+					break
+				}
+				insp.damage = ctx.Damage
+			}
+			if insp.on {
+				// The overlay is painted over the entire tree, and so the
+				// entire tree is painted under it, but the overlay shows
+				// what was damaged.
+				ctx.Damage = image.Rectangle{}
+			}
+			// Clip to the damage, if the window can, so that nodes that
+			// overlap it are not painted over their undamaged neighbors.
+			clipper, _ := w.(screen.Clipper)
+			if ctx.Damage == (image.Rectangle{}) {
+				clipper = nil
+			}
+			if clipper != nil {
+				clipper.PushClip(ctx.Damage)
+			}
 			if err := root.Paint(ctx, image.Point{}); err != nil {
 				return err
 			}
-			w.Publish()
-			paintPending = false
+			if ring = newRing; !ring.Empty() {
+				paintFocusRing(w, t, ring, ringClip)
+			}
+			if clipper != nil {
+				clipper.PopClip()
+			}
+			if insp.on {
+				if err := insp.paint(w, s, t, root.Wrappee(), hovered); err != nil {
+					return err
+				}
+			}
+			backBufferPreserved = w.Publish().BackBufferPreserved
+			if ti, ok := node.Focused(root).(textInputter); ok {
+				te := ti.textEditor()
+				r := te.textInputRect().Add(te.Rect.Min).Add(windowOrigin(&te.Embed))
+				if r != textInputRect {
+					textInputRect = r
+					w.SetTextInputRect(r)
+				}
+			}
+			if accessible {
+				if a, changed := access.build(root); changed {
+					accessible = w.SetAccessTree(a) == nil
+				}
+			}
+			if m := root.Wrappee().Marks; m.NeedsPaint() || m.DescendantNeedsPaint() {
+				framePending = true
+				w.NextFrame()
+			}
+
+		case screen.FrameEvent:
+			framePending = false
+			if clock != nil {
+				clock.Tick(e.Time)
+			}
+
+		case screen.AccessActionEvent:
+			n, a := access.node(e.ID)
+			if n == nil || a.Disabled {
+				break
+			}
+			switch e.Action {
+			case screen.AccessFocus:
+				if f, ok := n.Wrapper.(node.Focuser); ok && f.Focusable() {
+					node.Focus(n.Wrapper)
+					scrollIntoView(n)
+				}
+			case screen.AccessPress:
+				// Pressing is tapping on the middle of the node.
+				c := n.Rect.Add(windowOrigin(n))
+				p := gesture.Point{
+					X: float32(c.Min.X+c.Max.X) / 2,
+					Y: float32(c.Min.Y+c.Max.Y) / 2,
+				}
+				for _, typ := range [...]gesture.Type{gesture.TypeStart, gesture.TypeTap, gesture.TypeEnd} {
+					onGesture(gesture.Event{Type: typ, InitialPos: p, CurrentPos: p, Scale: 1})
+				}
+			}
+
+		case screen.ColorSchemeEvent:
+			if scheme != e.Scheme {
+				scheme = e.Scheme
+				chooseTheme()
+			}
+
+		case themeEvent:
+			override = e.t
+			chooseTheme()
+
+		case inspectEvent:
+			insp.show(root, e.on)
+
+		case bindingEvent:
+			updateBindings(root, e.v)
 
 		case size.Event:
 			if dpi := float64(e.PixelsPerPt) * unit.PointsPerInch; dpi != t.GetDPI() {
@@ -136,19 +410,79 @@ func RunWindow(s screen.Screen, root node.Node, opts *RunWindowOptions) error {
 				t = newT
 			}
 
-			size := e.Size()
-			root.Measure(t, size.X, size.Y)
+			// The layout happens when painting, so that multiple size
+			// events, or changes to the widgets, only lay out once.
+			hints = e.Size()
 			root.Wrappee().Rect = e.Bounds()
-			root.Layout(t)
-			// TODO: call Mark(node.MarkNeedsPaint)?
+			root.Mark(node.MarkNeedsMeasureLayout | node.MarkNeedsPaint)
 
 		case error:
 			return e
 		}
 
-		if !paintPending && root.Wrappee().Marks.NeedsPaint() {
+		if m := root.Wrappee().Marks; !paintPending && !framePending && (m.NeedsPaint() || m.DescendantNeedsPaint() ||
+			m.NeedsMeasureLayout() || m.DescendantNeedsMeasureLayout() || insp.stale(hovered)) {
 			paintPending = true
 			w.Send(paint.Event{})
 		}
+		// Running animations, such as those just started by an input
+		// event, are advanced on the next frame.
+		if clock != nil && !framePending && clock.Running() {
+			framePending = true
+			w.NextFrame()
+		}
+	}
+}
+
+// windows are the windows run by RunWindow, keyed by their widget trees'
+// roots.
+var (
+	windowsMu sync.Mutex
+	windows   = map[node.Node]screen.Window{}
+)
+
+// themeEvent is sent to a window by SetTheme.
+type themeEvent struct {
+	t *theme.Theme
+}
+
+// SetTheme changes the theme of the window, run by RunWindow, whose widget
+// tree's root is root. The tree is measured, laid out and painted again with
+// t, at the window's DPI. A nil t restores the RunWindowOptions' themes.
+//
+// It is safe to call SetTheme concurrently with RunWindow. It does nothing if
+// root is not the root of a running window.
+func SetTheme(root node.Node, t *theme.Theme) {
+	windowsMu.Lock()
+	w := windows[root]
+	windowsMu.Unlock()
+	if w != nil {
+		w.Send(themeEvent{t})
+	}
+}
+
+// markTree marks n and all of its descendants as needing to be measured, laid
+// out and painted, such as after the theme changes.
+func markTree(n *node.Embed) {
+	n.Mark(node.MarkNeedsMeasureLayout | node.MarkNeedsPaint | node.MarkNeedsPaintBase)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		markTree(c)
+	}
+}
+
+// textInputter is a widget that takes text input, such as a TextEditor.
+type textInputter interface {
+	textEditor() *TextEditor
+}
+
+// windowOrigin returns the origin of n's parent, in window coordinates, so
+// that n.Rect.Add(windowOrigin(n)) is where n is on the window.
+func windowOrigin(n *node.Embed) (o image.Point) {
+	for p := n.Parent; p != nil; p = p.Parent {
+		o = o.Add(p.Rect.Min)
+		if sc, ok := p.Wrapper.(*Scroller); ok {
+			o = o.Sub(sc.Offset())
+		}
 	}
+	return o
 }